@@ -0,0 +1,174 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package blocksig verifies the signatures of the blocks, including those an
+// orderer signs in batches.  Rather than signing each block, such an orderer
+// signs the root of the Merkle tree of the blocks of a batch once the batch
+// closes, and writes every block of the batch with the leaf hashes of the
+// batch and the signatures of their root, so that each block is verified on
+// its own.
+package blocksig
+
+import (
+	"bytes"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/util"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+var (
+	// the prefixes of the leaf and inner node hashes, which keep a leaf from
+	// being passed for an inner node
+	leafPrefix = []byte{0}
+	nodePrefix = []byte{1}
+
+	// the prefix of the data the signatures of a batch sign, which keeps them
+	// from being passed for the signatures of a block
+	signedPrefix = []byte("BlockSignatureBatch")
+
+	// the prefix of the SIGNATURES value of the batch-signed blocks, which
+	// tells them from the blocks signed on their own whose signatures cover a
+	// value, such as their last config
+	batchPrefix = []byte("BlockSignatureBatch\x00")
+)
+
+// Leaf returns the leaf hash of the block in the Merkle tree of its batch,
+// which covers its header and its LAST_CONFIG value, as the signatures of a
+// block signed on its own do.  The LAST_CONFIG metadata of the block must be
+// set.
+func Leaf(block *cb.Block) ([]byte, error) {
+	if block.Header == nil {
+		return nil, errors.New("block has no header")
+	}
+	if len(block.GetMetadata().GetMetadata()) <= int(cb.BlockMetadataIndex_LAST_CONFIG) {
+		return nil, errors.Errorf("block %d has no last config", block.Header.Number)
+	}
+	lastConfig, err := utils.GetMetadataFromBlock(block, cb.BlockMetadataIndex_LAST_CONFIG)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error unmarshaling last config of block %d", block.Header.Number)
+	}
+	return util.ComputeSHA256(util.ConcatenateBytes(leafPrefix, block.Header.Bytes(), lastConfig.Value)), nil
+}
+
+func node(left, right []byte) []byte {
+	return util.ComputeSHA256(util.ConcatenateBytes(nodePrefix, left, right))
+}
+
+// nextLevel returns the parents of the nodes of a level of a Merkle tree, the
+// last node of a level with an odd number of nodes being its own parent
+func nextLevel(level [][]byte) [][]byte {
+	next := make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			next = append(next, level[i])
+			continue
+		}
+		next = append(next, node(level[i], level[i+1]))
+	}
+	return next
+}
+
+// Root returns the root of the Merkle tree of the leaves.
+func Root(leaves [][]byte) []byte {
+	if len(leaves) == 0 {
+		return nil
+	}
+	level := leaves
+	for len(level) > 1 {
+		level = nextLevel(level)
+	}
+	return level[0]
+}
+
+// SignedBytes returns the data a signature of the batch with the root signs
+// along with its signature header.
+func SignedBytes(root, signatureHeader []byte) []byte {
+	return util.ConcatenateBytes(signedPrefix, root, signatureHeader)
+}
+
+// BatchValue returns the value of the SIGNATURES metadata of the blocks of
+// the batch.
+func BatchValue(batch *cb.BlockSignatureBatch) []byte {
+	return append(append([]byte{}, batchPrefix...), utils.MarshalOrPanic(batch)...)
+}
+
+// Batch returns the batch the SIGNATURES metadata of the block refers to,
+// along with the metadata, or a nil batch if the block is signed on its own.
+func Batch(block *cb.Block) (*cb.BlockSignatureBatch, *cb.Metadata, error) {
+	if block.Header == nil {
+		return nil, nil, errors.New("block has no header")
+	}
+	if len(block.GetMetadata().GetMetadata()) <= int(cb.BlockMetadataIndex_SIGNATURES) {
+		return nil, nil, errors.Errorf("block %d has no signatures", block.Header.Number)
+	}
+	metadata, err := utils.GetMetadataFromBlock(block, cb.BlockMetadataIndex_SIGNATURES)
+	if err != nil {
+		return nil, nil, errors.Wrapf(err, "error unmarshaling signatures of block %d", block.Header.Number)
+	}
+	if !bytes.HasPrefix(metadata.Value, batchPrefix) {
+		return nil, metadata, nil
+	}
+	batch := &cb.BlockSignatureBatch{}
+	if err := proto.Unmarshal(metadata.Value[len(batchPrefix):], batch); err != nil {
+		return nil, nil, errors.Wrapf(err, "error unmarshaling signature batch of block %d", block.Header.Number)
+	}
+	if batch.FirstBlock > block.Header.Number {
+		return nil, nil, errors.Errorf("block %d refers to a signature batch starting at block %d", block.Header.Number, batch.FirstBlock)
+	}
+	return batch, metadata, nil
+}
+
+// SignedData returns the signatures of the block, to be evaluated against the
+// BlockValidation policy of its channel, whether the block is signed on its
+// own or in a batch.  The signatures of a batch-signed block sign the root of
+// the leaf hashes the block carries, once its own leaf is checked to be among
+// them, and do not satisfy the policy if the block was tampered with.
+func SignedData(block *cb.Block) ([]*cb.SignedData, error) {
+	batch, metadata, err := Batch(block)
+	if err != nil {
+		return nil, err
+	}
+
+	var signedBytes func(signatureHeader []byte) []byte
+	if batch == nil {
+		signedBytes = func(signatureHeader []byte) []byte {
+			return util.ConcatenateBytes(metadata.Value, signatureHeader, block.Header.Bytes())
+		}
+	} else {
+		index := block.Header.Number - batch.FirstBlock
+		if index >= uint64(len(batch.LeafHashes)) {
+			return nil, errors.Errorf("signature batch of block %d has %d leaves from block %d", block.Header.Number, len(batch.LeafHashes), batch.FirstBlock)
+		}
+		leaf, err := Leaf(block)
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(leaf, batch.LeafHashes[index]) {
+			return nil, errors.Errorf("block %d does not match its leaf in its signature batch", block.Header.Number)
+		}
+		root := Root(batch.LeafHashes)
+		signedBytes = func(signatureHeader []byte) []byte {
+			return SignedBytes(root, signatureHeader)
+		}
+	}
+
+	signatureSet := make([]*cb.SignedData, 0, len(metadata.Signatures))
+	for _, signature := range metadata.Signatures {
+		shdr, err := utils.GetSignatureHeader(signature.SignatureHeader)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error unmarshaling signature header of block %d", block.Header.Number)
+		}
+		signatureSet = append(signatureSet, &cb.SignedData{
+			Identity:  shdr.Creator,
+			Data:      signedBytes(signature.SignatureHeader),
+			Signature: signature.Signature,
+		})
+	}
+	return signatureSet, nil
+}
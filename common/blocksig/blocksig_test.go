@@ -0,0 +1,180 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blocksig
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/util"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func leaves(n int) [][]byte {
+	var l [][]byte
+	for i := 0; i < n; i++ {
+		l = append(l, util.ComputeSHA256([]byte(fmt.Sprintf("leaf%d", i))))
+	}
+	return l
+}
+
+func TestMerkleTree(t *testing.T) {
+	assert.Nil(t, Root(nil))
+	one := leaves(1)
+	assert.Equal(t, one[0], Root(one))
+
+	l := leaves(3)
+	assert.Equal(t, node(node(l[0], l[1]), l[2]), Root(l), "the odd node is promoted")
+
+	l = leaves(4)
+	assert.Equal(t, node(node(l[0], l[1]), node(l[2], l[3])), Root(l))
+	assert.NotEqual(t, Root(l), Root([][]byte{l[1], l[0], l[2], l[3]}), "the leaves are ordered")
+}
+
+// signer "signs" by hashing the data it signs along with its identity
+type signer []byte
+
+func (s signer) sign(data []byte) []byte {
+	return util.ComputeSHA256(util.ConcatenateBytes(s, data))
+}
+
+func (s signer) verify(t *testing.T, signatureSet []*cb.SignedData) bool {
+	require.Len(t, signatureSet, 1)
+	return bytes.Equal(signatureSet[0].Identity, s) && bytes.Equal(s.sign(signatureSet[0].Data), signatureSet[0].Signature)
+}
+
+// batch returns the blocks of a batch from block first on, as an orderer
+// signing them in a batch writes them
+func batch(s signer, first uint64, count int) []*cb.Block {
+	var blocks []*cb.Block
+	var l [][]byte
+	for i := 0; i < count; i++ {
+		block := cb.NewBlock(first+uint64(i), []byte("previous"))
+		block.Header.DataHash = util.ComputeSHA256([]byte(fmt.Sprintf("data%d", i)))
+		block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{
+			Value: utils.MarshalOrPanic(&cb.LastConfig{Index: first - 1}),
+		})
+		leaf, err := Leaf(block)
+		if err != nil {
+			panic(err)
+		}
+		l = append(l, leaf)
+		blocks = append(blocks, block)
+	}
+
+	signature := &cb.MetadataSignature{
+		SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{Creator: s, Nonce: []byte("nonce")}),
+	}
+	signature.Signature = s.sign(SignedBytes(Root(l), signature.SignatureHeader))
+	for _, block := range blocks {
+		block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{
+			Value:      BatchValue(&cb.BlockSignatureBatch{FirstBlock: first, LeafHashes: l}),
+			Signatures: []*cb.MetadataSignature{signature},
+		})
+	}
+	return blocks
+}
+
+func TestSignedData(t *testing.T) {
+	s := signer("orderer")
+	blocks := batch(s, 5, 7)
+
+	for _, block := range blocks {
+		signatureSet, err := SignedData(block)
+		require.NoError(t, err)
+		assert.True(t, s.verify(t, signatureSet), "block %d", block.Header.Number)
+	}
+
+	t.Run("TamperedBlock", func(t *testing.T) {
+		block := blocks[2]
+		tampered := cb.NewBlock(block.Header.Number, []byte("other"))
+		tampered.Metadata = block.Metadata
+		_, err := SignedData(tampered)
+		assert.EqualError(t, err, "block 7 does not match its leaf in its signature batch")
+	})
+
+	t.Run("TamperedLastConfig", func(t *testing.T) {
+		block := &cb.Block{Header: blocks[2].Header, Data: blocks[2].Data, Metadata: &cb.BlockMetadata{}}
+		block.Metadata.Metadata = append([][]byte{}, blocks[2].Metadata.Metadata...)
+		block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{
+			Value: utils.MarshalOrPanic(&cb.LastConfig{Index: 0}),
+		})
+		_, err := SignedData(block)
+		assert.EqualError(t, err, "block 7 does not match its leaf in its signature batch")
+	})
+
+	t.Run("TamperedLeaves", func(t *testing.T) {
+		block := &cb.Block{Header: blocks[2].Header, Data: blocks[2].Data, Metadata: &cb.BlockMetadata{}}
+		block.Metadata.Metadata = append([][]byte{}, blocks[2].Metadata.Metadata...)
+		md := utils.GetMetadataFromBlockOrPanic(blocks[2], cb.BlockMetadataIndex_SIGNATURES)
+		b := &cb.BlockSignatureBatch{}
+		require.NoError(t, proto.Unmarshal(md.Value[len(batchPrefix):], b))
+		b.LeafHashes[0] = util.ComputeSHA256([]byte("forged"))
+		md.Value = BatchValue(b)
+		block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(md)
+		signatureSet, err := SignedData(block)
+		require.NoError(t, err)
+		assert.False(t, s.verify(t, signatureSet), "the root of the forged leaves is not signed")
+	})
+
+	t.Run("OutsideBatch", func(t *testing.T) {
+		block := &cb.Block{Header: &cb.BlockHeader{Number: 12}, Metadata: blocks[2].Metadata}
+		_, err := SignedData(block)
+		assert.EqualError(t, err, "signature batch of block 12 has 7 leaves from block 5")
+	})
+
+	t.Run("SignedOnItsOwn", func(t *testing.T) {
+		block := cb.NewBlock(3, nil)
+		signature := &cb.MetadataSignature{
+			SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{Creator: s, Nonce: []byte("nonce")}),
+		}
+		signature.Signature = s.sign(util.ConcatenateBytes(signature.SignatureHeader, block.Header.Bytes()))
+		block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{
+			Signatures: []*cb.MetadataSignature{signature},
+		})
+		signatureSet, err := SignedData(block)
+		require.NoError(t, err)
+		assert.True(t, s.verify(t, signatureSet))
+
+		// 签名覆盖的值（如最新配置）不被当作签名批次
+		value := utils.MarshalOrPanic(&cb.LastConfig{Index: 2})
+		signature.Signature = s.sign(util.ConcatenateBytes(value, signature.SignatureHeader, block.Header.Bytes()))
+		block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{
+			Value:      value,
+			Signatures: []*cb.MetadataSignature{signature},
+		})
+		signatureSet, err = SignedData(block)
+		require.NoError(t, err)
+		assert.True(t, s.verify(t, signatureSet))
+	})
+}
+
+func TestBatch(t *testing.T) {
+	b, metadata, err := Batch(cb.NewBlock(3, nil))
+	assert.NoError(t, err)
+	assert.Nil(t, b)
+	assert.NotNil(t, metadata)
+
+	block := cb.NewBlock(3, nil)
+	block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{Value: append(append([]byte{}, batchPrefix...), 0xff)})
+	_, _, err = Batch(block)
+	assert.Contains(t, err.Error(), "error unmarshaling signature batch of block 3")
+
+	block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{
+		Value: BatchValue(&cb.BlockSignatureBatch{FirstBlock: 4}),
+	})
+	_, _, err = Batch(block)
+	assert.EqualError(t, err, "block 3 refers to a signature batch starting at block 4")
+
+	_, _, err = Batch(&cb.Block{Header: &cb.BlockHeader{Number: 3}})
+	assert.EqualError(t, err, "block 3 has no signatures")
+}
@@ -19,6 +19,9 @@ const (
 	// ApplicationV1_2 is the capabilties string for standard new non-backwards compatible fabric v1.2 application capabilities.
 	ApplicationV1_2 = "V1_2"
 
+	// ApplicationV1_3 is the capabilties string for standard new non-backwards compatible fabric v1.3 application capabilities.
+	ApplicationV1_3 = "V1_3"
+
 	// ApplicationPvtDataExperimental is the capabilties string for private data using the experimental feature of collections/sideDB.
 	ApplicationPvtDataExperimental = "V1_1_PVTDATA_EXPERIMENTAL"
 
@@ -37,6 +40,7 @@ type ApplicationProvider struct {
 	*registry
 	v11                      bool
 	v12                      bool
+	v13                      bool
 	v11PvtDataExperimental   bool
 	v12LifecycleExperimental bool
 }
@@ -47,6 +51,7 @@ func NewApplicationProvider(capabilities map[string]*cb.Capability) *Application
 	ap.registry = newRegistry(ap, capabilities)
 	_, ap.v11 = capabilities[ApplicationV1_1]
 	_, ap.v12 = capabilities[ApplicationV1_2]
+	_, ap.v13 = capabilities[ApplicationV1_3]
 	_, ap.v11PvtDataExperimental = capabilities[ApplicationPvtDataExperimental]
 	_, ap.v12LifecycleExperimental = capabilities[ApplicationChaincodeLifecycleExperimental]
 	return ap
@@ -59,38 +64,38 @@ func (ap *ApplicationProvider) Type() string {
 
 // ACLs returns whether ACLs may be specified in the channel application config
 func (ap *ApplicationProvider) ACLs() bool {
-	return ap.v12
+	return ap.v12 || ap.v13
 }
 
 // ForbidDuplicateTXIdInBlock specifies whether two transactions with the same TXId are permitted
 // in the same block or whether we mark the second one as TxValidationCode_DUPLICATE_TXID
 func (ap *ApplicationProvider) ForbidDuplicateTXIdInBlock() bool {
-	return ap.v11 || ap.v12
+	return ap.v11 || ap.v12 || ap.v13
 }
 
 // PrivateChannelData returns true if support for private channel data (a.k.a. collections) is enabled.
 // In v1.1, the private channel data is experimental and has to be enabled explicitly.
 // In v1.2, the private channel data is enabled by default.
 func (ap *ApplicationProvider) PrivateChannelData() bool {
-	return ap.v11PvtDataExperimental || ap.v12
+	return ap.v11PvtDataExperimental || ap.v12 || ap.v13
 }
 
 // CollectionUpgrade returns true if this channel is configured to allow updates to
 // existing collection or add new collections through chaincode upgrade (as introduced in v1.2)
 func (ap ApplicationProvider) CollectionUpgrade() bool {
-	return ap.v12
+	return ap.v12 || ap.v13
 }
 
 // V1_1Validation returns true is this channel is configured to perform stricter validation
 // of transactions (as introduced in v1.1).
 func (ap *ApplicationProvider) V1_1Validation() bool {
-	return ap.v11 || ap.v12
+	return ap.v11 || ap.v12 || ap.v13
 }
 
 // V1_2Validation returns true if this channel is configured to perform stricter validation
 // of transactions (as introduced in v1.2).
 func (ap *ApplicationProvider) V1_2Validation() bool {
-	return ap.v12
+	return ap.v12 || ap.v13
 }
 
 // MetadataLifecycle indicates whether the peer should use the deprecated and problematic
@@ -103,7 +108,13 @@ func (ap *ApplicationProvider) MetadataLifecycle() bool {
 // KeyLevelEndorsement returns true if this channel supports endorsement
 // policies expressible at a ledger key granularity, as described in FAB-8812
 func (ap *ApplicationProvider) KeyLevelEndorsement() bool {
-	return ap.v12
+	return ap.v12 || ap.v13
+}
+
+// ChaincodeLimits returns true if this channel supports chaincode specific
+// execution timeout and resource limits, set upon instantiation or upgrade
+func (ap *ApplicationProvider) ChaincodeLimits() bool {
+	return ap.v13
 }
 
 // HasCapability returns true if the capability is supported by this binary.
@@ -114,6 +125,8 @@ func (ap *ApplicationProvider) HasCapability(capability string) bool {
 		return true
 	case ApplicationV1_2:
 		return true
+	case ApplicationV1_3:
+		return true
 	case ApplicationPvtDataExperimental:
 		return true
 	case ApplicationResourcesTreeExperimental:
@@ -72,3 +72,23 @@ func TestChaincodeLifecycleExperimental(t *testing.T) {
 	})
 	assert.True(t, op.MetadataLifecycle())
 }
+
+func TestApplicationV13(t *testing.T) {
+	op := NewApplicationProvider(map[string]*cb.Capability{
+		ApplicationV1_3: {},
+	})
+	assert.NoError(t, op.Supported())
+	assert.True(t, op.ForbidDuplicateTXIdInBlock())
+	assert.True(t, op.V1_1Validation())
+	assert.True(t, op.V1_2Validation())
+	assert.True(t, op.KeyLevelEndorsement())
+	assert.True(t, op.PrivateChannelData())
+	assert.True(t, op.CollectionUpgrade())
+	assert.True(t, op.ACLs())
+	assert.True(t, op.ChaincodeLimits())
+
+	op = NewApplicationProvider(map[string]*cb.Capability{
+		ApplicationV1_2: {},
+	})
+	assert.False(t, op.ChaincodeLimits())
+}
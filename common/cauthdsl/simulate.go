@@ -0,0 +1,215 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cauthdsl
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	mb "github.com/hyperledger/fabric/protos/msp"
+)
+
+// maxSimulatedSigners bounds the signers of a simulation, as the minimal
+// combinations are searched among all the subsets of the signers
+const maxSimulatedSigners = 16
+
+var roleNames = map[mb.MSPRole_MSPRoleType]string{
+	mb.MSPRole_MEMBER: RoleMember,
+	mb.MSPRole_ADMIN:  RoleAdmin,
+	mb.MSPRole_CLIENT: RoleClient,
+	mb.MSPRole_PEER:   RolePeer,
+}
+
+// Signer is a hypothetical identity signing a proposal, known only by its
+// MSP and its role
+type Signer struct {
+	MSPID string
+	Role  mb.MSPRole_MSPRoleType
+}
+
+// ParseSigner parses a signer written as a principal of the policy
+// language, e.g. 'Org1MSP.peer'
+func ParseSigner(s string) (*Signer, error) {
+	subm := regex.FindAllStringSubmatch(strings.Trim(s, "'"), -1)
+	if subm == nil || len(subm) != 1 || len(subm[0]) != 4 {
+		return nil, fmt.Errorf("Error parsing signer %s, expected <MSP_ID>.<ROLE>", s)
+	}
+	for role, name := range roleNames {
+		if name == subm[0][3] {
+			return &Signer{MSPID: subm[0][1], Role: role}, nil
+		}
+	}
+	return nil, fmt.Errorf("Error parsing role %s", s)
+}
+
+func (s *Signer) String() string {
+	return fmt.Sprintf("'%s.%s'", s.MSPID, roleNames[s.Role])
+}
+
+// satisfies returns whether the signer satisfies the role.  Any identity of
+// an MSP is a member of it, the other roles must match exactly.
+func (s *Signer) satisfies(role *mb.MSPRole) bool {
+	if s.MSPID != role.MspIdentifier {
+		return false
+	}
+	return role.Role == mb.MSPRole_MEMBER || role.Role == s.Role
+}
+
+// SimulationResult reports whether a policy is satisfied by a set of signers
+type SimulationResult struct {
+	// Satisfied is true if all the signers together satisfy the policy
+	Satisfied bool
+	// MinimalSets are the combinations of signers satisfying the policy
+	// none of whose subsets does
+	MinimalSets [][]*Signer
+}
+
+// Simulate evaluates the policy against the hypothetical signers, without
+// any certificate or signature.  As when evaluating signed data, a signer
+// contributes to a single principal of the policy.  Only role based
+// principals are supported.
+func Simulate(policy *cb.SignaturePolicyEnvelope, signers []*Signer) (*SimulationResult, error) {
+	if policy == nil || policy.Rule == nil {
+		return nil, fmt.Errorf("Empty policy element")
+	}
+	if len(signers) > maxSimulatedSigners {
+		return nil, fmt.Errorf("Too many signers, at most %d can be simulated but %d were given", maxSimulatedSigners, len(signers))
+	}
+
+	sim := &simulator{signers: signers, rule: policy.Rule}
+	for i, principal := range policy.Identities {
+		if principal.PrincipalClassification != mb.MSPPrincipal_ROLE {
+			return nil, fmt.Errorf("Principal %d of the policy is of classification %s, only ROLE principals can be simulated", i, principal.PrincipalClassification)
+		}
+		role := &mb.MSPRole{}
+		if err := proto.Unmarshal(principal.Principal, role); err != nil {
+			return nil, fmt.Errorf("Error unmarshaling principal %d of the policy: %s", i, err)
+		}
+		sim.roles = append(sim.roles, role)
+	}
+	if err := sim.check(sim.rule); err != nil {
+		return nil, err
+	}
+
+	all := uint32(1)<<uint(len(signers)) - 1
+	result := &SimulationResult{Satisfied: sim.satisfied(all)}
+	if !result.Satisfied {
+		return result, nil
+	}
+
+	// the subsets are visited by increasing size, so that a satisfying subset
+	// is minimal unless it contains one found before
+	var minimal []uint32
+	for size := 1; size <= len(signers); size++ {
+		combinations(len(signers), size, func(set uint32) {
+			for _, m := range minimal {
+				if set&m == m {
+					return
+				}
+			}
+			if sim.satisfied(set) {
+				minimal = append(minimal, set)
+				result.MinimalSets = append(result.MinimalSets, sim.subset(set))
+			}
+		})
+	}
+	return result, nil
+}
+
+type simulator struct {
+	signers []*Signer
+	roles   []*mb.MSPRole
+	rule    *cb.SignaturePolicy
+}
+
+// check verifies that the rule is well formed, so that the evaluation
+// does not need to
+func (sim *simulator) check(rule *cb.SignaturePolicy) error {
+	switch t := rule.Type.(type) {
+	case *cb.SignaturePolicy_SignedBy:
+		if t.SignedBy < 0 || t.SignedBy >= int32(len(sim.roles)) {
+			return fmt.Errorf("identity index out of range, requested %v, but identies length is %d", t.SignedBy, len(sim.roles))
+		}
+	case *cb.SignaturePolicy_NOutOf_:
+		for _, r := range t.NOutOf.Rules {
+			if err := sim.check(r); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("Unknown type: %T:%v", t, t)
+	}
+	return nil
+}
+
+// satisfied returns whether the signers of the set satisfy the rule
+func (sim *simulator) satisfied(set uint32) bool {
+	return sim.eval(sim.rule, ^set, func(uint32) bool { return true })
+}
+
+// eval looks for an assignment of the signers not yet used to the
+// principals of the rule, and returns true as soon as one satisfies both
+// the rule and the continuation, which evaluates the rest of the policy
+// with the signers left
+func (sim *simulator) eval(rule *cb.SignaturePolicy, used uint32, k func(used uint32) bool) bool {
+	switch t := rule.Type.(type) {
+	case *cb.SignaturePolicy_SignedBy:
+		role := sim.roles[t.SignedBy]
+		for i, signer := range sim.signers {
+			bit := uint32(1) << uint(i)
+			if used&bit == 0 && signer.satisfies(role) && k(used|bit) {
+				return true
+			}
+		}
+		return false
+	case *cb.SignaturePolicy_NOutOf_:
+		return sim.choose(t.NOutOf.Rules, int(t.NOutOf.N), used, k)
+	}
+	return false
+}
+
+// choose returns whether n of the rules are satisfied at once
+func (sim *simulator) choose(rules []*cb.SignaturePolicy, n int, used uint32, k func(used uint32) bool) bool {
+	if n <= 0 {
+		return k(used)
+	}
+	for i := 0; i+n <= len(rules); i++ {
+		rest := rules[i+1:]
+		if sim.eval(rules[i], used, func(used uint32) bool { return sim.choose(rest, n-1, used, k) }) {
+			return true
+		}
+	}
+	return false
+}
+
+func (sim *simulator) subset(set uint32) []*Signer {
+	var signers []*Signer
+	for i, signer := range sim.signers {
+		if set&(uint32(1)<<uint(i)) != 0 {
+			signers = append(signers, signer)
+		}
+	}
+	return signers
+}
+
+// combinations invokes f with the subsets of size k of the n signers, in
+// lexicographic order
+func combinations(n, k int, f func(set uint32)) {
+	var visit func(next, k int, set uint32)
+	visit = func(next, k int, set uint32) {
+		if k == 0 {
+			f(set)
+			return
+		}
+		for i := next; i+k <= n; i++ {
+			visit(i+1, k-1, set|uint32(1)<<uint(i))
+		}
+	}
+	visit(0, k, 0)
+}
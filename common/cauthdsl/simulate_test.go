@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cauthdsl
+
+import (
+	"testing"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	mb "github.com/hyperledger/fabric/protos/msp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parseSigners(t *testing.T, specs ...string) []*Signer {
+	var result []*Signer
+	for _, spec := range specs {
+		s, err := ParseSigner(spec)
+		require.NoError(t, err)
+		result = append(result, s)
+	}
+	return result
+}
+
+func TestParseSigner(t *testing.T) {
+	s, err := ParseSigner("'Org1MSP.peer'")
+	require.NoError(t, err)
+	assert.Equal(t, &Signer{MSPID: "Org1MSP", Role: mb.MSPRole_PEER}, s)
+	assert.Equal(t, "'Org1MSP.peer'", s.String())
+
+	_, err = ParseSigner("Org1MSP.orderer")
+	assert.EqualError(t, err, "Error parsing signer Org1MSP.orderer, expected <MSP_ID>.<ROLE>")
+}
+
+func TestSimulate(t *testing.T) {
+	policy, err := FromString("OutOf(2, 'A.peer', 'B.member', OR('C.admin', 'A.peer'))")
+	require.NoError(t, err)
+	all := parseSigners(t, "A.peer", "B.client", "A.peer", "C.admin", "B.admin")
+
+	res, err := Simulate(policy, all)
+	require.NoError(t, err)
+	assert.True(t, res.Satisfied)
+	assert.Equal(t, [][]*Signer{
+		{all[0], all[1]},
+		{all[0], all[2]},
+		{all[0], all[3]},
+		{all[0], all[4]},
+		{all[1], all[2]},
+		{all[1], all[3]},
+		{all[2], all[3]},
+		{all[2], all[4]},
+		{all[3], all[4]},
+	}, res.MinimalSets)
+
+	// a signer contributes to a single principal
+	res, err = Simulate(policy, parseSigners(t, "A.peer", "C.member"))
+	require.NoError(t, err)
+	assert.False(t, res.Satisfied)
+	assert.Empty(t, res.MinimalSets)
+
+	policy, err = FromString("AND('A.member', 'A.member', 'B.admin')")
+	require.NoError(t, err)
+	all = parseSigners(t, "A.client", "B.admin", "A.peer", "B.admin")
+	res, err = Simulate(policy, all)
+	require.NoError(t, err)
+	assert.True(t, res.Satisfied)
+	assert.Equal(t, [][]*Signer{
+		{all[0], all[1], all[2]},
+		{all[0], all[2], all[3]},
+	}, res.MinimalSets)
+}
+
+func TestSimulateErrors(t *testing.T) {
+	_, err := Simulate(&cb.SignaturePolicyEnvelope{}, nil)
+	assert.EqualError(t, err, "Empty policy element")
+
+	_, err = Simulate(SignedByMspMember("A"), make([]*Signer, maxSimulatedSigners+1))
+	assert.EqualError(t, err, "Too many signers, at most 16 can be simulated but 17 were given")
+
+	policy := SignedByMspMember("A")
+	policy.Identities[0].PrincipalClassification = mb.MSPPrincipal_IDENTITY
+	_, err = Simulate(policy, nil)
+	assert.EqualError(t, err, "Principal 0 of the policy is of classification IDENTITY, only ROLE principals can be simulated")
+
+	policy = SignedByMspMember("A")
+	policy.Rule = SignedBy(1)
+	_, err = Simulate(policy, nil)
+	assert.EqualError(t, err, "identity index out of range, requested 1, but identies length is 1")
+}
@@ -34,6 +34,15 @@ type ApplicationOrg interface {
 	AnchorPeers() []*pb.AnchorPeer
 }
 
+// OrdererOrg stores the per org orderer config
+type OrdererOrg interface {
+	Org
+
+	// Endpoints returns the orderer endpoints advertised by the org, along
+	// with their region and priority
+	Endpoints() []*cb.OrdererEndpoint
+}
+
 // Application stores the common shared application config
 type Application interface {
 	// Organizations returns a map of org ID to ApplicationOrg
@@ -83,6 +92,10 @@ type Orderer interface {
 	// ConsensusType returns the configured consensus type
 	ConsensusType() string
 
+	// ConsensusMetadataVersion returns the version of the format of the
+	// metadata of the consensus type
+	ConsensusMetadataVersion() uint32
+
 	// BatchSize returns the maximum number of messages to include in a block
 	BatchSize() *ab.BatchSize
 
@@ -97,6 +110,18 @@ type Orderer interface {
 	// used for ordering
 	KafkaBrokers() []string
 
+	// IngressWeight returns the share of the ordering throughput granted to the channel
+	// while other channels compete for it, relative to their weights
+	IngressWeight() uint32
+
+	// IngressQuotas returns the transaction rates the organizations may broadcast
+	// to the channel by MSP ID, the organizations without a quota being unlimited
+	IngressQuotas() map[string]*ab.IngressQuota
+
+	// TransactionLabels returns the visibility labels the transactions of the
+	// channel may carry by name, none being allowed if empty
+	TransactionLabels() map[string]*ab.TransactionLabel
+
 	// Organizations returns the organizations for the ordering service
 	Organizations() map[string]Org
 
@@ -151,6 +176,10 @@ type ApplicationCapabilities interface {
 	// KeyLevelEndorsement returns true if this channel supports endorsement
 	// policies expressible at a ledger key granularity, as described in FAB-8812
 	KeyLevelEndorsement() bool
+
+	// ChaincodeLimits returns true if this channel supports chaincode specific
+	// execution timeout and resource limits, set upon instantiation or upgrade
+	ChaincodeLimits() bool
 }
 
 // OrdererCapabilities defines the capabilities for the orderer portion of a channel
@@ -91,6 +91,10 @@ func (b *Bundle) ValidateNew(nb Resources) error {
 			return errors.Errorf("Attempted to change consensus type from %s to %s", oc.ConsensusType(), noc.ConsensusType())
 		}
 
+		if noc.ConsensusMetadataVersion() < oc.ConsensusMetadataVersion() {
+			return errors.Errorf("Attempted to downgrade the metadata of consensus type %s from version %d to version %d: consensus metadata versions may only be raised, as the orderers may rely on what the current version introduced", oc.ConsensusType(), oc.ConsensusMetadataVersion(), noc.ConsensusMetadataVersion())
+		}
+
 		for orgName, org := range oc.Organizations() {
 			norg, ok := noc.Organizations()[orgName]
 			if !ok {
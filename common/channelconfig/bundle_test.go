@@ -94,6 +94,41 @@ func TestValidateNew(t *testing.T) {
 		assert.Regexp(t, "Attempted to change consensus type from", err.Error())
 	})
 
+	t.Run("ConsensusMetadataDowngrade", func(t *testing.T) {
+		cb := &Bundle{
+			channelConfig: &ChannelConfig{
+				ordererConfig: &OrdererConfig{
+					protos: &OrdererProtos{
+						ConsensusType: &ab.ConsensusType{
+							Type:            "type1",
+							MetadataVersion: 2,
+						},
+					},
+				},
+			},
+		}
+
+		nb := &Bundle{
+			channelConfig: &ChannelConfig{
+				ordererConfig: &OrdererConfig{
+					protos: &OrdererProtos{
+						ConsensusType: &ab.ConsensusType{
+							Type:            "type1",
+							MetadataVersion: 1,
+						},
+					},
+				},
+			},
+		}
+
+		err := cb.ValidateNew(nb)
+		assert.Error(t, err)
+		assert.Regexp(t, "Attempted to downgrade the metadata of consensus type type1 from version 2 to version 1", err.Error())
+
+		nb.channelConfig.ordererConfig.protos.ConsensusType.MetadataVersion = 3
+		assert.NoError(t, cb.ValidateNew(nb), "Upgrading the consensus metadata")
+	})
+
 	t.Run("OrdererOrgMSPIDChange", func(t *testing.T) {
 		cb := &Bundle{
 			channelConfig: &ChannelConfig{
@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/capabilities"
 	cb "github.com/hyperledger/fabric/protos/common"
 	ab "github.com/hyperledger/fabric/protos/orderer"
@@ -40,8 +41,27 @@ const (
 
 	// KafkaBrokersKey is the cb.ConfigItem type key name for the KafkaBrokers message
 	KafkaBrokersKey = "KafkaBrokers"
+
+	// IngressWeightKey is the cb.ConfigItem type key name for the IngressWeight message
+	IngressWeightKey = "IngressWeight"
+
+	// IngressQuotasKey is the cb.ConfigItem type key name for the IngressQuotas message
+	IngressQuotasKey = "IngressQuotas"
+
+	// TransactionLabelsKey is the cb.ConfigItem type key name for the TransactionLabels message
+	TransactionLabelsKey = "TransactionLabels"
 )
 
+// DefaultIngressWeight is the ingress weight of the channels which do not
+// configure one
+const DefaultIngressWeight = 1
+
+// ConsensusMetadataVersions are the latest versions of the format of the
+// consensus type metadata this binary understands, by consensus type.  The
+// consensus implementations register the versions they support, and the
+// metadata of the other consensus types may only be unversioned, at version 0.
+var ConsensusMetadataVersions = map[string]uint32{}
+
 // OrdererProtos is used as the source of the OrdererConfig
 type OrdererProtos struct {
 	ConsensusType       *ab.ConsensusType
@@ -49,6 +69,9 @@ type OrdererProtos struct {
 	BatchTimeout        *ab.BatchTimeout
 	KafkaBrokers        *ab.KafkaBrokers
 	ChannelRestrictions *ab.ChannelRestrictions
+	IngressWeight       *ab.IngressWeight
+	IngressQuotas       *ab.IngressQuotas
+	TransactionLabels   *ab.TransactionLabels
 	Capabilities        *cb.Capabilities
 }
 
@@ -57,7 +80,9 @@ type OrdererConfig struct {
 	protos *OrdererProtos
 	orgs   map[string]Org
 
-	batchTimeout time.Duration
+	batchTimeout      time.Duration
+	ingressQuotas     map[string]*ab.IngressQuota
+	transactionLabels map[string]*ab.TransactionLabel
 }
 
 // NewOrdererConfig creates a new instance of the orderer config
@@ -77,7 +102,7 @@ func NewOrdererConfig(ordererGroup *cb.ConfigGroup, mspConfig *MSPConfigHandler)
 
 	for orgName, orgGroup := range ordererGroup.Groups {
 		var err error
-		if oc.orgs[orgName], err = NewOrganizationConfig(orgName, orgGroup, mspConfig); err != nil {
+		if oc.orgs[orgName], err = NewOrdererOrgConfig(orgName, orgGroup, mspConfig); err != nil {
 			return nil, err
 		}
 	}
@@ -89,6 +114,12 @@ func (oc *OrdererConfig) ConsensusType() string {
 	return oc.protos.ConsensusType.Type
 }
 
+// ConsensusMetadataVersion returns the version of the format of the metadata
+// of the consensus type
+func (oc *OrdererConfig) ConsensusMetadataVersion() uint32 {
+	return oc.protos.ConsensusType.MetadataVersion
+}
+
 // BatchSize returns the maximum number of messages to include in a block
 func (oc *OrdererConfig) BatchSize() *ab.BatchSize {
 	return oc.protos.BatchSize
@@ -111,6 +142,27 @@ func (oc *OrdererConfig) MaxChannelsCount() uint64 {
 	return oc.protos.ChannelRestrictions.MaxCount
 }
 
+// IngressWeight returns the share of the ordering throughput granted to the
+// channel while other channels compete for it, relative to their weights
+func (oc *OrdererConfig) IngressWeight() uint32 {
+	if oc.protos.IngressWeight.Weight == 0 {
+		return DefaultIngressWeight
+	}
+	return oc.protos.IngressWeight.Weight
+}
+
+// IngressQuotas returns the transaction rates the organizations may broadcast
+// to the channel by MSP ID, the organizations without a quota being unlimited
+func (oc *OrdererConfig) IngressQuotas() map[string]*ab.IngressQuota {
+	return oc.ingressQuotas
+}
+
+// TransactionLabels returns the visibility labels the transactions of the
+// channel may carry by name, none being allowed if empty
+func (oc *OrdererConfig) TransactionLabels() map[string]*ab.TransactionLabel {
+	return oc.transactionLabels
+}
+
 // Organizations returns a map of the orgs in the channel
 func (oc *OrdererConfig) Organizations() map[string]Org {
 	return oc.orgs
@@ -123,9 +175,12 @@ func (oc *OrdererConfig) Capabilities() OrdererCapabilities {
 
 func (oc *OrdererConfig) Validate() error {
 	for _, validator := range []func() error{
+		oc.validateConsensusMetadata,
 		oc.validateBatchSize,
 		oc.validateBatchTimeout,
 		oc.validateKafkaBrokers,
+		oc.validateIngressQuotas,
+		oc.validateTransactionLabels,
 	} {
 		if err := validator(); err != nil {
 			return err
@@ -135,6 +190,25 @@ func (oc *OrdererConfig) Validate() error {
 	return nil
 }
 
+func (oc *OrdererConfig) validateConsensusMetadata() error {
+	consensusType, version, metadata := oc.protos.ConsensusType.GetType(), oc.protos.ConsensusType.GetMetadataVersion(), oc.protos.ConsensusType.GetMetadata()
+	if supported := ConsensusMetadataVersions[consensusType]; version > supported {
+		return fmt.Errorf("Attempted to set the metadata of consensus type %s to version %d, but this binary supports up to version %d: upgrade the orderers and peers of the channel before upgrading its consensus metadata", consensusType, version, supported)
+	}
+	if len(metadata) == 0 {
+		if version > 0 {
+			return fmt.Errorf("Attempted to set the metadata of consensus type %s to version %d without metadata", consensusType, version)
+		}
+		return nil
+	}
+	if factory, ok := ab.ConsensusTypeMetadataMap[consensusType]; ok {
+		if err := proto.Unmarshal(metadata, factory.NewMessage()); err != nil {
+			return fmt.Errorf("Attempted to set invalid metadata for consensus type %s at version %d: %s", consensusType, version, err)
+		}
+	}
+	return nil
+}
+
 func (oc *OrdererConfig) validateBatchSize() error {
 	if oc.protos.BatchSize.MaxMessageCount == 0 {
 		return fmt.Errorf("Attempted to set the batch size max message count to an invalid value: 0")
@@ -172,6 +246,40 @@ func (oc *OrdererConfig) validateKafkaBrokers() error {
 	return nil
 }
 
+func (oc *OrdererConfig) validateIngressQuotas() error {
+	oc.ingressQuotas = map[string]*ab.IngressQuota{}
+	for _, quota := range oc.protos.IngressQuotas.GetQuotas() {
+		if quota.MspId == "" {
+			return fmt.Errorf("Attempted to set an ingress quota without MSP ID")
+		}
+		if _, ok := oc.ingressQuotas[quota.MspId]; ok {
+			return fmt.Errorf("Attempted to set the ingress quota of MSP %s twice", quota.MspId)
+		}
+		if quota.Rate == 0 {
+			return fmt.Errorf("Attempted to set the ingress quota rate of MSP %s to an invalid value: 0", quota.MspId)
+		}
+		oc.ingressQuotas[quota.MspId] = quota
+	}
+	return nil
+}
+
+func (oc *OrdererConfig) validateTransactionLabels() error {
+	oc.transactionLabels = map[string]*ab.TransactionLabel{}
+	for _, label := range oc.protos.TransactionLabels.GetLabels() {
+		if label.Name == "" {
+			return fmt.Errorf("Attempted to set a transaction label without name")
+		}
+		if _, ok := oc.transactionLabels[label.Name]; ok {
+			return fmt.Errorf("Attempted to set the transaction label %s twice", label.Name)
+		}
+		if label.ReadersPolicy == "" {
+			return fmt.Errorf("Attempted to set the transaction label %s without readers policy", label.Name)
+		}
+		oc.transactionLabels[label.Name] = label
+	}
+	return nil
+}
+
 // This does just a barebones sanity check.
 func brokerEntrySeemsValid(broker string) bool {
 	if !strings.Contains(broker, ":") {
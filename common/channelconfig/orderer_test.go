@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	ab "github.com/hyperledger/fabric/protos/orderer"
+	_ "github.com/hyperledger/fabric/protos/orderer/etcdraft"
 
 	logging "github.com/op/go-logging"
 	"github.com/stretchr/testify/assert"
@@ -19,6 +20,25 @@ func init() {
 	logging.SetLevel(logging.DEBUG, "")
 }
 
+func TestConsensusMetadata(t *testing.T) {
+	oc := &OrdererConfig{protos: &OrdererProtos{ConsensusType: &ab.ConsensusType{Type: "solo"}}}
+	assert.NoError(t, oc.validateConsensusMetadata(), "Unversioned consensus metadata")
+
+	oc = &OrdererConfig{protos: &OrdererProtos{ConsensusType: &ab.ConsensusType{Type: "solo", Metadata: []byte("options"), MetadataVersion: 1}}}
+	assert.EqualError(t, oc.validateConsensusMetadata(), "Attempted to set the metadata of consensus type solo to version 1, but this binary supports up to version 0: upgrade the orderers and peers of the channel before upgrading its consensus metadata")
+
+	ConsensusMetadataVersions["solo"] = 2
+	defer delete(ConsensusMetadataVersions, "solo")
+	assert.NoError(t, oc.validateConsensusMetadata(), "Supported consensus metadata version")
+	assert.Equal(t, uint32(1), oc.ConsensusMetadataVersion())
+
+	oc = &OrdererConfig{protos: &OrdererProtos{ConsensusType: &ab.ConsensusType{Type: "solo", MetadataVersion: 1}}}
+	assert.EqualError(t, oc.validateConsensusMetadata(), "Attempted to set the metadata of consensus type solo to version 1 without metadata")
+
+	oc = &OrdererConfig{protos: &OrdererProtos{ConsensusType: &ab.ConsensusType{Type: "etcdraft", Metadata: []byte("garbage")}}}
+	assert.Contains(t, oc.validateConsensusMetadata().Error(), "Attempted to set invalid metadata for consensus type etcdraft at version 0")
+}
+
 func TestBatchSize(t *testing.T) {
 	validMaxMessageCount := uint32(10)
 	validAbsoluteMaxBytes := uint32(1000)
@@ -55,3 +75,57 @@ func TestKafkaBrokers(t *testing.T) {
 	oc = &OrdererConfig{protos: &OrdererProtos{KafkaBrokers: &ab.KafkaBrokers{Brokers: []string{"127.0.0.1", "foo.bar", "127.0.0.1:-1", "localhost:65536", "foo.bar.:9092", ".127.0.0.1:9092", "-foo.bar:9092"}}}}
 	assert.Error(t, oc.validateKafkaBrokers(), "Invalid kafka brokers")
 }
+
+func TestIngressWeight(t *testing.T) {
+	oc := &OrdererConfig{protos: &OrdererProtos{IngressWeight: &ab.IngressWeight{}}}
+	assert.Equal(t, uint32(DefaultIngressWeight), oc.IngressWeight(), "Unset ingress weight")
+
+	oc = &OrdererConfig{protos: &OrdererProtos{IngressWeight: &ab.IngressWeight{Weight: 4}}}
+	assert.Equal(t, uint32(4), oc.IngressWeight(), "Configured ingress weight")
+}
+
+func TestIngressQuotas(t *testing.T) {
+	oc := &OrdererConfig{protos: &OrdererProtos{}}
+	assert.NoError(t, oc.validateIngressQuotas(), "Unset ingress quotas")
+	assert.Empty(t, oc.IngressQuotas())
+
+	oc = &OrdererConfig{protos: &OrdererProtos{IngressQuotas: &ab.IngressQuotas{Quotas: []*ab.IngressQuota{
+		{MspId: "Org1MSP", Rate: 10},
+		{MspId: "Org2MSP", Rate: 5, Burst: 20},
+	}}}}
+	assert.NoError(t, oc.validateIngressQuotas(), "Valid ingress quotas")
+	assert.Len(t, oc.IngressQuotas(), 2)
+	assert.Equal(t, uint32(20), oc.IngressQuotas()["Org2MSP"].Burst)
+
+	for _, quotas := range [][]*ab.IngressQuota{
+		{{Rate: 10}},
+		{{MspId: "Org1MSP", Rate: 10}, {MspId: "Org1MSP", Rate: 5}},
+		{{MspId: "Org1MSP"}},
+	} {
+		oc = &OrdererConfig{protos: &OrdererProtos{IngressQuotas: &ab.IngressQuotas{Quotas: quotas}}}
+		assert.Error(t, oc.validateIngressQuotas(), "Invalid ingress quotas")
+	}
+}
+
+func TestTransactionLabels(t *testing.T) {
+	oc := &OrdererConfig{protos: &OrdererProtos{}}
+	assert.NoError(t, oc.validateTransactionLabels(), "Unset transaction labels")
+	assert.Empty(t, oc.TransactionLabels())
+
+	oc = &OrdererConfig{protos: &OrdererProtos{TransactionLabels: &ab.TransactionLabels{Labels: []*ab.TransactionLabel{
+		{Name: "hr", ReadersPolicy: "/Channel/Application/HRReaders"},
+		{Name: "finance", OrganizationalUnits: []string{"finance", "audit"}, ReadersPolicy: "/Channel/Application/FinanceReaders"},
+	}}}}
+	assert.NoError(t, oc.validateTransactionLabels(), "Valid transaction labels")
+	assert.Len(t, oc.TransactionLabels(), 2)
+	assert.Equal(t, []string{"finance", "audit"}, oc.TransactionLabels()["finance"].OrganizationalUnits)
+
+	for _, labels := range [][]*ab.TransactionLabel{
+		{{ReadersPolicy: "/Channel/Application/Readers"}},
+		{{Name: "hr", ReadersPolicy: "/Channel/Application/Readers"}, {Name: "hr", ReadersPolicy: "/Channel/Application/HRReaders"}},
+		{{Name: "hr"}},
+	} {
+		oc = &OrdererConfig{protos: &OrdererProtos{TransactionLabels: &ab.TransactionLabels{Labels: labels}}}
+		assert.Error(t, oc.validateTransactionLabels(), "Invalid transaction labels")
+	}
+}
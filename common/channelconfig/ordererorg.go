@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package channelconfig
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// OrdererEndpointsKey is the key name for the OrdererEndpoints ConfigValue
+	OrdererEndpointsKey = "OrdererEndpoints"
+)
+
+// OrdererOrgProtos are deserialized from the config
+type OrdererOrgProtos struct {
+	OrdererEndpoints *cb.OrdererEndpoints
+}
+
+// OrdererOrgConfig defines the configuration for an orderer org
+type OrdererOrgConfig struct {
+	*OrganizationConfig
+	protos *OrdererOrgProtos
+	name   string
+}
+
+// NewOrdererOrgConfig creates a new config for an orderer org
+func NewOrdererOrgConfig(id string, orgGroup *cb.ConfigGroup, mspConfig *MSPConfigHandler) (*OrdererOrgConfig, error) {
+	if len(orgGroup.Groups) > 0 {
+		return nil, fmt.Errorf("OrdererOrg config does not allow sub-groups")
+	}
+
+	protos := &OrdererOrgProtos{}
+	orgProtos := &OrganizationProtos{}
+
+	if err := DeserializeProtoValuesFromGroup(orgGroup, protos, orgProtos); err != nil {
+		return nil, errors.Wrap(err, "failed to deserialize values")
+	}
+
+	ooc := &OrdererOrgConfig{
+		name:   id,
+		protos: protos,
+		OrganizationConfig: &OrganizationConfig{
+			name:             id,
+			protos:           orgProtos,
+			mspConfigHandler: mspConfig,
+		},
+	}
+
+	if err := ooc.Validate(); err != nil {
+		return nil, err
+	}
+
+	return ooc, nil
+}
+
+// Endpoints returns the orderer endpoints advertised by this organization,
+// which is empty if the organization relies on the channel's OrdererAddresses
+func (ooc *OrdererOrgConfig) Endpoints() []*cb.OrdererEndpoint {
+	return ooc.protos.OrdererEndpoints.Endpoints
+}
+
+// Validate returns whether the configuration is valid
+func (ooc *OrdererOrgConfig) Validate() error {
+	for _, endpoint := range ooc.protos.OrdererEndpoints.Endpoints {
+		host, port, err := net.SplitHostPort(endpoint.Address)
+		if err != nil || host == "" {
+			return errors.Errorf("invalid orderer endpoint %q for org %s", endpoint.Address, ooc.name)
+		}
+		if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+			return errors.Errorf("invalid port in orderer endpoint %q for org %s", endpoint.Address, ooc.name)
+		}
+	}
+	return ooc.OrganizationConfig.Validate()
+}
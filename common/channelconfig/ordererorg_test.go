@@ -0,0 +1,35 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package channelconfig
+
+import (
+	"testing"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOrdererOrgInterface(t *testing.T) {
+	_ = OrdererOrg(&OrdererOrgConfig{})
+}
+
+func TestOrdererOrgEndpoints(t *testing.T) {
+	endpoints := []*cb.OrdererEndpoint{
+		{Address: "orderer0.eu.example.com:7050", Region: "eu", Priority: 1},
+		{Address: "10.0.0.1:7050", Region: "us"},
+	}
+	ooc := &OrdererOrgConfig{
+		name:   "org",
+		protos: &OrdererOrgProtos{OrdererEndpoints: &cb.OrdererEndpoints{Endpoints: endpoints}},
+	}
+	assert.Equal(t, endpoints, ooc.Endpoints())
+
+	for _, address := range []string{"no-port", ":7050", "host:port", "host:70500"} {
+		ooc.protos.OrdererEndpoints.Endpoints = []*cb.OrdererEndpoint{{Address: address}}
+		assert.Error(t, ooc.Validate(), address)
+	}
+}
@@ -102,6 +102,15 @@ func OrdererAddressesValue(addresses []string) *StandardConfigValue {
 	}
 }
 
+// OrdererEndpointsValue returns the config definition for the orderer endpoints of an orderer org.
+// It is a value for the /Channel/Orderer/*.
+func OrdererEndpointsValue(endpoints []*cb.OrdererEndpoint) *StandardConfigValue {
+	return &StandardConfigValue{
+		key:   OrdererEndpointsKey,
+		value: &cb.OrdererEndpoints{Endpoints: endpoints},
+	}
+}
+
 // ConsensusTypeValue returns the config definition for the orderer consensus type.
 // It is a value for the /Channel/Orderer group.
 func ConsensusTypeValue(consensusType string) *StandardConfigValue {
@@ -159,6 +168,39 @@ func KafkaBrokersValue(brokers []string) *StandardConfigValue {
 	}
 }
 
+// IngressWeightValue returns the config definition for the share of the ordering throughput
+// granted to the channel.  It is a value for the /Channel/Orderer group.
+func IngressWeightValue(weight uint32) *StandardConfigValue {
+	return &StandardConfigValue{
+		key: IngressWeightKey,
+		value: &ab.IngressWeight{
+			Weight: weight,
+		},
+	}
+}
+
+// IngressQuotasValue returns the config definition for the transaction rates the
+// organizations may broadcast to the channel.  It is a value for the /Channel/Orderer group.
+func IngressQuotasValue(quotas []*ab.IngressQuota) *StandardConfigValue {
+	return &StandardConfigValue{
+		key: IngressQuotasKey,
+		value: &ab.IngressQuotas{
+			Quotas: quotas,
+		},
+	}
+}
+
+// TransactionLabelsValue returns the config definition for the visibility labels
+// the transactions of the channel may carry.  It is a value for the /Channel/Orderer group.
+func TransactionLabelsValue(labels []*ab.TransactionLabel) *StandardConfigValue {
+	return &StandardConfigValue{
+		key: TransactionLabelsKey,
+		value: &ab.TransactionLabels{
+			Labels: labels,
+		},
+	}
+}
+
 // MSPValue returns the config definition for an MSP.
 // It is a value for the /Channel/Orderer/*, /Channel/Application/*, and /Channel/Consortiums/*/*/* groups.
 func MSPValue(mspDef *mspprotos.MSPConfig) *StandardConfigValue {
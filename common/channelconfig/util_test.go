@@ -33,6 +33,7 @@ func TestUtilsBasic(t *testing.T) {
 	basicTest(t, HashingAlgorithmValue())
 	basicTest(t, BlockDataHashingStructureValue())
 	basicTest(t, OrdererAddressesValue([]string{"foo:1", "bar:2"}))
+	basicTest(t, OrdererEndpointsValue([]*cb.OrdererEndpoint{{Address: "foo:1", Region: "eu"}}))
 	basicTest(t, ConsensusTypeValue("foo"))
 	basicTest(t, BatchSizeValue(1, 2, 3))
 	basicTest(t, BatchTimeoutValue("1s"))
@@ -90,6 +90,29 @@ func TestMapConfigBack(t *testing.T) {
 	assert.NotEqual(t, config, newConfig, "Mutating the new config should not mutate the existing config")
 }
 
+func TestMapConfigBackUnknownFields(t *testing.T) {
+	// field 1000 with varint value 1, unknown to this version
+	unknown := []byte{0xc0, 0x3e, 0x01}
+
+	config := cb.NewConfigGroup()
+	config.XXX_unrecognized = unknown
+	config.Groups["0DeepGroup"] = cb.NewConfigGroup()
+	config.Groups["0DeepGroup"].XXX_unrecognized = unknown
+	config.Values["0DeepValue"] = &cb.ConfigValue{XXX_unrecognized: unknown}
+	config.Groups["0DeepGroup"].Policies["1DeepPolicy"] = &cb.ConfigPolicy{XXX_unrecognized: unknown}
+
+	confMap, err := mapConfig(config, "Channel")
+	assert.NoError(t, err, "Should not have errored building map")
+
+	newConfig, err := configMapToConfig(confMap, "Channel")
+	assert.NoError(t, err, "Should not have errored building config")
+
+	assert.Equal(t, unknown, newConfig.XXX_unrecognized)
+	assert.Equal(t, unknown, newConfig.Groups["0DeepGroup"].XXX_unrecognized)
+	assert.Equal(t, unknown, newConfig.Values["0DeepValue"].XXX_unrecognized)
+	assert.Equal(t, unknown, newConfig.Groups["0DeepGroup"].Policies["1DeepPolicy"].XXX_unrecognized)
+}
+
 func TestHackInmapConfigBack(t *testing.T) {
 	config := cb.NewConfigGroup()
 	config.Values["ChannelValue1"] = &cb.ConfigValue{}
@@ -151,13 +151,20 @@ func (vi *ValidatorImpl) proposeConfigUpdate(configtx *cb.Envelope) (*cb.ConfigE
 		return nil, errors.Errorf("could not turn configMap back to channelGroup: %s", err)
 	}
 
+	//最新版本 通道配置消息
+	config := &cb.Config{
+		Sequence:     vi.sequence + 1, //通道配置序号增加1
+		ChannelGroup: channelGroup,    //通道配置组
+	}
+
+	// Carry over the fields of the current config unknown to this version, so
+	// that the fields set by newer versions in a mixed-version network are not
+	// silently dropped by the update
+	config.XXX_unrecognized = append([]byte(nil), vi.configProto.XXX_unrecognized...)
+
 	//返回通道配置更新消息
 	return &cb.ConfigEnvelope{
-		//最新版本 通道配置消息
-		Config: &cb.Config{
-			Sequence:     vi.sequence + 1, //通道配置序号增加1
-			ChannelGroup: channelGroup, //通道配置组
-		},
+		Config:     config,
 		LastUpdate: configtx, //最近更新的通道配置交易消息
 	}, nil
 }
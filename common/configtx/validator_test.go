@@ -150,6 +150,39 @@ func TestValidConfigChange(t *testing.T) {
 	}
 }
 
+// TestConfigChangeUnknownFields tests that the fields unknown to this version
+// of the current config and of the update survive the update
+func TestConfigChangeUnknownFields(t *testing.T) {
+	// field 1000 with varint value 1, unknown to this version
+	unknown := []byte{0xc0, 0x3e, 0x01}
+
+	config := makeConfig(makeConfigPair("foo", "foo", 0, []byte("foo")), makeConfigPair("bar", "bar", 0, []byte("bar")))
+	config.XXX_unrecognized = unknown
+	config.ChannelGroup.Values["bar"].XXX_unrecognized = unknown
+
+	vi, err := NewValidatorImpl(defaultChain, config, "foonamespace", defaultPolicyManager())
+	if err != nil {
+		t.Fatalf("Error constructing config manager: %s", err)
+	}
+
+	update := makeConfigPair("foo", "foo", 1, []byte("foo"))
+	update.value.XXX_unrecognized = unknown
+	newConfig := makeConfigUpdateEnvelope(defaultChain, makeConfigSet(), makeConfigSet(update))
+
+	configEnv, err := vi.ProposeConfigUpdate(newConfig)
+	assert.NoError(t, err)
+	assert.Equal(t, unknown, configEnv.Config.XXX_unrecognized)
+	assert.Equal(t, unknown, configEnv.Config.ChannelGroup.Values["foo"].XXX_unrecognized)
+	assert.Equal(t, unknown, configEnv.Config.ChannelGroup.Values["bar"].XXX_unrecognized)
+
+	// the unknown fields survive the round trip through the wire, as when the
+	// config block is read back
+	configEnv = UnmarshalConfigEnvelopeOrPanic(utils.MarshalOrPanic(configEnv))
+	assert.Equal(t, unknown, configEnv.Config.XXX_unrecognized)
+	assert.Equal(t, unknown, configEnv.Config.ChannelGroup.Values["foo"].XXX_unrecognized)
+	assert.NoError(t, vi.Validate(configEnv))
+}
+
 // TestConfigChangeRegressedSequence tests to make sure that a new config cannot roll back one of the
 // config values while advancing another
 func TestConfigChangeRegressedSequence(t *testing.T) {
@@ -12,6 +12,7 @@ import (
 	"time"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
@@ -84,11 +85,43 @@ func (inspector InspectorFunc) Inspect(ctx context.Context, p proto.Message) err
 	return inspector(ctx, p)
 }
 
+//go:generate counterfeiter -o mock/malformed_recorder.go -fake-name MalformedRecorder . MalformedRecorder
+
+// MalformedRecorder records envelopes rejected because they could not be parsed.
+type MalformedRecorder interface {
+	Record(kind string, env *cb.Envelope, parseErr error)
+}
+
+//go:generate counterfeiter -o mock/embargo.go -fake-name Embargo . Embargo
+
+// Embargo holds back the new blocks of embargoed channels from the clients
+// which are not consenters of the channel.
+type Embargo interface {
+	// Hold returns a channel closed once the block with the given number of
+	// the channel, whose ledger has the given height, may be delivered to
+	// the clients which are not consenters, and the function to call once
+	// the block is no longer awaited.  It returns a nil channel if the block
+	// may be delivered at once.
+	Hold(channelID string, number, height uint64) (<-chan struct{}, func())
+}
+
 // Handler handles server requests.
 type Handler struct {
 	ChainManager     ChainManager
 	TimeWindow       time.Duration
 	BindingInspector Inspector
+
+	// MalformedRecorder, if set, records the envelopes which cannot be parsed.
+	MalformedRecorder MalformedRecorder
+
+	// MinHeartbeatInterval is the shortest interval at which heartbeats are
+	// sent to the clients asking for them, none being sent if 0.
+	MinHeartbeatInterval time.Duration
+
+	// Embargo, if set, holds back the new blocks of embargoed channels from
+	// the clients whose requests do not satisfy the block validation policy
+	// of the channel, that is from the clients which are not consenters.
+	Embargo Embargo
 }
 
 //go:generate counterfeiter -o mock/receiver.go -fake-name Receiver . Receiver
@@ -107,12 +140,43 @@ type ResponseSender interface {
 	SendBlockResponse(block *cb.Block) error
 }
 
+// LagAwareResponseSender is optionally implemented by a ResponseSender which
+// adapts its responses to how far the client lags behind the ledger.
+type LagAwareResponseSender interface {
+	// SendBlockResponseBehind sends the block, behind being the number of
+	// blocks requested which are already in the ledger after it.  The
+	// sender may hold back the block until its next response.
+	SendBlockResponseBehind(block *cb.Block, behind uint64) error
+}
+
+// HeartbeatSender sends the heartbeats of a deliver stream.
+type HeartbeatSender interface {
+	SendHeartbeatResponse(heartbeat *ab.Heartbeat) error
+}
+
+// StreamQuota counts a deliver stream towards the quotas of the identities
+// reading a channel on it.
+type StreamQuota interface {
+	// Acquire counts the stream towards the quota of the authorized identity
+	// on the channel, and returns an error if the identity has too many other
+	// streams open on the channel.
+	Acquire(channelID string, identity []byte) error
+}
+
 // Server is a polymorphic structure to support generalization of this handler
 // to be able to deliver different type of responses.
 type Server struct {
 	Receiver
 	PolicyChecker
 	ResponseSender
+
+	// HeartbeatSender, if set, sends heartbeats to the client while it waits
+	// for blocks, if it asked for them.
+	HeartbeatSender HeartbeatSender
+
+	// StreamQuota, if set, counts the stream towards the quotas of the
+	// identities whose requests were authorized on it.
+	StreamQuota StreamQuota
 }
 
 // ExtractChannelHeaderCertHash extracts the TLS cert hash from a channel header.
@@ -168,11 +232,13 @@ func (h *Handler) deliverBlocks(ctx context.Context, srv *Server, envelope *cb.E
 	payload, err := utils.UnmarshalPayload(envelope.Payload)
 	if err != nil {
 		logger.Warningf("Received an envelope from %s with no payload: %s", addr, err)
+		h.recordMalformed(envelope, err)
 		return srv.SendStatusResponse(cb.Status_BAD_REQUEST)
 	}
 
 	if payload.Header == nil {
 		logger.Warningf("Malformed envelope received from %s with bad header", addr)
+		h.recordMalformed(envelope, errors.New("missing payload header"))
 		return srv.SendStatusResponse(cb.Status_BAD_REQUEST)
 	}
 
@@ -180,6 +246,7 @@ func (h *Handler) deliverBlocks(ctx context.Context, srv *Server, envelope *cb.E
 	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
 	if err != nil {
 		logger.Warningf("Failed to unmarshal channel header from %s: %s", addr, err)
+		h.recordMalformed(envelope, err)
 		return srv.SendStatusResponse(cb.Status_BAD_REQUEST)
 	}
 
@@ -210,8 +277,30 @@ func (h *Handler) deliverBlocks(ctx context.Context, srv *Server, envelope *cb.E
 
 	}
 
+	seekInfo := &ab.SeekInfo{}
+	//解析区块搜索信息SeekInfo结构对象
+	if err = proto.Unmarshal(payload.Data, seekInfo); err != nil {
+		logger.Warningf("[channel: %s] Received a signed deliver request from %s with malformed seekInfo payload: %s", chdr.ChannelId, addr, err)
+		h.recordMalformed(envelope, err)
+		return srv.SendStatusResponse(cb.Status_BAD_REQUEST)
+	}
+
+	//检查开始位置和结束位置的合法性
+	if seekInfo.Start == nil || seekInfo.Stop == nil {
+		logger.Warningf("[channel: %s] Received seekInfo message from %s with missing start or stop %v, %v", chdr.ChannelId, addr, seekInfo.Start, seekInfo.Stop)
+		return srv.SendStatusResponse(cb.Status_BAD_REQUEST)
+	}
+
 	//创建访问控制对象，用于封装访问控制权限验证支持对象acSupport（也就是链支持对象）、访问策略检查器checkPolicy，身份证书过期时间等
-	accessControl, err := NewSessionAC(chain, envelope, srv.PolicyChecker, chdr.ChannelId, crypto.ExpiresAt)
+	policyChecker := srv.PolicyChecker
+	//请求标签视图的客户端按各标签的读权限策略授权，而不是通道的读权限策略
+	if labels := seekInfo.GetFilter().GetLabels(); len(labels) > 0 {
+		if policyChecker, err = labelPolicyChecker(chain, labels); err != nil {
+			logger.Warningf("[channel: %s] Rejecting deliver of labels %v for %s: %s", chdr.ChannelId, labels, addr, err)
+			return srv.SendStatusResponse(cb.Status_BAD_REQUEST)
+		}
+	}
+	accessControl, err := NewSessionAC(chain, envelope, policyChecker, chdr.ChannelId, crypto.ExpiresAt)
 	if err != nil {
 		logger.Warningf("[channel: %s] failed to create access control object due to %s", chdr.ChannelId, err)
 		return srv.SendStatusResponse(cb.Status_BAD_REQUEST)
@@ -223,17 +312,12 @@ func (h *Handler) deliverBlocks(ctx context.Context, srv *Server, envelope *cb.E
 		return srv.SendStatusResponse(cb.Status_FORBIDDEN)
 	}
 
-	seekInfo := &ab.SeekInfo{}
-	//解析区块搜索信息SeekInfo结构对象
-	if err = proto.Unmarshal(payload.Data, seekInfo); err != nil {
-		logger.Warningf("[channel: %s] Received a signed deliver request from %s with malformed seekInfo payload: %s", chdr.ChannelId, addr, err)
-		return srv.SendStatusResponse(cb.Status_BAD_REQUEST)
-	}
-
-	//检查开始位置和结束位置的合法性
-	if seekInfo.Start == nil || seekInfo.Stop == nil {
-		logger.Warningf("[channel: %s] Received seekInfo message from %s with missing start or stop %v, %v", chdr.ChannelId, addr, seekInfo.Start, seekInfo.Stop)
-		return srv.SendStatusResponse(cb.Status_BAD_REQUEST)
+	//授权通过后，消息流计入请求者在通道上的消息流配额
+	if srv.StreamQuota != nil {
+		if err := srv.StreamQuota.Acquire(chdr.ChannelId, requestCreator(payload.Header)); err != nil {
+			logger.Warningf("[channel: %s] Rejecting deliver request from %s: %s", chdr.ChannelId, addr, err)
+			return srv.SendStatusResponse(cb.Status_SERVICE_UNAVAILABLE)
+		}
 	}
 
 	logger.Debugf("[channel: %s] Received seekInfo (%p) %v from %s", chdr.ChannelId, seekInfo, seekInfo, addr)
@@ -263,6 +347,23 @@ func (h *Handler) deliverBlocks(ctx context.Context, srv *Server, envelope *cb.E
 		}
 	}
 
+	//已发送的区块数与字节数，用于限制单次请求返回的数据量
+	var sentBlocks, sentBytes uint64
+
+	//禁发期的区块不发送给非共识节点客户端，直至解禁
+	embargoed := h.Embargo != nil && !isConsenter(chain, envelope)
+
+	//客户端请求过滤条件时只发送匹配的交易
+	filter := newContentFilter(seekInfo.Filter)
+
+	//客户端要求心跳时，在等待区块期间定期发送通道高度
+	var heartbeats <-chan time.Time
+	if interval := h.heartbeatInterval(ctx, srv); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		heartbeats = ticker.C
+	}
+
 	//读取区块数据
 	//从本地区块账本中获取指定区块号范围内的区块数据，并依次顺序发送给请求客户端
 	for {
@@ -284,15 +385,24 @@ func (h *Handler) deliverBlocks(ctx context.Context, srv *Server, envelope *cb.E
 			close(iterCh)
 		}()
 
-		select {
-		case <-ctx.Done():
-			logger.Debugf("Context canceled, aborting wait for next block")
-			return errors.Wrapf(ctx.Err(), "context finished before block retrieved")
-		case <-erroredChan:
-			logger.Warningf("Aborting deliver for request because of background error")
-			return srv.SendStatusResponse(cb.Status_SERVICE_UNAVAILABLE)
-		case <-iterCh:
-			// Iterator has set the block and status vars
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				logger.Debugf("Context canceled, aborting wait for next block")
+				return errors.Wrapf(ctx.Err(), "context finished before block retrieved")
+			case <-erroredChan:
+				logger.Warningf("Aborting deliver for request because of background error")
+				return srv.SendStatusResponse(cb.Status_SERVICE_UNAVAILABLE)
+			case <-heartbeats:
+				if err := h.sendHeartbeat(srv, chdr.ChannelId, chain); err != nil {
+					logger.Warningf("[channel: %s] Error sending heartbeat to %s: %s", chdr.ChannelId, addr, err)
+					return err
+				}
+			case <-iterCh:
+				// Iterator has set the block and status vars
+				break wait
+			}
 		}
 
 		if status != cb.Status_SUCCESS {
@@ -310,18 +420,68 @@ func (h *Handler) deliverBlocks(ctx context.Context, srv *Server, envelope *cb.E
 			return srv.SendStatusResponse(cb.Status_FORBIDDEN)
 		}
 
+		if embargoed {
+			held, release := h.Embargo.Hold(chdr.ChannelId, block.Header.Number, chain.Reader().Height())
+			if held != nil && seekInfo.Behavior == ab.SeekInfo_FAIL_IF_NOT_READY {
+				release()
+				return srv.SendStatusResponse(cb.Status_NOT_FOUND)
+			}
+			if held != nil {
+				logger.Debugf("[channel: %s] Holding back embargoed block %d from %s", chdr.ChannelId, block.Header.Number, addr)
+				err := h.waitRelease(ctx, srv, chdr.ChannelId, chain, held, erroredChan, heartbeats)
+				release()
+				if err == errConsenterErrored {
+					logger.Warningf("Aborting deliver for request because of background error")
+					return srv.SendStatusResponse(cb.Status_SERVICE_UNAVAILABLE)
+				}
+				if err != nil {
+					return err
+				}
+			}
+		}
+
+		//没有交易匹配过滤条件的区块不发送
+		if filter != nil {
+			filtered := filter.apply(block)
+			if filtered == nil {
+				logger.Debugf("[channel: %s] No transaction of block %d matches the filter of (%p) for %s", chdr.ChannelId, block.Header.Number, seekInfo, addr)
+				if stopNum == block.Header.Number {
+					break
+				}
+				continue
+			}
+			block = filtered
+		}
+
+		// the first block is always sent, even when larger than the byte cap,
+		// so that a paginating client makes progress
+		//检查发送该区块是否会超出请求的字节数上限
+		blockSize := uint64(proto.Size(block))
+		if seekInfo.MaxBytes > 0 && sentBlocks > 0 && sentBytes+blockSize > seekInfo.MaxBytes {
+			logger.Debugf("[channel: %s] Reached the limit of %d bytes for (%p) for %s", chdr.ChannelId, seekInfo.MaxBytes, seekInfo, addr)
+			break
+		}
+
 		logger.Debugf("[channel: %s] Delivering block for (%p) for %s", chdr.ChannelId, seekInfo, addr)
 
 		//发送区块数据
-		if err := srv.SendBlockResponse(block); err != nil {
+		if err := h.sendBlock(srv, block, behind(chain.Reader().Height(), stopNum, block.Header.Number)); err != nil {
 			logger.Warningf("[channel: %s] Error sending to %s: %s", chdr.ChannelId, addr, err)
 			return err
 		}
+		sentBlocks++
+		sentBytes += blockSize
 
 		//检查获取区块的区块号是否到达结束区块号
 		if stopNum == block.Header.Number {
 			break
 		}
+
+		//检查是否达到请求的区块数上限
+		if seekInfo.MaxBlocks > 0 && sentBlocks >= seekInfo.MaxBlocks {
+			logger.Debugf("[channel: %s] Reached the limit of %d blocks for (%p) for %s", chdr.ChannelId, seekInfo.MaxBlocks, seekInfo, addr)
+			break
+		}
 	}
 	//循环结束
 
@@ -336,6 +496,107 @@ func (h *Handler) deliverBlocks(ctx context.Context, srv *Server, envelope *cb.E
 	return nil
 }
 
+// errConsenterErrored is returned by waitRelease when the consenter errors
+var errConsenterErrored = errors.New("consenter errored")
+
+// waitRelease waits for the release of an embargoed block, sending the
+// heartbeats asked for meanwhile
+func (h *Handler) waitRelease(ctx context.Context, srv *Server, channelID string, chain Chain, released <-chan struct{}, erroredChan <-chan struct{}, heartbeats <-chan time.Time) error {
+	for {
+		select {
+		case <-released:
+			return nil
+		case <-ctx.Done():
+			logger.Debugf("Context canceled, aborting wait for release of embargoed block")
+			return errors.Wrapf(ctx.Err(), "context finished before embargoed block released")
+		case <-erroredChan:
+			return errConsenterErrored
+		case <-heartbeats:
+			if err := h.sendHeartbeat(srv, channelID, chain); err != nil {
+				logger.Warningf("[channel: %s] Error sending heartbeat: %s", channelID, err)
+				return err
+			}
+		}
+	}
+}
+
+// isConsenter returns whether the request satisfies the block validation
+// policy of the channel, which the consenters of the channel satisfy
+func isConsenter(chain Chain, envelope *cb.Envelope) bool {
+	manager := chain.PolicyManager()
+	if manager == nil {
+		return false
+	}
+	policy, ok := manager.GetPolicy(policies.BlockValidation)
+	if !ok {
+		return false
+	}
+	signedData, err := envelope.AsSignedData()
+	if err != nil {
+		return false
+	}
+	return policy.Evaluate(signedData) == nil
+}
+
+// requestCreator returns the creator of the request, nil if its signature
+// header cannot be parsed
+func requestCreator(header *cb.Header) []byte {
+	shdr, err := utils.GetSignatureHeader(header.SignatureHeader)
+	if err != nil {
+		return nil
+	}
+	return shdr.Creator
+}
+
+// sendBlock sends the block, telling senders aware of the lag of the client how
+// many of the blocks requested are behind it
+func (h *Handler) sendBlock(srv *Server, block *cb.Block, behind uint64) error {
+	if las, ok := srv.ResponseSender.(LagAwareResponseSender); ok {
+		return las.SendBlockResponseBehind(block, behind)
+	}
+	return srv.SendBlockResponse(block)
+}
+
+// behind returns the number of blocks up to the stop number which are in a
+// ledger of the given height after the given block number
+func behind(height, stopNum, number uint64) uint64 {
+	last := height - 1
+	if stopNum < last {
+		last = stopNum
+	}
+	if last <= number {
+		return 0
+	}
+	return last - number
+}
+
+// heartbeatInterval returns the interval at which the client of the stream
+// asked for heartbeats, raised to the minimum interval, or 0 if none are sent
+func (h *Handler) heartbeatInterval(ctx context.Context, srv *Server) time.Duration {
+	if h.MinHeartbeatInterval <= 0 || srv.HeartbeatSender == nil {
+		return 0
+	}
+	interval := util.ExtractHeartbeatInterval(ctx)
+	if interval > 0 && interval < h.MinHeartbeatInterval {
+		interval = h.MinHeartbeatInterval
+	}
+	return interval
+}
+
+func (h *Handler) sendHeartbeat(srv *Server, channelID string, chain Chain) error {
+	return srv.HeartbeatSender.SendHeartbeatResponse(&ab.Heartbeat{
+		ChannelId: channelID,
+		Height:    chain.Reader().Height(),
+		Timestamp: ptypes.TimestampNow(),
+	})
+}
+
+func (h *Handler) recordMalformed(envelope *cb.Envelope, err error) {
+	if h.MalformedRecorder != nil {
+		h.MalformedRecorder.Record("deliver", envelope, err)
+	}
+}
+
 func (h *Handler) validateChannelHeader(ctx context.Context, chdr *cb.ChannelHeader) error {
 	if chdr.GetTimestamp() == nil {
 		err := errors.New("channel header in envelope must contain timestamp")
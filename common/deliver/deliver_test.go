@@ -7,19 +7,29 @@ SPDX-License-Identifier: Apache-2.0
 package deliver_test
 
 import (
+	"fmt"
 	"io"
 	"time"
 
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
 	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/deliver"
 	"github.com/hyperledger/fabric/common/deliver/mock"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	mockconfig "github.com/hyperledger/fabric/common/mocks/config"
+	mockpolicies "github.com/hyperledger/fabric/common/mocks/policies"
+	"github.com/hyperledger/fabric/common/policies"
 	"github.com/hyperledger/fabric/common/util"
 	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
 	ab "github.com/hyperledger/fabric/protos/orderer"
+	pb "github.com/hyperledger/fabric/protos/peer"
 	"github.com/hyperledger/fabric/protos/utils"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
 
 	. "github.com/onsi/ginkgo"
 	. "github.com/onsi/gomega"
@@ -271,6 +281,272 @@ var _ = Describe("Deliver", func() {
 					}))
 				}
 			})
+
+			Context("when the response sender is aware of the lag of the client", func() {
+				var lagAwareSender *lagAwareResponseSender
+
+				BeforeEach(func() {
+					lagAwareSender = &lagAwareResponseSender{ResponseSender: fakeResponseSender}
+					server.ResponseSender = lagAwareSender
+				})
+
+				It("tells the sender how many requested blocks are behind each block", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(0))
+					Expect(lagAwareSender.behind).To(Equal([]uint64{4, 3, 2, 1, 0}))
+				})
+
+				Context("when the stop number is below the height", func() {
+					BeforeEach(func() {
+						seekInfo.Stop = &ab.SeekPosition{
+							Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: 997}},
+						}
+					})
+
+					It("counts the blocks up to the stop number", func() {
+						err := handler.Handle(context.Background(), server)
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(lagAwareSender.behind).To(Equal([]uint64{2, 1, 0}))
+					})
+				})
+			})
+
+			Context("when the number of blocks is capped", func() {
+				BeforeEach(func() {
+					seekInfo.MaxBlocks = 2
+				})
+
+				It("sends at most the requested number of blocks followed by success", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(2))
+					Expect(fakeResponseSender.SendBlockResponseArgsForCall(1).Header.Number).To(Equal(uint64(996)))
+					Expect(fakeResponseSender.SendStatusResponseCallCount()).To(Equal(1))
+					Expect(fakeResponseSender.SendStatusResponseArgsForCall(0)).To(Equal(cb.Status_SUCCESS))
+				})
+			})
+
+			Context("when the number of bytes is capped", func() {
+				var blockSize uint64
+
+				BeforeEach(func() {
+					blockSize = uint64(proto.Size(&cb.Block{Header: &cb.BlockHeader{Number: 995}}))
+					seekInfo.MaxBytes = 3*blockSize - 1
+				})
+
+				It("sends the blocks fitting within the cap followed by success", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(2))
+					Expect(fakeResponseSender.SendStatusResponseCallCount()).To(Equal(1))
+					Expect(fakeResponseSender.SendStatusResponseArgsForCall(0)).To(Equal(cb.Status_SUCCESS))
+				})
+
+				Context("when the first block exceeds the cap", func() {
+					BeforeEach(func() {
+						seekInfo.MaxBytes = 1
+					})
+
+					It("still sends the first block", func() {
+						err := handler.Handle(context.Background(), server)
+						Expect(err).NotTo(HaveOccurred())
+
+						Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(1))
+						Expect(fakeResponseSender.SendBlockResponseArgsForCall(0).Header.Number).To(Equal(uint64(995)))
+					})
+				})
+			})
+		})
+
+		Context("when a filter is requested", func() {
+			var blocks []*cb.Block
+
+			BeforeEach(func() {
+				blocks = nil
+				for n := uint64(995); n < 1000; n++ {
+					blocks = append(blocks, &cb.Block{
+						Header: &cb.BlockHeader{Number: n},
+						Data: &cb.BlockData{Data: [][]byte{
+							filterTx(fmt.Sprintf("a%d", n), "Org1MSP", "mycc"),
+							filterTx(fmt.Sprintf("b%d", n), "Org2MSP", "othercc"),
+						}},
+						Metadata: &cb.BlockMetadata{Metadata: [][]byte{{}, {}, {0, 1}}},
+					})
+				}
+				fakeBlockIterator.NextStub = func() (*cb.Block, cb.Status) {
+					return blocks[fakeBlockIterator.NextCallCount()-1], cb.Status_SUCCESS
+				}
+				seekInfo = &ab.SeekInfo{
+					Start: &ab.SeekPosition{
+						Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: 995}},
+					},
+					Stop:   seekNewest,
+					Filter: &ab.DeliverFilter{ChaincodeNames: []string{"mycc"}},
+				}
+			})
+
+			It("sends the matching transactions with their validation flags", func() {
+				err := handler.Handle(context.Background(), server)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(5))
+				for i := 0; i < 5; i++ {
+					b := fakeResponseSender.SendBlockResponseArgsForCall(i)
+					Expect(b.Header).To(Equal(blocks[i].Header))
+					Expect(b.Data.Data).To(Equal(blocks[i].Data.Data[:1]))
+					Expect(b.Metadata.Metadata[cb.BlockMetadataIndex_TRANSACTIONS_FILTER]).To(Equal([]byte{0}))
+				}
+				Expect(blocks[0].Data.Data).To(HaveLen(2), "the blocks of the ledger are left untouched")
+			})
+
+			Context("when every transaction matches", func() {
+				BeforeEach(func() {
+					seekInfo.Filter = &ab.DeliverFilter{Types: []cb.HeaderType{cb.HeaderType_ENDORSER_TRANSACTION}}
+				})
+
+				It("sends the blocks unchanged", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(5))
+					Expect(fakeResponseSender.SendBlockResponseArgsForCall(0)).To(BeIdenticalTo(blocks[0]))
+				})
+			})
+
+			Context("when the transactions of a single block match", func() {
+				BeforeEach(func() {
+					seekInfo.Filter = &ab.DeliverFilter{TxIds: []string{"b997"}, CreatorMspIds: []string{"Org2MSP"}}
+				})
+
+				It("skips the other blocks up to the stop number", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeBlockIterator.NextCallCount()).To(Equal(5))
+					Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(1))
+					b := fakeResponseSender.SendBlockResponseArgsForCall(0)
+					Expect(b.Header.Number).To(Equal(uint64(997)))
+					Expect(b.Data.Data).To(Equal(blocks[2].Data.Data[1:]))
+					Expect(b.Metadata.Metadata[cb.BlockMetadataIndex_TRANSACTIONS_FILTER]).To(Equal([]byte{1}))
+					Expect(fakeResponseSender.SendStatusResponseArgsForCall(0)).To(Equal(cb.Status_SUCCESS))
+				})
+			})
+
+			Context("when no transaction matches every criterion", func() {
+				BeforeEach(func() {
+					seekInfo.Filter = &ab.DeliverFilter{ChaincodeNames: []string{"othercc"}, CreatorMspIds: []string{"Org1MSP"}}
+				})
+
+				It("sends no block followed by success", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(0))
+					Expect(fakeResponseSender.SendStatusResponseCallCount()).To(Equal(1))
+					Expect(fakeResponseSender.SendStatusResponseArgsForCall(0)).To(Equal(cb.Status_SUCCESS))
+				})
+			})
+		})
+
+		Context("when labels are requested", func() {
+			var (
+				blocks       []*cb.Block
+				labeled      *labeledChain
+				readersCheck *mockpolicies.Policy
+			)
+
+			BeforeEach(func() {
+				blocks = nil
+				for n := uint64(995); n < 1000; n++ {
+					blocks = append(blocks, &cb.Block{
+						Header: &cb.BlockHeader{Number: n},
+						Data: &cb.BlockData{Data: [][]byte{
+							filterTx(fmt.Sprintf("a%d", n), "Org1MSP", "mycc", "hr"),
+							filterTx(fmt.Sprintf("b%d", n), "Org1MSP", "mycc", "finance"),
+						}},
+					})
+				}
+				fakeBlockIterator.NextStub = func() (*cb.Block, cb.Status) {
+					return blocks[fakeBlockIterator.NextCallCount()-1], cb.Status_SUCCESS
+				}
+				readersCheck = &mockpolicies.Policy{}
+				fakeChain.PolicyManagerReturns(&mockpolicies.Manager{
+					PolicyMap: map[string]policies.Policy{"/Channel/Application/HRReaders": readersCheck},
+				})
+				labeled = &labeledChain{
+					Chain: fakeChain,
+					ordererConfig: &mockconfig.Orderer{TransactionLabelsVal: map[string]*ab.TransactionLabel{
+						"hr": {Name: "hr", ReadersPolicy: "/Channel/Application/HRReaders"},
+					}},
+				}
+				fakeChainManager.GetChainReturns(labeled, true)
+				seekInfo = &ab.SeekInfo{
+					Start: &ab.SeekPosition{
+						Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: 995}},
+					},
+					Stop:   seekNewest,
+					Filter: &ab.DeliverFilter{Labels: []string{"hr"}},
+				}
+			})
+
+			It("authorizes the client by the readers policies of the labels and sends the labeled transactions", func() {
+				err := handler.Handle(context.Background(), server)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(fakePolicyChecker.CheckPolicyCallCount()).To(Equal(0), "the readers policy of the channel is not checked")
+				Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(5))
+				for i := 0; i < 5; i++ {
+					Expect(fakeResponseSender.SendBlockResponseArgsForCall(i).Data.Data).To(Equal(blocks[i].Data.Data[:1]))
+				}
+			})
+
+			Context("when the client does not satisfy the readers policy of a label", func() {
+				BeforeEach(func() {
+					readersCheck.Err = errors.New("not a member of hr")
+				})
+
+				It("sends forbidden", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(0))
+					Expect(fakeResponseSender.SendStatusResponseCallCount()).To(Equal(1))
+					Expect(fakeResponseSender.SendStatusResponseArgsForCall(0)).To(Equal(cb.Status_FORBIDDEN))
+				})
+			})
+
+			Context("when a label is not configured", func() {
+				BeforeEach(func() {
+					seekInfo.Filter.Labels = []string{"hr", "legal"}
+				})
+
+				It("sends bad request", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeResponseSender.SendStatusResponseCallCount()).To(Equal(1))
+					Expect(fakeResponseSender.SendStatusResponseArgsForCall(0)).To(Equal(cb.Status_BAD_REQUEST))
+				})
+			})
+
+			Context("when the channel does not support labels", func() {
+				BeforeEach(func() {
+					fakeChainManager.GetChainReturns(fakeChain, true)
+				})
+
+				It("sends bad request", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeResponseSender.SendStatusResponseCallCount()).To(Equal(1))
+					Expect(fakeResponseSender.SendStatusResponseArgsForCall(0)).To(Equal(cb.Status_BAD_REQUEST))
+				})
+			})
 		})
 
 		Context("when seek info is configured to stop at the oldest block", func() {
@@ -382,6 +658,26 @@ var _ = Describe("Deliver", func() {
 				resp := fakeResponseSender.SendStatusResponseArgsForCall(0)
 				Expect(resp).To(Equal(cb.Status_BAD_REQUEST))
 			})
+
+			Context("when a malformed recorder is set", func() {
+				var fakeMalformedRecorder *mock.MalformedRecorder
+
+				BeforeEach(func() {
+					fakeMalformedRecorder = &mock.MalformedRecorder{}
+					handler.MalformedRecorder = fakeMalformedRecorder
+				})
+
+				It("records the envelope", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeMalformedRecorder.RecordCallCount()).To(Equal(1))
+					kind, env, parseErr := fakeMalformedRecorder.RecordArgsForCall(0)
+					Expect(kind).To(Equal("deliver"))
+					Expect(env).To(Equal(envelope))
+					Expect(parseErr).To(HaveOccurred())
+				})
+			})
 		})
 
 		Context("when the payload header is nil", func() {
@@ -492,6 +788,181 @@ var _ = Describe("Deliver", func() {
 			})
 		})
 
+		Context("when the client asks for heartbeats", func() {
+			var (
+				ctx             context.Context
+				heartbeatSender *fakeHeartbeatSender
+				done            chan struct{}
+			)
+
+			BeforeEach(func() {
+				ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(util.HeartbeatIntervalKey, "1ms"))
+				heartbeatSender = &fakeHeartbeatSender{heartbeats: make(chan *ab.Heartbeat, 10)}
+				server.HeartbeatSender = heartbeatSender
+				handler.MinHeartbeatInterval = 5 * time.Millisecond
+				done = make(chan struct{})
+				fakeBlockIterator.NextStub = func() (*cb.Block, cb.Status) {
+					<-done
+					return &cb.Block{Header: &cb.BlockHeader{Number: 100}}, cb.Status_SUCCESS
+				}
+			})
+
+			It("sends the height of the channel while waiting for blocks", func() {
+				errCh := make(chan error)
+				go func() { errCh <- handler.Handle(ctx, server) }()
+
+				var heartbeats []*ab.Heartbeat
+				for i := 0; i < 2; i++ {
+					heartbeats = append(heartbeats, <-heartbeatSender.heartbeats)
+				}
+				close(done)
+				Eventually(errCh).Should(Receive(BeNil()))
+
+				for _, heartbeat := range heartbeats {
+					Expect(heartbeat.ChannelId).To(Equal("chain-id"))
+					Expect(heartbeat.Height).To(Equal(uint64(1000)))
+					Expect(heartbeat.Timestamp).NotTo(BeNil())
+				}
+				sent, err := ptypes.Timestamp(heartbeats[0].Timestamp)
+				Expect(err).NotTo(HaveOccurred())
+				next, err := ptypes.Timestamp(heartbeats[1].Timestamp)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(next.Sub(sent)).To(BeNumerically(">=", 4*time.Millisecond))
+				Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(1))
+			})
+
+			Context("when heartbeats are disabled", func() {
+				BeforeEach(func() {
+					handler.MinHeartbeatInterval = 0
+					close(done)
+				})
+
+				It("sends none", func() {
+					err := handler.Handle(ctx, server)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(heartbeatSender.heartbeats).To(BeEmpty())
+				})
+			})
+
+			Context("when sending a heartbeat fails", func() {
+				BeforeEach(func() {
+					heartbeatSender.err = errors.New("stream closed")
+				})
+
+				AfterEach(func() {
+					close(done)
+				})
+
+				It("ends the stream", func() {
+					err := handler.Handle(ctx, server)
+					Expect(err).To(MatchError("stream closed"))
+				})
+			})
+		})
+
+		Context("when an embargo is set", func() {
+			var (
+				fakeEmbargo *mock.Embargo
+				released    chan struct{}
+				releases    int
+			)
+
+			BeforeEach(func() {
+				fakeEmbargo = &mock.Embargo{}
+				handler.Embargo = fakeEmbargo
+				released = make(chan struct{})
+				releases = 0
+				fakeEmbargo.HoldReturns(released, func() { releases++ })
+			})
+
+			It("holds back the blocks until they are released", func() {
+				result := make(chan error)
+				go func() { result <- handler.Handle(context.Background(), server) }()
+
+				Eventually(fakeEmbargo.HoldCallCount).Should(Equal(1))
+				channelID, number, height := fakeEmbargo.HoldArgsForCall(0)
+				Expect(channelID).To(Equal("chain-id"))
+				Expect(number).To(Equal(uint64(100)))
+				Expect(height).To(Equal(uint64(1000)))
+				Consistently(fakeResponseSender.SendBlockResponseCallCount).Should(Equal(0))
+
+				close(released)
+				Eventually(result).Should(Receive(BeNil()))
+				Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(1))
+				Expect(releases).To(Equal(1))
+			})
+
+			Context("when the block may be delivered at once", func() {
+				BeforeEach(func() {
+					fakeEmbargo.HoldReturns(nil, func() {})
+				})
+
+				It("sends it", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(1))
+				})
+			})
+
+			Context("when fail if not ready is set", func() {
+				BeforeEach(func() {
+					seekInfo.Behavior = ab.SeekInfo_FAIL_IF_NOT_READY
+				})
+
+				It("sends status not found for a block held back", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(0))
+					Expect(fakeResponseSender.SendStatusResponseArgsForCall(0)).To(Equal(cb.Status_NOT_FOUND))
+					Expect(releases).To(Equal(1))
+				})
+			})
+
+			Context("when the chain errors while a block is held back", func() {
+				It("sends status service unavailable", func() {
+					result := make(chan error)
+					go func() { result <- handler.Handle(context.Background(), server) }()
+
+					Eventually(fakeEmbargo.HoldCallCount).Should(Equal(1))
+					close(errCh)
+					Eventually(result).Should(Receive(BeNil()))
+					Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(0))
+					Expect(fakeResponseSender.SendStatusResponseArgsForCall(0)).To(Equal(cb.Status_SERVICE_UNAVAILABLE))
+					Expect(releases).To(Equal(1))
+				})
+			})
+
+			Context("when the client satisfies the block validation policy", func() {
+				BeforeEach(func() {
+					fakeChain.PolicyManagerReturns(&mockpolicies.Manager{
+						PolicyMap: map[string]policies.Policy{policies.BlockValidation: &mockpolicies.Policy{}},
+					})
+				})
+
+				It("delivers the blocks of the consenter at once", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeEmbargo.HoldCallCount()).To(Equal(0))
+					Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(1))
+				})
+			})
+
+			Context("when the client does not satisfy it", func() {
+				BeforeEach(func() {
+					fakeChain.PolicyManagerReturns(&mockpolicies.Manager{
+						PolicyMap: map[string]policies.Policy{policies.BlockValidation: &mockpolicies.Policy{Err: errors.New("not a consenter")}},
+					})
+					fakeEmbargo.HoldReturns(nil, func() {})
+				})
+
+				It("submits the blocks to the embargo", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeEmbargo.HoldCallCount()).To(Equal(1))
+				})
+			})
+		})
+
 		Context("when the chain errors before reading from the chain", func() {
 			BeforeEach(func() {
 				close(errCh)
@@ -542,6 +1013,52 @@ var _ = Describe("Deliver", func() {
 			})
 		})
 
+		Context("when a stream quota is set", func() {
+			var quota *fakeStreamQuota
+
+			BeforeEach(func() {
+				quota = &fakeStreamQuota{}
+				server.StreamQuota = quota
+			})
+
+			It("counts the stream towards the quota of the authorized creator", func() {
+				err := handler.Handle(context.Background(), server)
+				Expect(err).NotTo(HaveOccurred())
+
+				Expect(quota.channels).To(Equal([]string{"chain-id"}))
+				Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(1))
+			})
+
+			Context("when the access evaluation fails", func() {
+				BeforeEach(func() {
+					fakePolicyChecker.CheckPolicyReturns(errors.New("no-access-for-you"))
+				})
+
+				It("does not count the stream", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(quota.channels).To(BeEmpty())
+				})
+			})
+
+			Context("when the creator has too many streams open", func() {
+				BeforeEach(func() {
+					quota.err = errors.New("too many streams")
+				})
+
+				It("sends status service unavailable", func() {
+					err := handler.Handle(context.Background(), server)
+					Expect(err).NotTo(HaveOccurred())
+
+					Expect(fakeResponseSender.SendBlockResponseCallCount()).To(Equal(0))
+					Expect(fakeResponseSender.SendStatusResponseCallCount()).To(Equal(1))
+					resp := fakeResponseSender.SendStatusResponseArgsForCall(0)
+					Expect(resp).To(Equal(cb.Status_SERVICE_UNAVAILABLE))
+				})
+			})
+		})
+
 		Context("when the access expires", func() {
 			BeforeEach(func() {
 				fakeChain.SequenceStub = func() uint64 {
@@ -655,3 +1172,73 @@ var _ = Describe("Deliver", func() {
 		})
 	})
 })
+
+type lagAwareResponseSender struct {
+	deliver.ResponseSender
+	behind []uint64
+}
+
+func (l *lagAwareResponseSender) SendBlockResponseBehind(block *cb.Block, behind uint64) error {
+	l.behind = append(l.behind, behind)
+	return nil
+}
+
+type fakeHeartbeatSender struct {
+	heartbeats chan *ab.Heartbeat
+	err        error
+}
+
+func (f *fakeHeartbeatSender) SendHeartbeatResponse(heartbeat *ab.Heartbeat) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.heartbeats <- heartbeat
+	return nil
+}
+
+type fakeStreamQuota struct {
+	channels []string
+	err      error
+}
+
+func (f *fakeStreamQuota) Acquire(channelID string, identity []byte) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.channels = append(f.channels, channelID)
+	return nil
+}
+
+// filterTx returns an endorser transaction of the given ID, created by a
+// member of the given MSP, invoking the given chaincode and carrying the
+// given labels
+func filterTx(txID, mspID, chaincode string, labels ...string) []byte {
+	return utils.MarshalOrPanic(&cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
+					Type:      int32(cb.HeaderType_ENDORSER_TRANSACTION),
+					ChannelId: "chain-id",
+					TxId:      txID,
+					Labels:    labels,
+					Extension: utils.MarshalOrPanic(&pb.ChaincodeHeaderExtension{
+						ChaincodeId: &pb.ChaincodeID{Name: chaincode},
+					}),
+				}),
+				SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{
+					Creator: utils.MarshalOrPanic(&msp.SerializedIdentity{Mspid: mspID}),
+				}),
+			},
+		}),
+	})
+}
+
+// labeledChain is a chain whose Orderer config group lists labels
+type labeledChain struct {
+	*mock.Chain
+	ordererConfig *mockconfig.Orderer
+}
+
+func (c *labeledChain) OrdererConfig() (channelconfig.Orderer, bool) {
+	return c.ordererConfig, true
+}
@@ -0,0 +1,169 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package deliver
+
+import (
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// contentFilter selects the transactions of the blocks delivered to a client
+// which asked for the transactions matching a filter, so that light clients
+// watch a narrow stream instead of full blocks.
+type contentFilter struct {
+	chaincodes map[string]struct{}
+	types      map[cb.HeaderType]struct{}
+	creators   map[string]struct{}
+	txIDs      map[string]struct{}
+	labels     map[string]struct{}
+}
+
+// newContentFilter returns the filter of the transactions matching every
+// criterion set in the request, or nil if no criterion is set, in which case
+// every transaction is delivered
+func newContentFilter(f *ab.DeliverFilter) *contentFilter {
+	if len(f.GetChaincodeNames()) == 0 && len(f.GetTypes()) == 0 && len(f.GetCreatorMspIds()) == 0 && len(f.GetTxIds()) == 0 && len(f.GetLabels()) == 0 {
+		return nil
+	}
+	cf := &contentFilter{
+		chaincodes: stringSet(f.ChaincodeNames),
+		creators:   stringSet(f.CreatorMspIds),
+		txIDs:      stringSet(f.TxIds),
+		labels:     stringSet(f.Labels),
+	}
+	if len(f.Types) > 0 {
+		cf.types = make(map[cb.HeaderType]struct{}, len(f.Types))
+		for _, t := range f.Types {
+			cf.types[t] = struct{}{}
+		}
+	}
+	return cf
+}
+
+func stringSet(values []string) map[string]struct{} {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]struct{}, len(values))
+	for _, v := range values {
+		set[v] = struct{}{}
+	}
+	return set
+}
+
+// apply returns the block carrying the transactions of the given block which
+// match the filter, along with their validation flags, or nil if none match.
+// The block itself is returned if all of its transactions match, and is left
+// untouched otherwise, since it may be shared with the ledger.
+func (cf *contentFilter) apply(block *cb.Block) *cb.Block {
+	if block.Data == nil {
+		return nil
+	}
+	var matching []int
+	for i, envBytes := range block.Data.Data {
+		if cf.matches(envBytes) {
+			matching = append(matching, i)
+		}
+	}
+	switch len(matching) {
+	case 0:
+		return nil
+	case len(block.Data.Data):
+		return block
+	}
+
+	//只保留匹配的交易及其交易验证标志，区块头部保持不变
+	data := make([][]byte, len(matching))
+	for j, i := range matching {
+		data[j] = block.Data.Data[i]
+	}
+	filtered := &cb.Block{
+		Header: block.Header,
+		Data:   &cb.BlockData{Data: data},
+	}
+	if block.Metadata != nil {
+		metadata := make([][]byte, len(block.Metadata.Metadata))
+		copy(metadata, block.Metadata.Metadata)
+		idx := int(cb.BlockMetadataIndex_TRANSACTIONS_FILTER)
+		if idx < len(metadata) && len(metadata[idx]) == len(block.Data.Data) {
+			flags := make([]byte, len(matching))
+			for j, i := range matching {
+				flags[j] = metadata[idx][i]
+			}
+			metadata[idx] = flags
+		}
+		filtered.Metadata = &cb.BlockMetadata{Metadata: metadata}
+	}
+	return filtered
+}
+
+// matches returns whether the transaction matches every criterion of the
+// filter.  The transactions which cannot be parsed match none.
+func (cf *contentFilter) matches(envBytes []byte) bool {
+	env, err := utils.UnmarshalEnvelope(envBytes)
+	if err != nil {
+		return false
+	}
+	payload, err := utils.UnmarshalPayload(env.Payload)
+	if err != nil || payload.Header == nil {
+		return false
+	}
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		return false
+	}
+
+	if cf.txIDs != nil && !contains(cf.txIDs, chdr.TxId) {
+		return false
+	}
+	if cf.labels != nil && !containsAny(cf.labels, chdr.Labels) {
+		return false
+	}
+	if cf.types != nil {
+		if _, ok := cf.types[cb.HeaderType(chdr.Type)]; !ok {
+			return false
+		}
+	}
+	if cf.creators != nil {
+		shdr, err := utils.GetSignatureHeader(payload.Header.SignatureHeader)
+		if err != nil {
+			return false
+		}
+		creator := &msp.SerializedIdentity{}
+		if err := proto.Unmarshal(shdr.Creator, creator); err != nil || !contains(cf.creators, creator.Mspid) {
+			return false
+		}
+	}
+	if cf.chaincodes != nil {
+		//只有背书交易（包括加密的背书交易）调用链码，链码名称取自通道头部的扩展字段
+		if chdr.Type != int32(cb.HeaderType_ENDORSER_TRANSACTION) && chdr.Type != int32(cb.HeaderType_ENCRYPTED_TRANSACTION) {
+			return false
+		}
+		ext, err := utils.GetChaincodeHeaderExtension(payload.Header)
+		if err != nil || ext.ChaincodeId == nil || !contains(cf.chaincodes, ext.ChaincodeId.Name) {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(set map[string]struct{}, value string) bool {
+	_, ok := set[value]
+	return ok
+}
+
+func containsAny(set map[string]struct{}, values []string) bool {
+	for _, v := range values {
+		if contains(set, v) {
+			return true
+		}
+	}
+	return false
+}
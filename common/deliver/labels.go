@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package deliver
+
+import (
+	"github.com/hyperledger/fabric/common/channelconfig"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// OrdererConfigSupport is implemented by the chains whose transactions may
+// carry the visibility labels listed in the Orderer config group of the
+// channel.
+type OrdererConfigSupport interface {
+	// OrdererConfig returns the config.Orderer for the channel and whether the Orderer config exists
+	OrdererConfig() (channelconfig.Orderer, bool)
+}
+
+// labelPolicyChecker returns the policy checker authorizing the clients which
+// ask for the transactions carrying the labels, which must satisfy the
+// readers policy of every label.  The policies are looked up in the latest
+// config at every check, so that removing a label revokes the access to it.
+func labelPolicyChecker(chain Chain, labels []string) (PolicyChecker, error) {
+	support, ok := chain.(OrdererConfigSupport)
+	if !ok {
+		return nil, errors.New("the channel does not support labels")
+	}
+	ordererConfig, ok := support.OrdererConfig()
+	if !ok {
+		return nil, errors.New("could not find orderer config")
+	}
+	for _, name := range labels {
+		if _, ok := ordererConfig.TransactionLabels()[name]; !ok {
+			return nil, errors.Errorf("label %s is not configured on the channel", name)
+		}
+	}
+
+	return PolicyCheckerFunc(func(envelope *cb.Envelope, channelID string) error {
+		ordererConfig, ok := support.OrdererConfig()
+		if !ok {
+			return errors.New("could not find orderer config")
+		}
+		signedData, err := envelope.AsSignedData()
+		if err != nil {
+			return err
+		}
+		configured := ordererConfig.TransactionLabels()
+		for _, name := range labels {
+			label, ok := configured[name]
+			if !ok {
+				return errors.Errorf("label %s is not configured on channel %s", name, channelID)
+			}
+			policy, ok := chain.PolicyManager().GetPolicy(label.ReadersPolicy)
+			if !ok {
+				return errors.Errorf("could not find policy %s of label %s", label.ReadersPolicy, name)
+			}
+			if err := policy.Evaluate(signedData); err != nil {
+				return errors.WithMessage(err, "the client does not satisfy the readers policy of label "+name)
+			}
+		}
+		return nil
+	}), nil
+}
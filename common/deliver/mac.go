@@ -0,0 +1,121 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package deliver
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/tls"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// MACExporterLabel is the TLS keying material exporter label from which the
+// keys authenticating deliver responses are derived.
+const MACExporterLabel = "EXPORTER-fabric-deliver-mac"
+
+// macKeySize is the size in bytes of the keys authenticating deliver responses.
+const macKeySize = 32
+
+// SessionMACKey derives the key authenticating the deliver responses sent
+// over a mutual TLS session.  Both ends of the session derive the same key,
+// which no intermediary terminating TLS on either side can compute.
+func SessionMACKey(state *tls.ConnectionState) ([]byte, error) {
+	if state == nil || !state.HandshakeComplete {
+		return nil, errors.New("no TLS session established")
+	}
+	if len(state.PeerCertificates) == 0 {
+		return nil, errors.New("TLS session is not mutually authenticated")
+	}
+	key, err := state.ExportKeyingMaterial(MACExporterLabel, nil, macKeySize)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed exporting keying material from the TLS session")
+	}
+	return key, nil
+}
+
+// SessionMACKeyFromContext derives the key authenticating deliver responses
+// from the TLS session of the gRPC stream the context belongs to.
+func SessionMACKeyFromContext(ctx context.Context) ([]byte, error) {
+	pr, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, errors.New("no peer information in context")
+	}
+	tlsInfo, ok := pr.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, errors.New("connection does not use TLS")
+	}
+	return SessionMACKey(&tlsInfo.State)
+}
+
+// StreamMAC computes and verifies the MACs of the responses sent on a deliver
+// stream.  Each MAC covers the response and the MAC of the response before
+// it, so a response which is modified, dropped or reordered fails
+// verification, and a stream truncated by an intermediary is detected by the
+// absence of a verified closing status.
+type StreamMAC struct {
+	mutex sync.Mutex
+	key   []byte
+	prev  []byte
+}
+
+// NewStreamMAC creates a StreamMAC for a new stream authenticated with the key.
+func NewStreamMAC(key []byte) *StreamMAC {
+	return &StreamMAC{key: key}
+}
+
+// Seal sets the MAC of a response about to be sent on the stream.
+func (sm *StreamMAC) Seal(resp *ab.DeliverResponse) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	mac, err := sm.compute(resp)
+	if err != nil {
+		return err
+	}
+	resp.Mac = mac
+	sm.prev = mac
+	return nil
+}
+
+// Verify checks the MAC of a response received on the stream.  Responses
+// must be verified in the order they were received.
+func (sm *StreamMAC) Verify(resp *ab.DeliverResponse) error {
+	sm.mutex.Lock()
+	defer sm.mutex.Unlock()
+
+	if len(resp.Mac) == 0 {
+		return errors.New("response carries no MAC")
+	}
+	mac, err := sm.compute(resp)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal(mac, resp.Mac) {
+		return errors.New("response MAC mismatch, the response was tampered with or the stream is out of sequence")
+	}
+	sm.prev = mac
+	return nil
+}
+
+// compute must be called with the mutex held
+func (sm *StreamMAC) compute(resp *ab.DeliverResponse) ([]byte, error) {
+	content, err := proto.Marshal(&ab.DeliverResponse{Type: resp.Type})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshaling response")
+	}
+	h := hmac.New(sha256.New, sm.key)
+	h.Write(sm.prev)
+	h.Write(content)
+	return h.Sum(nil), nil
+}
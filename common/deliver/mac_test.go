@@ -0,0 +1,127 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package deliver_test
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	"github.com/hyperledger/fabric/common/deliver"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("StreamMAC", func() {
+	var (
+		sealer    *deliver.StreamMAC
+		verifier  *deliver.StreamMAC
+		responses []*ab.DeliverResponse
+	)
+
+	BeforeEach(func() {
+		sealer = deliver.NewStreamMAC([]byte("session-key"))
+		verifier = deliver.NewStreamMAC([]byte("session-key"))
+		responses = []*ab.DeliverResponse{
+			{Type: &ab.DeliverResponse_Block{Block: cb.NewBlock(1, []byte("prev"))}},
+			{Type: &ab.DeliverResponse_Block{Block: cb.NewBlock(2, []byte("prev"))}},
+			{Type: &ab.DeliverResponse_Status{Status: cb.Status_SUCCESS}},
+		}
+		for _, resp := range responses {
+			Expect(sealer.Seal(resp)).To(Succeed())
+			Expect(resp.Mac).NotTo(BeEmpty())
+		}
+	})
+
+	It("verifies the responses of the stream in order", func() {
+		for _, resp := range responses {
+			Expect(verifier.Verify(resp)).To(Succeed())
+		}
+	})
+
+	It("rejects a tampered response", func() {
+		responses[0].GetBlock().Header.Number = 5
+		Expect(verifier.Verify(responses[0])).To(MatchError("response MAC mismatch, the response was tampered with or the stream is out of sequence"))
+	})
+
+	It("rejects a dropped response", func() {
+		Expect(verifier.Verify(responses[0])).To(Succeed())
+		Expect(verifier.Verify(responses[2])).NotTo(Succeed())
+	})
+
+	It("rejects a response carrying no MAC", func() {
+		Expect(verifier.Verify(&ab.DeliverResponse{})).To(MatchError("response carries no MAC"))
+	})
+
+	It("rejects responses sealed with another key", func() {
+		verifier = deliver.NewStreamMAC([]byte("other-key"))
+		Expect(verifier.Verify(responses[0])).NotTo(Succeed())
+	})
+})
+
+var _ = Describe("SessionMACKey", func() {
+	It("derives the same key on both ends of a mutual TLS session", func() {
+		ca, err := tlsgen.NewCA()
+		Expect(err).NotTo(HaveOccurred())
+		serverPair, err := ca.NewServerCertKeyPair("localhost")
+		Expect(err).NotTo(HaveOccurred())
+		clientPair, err := ca.NewClientCertKeyPair()
+		Expect(err).NotTo(HaveOccurred())
+		serverCert, err := tls.X509KeyPair(serverPair.Cert, serverPair.Key)
+		Expect(err).NotTo(HaveOccurred())
+		clientCert, err := tls.X509KeyPair(clientPair.Cert, clientPair.Key)
+		Expect(err).NotTo(HaveOccurred())
+		roots := x509.NewCertPool()
+		Expect(roots.AppendCertsFromPEM(ca.CertBytes())).To(BeTrue())
+
+		serverConn, clientConn := net.Pipe()
+		server := tls.Server(serverConn, &tls.Config{
+			Certificates: []tls.Certificate{serverCert},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    roots,
+		})
+		client := tls.Client(clientConn, &tls.Config{
+			Certificates: []tls.Certificate{clientCert},
+			RootCAs:      roots,
+			ServerName:   "localhost",
+		})
+		defer server.Close()
+		defer client.Close()
+
+		handshake := make(chan error, 1)
+		go func() { handshake <- server.Handshake() }()
+		Expect(client.Handshake()).To(Succeed())
+		Expect(<-handshake).To(Succeed())
+
+		serverState := server.ConnectionState()
+		clientState := client.ConnectionState()
+		serverKey, err := deliver.SessionMACKeyFromContext(peer.NewContext(context.Background(), &peer.Peer{
+			AuthInfo: credentials.TLSInfo{State: serverState},
+		}))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(serverKey).To(HaveLen(32))
+		Expect(deliver.SessionMACKey(&clientState)).To(Equal(serverKey))
+	})
+
+	It("requires a mutually authenticated TLS session", func() {
+		_, err := deliver.SessionMACKey(&tls.ConnectionState{HandshakeComplete: true})
+		Expect(err).To(MatchError("TLS session is not mutually authenticated"))
+
+		_, err = deliver.SessionMACKey(&tls.ConnectionState{})
+		Expect(err).To(MatchError("no TLS session established"))
+
+		_, err = deliver.SessionMACKeyFromContext(context.Background())
+		Expect(err).To(MatchError("no peer information in context"))
+	})
+})
@@ -0,0 +1,246 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package flightrecorder keeps the recent history of a few internal metrics,
+// such as queue depths, GC pauses and block intervals, in a ring buffer, so
+// that the minutes leading to an incident can be dumped from the process
+// itself even if no external monitoring was scraping it.  Sampling is cheap
+// enough for the recorder to be always on.
+package flightrecorder
+
+import (
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Summary aggregates the values observed for a metric during a sample
+// interval.
+type Summary struct {
+	Count int     `json:"count"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Sum   float64 `json:"sum"`
+}
+
+func (s *Summary) add(value float64) {
+	if s.Count == 0 || value < s.Min {
+		s.Min = value
+	}
+	if s.Count == 0 || value > s.Max {
+		s.Max = value
+	}
+	s.Count++
+	s.Sum += value
+}
+
+// Sample holds the gauges read at the end of a sample interval and the
+// values observed during it.
+type Sample struct {
+	Time         time.Time           `json:"time"`
+	Gauges       map[string]float64  `json:"gauges"`
+	Observations map[string]*Summary `json:"observations,omitempty"`
+}
+
+// Dump is the exported form of the recorded samples, oldest first.
+type Dump struct {
+	Generated time.Time `json:"generated"`
+	Interval  string    `json:"interval"`
+	Samples   []*Sample `json:"samples"`
+}
+
+// Recorder samples its gauges at a fixed interval and retains the samples
+// of the last retention period.  All methods are safe to call on a nil
+// Recorder, in which case they do nothing.
+type Recorder struct {
+	interval time.Duration
+	now      func() time.Time
+
+	mutex     sync.Mutex
+	gauges    map[string]func() float64
+	pending   map[string]*Summary
+	samples   []*Sample
+	next      int
+	lastNumGC uint32
+	stop      chan struct{}
+}
+
+// NewRecorder creates a Recorder sampling every interval and retaining the
+// samples of the last retention period.  Besides the registered gauges, the
+// Recorder samples the number of goroutines and the heap size, and observes
+// the GC pauses.
+func NewRecorder(interval, retention time.Duration) (*Recorder, error) {
+	if interval <= 0 || retention < interval {
+		return nil, errors.Errorf("invalid sample interval %s and retention %s, the retention must hold at least one interval", interval, retention)
+	}
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+	return &Recorder{
+		interval:  interval,
+		now:       time.Now,
+		gauges:    map[string]func() float64{},
+		pending:   map[string]*Summary{},
+		samples:   make([]*Sample, int(retention/interval)),
+		lastNumGC: memStats.NumGC,
+	}, nil
+}
+
+// Gauge registers a gauge, such as a queue depth, read at the end of every
+// sample interval.  The gauge must not block.
+func (r *Recorder) Gauge(name string, gauge func() float64) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.gauges[name] = gauge
+}
+
+// Observe records a value, such as a block interval, in the current sample
+func (r *Recorder) Observe(name string, value float64) {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.observe(name, value)
+}
+
+// observe must be called with the mutex held
+func (r *Recorder) observe(name string, value float64) {
+	s, ok := r.pending[name]
+	if !ok {
+		s = &Summary{}
+		r.pending[name] = s
+	}
+	s.add(value)
+}
+
+// Start samples every interval until Stop is called
+func (r *Recorder) Start() {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	if r.stop != nil {
+		r.mutex.Unlock()
+		return
+	}
+	r.stop = make(chan struct{})
+	stop := r.stop
+	r.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				r.sample()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops sampling
+func (r *Recorder) Stop() {
+	if r == nil {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.stop != nil {
+		close(r.stop)
+		r.stop = nil
+	}
+}
+
+// sample closes the current sample interval
+func (r *Recorder) sample() {
+	var memStats runtime.MemStats
+	runtime.ReadMemStats(&memStats)
+
+	r.mutex.Lock()
+	gauges := make(map[string]func() float64, len(r.gauges))
+	for name, gauge := range r.gauges {
+		gauges[name] = gauge
+	}
+	r.mutex.Unlock()
+
+	// 在锁外读取注册的指标，避免指标回调与Observe相互阻塞
+	values := map[string]float64{
+		"runtime.goroutines":       float64(runtime.NumGoroutine()),
+		"runtime.heap_alloc_bytes": float64(memStats.HeapAlloc),
+	}
+	for name, gauge := range gauges {
+		values[name] = gauge()
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.observeGCPauses(&memStats)
+	sample := &Sample{Time: r.now(), Gauges: values}
+	if len(r.pending) > 0 {
+		sample.Observations = r.pending
+		r.pending = map[string]*Summary{}
+	}
+	r.samples[r.next] = sample
+	r.next = (r.next + 1) % len(r.samples)
+}
+
+// observeGCPauses observes the pauses of the collections since the last
+// sample, of which the runtime remembers the last 256.  It must be called
+// with the mutex held.
+func (r *Recorder) observeGCPauses(memStats *runtime.MemStats) {
+	remembered := uint32(len(memStats.PauseNs))
+	if memStats.NumGC-r.lastNumGC > remembered {
+		r.lastNumGC = memStats.NumGC - remembered
+	}
+	for gc := r.lastNumGC + 1; gc <= memStats.NumGC; gc++ {
+		pause := memStats.PauseNs[(gc+remembered-1)%remembered]
+		r.observe("runtime.gc_pause_seconds", time.Duration(pause).Seconds())
+	}
+	r.lastNumGC = memStats.NumGC
+}
+
+// Dump returns the recorded samples, oldest first
+func (r *Recorder) Dump() *Dump {
+	dump := &Dump{Samples: []*Sample{}}
+	if r == nil {
+		dump.Generated = time.Now()
+		return dump
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	dump.Generated = r.now()
+	dump.Interval = r.interval.String()
+	for i := range r.samples {
+		if s := r.samples[(r.next+i)%len(r.samples)]; s != nil {
+			dump.Samples = append(dump.Samples, s)
+		}
+	}
+	return dump
+}
+
+// ServeHTTP writes the recorded samples as JSON
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(r.Dump()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
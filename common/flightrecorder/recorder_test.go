@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flightrecorder
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewRecorder(t *testing.T) {
+	_, err := NewRecorder(0, time.Minute)
+	assert.EqualError(t, err, "invalid sample interval 0s and retention 1m0s, the retention must hold at least one interval")
+	_, err = NewRecorder(time.Minute, time.Second)
+	assert.Error(t, err)
+}
+
+func TestRecorder(t *testing.T) {
+	r, err := NewRecorder(time.Second, 2*time.Second)
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	r.now = func() time.Time { return now }
+
+	depth := 3.0
+	r.Gauge("queue", func() float64 { return depth })
+	r.Observe("block_interval_seconds", 2)
+	r.Observe("block_interval_seconds", 1)
+	r.sample()
+
+	dump := r.Dump()
+	require.Len(t, dump.Samples, 1)
+	s := dump.Samples[0]
+	assert.Equal(t, now, s.Time)
+	assert.Equal(t, 3.0, s.Gauges["queue"])
+	assert.Contains(t, s.Gauges, "runtime.goroutines")
+	assert.Equal(t, &Summary{Count: 2, Min: 1, Max: 2, Sum: 3}, s.Observations["block_interval_seconds"])
+	assert.Equal(t, "1s", dump.Interval)
+
+	// the oldest sample is overwritten once the retention is exceeded
+	runtime.GC()
+	for i := 1; i <= 2; i++ {
+		now = now.Add(time.Second)
+		depth = float64(3 + i)
+		r.sample()
+	}
+	dump = r.Dump()
+	require.Len(t, dump.Samples, 2)
+	assert.Equal(t, 4.0, dump.Samples[0].Gauges["queue"])
+	assert.Equal(t, 5.0, dump.Samples[1].Gauges["queue"])
+	assert.NotContains(t, dump.Samples[0].Observations, "block_interval_seconds")
+	assert.NotZero(t, dump.Samples[0].Observations["runtime.gc_pause_seconds"].Count)
+}
+
+func TestNilRecorder(t *testing.T) {
+	var r *Recorder
+	r.Gauge("queue", func() float64 { return 0 })
+	r.Observe("block_interval_seconds", 1)
+	r.Start()
+	r.Stop()
+	assert.Empty(t, r.Dump().Samples)
+}
+
+func TestRecorderStartStop(t *testing.T) {
+	r, err := NewRecorder(10*time.Millisecond, time.Second)
+	require.NoError(t, err)
+	r.Start()
+	r.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.Dump().Samples) == 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.NotEmpty(t, r.Dump().Samples)
+	r.Stop()
+	r.Stop()
+}
+
+func TestServeHTTP(t *testing.T) {
+	r, err := NewRecorder(time.Second, time.Minute)
+	require.NoError(t, err)
+	r.sample()
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/runtime/flightrecorder", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	dump := &Dump{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), dump))
+	assert.Len(t, dump.Samples, 1)
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/runtime/flightrecorder", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,263 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+// channelPattern matches the channel of the messages, which are prefixed by
+// "[channel: <channel>]"
+var channelPattern = regexp.MustCompile(`\[channel: ([^\]]+)\]`)
+
+// ChannelLevel is the logging level of the modules matching Module for the
+// messages about Channel, which overrides the level of the modules
+type ChannelLevel struct {
+	Module  string
+	Channel string
+	Level   string
+}
+
+type channelLevel struct {
+	ChannelLevel
+	re    *regexp.Regexp
+	level logging.Level
+}
+
+// Sampling limits the debug messages of the modules matching Module logged
+// per Tick: the first Initial messages of a module are logged, and then every
+// Thereafter-th message, none if it is zero
+type Sampling struct {
+	Module     string
+	Initial    int
+	Thereafter int
+	Tick       time.Duration
+}
+
+type sampler struct {
+	Sampling
+	re     *regexp.Regexp
+	mutex  sync.Mutex
+	counts map[string]*sampleCount
+}
+
+type sampleCount struct {
+	start time.Time
+	n     int
+}
+
+// sample returns whether the next debug message of the module is logged
+func (s *sampler) sample(module string, now time.Time) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	c, ok := s.counts[module]
+	if !ok || now.Sub(c.start) >= s.Tick {
+		c = &sampleCount{start: now}
+		s.counts[module] = c
+	}
+	c.n++
+	if c.n <= s.Initial {
+		return true
+	}
+	return s.Thereafter > 0 && (c.n-s.Initial)%s.Thereafter == 0
+}
+
+var (
+	filterLock    sync.RWMutex
+	channelLevels []*channelLevel
+	samplings     []*sampler
+	timeNow       = time.Now
+)
+
+// SetChannelLevel sets the logging level of the modules matching the regular
+// expression for the messages about the channel, which may be more or less
+// verbose than the level of the modules.
+func SetChannelLevel(moduleRegExp, channel, level string) error {
+	logLevel, err := logging.LogLevel(level)
+	if err != nil {
+		return errors.Errorf("invalid logging level '%s'", level)
+	}
+	re, err := regexp.Compile(moduleRegExp)
+	if err != nil {
+		return errors.Wrapf(err, "invalid regular expression '%s'", moduleRegExp)
+	}
+
+	cl := &channelLevel{
+		ChannelLevel: ChannelLevel{Module: moduleRegExp, Channel: channel, Level: logLevel.String()},
+		re:           re,
+		level:        logLevel,
+	}
+	filterLock.Lock()
+	replaced := false
+	for i, existing := range channelLevels {
+		if existing.Module == moduleRegExp && existing.Channel == channel {
+			channelLevels[i] = cl
+			replaced = true
+		}
+	}
+	if !replaced {
+		channelLevels = append(channelLevels, cl)
+	}
+	filterLock.Unlock()
+
+	// 日志记录需要读锁，须在释放写锁后记录
+	logger.Infof("Modules '%s' logging at level '%s' for channel %s", moduleRegExp, logLevel, channel)
+	return nil
+}
+
+// ClearChannelLevel removes the logging level set for the modules matching
+// the regular expression and the channel, whose messages are then logged at
+// the level of the modules
+func ClearChannelLevel(moduleRegExp, channel string) {
+	filterLock.Lock()
+	defer filterLock.Unlock()
+	for i, existing := range channelLevels {
+		if existing.Module == moduleRegExp && existing.Channel == channel {
+			channelLevels = append(channelLevels[:i], channelLevels[i+1:]...)
+			return
+		}
+	}
+}
+
+// ChannelLevels returns the logging levels set for channels
+func ChannelLevels() []ChannelLevel {
+	filterLock.RLock()
+	defer filterLock.RUnlock()
+	levels := make([]ChannelLevel, 0, len(channelLevels))
+	for _, cl := range channelLevels {
+		levels = append(levels, cl.ChannelLevel)
+	}
+	return levels
+}
+
+// SetSampling samples the debug messages of the modules matching the regular
+// expression, replacing the sampling set for it if any.  An initial count of
+// zero stops the sampling.
+func SetSampling(moduleRegExp string, initial, thereafter int, tick time.Duration) error {
+	if initial < 0 || thereafter < 0 || (initial > 0 && tick <= 0) {
+		return errors.New("sampling counts must not be negative and the tick must be positive")
+	}
+	re, err := regexp.Compile(moduleRegExp)
+	if err != nil {
+		return errors.Wrapf(err, "invalid regular expression '%s'", moduleRegExp)
+	}
+
+	filterLock.Lock()
+	for i, existing := range samplings {
+		if existing.Module == moduleRegExp {
+			samplings = append(samplings[:i], samplings[i+1:]...)
+			break
+		}
+	}
+	if initial > 0 {
+		samplings = append(samplings, &sampler{
+			Sampling: Sampling{Module: moduleRegExp, Initial: initial, Thereafter: thereafter, Tick: tick},
+			re:       re,
+			counts:   map[string]*sampleCount{},
+		})
+	}
+	filterLock.Unlock()
+
+	if initial == 0 {
+		logger.Infof("Stopped sampling debug messages of modules '%s'", moduleRegExp)
+		return nil
+	}
+	logger.Infof("Sampling debug messages of modules '%s': %d per %s, then 1 in %d", moduleRegExp, initial, tick, thereafter)
+	return nil
+}
+
+// Samplings returns the samplings of the debug messages
+func Samplings() []Sampling {
+	filterLock.RLock()
+	defer filterLock.RUnlock()
+	result := make([]Sampling, 0, len(samplings))
+	for _, s := range samplings {
+		result = append(result, s.Sampling)
+	}
+	return result
+}
+
+// resetFilters removes the channel levels and samplings
+func resetFilters() {
+	filterLock.Lock()
+	defer filterLock.Unlock()
+	channelLevels = nil
+	samplings = nil
+}
+
+// filterBackend logs the records of the modules at their level, or at the
+// level set for the channel of the message, and samples the debug records
+type filterBackend struct {
+	logging.LeveledBackend // 模块日志级别
+	backend                logging.Backend
+}
+
+func newFilterBackend(backend logging.Backend) *filterBackend {
+	return &filterBackend{
+		LeveledBackend: logging.AddModuleLevel(backend),
+		backend:        backend,
+	}
+}
+
+// IsEnabledFor returns whether records of the level of the module may be
+// logged, that is if the level of the module or of one of its channels
+// allows it
+func (fb *filterBackend) IsEnabledFor(level logging.Level, module string) bool {
+	if fb.LeveledBackend.IsEnabledFor(level, module) {
+		return true
+	}
+	filterLock.RLock()
+	defer filterLock.RUnlock()
+	for _, cl := range channelLevels {
+		if level <= cl.level && cl.re.MatchString(module) {
+			return true
+		}
+	}
+	return false
+}
+
+// Log logs the record if the level of its channel, or of its module if none
+// is set for its channel, allows it and it is sampled
+func (fb *filterBackend) Log(level logging.Level, calldepth int, rec *logging.Record) error {
+	if !fb.allows(level, rec) {
+		return nil
+	}
+	return fb.backend.Log(level, calldepth+1, rec)
+}
+
+func (fb *filterBackend) allows(level logging.Level, rec *logging.Record) bool {
+	filterLock.RLock()
+	defer filterLock.RUnlock()
+	enabled := fb.LeveledBackend.IsEnabledFor(level, rec.Module)
+	if len(channelLevels) > 0 {
+		if match := channelPattern.FindStringSubmatch(rec.Message()); match != nil {
+			for _, cl := range channelLevels {
+				if cl.Channel == match[1] && cl.re.MatchString(rec.Module) {
+					enabled = level <= cl.level
+					break
+				}
+			}
+		}
+	}
+	if !enabled {
+		return false
+	}
+	if level != logging.DEBUG {
+		return true
+	}
+	for _, s := range samplings {
+		if s.re.MatchString(rec.Module) {
+			return s.sample(rec.Module, timeNow())
+		}
+	}
+	return true
+}
@@ -0,0 +1,103 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package flogging
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/op/go-logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChannelLevel(t *testing.T) {
+	defer Reset()
+	buf := &bytes.Buffer{}
+	InitBackend(SetFormat("%{message}"), buf)
+	InitFromSpec("INFO")
+	l := MustGetLogger("orderer/test/channel")
+
+	require.NoError(t, SetChannelLevel("orderer/test", "debugchannel", "DEBUG"))
+	require.NoError(t, SetChannelLevel("orderer/test", "quietchannel", "WARNING"))
+	buf.Reset()
+	l.Debugf("[channel: %s] debug", "debugchannel")
+	l.Debugf("[channel: %s] debug", "otherchannel")
+	l.Infof("[channel: %s] info", "quietchannel")
+	l.Warningf("[channel: %s] warning", "quietchannel")
+	l.Infof("[channel: %s] info", "otherchannel")
+	l.Debug("no channel")
+	assert.Equal(t, []string{"[channel: debugchannel] debug", "[channel: quietchannel] warning", "[channel: otherchannel] info"}, lines(buf))
+	assert.Equal(t, []ChannelLevel{
+		{Module: "orderer/test", Channel: "debugchannel", Level: "DEBUG"},
+		{Module: "orderer/test", Channel: "quietchannel", Level: "WARNING"},
+	}, ChannelLevels())
+
+	ClearChannelLevel("orderer/test", "debugchannel")
+	require.NoError(t, SetChannelLevel("orderer/test", "quietchannel", "INFO"))
+	buf.Reset()
+	l.Debugf("[channel: %s] debug", "debugchannel")
+	l.Infof("[channel: %s] info", "quietchannel")
+	assert.Equal(t, []string{"[channel: quietchannel] info"}, lines(buf))
+	assert.Len(t, ChannelLevels(), 1)
+
+	assert.EqualError(t, SetChannelLevel("orderer/test", "mychannel", "LOUD"), "invalid logging level 'LOUD'")
+	assert.Error(t, SetChannelLevel("orderer/(", "mychannel", "DEBUG"))
+}
+
+func TestSampling(t *testing.T) {
+	defer Reset()
+	defer func() { timeNow = time.Now }()
+	now := time.Unix(1000, 0)
+	timeNow = func() time.Time { return now }
+	buf := &bytes.Buffer{}
+	InitBackend(SetFormat("%{message}"), buf)
+	InitFromSpec("DEBUG")
+	l := MustGetLogger("orderer/test/sampled")
+
+	require.NoError(t, SetSampling("orderer/test", 2, 3, time.Second))
+	buf.Reset()
+	for i := 1; i <= 8; i++ {
+		l.Debugf("debug %d", i)
+	}
+	l.Info("info")
+	assert.Equal(t, []string{"debug 1", "debug 2", "debug 5", "debug 8", "info"}, lines(buf))
+	assert.Equal(t, []Sampling{{Module: "orderer/test", Initial: 2, Thereafter: 3, Tick: time.Second}}, Samplings())
+
+	// the counts start over every tick
+	buf.Reset()
+	now = now.Add(time.Second)
+	l.Debug("debug 9")
+	assert.Equal(t, []string{"debug 9"}, lines(buf))
+
+	// and the sampling stops with an initial count of zero
+	require.NoError(t, SetSampling("orderer/test", 0, 0, 0))
+	assert.Empty(t, Samplings())
+	buf.Reset()
+	for i := 0; i < 5; i++ {
+		l.Debug("debug")
+	}
+	assert.Len(t, lines(buf), 5)
+
+	assert.Error(t, SetSampling("orderer/test", 1, 1, 0))
+	assert.Error(t, SetSampling("orderer/test", -1, 1, time.Second))
+}
+
+func TestFilterBackendLevels(t *testing.T) {
+	defer Reset()
+	InitBackend(SetFormat("%{message}"), &bytes.Buffer{})
+	_, err := SetModuleLevel("flogging", "WARNING")
+	require.NoError(t, err)
+	assert.Equal(t, "WARNING", ModuleLevels()["flogging"])
+	assert.Equal(t, logging.WARNING, logging.GetLevel("flogging"))
+}
+
+func lines(buf *bytes.Buffer) []string {
+	return strings.Split(strings.TrimSpace(buf.String()), "\n")
+}
@@ -0,0 +1,138 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package httpadmin serves the logging levels and samplings of a node over
+// HTTP, so that admins change them while the node runs.
+package httpadmin
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+)
+
+var logger = flogging.MustGetLogger("flogging/httpadmin")
+
+// LogSpec is the logging configuration of the node
+type LogSpec struct {
+	Modules  map[string]string `json:"modules"`
+	Channels []ChannelLevel    `json:"channels"`
+	Sampling []Sampling        `json:"sampling"`
+}
+
+// ChannelLevel is the logging level of the modules matching Module, a regular
+// expression, for the messages about Channel.  Without Channel it is the
+// level of the modules.  An empty Level clears the level of the channel.
+type ChannelLevel struct {
+	Module  string `json:"module"`
+	Channel string `json:"channel,omitempty"`
+	Level   string `json:"level"`
+}
+
+// Sampling is the sampling of the debug messages of the modules matching
+// Module, a regular expression, Tick being a duration such as "1s".  An
+// Initial count of zero stops the sampling.
+type Sampling struct {
+	Module     string `json:"module"`
+	Initial    int    `json:"initial"`
+	Thereafter int    `json:"thereafter"`
+	Tick       string `json:"tick"`
+}
+
+// SpecHandler serves the logging configuration on GET, and sets the level of
+// a ChannelLevel on PUT
+type SpecHandler struct{}
+
+// NewSpecHandler creates a handler of the logging levels
+func NewSpecHandler() *SpecHandler {
+	return &SpecHandler{}
+}
+
+func (h *SpecHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		h.get(w)
+	case http.MethodPut:
+		level := &ChannelLevel{}
+		if err := json.NewDecoder(req.Body).Decode(level); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := setLevel(level); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Infof("Logging level of modules '%s' for channel '%s' set to '%s' by %s", level.Module, level.Channel, level.Level, req.RemoteAddr)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (h *SpecHandler) get(w http.ResponseWriter) {
+	spec := &LogSpec{Modules: flogging.ModuleLevels()}
+	for _, cl := range flogging.ChannelLevels() {
+		spec.Channels = append(spec.Channels, ChannelLevel{Module: cl.Module, Channel: cl.Channel, Level: cl.Level})
+	}
+	for _, s := range flogging.Samplings() {
+		spec.Sampling = append(spec.Sampling, Sampling{Module: s.Module, Initial: s.Initial, Thereafter: s.Thereafter, Tick: s.Tick.String()})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(spec); err != nil {
+		logger.Warningf("Failed writing log spec response: %s", err)
+	}
+}
+
+func setLevel(level *ChannelLevel) error {
+	switch {
+	case level.Channel == "":
+		_, err := flogging.SetModuleLevel(level.Module, level.Level)
+		return err
+	case level.Level == "":
+		flogging.ClearChannelLevel(level.Module, level.Channel)
+		return nil
+	default:
+		return flogging.SetChannelLevel(level.Module, level.Channel, level.Level)
+	}
+}
+
+// SamplingHandler sets a Sampling on PUT
+type SamplingHandler struct{}
+
+// NewSamplingHandler creates a handler of the samplings of debug messages
+func NewSamplingHandler() *SamplingHandler {
+	return &SamplingHandler{}
+}
+
+func (h *SamplingHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPut {
+		w.Header().Set("Allow", "PUT")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	sampling := &Sampling{}
+	if err := json.NewDecoder(req.Body).Decode(sampling); err != nil {
+		http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	var tick time.Duration
+	if sampling.Tick != "" {
+		var err error
+		if tick, err = time.ParseDuration(sampling.Tick); err != nil {
+			http.Error(w, "invalid tick: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	if err := flogging.SetSampling(sampling.Module, sampling.Initial, sampling.Thereafter, tick); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	logger.Infof("Sampling of modules '%s' set to %d per %s then 1 in %d by %s", sampling.Module, sampling.Initial, tick, sampling.Thereafter, req.RemoteAddr)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,85 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package httpadmin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpecHandler(t *testing.T) {
+	defer flogging.Reset()
+	flogging.MustGetLogger("orderer/common/broadcast")
+	h := NewSpecHandler()
+
+	for _, tc := range []struct {
+		body   string
+		status int
+	}{
+		{`{"module": "orderer/common/broadcast", "level": "WARNING"}`, http.StatusNoContent},
+		{`{"module": "orderer/common/broadcast", "channel": "mychannel", "level": "DEBUG"}`, http.StatusNoContent},
+		{`{"module": "orderer/common/broadcast", "channel": "otherchannel", "level": "DEBUG"}`, http.StatusNoContent},
+		{`{"module": "orderer/common/broadcast", "channel": "otherchannel"}`, http.StatusNoContent},
+		{`{"module": "orderer/common/broadcast", "channel": "mychannel", "level": "LOUD"}`, http.StatusBadRequest},
+		{`{"module": "orderer/common/broadcast", "level": "LOUD"}`, http.StatusBadRequest},
+		{`garbage`, http.StatusBadRequest},
+	} {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, "/logspec", strings.NewReader(tc.body)))
+		assert.Equal(t, tc.status, resp.Code, tc.body)
+	}
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/logspec", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	spec := &LogSpec{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(spec))
+	assert.Equal(t, "WARNING", spec.Modules["orderer/common/broadcast"])
+	assert.Equal(t, []ChannelLevel{{Module: "orderer/common/broadcast", Channel: "mychannel", Level: "DEBUG"}}, spec.Channels)
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/logspec", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
+
+func TestSamplingHandler(t *testing.T) {
+	defer flogging.Reset()
+	h := NewSamplingHandler()
+
+	for _, tc := range []struct {
+		body   string
+		status int
+	}{
+		{`{"module": "orderer/common/broadcast", "initial": 10, "thereafter": 1000, "tick": "1s"}`, http.StatusNoContent},
+		{`{"module": "orderer/common/broadcast", "initial": 10, "thereafter": 1000, "tick": "soon"}`, http.StatusBadRequest},
+		{`{"module": "orderer/common/broadcast", "initial": 10, "thereafter": 1000}`, http.StatusBadRequest},
+		{`garbage`, http.StatusBadRequest},
+	} {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, "/logspec/sampling", strings.NewReader(tc.body)))
+		assert.Equal(t, tc.status, resp.Code, tc.body)
+	}
+	assert.Equal(t, []flogging.Sampling{{Module: "orderer/common/broadcast", Initial: 10, Thereafter: 1000, Tick: time.Second}}, flogging.Samplings())
+
+	resp := httptest.NewRecorder()
+	NewSpecHandler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/logspec", nil))
+	spec := &LogSpec{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(spec))
+	assert.Equal(t, []Sampling{{Module: "orderer/common/broadcast", Initial: 10, Thereafter: 1000, Tick: "1s"}}, spec.Sampling)
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/logspec/sampling", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
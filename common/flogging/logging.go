@@ -54,6 +54,7 @@ func init() {
 func Reset() {
 	modules = make(map[string]string)
 	lock = sync.RWMutex{}
+	resetFilters()
 
 	defaultOutput = os.Stderr
 	InitBackend(SetFormat(defaultFormat), defaultOutput)
@@ -69,11 +70,13 @@ func SetFormat(formatSpec string) logging.Formatter {
 }
 
 // InitBackend sets up the logging backend based on
-// the provided logging formatter and I/O writer.
+// the provided logging formatter and I/O writer.  The backend logs the
+// messages about channels at the levels set for them, and samples debug
+// messages.
 func InitBackend(formatter logging.Formatter, output io.Writer) {
 	backend := logging.NewLogBackend(output, "", 0)
 	backendFormatter := logging.NewBackendFormatter(backend, formatter)
-	logging.SetBackend(backendFormatter).SetLevel(defaultLevel, "")
+	logging.SetBackend(newFilterBackend(backendFormatter)).SetLevel(defaultLevel, "")
 }
 
 // DefaultLevel returns the fallback value for loggers to use if parsing fails.
@@ -90,6 +93,17 @@ func GetModuleLevel(module string) string {
 	return level
 }
 
+// ModuleLevels returns the logging level of every module which has a logger
+func ModuleLevels() map[string]string {
+	lock.RLock()
+	defer lock.RUnlock()
+	levels := make(map[string]string, len(modules))
+	for module := range modules {
+		levels[module] = GetModuleLevel(module)
+	}
+	return levels
+}
+
 // SetModuleLevel sets the logging level for the modules that match the supplied
 // regular expression. Can be used to dynamically change the log level for the
 // module.
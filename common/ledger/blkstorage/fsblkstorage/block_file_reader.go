@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsblkstorage
+
+import (
+	"io"
+
+	"github.com/hyperledger/fabric/common/ledger/util"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// BlockFileReader reads the blocks of a ledger sequentially, straight from its block files.
+// Unlike a block store, it neither opens nor updates the block index, and never writes to
+// the ledger directory, so it is safe to use on a copy of a ledger which must not be altered.
+type BlockFileReader struct {
+	stream *blockStream
+}
+
+// ListLedgerIDs lists the ids of the ledgers present under the block storage directory of the given conf
+func ListLedgerIDs(conf *Conf) ([]string, error) {
+	return util.ListSubdirs(conf.getChainsDir())
+}
+
+// NewBlockFileReader returns a reader positioned at the first block of the given ledger
+func NewBlockFileReader(conf *Conf, ledgerid string) (*BlockFileReader, error) {
+	rootDir := conf.getLedgerBlockDir(ledgerid)
+	lastFileNum, err := retrieveLastFileSuffix(rootDir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error reading block files of ledger [%s]", ledgerid)
+	}
+	if lastFileNum < 0 {
+		return nil, errors.Errorf("no block files found for ledger [%s]", ledgerid)
+	}
+	stream, err := newBlockStream(rootDir, 0, 0, lastFileNum)
+	if err != nil {
+		return nil, err
+	}
+	return &BlockFileReader{stream: stream}, nil
+}
+
+// Next returns the next block, or io.EOF once all the blocks have been read.
+// A block only partially written at the tail of the last file, as may be left
+// behind by a crash, is treated as the end of the ledger.
+func (r *BlockFileReader) Next() (*common.Block, error) {
+	blockBytes, err := r.stream.nextBlockBytes()
+	if err == ErrUnexpectedEndOfBlockfile {
+		logger.Warningf("Ignoring partially written block at the end of block file [%d]", r.stream.currentFileNum)
+		return nil, io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	if blockBytes == nil {
+		return nil, io.EOF
+	}
+	return deserializeBlock(blockBytes)
+}
+
+// Close releases the file handle held by the reader
+func (r *BlockFileReader) Close() error {
+	return r.stream.close()
+}
@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fsblkstorage
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/common/ledger/util"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBlockFileReader(t *testing.T) {
+	env := newTestEnv(t, NewConf(testPath(), 0))
+	defer env.Cleanup()
+	ledgerid := "testLedger"
+	blkfileMgrWrapper := newTestBlockfileWrapper(env, ledgerid)
+	bg, gb := testutil.NewBlockGenerator(t, ledgerid, false)
+	blocks := []*common.Block{gb}
+	blocks = append(blocks, bg.NextTestBlocks(3)...)
+	blkfileMgrWrapper.addBlocks(blocks)
+	blkfileMgrWrapper.close()
+
+	ledgerIDs, err := ListLedgerIDs(env.provider.conf)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ledgerid}, ledgerIDs)
+
+	reader, err := NewBlockFileReader(env.provider.conf, ledgerid)
+	assert.NoError(t, err)
+	for _, expected := range blocks {
+		block, err := reader.Next()
+		assert.NoError(t, err)
+		assert.True(t, proto.Equal(expected, block), "unexpected block %d", expected.Header.Number)
+	}
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+	assert.NoError(t, reader.Close())
+
+	// A partially written last block is treated as the end of the ledger
+	filePath := deriveBlockfilePath(env.provider.conf.getLedgerBlockDir(ledgerid), 0)
+	_, fileSize, err := util.FileExists(filePath)
+	assert.NoError(t, err)
+	assert.NoError(t, os.Truncate(filePath, fileSize-1))
+
+	reader, err = NewBlockFileReader(env.provider.conf, ledgerid)
+	assert.NoError(t, err)
+	defer reader.Close()
+	for range blocks[:len(blocks)-1] {
+		_, err := reader.Next()
+		assert.NoError(t, err)
+	}
+	_, err = reader.Next()
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestBlockFileReaderMissingLedger(t *testing.T) {
+	env := newTestEnv(t, NewConf(testPath(), 0))
+	defer env.Cleanup()
+
+	_, err := NewBlockFileReader(env.provider.conf, "missing")
+	assert.Error(t, err)
+}
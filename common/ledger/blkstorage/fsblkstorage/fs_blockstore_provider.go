@@ -17,11 +17,17 @@ limitations under the License.
 package fsblkstorage
 
 import (
+	"os"
+
 	"github.com/hyperledger/fabric/common/ledger/blkstorage"
 	"github.com/hyperledger/fabric/common/ledger/util"
 	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
+	"github.com/pkg/errors"
 )
 
+// index entries deleted per batch when clearing the index of a ledger
+const clearIndexBatchSize = 10000
+
 //FsBlockstoreProvider结构体实现了一些接口
 
 // FsBlockstoreProvider provides handle to block storage - this is not thread-safe
@@ -61,6 +67,90 @@ func (p *FsBlockstoreProvider) List() ([]string, error) {
 	return util.ListSubdirs(p.conf.getChainsDir())
 }
 
+// RebuildIndex drops the block index and the checkpoint info of the given ledger, and builds
+// them again by scanning its block files. It is meant for repairing a corrupted index while the
+// peer is stopped, and must not be invoked while a block store is open for the ledger
+func (p *FsBlockstoreProvider) RebuildIndex(ledgerid string) (err error) {
+	exists, err := p.Exists(ledgerid)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Errorf("ledger [%s] does not exist", ledgerid)
+	}
+	indexStoreHandle := p.leveldbProvider.GetDBHandle(ledgerid)
+	if err := clearIndex(indexStoreHandle); err != nil {
+		return errors.Wrapf(err, "error clearing the block index of ledger [%s]", ledgerid)
+	}
+	logger.Infof("Cleared the block index of ledger [%s], rebuilding it from the block files", ledgerid)
+
+	// The block file manager panics when it fails to open, which here is
+	// reported as an error instead of taking the command down
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("error rebuilding the block index of ledger [%s]: %s", ledgerid, r)
+		}
+	}()
+	mgr := newBlockfileMgr(ledgerid, p.conf, p.indexConfig, indexStoreHandle)
+	defer mgr.close()
+	// The manager syncs the index when it opens the block files but ignores
+	// the failures, syncing again reports them and is a no-op otherwise
+	if err := mgr.syncIndex(); err != nil {
+		return errors.Wrapf(err, "error rebuilding the block index of ledger [%s]", ledgerid)
+	}
+	logger.Infof("Rebuilt the block index of ledger [%s], height is [%d]", ledgerid, mgr.getBlockchainInfo().Height)
+	return nil
+}
+
+// CompactIndex compacts the database holding the block indexes of all the ledgers,
+// reclaiming the space of the entries deleted or overwritten
+func (p *FsBlockstoreProvider) CompactIndex() error {
+	return p.leveldbProvider.Compact()
+}
+
+// Remove deletes the block files and the block index of the given ledger. It must not be
+// invoked while a block store is open for the ledger
+func (p *FsBlockstoreProvider) Remove(ledgerid string) error {
+	exists, err := p.Exists(ledgerid)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.Errorf("ledger [%s] does not exist", ledgerid)
+	}
+	// The index is cleared first so that the ledger is not left with an index
+	// but no block files, which it could not be opened with again
+	if err := clearIndex(p.leveldbProvider.GetDBHandle(ledgerid)); err != nil {
+		return errors.Wrapf(err, "error clearing the block index of ledger [%s]", ledgerid)
+	}
+	if err := os.RemoveAll(p.conf.getLedgerBlockDir(ledgerid)); err != nil {
+		return errors.Wrapf(err, "error deleting the block files of ledger [%s]", ledgerid)
+	}
+	logger.Infof("Removed ledger [%s]", ledgerid)
+	return nil
+}
+
+func clearIndex(db *leveldbhelper.DBHandle) error {
+	for {
+		itr := db.GetIterator(nil, nil)
+		batch := leveldbhelper.NewUpdateBatch()
+		for len(batch.KVs) < clearIndexBatchSize && itr.Next() {
+			batch.Delete(append([]byte{}, itr.Key()...))
+		}
+		err := itr.Error()
+		itr.Release()
+		if err != nil {
+			return err
+		}
+		if len(batch.KVs) == 0 {
+			return nil
+		}
+		if err := db.WriteBatch(batch, true); err != nil {
+			return errors.Wrap(err, "error deleting index entries")
+		}
+	}
+}
+
 // Close closes the FsBlockstoreProvider
 func (p *FsBlockstoreProvider) Close() {
 	p.leveldbProvider.Close()
@@ -55,6 +55,86 @@ func TestMultipleBlockStores(t *testing.T) {
 	checkWithWrongInputs(t, store2, 10)
 }
 
+func TestRebuildIndex(t *testing.T) {
+	env := newTestEnv(t, NewConf(testPath(), 0))
+	defer env.Cleanup()
+
+	provider := env.provider
+	store1, _ := provider.OpenBlockStore("ledger1")
+	store2, _ := provider.OpenBlockStore("ledger2")
+	blocks1 := testutil.ConstructTestBlocks(t, 5)
+	for _, b := range blocks1 {
+		store1.AddBlock(b)
+	}
+	blocks2 := testutil.ConstructTestBlocks(t, 3)
+	for _, b := range blocks2 {
+		store2.AddBlock(b)
+	}
+	store1.Shutdown()
+	store2.Shutdown()
+
+	// corrupt the index of ledger1 by dropping the entries of a block and the checkpoint info
+	db := provider.leveldbProvider.GetDBHandle("ledger1")
+	testutil.AssertNoError(t, db.Delete(constructBlockNumKey(3), true), "")
+	testutil.AssertNoError(t, db.Delete(constructBlockHashKey(blocks1[2].Header.Hash()), true), "")
+	testutil.AssertNoError(t, db.Delete(blkMgrInfoKey, true), "")
+	testutil.AssertNoError(t, db.Put(indexCheckpointKey, []byte("garbage"), true), "")
+
+	testutil.AssertNoError(t, provider.RebuildIndex("ledger1"), "")
+	testutil.AssertNoError(t, provider.CompactIndex(), "")
+
+	store1, _ = provider.OpenBlockStore("ledger1")
+	defer store1.Shutdown()
+	store2, _ = provider.OpenBlockStore("ledger2")
+	defer store2.Shutdown()
+	checkBlocks(t, blocks1, store1)
+	checkBlocks(t, blocks2, store2)
+	checkWithWrongInputs(t, store1, 5)
+
+	err := provider.RebuildIndex("ledger3")
+	testutil.AssertError(t, err, "")
+	testutil.AssertEquals(t, err.Error(), "ledger [ledger3] does not exist")
+}
+
+func TestRemove(t *testing.T) {
+	env := newTestEnv(t, NewConf(testPath(), 0))
+	defer env.Cleanup()
+
+	provider := env.provider
+	store1, _ := provider.OpenBlockStore("ledger1")
+	store2, _ := provider.OpenBlockStore("ledger2")
+	blocks1 := testutil.ConstructTestBlocks(t, 5)
+	for _, b := range blocks1 {
+		store1.AddBlock(b)
+	}
+	blocks2 := testutil.ConstructTestBlocks(t, 3)
+	for _, b := range blocks2 {
+		store2.AddBlock(b)
+	}
+	store1.Shutdown()
+	defer store2.Shutdown()
+
+	testutil.AssertNoError(t, provider.Remove("ledger1"), "")
+	exists, err := provider.Exists("ledger1")
+	testutil.AssertNoError(t, err, "")
+	testutil.AssertEquals(t, exists, false)
+	ledgerIDs, err := provider.List()
+	testutil.AssertNoError(t, err, "")
+	testutil.AssertEquals(t, ledgerIDs, []string{"ledger2"})
+	checkBlocks(t, blocks2, store2)
+
+	// a ledger created again with the same id starts empty
+	store1, _ = provider.OpenBlockStore("ledger1")
+	defer store1.Shutdown()
+	bcInfo, err := store1.GetBlockchainInfo()
+	testutil.AssertNoError(t, err, "")
+	testutil.AssertEquals(t, bcInfo.Height, uint64(0))
+
+	err = provider.Remove("ledger3")
+	testutil.AssertError(t, err, "")
+	testutil.AssertEquals(t, err.Error(), "ledger [ledger3] does not exist")
+}
+
 func checkBlocks(t *testing.T, expectedBlocks []*common.Block, store blkstorage.BlockStore) {
 	bcInfo, _ := store.GetBlockchainInfo()
 	testutil.AssertEquals(t, bcInfo.Height, uint64(len(expectedBlocks)))
@@ -22,6 +22,7 @@ import (
 	"github.com/hyperledger/fabric/common/ledger/blkstorage"
 	"github.com/hyperledger/fabric/common/ledger/blkstorage/fsblkstorage"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	"github.com/pkg/errors"
 )
 
 type fileLedgerFactory struct {
@@ -67,6 +68,36 @@ func (flf *fileLedgerFactory) ChainIDs() []string {
 	return chainIDs
 }
 
+// CompactIndex compacts the database holding the block indexes of the ledgers
+func (flf *fileLedgerFactory) CompactIndex() error {
+	compactor, ok := flf.blkstorageProvider.(interface{ CompactIndex() error })
+	if !ok {
+		return errors.New("block storage does not support compacting its index")
+	}
+	return compactor.CompactIndex()
+}
+
+// Remove closes the ledger of the chain and deletes its block files and index
+func (flf *fileLedgerFactory) Remove(chainID string) error {
+	remover, ok := flf.blkstorageProvider.(interface{ Remove(ledgerid string) error })
+	if !ok {
+		return errors.New("block storage does not support removing ledgers")
+	}
+
+	flf.mutex.Lock()
+	defer flf.mutex.Unlock()
+
+	//先关闭账本上打开的迭代器与区块数据存储对象，再删除其区块文件与索引
+	if ledger, ok := flf.ledgers[chainID]; ok {
+		ledger.(*FileLedger).closeIterators()
+		if store, ok := ledger.(*FileLedger).blockStore.(interface{ Shutdown() }); ok {
+			store.Shutdown()
+		}
+		delete(flf.ledgers, chainID)
+	}
+	return remover.Remove(chainID)
+}
+
 // Close releases all resources acquired by the factory
 func (flf *fileLedgerFactory) Close() {
 	flf.blkstorageProvider.Close()
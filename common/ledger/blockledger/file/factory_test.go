@@ -19,11 +19,14 @@ package fileledger
 import (
 	"fmt"
 	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/hyperledger/fabric/common/ledger/blkstorage"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
 	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -90,3 +93,56 @@ func TestMultiReinitialization(t *testing.T) {
 	assert.Equal(t, 3, len(flf.ChainIDs()), "Expected chain to be recovered")
 	flf.Close()
 }
+
+func TestCompactIndex(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hyperledger_fabric")
+	assert.NoError(t, err, "Error creating temp dir: %s", err)
+	defer os.RemoveAll(dir)
+
+	flf := New(dir)
+	defer flf.Close()
+	_, err = flf.GetOrCreate(genesisconfig.TestChainID)
+	assert.NoError(t, err, "Error GetOrCreate chain")
+	assert.NoError(t, flf.(*fileLedgerFactory).CompactIndex(), "Error compacting the block index")
+
+	flf = &fileLedgerFactory{blkstorageProvider: &mockBlockStoreProvider{}}
+	assert.Error(t, flf.(*fileLedgerFactory).CompactIndex(), "Expected an error if the block storage cannot compact its index")
+}
+
+func TestRemove(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hyperledger_fabric")
+	assert.NoError(t, err, "Error creating temp dir: %s", err)
+	defer os.RemoveAll(dir)
+
+	flf := New(dir)
+	defer flf.Close()
+	ledger, err := flf.GetOrCreate("foo")
+	assert.NoError(t, err, "Error GetOrCreate chain")
+	assert.NoError(t, ledger.Append(blockledger.CreateNextBlock(ledger, []*cb.Envelope{{Payload: []byte("foo")}})))
+	_, err = flf.GetOrCreate("bar")
+	assert.NoError(t, err, "Error GetOrCreate chain")
+
+	// an iterator waiting for the next block is ended by the removal
+	it, _ := ledger.Iterator(&ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: 1}}})
+	defer it.Close()
+	statuses := make(chan cb.Status)
+	go func() {
+		_, status := it.Next()
+		statuses <- status
+	}()
+
+	assert.NoError(t, flf.(blockledger.Remover).Remove("foo"), "Error removing chain")
+	assert.Equal(t, []string{"bar"}, flf.ChainIDs(), "Expected the chain to be removed")
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, <-statuses, "Expected the waiting iterator to be ended")
+	it, _ = ledger.Iterator(&ab.SeekPosition{Type: &ab.SeekPosition_Oldest{Oldest: &ab.SeekOldest{}}})
+	assert.IsType(t, &blockledger.NotFoundErrorIterator{}, it, "Expected no iterator on the removed ledger")
+	assert.Error(t, flf.(blockledger.Remover).Remove("foo"), "Expected an error removing a chain which does not exist")
+
+	// the chain is created again empty
+	ledger, err = flf.GetOrCreate("foo")
+	assert.NoError(t, err, "Error GetOrCreate chain")
+	assert.Equal(t, uint64(0), ledger.Height())
+
+	flf = &fileLedgerFactory{blkstorageProvider: &mockBlockStoreProvider{}}
+	assert.Error(t, flf.(blockledger.Remover).Remove("foo"), "Expected an error if the block storage cannot remove ledgers")
+}
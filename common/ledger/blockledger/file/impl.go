@@ -17,6 +17,8 @@ limitations under the License.
 package fileledger
 
 import (
+	"sync"
+
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/ledger"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
@@ -42,6 +44,10 @@ func init() {
 type FileLedger struct {
 	blockStore FileLedgerBlockStore
 	signal     chan struct{}
+
+	iteratorsLock sync.Mutex
+	iterators     map[*fileLedgerIterator]struct{} //打开的迭代器，删除账本前关闭
+	closed        bool                             //账本被删除后不再打开迭代器
 }
 
 // FileLedgerBlockStore defines the interface to interact with deliver when using a
@@ -89,7 +95,9 @@ func (i *fileLedgerIterator) ReadyChan() <-chan struct{} {
 
 // Close releases resources acquired by the Iterator
 func (i *fileLedgerIterator) Close() {
-	i.commonIterator.Close()
+	if i.ledger.untrack(i) {
+		i.commonIterator.Close()
+	}
 }
 
 // Iterator returns an Iterator, as specified by an ab.SeekInfo message, and its
@@ -126,7 +134,55 @@ func (fl *FileLedger) Iterator(startPosition *ab.SeekPosition) (blockledger.Iter
 		return &blockledger.NotFoundErrorIterator{}, 0
 	}
 
-	return &fileLedgerIterator{ledger: fl, blockNumber: startingBlockNumber, commonIterator: iterator}, startingBlockNumber
+	it := &fileLedgerIterator{ledger: fl, blockNumber: startingBlockNumber, commonIterator: iterator}
+	if !fl.track(it) {
+		iterator.Close()
+		return &blockledger.NotFoundErrorIterator{}, 0
+	}
+	return it, startingBlockNumber
+}
+
+// track registers the iterator opened, unless the iterators of the ledger
+// were closed
+func (fl *FileLedger) track(it *fileLedgerIterator) bool {
+	fl.iteratorsLock.Lock()
+	defer fl.iteratorsLock.Unlock()
+	if fl.closed {
+		return false
+	}
+	if fl.iterators == nil {
+		fl.iterators = make(map[*fileLedgerIterator]struct{})
+	}
+	fl.iterators[it] = struct{}{}
+	return true
+}
+
+// untrack unregisters the iterator, and returns whether it was registered,
+// that is whether it is still to be closed
+func (fl *FileLedger) untrack(it *fileLedgerIterator) bool {
+	fl.iteratorsLock.Lock()
+	defer fl.iteratorsLock.Unlock()
+	if _, ok := fl.iterators[it]; !ok {
+		return false
+	}
+	delete(fl.iterators, it)
+	return true
+}
+
+// closeIterators closes the open iterators of the ledger, waiting for the
+// blocks they are reading, and stops the ledger from opening new ones, so
+// that its block store can be shut down and removed.  The iterators return
+// SERVICE_UNAVAILABLE from then on.
+func (fl *FileLedger) closeIterators() {
+	fl.iteratorsLock.Lock()
+	fl.closed = true
+	iterators := fl.iterators
+	fl.iterators = nil
+	fl.iteratorsLock.Unlock()
+
+	for it := range iterators {
+		it.commonIterator.Close()
+	}
 }
 
 // Height returns the number of blocks on the ledger
@@ -25,6 +25,7 @@ import (
 
 	"github.com/golang/protobuf/jsonpb"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	"github.com/pkg/errors"
 )
 
 type jsonLedgerFactory struct {
@@ -122,6 +123,18 @@ func (jlf *jsonLedgerFactory) ChainIDs() []string {
 	return ids
 }
 
+// Remove drops the ledger of the chain and deletes its directory
+func (jlf *jsonLedgerFactory) Remove(chainID string) error {
+	jlf.mutex.Lock()
+	defer jlf.mutex.Unlock()
+
+	if _, ok := jlf.ledgers[chainID]; !ok {
+		return errors.Errorf("ledger %s does not exist", chainID)
+	}
+	delete(jlf.ledgers, chainID)
+	return os.RemoveAll(filepath.Join(jlf.directory, fmt.Sprintf(chainDirectoryFormatString, chainID)))
+}
+
 // Close is a no-op for the JSON ledger
 func (jlf *jsonLedgerFactory) Close() {
 	return // nothing to do
@@ -31,6 +31,15 @@ type Factory interface {
 	Close()
 }
 
+// Remover is optionally implemented by a Factory which can remove the ledgers
+// it created
+type Remover interface {
+	// Remove closes the ledger of the chain and deletes its blocks.  The
+	// ledger must no longer be written to, and the iterators reading it
+	// may be ended.
+	Remove(chainID string) error
+}
+
 // Iterator is useful for a chain Reader to stream blocks as they are created
 type Iterator interface {
 	// Next blocks until there is a new block available, or returns an error if
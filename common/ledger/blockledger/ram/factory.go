@@ -21,6 +21,7 @@ import (
 
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
 	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
 )
 
 type ramLedgerFactory struct {
@@ -81,6 +82,18 @@ func (rlf *ramLedgerFactory) ChainIDs() []string {
 	return ids
 }
 
+// Remove drops the ledger of the chain
+func (rlf *ramLedgerFactory) Remove(chainID string) error {
+	rlf.mutex.Lock()
+	defer rlf.mutex.Unlock()
+
+	if _, ok := rlf.ledgers[chainID]; !ok {
+		return errors.Errorf("ledger %s does not exist", chainID)
+	}
+	delete(rlf.ledgers, chainID)
+	return nil
+}
+
 // Close is a no-op for the RAM ledger
 func (rlf *ramLedgerFactory) Close() {
 	return // nothing to do
@@ -19,6 +19,7 @@ package ramledger
 import (
 	"testing"
 
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
 	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
 
 	logging "github.com/op/go-logging"
@@ -53,3 +54,18 @@ func TestChainIDs(t *testing.T) {
 	}
 	rlf.Close()
 }
+
+func TestRemove(t *testing.T) {
+	rlf := New(3)
+	rlf.GetOrCreate("channel1")
+	rlf.GetOrCreate("channel2")
+	if err := rlf.(blockledger.Remover).Remove("channel1"); err != nil {
+		t.Fatalf("Error removing channel: %s", err)
+	}
+	if ids := rlf.ChainIDs(); len(ids) != 1 || ids[0] != "channel2" {
+		t.Fatalf("Expecting only channel2, got %v", ids)
+	}
+	if err := rlf.(blockledger.Remover).Remove("channel1"); err == nil {
+		t.Fatalf("Expecting an error removing a channel which does not exist")
+	}
+}
@@ -164,3 +164,13 @@ func (dbInst *DB) WriteBatch(batch *leveldb.Batch, sync bool) error {
 	}
 	return nil
 }
+
+// Compact compacts the underlying storage for the key range between the startKey (inclusive) and the endKey (exclusive),
+// discarding the deleted and overwritten entries. A nil startKey or endKey represents the first or the last available key
+func (dbInst *DB) Compact(startKey []byte, endKey []byte) error {
+	if err := dbInst.db.CompactRange(goleveldbutil.Range{Start: startKey, Limit: endKey}); err != nil {
+		logger.Errorf("Error while trying to compact range [%#v] - [%#v]: %s", startKey, endKey, err)
+		return err
+	}
+	return nil
+}
@@ -53,6 +53,11 @@ func (p *Provider) GetDBHandle(dbName string) *DBHandle {
 	return dbHandle
 }
 
+// Compact compacts the whole underlying leveldb
+func (p *Provider) Compact() error {
+	return p.db.Compact(nil, nil)
+}
+
 // Close closes the underlying leveldb
 func (p *Provider) Close() {
 	p.db.Close()
@@ -111,6 +116,14 @@ func (h *DBHandle) GetIterator(startKey []byte, endKey []byte) *Iterator {
 	return &Iterator{h.db.GetIterator(sKey, eKey)}
 }
 
+// Compact compacts the keys of the named db
+func (h *DBHandle) Compact() error {
+	sKey := constructLevelKey(h.dbName, nil)
+	eKey := constructLevelKey(h.dbName, nil)
+	eKey[len(eKey)-1] = lastKeyIndicator
+	return h.db.Compact(sKey, eKey)
+}
+
 // UpdateBatch encloses the details of multiple `updates`
 type UpdateBatch struct {
 	KVs map[string][]byte
@@ -90,6 +90,30 @@ func TestBatchedUpdates(t *testing.T) {
 	}
 }
 
+func TestCompact(t *testing.T) {
+	env := newTestProviderEnv(t, testDBPath)
+	defer env.cleanup()
+	p := env.provider
+
+	db1 := p.GetDBHandle("db1")
+	db2 := p.GetDBHandle("db2")
+	for i := 0; i < 20; i++ {
+		db1.Put([]byte(createTestKey(i)), []byte(createTestValue("db1", i)), false)
+		db2.Put([]byte(createTestKey(i)), []byte(createTestValue("db2", i)), false)
+	}
+	for i := 0; i < 10; i++ {
+		db1.Delete([]byte(createTestKey(i)), false)
+	}
+
+	testutil.AssertNoError(t, db1.Compact(), "")
+	checkItrResults(t, db1.GetIterator(nil, nil), createTestKeys(10, 19), createTestValues("db1", 10, 19))
+	checkItrResults(t, db2.GetIterator(nil, nil), createTestKeys(0, 19), createTestValues("db2", 0, 19))
+
+	testutil.AssertNoError(t, p.Compact(), "")
+	checkItrResults(t, db1.GetIterator(nil, nil), createTestKeys(10, 19), createTestValues("db1", 10, 19))
+	checkItrResults(t, db2.GetIterator(nil, nil), createTestKeys(0, 19), createTestValues("db2", 0, 19))
+}
+
 func testDBBasicWriteAndReads(t *testing.T, dbNames ...string) {
 	env := newTestProviderEnv(t, testDBPath)
 	defer env.cleanup()
@@ -0,0 +1,193 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package memtuning applies garbage collector and heap tuning from node
+// configuration at startup, so that operators need not rely on environment
+// variables set by wrapper scripts, and reports the values in effect.
+package memtuning
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// Config holds the memory tuning to apply.  Empty fields leave the
+// corresponding runtime setting, including one taken from the GOGC
+// environment variable, unchanged.
+type Config struct {
+	// GOGC is the garbage collection target percentage, or "off".
+	GOGC string
+
+	// Ballast is the size of a heap ballast allocated at startup, which
+	// raises the heap size the collector paces against without letting the
+	// process touch the memory, as a number of bytes with an optional unit
+	// suffix, e.g. 2GiB or 512MB.
+	Ballast string
+}
+
+// Status reports the tuning currently in effect.
+type Status struct {
+	// GOGC is the garbage collection target percentage, negative if collection is off.
+	GOGC int `json:"gogc"`
+
+	// Ballast is the size of the heap ballast in bytes.
+	Ballast int64 `json:"ballast"`
+
+	// Env holds the GOGC environment variable the process was started with.
+	Env map[string]string `json:"env,omitempty"`
+
+	HeapAlloc uint64 `json:"heap_alloc"`
+	HeapSys   uint64 `json:"heap_sys"`
+	NextGC    uint64 `json:"next_gc"`
+	NumGC     uint32 `json:"num_gc"`
+}
+
+var (
+	mutex   sync.Mutex
+	ballast []byte
+	// gogc is the garbage collection target percentage in effect, cached as
+	// the runtime only reads it back by setting it, which triggers a collection
+	gogc = envGOGC()
+)
+
+// Apply validates the config and applies it to the runtime.  Calling Apply
+// again replaces the ballast, if one is configured.
+func Apply(conf Config) error {
+	percent, setGOGC, err := parseGOGC(conf.GOGC)
+	if err != nil {
+		return err
+	}
+	ballastSize, setBallast, err := parseBytesField("ballast", conf.Ballast)
+	if err != nil {
+		return err
+	}
+
+	mutex.Lock()
+	defer mutex.Unlock()
+
+	if setGOGC {
+		debug.SetGCPercent(percent)
+		gogc = percent
+	}
+	if setBallast {
+		ballast = make([]byte, ballastSize)
+	}
+	return nil
+}
+
+// CurrentStatus returns the tuning in effect and a summary of heap usage
+func CurrentStatus() *Status {
+	mutex.Lock()
+	ballastSize := int64(len(ballast))
+	percent := gogc
+	mutex.Unlock()
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+
+	status := &Status{
+		GOGC:      percent,
+		Ballast:   ballastSize,
+		HeapAlloc: ms.HeapAlloc,
+		HeapSys:   ms.HeapSys,
+		NextGC:    ms.NextGC,
+		NumGC:     ms.NumGC,
+	}
+	if value, ok := os.LookupEnv("GOGC"); ok {
+		status.Env = map[string]string{"GOGC": value}
+	}
+	return status
+}
+
+// Handler returns an http.Handler which writes the current status as JSON
+func Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(CurrentStatus()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// envGOGC returns the garbage collection target percentage the runtime took
+// from the GOGC environment variable, 100 if it is unset or invalid
+func envGOGC() int {
+	percent, ok, err := parseGOGC(os.Getenv("GOGC"))
+	if !ok || err != nil {
+		return 100
+	}
+	return percent
+}
+
+func parseGOGC(value string) (int, bool, error) {
+	value = strings.TrimSpace(value)
+	switch {
+	case value == "":
+		return 0, false, nil
+	case strings.EqualFold(value, "off"):
+		return -1, true, nil
+	}
+	gogc, err := strconv.Atoi(value)
+	if err != nil || gogc < 0 {
+		return 0, false, errors.Errorf("invalid GOGC value %q, expected a non-negative percentage or off", value)
+	}
+	return gogc, true, nil
+}
+
+func parseBytesField(name, value string) (int64, bool, error) {
+	if strings.TrimSpace(value) == "" {
+		return 0, false, nil
+	}
+	n, err := ParseBytes(value)
+	if err != nil {
+		return 0, false, errors.WithMessage(err, "invalid "+name)
+	}
+	return n, true, nil
+}
+
+var byteUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	// Longer suffixes first, so that KiB is not mistaken for a B suffix
+	{"KiB", 1 << 10}, {"MiB", 1 << 20}, {"GiB", 1 << 30}, {"TiB", 1 << 40},
+	{"KB", 1000}, {"MB", 1000 * 1000}, {"GB", 1000 * 1000 * 1000}, {"TB", 1000 * 1000 * 1000 * 1000},
+	{"B", 1},
+}
+
+// ParseBytes parses a byte quantity such as 1024, 512MB or 2GiB
+func ParseBytes(quantity string) (int64, error) {
+	value := strings.TrimSpace(quantity)
+	multiplier := int64(1)
+	for _, unit := range byteUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			multiplier = unit.multiplier
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n < 0 {
+		return 0, errors.Errorf("%q is not a valid byte quantity", quantity)
+	}
+	if n > 0 && multiplier > (1<<63-1)/n {
+		return 0, errors.Errorf("%q overflows", quantity)
+	}
+	return n * multiplier, nil
+}
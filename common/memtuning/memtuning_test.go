@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package memtuning
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBytes(t *testing.T) {
+	for _, tc := range []struct {
+		input    string
+		expected int64
+	}{
+		{"0", 0},
+		{"1024", 1024},
+		{"10B", 10},
+		{"2KB", 2000},
+		{"2KiB", 2048},
+		{"512MB", 512 * 1000 * 1000},
+		{" 3 GiB ", 3 << 30},
+		{"1TiB", 1 << 40},
+	} {
+		n, err := ParseBytes(tc.input)
+		assert.NoError(t, err, tc.input)
+		assert.Equal(t, tc.expected, n, tc.input)
+	}
+
+	for _, input := range []string{"", "abc", "-1", "1.5GB", "10XB", "9223372036854775807KB"} {
+		_, err := ParseBytes(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestApply(t *testing.T) {
+	previousGOGC := debug.SetGCPercent(100)
+	defer func() {
+		debug.SetGCPercent(previousGOGC)
+		gogc = previousGOGC
+		ballast = nil
+	}()
+
+	require.NoError(t, Apply(Config{GOGC: "50", Ballast: "1MiB"}))
+	status := CurrentStatus()
+	assert.Equal(t, 50, status.GOGC)
+	assert.Equal(t, 50, debug.SetGCPercent(50))
+	assert.Equal(t, int64(1<<20), status.Ballast)
+
+	// Empty fields leave the settings unchanged
+	require.NoError(t, Apply(Config{}))
+	status = CurrentStatus()
+	assert.Equal(t, 50, status.GOGC)
+	assert.Equal(t, int64(1<<20), status.Ballast)
+
+	require.NoError(t, Apply(Config{GOGC: "off"}))
+	assert.Equal(t, -1, CurrentStatus().GOGC)
+
+	assert.EqualError(t, Apply(Config{GOGC: "fast"}), `invalid GOGC value "fast", expected a non-negative percentage or off`)
+	assert.EqualError(t, Apply(Config{Ballast: "-1"}), `invalid ballast: "-1" is not a valid byte quantity`)
+	// A rejected config is not partially applied
+	assert.Equal(t, -1, CurrentStatus().GOGC)
+}
+
+func TestHandler(t *testing.T) {
+	resp := httptest.NewRecorder()
+	Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/runtime/memory", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+
+	status := &Status{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), status))
+	assert.NotZero(t, status.HeapSys)
+
+	resp = httptest.NewRecorder()
+	Handler().ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/runtime/memory", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
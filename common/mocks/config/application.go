@@ -45,6 +45,7 @@ type MockApplicationCapabilities struct {
 	V1_2ValidationRv             bool
 	MetadataLifecycleRv          bool
 	KeyLevelEndorsementRv        bool
+	ChaincodeLimitsRv            bool
 }
 
 func (mac *MockApplicationCapabilities) Supported() error {
@@ -82,3 +83,7 @@ func (mac *MockApplicationCapabilities) MetadataLifecycle() bool {
 func (mac *MockApplicationCapabilities) KeyLevelEndorsement() bool {
 	return mac.KeyLevelEndorsementRv
 }
+
+func (mac *MockApplicationCapabilities) ChaincodeLimits() bool {
+	return mac.ChaincodeLimitsRv
+}
@@ -17,6 +17,8 @@ import (
 type Orderer struct {
 	// ConsensusTypeVal is returned as the result of ConsensusType()
 	ConsensusTypeVal string
+	// ConsensusMetadataVersionVal is returned as the result of ConsensusMetadataVersion()
+	ConsensusMetadataVersionVal uint32
 	// BatchSizeVal is returned as the result of BatchSize()
 	BatchSizeVal *ab.BatchSize
 	// BatchTimeoutVal is returned as the result of BatchTimeout()
@@ -25,6 +27,12 @@ type Orderer struct {
 	KafkaBrokersVal []string
 	// MaxChannelsCountVal is returns as the result of MaxChannelsCount()
 	MaxChannelsCountVal uint64
+	// IngressWeightVal is returned as the result of IngressWeight()
+	IngressWeightVal uint32
+	// IngressQuotasVal is returned as the result of IngressQuotas()
+	IngressQuotasVal map[string]*ab.IngressQuota
+	// TransactionLabelsVal is returned as the result of TransactionLabels()
+	TransactionLabelsVal map[string]*ab.TransactionLabel
 	// OrganizationsVal is returned as the result of Organizations()
 	OrganizationsVal map[string]channelconfig.Org
 	// CapabilitiesVal is returned as the result of Capabilities()
@@ -36,6 +44,11 @@ func (scm *Orderer) ConsensusType() string {
 	return scm.ConsensusTypeVal
 }
 
+// ConsensusMetadataVersion returns the ConsensusMetadataVersionVal
+func (scm *Orderer) ConsensusMetadataVersion() uint32 {
+	return scm.ConsensusMetadataVersionVal
+}
+
 // BatchSize returns the BatchSizeVal
 func (scm *Orderer) BatchSize() *ab.BatchSize {
 	return scm.BatchSizeVal
@@ -56,6 +69,21 @@ func (scm *Orderer) MaxChannelsCount() uint64 {
 	return scm.MaxChannelsCountVal
 }
 
+// IngressWeight returns the IngressWeightVal
+func (scm *Orderer) IngressWeight() uint32 {
+	return scm.IngressWeightVal
+}
+
+// IngressQuotas returns the IngressQuotasVal
+func (scm *Orderer) IngressQuotas() map[string]*ab.IngressQuota {
+	return scm.IngressQuotasVal
+}
+
+// TransactionLabels returns the TransactionLabelsVal
+func (scm *Orderer) TransactionLabels() map[string]*ab.TransactionLabel {
+	return scm.TransactionLabelsVal
+}
+
 // Organizations returns OrganizationsVal
 func (scm *Orderer) Organizations() map[string]channelconfig.Org {
 	return scm.OrganizationsVal
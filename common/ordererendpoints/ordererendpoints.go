@@ -0,0 +1,116 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package ordererendpoints orders the orderer endpoints advertised in channel
+// config by preference, so that clients connect to orderers in their own
+// region first, in the order of the advertised priorities, and fail over to
+// the other regions.
+package ordererendpoints
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/channelconfig"
+	cb "github.com/hyperledger/fabric/protos/common"
+)
+
+const (
+	// remoteRegion is added to the rank of endpoints outside the preferred region
+	remoteRegion = uint64(1) << 32
+
+	// Unknown is the rank of addresses not advertised in the channel config,
+	// which are only tried after every advertised endpoint.
+	Unknown = uint64(1) << 33
+)
+
+// FromConfig returns the endpoints advertised by the orderer organizations.
+// The channel wide addresses, which carry no region or priority, are
+// returned for organizations which advertise no endpoints of their own.
+func FromConfig(orderer channelconfig.Orderer, addresses []string) []*cb.OrdererEndpoint {
+	var endpoints []*cb.OrdererEndpoint
+	fallback := orderer == nil
+	if orderer != nil {
+		for _, org := range orderer.Organizations() {
+			ordererOrg, ok := org.(channelconfig.OrdererOrg)
+			if !ok || len(ordererOrg.Endpoints()) == 0 {
+				fallback = true
+				continue
+			}
+			endpoints = append(endpoints, ordererOrg.Endpoints()...)
+		}
+	}
+	if fallback || len(endpoints) == 0 {
+		seen := make(map[string]struct{}, len(endpoints))
+		for _, endpoint := range endpoints {
+			seen[endpoint.Address] = struct{}{}
+		}
+		for _, address := range addresses {
+			if _, ok := seen[address]; !ok {
+				endpoints = append(endpoints, &cb.OrdererEndpoint{Address: address})
+			}
+		}
+	}
+	return endpoints
+}
+
+// Rank returns the preference of the endpoint for a client in the given
+// region, lower ranks being preferred
+func Rank(endpoint *cb.OrdererEndpoint, region string) uint64 {
+	rank := uint64(endpoint.Priority)
+	if region == "" || endpoint.Region != region {
+		rank += remoteRegion
+	}
+	return rank
+}
+
+// Sort orders the endpoints by preference for a client in the given region,
+// keeping the relative order of endpoints of equal rank
+func Sort(endpoints []*cb.OrdererEndpoint, region string) {
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		return Rank(endpoints[i], region) < Rank(endpoints[j], region)
+	})
+}
+
+// Addresses returns the addresses of the endpoints
+func Addresses(endpoints []*cb.OrdererEndpoint) []string {
+	addresses := make([]string, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		addresses = append(addresses, endpoint.Address)
+	}
+	return addresses
+}
+
+var registry = struct {
+	sync.RWMutex
+	channels map[string]map[string]*cb.OrdererEndpoint
+}{channels: make(map[string]map[string]*cb.OrdererEndpoint)}
+
+// Update records the endpoints currently advertised in the config of a channel
+func Update(channelID string, endpoints []*cb.OrdererEndpoint) {
+	byAddress := make(map[string]*cb.OrdererEndpoint, len(endpoints))
+	for _, endpoint := range endpoints {
+		byAddress[endpoint.Address] = endpoint
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+	registry.channels[channelID] = byAddress
+}
+
+// Ranker returns a function ranking addresses for a client in the given
+// region using the endpoints most recently recorded for the channel
+func Ranker(channelID, region string) func(address string) uint64 {
+	return func(address string) uint64 {
+		registry.RLock()
+		endpoint, ok := registry.channels[channelID][address]
+		registry.RUnlock()
+		if !ok {
+			return Unknown
+		}
+		return Rank(endpoint, region)
+	}
+}
@@ -0,0 +1,71 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ordererendpoints
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/channelconfig"
+	mockconfig "github.com/hyperledger/fabric/common/mocks/config"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+type ordererOrg struct {
+	endpoints []*cb.OrdererEndpoint
+}
+
+func (oo *ordererOrg) Name() string                     { return "org" }
+func (oo *ordererOrg) MSPID() string                    { return "OrgMSP" }
+func (oo *ordererOrg) Endpoints() []*cb.OrdererEndpoint { return oo.endpoints }
+
+func TestFromConfig(t *testing.T) {
+	eu := &cb.OrdererEndpoint{Address: "eu:7050", Region: "eu", Priority: 1}
+	us := &cb.OrdererEndpoint{Address: "us:7050", Region: "us"}
+	addresses := []string{"eu:7050", "legacy:7050"}
+
+	orderer := &mockconfig.Orderer{OrganizationsVal: map[string]channelconfig.Org{
+		"org1": &ordererOrg{endpoints: []*cb.OrdererEndpoint{eu, us}},
+	}}
+	assert.ElementsMatch(t, []*cb.OrdererEndpoint{eu, us}, FromConfig(orderer, addresses))
+
+	orderer.OrganizationsVal["org2"] = &ordererOrg{}
+	assert.ElementsMatch(t, []string{"eu:7050", "us:7050", "legacy:7050"}, Addresses(FromConfig(orderer, addresses)),
+		"addresses of orgs without endpoints should be included once")
+
+	assert.Equal(t, addresses, Addresses(FromConfig(nil, addresses)))
+}
+
+func TestSort(t *testing.T) {
+	endpoints := []*cb.OrdererEndpoint{
+		{Address: "us-backup:7050", Region: "us", Priority: 2},
+		{Address: "eu-backup:7050", Region: "eu", Priority: 2},
+		{Address: "legacy:7050"},
+		{Address: "eu:7050", Region: "eu", Priority: 1},
+		{Address: "us:7050", Region: "us", Priority: 1},
+	}
+
+	Sort(endpoints, "eu")
+	assert.Equal(t, []string{"eu:7050", "eu-backup:7050", "legacy:7050", "us:7050", "us-backup:7050"}, Addresses(endpoints))
+
+	Sort(endpoints, "")
+	assert.Equal(t, []string{"legacy:7050", "eu:7050", "us:7050", "eu-backup:7050", "us-backup:7050"}, Addresses(endpoints))
+}
+
+func TestRanker(t *testing.T) {
+	ranker := Ranker("mychannel", "eu")
+	assert.Equal(t, Unknown, ranker("eu:7050"))
+
+	Update("mychannel", []*cb.OrdererEndpoint{
+		{Address: "eu:7050", Region: "eu", Priority: 3},
+		{Address: "us:7050", Region: "us"},
+	})
+	assert.Equal(t, uint64(3), ranker("eu:7050"))
+	assert.True(t, ranker("eu:7050") < ranker("us:7050"))
+	assert.True(t, ranker("us:7050") < ranker("bootstrap:7050"))
+	assert.Equal(t, Unknown, Ranker("otherchannel", "eu")("eu:7050"))
+}
@@ -0,0 +1,137 @@
+// +build go1.18
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package pluginabi checks that Go plugins were built compatibly with the
+// running binary before they are opened, so that a mismatched plugin is
+// refused with an explanation of the mismatch rather than failing to open
+// with an obscure error or misbehaving once called.
+package pluginabi
+
+import (
+	"debug/buildinfo"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("common/pluginabi")
+
+// abiSettings are the build settings which must be identical for a plugin
+// to share the ABI of the binary
+var abiSettings = []string{"-compiler", "-race", "-msan", "-asan", "GOOS", "GOARCH", "GO386", "GOAMD64", "GOARM", "GOARM64", "GOMIPS", "GOPPC64", "GORISCV64"}
+
+// devel is the version of a main module built from a working tree
+const devel = "(devel)"
+
+// Check returns an error explaining why the plugin at the path is not
+// compatible with the running binary: it is not a plugin, or it was built
+// by another Go toolchain, with other build settings affecting the ABI, or
+// against other versions of the modules both depend on.
+func Check(path string) error {
+	pluginInfo, err := buildinfo.ReadFile(path)
+	if err != nil {
+		return errors.Wrapf(err, "could not read build information of plugin %s", path)
+	}
+	binaryInfo, ok := debug.ReadBuildInfo()
+	if !ok {
+		logger.Debugf("Binary has no build information, skipping compatibility check of plugin %s", path)
+		return nil
+	}
+	if mismatches := compare(binaryInfo, pluginInfo); len(mismatches) > 0 {
+		return errors.Errorf("plugin %s is not compatible with this binary: %s", path, strings.Join(mismatches, "; "))
+	}
+	return nil
+}
+
+// compare returns the differences between the builds of the binary and of
+// the plugin preventing them from sharing types
+func compare(binary, plugin *debug.BuildInfo) []string {
+	var mismatches []string
+	if buildMode := setting(plugin, "-buildmode"); buildMode != "" && buildMode != "plugin" {
+		mismatches = append(mismatches, "it was built with -buildmode="+buildMode+" instead of -buildmode=plugin")
+	}
+	if binary.GoVersion != plugin.GoVersion {
+		mismatches = append(mismatches, "it was built with "+plugin.GoVersion+" but the binary with "+binary.GoVersion)
+	}
+	for _, key := range abiSettings {
+		if b, p := setting(binary, key), setting(plugin, key); b != p {
+			mismatches = append(mismatches, "it was built with "+describe(key, p)+" but the binary with "+describe(key, b))
+		}
+	}
+
+	binaryModules, pluginModules := modules(binary), modules(plugin)
+	var shared []string
+	for path := range pluginModules {
+		if _, ok := binaryModules[path]; ok {
+			shared = append(shared, path)
+		}
+	}
+	sort.Strings(shared)
+	for _, path := range shared {
+		b, p := binaryModules[path], pluginModules[path]
+		if b == devel || p == devel {
+			//工作区构建的模块没有可比较的版本
+			continue
+		}
+		if b != p {
+			mismatches = append(mismatches, "it depends on "+path+" "+p+" but the binary on "+path+" "+b)
+		}
+	}
+	return mismatches
+}
+
+// modules maps the paths of the modules of the build, its main module
+// included, to their versions, as replaced
+func modules(info *debug.BuildInfo) map[string]string {
+	versions := map[string]string{}
+	if info.Main.Path != "" {
+		versions[info.Main.Path] = version(&info.Main)
+	}
+	for _, dep := range info.Deps {
+		versions[dep.Path] = version(dep)
+	}
+	return versions
+}
+
+func version(m *debug.Module) string {
+	if m.Replace != nil {
+		m = m.Replace
+		if m.Version == "" {
+			//替换为本地目录的模块没有版本
+			return "=> " + m.Path
+		}
+		return "=> " + m.Path + " " + versionSum(m)
+	}
+	return versionSum(m)
+}
+
+func versionSum(m *debug.Module) string {
+	if m.Sum == "" {
+		return m.Version
+	}
+	return m.Version + " (" + m.Sum + ")"
+}
+
+func describe(key, value string) string {
+	if value == "" {
+		return key + " unset"
+	}
+	return key + "=" + value
+}
+
+func setting(info *debug.BuildInfo, key string) string {
+	for _, s := range info.Settings {
+		if s.Key == key {
+			return s.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,15 @@
+// +build !go1.18
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pluginabi
+
+// Check cannot read the build information of plugins before Go 1.18 and
+// leaves the compatibility check to plugin.Open.
+func Check(path string) error {
+	return nil
+}
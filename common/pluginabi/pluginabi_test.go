@@ -0,0 +1,83 @@
+// +build go1.18
+
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package pluginabi
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func build(goVersion, buildMode string, deps ...*debug.Module) *debug.BuildInfo {
+	info := &debug.BuildInfo{
+		GoVersion: goVersion,
+		Main:      debug.Module{Path: "github.com/hyperledger/fabric", Version: devel},
+		Deps:      deps,
+		Settings:  []debug.BuildSetting{{Key: "GOOS", Value: "linux"}, {Key: "GOARCH", Value: "amd64"}},
+	}
+	if buildMode != "" {
+		info.Settings = append(info.Settings, debug.BuildSetting{Key: "-buildmode", Value: buildMode})
+	}
+	return info
+}
+
+func TestCompare(t *testing.T) {
+	protobuf := &debug.Module{Path: "github.com/golang/protobuf", Version: "v1.3.2", Sum: "h1:abc"}
+	pkgErrors := &debug.Module{Path: "github.com/pkg/errors", Version: "v0.8.1"}
+	binary := build("go1.20.3", "exe", protobuf, pkgErrors)
+
+	assert.Empty(t, compare(binary, build("go1.20.3", "plugin", protobuf)))
+
+	// 只比较双方共同依赖的模块
+	other := &debug.Module{Path: "github.com/other/module", Version: "v1.0.0"}
+	assert.Empty(t, compare(binary, build("go1.20.3", "plugin", other, pkgErrors)))
+
+	newerProtobuf := &debug.Module{Path: "github.com/golang/protobuf", Version: "v1.4.0", Sum: "h1:def"}
+	plugin := build("go1.21.0", "exe", newerProtobuf)
+	plugin.Settings = append(plugin.Settings, debug.BuildSetting{Key: "-race", Value: "true"})
+	assert.Equal(t, []string{
+		"it was built with -buildmode=exe instead of -buildmode=plugin",
+		"it was built with go1.21.0 but the binary with go1.20.3",
+		"it was built with -race=true but the binary with -race unset",
+		"it depends on github.com/golang/protobuf v1.4.0 (h1:def) but the binary on github.com/golang/protobuf v1.3.2 (h1:abc)",
+	}, compare(binary, plugin))
+
+	replaced := &debug.Module{Path: "github.com/pkg/errors", Version: "v0.8.1", Replace: &debug.Module{Path: "../errors"}}
+	assert.Equal(t, []string{
+		"it depends on github.com/pkg/errors => ../errors but the binary on github.com/pkg/errors v0.8.1",
+	}, compare(binary, build("go1.20.3", "plugin", replaced)))
+
+	// 工作区构建的主模块不比较版本
+	released := build("go1.20.3", "plugin", &debug.Module{Path: "github.com/hyperledger/fabric", Version: "v1.2.0"})
+	assert.Empty(t, compare(binary, released))
+}
+
+func TestCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pluginabi")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plugin.so")
+	require.NoError(t, ioutil.WriteFile(path, []byte("not a plugin"), 0600))
+
+	err = Check(path)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "could not read build information of plugin "+path)
+
+	assert.Error(t, Check(filepath.Join(dir, "missing.so")))
+
+	// 测试程序与自身的构建一致，只有构建模式不同
+	executable, err := os.Executable()
+	require.NoError(t, err)
+	assert.EqualError(t, Check(executable), "plugin "+executable+" is not compatible with this binary: it was built with -buildmode=exe instead of -buildmode=plugin")
+}
@@ -0,0 +1,242 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package retry tells the clients of orderers and peers, such as the SDKs,
+// whether and how to retry a request which failed: it classifies every
+// status, broadcast rejection reason and transaction validation code, so
+// that all clients retry the same failures with the same backoff.  The
+// classification of the rejections of the Broadcast service agrees with the
+// categories of the errors of the orderer message processors.
+package retry
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	pb "github.com/hyperledger/fabric/protos/peer"
+)
+
+// Action is what a client does about the outcome of a request.
+type Action int
+
+const (
+	// Done is the action of the requests which succeeded
+	Done Action = iota
+
+	// Fail is the action of the requests which would fail again, and are
+	// reported to the application
+	Fail
+
+	// Resubmit is the action of the requests which may succeed if submitted
+	// again as they are, after a backoff
+	Resubmit
+
+	// Reendorse is the action of the transactions which may succeed if
+	// endorsed again, as a new transaction, after a backoff
+	Reendorse
+)
+
+func (a Action) String() string {
+	switch a {
+	case Done:
+		return "done"
+	case Fail:
+		return "fail"
+	case Resubmit:
+		return "resubmit"
+	case Reendorse:
+		return "reendorse"
+	default:
+		return fmt.Sprintf("Action(%d)", int(a))
+	}
+}
+
+// Backoff is the class of the delay before a request is retried.
+type Backoff int
+
+const (
+	// NoBackoff retries at once
+	NoBackoff Backoff = iota
+
+	// ShortBackoff retries once contention clears, such as load shedding and
+	// rate limiting
+	ShortBackoff
+
+	// LongBackoff retries once a component recovers, such as a consenter
+	// electing a leader or reconnecting to its brokers
+	LongBackoff
+)
+
+func (b Backoff) String() string {
+	switch b {
+	case NoBackoff:
+		return "none"
+	case ShortBackoff:
+		return "short"
+	case LongBackoff:
+		return "long"
+	default:
+		return fmt.Sprintf("Backoff(%d)", int(b))
+	}
+}
+
+// Delays returns the suggested delay before the first retry, and the
+// suggested maximum delay the delays of the following retries grow to.
+func (b Backoff) Delays() (initial, max time.Duration) {
+	switch b {
+	case ShortBackoff:
+		return 100 * time.Millisecond, 2 * time.Second
+	case LongBackoff:
+		return time.Second, 30 * time.Second
+	default:
+		return 0, 0
+	}
+}
+
+// Classification is what a client does about the outcome of a request.
+type Classification struct {
+	Action  Action
+	Backoff Backoff
+	// RetryAfter is the delay the server asked the client to wait before
+	// retrying, which overrides the initial delay of the backoff, if set
+	RetryAfter time.Duration
+}
+
+// Retryable returns whether the request may succeed if retried.
+func (c Classification) Retryable() bool {
+	return c.Action == Resubmit || c.Action == Reendorse
+}
+
+var (
+	done      = Classification{Action: Done}
+	fail      = Classification{Action: Fail}
+	transient = Classification{Action: Resubmit, Backoff: ShortBackoff}
+	outage    = Classification{Action: Resubmit, Backoff: LongBackoff}
+	conflict  = Classification{Action: Reendorse, Backoff: ShortBackoff}
+	outdated  = Classification{Action: Reendorse, Backoff: NoBackoff}
+)
+
+// statuses classifies the statuses of the responses of the orderers and of
+// the deliver services of the peers
+var statuses = map[cb.Status]Classification{
+	cb.Status_UNKNOWN:                  fail,
+	cb.Status_SUCCESS:                  done,
+	cb.Status_BAD_REQUEST:              fail,
+	cb.Status_FORBIDDEN:                fail,
+	cb.Status_NOT_FOUND:                fail,
+	cb.Status_REQUEST_ENTITY_TOO_LARGE: fail,
+	cb.Status_INTERNAL_SERVER_ERROR:    outage,
+	cb.Status_NOT_IMPLEMENTED:          fail,
+	cb.Status_SERVICE_UNAVAILABLE:      transient,
+}
+
+// reasons classifies the reasons the orderers reject broadcast messages for
+var reasons = map[ab.ErrorDetail_Code]Classification{
+	ab.ErrorDetail_MALFORMED_MESSAGE:     fail,
+	ab.ErrorDetail_INVALID_MESSAGE:       fail,
+	ab.ErrorDetail_MESSAGE_TOO_LARGE:     fail,
+	ab.ErrorDetail_CHANNEL_NOT_FOUND:     fail,
+	ab.ErrorDetail_PERMISSION_DENIED:     fail,
+	ab.ErrorDetail_RATE_LIMITED:          transient,
+	ab.ErrorDetail_OVERLOADED:            transient,
+	ab.ErrorDetail_CONSENTER_UNAVAILABLE: outage,
+	ab.ErrorDetail_ADMISSION_DENIED:      fail,
+	ab.ErrorDetail_IDENTITY_EXPIRED:      fail,
+	ab.ErrorDetail_CLIENT_BLOCKED:        fail,
+	ab.ErrorDetail_QUOTA_EXCEEDED:        fail,
+}
+
+// validationCodes classifies the validation codes of the transactions
+// committed by the peers
+var validationCodes = map[pb.TxValidationCode]Classification{
+	pb.TxValidationCode_VALID:                        done,
+	pb.TxValidationCode_NIL_ENVELOPE:                 fail,
+	pb.TxValidationCode_BAD_PAYLOAD:                  fail,
+	pb.TxValidationCode_BAD_COMMON_HEADER:            fail,
+	pb.TxValidationCode_BAD_CREATOR_SIGNATURE:        fail,
+	pb.TxValidationCode_INVALID_ENDORSER_TRANSACTION: fail,
+	pb.TxValidationCode_INVALID_CONFIG_TRANSACTION:   fail,
+	pb.TxValidationCode_UNSUPPORTED_TX_PAYLOAD:       fail,
+	pb.TxValidationCode_BAD_PROPOSAL_TXID:            fail,
+	pb.TxValidationCode_DUPLICATE_TXID:               fail,
+	pb.TxValidationCode_ENDORSEMENT_POLICY_FAILURE:   fail,
+	pb.TxValidationCode_MVCC_READ_CONFLICT:           conflict,
+	pb.TxValidationCode_PHANTOM_READ_CONFLICT:        conflict,
+	pb.TxValidationCode_UNKNOWN_TX_TYPE:              fail,
+	pb.TxValidationCode_TARGET_CHAIN_NOT_FOUND:       fail,
+	pb.TxValidationCode_MARSHAL_TX_ERROR:             fail,
+	pb.TxValidationCode_NIL_TXACTION:                 fail,
+	pb.TxValidationCode_EXPIRED_CHAINCODE:            outdated,
+	pb.TxValidationCode_CHAINCODE_VERSION_CONFLICT:   outdated,
+	pb.TxValidationCode_BAD_HEADER_EXTENSION:         fail,
+	pb.TxValidationCode_BAD_CHANNEL_HEADER:           fail,
+	pb.TxValidationCode_BAD_RESPONSE_PAYLOAD:         fail,
+	pb.TxValidationCode_BAD_RWSET:                    fail,
+	pb.TxValidationCode_ILLEGAL_WRITESET:             fail,
+	pb.TxValidationCode_INVALID_WRITESET:             fail,
+	pb.TxValidationCode_NOT_VALIDATED:                fail,
+	pb.TxValidationCode_INVALID_OTHER_REASON:         fail,
+}
+
+// Status classifies a status of an orderer, or of the deliver service of a
+// peer.  The statuses this package does not know are classified by their
+// class, as HTTP status codes are.
+func Status(status cb.Status) Classification {
+	if c, ok := statuses[status]; ok {
+		return c
+	}
+	switch {
+	case status >= 500:
+		return outage
+	case status >= 200 && status < 300:
+		return done
+	default:
+		return fail
+	}
+}
+
+// Reason classifies a reason an orderer rejects broadcast messages for, and
+// returns false for the reasons this package does not know, such as
+// UNSPECIFIED, which are classified by the status of the response.
+func Reason(code ab.ErrorDetail_Code) (Classification, bool) {
+	c, ok := reasons[code]
+	return c, ok
+}
+
+// Broadcast classifies a response of the Broadcast service: by the reason of
+// the rejection if it is known, and otherwise by its status.  The response
+// carries the delay the orderer asks the client to wait, if any.
+func Broadcast(resp *ab.BroadcastResponse) Classification {
+	detail := resp.GetErrorDetail()
+	c, ok := Reason(detail.GetCode())
+	if !ok {
+		c = Status(resp.GetStatus())
+	}
+	if c.Retryable() && detail.GetRetryAfter() != nil {
+		if wait, err := ptypes.Duration(detail.RetryAfter); err == nil && wait > 0 {
+			c.RetryAfter = wait
+		}
+	}
+	return c
+}
+
+// Deliver classifies the status a Deliver stream of an orderer or of a peer
+// ends with.
+func Deliver(status cb.Status) Classification {
+	return Status(status)
+}
+
+// Validation classifies the validation code of a transaction committed by a
+// peer.  The validation codes this package does not know fail.
+func Validation(code pb.TxValidationCode) Classification {
+	if c, ok := validationCodes[code]; ok {
+		return c
+	}
+	return fail
+}
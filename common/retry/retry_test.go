@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package retry
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestEveryValueClassified fails when a status, a reason or a validation code
+// is added without being classified
+func TestEveryValueClassified(t *testing.T) {
+	for value, name := range cb.Status_name {
+		assert.Contains(t, statuses, cb.Status(value), "status %s is not classified", name)
+	}
+	for value, name := range ab.ErrorDetail_Code_name {
+		if ab.ErrorDetail_Code(value) == ab.ErrorDetail_UNSPECIFIED {
+			continue
+		}
+		assert.Contains(t, reasons, ab.ErrorDetail_Code(value), "reason %s is not classified", name)
+	}
+	for value, name := range pb.TxValidationCode_name {
+		assert.Contains(t, validationCodes, pb.TxValidationCode(value), "validation code %s is not classified", name)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	assert.Equal(t, Done, Status(cb.Status_SUCCESS).Action)
+	assert.False(t, Status(cb.Status_FORBIDDEN).Retryable())
+	assert.Equal(t, Classification{Action: Resubmit, Backoff: ShortBackoff}, Status(cb.Status_SERVICE_UNAVAILABLE))
+
+	// the unknown statuses are classified by their class
+	assert.Equal(t, Classification{Action: Resubmit, Backoff: LongBackoff}, Status(cb.Status(502)))
+	assert.Equal(t, Fail, Status(cb.Status(429)).Action)
+	assert.Equal(t, Done, Status(cb.Status(202)).Action)
+
+	assert.Equal(t, Status(cb.Status_NOT_FOUND), Deliver(cb.Status_NOT_FOUND))
+}
+
+func TestBroadcast(t *testing.T) {
+	assert.Equal(t, Done, Broadcast(&ab.BroadcastResponse{Status: cb.Status_SUCCESS}).Action)
+
+	// the reason takes precedence over the status
+	c := Broadcast(&ab.BroadcastResponse{
+		Status:      cb.Status_SERVICE_UNAVAILABLE,
+		ErrorDetail: &ab.ErrorDetail{Code: ab.ErrorDetail_CONSENTER_UNAVAILABLE},
+	})
+	assert.Equal(t, Classification{Action: Resubmit, Backoff: LongBackoff}, c)
+
+	// the unspecified and unknown reasons are classified by the status
+	c = Broadcast(&ab.BroadcastResponse{
+		Status:      cb.Status_BAD_REQUEST,
+		ErrorDetail: &ab.ErrorDetail{Code: ab.ErrorDetail_Code(1000)},
+	})
+	assert.Equal(t, Fail, c.Action)
+	assert.Equal(t, Status(cb.Status_SERVICE_UNAVAILABLE), Broadcast(&ab.BroadcastResponse{Status: cb.Status_SERVICE_UNAVAILABLE}))
+
+	// the retry-after hint is carried by the retryable rejections
+	c = Broadcast(&ab.BroadcastResponse{
+		Status:      cb.Status_SERVICE_UNAVAILABLE,
+		ErrorDetail: &ab.ErrorDetail{Code: ab.ErrorDetail_RATE_LIMITED, RetryAfter: ptypes.DurationProto(3 * time.Second)},
+	})
+	assert.Equal(t, Classification{Action: Resubmit, Backoff: ShortBackoff, RetryAfter: 3 * time.Second}, c)
+	c = Broadcast(&ab.BroadcastResponse{
+		Status:      cb.Status_FORBIDDEN,
+		ErrorDetail: &ab.ErrorDetail{Code: ab.ErrorDetail_CLIENT_BLOCKED, RetryAfter: ptypes.DurationProto(time.Minute)},
+	})
+	assert.Equal(t, fail, c)
+}
+
+func TestValidation(t *testing.T) {
+	assert.Equal(t, Done, Validation(pb.TxValidationCode_VALID).Action)
+	assert.Equal(t, Classification{Action: Reendorse, Backoff: ShortBackoff}, Validation(pb.TxValidationCode_MVCC_READ_CONFLICT))
+	assert.Equal(t, Reendorse, Validation(pb.TxValidationCode_CHAINCODE_VERSION_CONFLICT).Action)
+	assert.False(t, Validation(pb.TxValidationCode_DUPLICATE_TXID).Retryable())
+	assert.Equal(t, Fail, Validation(pb.TxValidationCode(100)).Action)
+}
+
+func TestBackoffDelays(t *testing.T) {
+	initial, max := NoBackoff.Delays()
+	assert.Zero(t, initial)
+	assert.Zero(t, max)
+	for _, b := range []Backoff{ShortBackoff, LongBackoff} {
+		initial, max := b.Delays()
+		assert.True(t, initial > 0 && initial < max, "backoff %s", b)
+	}
+	assert.Equal(t, "Backoff(7)", Backoff(7).String())
+	assert.Equal(t, "reendorse", Reendorse.String())
+}
@@ -0,0 +1,277 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package archive reads and writes block archives, portable tar files holding
+// a range of consecutive blocks of a channel, so that blocks can be carried
+// from one node to another without a network connection between them.
+//
+// An archive holds one entry per block, named after the block number, followed
+// by a manifest listing the SHA256 hash of every block entry and a signature
+// of the manifest.  The manifest comes last so that an archive can be written
+// while walking a ledger; consequently an archive is only trusted once it has
+// been read to the end.
+package archive
+
+import (
+	"archive/tar"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/util"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+const (
+	// FormatVersion is the version of the archive format written by this package
+	FormatVersion = 1
+
+	// ManifestName is the name of the manifest entry of an archive
+	ManifestName = "manifest.json"
+
+	// SignatureName is the name of the manifest signature entry of an archive
+	SignatureName = "manifest.sig"
+
+	blockPrefix = "blocks/"
+
+	// maxEntrySize bounds the size of any entry read from an archive
+	maxEntrySize = 100 * 1024 * 1024
+)
+
+// BlockEntry describes a block of an archive.
+type BlockEntry struct {
+	Number uint64 `json:"number"`
+	Hash   string `json:"hash"`
+}
+
+// Manifest describes the content of an archive.
+type Manifest struct {
+	Version   int          `json:"version"`
+	ChannelID string       `json:"channel_id"`
+	Created   time.Time    `json:"created"`
+	Blocks    []BlockEntry `json:"blocks"`
+}
+
+// First returns the number of the first block of the archive
+func (m *Manifest) First() uint64 {
+	return m.Blocks[0].Number
+}
+
+// Last returns the number of the last block of the archive
+func (m *Manifest) Last() uint64 {
+	return m.Blocks[len(m.Blocks)-1].Number
+}
+
+// SignedData returns the manifest signature in the form expected by policies
+func SignedData(manifestBytes []byte, signature *cb.MetadataSignature) (*cb.SignedData, error) {
+	shdr, err := utils.GetSignatureHeader(signature.SignatureHeader)
+	if err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling signature header of manifest")
+	}
+	return &cb.SignedData{
+		Data:      util.ConcatenateBytes(manifestBytes, signature.SignatureHeader),
+		Identity:  shdr.Creator,
+		Signature: signature.Signature,
+	}, nil
+}
+
+// Writer writes the blocks of a channel to an archive.
+type Writer struct {
+	tw       *tar.Writer
+	signer   crypto.LocalSigner
+	manifest *Manifest
+	now      func() time.Time
+}
+
+// NewWriter returns a Writer of an archive of the blocks of the channel, whose
+// manifest is signed by signer
+func NewWriter(w io.Writer, channelID string, signer crypto.LocalSigner) *Writer {
+	return &Writer{
+		tw:     tar.NewWriter(w),
+		signer: signer,
+		manifest: &Manifest{
+			Version:   FormatVersion,
+			ChannelID: channelID,
+		},
+		now: time.Now,
+	}
+}
+
+// Append adds a block to the archive.  Blocks must be appended in order and
+// without gaps.
+func (w *Writer) Append(block *cb.Block) error {
+	if block.GetHeader() == nil {
+		return errors.New("block has no header")
+	}
+	number := block.Header.Number
+	if n := len(w.manifest.Blocks); n > 0 && number != w.manifest.Blocks[n-1].Number+1 {
+		return errors.Errorf("block %d appended after block %d", number, w.manifest.Blocks[n-1].Number)
+	}
+
+	blockBytes, err := proto.Marshal(block)
+	if err != nil {
+		return errors.Wrapf(err, "error marshaling block %d", number)
+	}
+	if err := w.writeEntry(blockEntryName(number), blockBytes); err != nil {
+		return err
+	}
+	digest := sha256.Sum256(blockBytes)
+	w.manifest.Blocks = append(w.manifest.Blocks, BlockEntry{Number: number, Hash: hex.EncodeToString(digest[:])})
+	return nil
+}
+
+// Close signs the manifest and completes the archive.  It does not close the
+// underlying writer.
+func (w *Writer) Close() error {
+	if len(w.manifest.Blocks) == 0 {
+		return errors.New("archive has no blocks")
+	}
+	w.manifest.Created = w.now().UTC()
+	manifestBytes, err := json.MarshalIndent(w.manifest, "", "\t")
+	if err != nil {
+		return errors.Wrap(err, "error marshaling manifest")
+	}
+
+	shdr, err := w.signer.NewSignatureHeader()
+	if err != nil {
+		return errors.Wrap(err, "error creating signature header")
+	}
+	shdrBytes := utils.MarshalOrPanic(shdr)
+	signature, err := w.signer.Sign(util.ConcatenateBytes(manifestBytes, shdrBytes))
+	if err != nil {
+		return errors.Wrap(err, "error signing manifest")
+	}
+	signatureBytes := utils.MarshalOrPanic(&cb.MetadataSignature{
+		SignatureHeader: shdrBytes,
+		Signature:       signature,
+	})
+
+	if err := w.writeEntry(ManifestName, manifestBytes); err != nil {
+		return err
+	}
+	if err := w.writeEntry(SignatureName, signatureBytes); err != nil {
+		return err
+	}
+	return errors.Wrap(w.tw.Close(), "error completing archive")
+}
+
+// Manifest returns the manifest of the blocks appended so far
+func (w *Writer) Manifest() *Manifest {
+	return w.manifest
+}
+
+func (w *Writer) writeEntry(name string, data []byte) error {
+	err := w.tw.WriteHeader(&tar.Header{
+		Name:     name,
+		Mode:     0644,
+		Size:     int64(len(data)),
+		ModTime:  w.now(),
+		Typeflag: tar.TypeReg,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error writing header of %s", name)
+	}
+	_, err = w.tw.Write(data)
+	return errors.Wrapf(err, "error writing %s", name)
+}
+
+func blockEntryName(number uint64) string {
+	return fmt.Sprintf("%s%020d.block", blockPrefix, number)
+}
+
+// Contents is the manifest and manifest signature of an archive.
+type Contents struct {
+	Manifest      *Manifest
+	ManifestBytes []byte
+	Signature     *cb.MetadataSignature
+}
+
+// Read reads an archive, and calls fn on each of its blocks in order.  Once
+// the end of the archive is reached, Read checks that the blocks read are
+// exactly those listed by the manifest, and returns the manifest.  The manifest
+// signature is returned but not verified; since the blocks are only known to
+// match the manifest once the archive has been read to the end, fn must not act
+// on the blocks before Read returns successfully.
+func Read(r io.Reader, fn func(block *cb.Block) error) (*Contents, error) {
+	tr := tar.NewReader(r)
+	var blocks []BlockEntry
+	var contents Contents
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, errors.Wrap(err, "error reading archive")
+		}
+		if hdr.Size > maxEntrySize {
+			return nil, errors.Errorf("entry %s of %d bytes exceeds the maximum of %d bytes", hdr.Name, hdr.Size, maxEntrySize)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error reading %s", hdr.Name)
+		}
+
+		switch {
+		case hdr.Name == ManifestName:
+			contents.ManifestBytes = data
+		case hdr.Name == SignatureName:
+			contents.Signature = &cb.MetadataSignature{}
+			if err := proto.Unmarshal(data, contents.Signature); err != nil {
+				return nil, errors.Wrap(err, "error unmarshaling manifest signature")
+			}
+		case contents.ManifestBytes != nil || contents.Signature != nil:
+			return nil, errors.Errorf("unexpected entry %s after the manifest", hdr.Name)
+		default:
+			block, err := utils.GetBlockFromBlockBytes(data)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error unmarshaling %s", hdr.Name)
+			}
+			if block.Header == nil || hdr.Name != blockEntryName(block.Header.Number) {
+				return nil, errors.Errorf("entry %s does not hold the block it is named after", hdr.Name)
+			}
+			digest := sha256.Sum256(data)
+			blocks = append(blocks, BlockEntry{Number: block.Header.Number, Hash: hex.EncodeToString(digest[:])})
+			if err := fn(block); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if contents.ManifestBytes == nil {
+		return nil, errors.New("archive has no manifest")
+	}
+	if contents.Signature == nil {
+		return nil, errors.New("archive has no manifest signature")
+	}
+	contents.Manifest = &Manifest{}
+	if err := json.Unmarshal(contents.ManifestBytes, contents.Manifest); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling manifest")
+	}
+	if contents.Manifest.Version != FormatVersion {
+		return nil, errors.Errorf("unsupported archive format version %d", contents.Manifest.Version)
+	}
+	if len(contents.Manifest.Blocks) == 0 {
+		return nil, errors.New("manifest lists no blocks")
+	}
+	if len(blocks) != len(contents.Manifest.Blocks) {
+		return nil, errors.Errorf("manifest lists %d blocks but the archive holds %d", len(contents.Manifest.Blocks), len(blocks))
+	}
+	for i, entry := range contents.Manifest.Blocks {
+		if entry != blocks[i] {
+			return nil, errors.Errorf("block %d of the archive does not match the manifest", blocks[i].Number)
+		}
+	}
+	return &contents, nil
+}
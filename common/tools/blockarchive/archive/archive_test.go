@@ -0,0 +1,313 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/blocksig"
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	ramledger "github.com/hyperledger/fabric/common/ledger/blockledger/ram"
+	"github.com/hyperledger/fabric/common/localmsp"
+	mockcrypto "github.com/hyperledger/fabric/common/mocks/crypto"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/configtxgentest"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/encoder"
+	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	"github.com/hyperledger/fabric/common/util"
+	msptesttools "github.com/hyperledger/fabric/msp/mgmt/testtools"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const channelID = "testchannel"
+
+func TestMain(m *testing.M) {
+	if err := msptesttools.LoadDevMsp(); err != nil {
+		fmt.Printf("Failed to load dev MSP: %s\n", err)
+		os.Exit(-1)
+	}
+	os.Exit(m.Run())
+}
+
+func newLedger() blockledger.ReadWriter {
+	rl, _ := ramledger.New(10).GetOrCreate(channelID)
+	return rl
+}
+
+// signBlock signs the block as an orderer would, recording the genesis block
+// as its last config
+func signBlock(block *cb.Block, signer crypto.LocalSigner) {
+	value := utils.MarshalOrPanic(&cb.LastConfig{Index: 0})
+	block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{Value: value})
+
+	shdr, _ := signer.NewSignatureHeader()
+	shdrBytes := utils.MarshalOrPanic(shdr)
+	signature, _ := signer.Sign(util.ConcatenateBytes(value, shdrBytes, block.Header.Bytes()))
+	block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{
+		Value:      value,
+		Signatures: []*cb.MetadataSignature{{SignatureHeader: shdrBytes, Signature: signature}},
+	})
+}
+
+// signBatch signs the blocks in a batch as an orderer signing blocks in
+// batches would, recording the genesis block as their last config
+func signBatch(blocks []*cb.Block, signer crypto.LocalSigner) {
+	var leaves [][]byte
+	for _, block := range blocks {
+		block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{
+			Value: utils.MarshalOrPanic(&cb.LastConfig{Index: 0}),
+		})
+		leaf, _ := blocksig.Leaf(block)
+		leaves = append(leaves, leaf)
+	}
+	shdr, _ := signer.NewSignatureHeader()
+	shdrBytes := utils.MarshalOrPanic(shdr)
+	signature, _ := signer.Sign(blocksig.SignedBytes(blocksig.Root(leaves), shdrBytes))
+	for _, block := range blocks {
+		block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{
+			Value:      blocksig.BatchValue(&cb.BlockSignatureBatch{FirstBlock: blocks[0].Header.Number, LeafHashes: leaves}),
+			Signatures: []*cb.MetadataSignature{{SignatureHeader: shdrBytes, Signature: signature}},
+		})
+	}
+}
+
+// newSourceLedger returns a ledger holding the genesis block followed by
+// three blocks signed by the local MSP identity.  The genesis block has an
+// orderer org, so that the block validation policy requires its signature.
+func newSourceLedger(t *testing.T) blockledger.ReadWriter {
+	rl := newLedger()
+	genesisBlock := encoder.New(configtxgentest.Load(genesisconfig.SampleDevModeSoloProfile)).GenesisBlockForChannel(channelID)
+	require.NoError(t, rl.Append(genesisBlock))
+	for i := 0; i < 3; i++ {
+		env := &cb.Envelope{Payload: []byte(fmt.Sprintf("tx%d", i))}
+		block := blockledger.CreateNextBlock(rl, []*cb.Envelope{env})
+		signBlock(block, localmsp.NewSigner())
+		require.NoError(t, rl.Append(block))
+	}
+	return rl
+}
+
+func export(t *testing.T, source blockledger.Reader, first, last uint64, signer crypto.LocalSigner) []byte {
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf, channelID, signer)
+	for number := first; number <= last; number++ {
+		require.NoError(t, w.Append(blockledger.GetBlock(source, number)))
+	}
+	require.NoError(t, w.Close())
+	return buf.Bytes()
+}
+
+// rewrite copies the archive, passing the data of every entry through fn,
+// and dropping the entries for which fn returns nil
+func rewrite(t *testing.T, archive []byte, fn func(name string, data []byte) []byte) []byte {
+	tr := tar.NewReader(bytes.NewReader(archive))
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		data, err := ioutil.ReadAll(tr)
+		require.NoError(t, err)
+		if data = fn(hdr.Name, data); data == nil {
+			continue
+		}
+		hdr.Size = int64(len(data))
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err = tw.Write(data)
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestWriteRead(t *testing.T) {
+	source := newSourceLedger(t)
+
+	w := NewWriter(ioutil.Discard, channelID, mockcrypto.FakeLocalSigner)
+	assert.EqualError(t, w.Close(), "archive has no blocks")
+	require.NoError(t, w.Append(blockledger.GetBlock(source, 1)))
+	assert.EqualError(t, w.Append(blockledger.GetBlock(source, 3)), "block 3 appended after block 1")
+
+	archive := export(t, source, 1, 3, mockcrypto.FakeLocalSigner)
+	var numbers []uint64
+	contents, err := Read(bytes.NewReader(archive), func(block *cb.Block) error {
+		numbers = append(numbers, block.Header.Number)
+		return nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1, 2, 3}, numbers)
+	assert.Equal(t, channelID, contents.Manifest.ChannelID)
+	assert.Equal(t, uint64(1), contents.Manifest.First())
+	assert.Equal(t, uint64(3), contents.Manifest.Last())
+
+	signedData, err := SignedData(contents.ManifestBytes, contents.Signature)
+	require.NoError(t, err)
+	assert.Equal(t, mockcrypto.FakeLocalSigner.Identity, signedData.Identity)
+	assert.Equal(t, signedData.Data, signedData.Signature)
+}
+
+func TestReadTampered(t *testing.T) {
+	source := newSourceLedger(t)
+	archive := export(t, source, 1, 3, mockcrypto.FakeLocalSigner)
+	otherBlock := utils.MarshalOrPanic(blockledger.GetBlock(newSourceLedger(t), 2))
+
+	tampered := rewrite(t, archive, func(name string, data []byte) []byte {
+		if name == blockEntryName(2) {
+			return otherBlock
+		}
+		return data
+	})
+	_, err := Read(bytes.NewReader(tampered), func(*cb.Block) error { return nil })
+	assert.EqualError(t, err, "block 2 of the archive does not match the manifest")
+
+	misnamed := rewrite(t, archive, func(name string, data []byte) []byte {
+		if name == blockEntryName(2) {
+			return utils.MarshalOrPanic(blockledger.GetBlock(source, 3))
+		}
+		return data
+	})
+	_, err = Read(bytes.NewReader(misnamed), func(*cb.Block) error { return nil })
+	assert.EqualError(t, err, "entry blocks/00000000000000000002.block does not hold the block it is named after")
+
+	unsigned := rewrite(t, archive, func(name string, data []byte) []byte {
+		if name == SignatureName {
+			return nil
+		}
+		return data
+	})
+	_, err = Read(bytes.NewReader(unsigned), func(*cb.Block) error { return nil })
+	assert.EqualError(t, err, "archive has no manifest signature")
+}
+
+func TestImport(t *testing.T) {
+	source := newSourceLedger(t)
+	archive := export(t, source, 0, 3, localmsp.NewSigner())
+
+	target := newLedger()
+	manifest, err := Import(bytes.NewReader(archive), channelID, target)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), manifest.Last())
+	assert.Equal(t, uint64(4), target.Height())
+	for number := uint64(0); number < 4; number++ {
+		assert.True(t, proto.Equal(blockledger.GetBlock(source, number), blockledger.GetBlock(target, number)), "block %d differs", number)
+	}
+}
+
+func TestImportExtendsLedger(t *testing.T) {
+	source := newSourceLedger(t)
+	target := newLedger()
+	require.NoError(t, target.Append(blockledger.GetBlock(source, 0)))
+
+	// the archive must start at the height of the ledger
+	_, err := Import(bytes.NewReader(export(t, source, 2, 3, localmsp.NewSigner())), channelID, target)
+	assert.EqualError(t, err, "expected block 1 but got block 2")
+	assert.Equal(t, uint64(1), target.Height())
+
+	_, err = Import(bytes.NewReader(export(t, source, 1, 3, localmsp.NewSigner())), channelID, target)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), target.Height())
+
+	// blocks of another chain are not chained to the ledger
+	other := newSourceLedger(t)
+	_, err = Verify(bytes.NewReader(export(t, other, 0, 3, localmsp.NewSigner())), channelID, newLedger())
+	require.NoError(t, err)
+	target = newLedger()
+	require.NoError(t, target.Append(blockledger.GetBlock(source, 0)))
+	_, err = Verify(bytes.NewReader(export(t, other, 1, 3, localmsp.NewSigner())), channelID, target)
+	assert.EqualError(t, err, "block 1 is not chained to block 0")
+}
+
+func TestImportSignatures(t *testing.T) {
+	source := newSourceLedger(t)
+
+	// the manifest must be signed by an identity entitled to sign blocks
+	_, err := Verify(bytes.NewReader(export(t, source, 0, 3, mockcrypto.FakeLocalSigner)), channelID, newLedger())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "manifest signature does not satisfy the block validation policy")
+
+	// and so must the blocks
+	forged := proto.Clone(blockledger.GetBlock(source, 1)).(*cb.Block)
+	signBlock(forged, mockcrypto.FakeLocalSigner)
+	buf := &bytes.Buffer{}
+	w := NewWriter(buf, channelID, localmsp.NewSigner())
+	require.NoError(t, w.Append(blockledger.GetBlock(source, 0)))
+	require.NoError(t, w.Append(forged))
+	require.NoError(t, w.Close())
+	target := newLedger()
+	_, err = Import(bytes.NewReader(buf.Bytes()), channelID, target)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "signatures of block 1 do not satisfy the block validation policy")
+	assert.Equal(t, uint64(0), target.Height(), "no block should be imported from an invalid archive")
+
+	_, err = Verify(bytes.NewReader(export(t, source, 0, 3, localmsp.NewSigner())), "otherchannel", newLedger())
+	assert.EqualError(t, err, "archive holds blocks of channel testchannel, not otherchannel")
+}
+
+func TestVerifyBatchSigned(t *testing.T) {
+	source := newLedger()
+	genesisBlock := encoder.New(configtxgentest.Load(genesisconfig.SampleDevModeSoloProfile)).GenesisBlockForChannel(channelID)
+	require.NoError(t, source.Append(genesisBlock))
+	var blocks []*cb.Block
+	previousHash := genesisBlock.Header.Hash()
+	for i := 0; i < 3; i++ {
+		block := cb.NewBlock(uint64(i+1), previousHash)
+		block.Data.Data = [][]byte{utils.MarshalOrPanic(&cb.Envelope{Payload: []byte(fmt.Sprintf("tx%d", i))})}
+		block.Header.DataHash = block.Data.Hash()
+		previousHash = block.Header.Hash()
+		blocks = append(blocks, block)
+	}
+
+	// the blocks are each verified on their own
+	signBatch(blocks, localmsp.NewSigner())
+	verifier, err := NewVerifier(channelID, newLedger())
+	require.NoError(t, err)
+	require.NoError(t, verifier.Verify(genesisBlock))
+	for _, block := range blocks {
+		assert.NoError(t, verifier.Verify(block))
+	}
+
+	signBatch(blocks, mockcrypto.FakeLocalSigner)
+	verifier, err = NewVerifier(channelID, newLedger())
+	require.NoError(t, err)
+	require.NoError(t, verifier.Verify(genesisBlock))
+	err = verifier.Verify(blocks[0])
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "signatures of block 1 do not satisfy the block validation policy")
+}
+
+func TestConfigVerifier(t *testing.T) {
+	source := newSourceLedger(t)
+	configEnv, err := utils.ExtractEnvelope(blockledger.GetBlock(source, 0), 0)
+	require.NoError(t, err)
+	configBlock := blockledger.CreateNextBlock(source, []*cb.Envelope{configEnv})
+	signBlock(configBlock, localmsp.NewSigner())
+	require.NoError(t, source.Append(configBlock))
+	block := blockledger.CreateNextBlock(source, []*cb.Envelope{{Payload: []byte("tx4")}})
+	signBlock(block, localmsp.NewSigner())
+	require.NoError(t, source.Append(block))
+
+	verifier := NewConfigVerifier(channelID, 4)
+	assert.EqualError(t, verifier.Verify(blockledger.GetBlock(source, 3)), "expected block 4 but got block 3")
+	require.NoError(t, verifier.Verify(configBlock), "the blocks before the config block are not needed")
+	assert.NoError(t, verifier.Verify(block))
+
+	verifier = NewConfigVerifier(channelID, 1)
+	assert.EqualError(t, verifier.Verify(blockledger.GetBlock(source, 1)), "first block 1 of channel testchannel is not a config block")
+}
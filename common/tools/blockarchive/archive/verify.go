@@ -0,0 +1,224 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package archive
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/hyperledger/fabric/common/blocksig"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/configtx"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	"github.com/hyperledger/fabric/common/policies"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+// Verifier checks that blocks extend the ledger of a channel: each block must
+// follow the previous one, be chained to it by hash, and carry signatures
+// satisfying the BlockValidation policy of the channel config in effect, be it
+// signed on its own or in a batch.  The config blocks verified update the config
+// against which the following blocks are verified.
+type Verifier struct {
+	channelID    string
+	next         uint64
+	previousHash []byte
+	bundle       *channelconfig.Bundle
+}
+
+// NewVerifier returns a Verifier of the blocks extending the given ledger of
+// the channel.  If the ledger is empty, the first block verified must be the
+// genesis block, whose config is trusted as when a node is bootstrapped from it.
+func NewVerifier(channelID string, ledger blockledger.Reader) (*Verifier, error) {
+	v := &Verifier{channelID: channelID}
+	height := ledger.Height()
+	if height == 0 {
+		return v, nil
+	}
+
+	lastBlock := blockledger.GetBlock(ledger, height-1)
+	if lastBlock == nil {
+		return nil, errors.Errorf("could not read block %d of the ledger", height-1)
+	}
+	index, err := utils.GetLastConfigIndexFromBlock(lastBlock)
+	if err != nil {
+		return nil, errors.Wrapf(err, "block %d of the ledger has no last config index", height-1)
+	}
+	configBlock := blockledger.GetBlock(ledger, index)
+	if configBlock == nil {
+		return nil, errors.Errorf("could not read config block %d of the ledger", index)
+	}
+	config, err := configFromBlock(configBlock)
+	if err != nil {
+		return nil, err
+	}
+	if config == nil {
+		return nil, errors.Errorf("block %d referenced as the last config block is not a config block", index)
+	}
+	if v.bundle, err = channelconfig.NewBundle(channelID, config); err != nil {
+		return nil, errors.Wrapf(err, "error loading config of block %d", index)
+	}
+	v.next = height
+	v.previousHash = lastBlock.Header.Hash()
+	return v, nil
+}
+
+// NewConfigVerifier returns a Verifier of the blocks of the channel from the
+// config block of the given number on, such as the blocks of a snapshot.  The
+// first block verified must be that config block, whose config is trusted as
+// that of a genesis block: the caller is expected to have checked it against
+// a config block obtained from a trusted source.
+func NewConfigVerifier(channelID string, configBlockNumber uint64) *Verifier {
+	return &Verifier{channelID: channelID, next: configBlockNumber}
+}
+
+// Verify checks the next block
+func (v *Verifier) Verify(block *cb.Block) error {
+	if block.GetHeader() == nil || block.GetData() == nil {
+		return errors.New("block is missing its header or data")
+	}
+	number := block.Header.Number
+	if number != v.next {
+		return errors.Errorf("expected block %d but got block %d", v.next, number)
+	}
+	if !bytes.Equal(block.Header.DataHash, block.Data.Hash()) {
+		return errors.Errorf("data hash of block %d does not match its data", number)
+	}
+	if v.previousHash != nil && !bytes.Equal(block.Header.PreviousHash, v.previousHash) {
+		return errors.Errorf("block %d is not chained to block %d", number, number-1)
+	}
+
+	// 创世区块（或快照的首个配置区块）的签名不验证，其配置作为信任的起点
+	if v.bundle != nil {
+		signatureSet, err := blocksig.SignedData(block)
+		if err != nil {
+			return err
+		}
+		if err := v.evaluate(signatureSet); err != nil {
+			return errors.Wrapf(err, "signatures of block %d do not satisfy the block validation policy", number)
+		}
+	}
+
+	config, err := configFromBlock(block)
+	if err != nil {
+		return err
+	}
+	if config != nil {
+		bundle, err := channelconfig.NewBundle(v.channelID, config)
+		if err != nil {
+			return errors.Wrapf(err, "error loading config of block %d", number)
+		}
+		v.bundle = bundle
+	}
+	if v.bundle == nil {
+		if number > 0 {
+			return errors.Errorf("first block %d of channel %s is not a config block", number, v.channelID)
+		}
+		return errors.Errorf("genesis block of channel %s is not a config block", v.channelID)
+	}
+
+	v.next++
+	v.previousHash = block.Header.Hash()
+	return nil
+}
+
+// VerifySignature checks that the manifest of an archive is signed by an
+// identity satisfying the BlockValidation policy of the config in effect after
+// the last block verified, that is an identity entitled to sign the blocks
+func (v *Verifier) VerifySignature(contents *Contents) error {
+	if v.bundle == nil {
+		return errors.New("no block verified")
+	}
+	signedData, err := SignedData(contents.ManifestBytes, contents.Signature)
+	if err != nil {
+		return err
+	}
+	return errors.Wrap(v.evaluate([]*cb.SignedData{signedData}), "manifest signature does not satisfy the block validation policy")
+}
+
+func (v *Verifier) evaluate(signatureSet []*cb.SignedData) error {
+	policy, ok := v.bundle.PolicyManager().GetPolicy(policies.BlockValidation)
+	if !ok {
+		return errors.Errorf("channel %s has no %s policy", v.channelID, policies.BlockValidation)
+	}
+	return policy.Evaluate(signatureSet)
+}
+
+// configFromBlock returns the config carried by the block, or nil if the block
+// is not a config block
+func configFromBlock(block *cb.Block) (*cb.Config, error) {
+	if len(block.GetData().GetData()) != 1 {
+		return nil, nil
+	}
+	env, err := utils.ExtractEnvelope(block, 0)
+	if err != nil {
+		return nil, nil
+	}
+	payload, err := utils.UnmarshalPayload(env.Payload)
+	if err != nil || payload.Header == nil {
+		return nil, nil
+	}
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil || cb.HeaderType(chdr.Type) != cb.HeaderType_CONFIG {
+		return nil, nil
+	}
+	configEnvelope, err := configtx.UnmarshalConfigEnvelope(payload.Data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error unmarshaling config envelope of block %d", block.Header.Number)
+	}
+	return configEnvelope.Config, nil
+}
+
+// Verify reads the archive and checks that its blocks extend the ledger of the
+// channel and that its manifest is signed by an identity entitled to sign them
+func Verify(r io.Reader, channelID string, ledger blockledger.Reader) (*Manifest, error) {
+	verifier, err := NewVerifier(channelID, ledger)
+	if err != nil {
+		return nil, err
+	}
+	contents, err := Read(r, verifier.Verify)
+	if err != nil {
+		return nil, err
+	}
+	if contents.Manifest.ChannelID != channelID {
+		return nil, errors.Errorf("archive holds blocks of channel %s, not %s", contents.Manifest.ChannelID, channelID)
+	}
+	if err := verifier.VerifySignature(contents); err != nil {
+		return nil, err
+	}
+	return contents.Manifest, nil
+}
+
+// Import appends the blocks of the archive to the ledger of the channel.  The
+// archive is verified in full before any block is appended, and each block is
+// checked again as it is appended, so the archive is read twice.
+func Import(r io.ReadSeeker, channelID string, ledger blockledger.ReadWriter) (*Manifest, error) {
+	manifest, err := Verify(r, channelID, ledger)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, errors.Wrap(err, "error rewinding archive")
+	}
+
+	verifier, err := NewVerifier(channelID, ledger)
+	if err != nil {
+		return nil, err
+	}
+	_, err = Read(r, func(block *cb.Block) error {
+		if err := verifier.Verify(block); err != nil {
+			return err
+		}
+		return errors.Wrapf(ledger.Append(block), "error appending block %d", block.Header.Number)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return manifest, nil
+}
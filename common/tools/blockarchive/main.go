@@ -0,0 +1,174 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"github.com/hyperledger/fabric/common/crypto"
+	fileledger "github.com/hyperledger/fabric/common/ledger/blockledger/file"
+	ramledger "github.com/hyperledger/fabric/common/ledger/blockledger/ram"
+	"github.com/hyperledger/fabric/common/localmsp"
+	"github.com/hyperledger/fabric/common/tools/blockarchive/archive"
+	"github.com/hyperledger/fabric/common/tools/ledgerexplorer/explorer"
+	mspmgmt "github.com/hyperledger/fabric/msp/mgmt"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	cb "github.com/hyperledger/fabric/protos/common"
+
+	"github.com/pkg/errors"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// command line flags
+var (
+	app = kingpin.New("blockarchive", "Utility for transferring blocks between stopped orderers through signed archives")
+
+	path = app.Flag("path", "The block storage directory, defaults to the FileLedger.Location of the orderer config.").String()
+
+	export          = app.Command("export", "Writes a range of blocks of a channel to an archive signed by the local MSP identity.")
+	exportChannelID = export.Flag("channelID", "The channel whose blocks are exported.").Required().String()
+	exportStart     = export.Flag("start", "The first block to export.").Default("0").Uint64()
+	exportStop      = export.Flag("stop", "The last block to export, defaults to the last block of the ledger.").Default(fmt.Sprint(uint64(math.MaxUint64))).Uint64()
+	exportOutput    = export.Flag("output", "The archive file to write.").Required().String()
+
+	verify          = app.Command("verify", "Checks that an archive extends the ledger of its channel, without importing it.")
+	verifyChannelID = verify.Flag("channelID", "The channel the archive holds blocks of.").Required().String()
+	verifyInput     = verify.Flag("input", "The archive file to read.").Required().ExistingFile()
+
+	importCmd       = app.Command("import", "Verifies an archive and appends its blocks to the ledger of its channel.")
+	importChannelID = importCmd.Flag("channelID", "The channel the archive holds blocks of.").Required().String()
+	importInput     = importCmd.Flag("input", "The archive file to read.").Required().ExistingFile()
+)
+
+// errDone ends the walk of the ledger once the last block is exported
+var errDone = errors.New("done")
+
+func main() {
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	conf, err := localconfig.Load()
+	if err != nil {
+		app.Fatalf("Failed to load config: %s", err)
+	}
+	if *path == "" {
+		*path = conf.FileLedger.Location
+	}
+
+	// The local MSP signs the exported archives, and provides the crypto
+	// used to verify the imported ones
+	err = mspmgmt.LoadLocalMsp(conf.General.LocalMSPDir, conf.General.BCCSP, conf.General.LocalMSPID)
+	if err != nil {
+		app.Fatalf("Failed to initialize local MSP: %s", err)
+	}
+
+	var manifest *archive.Manifest
+	switch command {
+	case export.FullCommand():
+		manifest, err = doExport(localmsp.NewSigner())
+	case verify.FullCommand():
+		manifest, err = doImport(*verifyChannelID, *verifyInput, false)
+	case importCmd.FullCommand():
+		manifest, err = doImport(*importChannelID, *importInput, true)
+	}
+	if err != nil {
+		app.Fatalf("Error running %s: %s", command, err)
+	}
+	fmt.Printf("%s: blocks %d to %d of channel %s\n", command, manifest.First(), manifest.Last(), manifest.ChannelID)
+}
+
+func doExport(signer crypto.LocalSigner) (manifest *archive.Manifest, err error) {
+	if *exportStart > *exportStop {
+		return nil, errors.Errorf("start block %d is after stop block %d", *exportStart, *exportStop)
+	}
+	ledger, err := explorer.Open(*path, *exportChannelID)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(*exportOutput, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating archive")
+	}
+	defer func() {
+		file.Close()
+		if err != nil {
+			os.Remove(*exportOutput)
+		}
+	}()
+
+	writer := archive.NewWriter(file, *exportChannelID, signer)
+	var last *cb.Block
+	err = ledger.Walk(func(block *cb.Block) error {
+		if block.Header.Number < *exportStart {
+			return nil
+		}
+		if err := writer.Append(block); err != nil {
+			return err
+		}
+		last = block
+		if block.Header.Number == *exportStop {
+			return errDone
+		}
+		return nil
+	})
+	if err != nil && err != errDone {
+		return nil, err
+	}
+	if last == nil {
+		return nil, errors.Errorf("ledger of channel %s has no block %d", *exportChannelID, *exportStart)
+	}
+	if *exportStop != math.MaxUint64 && last.Header.Number != *exportStop {
+		return nil, errors.Errorf("ledger of channel %s ends at block %d, before block %d", *exportChannelID, last.Header.Number, *exportStop)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	if err := file.Sync(); err != nil {
+		return nil, errors.Wrap(err, "error writing archive")
+	}
+	return writer.Manifest(), nil
+}
+
+func doImport(channelID, input string, commit bool) (*archive.Manifest, error) {
+	file, err := os.Open(input)
+	if err != nil {
+		return nil, errors.Wrap(err, "error opening archive")
+	}
+	defer file.Close()
+
+	factory := fileledger.New(*path)
+	defer factory.Close()
+
+	// 通道账本不存在时先针对空账本验证归档，避免验证失败后遗留一个空的通道账本，
+	// 否则Orderer节点启动时会因该通道账本没有配置区块而退出
+	exists := false
+	for _, existing := range factory.ChainIDs() {
+		exists = exists || existing == channelID
+	}
+	if !exists {
+		empty, _ := ramledger.New(1).GetOrCreate(channelID)
+		manifest, err := archive.Verify(file, channelID, empty)
+		if err != nil || !commit {
+			return manifest, err
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			return nil, errors.Wrap(err, "error rewinding archive")
+		}
+	}
+
+	ledger, err := factory.GetOrCreate(channelID)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening ledger of channel %s", channelID)
+	}
+	if !commit {
+		return archive.Verify(file, channelID, ledger)
+	}
+	return archive.Import(file, channelID, ledger)
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/msp"
 	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
 	pb "github.com/hyperledger/fabric/protos/peer"
 	"github.com/hyperledger/fabric/protos/utils"
 
@@ -44,6 +45,8 @@ const (
 	ConsensusTypeSolo = "solo"
 	// ConsensusTypeKafka identifies the Kafka-based consensus implementation.
 	ConsensusTypeKafka = "kafka"
+	// ConsensusTypeBFT identifies the BFT consensus implementation.
+	ConsensusTypeBFT = "bft"
 
 	// BlockValidationPolicyKey TODO
 	BlockValidationPolicyKey = "BlockValidation"
@@ -208,13 +211,30 @@ func NewOrdererGroup(conf *genesisconfig.Orderer) (*cb.ConfigGroup, error) {
 	), channelconfig.AdminsPolicyKey)
 	addValue(ordererGroup, channelconfig.BatchTimeoutValue(conf.BatchTimeout.String()), channelconfig.AdminsPolicyKey)
 	addValue(ordererGroup, channelconfig.ChannelRestrictionsValue(conf.MaxChannels), channelconfig.AdminsPolicyKey)
+	if conf.IngressWeight > 0 {
+		addValue(ordererGroup, channelconfig.IngressWeightValue(conf.IngressWeight), channelconfig.AdminsPolicyKey)
+	}
+	if len(conf.IngressQuotas) > 0 {
+		quotas := make([]*ab.IngressQuota, len(conf.IngressQuotas))
+		for i, quota := range conf.IngressQuotas {
+			quotas[i] = &ab.IngressQuota{MspId: quota.MSPID, Rate: quota.Rate, Burst: quota.Burst}
+		}
+		addValue(ordererGroup, channelconfig.IngressQuotasValue(quotas), channelconfig.AdminsPolicyKey)
+	}
+	if len(conf.Labels) > 0 {
+		labels := make([]*ab.TransactionLabel, len(conf.Labels))
+		for i, label := range conf.Labels {
+			labels[i] = &ab.TransactionLabel{Name: label.Name, OrganizationalUnits: label.OrganizationalUnits, ReadersPolicy: label.ReadersPolicy}
+		}
+		addValue(ordererGroup, channelconfig.TransactionLabelsValue(labels), channelconfig.AdminsPolicyKey)
+	}
 
 	if len(conf.Capabilities) > 0 {
 		addValue(ordererGroup, channelconfig.CapabilitiesValue(conf.Capabilities), channelconfig.AdminsPolicyKey)
 	}
 
 	switch conf.OrdererType {
-	case ConsensusTypeSolo:
+	case ConsensusTypeSolo, ConsensusTypeBFT:
 	case ConsensusTypeKafka:
 		addValue(ordererGroup, channelconfig.KafkaBrokersValue(conf.Kafka.Brokers), channelconfig.AdminsPolicyKey)
 	default:
@@ -253,6 +273,18 @@ func NewOrdererOrgGroup(conf *genesisconfig.Organization) (*cb.ConfigGroup, erro
 
 	addValue(ordererOrgGroup, channelconfig.MSPValue(mspConfig), channelconfig.AdminsPolicyKey)
 
+	if len(conf.OrdererEndpoints) > 0 {
+		var endpoints []*cb.OrdererEndpoint
+		for _, endpoint := range conf.OrdererEndpoints {
+			endpoints = append(endpoints, &cb.OrdererEndpoint{
+				Address:  endpoint.Address,
+				Region:   endpoint.Region,
+				Priority: endpoint.Priority,
+			})
+		}
+		addValue(ordererOrgGroup, channelconfig.OrdererEndpointsValue(endpoints), channelconfig.AdminsPolicyKey)
+	}
+
 	ordererOrgGroup.ModPolicy = channelconfig.AdminsPolicyKey
 	return ordererOrgGroup, nil
 }
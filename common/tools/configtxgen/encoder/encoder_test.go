@@ -284,6 +284,20 @@ func TestNewOrdererGroup(t *testing.T) {
 		assert.Error(t, err)
 		assert.Nil(t, group)
 	})
+
+	t.Run("Orderer endpoints", func(t *testing.T) {
+		config := configtxgentest.Load(genesisconfig.SampleDevModeSoloProfile)
+		org := config.Orderer.Organizations[0]
+		org.OrdererEndpoints = []*genesisconfig.OrdererEndpoint{{Address: "orderer.eu:7050", Region: "eu", Priority: 1}}
+		group, err := NewOrdererGroup(config.Orderer)
+		assert.NoError(t, err)
+
+		value := group.Groups[org.Name].Values[channelconfig.OrdererEndpointsKey]
+		assert.NotNil(t, value)
+		endpoints := &cb.OrdererEndpoints{}
+		assert.NoError(t, proto.Unmarshal(value.Value, endpoints))
+		assert.Equal(t, []*cb.OrdererEndpoint{{Address: "orderer.eu:7050", Region: "eu", Priority: 1}}, endpoints.Endpoints)
+	})
 }
 
 func TestBootstrapper(t *testing.T) {
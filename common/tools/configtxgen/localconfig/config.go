@@ -152,6 +152,9 @@ type Organization struct {
 	// for both orderers and applications.
 	AnchorPeers []*AnchorPeer `yaml:"AnchorPeers"`
 
+	// OrdererEndpoints is only encoded for orderer organizations
+	OrdererEndpoints []*OrdererEndpoint `yaml:"OrdererEndpoints"`
+
 	// AdminPrincipal is deprecated and may be removed in a future release
 	// it was used for modifying the default policy generation, but policies
 	// may now be specified explicitly so it is redundant and unnecessary
@@ -164,6 +167,14 @@ type AnchorPeer struct {
 	Port int    `yaml:"Port"`
 }
 
+// OrdererEndpoint encodes the address of an orderer of an organization,
+// along with the region it is deployed in and its priority in that region.
+type OrdererEndpoint struct {
+	Address  string `yaml:"Address"`
+	Region   string `yaml:"Region"`
+	Priority uint32 `yaml:"Priority"`
+}
+
 // Orderer contains configuration which is used for the
 // bootstrapping of an orderer by the provisional bootstrapper.
 type Orderer struct {
@@ -174,10 +185,28 @@ type Orderer struct {
 	Kafka         Kafka              `yaml:"Kafka"`
 	Organizations []*Organization    `yaml:"Organizations"`
 	MaxChannels   uint64             `yaml:"MaxChannels"`
+	IngressWeight uint32             `yaml:"IngressWeight"`
+	IngressQuotas []IngressQuota     `yaml:"IngressQuotas"`
+	Labels        []Label            `yaml:"Labels"`
 	Capabilities  map[string]bool    `yaml:"Capabilities"`
 	Policies      map[string]*Policy `yaml:"Policies"`
 }
 
+// IngressQuota is the transaction rate an organization may broadcast to the
+// channel.
+type IngressQuota struct {
+	MSPID string `yaml:"MSPID"`
+	Rate  uint32 `yaml:"Rate"`
+	Burst uint32 `yaml:"Burst"`
+}
+
+// Label is a visibility label the transactions of the channel may carry.
+type Label struct {
+	Name                string   `yaml:"Name"`
+	OrganizationalUnits []string `yaml:"OrganizationalUnits"`
+	ReadersPolicy       string   `yaml:"ReadersPolicy"`
+}
+
 // BatchSize contains configuration affecting the size of batches.
 type BatchSize struct {
 	MaxMessageCount   uint32 `yaml:"MaxMessageCount"`
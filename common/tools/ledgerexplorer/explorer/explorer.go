@@ -0,0 +1,260 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package explorer answers forensic queries against a block store which is
+// not in use by a running node.  The block files are read sequentially and
+// the block index is never opened, so the store is left untouched, and the
+// same code serves both orderer and peer ledgers.
+package explorer
+
+import (
+	"io"
+
+	"github.com/hyperledger/fabric/common/configtx"
+	"github.com/hyperledger/fabric/common/ledger/blkstorage/fsblkstorage"
+	"github.com/hyperledger/fabric/common/tools/configtxlator/update"
+	ledgerutil "github.com/hyperledger/fabric/core/ledger/util"
+	cb "github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+// errStop is returned by a walk function to end the walk early without error
+var errStop = errors.New("stop walking")
+
+// ListLedgers returns the ids of the ledgers in the given block storage directory
+func ListLedgers(dir string) ([]string, error) {
+	return fsblkstorage.ListLedgerIDs(fsblkstorage.NewConf(dir, 0))
+}
+
+// Ledger gives read only access to the blocks of a single ledger.
+type Ledger struct {
+	conf     *fsblkstorage.Conf
+	ledgerID string
+}
+
+// Open returns the ledger with the given id from the block storage directory
+// dir.  For an orderer, dir is its FileLedger.Location; for a peer, it is the
+// ledgersData/chains directory under its file system path.
+func Open(dir, ledgerID string) (*Ledger, error) {
+	ledgerIDs, err := ListLedgers(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error listing ledgers in %s", dir)
+	}
+	for _, id := range ledgerIDs {
+		if id == ledgerID {
+			return &Ledger{conf: fsblkstorage.NewConf(dir, 0), ledgerID: ledgerID}, nil
+		}
+	}
+	return nil, errors.Errorf("ledger %s not found in %s", ledgerID, dir)
+}
+
+// Walk calls fn on every block of the ledger in order, until fn returns an error
+func (l *Ledger) Walk(fn func(*cb.Block) error) error {
+	reader, err := fsblkstorage.NewBlockFileReader(l.conf, l.ledgerID)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+
+	for {
+		block, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return errors.Wrap(err, "error reading block files")
+		}
+		if err := fn(block); err != nil {
+			if err == errStop {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// Height returns the number of blocks in the ledger
+func (l *Ledger) Height() (uint64, error) {
+	var height uint64
+	err := l.Walk(func(*cb.Block) error {
+		height++
+		return nil
+	})
+	return height, err
+}
+
+// Block returns the block with the given number
+func (l *Ledger) Block(number uint64) (*cb.Block, error) {
+	var found *cb.Block
+	err := l.Walk(func(block *cb.Block) error {
+		if block.Header.Number == number {
+			found = block
+			return errStop
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if found == nil {
+		return nil, errors.Errorf("block %d not found", number)
+	}
+	return found, nil
+}
+
+// Envelope returns the transaction at the given index of the given block
+func (l *Ledger) Envelope(blockNumber uint64, txIndex int) (*cb.Envelope, error) {
+	block, err := l.Block(blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if txIndex < 0 || txIndex >= len(block.Data.Data) {
+		return nil, errors.Errorf("block %d has %d transactions, index %d out of range", blockNumber, len(block.Data.Data), txIndex)
+	}
+	return utils.ExtractEnvelope(block, txIndex)
+}
+
+// TxLocation describes where a transaction was found in the ledger.
+type TxLocation struct {
+	BlockNumber uint64
+	TxIndex     int
+	Envelope    *cb.Envelope
+
+	// ValidationCode is the validation result the peer recorded for the
+	// transaction.  Orderer ledgers do not record one, in which case it is
+	// left at the zero value and HasValidationCode is false.
+	ValidationCode    pb.TxValidationCode
+	HasValidationCode bool
+}
+
+// FindTx returns the location of every transaction with the given id.  A
+// transaction id is normally unique, but duplicates are exactly what a
+// forensic investigation may be looking for, so all occurrences are reported.
+func (l *Ledger) FindTx(txID string) ([]*TxLocation, error) {
+	var locations []*TxLocation
+	err := l.Walk(func(block *cb.Block) error {
+		for i := range block.Data.Data {
+			env, err := utils.ExtractEnvelope(block, i)
+			if err != nil {
+				continue
+			}
+			chdr, err := utils.ChannelHeader(env)
+			if err != nil || chdr.TxId != txID {
+				continue
+			}
+			location := &TxLocation{BlockNumber: block.Header.Number, TxIndex: i, Envelope: env}
+			filter := ledgerutil.TxValidationFlags(block.GetMetadata().GetMetadata()[cb.BlockMetadataIndex_TRANSACTIONS_FILTER])
+			if i < len(filter) {
+				location.ValidationCode = filter.Flag(i)
+				location.HasValidationCode = true
+			}
+			locations = append(locations, location)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(locations) == 0 {
+		return nil, errors.Errorf("transaction %s not found", txID)
+	}
+	return locations, nil
+}
+
+// ConfigBlock describes a config block of the ledger.
+type ConfigBlock struct {
+	BlockNumber uint64
+	Sequence    uint64
+	Config      *cb.Config
+}
+
+// ConfigBlocks returns every config block of the ledger, in order
+func (l *Ledger) ConfigBlocks() ([]*ConfigBlock, error) {
+	var configBlocks []*ConfigBlock
+	err := l.Walk(func(block *cb.Block) error {
+		config, err := extractConfig(block)
+		if err != nil {
+			return err
+		}
+		if config == nil {
+			return nil
+		}
+		configBlocks = append(configBlocks, &ConfigBlock{
+			BlockNumber: block.Header.Number,
+			Sequence:    config.Sequence,
+			Config:      config,
+		})
+		return nil
+	})
+	return configBlocks, err
+}
+
+// ConfigAt returns the config block which was in effect at the given block
+// number, that is the last config block whose number is not greater than it
+func (l *Ledger) ConfigAt(blockNumber uint64) (*ConfigBlock, error) {
+	configBlocks, err := l.ConfigBlocks()
+	if err != nil {
+		return nil, err
+	}
+	var current *ConfigBlock
+	for _, configBlock := range configBlocks {
+		if configBlock.BlockNumber > blockNumber {
+			break
+		}
+		current = configBlock
+	}
+	if current == nil {
+		return nil, errors.Errorf("no config block found at or before block %d", blockNumber)
+	}
+	return current, nil
+}
+
+// ConfigDiff computes the config update which transforms the config in
+// effect at block from into the config in effect at block to
+func (l *Ledger) ConfigDiff(from, to uint64) (*cb.ConfigUpdate, error) {
+	original, err := l.ConfigAt(from)
+	if err != nil {
+		return nil, err
+	}
+	updated, err := l.ConfigAt(to)
+	if err != nil {
+		return nil, err
+	}
+	configUpdate, err := update.Compute(original.Config, updated.Config)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error comparing config of block %d with config of block %d", original.BlockNumber, updated.BlockNumber)
+	}
+	configUpdate.ChannelId = l.ledgerID
+	return configUpdate, nil
+}
+
+// extractConfig returns the config carried by the block, or nil if the block
+// is not a config block.  Orderer transactions on the system channel are not
+// config blocks of this ledger, they carry the genesis config of a new channel.
+func extractConfig(block *cb.Block) (*cb.Config, error) {
+	if len(block.GetData().GetData()) != 1 {
+		return nil, nil
+	}
+	env, err := utils.ExtractEnvelope(block, 0)
+	if err != nil {
+		return nil, nil
+	}
+	payload, err := utils.UnmarshalPayload(env.Payload)
+	if err != nil || payload.Header == nil {
+		return nil, nil
+	}
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil || cb.HeaderType(chdr.Type) != cb.HeaderType_CONFIG {
+		return nil, nil
+	}
+	configEnvelope, err := configtx.UnmarshalConfigEnvelope(payload.Data)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error unmarshaling config envelope of config block %d", block.Header.Number)
+	}
+	return configEnvelope.Config, nil
+}
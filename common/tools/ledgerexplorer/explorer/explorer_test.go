@@ -0,0 +1,171 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package explorer
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	fileledger "github.com/hyperledger/fabric/common/ledger/blockledger/file"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/configtxgentest"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/encoder"
+	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const channelID = "testchannel"
+
+func makeTx(txID string) *cb.Envelope {
+	chdr := utils.MakeChannelHeader(cb.HeaderType_ENDORSER_TRANSACTION, 0, channelID, 0)
+	chdr.TxId = txID
+	return &cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: utils.MakePayloadHeader(chdr, &cb.SignatureHeader{}),
+			Data:   []byte(txID),
+		}),
+	}
+}
+
+func makeConfigTx(config *cb.Config) *cb.Envelope {
+	env, err := utils.CreateSignedEnvelope(cb.HeaderType_CONFIG, channelID, nil, &cb.ConfigEnvelope{Config: config}, 0, 0)
+	if err != nil {
+		panic(err)
+	}
+	return env
+}
+
+// newTestLedger writes a ledger containing the genesis block, two blocks of
+// transactions, a config block changing the batch timeout and one more block
+// of transactions, and returns the block storage directory
+func newTestLedger(t *testing.T) (string, *cb.Config) {
+	dir, err := ioutil.TempDir("", "ledgerexplorer")
+	require.NoError(t, err)
+
+	lf := fileledger.New(dir)
+	defer lf.Close()
+	rl, err := lf.GetOrCreate(channelID)
+	require.NoError(t, err)
+
+	genesisBlock := encoder.New(configtxgentest.Load(genesisconfig.SampleInsecureSoloProfile)).GenesisBlockForChannel(channelID)
+	require.NoError(t, rl.Append(genesisBlock))
+	genesisEnv, err := utils.ExtractEnvelope(genesisBlock, 0)
+	require.NoError(t, err)
+	configEnv := &cb.ConfigEnvelope{}
+	_, err = utils.UnmarshalEnvelopeOfType(genesisEnv, cb.HeaderType_CONFIG, configEnv)
+	require.NoError(t, err)
+
+	updated := proto.Clone(configEnv.Config).(*cb.Config)
+	updated.Sequence++
+	updated.ChannelGroup.Groups[channelconfig.OrdererGroupKey].Values[channelconfig.BatchTimeoutKey] = &cb.ConfigValue{
+		Value:     utils.MarshalOrPanic(&ab.BatchTimeout{Timeout: "5s"}),
+		ModPolicy: channelconfig.AdminsPolicyKey,
+	}
+
+	require.NoError(t, rl.Append(blockledger.CreateNextBlock(rl, []*cb.Envelope{makeTx("tx1"), makeTx("tx2")})))
+	require.NoError(t, rl.Append(blockledger.CreateNextBlock(rl, []*cb.Envelope{makeTx("tx3"), makeTx("tx1")})))
+	require.NoError(t, rl.Append(blockledger.CreateNextBlock(rl, []*cb.Envelope{makeConfigTx(updated)})))
+	require.NoError(t, rl.Append(blockledger.CreateNextBlock(rl, []*cb.Envelope{makeTx("tx4")})))
+
+	return dir, configEnv.Config
+}
+
+func TestListAndOpen(t *testing.T) {
+	dir, _ := newTestLedger(t)
+	defer os.RemoveAll(dir)
+
+	ledgerIDs, err := ListLedgers(dir)
+	require.NoError(t, err)
+	assert.Equal(t, []string{channelID}, ledgerIDs)
+
+	_, err = Open(dir, "missing")
+	assert.EqualError(t, err, "ledger missing not found in "+dir)
+
+	ledger, err := Open(dir, channelID)
+	require.NoError(t, err)
+	height, err := ledger.Height()
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), height)
+}
+
+func TestBlockAndEnvelope(t *testing.T) {
+	dir, _ := newTestLedger(t)
+	defer os.RemoveAll(dir)
+	ledger, err := Open(dir, channelID)
+	require.NoError(t, err)
+
+	block, err := ledger.Block(2)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), block.Header.Number)
+	assert.Len(t, block.Data.Data, 2)
+
+	_, err = ledger.Block(10)
+	assert.EqualError(t, err, "block 10 not found")
+
+	env, err := ledger.Envelope(2, 0)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(makeTx("tx3"), env))
+
+	_, err = ledger.Envelope(2, 2)
+	assert.EqualError(t, err, "block 2 has 2 transactions, index 2 out of range")
+}
+
+func TestFindTx(t *testing.T) {
+	dir, _ := newTestLedger(t)
+	defer os.RemoveAll(dir)
+	ledger, err := Open(dir, channelID)
+	require.NoError(t, err)
+
+	locations, err := ledger.FindTx("tx1")
+	require.NoError(t, err)
+	require.Len(t, locations, 2)
+	assert.Equal(t, uint64(1), locations[0].BlockNumber)
+	assert.Equal(t, 0, locations[0].TxIndex)
+	assert.Equal(t, uint64(2), locations[1].BlockNumber)
+	assert.Equal(t, 1, locations[1].TxIndex)
+
+	_, err = ledger.FindTx("missing")
+	assert.EqualError(t, err, "transaction missing not found")
+}
+
+func TestConfig(t *testing.T) {
+	dir, genesisConfig := newTestLedger(t)
+	defer os.RemoveAll(dir)
+	ledger, err := Open(dir, channelID)
+	require.NoError(t, err)
+
+	configBlocks, err := ledger.ConfigBlocks()
+	require.NoError(t, err)
+	require.Len(t, configBlocks, 2)
+	assert.Equal(t, uint64(0), configBlocks[0].BlockNumber)
+	assert.Equal(t, uint64(3), configBlocks[1].BlockNumber)
+	assert.Equal(t, genesisConfig.Sequence+1, configBlocks[1].Sequence)
+
+	configBlock, err := ledger.ConfigAt(2)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), configBlock.BlockNumber)
+	configBlock, err = ledger.ConfigAt(4)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), configBlock.BlockNumber)
+
+	configUpdate, err := ledger.ConfigDiff(1, 4)
+	require.NoError(t, err)
+	assert.Equal(t, channelID, configUpdate.ChannelId)
+	ordererGroup := configUpdate.WriteSet.Groups[channelconfig.OrdererGroupKey]
+	require.NotNil(t, ordererGroup)
+	assert.Contains(t, ordererGroup.Values, channelconfig.BatchTimeoutKey)
+
+	_, err = ledger.ConfigDiff(1, 2)
+	assert.Error(t, err, "no differences between the configs")
+}
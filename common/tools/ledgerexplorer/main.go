@@ -0,0 +1,161 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/hyperledger/fabric/common/tools/ledgerexplorer/explorer"
+	"github.com/hyperledger/fabric/common/tools/protolator"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// command line flags
+var (
+	app = kingpin.New("ledgerexplorer", "Utility for inspecting the block store of a stopped orderer or peer")
+
+	path   = app.Flag("path", "The block storage directory: the FileLedger.Location of an orderer, or the ledgersData/chains directory of a peer.").Required().ExistingDir()
+	output = app.Flag("output", "A file to write the output to.").Default(os.Stdout.Name()).OpenFile(os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+
+	listLedgers = app.Command("list_ledgers", "Lists the ledgers in the block store.")
+
+	showBlock       = app.Command("show_block", "Prints a block, decoded to JSON.")
+	showBlockLedger = showBlock.Flag("ledger", "The ledger to read from.").Required().String()
+	showBlockNumber = showBlock.Flag("number", "The number of the block.").Required().Uint64()
+
+	findTx       = app.Command("find_tx", "Finds every transaction with the given id.")
+	findTxLedger = findTx.Flag("ledger", "The ledger to search.").Required().String()
+	findTxID     = findTx.Flag("txid", "The transaction id to look for.").Required().String()
+
+	listConfig       = app.Command("list_config", "Lists the config blocks of a ledger.")
+	listConfigLedger = listConfig.Flag("ledger", "The ledger to read from.").Required().String()
+
+	diffConfig       = app.Command("diff_config", "Prints the config update between the configs in effect at two block numbers, decoded to JSON.")
+	diffConfigLedger = diffConfig.Flag("ledger", "The ledger to read from.").Required().String()
+	diffConfigFrom   = diffConfig.Flag("from", "The block number whose config is the original.").Required().Uint64()
+	diffConfigTo     = diffConfig.Flag("to", "The block number whose config is the updated one.").Required().Uint64()
+
+	extractTx       = app.Command("extract_tx", "Writes a marshaled transaction envelope to the output.")
+	extractTxLedger = extractTx.Flag("ledger", "The ledger to read from.").Required().String()
+	extractTxBlock  = extractTx.Flag("block", "The number of the block containing the transaction.").Required().Uint64()
+	extractTxIndex  = extractTx.Flag("index", "The index of the transaction in the block.").Default("0").Int()
+)
+
+func main() {
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+	defer (*output).Close()
+
+	var err error
+	switch command {
+	case listLedgers.FullCommand():
+		err = doListLedgers(*output)
+	case showBlock.FullCommand():
+		err = doShowBlock(*output)
+	case findTx.FullCommand():
+		err = doFindTx(*output)
+	case listConfig.FullCommand():
+		err = doListConfig(*output)
+	case diffConfig.FullCommand():
+		err = doDiffConfig(*output)
+	case extractTx.FullCommand():
+		err = doExtractTx(*output)
+	}
+	if err != nil {
+		app.Fatalf("Error running %s: %s", command, err)
+	}
+}
+
+func doListLedgers(out io.Writer) error {
+	ledgerIDs, err := explorer.ListLedgers(*path)
+	if err != nil {
+		return err
+	}
+	for _, ledgerID := range ledgerIDs {
+		fmt.Fprintln(out, ledgerID)
+	}
+	return nil
+}
+
+func doShowBlock(out io.Writer) error {
+	ledger, err := explorer.Open(*path, *showBlockLedger)
+	if err != nil {
+		return err
+	}
+	block, err := ledger.Block(*showBlockNumber)
+	if err != nil {
+		return err
+	}
+	return protolator.DeepMarshalJSON(out, block)
+}
+
+func doFindTx(out io.Writer) error {
+	ledger, err := explorer.Open(*path, *findTxLedger)
+	if err != nil {
+		return err
+	}
+	locations, err := ledger.FindTx(*findTxID)
+	if err != nil {
+		return err
+	}
+	for _, location := range locations {
+		if location.HasValidationCode {
+			fmt.Fprintf(out, "block %d, index %d, validation code %s\n", location.BlockNumber, location.TxIndex, location.ValidationCode)
+		} else {
+			fmt.Fprintf(out, "block %d, index %d\n", location.BlockNumber, location.TxIndex)
+		}
+	}
+	return nil
+}
+
+func doListConfig(out io.Writer) error {
+	ledger, err := explorer.Open(*path, *listConfigLedger)
+	if err != nil {
+		return err
+	}
+	configBlocks, err := ledger.ConfigBlocks()
+	if err != nil {
+		return err
+	}
+	for _, configBlock := range configBlocks {
+		fmt.Fprintf(out, "block %d, config sequence %d\n", configBlock.BlockNumber, configBlock.Sequence)
+	}
+	return nil
+}
+
+func doDiffConfig(out io.Writer) error {
+	ledger, err := explorer.Open(*path, *diffConfigLedger)
+	if err != nil {
+		return err
+	}
+	configUpdate, err := ledger.ConfigDiff(*diffConfigFrom, *diffConfigTo)
+	if err != nil {
+		return err
+	}
+	return protolator.DeepMarshalJSON(out, configUpdate)
+}
+
+func doExtractTx(out io.Writer) error {
+	ledger, err := explorer.Open(*path, *extractTxLedger)
+	if err != nil {
+		return err
+	}
+	env, err := ledger.Envelope(*extractTxBlock, *extractTxIndex)
+	if err != nil {
+		return err
+	}
+	envBytes, err := proto.Marshal(env)
+	if err != nil {
+		return errors.Wrap(err, "error marshaling envelope")
+	}
+	_, err = out.Write(envBytes)
+	return errors.Wrap(err, "error writing output")
+}
@@ -0,0 +1,101 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/hyperledger/fabric/common/cauthdsl"
+	"github.com/pkg/errors"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+// command line flags
+var (
+	app = kingpin.New("policysim", "Utility for simulating whether an endorsement policy is satisfied by a set of signers")
+
+	policy            = app.Flag("policy", "The endorsement policy, e.g. \"AND('Org1MSP.peer', 'Org2MSP.peer')\".").String()
+	collectionsConfig = app.Flag("collectionsConfig", "A collections configuration file, as passed to peer chaincode instantiate, to take the policy from.").String()
+	collection        = app.Flag("collection", "The collection of the collections configuration whose policy is simulated.").String()
+	signerSpecs       = app.Flag("signer", "A hypothetical signer, e.g. 'Org1MSP.peer'. Can be repeated.").Required().Strings()
+)
+
+func main() {
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	expression, err := policyExpression()
+	if err != nil {
+		app.Fatalf("%s", err)
+	}
+	envelope, err := cauthdsl.FromString(expression)
+	if err != nil {
+		app.Fatalf("Invalid policy %s: %s", expression, err)
+	}
+
+	var signers []*cauthdsl.Signer
+	for _, spec := range *signerSpecs {
+		signer, err := cauthdsl.ParseSigner(spec)
+		if err != nil {
+			app.Fatalf("%s", err)
+		}
+		signers = append(signers, signer)
+	}
+
+	result, err := cauthdsl.Simulate(envelope, signers)
+	if err != nil {
+		app.Fatalf("Error simulating policy: %s", err)
+	}
+
+	if !result.Satisfied {
+		fmt.Printf("Policy %s is not satisfied by the signers\n", expression)
+		os.Exit(1)
+	}
+	fmt.Printf("Policy %s is satisfied by the signers, minimal combinations:\n", expression)
+	for _, set := range result.MinimalSets {
+		names := make([]string, len(set))
+		for i, signer := range set {
+			names[i] = signer.String()
+		}
+		fmt.Printf("  %s\n", strings.Join(names, ", "))
+	}
+}
+
+// policyExpression returns the policy given on the command line or the one
+// of the collection
+func policyExpression() (string, error) {
+	if *collectionsConfig == "" {
+		if *policy == "" {
+			return "", errors.New("either --policy or --collectionsConfig must be set")
+		}
+		return *policy, nil
+	}
+	if *policy != "" {
+		return "", errors.New("--policy and --collectionsConfig are mutually exclusive")
+	}
+
+	fileBytes, err := ioutil.ReadFile(*collectionsConfig)
+	if err != nil {
+		return "", errors.Wrapf(err, "could not read file '%s'", *collectionsConfig)
+	}
+	var collections []struct {
+		Name   string `json:"name"`
+		Policy string `json:"policy"`
+	}
+	if err := json.Unmarshal(fileBytes, &collections); err != nil {
+		return "", errors.Wrap(err, "could not parse the collection configuration")
+	}
+	for _, c := range collections {
+		if c.Name == *collection {
+			return c.Policy, nil
+		}
+	}
+	return "", errors.Errorf("collection '%s' not found in %s", *collection, *collectionsConfig)
+}
@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/localmsp"
+	"github.com/hyperledger/fabric/common/tools/replayverifier/verify"
+	mspmgmt "github.com/hyperledger/fabric/msp/mgmt"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var logger = logging.MustGetLogger("replayverifier")
+
+// command line flags
+var (
+	app = kingpin.New("replayverifier", "Utility for verifying that two ordering nodes hold identical ledgers for a channel")
+
+	left      = app.Flag("left", "The address of the first orderer.").Required().String()
+	right     = app.Flag("right", "The address of the second orderer.").Required().String()
+	channelID = app.Flag("channelID", "The channel whose ledgers are compared.").Default(localconfig.Defaults.General.SystemChannel).String()
+	start     = app.Flag("start", "The first block to compare.").Default("0").Uint64()
+	stop      = app.Flag("stop", "The last block to compare, defaults to the height of the shorter ledger.").Default(fmt.Sprint(uint64(math.MaxUint64))).Uint64()
+	strict    = app.Flag("strict", "Also fail when blocks differ only in their metadata signatures.").Bool()
+	tlsCA     = app.Flag("tlsCAFile", "The PEM encoded CA certificate used to verify the orderers' TLS certificates, if TLS is enabled.").String()
+	timeout   = app.Flag("timeout", "The timeout for connecting to each orderer.").Default("10s").Duration()
+)
+
+func main() {
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	conf, err := localconfig.Load()
+	if err != nil {
+		app.Fatalf("Failed to load config: %s", err)
+	}
+
+	// Load local MSP
+	err = mspmgmt.LoadLocalMsp(conf.General.LocalMSPDir, conf.General.BCCSP, conf.General.LocalMSPID)
+	if err != nil {
+		app.Fatalf("Failed to initialize local MSP: %s", err)
+	}
+
+	report, err := run(localmsp.NewSigner())
+	if err != nil {
+		app.Fatalf("Error verifying ledgers: %s", err)
+	}
+
+	for _, d := range report.Divergences {
+		fmt.Println(d)
+	}
+	fmt.Printf("Compared %d blocks, %d identical, %d divergences\n", report.Compared, report.Identical, len(report.Divergences))
+
+	if !report.Consistent(*strict) {
+		os.Exit(1)
+	}
+}
+
+func run(signer crypto.LocalSigner) (*verify.Report, error) {
+	leftSource, err := connect(*left, signer)
+	if err != nil {
+		return nil, err
+	}
+	rightSource, err := connect(*right, signer)
+	if err != nil {
+		return nil, err
+	}
+	return verify.Verify(leftSource, rightSource)
+}
+
+func connect(address string, signer crypto.LocalSigner) (verify.BlockSource, error) {
+	dialOpts := []grpc.DialOption{grpc.WithBlock(), grpc.WithTimeout(*timeout)}
+	if *tlsCA != "" {
+		creds, err := credentials.NewClientTLSFromFile(*tlsCA, "")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load TLS CA certificate")
+		}
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithInsecure())
+	}
+
+	logger.Debugf("Connecting to %s", address)
+	conn, err := grpc.Dial(address, dialOpts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to %s", address)
+	}
+	client, err := ab.NewAtomicBroadcastClient(conn).Deliver(context.Background())
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open deliver stream to %s", address)
+	}
+	return verify.NewDeliverSource(client, *channelID, signer, *start, *stop)
+}
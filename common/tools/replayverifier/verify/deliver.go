@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verify
+
+import (
+	"io"
+
+	"github.com/hyperledger/fabric/common/crypto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+// DeliverSource is a BlockSource reading blocks from an orderer's Deliver service.
+type DeliverSource struct {
+	client ab.AtomicBroadcast_DeliverClient
+}
+
+// NewDeliverSource requests the blocks from start to stop inclusive on the
+// given deliver stream.  The request does not wait for blocks which have not
+// been written yet, so the source ends at the current height of the channel
+// if that is lower than stop.
+func NewDeliverSource(client ab.AtomicBroadcast_DeliverClient, channelID string, signer crypto.LocalSigner, start, stop uint64) (*DeliverSource, error) {
+	env, err := utils.CreateSignedEnvelope(cb.HeaderType_DELIVER_SEEK_INFO, channelID, signer, &ab.SeekInfo{
+		Start:    &ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: start}}},
+		Stop:     &ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: stop}}},
+		Behavior: ab.SeekInfo_FAIL_IF_NOT_READY,
+	}, 0, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create seek request")
+	}
+	if err := client.Send(env); err != nil {
+		return nil, errors.Wrap(err, "failed to send seek request")
+	}
+	return &DeliverSource{client: client}, nil
+}
+
+// Next returns the next block of the stream, or io.EOF once the orderer has
+// sent every block in the requested range which it has.
+func (ds *DeliverSource) Next() (*cb.Block, error) {
+	msg, err := ds.client.Recv()
+	if err != nil {
+		return nil, err
+	}
+
+	switch t := msg.Type.(type) {
+	case *ab.DeliverResponse_Block:
+		return t.Block, nil
+	case *ab.DeliverResponse_Status:
+		switch t.Status {
+		case cb.Status_SUCCESS, cb.Status_NOT_FOUND:
+			return nil, io.EOF
+		default:
+			return nil, errors.Errorf("deliver failed with status %s", t.Status)
+		}
+	default:
+		return nil, errors.Errorf("unexpected deliver response type %T", t)
+	}
+}
@@ -0,0 +1,266 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package verify compares the block streams of two ordering nodes serving the
+// same channel.  Blocks are expected to be byte-identical except for the
+// signatures each node adds to the block metadata; any other difference is
+// reported as a divergence, classified by which part of the block differs.
+package verify
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// Kind classifies how two blocks with the same number differ.
+type Kind int
+
+const (
+	// Identical means the two blocks are byte-identical.
+	Identical Kind = iota
+
+	// SignerDivergence means the blocks agree on header, data and metadata
+	// values, and differ only in the metadata signatures.  This is expected
+	// when the blocks were signed by different orderers, for instance after
+	// a consensus migration or a restore onto a node with a new identity.
+	SignerDivergence
+
+	// MetadataDivergence means a metadata value differs, for instance the
+	// last config index or the consenter specific metadata.
+	MetadataDivergence
+
+	// ContentDivergence means the header or the data of the blocks differ.
+	ContentDivergence
+
+	// HeightDivergence means one of the streams ended before the other.
+	HeightDivergence
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Identical:
+		return "identical"
+	case SignerDivergence:
+		return "signer"
+	case MetadataDivergence:
+		return "metadata"
+	case ContentDivergence:
+		return "content"
+	case HeightDivergence:
+		return "height"
+	default:
+		return fmt.Sprintf("unknown(%d)", int(k))
+	}
+}
+
+// Divergence describes a difference between the two streams at a given block.
+type Divergence struct {
+	Number uint64
+	Kind   Kind
+	Detail string
+}
+
+func (d *Divergence) String() string {
+	return fmt.Sprintf("block %d: %s divergence: %s", d.Number, d.Kind, d.Detail)
+}
+
+// Compare returns nil if the two blocks are byte-identical, and otherwise a
+// Divergence describing the most significant difference between them.
+func Compare(left, right *cb.Block) *Divergence {
+	number := left.GetHeader().GetNumber()
+
+	leftBytes, leftErr := proto.Marshal(left)
+	rightBytes, rightErr := proto.Marshal(right)
+	if leftErr == nil && rightErr == nil && bytes.Equal(leftBytes, rightBytes) {
+		return nil
+	}
+
+	if !proto.Equal(left.Header, right.Header) {
+		return &Divergence{
+			Number: number,
+			Kind:   ContentDivergence,
+			Detail: fmt.Sprintf("headers differ: left %s, right %s", describeHeader(left.Header), describeHeader(right.Header)),
+		}
+	}
+
+	leftData, rightData := left.GetData().GetData(), right.GetData().GetData()
+	if len(leftData) != len(rightData) {
+		return &Divergence{
+			Number: number,
+			Kind:   ContentDivergence,
+			Detail: fmt.Sprintf("left has %d transactions, right has %d", len(leftData), len(rightData)),
+		}
+	}
+	for i := range leftData {
+		if !bytes.Equal(leftData[i], rightData[i]) {
+			return &Divergence{
+				Number: number,
+				Kind:   ContentDivergence,
+				Detail: fmt.Sprintf("transaction %d differs", i),
+			}
+		}
+	}
+
+	return compareMetadata(number, left.GetMetadata().GetMetadata(), right.GetMetadata().GetMetadata())
+}
+
+func compareMetadata(number uint64, left, right [][]byte) *Divergence {
+	var signerDiff *Divergence
+
+	max := len(left)
+	if len(right) > max {
+		max = len(right)
+	}
+
+	for i := 0; i < max; i++ {
+		var leftEntry, rightEntry []byte
+		if i < len(left) {
+			leftEntry = left[i]
+		}
+		if i < len(right) {
+			rightEntry = right[i]
+		}
+		if bytes.Equal(leftEntry, rightEntry) {
+			continue
+		}
+
+		name := metadataName(i)
+
+		// The transactions filter is a raw byte array, not a Metadata message
+		if i == int(cb.BlockMetadataIndex_TRANSACTIONS_FILTER) {
+			return &Divergence{Number: number, Kind: MetadataDivergence, Detail: fmt.Sprintf("%s metadata differs", name)}
+		}
+
+		leftMD, rightMD := &cb.Metadata{}, &cb.Metadata{}
+		if err := proto.Unmarshal(leftEntry, leftMD); err != nil {
+			return &Divergence{Number: number, Kind: MetadataDivergence, Detail: fmt.Sprintf("left %s metadata is malformed: %s", name, err)}
+		}
+		if err := proto.Unmarshal(rightEntry, rightMD); err != nil {
+			return &Divergence{Number: number, Kind: MetadataDivergence, Detail: fmt.Sprintf("right %s metadata is malformed: %s", name, err)}
+		}
+
+		if !bytes.Equal(leftMD.Value, rightMD.Value) {
+			return &Divergence{Number: number, Kind: MetadataDivergence, Detail: fmt.Sprintf("%s metadata values differ", name)}
+		}
+
+		if signerDiff == nil {
+			signerDiff = &Divergence{
+				Number: number,
+				Kind:   SignerDivergence,
+				Detail: fmt.Sprintf("%s metadata signatures differ (left has %d, right has %d)", name, len(leftMD.Signatures), len(rightMD.Signatures)),
+			}
+		}
+	}
+
+	if signerDiff != nil {
+		return signerDiff
+	}
+
+	// The marshaled blocks differ but every field compares equal, which can
+	// only happen if one of them carries unknown fields or a non canonical encoding
+	return &Divergence{Number: number, Kind: ContentDivergence, Detail: "blocks are equal field by field but their encodings differ"}
+}
+
+func metadataName(index int) string {
+	if name, ok := cb.BlockMetadataIndex_name[int32(index)]; ok {
+		return name
+	}
+	return fmt.Sprintf("index %d", index)
+}
+
+func describeHeader(header *cb.BlockHeader) string {
+	if header == nil {
+		return "<nil>"
+	}
+	return fmt.Sprintf("{number: %d, previous_hash: %x, data_hash: %x}", header.Number, header.PreviousHash, header.DataHash)
+}
+
+// BlockSource yields consecutive blocks of a channel.  Next returns io.EOF
+// once there are no more blocks to read.
+type BlockSource interface {
+	Next() (*cb.Block, error)
+}
+
+// Report summarizes the result of a Verify run.
+type Report struct {
+	// Compared is the number of block pairs which were compared.
+	Compared uint64
+
+	// Identical is the number of block pairs which were byte-identical.
+	Identical uint64
+
+	// Divergences lists every difference found, in block order.
+	Divergences []*Divergence
+}
+
+// Consistent returns true if no divergence other than a signer divergence was
+// found.  If strict is set, signer divergences also make the report inconsistent.
+func (r *Report) Consistent(strict bool) bool {
+	for _, d := range r.Divergences {
+		if d.Kind != SignerDivergence || strict {
+			return false
+		}
+	}
+	return true
+}
+
+// Verify reads both sources until they are exhausted and compares the blocks
+// pairwise.  If the blocks read do not have matching numbers the comparison
+// stops, since every subsequent pair would diverge as well.
+func Verify(left, right BlockSource) (*Report, error) {
+	report := &Report{}
+	for {
+		leftBlock, leftErr := left.Next()
+		if leftErr != nil && leftErr != io.EOF {
+			return report, errors.Wrap(leftErr, "error reading from left source")
+		}
+		rightBlock, rightErr := right.Next()
+		if rightErr != nil && rightErr != io.EOF {
+			return report, errors.Wrap(rightErr, "error reading from right source")
+		}
+
+		switch {
+		case leftErr == io.EOF && rightErr == io.EOF:
+			return report, nil
+		case leftErr == io.EOF:
+			report.Divergences = append(report.Divergences, &Divergence{
+				Number: rightBlock.GetHeader().GetNumber(),
+				Kind:   HeightDivergence,
+				Detail: "left stream ended, right stream has more blocks",
+			})
+			return report, nil
+		case rightErr == io.EOF:
+			report.Divergences = append(report.Divergences, &Divergence{
+				Number: leftBlock.GetHeader().GetNumber(),
+				Kind:   HeightDivergence,
+				Detail: "right stream ended, left stream has more blocks",
+			})
+			return report, nil
+		}
+
+		leftNumber, rightNumber := leftBlock.GetHeader().GetNumber(), rightBlock.GetHeader().GetNumber()
+		if leftNumber != rightNumber {
+			report.Divergences = append(report.Divergences, &Divergence{
+				Number: leftNumber,
+				Kind:   ContentDivergence,
+				Detail: fmt.Sprintf("streams out of step, right returned block %d", rightNumber),
+			})
+			return report, nil
+		}
+
+		report.Compared++
+		if d := Compare(leftBlock, rightBlock); d != nil {
+			report.Divergences = append(report.Divergences, d)
+		} else {
+			report.Identical++
+		}
+	}
+}
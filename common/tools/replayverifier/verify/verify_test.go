@@ -0,0 +1,206 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package verify
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	mockcrypto "github.com/hyperledger/fabric/common/mocks/crypto"
+	"github.com/hyperledger/fabric/orderer/orderertest"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type sliceSource struct {
+	blocks []*cb.Block
+	err    error
+}
+
+func (ss *sliceSource) Next() (*cb.Block, error) {
+	if len(ss.blocks) == 0 {
+		if ss.err != nil {
+			return nil, ss.err
+		}
+		return nil, io.EOF
+	}
+	block := ss.blocks[0]
+	ss.blocks = ss.blocks[1:]
+	return block, nil
+}
+
+func makeBlock(number uint64, signer string) *cb.Block {
+	block := cb.NewBlock(number, []byte("previous"))
+	block.Data.Data = [][]byte{[]byte(fmt.Sprintf("tx-%d", number))}
+	block.Header.DataHash = block.Data.Hash()
+	block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{
+		Signatures: []*cb.MetadataSignature{{SignatureHeader: []byte(signer), Signature: []byte(signer)}},
+	})
+	block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{
+		Value: utils.MarshalOrPanic(&cb.LastConfig{Index: 0}),
+	})
+	return block
+}
+
+func TestCompare(t *testing.T) {
+	t.Run("Identical", func(t *testing.T) {
+		assert.Nil(t, Compare(makeBlock(1, "a"), makeBlock(1, "a")))
+	})
+
+	t.Run("Signer", func(t *testing.T) {
+		d := Compare(makeBlock(1, "a"), makeBlock(1, "b"))
+		require.NotNil(t, d)
+		assert.Equal(t, SignerDivergence, d.Kind)
+		assert.Equal(t, uint64(1), d.Number)
+		assert.Contains(t, d.Detail, "SIGNATURES")
+	})
+
+	t.Run("MetadataValue", func(t *testing.T) {
+		right := makeBlock(1, "b")
+		right.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{
+			Value: utils.MarshalOrPanic(&cb.LastConfig{Index: 1}),
+		})
+		d := Compare(makeBlock(1, "a"), right)
+		require.NotNil(t, d)
+		assert.Equal(t, MetadataDivergence, d.Kind)
+		assert.Equal(t, "LAST_CONFIG metadata values differ", d.Detail)
+	})
+
+	t.Run("TransactionsFilter", func(t *testing.T) {
+		right := makeBlock(1, "a")
+		right.Metadata.Metadata[cb.BlockMetadataIndex_TRANSACTIONS_FILTER] = []byte{1}
+		d := Compare(makeBlock(1, "a"), right)
+		require.NotNil(t, d)
+		assert.Equal(t, MetadataDivergence, d.Kind)
+	})
+
+	t.Run("Header", func(t *testing.T) {
+		right := makeBlock(1, "a")
+		right.Header.PreviousHash = []byte("other")
+		d := Compare(makeBlock(1, "a"), right)
+		require.NotNil(t, d)
+		assert.Equal(t, ContentDivergence, d.Kind)
+		assert.Contains(t, d.Detail, "headers differ")
+	})
+
+	t.Run("Data", func(t *testing.T) {
+		right := makeBlock(1, "b")
+		right.Data.Data = [][]byte{[]byte("other")}
+		d := Compare(makeBlock(1, "a"), right)
+		require.NotNil(t, d)
+		assert.Equal(t, ContentDivergence, d.Kind)
+		assert.Equal(t, "transaction 0 differs", d.Detail)
+	})
+
+	t.Run("DataLength", func(t *testing.T) {
+		right := makeBlock(1, "a")
+		right.Data.Data = append(right.Data.Data, []byte("extra"))
+		d := Compare(makeBlock(1, "a"), right)
+		require.NotNil(t, d)
+		assert.Equal(t, ContentDivergence, d.Kind)
+		assert.Equal(t, "left has 1 transactions, right has 2", d.Detail)
+	})
+}
+
+func TestVerify(t *testing.T) {
+	t.Run("Consistent", func(t *testing.T) {
+		report, err := Verify(
+			&sliceSource{blocks: []*cb.Block{makeBlock(0, "a"), makeBlock(1, "a")}},
+			&sliceSource{blocks: []*cb.Block{makeBlock(0, "a"), makeBlock(1, "b")}},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(2), report.Compared)
+		assert.Equal(t, uint64(1), report.Identical)
+		require.Len(t, report.Divergences, 1)
+		assert.Equal(t, SignerDivergence, report.Divergences[0].Kind)
+		assert.True(t, report.Consistent(false))
+		assert.False(t, report.Consistent(true))
+	})
+
+	t.Run("Height", func(t *testing.T) {
+		report, err := Verify(
+			&sliceSource{blocks: []*cb.Block{makeBlock(0, "a"), makeBlock(1, "a")}},
+			&sliceSource{blocks: []*cb.Block{makeBlock(0, "a")}},
+		)
+		require.NoError(t, err)
+		require.Len(t, report.Divergences, 1)
+		assert.Equal(t, HeightDivergence, report.Divergences[0].Kind)
+		assert.Equal(t, uint64(1), report.Divergences[0].Number)
+		assert.False(t, report.Consistent(false))
+	})
+
+	t.Run("OutOfStep", func(t *testing.T) {
+		report, err := Verify(
+			&sliceSource{blocks: []*cb.Block{makeBlock(0, "a"), makeBlock(1, "a")}},
+			&sliceSource{blocks: []*cb.Block{makeBlock(1, "a"), makeBlock(2, "a")}},
+		)
+		require.NoError(t, err)
+		assert.Equal(t, uint64(0), report.Compared)
+		require.Len(t, report.Divergences, 1)
+		assert.Equal(t, "block 0: content divergence: streams out of step, right returned block 1", report.Divergences[0].String())
+	})
+
+	t.Run("SourceError", func(t *testing.T) {
+		_, err := Verify(
+			&sliceSource{blocks: []*cb.Block{makeBlock(0, "a")}},
+			&sliceSource{err: fmt.Errorf("stream broken")},
+		)
+		assert.EqualError(t, err, "error reading from right source: stream broken")
+	})
+}
+
+func TestDeliverSource(t *testing.T) {
+	o, err := orderertest.New(orderertest.Config{})
+	require.NoError(t, err)
+	defer o.Stop()
+
+	client, conn, err := o.NewClient()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	newSource := func() *DeliverSource {
+		deliver, err := client.Deliver(context.Background())
+		require.NoError(t, err)
+		source, err := NewDeliverSource(deliver, o.SystemChannelID(), mockcrypto.FakeLocalSigner, 0, 10)
+		require.NoError(t, err)
+		return source
+	}
+
+	report, err := Verify(newSource(), newSource())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), report.Compared)
+	assert.Equal(t, uint64(1), report.Identical)
+	assert.True(t, report.Consistent(true))
+}
+
+func TestDeliverSourceBadStatus(t *testing.T) {
+	source := &DeliverSource{client: &mockDeliverClient{
+		responses: []*ab.DeliverResponse{{Type: &ab.DeliverResponse_Status{Status: cb.Status_FORBIDDEN}}},
+	}}
+	_, err := source.Next()
+	assert.EqualError(t, err, "deliver failed with status FORBIDDEN")
+}
+
+type mockDeliverClient struct {
+	ab.AtomicBroadcast_DeliverClient
+	responses []*ab.DeliverResponse
+}
+
+func (m *mockDeliverClient) Recv() (*ab.DeliverResponse, error) {
+	if len(m.responses) == 0 {
+		return nil, io.EOF
+	}
+	resp := m.responses[0]
+	m.responses = m.responses[1:]
+	return proto.Clone(resp).(*ab.DeliverResponse), nil
+}
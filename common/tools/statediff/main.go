@@ -0,0 +1,156 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+
+	"github.com/hyperledger/fabric/core/ledger/statediff"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var logger = logging.MustGetLogger("statediff")
+
+// command line flags
+var (
+	app = kingpin.New("statediff", "Utility for finding the keys whose state differs between two peers of a channel at the same height, and repairing a peer by replaying the blocks which wrote them")
+
+	left       = app.Flag("left", "The URL of the state handler on the operations endpoint of the first peer, such as https://peer0:9443/ledger/state/.").Required().String()
+	right      = app.Flag("right", "The URL of the state handler on the operations endpoint of the second peer.").Required().String()
+	channelID  = app.Flag("channelID", "The channel whose state is compared.").Required().String()
+	namespaces = app.Flag("namespace", "A namespace to compare, defaults to every chaincode instantiated on the channel.").Strings()
+	fanout     = app.Flag("fanout", "The number of sub-ranges a differing range is split into.").Default("16").Int()
+	maxKeys    = app.Flag("maxKeys", "The number of keys up to which a differing range is compared key by key.").Default("256").Int()
+	repair     = app.Flag("repair", "The peer to repair, the other one being the reference, by replaying the blocks which last wrote the differing keys.").Enum("left", "right")
+	from       = app.Flag("from", "The first block to replay when repairing, defaults to the earliest block which last wrote a differing key.").Uint64()
+	tlsCA      = app.Flag("tlsCAFile", "The PEM encoded CA certificate used to verify the TLS certificates of the operations endpoints.").String()
+	tlsCert    = app.Flag("tlsCertFile", "The PEM encoded client certificate presented to the operations endpoints.").String()
+	tlsKey     = app.Flag("tlsKeyFile", "The PEM encoded private key of the client certificate.").String()
+)
+
+func main() {
+	kingpin.MustParse(app.Parse(os.Args[1:]))
+
+	client, err := httpClient()
+	if err != nil {
+		app.Fatalf("Failed to configure TLS: %s", err)
+	}
+	comparer := &statediff.Comparer{
+		Left:      &statediff.HTTPSource{URL: *left, Client: client},
+		Right:     &statediff.HTTPSource{URL: *right, Client: client},
+		ChannelID: *channelID,
+		Options:   statediff.Options{Fanout: *fanout, MaxKeys: *maxKeys},
+	}
+
+	differences, err := compare(comparer)
+	if err != nil {
+		app.Fatalf("Error comparing state: %s", err)
+	}
+	for _, d := range differences {
+		fmt.Println(d)
+	}
+	fmt.Printf("Compared state at height %d, %d differing keys\n", comparer.Height(), len(differences))
+	if len(differences) == 0 {
+		return
+	}
+
+	if *repair != "" {
+		if err := replay(comparer, differences); err != nil {
+			app.Fatalf("Error repairing state: %s", err)
+		}
+		return
+	}
+	os.Exit(1)
+}
+
+func compare(comparer *statediff.Comparer) ([]*statediff.Difference, error) {
+	nss := *namespaces
+	if len(nss) == 0 {
+		var err error
+		if nss, err = comparer.Namespaces(); err != nil {
+			return nil, err
+		}
+	}
+
+	var differences []*statediff.Difference
+	for _, ns := range nss {
+		logger.Debugf("Comparing namespace %s", ns)
+		nsDifferences, err := comparer.Compare(ns)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed comparing namespace "+ns)
+		}
+		differences = append(differences, nsDifferences...)
+	}
+	return differences, nil
+}
+
+func replay(comparer *statediff.Comparer, differences []*statediff.Difference) error {
+	reference, target := comparer.Left, comparer.Right
+	if *repair == "left" {
+		reference, target = comparer.Right, comparer.Left
+	}
+
+	start := *from
+	if start == 0 {
+		var err error
+		if start, err = statediff.RepairStart(reference, target, *channelID, differences); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("Replaying the blocks from block %d on the %s peer\n", start, *repair)
+	if err := target.Replay(*channelID, start); err != nil {
+		return err
+	}
+
+	// The heights are checked again, so the peers must still be at the
+	// height they were compared at
+	remaining, err := compare(comparer)
+	if err != nil {
+		return errors.WithMessage(err, "failed comparing state after replaying")
+	}
+	for _, d := range remaining {
+		fmt.Println(d)
+	}
+	if len(remaining) > 0 {
+		return errors.Errorf("%d keys still differ after replaying", len(remaining))
+	}
+	fmt.Println("The state of both peers is identical")
+	return nil
+}
+
+func httpClient() (*http.Client, error) {
+	if *tlsCA == "" && *tlsCert == "" {
+		return http.DefaultClient, nil
+	}
+
+	tlsConfig := &tls.Config{}
+	if *tlsCA != "" {
+		caPEM, err := ioutil.ReadFile(*tlsCA)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load TLS CA certificate")
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(caPEM) {
+			return nil, errors.Errorf("no certificate found in %s", *tlsCA)
+		}
+	}
+	if *tlsCert != "" {
+		cert, err := tls.LoadX509KeyPair(*tlsCert, *tlsKey)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load TLS client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig}}, nil
+}
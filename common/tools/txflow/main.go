@@ -0,0 +1,209 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/localmsp"
+	"github.com/hyperledger/fabric/common/txtimeline"
+	mspmgmt "github.com/hyperledger/fabric/msp/mgmt"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/hyperledger/fabric/protos/utils"
+
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"gopkg.in/alecthomas/kingpin.v2"
+)
+
+var logger = logging.MustGetLogger("txflow")
+
+// peerCommitCapacity bounds the number of peer commits observed by one export
+const peerCommitCapacity = 1 << 20
+
+// command line flags
+var (
+	app = kingpin.New("txflow", "Utility for exporting transaction flow timelines, for use with the bundled visualizer")
+
+	output = app.Flag("output", "A file to write the timeline JSON to.").Default(os.Stdout.Name()).OpenFile(os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0600)
+
+	export          = app.Command("export", "Collects the timelines recorded by orderers, optionally observing the commits of a peer, and writes the merged timeline.")
+	exportOrderers  = export.Flag("orderer", "The URL of an orderer's timeline, e.g. http://127.0.0.1:6060/debug/txtimeline. May be repeated.").Required().Strings()
+	exportPeer      = export.Flag("peer", "The address of a peer whose commits are observed while the export runs.").String()
+	exportChannelID = export.Flag("channelID", "The channel whose commits are observed on the peer.").String()
+	exportDuration  = export.Flag("duration", "How long to observe the peer's commits before collecting the orderers' timelines.").Default("30s").Duration()
+	exportMSPDir    = export.Flag("mspDir", "The MSP directory of the identity used to connect to the peer.").String()
+	exportMSPID     = export.Flag("mspID", "The MSP ID of the identity used to connect to the peer.").String()
+	exportTLSCA     = export.Flag("tlsCAFile", "The PEM encoded CA certificate used to verify the peer's TLS certificate, if TLS is enabled.").String()
+
+	merge       = app.Command("merge", "Merges previously exported timelines.")
+	mergeInputs = merge.Flag("input", "A timeline JSON file. May be repeated.").Required().ExistingFiles()
+)
+
+func main() {
+	command := kingpin.MustParse(app.Parse(os.Args[1:]))
+	defer (*output).Close()
+
+	var timeline *txtimeline.Timeline
+	var err error
+	switch command {
+	case export.FullCommand():
+		timeline, err = doExport()
+	case merge.FullCommand():
+		timeline, err = doMerge()
+	}
+	if err != nil {
+		app.Fatalf("Error running %s: %s", command, err)
+	}
+
+	if err := writeTimeline(*output, timeline); err != nil {
+		app.Fatalf("Error writing output: %s", err)
+	}
+}
+
+func doExport() (*txtimeline.Timeline, error) {
+	var timelines []*txtimeline.Timeline
+
+	if *exportPeer != "" {
+		if *exportChannelID == "" {
+			return nil, errors.New("a channel ID is required to observe a peer")
+		}
+		if err := mspmgmt.LoadLocalMsp(*exportMSPDir, nil, *exportMSPID); err != nil {
+			return nil, errors.Wrap(err, "failed to initialize local MSP")
+		}
+		commits, err := observePeer(*exportPeer, *exportChannelID, localmsp.NewSigner(), *exportDuration)
+		if err != nil {
+			return nil, err
+		}
+		timelines = append(timelines, commits)
+	}
+
+	for _, url := range *exportOrderers {
+		timeline, err := fetchTimeline(url)
+		if err != nil {
+			return nil, err
+		}
+		timelines = append(timelines, timeline)
+	}
+
+	return txtimeline.Merge(timelines...), nil
+}
+
+func doMerge() (*txtimeline.Timeline, error) {
+	var timelines []*txtimeline.Timeline
+	for _, input := range *mergeInputs {
+		f, err := os.Open(input)
+		if err != nil {
+			return nil, err
+		}
+		timeline, err := readTimeline(f)
+		f.Close()
+		if err != nil {
+			return nil, errors.WithMessage(err, input)
+		}
+		timelines = append(timelines, timeline)
+	}
+	return txtimeline.Merge(timelines...), nil
+}
+
+func fetchTimeline(url string) (*txtimeline.Timeline, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch timeline from %s", url)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch timeline from %s: %s", url, resp.Status)
+	}
+	timeline, err := readTimeline(resp.Body)
+	return timeline, errors.WithMessage(err, url)
+}
+
+func readTimeline(r io.Reader) (*txtimeline.Timeline, error) {
+	timeline := &txtimeline.Timeline{}
+	if err := json.NewDecoder(r).Decode(timeline); err != nil {
+		return nil, errors.Wrap(err, "error decoding timeline")
+	}
+	return timeline, nil
+}
+
+func writeTimeline(w io.Writer, timeline *txtimeline.Timeline) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(timeline)
+}
+
+// observePeer records the time at which the peer reports each transaction of
+// the channel committed, until the duration elapses.  Filtered blocks are
+// delivered once committed, so their arrival is taken as the commit time.
+func observePeer(address, channelID string, signer crypto.LocalSigner, duration time.Duration) (*txtimeline.Timeline, error) {
+	dialOpts := []grpc.DialOption{grpc.WithBlock(), grpc.WithTimeout(10 * time.Second)}
+	if *exportTLSCA != "" {
+		creds, err := credentials.NewClientTLSFromFile(*exportTLSCA, "")
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to load TLS CA certificate")
+		}
+		dialOpts = append(dialOpts, grpc.WithTransportCredentials(creds))
+	} else {
+		dialOpts = append(dialOpts, grpc.WithInsecure())
+	}
+	conn, err := grpc.Dial(address, dialOpts...)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to connect to %s", address)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), duration)
+	defer cancel()
+	client, err := pb.NewDeliverClient(conn).DeliverFiltered(ctx)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open deliver stream to %s", address)
+	}
+
+	env, err := utils.CreateSignedEnvelope(cb.HeaderType_DELIVER_SEEK_INFO, channelID, signer, &ab.SeekInfo{
+		Start:    &ab.SeekPosition{Type: &ab.SeekPosition_Newest{Newest: &ab.SeekNewest{}}},
+		Stop:     &ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: math.MaxUint64}}},
+		Behavior: ab.SeekInfo_BLOCK_UNTIL_READY,
+	}, 0, 0)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create seek request")
+	}
+	if err := client.Send(env); err != nil {
+		return nil, errors.Wrap(err, "failed to send seek request")
+	}
+
+	logger.Infof("Observing commits on channel %s of peer %s for %s", channelID, address, duration)
+	recorder := txtimeline.NewRecorder(peerCommitCapacity)
+	for {
+		resp, err := client.Recv()
+		if err != nil {
+			if ctx.Err() != nil {
+				return recorder.Timeline(), nil
+			}
+			return nil, errors.Wrapf(err, "error receiving from %s", address)
+		}
+		switch t := resp.Type.(type) {
+		case *pb.DeliverResponse_FilteredBlock:
+			for _, tx := range t.FilteredBlock.FilteredTransactions {
+				recorder.PeerCommitted(t.FilteredBlock.ChannelId, t.FilteredBlock.Number, tx.Txid, tx.TxValidationCode.String())
+			}
+		case *pb.DeliverResponse_Status:
+			return nil, errors.Errorf("peer %s ended the deliver stream with status %s", address, t.Status)
+		}
+	}
+}
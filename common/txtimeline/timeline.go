@@ -0,0 +1,221 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package txtimeline records when a transaction passes through each stage of
+// the transaction flow: received by the orderer's Broadcast service, enqueued
+// for ordering, cut into a block, and committed by a peer.  Timelines recorded
+// by different processes can be merged by transaction id and exported as JSON
+// for the visualizer bundled with the txflow tool.
+package txtimeline
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Entry is the timeline of a single transaction.  Stages which were not
+// observed are left nil.
+type Entry struct {
+	TxID              string     `json:"txid"`
+	ChannelID         string     `json:"channel"`
+	BroadcastReceived *time.Time `json:"broadcast_received,omitempty"`
+	Enqueued          *time.Time `json:"enqueued,omitempty"`
+	BlockCut          *time.Time `json:"block_cut,omitempty"`
+	BlockNumber       *uint64    `json:"block_number,omitempty"`
+	PeerCommitted     *time.Time `json:"peer_committed,omitempty"`
+	ValidationCode    string     `json:"validation_code,omitempty"`
+}
+
+// Timeline is the exported form of a set of entries.
+type Timeline struct {
+	Generated    time.Time `json:"generated"`
+	Transactions []*Entry  `json:"transactions"`
+}
+
+type entryKey struct {
+	channelID string
+	txID      string
+}
+
+// Recorder keeps the timelines of the most recent transactions in memory.
+// All methods are safe to call on a nil Recorder, in which case they do
+// nothing, so that call sites need not check whether recording is enabled.
+type Recorder struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[entryKey]*Entry
+	order    []entryKey
+	now      func() time.Time
+}
+
+// NewRecorder creates a Recorder which retains the timelines of at most
+// capacity transactions, evicting the oldest first.
+func NewRecorder(capacity int) *Recorder {
+	return &Recorder{
+		capacity: capacity,
+		entries:  make(map[entryKey]*Entry),
+		now:      time.Now,
+	}
+}
+
+// entry returns the entry for the transaction, creating it if needed.
+// The caller must hold the mutex.
+func (r *Recorder) entry(channelID, txID string) *Entry {
+	key := entryKey{channelID: channelID, txID: txID}
+	if e, ok := r.entries[key]; ok {
+		return e
+	}
+
+	if len(r.order) >= r.capacity {
+		delete(r.entries, r.order[0])
+		r.order = r.order[1:]
+	}
+	e := &Entry{TxID: txID, ChannelID: channelID}
+	r.entries[key] = e
+	r.order = append(r.order, key)
+	return e
+}
+
+func (r *Recorder) record(channelID, txID string, update func(e *Entry, now time.Time)) {
+	if r == nil || txID == "" || r.capacity <= 0 {
+		return
+	}
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	update(r.entry(channelID, txID), r.now())
+}
+
+// BroadcastReceived records that the orderer received the transaction
+func (r *Recorder) BroadcastReceived(channelID, txID string) {
+	r.record(channelID, txID, func(e *Entry, now time.Time) {
+		e.BroadcastReceived = &now
+	})
+}
+
+// Enqueued records that the orderer accepted the transaction for ordering
+func (r *Recorder) Enqueued(channelID, txID string) {
+	r.record(channelID, txID, func(e *Entry, now time.Time) {
+		e.Enqueued = &now
+	})
+}
+
+// BlockCut records that the transactions were cut into the given block
+func (r *Recorder) BlockCut(channelID string, blockNumber uint64, txIDs []string) {
+	for _, txID := range txIDs {
+		r.record(channelID, txID, func(e *Entry, now time.Time) {
+			number := blockNumber
+			e.BlockCut = &now
+			e.BlockNumber = &number
+		})
+	}
+}
+
+// PeerCommitted records that a peer committed the transaction in the given
+// block, with the given validation code
+func (r *Recorder) PeerCommitted(channelID string, blockNumber uint64, txID string, validationCode string) {
+	r.record(channelID, txID, func(e *Entry, now time.Time) {
+		number := blockNumber
+		e.PeerCommitted = &now
+		e.BlockNumber = &number
+		e.ValidationCode = validationCode
+	})
+}
+
+// Timeline returns a copy of the recorded entries, oldest first
+func (r *Recorder) Timeline() *Timeline {
+	timeline := &Timeline{Transactions: []*Entry{}}
+	if r == nil {
+		timeline.Generated = time.Now()
+		return timeline
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	timeline.Generated = r.now()
+	for _, key := range r.order {
+		e := *r.entries[key]
+		timeline.Transactions = append(timeline.Transactions, &e)
+	}
+	return timeline
+}
+
+// ServeHTTP writes the recorded timeline as JSON
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(r.Timeline()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Merge combines timelines recorded by different processes.  Entries for the
+// same transaction are joined, keeping for each stage the earliest time any
+// of the timelines observed.  Transactions are ordered by their first
+// observed stage.
+func Merge(timelines ...*Timeline) *Timeline {
+	merged := map[entryKey]*Entry{}
+	var generated time.Time
+	for _, timeline := range timelines {
+		if timeline.Generated.After(generated) {
+			generated = timeline.Generated
+		}
+		for _, e := range timeline.Transactions {
+			key := entryKey{channelID: e.ChannelID, txID: e.TxID}
+			m, ok := merged[key]
+			if !ok {
+				m = &Entry{TxID: e.TxID, ChannelID: e.ChannelID}
+				merged[key] = m
+			}
+			m.BroadcastReceived = earliest(m.BroadcastReceived, e.BroadcastReceived)
+			m.Enqueued = earliest(m.Enqueued, e.Enqueued)
+			m.BlockCut = earliest(m.BlockCut, e.BlockCut)
+			m.PeerCommitted = earliest(m.PeerCommitted, e.PeerCommitted)
+			if m.BlockNumber == nil {
+				m.BlockNumber = e.BlockNumber
+			}
+			if m.ValidationCode == "" {
+				m.ValidationCode = e.ValidationCode
+			}
+		}
+	}
+
+	result := &Timeline{Generated: generated, Transactions: make([]*Entry, 0, len(merged))}
+	for _, e := range merged {
+		result.Transactions = append(result.Transactions, e)
+	}
+	sort.Slice(result.Transactions, func(i, j int) bool {
+		ti, tj := result.Transactions[i].start(), result.Transactions[j].start()
+		if !ti.Equal(tj) {
+			return ti.Before(tj)
+		}
+		return result.Transactions[i].TxID < result.Transactions[j].TxID
+	})
+	return result
+}
+
+// start returns the time of the first stage observed for the entry
+func (e *Entry) start() time.Time {
+	var start *time.Time
+	for _, t := range []*time.Time{e.BroadcastReceived, e.Enqueued, e.BlockCut, e.PeerCommitted} {
+		start = earliest(start, t)
+	}
+	if start == nil {
+		return time.Time{}
+	}
+	return *start
+}
+
+func earliest(a, b *time.Time) *time.Time {
+	if a == nil {
+		return b
+	}
+	if b == nil || a.Before(*b) {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,123 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package txtimeline
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fakeClock() func() time.Time {
+	now := time.Unix(1000, 0).UTC()
+	return func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder(10)
+	r.now = fakeClock()
+
+	r.BroadcastReceived("ch", "tx1")
+	r.Enqueued("ch", "tx1")
+	r.BroadcastReceived("ch", "tx2")
+	r.BlockCut("ch", 5, []string{"tx1", "tx2"})
+	r.PeerCommitted("ch", 5, "tx1", "VALID")
+	r.BroadcastReceived("ch", "")
+
+	timeline := r.Timeline()
+	require.Len(t, timeline.Transactions, 2)
+
+	tx1 := timeline.Transactions[0]
+	assert.Equal(t, "tx1", tx1.TxID)
+	assert.Equal(t, "ch", tx1.ChannelID)
+	assert.Equal(t, time.Unix(1001, 0).UTC(), *tx1.BroadcastReceived)
+	assert.Equal(t, time.Unix(1002, 0).UTC(), *tx1.Enqueued)
+	assert.Equal(t, time.Unix(1004, 0).UTC(), *tx1.BlockCut)
+	assert.Equal(t, time.Unix(1006, 0).UTC(), *tx1.PeerCommitted)
+	assert.Equal(t, uint64(5), *tx1.BlockNumber)
+	assert.Equal(t, "VALID", tx1.ValidationCode)
+
+	tx2 := timeline.Transactions[1]
+	assert.Equal(t, "tx2", tx2.TxID)
+	assert.Nil(t, tx2.Enqueued)
+	assert.Nil(t, tx2.PeerCommitted)
+}
+
+func TestRecorderEviction(t *testing.T) {
+	r := NewRecorder(2)
+	r.BroadcastReceived("ch", "tx1")
+	r.BroadcastReceived("ch", "tx2")
+	r.BroadcastReceived("ch", "tx3")
+
+	timeline := r.Timeline()
+	require.Len(t, timeline.Transactions, 2)
+	assert.Equal(t, "tx2", timeline.Transactions[0].TxID)
+	assert.Equal(t, "tx3", timeline.Transactions[1].TxID)
+}
+
+func TestNilRecorder(t *testing.T) {
+	var r *Recorder
+	r.BroadcastReceived("ch", "tx1")
+	r.Enqueued("ch", "tx1")
+	r.BlockCut("ch", 1, []string{"tx1"})
+	r.PeerCommitted("ch", 1, "tx1", "VALID")
+	assert.Empty(t, r.Timeline().Transactions)
+}
+
+func TestServeHTTP(t *testing.T) {
+	r := NewRecorder(10)
+	r.BroadcastReceived("ch", "tx1")
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/debug/txtimeline", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+
+	timeline := &Timeline{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), timeline))
+	require.Len(t, timeline.Transactions, 1)
+	assert.Equal(t, "tx1", timeline.Transactions[0].TxID)
+	assert.NotNil(t, timeline.Transactions[0].BroadcastReceived)
+}
+
+func TestMerge(t *testing.T) {
+	orderer := NewRecorder(10)
+	orderer.now = fakeClock()
+	orderer.BroadcastReceived("ch", "tx2")
+	orderer.BroadcastReceived("ch", "tx1")
+	orderer.BlockCut("ch", 3, []string{"tx2", "tx1"})
+
+	peer := NewRecorder(10)
+	peer.now = func() time.Time { return time.Unix(2000, 0).UTC() }
+	peer.PeerCommitted("ch", 3, "tx1", "MVCC_READ_CONFLICT")
+	peer.PeerCommitted("other", 1, "tx9", "VALID")
+
+	merged := Merge(orderer.Timeline(), peer.Timeline())
+	require.Len(t, merged.Transactions, 3)
+	assert.Equal(t, time.Unix(2000, 0).UTC(), merged.Generated)
+
+	assert.Equal(t, "tx2", merged.Transactions[0].TxID)
+	assert.Nil(t, merged.Transactions[0].PeerCommitted)
+
+	tx1 := merged.Transactions[1]
+	assert.Equal(t, "tx1", tx1.TxID)
+	assert.Equal(t, time.Unix(1002, 0).UTC(), *tx1.BroadcastReceived)
+	assert.Equal(t, time.Unix(1004, 0).UTC(), *tx1.BlockCut)
+	assert.Equal(t, time.Unix(2000, 0).UTC(), *tx1.PeerCommitted)
+	assert.Equal(t, "MVCC_READ_CONFLICT", tx1.ValidationCode)
+
+	assert.Equal(t, "tx9", merged.Transactions[2].TxID)
+	assert.Equal(t, "other", merged.Transactions[2].ChannelID)
+}
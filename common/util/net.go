@@ -7,10 +7,17 @@ SPDX-License-Identifier: Apache-2.0
 package util
 
 import (
+	"time"
+
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 )
 
+// HeartbeatIntervalKey is the gRPC metadata key by which the client of a
+// stream asks for heartbeats at the interval it holds, such as "10s"
+const HeartbeatIntervalKey = "heartbeat-interval"
+
 func ExtractRemoteAddress(ctx context.Context) string {
 	var remoteAddress string
 	p, ok := peer.FromContext(ctx)
@@ -22,3 +29,17 @@ func ExtractRemoteAddress(ctx context.Context) string {
 	}
 	return remoteAddress
 }
+
+// ExtractHeartbeatInterval returns the interval at which the client of the
+// stream of the context asks for heartbeats, or 0 if it asks for none
+func ExtractHeartbeatInterval(ctx context.Context) time.Duration {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md[HeartbeatIntervalKey]) == 0 {
+		return 0
+	}
+	interval, err := time.ParseDuration(md[HeartbeatIntervalKey][0])
+	if err != nil || interval < 0 {
+		return 0
+	}
+	return interval
+}
@@ -8,9 +8,11 @@ package util
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
 )
 
@@ -34,3 +36,16 @@ func TestExtractAddress(t *testing.T) {
 	})
 	assert.Equal(t, "1.2.3.4:5000", ExtractRemoteAddress(ctx))
 }
+
+func TestExtractHeartbeatInterval(t *testing.T) {
+	assert.Zero(t, ExtractHeartbeatInterval(context.Background()))
+
+	for value, expected := range map[string]time.Duration{
+		"10s":   10 * time.Second,
+		"-1s":   0,
+		"often": 0,
+	} {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(HeartbeatIntervalKey, value))
+		assert.Equal(t, expected, ExtractHeartbeatInterval(ctx), value)
+	}
+}
@@ -0,0 +1,727 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package wasm
+
+import (
+	"bytes"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/pkg/errors"
+)
+
+var header = []byte{0x00, 'a', 's', 'm', 0x01, 0x00, 0x00, 0x00}
+
+const (
+	sectionCustom    = 0
+	sectionType      = 1
+	sectionImport    = 2
+	sectionFunction  = 3
+	sectionTable     = 4
+	sectionMemory    = 5
+	sectionGlobal    = 6
+	sectionExport    = 7
+	sectionStart     = 8
+	sectionElement   = 9
+	sectionCode      = 10
+	sectionData      = 11
+	sectionDataCount = 12
+)
+
+// instr is a decoded instruction, whose structured control instructions
+// record where their branches lead
+type instr struct {
+	op     uint16
+	imm    uint64   // 常量、索引、内存偏移量或分支深度
+	arity  int      // block、loop与if的结果个数
+	elsePc int      // if对应的else指令位置，没有else时为0
+	endPc  int      // block、loop、if与else对应的end指令位置
+	table  []uint32 // br_table的分支深度，默认深度在imm中
+}
+
+// opcodes of the instructions prefixed with 0xfc
+const (
+	opMemoryCopy = 0xfc00 | 10
+	opMemoryFill = 0xfc00 | 11
+)
+
+type reader struct {
+	buf []byte
+	pos int
+}
+
+func (r *reader) done() bool {
+	return r.pos >= len(r.buf)
+}
+
+func (r *reader) byte() (byte, error) {
+	if r.pos >= len(r.buf) {
+		return 0, errors.New("unexpected end of module")
+	}
+	b := r.buf[r.pos]
+	r.pos++
+	return b, nil
+}
+
+func (r *reader) bytes(n uint32) ([]byte, error) {
+	if uint64(n) > uint64(len(r.buf)-r.pos) {
+		return nil, errors.New("unexpected end of module")
+	}
+	b := r.buf[r.pos : r.pos+int(n)]
+	r.pos += int(n)
+	return b, nil
+}
+
+// u32 reads an unsigned LEB128 integer of at most 32 bits
+func (r *reader) u32() (uint32, error) {
+	var result uint32
+	for shift := uint(0); ; shift += 7 {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		if shift == 28 && b > 0x0f {
+			return 0, errors.New("integer too large")
+		}
+		result |= uint32(b&0x7f) << shift
+		if b&0x80 == 0 {
+			return result, nil
+		}
+	}
+}
+
+// signed reads a signed LEB128 integer of at most the given number of bits
+func (r *reader) signed(bits uint) (int64, error) {
+	var result int64
+	var shift uint
+	for {
+		b, err := r.byte()
+		if err != nil {
+			return 0, err
+		}
+		if shift >= bits {
+			return 0, errors.New("integer too large")
+		}
+		result |= int64(b&0x7f) << shift
+		shift += 7
+		if b&0x80 == 0 {
+			if shift < 64 && b&0x40 != 0 {
+				result |= -1 << shift
+			}
+			return result, nil
+		}
+	}
+}
+
+func (r *reader) count() (uint32, error) {
+	n, err := r.u32()
+	if err != nil {
+		return 0, err
+	}
+	// 每个元素至少占用一个字节，避免按伪造的个数分配内存
+	if uint64(n) > uint64(len(r.buf)-r.pos) {
+		return 0, errors.New("vector length exceeds the section")
+	}
+	return n, nil
+}
+
+func (r *reader) name() (string, error) {
+	n, err := r.u32()
+	if err != nil {
+		return "", err
+	}
+	b, err := r.bytes(n)
+	if err != nil {
+		return "", err
+	}
+	if !utf8.Valid(b) {
+		return "", errors.New("name is not valid UTF-8")
+	}
+	return string(b), nil
+}
+
+func (r *reader) valueType() (ValueType, error) {
+	b, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	switch ValueType(b) {
+	case I32, I64:
+		return ValueType(b), nil
+	case 0x7d, 0x7c:
+		return 0, errors.New("floating point types are not supported")
+	default:
+		return 0, errors.Errorf("unsupported value type 0x%x", b)
+	}
+}
+
+// Compile decodes and validates a module in the WebAssembly binary format
+func Compile(binary []byte) (*Module, error) {
+	r := &reader{buf: binary}
+	if magic, err := r.bytes(uint32(len(header))); err != nil || !bytes.Equal(magic, header) {
+		return nil, errors.New("not a WebAssembly 1.0 module")
+	}
+
+	m := &Module{exports: map[string]export{}}
+	var funcTypes []uint32
+	seen := map[byte]bool{}
+	for !r.done() {
+		id, err := r.byte()
+		if err != nil {
+			return nil, err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return nil, err
+		}
+		payload, err := r.bytes(size)
+		if err != nil {
+			return nil, err
+		}
+		if id != sectionCustom {
+			if seen[id] {
+				return nil, errors.Errorf("duplicate section %d", id)
+			}
+			seen[id] = true
+		}
+
+		sr := &reader{buf: payload}
+		switch id {
+		case sectionCustom, sectionTable, sectionElement, sectionDataCount:
+			// 不支持表相关的指令，表与元素段不会被使用
+			continue
+		case sectionType:
+			err = m.decodeTypes(sr)
+		case sectionImport:
+			err = m.decodeImports(sr)
+		case sectionFunction:
+			funcTypes, err = m.decodeFunctions(sr)
+		case sectionMemory:
+			err = m.decodeMemory(sr)
+		case sectionGlobal:
+			err = m.decodeGlobals(sr)
+		case sectionExport:
+			err = m.decodeExports(sr, len(funcTypes))
+		case sectionStart:
+			err = m.decodeStart(sr, len(funcTypes))
+		case sectionCode:
+			err = m.decodeCode(sr, funcTypes)
+		case sectionData:
+			err = m.decodeData(sr)
+		default:
+			err = errors.Errorf("unknown section %d", id)
+		}
+		if err != nil {
+			return nil, errors.WithMessage(err, "invalid module")
+		}
+		if !sr.done() {
+			return nil, errors.Errorf("invalid module: section %d is longer than its contents", id)
+		}
+	}
+	if len(funcTypes) != len(m.funcs) {
+		return nil, errors.New("invalid module: function and code sections do not match")
+	}
+	return m, nil
+}
+
+func (m *Module) decodeTypes(r *reader) error {
+	n, err := r.count()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		form, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if form != 0x60 {
+			return errors.Errorf("unsupported type form 0x%x", form)
+		}
+		var ft FuncType
+		for _, types := range []*[]ValueType{&ft.Params, &ft.Results} {
+			count, err := r.count()
+			if err != nil {
+				return err
+			}
+			for j := uint32(0); j < count; j++ {
+				vt, err := r.valueType()
+				if err != nil {
+					return err
+				}
+				*types = append(*types, vt)
+			}
+		}
+		m.types = append(m.types, ft)
+	}
+	return nil
+}
+
+func (m *Module) decodeImports(r *reader) error {
+	n, err := r.count()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		module, err := r.name()
+		if err != nil {
+			return err
+		}
+		name, err := r.name()
+		if err != nil {
+			return err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if kind != exportFunc {
+			return errors.Errorf("import %s.%s is not a function, only functions may be imported", module, name)
+		}
+		typeIdx, err := r.u32()
+		if err != nil {
+			return err
+		}
+		if typeIdx >= uint32(len(m.types)) {
+			return errors.Errorf("import %s.%s has unknown type %d", module, name, typeIdx)
+		}
+		m.imports = append(m.imports, funcImport{module: module, name: name, typeIdx: typeIdx})
+	}
+	return nil
+}
+
+func (m *Module) decodeFunctions(r *reader) ([]uint32, error) {
+	n, err := r.count()
+	if err != nil {
+		return nil, err
+	}
+	funcTypes := make([]uint32, n)
+	for i := range funcTypes {
+		if funcTypes[i], err = r.u32(); err != nil {
+			return nil, err
+		}
+		if funcTypes[i] >= uint32(len(m.types)) {
+			return nil, errors.Errorf("function %d has unknown type %d", i, funcTypes[i])
+		}
+	}
+	return funcTypes, nil
+}
+
+func (m *Module) decodeMemory(r *reader) error {
+	n, err := r.count()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	if n > 1 {
+		return errors.New("at most one memory is supported")
+	}
+	flags, err := r.byte()
+	if err != nil {
+		return err
+	}
+	mem := &memoryType{}
+	if mem.min, err = r.u32(); err != nil {
+		return err
+	}
+	switch flags {
+	case 0x00:
+	case 0x01:
+		if mem.max, err = r.u32(); err != nil {
+			return err
+		}
+		mem.hasMax = true
+		if mem.max < mem.min {
+			return errors.New("memory maximum is below its minimum")
+		}
+	default:
+		return errors.Errorf("unsupported memory flags 0x%x", flags)
+	}
+	if mem.min > maxPages || (mem.hasMax && mem.max > maxPages) {
+		return errors.New("memory exceeds 4GiB")
+	}
+	m.memory = mem
+	return nil
+}
+
+// constExpr reads an initializer expression made of a single constant
+func constExpr(r *reader, vt ValueType) (uint64, error) {
+	op, err := r.byte()
+	if err != nil {
+		return 0, err
+	}
+	var v int64
+	switch {
+	case op == 0x41 && vt == I32:
+		v, err = r.signed(32)
+		v = int64(uint32(v))
+	case op == 0x42 && vt == I64:
+		v, err = r.signed(64)
+	default:
+		return 0, errors.Errorf("unsupported initializer expression 0x%x", op)
+	}
+	if err != nil {
+		return 0, err
+	}
+	if end, err := r.byte(); err != nil || end != 0x0b {
+		return 0, errors.New("initializer expression is not terminated")
+	}
+	return uint64(v), nil
+}
+
+func (m *Module) decodeGlobals(r *reader) error {
+	n, err := r.count()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		vt, err := r.valueType()
+		if err != nil {
+			return err
+		}
+		mutable, err := r.byte()
+		if err != nil {
+			return err
+		}
+		if mutable > 1 {
+			return errors.Errorf("invalid mutability 0x%x", mutable)
+		}
+		init, err := constExpr(r, vt)
+		if err != nil {
+			return err
+		}
+		m.globals = append(m.globals, global{mutable: mutable == 1, init: init})
+	}
+	return nil
+}
+
+func (m *Module) decodeExports(r *reader, numFuncs int) error {
+	n, err := r.count()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		name, err := r.name()
+		if err != nil {
+			return err
+		}
+		kind, err := r.byte()
+		if err != nil {
+			return err
+		}
+		index, err := r.u32()
+		if err != nil {
+			return err
+		}
+		switch kind {
+		case exportFunc:
+			if index >= uint32(len(m.imports)+numFuncs) {
+				return errors.Errorf("export %s has unknown function %d", name, index)
+			}
+		case exportMemory:
+			if m.memory == nil || index != 0 {
+				return errors.Errorf("export %s has unknown memory %d", name, index)
+			}
+		case exportGlobal:
+			if index >= uint32(len(m.globals)) {
+				return errors.Errorf("export %s has unknown global %d", name, index)
+			}
+		case exportTable:
+		default:
+			return errors.Errorf("export %s has unknown kind 0x%x", name, kind)
+		}
+		if _, ok := m.exports[name]; ok {
+			return errors.Errorf("duplicate export %s", name)
+		}
+		m.exports[name] = export{kind: kind, index: index}
+	}
+	return nil
+}
+
+func (m *Module) decodeStart(r *reader, numFuncs int) error {
+	index, err := r.u32()
+	if err != nil {
+		return err
+	}
+	if index >= uint32(len(m.imports)+numFuncs) {
+		return errors.Errorf("start function %d is unknown", index)
+	}
+	m.start = &index
+	return nil
+}
+
+func (m *Module) decodeData(r *reader) error {
+	n, err := r.count()
+	if err != nil {
+		return err
+	}
+	for i := uint32(0); i < n; i++ {
+		flags, err := r.u32()
+		if err != nil {
+			return err
+		}
+		switch flags {
+		case 0:
+		case 2:
+			if memIdx, err := r.u32(); err != nil || memIdx != 0 {
+				return errors.New("data segment of unknown memory")
+			}
+		default:
+			return errors.New("passive data segments are not supported")
+		}
+		if m.memory == nil {
+			return errors.New("data segment without memory")
+		}
+		offset, err := constExpr(r, I32)
+		if err != nil {
+			return err
+		}
+		size, err := r.u32()
+		if err != nil {
+			return err
+		}
+		init, err := r.bytes(size)
+		if err != nil {
+			return err
+		}
+		m.data = append(m.data, dataSegment{offset: uint32(offset), init: init})
+	}
+	return nil
+}
+
+func (m *Module) decodeCode(r *reader, funcTypes []uint32) error {
+	n, err := r.count()
+	if err != nil {
+		return err
+	}
+	if int(n) != len(funcTypes) {
+		return errors.New("function and code sections do not match")
+	}
+	for i := uint32(0); i < n; i++ {
+		size, err := r.u32()
+		if err != nil {
+			return err
+		}
+		code, err := r.bytes(size)
+		if err != nil {
+			return err
+		}
+		fn, err := m.decodeFunction(&reader{buf: code}, funcTypes[i], len(m.imports)+len(funcTypes))
+		if err != nil {
+			return errors.WithMessage(err, "function "+strconv.Itoa(int(i)+len(m.imports)))
+		}
+		m.funcs = append(m.funcs, fn)
+	}
+	return nil
+}
+
+func (m *Module) decodeFunction(r *reader, typeIdx uint32, numFuncs int) (function, error) {
+	ft := m.types[typeIdx]
+	groups, err := r.count()
+	if err != nil {
+		return function{}, err
+	}
+	numLocals := 0
+	for i := uint32(0); i < groups; i++ {
+		count, err := r.u32()
+		if err != nil {
+			return function{}, err
+		}
+		if uint64(count) > uint64(maxLocals-numLocals) {
+			return function{}, errors.New("too many locals")
+		}
+		if _, err := r.valueType(); err != nil {
+			return function{}, err
+		}
+		numLocals += int(count)
+	}
+	if len(ft.Params)+numLocals > maxLocals {
+		return function{}, errors.New("too many locals")
+	}
+	body, err := m.decodeBody(r, len(ft.Params)+numLocals, numFuncs)
+	if err != nil {
+		return function{}, err
+	}
+	return function{typeIdx: typeIdx, numLocals: numLocals, body: body}, nil
+}
+
+// decodeBody decodes the instructions of a function, resolving the targets
+// of its structured control instructions
+func (m *Module) decodeBody(r *reader, numLocals, numFuncs int) ([]instr, error) {
+	var body []instr
+	var ctrl []int // 未结束的block、loop与if指令位置
+	for {
+		op, err := r.byte()
+		if err != nil {
+			return nil, errors.New("unexpected end of function body")
+		}
+		in := instr{op: uint16(op)}
+		switch {
+		case op == 0x00, op == 0x01, op == 0x0f, op == 0x1a, op == 0x1b:
+		case op == 0x02, op == 0x03, op == 0x04:
+			bt, err := r.byte()
+			if err != nil {
+				return nil, err
+			}
+			switch bt {
+			case 0x40:
+			case byte(I32), byte(I64):
+				in.arity = 1
+			default:
+				return nil, errors.Errorf("unsupported block type 0x%x", bt)
+			}
+			ctrl = append(ctrl, len(body))
+		case op == 0x05:
+			if len(ctrl) == 0 || body[ctrl[len(ctrl)-1]].op != 0x04 || body[ctrl[len(ctrl)-1]].elsePc != 0 {
+				return nil, errors.New("else without if")
+			}
+			body[ctrl[len(ctrl)-1]].elsePc = len(body)
+		case op == 0x0b:
+			if len(ctrl) == 0 {
+				if !r.done() {
+					return nil, errors.New("instructions after the end of the function")
+				}
+				return append(body, in), nil
+			}
+			opener := &body[ctrl[len(ctrl)-1]]
+			opener.endPc = len(body)
+			if opener.elsePc != 0 {
+				body[opener.elsePc].endPc = len(body)
+			}
+			ctrl = ctrl[:len(ctrl)-1]
+		case op == 0x0c, op == 0x0d:
+			if in.imm, err = branchDepth(r, len(ctrl)); err != nil {
+				return nil, err
+			}
+		case op == 0x0e:
+			n, err := r.count()
+			if err != nil {
+				return nil, err
+			}
+			in.table = make([]uint32, n)
+			for i := range in.table {
+				depth, err := branchDepth(r, len(ctrl))
+				if err != nil {
+					return nil, err
+				}
+				in.table[i] = uint32(depth)
+			}
+			if in.imm, err = branchDepth(r, len(ctrl)); err != nil {
+				return nil, err
+			}
+		case op == 0x10:
+			idx, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			if uint64(idx) >= uint64(numFuncs) {
+				return nil, errors.Errorf("call of unknown function %d", idx)
+			}
+			in.imm = uint64(idx)
+		case op == 0x1c:
+			if n, err := r.u32(); err != nil || n != 1 {
+				return nil, errors.New("invalid typed select")
+			}
+			if _, err := r.valueType(); err != nil {
+				return nil, err
+			}
+			in.op = 0x1b
+		case op >= 0x20 && op <= 0x22:
+			idx, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			if uint64(idx) >= uint64(numLocals) {
+				return nil, errors.Errorf("unknown local %d", idx)
+			}
+			in.imm = uint64(idx)
+		case op == 0x23, op == 0x24:
+			idx, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			if idx >= uint32(len(m.globals)) {
+				return nil, errors.Errorf("unknown global %d", idx)
+			}
+			if op == 0x24 && !m.globals[idx].mutable {
+				return nil, errors.Errorf("global %d is immutable", idx)
+			}
+			in.imm = uint64(idx)
+		case op >= 0x28 && op <= 0x3e && op != 0x2a && op != 0x2b && op != 0x38 && op != 0x39:
+			if m.memory == nil {
+				return nil, errors.New("memory instruction without memory")
+			}
+			if _, err := r.u32(); err != nil { // 对齐提示
+				return nil, err
+			}
+			offset, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			in.imm = uint64(offset)
+		case op == 0x3f, op == 0x40:
+			if m.memory == nil {
+				return nil, errors.New("memory instruction without memory")
+			}
+			if b, err := r.byte(); err != nil || b != 0 {
+				return nil, errors.New("unknown memory")
+			}
+		case op == 0x41:
+			v, err := r.signed(32)
+			if err != nil {
+				return nil, err
+			}
+			in.imm = uint64(uint32(v))
+		case op == 0x42:
+			v, err := r.signed(64)
+			if err != nil {
+				return nil, err
+			}
+			in.imm = uint64(v)
+		case op >= 0x45 && op <= 0x5a, op >= 0x67 && op <= 0x8a, op == 0xa7, op == 0xac, op == 0xad, op >= 0xc0 && op <= 0xc4:
+		case op == 0xfc:
+			sub, err := r.u32()
+			if err != nil {
+				return nil, err
+			}
+			in.op = 0xfc00 | uint16(sub)
+			switch in.op {
+			case opMemoryCopy, opMemoryFill:
+				if m.memory == nil {
+					return nil, errors.New("memory instruction without memory")
+				}
+				reserved := 1
+				if in.op == opMemoryCopy {
+					reserved = 2
+				}
+				for i := 0; i < reserved; i++ {
+					if b, err := r.byte(); err != nil || b != 0 {
+						return nil, errors.New("unknown memory")
+					}
+				}
+			default:
+				return nil, errors.Errorf("unsupported instruction 0xfc %d", sub)
+			}
+		default:
+			return nil, errors.Errorf("unsupported instruction 0x%x", op)
+		}
+		body = append(body, in)
+	}
+}
+
+func branchDepth(r *reader, nesting int) (uint64, error) {
+	depth, err := r.u32()
+	if err != nil {
+		return 0, err
+	}
+	// 最外层的标签是函数本身
+	if uint64(depth) > uint64(nesting) {
+		return 0, errors.Errorf("invalid branch depth %d", depth)
+	}
+	return uint64(depth), nil
+}
@@ -0,0 +1,643 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package wasm
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+
+	"github.com/pkg/errors"
+)
+
+// Instance is an instantiated module with its own memory and globals.  An
+// instance is not safe for concurrent use.
+type Instance struct {
+	module   *Module
+	hosts    []HostFunc
+	memory   []byte
+	maxPages uint32
+	globals  []uint64
+	fuel     uint64
+	stack    []uint64
+	depth    int
+}
+
+// trap aborts the execution of an instance
+type trap struct {
+	err error
+}
+
+func (inst *Instance) trap(format string, args ...interface{}) {
+	panic(trap{err: errors.Errorf("trap: "+format, args...)})
+}
+
+// catch converts the trap, or any runtime error of the interpreter raised by
+// an invalid module, into an error
+func catch(err *error) {
+	switch r := recover().(type) {
+	case nil:
+	case trap:
+		*err = r.err
+	default:
+		*err = errors.Errorf("trap: %v", r)
+	}
+}
+
+// Instantiate creates an instance of the module whose imports are resolved
+// from the host functions, keyed by "module.name", runs its start function
+// and returns it.  The limits bound the resources of the instance, including
+// those its start function consumes.
+func (m *Module) Instantiate(hosts map[string]HostFunc, limits Limits) (inst *Instance, err error) {
+	inst = &Instance{
+		module:   m,
+		hosts:    make([]HostFunc, len(m.imports)),
+		maxPages: limits.MaxMemoryPages,
+		fuel:     limits.Fuel,
+	}
+	for i, imp := range m.imports {
+		host, ok := hosts[imp.module+"."+imp.name]
+		if !ok {
+			return nil, errors.Errorf("unknown import %s.%s", imp.module, imp.name)
+		}
+		if !host.Type.Equal(m.types[imp.typeIdx]) {
+			return nil, errors.Errorf("import %s.%s has the wrong signature", imp.module, imp.name)
+		}
+		inst.hosts[i] = host
+	}
+
+	if m.memory != nil {
+		if m.memory.hasMax && m.memory.max < inst.maxPages {
+			inst.maxPages = m.memory.max
+		}
+		if m.memory.min > inst.maxPages {
+			return nil, errors.Errorf("module requires %d pages of memory, exceeding the limit of %d", m.memory.min, inst.maxPages)
+		}
+		inst.memory = make([]byte, int(m.memory.min)*PageSize)
+	}
+	for _, seg := range m.data {
+		if uint64(seg.offset)+uint64(len(seg.init)) > uint64(len(inst.memory)) {
+			return nil, errors.New("data segment exceeds the memory")
+		}
+		copy(inst.memory[seg.offset:], seg.init)
+	}
+	inst.globals = make([]uint64, len(m.globals))
+	for i, g := range m.globals {
+		inst.globals[i] = g.init
+	}
+
+	if m.start != nil {
+		if ft := m.funcType(*m.start); len(ft.Params) != 0 || len(ft.Results) != 0 {
+			return nil, errors.New("start function has parameters or results")
+		}
+		defer catch(&err)
+		inst.call(*m.start)
+	}
+	return inst, nil
+}
+
+// Call calls the function exported under the name with the arguments, i32
+// arguments being passed in the low 32 bits, and returns its results
+func (inst *Instance) Call(name string, args ...uint64) (results []uint64, err error) {
+	exp, ok := inst.module.exports[name]
+	if !ok || exp.kind != exportFunc {
+		return nil, errors.Errorf("no function exported as %s", name)
+	}
+	ft := inst.module.funcType(exp.index)
+	if len(args) != len(ft.Params) {
+		return nil, errors.Errorf("function %s takes %d arguments, got %d", name, len(ft.Params), len(args))
+	}
+
+	defer catch(&err)
+	inst.stack = inst.stack[:0]
+	for i, arg := range args {
+		if ft.Params[i] == I32 {
+			arg = uint64(uint32(arg))
+		}
+		inst.stack = append(inst.stack, arg)
+	}
+	inst.call(exp.index)
+	results = make([]uint64, len(ft.Results))
+	copy(results, inst.stack[len(inst.stack)-len(results):])
+	return results, nil
+}
+
+// Fuel returns the fuel left to the instance
+func (inst *Instance) Fuel() uint64 {
+	return inst.fuel
+}
+
+// Memory returns the linear memory of the instance, which its calls may
+// replace when they grow it
+func (inst *Instance) Memory() []byte {
+	return inst.memory
+}
+
+// Read returns a copy of size bytes of the memory at ptr
+func (inst *Instance) Read(ptr, size uint32) ([]byte, error) {
+	if uint64(ptr)+uint64(size) > uint64(len(inst.memory)) {
+		return nil, errors.Errorf("range [%d, %d) is out of the memory", ptr, uint64(ptr)+uint64(size))
+	}
+	data := make([]byte, size)
+	copy(data, inst.memory[ptr:])
+	return data, nil
+}
+
+// Write copies the data into the memory at ptr
+func (inst *Instance) Write(ptr uint32, data []byte) error {
+	if uint64(ptr)+uint64(len(data)) > uint64(len(inst.memory)) {
+		return errors.Errorf("range [%d, %d) is out of the memory", ptr, uint64(ptr)+uint64(len(data)))
+	}
+	copy(inst.memory[ptr:], data)
+	return nil
+}
+
+func (inst *Instance) push(v uint64) {
+	if len(inst.stack) >= maxStackSize {
+		inst.trap("stack overflow")
+	}
+	inst.stack = append(inst.stack, v)
+}
+
+func (inst *Instance) pop() uint64 {
+	if len(inst.stack) == 0 {
+		inst.trap("stack underflow")
+	}
+	v := inst.stack[len(inst.stack)-1]
+	inst.stack = inst.stack[:len(inst.stack)-1]
+	return v
+}
+
+func (inst *Instance) pop32() uint32 {
+	return uint32(inst.pop())
+}
+
+func (inst *Instance) push32(v uint32) {
+	inst.push(uint64(v))
+}
+
+func (inst *Instance) pushBool(b bool) {
+	if b {
+		inst.push(1)
+	} else {
+		inst.push(0)
+	}
+}
+
+// call calls the function of the index space of the module, taking its
+// arguments from the stack and leaving its results in their place
+func (inst *Instance) call(idx uint32) {
+	if inst.depth >= maxCallDepth {
+		inst.trap("call stack exhausted")
+	}
+	inst.depth++
+	defer func() { inst.depth-- }()
+
+	ft := inst.module.funcType(idx)
+	if len(inst.stack) < len(ft.Params) {
+		inst.trap("stack underflow")
+	}
+	base := len(inst.stack) - len(ft.Params)
+
+	if idx < uint32(len(inst.hosts)) {
+		args := make([]uint64, len(ft.Params))
+		copy(args, inst.stack[base:])
+		inst.stack = inst.stack[:base]
+		results, err := inst.hosts[idx].Fn(inst, args)
+		if err != nil {
+			panic(trap{err: errors.WithMessage(err, "trap in host function")})
+		}
+		if len(results) != len(ft.Results) {
+			inst.trap("host function returned %d results, expected %d", len(results), len(ft.Results))
+		}
+		for _, r := range results {
+			inst.push(r)
+		}
+		return
+	}
+
+	fn := &inst.module.funcs[idx-uint32(len(inst.hosts))]
+	locals := make([]uint64, len(ft.Params)+fn.numLocals)
+	copy(locals, inst.stack[base:])
+	inst.stack = inst.stack[:base]
+	inst.run(fn, len(ft.Results), locals)
+
+	n := len(inst.stack)
+	if n < base+len(ft.Results) {
+		inst.trap("stack underflow")
+	}
+	copy(inst.stack[base:], inst.stack[n-len(ft.Results):])
+	inst.stack = inst.stack[:base+len(ft.Results)]
+}
+
+// label is the target of the branches out of a block, loop or function
+type label struct {
+	loop   bool
+	arity  int
+	height int // 进入时的操作数栈高度
+	cont   int // 分支的目标指令位置
+}
+
+// address returns the index in memory of the access of size bytes at the
+// address on the stack plus the offset
+func (inst *Instance) address(offset uint64, size uint64) uint64 {
+	ea := uint64(inst.pop32()) + offset
+	if ea+size > uint64(len(inst.memory)) {
+		inst.trap("out of bounds memory access")
+	}
+	return ea
+}
+
+func (inst *Instance) run(fn *function, arity int, locals []uint64) {
+	labels := []label{{arity: arity, height: len(inst.stack), cont: len(fn.body)}}
+	body := fn.body
+	mem := binary.LittleEndian
+
+	// branch unwinds the stack to the label at the depth and returns where
+	// the execution continues
+	branch := func(depth uint64) int {
+		l := labels[len(labels)-1-int(depth)]
+		n := l.arity
+		if l.loop {
+			n = 0
+		}
+		if len(inst.stack)-n < l.height {
+			inst.trap("stack underflow")
+		}
+		copy(inst.stack[l.height:], inst.stack[len(inst.stack)-n:])
+		inst.stack = inst.stack[:l.height+n]
+		if l.loop {
+			labels = labels[:len(labels)-int(depth)]
+		} else {
+			labels = labels[:len(labels)-1-int(depth)]
+		}
+		return l.cont
+	}
+
+	for pc := 0; pc < len(body); {
+		if inst.fuel == 0 {
+			panic(trap{err: ErrOutOfFuel})
+		}
+		inst.fuel--
+		in := &body[pc]
+		pc++
+
+		switch in.op {
+		case 0x00: // unreachable
+			inst.trap("unreachable")
+		case 0x01: // nop
+		case 0x02: // block
+			labels = append(labels, label{arity: in.arity, height: len(inst.stack), cont: in.endPc + 1})
+		case 0x03: // loop
+			labels = append(labels, label{loop: true, height: len(inst.stack), cont: pc})
+		case 0x04: // if
+			cond := inst.pop32()
+			labels = append(labels, label{arity: in.arity, height: len(inst.stack), cont: in.endPc + 1})
+			if cond == 0 {
+				if in.elsePc != 0 {
+					pc = in.elsePc + 1
+				} else {
+					pc = in.endPc
+				}
+			}
+		case 0x05: // else，then分支执行完毕
+			pc = in.endPc
+		case 0x0b: // end
+			labels = labels[:len(labels)-1]
+		case 0x0c: // br
+			pc = branch(in.imm)
+		case 0x0d: // br_if
+			if inst.pop32() != 0 {
+				pc = branch(in.imm)
+			}
+		case 0x0e: // br_table
+			idx := inst.pop32()
+			if idx < uint32(len(in.table)) {
+				pc = branch(uint64(in.table[idx]))
+			} else {
+				pc = branch(in.imm)
+			}
+		case 0x0f: // return
+			pc = branch(uint64(len(labels) - 1))
+		case 0x10: // call
+			inst.call(uint32(in.imm))
+		case 0x1a: // drop
+			inst.pop()
+		case 0x1b: // select
+			cond := inst.pop32()
+			b, a := inst.pop(), inst.pop()
+			if cond != 0 {
+				inst.push(a)
+			} else {
+				inst.push(b)
+			}
+
+		case 0x20: // local.get
+			inst.push(locals[in.imm])
+		case 0x21: // local.set
+			locals[in.imm] = inst.pop()
+		case 0x22: // local.tee
+			v := inst.pop()
+			locals[in.imm] = v
+			inst.push(v)
+		case 0x23: // global.get
+			inst.push(inst.globals[in.imm])
+		case 0x24: // global.set
+			inst.globals[in.imm] = inst.pop()
+
+		case 0x28: // i32.load
+			ea := inst.address(in.imm, 4)
+			inst.push32(mem.Uint32(inst.memory[ea:]))
+		case 0x29: // i64.load
+			ea := inst.address(in.imm, 8)
+			inst.push(mem.Uint64(inst.memory[ea:]))
+		case 0x2c: // i32.load8_s
+			ea := inst.address(in.imm, 1)
+			inst.push32(uint32(int32(int8(inst.memory[ea]))))
+		case 0x2d: // i32.load8_u
+			ea := inst.address(in.imm, 1)
+			inst.push32(uint32(inst.memory[ea]))
+		case 0x2e: // i32.load16_s
+			ea := inst.address(in.imm, 2)
+			inst.push32(uint32(int32(int16(mem.Uint16(inst.memory[ea:])))))
+		case 0x2f: // i32.load16_u
+			ea := inst.address(in.imm, 2)
+			inst.push32(uint32(mem.Uint16(inst.memory[ea:])))
+		case 0x30: // i64.load8_s
+			ea := inst.address(in.imm, 1)
+			inst.push(uint64(int64(int8(inst.memory[ea]))))
+		case 0x31: // i64.load8_u
+			ea := inst.address(in.imm, 1)
+			inst.push(uint64(inst.memory[ea]))
+		case 0x32: // i64.load16_s
+			ea := inst.address(in.imm, 2)
+			inst.push(uint64(int64(int16(mem.Uint16(inst.memory[ea:])))))
+		case 0x33: // i64.load16_u
+			ea := inst.address(in.imm, 2)
+			inst.push(uint64(mem.Uint16(inst.memory[ea:])))
+		case 0x34: // i64.load32_s
+			ea := inst.address(in.imm, 4)
+			inst.push(uint64(int64(int32(mem.Uint32(inst.memory[ea:])))))
+		case 0x35: // i64.load32_u
+			ea := inst.address(in.imm, 4)
+			inst.push(uint64(mem.Uint32(inst.memory[ea:])))
+		case 0x36: // i32.store
+			v := inst.pop32()
+			ea := inst.address(in.imm, 4)
+			mem.PutUint32(inst.memory[ea:], v)
+		case 0x37: // i64.store
+			v := inst.pop()
+			ea := inst.address(in.imm, 8)
+			mem.PutUint64(inst.memory[ea:], v)
+		case 0x3a, 0x3c: // i32.store8, i64.store8
+			v := inst.pop()
+			ea := inst.address(in.imm, 1)
+			inst.memory[ea] = byte(v)
+		case 0x3b, 0x3d: // i32.store16, i64.store16
+			v := inst.pop()
+			ea := inst.address(in.imm, 2)
+			mem.PutUint16(inst.memory[ea:], uint16(v))
+		case 0x3e: // i64.store32
+			v := inst.pop()
+			ea := inst.address(in.imm, 4)
+			mem.PutUint32(inst.memory[ea:], uint32(v))
+		case 0x3f: // memory.size
+			inst.push32(uint32(len(inst.memory) / PageSize))
+		case 0x40: // memory.grow
+			inst.push32(inst.grow(inst.pop32()))
+		case opMemoryCopy:
+			n, src, dst := uint64(inst.pop32()), uint64(inst.pop32()), uint64(inst.pop32())
+			if src+n > uint64(len(inst.memory)) || dst+n > uint64(len(inst.memory)) {
+				inst.trap("out of bounds memory access")
+			}
+			inst.consume(n)
+			copy(inst.memory[dst:dst+n], inst.memory[src:src+n])
+		case opMemoryFill:
+			n, v, dst := uint64(inst.pop32()), byte(inst.pop32()), uint64(inst.pop32())
+			if dst+n > uint64(len(inst.memory)) {
+				inst.trap("out of bounds memory access")
+			}
+			inst.consume(n)
+			for i := dst; i < dst+n; i++ {
+				inst.memory[i] = v
+			}
+
+		case 0x41, 0x42: // i32.const, i64.const
+			inst.push(in.imm)
+
+		case 0x45: // i32.eqz
+			inst.pushBool(inst.pop32() == 0)
+		case 0x50: // i64.eqz
+			inst.pushBool(inst.pop() == 0)
+		case 0x46, 0x47, 0x48, 0x49, 0x4a, 0x4b, 0x4c, 0x4d, 0x4e, 0x4f:
+			b, a := inst.pop32(), inst.pop32()
+			inst.pushBool(compare(in.op-0x46, uint64(a), uint64(b), int64(int32(a)), int64(int32(b))))
+		case 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57, 0x58, 0x59, 0x5a:
+			b, a := inst.pop(), inst.pop()
+			inst.pushBool(compare(in.op-0x51, a, b, int64(a), int64(b)))
+
+		case 0x67: // i32.clz
+			inst.push32(uint32(bits.LeadingZeros32(inst.pop32())))
+		case 0x68: // i32.ctz
+			inst.push32(uint32(bits.TrailingZeros32(inst.pop32())))
+		case 0x69: // i32.popcnt
+			inst.push32(uint32(bits.OnesCount32(inst.pop32())))
+		case 0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f, 0x70, 0x71, 0x72, 0x73, 0x74, 0x75, 0x76, 0x77, 0x78:
+			b, a := inst.pop32(), inst.pop32()
+			inst.push32(inst.binary32(in.op, a, b))
+		case 0x79: // i64.clz
+			inst.push(uint64(bits.LeadingZeros64(inst.pop())))
+		case 0x7a: // i64.ctz
+			inst.push(uint64(bits.TrailingZeros64(inst.pop())))
+		case 0x7b: // i64.popcnt
+			inst.push(uint64(bits.OnesCount64(inst.pop())))
+		case 0x7c, 0x7d, 0x7e, 0x7f, 0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87, 0x88, 0x89, 0x8a:
+			b, a := inst.pop(), inst.pop()
+			inst.push(inst.binary64(in.op-0x12, a, b))
+
+		case 0xa7: // i32.wrap_i64
+			inst.push32(uint32(inst.pop()))
+		case 0xac: // i64.extend_i32_s
+			inst.push(uint64(int64(int32(inst.pop32()))))
+		case 0xad: // i64.extend_i32_u
+			inst.push(uint64(inst.pop32()))
+		case 0xc0: // i32.extend8_s
+			inst.push32(uint32(int32(int8(inst.pop32()))))
+		case 0xc1: // i32.extend16_s
+			inst.push32(uint32(int32(int16(inst.pop32()))))
+		case 0xc2: // i64.extend8_s
+			inst.push(uint64(int64(int8(inst.pop()))))
+		case 0xc3: // i64.extend16_s
+			inst.push(uint64(int64(int16(inst.pop()))))
+		case 0xc4: // i64.extend32_s
+			inst.push(uint64(int64(int32(inst.pop()))))
+
+		default:
+			// 解码时已拒绝不支持的指令
+			panic(fmt.Sprintf("unexpected instruction 0x%x", in.op))
+		}
+	}
+}
+
+// consume charges the fuel of the bulk memory instructions by the bytes
+// they process, so that they are not cheaper than the equivalent loops
+func (inst *Instance) consume(n uint64) {
+	if n > inst.fuel {
+		inst.fuel = 0
+		panic(trap{err: ErrOutOfFuel})
+	}
+	inst.fuel -= n
+}
+
+// grow grows the memory by the pages, and returns its previous size in
+// pages or -1 if it may not grow that much
+func (inst *Instance) grow(pages uint32) uint32 {
+	current := uint32(len(inst.memory) / PageSize)
+	if inst.module.memory == nil || uint64(current)+uint64(pages) > uint64(inst.maxPages) {
+		return 0xffffffff
+	}
+	grown := make([]byte, (int(current)+int(pages))*PageSize)
+	copy(grown, inst.memory)
+	inst.memory = grown
+	return current
+}
+
+// compare evaluates the comparison of the given index, in the order of the
+// i32 and i64 comparison opcodes from eq
+func compare(op uint16, a, b uint64, sa, sb int64) bool {
+	switch op {
+	case 0: // eq
+		return a == b
+	case 1: // ne
+		return a != b
+	case 2: // lt_s
+		return sa < sb
+	case 3: // lt_u
+		return a < b
+	case 4: // gt_s
+		return sa > sb
+	case 5: // gt_u
+		return a > b
+	case 6: // le_s
+		return sa <= sb
+	case 7: // le_u
+		return a <= b
+	case 8: // ge_s
+		return sa >= sb
+	default: // ge_u
+		return a >= b
+	}
+}
+
+// binary32 evaluates the i32 binary operation of the opcode
+func (inst *Instance) binary32(op uint16, a, b uint32) uint32 {
+	switch op {
+	case 0x6a: // add
+		return a + b
+	case 0x6b: // sub
+		return a - b
+	case 0x6c: // mul
+		return a * b
+	case 0x6d: // div_s
+		if b == 0 {
+			inst.trap("integer divide by zero")
+		}
+		if int32(a) == -1<<31 && int32(b) == -1 {
+			inst.trap("integer overflow")
+		}
+		return uint32(int32(a) / int32(b))
+	case 0x6e: // div_u
+		if b == 0 {
+			inst.trap("integer divide by zero")
+		}
+		return a / b
+	case 0x6f: // rem_s
+		if b == 0 {
+			inst.trap("integer divide by zero")
+		}
+		if int32(b) == -1 {
+			return 0
+		}
+		return uint32(int32(a) % int32(b))
+	case 0x70: // rem_u
+		if b == 0 {
+			inst.trap("integer divide by zero")
+		}
+		return a % b
+	case 0x71: // and
+		return a & b
+	case 0x72: // or
+		return a | b
+	case 0x73: // xor
+		return a ^ b
+	case 0x74: // shl
+		return a << (b & 31)
+	case 0x75: // shr_s
+		return uint32(int32(a) >> (b & 31))
+	case 0x76: // shr_u
+		return a >> (b & 31)
+	case 0x77: // rotl
+		return bits.RotateLeft32(a, int(b&31))
+	default: // rotr
+		return bits.RotateLeft32(a, -int(b&31))
+	}
+}
+
+// binary64 evaluates the i64 binary operation of the opcode, given as the
+// opcode of the corresponding i32 operation
+func (inst *Instance) binary64(op uint16, a, b uint64) uint64 {
+	switch op {
+	case 0x6a: // add
+		return a + b
+	case 0x6b: // sub
+		return a - b
+	case 0x6c: // mul
+		return a * b
+	case 0x6d: // div_s
+		if b == 0 {
+			inst.trap("integer divide by zero")
+		}
+		if int64(a) == -1<<63 && int64(b) == -1 {
+			inst.trap("integer overflow")
+		}
+		return uint64(int64(a) / int64(b))
+	case 0x6e: // div_u
+		if b == 0 {
+			inst.trap("integer divide by zero")
+		}
+		return a / b
+	case 0x6f: // rem_s
+		if b == 0 {
+			inst.trap("integer divide by zero")
+		}
+		if int64(b) == -1 {
+			return 0
+		}
+		return uint64(int64(a) % int64(b))
+	case 0x70: // rem_u
+		if b == 0 {
+			inst.trap("integer divide by zero")
+		}
+		return a % b
+	case 0x71: // and
+		return a & b
+	case 0x72: // or
+		return a | b
+	case 0x73: // xor
+		return a ^ b
+	case 0x74: // shl
+		return a << (b & 63)
+	case 0x75: // shr_s
+		return uint64(int64(a) >> (b & 63))
+	case 0x76: // shr_u
+		return a >> (b & 63)
+	case 0x77: // rotl
+		return bits.RotateLeft64(a, int(b&63))
+	default: // rotr
+		return bits.RotateLeft64(a, -int(b&63))
+	}
+}
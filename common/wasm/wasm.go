@@ -0,0 +1,168 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package wasm runs WebAssembly modules in a sandbox.  It interprets the
+// integer subset of WebAssembly 1.0, with the sign extension and bulk memory
+// copy and fill instructions, which is enough for modules deciding on byte
+// inputs such as protobuf messages.  Modules using floating point numbers,
+// tables or imports other than host functions are rejected when compiled.
+//
+// A module only reaches the host through the host functions it is given, and
+// each call is bounded by the fuel of the instance, one unit being consumed
+// per instruction executed, and by the memory the instance may grow to.
+package wasm
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ValueType is the type of a WebAssembly value
+type ValueType byte
+
+const (
+	// I32 is the 32-bit integer type
+	I32 ValueType = 0x7f
+	// I64 is the 64-bit integer type
+	I64 ValueType = 0x7e
+)
+
+// PageSize is the size of a page of linear memory
+const PageSize = 65536
+
+// maxPages is the largest number of pages of a 32-bit linear memory
+const maxPages = 65536
+
+const (
+	// maxCallDepth bounds the recursion of calls between functions
+	maxCallDepth = 512
+	// maxStackSize bounds the number of values on the operand stack
+	maxStackSize = 1 << 16
+	// maxLocals bounds the number of locals of a function
+	maxLocals = 50000
+)
+
+// ErrOutOfFuel is the trap of an instance which ran out of fuel
+var ErrOutOfFuel = errors.New("out of fuel")
+
+// FuncType is the signature of a function
+type FuncType struct {
+	Params  []ValueType
+	Results []ValueType
+}
+
+// Equal returns whether the signatures are the same
+func (ft FuncType) Equal(other FuncType) bool {
+	if len(ft.Params) != len(other.Params) || len(ft.Results) != len(other.Results) {
+		return false
+	}
+	for i := range ft.Params {
+		if ft.Params[i] != other.Params[i] {
+			return false
+		}
+	}
+	for i := range ft.Results {
+		if ft.Results[i] != other.Results[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// HostFunc is a function of the host which a module may import
+type HostFunc struct {
+	// Type is the signature the module must import the function with
+	Type FuncType
+	// Fn is called with the arguments of the call, and returns its results
+	// or an error which traps the instance
+	Fn func(inst *Instance, args []uint64) ([]uint64, error)
+}
+
+// Limits bounds the resources of an instance
+type Limits struct {
+	// Fuel is the number of instructions the instance may execute
+	Fuel uint64
+	// MaxMemoryPages is the number of pages the memory of the instance may
+	// grow to, in addition to the maximum declared by the module
+	MaxMemoryPages uint32
+}
+
+// Module is a compiled WebAssembly module, which may be instantiated any
+// number of times
+type Module struct {
+	types   []FuncType
+	imports []funcImport
+	funcs   []function
+	memory  *memoryType
+	globals []global
+	exports map[string]export
+	start   *uint32
+	data    []dataSegment
+}
+
+type funcImport struct {
+	module  string
+	name    string
+	typeIdx uint32
+}
+
+type function struct {
+	typeIdx   uint32
+	numLocals int // 参数之外的局部变量个数
+	body      []instr
+}
+
+type memoryType struct {
+	min    uint32
+	max    uint32
+	hasMax bool
+}
+
+type global struct {
+	mutable bool
+	init    uint64
+}
+
+const (
+	exportFunc   = 0x00
+	exportTable  = 0x01
+	exportMemory = 0x02
+	exportGlobal = 0x03
+)
+
+type export struct {
+	kind  byte
+	index uint32
+}
+
+type dataSegment struct {
+	offset uint32
+	init   []byte
+}
+
+// funcType returns the signature of the function of the index space of the
+// module, imported functions coming first
+func (m *Module) funcType(idx uint32) FuncType {
+	if idx < uint32(len(m.imports)) {
+		return m.types[m.imports[idx].typeIdx]
+	}
+	return m.types[m.funcs[idx-uint32(len(m.imports))].typeIdx]
+}
+
+// ExportedFunc returns the signature of the function exported under the
+// name, if any
+func (m *Module) ExportedFunc(name string) (FuncType, bool) {
+	exp, ok := m.exports[name]
+	if !ok || exp.kind != exportFunc {
+		return FuncType{}, false
+	}
+	return m.funcType(exp.index), true
+}
+
+// ExportsMemory returns whether the module exports its memory under the name
+func (m *Module) ExportsMemory(name string) bool {
+	exp, ok := m.exports[name]
+	return ok && exp.kind == exportMemory
+}
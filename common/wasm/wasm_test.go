@@ -0,0 +1,419 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package wasm
+
+import (
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testFunc is a function of a test module, whose body is given without its
+// final end
+type testFunc struct {
+	params, results []ValueType
+	locals          []ValueType
+	body            []byte
+	export          string
+}
+
+type testImport struct {
+	module, name    string
+	params, results []ValueType
+}
+
+// testModule describes a module to encode in the binary format
+type testModule struct {
+	imports []testImport
+	funcs   []testFunc
+	memory  []byte // 内存段的内容，nil表示没有内存
+	data    map[uint32]string
+	start   *uint32
+}
+
+func uleb(n uint64) []byte {
+	var b []byte
+	for {
+		c := byte(n & 0x7f)
+		n >>= 7
+		if n == 0 {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func sleb(v int64) []byte {
+	var b []byte
+	for {
+		c := byte(v & 0x7f)
+		v >>= 7
+		if (v == 0 && c&0x40 == 0) || (v == -1 && c&0x40 != 0) {
+			return append(b, c)
+		}
+		b = append(b, c|0x80)
+	}
+}
+
+func vec(items ...[]byte) []byte {
+	b := uleb(uint64(len(items)))
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+func str(s string) []byte {
+	return append(uleb(uint64(len(s))), s...)
+}
+
+func types(vts []ValueType) []byte {
+	b := uleb(uint64(len(vts)))
+	for _, vt := range vts {
+		b = append(b, byte(vt))
+	}
+	return b
+}
+
+func section(id byte, contents []byte) []byte {
+	return append(append([]byte{id}, uleb(uint64(len(contents)))...), contents...)
+}
+
+func (tm testModule) encode() []byte {
+	var typeSec, importSec, funcSec, exportSec, codeSec [][]byte
+	for _, imp := range tm.imports {
+		importSec = append(importSec, append(append(str(imp.module), str(imp.name)...), append([]byte{exportFunc}, uleb(uint64(len(typeSec)))...)...))
+		typeSec = append(typeSec, append(append([]byte{0x60}, types(imp.params)...), types(imp.results)...))
+	}
+	for i, fn := range tm.funcs {
+		funcSec = append(funcSec, uleb(uint64(len(typeSec))))
+		typeSec = append(typeSec, append(append([]byte{0x60}, types(fn.params)...), types(fn.results)...))
+		if fn.export != "" {
+			exportSec = append(exportSec, append(append(str(fn.export), exportFunc), uleb(uint64(len(tm.imports)+i))...))
+		}
+		var groups [][]byte
+		for _, vt := range fn.locals {
+			groups = append(groups, []byte{0x01, byte(vt)})
+		}
+		code := append(append(vec(groups...), fn.body...), 0x0b)
+		codeSec = append(codeSec, append(uleb(uint64(len(code))), code...))
+	}
+
+	binary := append([]byte{}, header...)
+	binary = append(binary, section(sectionType, vec(typeSec...))...)
+	if len(importSec) > 0 {
+		binary = append(binary, section(sectionImport, vec(importSec...))...)
+	}
+	binary = append(binary, section(sectionFunction, vec(funcSec...))...)
+	if tm.memory != nil {
+		binary = append(binary, section(sectionMemory, vec(tm.memory))...)
+		exportSec = append(exportSec, append(str("memory"), exportMemory, 0x00))
+	}
+	binary = append(binary, section(sectionExport, vec(exportSec...))...)
+	if tm.start != nil {
+		binary = append(binary, section(sectionStart, uleb(uint64(*tm.start)))...)
+	}
+	binary = append(binary, section(sectionCode, vec(codeSec...))...)
+	var dataSec [][]byte
+	for offset, init := range tm.data {
+		dataSec = append(dataSec, append(append([]byte{0x00, 0x41}, sleb(int64(offset))...), append([]byte{0x0b}, str(init)...)...))
+	}
+	if len(dataSec) > 0 {
+		binary = append(binary, section(sectionData, vec(dataSec...))...)
+	}
+	return binary
+}
+
+func instantiate(t *testing.T, tm testModule, hosts map[string]HostFunc, limits Limits) *Instance {
+	m, err := Compile(tm.encode())
+	require.NoError(t, err)
+	inst, err := m.Instantiate(hosts, limits)
+	require.NoError(t, err)
+	return inst
+}
+
+var defaultLimits = Limits{Fuel: 1000000, MaxMemoryPages: 4}
+
+func TestArithmetic(t *testing.T) {
+	binop := func(op byte) testFunc {
+		return testFunc{params: []ValueType{I32, I32}, results: []ValueType{I32}, body: []byte{0x20, 0x00, 0x20, 0x01, op}}
+	}
+	binop64 := func(op byte) testFunc {
+		return testFunc{params: []ValueType{I64, I64}, results: []ValueType{I64}, body: []byte{0x20, 0x00, 0x20, 0x01, op}}
+	}
+	m, err := Compile(testModule{funcs: []testFunc{
+		binop(0x6a), binop(0x6b), binop(0x6d), binop(0x6f), binop(0x74), binop(0x77), binop(0x48), binop64(0x7e), binop64(0x7f),
+	}}.encode())
+	require.NoError(t, err)
+	ops := []string{"i32.add", "i32.sub", "i32.div_s", "i32.rem_s", "i32.shl", "i32.rotl", "i32.lt_s", "i64.mul", "i64.div_s"}
+	for i := range m.funcs {
+		m.exports[ops[i]] = export{kind: exportFunc, index: uint32(i)}
+	}
+
+	neg := func(v int32) uint64 { return uint64(uint32(v)) }
+	for _, tc := range []struct {
+		op       string
+		a, b     uint64
+		expected uint64
+		trap     string
+	}{
+		{op: "i32.add", a: 0xffffffff, b: 2, expected: 1},
+		{op: "i32.sub", a: 1, b: 2, expected: neg(-1)},
+		{op: "i32.div_s", a: neg(-7), b: 2, expected: neg(-3)},
+		{op: "i32.div_s", a: 1, b: 0, trap: "integer divide by zero"},
+		{op: "i32.div_s", a: neg(-1 << 31), b: neg(-1), trap: "integer overflow"},
+		{op: "i32.rem_s", a: neg(-1 << 31), b: neg(-1), expected: 0},
+		{op: "i32.rem_s", a: neg(-7), b: 2, expected: neg(-1)},
+		{op: "i32.shl", a: 1, b: 33, expected: 2},
+		{op: "i32.rotl", a: 0x80000001, b: 1, expected: 3},
+		{op: "i32.lt_s", a: neg(-1), b: 1, expected: 1},
+		{op: "i64.mul", a: 1 << 32, b: 1 << 32, expected: 0},
+		{op: "i64.div_s", a: uint64(1) << 63, b: 0xffffffffffffffff, trap: "integer overflow"},
+	} {
+		inst, err := m.Instantiate(nil, defaultLimits)
+		require.NoError(t, err)
+		results, err := inst.Call(tc.op, tc.a, tc.b)
+		if tc.trap != "" {
+			assert.EqualError(t, err, "trap: "+tc.trap, "%s(%d, %d)", tc.op, tc.a, tc.b)
+			continue
+		}
+		require.NoError(t, err, "%s(%d, %d)", tc.op, tc.a, tc.b)
+		assert.Equal(t, []uint64{tc.expected}, results, "%s(%d, %d)", tc.op, tc.a, tc.b)
+	}
+}
+
+func TestControlFlow(t *testing.T) {
+	inst := instantiate(t, testModule{funcs: []testFunc{
+		{
+			export: "factorial", params: []ValueType{I64}, results: []ValueType{I64}, locals: []ValueType{I64},
+			body: []byte{
+				0x42, 0x01, 0x21, 0x01, // acc = 1
+				0x02, 0x40, 0x03, 0x40,
+				0x20, 0x00, 0x50, 0x0d, 0x01, // n == 0时跳出
+				0x20, 0x01, 0x20, 0x00, 0x7e, 0x21, 0x01, // acc *= n
+				0x20, 0x00, 0x42, 0x01, 0x7d, 0x21, 0x00, // n--
+				0x0c, 0x00, 0x0b, 0x0b,
+				0x20, 0x01,
+			},
+		},
+		{
+			export: "fib", params: []ValueType{I32}, results: []ValueType{I32},
+			body: []byte{
+				0x20, 0x00, 0x41, 0x02, 0x49, 0x04, 0x7f,
+				0x20, 0x00,
+				0x05,
+				0x20, 0x00, 0x41, 0x01, 0x6b, 0x10, 0x01,
+				0x20, 0x00, 0x41, 0x02, 0x6b, 0x10, 0x01,
+				0x6a,
+				0x0b,
+			},
+		},
+		{
+			export: "early", params: []ValueType{I32}, results: []ValueType{I32},
+			body: []byte{0x20, 0x00, 0x04, 0x40, 0x41, 0x07, 0x0f, 0x0b, 0x41, 0x09},
+		},
+		{
+			export: "switch", params: []ValueType{I32}, results: []ValueType{I32},
+			body: []byte{
+				0x02, 0x40, 0x02, 0x40, 0x02, 0x40,
+				0x20, 0x00, 0x0e, 0x02, 0x00, 0x01, 0x02,
+				0x0b, 0x41, 0x0a, 0x0f,
+				0x0b, 0x41, 0x0b, 0x0f,
+				0x0b, 0x41, 0x0c,
+			},
+		},
+	}}, nil, defaultLimits)
+
+	call := func(name string, arg uint64) uint64 {
+		results, err := inst.Call(name, arg)
+		require.NoError(t, err)
+		require.Len(t, results, 1)
+		return results[0]
+	}
+	assert.Equal(t, uint64(1), call("factorial", 0))
+	assert.Equal(t, uint64(3628800), call("factorial", 10))
+	assert.Equal(t, uint64(55), call("fib", 10))
+	assert.Equal(t, uint64(7), call("early", 1))
+	assert.Equal(t, uint64(9), call("early", 0))
+	assert.Equal(t, uint64(10), call("switch", 0))
+	assert.Equal(t, uint64(11), call("switch", 1))
+	assert.Equal(t, uint64(12), call("switch", 2))
+	assert.Equal(t, uint64(12), call("switch", 100))
+
+	_, err := inst.Call("fib")
+	assert.EqualError(t, err, "function fib takes 1 arguments, got 0")
+	_, err = inst.Call("missing")
+	assert.EqualError(t, err, "no function exported as missing")
+}
+
+func TestMemory(t *testing.T) {
+	tm := testModule{
+		memory: []byte{0x01, 0x01, 0x02}, // 最少1页，最多2页
+		data:   map[uint32]string{16: "hello"},
+		funcs: []testFunc{
+			{export: "load", params: []ValueType{I32}, results: []ValueType{I32}, body: []byte{0x20, 0x00, 0x28, 0x02, 0x00}},
+			{export: "store", params: []ValueType{I32, I32}, body: []byte{0x20, 0x00, 0x20, 0x01, 0x36, 0x02, 0x00}},
+			{export: "load8_s", params: []ValueType{I32}, results: []ValueType{I32}, body: []byte{0x20, 0x00, 0x2c, 0x00, 0x00}},
+			{export: "grow", params: []ValueType{I32}, results: []ValueType{I32}, body: []byte{0x20, 0x00, 0x40, 0x00}},
+			{export: "fill", params: []ValueType{I32, I32, I32}, body: []byte{0x20, 0x00, 0x20, 0x01, 0x20, 0x02, 0xfc, 0x0b, 0x00}},
+		},
+	}
+	inst := instantiate(t, tm, nil, defaultLimits)
+
+	data, err := inst.Read(16, 5)
+	require.NoError(t, err)
+	assert.Equal(t, "hello", string(data))
+
+	_, err = inst.Call("store", 100, 0xdeadbeef)
+	require.NoError(t, err)
+	results, err := inst.Call("load", 100)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{0xdeadbeef}, results)
+	results, err = inst.Call("load8_s", 103)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{0xffffffde}, results)
+
+	_, err = inst.Call("load", PageSize-2)
+	assert.EqualError(t, err, "trap: out of bounds memory access")
+
+	_, err = inst.Call("fill", 200, 'x', 3)
+	require.NoError(t, err)
+	data, err = inst.Read(199, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0, 'x', 'x', 'x', 0}, data)
+
+	// 模块声明的最大值低于限制
+	results, err = inst.Call("grow", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{1}, results)
+	results, err = inst.Call("grow", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{0xffffffff}, results)
+	assert.Len(t, inst.Memory(), 2*PageSize)
+
+	require.NoError(t, inst.Write(PageSize, []byte("world")))
+	results, err = inst.Call("load", PageSize)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{uint64('w') | uint64('o')<<8 | uint64('r')<<16 | uint64('l')<<24}, results)
+	assert.Error(t, inst.Write(2*PageSize-1, []byte("world")))
+	_, err = inst.Read(2*PageSize, 1)
+	assert.Error(t, err)
+
+	// 限制低于模块声明的最大值
+	inst = instantiate(t, tm, nil, Limits{Fuel: 1000, MaxMemoryPages: 1})
+	results, err = inst.Call("grow", 1)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{0xffffffff}, results)
+}
+
+func TestMemoryLimit(t *testing.T) {
+	m, err := Compile(testModule{memory: []byte{0x00, 0x05}}.encode())
+	require.NoError(t, err)
+	_, err = m.Instantiate(nil, Limits{Fuel: 1000, MaxMemoryPages: 4})
+	assert.EqualError(t, err, "module requires 5 pages of memory, exceeding the limit of 4")
+
+	m, err = Compile(testModule{memory: []byte{0x00, 0x01}, data: map[uint32]string{PageSize - 2: "abc"}}.encode())
+	require.NoError(t, err)
+	_, err = m.Instantiate(nil, defaultLimits)
+	assert.EqualError(t, err, "data segment exceeds the memory")
+}
+
+func TestFuel(t *testing.T) {
+	tm := testModule{funcs: []testFunc{
+		{export: "spin", body: []byte{0x03, 0x40, 0x0c, 0x00, 0x0b}},
+		{export: "recurse", body: []byte{0x10, 0x01}},
+	}}
+	inst := instantiate(t, tm, nil, Limits{Fuel: 1000})
+	_, err := inst.Call("spin")
+	assert.Equal(t, ErrOutOfFuel, errors.Cause(err))
+	assert.Equal(t, uint64(0), inst.Fuel())
+
+	inst = instantiate(t, tm, nil, defaultLimits)
+	_, err = inst.Call("recurse")
+	assert.EqualError(t, err, "trap: call stack exhausted")
+
+	// 启动函数同样受限
+	start := uint32(0)
+	tm.start = &start
+	m, err := Compile(tm.encode())
+	require.NoError(t, err)
+	_, err = m.Instantiate(nil, Limits{Fuel: 1000})
+	assert.Equal(t, ErrOutOfFuel, errors.Cause(err))
+}
+
+func TestHostFunc(t *testing.T) {
+	tm := testModule{
+		imports: []testImport{
+			{module: "env", name: "double", params: []ValueType{I32}, results: []ValueType{I32}},
+			{module: "env", name: "fail"},
+		},
+		funcs: []testFunc{
+			{export: "double_plus_one", params: []ValueType{I32}, results: []ValueType{I32}, body: []byte{0x20, 0x00, 0x10, 0x00, 0x41, 0x01, 0x6a}},
+			{export: "fail", body: []byte{0x10, 0x01}},
+		},
+	}
+	hosts := map[string]HostFunc{
+		"env.double": {
+			Type: FuncType{Params: []ValueType{I32}, Results: []ValueType{I32}},
+			Fn: func(inst *Instance, args []uint64) ([]uint64, error) {
+				return []uint64{args[0] * 2}, nil
+			},
+		},
+		"env.fail": {
+			Fn: func(inst *Instance, args []uint64) ([]uint64, error) {
+				return nil, errors.New("rejected")
+			},
+		},
+	}
+
+	inst := instantiate(t, tm, hosts, defaultLimits)
+	results, err := inst.Call("double_plus_one", 20)
+	require.NoError(t, err)
+	assert.Equal(t, []uint64{41}, results)
+	_, err = inst.Call("fail")
+	assert.EqualError(t, err, "trap in host function: rejected")
+
+	m, err := Compile(tm.encode())
+	require.NoError(t, err)
+	_, err = m.Instantiate(map[string]HostFunc{"env.double": hosts["env.double"]}, defaultLimits)
+	assert.EqualError(t, err, "unknown import env.fail")
+	_, err = m.Instantiate(map[string]HostFunc{"env.double": hosts["env.fail"], "env.fail": hosts["env.fail"]}, defaultLimits)
+	assert.EqualError(t, err, "import env.double has the wrong signature")
+}
+
+func TestCompileInvalid(t *testing.T) {
+	valid := testModule{funcs: []testFunc{{export: "f", params: []ValueType{I32}, body: []byte{0x01}}}}
+	_, err := Compile(valid.encode())
+	require.NoError(t, err)
+
+	withBody := func(body ...byte) []byte {
+		tm := valid
+		tm.funcs = []testFunc{{params: []ValueType{I32}, body: body}}
+		return tm.encode()
+	}
+	for name, tc := range map[string]struct {
+		binary []byte
+		err    string
+	}{
+		"NotWasm":         {binary: []byte("\x00asm\x02\x00\x00\x00"), err: "not a WebAssembly 1.0 module"},
+		"Truncated":       {binary: valid.encode()[:20], err: "unexpected end of module"},
+		"FloatType":       {binary: testModule{funcs: []testFunc{{params: []ValueType{0x7d}}}}.encode(), err: "invalid module: floating point types are not supported"},
+		"FloatOp":         {binary: withBody(0x43, 0, 0, 0, 0), err: "invalid module: function 0: unsupported instruction 0x43"},
+		"CallIndirect":    {binary: withBody(0x41, 0x00, 0x11, 0x00, 0x00), err: "invalid module: function 0: unsupported instruction 0x11"},
+		"UnknownLocal":    {binary: withBody(0x20, 0x01, 0x1a), err: "invalid module: function 0: unknown local 1"},
+		"UnknownFunction": {binary: withBody(0x10, 0x01), err: "invalid module: function 0: call of unknown function 1"},
+		"BranchDepth":     {binary: withBody(0x02, 0x40, 0x0c, 0x02, 0x0b), err: "invalid module: function 0: invalid branch depth 2"},
+		"ElseWithoutIf":   {binary: withBody(0x02, 0x40, 0x05, 0x0b), err: "invalid module: function 0: else without if"},
+		"Unterminated":    {binary: withBody(0x02, 0x40), err: "invalid module: function 0: unexpected end of function body"},
+		"MemoryWithout":   {binary: withBody(0x20, 0x00, 0x28, 0x02, 0x00, 0x1a), err: "invalid module: function 0: memory instruction without memory"},
+	} {
+		_, err := Compile(tc.binary)
+		assert.EqualError(t, err, tc.err, name)
+	}
+}
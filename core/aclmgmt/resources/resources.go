@@ -28,6 +28,8 @@ const (
 	Qscc_GetTransactionByID = "qscc/GetTransactionByID"
 	Qscc_GetBlockByTxID     = "qscc/GetBlockByTxID"
 
+	Qscc_GetValidationFailureByTxID = "qscc/GetValidationFailureByTxID"
+
 	//Cscc resources
 	Cscc_JoinChain                = "cscc/JoinChain"
 	Cscc_GetConfigBlock           = "cscc/GetConfigBlock"
@@ -250,7 +250,8 @@ func (cs *ChaincodeSupport) execute(ctxt context.Context, cccid *ccprovider.CCCo
 		return nil, errors.Errorf("unable to invoke chaincode %s", cname)
 	}
 
-	ccresp, err := handler.Execute(ctxt, cccid, msg, cs.ExecuteTimeout)
+	limits, _ := ctxt.Value(ChaincodeLimitsKey).(*ccprovider.ChaincodeLimits)
+	ccresp, err := handler.Execute(ctxt, cccid, msg, limits.GetExecuteTimeout(cs.ExecuteTimeout))
 	if err != nil {
 		return nil, errors.WithMessage(err, fmt.Sprintf("error sending"))
 	}
@@ -70,6 +70,10 @@ func (c *ContainerRuntime) Start(ctxt context.Context, cccid *ccprovider.CCConte
 		},
 	}
 
+	if limits, _ := ctxt.Value(ChaincodeLimitsKey).(*ccprovider.ChaincodeLimits); limits != nil && (limits.Memory > 0 || limits.CPUShares > 0) {
+		scr.Limits = &ccintf.ResourceLimits{Memory: limits.Memory, CPUShares: limits.CPUShares}
+	}
+
 	vmtype := getVMType(cds)
 
 	if err := c.Processor.Process(ctxt, vmtype, scr); err != nil {
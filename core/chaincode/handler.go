@@ -877,6 +877,10 @@ func (h *Handler) HandleInvokeChaincode(msg *pb.ChaincodeMessage, txContext *Tra
 		if err != nil {
 			return nil, errors.WithStack(err)
 		}
+
+		if limits := cd.(*ccprovider.ChaincodeData).Limits; limits != nil {
+			ctxt = context.WithValue(ctxt, ChaincodeLimitsKey, limits)
+		}
 	}
 
 	// Launch the new chaincode if not already running
@@ -83,6 +83,7 @@ func (carPlatform *Platform) GenerateDockerBuild(cds *pb.ChaincodeDeploymentSpec
 		Cmd:          "java -jar /usr/local/bin/chaintool buildcar /chaincode/input/codepackage.car -o /chaincode/output/chaincode",
 		InputStream:  codepackage,
 		OutputStream: binpackage,
+		Cache:        util.GetBuildCache(),
 	})
 	if err != nil {
 		return fmt.Errorf("Error building CAR: %s", err)
@@ -506,6 +506,22 @@ func getLDFlagsOpts() string {
 	return staticLDFlagsOpts
 }
 
+// reproducibleFlagsOpts strips the paths of the builder container from the
+// binary, so that building the same chaincode makes the same binary
+const reproducibleFlagsOpts = "-gcflags=all=-trimpath=/chaincode/input/src -asmflags=all=-trimpath=/chaincode/input/src"
+
+// getBuildEnv returns the environment building the chaincode for the target
+// architecture, and whether the chaincode is cross-compiled
+func getBuildEnv() ([]string, bool) {
+	targetArch := cutil.TargetArch()
+	env := []string{"GOOS=linux", "GOARCH=" + targetArch}
+	if targetArch == cutil.BuildArch() {
+		return env, false
+	}
+	//交叉编译时构建容器的C工具链不适用于目标架构，禁用cgo后编译出的二进制文件即为静态链接
+	return append(env, "CGO_ENABLED=0"), true
+}
+
 func (goPlatform *Platform) GenerateDockerBuild(cds *pb.ChaincodeDeploymentSpec, tw *tar.Writer) error {
 	spec := cds.ChaincodeSpec
 
@@ -514,8 +530,19 @@ func (goPlatform *Platform) GenerateDockerBuild(cds *pb.ChaincodeDeploymentSpec,
 		return fmt.Errorf("could not decode url: %s", err)
 	}
 
+	if err = cutil.CheckTargetArch(); err != nil {
+		return err
+	}
+	env, cross := getBuildEnv()
 	ldflagsOpt := getLDFlagsOpts()
-	logger.Infof("building chaincode with ldflagsOpt: '%s'", ldflagsOpt)
+	if cross {
+		ldflagsOpt = dynamicLDFlagsOpts
+	}
+	reproducible := viper.GetBool("chaincode.golang.reproducible")
+	if reproducible {
+		ldflagsOpt = strings.TrimSpace(ldflagsOpt + " " + reproducibleFlagsOpts)
+	}
+	logger.Infof("building chaincode for %s with ldflagsOpt: '%s'", cutil.TargetArch(), ldflagsOpt)
 
 	var gotags string
 	// check if experimental features are enabled
@@ -527,9 +554,12 @@ func (goPlatform *Platform) GenerateDockerBuild(cds *pb.ChaincodeDeploymentSpec,
 	codepackage := bytes.NewReader(cds.CodePackage)
 	binpackage := bytes.NewBuffer(nil)
 	err = util.DockerBuild(util.DockerBuildOptions{
+		Env:          env,
 		Cmd:          fmt.Sprintf("GOPATH=/chaincode/input:$GOPATH go build -tags \"%s\" %s -o /chaincode/output/chaincode %s", gotags, ldflagsOpt, pkgname),
 		InputStream:  codepackage,
 		OutputStream: binpackage,
+		Cache:        util.GetBuildCache(),
+		Reproducible: reproducible,
 	})
 	if err != nil {
 		return err
@@ -180,14 +180,39 @@ func (nodePlatform *Platform) GenerateDockerfile(cds *pb.ChaincodeDeploymentSpec
 	return dockerFileContents, nil
 }
 
+// nodeArchs are the names node gives the architectures of the chaincode images
+var nodeArchs = map[string]string{
+	"amd64": "x64",
+	"arm64": "arm64",
+}
+
+// getBuildEnv returns the environment installing the native modules of the
+// chaincode for the target architecture, rather than for the builder's
+func getBuildEnv() []string {
+	targetArch := cutil.TargetArch()
+	if targetArch == cutil.BuildArch() {
+		return nil
+	}
+	arch, ok := nodeArchs[targetArch]
+	if !ok {
+		arch = targetArch
+	}
+	return []string{"npm_config_arch=" + arch, "npm_config_target_arch=" + arch}
+}
+
 func (nodePlatform *Platform) GenerateDockerBuild(cds *pb.ChaincodeDeploymentSpec, tw *tar.Writer) error {
 
 	codepackage := bytes.NewReader(cds.CodePackage)
 	binpackage := bytes.NewBuffer(nil)
+	if err := cutil.CheckTargetArch(); err != nil {
+		return err
+	}
 	err := util.DockerBuild(util.DockerBuildOptions{
+		Env:          getBuildEnv(),
 		Cmd:          fmt.Sprint("cp -R /chaincode/input/src/. /chaincode/output && cd /chaincode/output && npm install --production"),
 		InputStream:  codepackage,
 		OutputStream: binpackage,
+		Cache:        util.GetBuildCache(),
 	})
 	if err != nil {
 		return err
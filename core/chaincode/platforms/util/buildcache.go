@@ -0,0 +1,165 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/config"
+	"github.com/spf13/viper"
+)
+
+// DefaultBuildCacheEntries is the number of build outputs kept when no limit
+// is configured
+const DefaultBuildCacheEntries = 100
+
+// BuildCache keeps the outputs of chaincode builds on disk, keyed by the
+// builder image and everything passed to it, so that building the same
+// chaincode again, such as after its image was removed or on a peer sharing
+// the cache, skips the builder container.  A nil BuildCache keeps nothing.
+type BuildCache struct {
+	Dir        string
+	MaxEntries int
+}
+
+// GetBuildCache returns the build cache configured by chaincode.buildCache,
+// or nil if it is not enabled
+func GetBuildCache() *BuildCache {
+	if !viper.GetBool("chaincode.buildCache.enabled") {
+		return nil
+	}
+	maxEntries := viper.GetInt("chaincode.buildCache.maxEntries")
+	if maxEntries <= 0 {
+		maxEntries = DefaultBuildCacheEntries
+	}
+	return &BuildCache{
+		Dir:        config.GetPath("chaincode.buildCache.path"),
+		MaxEntries: maxEntries,
+	}
+}
+
+// Key returns the key of the output of the build of the input by the
+// command in the environment of the builder image of the ID
+func (c *BuildCache) Key(imageID string, env []string, cmd string, input []byte) string {
+	var buf bytes.Buffer
+	for _, field := range append([]string{imageID, cmd}, env...) {
+		fmt.Fprintf(&buf, "%d:%s", len(field), field)
+	}
+	buf.Write(input)
+	return hex.EncodeToString(util.ComputeSHA256(buf.Bytes()))
+}
+
+// Get returns the output of the build of the key, if it is cached
+func (c *BuildCache) Get(key string) ([]byte, bool) {
+	if c == nil {
+		return nil, false
+	}
+	output, err := ioutil.ReadFile(filepath.Join(c.Dir, key))
+	if err != nil {
+		return nil, false
+	}
+	//更新修改时间，按最近使用淘汰
+	now := time.Now()
+	os.Chtimes(filepath.Join(c.Dir, key), now, now)
+	return output, true
+}
+
+// Put caches the output of the build of the key, evicting the least
+// recently used outputs past the maximum number of entries
+func (c *BuildCache) Put(key string, output []byte) error {
+	if c == nil {
+		return nil
+	}
+	if err := os.MkdirAll(c.Dir, 0755); err != nil {
+		return fmt.Errorf("Error creating build cache directory: %s", err)
+	}
+	//先写入临时文件再重命名，读取方不会看到写入一半的输出
+	tmp, err := ioutil.TempFile(c.Dir, "."+key)
+	if err != nil {
+		return fmt.Errorf("Error creating build cache entry: %s", err)
+	}
+	_, err = tmp.Write(output)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.Dir, key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("Error writing build cache entry: %s", err)
+	}
+	return c.evict()
+}
+
+// evict removes the least recently used outputs past the maximum number of
+// entries
+func (c *BuildCache) evict() error {
+	fis, err := ioutil.ReadDir(c.Dir)
+	if err != nil {
+		return fmt.Errorf("Error reading build cache directory: %s", err)
+	}
+	var entries []os.FileInfo
+	for _, fi := range fis {
+		if !fi.IsDir() && fi.Name()[0] != '.' {
+			entries = append(entries, fi)
+		}
+	}
+	if len(entries) <= c.MaxEntries {
+		return nil
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ModTime().Before(entries[j].ModTime()) })
+	for _, fi := range entries[:len(entries)-c.MaxEntries] {
+		logger.Debugf("Evicting build output %s from the build cache", fi.Name())
+		os.Remove(filepath.Join(c.Dir, fi.Name()))
+	}
+	return nil
+}
+
+// NormalizeTar rewrites the tarball with the times and owners of its files
+// cleared, so that identical files make identical tarballs whenever and
+// whoever builds them
+func NormalizeTar(in []byte) ([]byte, error) {
+	var out bytes.Buffer
+	tr := tar.NewReader(bytes.NewReader(in))
+	tw := tar.NewWriter(&out)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Error reading build output: %s", err)
+		}
+		var zeroTime time.Time
+		hdr.ModTime, hdr.AccessTime, hdr.ChangeTime = zeroTime, zeroTime, zeroTime
+		hdr.Uid, hdr.Gid, hdr.Uname, hdr.Gname = 0, 0, "", ""
+		//PAX扩展头中也记录了时间
+		hdr.PAXRecords = nil
+		hdr.Format = tar.FormatUnknown
+		if err := tw.WriteHeader(hdr); err != nil {
+			return nil, fmt.Errorf("Error writing build output: %s", err)
+		}
+		if _, err := io.Copy(tw, tr); err != nil {
+			return nil, fmt.Errorf("Error writing build output: %s", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("Error writing build output: %s", err)
+	}
+	return out.Bytes(), nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package util
+
+import (
+	"archive/tar"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "buildcache")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	cache := &BuildCache{Dir: filepath.Join(dir, "cache"), MaxEntries: 2}
+
+	// 构建镜像、命令、环境变量与输入均参与计算缓存键
+	key := cache.Key("sha256:1", []string{"GOARCH=amd64"}, "go build", []byte("input"))
+	assert.Equal(t, key, cache.Key("sha256:1", []string{"GOARCH=amd64"}, "go build", []byte("input")))
+	for _, other := range []string{
+		cache.Key("sha256:2", []string{"GOARCH=amd64"}, "go build", []byte("input")),
+		cache.Key("sha256:1", []string{"GOARCH=arm64"}, "go build", []byte("input")),
+		cache.Key("sha256:1", []string{"GOARCH=amd64"}, "go build -v", []byte("input")),
+		cache.Key("sha256:1", []string{"GOARCH=amd64"}, "go build", []byte("other")),
+		cache.Key("sha256:1", []string{"GOARCH=amd64", "go build"}, "", []byte("input")),
+	} {
+		assert.NotEqual(t, key, other)
+	}
+
+	_, ok := cache.Get(key)
+	assert.False(t, ok)
+	require.NoError(t, cache.Put(key, []byte("output")))
+	output, ok := cache.Get(key)
+	assert.True(t, ok)
+	assert.Equal(t, []byte("output"), output)
+
+	// 超出条目上限时淘汰最久未使用的输出
+	old := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(filepath.Join(cache.Dir, key), old, old))
+	require.NoError(t, cache.Put("second", []byte("2")))
+	_, ok = cache.Get(key)
+	assert.True(t, ok)
+	require.NoError(t, os.Chtimes(filepath.Join(cache.Dir, "second"), old, old))
+	require.NoError(t, cache.Put("third", []byte("3")))
+	_, ok = cache.Get("second")
+	assert.False(t, ok)
+	_, ok = cache.Get(key)
+	assert.True(t, ok)
+
+	var nilCache *BuildCache
+	assert.NoError(t, nilCache.Put(key, []byte("output")))
+	_, ok = nilCache.Get(key)
+	assert.False(t, ok)
+}
+
+func TestGetBuildCache(t *testing.T) {
+	defer viper.Set("chaincode.buildCache.enabled", false)
+	viper.Set("chaincode.buildCache.enabled", false)
+	assert.Nil(t, GetBuildCache())
+
+	viper.Set("chaincode.buildCache.enabled", true)
+	viper.Set("chaincode.buildCache.path", "/var/hyperledger/production/buildcache")
+	viper.Set("chaincode.buildCache.maxEntries", 0)
+	cache := GetBuildCache()
+	require.NotNil(t, cache)
+	assert.Equal(t, "/var/hyperledger/production/buildcache", cache.Dir)
+	assert.Equal(t, DefaultBuildCacheEntries, cache.MaxEntries)
+}
+
+func writeTar(t *testing.T, modTime time.Time, uid int) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "chaincode", Mode: 0755, Size: 3, ModTime: modTime, Uid: uid, Uname: "builder"}))
+	_, err := tw.Write([]byte("bin"))
+	require.NoError(t, err)
+	require.NoError(t, tw.Close())
+	return buf.Bytes()
+}
+
+func TestNormalizeTar(t *testing.T) {
+	first, err := NormalizeTar(writeTar(t, time.Unix(1000, 0), 1000))
+	require.NoError(t, err)
+	second, err := NormalizeTar(writeTar(t, time.Unix(2000, 0), 0))
+	require.NoError(t, err)
+	assert.Equal(t, first, second)
+
+	tr := tar.NewReader(bytes.NewReader(first))
+	hdr, err := tr.Next()
+	require.NoError(t, err)
+	assert.Equal(t, "chaincode", hdr.Name)
+	assert.Equal(t, int64(0755), hdr.Mode)
+	content, err := ioutil.ReadAll(tr)
+	require.NoError(t, err)
+	assert.Equal(t, "bin", string(content))
+
+	_, err = NormalizeTar([]byte("not a tarball, long enough to be read as a header block"))
+	assert.Error(t, err)
+}
@@ -115,6 +115,8 @@ type DockerBuildOptions struct {
 	Cmd          string
 	InputStream  io.Reader
 	OutputStream io.Writer
+	Cache        *BuildCache
+	Reproducible bool
 }
 
 //-------------------------------------------------------------------------------------------
@@ -140,6 +142,9 @@ type DockerBuildOptions struct {
 //      - InputStream:  A tarball of files that will be expanded into /chaincode/input.
 //      - OutputStream: A tarball of files that will be gathered from /chaincode/output
 //                      after successful execution of Cmd.
+//      - Cache:        (optional) The cache of the outputs of identical builds.
+//      - Reproducible: (optional) Clears the times and owners of the output files, so
+//                      that an identical output makes an identical tarball.
 //-------------------------------------------------------------------------------------------
 func DockerBuild(opts DockerBuildOptions) error {
 	client, err := cutil.NewDockerClient()
@@ -158,7 +163,7 @@ func DockerBuild(opts DockerBuildOptions) error {
 	//-----------------------------------------------------------------------------------
 	// Ensure the image exists locally, or pull it from a registry if it doesn't
 	//-----------------------------------------------------------------------------------
-	_, err = client.InspectImage(opts.Image)
+	image, err := client.InspectImage(opts.Image)
 	if err != nil {
 		logger.Debugf("Image %s does not exist locally, attempt pull", opts.Image)
 
@@ -166,6 +171,29 @@ func DockerBuild(opts DockerBuildOptions) error {
 		if err != nil {
 			return fmt.Errorf("Failed to pull %s: %s", opts.Image, err)
 		}
+		if opts.Cache != nil {
+			if image, err = client.InspectImage(opts.Image); err != nil {
+				return fmt.Errorf("Failed to inspect %s: %s", opts.Image, err)
+			}
+		}
+	}
+
+	//-----------------------------------------------------------------------------------
+	// Serve the output of an identical build by the same builder image if it is cached
+	//-----------------------------------------------------------------------------------
+	var cacheKey string
+	if opts.Cache != nil {
+		input, err := ioutil.ReadAll(opts.InputStream)
+		if err != nil {
+			return fmt.Errorf("Error reading input: %s", err)
+		}
+		opts.InputStream = bytes.NewReader(input)
+		cacheKey = opts.Cache.Key(image.ID, opts.Env, opts.Cmd, input)
+		if output, ok := opts.Cache.Get(cacheKey); ok {
+			logger.Infof("Using cached output of build %s by image %s", cacheKey, opts.Image)
+			_, err = opts.OutputStream.Write(output)
+			return err
+		}
 	}
 
 	//-----------------------------------------------------------------------------------
@@ -235,13 +263,27 @@ func DockerBuild(opts DockerBuildOptions) error {
 	//-----------------------------------------------------------------------------------
 	// Finally, download the result
 	//-----------------------------------------------------------------------------------
+	output := bytes.NewBuffer(nil)
 	err = client.DownloadFromContainer(container.ID, docker.DownloadFromContainerOptions{
 		Path:         "/chaincode/output/.",
-		OutputStream: opts.OutputStream,
+		OutputStream: output,
 	})
 	if err != nil {
 		return fmt.Errorf("Error downloading output: %s", err)
 	}
 
-	return nil
+	result := output.Bytes()
+	if opts.Reproducible {
+		if result, err = NormalizeTar(result); err != nil {
+			return err
+		}
+	}
+	if opts.Cache != nil {
+		//缓存失败不影响构建结果
+		if err := opts.Cache.Put(cacheKey, result); err != nil {
+			logger.Warningf("Failed to cache output of build %s: %s", cacheKey, err)
+		}
+	}
+	_, err = opts.OutputStream.Write(result)
+	return err
 }
@@ -48,6 +48,10 @@ const (
 	emptyKeySubstitute    = "\x01"
 )
 
+// RandomBeaconDecorationKey is the key of the proposal decoration through which
+// the peer passes the random beacon value of its latest block to the chaincode
+const RandomBeaconDecorationKey = "RANDOM_BEACON"
+
 // ChaincodeStub is an object passed to chaincode for shim side handling of
 // APIs.
 type ChaincodeStub struct {
@@ -392,6 +396,15 @@ func (stub *ChaincodeStub) GetDecorations() map[string][]byte {
 	return stub.decorations
 }
 
+// GetRandomBeacon documentation can be found in interfaces.go
+func (stub *ChaincodeStub) GetRandomBeacon() ([]byte, error) {
+	beacon, ok := stub.decorations[RandomBeaconDecorationKey]
+	if !ok || len(beacon) == 0 {
+		return nil, errors.New("no random beacon was provided by the peer")
+	}
+	return beacon, nil
+}
+
 // ------------- Call Chaincode functions ---------------
 
 // InvokeChaincode documentation can be found in interfaces.go
@@ -246,6 +246,13 @@ type ChaincodeStubInterface interface {
 	// peer, which append or mutate the chaincode input passed to the chaincode.
 	GetDecorations() map[string][]byte
 
+	// GetRandomBeacon returns the random beacon value of the latest block
+	// committed to the ledger of the endorsing peer, as set by the ordering
+	// service in the block metadata. The value is derived from the hash chain
+	// of the blocks, therefore endorsers at the same ledger height will see the
+	// same value and anyone can verify it from the blocks.
+	GetRandomBeacon() ([]byte, error)
+
 	// GetSignedProposal returns the SignedProposal object, which contains all
 	// data elements part of a transaction proposal.
 	GetSignedProposal() (*pb.SignedProposal, error)
@@ -52,6 +52,9 @@ type MockStub struct {
 
 	TxTimestamp *timestamp.Timestamp
 
+	// RandomBeacon is returned by GetRandomBeacon
+	RandomBeacon []byte
+
 	// mocked signedProposal
 	signedProposal *pb.SignedProposal
 
@@ -140,6 +143,13 @@ func (stub *MockStub) GetDecorations() map[string][]byte {
 	return nil
 }
 
+func (stub *MockStub) GetRandomBeacon() ([]byte, error) {
+	if len(stub.RandomBeacon) == 0 {
+		return nil, errors.New("no random beacon was provided by the peer")
+	}
+	return stub.RandomBeacon, nil
+}
+
 // Invoke this chaincode, also starts and ends a transaction.
 func (stub *MockStub) MockInvokeWithSignedProposal(uuid string, args [][]byte, sp *pb.SignedProposal) pb.Response {
 	stub.args = args
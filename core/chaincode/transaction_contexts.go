@@ -26,6 +26,11 @@ const (
 	// HistoryQueryExecutorKey is the context key used to provide a
 	// ledger.HistoryQueryExecutor from the endorser to the chaincode.
 	HistoryQueryExecutorKey key = "historyqueryexecutorkey"
+
+	// ChaincodeLimitsKey is the context key used to provide the
+	// *ccprovider.ChaincodeLimits of the invoked chaincode from the endorser
+	// to the chaincode.
+	ChaincodeLimitsKey key = "chaincodelimitskey"
 )
 
 // TransactionContexts maintains active transaction contexts for a Handler.
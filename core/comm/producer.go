@@ -9,6 +9,7 @@ package comm
 import (
 	"fmt"
 	"math/rand"
+	"sort"
 	"sync"
 	"time"
 
@@ -23,6 +24,9 @@ var EndpointDisableInterval = time.Second * 10
 // ConnectionFactory creates a connection to a certain endpoint
 type ConnectionFactory func(endpoint string) (*grpc.ClientConn, error)
 
+// EndpointRanker ranks endpoints by preference, lower ranks being preferred
+type EndpointRanker func(endpoint string) uint64
+
 // ConnectionProducer produces connections out of a set of predefined
 // endpoints
 type ConnectionProducer interface {
@@ -44,15 +48,24 @@ type connProducer struct {
 	endpoints         []string
 	disabledEndpoints map[string]time.Time
 	connect           ConnectionFactory
+	rank              EndpointRanker
 }
 
 // NewConnectionProducer creates a new ConnectionProducer with given endpoints and connection factory.
 // It returns nil, if the given endpoints slice is empty.
 func NewConnectionProducer(factory ConnectionFactory, endpoints []string) ConnectionProducer {
+	return NewRankedConnectionProducer(factory, endpoints, nil)
+}
+
+// NewRankedConnectionProducer creates a new ConnectionProducer which tries endpoints
+// in the order of their rank, and endpoints of equal rank in random order.
+// A nil ranker ranks all endpoints equally.
+// It returns nil, if the given endpoints slice is empty.
+func NewRankedConnectionProducer(factory ConnectionFactory, endpoints []string, ranker EndpointRanker) ConnectionProducer {
 	if len(endpoints) == 0 {
 		return nil
 	}
-	return &connProducer{endpoints: endpoints, connect: factory, disabledEndpoints: make(map[string]time.Time)}
+	return &connProducer{endpoints: endpoints, connect: factory, disabledEndpoints: make(map[string]time.Time), rank: ranker}
 }
 
 // NewConnection creates a new connection.
@@ -71,6 +84,12 @@ func (cp *connProducer) NewConnection() (*grpc.ClientConn, string, error) {
 
 	//对当前endpoints列表中的节点进行随机混洗
 	endpoints := shuffle(cp.endpoints)
+	//按优先级排序，同一优先级的节点保持随机顺序
+	if cp.rank != nil {
+		sort.SliceStable(endpoints, func(i, j int) bool {
+			return cp.rank(endpoints[i]) < cp.rank(endpoints[j])
+		})
+	}
 	checkedEndpoints := make([]string, 0)
 	//遍历endpoints列表检查每一个节点的可用性，获取第一个可用的Orderer服务节点，并且不属于disabledEndpoints列表
 	for _, endpoint := range endpoints {
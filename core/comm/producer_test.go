@@ -133,3 +133,34 @@ func TestDisableEndpoint(t *testing.T) {
 	assert.Equal(t, "b", a)
 
 }
+
+func TestRankedEndpoints(t *testing.T) {
+	t.Parallel()
+	shouldConnFail := map[string]bool{}
+	connFactory := func(endpoint string) (*grpc.ClientConn, error) {
+		if shouldConnFail[endpoint] {
+			return nil, fmt.Errorf("Failed connecting to %s", endpoint)
+		}
+		return &grpc.ClientConn{}, nil
+	}
+	ranks := map[string]uint64{"near1": 0, "near2": 0, "far": 1}
+	producer := NewRankedConnectionProducer(connFactory, []string{"far", "near1", "near2"}, func(endpoint string) uint64 {
+		return ranks[endpoint]
+	})
+
+	// Endpoints of the best rank are selected at random
+	selected := make(map[string]struct{})
+	for i := 0; i < 100; i++ {
+		_, endpoint, err := producer.NewConnection()
+		assert.NoError(t, err)
+		selected[endpoint] = struct{}{}
+	}
+	assert.Equal(t, map[string]struct{}{"near1": {}, "near2": {}}, selected)
+
+	// Fail over to the next rank once the best ones are unavailable
+	shouldConnFail["near1"] = true
+	shouldConnFail["near2"] = true
+	_, endpoint, err := producer.NewConnection()
+	assert.NoError(t, err)
+	assert.Equal(t, "far", endpoint)
+}
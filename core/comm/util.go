@@ -114,6 +114,16 @@ func noopBinding(_ context.Context, _ []byte) error {
 
 // ExtractCertificateHashFromContext extracts the hash of the certificate from the given context
 func ExtractCertificateHashFromContext(ctx context.Context) []byte {
+	cert := ExtractCertificateFromContext(ctx)
+	if cert == nil || len(cert.Raw) == 0 {
+		return nil
+	}
+	return util.ComputeSHA256(cert.Raw)
+}
+
+// ExtractCertificateFromContext extracts the certificate the client presented
+// in the TLS handshake from the given context, or returns nil if there is none
+func ExtractCertificateFromContext(ctx context.Context) *x509.Certificate {
 	pr, extracted := peer.FromContext(ctx)
 	if !extracted {
 		return nil
@@ -132,9 +142,5 @@ func ExtractCertificateHashFromContext(ctx context.Context) []byte {
 	if len(certs) == 0 {
 		return nil
 	}
-	raw := certs[0].Raw
-	if len(raw) == 0 {
-		return nil
-	}
-	return util.ComputeSHA256(raw)
+	return certs[0]
 }
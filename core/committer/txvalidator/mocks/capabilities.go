@@ -22,6 +22,20 @@ func (_m *Capabilities) ACLs() bool {
 	return r0
 }
 
+// ChaincodeLimits provides a mock function with given fields:
+func (_m *Capabilities) ChaincodeLimits() bool {
+	ret := _m.Called()
+
+	var r0 bool
+	if rf, ok := ret.Get(0).(func() bool); ok {
+		r0 = rf()
+	} else {
+		r0 = ret.Get(0).(bool)
+	}
+
+	return r0
+}
+
 // CollectionUpgrade provides a mock function with given fields:
 func (_m *Capabilities) CollectionUpgrade() bool {
 	ret := _m.Called()
@@ -96,6 +96,7 @@ type blockValidationResult struct {
 	txsUpgradedChaincode *sysccprovider.ChaincodeInstance
 	err                  error
 	txid                 string
+	failure              *ledger.ValidationFailure
 }
 
 // NewTxValidator creates new transactions validator
@@ -147,6 +148,8 @@ func (v *TxValidator) Validate(block *common.Block) error {
 	txsUpgradedChaincodes := make(map[int]*sysccprovider.ChaincodeInstance)
 	// array of txids
 	txidArray := make([]string, len(block.Data.Data))
+	// failures records the reasons of the invalid transactions, by index in the block
+	failures := make(map[uint64]*ledger.ValidationFailure)
 
 	results := make(chan *blockValidationResult)
 	go func() {
@@ -188,6 +191,9 @@ func (v *TxValidator) Validate(block *common.Block) error {
 			logger.Debugf("got result for idx %d, code %d", res.tIdx, res.validationCode)
 
 			txsfltr.SetFlag(res.tIdx, res.validationCode)
+			if res.failure != nil {
+				failures[uint64(res.tIdx)] = res.failure
+			}
 
 			if res.validationCode == peer.TxValidationCode_VALID {
 				if res.txsChaincodeName != nil {
@@ -230,6 +236,12 @@ func (v *TxValidator) Validate(block *common.Block) error {
 
 	block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = txsfltr
 
+	// hand the reasons of the invalid transactions to the ledger, which
+	// keeps those still matching the validation codes when the block is committed
+	if recorder, ok := v.Support.Ledger().(ledger.ValidationFailureRecorder); ok {
+		recorder.RecordValidationFailures(block.GetHeader().GetNumber(), failures)
+	}
+
 	return nil
 }
 
@@ -306,6 +318,18 @@ func (v *TxValidator) validateTx(req *blockValidationRequest, results chan<- *bl
 			return
 		}
 
+		if outer, err := utils.ChannelHeader(env); err == nil && common.HeaderType(outer.Type) == common.HeaderType_ENCRYPTED_TRANSACTION {
+			//加密交易已解密为背书交易，VSCC从区块中重新解析交易，因此向其提供解密后的交易
+			if d, block, err = decryptedTx(env, payload, block, tIdx); err != nil {
+				logger.Warningf("Could not marshal decrypted transaction, err %s, skipping", err)
+				results <- &blockValidationResult{
+					tIdx:           tIdx,
+					validationCode: peer.TxValidationCode_MARSHAL_TX_ERROR,
+				}
+				return
+			}
+		}
+
 		chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
 		if err != nil {
 			logger.Warningf("Could not unmarshal channel header, err %s, skipping", err)
@@ -375,6 +399,7 @@ func (v *TxValidator) validateTx(req *blockValidationRequest, results chan<- *bl
 					results <- &blockValidationResult{
 						tIdx:           tIdx,
 						validationCode: cde,
+						failure:        newValidationFailure(txID, cde, err),
 					}
 					return
 				}
@@ -453,6 +478,38 @@ func (v *TxValidator) validateTx(req *blockValidationRequest, results chan<- *bl
 	}
 }
 
+// decryptedTx returns the bytes of the envelope carrying the given decrypted
+// payload of an encrypted transaction, along with a shallow copy of the block
+// holding them at the index of the transaction, since the validation plugins
+// parse the transaction out of the block.  The block committed keeps the
+// ciphertext.
+func decryptedTx(env *common.Envelope, payload *common.Payload, block *common.Block, tIdx int) ([]byte, *common.Block, error) {
+	payloadBytes, err := proto.Marshal(payload)
+	if err != nil {
+		return nil, nil, err
+	}
+	d, err := proto.Marshal(&common.Envelope{Payload: payloadBytes, Signature: env.Signature})
+	if err != nil {
+		return nil, nil, err
+	}
+	data := make([][]byte, len(block.Data.Data))
+	copy(data, block.Data.Data)
+	data[tIdx] = d
+	return d, &common.Block{Header: block.Header, Data: &common.BlockData{Data: data}, Metadata: block.Metadata}, nil
+}
+
+// newValidationFailure describes the failure of a transaction rejected by the
+// validation of its endorsements
+func newValidationFailure(txID string, code peer.TxValidationCode, err error) *ledger.ValidationFailure {
+	failure := &ledger.ValidationFailure{TxID: txID, Code: code, Message: err.Error()}
+	if vf, ok := err.(*vsccFailure); ok {
+		failure.Plugin = vf.plugin
+		failure.Namespace = vf.namespace
+		failure.Policy = policyString(vf.policy)
+	}
+	return failure
+}
+
 // generateCCKey generates a unique identifier for chaincode in specific channel
 func (v *TxValidator) generateCCKey(ccName, chainID string) string {
 	return fmt.Sprintf("%s/%s", ccName, chainID)
@@ -605,6 +662,10 @@ func (ds *dynamicCapabilities) ForbidDuplicateTXIdInBlock() bool {
 	return ds.support.Capabilities().ForbidDuplicateTXIdInBlock()
 }
 
+func (ds *dynamicCapabilities) ChaincodeLimits() bool {
+	return ds.support.Capabilities().ChaincodeLimits()
+}
+
 func (ds *dynamicCapabilities) KeyLevelEndorsement() bool {
 	return ds.support.Capabilities().KeyLevelEndorsement()
 }
@@ -906,12 +906,23 @@ func TestDuplicateTxId(t *testing.T) {
 	assertion.True(txsfltr.Flag(0) == peer.TxValidationCode_DUPLICATE_TXID)
 }
 
+// recordingLedger keeps the validation failures handed over by the validator
+type recordingLedger struct {
+	*mockLedger
+	failures map[uint64]*ledger.ValidationFailure
+}
+
+func (l *recordingLedger) RecordValidationFailures(blockNum uint64, failures map[uint64]*ledger.ValidationFailure) {
+	l.failures = failures
+}
+
 func TestValidationInvalidEndorsing(t *testing.T) {
 	theLedger := new(mockLedger)
+	recorder := &recordingLedger{mockLedger: theLedger}
 	vcs := struct {
 		*mocktxvalidator.Support
 		*semaphore.Weighted
-	}{&mocktxvalidator.Support{LedgerVal: theLedger, ACVal: &mockconfig.MockApplicationCapabilities{}}, semaphore.NewWeighted(10)}
+	}{&mocktxvalidator.Support{LedgerVal: recorder, ACVal: &mockconfig.MockApplicationCapabilities{}}, semaphore.NewWeighted(10)}
 	mp := (&scc.MocksccProviderFactory{}).NewSystemChaincodeProvider()
 	pm := &mocks.PluginMapper{}
 	factory := &mocks.PluginFactory{}
@@ -947,6 +958,19 @@ func TestValidationInvalidEndorsing(t *testing.T) {
 	// Restore default callback
 	assert.NoError(t, err)
 	assertInvalid(b, t, peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE)
+
+	// the reason of the failure is recorded in the ledger
+	chdr, err := utils.ChannelHeader(tx)
+	assert.NoError(t, err)
+	assert.Len(t, recorder.failures, 1)
+	assert.Equal(t, &ledger.ValidationFailure{
+		TxID:      chdr.TxId,
+		Code:      peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE,
+		Plugin:    "vscc",
+		Namespace: ccID,
+		Policy:    "OR('SampleOrg.member')",
+		Message:   "invalid tx",
+	}, recorder.failures[0])
 }
 
 func createMockLedger(t *testing.T, ccID string) *mockLedger {
@@ -10,7 +10,9 @@
 package txvalidator
 
 import (
+	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/cauthdsl"
@@ -21,6 +23,7 @@ import (
 	"github.com/hyperledger/fabric/core/handlers/validation/api"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
 	"github.com/hyperledger/fabric/protos/common"
+	mspprotos "github.com/hyperledger/fabric/protos/msp"
 	"github.com/hyperledger/fabric/protos/peer"
 	"github.com/hyperledger/fabric/protos/utils"
 	"github.com/pkg/errors"
@@ -206,7 +209,7 @@ func (v *VsccValidatorImpl) VSCCValidateTx(seq int, payload *common.Payload, env
 			if err = v.VSCCValidateTxForCC(ctx); err != nil {
 				switch err.(type) {
 				case *commonerrors.VSCCEndorsementPolicyError:
-					return err, peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE
+					return newVSCCFailure(ctx, err), peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE
 				default:
 					return err, peer.TxValidationCode_INVALID_OTHER_REASON
 				}
@@ -247,7 +250,7 @@ func (v *VsccValidatorImpl) VSCCValidateTx(seq int, payload *common.Payload, env
 		if err = v.VSCCValidateTxForCC(ctx); err != nil {
 			switch err.(type) {
 			case *commonerrors.VSCCEndorsementPolicyError:
-				return err, peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE
+				return newVSCCFailure(ctx, err), peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE
 			default:
 				return err, peer.TxValidationCode_INVALID_OTHER_REASON
 			}
@@ -271,6 +274,63 @@ func (v *VsccValidatorImpl) VSCCValidateTxForCC(ctx *Context) error {
 	return &commonerrors.VSCCEndorsementPolicyError{Err: err}
 }
 
+// vsccFailure is the error of a validation plugin which rejected the
+// endorsements of a namespace
+type vsccFailure struct {
+	error
+	plugin    string
+	namespace string
+	policy    []byte
+}
+
+func newVSCCFailure(ctx *Context, err error) *vsccFailure {
+	return &vsccFailure{error: err, plugin: ctx.VSCCName, namespace: ctx.Namespace, policy: ctx.Policy}
+}
+
+// policyString prints an endorsement policy the way it is written when the
+// chaincode is instantiated, e.g. OR('Org1MSP.member','Org2MSP.member'), or
+// hex encoded if it is not a signature policy
+func policyString(policy []byte) string {
+	env := &common.SignaturePolicyEnvelope{}
+	if err := proto.Unmarshal(policy, env); err != nil || env.Rule == nil {
+		return hex.EncodeToString(policy)
+	}
+	return signaturePolicyString(env.Rule, env.Identities)
+}
+
+func signaturePolicyString(policy *common.SignaturePolicy, identities []*mspprotos.MSPPrincipal) string {
+	switch t := policy.Type.(type) {
+	case *common.SignaturePolicy_SignedBy:
+		if t.SignedBy < 0 || int(t.SignedBy) >= len(identities) {
+			return fmt.Sprintf("'identity %d'", t.SignedBy)
+		}
+		return principalString(identities[t.SignedBy])
+	case *common.SignaturePolicy_NOutOf_:
+		rules := make([]string, len(t.NOutOf.Rules))
+		for i, rule := range t.NOutOf.Rules {
+			rules[i] = signaturePolicyString(rule, identities)
+		}
+		switch int(t.NOutOf.N) {
+		case 1:
+			return "OR(" + strings.Join(rules, ",") + ")"
+		case len(rules):
+			return "AND(" + strings.Join(rules, ",") + ")"
+		}
+		return fmt.Sprintf("OutOf(%d,%s)", t.NOutOf.N, strings.Join(rules, ","))
+	}
+	return "?"
+}
+
+func principalString(principal *mspprotos.MSPPrincipal) string {
+	if principal.PrincipalClassification == mspprotos.MSPPrincipal_ROLE {
+		role := &mspprotos.MSPRole{}
+		if err := proto.Unmarshal(principal.Principal, role); err == nil {
+			return fmt.Sprintf("'%s.%s'", role.MspIdentifier, strings.ToLower(role.Role.String()))
+		}
+	}
+	return "'" + principal.PrincipalClassification.String() + "'"
+}
+
 func (v *VsccValidatorImpl) getCDataForCC(chid, ccid string) (ccprovider.ChaincodeDefinition, error) {
 	l := v.support.Ledger()
 	if l == nil {
@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/flogging"
@@ -438,6 +439,9 @@ type ChaincodeData struct {
 
 	// InstantiationPolicy for the chaincode
 	InstantiationPolicy []byte `protobuf:"bytes,8,opt,name=instantiation_policy,proto3"`
+
+	// Limits of the chaincode instance, overriding those of the peers
+	Limits *ChaincodeLimits `protobuf:"bytes,9,opt,name=limits"`
 }
 
 // CCName returns the name of this chaincode (the name it was put in the ChaincodeRegistry with).
@@ -480,6 +484,58 @@ func (cd *ChaincodeData) String() string { return proto.CompactTextString(cd) }
 // ProtoMessage just exists to make proto happy
 func (*ChaincodeData) ProtoMessage() {}
 
+// ChaincodeLimits are the execution timeout and resource limits of a
+// chaincode instance, set upon instantiation or upgrade.  A zero value
+// leaves the limit configured on the peer.
+type ChaincodeLimits struct {
+	// ExecuteTimeout is the timeout of an invocation, in milliseconds
+	ExecuteTimeout int64 `protobuf:"varint,1,opt,name=execute_timeout"`
+
+	// Memory is the memory limit of the chaincode container, in bytes
+	Memory int64 `protobuf:"varint,2,opt,name=memory"`
+
+	// CPUShares is the relative CPU weight of the chaincode container
+	CPUShares int64 `protobuf:"varint,3,opt,name=cpu_shares"`
+}
+
+// GetExecuteTimeout returns the execute timeout of the limits, or
+// defaultTimeout if it is not set
+func (cl *ChaincodeLimits) GetExecuteTimeout(defaultTimeout time.Duration) time.Duration {
+	if cl == nil || cl.ExecuteTimeout <= 0 {
+		return defaultTimeout
+	}
+	return time.Duration(cl.ExecuteTimeout) * time.Millisecond
+}
+
+// Validate checks that none of the limits is negative
+func (cl *ChaincodeLimits) Validate() error {
+	if cl.ExecuteTimeout < 0 || cl.Memory < 0 || cl.CPUShares < 0 {
+		return fmt.Errorf("invalid chaincode limits (execute timeout %d, memory %d, cpu shares %d), limits cannot be negative", cl.ExecuteTimeout, cl.Memory, cl.CPUShares)
+	}
+	return nil
+}
+
+// GetChaincodeLimits unmarshals and validates chaincode limits
+func GetChaincodeLimits(limitsBytes []byte) (*ChaincodeLimits, error) {
+	limits := &ChaincodeLimits{}
+	if err := proto.Unmarshal(limitsBytes, limits); err != nil {
+		return nil, fmt.Errorf("invalid chaincode limits: %s", err)
+	}
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+	return limits, nil
+}
+
+// Reset resets
+func (cl *ChaincodeLimits) Reset() { *cl = ChaincodeLimits{} }
+
+// String converts to string
+func (cl *ChaincodeLimits) String() string { return proto.CompactTextString(cl) }
+
+// ProtoMessage just exists to make proto happy
+func (*ChaincodeLimits) ProtoMessage() {}
+
 // ChaincodeSpecGetter normalizes getting a chaincode spec from an
 // ChaincodeInvocationSpec or a ChaincodeDeploymentSpec.
 type ChaincodeSpecGetter interface {
@@ -0,0 +1,171 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package encryptedtx
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+// Decrypter decrypts the data of the encrypted transactions of a channel
+type Decrypter interface {
+	// Decrypt returns the plaintext of the ciphertext encrypted for the key
+	// of the given ID of the channel
+	Decrypt(channelID string, keyID string, ciphertext []byte) ([]byte, error)
+}
+
+// ErrNoDecrypter is returned when an encrypted transaction is opened by a
+// peer which has no KMS plugin configured
+var ErrNoDecrypter = errors.New("no decrypter is configured")
+
+var logger = flogging.MustGetLogger("encryptedtx")
+
+var (
+	lock      sync.RWMutex
+	decrypter Decrypter
+	once      sync.Once
+
+	// retryInterval 为解密失败后首次重试的间隔，之后每次加倍直至 maxRetryInterval
+	retryInterval    = time.Second
+	maxRetryInterval = time.Minute
+)
+
+// Initialize sets the decrypter of the encrypted transactions. This function
+// is expected to be invoked only once, when the peer starts.
+func Initialize(d Decrypter) {
+	once.Do(func() {
+		initialize(d)
+	})
+}
+
+func initialize(d Decrypter) {
+	lock.Lock()
+	defer lock.Unlock()
+	decrypter = d
+}
+
+func decrypt(channelID string, encrypted *common.EncryptedPayload) ([]byte, error) {
+	lock.RLock()
+	d := decrypter
+	lock.RUnlock()
+	if d == nil {
+		return nil, ErrNoDecrypter
+	}
+	plaintext, err := d.Decrypt(channelID, encrypted.KeyId, encrypted.Ciphertext)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error decrypting payload")
+	}
+	return plaintext, nil
+}
+
+// Open returns the payload of the endorser transaction carried encrypted by
+// the given payload of an encrypted transaction.  The channel header of the
+// endorsed proposal is recovered by setting back its type, hence clients
+// are expected to marshal it the way Seal does.  The decrypted payload is
+// never stored: the ledger keeps the ciphertext the orderer ordered.
+//
+// Whether a transaction decrypts depends on the keys and the KMS of the
+// peer, not on the transaction, so a payload which cannot be decrypted is
+// never reported: Open logs the failure and retries, with an exponential
+// backoff, until the decrypter of the peer is able to decrypt it.  The
+// commit of the block carrying the transaction is thus held up rather than
+// the transaction being marked invalid by this peer only.  An error is only
+// returned for a payload which is malformed, and hence invalid on every peer.
+func Open(payload *common.Payload) (*common.Payload, error) {
+	if payload == nil || payload.Header == nil {
+		return nil, errors.New("nil payload or header")
+	}
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		return nil, err
+	}
+	if common.HeaderType(chdr.Type) != common.HeaderType_ENCRYPTED_TRANSACTION {
+		return nil, errors.Errorf("invalid header type %s", common.HeaderType(chdr.Type))
+	}
+	encrypted := &common.EncryptedPayload{}
+	if err := proto.Unmarshal(payload.Data, encrypted); err != nil {
+		return nil, errors.Wrap(err, "error unmarshaling EncryptedPayload")
+	}
+	plaintext, err := decrypt(chdr.ChannelId, encrypted)
+	for backoff := retryInterval; err != nil; plaintext, err = decrypt(chdr.ChannelId, encrypted) {
+		logger.Errorf("[%s] Could not decrypt transaction %s with key %s, retrying in %s: %s", chdr.ChannelId, chdr.TxId, encrypted.KeyId, backoff, err)
+		time.Sleep(backoff)
+		if backoff *= 2; backoff > maxRetryInterval {
+			backoff = maxRetryInterval
+		}
+	}
+
+	//恢复背书提案的通道头部，签名头部保持不变
+	chdr.Type = int32(common.HeaderType_ENDORSER_TRANSACTION)
+	chdrBytes, err := proto.Marshal(chdr)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling ChannelHeader")
+	}
+	return &common.Payload{
+		Header: &common.Header{ChannelHeader: chdrBytes, SignatureHeader: payload.Header.SignatureHeader},
+		Data:   plaintext,
+	}, nil
+}
+
+// OpenEnvelope returns the envelope of the endorser transaction carried
+// encrypted by the given envelope.  The returned envelope keeps the signature
+// of the encrypted one, which does not verify against the decrypted payload.
+func OpenEnvelope(env *common.Envelope) (*common.Envelope, error) {
+	payload, err := utils.UnmarshalPayload(env.Payload)
+	if err != nil {
+		return nil, err
+	}
+	opened, err := Open(payload)
+	if err != nil {
+		return nil, err
+	}
+	payloadBytes, err := proto.Marshal(opened)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling Payload")
+	}
+	return &common.Envelope{Payload: payloadBytes, Signature: env.Signature}, nil
+}
+
+// Seal returns the payload of the encrypted transaction carrying the data of
+// the given payload of an endorser transaction, encrypted by the given
+// function for the key of the given ID.  The header is left in the clear so
+// that the orderer orders the transaction without decrypting it.
+func Seal(payload *common.Payload, keyID string, encrypt func(plaintext []byte) ([]byte, error)) (*common.Payload, error) {
+	if payload == nil || payload.Header == nil {
+		return nil, errors.New("nil payload or header")
+	}
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		return nil, err
+	}
+	if common.HeaderType(chdr.Type) != common.HeaderType_ENDORSER_TRANSACTION {
+		return nil, errors.Errorf("invalid header type %s", common.HeaderType(chdr.Type))
+	}
+	ciphertext, err := encrypt(payload.Data)
+	if err != nil {
+		return nil, errors.WithMessage(err, "error encrypting payload")
+	}
+	data, err := proto.Marshal(&common.EncryptedPayload{KeyId: keyID, Ciphertext: ciphertext})
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling EncryptedPayload")
+	}
+	chdr.Type = int32(common.HeaderType_ENCRYPTED_TRANSACTION)
+	chdrBytes, err := proto.Marshal(chdr)
+	if err != nil {
+		return nil, errors.Wrap(err, "error marshaling ChannelHeader")
+	}
+	return &common.Payload{
+		Header: &common.Header{ChannelHeader: chdrBytes, SignatureHeader: payload.Header.SignatureHeader},
+		Data:   data,
+	}, nil
+}
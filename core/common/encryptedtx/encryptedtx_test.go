@@ -0,0 +1,165 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package encryptedtx
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// xorDecrypter reverses the bytes encrypted by xor for the key "key1"
+type xorDecrypter struct{}
+
+func (xorDecrypter) Decrypt(channelID string, keyID string, ciphertext []byte) ([]byte, error) {
+	if keyID != "key1" {
+		return nil, errors.Errorf("unknown key %s of channel %s", keyID, channelID)
+	}
+	return xor(ciphertext)
+}
+
+// keysDecrypter reverses the bytes encrypted by xor for the keys it holds
+type keysDecrypter struct {
+	keys map[string]bool
+}
+
+func (kd *keysDecrypter) Decrypt(channelID string, keyID string, ciphertext []byte) ([]byte, error) {
+	if !kd.keys[keyID] {
+		return nil, errors.Errorf("unknown key %s of channel %s", keyID, channelID)
+	}
+	return xor(ciphertext)
+}
+
+func xor(data []byte) ([]byte, error) {
+	out := make([]byte, len(data))
+	for i, b := range data {
+		out[i] = b ^ 0x5a
+	}
+	return out, nil
+}
+
+func endorserPayload(t *testing.T, data []byte) *common.Payload {
+	chdr := utils.MakeChannelHeader(common.HeaderType_ENDORSER_TRANSACTION, 0, "mychannel", 0)
+	chdr.TxId = "txid"
+	return &common.Payload{
+		Header: &common.Header{
+			ChannelHeader:   utils.MarshalOrPanic(chdr),
+			SignatureHeader: utils.MarshalOrPanic(&common.SignatureHeader{Creator: []byte("creator"), Nonce: []byte("nonce")}),
+		},
+		Data: data,
+	}
+}
+
+// openAsync opens the payload in a goroutine, the result being sent on the
+// returned channel
+func openAsync(payload *common.Payload) <-chan *common.Payload {
+	result := make(chan *common.Payload, 1)
+	go func() {
+		opened, err := Open(payload)
+		if err != nil {
+			panic(err)
+		}
+		result <- opened
+	}()
+	return result
+}
+
+func TestSealOpen(t *testing.T) {
+	defer InitializeTestEnv(nil)
+	InitializeTestEnv(xorDecrypter{})
+	payload := endorserPayload(t, []byte("transaction"))
+
+	sealed, err := Seal(payload, "key1", xor)
+	require.NoError(t, err)
+	chdr, err := utils.UnmarshalChannelHeader(sealed.Header.ChannelHeader)
+	require.NoError(t, err)
+	assert.Equal(t, int32(common.HeaderType_ENCRYPTED_TRANSACTION), chdr.Type)
+	assert.Equal(t, "txid", chdr.TxId, "the header is left in the clear")
+	assert.False(t, bytes.Contains(sealed.Data, []byte("transaction")))
+
+	opened, err := Open(sealed)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(payload, opened), "the header of the proposal is recovered")
+
+	env := &common.Envelope{Payload: utils.MarshalOrPanic(sealed), Signature: []byte("signature")}
+	openedEnv, err := OpenEnvelope(env)
+	require.NoError(t, err)
+	assert.Equal(t, utils.MarshalOrPanic(payload), openedEnv.Payload)
+	assert.Equal(t, []byte("signature"), openedEnv.Signature)
+}
+
+func TestOpenRetries(t *testing.T) {
+	defer InitializeTestEnv(nil)
+	defer SetRetryIntervalTestEnv(time.Second, time.Minute)
+	SetRetryIntervalTestEnv(time.Millisecond, 10*time.Millisecond)
+	InitializeTestEnv(nil)
+	payload := endorserPayload(t, []byte("transaction"))
+	sealed, err := Seal(payload, "key1", xor)
+	require.NoError(t, err)
+
+	// without a decrypter the payload is not reported undecryptable, Open
+	// waits for one to be configured
+	result := openAsync(sealed)
+	select {
+	case <-result:
+		t.Fatal("Open returned without a decrypter")
+	case <-time.After(100 * time.Millisecond):
+	}
+	InitializeTestEnv(xorDecrypter{})
+	select {
+	case opened := <-result:
+		assert.True(t, proto.Equal(payload, opened))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Open did not return once a decrypter was configured")
+	}
+
+	// as it waits for the key to be available
+	sealed, err = Seal(payload, "key2", xor)
+	require.NoError(t, err)
+	result = openAsync(sealed)
+	select {
+	case <-result:
+		t.Fatal("Open returned without the key")
+	case <-time.After(100 * time.Millisecond):
+	}
+	InitializeTestEnv(&keysDecrypter{keys: map[string]bool{"key1": true, "key2": true}})
+	select {
+	case opened := <-result:
+		assert.True(t, proto.Equal(payload, opened))
+	case <-time.After(5 * time.Second):
+		t.Fatal("Open did not return once the key was available")
+	}
+}
+
+func TestSealOpenErrors(t *testing.T) {
+	defer InitializeTestEnv(nil)
+	InitializeTestEnv(xorDecrypter{})
+	payload := endorserPayload(t, []byte("transaction"))
+
+	_, err := Open(payload)
+	assert.EqualError(t, err, "invalid header type ENDORSER_TRANSACTION")
+	_, err = Seal(&common.Payload{Header: &common.Header{ChannelHeader: utils.MarshalOrPanic(
+		utils.MakeChannelHeader(common.HeaderType_CONFIG, 0, "mychannel", 0))}}, "key1", xor)
+	assert.EqualError(t, err, "invalid header type CONFIG")
+	_, err = Seal(nil, "key1", xor)
+	assert.EqualError(t, err, "nil payload or header")
+	_, err = Seal(payload, "key1", func([]byte) ([]byte, error) { return nil, errors.New("kms down") })
+	assert.EqualError(t, err, "error encrypting payload: kms down")
+
+	sealed, err := Seal(payload, "key1", xor)
+	require.NoError(t, err)
+	sealed.Data = []byte("garbage")
+	_, err = Open(sealed)
+	assert.Contains(t, err.Error(), "error unmarshaling EncryptedPayload")
+}
@@ -0,0 +1,20 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package encryptedtx
+
+import "time"
+
+// InitializeTestEnv sets the decrypter of the encrypted transactions for test
+func InitializeTestEnv(d Decrypter) {
+	initialize(d)
+}
+
+// SetRetryIntervalTestEnv sets the intervals at which the decryption of a
+// transaction is retried for test
+func SetRetryIntervalTestEnv(interval, maxInterval time.Duration) {
+	retryInterval, maxRetryInterval = interval, maxInterval
+}
@@ -17,15 +17,23 @@ limitations under the License.
 package validation
 
 import (
+	"crypto/aes"
+	"crypto/cipher"
+	crand "crypto/rand"
 	"fmt"
+	"io/ioutil"
 	"math/rand"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/hyperledger/fabric/common/mocks/config"
 	mmsp "github.com/hyperledger/fabric/common/mocks/msp"
 	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/common/encryptedtx"
+	kmsapi "github.com/hyperledger/fabric/core/handlers/kms/api"
+	kms "github.com/hyperledger/fabric/core/handlers/kms/builtin"
 	"github.com/hyperledger/fabric/msp"
 	mspmgmt "github.com/hyperledger/fabric/msp/mgmt"
 	"github.com/hyperledger/fabric/msp/mgmt/testtools"
@@ -33,6 +41,7 @@ import (
 	"github.com/hyperledger/fabric/protos/peer"
 	"github.com/hyperledger/fabric/protos/utils"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func getProposal(channel string) (*peer.Proposal, error) {
@@ -467,6 +476,112 @@ func TestInvocationsBadArgs(t *testing.T) {
 	assert.Error(t, err)
 }
 
+// sealForKeyFile encrypts the payload of the transaction for the key of
+// the channel, the way the key file KMS plugin decrypts it, and signs the
+// encrypted payload
+func sealForKeyFile(t *testing.T, tx *common.Envelope, channelID, keyID string, key []byte) *common.Envelope {
+	payload, err := utils.UnmarshalPayload(tx.Payload)
+	require.NoError(t, err)
+	sealed, err := encryptedtx.Seal(payload, keyID, func(plaintext []byte) ([]byte, error) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		nonce := make([]byte, aead.NonceSize())
+		if _, err := crand.Read(nonce); err != nil {
+			return nil, err
+		}
+		return aead.Seal(nonce, nonce, plaintext, kms.KeyFileAdditionalData(channelID, keyID)), nil
+	})
+	require.NoError(t, err)
+	payloadBytes := utils.MarshalOrPanic(sealed)
+	sig, err := signer.Sign(payloadBytes)
+	require.NoError(t, err)
+	return &common.Envelope{Payload: payloadBytes, Signature: sig}
+}
+
+// keyFileDecrypter returns a decrypter reading the keys of the given directory
+func keyFileDecrypter(t *testing.T, keyDir string) encryptedtx.Decrypter {
+	d := (&kms.KeyFileDecrypterFactory{}).New()
+	require.NoError(t, d.Init(kmsapi.Parameters{"keyDir": keyDir}))
+	return d
+}
+
+func TestEncryptedTxOnePeerWithoutKey(t *testing.T) {
+	defer encryptedtx.InitializeTestEnv(nil)
+	defer encryptedtx.SetRetryIntervalTestEnv(time.Second, time.Minute)
+	encryptedtx.SetRetryIntervalTestEnv(time.Millisecond, 10*time.Millisecond)
+
+	channelID := util.GetTestChainID()
+	prop, err := getProposal(channelID)
+	require.NoError(t, err)
+	presp, err := utils.CreateProposalResponse(prop.Header, prop.Payload, &peer.Response{Status: 200}, []byte("simulation_result"), nil, getChaincodeID(), nil, signer)
+	require.NoError(t, err)
+	tx, err := utils.CreateSignedTx(prop, signer, presp)
+	require.NoError(t, err)
+
+	key := make([]byte, 32)
+	_, err = crand.Read(key)
+	require.NoError(t, err)
+	encrypted := sealForKeyFile(t, tx, channelID, "key1", key)
+
+	// peer1 holds the key of the channel, peer2 does not
+	keyDir1, err := ioutil.TempDir("", "peer1-keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(keyDir1)
+	require.NoError(t, os.MkdirAll(filepath.Join(keyDir1, channelID), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(keyDir1, channelID, "key1"), key, 0600))
+	keyDir2, err := ioutil.TempDir("", "peer2-keys")
+	require.NoError(t, err)
+	defer os.RemoveAll(keyDir2)
+
+	encryptedtx.InitializeTestEnv(keyFileDecrypter(t, keyDir1))
+	payload1, code := ValidateTransaction(encrypted, &config.MockApplicationCapabilities{})
+	require.Equal(t, peer.TxValidationCode_VALID, code)
+
+	// peer2 does not mark the transaction invalid, which would fork its
+	// ledger from the one of peer1, the validation waits for the key instead
+	encryptedtx.InitializeTestEnv(keyFileDecrypter(t, keyDir2))
+	type result struct {
+		payload *common.Payload
+		code    peer.TxValidationCode
+	}
+	results := make(chan result, 1)
+	go func() {
+		payload, code := ValidateTransaction(encrypted, &config.MockApplicationCapabilities{})
+		results <- result{payload: payload, code: code}
+	}()
+	select {
+	case r := <-results:
+		t.Fatalf("validation without the key returned %s", r.code)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	require.NoError(t, os.MkdirAll(filepath.Join(keyDir2, channelID), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(keyDir2, channelID, "key1"), key, 0600))
+	select {
+	case r := <-results:
+		assert.Equal(t, peer.TxValidationCode_VALID, r.code)
+		assert.Equal(t, utils.MarshalOrPanic(payload1), utils.MarshalOrPanic(r.payload), "both peers validate the same transaction")
+	case <-time.After(5 * time.Second):
+		t.Fatal("validation did not complete once the key was available")
+	}
+
+	// a malformed encrypted payload is invalid on every peer
+	payload, err := utils.UnmarshalPayload(encrypted.Payload)
+	require.NoError(t, err)
+	payload.Data = []byte("garbage")
+	payloadBytes := utils.MarshalOrPanic(payload)
+	sig, err := signer.Sign(payloadBytes)
+	require.NoError(t, err)
+	_, code = ValidateTransaction(&common.Envelope{Payload: payloadBytes, Signature: sig}, &config.MockApplicationCapabilities{})
+	assert.Equal(t, peer.TxValidationCode_BAD_PAYLOAD, code)
+}
+
 var signer msp.SigningIdentity
 var signerSerialized []byte
 var signerMSPId string
@@ -22,6 +22,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/common/encryptedtx"
 	mspmgmt "github.com/hyperledger/fabric/msp/mgmt"
 	"github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/msp"
@@ -219,6 +220,7 @@ func validateChannelHeader(cHdr *common.ChannelHeader) error {
 
 	// validate the header type
 	if common.HeaderType(cHdr.Type) != common.HeaderType_ENDORSER_TRANSACTION &&
+		common.HeaderType(cHdr.Type) != common.HeaderType_ENCRYPTED_TRANSACTION &&
 		common.HeaderType(cHdr.Type) != common.HeaderType_CONFIG_UPDATE &&
 		common.HeaderType(cHdr.Type) != common.HeaderType_CONFIG {
 		return errors.Errorf("invalid header type %s", common.HeaderType(cHdr.Type))
@@ -429,6 +431,33 @@ func ValidateTransaction(e *common.Envelope, c channelconfig.ApplicationCapabili
 		} else {
 			return payload, pb.TxValidationCode_VALID
 		}
+	case common.HeaderType_ENCRYPTED_TRANSACTION:
+		// The creator signed the encrypted payload, whose header is the one
+		// of the endorsed proposal but for its type
+		err = utils.CheckProposalTxID(
+			chdr.TxId,
+			shdr.Nonce,
+			shdr.Creator)
+
+		if err != nil {
+			putilsLogger.Errorf("CheckProposalTxID returns err %s", err)
+			return nil, pb.TxValidationCode_BAD_PROPOSAL_TXID
+		}
+
+		//解密后按背书交易验证，返回解密后的负载
+		//无法解密时 Open 会一直重试，只有格式错误的负载才返回错误
+		opened, err := encryptedtx.Open(payload)
+		if err != nil {
+			putilsLogger.Errorf("Failed opening the payload of transaction %s: %s", chdr.TxId, err)
+			return nil, pb.TxValidationCode_BAD_PAYLOAD
+		}
+
+		err = validateEndorserTransaction(opened.Data, opened.Header)
+		if err != nil {
+			putilsLogger.Errorf("validateEndorserTransaction returns err %s", err)
+			return opened, pb.TxValidationCode_INVALID_ENDORSER_TRANSACTION
+		}
+		return opened, pb.TxValidationCode_VALID
 	case common.HeaderType_CONFIG:
 		// Config transactions have signatures inside which will be validated, especially at genesis there may be no creator or
 		// signature on the outermost envelope
@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package connectors
+
+import (
+	"fmt"
+
+	connector "github.com/hyperledger/fabric/core/handlers/connector/api"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
+	"github.com/hyperledger/fabric/core/ledger/util"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/peer"
+	putils "github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+// NewBlock converts a block committed on the channel into the block written
+// to the sinks.  The writes are extracted from the valid endorser
+// transactions only, as the others did not change the world state.
+func NewBlock(channelID string, block *common.Block) (*connector.Block, error) {
+	txsFilter := util.TxValidationFlags(block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER])
+	b := &connector.Block{
+		ChannelID: channelID,
+		Number:    block.Header.Number,
+		Hash:      block.Header.Hash(),
+	}
+	for txNum, envBytes := range block.Data.Data {
+		tx, err := newTransaction(uint64(txNum), envBytes, txsFilter.Flag(txNum))
+		if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("failed converting transaction [%d] of block [%d]", txNum, block.Header.Number))
+		}
+		b.Transactions = append(b.Transactions, tx)
+	}
+	return b, nil
+}
+
+func newTransaction(txNum uint64, envBytes []byte, code peer.TxValidationCode) (*connector.Transaction, error) {
+	tx := &connector.Transaction{
+		Number:         txNum,
+		ValidationCode: code,
+	}
+	env, err := putils.GetEnvelopeFromBlock(envBytes)
+	if err == nil {
+		var payload *common.Payload
+		if payload, err = putils.GetPayload(env); err == nil {
+			var chdr *common.ChannelHeader
+			if chdr, err = putils.UnmarshalChannelHeader(payload.Header.ChannelHeader); err == nil {
+				tx.TxID = chdr.TxId
+				tx.Type = common.HeaderType(chdr.Type).String()
+			}
+		}
+	}
+	// A transaction may have been invalidated precisely because it could not
+	// be parsed, in which case it is passed on without id
+	if err != nil {
+		if code == peer.TxValidationCode_VALID {
+			return nil, err
+		}
+		return tx, nil
+	}
+	if code != peer.TxValidationCode_VALID || tx.Type != common.HeaderType_ENDORSER_TRANSACTION.String() {
+		return tx, nil
+	}
+
+	respPayload, err := putils.GetActionFromEnvelope(envBytes)
+	if err != nil {
+		return nil, err
+	}
+	txRWSet := &rwsetutil.TxRwSet{}
+	if err = txRWSet.FromProtoBytes(respPayload.Results); err != nil {
+		return nil, err
+	}
+	for _, nsRWSet := range txRWSet.NsRwSets {
+		for _, kvWrite := range nsRWSet.KvRwSet.Writes {
+			tx.Writes = append(tx.Writes, &connector.Write{
+				Namespace: nsRWSet.NameSpace,
+				Key:       kvWrite.Key,
+				Value:     kvWrite.Value,
+				IsDelete:  kvWrite.IsDelete,
+			})
+		}
+	}
+	return tx, nil
+}
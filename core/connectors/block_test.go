@@ -0,0 +1,65 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package connectors
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	connector "github.com/hyperledger/fabric/core/handlers/connector/api"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
+	"github.com/hyperledger/fabric/core/ledger/util"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBlock(t *testing.T) {
+	builder := rwsetutil.NewRWSetBuilder()
+	builder.AddToWriteSet("mycc", "a", []byte("1"))
+	builder.AddToWriteSet("mycc", "b", nil)
+	simRes, err := builder.GetTxSimulationResults()
+	require.NoError(t, err)
+	pubSimResBytes, err := simRes.GetPubSimulationBytes()
+	require.NoError(t, err)
+
+	block := testutil.ConstructBlockWithTxid(t, 5, []byte("previous"), [][]byte{pubSimResBytes, pubSimResBytes}, []string{"tx1", "tx2"}, false)
+	flags := util.NewTxValidationFlagsSetValue(2, peer.TxValidationCode_VALID)
+	flags.SetFlag(1, peer.TxValidationCode_MVCC_READ_CONFLICT)
+	block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = flags
+
+	b, err := NewBlock("mychannel", block)
+	require.NoError(t, err)
+	assert.Equal(t, "mychannel", b.ChannelID)
+	assert.Equal(t, uint64(5), b.Number)
+	assert.Equal(t, block.Header.Hash(), b.Hash)
+	require.Len(t, b.Transactions, 2)
+	assert.Equal(t, &connector.Transaction{
+		TxID:           "tx1",
+		Number:         0,
+		Type:           "ENDORSER_TRANSACTION",
+		ValidationCode: peer.TxValidationCode_VALID,
+		Writes: []*connector.Write{
+			{Namespace: "mycc", Key: "a", Value: []byte("1")},
+			{Namespace: "mycc", Key: "b", IsDelete: true},
+		},
+	}, b.Transactions[0])
+	assert.Equal(t, "tx2", b.Transactions[1].TxID)
+	assert.Equal(t, peer.TxValidationCode_MVCC_READ_CONFLICT, b.Transactions[1].ValidationCode)
+	assert.Empty(t, b.Transactions[1].Writes, "invalid transactions should not carry writes")
+
+	// an unparsable transaction is passed on only if it was invalidated
+	block.Data.Data[1] = []byte("garbage")
+	b, err = NewBlock("mychannel", block)
+	require.NoError(t, err)
+	assert.Equal(t, &connector.Transaction{Number: 1, ValidationCode: peer.TxValidationCode_MVCC_READ_CONFLICT}, b.Transactions[1])
+
+	flags.SetFlag(1, peer.TxValidationCode_VALID)
+	_, err = NewBlock("mychannel", block)
+	assert.Contains(t, err.Error(), "failed converting transaction [1] of block [5]")
+}
@@ -0,0 +1,240 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package connectors
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	commonledger "github.com/hyperledger/fabric/common/ledger"
+	connector "github.com/hyperledger/fabric/core/handlers/connector/api"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("connectors")
+
+const (
+	defaultRetryInterval    = time.Second
+	defaultMaxRetryInterval = time.Minute
+)
+
+// Ledger is the ledger the blocks of a channel are streamed from
+type Ledger interface {
+	GetBlocksIterator(startBlockNumber uint64) (commonledger.ResultsIterator, error)
+}
+
+// Connector is a sink configured on the peer
+type Connector struct {
+	Name string
+	Sink connector.Sink
+}
+
+// NewConnector creates a sink from the factory and initializes it with the
+// parameters of its configuration
+func NewConnector(name string, factory connector.SinkFactory, parameters map[string]string) (*Connector, error) {
+	sink := factory.New()
+	if sink == nil {
+		return nil, errors.Errorf("factory of connector %s returned a nil sink", name)
+	}
+	if err := sink.Init(connector.Parameters(parameters)); err != nil {
+		return nil, errors.WithMessage(err, "failed initializing connector "+name)
+	}
+	return &Connector{Name: name, Sink: sink}, nil
+}
+
+// Manager streams the blocks committed on the channels of the peer to all
+// the connectors, each from its own checkpoint
+type Manager struct {
+	connectors       []*Connector
+	retryInterval    time.Duration
+	maxRetryInterval time.Duration
+
+	mutex   sync.Mutex
+	stopped bool
+	streams []*stream
+}
+
+// NewManager creates a Manager for the given connectors
+func NewManager(connectors []*Connector) *Manager {
+	return &Manager{
+		connectors:       connectors,
+		retryInterval:    defaultRetryInterval,
+		maxRetryInterval: defaultMaxRetryInterval,
+	}
+}
+
+// Start streams the blocks of the channel to the connectors.  It is meant to
+// be invoked once per channel, when the channel is initialized on the peer.
+func (m *Manager) Start(channelID string, ledger Ledger) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.stopped {
+		return
+	}
+	for _, c := range m.connectors {
+		s := &stream{
+			manager:   m,
+			connector: c,
+			channelID: channelID,
+			ledger:    ledger,
+			stop:      make(chan struct{}),
+			done:      make(chan struct{}),
+		}
+		m.streams = append(m.streams, s)
+		go s.run()
+	}
+}
+
+// Stop stops streaming, waits for the blocks being written to be done and
+// closes the connectors
+func (m *Manager) Stop() {
+	m.mutex.Lock()
+	if m.stopped {
+		m.mutex.Unlock()
+		return
+	}
+	m.stopped = true
+	streams := m.streams
+	m.mutex.Unlock()
+
+	for _, s := range streams {
+		s.close()
+	}
+	for _, s := range streams {
+		<-s.done
+	}
+	for _, c := range m.connectors {
+		if err := c.Sink.Close(); err != nil {
+			logger.Warningf("Failed closing connector %s: %s", c.Name, err)
+		}
+	}
+}
+
+// stream streams the blocks of a channel to a connector
+type stream struct {
+	manager   *Manager
+	connector *Connector
+	channelID string
+	ledger    Ledger
+	stop      chan struct{}
+	done      chan struct{}
+
+	mutex sync.Mutex
+	itr   commonledger.ResultsIterator
+}
+
+func (s *stream) run() {
+	defer close(s.done)
+
+	var next uint64
+	err := s.retry("retrieving the checkpoint", func() error {
+		var err error
+		next, err = s.connector.Sink.Checkpoint(s.channelID)
+		return err
+	})
+	if err != nil {
+		return
+	}
+	itr, err := s.ledger.GetBlocksIterator(next)
+	if err != nil {
+		logger.Errorf("Connector %s on channel [%s]: failed iterating the blocks from block [%d], the connector is stopped: %s", s.connector.Name, s.channelID, next, err)
+		return
+	}
+	if !s.setIterator(itr) {
+		return
+	}
+	logger.Infof("Connector %s on channel [%s]: streaming the blocks from block [%d]", s.connector.Name, s.channelID, next)
+
+	for {
+		res, err := itr.Next()
+		if err != nil {
+			logger.Errorf("Connector %s on channel [%s]: failed retrieving block [%d], the connector is stopped: %s", s.connector.Name, s.channelID, next, err)
+			return
+		}
+		if res == nil {
+			// the iterator is closed
+			return
+		}
+		block, err := NewBlock(s.channelID, res.(*common.Block))
+		if err != nil {
+			logger.Errorf("Connector %s on channel [%s]: the connector is stopped: %s", s.connector.Name, s.channelID, err)
+			return
+		}
+		if err := s.write(block); err != nil {
+			return
+		}
+		next = block.Number + 1
+	}
+}
+
+// write writes the block until it succeeds or the stream is stopped.  When a
+// write fails the checkpoint is checked, as the sink may have stored the
+// block before failing, so the block is not stored twice.
+func (s *stream) write(block *connector.Block) error {
+	err := s.retry("writing block", func() error {
+		err := s.connector.Sink.Write(block)
+		if err == nil {
+			return nil
+		}
+		if next, cpErr := s.connector.Sink.Checkpoint(s.channelID); cpErr == nil && next > block.Number {
+			logger.Debugf("Connector %s on channel [%s]: block [%d] was stored despite the failure: %s", s.connector.Name, s.channelID, block.Number, err)
+			return nil
+		}
+		return err
+	})
+	if err == nil {
+		logger.Debugf("Connector %s on channel [%s]: wrote block [%d]", s.connector.Name, s.channelID, block.Number)
+	}
+	return err
+}
+
+// retry invokes f until it succeeds, backing off between the attempts, and
+// returns an error only if the stream is stopped
+func (s *stream) retry(operation string, f func() error) error {
+	interval := s.manager.retryInterval
+	for {
+		err := f()
+		if err == nil {
+			return nil
+		}
+		logger.Warningf("Connector %s on channel [%s]: failed %s, retrying in %s: %s", s.connector.Name, s.channelID, operation, interval, err)
+		select {
+		case <-s.stop:
+			return errors.Errorf("connector stopped while %s", operation)
+		case <-time.After(interval):
+		}
+		if interval *= 2; interval > s.manager.maxRetryInterval {
+			interval = s.manager.maxRetryInterval
+		}
+	}
+}
+
+// setIterator records the iterator, so that closing the stream unblocks it,
+// and returns false if the stream is already closed
+func (s *stream) setIterator(itr commonledger.ResultsIterator) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	select {
+	case <-s.stop:
+		itr.Close()
+		return false
+	default:
+	}
+	s.itr = itr
+	return true
+}
+
+func (s *stream) close() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	close(s.stop)
+	if s.itr != nil {
+		s.itr.Close()
+	}
+}
@@ -0,0 +1,209 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package connectors
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	commonledger "github.com/hyperledger/fabric/common/ledger"
+	connector "github.com/hyperledger/fabric/core/handlers/connector/api"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeLedger serves the blocks added to it, blocking for the next ones
+type fakeLedger struct {
+	sync.Mutex
+	cond   *sync.Cond
+	blocks []*common.Block
+}
+
+func newFakeLedger(height int) *fakeLedger {
+	l := &fakeLedger{}
+	l.cond = sync.NewCond(&l.Mutex)
+	for i := 0; i < height; i++ {
+		l.addBlock()
+	}
+	return l
+}
+
+func (l *fakeLedger) addBlock() {
+	l.Lock()
+	defer l.Unlock()
+	l.blocks = append(l.blocks, common.NewBlock(uint64(len(l.blocks)), nil))
+	l.cond.Broadcast()
+}
+
+func (l *fakeLedger) GetBlocksIterator(startBlockNumber uint64) (commonledger.ResultsIterator, error) {
+	return &fakeIterator{ledger: l, next: startBlockNumber}, nil
+}
+
+type fakeIterator struct {
+	ledger *fakeLedger
+	next   uint64
+	closed bool
+}
+
+func (itr *fakeIterator) Next() (commonledger.QueryResult, error) {
+	l := itr.ledger
+	l.Lock()
+	defer l.Unlock()
+	for uint64(len(l.blocks)) <= itr.next && !itr.closed {
+		l.cond.Wait()
+	}
+	if itr.closed {
+		return nil, nil
+	}
+	itr.next++
+	return l.blocks[itr.next-1], nil
+}
+
+func (itr *fakeIterator) Close() {
+	itr.ledger.Lock()
+	defer itr.ledger.Unlock()
+	itr.closed = true
+	itr.ledger.cond.Broadcast()
+}
+
+// fakeSink stores the block numbers per channel and fails on demand, either
+// before or after storing a block
+type fakeSink struct {
+	sync.Mutex
+	blocks           map[string][]uint64
+	checkpoints      map[string]uint64
+	failWrites       int
+	failAfterStoring int
+	failCheckpoints  int
+	closed           bool
+}
+
+func newFakeSink() *fakeSink {
+	return &fakeSink{blocks: map[string][]uint64{}, checkpoints: map[string]uint64{}}
+}
+
+func (s *fakeSink) Checkpoint(channelID string) (uint64, error) {
+	s.Lock()
+	defer s.Unlock()
+	if s.failCheckpoints > 0 {
+		s.failCheckpoints--
+		return 0, errors.New("unavailable")
+	}
+	return s.checkpoints[channelID], nil
+}
+
+func (s *fakeSink) Write(block *connector.Block) error {
+	s.Lock()
+	defer s.Unlock()
+	if s.failWrites > 0 {
+		s.failWrites--
+		return errors.New("unavailable")
+	}
+	s.blocks[block.ChannelID] = append(s.blocks[block.ChannelID], block.Number)
+	s.checkpoints[block.ChannelID] = block.Number + 1
+	if s.failAfterStoring > 0 {
+		s.failAfterStoring--
+		return errors.New("timeout")
+	}
+	return nil
+}
+
+func (s *fakeSink) Init(dependencies ...connector.Dependency) error {
+	for _, dep := range dependencies {
+		if params, ok := dep.(connector.Parameters); ok && params["fail"] == "true" {
+			return errors.New("bad parameters")
+		}
+	}
+	return nil
+}
+
+func (s *fakeSink) Close() error {
+	s.Lock()
+	defer s.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *fakeSink) stored(channelID string) []uint64 {
+	s.Lock()
+	defer s.Unlock()
+	return append([]uint64{}, s.blocks[channelID]...)
+}
+
+type fakeSinkFactory struct {
+	sink *fakeSink
+}
+
+func (f *fakeSinkFactory) New() connector.Sink {
+	if f.sink == nil {
+		return nil
+	}
+	return f.sink
+}
+
+func eventually(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestNewConnector(t *testing.T) {
+	sink := newFakeSink()
+	c, err := NewConnector("fake", &fakeSinkFactory{sink: sink}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "fake", c.Name)
+	assert.Equal(t, sink, c.Sink)
+
+	_, err = NewConnector("fake", &fakeSinkFactory{sink: sink}, map[string]string{"fail": "true"})
+	assert.EqualError(t, err, "failed initializing connector fake: bad parameters")
+
+	_, err = NewConnector("fake", &fakeSinkFactory{}, nil)
+	assert.EqualError(t, err, "factory of connector fake returned a nil sink")
+}
+
+func TestManager(t *testing.T) {
+	sink1, sink2 := newFakeSink(), newFakeSink()
+	// sink2 already stored the first blocks of the channel
+	sink2.checkpoints["mychannel"] = 3
+	sink1.failCheckpoints = 1
+	sink1.failWrites = 2
+	sink2.failAfterStoring = 1
+
+	m := NewManager([]*Connector{{Name: "sink1", Sink: sink1}, {Name: "sink2", Sink: sink2}})
+	m.retryInterval = time.Millisecond
+	m.maxRetryInterval = 2 * time.Millisecond
+
+	l := newFakeLedger(5)
+	m.Start("mychannel", l)
+	eventually(t, func() bool { return len(sink1.stored("mychannel")) == 5 })
+	eventually(t, func() bool { return len(sink2.stored("mychannel")) == 2 })
+
+	l.addBlock()
+	eventually(t, func() bool { return len(sink2.stored("mychannel")) == 3 })
+	eventually(t, func() bool { return len(sink1.stored("mychannel")) == 6 })
+	assert.Equal(t, []uint64{0, 1, 2, 3, 4, 5}, sink1.stored("mychannel"))
+	assert.Equal(t, []uint64{3, 4, 5}, sink2.stored("mychannel"), "a block stored despite a failure should not be written again")
+
+	// a stream stuck retrying is stopped along with the ones waiting for blocks
+	sink1.Lock()
+	sink1.failWrites = 1000000
+	sink1.Unlock()
+	l.addBlock()
+	m.Stop()
+	assert.True(t, sink1.closed)
+	assert.True(t, sink2.closed)
+
+	m.Start("otherchannel", l)
+	assert.Empty(t, sink1.stored("otherchannel"), "a stopped manager should not start streaming")
+}
@@ -47,3 +47,12 @@ func (ccid *CCID) GetName() string {
 	}
 	return ccid.Name
 }
+
+// ResourceLimits are the resources a chaincode container may use, a zero
+// value leaves the limit of the VM
+type ResourceLimits struct {
+	// Memory is the memory limit, in bytes
+	Memory int64
+	// CPUShares is the relative CPU weight
+	CPUShares int64
+}
@@ -0,0 +1,295 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package cclogs keeps the recent output of the chaincode containers and
+// streams it on the operations server, so that chaincode developers can
+// follow their chaincode without access to the docker daemon of the peer.
+package cclogs
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("cclogs")
+
+// The streams of the output of a chaincode container
+const (
+	Stdout = "stdout"
+	Stderr = "stderr"
+)
+
+// DefaultHistory is the number of lines kept for each chaincode when no
+// history size is configured
+const DefaultHistory = 1000
+
+// subscriberBuffer is the number of lines a subscriber may lag behind
+// before the lines are dropped for it
+const subscriberBuffer = 256
+
+// Line is a line of output of a chaincode container
+type Line struct {
+	Seq       uint64    `json:"seq"`
+	Time      time.Time `json:"time"`
+	Chaincode string    `json:"chaincode"`
+	Version   string    `json:"version,omitempty"`
+	Container string    `json:"container"`
+	Stream    string    `json:"stream"`
+	Text      string    `json:"text"`
+}
+
+// Filter selects the lines of output.  Its empty fields select all lines.
+type Filter struct {
+	Chaincode string
+	Version   string
+	Stream    string
+	Match     *regexp.Regexp
+}
+
+// matches returns whether the filter selects the line
+func (f Filter) matches(l Line) bool {
+	return (f.Chaincode == "" || f.Chaincode == l.Chaincode) &&
+		(f.Version == "" || f.Version == l.Version) &&
+		(f.Stream == "" || f.Stream == l.Stream) &&
+		(f.Match == nil || f.Match.MatchString(l.Text))
+}
+
+// history is the ring buffer of the latest lines of a chaincode
+type history struct {
+	lines []Line
+	next  int
+	full  bool
+}
+
+func (h *history) add(l Line) {
+	h.lines[h.next] = l
+	h.next = (h.next + 1) % len(h.lines)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// ordered returns the lines from the oldest to the latest
+func (h *history) ordered() []Line {
+	if !h.full {
+		return h.lines[:h.next]
+	}
+	return append(append([]Line{}, h.lines[h.next:]...), h.lines[:h.next]...)
+}
+
+type subscriber struct {
+	filter  Filter
+	lines   chan Line
+	dropped uint64
+}
+
+// Hub keeps the latest lines of output of each chaincode and passes the new
+// ones to its subscribers.  A nil Hub keeps nothing.
+type Hub struct {
+	capacity int
+
+	mutex       sync.Mutex
+	seq         uint64
+	histories   map[string]*history
+	subscribers map[*subscriber]struct{}
+}
+
+// NewHub creates a Hub keeping the given number of lines of each chaincode,
+// or DefaultHistory if it is not positive
+func NewHub(capacity int) *Hub {
+	if capacity <= 0 {
+		capacity = DefaultHistory
+	}
+	return &Hub{
+		capacity:    capacity,
+		histories:   map[string]*history{},
+		subscribers: map[*subscriber]struct{}{},
+	}
+}
+
+// Append records a line written by the container of the chaincode on the
+// stream.  Subscribers lagging behind miss the line.
+func (h *Hub) Append(ccid ccintf.CCID, container, stream, text string) {
+	if h == nil {
+		return
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.seq++
+	l := Line{
+		Seq:       h.seq,
+		Time:      time.Now(),
+		Chaincode: ccid.Name,
+		Version:   ccid.Version,
+		Container: container,
+		Stream:    stream,
+		Text:      text,
+	}
+	hist, ok := h.histories[ccid.Name]
+	if !ok {
+		hist = &history{lines: make([]Line, h.capacity)}
+		h.histories[ccid.Name] = hist
+	}
+	hist.add(l)
+	for s := range h.subscribers {
+		if !s.filter.matches(l) {
+			continue
+		}
+		select {
+		case s.lines <- l:
+		default:
+			s.dropped++
+		}
+	}
+}
+
+// History returns the latest lines selected by the filter, at most tail
+// lines if tail is positive, from the oldest to the latest
+func (h *Hub) History(f Filter, tail int) []Line {
+	if h == nil {
+		return nil
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	return h.history(f, tail)
+}
+
+func (h *Hub) history(f Filter, tail int) []Line {
+	var lines []Line
+	for name, hist := range h.histories {
+		if f.Chaincode != "" && f.Chaincode != name {
+			continue
+		}
+		for _, l := range hist.ordered() {
+			if f.matches(l) {
+				lines = append(lines, l)
+			}
+		}
+	}
+	sort.Slice(lines, func(i, j int) bool { return lines[i].Seq < lines[j].Seq })
+	if tail > 0 && len(lines) > tail {
+		lines = lines[len(lines)-tail:]
+	}
+	return lines
+}
+
+// Subscribe returns the latest lines selected by the filter, at most tail
+// lines if tail is positive, and the channel receiving the lines selected
+// appended next.  The function returned ends the subscription and returns
+// the number of lines dropped because the subscriber lagged behind.
+func (h *Hub) Subscribe(f Filter, tail int) ([]Line, <-chan Line, func() uint64) {
+	s := &subscriber{filter: f, lines: make(chan Line, subscriberBuffer)}
+	if h == nil {
+		return nil, s.lines, func() uint64 { return 0 }
+	}
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.subscribers[s] = struct{}{}
+	return h.history(f, tail), s.lines, func() uint64 {
+		h.mutex.Lock()
+		defer h.mutex.Unlock()
+		delete(h.subscribers, s)
+		return s.dropped
+	}
+}
+
+// ServeHTTP serves the lines of output selected by the query parameters
+//
+//	GET ?chaincode=&version=&stream=&match=&tail=&follow=
+//
+// as a stream of JSON lines.  The lines kept, or the latest tail of them,
+// are served first; if follow is true, the lines appended next are then
+// served until the client goes away.  The match parameter is a regular
+// expression the text of the lines must match.
+func (h *Hub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := r.URL.Query()
+	f, tail, follow, err := parseQuery(query.Get)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	enc := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	if !follow {
+		for _, l := range h.History(f, tail) {
+			enc.Encode(l)
+		}
+		return
+	}
+
+	past, lines, cancel := h.Subscribe(f, tail)
+	defer func() {
+		if dropped := cancel(); dropped > 0 {
+			logger.Warningf("Dropped %d chaincode log lines for slow client %s", dropped, r.RemoteAddr)
+		}
+	}()
+	for _, l := range past {
+		if err := enc.Encode(l); err != nil {
+			return
+		}
+	}
+	for {
+		if flusher != nil {
+			flusher.Flush()
+		}
+		select {
+		case l := <-lines:
+			if err := enc.Encode(l); err != nil {
+				return
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func parseQuery(param func(string) string) (Filter, int, bool, error) {
+	f := Filter{
+		Chaincode: param("chaincode"),
+		Version:   param("version"),
+		Stream:    param("stream"),
+	}
+	if f.Stream != "" && f.Stream != Stdout && f.Stream != Stderr {
+		return Filter{}, 0, false, errors.Errorf("invalid stream %s, must be %s or %s", f.Stream, Stdout, Stderr)
+	}
+	if match := param("match"); match != "" {
+		var err error
+		if f.Match, err = regexp.Compile(match); err != nil {
+			return Filter{}, 0, false, errors.Wrap(err, "invalid match expression")
+		}
+	}
+	tail := 0
+	if s := param("tail"); s != "" {
+		var err error
+		if tail, err = strconv.Atoi(s); err != nil || tail < 0 {
+			return Filter{}, 0, false, errors.Errorf("invalid tail %s", s)
+		}
+	}
+	follow := false
+	if s := param("follow"); s != "" {
+		var err error
+		if follow, err = strconv.ParseBool(s); err != nil {
+			return Filter{}, 0, false, errors.Errorf("invalid follow %s", s)
+		}
+	}
+	return f, tail, follow, nil
+}
@@ -0,0 +1,136 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package cclogs
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	mycc    = ccintf.CCID{Name: "mycc", Version: "1.0"}
+	othercc = ccintf.CCID{Name: "othercc", Version: "2.0"}
+)
+
+func texts(lines []Line) []string {
+	var texts []string
+	for _, l := range lines {
+		texts = append(texts, l.Text)
+	}
+	return texts
+}
+
+func TestHistory(t *testing.T) {
+	hub := NewHub(3)
+	hub.Append(mycc, "dev-peer0-mycc-1.0", Stdout, "a")
+	hub.Append(othercc, "dev-peer0-othercc-2.0", Stdout, "b")
+	hub.Append(mycc, "dev-peer0-mycc-1.0", Stderr, "c")
+	hub.Append(mycc, "dev-peer0-mycc-1.0", Stdout, "d")
+	hub.Append(mycc, "dev-peer0-mycc-1.0", Stdout, "e")
+
+	// 每个链码只保留最近的行，各链码的行按写入顺序合并
+	assert.Equal(t, []string{"b", "c", "d", "e"}, texts(hub.History(Filter{}, 0)))
+	assert.Equal(t, []string{"d", "e"}, texts(hub.History(Filter{}, 2)))
+	assert.Equal(t, []string{"c", "d", "e"}, texts(hub.History(Filter{Chaincode: "mycc"}, 0)))
+	assert.Equal(t, []string{"c"}, texts(hub.History(Filter{Stream: Stderr}, 0)))
+	assert.Equal(t, []string{"b"}, texts(hub.History(Filter{Version: "2.0"}, 0)))
+	assert.Equal(t, []string{"d"}, texts(hub.History(Filter{Match: regexp.MustCompile("^d")}, 0)))
+
+	line := hub.History(Filter{Stream: Stderr}, 0)[0]
+	assert.Equal(t, "mycc", line.Chaincode)
+	assert.Equal(t, "1.0", line.Version)
+	assert.Equal(t, "dev-peer0-mycc-1.0", line.Container)
+}
+
+func TestSubscribe(t *testing.T) {
+	hub := NewHub(0)
+	hub.Append(mycc, "mycc", Stdout, "old")
+	past, lines, cancel := hub.Subscribe(Filter{Chaincode: "mycc"}, 0)
+	assert.Equal(t, []string{"old"}, texts(past))
+
+	hub.Append(othercc, "othercc", Stdout, "other")
+	hub.Append(mycc, "mycc", Stdout, "new")
+	assert.Equal(t, "new", (<-lines).Text)
+
+	// 跟不上的订阅者丢弃新行
+	for i := 0; i < subscriberBuffer+2; i++ {
+		hub.Append(mycc, "mycc", Stdout, "flood")
+	}
+	assert.Equal(t, uint64(2), cancel())
+	hub.Append(mycc, "mycc", Stdout, "gone")
+	assert.Len(t, lines, subscriberBuffer)
+}
+
+func TestNilHub(t *testing.T) {
+	var hub *Hub
+	hub.Append(mycc, "mycc", Stdout, "line")
+	assert.Empty(t, hub.History(Filter{}, 0))
+	past, _, cancel := hub.Subscribe(Filter{}, 0)
+	assert.Empty(t, past)
+	assert.Zero(t, cancel())
+}
+
+func TestServeHTTP(t *testing.T) {
+	hub := NewHub(0)
+	hub.Append(mycc, "mycc", Stdout, "ready")
+	hub.Append(mycc, "mycc", Stderr, "panic: oops")
+
+	resp := httptest.NewRecorder()
+	hub.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/chaincode/logs?chaincode=mycc&stream=stderr", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "application/x-ndjson", resp.Header().Get("Content-Type"))
+	var line Line
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &line))
+	assert.Equal(t, "panic: oops", line.Text)
+
+	for _, query := range []string{"stream=stdin", "match=(", "tail=-1", "follow=maybe"} {
+		resp = httptest.NewRecorder()
+		hub.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/chaincode/logs?"+query, nil))
+		assert.Equal(t, http.StatusBadRequest, resp.Code, query)
+	}
+
+	resp = httptest.NewRecorder()
+	hub.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/chaincode/logs", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
+
+func TestFollow(t *testing.T) {
+	hub := NewHub(0)
+	hub.Append(mycc, "mycc", Stdout, "first")
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "?follow=true&match=" + url.QueryEscape("^[a-z]+$"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	scanner := bufio.NewScanner(resp.Body)
+	next := func() string {
+		require.True(t, scanner.Scan())
+		var line Line
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &line))
+		return line.Text
+	}
+	assert.Equal(t, "first", next())
+
+	// 关注的请求持续接收新写入的行
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		hub.Append(mycc, "mycc", Stdout, "Skipped")
+		hub.Append(mycc, "mycc", Stdout, "second")
+	}()
+	assert.Equal(t, "second", next())
+}
@@ -69,6 +69,26 @@ var _ = Describe("Container", func() {
 						Expect(err).To(MatchError("Boo"))
 					})
 				})
+
+				Context("when resource limits are set", func() {
+					BeforeEach(func() {
+						startReq.Limits = &ccintf.ResourceLimits{Memory: 1 << 28, CPUShares: 512}
+					})
+
+					It("starts the vm with the limits when it enforces them", func() {
+						vm := &limitingVM{VM: fakeVM}
+						err := startReq.Do(ctxt, vm)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(vm.limits).To(Equal(&ccintf.ResourceLimits{Memory: 1 << 28, CPUShares: 512}))
+						Expect(fakeVM.StartCallCount()).To(Equal(0))
+					})
+
+					It("starts the vm without the limits when it does not enforce them", func() {
+						err := startReq.Do(ctxt, fakeVM)
+						Expect(err).NotTo(HaveOccurred())
+						Expect(fakeVM.StartCallCount()).To(Equal(1))
+					})
+				})
 			})
 
 			Describe("GetCCID", func() {
@@ -155,3 +175,14 @@ var _ = Describe("Container", func() {
 		})
 	})
 })
+
+// limitingVM is a VM enforcing resource limits
+type limitingVM struct {
+	*mock.VM
+	limits *ccintf.ResourceLimits
+}
+
+func (vm *limitingVM) StartWithLimits(ctxt context.Context, ccid ccintf.CCID, args []string, env []string, filesToUpload map[string][]byte, builder container.Builder, limits *ccintf.ResourceLimits) error {
+	vm.limits = limits
+	return nil
+}
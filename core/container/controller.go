@@ -33,6 +33,12 @@ type VM interface {
 	Stop(ctxt context.Context, ccid ccintf.CCID, timeout uint, dontkill bool, dontremove bool) error
 }
 
+// ResourceLimiter is implemented by the VMs able to bound the resources
+// used by the chaincode they start
+type ResourceLimiter interface {
+	StartWithLimits(ctxt context.Context, ccid ccintf.CCID, args []string, env []string, filesToUpload map[string][]byte, builder Builder, limits *ccintf.ResourceLimits) error
+}
+
 type refCountedLock struct {
 	refCount int
 	lock     *sync.RWMutex
@@ -117,6 +123,8 @@ type StartContainerReq struct {
 	Args          []string
 	Env           []string
 	FilesToUpload map[string][]byte
+	// Limits are the resources the container may use, if any
+	Limits *ccintf.ResourceLimits
 }
 
 // PlatformBuilder implements the Build interface using
@@ -136,6 +144,12 @@ func (b *PlatformBuilder) Build() (io.Reader, error) {
 }
 
 func (si StartContainerReq) Do(ctxt context.Context, v VM) error {
+	if si.Limits != nil {
+		if rl, ok := v.(ResourceLimiter); ok {
+			return rl.StartWithLimits(ctxt, si.CCID, si.Args, si.Env, si.FilesToUpload, si.Builder, si.Limits)
+		}
+		vmLogger.Warningf("Resource limits of chaincode %s are not enforced by its VM", si.CCID.GetName())
+	}
 	return v.Start(ctxt, si.CCID, si.Args, si.Env, si.FilesToUpload, si.Builder)
 }
 
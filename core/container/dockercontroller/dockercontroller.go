@@ -25,6 +25,7 @@ import (
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/container"
 	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/hyperledger/fabric/core/container/cclogs"
 	cutil "github.com/hyperledger/fabric/core/container/util"
 	"github.com/op/go-logging"
 	"github.com/spf13/viper"
@@ -50,6 +51,8 @@ type DockerVM struct {
 	getClientFnc getClient
 	PeerID       string
 	NetworkID    string
+	//ChaincodeLogs records the output of the chaincode containers, nothing if nil
+	ChaincodeLogs *cclogs.Hub
 }
 
 // dockerClient represents a docker client
@@ -82,8 +85,9 @@ type dockerClient interface {
 
 // Controller implements container.VMProvider
 type Provider struct {
-	PeerID    string
-	NetworkID string
+	PeerID        string
+	NetworkID     string
+	ChaincodeLogs *cclogs.Hub
 }
 
 // NewProvider creates a new instance of Provider
@@ -96,7 +100,9 @@ func NewProvider(peerID, networkID string) *Provider {
 
 // NewVM creates a new DockerVM instance
 func (p *Provider) NewVM() container.VM {
-	return NewDockerVM(p.PeerID, p.NetworkID)
+	vm := NewDockerVM(p.PeerID, p.NetworkID)
+	vm.ChaincodeLogs = p.ChaincodeLogs
+	return vm
 }
 
 // NewDockerVM returns a new DockerVM instance
@@ -173,11 +179,31 @@ func getDockerHostConfig() *docker.HostConfig {
 	return hostConfig
 }
 
+// getLimitedHostConfig returns the host config of the peer with the memory
+// and CPU limits of the chaincode in place of the configured ones
+func getLimitedHostConfig(limits *ccintf.ResourceLimits) *docker.HostConfig {
+	if limits == nil {
+		return getDockerHostConfig()
+	}
+	limited := *getDockerHostConfig()
+	if limits.Memory > 0 {
+		limited.Memory = limits.Memory
+		// docker refuses a swap limit lower than the memory limit
+		if limited.MemorySwap > 0 && limited.MemorySwap < limited.Memory {
+			limited.MemorySwap = limited.Memory
+		}
+	}
+	if limits.CPUShares > 0 {
+		limited.CPUShares = limits.CPUShares
+	}
+	return &limited
+}
+
 func (vm *DockerVM) createContainer(ctxt context.Context, client dockerClient,
 	imageID string, containerID string, args []string,
-	env []string, attachStdout bool) error {
+	env []string, attachStdout bool, hc *docker.HostConfig) error {
 	config := docker.Config{Cmd: args, Image: imageID, Env: env, AttachStdout: attachStdout, AttachStderr: attachStdout}
-	copts := docker.CreateContainerOptions{Name: containerID, Config: &config, HostConfig: getDockerHostConfig()}
+	copts := docker.CreateContainerOptions{Name: containerID, Config: &config, HostConfig: hc}
 	dockerLogger.Debugf("Create container: %s", containerID)
 	_, err := client.CreateContainer(copts)
 	if err != nil {
@@ -215,6 +241,14 @@ func (vm *DockerVM) deployImage(client dockerClient, ccid ccintf.CCID,
 //Start starts a container using a previously created docker image
 func (vm *DockerVM) Start(ctxt context.Context, ccid ccintf.CCID,
 	args []string, env []string, filesToUpload map[string][]byte, builder container.Builder) error {
+	return vm.StartWithLimits(ctxt, ccid, args, env, filesToUpload, builder, nil)
+}
+
+//StartWithLimits starts a container using a previously created docker image,
+//bounding the memory and CPU it may use
+func (vm *DockerVM) StartWithLimits(ctxt context.Context, ccid ccintf.CCID,
+	args []string, env []string, filesToUpload map[string][]byte, builder container.Builder,
+	limits *ccintf.ResourceLimits) error {
 	imageName, err := vm.GetVMNameForDocker(ccid)
 	if err != nil {
 		return err
@@ -229,13 +263,14 @@ func (vm *DockerVM) Start(ctxt context.Context, ccid ccintf.CCID,
 	containerName := vm.GetVMName(ccid)
 
 	attachStdout := viper.GetBool("vm.docker.attachStdout")
+	hc := getLimitedHostConfig(limits)
 
 	//stop,force remove if necessary
 	dockerLogger.Debugf("Cleanup container %s", containerName)
 	vm.stopInternal(ctxt, client, containerName, 0, false, false)
 
 	dockerLogger.Debugf("Start container %s", containerName)
-	err = vm.createContainer(ctxt, client, imageName, containerName, args, env, attachStdout)
+	err = vm.createContainer(ctxt, client, imageName, containerName, args, env, attachStdout, hc)
 	if err != nil {
 		//if image not found try to create image and retry
 		if err == docker.ErrNoSuchImage {
@@ -254,7 +289,7 @@ func (vm *DockerVM) Start(ctxt context.Context, ccid ccintf.CCID,
 				}
 
 				dockerLogger.Debug("start-recreated image successfully")
-				if err1 = vm.createContainer(ctxt, client, imageName, containerName, args, env, attachStdout); err1 != nil {
+				if err1 = vm.createContainer(ctxt, client, imageName, containerName, args, env, attachStdout, hc); err1 != nil {
 					dockerLogger.Errorf("start-could not recreate container post recreate image: %s", err1)
 					return err1
 				}
@@ -268,11 +303,13 @@ func (vm *DockerVM) Start(ctxt context.Context, ccid ccintf.CCID,
 		}
 	}
 
-	if attachStdout {
+	//记录链码输出时即使不打印到节点日志也需要连接容器
+	if attachStdout || vm.ChaincodeLogs != nil {
 		// Launch a few go-threads to manage output streams from the container.
 		// They will be automatically destroyed when the container exits
 		attached := make(chan struct{})
-		r, w := io.Pipe()
+		stdoutR, stdoutW := io.Pipe()
+		stderrR, stderrW := io.Pipe()
 
 		go func() {
 			// AttachToContainer will fire off a message on the "attached" channel once the
@@ -281,8 +318,8 @@ func (vm *DockerVM) Start(ctxt context.Context, ccid ccintf.CCID,
 			// error to a local variable to prevent clobbering the function variable 'err'.
 			err := client.AttachToContainer(docker.AttachToContainerOptions{
 				Container:    containerName,
-				OutputStream: w,
-				ErrorStream:  w,
+				OutputStream: stdoutW,
+				ErrorStream:  stderrW,
 				Logs:         true,
 				Stdout:       true,
 				Stderr:       true,
@@ -290,9 +327,10 @@ func (vm *DockerVM) Start(ctxt context.Context, ccid ccintf.CCID,
 				Success:      attached,
 			})
 
-			// If we get here, the container has terminated.  Send a signal on the pipe
+			// If we get here, the container has terminated.  Send a signal on the pipes
 			// so that downstream may clean up appropriately
-			_ = w.CloseWithError(err)
+			_ = stdoutW.CloseWithError(err)
+			_ = stderrW.CloseWithError(err)
 		}()
 
 		go func() {
@@ -310,31 +348,15 @@ func (vm *DockerVM) Start(ctxt context.Context, ccid ccintf.CCID,
 			// appear to hurt anything.
 			attached <- struct{}{}
 
-			// Establish a buffer for our IO channel so that we may do readline-style
-			// ingestion of the IO, one log entry per line
-			is := bufio.NewReader(r)
-
 			// Acquire a custom logger for our chaincode, inheriting the level from the peer
-			containerLogger := flogging.MustGetLogger(containerName)
-			logging.SetLevel(logging.GetLevel("peer"), containerName)
-
-			for {
-				// Loop forever dumping lines of text into the containerLogger
-				// until the pipe is closed
-				line, err2 := is.ReadString('\n')
-				if err2 != nil {
-					switch err2 {
-					case io.EOF:
-						dockerLogger.Infof("Container %s has closed its IO channel", containerName)
-					default:
-						dockerLogger.Errorf("Error reading container output: %s", err2)
-					}
-
-					return
-				}
-
-				containerLogger.Info(line)
+			var containerLogger *logging.Logger
+			if attachStdout {
+				containerLogger = flogging.MustGetLogger(containerName)
+				logging.SetLevel(logging.GetLevel("peer"), containerName)
 			}
+
+			go vm.streamOutput(ccid, containerName, cclogs.Stderr, stderrR, containerLogger)
+			vm.streamOutput(ccid, containerName, cclogs.Stdout, stdoutR, containerLogger)
 		}()
 	}
 
@@ -380,6 +402,32 @@ func (vm *DockerVM) Start(ctxt context.Context, ccid ccintf.CCID,
 	return nil
 }
 
+// streamOutput reads the output of the container on the stream line by line
+// until the container closes it, dumping each line into the container logger,
+// if any, and into the chaincode logs
+func (vm *DockerVM) streamOutput(ccid ccintf.CCID, containerName, stream string, r io.Reader, containerLogger *logging.Logger) {
+	// Establish a buffer for our IO channel so that we may do readline-style
+	// ingestion of the IO, one log entry per line
+	is := bufio.NewReader(r)
+	for {
+		line, err := is.ReadString('\n')
+		if err != nil {
+			switch err {
+			case io.EOF:
+				dockerLogger.Infof("Container %s has closed its %s", containerName, stream)
+			default:
+				dockerLogger.Errorf("Error reading container %s: %s", stream, err)
+			}
+			return
+		}
+
+		if containerLogger != nil {
+			containerLogger.Info(line)
+		}
+		vm.ChaincodeLogs.Append(ccid, containerName, stream, strings.TrimSuffix(line, "\n"))
+	}
+}
+
 //Stop stops a running chaincode
 func (vm *DockerVM) Stop(ctxt context.Context, ccid ccintf.CCID, timeout uint, dontkill bool, dontremove bool) error {
 	id := vm.GetVMName(ccid)
@@ -436,9 +484,13 @@ func (vm *DockerVM) GetVMName(ccid ccintf.CCID) string {
 // needed to keep image (repository) names unique in a single host, multi-peer
 // environment (such as a development environment). It computes the hash for the
 // supplied image name and then appends it to the lowercase image name to ensure
-// uniqueness.
+// uniqueness. Images built for another architecture than the peer's are named
+// after it, so that they are not mistaken for the images built for the peer.
 func (vm *DockerVM) GetVMNameForDocker(ccid ccintf.CCID) (string, error) {
 	name := vm.preFormatImageName(ccid)
+	if arch := cutil.TargetArch(); arch != cutil.BuildArch() {
+		name = fmt.Sprintf("%s-%s", name, arch)
+	}
 	hash := hex.EncodeToString(util.ComputeSHA256([]byte(name)))
 	saniName := vmRegExp.ReplaceAllString(name, "-")
 	imageName := strings.ToLower(fmt.Sprintf("%s-%s", saniName, hash))
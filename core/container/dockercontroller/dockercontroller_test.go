@@ -16,6 +16,7 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"runtime"
 	"testing"
 	"time"
 
@@ -28,6 +29,7 @@ import (
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/chaincode/platforms"
 	"github.com/hyperledger/fabric/core/container/ccintf"
+	"github.com/hyperledger/fabric/core/container/cclogs"
 	coreutil "github.com/hyperledger/fabric/core/testutil"
 	pb "github.com/hyperledger/fabric/protos/peer"
 )
@@ -81,6 +83,23 @@ func TestGetDockerHostConfig(t *testing.T) {
 	testutil.AssertEquals(t, hostConfig.CPUShares, int64(1024*1024*1024*2))
 }
 
+func TestGetLimitedHostConfig(t *testing.T) {
+	hostConfig = &docker.HostConfig{NetworkMode: "host", Memory: 1 << 30, MemorySwap: 1 << 30, CPUShares: 1024}
+	defer func() { hostConfig = nil }()
+
+	assert.Equal(t, hostConfig, getLimitedHostConfig(nil))
+
+	limited := getLimitedHostConfig(&ccintf.ResourceLimits{Memory: 1 << 31})
+	assert.Equal(t, &docker.HostConfig{NetworkMode: "host", Memory: 1 << 31, MemorySwap: 1 << 31, CPUShares: 1024}, limited)
+
+	limited = getLimitedHostConfig(&ccintf.ResourceLimits{Memory: 1 << 28, CPUShares: 256})
+	assert.Equal(t, &docker.HostConfig{NetworkMode: "host", Memory: 1 << 28, MemorySwap: 1 << 30, CPUShares: 256}, limited)
+
+	// the host config of the peer is left untouched
+	assert.Equal(t, int64(1<<30), hostConfig.Memory)
+	assert.Equal(t, int64(1024), hostConfig.CPUShares)
+}
+
 func Test_Start(t *testing.T) {
 	dvm := DockerVM{}
 	ccid := ccintf.CCID{Name: "simple"}
@@ -164,6 +183,34 @@ func Test_Start(t *testing.T) {
 	testerr(t, err, true)
 }
 
+func TestChaincodeLogs(t *testing.T) {
+	dvm := DockerVM{ChaincodeLogs: cclogs.NewHub(0)}
+	dvm.getClientFnc = getMockClient
+	viper.Set("vm.docker.attachStdout", false)
+	containerStdout, containerStderr = "started\nserving\n", "failed\n"
+	defer func() { containerStdout, containerStderr = "", "" }()
+
+	// 未打印到节点日志时也记录链码容器的输出
+	ccid := ccintf.CCID{Name: "simple", Version: "1.0"}
+	err := dvm.Start(context.Background(), ccid, nil, nil, nil, nil)
+	assert.NoError(t, err)
+	var lines []cclogs.Line
+	for i := 0; i < 100 && len(lines) < 3; i++ {
+		time.Sleep(10 * time.Millisecond)
+		lines = dvm.ChaincodeLogs.History(cclogs.Filter{Chaincode: "simple"}, 0)
+	}
+	require.Len(t, lines, 3)
+	stdout := dvm.ChaincodeLogs.History(cclogs.Filter{Stream: cclogs.Stdout}, 0)
+	require.Len(t, stdout, 2)
+	assert.Equal(t, "started", stdout[0].Text)
+	assert.Equal(t, "serving", stdout[1].Text)
+	assert.Equal(t, "1.0", stdout[0].Version)
+	stderr := dvm.ChaincodeLogs.History(cclogs.Filter{Stream: cclogs.Stderr}, 0)
+	require.Len(t, stderr, 1)
+	assert.Equal(t, "failed", stderr[0].Text)
+	assert.Equal(t, dvm.GetVMName(ccid), stderr[0].Container)
+}
+
 func Test_Stop(t *testing.T) {
 	dvm := DockerVM{}
 	ccid := ccintf.CCID{Name: "simple"}
@@ -234,6 +281,17 @@ func TestGetVMNameForDocker(t *testing.T) {
 		assert.Equal(t, test.expectedOutput, name, "Unexpected output for test case name: %s", test.name)
 	}
 
+	// Images built for another architecture are named after it
+	arch := "s390x"
+	if runtime.GOARCH == arch {
+		arch = "amd64"
+	}
+	viper.Set("chaincode.arch", arch)
+	defer viper.Set("chaincode.arch", "")
+	name, err := (&DockerVM{NetworkID: "dev", PeerID: "peer0"}).GetVMNameForDocker(ccintf.CCID{Name: "mycc", Version: "1.0"})
+	assert.NoError(t, err)
+	expected := "dev-peer0-mycc-1.0-" + arch
+	assert.Equal(t, fmt.Sprintf("%s-%s", expected, hex.EncodeToString(util.ComputeSHA256([]byte(expected)))), name)
 }
 
 func TestGetVMName(t *testing.T) {
@@ -306,6 +364,9 @@ type mockClient struct {
 var getClientErr, createErr, uploadErr, noSuchImgErr, buildErr, removeImgErr,
 	startErr, stopErr, killErr, removeErr bool
 
+// containerStdout and containerStderr are the output of the containers attached to
+var containerStdout, containerStderr string
+
 func (c *mockClient) CreateContainer(options docker.CreateContainerOptions) (*docker.Container, error) {
 	if createErr {
 		return nil, errors.New("Error creating the container")
@@ -333,7 +394,10 @@ func (c *mockClient) UploadToContainer(id string, opts docker.UploadToContainerO
 func (c *mockClient) AttachToContainer(opts docker.AttachToContainerOptions) error {
 	if opts.Success != nil {
 		opts.Success <- struct{}{}
+		<-opts.Success
 	}
+	io.WriteString(opts.OutputStream, containerStdout)
+	io.WriteString(opts.ErrorStream, containerStderr)
 	return nil
 }
 
@@ -13,6 +13,7 @@ import (
 	docker "github.com/fsouza/go-dockerclient"
 	"github.com/hyperledger/fabric/common/metadata"
 	"github.com/hyperledger/fabric/core/config"
+	"github.com/pkg/errors"
 	"github.com/spf13/viper"
 )
 
@@ -31,9 +32,46 @@ func NewDockerClient() (client *docker.Client, err error) {
 	return
 }
 
+// supportedArchs are the architectures the chaincode images may be built for
+var supportedArchs = map[string]bool{
+	"amd64":   true,
+	"arm64":   true,
+	"ppc64le": true,
+	"s390x":   true,
+}
+
+// TargetArch returns the architecture the chaincode images are built for,
+// set by chaincode.arch, or the architecture of the peer if it is unset
+func TargetArch() string {
+	if arch := viper.GetString("chaincode.arch"); arch != "" {
+		return arch
+	}
+	return runtime.GOARCH
+}
+
+// BuildArch returns the architecture the chaincode builder containers run
+// natively on, which is that of the peer, as they run on the docker daemon
+// next to it
+func BuildArch() string {
+	return runtime.GOARCH
+}
+
+// CheckTargetArch returns an error if the chaincode images cannot be built
+// for the target architecture
+func CheckTargetArch() error {
+	if arch := TargetArch(); !supportedArchs[arch] {
+		return errors.Errorf("unsupported chaincode architecture %s", arch)
+	}
+	return nil
+}
+
+//ParseDockerfileTemplate replaces the variables of the template, $(ARCH)
+//standing for the target architecture of the chaincode images and
+//$(BUILD_ARCH) for the architecture of the builder containers
 func ParseDockerfileTemplate(template string) string {
 	r := strings.NewReplacer(
-		"$(ARCH)", runtime.GOARCH,
+		"$(ARCH)", TargetArch(),
+		"$(BUILD_ARCH)", BuildArch(),
 		"$(PROJECT_VERSION)", metadata.Version,
 		"$(BASE_VERSION)", metadata.BaseVersion,
 		"$(DOCKER_NS)", metadata.DockerNamespace,
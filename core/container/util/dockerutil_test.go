@@ -31,6 +31,21 @@ func TestUtil_GetDockerfileFromConfig(t *testing.T) {
 		expected, actual)
 }
 
+func TestUtil_TargetArch(t *testing.T) {
+	defer viper.Set("chaincode.arch", "")
+	assert.Equal(t, runtime.GOARCH, TargetArch())
+	assert.NoError(t, CheckTargetArch())
+
+	// 运行时镜像按目标架构选取，构建镜像按节点架构选取
+	viper.Set("chaincode.arch", "s390x")
+	assert.Equal(t, "FROM foo:s390x", ParseDockerfileTemplate("FROM foo:$(ARCH)"))
+	assert.Equal(t, "FROM foo:"+runtime.GOARCH, ParseDockerfileTemplate("FROM foo:$(BUILD_ARCH)"))
+	assert.NoError(t, CheckTargetArch())
+
+	viper.Set("chaincode.arch", "mips")
+	assert.EqualError(t, CheckTargetArch(), "unsupported chaincode architecture mips")
+}
+
 func TestUtil_GetDockertClient(t *testing.T) {
 	viper.Set("vm.endpoint", "unix:///var/run/docker.sock")
 	_, err := NewDockerClient()
@@ -105,6 +105,18 @@ func (ac *abclient) Broadcast(ctx context.Context, opts ...grpc.CallOption) (ord
 	panic("Not implemented")
 }
 
+func (ac *abclient) BroadcastBatch(ctx context.Context, opts ...grpc.CallOption) (orderer.AtomicBroadcast_BroadcastBatchClient, error) {
+	panic("Not implemented")
+}
+
+func (ac *abclient) SubscribeConfig(ctx context.Context, in *common.Envelope, opts ...grpc.CallOption) (orderer.AtomicBroadcast_SubscribeConfigClient, error) {
+	panic("Not implemented")
+}
+
+func (ac *abclient) Watermark(ctx context.Context, in *common.Envelope, opts ...grpc.CallOption) (*orderer.WatermarkResponse, error) {
+	panic("Not implemented")
+}
+
 func (ac *abclient) Deliver(ctx context.Context, opts ...grpc.CallOption) (orderer.AtomicBroadcast_DeliverClient, error) {
 	if ac.stream != nil {
 		return ac.stream, nil
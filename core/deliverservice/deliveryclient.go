@@ -14,6 +14,7 @@ import (
 	"time"
 
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/ordererendpoints"
 	"github.com/hyperledger/fabric/core/comm"
 	"github.com/hyperledger/fabric/core/deliverservice/blocksprovider"
 	"github.com/hyperledger/fabric/gossip/api"
@@ -95,6 +96,9 @@ type Config struct {
 	Gossip blocksprovider.GossipServiceAdapter
 	// Endpoints specifies the endpoints of the ordering service
 	Endpoints []string
+	// EndpointRanker optionally returns the preference of the endpoints of a channel,
+	// otherwise endpoints are selected at random
+	EndpointRanker func(channelID string) comm.EndpointRanker
 }
 
 // NewDeliverService construction function to create and initialize
@@ -244,7 +248,11 @@ func (d *deliverServiceImpl) newClient(chainID string, ledgerInfoProvider blocks
 		return time.Duration(math.Min(math.Pow(2, attempt)*sleepIncrement, getReConnectBackoffThreshold())), true
 	}
 	//创建connProducer对象
-	connProd := comm.NewConnectionProducer(d.conf.ConnFactory(chainID), d.conf.Endpoints)
+	var ranker comm.EndpointRanker
+	if d.conf.EndpointRanker != nil {
+		ranker = d.conf.EndpointRanker(chainID)
+	}
+	connProd := comm.NewRankedConnectionProducer(d.conf.ConnFactory(chainID), d.conf.Endpoints, ranker)
 	//broadcastSetup作为参数传递给NewBroadcastClient
 	//创建broadcastClient客户端
 	bClient := NewBroadcastClient(connProd, d.conf.ABCFactory, broadcastSetup, backoffPolicy)
@@ -253,6 +261,12 @@ func (d *deliverServiceImpl) newClient(chainID string, ledgerInfoProvider blocks
 	return bClient
 }
 
+// DefaultEndpointRanker prefers the orderers advertised in the channel config for
+// the region configured in peer.deliveryclient.region, in order of their priority
+func DefaultEndpointRanker(channelID string) comm.EndpointRanker {
+	return ordererendpoints.Ranker(channelID, viper.GetString("peer.deliveryclient.region"))
+}
+
 func DefaultConnectionFactory(channelID string) func(endpoint string) (*grpc.ClientConn, error) {
 	return func(endpoint string) (*grpc.ClientConn, error) {
 		dialOpts := []grpc.DialOption{grpc.WithBlock()}
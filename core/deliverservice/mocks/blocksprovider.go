@@ -36,6 +36,15 @@ type MockAtomicBroadcastClient struct {
 func (mabc *MockAtomicBroadcastClient) Broadcast(ctx context.Context, opts ...grpc.CallOption) (orderer.AtomicBroadcast_BroadcastClient, error) {
 	panic("Should not be used")
 }
+func (mabc *MockAtomicBroadcastClient) BroadcastBatch(ctx context.Context, opts ...grpc.CallOption) (orderer.AtomicBroadcast_BroadcastBatchClient, error) {
+	panic("Should not be used")
+}
+func (mabc *MockAtomicBroadcastClient) SubscribeConfig(ctx context.Context, in *common.Envelope, opts ...grpc.CallOption) (orderer.AtomicBroadcast_SubscribeConfigClient, error) {
+	panic("Should not be used")
+}
+func (mabc *MockAtomicBroadcastClient) Watermark(ctx context.Context, in *common.Envelope, opts ...grpc.CallOption) (*orderer.WatermarkResponse, error) {
+	panic("Should not be used")
+}
 func (mabc *MockAtomicBroadcastClient) Deliver(ctx context.Context, opts ...grpc.CallOption) (orderer.AtomicBroadcast_DeliverClient, error) {
 	return mabc.BD, nil
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/orderer"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 )
 
@@ -83,6 +84,18 @@ func (*Orderer) Broadcast(orderer.AtomicBroadcast_BroadcastServer) error {
 	panic("Should not have ben called")
 }
 
+func (*Orderer) BroadcastBatch(orderer.AtomicBroadcast_BroadcastBatchServer) error {
+	panic("Should not have been called")
+}
+
+func (*Orderer) SubscribeConfig(*common.Envelope, orderer.AtomicBroadcast_SubscribeConfigServer) error {
+	panic("Should not have been called")
+}
+
+func (*Orderer) Watermark(context.Context, *common.Envelope) (*orderer.WatermarkResponse, error) {
+	panic("Should not have been called")
+}
+
 func (o *Orderer) SetNextExpectedSeek(seq uint64) {
 	atomic.StoreUint64(&o.nextExpectedSeek, uint64(seq))
 }
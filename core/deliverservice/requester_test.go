@@ -128,6 +128,18 @@ func (*mockOrderer) Broadcast(orderer.AtomicBroadcast_BroadcastServer) error {
 	panic("not implemented")
 }
 
+func (*mockOrderer) BroadcastBatch(orderer.AtomicBroadcast_BroadcastBatchServer) error {
+	panic("not implemented")
+}
+
+func (*mockOrderer) SubscribeConfig(*common.Envelope, orderer.AtomicBroadcast_SubscribeConfigServer) error {
+	panic("not implemented")
+}
+
+func (*mockOrderer) Watermark(context.Context, *common.Envelope) (*orderer.WatermarkResponse, error) {
+	panic("not implemented")
+}
+
 func (o *mockOrderer) Deliver(stream orderer.AtomicBroadcast_DeliverServer) error {
 	env, _ := stream.Recv()
 	inspectTLSBinding := comm.NewBindingInspector(true, func(msg proto.Message) []byte {
@@ -170,6 +170,15 @@ func (e *Endorser) callChaincode(ctxt context.Context, chainID string, version s
 			return nil, nil, errors.Errorf("attempting to deploy a system chaincode %s/%s", cds.ChaincodeSpec.ChaincodeId.Name, chainID)
 		}
 
+		// args[7] holds the limits of the chaincode, if any
+		if len(cis.ChaincodeSpec.Input.Args) > 7 && len(cis.ChaincodeSpec.Input.Args[7]) > 0 {
+			limits, err := ccprovider.GetChaincodeLimits(cis.ChaincodeSpec.Input.Args[7])
+			if err != nil {
+				return nil, nil, err
+			}
+			ctxt = context.WithValue(ctxt, chaincode.ChaincodeLimitsKey, limits)
+		}
+
 		_, _, err = e.s.Execute(ctxt, chainID, cds.ChaincodeSpec.ChaincodeId.Name, cds.ChaincodeSpec.ChaincodeId.Version, txid, false, signedProp, prop, cds)
 		if err != nil {
 			return nil, nil, err
@@ -276,6 +285,10 @@ func (e *Endorser) SimulateProposal(ctx context.Context, chainID string, txid st
 		if err != nil {
 			return nil, nil, nil, nil, err
 		}
+
+		if cd, ok := cdLedger.(*ccprovider.ChaincodeData); ok && cd.Limits != nil {
+			ctx = context.WithValue(ctx, chaincode.ChaincodeLimitsKey, cd.Limits)
+		}
 	} else {
 		version = util.GetSysCCVersion()
 	}
@@ -429,8 +442,9 @@ func (e *Endorser) preProcess(signedProp *pb.SignedProposal) (*validateResult, e
 		//对链中提案唯一性的检查
 		// Here we handle uniqueness check and ACLs for proposals targeting a chain
 		// Notice that ValidateProposalMessage has already verified that TxID is computed properly
-		if _, err = e.s.GetTransactionByID(chainID, txid); err == nil {
-			err = errors.Errorf("duplicate transaction found [%s]. Creator [%x]", txid, shdr.Creator)
+		// the validation code tells the clients retrying a submission whether it took effect
+		if pt, err := e.s.GetTransactionByID(chainID, txid); err == nil {
+			err = errors.Errorf("duplicate transaction found [%s], committed with validation code %s. Creator [%x]", txid, pb.TxValidationCode(pt.GetValidationCode()), shdr.Creator)
 			vr.resp = &pb.ProposalResponse{Response: &pb.Response{Status: 500, Message: err.Error()}}
 			return vr, err
 		}
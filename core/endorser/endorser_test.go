@@ -270,6 +270,7 @@ func TestEndorserDupTXId(t *testing.T) {
 	assert.Error(t, err)
 	assert.EqualValues(t, 500, pResp.Response.Status)
 	assert.Regexp(t, "duplicate transaction found", pResp.Response.Message)
+	assert.Contains(t, pResp.Response.Message, "committed with validation code VALID")
 }
 
 func TestEndorserBadACL(t *testing.T) {
@@ -14,6 +14,7 @@ import (
 	"github.com/hyperledger/fabric/core/aclmgmt"
 	"github.com/hyperledger/fabric/core/aclmgmt/resources"
 	"github.com/hyperledger/fabric/core/chaincode"
+	"github.com/hyperledger/fabric/core/chaincode/shim"
 	"github.com/hyperledger/fabric/core/common/ccprovider"
 	"github.com/hyperledger/fabric/core/handlers/decoration"
 	. "github.com/hyperledger/fabric/core/handlers/endorsement/api/identities"
@@ -23,6 +24,7 @@ import (
 	"github.com/hyperledger/fabric/core/scc"
 	"github.com/hyperledger/fabric/protos/common"
 	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/hyperledger/fabric/protos/utils"
 	"github.com/pkg/errors"
 	"golang.org/x/net/context"
 )
@@ -136,6 +138,9 @@ func (s *SupportImpl) Execute(ctxt context.Context, cid, name, version, txid str
 		decorators := library.InitRegistry(library.Config{}).Lookup(library.Decoration).([]decoration.Decorator)
 		cis.ChaincodeSpec.Input.Decorations = make(map[string][]byte)
 		cis.ChaincodeSpec.Input = decoration.Apply(prop, cis.ChaincodeSpec.Input, decorators...)
+		if beacon := s.latestRandomBeacon(cid); beacon != nil {
+			cis.ChaincodeSpec.Input.Decorations[shim.RandomBeaconDecorationKey] = beacon
+		}
 		cccid.ProposalDecorations = cis.ChaincodeSpec.Input.Decorations
 
 		return s.ChaincodeSupport.Execute(ctxt, cccid, cis)
@@ -144,6 +149,29 @@ func (s *SupportImpl) Execute(ctxt context.Context, cid, name, version, txid str
 	}
 }
 
+// latestRandomBeacon returns the random beacon value of the latest block of the
+// given channel, or nil if there is none
+func (s *SupportImpl) latestRandomBeacon(channelID string) []byte {
+	lgr := s.Peer.GetLedger(channelID)
+	if lgr == nil {
+		return nil
+	}
+	info, err := lgr.GetBlockchainInfo()
+	if err != nil || info.Height == 0 {
+		return nil
+	}
+	block, err := lgr.GetBlockByNumber(info.Height - 1)
+	if err != nil {
+		endorserLogger.Warningf("[%s] Failed retrieving block %d for its random beacon: %s", channelID, info.Height-1, err)
+		return nil
+	}
+	beacon, err := utils.GetRandomBeaconFromBlock(block)
+	if err != nil {
+		return nil
+	}
+	return beacon
+}
+
 // GetChaincodeDefinition returns ccprovider.ChaincodeDefinition for the chaincode with the supplied name
 func (s *SupportImpl) GetChaincodeDefinition(ctx context.Context, chainID string, txid string, signedProp *pb.SignedProposal, prop *pb.Proposal, chaincodeID string, txsim ledger.TxSimulator) (ccprovider.ChaincodeDefinition, error) {
 	ctxt := ctx
@@ -0,0 +1,189 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package filter
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/handlers/auth"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+var logger = flogging.MustGetLogger("auth.filter")
+
+// StatusTooManyRequests is the status of the responses to the proposals of
+// a client exceeding its proposal rate
+const StatusTooManyRequests = 429
+
+// idleSweepInterval is how often the rates of the clients that stopped
+// sending proposals are forgotten
+const idleSweepInterval = time.Minute
+
+// RateLimitConfig configures the limits enforced by the rate limit filter
+type RateLimitConfig struct {
+	// MaxConcurrency is the maximum number of proposals processed at once,
+	// 0 for no limit
+	MaxConcurrency int
+	// BusyBackoff is the backoff hinted to the clients whose proposal is
+	// rejected because MaxConcurrency proposals are being processed
+	BusyBackoff time.Duration
+	// ClientRate is the number of proposals per second each client identity
+	// may send, 0 for no limit
+	ClientRate float64
+	// ClientBurst is the number of proposals a client may send at once
+	// beyond its rate
+	ClientBurst int
+}
+
+// NewRateLimitFilter creates a new Filter that rejects the proposals
+// exceeding the limits of the configuration.  The rejections are proposal
+// responses whose message ends with "retry after <duration>", the backoff
+// after which the proposal is likely to be accepted.
+func NewRateLimitFilter(conf RateLimitConfig) auth.Filter {
+	f := &rateLimitFilter{
+		conf:    conf,
+		clients: map[string]*tokenBucket{},
+		now:     time.Now,
+	}
+	if conf.MaxConcurrency > 0 {
+		f.inFlight = make(chan struct{}, conf.MaxConcurrency)
+	}
+	if conf.ClientBurst < 1 {
+		f.conf.ClientBurst = 1
+	}
+	f.lastSweep = f.now()
+	return f
+}
+
+type rateLimitFilter struct {
+	next     peer.EndorserServer
+	conf     RateLimitConfig
+	inFlight chan struct{}
+	now      func() time.Time
+
+	lock      sync.Mutex
+	clients   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// tokenBucket holds the proposals a client may still send
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Init initializes the Filter with the next EndorserServer
+func (f *rateLimitFilter) Init(next peer.EndorserServer) {
+	f.next = next
+}
+
+// ProcessProposal processes a signed proposal
+func (f *rateLimitFilter) ProcessProposal(ctx context.Context, signedProp *peer.SignedProposal) (*peer.ProposalResponse, error) {
+	if f.conf.ClientRate > 0 {
+		creator, err := proposalCreator(signedProp)
+		if err != nil {
+			return nil, err
+		}
+		if backoff := f.take(string(creator)); backoff > 0 {
+			logger.Debugf("Rejecting proposal from %s: proposal rate exceeded", util.ExtractRemoteAddress(ctx))
+			return rejection(StatusTooManyRequests, "proposal rate of the client exceeded", backoff), nil
+		}
+	}
+
+	if f.inFlight != nil {
+		select {
+		case f.inFlight <- struct{}{}:
+			defer func() { <-f.inFlight }()
+		default:
+			logger.Debugf("Rejecting proposal from %s: %d proposals are being processed", util.ExtractRemoteAddress(ctx), f.conf.MaxConcurrency)
+			return rejection(int32(common.Status_SERVICE_UNAVAILABLE), "too many proposals being processed", f.conf.BusyBackoff), nil
+		}
+	}
+
+	return f.next.ProcessProposal(ctx, signedProp)
+}
+
+// take consumes a token of the client, and if none is left returns the
+// time until the next one
+func (f *rateLimitFilter) take(client string) time.Duration {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	now := f.now()
+	if now.Sub(f.lastSweep) > idleSweepInterval {
+		f.sweep(now)
+	}
+
+	b, exists := f.clients[client]
+	if !exists {
+		b = &tokenBucket{tokens: float64(f.conf.ClientBurst), last: now}
+		f.clients[client] = b
+	}
+	f.refill(b, now)
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / f.conf.ClientRate * float64(time.Second))
+}
+
+func (f *rateLimitFilter) refill(b *tokenBucket, now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * f.conf.ClientRate
+		if b.tokens > float64(f.conf.ClientBurst) {
+			b.tokens = float64(f.conf.ClientBurst)
+		}
+		b.last = now
+	}
+}
+
+// sweep forgets the clients whose bucket is full again, as they would get
+// the same bucket if they came back
+func (f *rateLimitFilter) sweep(now time.Time) {
+	for client, b := range f.clients {
+		f.refill(b, now)
+		if b.tokens >= float64(f.conf.ClientBurst) {
+			delete(f.clients, client)
+		}
+	}
+	f.lastSweep = now
+}
+
+func proposalCreator(signedProp *peer.SignedProposal) ([]byte, error) {
+	prop, err := utils.GetProposal(signedProp.ProposalBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing proposal")
+	}
+
+	hdr, err := utils.GetHeader(prop.Header)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing header")
+	}
+
+	sh, err := utils.GetSignatureHeader(hdr.SignatureHeader)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing signature header")
+	}
+	return sh.Creator, nil
+}
+
+func rejection(status int32, reason string, backoff time.Duration) *peer.ProposalResponse {
+	return &peer.ProposalResponse{
+		Response: &peer.Response{
+			Status:  status,
+			Message: fmt.Sprintf("%s, retry after %s", reason, backoff),
+		},
+	}
+}
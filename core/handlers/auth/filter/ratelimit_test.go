@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package filter
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// blockingEndorserServer processes proposals until released
+type blockingEndorserServer struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (es *blockingEndorserServer) ProcessProposal(context.Context, *peer.SignedProposal) (*peer.ProposalResponse, error) {
+	es.started <- struct{}{}
+	<-es.release
+	return &peer.ProposalResponse{Response: &peer.Response{Status: 200}}, nil
+}
+
+func TestRateLimitFilterClientRate(t *testing.T) {
+	nextEndorser := &mockEndorserServer{}
+	auth := NewRateLimitFilter(RateLimitConfig{ClientRate: 2, ClientBurst: 2})
+	auth.Init(nextEndorser)
+	now := time.Now()
+	auth.(*rateLimitFilter).now = func() time.Time { return now }
+
+	alice := createValidSignedProposal(t, []byte("alice"))
+	bob := createValidSignedProposal(t, []byte("bob"))
+
+	// Scenario I: a client sends its burst, the next proposal is rejected
+	for i := 0; i < 2; i++ {
+		_, err := auth.ProcessProposal(context.Background(), alice)
+		assert.NoError(t, err)
+		assert.True(t, nextEndorser.invoked)
+		nextEndorser.invoked = false
+	}
+	resp, err := auth.ProcessProposal(context.Background(), alice)
+	assert.NoError(t, err)
+	assert.False(t, nextEndorser.invoked)
+	assert.Equal(t, int32(StatusTooManyRequests), resp.Response.Status)
+	assert.Equal(t, "proposal rate of the client exceeded, retry after 500ms", resp.Response.Message)
+
+	// Scenario II: other clients are not affected
+	_, err = auth.ProcessProposal(context.Background(), bob)
+	assert.NoError(t, err)
+	assert.True(t, nextEndorser.invoked)
+	nextEndorser.invoked = false
+
+	// Scenario III: the client is accepted again after the backoff
+	now = now.Add(250 * time.Millisecond)
+	resp, err = auth.ProcessProposal(context.Background(), alice)
+	assert.NoError(t, err)
+	assert.Equal(t, "proposal rate of the client exceeded, retry after 250ms", resp.Response.Message)
+	now = now.Add(250 * time.Millisecond)
+	_, err = auth.ProcessProposal(context.Background(), alice)
+	assert.NoError(t, err)
+	assert.True(t, nextEndorser.invoked)
+
+	// Scenario IV: idle clients are forgotten
+	now = now.Add(idleSweepInterval + time.Second)
+	_, err = auth.ProcessProposal(context.Background(), bob)
+	assert.NoError(t, err)
+	assert.Len(t, auth.(*rateLimitFilter).clients, 1)
+
+	// Scenario V: malformed proposal
+	sp := createSignedProposalWithInvalidHeader(t, []byte("alice"))
+	_, err = auth.ProcessProposal(context.Background(), sp)
+	assert.Contains(t, err.Error(), "failed parsing header")
+}
+
+func TestRateLimitFilterConcurrency(t *testing.T) {
+	nextEndorser := &blockingEndorserServer{started: make(chan struct{}), release: make(chan struct{})}
+	auth := NewRateLimitFilter(RateLimitConfig{MaxConcurrency: 1, BusyBackoff: time.Second})
+	auth.Init(nextEndorser)
+	sp := createValidSignedProposal(t, []byte("alice"))
+
+	done := make(chan *peer.ProposalResponse)
+	go func() {
+		resp, _ := auth.ProcessProposal(context.Background(), sp)
+		done <- resp
+	}()
+	<-nextEndorser.started
+
+	resp, err := auth.ProcessProposal(context.Background(), sp)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(503), resp.Response.Status)
+	assert.Equal(t, "too many proposals being processed, retry after 1s", resp.Response.Message)
+
+	close(nextEndorser.release)
+	assert.Equal(t, int32(200), (<-done).Response.Status)
+
+	// the slot is released once the proposal is processed
+	go func() { <-nextEndorser.started }()
+	resp, err = auth.ProcessProposal(context.Background(), sp)
+	require.NoError(t, err)
+	assert.Equal(t, int32(200), resp.Response.Status)
+}
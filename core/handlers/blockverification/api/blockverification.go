@@ -0,0 +1,57 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package blockverification
+
+import (
+	"github.com/hyperledger/fabric/protos/common"
+)
+
+// Dependency marks a dependency passed to the Init() method
+type Dependency interface {
+}
+
+// PolicyEvaluator evaluates the policies of a channel
+type PolicyEvaluator interface {
+	Dependency
+
+	// Evaluate returns nil if the signatures satisfy the policy with the
+	// given name, such as policies.BlockValidation, of the channel
+	Evaluate(channelID string, policyName string, signatures []*common.SignedData) error
+}
+
+// IdentityDeserializer deserializes the identities of the members of a
+// channel
+type IdentityDeserializer interface {
+	Dependency
+
+	// Identifier deserializes the identity through the MSP of the channel
+	// and returns an identifier of it, such as the hash of its certificate,
+	// which does not depend on the way it was serialized nor on the MSP it
+	// claims to belong to
+	Identifier(channelID string, serializedIdentity []byte) (string, error)
+}
+
+// Parameters are the plugin parameters set in the peer configuration
+type Parameters map[string]string
+
+// Plugin authenticates the blocks the peer receives from the ordering service
+type Plugin interface {
+	// Verify returns nil if the block of the given channel is authentic
+	// according to the signatures over its header, or over the root of its
+	// signature batch for the blocks the orderers sign in batches.  The block
+	// number, channel and data hash have already been checked, and the leaf of
+	// a batch-signed block found in its batch, when Verify is called.
+	Verify(channelID string, block *common.Block, signatures []*common.SignedData) error
+
+	// Init injects dependencies into the instance of the Plugin
+	Init(dependencies ...Dependency) error
+}
+
+// PluginFactory creates a new instance of a Plugin
+type PluginFactory interface {
+	New() Plugin
+}
@@ -0,0 +1,174 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package builtin
+
+import (
+	"strconv"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/policies"
+	. "github.com/hyperledger/fabric/core/handlers/blockverification/api"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("core/handlers/blockverification")
+
+// DefaultBlockVerificationFactory returns a block verification plugin factory
+// which returns plugins that require the signatures of a block to satisfy the
+// BlockValidation policy of its channel
+type DefaultBlockVerificationFactory struct {
+}
+
+// New returns a plugin which checks blocks against the BlockValidation policy
+func (*DefaultBlockVerificationFactory) New() Plugin {
+	return &DefaultBlockVerification{}
+}
+
+// DefaultBlockVerification is a block verification plugin that requires the
+// signatures of a block to satisfy the BlockValidation policy of its channel
+type DefaultBlockVerification struct {
+	PolicyEvaluator
+}
+
+// Verify returns nil if the signatures satisfy the BlockValidation policy
+func (v *DefaultBlockVerification) Verify(channelID string, block *common.Block, signatures []*common.SignedData) error {
+	return v.Evaluate(channelID, policies.BlockValidation, signatures)
+}
+
+// Init injects dependencies into the instance of the Plugin
+func (v *DefaultBlockVerification) Init(dependencies ...Dependency) error {
+	evaluator, err := policyEvaluator(dependencies)
+	if err != nil {
+		return err
+	}
+	v.PolicyEvaluator = evaluator
+	return nil
+}
+
+// ordererWritersPolicy is satisfied by the signature of any orderer
+const ordererWritersPolicy = "/Channel/Orderer/Writers"
+
+// SingleOrdererBlockVerificationFactory returns a block verification plugin
+// factory which returns plugins that accept a block signed by any single
+// orderer, regardless of the number of signatures the BlockValidation policy
+// of the channel requires
+type SingleOrdererBlockVerificationFactory struct {
+}
+
+// New returns a plugin which accepts a block signed by a single orderer
+func (*SingleOrdererBlockVerificationFactory) New() Plugin {
+	return &QuorumBlockVerification{quorum: 1, fixedQuorum: true}
+}
+
+// QuorumBlockVerificationFactory returns a block verification plugin factory
+// which returns plugins that require a block to be signed by a quorum of
+// distinct orderers, set by the quorum parameter, each of whose signature
+// satisfies the orderer writers policy, or the policy set by the policy
+// parameter, on its own.  The orderers are told apart by their identity as
+// deserialized by the MSP of the channel, not by the bytes of their
+// serialized identity, which an orderer could encode differently to sign
+// several times.
+type QuorumBlockVerificationFactory struct {
+}
+
+// New returns a plugin which requires a block to be signed by a quorum of orderers
+func (*QuorumBlockVerificationFactory) New() Plugin {
+	return &QuorumBlockVerification{}
+}
+
+// QuorumBlockVerification is a block verification plugin that requires a block
+// to be signed by a quorum of distinct orderers
+type QuorumBlockVerification struct {
+	PolicyEvaluator
+	IdentityDeserializer
+	policy      string
+	quorum      int
+	fixedQuorum bool
+}
+
+// Verify returns nil if the block carries signatures from at least a quorum
+// of distinct identities, each satisfying the policy on its own
+func (v *QuorumBlockVerification) Verify(channelID string, block *common.Block, signatures []*common.SignedData) error {
+	signers := map[string]struct{}{}
+	for _, signature := range signatures {
+		//按 MSP 反序列化后的身份计数，同一证书的不同编码只算一个签名者
+		signer, err := v.Identifier(channelID, signature.Identity)
+		if err != nil {
+			logger.Debugf("Ignoring signature of block [%d] on channel [%s]: %s", block.Header.Number, channelID, err)
+			continue
+		}
+		if _, counted := signers[signer]; counted {
+			continue
+		}
+		if err := v.Evaluate(channelID, v.policy, []*common.SignedData{signature}); err != nil {
+			logger.Debugf("Ignoring signature of block [%d] on channel [%s]: %s", block.Header.Number, channelID, err)
+			continue
+		}
+		signers[signer] = struct{}{}
+		if len(signers) >= v.quorum {
+			return nil
+		}
+	}
+	return errors.Errorf("block [%d] on channel [%s] is signed by %d valid orderers, %d required", block.Header.Number, channelID, len(signers), v.quorum)
+}
+
+// Init injects dependencies into the instance of the Plugin
+func (v *QuorumBlockVerification) Init(dependencies ...Dependency) error {
+	evaluator, err := policyEvaluator(dependencies)
+	if err != nil {
+		return err
+	}
+	v.PolicyEvaluator = evaluator
+	deserializer, err := identityDeserializer(dependencies)
+	if err != nil {
+		return err
+	}
+	v.IdentityDeserializer = deserializer
+
+	params := parameters(dependencies)
+	v.policy = ordererWritersPolicy
+	if policy, ok := params["policy"]; ok && policy != "" {
+		v.policy = policy
+	}
+	if v.fixedQuorum {
+		return nil
+	}
+	quorum, err := strconv.Atoi(params["quorum"])
+	if err != nil || quorum < 1 {
+		return errors.Errorf("quorum parameter must be a positive integer, got %q", params["quorum"])
+	}
+	v.quorum = quorum
+	return nil
+}
+
+func policyEvaluator(dependencies []Dependency) (PolicyEvaluator, error) {
+	for _, dep := range dependencies {
+		if evaluator, isPolicyEvaluator := dep.(PolicyEvaluator); isPolicyEvaluator {
+			return evaluator, nil
+		}
+	}
+	return nil, errors.New("could not find PolicyEvaluator in dependencies")
+}
+
+func identityDeserializer(dependencies []Dependency) (IdentityDeserializer, error) {
+	for _, dep := range dependencies {
+		if deserializer, isIdentityDeserializer := dep.(IdentityDeserializer); isIdentityDeserializer {
+			return deserializer, nil
+		}
+	}
+	return nil, errors.New("could not find IdentityDeserializer in dependencies")
+}
+
+func parameters(dependencies []Dependency) Parameters {
+	for _, dep := range dependencies {
+		if params, isParameters := dep.(Parameters); isParameters {
+			return params
+		}
+	}
+	return Parameters{}
+}
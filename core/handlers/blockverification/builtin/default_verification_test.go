@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package builtin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/policies"
+	. "github.com/hyperledger/fabric/core/handlers/blockverification/api"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockPolicyEvaluator accepts a set of signatures if all of them are made by
+// one of the valid identities and there are at least required of them
+type mockPolicyEvaluator struct {
+	valid    map[string]bool
+	required int
+	policies []string
+}
+
+func (pe *mockPolicyEvaluator) Evaluate(channelID string, policyName string, signatures []*common.SignedData) error {
+	pe.policies = append(pe.policies, policyName)
+	if len(signatures) < pe.required {
+		return errors.Errorf("%d signatures required", pe.required)
+	}
+	for _, signature := range signatures {
+		if !pe.valid[string(signature.Identity)] {
+			return errors.Errorf("invalid identity %s", signature.Identity)
+		}
+	}
+	return nil
+}
+
+// mockIdentityDeserializer identifies the identities by their name, the
+// identities "<name>#<encoding>" being other encodings of "<name>"
+type mockIdentityDeserializer struct{}
+
+func (mockIdentityDeserializer) Identifier(channelID string, serializedIdentity []byte) (string, error) {
+	if len(serializedIdentity) == 0 {
+		return "", errors.New("empty identity")
+	}
+	return strings.Split(string(serializedIdentity), "#")[0], nil
+}
+
+func signedBy(identities ...string) []*common.SignedData {
+	var signatures []*common.SignedData
+	for _, identity := range identities {
+		signatures = append(signatures, &common.SignedData{Identity: []byte(identity)})
+	}
+	return signatures
+}
+
+var block = &common.Block{Header: &common.BlockHeader{Number: 5}}
+
+func TestDefaultBlockVerification(t *testing.T) {
+	plugin := (&DefaultBlockVerificationFactory{}).New()
+	assert.EqualError(t, plugin.Init(), "could not find PolicyEvaluator in dependencies")
+
+	evaluator := &mockPolicyEvaluator{valid: map[string]bool{"o1": true, "o2": true}, required: 2}
+	assert.NoError(t, plugin.Init(Parameters{}, evaluator))
+	assert.NoError(t, plugin.Verify("foo", block, signedBy("o1", "o2")))
+	assert.Error(t, plugin.Verify("foo", block, signedBy("o1")))
+	assert.Equal(t, []string{policies.BlockValidation, policies.BlockValidation}, evaluator.policies)
+}
+
+func TestSingleOrdererBlockVerification(t *testing.T) {
+	plugin := (&SingleOrdererBlockVerificationFactory{}).New()
+	evaluator := &mockPolicyEvaluator{valid: map[string]bool{"o1": true, "o2": true}, required: 1}
+	assert.NoError(t, plugin.Init(evaluator, mockIdentityDeserializer{}))
+
+	assert.NoError(t, plugin.Verify("foo", block, signedBy("o1")))
+	assert.NoError(t, plugin.Verify("foo", block, signedBy("intruder", "o2")))
+	assert.EqualError(t, plugin.Verify("foo", block, signedBy("intruder")), "block [5] on channel [foo] is signed by 0 valid orderers, 1 required")
+	assert.Equal(t, ordererWritersPolicy, evaluator.policies[0])
+}
+
+func TestQuorumBlockVerification(t *testing.T) {
+	plugin := (&QuorumBlockVerificationFactory{}).New()
+	evaluator := &mockPolicyEvaluator{valid: map[string]bool{"o1": true, "o1#reencoded": true, "o2": true, "o3": true}, required: 1}
+	assert.EqualError(t, plugin.Init(evaluator, Parameters{"quorum": "2"}), "could not find IdentityDeserializer in dependencies")
+	assert.EqualError(t, plugin.Init(evaluator, mockIdentityDeserializer{}), `quorum parameter must be a positive integer, got ""`)
+	assert.EqualError(t, plugin.Init(evaluator, mockIdentityDeserializer{}, Parameters{"quorum": "0"}), `quorum parameter must be a positive integer, got "0"`)
+
+	assert.NoError(t, plugin.Init(evaluator, mockIdentityDeserializer{}, Parameters{"quorum": "2", "policy": "/Channel/Orderer/Consenters"}))
+	assert.NoError(t, plugin.Verify("foo", block, signedBy("o1", "intruder", "o3")))
+	assert.EqualError(t, plugin.Verify("foo", block, signedBy("o1", "o1", "intruder")), "block [5] on channel [foo] is signed by 1 valid orderers, 2 required")
+	assert.EqualError(t, plugin.Verify("foo", block, signedBy("o1", "", "o1#reencoded")), "block [5] on channel [foo] is signed by 1 valid orderers, 2 required",
+		"an orderer encoding its identity twice is counted once")
+	assert.Contains(t, evaluator.policies, "/Channel/Orderer/Consenters")
+	assert.NotContains(t, evaluator.policies, ordererWritersPolicy)
+}
@@ -0,0 +1,90 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package builtin
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	. "github.com/hyperledger/fabric/core/handlers/blockverification/api"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+const defaultNotaryTimeout = 5 * time.Second
+
+// NotaryBlockVerificationFactory returns a block verification plugin factory
+// which returns plugins that delegate the authentication of blocks to an
+// external notary service, set by the url parameter
+type NotaryBlockVerificationFactory struct {
+}
+
+// New returns a plugin which delegates block authentication to a notary
+func (*NotaryBlockVerificationFactory) New() Plugin {
+	return &NotaryBlockVerification{}
+}
+
+// NotaryRequest is the request posted to the notary service as JSON.  The
+// notary replies with 200 OK if it vouches for the block, and any other
+// status with the reason in the body otherwise.
+type NotaryRequest struct {
+	ChannelID  string               `json:"channel_id"`
+	Number     uint64               `json:"number"`
+	Header     []byte               `json:"header"`
+	Signatures []*common.SignedData `json:"signatures"`
+}
+
+// NotaryBlockVerification is a block verification plugin that delegates the
+// authentication of blocks to an external notary service
+type NotaryBlockVerification struct {
+	url    string
+	client *http.Client
+}
+
+// Verify returns nil if the notary vouches for the block
+func (v *NotaryBlockVerification) Verify(channelID string, block *common.Block, signatures []*common.SignedData) error {
+	request, err := json.Marshal(&NotaryRequest{
+		ChannelID:  channelID,
+		Number:     block.Header.Number,
+		Header:     block.Header.Bytes(),
+		Signatures: signatures,
+	})
+	if err != nil {
+		return errors.Wrap(err, "failed marshaling notary request")
+	}
+	resp, err := v.client.Post(v.url, "application/json", bytes.NewReader(request))
+	if err != nil {
+		return errors.Wrapf(err, "failed reaching notary for block [%d] on channel [%s]", block.Header.Number, channelID)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		reason, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("notary rejected block [%d] on channel [%s] with status %d: %s", block.Header.Number, channelID, resp.StatusCode, bytes.TrimSpace(reason))
+	}
+	return nil
+}
+
+// Init injects dependencies into the instance of the Plugin
+func (v *NotaryBlockVerification) Init(dependencies ...Dependency) error {
+	params := parameters(dependencies)
+	v.url = params["url"]
+	if v.url == "" {
+		return errors.New("url parameter of the notary is not set")
+	}
+	timeout := defaultNotaryTimeout
+	if t, ok := params["timeout"]; ok {
+		var err error
+		if timeout, err = time.ParseDuration(t); err != nil {
+			return errors.Wrapf(err, "invalid timeout parameter %q", t)
+		}
+	}
+	v.client = &http.Client{Timeout: timeout}
+	return nil
+}
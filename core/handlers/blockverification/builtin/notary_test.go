@@ -0,0 +1,47 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package builtin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/hyperledger/fabric/core/handlers/blockverification/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotaryBlockVerification(t *testing.T) {
+	var requests []*NotaryRequest
+	notary := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		request := &NotaryRequest{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(request))
+		requests = append(requests, request)
+		if request.ChannelID != "foo" {
+			http.Error(w, "unknown channel", http.StatusForbidden)
+		}
+	}))
+	defer notary.Close()
+
+	plugin := (&NotaryBlockVerificationFactory{}).New()
+	assert.EqualError(t, plugin.Init(), "url parameter of the notary is not set")
+	assert.Error(t, plugin.Init(Parameters{"url": notary.URL, "timeout": "soon"}))
+	require.NoError(t, plugin.Init(Parameters{"url": notary.URL, "timeout": "1s"}))
+
+	assert.NoError(t, plugin.Verify("foo", block, signedBy("o1")))
+	require.Len(t, requests, 1)
+	assert.Equal(t, uint64(5), requests[0].Number)
+	assert.Equal(t, block.Header.Bytes(), requests[0].Header)
+	assert.Equal(t, []byte("o1"), requests[0].Signatures[0].Identity)
+
+	assert.EqualError(t, plugin.Verify("bar", block, signedBy("o1")), "notary rejected block [5] on channel [bar] with status 403: unknown channel")
+
+	notary.Close()
+	assert.Error(t, plugin.Verify("foo", block, signedBy("o1")))
+}
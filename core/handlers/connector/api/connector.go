@@ -0,0 +1,72 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package connector
+
+import (
+	"github.com/hyperledger/fabric/protos/peer"
+)
+
+// Write is a write of a transaction to the world state
+type Write struct {
+	Namespace string
+	Key       string
+	Value     []byte
+	IsDelete  bool
+}
+
+// Transaction is a transaction of a block along with its validation result.
+// Only the valid endorser transactions carry writes
+type Transaction struct {
+	TxID           string
+	Number         uint64
+	Type           string
+	ValidationCode peer.TxValidationCode
+	Writes         []*Write
+}
+
+// Block is a block committed by the peer, as streamed to the sinks
+type Block struct {
+	ChannelID    string
+	Number       uint64
+	Hash         []byte
+	Transactions []*Transaction
+}
+
+// Dependency marks a dependency passed to the Init() method
+type Dependency interface {
+}
+
+// Parameters are the sink parameters set in the peer configuration
+type Parameters map[string]string
+
+// Sink stores the blocks committed by the peer into an external database.
+//
+// The blocks of a channel are written to a sink one at a time and in order,
+// starting from the checkpoint of the sink.  For the blocks to be stored
+// exactly once, a sink must store a block and advance its checkpoint past
+// it atomically, or store it idempotently; a block whose write failed is
+// written again unless the checkpoint went past it.
+type Sink interface {
+	// Checkpoint returns the number of the next block the sink expects for
+	// the channel, which is 0 if it never stored a block of the channel
+	Checkpoint(channelID string) (uint64, error)
+
+	// Write stores the block and advances the checkpoint of its channel to
+	// the number of the block plus one
+	Write(block *Block) error
+
+	// Init injects dependencies into the instance of the Sink
+	Init(dependencies ...Dependency) error
+
+	// Close releases the resources held by the Sink
+	Close() error
+}
+
+// SinkFactory creates a new instance of a Sink
+type SinkFactory interface {
+	New() Sink
+}
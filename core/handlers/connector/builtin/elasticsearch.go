@@ -0,0 +1,271 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package builtin
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/hyperledger/fabric/core/handlers/connector/api"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultElasticsearchIndex   = "fabric"
+	defaultElasticsearchTimeout = 30 * time.Second
+)
+
+// ElasticsearchSinkFactory returns a sink factory which returns sinks that
+// index the blocks into Elasticsearch, at the url and in the index set by the
+// parameters
+type ElasticsearchSinkFactory struct {
+}
+
+// New returns a sink which indexes the blocks into Elasticsearch
+func (*ElasticsearchSinkFactory) New() Sink {
+	return &ElasticsearchSink{}
+}
+
+// ElasticsearchSink indexes a document per transaction and a document per
+// key of the world state, the latter being deleted along with the key.
+//
+// The bulk API of Elasticsearch is not atomic, so every document, including
+// the checkpoint which is indexed last, has an id derived from its content:
+// writing a block again after a partial failure yields the same documents.
+type ElasticsearchSink struct {
+	url    string
+	index  string
+	client *http.Client
+}
+
+// TransactionDocument is the document indexed for a transaction
+type TransactionDocument struct {
+	DocType        string `json:"doc_type"`
+	ChannelID      string `json:"channel_id"`
+	BlockNumber    uint64 `json:"block_number"`
+	TxNumber       uint64 `json:"tx_number"`
+	TxID           string `json:"tx_id"`
+	TxType         string `json:"tx_type"`
+	ValidationCode string `json:"validation_code"`
+}
+
+// StateDocument is the document indexed for a key of the world state
+type StateDocument struct {
+	DocType     string `json:"doc_type"`
+	ChannelID   string `json:"channel_id"`
+	Namespace   string `json:"namespace"`
+	Key         string `json:"key"`
+	Value       []byte `json:"value"`
+	BlockNumber uint64 `json:"block_number"`
+	TxNumber    uint64 `json:"tx_number"`
+}
+
+// CheckpointDocument is the document holding the checkpoint of a channel
+type CheckpointDocument struct {
+	DocType   string `json:"doc_type"`
+	ChannelID string `json:"channel_id"`
+	NextBlock uint64 `json:"next_block"`
+}
+
+type bulkAction struct {
+	Index  *bulkTarget `json:"index,omitempty"`
+	Delete *bulkTarget `json:"delete,omitempty"`
+}
+
+type bulkTarget struct {
+	Index string `json:"_index"`
+	ID    string `json:"_id"`
+}
+
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		ID     string          `json:"_id"`
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// Checkpoint returns the next block expected for the channel
+func (s *ElasticsearchSink) Checkpoint(channelID string) (uint64, error) {
+	resp, err := s.client.Get(s.docURL(checkpointDocID(channelID)))
+	if err != nil {
+		return 0, errors.Wrap(err, "failed reaching Elasticsearch")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return 0, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return 0, errors.Errorf("failed retrieving checkpoint of channel [%s] with status %d: %s", channelID, resp.StatusCode, bytes.TrimSpace(body))
+	}
+	doc := &struct {
+		Source CheckpointDocument `json:"_source"`
+	}{}
+	if err := json.NewDecoder(resp.Body).Decode(doc); err != nil {
+		return 0, errors.Wrapf(err, "failed decoding checkpoint of channel [%s]", channelID)
+	}
+	return doc.Source.NextBlock, nil
+}
+
+// Write indexes the documents of the block and then its checkpoint
+func (s *ElasticsearchSink) Write(block *Block) error {
+	buf := &bytes.Buffer{}
+	enc := json.NewEncoder(buf)
+	add := func(action *bulkAction, doc interface{}) error {
+		if err := enc.Encode(action); err != nil {
+			return err
+		}
+		if doc == nil {
+			return nil
+		}
+		return enc.Encode(doc)
+	}
+
+	for _, tx := range block.Transactions {
+		err := add(&bulkAction{Index: s.target(txDocID(block.ChannelID, block.Number, tx.Number))}, &TransactionDocument{
+			DocType:        "transaction",
+			ChannelID:      block.ChannelID,
+			BlockNumber:    block.Number,
+			TxNumber:       tx.Number,
+			TxID:           tx.TxID,
+			TxType:         tx.Type,
+			ValidationCode: tx.ValidationCode.String(),
+		})
+		if err != nil {
+			return err
+		}
+		for _, w := range tx.Writes {
+			target := s.target(stateDocID(block.ChannelID, w.Namespace, w.Key))
+			if w.IsDelete {
+				err = add(&bulkAction{Delete: target}, nil)
+			} else {
+				err = add(&bulkAction{Index: target}, &StateDocument{
+					DocType:     "state",
+					ChannelID:   block.ChannelID,
+					Namespace:   w.Namespace,
+					Key:         w.Key,
+					Value:       w.Value,
+					BlockNumber: block.Number,
+					TxNumber:    tx.Number,
+				})
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if err := s.bulk(buf); err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("failed indexing block [%d] of channel [%s]", block.Number, block.ChannelID))
+	}
+
+	// The checkpoint goes in a request of its own, so it is only advanced once
+	// all the documents of the block are indexed
+	buf.Reset()
+	err := add(&bulkAction{Index: s.target(checkpointDocID(block.ChannelID))}, &CheckpointDocument{
+		DocType:   "checkpoint",
+		ChannelID: block.ChannelID,
+		NextBlock: block.Number + 1,
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.bulk(buf); err != nil {
+		return errors.WithMessage(err, fmt.Sprintf("failed indexing checkpoint of block [%d] of channel [%s]", block.Number, block.ChannelID))
+	}
+	return nil
+}
+
+// Init injects dependencies into the instance of the Sink
+func (s *ElasticsearchSink) Init(dependencies ...Dependency) error {
+	params := parameters(dependencies)
+	s.url = strings.TrimSuffix(params["url"], "/")
+	if s.url == "" {
+		return errors.New("url parameter of Elasticsearch is not set")
+	}
+	s.index = params["index"]
+	if s.index == "" {
+		s.index = defaultElasticsearchIndex
+	}
+	timeout, err := durationParameter(params, "timeout", defaultElasticsearchTimeout)
+	if err != nil {
+		return err
+	}
+	s.client = &http.Client{Timeout: timeout}
+	return nil
+}
+
+// Close releases the resources held by the Sink
+func (s *ElasticsearchSink) Close() error {
+	return nil
+}
+
+func (s *ElasticsearchSink) bulk(body *bytes.Buffer) error {
+	if body.Len() == 0 {
+		return nil
+	}
+	resp, err := s.client.Post(s.url+"/_bulk", "application/x-ndjson", body)
+	if err != nil {
+		return errors.Wrap(err, "failed reaching Elasticsearch")
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		reason, _ := ioutil.ReadAll(resp.Body)
+		return errors.Errorf("bulk request failed with status %d: %s", resp.StatusCode, bytes.TrimSpace(reason))
+	}
+	result := &bulkResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+		return errors.Wrap(err, "failed decoding bulk response")
+	}
+	if !result.Errors {
+		return nil
+	}
+	for _, item := range result.Items {
+		for op, res := range item {
+			// Deleting a key which was never indexed is not a failure
+			if op == "delete" && res.Status == http.StatusNotFound {
+				continue
+			}
+			if res.Status >= 300 {
+				return errors.Errorf("%s of document %s failed with status %d: %s", op, res.ID, res.Status, res.Error)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *ElasticsearchSink) target(id string) *bulkTarget {
+	return &bulkTarget{Index: s.index, ID: id}
+}
+
+func (s *ElasticsearchSink) docURL(id string) string {
+	return fmt.Sprintf("%s/%s/_doc/%s", s.url, url.PathEscape(s.index), url.PathEscape(id))
+}
+
+func checkpointDocID(channelID string) string {
+	return "checkpoint-" + channelID
+}
+
+func txDocID(channelID string, blockNum, txNum uint64) string {
+	return fmt.Sprintf("tx-%s-%d-%d", channelID, blockNum, txNum)
+}
+
+// stateDocID hashes the key, as keys are arbitrary bytes and can be longer
+// than what a document id allows
+func stateDocID(channelID, namespace, key string) string {
+	h := sha256.Sum256([]byte(namespace + "\x00" + key))
+	return fmt.Sprintf("state-%s-%s", channelID, hex.EncodeToString(h[:]))
+}
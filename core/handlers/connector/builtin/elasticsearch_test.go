@@ -0,0 +1,149 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package builtin
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/hyperledger/fabric/core/handlers/connector/api"
+	"github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// elasticsearch is an in memory Elasticsearch serving the document and bulk APIs
+type elasticsearch struct {
+	sync.Mutex
+	docs      map[string]json.RawMessage
+	failBulks int
+}
+
+func (es *elasticsearch) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	es.Lock()
+	defer es.Unlock()
+	if r.Method == http.MethodGet {
+		doc, ok := es.docs[strings.TrimPrefix(r.URL.Path, "/")]
+		if !ok {
+			http.Error(w, `{"found":false}`, http.StatusNotFound)
+			return
+		}
+		fmt.Fprintf(w, `{"found":true,"_source":%s}`, doc)
+		return
+	}
+
+	if es.failBulks > 0 {
+		es.failBulks--
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	scanner := bufio.NewScanner(r.Body)
+	var items []string
+	failed := false
+	for scanner.Scan() {
+		action := &bulkAction{}
+		if err := json.Unmarshal(scanner.Bytes(), action); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if action.Delete != nil {
+			id := action.Delete.Index + "/_doc/" + action.Delete.ID
+			status := http.StatusOK
+			if _, ok := es.docs[id]; !ok {
+				status = http.StatusNotFound
+				failed = true
+			}
+			delete(es.docs, id)
+			items = append(items, fmt.Sprintf(`{"delete":{"_id":%q,"status":%d}}`, action.Delete.ID, status))
+			continue
+		}
+		scanner.Scan()
+		es.docs[action.Index.Index+"/_doc/"+action.Index.ID] = append(json.RawMessage{}, scanner.Bytes()...)
+		items = append(items, fmt.Sprintf(`{"index":{"_id":%q,"status":201}}`, action.Index.ID))
+	}
+	fmt.Fprintf(w, `{"errors":%t,"items":[%s]}`, failed, strings.Join(items, ","))
+}
+
+func (es *elasticsearch) doc(t *testing.T, id string, v interface{}) bool {
+	es.Lock()
+	defer es.Unlock()
+	doc, ok := es.docs["blocks/_doc/"+id]
+	if ok {
+		require.NoError(t, json.Unmarshal(doc, v))
+	}
+	return ok
+}
+
+func testBlock(number uint64, writes ...*Write) *Block {
+	return &Block{
+		ChannelID: "mychannel",
+		Number:    number,
+		Transactions: []*Transaction{
+			{TxID: "tx1", Number: 0, Type: "ENDORSER_TRANSACTION", ValidationCode: peer.TxValidationCode_VALID, Writes: writes},
+			{TxID: "tx2", Number: 1, Type: "ENDORSER_TRANSACTION", ValidationCode: peer.TxValidationCode_MVCC_READ_CONFLICT},
+		},
+	}
+}
+
+func TestElasticsearchSink(t *testing.T) {
+	es := &elasticsearch{docs: map[string]json.RawMessage{}}
+	server := httptest.NewServer(es)
+	defer server.Close()
+
+	sink := (&ElasticsearchSinkFactory{}).New()
+	assert.EqualError(t, sink.Init(), "url parameter of Elasticsearch is not set")
+	assert.Error(t, sink.Init(Parameters{"url": server.URL, "timeout": "soon"}))
+	require.NoError(t, sink.Init(Parameters{"url": server.URL + "/", "index": "blocks"}))
+	defer sink.Close()
+
+	next, err := sink.Checkpoint("mychannel")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), next)
+
+	require.NoError(t, sink.Write(testBlock(0,
+		&Write{Namespace: "mycc", Key: "a", Value: []byte("1")},
+		&Write{Namespace: "mycc", Key: "b", Value: []byte("2")},
+		&Write{Namespace: "mycc", Key: "c", IsDelete: true},
+	)))
+	next, err = sink.Checkpoint("mychannel")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), next)
+
+	tx := &TransactionDocument{}
+	require.True(t, es.doc(t, txDocID("mychannel", 0, 1), tx))
+	assert.Equal(t, "tx2", tx.TxID)
+	assert.Equal(t, "MVCC_READ_CONFLICT", tx.ValidationCode)
+	state := &StateDocument{}
+	require.True(t, es.doc(t, stateDocID("mychannel", "mycc", "a"), state))
+	assert.Equal(t, []byte("1"), state.Value)
+
+	// rewriting a block after a failure yields the same documents
+	es.failBulks = 1
+	block := testBlock(1, &Write{Namespace: "mycc", Key: "a", IsDelete: true})
+	assert.Contains(t, sink.Write(block).Error(), "failed indexing block [1] of channel [mychannel]: bulk request failed with status 503")
+	require.NoError(t, sink.Write(block))
+	require.NoError(t, sink.Write(block))
+	assert.False(t, es.doc(t, stateDocID("mychannel", "mycc", "a"), state))
+	assert.True(t, es.doc(t, stateDocID("mychannel", "mycc", "b"), state))
+	next, err = sink.Checkpoint("mychannel")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), next)
+
+	next, err = sink.Checkpoint("otherchannel")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), next)
+
+	server.Close()
+	_, err = sink.Checkpoint("mychannel")
+	assert.Contains(t, err.Error(), "failed reaching Elasticsearch")
+}
@@ -0,0 +1,163 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package builtin
+
+import (
+	"database/sql"
+	"fmt"
+
+	. "github.com/hyperledger/fabric/core/handlers/connector/api"
+	_ "github.com/lib/pq" // registers the postgres driver
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultPostgresDriver      = "postgres"
+	defaultPostgresTablePrefix = "fabric_"
+)
+
+// PostgresSinkFactory returns a sink factory which returns sinks that store
+// the blocks into PostgreSQL, reached with the database/sql driver and the
+// data source set by the parameters.  The peer is built with the postgres
+// driver of lib/pq, other drivers must be registered by a plugin or by a
+// build of the peer importing them.
+type PostgresSinkFactory struct {
+}
+
+// New returns a sink which stores the blocks into PostgreSQL
+func (*PostgresSinkFactory) New() Sink {
+	return &PostgresSink{}
+}
+
+// PostgresSink stores a row per transaction and a row per key of the world
+// state, the latter being deleted along with the key.  The rows of a block
+// and the checkpoint of its channel are written in a single SQL transaction.
+type PostgresSink struct {
+	db     *sql.DB
+	tables struct {
+		checkpoints  string
+		transactions string
+		state        string
+	}
+}
+
+// Checkpoint returns the next block expected for the channel
+func (s *PostgresSink) Checkpoint(channelID string) (uint64, error) {
+	var next uint64
+	err := s.db.QueryRow(fmt.Sprintf("SELECT next_block FROM %s WHERE channel = $1", s.tables.checkpoints), channelID).Scan(&next)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed retrieving checkpoint of channel [%s]", channelID)
+	}
+	return next, nil
+}
+
+// Write stores the rows of the block and advances the checkpoint of its
+// channel in a single SQL transaction
+func (s *PostgresSink) Write(block *Block) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "failed starting SQL transaction")
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	// The checkpoint row is locked, so that two peers sharing a database
+	// cannot both write the block
+	var next uint64
+	err = tx.QueryRow(fmt.Sprintf("SELECT next_block FROM %s WHERE channel = $1 FOR UPDATE", s.tables.checkpoints), block.ChannelID).Scan(&next)
+	if err != nil && err != sql.ErrNoRows {
+		return errors.Wrapf(err, "failed retrieving checkpoint of channel [%s]", block.ChannelID)
+	}
+	if next != block.Number {
+		return errors.Errorf("checkpoint of channel [%s] is at block [%d], cannot write block [%d]", block.ChannelID, next, block.Number)
+	}
+
+	insertTx := fmt.Sprintf("INSERT INTO %s (channel, block_number, tx_number, tx_id, tx_type, validation_code) VALUES ($1, $2, $3, $4, $5, $6)", s.tables.transactions)
+	upsertState := fmt.Sprintf("INSERT INTO %s (channel, namespace, key, value, block_number, tx_number) VALUES ($1, $2, $3, $4, $5, $6) "+
+		"ON CONFLICT (channel, namespace, key) DO UPDATE SET value = EXCLUDED.value, block_number = EXCLUDED.block_number, tx_number = EXCLUDED.tx_number", s.tables.state)
+	deleteState := fmt.Sprintf("DELETE FROM %s WHERE channel = $1 AND namespace = $2 AND key = $3", s.tables.state)
+	for _, t := range block.Transactions {
+		if _, err = tx.Exec(insertTx, block.ChannelID, block.Number, t.Number, t.TxID, t.Type, t.ValidationCode.String()); err != nil {
+			return errors.Wrapf(err, "failed inserting transaction [%d] of block [%d]", t.Number, block.Number)
+		}
+		for _, w := range t.Writes {
+			if w.IsDelete {
+				_, err = tx.Exec(deleteState, block.ChannelID, w.Namespace, []byte(w.Key))
+			} else {
+				_, err = tx.Exec(upsertState, block.ChannelID, w.Namespace, []byte(w.Key), w.Value, block.Number, t.Number)
+			}
+			if err != nil {
+				return errors.Wrapf(err, "failed writing key %q of namespace %s", w.Key, w.Namespace)
+			}
+		}
+	}
+
+	upsertCheckpoint := fmt.Sprintf("INSERT INTO %s (channel, next_block) VALUES ($1, $2) "+
+		"ON CONFLICT (channel) DO UPDATE SET next_block = EXCLUDED.next_block", s.tables.checkpoints)
+	if _, err = tx.Exec(upsertCheckpoint, block.ChannelID, block.Number+1); err != nil {
+		return errors.Wrapf(err, "failed advancing checkpoint of channel [%s]", block.ChannelID)
+	}
+	if err = tx.Commit(); err != nil {
+		return errors.Wrapf(err, "failed committing block [%d] of channel [%s]", block.Number, block.ChannelID)
+	}
+	return nil
+}
+
+// Init injects dependencies into the instance of the Sink, and creates the
+// tables if they do not exist
+func (s *PostgresSink) Init(dependencies ...Dependency) error {
+	params := parameters(dependencies)
+	dataSource := params["dataSource"]
+	if dataSource == "" {
+		return errors.New("dataSource parameter of PostgreSQL is not set")
+	}
+	driver := params["driver"]
+	if driver == "" {
+		driver = defaultPostgresDriver
+	}
+	prefix, ok := params["tablePrefix"]
+	if !ok {
+		prefix = defaultPostgresTablePrefix
+	}
+	s.tables.checkpoints = prefix + "checkpoints"
+	s.tables.transactions = prefix + "transactions"
+	s.tables.state = prefix + "state"
+
+	db, err := sql.Open(driver, dataSource)
+	if err != nil {
+		return errors.Wrapf(err, "failed opening database with driver %s", driver)
+	}
+	schema := []string{
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (channel TEXT PRIMARY KEY, next_block BIGINT NOT NULL)", s.tables.checkpoints),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (channel TEXT, block_number BIGINT, tx_number BIGINT, tx_id TEXT, tx_type TEXT, "+
+			"validation_code TEXT, PRIMARY KEY (channel, block_number, tx_number))", s.tables.transactions),
+		fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (channel TEXT, namespace TEXT, key BYTEA, value BYTEA, block_number BIGINT, "+
+			"tx_number BIGINT, PRIMARY KEY (channel, namespace, key))", s.tables.state),
+	}
+	for _, stmt := range schema {
+		if _, err := db.Exec(stmt); err != nil {
+			db.Close()
+			return errors.Wrap(err, "failed creating tables")
+		}
+	}
+	s.db = db
+	return nil
+}
+
+// Close releases the resources held by the Sink
+func (s *PostgresSink) Close() error {
+	if s.db == nil {
+		return nil
+	}
+	return s.db.Close()
+}
@@ -0,0 +1,204 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package builtin
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	. "github.com/hyperledger/fabric/core/handlers/connector/api"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeDB is an in memory database understanding the statements of the
+// PostgresSink, registered as a database/sql driver
+type fakeDB struct {
+	sync.Mutex
+	tables      []string
+	checkpoints map[string]int64
+	state       map[string][]byte
+	txs         int
+	failOn      string
+}
+
+var fakeDBs = map[string]*fakeDB{}
+
+func init() {
+	sql.Register("fakepostgres", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	db, ok := fakeDBs[name]
+	if !ok {
+		return nil, errors.Errorf("database %s does not exist", name)
+	}
+	return &fakeConn{db: db}, nil
+}
+
+type fakeConn struct {
+	db      *fakeDB
+	pending []func()
+	inTx    bool
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.inTx = true
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.db.Lock()
+	defer c.db.Unlock()
+	for _, op := range c.pending {
+		op()
+	}
+	c.pending, c.inTx = nil, false
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.pending, c.inTx = nil, false
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	db := s.conn.db
+	if db.failOn != "" && strings.Contains(s.query, db.failOn) {
+		return nil, errors.New("connection reset")
+	}
+	var op func()
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+		op = func() { db.tables = append(db.tables, strings.Fields(s.query)[5]) }
+	case strings.Contains(s.query, "checkpoints"):
+		op = func() { db.checkpoints[args[0].(string)] = args[1].(int64) }
+	case strings.Contains(s.query, "transactions"):
+		op = func() { db.txs++ }
+	case strings.HasPrefix(s.query, "INSERT"):
+		op = func() { db.state[args[1].(string)+"/"+string(args[2].([]byte))] = args[3].([]byte) }
+	case strings.HasPrefix(s.query, "DELETE"):
+		op = func() { delete(db.state, args[1].(string)+"/"+string(args[2].([]byte))) }
+	default:
+		return nil, errors.Errorf("unexpected statement %s", s.query)
+	}
+	if s.conn.inTx {
+		s.conn.pending = append(s.conn.pending, op)
+	} else {
+		db.Lock()
+		op()
+		db.Unlock()
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	db := s.conn.db
+	db.Lock()
+	defer db.Unlock()
+	rows := &fakeRows{}
+	if next, ok := db.checkpoints[args[0].(string)]; ok {
+		rows.values = []driver.Value{next}
+	}
+	return rows, nil
+}
+
+type fakeRows struct {
+	values []driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"next_block"}
+}
+
+func (r *fakeRows) Close() error {
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	dest[0], r.values = r.values[0], r.values[1:]
+	return nil
+}
+
+func TestPostgresSink(t *testing.T) {
+	db := &fakeDB{checkpoints: map[string]int64{}, state: map[string][]byte{}}
+	fakeDBs["fabric"] = db
+
+	sink := (&PostgresSinkFactory{}).New()
+	assert.EqualError(t, sink.Init(), "dataSource parameter of PostgreSQL is not set")
+	assert.Contains(t, sql.Drivers(), defaultPostgresDriver, "the default driver is built in")
+	require.NoError(t, sink.Init(Parameters{"driver": "fakepostgres", "dataSource": "fabric", "tablePrefix": "ledger_"}))
+	defer sink.Close()
+	assert.Equal(t, []string{"ledger_checkpoints", "ledger_transactions", "ledger_state"}, db.tables)
+
+	next, err := sink.Checkpoint("mychannel")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(0), next)
+
+	require.NoError(t, sink.Write(testBlock(0,
+		&Write{Namespace: "mycc", Key: "a", Value: []byte("1")},
+		&Write{Namespace: "mycc", Key: "b\x00c", Value: []byte("2")},
+	)))
+	next, err = sink.Checkpoint("mychannel")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), next)
+	assert.Equal(t, 2, db.txs)
+	assert.Equal(t, map[string][]byte{"mycc/a": []byte("1"), "mycc/b\x00c": []byte("2")}, db.state)
+
+	// a failed write leaves nothing behind
+	db.failOn = "ledger_checkpoints (channel, next_block)"
+	block := testBlock(1, &Write{Namespace: "mycc", Key: "a", IsDelete: true})
+	assert.Contains(t, sink.Write(block).Error(), "failed advancing checkpoint of channel [mychannel]")
+	assert.Equal(t, 2, db.txs)
+	assert.Len(t, db.state, 2)
+	next, err = sink.Checkpoint("mychannel")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), next)
+
+	db.failOn = ""
+	require.NoError(t, sink.Write(block))
+	assert.Equal(t, 4, db.txs)
+	assert.Equal(t, map[string][]byte{"mycc/b\x00c": []byte("2")}, db.state)
+
+	// the same block is not written twice
+	assert.EqualError(t, sink.Write(block), "checkpoint of channel [mychannel] is at block [2], cannot write block [1]")
+	assert.Equal(t, 4, db.txs)
+
+	sink = (&PostgresSinkFactory{}).New()
+	assert.Contains(t, sink.Init(Parameters{"driver": "fakepostgres", "dataSource": "missing"}).Error(), "failed creating tables")
+}
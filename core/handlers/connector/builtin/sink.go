@@ -0,0 +1,35 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package builtin
+
+import (
+	"time"
+
+	. "github.com/hyperledger/fabric/core/handlers/connector/api"
+	"github.com/pkg/errors"
+)
+
+func parameters(dependencies []Dependency) Parameters {
+	for _, dep := range dependencies {
+		if params, isParameters := dep.(Parameters); isParameters {
+			return params
+		}
+	}
+	return Parameters{}
+}
+
+func durationParameter(params Parameters, name string, defaultValue time.Duration) (time.Duration, error) {
+	value, ok := params[name]
+	if !ok {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, errors.Wrapf(err, "invalid %s parameter %q", name, value)
+	}
+	return d, nil
+}
@@ -0,0 +1,32 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package kms
+
+// Dependency marks a dependency passed to the Init() method
+type Dependency interface {
+}
+
+// Parameters are the plugin parameters set in the peer configuration
+type Parameters map[string]string
+
+// Decrypter decrypts the data of the encrypted transactions of the channels
+// the peer is authorized for, by means of a key management service holding
+// the keys of the channels.
+type Decrypter interface {
+	// Decrypt returns the plaintext of the ciphertext encrypted for the key
+	// of the given ID of the channel, or an error if the key is unknown, the
+	// peer is not authorized to use it or the ciphertext is not authentic
+	Decrypt(channelID string, keyID string, ciphertext []byte) ([]byte, error)
+
+	// Init injects dependencies into the instance of the Decrypter
+	Init(dependencies ...Dependency) error
+}
+
+// PluginFactory creates a new instance of a Decrypter
+type PluginFactory interface {
+	New() Decrypter
+}
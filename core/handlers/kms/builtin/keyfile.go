@@ -0,0 +1,98 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package builtin
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	. "github.com/hyperledger/fabric/core/handlers/kms/api"
+	"github.com/pkg/errors"
+)
+
+// KeyFileDecrypterFactory creates decrypters whose keys are files of a
+// local directory, for tests and deployments without a key management
+// service
+type KeyFileDecrypterFactory struct {
+}
+
+// New returns a new key file decrypter
+func (*KeyFileDecrypterFactory) New() Decrypter {
+	return &KeyFileDecrypter{}
+}
+
+// KeyFileDecrypter decrypts with AES-GCM the ciphertexts sealed for the
+// 256-bit keys held in <keyDir>/<channel>/<key ID>, keyDir being the
+// required parameter of the plugin.  The ciphertext is the nonce followed
+// by the sealed data, authenticated along with the channel and key IDs.
+type KeyFileDecrypter struct {
+	keyDir string
+}
+
+// Init reads the key directory from the parameters of the plugin
+func (d *KeyFileDecrypter) Init(dependencies ...Dependency) error {
+	for _, dep := range dependencies {
+		if params, isParameters := dep.(Parameters); isParameters {
+			d.keyDir = params["keyDir"]
+		}
+	}
+	if d.keyDir == "" {
+		return errors.New("keyDir parameter is required")
+	}
+	return nil
+}
+
+// Decrypt opens the ciphertext with the key of the given ID of the channel
+func (d *KeyFileDecrypter) Decrypt(channelID string, keyID string, ciphertext []byte) ([]byte, error) {
+	aead, err := d.aead(channelID, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, KeyFileAdditionalData(channelID, keyID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed decrypting with key %s of channel %s", keyID, channelID)
+	}
+	return plaintext, nil
+}
+
+func (d *KeyFileDecrypter) aead(channelID string, keyID string) (cipher.AEAD, error) {
+	if !validName(channelID) || !validName(keyID) {
+		return nil, errors.Errorf("invalid key %s of channel %s", keyID, channelID)
+	}
+	key, err := ioutil.ReadFile(filepath.Join(d.keyDir, channelID, keyID))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading key %s of channel %s", keyID, channelID)
+	}
+	if len(key) != 32 {
+		return nil, errors.Errorf("key %s of channel %s is %d bytes long instead of 32", keyID, channelID, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// KeyFileAdditionalData returns the data authenticated along with the data
+// encrypted for the key of the given ID of the channel, which binds the
+// ciphertext to the channel and key
+func KeyFileAdditionalData(channelID string, keyID string) []byte {
+	return []byte(channelID + "/" + keyID)
+}
+
+// validName returns whether the channel or key ID names a file of the key
+// directory, rather than escaping it
+func validName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
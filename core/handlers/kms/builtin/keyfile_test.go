@@ -0,0 +1,67 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package builtin
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	. "github.com/hyperledger/fabric/core/handlers/kms/api"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func seal(t *testing.T, key []byte, channelID, keyID string, plaintext []byte) []byte {
+	block, err := aes.NewCipher(key)
+	require.NoError(t, err)
+	aead, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+	nonce := make([]byte, aead.NonceSize())
+	_, err = rand.Read(nonce)
+	require.NoError(t, err)
+	return aead.Seal(nonce, nonce, plaintext, KeyFileAdditionalData(channelID, keyID))
+}
+
+func TestKeyFileDecrypter(t *testing.T) {
+	keyDir, err := ioutil.TempDir("", "kms")
+	require.NoError(t, err)
+	defer os.RemoveAll(keyDir)
+	key := make([]byte, 32)
+	_, err = rand.Read(key)
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(keyDir, "mychannel"), 0700))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(keyDir, "mychannel", "key1"), key, 0600))
+	require.NoError(t, ioutil.WriteFile(filepath.Join(keyDir, "mychannel", "short"), key[:16], 0600))
+
+	d := (&KeyFileDecrypterFactory{}).New()
+	assert.EqualError(t, d.Init(), "keyDir parameter is required")
+	require.NoError(t, d.Init(Parameters{"keyDir": keyDir}))
+
+	plaintext, err := d.Decrypt("mychannel", "key1", seal(t, key, "mychannel", "key1", []byte("secret")))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("secret"), plaintext)
+
+	// the ciphertext is bound to the channel and key
+	_, err = d.Decrypt("mychannel", "key1", seal(t, key, "otherchannel", "key1", []byte("secret")))
+	assert.Contains(t, err.Error(), "failed decrypting with key key1 of channel mychannel")
+
+	_, err = d.Decrypt("mychannel", "key1", []byte{1})
+	assert.EqualError(t, err, "ciphertext too short")
+	_, err = d.Decrypt("mychannel", "key2", nil)
+	assert.Contains(t, err.Error(), "failed reading key key2 of channel mychannel")
+	_, err = d.Decrypt("mychannel", "short", nil)
+	assert.EqualError(t, err, "key short of channel mychannel is 16 bytes long instead of 32")
+	_, err = d.Decrypt("..", "key1", nil)
+	assert.EqualError(t, err, "invalid key key1 of channel ..")
+	_, err = d.Decrypt("mychannel", "../mychannel/key1", nil)
+	assert.EqualError(t, err, "invalid key ../mychannel/key1 of channel mychannel")
+}
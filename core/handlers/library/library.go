@@ -9,10 +9,16 @@ package library
 import (
 	"github.com/hyperledger/fabric/core/handlers/auth"
 	"github.com/hyperledger/fabric/core/handlers/auth/filter"
+	blockverification "github.com/hyperledger/fabric/core/handlers/blockverification/api"
+	verifiers "github.com/hyperledger/fabric/core/handlers/blockverification/builtin"
+	connector "github.com/hyperledger/fabric/core/handlers/connector/api"
+	sinks "github.com/hyperledger/fabric/core/handlers/connector/builtin"
 	"github.com/hyperledger/fabric/core/handlers/decoration"
 	"github.com/hyperledger/fabric/core/handlers/decoration/decorator"
 	"github.com/hyperledger/fabric/core/handlers/endorsement/api"
 	"github.com/hyperledger/fabric/core/handlers/endorsement/builtin"
+	kms "github.com/hyperledger/fabric/core/handlers/kms/api"
+	decrypters "github.com/hyperledger/fabric/core/handlers/kms/builtin"
 	"github.com/hyperledger/fabric/core/handlers/validation/api"
 	. "github.com/hyperledger/fabric/core/handlers/validation/builtin"
 )
@@ -51,3 +57,45 @@ func (r *HandlerLibrary) DefaultEndorsement() endorsement.PluginFactory {
 func (r *HandlerLibrary) DefaultValidation() validation.PluginFactory {
 	return &DefaultValidationFactory{}
 }
+
+// DefaultBlockVerification creates a block verification plugin factory
+// whose plugins enforce the BlockValidation policy of the channel
+func (r *HandlerLibrary) DefaultBlockVerification() blockverification.PluginFactory {
+	return &verifiers.DefaultBlockVerificationFactory{}
+}
+
+// SingleOrdererBlockVerification creates a block verification plugin factory
+// whose plugins accept blocks signed by any single orderer
+func (r *HandlerLibrary) SingleOrdererBlockVerification() blockverification.PluginFactory {
+	return &verifiers.SingleOrdererBlockVerificationFactory{}
+}
+
+// QuorumBlockVerification creates a block verification plugin factory whose
+// plugins require blocks to be signed by a quorum of distinct orderers
+func (r *HandlerLibrary) QuorumBlockVerification() blockverification.PluginFactory {
+	return &verifiers.QuorumBlockVerificationFactory{}
+}
+
+// NotaryBlockVerification creates a block verification plugin factory whose
+// plugins delegate the authentication of blocks to an external notary
+func (r *HandlerLibrary) NotaryBlockVerification() blockverification.PluginFactory {
+	return &verifiers.NotaryBlockVerificationFactory{}
+}
+
+// PostgresConnector creates a sink factory whose sinks store the
+// committed blocks into PostgreSQL
+func (r *HandlerLibrary) PostgresConnector() connector.SinkFactory {
+	return &sinks.PostgresSinkFactory{}
+}
+
+// ElasticsearchConnector creates a sink factory whose sinks index the
+// committed blocks into Elasticsearch
+func (r *HandlerLibrary) ElasticsearchConnector() connector.SinkFactory {
+	return &sinks.ElasticsearchSinkFactory{}
+}
+
+// KeyFileKMS creates a decrypter factory whose decrypters read the keys
+// of the channels from files
+func (r *HandlerLibrary) KeyFileKMS() kms.PluginFactory {
+	return &decrypters.KeyFileDecrypterFactory{}
+}
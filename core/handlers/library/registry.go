@@ -14,9 +14,13 @@ import (
 	"sync"
 
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/pluginabi"
 	"github.com/hyperledger/fabric/core/handlers/auth"
+	blockverification "github.com/hyperledger/fabric/core/handlers/blockverification/api"
+	connector "github.com/hyperledger/fabric/core/handlers/connector/api"
 	"github.com/hyperledger/fabric/core/handlers/decoration"
 	endorsement2 "github.com/hyperledger/fabric/core/handlers/endorsement/api"
+	kms "github.com/hyperledger/fabric/core/handlers/kms/api"
 	"github.com/hyperledger/fabric/core/handlers/validation/api"
 )
 
@@ -42,6 +46,15 @@ const (
 	Decoration
 	Endorsement
 	Validation
+	// BlockVerification handler - authenticate the blocks
+	// received from the ordering service
+	BlockVerification
+	// Connector handler - stream the committed blocks
+	// into an external database
+	Connector
+	// KMS handler - decrypt the encrypted transactions
+	// of the channels
+	KMS
 
 	authPluginFactory      = "NewFilter"
 	decoratorPluginFactory = "NewDecorator"
@@ -53,6 +66,9 @@ type registry struct {
 	decorators []decoration.Decorator
 	endorsers  map[string]endorsement2.PluginFactory
 	validators map[string]validation.PluginFactory
+	verifier   blockverification.PluginFactory
+	connectors []connector.SinkFactory
+	decrypter  kms.PluginFactory
 }
 
 var once sync.Once
@@ -65,6 +81,16 @@ type Config struct {
 	Decorators  []*HandlerConfig `mapstructure:"decorators" yaml:"decorators"`
 	Endorsers   PluginMapping    `mapstructure:"endorsers" yaml:"endorsers"`
 	Validators  PluginMapping    `mapstructure:"validators" yaml:"validators"`
+	// BlockVerifier is the plugin authenticating the blocks received
+	// from the ordering service, the BlockValidation policy of the
+	// channel being enforced if it is not set
+	BlockVerifier *HandlerConfig `mapstructure:"blockVerifier" yaml:"blockVerifier"`
+	// Connectors are the sinks the committed blocks are streamed to,
+	// looked up in the same order
+	Connectors []*HandlerConfig `mapstructure:"connectors" yaml:"connectors"`
+	// KMS is the plugin decrypting the encrypted transactions, which are
+	// marked invalid if it is not set
+	KMS *HandlerConfig `mapstructure:"kms" yaml:"kms"`
 }
 
 type PluginMapping map[string]*HandlerConfig
@@ -73,6 +99,8 @@ type PluginMapping map[string]*HandlerConfig
 type HandlerConfig struct {
 	Name    string `mapstructure:"name" yaml:"name"`
 	Library string `mapstructure:"library" yaml:"library"`
+	// Parameters are passed to the plugins which support them
+	Parameters map[string]string `mapstructure:"parameters" yaml:"parameters"`
 }
 
 // InitRegistry creates the (only) instance
@@ -104,6 +132,18 @@ func (r *registry) loadHandlers(c Config) {
 	for chaincodeID, config := range c.Validators {
 		r.evaluateModeAndLoad(config, Validation, chaincodeID)
 	}
+
+	if c.BlockVerifier != nil && (c.BlockVerifier.Name != "" || c.BlockVerifier.Library != "") {
+		r.evaluateModeAndLoad(c.BlockVerifier, BlockVerification)
+	}
+
+	for _, config := range c.Connectors {
+		r.evaluateModeAndLoad(config, Connector)
+	}
+
+	if c.KMS != nil && (c.KMS.Name != "" || c.KMS.Library != "") {
+		r.evaluateModeAndLoad(c.KMS, KMS)
+	}
 }
 
 // evaluateModeAndLoad if a library path is provided, load the shared object
@@ -140,6 +180,12 @@ func (r *registry) loadCompiled(handlerFactory string, handlerType HandlerType,
 			logger.Panicf("expected 1 argument in extraArgs")
 		}
 		r.validators[extraArgs[0]] = inst.(validation.PluginFactory)
+	} else if handlerType == BlockVerification {
+		r.verifier = inst.(blockverification.PluginFactory)
+	} else if handlerType == Connector {
+		r.connectors = append(r.connectors, inst.(connector.SinkFactory))
+	} else if handlerType == KMS {
+		r.decrypter = inst.(kms.PluginFactory)
 	}
 }
 
@@ -148,6 +194,9 @@ func (r *registry) loadPlugin(pluginPath string, handlerType HandlerType, extraA
 	if _, err := os.Stat(pluginPath); err != nil {
 		logger.Panicf(fmt.Sprintf("Could not find plugin at path %s: %s", pluginPath, err))
 	}
+	if err := pluginabi.Check(pluginPath); err != nil {
+		logger.Panicf(fmt.Sprintf("Refusing to load plugin at path %s: %s", pluginPath, err))
+	}
 	p, err := plugin.Open(pluginPath)
 	if err != nil {
 		logger.Panicf(fmt.Sprintf("Error opening plugin at path %s: %s", pluginPath, err))
@@ -161,6 +210,12 @@ func (r *registry) loadPlugin(pluginPath string, handlerType HandlerType, extraA
 		r.initEndorsementPlugin(p, extraArgs...)
 	} else if handlerType == Validation {
 		r.initValidationPlugin(p, extraArgs...)
+	} else if handlerType == BlockVerification {
+		r.initBlockVerificationPlugin(p)
+	} else if handlerType == Connector {
+		r.initConnectorPlugin(p)
+	} else if handlerType == KMS {
+		r.initKMSPlugin(p)
 	}
 }
 
@@ -237,6 +292,57 @@ func (r *registry) initValidationPlugin(p *plugin.Plugin, extraArgs ...string) {
 	r.validators[extraArgs[0]] = factory
 }
 
+func (r *registry) initBlockVerificationPlugin(p *plugin.Plugin) {
+	factorySymbol, err := p.Lookup(pluginFactory)
+	if err != nil {
+		panicWithLookupError(pluginFactory, err)
+	}
+
+	constructor, ok := factorySymbol.(func() blockverification.PluginFactory)
+	if !ok {
+		panicWithDefinitionError(pluginFactory)
+	}
+	factory := constructor()
+	if factory == nil {
+		logger.Panicf("factory instance returned nil")
+	}
+	r.verifier = factory
+}
+
+func (r *registry) initConnectorPlugin(p *plugin.Plugin) {
+	factorySymbol, err := p.Lookup(pluginFactory)
+	if err != nil {
+		panicWithLookupError(pluginFactory, err)
+	}
+
+	constructor, ok := factorySymbol.(func() connector.SinkFactory)
+	if !ok {
+		panicWithDefinitionError(pluginFactory)
+	}
+	factory := constructor()
+	if factory == nil {
+		logger.Panicf("factory instance returned nil")
+	}
+	r.connectors = append(r.connectors, factory)
+}
+
+func (r *registry) initKMSPlugin(p *plugin.Plugin) {
+	factorySymbol, err := p.Lookup(pluginFactory)
+	if err != nil {
+		panicWithLookupError(pluginFactory, err)
+	}
+
+	constructor, ok := factorySymbol.(func() kms.PluginFactory)
+	if !ok {
+		panicWithDefinitionError(pluginFactory)
+	}
+	factory := constructor()
+	if factory == nil {
+		logger.Panicf("factory instance returned nil")
+	}
+	r.decrypter = factory
+}
+
 // panicWithLookupError panics when a handler constructor lookup fails
 func panicWithLookupError(factory string, err error) {
 	logger.Panicf(fmt.Sprintf("Plugin must contain constructor with name %s. Error from lookup: %s",
@@ -261,6 +367,12 @@ func (r *registry) Lookup(handlerType HandlerType) interface{} {
 		return r.endorsers
 	} else if handlerType == Validation {
 		return r.validators
+	} else if handlerType == BlockVerification {
+		return r.verifier
+	} else if handlerType == Connector {
+		return r.connectors
+	} else if handlerType == KMS {
+		return r.decrypter
 	}
 
 	return nil
@@ -10,14 +10,23 @@ import (
 	"testing"
 
 	"github.com/hyperledger/fabric/core/handlers/auth"
+	blockverification "github.com/hyperledger/fabric/core/handlers/blockverification/api"
+	"github.com/hyperledger/fabric/core/handlers/blockverification/builtin"
+	connector "github.com/hyperledger/fabric/core/handlers/connector/api"
+	sinks "github.com/hyperledger/fabric/core/handlers/connector/builtin"
 	"github.com/hyperledger/fabric/core/handlers/decoration"
+	kms "github.com/hyperledger/fabric/core/handlers/kms/api"
+	decrypters "github.com/hyperledger/fabric/core/handlers/kms/builtin"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestInitRegistry(t *testing.T) {
 	r := InitRegistry(Config{
-		AuthFilters: []*HandlerConfig{{Name: "DefaultAuth"}},
-		Decorators:  []*HandlerConfig{{Name: "DefaultDecorator"}},
+		AuthFilters:   []*HandlerConfig{{Name: "DefaultAuth"}},
+		Decorators:    []*HandlerConfig{{Name: "DefaultDecorator"}},
+		BlockVerifier: &HandlerConfig{Name: "QuorumBlockVerification"},
+		Connectors:    []*HandlerConfig{{Name: "ElasticsearchConnector"}, {Name: "PostgresConnector"}},
+		KMS:           &HandlerConfig{Name: "KeyFileKMS"},
 	})
 	assert.NotNil(t, r)
 	authHandlers := r.Lookup(Auth)
@@ -31,6 +40,20 @@ func TestInitRegistry(t *testing.T) {
 	decorators, isDecorators := decorationHandlers.([]decoration.Decorator)
 	assert.True(t, isDecorators)
 	assert.Len(t, decorators, 1)
+
+	verifier, isBlockVerifier := r.Lookup(BlockVerification).(blockverification.PluginFactory)
+	assert.True(t, isBlockVerifier)
+	assert.IsType(t, &builtin.QuorumBlockVerificationFactory{}, verifier)
+
+	connectors, isConnectors := r.Lookup(Connector).([]connector.SinkFactory)
+	assert.True(t, isConnectors)
+	assert.Len(t, connectors, 2)
+	assert.IsType(t, &sinks.ElasticsearchSinkFactory{}, connectors[0])
+	assert.IsType(t, &sinks.PostgresSinkFactory{}, connectors[1])
+
+	decrypter, isKMS := r.Lookup(KMS).(kms.PluginFactory)
+	assert.True(t, isKMS)
+	assert.IsType(t, &decrypters.KeyFileDecrypterFactory{}, decrypter)
 }
 
 func TestLoadCompiledInvalid(t *testing.T) {
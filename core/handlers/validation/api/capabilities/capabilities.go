@@ -45,4 +45,8 @@ type Capabilities interface {
 	// KeyLevelEndorsement returns true if this channel supports endorsement
 	// policies expressible at a ledger key granularity, as described in FAB-8812
 	KeyLevelEndorsement() bool
+
+	// ChaincodeLimits returns true if this channel supports chaincode specific
+	// execution timeout and resource limits, set upon instantiation or upgrade
+	ChaincodeLimits() bool
 }
@@ -490,7 +490,8 @@ func (vscc *ValidatorOneValidSignature) ValidateLSCCInvocation(
 		}
 
 		if (!ac.PrivateChannelData() && len(lsccArgs) > 5) ||
-			(ac.PrivateChannelData() && len(lsccArgs) > 6) {
+			(ac.PrivateChannelData() && !ac.ChaincodeLimits() && len(lsccArgs) > 6) ||
+			(ac.ChaincodeLimits() && len(lsccArgs) > 7) {
 			return policyErr(fmt.Errorf("Wrong number of arguments for invocation lscc(%s): received %d", lsccFunc, len(lsccArgs)))
 		}
 
@@ -566,6 +567,17 @@ func (vscc *ValidatorOneValidSignature) ValidateLSCCInvocation(
 		if cdRWSet.Version != cdsArgs.ChaincodeSpec.ChaincodeId.Version {
 			return policyErr(fmt.Errorf("expected cc version %s, found %s", cdsArgs.ChaincodeSpec.ChaincodeId.Version, cdRWSet.Version))
 		}
+		// the chaincode limits in the lsccwriteset must match the ones supplied
+		var limitsArgs *ccprovider.ChaincodeLimits
+		if ac.ChaincodeLimits() && len(lsccArgs) > 6 && len(lsccArgs[6]) > 0 {
+			limitsArgs, err = ccprovider.GetChaincodeLimits(lsccArgs[6])
+			if err != nil {
+				return policyErr(err)
+			}
+		}
+		if !proto.Equal(limitsArgs, cdRWSet.Limits) {
+			return policyErr(fmt.Errorf("chaincode limits supplied for chaincode %s:%s do not match the limits in the lscc writeset", cdRWSet.Name, cdRWSet.Version))
+		}
 		// it must only write to 2 namespaces: LSCC's and the cc that we are deploying/upgrading
 		for _, ns := range txRWSet.NsRwSets {
 			if ns.NameSpace != "lscc" && ns.NameSpace != cdRWSet.Name && len(ns.KvRwSet.Writes) > 0 {
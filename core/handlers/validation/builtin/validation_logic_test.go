@@ -142,6 +142,25 @@ func createCCDataRWsetWithCollection(nameK, nameV, version string, policy []byte
 	return sr.GetPubSimulationBytes()
 }
 
+func createCCDataRWsetWithLimits(nameK, nameV, version string, policy []byte, limits *ccprovider.ChaincodeLimits) ([]byte, error) {
+	cd := &ccprovider.ChaincodeData{
+		Name:                nameV,
+		Version:             version,
+		InstantiationPolicy: policy,
+		Limits:              limits,
+	}
+
+	cdbytes := utils.MarshalOrPanic(cd)
+
+	rwsetBuilder := rwsetutil.NewRWSetBuilder()
+	rwsetBuilder.AddToWriteSet("lscc", nameK, cdbytes)
+	sr, err := rwsetBuilder.GetTxSimulationResults()
+	if err != nil {
+		return nil, err
+	}
+	return sr.GetPubSimulationBytes()
+}
+
 func createCCDataRWset(nameK, nameV, version string, policy []byte) ([]byte, error) {
 	cd := &ccprovider.ChaincodeData{
 		Name:                nameV,
@@ -168,7 +187,7 @@ func createLSCCTx(ccname, ccver, f string, res []byte) (*common.Envelope, error)
 	return createLSCCTxPutCds(ccname, ccver, f, res, nil, true)
 }
 
-func createLSCCTxPutCdsWithCollection(ccname, ccver, f string, res, cdsbytes []byte, putcds bool, policy []byte, ccpBytes []byte) (*common.Envelope, error) {
+func createLSCCTxPutCdsWithCollection(ccname, ccver, f string, res, cdsbytes []byte, putcds bool, policy []byte, ccpBytes []byte, extraArgs ...[]byte) (*common.Envelope, error) {
 	cds := &peer.ChaincodeDeploymentSpec{
 		ChaincodeSpec: &peer.ChaincodeSpec{
 			ChaincodeId: &peer.ChaincodeID{
@@ -193,7 +212,7 @@ func createLSCCTxPutCdsWithCollection(ccname, ccver, f string, res, cdsbytes []b
 			ChaincodeSpec: &peer.ChaincodeSpec{
 				ChaincodeId: &peer.ChaincodeID{Name: "lscc"},
 				Input: &peer.ChaincodeInput{
-					Args: [][]byte{[]byte(f), []byte("barf"), cdsBytes, []byte("escc"), []byte("vscc"), policy, ccpBytes},
+					Args: append([][]byte{[]byte(f), []byte("barf"), cdsBytes, []byte("escc"), []byte("vscc"), policy, ccpBytes}, extraArgs...),
 				},
 				Type: peer.ChaincodeSpec_GOLANG,
 			},
@@ -991,6 +1010,96 @@ func TestValidateDeployWithCollection(t *testing.T) {
 	err = v.Validate(envBytes, policy)
 }
 
+func TestValidateDeployWithLimits(t *testing.T) {
+	state := make(map[string]map[string][]byte)
+	mp := (&scc.MocksccProviderFactory{
+		Qe: lm.NewMockQueryExecutor(state),
+		ApplicationConfigBool: true,
+		ApplicationConfigRv: &mc.MockApplication{CapabilitiesRv: &mc.MockApplicationCapabilities{
+			PrivateChannelDataRv: true,
+			ChaincodeLimitsRv:    true,
+		}},
+	}).NewSystemChaincodeProvider().(*scc.MocksccProviderImpl)
+
+	qec := &mocks2.QueryExecutorCreator{}
+	qec.On("NewQueryExecutor").Return(lm.NewMockQueryExecutor(state), nil)
+	v := newCustomValidationInstance(qec, &mc.MockApplicationCapabilities{
+		PrivateChannelDataRv: true,
+		ChaincodeLimitsRv:    true,
+	})
+
+	mockAclProvider := &aclmocks.MockACLProvider{}
+	lccc := lscc.New(mp, mockAclProvider)
+	stublccc := shim.NewMockStub("lscc", lccc)
+	state["lscc"] = stublccc.State
+
+	ccname := "mycc"
+	ccver := "1"
+
+	defaultPolicy, err := getSignedByMSPAdminPolicy(mspid)
+	assert.NoError(t, err)
+	policy, err := getSignedByMSPMemberPolicy(mspid)
+	if err != nil {
+		t.Fatalf("failed getting policy, err %s", err)
+	}
+
+	limits := &ccprovider.ChaincodeLimits{ExecuteTimeout: 60000, Memory: 1 << 28, CPUShares: 512}
+	limitsBytes := utils.MarshalOrPanic(limits)
+
+	// Test 1: the limits in the writeset match the ones supplied --> success
+	res, err := createCCDataRWsetWithLimits(ccname, ccname, ccver, defaultPolicy, limits)
+	assert.NoError(t, err)
+	tx, err := createLSCCTxPutCdsWithCollection(ccname, ccver, lscc.DEPLOY, res, nil, true, defaultPolicy, nil, limitsBytes)
+	if err != nil {
+		t.Fatalf("createTx returned err %s", err)
+	}
+	envBytes, err := utils.GetBytesEnvelope(tx)
+	if err != nil {
+		t.Fatalf("GetBytesEnvelope returned err %s", err)
+	}
+	err = v.Validate(envBytes, policy)
+	assert.NoError(t, err)
+
+	// Test 2: the limits in the writeset differ from the ones supplied --> failure
+	res, err = createCCDataRWsetWithLimits(ccname, ccname, ccver, defaultPolicy, &ccprovider.ChaincodeLimits{ExecuteTimeout: 1})
+	assert.NoError(t, err)
+	tx, err = createLSCCTxPutCdsWithCollection(ccname, ccver, lscc.DEPLOY, res, nil, true, defaultPolicy, nil, limitsBytes)
+	if err != nil {
+		t.Fatalf("createTx returned err %s", err)
+	}
+	envBytes, err = utils.GetBytesEnvelope(tx)
+	if err != nil {
+		t.Fatalf("GetBytesEnvelope returned err %s", err)
+	}
+	err = v.Validate(envBytes, policy)
+	assert.EqualError(t, err, "chaincode limits supplied for chaincode mycc:1 do not match the limits in the lscc writeset")
+
+	// Test 3: limits in the writeset but none supplied --> failure
+	tx, err = createLSCCTxWithCollection(ccname, ccver, lscc.DEPLOY, res, defaultPolicy, nil)
+	if err != nil {
+		t.Fatalf("createTx returned err %s", err)
+	}
+	envBytes, err = utils.GetBytesEnvelope(tx)
+	if err != nil {
+		t.Fatalf("GetBytesEnvelope returned err %s", err)
+	}
+	err = v.Validate(envBytes, policy)
+	assert.EqualError(t, err, "chaincode limits supplied for chaincode mycc:1 do not match the limits in the lscc writeset")
+
+	// Test 4: negative limits --> failure
+	tx, err = createLSCCTxPutCdsWithCollection(ccname, ccver, lscc.DEPLOY, res, nil, true, defaultPolicy, nil,
+		utils.MarshalOrPanic(&ccprovider.ChaincodeLimits{Memory: -1}))
+	if err != nil {
+		t.Fatalf("createTx returned err %s", err)
+	}
+	envBytes, err = utils.GetBytesEnvelope(tx)
+	if err != nil {
+		t.Fatalf("GetBytesEnvelope returned err %s", err)
+	}
+	err = v.Validate(envBytes, policy)
+	assert.EqualError(t, err, "invalid chaincode limits (execute timeout 0, memory -1, cpu shares 0), limits cannot be negative")
+}
+
 func TestValidateDeployWithPolicies(t *testing.T) {
 	state := make(map[string]map[string][]byte)
 	mp := (&scc.MocksccProviderFactory{
@@ -0,0 +1,190 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package fingerprint keeps the state fingerprints of the most recent blocks
+// committed on every channel.  The fingerprint of a block is a hash of the
+// state updates the block caused, which is the same on every peer of an
+// organization that committed the block correctly, so comparing the
+// fingerprints of two peers finds the first block their states diverged at.
+package fingerprint
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
+)
+
+var logger = flogging.MustGetLogger("fingerprint")
+
+var (
+	once     sync.Once
+	registry *Registry
+)
+
+// GetRegistry returns the process wide registry, keeping as many
+// fingerprints per channel as configured by ledger.state.fingerprintHistory
+func GetRegistry() *Registry {
+	once.Do(func() {
+		registry = NewRegistry(ledgerconfig.GetStateFingerprintHistory())
+	})
+	return registry
+}
+
+// Entry is the state fingerprint of a block
+type Entry struct {
+	BlockNumber uint64 `json:"block_number"`
+	StateHash   string `json:"state_hash"`
+}
+
+// Registry keeps the fingerprints of the most recent blocks of every channel
+type Registry struct {
+	mutex    sync.RWMutex
+	capacity int
+	ledgers  map[string][]Entry
+}
+
+// NewRegistry creates a Registry keeping capacity fingerprints per channel
+func NewRegistry(capacity int) *Registry {
+	return &Registry{
+		capacity: capacity,
+		ledgers:  make(map[string][]Entry),
+	}
+}
+
+// Record records the state fingerprint of a block committed on a channel,
+// evicting the oldest fingerprint of the channel if the registry is full.
+// Blocks are expected to be recorded in increasing order.
+func (r *Registry) Record(ledgerID string, blockNumber uint64, stateHash []byte) {
+	entry := Entry{BlockNumber: blockNumber, StateHash: hex.EncodeToString(stateHash)}
+	logger.Debugf("Channel [%s]: State fingerprint of block [%d] is [%s]", ledgerID, blockNumber, entry.StateHash)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	entries := r.ledgers[ledgerID]
+	if n := len(entries); n > 0 && entries[n-1].BlockNumber >= blockNumber {
+		// The block is being recommitted, such as during recovery
+		entries = truncate(entries, blockNumber)
+	}
+	entries = append(entries, entry)
+	if len(entries) > r.capacity {
+		entries = append([]Entry(nil), entries[len(entries)-r.capacity:]...)
+	}
+	r.ledgers[ledgerID] = entries
+}
+
+// truncate drops the entries of the block number and above
+func truncate(entries []Entry, blockNumber uint64) []Entry {
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].BlockNumber >= blockNumber
+	})
+	return entries[:i]
+}
+
+// Latest returns the fingerprint of the last block recorded on the channel
+func (r *Registry) Latest(ledgerID string) (Entry, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	entries := r.ledgers[ledgerID]
+	if len(entries) == 0 {
+		return Entry{}, false
+	}
+	return entries[len(entries)-1], true
+}
+
+// Get returns the fingerprint of a block of the channel if it is still kept
+func (r *Registry) Get(ledgerID string, blockNumber uint64) (Entry, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	entries := r.ledgers[ledgerID]
+	i := sort.Search(len(entries), func(i int) bool {
+		return entries[i].BlockNumber >= blockNumber
+	})
+	if i == len(entries) || entries[i].BlockNumber != blockNumber {
+		return Entry{}, false
+	}
+	return entries[i], true
+}
+
+// History returns the fingerprints kept for the channel, oldest first
+func (r *Registry) History(ledgerID string) []Entry {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return append([]Entry(nil), r.ledgers[ledgerID]...)
+}
+
+// Channels returns the channels fingerprints were recorded on
+func (r *Registry) Channels() []string {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	var channels []string
+	for ledgerID := range r.ledgers {
+		channels = append(channels, ledgerID)
+	}
+	sort.Strings(channels)
+	return channels
+}
+
+// NewHandler creates a handler serving the fingerprints of the registry.
+// Without query parameters, the handler serves the latest fingerprint of
+// every channel.  The channel parameter selects the fingerprints kept for a
+// channel, and the block parameter the fingerprint of a single block.
+func NewHandler(r *Registry) http.Handler {
+	return &handler{registry: r}
+}
+
+type handler struct {
+	registry *Registry
+}
+
+// ServeHTTP implements http.Handler
+func (h *handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channel := req.URL.Query().Get("channel")
+	block := req.URL.Query().Get("block")
+	switch {
+	case channel == "" && block != "":
+		http.Error(w, "the block parameter requires the channel parameter", http.StatusBadRequest)
+	case channel == "":
+		latest := make(map[string]Entry)
+		for _, ledgerID := range h.registry.Channels() {
+			if entry, ok := h.registry.Latest(ledgerID); ok {
+				latest[ledgerID] = entry
+			}
+		}
+		h.write(w, latest)
+	case block == "":
+		h.write(w, h.registry.History(channel))
+	default:
+		blockNumber, err := strconv.ParseUint(block, 10, 64)
+		if err != nil {
+			http.Error(w, "invalid block number: "+block, http.StatusBadRequest)
+			return
+		}
+		entry, ok := h.registry.Get(channel, blockNumber)
+		if !ok {
+			http.Error(w, "no fingerprint kept for block "+block+" of channel "+channel, http.StatusNotFound)
+			return
+		}
+		h.write(w, entry)
+	}
+}
+
+func (h *handler) write(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warningf("Failed writing state fingerprints: %s", err)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fingerprint
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(3)
+	_, ok := r.Latest("ch1")
+	assert.False(t, ok)
+
+	for i := uint64(0); i < 5; i++ {
+		r.Record("ch1", i, []byte{byte(i)})
+	}
+	r.Record("ch2", 0, []byte{0xff})
+
+	assert.Equal(t, []string{"ch1", "ch2"}, r.Channels())
+	assert.Equal(t, []Entry{{2, "02"}, {3, "03"}, {4, "04"}}, r.History("ch1"))
+
+	latest, ok := r.Latest("ch1")
+	assert.True(t, ok)
+	assert.Equal(t, Entry{4, "04"}, latest)
+
+	entry, ok := r.Get("ch1", 3)
+	assert.True(t, ok)
+	assert.Equal(t, Entry{3, "03"}, entry)
+	_, ok = r.Get("ch1", 1)
+	assert.False(t, ok, "evicted fingerprints should not be kept")
+	_, ok = r.Get("ch1", 5)
+	assert.False(t, ok)
+
+	// A recommitted block supersedes the block and those above it
+	r.Record("ch1", 3, []byte{0x33})
+	assert.Equal(t, []Entry{{2, "02"}, {3, "33"}}, r.History("ch1"))
+}
+
+func TestHandler(t *testing.T) {
+	r := NewRegistry(10)
+	r.Record("ch1", 0, []byte{0x01})
+	r.Record("ch1", 1, []byte{0x02})
+	r.Record("ch2", 7, []byte{0x03})
+	h := NewHandler(r)
+
+	get := func(url string) *httptest.ResponseRecorder {
+		resp := httptest.NewRecorder()
+		h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, url, nil))
+		return resp
+	}
+
+	resp := get("/ledger/fingerprints")
+	require.Equal(t, http.StatusOK, resp.Code)
+	latest := map[string]Entry{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &latest))
+	assert.Equal(t, map[string]Entry{"ch1": {1, "02"}, "ch2": {7, "03"}}, latest)
+
+	resp = get("/ledger/fingerprints?channel=ch1")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var history []Entry
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &history))
+	assert.Equal(t, []Entry{{0, "01"}, {1, "02"}}, history)
+
+	resp = get("/ledger/fingerprints?channel=ch1&block=0")
+	require.Equal(t, http.StatusOK, resp.Code)
+	var entry Entry
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &entry))
+	assert.Equal(t, Entry{0, "01"}, entry)
+
+	assert.Equal(t, http.StatusNotFound, get("/ledger/fingerprints?channel=ch1&block=5").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/ledger/fingerprints?channel=ch1&block=x").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/ledger/fingerprints?block=0").Code)
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/ledger/fingerprints", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
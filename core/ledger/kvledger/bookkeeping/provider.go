@@ -19,6 +19,8 @@ type Category int
 const (
 	// PvtdataExpiry repersents the bookkeeping related to expiry of pvtdata because of BTL policy
 	PvtdataExpiry Category = iota
+	// ValidationFailures represents the bookkeeping of the reasons of the transactions marked invalid at commit
+	ValidationFailures
 )
 
 // Provider provides handle to different bookkeepers for the given ledger
@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package kvledger
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"sync"
@@ -15,10 +16,12 @@ import (
 
 	"github.com/hyperledger/fabric/common/flogging"
 	commonledger "github.com/hyperledger/fabric/common/ledger"
+	"github.com/hyperledger/fabric/common/ledger/util/leveldbhelper"
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/cceventmgmt"
 	"github.com/hyperledger/fabric/core/ledger/confighistory"
+	"github.com/hyperledger/fabric/core/ledger/fingerprint"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/bookkeeping"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/history/historydb"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
@@ -26,6 +29,7 @@ import (
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/txmgr/lockbasedtxmgr"
 	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
 	"github.com/hyperledger/fabric/core/ledger/ledgerstorage"
+	lutil "github.com/hyperledger/fabric/core/ledger/util"
 	"github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/peer"
 )
@@ -43,6 +47,11 @@ type kvLedger struct {
 	historyDB              historydb.HistoryDB
 	configHistoryRetriever ledger.ConfigHistoryRetriever
 	blockAPIsRWLock        *sync.RWMutex
+	commitLock             sync.Mutex
+	validationFailures     *leveldbhelper.DBHandle
+	// failures recorded by the validator for the blocks yet to be committed
+	recordedFailures map[uint64]map[uint64]*ledger.ValidationFailure
+	failuresLock     sync.Mutex
 }
 
 // NewKVLedger constructs new `KVLedger`
@@ -59,7 +68,9 @@ func newKVLedger(
 	stateListeners = append(stateListeners, configHistoryMgr)
 	// Create a kvLedger for this chain/ledger, which encasulates the underlying
 	// id store, blockstore, txmgr (state database), history database
-	l := &kvLedger{ledgerID: ledgerID, blockStore: blockStore, historyDB: historyDB, blockAPIsRWLock: &sync.RWMutex{}}
+	l := &kvLedger{ledgerID: ledgerID, blockStore: blockStore, historyDB: historyDB, blockAPIsRWLock: &sync.RWMutex{},
+		validationFailures: bookkeeperProvider.GetDBHandle(ledgerID, bookkeeping.ValidationFailures),
+		recordedFailures:   make(map[uint64]map[uint64]*ledger.ValidationFailure)}
 
 	// TODO Move the function `GetChaincodeEventListener` to ledger interface and
 	// this functionality of regiserting for events to ledgermgmt package so that this
@@ -238,6 +249,86 @@ func (l *kvLedger) GetTxValidationCodeByTxID(txID string) (peer.TxValidationCode
 	return txValidationCode, err
 }
 
+// GetValidationFailureByTxID implements method in interface `ledger.ValidationFailureRetriever`
+func (l *kvLedger) GetValidationFailureByTxID(txID string) (*ledger.ValidationFailure, error) {
+	txValidationCode, err := l.GetTxValidationCodeByTxID(txID)
+	if err != nil {
+		return nil, err
+	}
+	if txValidationCode == peer.TxValidationCode_VALID {
+		return nil, nil
+	}
+	failureBytes, err := l.validationFailures.Get([]byte(txID))
+	if err != nil {
+		return nil, err
+	}
+	failure := &ledger.ValidationFailure{}
+	if failureBytes != nil {
+		if err := json.Unmarshal(failureBytes, failure); err != nil {
+			return nil, fmt.Errorf("failed unmarshalling validation failure of transaction [%s]: %s", txID, err)
+		}
+	}
+	if failure.Code != txValidationCode {
+		// nothing more than the validation code is known about the failure
+		failure = &ledger.ValidationFailure{TxID: txID, Code: txValidationCode}
+	}
+	return failure, nil
+}
+
+// RecordValidationFailures implements method in interface `ledger.ValidationFailureRecorder`.
+// The failures are kept until the block is committed and replace the ones
+// recorded by a previous validation of the block
+func (l *kvLedger) RecordValidationFailures(blockNum uint64, failures map[uint64]*ledger.ValidationFailure) {
+	l.failuresLock.Lock()
+	defer l.failuresLock.Unlock()
+	l.recordedFailures[blockNum] = failures
+}
+
+// addRecordedFailures adds the failures recorded for the block to the ones it carries and
+// drops those recorded for the block and the blocks below it
+func (l *kvLedger) addRecordedFailures(pvtdataAndBlock *ledger.BlockAndPvtData) {
+	l.failuresLock.Lock()
+	defer l.failuresLock.Unlock()
+	blockNo := pvtdataAndBlock.Block.Header.Number
+	for txNum, failure := range l.recordedFailures[blockNo] {
+		if pvtdataAndBlock.ValidationFailures == nil {
+			pvtdataAndBlock.ValidationFailures = make(map[uint64]*ledger.ValidationFailure)
+		}
+		if _, ok := pvtdataAndBlock.ValidationFailures[txNum]; !ok {
+			pvtdataAndBlock.ValidationFailures[txNum] = failure
+		}
+	}
+	for num := range l.recordedFailures {
+		if num <= blockNo {
+			delete(l.recordedFailures, num)
+		}
+	}
+}
+
+// commitValidationFailures stores by transaction id the failures of the block which
+// match the final validation code of their transaction.  The failures of the duplicates
+// of a transaction are not stored, as they would hide the one the id refers to
+func (l *kvLedger) commitValidationFailures(pvtdataAndBlock *ledger.BlockAndPvtData) error {
+	block := pvtdataAndBlock.Block
+	txsFilter := lutil.TxValidationFlags(block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER])
+	batch := leveldbhelper.NewUpdateBatch()
+	for txNum, failure := range pvtdataAndBlock.ValidationFailures {
+		if failure.TxID == "" || failure.Code == peer.TxValidationCode_DUPLICATE_TXID ||
+			txNum >= uint64(len(txsFilter)) || txsFilter.Flag(int(txNum)) != failure.Code {
+			continue
+		}
+		failureBytes, err := json.Marshal(failure)
+		if err != nil {
+			return fmt.Errorf("failed marshalling validation failure of transaction [%s]: %s", failure.TxID, err)
+		}
+		batch.Put([]byte(failure.TxID), failureBytes)
+	}
+	if len(batch.KVs) == 0 {
+		return nil
+	}
+	return l.validationFailures.WriteBatch(batch, true)
+}
+
 //Prune prunes the blocks/transactions that satisfy the given policy
 func (l *kvLedger) Prune(policy commonledger.PrunePolicy) error {
 	return errors.New("Not yet implemented")
@@ -266,10 +357,13 @@ func (l *kvLedger) NewHistoryQueryExecutor() (ledger.HistoryQueryExecutor, error
 // CommitWithPvtData commits the block and the corresponding pvt data in an atomic operation
 //提交block和相应的pvtdata（原子操作）
 func (l *kvLedger) CommitWithPvtData(pvtdataAndBlock *ledger.BlockAndPvtData) error {
+	l.commitLock.Lock()
+	defer l.commitLock.Unlock()
 	var err error
 	block := pvtdataAndBlock.Block
 	blockNo := pvtdataAndBlock.Block.Header.Number
 
+	l.addRecordedFailures(pvtdataAndBlock)
 	logger.Debugf("Channel [%s]: Validating state for block [%d]", l.ledgerID, blockNo)
 	//验证并准备block数据，为向VersionedDB中写入做准备
 	//使用验证器验证交易的读写集，以确定交易的有效性
@@ -280,6 +374,12 @@ func (l *kvLedger) CommitWithPvtData(pvtdataAndBlock *ledger.BlockAndPvtData) er
 	if err != nil {
 		return err
 	}
+	stateFingerprint := l.txtmgmt.StateFingerprint()
+	// the failures are stored ahead of the block, the ones of a block which fails to be
+	// committed being overwritten when the block is committed again
+	if err = l.commitValidationFailures(pvtdataAndBlock); err != nil {
+		return err
+	}
 
 	logger.Debugf("Channel [%s]: Committing block [%d] to storage", l.ledgerID, blockNo)
 	//加写锁
@@ -306,10 +406,30 @@ func (l *kvLedger) CommitWithPvtData(pvtdataAndBlock *ledger.BlockAndPvtData) er
 			panic(fmt.Errorf(`Error during commit to history db:%s`, err))
 		}
 	}
+	fingerprint.GetRegistry().Record(l.ledgerID, blockNo, stateFingerprint)
 	//这里需注意一下，虽说三个账本都是写入block数据，但是写入的数据各有不同，具体写何内容在下文各个账本章节中详述
 	return nil
 }
 
+// ReplayState recommits the blocks from startBlockNum up to the last block of
+// the block storage to the state database, so that every key written by these
+// blocks is restored to the value the blocks leave it with.  This repairs a
+// state database which diverged from the blocks, the history database being
+// left untouched.
+func (l *kvLedger) ReplayState(startBlockNum uint64) error {
+	l.commitLock.Lock()
+	defer l.commitLock.Unlock()
+	info, err := l.blockStore.GetBlockchainInfo()
+	if err != nil {
+		return err
+	}
+	if startBlockNum >= info.Height {
+		return fmt.Errorf("cannot replay from block [%d], the ledger height is [%d]", startBlockNum, info.Height)
+	}
+	logger.Infof("Channel [%s]: Replaying blocks [%d] to [%d] to the state database", l.ledgerID, startBlockNum, info.Height-1)
+	return l.recommitLostBlocks(startBlockNum, info.Height-1, l.txtmgmt)
+}
+
 // GetPvtDataAndBlockByNum returns the block and the corresponding pvt data.
 // The pvt data is filtered by the list of 'collections' supplied
 func (l *kvLedger) GetPvtDataAndBlockByNum(blockNum uint64, filter ledger.PvtNsCollFilter) (*ledger.BlockAndPvtData, error) {
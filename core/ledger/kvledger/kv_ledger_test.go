@@ -17,8 +17,10 @@ import (
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/common/privdata"
 	lgr "github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/fingerprint"
 	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
 	ledgertestutil "github.com/hyperledger/fabric/core/ledger/testutil"
+	lutil "github.com/hyperledger/fabric/core/ledger/util"
 	"github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/ledger/queryresult"
 	"github.com/hyperledger/fabric/protos/peer"
@@ -84,6 +86,12 @@ func TestKVLedgerBlockStorage(t *testing.T) {
 	testutil.AssertEquals(t, bcInfo, &common.BlockchainInfo{
 		Height: 3, CurrentBlockHash: block2Hash, PreviousBlockHash: block1Hash})
 
+	fingerprint1, ok := fingerprint.GetRegistry().Get("testLedger", 1)
+	assert.True(t, ok)
+	fingerprint2, ok := fingerprint.GetRegistry().Get("testLedger", 2)
+	assert.True(t, ok)
+	assert.NotEqual(t, fingerprint1.StateHash, fingerprint2.StateHash)
+
 	b0, _ := ledger.GetBlockByHash(gbHash)
 	testutil.AssertEquals(t, b0, gb)
 
@@ -369,6 +377,104 @@ func TestKVLedgerDBRecovery(t *testing.T) {
 	)
 }
 
+func TestKVLedgerReplayState(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+	provider, _ := NewProvider()
+	defer provider.Close()
+	bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	ledger, _ := provider.Create(gb)
+	defer ledger.Close()
+	nextBlock := func(txid string, pubKVs map[string]string) *lgr.BlockAndPvtData {
+		return &lgr.BlockAndPvtData{Block: prepareNextBlockForTest(t, ledger, bg, txid, pubKVs, nil).Block}
+	}
+
+	assert.NoError(t, ledger.CommitWithPvtData(nextBlock("SimulateForBlk1",
+		map[string]string{"key1": "value1.1", "key2": "value2.1"})))
+	assert.NoError(t, ledger.CommitWithPvtData(nextBlock("SimulateForBlk2",
+		map[string]string{"key1": "value1.2"})))
+
+	// Corrupt the state database by committing a block which is not in the block storage
+	corruptBlock := nextBlock("SimulateForCorruption",
+		map[string]string{"key1": "corrupted", "key2": "corrupted"})
+	assert.NoError(t, ledger.(*kvLedger).txtmgmt.ValidateAndPrepare(corruptBlock, true))
+	assert.NoError(t, ledger.(*kvLedger).txtmgmt.Commit())
+	checkBCSummaryForTest(t, ledger, &bcSummary{
+		stateDBKVs: map[string]string{"key1": "corrupted", "key2": "corrupted"},
+	})
+
+	// Replaying the last block only restores the key it writes
+	assert.NoError(t, ledger.(*kvLedger).ReplayState(2))
+	checkBCSummaryForTest(t, ledger, &bcSummary{
+		stateDBSavePoint: uint64(2),
+		stateDBKVs:       map[string]string{"key1": "value1.2", "key2": "corrupted"},
+	})
+
+	assert.NoError(t, ledger.(*kvLedger).ReplayState(1))
+	checkBCSummaryForTest(t, ledger, &bcSummary{
+		stateDBSavePoint: uint64(2),
+		stateDBKVs:       map[string]string{"key1": "value1.2", "key2": "value2.1"},
+	})
+
+	err := ledger.(*kvLedger).ReplayState(3)
+	assert.EqualError(t, err, "cannot replay from block [3], the ledger height is [3]")
+}
+
+func TestKVLedgerValidationFailures(t *testing.T) {
+	env := newTestEnv(t)
+	defer env.cleanup()
+	provider, _ := NewProvider()
+	defer provider.Close()
+	bg, gb := testutil.NewBlockGenerator(t, "testLedger", false)
+	ledger, _ := provider.Create(gb)
+	defer ledger.Close()
+
+	simulator, _ := ledger.NewTxSimulator("tx1")
+	simulator.SetState("ns", "key1", []byte("value1"))
+	simulator.Done()
+	simRes, _ := simulator.GetTxSimulationResults()
+	pubSimBytes, _ := simRes.GetPubSimulationBytes()
+	block := bg.NextBlockWithTxid([][]byte{pubSimBytes, pubSimBytes, pubSimBytes, pubSimBytes}, []string{"tx1", "tx2", "tx3", "tx4"})
+	txsFilter := lutil.NewTxValidationFlagsSetValue(4, peer.TxValidationCode_VALID)
+	txsFilter.SetFlag(1, peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE)
+	txsFilter.SetFlag(2, peer.TxValidationCode_BAD_PAYLOAD)
+	txsFilter.SetFlag(3, peer.TxValidationCode_CHAINCODE_VERSION_CONFLICT)
+	block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = txsFilter
+
+	policyFailure := &lgr.ValidationFailure{
+		TxID:      "tx2",
+		Code:      peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE,
+		Plugin:    "vscc",
+		Namespace: "ns",
+		Policy:    "OR('Org1MSP.member')",
+		Message:   "signature set did not satisfy policy",
+	}
+	l := ledger.(*kvLedger)
+	l.RecordValidationFailures(0, map[uint64]*lgr.ValidationFailure{0: {TxID: "stale"}})
+	l.RecordValidationFailures(1, map[uint64]*lgr.ValidationFailure{1: policyFailure})
+	assert.NoError(t, ledger.CommitWithPvtData(&lgr.BlockAndPvtData{
+		Block: block,
+		// a failure overridden by a later check is not kept
+		ValidationFailures: map[uint64]*lgr.ValidationFailure{3: {TxID: "tx4", Code: peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE}},
+	}))
+	assert.Empty(t, l.recordedFailures)
+
+	failure, err := l.GetValidationFailureByTxID("tx1")
+	assert.NoError(t, err)
+	assert.Nil(t, failure)
+	failure, err = l.GetValidationFailureByTxID("tx2")
+	assert.NoError(t, err)
+	assert.Equal(t, policyFailure, failure)
+	failure, err = l.GetValidationFailureByTxID("tx3")
+	assert.NoError(t, err)
+	assert.Equal(t, &lgr.ValidationFailure{TxID: "tx3", Code: peer.TxValidationCode_BAD_PAYLOAD}, failure)
+	failure, err = l.GetValidationFailureByTxID("tx4")
+	assert.NoError(t, err)
+	assert.Equal(t, &lgr.ValidationFailure{TxID: "tx4", Code: peer.TxValidationCode_CHAINCODE_VERSION_CONFLICT}, failure)
+	_, err = l.GetValidationFailureByTxID("unknown")
+	assert.Error(t, err)
+}
+
 func TestLedgerWithCouchDbEnabledWithBinaryAndJSONData(t *testing.T) {
 
 	//call a helper method to load the core.yaml
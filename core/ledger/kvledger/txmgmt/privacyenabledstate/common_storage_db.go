@@ -37,16 +37,41 @@ type CommonStorageDBProvider struct {
 
 // NewCommonStorageDBProvider constructs an instance of DBProvider
 func NewCommonStorageDBProvider() (DBProvider, error) {
-	var vdbProvider statedb.VersionedDBProvider
-	var err error
-	if ledgerconfig.IsCouchDBEnabled() {
-		if vdbProvider, err = statecouchdb.NewVersionedDBProvider(); err != nil {
-			return nil, err
-		}
-	} else {
-		vdbProvider = stateleveldb.NewVersionedDBProvider()
+	vdbProvider, err := newVersionedDBProvider(ledgerconfig.IsCouchDBEnabled())
+	if err != nil {
+		return nil, err
+	}
+	target := ledgerconfig.GetStateMigrationTarget()
+	if target == "" {
+		return &CommonStorageDBProvider{vdbProvider}, nil
+	}
+
+	// the state is migrated to the target state database while written to
+	// both, and served from the target once both are found to match
+	if strings.EqualFold(target, "CouchDB") == ledgerconfig.IsCouchDBEnabled() {
+		vdbProvider.Close()
+		return nil, fmt.Errorf("state database migration target [%s] is the state database in use", target)
+	}
+	targetProvider, err := newVersionedDBProvider(strings.EqualFold(target, "CouchDB"))
+	if err != nil {
+		vdbProvider.Close()
+		return nil, err
+	}
+	migrationProvider, err := newMigrationDBProvider(vdbProvider, targetProvider, migrationConfigFromLedgerConfig())
+	if err != nil {
+		vdbProvider.Close()
+		targetProvider.Close()
+		return nil, err
+	}
+	logger.Infof("Migrating the state database to [%s]", target)
+	return &CommonStorageDBProvider{migrationProvider}, nil
+}
+
+func newVersionedDBProvider(couchDB bool) (statedb.VersionedDBProvider, error) {
+	if couchDB {
+		return statecouchdb.NewVersionedDBProvider()
 	}
-	return &CommonStorageDBProvider{vdbProvider}, nil
+	return stateleveldb.NewVersionedDBProvider(), nil
 }
 
 // GetDBHandle implements function from interface DBProvider
@@ -0,0 +1,88 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privacyenabledstate
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"sort"
+
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+)
+
+// Fingerprint returns a hash of the public and hashed updates in the batch.
+// The updates are hashed in the order of their namespaces, collections and
+// keys, so that every peer committing the same block computes the same
+// fingerprint, whatever the state database.  The private updates are left
+// out, as a peer may not be eligible to the private data whose hashes it
+// commits.
+func (b *UpdateBatch) Fingerprint() []byte {
+	h := sha256.New()
+	pubUpdates := b.PubUpdates.UpdateBatch
+	for _, ns := range sortedStrings(pubUpdates.GetUpdatedNamespaces()) {
+		writeUpdates(h, pubUpdates.GetUpdates(ns), ns)
+	}
+	for _, ns := range sortedNamespaces(b.HashUpdates.UpdateMap) {
+		nsBatch := b.HashUpdates.UpdateMap[ns]
+		for _, coll := range sortedStrings(nsBatch.GetCollectionNames()) {
+			writeUpdates(h, nsBatch.GetUpdates(coll), ns, coll)
+		}
+	}
+	return h.Sum(nil)
+}
+
+// writeUpdates writes the updates in the order of their keys, each being
+// prefixed with the names it is scoped by
+func writeUpdates(h hash.Hash, updates map[string]*statedb.VersionedValue, names ...string) {
+	keys := make([]string, 0, len(updates))
+	for key := range updates {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, name := range names {
+			writeField(h, []byte(name))
+		}
+		writeField(h, []byte(key))
+		vv := updates[key]
+		if vv.Value == nil {
+			// Distinguishes a delete from a write of an empty value
+			h.Write([]byte{0})
+		} else {
+			h.Write([]byte{1})
+			writeField(h, vv.Value)
+		}
+		if vv.Version != nil {
+			writeField(h, vv.Version.ToBytes())
+		} else {
+			writeField(h, nil)
+		}
+	}
+}
+
+// writeField writes the field prefixed with its length, so that the
+// boundaries between the fields are unambiguous
+func writeField(h hash.Hash, field []byte) {
+	length := make([]byte, binary.MaxVarintLen64)
+	h.Write(length[:binary.PutUvarint(length, uint64(len(field)))])
+	h.Write(field)
+}
+
+func sortedNamespaces(m UpdateMap) []string {
+	namespaces := make([]string, 0, len(m))
+	for ns := range m {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+func sortedStrings(s []string) []string {
+	sort.Strings(s)
+	return s
+}
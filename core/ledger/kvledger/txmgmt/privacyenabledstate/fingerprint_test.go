@@ -0,0 +1,58 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privacyenabledstate
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFingerprint(t *testing.T) {
+	v := version.NewHeight(5, 1)
+	newBatch := func(reverse bool) *UpdateBatch {
+		batch := NewUpdateBatch()
+		puts := []func(){
+			func() { batch.PubUpdates.Put("ns1", "key1", []byte("value1"), v) },
+			func() { batch.PubUpdates.Put("ns2", "key1", []byte("value2"), v) },
+			func() { batch.PubUpdates.Delete("ns1", "key2", v) },
+			func() { batch.HashUpdates.Put("ns1", "coll1", []byte("keyHash1"), []byte("valueHash1"), v) },
+			func() { batch.HashUpdates.Put("ns1", "coll2", []byte("keyHash1"), []byte("valueHash2"), v) },
+		}
+		for i := range puts {
+			if reverse {
+				puts[len(puts)-1-i]()
+			} else {
+				puts[i]()
+			}
+		}
+		return batch
+	}
+
+	fingerprint := newBatch(false).Fingerprint()
+	assert.Len(t, fingerprint, 32)
+	assert.Equal(t, fingerprint, newBatch(true).Fingerprint(), "the order of the updates should not matter")
+
+	batch := newBatch(false)
+	batch.PvtUpdates.Put("ns1", "coll1", "key1", []byte("value1"), v)
+	assert.Equal(t, fingerprint, batch.Fingerprint(), "the private updates should be left out")
+
+	batch = newBatch(false)
+	batch.PubUpdates.Put("ns1", "key2", []byte{}, v)
+	assert.NotEqual(t, fingerprint, batch.Fingerprint(), "a write of an empty value should differ from a delete")
+
+	batch = newBatch(false)
+	batch.PubUpdates.Put("ns1", "key1", []byte("value1"), version.NewHeight(5, 2))
+	assert.NotEqual(t, fingerprint, batch.Fingerprint(), "the versions should be hashed")
+
+	batch = newBatch(false)
+	batch.HashUpdates.Put("ns1", "coll1", []byte("keyHash1"), []byte("valueHash3"), v)
+	assert.NotEqual(t, fingerprint, batch.Fingerprint(), "the hashed updates should be hashed")
+
+	assert.Equal(t, NewUpdateBatch().Fingerprint(), NewUpdateBatch().Fingerprint())
+}
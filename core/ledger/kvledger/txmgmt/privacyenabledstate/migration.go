@@ -0,0 +1,617 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privacyenabledstate
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/core/common/ccprovider"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
+	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+const (
+	// lsccNamespace holds a key for every chaincode instantiated on the
+	// channel, along with the collection configs of the chaincodes
+	lsccNamespace = "lscc"
+	// collectionConfigSuffix ends the keys of the collection configs
+	collectionConfigSuffix = "~collection"
+)
+
+// errMigrationStopped is returned by the passes of a migration stopped as the
+// database is closed
+var errMigrationStopped = errors.New("state migration stopped")
+
+type migrationStatus int
+
+const (
+	migrationCopying migrationStatus = iota
+	migrationVerified
+	migrationFailed
+)
+
+type migrationConfig struct {
+	// the directory recording the channels cut over to the target
+	dir            string
+	verifyInterval time.Duration
+	batchSize      int
+	autoCutover    bool
+}
+
+func migrationConfigFromLedgerConfig() migrationConfig {
+	return migrationConfig{
+		dir:            ledgerconfig.GetStateMigrationPath(),
+		verifyInterval: ledgerconfig.GetStateMigrationVerifyInterval(),
+		batchSize:      ledgerconfig.GetStateMigrationBatchSize(),
+		autoCutover:    ledgerconfig.IsStateMigrationAutoCutoverEnabled(),
+	}
+}
+
+// migrationDBProvider provides the state databases of the channels being
+// migrated from the source backend to the target backend, or of the target
+// backend once they are cut over.
+type migrationDBProvider struct {
+	source statedb.VersionedDBProvider
+	target statedb.VersionedDBProvider
+	conf   migrationConfig
+
+	mutex sync.Mutex
+	dbs   []*migrationDB
+}
+
+func newMigrationDBProvider(source, target statedb.VersionedDBProvider, conf migrationConfig) (*migrationDBProvider, error) {
+	if err := os.MkdirAll(conf.dir, 0755); err != nil {
+		return nil, errors.Wrapf(err, "failed to create state migration directory %s", conf.dir)
+	}
+	return &migrationDBProvider{source: source, target: target, conf: conf}, nil
+}
+
+// GetDBHandle returns the database of the target backend if the channel was
+// cut over to it, and otherwise starts migrating the database of the channel.
+func (p *migrationDBProvider) GetDBHandle(id string) (statedb.VersionedDB, error) {
+	target, err := p.target.GetDBHandle(id)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to open state database migrated to")
+	}
+	marker := filepath.Join(p.conf.dir, id)
+	if _, err := os.Stat(marker); err == nil {
+		logger.Warningf("Channel [%s]: state database was migrated, set ledger.state.stateDatabase to ledger.state.migration.targetDatabase", id)
+		return target, nil
+	}
+	source, err := p.source.GetDBHandle(id)
+	if err != nil {
+		return nil, err
+	}
+	db := newMigrationDB(id, source, target, marker, p.conf)
+	p.mutex.Lock()
+	p.dbs = append(p.dbs, db)
+	p.mutex.Unlock()
+	go db.migrate()
+	return db, nil
+}
+
+// Close stops the migrations and closes both backends.
+func (p *migrationDBProvider) Close() {
+	p.mutex.Lock()
+	for _, db := range p.dbs {
+		db.stop()
+	}
+	p.mutex.Unlock()
+	p.source.Close()
+	p.target.Close()
+}
+
+// migrationDB serves the state from the source database while the state is
+// copied to the target database, applying the updates to both.  The state is
+// copied by passes comparing both databases a batch of keys at a time, the
+// keys missing or differing in the target being copied to it and the keys
+// missing in the source being deleted from the target.  Once a pass finds no
+// difference, the state is served from the target as of the next commit.
+type migrationDB struct {
+	id       string
+	source   statedb.VersionedDB
+	target   statedb.VersionedDB
+	toTarget keyTranslator
+	toSource keyTranslator
+	marker   string
+	conf     migrationConfig
+
+	// serializes the updates with the batches of the passes, so that each
+	// batch compares a consistent state of both databases
+	commitLock sync.Mutex
+	status     migrationStatus
+	cutOver    int32
+
+	stopOnce sync.Once
+	stopChan chan struct{}
+	doneChan chan struct{}
+}
+
+func newMigrationDB(id string, source, target statedb.VersionedDB, marker string, conf migrationConfig) *migrationDB {
+	return &migrationDB{
+		id:       id,
+		source:   source,
+		target:   target,
+		toTarget: newKeyTranslator(source, target),
+		toSource: newKeyTranslator(target, source),
+		marker:   marker,
+		conf:     conf,
+		stopChan: make(chan struct{}),
+		doneChan: make(chan struct{}),
+	}
+}
+
+// active returns the database the state is served from
+func (db *migrationDB) active() statedb.VersionedDB {
+	if atomic.LoadInt32(&db.cutOver) == 1 {
+		return db.target
+	}
+	return db.source
+}
+
+// GetState implements method in VersionedDB interface
+func (db *migrationDB) GetState(namespace string, key string) (*statedb.VersionedValue, error) {
+	return db.active().GetState(namespace, key)
+}
+
+// GetVersion implements method in VersionedDB interface
+func (db *migrationDB) GetVersion(namespace string, key string) (*version.Height, error) {
+	return db.active().GetVersion(namespace, key)
+}
+
+// GetStateMultipleKeys implements method in VersionedDB interface
+func (db *migrationDB) GetStateMultipleKeys(namespace string, keys []string) ([]*statedb.VersionedValue, error) {
+	return db.active().GetStateMultipleKeys(namespace, keys)
+}
+
+// GetStateRangeScanIterator implements method in VersionedDB interface
+func (db *migrationDB) GetStateRangeScanIterator(namespace string, startKey string, endKey string) (statedb.ResultsIterator, error) {
+	return db.active().GetStateRangeScanIterator(namespace, startKey, endKey)
+}
+
+// ExecuteQuery implements method in VersionedDB interface
+func (db *migrationDB) ExecuteQuery(namespace, query string) (statedb.ResultsIterator, error) {
+	return db.active().ExecuteQuery(namespace, query)
+}
+
+// GetLatestSavePoint implements method in VersionedDB interface
+func (db *migrationDB) GetLatestSavePoint() (*version.Height, error) {
+	return db.active().GetLatestSavePoint()
+}
+
+// ValidateKeyValue implements method in VersionedDB interface
+func (db *migrationDB) ValidateKeyValue(key string, value []byte) error {
+	return db.active().ValidateKeyValue(key, value)
+}
+
+// BytesKeySuppoted implements method in VersionedDB interface
+func (db *migrationDB) BytesKeySuppoted() bool {
+	return db.active().BytesKeySuppoted()
+}
+
+// Open implements method in VersionedDB interface
+func (db *migrationDB) Open() error {
+	if err := db.source.Open(); err != nil {
+		return err
+	}
+	return db.target.Open()
+}
+
+// Close implements method in VersionedDB interface
+func (db *migrationDB) Close() {
+	db.stop()
+	db.source.Close()
+	db.target.Close()
+}
+
+// ApplyUpdates applies the updates to the source database and, unless the
+// migration failed, to the target database, a failure to update the target
+// failing the migration rather than the commit.  Once the state was found to
+// match, the updates are only applied to the target database.
+func (db *migrationDB) ApplyUpdates(batch *statedb.UpdateBatch, height *version.Height) error {
+	db.commitLock.Lock()
+	defer db.commitLock.Unlock()
+	if atomic.LoadInt32(&db.cutOver) == 1 {
+		return db.target.ApplyUpdates(batch, height)
+	}
+	if err := db.source.ApplyUpdates(batch, height); err != nil {
+		return err
+	}
+	if db.status == migrationFailed {
+		return nil
+	}
+	targetBatch, err := db.toTarget.batch(batch)
+	if err == nil {
+		err = db.target.ApplyUpdates(targetBatch, height)
+	}
+	if err != nil {
+		db.fail(errors.WithMessage(err, "failed to apply updates to state database migrated to"))
+		return nil
+	}
+	if db.status == migrationVerified && db.conf.autoCutover {
+		db.cutover()
+	}
+	return nil
+}
+
+// LoadCommittedVersions implements method in BulkOptimizable interface, the
+// versions being loaded by both databases for the updates to be applied to
+// both
+func (db *migrationDB) LoadCommittedVersions(keys []*statedb.CompositeKey) error {
+	for _, vdb := range []statedb.VersionedDB{db.source, db.target} {
+		if bulkOptimizable, ok := vdb.(statedb.BulkOptimizable); ok {
+			if err := bulkOptimizable.LoadCommittedVersions(keys); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// GetCachedVersion implements method in BulkOptimizable interface
+func (db *migrationDB) GetCachedVersion(namespace, key string) (*version.Height, bool) {
+	if bulkOptimizable, ok := db.active().(statedb.BulkOptimizable); ok {
+		return bulkOptimizable.GetCachedVersion(namespace, key)
+	}
+	return nil, false
+}
+
+// ClearCachedVersions implements method in BulkOptimizable interface
+func (db *migrationDB) ClearCachedVersions() {
+	for _, vdb := range []statedb.VersionedDB{db.source, db.target} {
+		if bulkOptimizable, ok := vdb.(statedb.BulkOptimizable); ok {
+			bulkOptimizable.ClearCachedVersions()
+		}
+	}
+}
+
+// GetDBType implements method in IndexCapable interface
+func (db *migrationDB) GetDBType() string {
+	for _, vdb := range []statedb.VersionedDB{db.target, db.source} {
+		if indexCapable, ok := vdb.(statedb.IndexCapable); ok {
+			return indexCapable.GetDBType()
+		}
+	}
+	return ""
+}
+
+// ProcessIndexesForChaincodeDeploy implements method in IndexCapable
+// interface, the indexes being created in both databases
+func (db *migrationDB) ProcessIndexesForChaincodeDeploy(namespace string, fileEntries []*ccprovider.TarFileEntry) error {
+	for _, vdb := range []statedb.VersionedDB{db.source, db.target} {
+		if indexCapable, ok := vdb.(statedb.IndexCapable); ok {
+			if err := indexCapable.ProcessIndexesForChaincodeDeploy(namespace, fileEntries); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// migrate runs the passes until one finds both databases to match
+func (db *migrationDB) migrate() {
+	defer close(db.doneChan)
+	logger.Infof("Channel [%s]: migrating state database", db.id)
+	for {
+		start := time.Now()
+		differences, err := db.syncPass()
+		if err == errMigrationStopped {
+			return
+		}
+		if err != nil {
+			db.commitLock.Lock()
+			db.fail(err)
+			db.commitLock.Unlock()
+			return
+		}
+		if differences == 0 {
+			db.verified()
+			return
+		}
+		logger.Infof("Channel [%s]: copied %d differences to state database migrated to in %s, verifying again in %s",
+			db.id, differences, time.Since(start), db.conf.verifyInterval)
+		select {
+		case <-time.After(db.conf.verifyInterval):
+		case <-db.stopChan:
+			return
+		}
+	}
+}
+
+func (db *migrationDB) stop() {
+	db.stopOnce.Do(func() {
+		close(db.stopChan)
+	})
+	<-db.doneChan
+}
+
+// fail abandons the migration, the caller holding the commit lock
+func (db *migrationDB) fail(err error) {
+	if db.status == migrationFailed {
+		return
+	}
+	db.status = migrationFailed
+	logger.Errorf("Channel [%s]: state database migration failed, the state remains served from ledger.state.stateDatabase: %s", db.id, err)
+}
+
+func (db *migrationDB) verified() {
+	db.commitLock.Lock()
+	defer db.commitLock.Unlock()
+	if db.status == migrationFailed {
+		return
+	}
+	db.status = migrationVerified
+	if !db.conf.autoCutover {
+		logger.Infof("Channel [%s]: state database migrated and verified, set ledger.state.stateDatabase to ledger.state.migration.targetDatabase and restart to complete the migration", db.id)
+		return
+	}
+	logger.Infof("Channel [%s]: state database migrated and verified, serving the state from the state database migrated to as of the next commit", db.id)
+}
+
+// cutover serves the state from the target database from now on, and on
+// every restart, the caller holding the commit lock
+func (db *migrationDB) cutover() {
+	if err := ioutil.WriteFile(db.marker, []byte(db.id), 0644); err != nil {
+		db.fail(errors.Wrap(err, "failed to record the migration"))
+		return
+	}
+	atomic.StoreInt32(&db.cutOver, 1)
+	logger.Infof("Channel [%s]: state is now served from the state database migrated to", db.id)
+}
+
+// syncPass compares both databases, copying the differences to the target,
+// and returns the number of differences
+func (db *migrationDB) syncPass() (int, error) {
+	namespaces, err := db.namespaces()
+	if err != nil {
+		return 0, err
+	}
+	differences := 0
+	for _, ns := range namespaces {
+		copied, err := db.syncNamespace(ns, db.source, db.target, db.toTarget, true)
+		if err != nil {
+			return 0, errors.WithMessage(err, "failed to copy namespace "+ns)
+		}
+		deleted, err := db.syncNamespace(ns, db.target, db.source, db.toSource, false)
+		if err != nil {
+			return 0, errors.WithMessage(err, "failed to clean up namespace "+ns)
+		}
+		differences += copied + deleted
+	}
+
+	saved, err := db.syncSavepoint()
+	if err != nil {
+		return 0, err
+	}
+	if saved {
+		differences++
+	}
+	return differences, nil
+}
+
+// syncNamespace compares the keys of the namespace in the database scanned
+// with the other database, a batch of keys at a time.  Copying, the keys of
+// the source missing or differing in the target are copied to it, and
+// otherwise the keys of the target missing in the source are deleted.
+func (db *migrationDB) syncNamespace(ns string, scanned, other statedb.VersionedDB, translator keyTranslator, copying bool) (int, error) {
+	differences := 0
+	start := ""
+	for {
+		n, next, err := db.syncBatch(ns, start, scanned, other, translator, copying)
+		if err != nil {
+			return 0, err
+		}
+		differences += n
+		if next == "" {
+			return differences, nil
+		}
+		start = next
+	}
+}
+
+// syncBatch compares a batch of keys from the start key, and returns the
+// number of differences and the key to start the next batch from, empty if
+// the batch was the last one
+func (db *migrationDB) syncBatch(ns, start string, scanned, other statedb.VersionedDB, translator keyTranslator, copying bool) (int, string, error) {
+	db.commitLock.Lock()
+	defer db.commitLock.Unlock()
+	select {
+	case <-db.stopChan:
+		return 0, "", errMigrationStopped
+	default:
+	}
+	if db.status == migrationFailed {
+		return 0, "", errMigrationStopped
+	}
+
+	kvs, err := scanBatch(scanned, ns, start, db.conf.batchSize)
+	if err != nil {
+		return 0, "", err
+	}
+	if len(kvs) == 0 {
+		return 0, "", nil
+	}
+	keys := make([]string, len(kvs))
+	for i, kv := range kvs {
+		if keys[i], err = translator.key(ns, kv.Key); err != nil {
+			return 0, "", err
+		}
+	}
+	values, err := other.GetStateMultipleKeys(ns, keys)
+	if err != nil {
+		return 0, "", err
+	}
+
+	batch := statedb.NewUpdateBatch()
+	for i, kv := range kvs {
+		switch {
+		case copying && !sameValue(&kv.VersionedValue, values[i]):
+			batch.Put(ns, keys[i], kv.Value, kv.Version)
+		case !copying && values[i] == nil:
+			batch.Delete(ns, kv.Key, kv.Version)
+		}
+	}
+	differences := len(batch.GetUpdates(ns))
+	if differences > 0 {
+		savepoint, err := db.source.GetLatestSavePoint()
+		if err != nil {
+			return 0, "", err
+		}
+		if err := db.target.ApplyUpdates(batch, savepoint); err != nil {
+			return 0, "", err
+		}
+	}
+
+	next := ""
+	if len(kvs) == db.conf.batchSize {
+		next = kvs[len(kvs)-1].Key + "\x00"
+	}
+	return differences, next, nil
+}
+
+// syncSavepoint records the savepoint of the source in the target, and
+// returns whether it differed
+func (db *migrationDB) syncSavepoint() (bool, error) {
+	db.commitLock.Lock()
+	defer db.commitLock.Unlock()
+	source, err := db.source.GetLatestSavePoint()
+	if err != nil || source == nil {
+		return false, err
+	}
+	target, err := db.target.GetLatestSavePoint()
+	if err != nil {
+		return false, err
+	}
+	if target != nil && version.AreSame(source, target) {
+		return false, nil
+	}
+	return true, db.target.ApplyUpdates(statedb.NewUpdateBatch(), source)
+}
+
+// namespaces returns the namespaces of the chaincodes instantiated on the
+// channel and of their collections, along with the lscc namespace itself
+func (db *migrationDB) namespaces() ([]string, error) {
+	namespaces := []string{lsccNamespace}
+	kvs, err := scanBatch(db.source, lsccNamespace, "", 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed listing the chaincodes")
+	}
+	for _, kv := range kvs {
+		if !strings.HasSuffix(kv.Key, collectionConfigSuffix) {
+			namespaces = append(namespaces, kv.Key)
+			continue
+		}
+		ccName := strings.TrimSuffix(kv.Key, collectionConfigSuffix)
+		collections := &common.CollectionConfigPackage{}
+		if err := proto.Unmarshal(kv.Value, collections); err != nil {
+			return nil, errors.Wrapf(err, "invalid collection configuration of chaincode %s", ccName)
+		}
+		for _, collection := range collections.Config {
+			name := collection.GetStaticCollectionConfig().GetName()
+			if name == "" {
+				continue
+			}
+			namespaces = append(namespaces, derivePvtDataNs(ccName, name), deriveHashedDataNs(ccName, name))
+		}
+	}
+	return namespaces, nil
+}
+
+// scanBatch returns up to max keys of the namespace from the start key, or
+// every key if max is 0
+func scanBatch(vdb statedb.VersionedDB, ns, start string, max int) ([]*statedb.VersionedKV, error) {
+	itr, err := vdb.GetStateRangeScanIterator(ns, start, "")
+	if err != nil {
+		return nil, err
+	}
+	defer itr.Close()
+	var kvs []*statedb.VersionedKV
+	for max == 0 || len(kvs) < max {
+		result, err := itr.Next()
+		if err != nil {
+			return nil, err
+		}
+		if result == nil {
+			break
+		}
+		kvs = append(kvs, result.(*statedb.VersionedKV))
+	}
+	return kvs, nil
+}
+
+func sameValue(vv1, vv2 *statedb.VersionedValue) bool {
+	if vv1 == nil || vv2 == nil {
+		return vv1 == vv2
+	}
+	return bytes.Equal(vv1.Value, vv2.Value) && version.AreSame(vv1.Version, vv2.Version)
+}
+
+// keyTranslator translates the keys of the hashed data from the encoding of
+// a database to that of another, the keys being base64 encoded in the
+// databases which do not support any bytes as keys
+type keyTranslator struct {
+	encode bool
+	decode bool
+}
+
+func newKeyTranslator(from, to statedb.VersionedDB) keyTranslator {
+	return keyTranslator{
+		encode: from.BytesKeySuppoted() && !to.BytesKeySuppoted(),
+		decode: !from.BytesKeySuppoted() && to.BytesKeySuppoted(),
+	}
+}
+
+func (t keyTranslator) key(ns, key string) (string, error) {
+	if !isHashedDataNs(ns) {
+		return key, nil
+	}
+	switch {
+	case t.encode:
+		return base64.StdEncoding.EncodeToString([]byte(key)), nil
+	case t.decode:
+		decoded, err := base64.StdEncoding.DecodeString(key)
+		if err != nil {
+			return "", errors.Wrapf(err, "invalid key hash in namespace %s", ns)
+		}
+		return string(decoded), nil
+	default:
+		return key, nil
+	}
+}
+
+func (t keyTranslator) batch(batch *statedb.UpdateBatch) (*statedb.UpdateBatch, error) {
+	if !t.encode && !t.decode {
+		return batch, nil
+	}
+	translated := statedb.NewUpdateBatch()
+	for _, ns := range batch.GetUpdatedNamespaces() {
+		for key, vv := range batch.GetUpdates(ns) {
+			translatedKey, err := t.key(ns, key)
+			if err != nil {
+				return nil, err
+			}
+			translated.Update(ns, translatedKey, vv)
+		}
+	}
+	return translated, nil
+}
+
+func isHashedDataNs(ns string) bool {
+	return strings.Contains(ns, nsJoiner+hashDataPrefix)
+}
@@ -0,0 +1,218 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privacyenabledstate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb/stateleveldb"
+	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
+	"github.com/hyperledger/fabric/core/ledger/ledgerconfig"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type migrationTestEnv struct {
+	dir    string
+	source statedb.VersionedDBProvider
+	target statedb.VersionedDBProvider
+	conf   migrationConfig
+}
+
+func newMigrationTestEnv(t *testing.T, autoCutover bool) *migrationTestEnv {
+	dir, err := ioutil.TempDir("", "statemigration")
+	require.NoError(t, err)
+	// each provider opens the leveldb under the file system path set
+	// when it is created
+	viper.Set("peer.fileSystemPath", filepath.Join(dir, "source"))
+	source := stateleveldb.NewVersionedDBProvider()
+	viper.Set("peer.fileSystemPath", filepath.Join(dir, "target"))
+	target := stateleveldb.NewVersionedDBProvider()
+	return &migrationTestEnv{
+		dir:    dir,
+		source: source,
+		target: target,
+		conf: migrationConfig{
+			dir:            ledgerconfig.GetStateMigrationPath(),
+			verifyInterval: time.Millisecond,
+			batchSize:      2,
+			autoCutover:    autoCutover,
+		},
+	}
+}
+
+func (env *migrationTestEnv) cleanup() {
+	os.RemoveAll(env.dir)
+}
+
+func (env *migrationTestEnv) provider(t *testing.T) *migrationDBProvider {
+	provider, err := newMigrationDBProvider(env.source, env.target, env.conf)
+	require.NoError(t, err)
+	return provider
+}
+
+func populateSource(t *testing.T, vdb statedb.VersionedDB) {
+	collections := &common.CollectionConfigPackage{Config: []*common.CollectionConfig{{
+		Payload: &common.CollectionConfig_StaticCollectionConfig{
+			StaticCollectionConfig: &common.StaticCollectionConfig{Name: "coll"},
+		},
+	}}}
+	batch := statedb.NewUpdateBatch()
+	batch.Put(lsccNamespace, "mycc", []byte("ccdata"), version.NewHeight(1, 0))
+	batch.Put(lsccNamespace, "mycc~collection", utils.MarshalOrPanic(collections), version.NewHeight(1, 0))
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		batch.Put("mycc", key, []byte("value-"+key), version.NewHeight(1, 1))
+	}
+	batch.Put(deriveHashedDataNs("mycc", "coll"), string([]byte{0xff, 0x01}), []byte("hash"), version.NewHeight(1, 2))
+	require.NoError(t, vdb.ApplyUpdates(batch, version.NewHeight(1, 2)))
+}
+
+func TestStateMigration(t *testing.T) {
+	env := newMigrationTestEnv(t, true)
+	defer env.cleanup()
+
+	source, err := env.source.GetDBHandle("ch1")
+	require.NoError(t, err)
+	populateSource(t, source)
+	target, err := env.target.GetDBHandle("ch1")
+	require.NoError(t, err)
+	stale := statedb.NewUpdateBatch()
+	stale.Put("mycc", "b", []byte("stale"), version.NewHeight(1, 0))
+	stale.Put("mycc", "z", []byte("deleted since"), version.NewHeight(1, 0))
+	require.NoError(t, target.ApplyUpdates(stale, version.NewHeight(1, 0)))
+
+	provider := env.provider(t)
+	vdb, err := provider.GetDBHandle("ch1")
+	require.NoError(t, err)
+	db := vdb.(*migrationDB)
+	<-db.doneChan
+
+	// the state was copied, the stale keys fixed and the deleted ones removed
+	for _, key := range []string{"a", "b", "c", "d", "e"} {
+		vv, err := target.GetState("mycc", key)
+		require.NoError(t, err)
+		require.NotNil(t, vv, key)
+		assert.Equal(t, []byte("value-"+key), vv.Value)
+	}
+	vv, err := target.GetState("mycc", "z")
+	require.NoError(t, err)
+	assert.Nil(t, vv)
+	vv, err = target.GetState(deriveHashedDataNs("mycc", "coll"), string([]byte{0xff, 0x01}))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hash"), vv.Value)
+	savepoint, err := target.GetLatestSavePoint()
+	require.NoError(t, err)
+	assert.Equal(t, version.NewHeight(1, 2), savepoint)
+
+	// the commit after the verification is the last applied to the source
+	assert.Equal(t, source, db.active())
+	batch := statedb.NewUpdateBatch()
+	batch.Put("mycc", "f", []byte("value-f"), version.NewHeight(2, 0))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(2, 0)))
+	assert.Equal(t, target, db.active())
+	vv, err = source.GetState("mycc", "f")
+	require.NoError(t, err)
+	assert.NotNil(t, vv)
+
+	batch = statedb.NewUpdateBatch()
+	batch.Put("mycc", "g", []byte("value-g"), version.NewHeight(3, 0))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(3, 0)))
+	vv, err = source.GetState("mycc", "g")
+	require.NoError(t, err)
+	assert.Nil(t, vv)
+	vv, err = db.GetState("mycc", "g")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value-g"), vv.Value)
+
+	// once cut over, the target is served directly
+	vdb, err = provider.GetDBHandle("ch1")
+	require.NoError(t, err)
+	assert.Equal(t, target, vdb)
+	provider.Close()
+}
+
+func TestStateMigrationWithoutCutover(t *testing.T) {
+	env := newMigrationTestEnv(t, false)
+	defer env.cleanup()
+
+	source, err := env.source.GetDBHandle("ch1")
+	require.NoError(t, err)
+	populateSource(t, source)
+
+	provider := env.provider(t)
+	defer provider.Close()
+	vdb, err := provider.GetDBHandle("ch1")
+	require.NoError(t, err)
+	db := vdb.(*migrationDB)
+	<-db.doneChan
+	assert.Equal(t, migrationVerified, db.status)
+
+	// the updates keep being applied to both databases
+	batch := statedb.NewUpdateBatch()
+	batch.Put("mycc", "f", []byte("value-f"), version.NewHeight(2, 0))
+	require.NoError(t, db.ApplyUpdates(batch, version.NewHeight(2, 0)))
+	assert.Equal(t, source, db.active())
+	target, err := env.target.GetDBHandle("ch1")
+	require.NoError(t, err)
+	vv, err := target.GetState("mycc", "f")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("value-f"), vv.Value)
+	_, err = os.Stat(filepath.Join(env.conf.dir, "ch1"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+type bytesKeyDB struct {
+	statedb.VersionedDB
+	bytesKeySupported bool
+}
+
+func (db *bytesKeyDB) BytesKeySuppoted() bool {
+	return db.bytesKeySupported
+}
+
+func TestKeyTranslator(t *testing.T) {
+	leveldb := &bytesKeyDB{bytesKeySupported: true}
+	couchdb := &bytesKeyDB{bytesKeySupported: false}
+	hashedNs := deriveHashedDataNs("mycc", "coll")
+	keyHash := string([]byte{0xff, 0x01})
+
+	toCouch := newKeyTranslator(leveldb, couchdb)
+	encoded, err := toCouch.key(hashedNs, keyHash)
+	require.NoError(t, err)
+	assert.Equal(t, "/wE=", encoded)
+	key, err := toCouch.key("mycc", "plain")
+	require.NoError(t, err)
+	assert.Equal(t, "plain", key)
+
+	toLevel := newKeyTranslator(couchdb, leveldb)
+	decoded, err := toLevel.key(hashedNs, encoded)
+	require.NoError(t, err)
+	assert.Equal(t, keyHash, decoded)
+	_, err = toLevel.key(hashedNs, "not base64!")
+	assert.Error(t, err)
+
+	batch := statedb.NewUpdateBatch()
+	batch.Put(hashedNs, keyHash, []byte("hash"), version.NewHeight(1, 0))
+	batch.Delete("mycc", "plain", version.NewHeight(1, 0))
+	translated, err := toCouch.batch(batch)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("hash"), translated.Get(hashedNs, "/wE=").Value)
+	assert.True(t, translated.Exists("mycc", "plain"))
+
+	same := newKeyTranslator(leveldb, leveldb)
+	translated, err = same.batch(batch)
+	require.NoError(t, err)
+	assert.Equal(t, batch, translated)
+}
@@ -108,6 +108,16 @@ func (txmgr *LockBasedTxMgr) ValidateAndPrepare(blockAndPvtdata *ledger.BlockAnd
 	return nil
 }
 
+// StateFingerprint implements method in interface `txmgmt.TxMgr`.
+// It returns the fingerprint of the updates prepared for the block being
+// committed, or nil if no block is being committed
+func (txmgr *LockBasedTxMgr) StateFingerprint() []byte {
+	if txmgr.current == nil {
+		return nil
+	}
+	return txmgr.current.batch.Fingerprint()
+}
+
 func (txmgr *LockBasedTxMgr) invokeNamespaceListeners() error {
 	for _, listener := range txmgr.stateListeners {
 		stateUpdatesForListener := extractStateUpdates(txmgr.current.batch, listener.InterestedInNamespaces())
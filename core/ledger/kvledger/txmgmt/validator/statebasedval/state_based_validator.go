@@ -6,7 +6,11 @@ SPDX-License-Identifier: Apache-2.0
 package statebasedval
 
 import (
+	"encoding/hex"
+	"fmt"
+
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
@@ -106,16 +110,22 @@ func (v *Validator) ValidateAndPrepareBatch(block *valinternal.Block, doMVCCVali
 	//准备updates用于存放批量升级包
 	updates := valinternal.NewPubAndHashUpdates()
 	for _, tx := range block.Txs {
-		var validationCode peer.TxValidationCode
+		var failure *ledger.ValidationFailure
 		var err error
 		//验证交易，返回交易的读写集和验证结果
 		//就是一个分发任务依次验证的过程
-		//返回为peer.TxValidationCode_VALID，则说明经过上述验证读集、验证范围读集的过程，证明当前交易有效且可以写入state数据库
-		if validationCode, err = v.validateEndorserTX(tx.RWSet, doMVCCValidation, updates); err != nil {
+		//返回的failure为nil，则说明经过上述验证读集、验证范围读集的过程，证明当前交易有效且可以写入state数据库
+		if failure, err = v.validateEndorserTX(tx.RWSet, doMVCCValidation, updates); err != nil {
 			return nil, err
 		}
 
+		validationCode := peer.TxValidationCode_VALID
+		if failure != nil {
+			failure.TxID = tx.ID
+			validationCode = failure.Code
+		}
 		tx.ValidationCode = validationCode
+		tx.ValidationFailure = failure
 		if validationCode == peer.TxValidationCode_VALID {
 			logger.Debugf("Block [%d] Transaction index [%d] TxId [%s] marked as valid by state validator", block.Num, tx.IndexInBlock, tx.ID)
 			//生成提交版本号(写值自身是不带版本号的，而写值的向state数据提交的版本号就是在这生成的）
@@ -137,76 +147,92 @@ func (v *Validator) ValidateAndPrepareBatch(block *valinternal.Block, doMVCCVali
 func (v *Validator) validateEndorserTX(
 	txRWSet *rwsetutil.TxRwSet,
 	doMVCCValidation bool,
-	updates *valinternal.PubAndHashUpdates) (peer.TxValidationCode, error) {
+	updates *valinternal.PubAndHashUpdates) (*ledger.ValidationFailure, error) {
 
-	var validationCode = peer.TxValidationCode_VALID
-	var err error
 	//mvccvalidation, may invalidate transaction
-	if doMVCCValidation {
-		//将任务进行分发
-		validationCode, err = v.validateTx(txRWSet, updates)
+	if !doMVCCValidation {
+		return nil, nil
 	}
-	return validationCode, err
+	//将任务进行分发
+	return v.validateTx(txRWSet, updates)
 }
 
-func (v *Validator) validateTx(txRWSet *rwsetutil.TxRwSet, updates *valinternal.PubAndHashUpdates) (peer.TxValidationCode, error) {
+// validateTx returns the failure of the first read of the transaction which
+// conflicts with the state, or nil if the transaction is valid
+func (v *Validator) validateTx(txRWSet *rwsetutil.TxRwSet, updates *valinternal.PubAndHashUpdates) (*ledger.ValidationFailure, error) {
 	// Uncomment the following only for local debugging. Don't want to print data in the logs in production
 	//logger.Debugf("validateTx - validating txRWSet: %s", spew.Sdump(txRWSet))
 	for _, nsRWSet := range txRWSet.NsRwSets {
 		ns := nsRWSet.NameSpace
 		// Validate public reads
 		//验证读集合
-		if valid, err := v.validateReadSet(ns, nsRWSet.KvRwSet.Reads, updates.PubUpdates); !valid || err != nil {
-			if err != nil {
-				return peer.TxValidationCode(-1), err
-			}
-			return peer.TxValidationCode_MVCC_READ_CONFLICT, nil
+		if failure, err := v.validateReadSet(ns, nsRWSet.KvRwSet.Reads, updates.PubUpdates); failure != nil || err != nil {
+			return failure, err
 		}
 		// Validate range queries for phantom items
 		//验证范围读集合
-		if valid, err := v.validateRangeQueries(ns, nsRWSet.KvRwSet.RangeQueriesInfo, updates.PubUpdates); !valid || err != nil {
-			if err != nil {
-				return peer.TxValidationCode(-1), err
-			}
-			return peer.TxValidationCode_PHANTOM_READ_CONFLICT, nil
+		if failure, err := v.validateRangeQueries(ns, nsRWSet.KvRwSet.RangeQueriesInfo, updates.PubUpdates); failure != nil || err != nil {
+			return failure, err
 		}
 		// Validate hashes for private reads
-		if valid, err := v.validateNsHashedReadSets(ns, nsRWSet.CollHashedRwSets, updates.HashUpdates); !valid || err != nil {
-			if err != nil {
-				return peer.TxValidationCode(-1), err
-			}
-			return peer.TxValidationCode_MVCC_READ_CONFLICT, nil
+		if failure, err := v.validateNsHashedReadSets(ns, nsRWSet.CollHashedRwSets, updates.HashUpdates); failure != nil || err != nil {
+			return failure, err
 		}
 	}
-	return peer.TxValidationCode_VALID, nil
+	return nil, nil
+}
+
+const updatedInBlock = "key updated by a preceding transaction of the block"
+
+// readConflict returns the failure of a transaction whose read of a key conflicts with the state
+func readConflict(ns, coll, key, msg string) *ledger.ValidationFailure {
+	return &ledger.ValidationFailure{
+		Code:       peer.TxValidationCode_MVCC_READ_CONFLICT,
+		Namespace:  ns,
+		Collection: coll,
+		Key:        key,
+		Message:    msg,
+	}
+}
+
+func versionMismatch(readVersion *kvrwset.Version, committedVersion *version.Height) string {
+	return fmt.Sprintf("read version [%s] differs from committed version [%s]",
+		versionString(rwsetutil.NewVersion(readVersion)), versionString(committedVersion))
+}
+
+func versionString(h *version.Height) string {
+	if h == nil {
+		return "none"
+	}
+	return fmt.Sprintf("%d:%d", h.BlockNum, h.TxNum)
 }
 
 ////////////////////////////////////////////////////////////////////////////////
 /////                 Validation of public read-set
 ////////////////////////////////////////////////////////////////////////////////
 //验证读集合
-func (v *Validator) validateReadSet(ns string, kvReads []*kvrwset.KVRead, updates *privacyenabledstate.PubUpdateBatch) (bool, error) {
+func (v *Validator) validateReadSet(ns string, kvReads []*kvrwset.KVRead, updates *privacyenabledstate.PubUpdateBatch) (*ledger.ValidationFailure, error) {
 	//循环调用validateKVRead，一一验证每个读值
 	for _, kvRead := range kvReads {
-		if valid, err := v.validateKVRead(ns, kvRead, updates); !valid || err != nil {
-			return valid, err
+		if failure, err := v.validateKVRead(ns, kvRead, updates); failure != nil || err != nil {
+			return failure, err
 		}
 	}
-	return true, nil
+	return nil, nil
 }
 
 // validateKVRead performs mvcc check for a key read during transaction simulation.
 // i.e., it checks whether a key/version combination is already updated in the statedb (by an already committed block)
 // or in the updates (by a preceding valid transaction in the current block)
-func (v *Validator) validateKVRead(ns string, kvRead *kvrwset.KVRead, updates *privacyenabledstate.PubUpdateBatch) (bool, error) {
+func (v *Validator) validateKVRead(ns string, kvRead *kvrwset.KVRead, updates *privacyenabledstate.PubUpdateBatch) (*ledger.ValidationFailure, error) {
 	//查看升级包中是否有与读值相同的key，若存在，则判断交易失效
 	if updates.Exists(ns, kvRead.Key) {
-		return false, nil
+		return readConflict(ns, "", kvRead.Key, updatedInBlock), nil
 	}
 	//状态数据库中key的版本
 	committedVersion, err := v.db.GetVersion(ns, kvRead.Key)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	logger.Debugf("Comparing versions for key [%s]: committed version=%#v and read version=%#v",
@@ -215,9 +241,9 @@ func (v *Validator) validateKVRead(ns string, kvRead *kvrwset.KVRead, updates *p
 	if !version.AreSame(committedVersion, rwsetutil.NewVersion(kvRead.Version)) {
 		logger.Debugf("Version mismatch for key [%s:%s]. Committed version = [%#v], Version in readSet [%#v]",
 			ns, kvRead.Key, committedVersion, kvRead.Version)
-		return false, nil
+		return readConflict(ns, "", kvRead.Key, versionMismatch(kvRead.Version, committedVersion)), nil
 	}
-	return true, nil
+	return nil, nil
 }
 
 ////////////////////////////////////////////////////////////////////////////////
@@ -228,14 +254,22 @@ func (v *Validator) validateKVRead(ns string, kvRead *kvrwset.KVRead, updates *p
 //范围读值的验证由于是一系列的值，这一系列的值既要与state中现存的版本号比较，也要与updates中已存在的key比较
 //还牵扯到可能范围读值中的一个key在updates和state中同时存在（这是这个key就存在两个版本号了）而选哪一个版本号来和范围读值中这个key的版本比较的问题，所以就稍显麻烦
 
-func (v *Validator) validateRangeQueries(ns string, rangeQueriesInfo []*kvrwset.RangeQueryInfo, updates *privacyenabledstate.PubUpdateBatch) (bool, error) {
+func (v *Validator) validateRangeQueries(ns string, rangeQueriesInfo []*kvrwset.RangeQueryInfo, updates *privacyenabledstate.PubUpdateBatch) (*ledger.ValidationFailure, error) {
 	//验证范围读集的方法就是循环调用rangeQueriesInfo，一一验证每个范围读值
 	for _, rqi := range rangeQueriesInfo {
 		if valid, err := v.validateRangeQuery(ns, rqi, updates); !valid || err != nil {
-			return valid, err
+			if err != nil {
+				return nil, err
+			}
+			return &ledger.ValidationFailure{
+				Code:      peer.TxValidationCode_PHANTOM_READ_CONFLICT,
+				Namespace: ns,
+				Key:       rqi.StartKey,
+				Message:   fmt.Sprintf("results of range query [%s, %s) changed", rqi.StartKey, rqi.EndKey),
+			}, nil
 		}
 	}
-	return true, nil
+	return nil, nil
 }
 
 // validateRangeQuery performs a phantom read check i.e., it
@@ -285,42 +319,44 @@ func (v *Validator) validateRangeQuery(ns string, rangeQueryInfo *kvrwset.RangeQ
 /////                 Validation of hashed read-set
 ////////////////////////////////////////////////////////////////////////////////
 func (v *Validator) validateNsHashedReadSets(ns string, collHashedRWSets []*rwsetutil.CollHashedRwSet,
-	updates *privacyenabledstate.HashedUpdateBatch) (bool, error) {
+	updates *privacyenabledstate.HashedUpdateBatch) (*ledger.ValidationFailure, error) {
 	for _, collHashedRWSet := range collHashedRWSets {
-		if valid, err := v.validateCollHashedReadSet(ns, collHashedRWSet.CollectionName, collHashedRWSet.HashedRwSet.HashedReads, updates); !valid || err != nil {
-			return valid, err
+		if failure, err := v.validateCollHashedReadSet(ns, collHashedRWSet.CollectionName, collHashedRWSet.HashedRwSet.HashedReads, updates); failure != nil || err != nil {
+			return failure, err
 		}
 	}
-	return true, nil
+	return nil, nil
 }
 
 func (v *Validator) validateCollHashedReadSet(ns, coll string, kvReadHashes []*kvrwset.KVReadHash,
-	updates *privacyenabledstate.HashedUpdateBatch) (bool, error) {
+	updates *privacyenabledstate.HashedUpdateBatch) (*ledger.ValidationFailure, error) {
 	for _, kvReadHash := range kvReadHashes {
-		if valid, err := v.validateKVReadHash(ns, coll, kvReadHash, updates); !valid || err != nil {
-			return valid, err
+		if failure, err := v.validateKVReadHash(ns, coll, kvReadHash, updates); failure != nil || err != nil {
+			return failure, err
 		}
 	}
-	return true, nil
+	return nil, nil
 }
 
 // validateKVReadHash performs mvcc check for a hash of a key that is present in the private data space
 // i.e., it checks whether a key/version combination is already updated in the statedb (by an already committed block)
-// or in the updates (by a preceding valid transaction in the current block)
+// or in the updates (by a preceding valid transaction in the current block).
+// The key of the failure is the hex encoded hash of the key.
 func (v *Validator) validateKVReadHash(ns, coll string, kvReadHash *kvrwset.KVReadHash,
-	updates *privacyenabledstate.HashedUpdateBatch) (bool, error) {
+	updates *privacyenabledstate.HashedUpdateBatch) (*ledger.ValidationFailure, error) {
+	keyHash := hex.EncodeToString(kvReadHash.KeyHash)
 	if updates.Contains(ns, coll, kvReadHash.KeyHash) {
-		return false, nil
+		return readConflict(ns, coll, keyHash, updatedInBlock), nil
 	}
 	committedVersion, err := v.db.GetKeyHashVersion(ns, coll, kvReadHash.KeyHash)
 	if err != nil {
-		return false, err
+		return nil, err
 	}
 
 	if !version.AreSame(committedVersion, rwsetutil.NewVersion(kvReadHash.Version)) {
 		logger.Debugf("Version mismatch for key hash [%s:%s:%#v]. Committed version = [%s], Version in hashedReadSet [%s]",
 			ns, coll, kvReadHash.KeyHash, committedVersion, kvReadHash.Version)
-		return false, nil
+		return readConflict(ns, coll, keyHash, versionMismatch(kvReadHash.Version, committedVersion)), nil
 	}
-	return true, nil
+	return nil, nil
 }
@@ -23,6 +23,7 @@ import (
 
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/statedb"
@@ -221,6 +222,58 @@ func TestValidator(t *testing.T) {
 	checkValidation(t, validator, getTestPubSimulationRWSet(t, rwsetBuilder4, rwsetBuilder5), []int{1})
 }
 
+func TestValidationFailures(t *testing.T) {
+	testDBEnv := privacyenabledstate.LevelDBCommonStorageTestEnv{}
+	testDBEnv.Init(t)
+	defer testDBEnv.Cleanup()
+	db := testDBEnv.GetDBHandle("TestDB")
+
+	batch := privacyenabledstate.NewUpdateBatch()
+	batch.PubUpdates.Put("ns1", "key1", []byte("value1"), version.NewHeight(1, 0))
+	batch.PubUpdates.Put("ns1", "key2", []byte("value2"), version.NewHeight(1, 1))
+	db.ApplyPrivacyAwareUpdates(batch, version.NewHeight(1, 1))
+
+	validator := NewValidator(db)
+
+	// a stale read, a read of a key written earlier in the block and a phantom read
+	rwsetBuilder1 := rwsetutil.NewRWSetBuilder()
+	rwsetBuilder1.AddToReadSet("ns1", "key1", version.NewHeight(1, 1))
+	rwsetBuilder2 := rwsetutil.NewRWSetBuilder()
+	rwsetBuilder2.AddToReadSet("ns1", "key2", version.NewHeight(1, 1))
+	rwsetBuilder2.AddToWriteSet("ns1", "key2", []byte("value2_new"))
+	rwsetBuilder3 := rwsetutil.NewRWSetBuilder()
+	rwsetBuilder3.AddToReadSet("ns1", "key2", version.NewHeight(1, 1))
+	rwsetBuilder4 := rwsetutil.NewRWSetBuilder()
+	rqi := &kvrwset.RangeQueryInfo{StartKey: "key1", EndKey: "key3", ItrExhausted: true}
+	rqi.SetRawReads([]*kvrwset.KVRead{rwsetutil.NewKVRead("key1", version.NewHeight(1, 0))})
+	rwsetBuilder4.AddToRangeQuerySet("ns1", rqi)
+
+	var txs []*valinternal.Transaction
+	for i, txRWSet := range getTestPubSimulationRWSet(t, rwsetBuilder1, rwsetBuilder2, rwsetBuilder3, rwsetBuilder4) {
+		txs = append(txs, &valinternal.Transaction{ID: fmt.Sprintf("txid-%d", i), IndexInBlock: i, RWSet: txRWSet})
+	}
+	_, err := validator.ValidateAndPrepareBatch(&valinternal.Block{Num: 2, Txs: txs}, true)
+	testutil.AssertNoError(t, err, "")
+
+	testutil.AssertEquals(t, txs[0].ValidationFailure, &ledger.ValidationFailure{
+		TxID:      "txid-0",
+		Code:      peer.TxValidationCode_MVCC_READ_CONFLICT,
+		Namespace: "ns1",
+		Key:       "key1",
+		Message:   "read version [1:1] differs from committed version [1:0]",
+	})
+	testutil.AssertNil(t, txs[1].ValidationFailure)
+	testutil.AssertEquals(t, txs[2].ValidationFailure, &ledger.ValidationFailure{
+		TxID:      "txid-2",
+		Code:      peer.TxValidationCode_MVCC_READ_CONFLICT,
+		Namespace: "ns1",
+		Key:       "key2",
+		Message:   "key updated by a preceding transaction of the block",
+	})
+	testutil.AssertEquals(t, txs[3].ValidationCode, peer.TxValidationCode_PHANTOM_READ_CONFLICT)
+	testutil.AssertEquals(t, txs[3].ValidationFailure.Message, "results of range query [key1, key3) changed")
+}
+
 func TestPhantomValidation(t *testing.T) {
 	testDBEnv := privacyenabledstate.LevelDBCommonStorageTestEnv{}
 	testDBEnv.Init(t)
@@ -60,6 +60,7 @@ func (impl *DefaultImpl) ValidateAndPrepareBatch(blockAndPvtdata *ledger.BlockAn
 	}
 	logger.Debug("postprocessing ProtoBlock...")
 	postprocessProtoBlock(block, internalBlock)
+	addValidationFailures(blockAndPvtdata, internalBlock)
 	logger.Debug("ValidateAndPrepareBatch() complete")
 	return &privacyenabledstate.UpdateBatch{
 		PubUpdates:  pubAndHashUpdates.PubUpdates,
@@ -10,6 +10,7 @@ import (
 	"bytes"
 	"fmt"
 
+	"github.com/hyperledger/fabric/core/common/encryptedtx"
 	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/customtx"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
@@ -130,6 +131,21 @@ func preprocessProtoBlock(txmgr txmgr.TxMgr, validateKVFunc func(key string, val
 				txsFilter.SetFlag(txIndex, peer.TxValidationCode_INVALID_OTHER_REASON)
 				continue
 			}
+		} else if txType == common.HeaderType_ENCRYPTED_TRANSACTION {
+			// the read-write set is extracted from the decrypted transaction,
+			// the block keeps the ciphertext
+			respPayload, err := decryptedAction(env)
+			if err != nil {
+				logger.Warningf("Channel [%s]: Block [%d] Transaction index [%d] TxId [%s]"+
+					" is malformed: %s", chdr.GetChannelId(), block.Header.Number, txIndex, chdr.GetTxId(), err)
+				txsFilter.SetFlag(txIndex, peer.TxValidationCode_INVALID_OTHER_REASON)
+				continue
+			}
+			txRWSet = &rwsetutil.TxRwSet{}
+			if err = txRWSet.FromProtoBytes(respPayload.Results); err != nil {
+				txsFilter.SetFlag(txIndex, peer.TxValidationCode_INVALID_OTHER_REASON)
+				continue
+			}
 		} else {
 			rwsetProto, err := processNonEndorserTx(env, chdr.TxId, txType, txmgr, !doMVCCValidation)
 			if _, ok := err.(*customtx.InvalidTxError); ok {
@@ -160,6 +176,17 @@ func preprocessProtoBlock(txmgr txmgr.TxMgr, validateKVFunc func(key string, val
 	return b, nil
 }
 
+// decryptedAction returns the chaincode action of the endorser transaction
+// carried encrypted by the given envelope.  It waits until the peer is able
+// to decrypt the transaction, an error is only returned for a malformed one.
+func decryptedAction(env *common.Envelope) (*peer.ChaincodeAction, error) {
+	opened, err := encryptedtx.OpenEnvelope(env)
+	if err != nil {
+		return nil, err
+	}
+	return utils.GetActionFromEnvelopeMsg(opened)
+}
+
 func processNonEndorserTx(txEnv *common.Envelope, txid string, txType common.HeaderType, txmgr txmgr.TxMgr, synchingState bool) (*rwset.TxReadWriteSet, error) {
 	logger.Debugf("Performing custom processing for transaction [txid=%s], [txType=%s]", txid, txType)
 	processor := customtx.GetProcessor(txType)
@@ -211,6 +238,20 @@ func postprocessProtoBlock(block *common.Block, validatedBlock *valinternal.Bloc
 	block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER] = txsFilter
 }
 
+// addValidationFailures adds the reasons of the transactions invalidated by the state
+// validation to the failures of the block
+func addValidationFailures(blockAndPvtdata *ledger.BlockAndPvtData, validatedBlock *valinternal.Block) {
+	for _, tx := range validatedBlock.Txs {
+		if tx.ValidationFailure == nil {
+			continue
+		}
+		if blockAndPvtdata.ValidationFailures == nil {
+			blockAndPvtdata.ValidationFailures = make(map[uint64]*ledger.ValidationFailure)
+		}
+		blockAndPvtdata.ValidationFailures[uint64(tx.IndexInBlock)] = tx.ValidationFailure
+	}
+}
+
 func addPvtRWSetToPvtUpdateBatch(pvtRWSet *rwsetutil.TxPvtRwSet, pvtUpdateBatch *privacyenabledstate.PvtUpdateBatch, ver *version.Height) {
 	for _, ns := range pvtRWSet.NsPvtRwSet {
 		for _, coll := range ns.CollPvtRwSets {
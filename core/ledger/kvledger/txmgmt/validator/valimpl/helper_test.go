@@ -151,6 +151,24 @@ func TestValidateAndPreparePvtBatch(t *testing.T) {
 	assert.Equal(t, expectedtxsFilter, block.Metadata.Metadata[common.BlockMetadataIndex_TRANSACTIONS_FILTER])
 }
 
+func TestAddValidationFailures(t *testing.T) {
+	failure := &ledger.ValidationFailure{TxID: "tx2", Code: peer.TxValidationCode_MVCC_READ_CONFLICT, Namespace: "ns1", Key: "key1"}
+	validatedBlock := &valinternal.Block{Num: 10, Txs: []*valinternal.Transaction{
+		{IndexInBlock: 0, ID: "tx1", ValidationCode: peer.TxValidationCode_VALID},
+		{IndexInBlock: 2, ID: "tx2", ValidationCode: peer.TxValidationCode_MVCC_READ_CONFLICT, ValidationFailure: failure},
+	}}
+
+	blockAndPvtdata := &ledger.BlockAndPvtData{}
+	addValidationFailures(blockAndPvtdata, validatedBlock)
+	assert.Equal(t, map[uint64]*ledger.ValidationFailure{2: failure}, blockAndPvtdata.ValidationFailures)
+
+	// the failures recorded by the validation of the endorsements are kept
+	endorsementFailure := &ledger.ValidationFailure{TxID: "tx0", Code: peer.TxValidationCode_ENDORSEMENT_POLICY_FAILURE}
+	blockAndPvtdata = &ledger.BlockAndPvtData{ValidationFailures: map[uint64]*ledger.ValidationFailure{1: endorsementFailure}}
+	addValidationFailures(blockAndPvtdata, validatedBlock)
+	assert.Equal(t, map[uint64]*ledger.ValidationFailure{1: endorsementFailure, 2: failure}, blockAndPvtdata.ValidationFailures)
+}
+
 func TestPreprocessProtoBlock(t *testing.T) {
 	allwaysValidKVfunc := func(key string, value []byte) error {
 		return nil
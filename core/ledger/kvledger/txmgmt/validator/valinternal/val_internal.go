@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package valinternal
 
 import (
+	"github.com/hyperledger/fabric/core/ledger"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/privacyenabledstate"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/rwsetutil"
 	"github.com/hyperledger/fabric/core/ledger/kvledger/txmgmt/version"
@@ -27,12 +28,14 @@ type Block struct {
 }
 
 // Transaction is used to hold the information from its proto format to a structure
-// that is more suitable/friendly for validation
+// that is more suitable/friendly for validation.  ValidationFailure holds the reason
+// the transaction was invalidated, if known
 type Transaction struct {
-	IndexInBlock   int
-	ID             string
-	RWSet          *rwsetutil.TxRwSet
-	ValidationCode peer.TxValidationCode
+	IndexInBlock      int
+	ID                string
+	RWSet             *rwsetutil.TxRwSet
+	ValidationCode    peer.TxValidationCode
+	ValidationFailure *ledger.ValidationFailure
 }
 
 // PubAndHashUpdates encapsulates public and hash updates. The intended use of this to hold the updates
@@ -198,11 +198,42 @@ type MissingPrivateData struct {
 }
 
 // BlockAndPvtData encapsulates the block and a map that contains the tuples <seqInBlock, *TxPvtData>
-// The map is expected to contain the entries only for the transactions that has associated pvt data
+// The map is expected to contain the entries only for the transactions that has associated pvt data.
+// ValidationFailures holds the reasons of the transactions invalidated while the block is validated,
+// by index of the transaction in the block
 type BlockAndPvtData struct {
-	Block        *common.Block
-	BlockPvtData map[uint64]*TxPvtData
-	Missing      []MissingPrivateData
+	Block              *common.Block
+	BlockPvtData       map[uint64]*TxPvtData
+	Missing            []MissingPrivateData
+	ValidationFailures map[uint64]*ValidationFailure
+}
+
+// ValidationFailure is the reason a transaction was marked invalid at commit, beyond its validation code.
+// Depending on the check that failed, it names the validation plugin and the endorsement policy
+// of the namespace that rejected the endorsements, or the key whose read conflicted
+type ValidationFailure struct {
+	TxID       string                `json:"txId"`
+	Code       peer.TxValidationCode `json:"code"`
+	Plugin     string                `json:"plugin,omitempty"`
+	Namespace  string                `json:"namespace,omitempty"`
+	Policy     string                `json:"policy,omitempty"`
+	Collection string                `json:"collection,omitempty"`
+	Key        string                `json:"key,omitempty"`
+	Message    string                `json:"message,omitempty"`
+}
+
+// ValidationFailureRecorder is implemented by the ledgers that keep the reasons of the invalid transactions.
+// The validator of the blocks records there the failures of a block before the block is committed
+type ValidationFailureRecorder interface {
+	// RecordValidationFailures records the failures of the given block, by index of the transaction in the block
+	RecordValidationFailures(blockNum uint64, failures map[uint64]*ValidationFailure)
+}
+
+// ValidationFailureRetriever is implemented by the ledgers that keep the reasons of the invalid transactions
+type ValidationFailureRetriever interface {
+	// GetValidationFailureByTxID returns the reason the transaction was marked invalid, or nil if it is valid.
+	// A failure carrying only the validation code is returned if no more is known about it
+	GetValidationFailureByTxID(txID string) (*ValidationFailure, error)
 }
 
 // PvtCollFilter represents the set of the collection names (as keys of the map with value 'true')
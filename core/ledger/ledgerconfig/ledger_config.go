@@ -8,6 +8,7 @@ package ledgerconfig
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/hyperledger/fabric/core/config"
 	"github.com/spf13/viper"
@@ -36,6 +37,12 @@ const confEnableHistoryDatabase = "ledger.history.enableHistoryDatabase"
 const confMaxBatchSize = "ledger.state.couchDBConfig.maxBatchUpdateSize"
 const confAutoWarmIndexes = "ledger.state.couchDBConfig.autoWarmIndexes"
 const confWarmIndexesAfterNBlocks = "ledger.state.couchDBConfig.warmIndexesAfterNBlocks"
+const confFingerprintHistory = "ledger.state.fingerprintHistory"
+const confStateMigration = "stateMigration"
+const confMigrationTarget = "ledger.state.migration.targetDatabase"
+const confMigrationVerifyInterval = "ledger.state.migration.verifyInterval"
+const confMigrationBatchSize = "ledger.state.migration.batchSize"
+const confMigrationAutoCutover = "ledger.state.migration.autoCutover"
 
 // GetRootPath returns the filesystem path.
 // All ledger related contents are expected to be stored under this path
@@ -151,3 +158,55 @@ func GetWarmIndexesAfterNBlocks() int {
 	}
 	return warmAfterNBlocks
 }
+
+// GetStateFingerprintHistory returns the number of most recent blocks whose
+// state fingerprint is kept for every channel
+func GetStateFingerprintHistory() int {
+	history := viper.GetInt(confFingerprintHistory)
+	if history <= 0 {
+		history = 1000
+	}
+	return history
+}
+
+// GetStateMigrationTarget returns the state database the state is migrated
+// to, "goleveldb" or "CouchDB", or an empty string if no migration is
+// configured
+func GetStateMigrationTarget() string {
+	return viper.GetString(confMigrationTarget)
+}
+
+// GetStateMigrationPath returns the filesystem path that is used to record the
+// channels whose state database was migrated
+func GetStateMigrationPath() string {
+	return filepath.Join(GetRootPath(), confStateMigration)
+}
+
+// GetStateMigrationVerifyInterval returns the interval between the passes
+// comparing the state databases migrated from and to
+func GetStateMigrationVerifyInterval() time.Duration {
+	interval := viper.GetDuration(confMigrationVerifyInterval)
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	return interval
+}
+
+// GetStateMigrationBatchSize returns the number of keys compared, and copied
+// if they differ, at once while migrating the state database
+func GetStateMigrationBatchSize() int {
+	batchSize := viper.GetInt(confMigrationBatchSize)
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+	return batchSize
+}
+
+// IsStateMigrationAutoCutoverEnabled returns whether the state is served from
+// the state database migrated to once both databases are found to match
+func IsStateMigrationAutoCutoverEnabled() bool {
+	if viper.IsSet(confMigrationAutoCutover) {
+		return viper.GetBool(confMigrationAutoCutover)
+	}
+	return true
+}
@@ -18,6 +18,7 @@ package ledgerconfig
 
 import (
 	"testing"
+	"time"
 
 	"github.com/hyperledger/fabric/common/ledger/testutil"
 	ledgertestutil "github.com/hyperledger/fabric/core/ledger/testutil"
@@ -167,6 +168,20 @@ func TestPvtdataStorePurgeInterval(t *testing.T) {
 	testutil.AssertEquals(t, updatedValue, uint64(1000)) //test config returns 1000
 }
 
+func TestGetStateFingerprintHistoryUnset(t *testing.T) {
+	viper.Reset()
+	defaultValue := GetStateFingerprintHistory()
+	testutil.AssertEquals(t, defaultValue, 1000) // 1000 if fingerprintHistory is not set
+}
+
+func TestGetStateFingerprintHistory(t *testing.T) {
+	setUpCoreYAMLConfig()
+	defer ledgertestutil.ResetConfigToDefaultValues()
+	viper.Set("ledger.state.fingerprintHistory", 50)
+	updatedValue := GetStateFingerprintHistory()
+	testutil.AssertEquals(t, updatedValue, 50) //test config returns 50
+}
+
 func TestIsHistoryDBEnabledDefault(t *testing.T) {
 	setUpCoreYAMLConfig()
 	defaultValue := IsHistoryDBEnabled()
@@ -237,6 +252,36 @@ func TestGetWarmIndexesAfterNBlocks(t *testing.T) {
 	testutil.AssertEquals(t, updatedValue, 10)
 }
 
+func TestStateMigrationDefault(t *testing.T) {
+	setUpCoreYAMLConfig()
+	testutil.AssertEquals(t, GetStateMigrationTarget(), "")
+	testutil.AssertEquals(t, GetStateMigrationVerifyInterval(), time.Minute)
+	testutil.AssertEquals(t, GetStateMigrationBatchSize(), 1000)
+	testutil.AssertEquals(t, IsStateMigrationAutoCutoverEnabled(), true)
+}
+
+func TestStateMigrationUnset(t *testing.T) {
+	viper.Reset()
+	testutil.AssertEquals(t, GetStateMigrationVerifyInterval(), time.Minute)
+	testutil.AssertEquals(t, GetStateMigrationBatchSize(), 1000)
+	testutil.AssertEquals(t, IsStateMigrationAutoCutoverEnabled(), true)
+}
+
+func TestStateMigration(t *testing.T) {
+	setUpCoreYAMLConfig()
+	defer ledgertestutil.ResetConfigToDefaultValues()
+	viper.Set("peer.fileSystemPath", "/tmp/hyperledger/production")
+	viper.Set("ledger.state.migration.targetDatabase", "CouchDB")
+	viper.Set("ledger.state.migration.verifyInterval", "10s")
+	viper.Set("ledger.state.migration.batchSize", 50)
+	viper.Set("ledger.state.migration.autoCutover", false)
+	testutil.AssertEquals(t, GetStateMigrationTarget(), "CouchDB")
+	testutil.AssertEquals(t, GetStateMigrationPath(), "/tmp/hyperledger/production/ledgersData/stateMigration")
+	testutil.AssertEquals(t, GetStateMigrationVerifyInterval(), 10*time.Second)
+	testutil.AssertEquals(t, GetStateMigrationBatchSize(), 50)
+	testutil.AssertEquals(t, IsStateMigrationAutoCutoverEnabled(), false)
+}
+
 func TestGetMaxBlockfileSize(t *testing.T) {
 	testutil.AssertEquals(t, GetMaxBlockfileSize(), 67108864)
 }
@@ -170,6 +170,33 @@ func (l *closableLedger) closeWithoutLock() {
 	delete(openedLedgers, l.id)
 }
 
+// ReplayState replays the state of the actual ledger, if it supports replaying its state
+func (l *closableLedger) ReplayState(startBlockNum uint64) error {
+	replayer, ok := l.PeerLedger.(interface {
+		ReplayState(startBlockNum uint64) error
+	})
+	if !ok {
+		return fmt.Errorf("ledger [%s] does not support replaying its state", l.id)
+	}
+	return replayer.ReplayState(startBlockNum)
+}
+
+// RecordValidationFailures passes the failures on to the actual ledger, if it keeps them
+func (l *closableLedger) RecordValidationFailures(blockNum uint64, failures map[uint64]*ledger.ValidationFailure) {
+	if recorder, ok := l.PeerLedger.(ledger.ValidationFailureRecorder); ok {
+		recorder.RecordValidationFailures(blockNum, failures)
+	}
+}
+
+// GetValidationFailureByTxID retrieves the failure from the actual ledger, if it keeps them
+func (l *closableLedger) GetValidationFailureByTxID(txID string) (*ledger.ValidationFailure, error) {
+	retriever, ok := l.PeerLedger.(ledger.ValidationFailureRetriever)
+	if !ok {
+		return nil, fmt.Errorf("ledger [%s] does not keep the validation failures", l.id)
+	}
+	return retriever.GetValidationFailureByTxID(txID)
+}
+
 // lscc namespace listener for chaincode instantiate transactions (which manipulates data in 'lscc' namespace)
 // this code should be later moved to peer and passed via `Initialize` function of ledgermgmt
 func addListenerForCCEventsHandler(stateListeners []ledger.StateListener) []ledger.StateListener {
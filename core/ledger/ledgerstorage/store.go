@@ -51,6 +51,12 @@ type Store struct {
 // NewProvider returns the handle to the provider
 func NewProvider() *Provider {
 	// Initialize the block storage
+	blockStoreProvider := newBlockStoreProvider()
+	pvtStoreProvider := pvtdatastorage.NewProvider()
+	return &Provider{blockStoreProvider, pvtStoreProvider}
+}
+
+func newBlockStoreProvider() *fsblkstorage.FsBlockstoreProvider {
 	attrsToIndex := []blkstorage.IndexableAttr{
 		blkstorage.IndexableAttrBlockHash,
 		blkstorage.IndexableAttrBlockNum,
@@ -60,12 +66,50 @@ func NewProvider() *Provider {
 		blkstorage.IndexableAttrTxValidationCode,
 	}
 	indexConfig := &blkstorage.IndexConfig{AttrsToIndex: attrsToIndex}
-	blockStoreProvider := fsblkstorage.NewProvider(
+	return fsblkstorage.NewProvider(
 		fsblkstorage.NewConf(ledgerconfig.GetBlockStorePath(), ledgerconfig.GetMaxBlockfileSize()),
-		indexConfig)
+		indexConfig).(*fsblkstorage.FsBlockstoreProvider)
+}
 
-	pvtStoreProvider := pvtdatastorage.NewProvider()
-	return &Provider{blockStoreProvider, pvtStoreProvider}
+// openBlockStoreProvider opens the block store provider, reporting as an error the panic
+// raised when the index database is locked by another process
+func openBlockStoreProvider() (provider *fsblkstorage.FsBlockstoreProvider, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("error opening the block storage, make sure the peer is stopped: %s", r)
+		}
+	}()
+	return newBlockStoreProvider(), nil
+}
+
+// RebuildBlockIndexes rebuilds the block store indexes of the given ledgers from their block files,
+// or of all the ledgers if none is given, and then compacts the index database.  When compactOnly
+// is set the indexes are only compacted.  This opens the block storage exclusively, so it fails
+// while the peer is running
+func RebuildBlockIndexes(compactOnly bool, ledgerIDs ...string) error {
+	provider, err := openBlockStoreProvider()
+	if err != nil {
+		return err
+	}
+	defer provider.Close()
+
+	if !compactOnly {
+		if len(ledgerIDs) == 0 {
+			if ledgerIDs, err = provider.List(); err != nil {
+				return err
+			}
+		}
+		for _, ledgerID := range ledgerIDs {
+			if err := provider.RebuildIndex(ledgerID); err != nil {
+				return err
+			}
+		}
+	}
+	logger.Info("Compacting the block index database")
+	if err := provider.CompactIndex(); err != nil {
+		return fmt.Errorf("error compacting the block index database: %s", err)
+	}
+	return nil
 }
 
 // Open opens the store
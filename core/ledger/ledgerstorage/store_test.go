@@ -146,6 +146,44 @@ func TestStoreWithExistingBlockchain(t *testing.T) {
 	assert.Equal(t, uint64(10), pvtdataBlockHt)
 }
 
+func TestRebuildBlockIndexes(t *testing.T) {
+	testEnv := newTestEnv(t)
+	defer testEnv.cleanup()
+	provider := NewProvider()
+	store, err := provider.Open("testLedger")
+	assert.NoError(t, err)
+	store.Init(btlPolicyForSampleData())
+	sampleData := sampleDataWithPvtdataForSelectiveTx(t)
+	for _, sampleDatum := range sampleData {
+		assert.NoError(t, store.CommitWithPvtData(sampleDatum))
+	}
+
+	err = RebuildBlockIndexes(false)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "make sure the peer is stopped")
+	store.Shutdown()
+	provider.Close()
+
+	assert.NoError(t, RebuildBlockIndexes(false))
+	assert.NoError(t, RebuildBlockIndexes(true))
+	assert.EqualError(t, RebuildBlockIndexes(false, "testLedger", "otherLedger"), "ledger [otherLedger] does not exist")
+
+	provider = NewProvider()
+	defer provider.Close()
+	store, err = provider.Open("testLedger")
+	assert.NoError(t, err)
+	store.Init(btlPolicyForSampleData())
+	defer store.Shutdown()
+	for _, sampleDatum := range sampleData {
+		block, err := store.RetrieveBlockByHash(sampleDatum.Block.Header.Hash())
+		assert.NoError(t, err)
+		assert.Equal(t, sampleDatum.Block, block)
+	}
+	blockAndPvtdata, err := store.GetPvtDataAndBlockByNum(2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, sampleData[2], blockAndPvtdata)
+}
+
 func TestCrashAfterPvtdataStorePreparation(t *testing.T) {
 	testEnv := newTestEnv(t)
 	defer testEnv.cleanup()
@@ -0,0 +1,104 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statediff
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// HTTPSource is a Source served by the Handler of a peer
+type HTTPSource struct {
+	// URL is the URL the handler is registered at on the operations
+	// endpoint of the peer, such as https://peer0:9443/ledger/state/
+	URL string
+
+	// Client is the client used to reach the peer
+	Client *http.Client
+}
+
+// Summarize implements Source
+func (s *HTTPSource) Summarize(channelID string, req Request) (*Summary, error) {
+	params := url.Values{
+		"channel":   {channelID},
+		"namespace": {req.Namespace},
+		"start":     {hex.EncodeToString([]byte(req.Start))},
+		"end":       {hex.EncodeToString([]byte(req.End))},
+		"fanout":    {strconv.Itoa(req.Fanout)},
+		"maxKeys":   {strconv.Itoa(req.MaxKeys)},
+		"listKeys":  {strconv.FormatBool(req.ListKeys)},
+	}
+	summary := &Summary{}
+	if _, err := s.do(http.MethodGet, SummaryPath, params, summary); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// LastWrite implements Source
+func (s *HTTPSource) LastWrite(channelID, namespace string, key []byte) (uint64, bool, error) {
+	params := url.Values{
+		"channel":   {channelID},
+		"namespace": {namespace},
+		"key":       {hex.EncodeToString(key)},
+	}
+	resp := &LastWriteResponse{}
+	status, err := s.do(http.MethodGet, LastWritePath, params, resp)
+	if status == http.StatusNotFound {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return resp.BlockNumber, true, nil
+}
+
+// Replay implements Source
+func (s *HTTPSource) Replay(channelID string, startBlockNum uint64) error {
+	params := url.Values{
+		"channel": {channelID},
+		"from":    {strconv.FormatUint(startBlockNum, 10)},
+	}
+	_, err := s.do(http.MethodPost, ReplayPath, params, nil)
+	return err
+}
+
+// do performs the operation, decoding the response into v unless it is nil
+func (s *HTTPSource) do(method, operation string, params url.Values, v interface{}) (int, error) {
+	u := strings.TrimSuffix(s.URL, "/") + "/" + operation + "?" + params.Encode()
+	req, err := http.NewRequest(method, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, errors.Wrapf(err, "failed reaching %s", s.URL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, errors.Errorf("%s %s failed with status %d: %s", method, operation, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	if v == nil {
+		return resp.StatusCode, nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return resp.StatusCode, errors.Wrapf(err, "failed decoding response of %s", s.URL)
+	}
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,222 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statediff
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// lsccNamespace holds a key for every chaincode instantiated on the channel,
+// named after the chaincode which is also the name of its namespace
+const lsccNamespace = "lscc"
+
+// Source serves the state of a peer on a channel
+type Source interface {
+	// Summarize summarizes the keys selected by the request
+	Summarize(channelID string, req Request) (*Summary, error)
+
+	// LastWrite returns the number of the last block which wrote the key,
+	// and false if the key was never written
+	LastWrite(channelID, namespace string, key []byte) (uint64, bool, error)
+
+	// Replay replays the blocks from the start block to the state database
+	Replay(channelID string, startBlockNum uint64) error
+}
+
+// Difference is a key whose value differs between the two peers
+type Difference struct {
+	Namespace string `json:"namespace"`
+	Key       []byte `json:"key"`
+
+	// Left and Right are the hashes of the values on each peer, nil if the
+	// key is missing on the peer
+	Left  []byte `json:"left"`
+	Right []byte `json:"right"`
+}
+
+func (d *Difference) String() string {
+	switch {
+	case d.Left == nil:
+		return fmt.Sprintf("%s %q: missing on left peer", d.Namespace, d.Key)
+	case d.Right == nil:
+		return fmt.Sprintf("%s %q: missing on right peer", d.Namespace, d.Key)
+	default:
+		return fmt.Sprintf("%s %q: values differ, left hash %x, right hash %x", d.Namespace, d.Key, d.Left, d.Right)
+	}
+}
+
+// Options tune the comparison
+type Options struct {
+	// Fanout is the number of sub-ranges a range is split into
+	Fanout int
+
+	// MaxKeys is the number of keys up to which a range is compared key by key
+	MaxKeys int
+}
+
+// Comparer compares the state of two peers on a channel
+type Comparer struct {
+	Left      Source
+	Right     Source
+	ChannelID string
+	Options   Options
+
+	height uint64
+}
+
+// Namespaces returns the namespaces of the chaincodes instantiated on either
+// peer, along with the lscc namespace itself
+func (c *Comparer) Namespaces() ([]string, error) {
+	set := map[string]struct{}{lsccNamespace: {}}
+	for _, source := range []Source{c.Left, c.Right} {
+		summary, err := source.Summarize(c.ChannelID, Request{Namespace: lsccNamespace, ListKeys: true})
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed listing the chaincodes")
+		}
+		for _, k := range summary.Keys {
+			set[string(k.Key)] = struct{}{}
+		}
+	}
+	var namespaces []string
+	for ns := range set {
+		namespaces = append(namespaces, ns)
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// Compare returns the keys of the namespace whose values differ between the
+// peers, failing if the peers are not at the same height or if the height of
+// either changes during the comparison.
+func (c *Comparer) Compare(namespace string) ([]*Difference, error) {
+	return c.compareRange(namespace, "", "")
+}
+
+func (c *Comparer) compareRange(namespace, start, end string) ([]*Difference, error) {
+	req := Request{
+		Namespace: namespace,
+		Start:     start,
+		End:       end,
+		Fanout:    c.Options.Fanout,
+		MaxKeys:   c.Options.MaxKeys,
+	}
+	left, right, err := c.summarize(req)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(left.Hash, right.Hash) {
+		return nil, nil
+	}
+
+	if left.Keys == nil || right.Keys == nil {
+		if left.Keys != nil || right.Keys != nil {
+			// One of the peers holds few keys in the range, so listing
+			// the keys of both is cheaper than splitting further
+			req.ListKeys = true
+			if left, right, err = c.summarize(req); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if left.Keys != nil && right.Keys != nil {
+		return diffKeys(namespace, left.Keys, right.Keys), nil
+	}
+
+	var differences []*Difference
+	for _, r := range left.Ranges {
+		rangeDifferences, err := c.compareRange(namespace, string(r.Start), string(r.End))
+		if err != nil {
+			return nil, err
+		}
+		differences = append(differences, rangeDifferences...)
+	}
+	return differences, nil
+}
+
+// summarize summarizes the range on both peers, checking their heights
+func (c *Comparer) summarize(req Request) (*Summary, *Summary, error) {
+	left, err := c.Left.Summarize(c.ChannelID, req)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed summarizing state of left peer")
+	}
+	right, err := c.Right.Summarize(c.ChannelID, req)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed summarizing state of right peer")
+	}
+	if left.Height != right.Height {
+		return nil, nil, errors.Errorf("peers are at different heights, left at %d and right at %d", left.Height, right.Height)
+	}
+	if c.height == 0 {
+		c.height = left.Height
+	}
+	if left.Height != c.height {
+		return nil, nil, errors.Errorf("peers moved from height %d to %d during the comparison", c.height, left.Height)
+	}
+	return left, right, nil
+}
+
+// Height returns the height the peers were compared at
+func (c *Comparer) Height() uint64 {
+	return c.height
+}
+
+// diffKeys merges the sorted key hashes of both peers into the differences
+func diffKeys(namespace string, left, right []KeyHash) []*Difference {
+	var differences []*Difference
+	i, j := 0, 0
+	for i < len(left) || j < len(right) {
+		switch {
+		case j == len(right) || (i < len(left) && bytes.Compare(left[i].Key, right[j].Key) < 0):
+			differences = append(differences, &Difference{Namespace: namespace, Key: left[i].Key, Left: left[i].Hash})
+			i++
+		case i == len(left) || bytes.Compare(left[i].Key, right[j].Key) > 0:
+			differences = append(differences, &Difference{Namespace: namespace, Key: right[j].Key, Right: right[j].Hash})
+			j++
+		default:
+			if !bytes.Equal(left[i].Hash, right[j].Hash) {
+				differences = append(differences, &Difference{Namespace: namespace, Key: left[i].Key, Left: left[i].Hash, Right: right[j].Hash})
+			}
+			i++
+			j++
+		}
+	}
+	return differences
+}
+
+// RepairStart returns the first block to replay on the target peer for the
+// differing keys to be restored to their values on the reference peer, that
+// is the earliest block among the last writes of the keys on the reference
+// peer.  A key missing on the reference peer is looked up on the target peer,
+// and the repair fails if a key was never written on either peer.
+func RepairStart(reference, target Source, channelID string, differences []*Difference) (uint64, error) {
+	if len(differences) == 0 {
+		return 0, errors.New("no differences to repair")
+	}
+	var start uint64
+	for i, d := range differences {
+		blockNum, found, err := reference.LastWrite(channelID, d.Namespace, d.Key)
+		if err != nil {
+			return 0, errors.WithMessage(err, "failed looking up last write on reference peer")
+		}
+		if !found {
+			if blockNum, found, err = target.LastWrite(channelID, d.Namespace, d.Key); err != nil {
+				return 0, errors.WithMessage(err, "failed looking up last write on target peer")
+			}
+		}
+		if !found {
+			return 0, errors.Errorf("cannot find the block which last wrote %s %q on either peer", d.Namespace, d.Key)
+		}
+		if i == 0 || blockNum < start {
+			start = blockNum
+		}
+	}
+	return start, nil
+}
@@ -0,0 +1,194 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statediff
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+
+	commonledger "github.com/hyperledger/fabric/common/ledger"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// state is an in memory state implementing RangeScanner and Source
+type state struct {
+	height     uint64
+	namespaces map[string]map[string][]byte
+	lastWrites map[string]uint64
+	replayed   []uint64
+	summaries  int
+}
+
+func newState(height uint64) *state {
+	return &state{
+		height:     height,
+		namespaces: map[string]map[string][]byte{},
+		lastWrites: map[string]uint64{},
+	}
+}
+
+func (s *state) put(ns, key string, value string) {
+	if s.namespaces[ns] == nil {
+		s.namespaces[ns] = map[string][]byte{}
+	}
+	s.namespaces[ns][key] = []byte(value)
+}
+
+func (s *state) GetStateRangeScanIterator(namespace string, startKey string, endKey string) (commonledger.ResultsIterator, error) {
+	var keys []string
+	for key := range s.namespaces[namespace] {
+		if key >= startKey && (endKey == "" || key < endKey) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	itr := &iterator{}
+	for _, key := range keys {
+		itr.results = append(itr.results, &queryresult.KV{Namespace: namespace, Key: key, Value: s.namespaces[namespace][key]})
+	}
+	return itr, nil
+}
+
+func (s *state) Summarize(channelID string, req Request) (*Summary, error) {
+	s.summaries++
+	summary, err := Summarize(s, req)
+	if err != nil {
+		return nil, err
+	}
+	summary.Height = s.height
+	return summary, nil
+}
+
+func (s *state) LastWrite(channelID, namespace string, key []byte) (uint64, bool, error) {
+	blockNum, ok := s.lastWrites[namespace+"/"+string(key)]
+	return blockNum, ok, nil
+}
+
+func (s *state) Replay(channelID string, startBlockNum uint64) error {
+	s.replayed = append(s.replayed, startBlockNum)
+	return nil
+}
+
+type iterator struct {
+	results []commonledger.QueryResult
+}
+
+func (itr *iterator) Next() (commonledger.QueryResult, error) {
+	if len(itr.results) == 0 {
+		return nil, nil
+	}
+	res := itr.results[0]
+	itr.results = itr.results[1:]
+	return res, nil
+}
+
+func (itr *iterator) Close() {}
+
+func TestSummarize(t *testing.T) {
+	s := newState(10)
+	for i := 0; i < 10; i++ {
+		s.put("ns", fmt.Sprintf("key%d", i), fmt.Sprintf("value%d", i))
+	}
+
+	summary, err := Summarize(s, Request{Namespace: "ns", Fanout: 3, MaxKeys: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 10, summary.Count)
+	assert.Len(t, summary.Keys, 10)
+	assert.Nil(t, summary.Ranges)
+
+	split, err := Summarize(s, Request{Namespace: "ns", Start: "a", End: "z", Fanout: 3, MaxKeys: 2})
+	require.NoError(t, err)
+	assert.Equal(t, summary.Hash, split.Hash, "the hash should not depend on how the range is summarized")
+	assert.Nil(t, split.Keys)
+	require.Len(t, split.Ranges, 3)
+	assert.Equal(t, Range{Start: []byte("a"), End: []byte("key4"), Count: 4, Hash: hashKeys(summary.Keys[0:4])}, split.Ranges[0])
+	assert.Equal(t, Range{Start: []byte("key4"), End: []byte("key8"), Count: 4, Hash: hashKeys(summary.Keys[4:8])}, split.Ranges[1])
+	assert.Equal(t, Range{Start: []byte("key8"), End: []byte("z"), Count: 2, Hash: hashKeys(summary.Keys[8:10])}, split.Ranges[2])
+
+	listed, err := Summarize(s, Request{Namespace: "ns", MaxKeys: 2, ListKeys: true})
+	require.NoError(t, err)
+	assert.Len(t, listed.Keys, 10)
+
+	_, err = Summarize(s, Request{Namespace: "ns", Fanout: 1})
+	assert.EqualError(t, err, "fanout must be at least 2, got 1")
+}
+
+func TestCompare(t *testing.T) {
+	left, right := newState(42), newState(42)
+	for i := 0; i < 1000; i++ {
+		key, value := fmt.Sprintf("key%04d", i), fmt.Sprintf("value%d", i)
+		left.put("mycc", key, value)
+		right.put("mycc", key, value)
+	}
+	left.put("lscc", "mycc", "")
+	right.put("lscc", "mycc", "")
+	right.put("lscc", "othercc", "")
+
+	c := &Comparer{Left: left, Right: right, ChannelID: "ch", Options: Options{Fanout: 4, MaxKeys: 16}}
+	namespaces, err := c.Namespaces()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lscc", "mycc", "othercc"}, namespaces)
+
+	differences, err := c.Compare("mycc")
+	require.NoError(t, err)
+	assert.Empty(t, differences)
+	assert.Equal(t, uint64(42), c.Height())
+
+	right.put("mycc", "key0500", "corrupted")
+	right.put("mycc", "key0500a", "extra")
+	delete(right.namespaces["mycc"], "key0999")
+	left.summaries = 0
+	differences, err = c.Compare("mycc")
+	require.NoError(t, err)
+	require.Len(t, differences, 3)
+	assert.Equal(t, []byte("key0500"), differences[0].Key)
+	assert.NotNil(t, differences[0].Left)
+	assert.NotNil(t, differences[0].Right)
+	assert.Equal(t, []byte("key0500a"), differences[1].Key)
+	assert.Nil(t, differences[1].Left)
+	assert.Equal(t, []byte("key0999"), differences[2].Key)
+	assert.Nil(t, differences[2].Right)
+	assert.Contains(t, differences[1].String(), "missing on left peer")
+	assert.True(t, left.summaries < 50, "the comparison should only descend into the differing ranges, took %d summaries", left.summaries)
+
+	right.height = 43
+	_, err = c.Compare("mycc")
+	assert.EqualError(t, err, "peers are at different heights, left at 42 and right at 43")
+	left.height = 43
+	_, err = c.Compare("mycc")
+	assert.EqualError(t, err, "peers moved from height 42 to 43 during the comparison")
+}
+
+func TestRepairStart(t *testing.T) {
+	reference, target := newState(10), newState(10)
+	reference.lastWrites["mycc/a"] = 7
+	reference.lastWrites["mycc/b"] = 4
+	target.lastWrites["mycc/c"] = 3
+
+	start, err := RepairStart(reference, target, "ch", []*Difference{
+		{Namespace: "mycc", Key: []byte("a")},
+		{Namespace: "mycc", Key: []byte("b")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), start)
+
+	start, err = RepairStart(reference, target, "ch", []*Difference{
+		{Namespace: "mycc", Key: []byte("a")},
+		{Namespace: "mycc", Key: []byte("c")},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), start, "keys missing on the reference peer should be looked up on the target peer")
+
+	_, err = RepairStart(reference, target, "ch", []*Difference{{Namespace: "mycc", Key: []byte("d")}})
+	assert.EqualError(t, err, `cannot find the block which last wrote mycc "d" on either peer`)
+
+	_, err = RepairStart(reference, target, "ch", nil)
+	assert.EqualError(t, err, "no differences to repair")
+}
@@ -0,0 +1,243 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statediff
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	"github.com/pkg/errors"
+)
+
+var logger = flogging.MustGetLogger("statediff")
+
+// The operations served under the path the handler is registered at
+const (
+	SummaryPath   = "summary"
+	LastWritePath = "lastwrite"
+	ReplayPath    = "replay"
+)
+
+// LedgerGetter returns the ledger of a channel, or nil if the peer has not
+// joined the channel
+type LedgerGetter func(channelID string) ledger.PeerLedger
+
+// StateReplayer is implemented by the ledgers whose state can be replayed
+// from their blocks
+type StateReplayer interface {
+	ReplayState(startBlockNum uint64) error
+}
+
+// LastWriteResponse is the response to a last write lookup
+type LastWriteResponse struct {
+	BlockNumber uint64 `json:"block_number"`
+}
+
+// Handler serves the state of the ledgers of a peer for comparison with
+// another peer.  It is meant to be registered at a path ending with a slash,
+// and serves:
+//
+//	GET  summary?channel=&namespace=&start=&end=&fanout=&maxKeys=&listKeys=
+//	GET  lastwrite?channel=&namespace=&key=
+//	POST replay?channel=&from=
+//
+// the keys being hex encoded.  Replaying is refused unless allowed, as it
+// rewrites the state database.
+type Handler struct {
+	ledgers     LedgerGetter
+	allowReplay bool
+}
+
+// NewHandler creates a Handler
+func NewHandler(ledgers LedgerGetter, allowReplay bool) *Handler {
+	return &Handler{
+		ledgers:     ledgers,
+		allowReplay: allowReplay,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	operation := path.Base(r.URL.Path)
+	method := http.MethodGet
+	if operation == ReplayPath {
+		method = http.MethodPost
+	}
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	query := r.URL.Query()
+	channelID := query.Get("channel")
+	l := h.ledgers(channelID)
+	if l == nil {
+		http.Error(w, "channel not found: "+channelID, http.StatusNotFound)
+		return
+	}
+
+	var err error
+	switch operation {
+	case SummaryPath:
+		err = h.summarize(w, l, query.Get)
+	case LastWritePath:
+		err = h.lastWrite(w, l, query.Get)
+	case ReplayPath:
+		err = h.replay(w, l, channelID, query.Get)
+	default:
+		http.Error(w, "unknown operation: "+operation, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if e, ok := errors.Cause(err).(*httpError); ok {
+			status = e.status
+		}
+		http.Error(w, err.Error(), status)
+	}
+}
+
+func (h *Handler) summarize(w http.ResponseWriter, l ledger.PeerLedger, param func(string) string) error {
+	req := Request{Namespace: param("namespace")}
+	var err error
+	if req.Start, err = hexParam(param, "start"); err != nil {
+		return err
+	}
+	if req.End, err = hexParam(param, "end"); err != nil {
+		return err
+	}
+	if req.Fanout, err = intParam(param, "fanout"); err != nil {
+		return err
+	}
+	if req.MaxKeys, err = intParam(param, "maxKeys"); err != nil {
+		return err
+	}
+	req.ListKeys = param("listKeys") == "true"
+
+	// The query executor holds off the commit of the state, so the height
+	// read while it is held matches the state being summarized unless a
+	// block is between its commit to the block storage and to the state
+	qe, err := l.NewQueryExecutor()
+	if err != nil {
+		return err
+	}
+	defer qe.Done()
+	info, err := l.GetBlockchainInfo()
+	if err != nil {
+		return err
+	}
+	summary, err := Summarize(qe, req)
+	if err != nil {
+		return badRequest(err)
+	}
+	summary.Height = info.Height
+	return writeJSON(w, summary)
+}
+
+func (h *Handler) lastWrite(w http.ResponseWriter, l ledger.PeerLedger, param func(string) string) error {
+	key, err := hexParam(param, "key")
+	if err != nil {
+		return err
+	}
+	hqe, err := l.NewHistoryQueryExecutor()
+	if err != nil {
+		return err
+	}
+	itr, err := hqe.GetHistoryForKey(param("namespace"), key)
+	if err != nil {
+		return err
+	}
+	defer itr.Close()
+
+	// The history is ordered from the oldest to the latest write
+	var last *queryresult.KeyModification
+	for {
+		res, err := itr.Next()
+		if err != nil {
+			return err
+		}
+		if res == nil {
+			break
+		}
+		last = res.(*queryresult.KeyModification)
+	}
+	if last == nil {
+		return &httpError{status: http.StatusNotFound, msg: "key was never written"}
+	}
+	block, err := l.GetBlockByTxID(last.TxId)
+	if err != nil {
+		return errors.WithMessage(err, "failed retrieving block of transaction "+last.TxId)
+	}
+	return writeJSON(w, &LastWriteResponse{BlockNumber: block.Header.Number})
+}
+
+func (h *Handler) replay(w http.ResponseWriter, l ledger.PeerLedger, channelID string, param func(string) string) error {
+	if !h.allowReplay {
+		return &httpError{status: http.StatusForbidden, msg: "replaying the state is not allowed by the peer configuration"}
+	}
+	replayer, ok := l.(StateReplayer)
+	if !ok {
+		return &httpError{status: http.StatusNotImplemented, msg: "the ledger does not support replaying its state"}
+	}
+	from, err := strconv.ParseUint(param("from"), 10, 64)
+	if err != nil {
+		return badRequest(errors.Errorf("invalid start block: %s", param("from")))
+	}
+	logger.Warningf("Channel [%s]: Replaying the state from block [%d] as requested on the operations endpoint", channelID, from)
+	if err := replayer.ReplayState(from); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusOK)
+	return nil
+}
+
+type httpError struct {
+	status int
+	msg    string
+}
+
+func (e *httpError) Error() string {
+	return e.msg
+}
+
+func badRequest(err error) error {
+	return &httpError{status: http.StatusBadRequest, msg: err.Error()}
+}
+
+func hexParam(param func(string) string, name string) (string, error) {
+	value, err := hex.DecodeString(param(name))
+	if err != nil {
+		return "", badRequest(errors.Errorf("invalid %s: expected hex encoding", name))
+	}
+	return string(value), nil
+}
+
+func intParam(param func(string) string, name string) (int, error) {
+	if param(name) == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(param(name))
+	if err != nil {
+		return 0, badRequest(errors.Errorf("invalid %s: %s", name, param(name)))
+	}
+	return value, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warningf("Failed writing response: %s", err)
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package statediff
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	commonledger "github.com/hyperledger/fabric/common/ledger"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeLedger struct {
+	ledger.PeerLedger
+	state    *state
+	history  map[string][]string
+	txBlocks map[string]uint64
+}
+
+type fakeQueryExecutor struct {
+	ledger.QueryExecutor
+	state *state
+}
+
+func (qe *fakeQueryExecutor) GetStateRangeScanIterator(namespace string, startKey string, endKey string) (commonledger.ResultsIterator, error) {
+	return qe.state.GetStateRangeScanIterator(namespace, startKey, endKey)
+}
+
+func (qe *fakeQueryExecutor) Done() {}
+
+type fakeHistoryQueryExecutor struct {
+	history map[string][]string
+}
+
+func (hqe *fakeHistoryQueryExecutor) GetHistoryForKey(namespace string, key string) (commonledger.ResultsIterator, error) {
+	itr := &iterator{}
+	for _, txID := range hqe.history[namespace+"/"+key] {
+		itr.results = append(itr.results, &queryresult.KeyModification{TxId: txID})
+	}
+	return itr, nil
+}
+
+func (l *fakeLedger) NewQueryExecutor() (ledger.QueryExecutor, error) {
+	return &fakeQueryExecutor{state: l.state}, nil
+}
+
+func (l *fakeLedger) NewHistoryQueryExecutor() (ledger.HistoryQueryExecutor, error) {
+	return &fakeHistoryQueryExecutor{history: l.history}, nil
+}
+
+func (l *fakeLedger) GetBlockchainInfo() (*common.BlockchainInfo, error) {
+	return &common.BlockchainInfo{Height: l.state.height}, nil
+}
+
+func (l *fakeLedger) GetBlockByTxID(txID string) (*common.Block, error) {
+	blockNum, ok := l.txBlocks[txID]
+	if !ok {
+		return nil, errors.New("transaction not found")
+	}
+	return common.NewBlock(blockNum, nil), nil
+}
+
+func (l *fakeLedger) ReplayState(startBlockNum uint64) error {
+	return l.state.Replay("", startBlockNum)
+}
+
+func newServer(l *fakeLedger, allowReplay bool) (*httptest.Server, *HTTPSource) {
+	mux := http.NewServeMux()
+	mux.Handle("/ledger/state/", NewHandler(func(channelID string) ledger.PeerLedger {
+		if channelID != "ch" {
+			return nil
+		}
+		return l
+	}, allowReplay))
+	server := httptest.NewServer(mux)
+	return server, &HTTPSource{URL: server.URL + "/ledger/state/", Client: server.Client()}
+}
+
+func TestHandler(t *testing.T) {
+	s := newState(5)
+	s.put("mycc", "a", "1")
+	s.put("mycc", "b\x00c", "2")
+	l := &fakeLedger{
+		state:    s,
+		history:  map[string][]string{"mycc/a": {"tx1", "tx2"}},
+		txBlocks: map[string]uint64{"tx1": 1, "tx2": 3},
+	}
+	server, source := newServer(l, false)
+	defer server.Close()
+
+	summary, err := source.Summarize("ch", Request{Namespace: "mycc", ListKeys: true})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), summary.Height)
+	require.Len(t, summary.Keys, 2)
+	assert.Equal(t, []byte("b\x00c"), summary.Keys[1].Key, "keys should not be mangled on the way")
+
+	_, err = source.Summarize("ch", Request{Namespace: "mycc", Fanout: 1})
+	assert.Contains(t, err.Error(), "failed with status 400: fanout must be at least 2, got 1")
+
+	_, err = source.Summarize("other", Request{Namespace: "mycc", ListKeys: true})
+	assert.Contains(t, err.Error(), "failed with status 404: channel not found: other")
+
+	blockNum, found, err := source.LastWrite("ch", "mycc", []byte("a"))
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, uint64(3), blockNum)
+
+	_, found, err = source.LastWrite("ch", "mycc", []byte("z"))
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	err = source.Replay("ch", 3)
+	assert.Contains(t, err.Error(), "failed with status 403")
+	assert.Empty(t, s.replayed)
+
+	resp, err := server.Client().Get(server.URL + "/ledger/state/replay?channel=ch&from=3")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
+
+func TestHandlerReplay(t *testing.T) {
+	s := newState(5)
+	server, source := newServer(&fakeLedger{state: s}, true)
+	defer server.Close()
+
+	require.NoError(t, source.Replay("ch", 3))
+	assert.Equal(t, []uint64{3}, s.replayed)
+
+	resp, err := server.Client().Post(server.URL+"/ledger/state/replay?channel=ch&from=x", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
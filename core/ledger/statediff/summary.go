@@ -0,0 +1,162 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package statediff finds the keys whose public state differs between two
+// peers of a channel at the same height.  Each peer summarizes a range of keys
+// of a namespace by a hash, and splits the range into sub-ranges of a similar
+// number of keys when the range is too large to list.  Comparing the
+// summaries of both peers, and recursing into the sub-ranges whose hashes
+// differ, narrows the divergence down to the differing keys while exchanging
+// hashes only for the ranges that agree.
+package statediff
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+
+	commonledger "github.com/hyperledger/fabric/common/ledger"
+	"github.com/hyperledger/fabric/protos/ledger/queryresult"
+	"github.com/pkg/errors"
+)
+
+// Request selects the keys of a namespace from Start included to End
+// excluded, an empty End selecting the keys up to the last one.
+type Request struct {
+	Namespace string
+	Start     string
+	End       string
+
+	// Fanout is the number of sub-ranges the range is split into, if it
+	// holds more than MaxKeys keys
+	Fanout int
+
+	// MaxKeys is the number of keys up to which the range is summarized by
+	// the hashes of its keys rather than by sub-ranges
+	MaxKeys int
+
+	// ListKeys requests the hashes of the keys whatever their number
+	ListKeys bool
+}
+
+// KeyHash is the hash of the value of a key
+type KeyHash struct {
+	Key  []byte `json:"key"`
+	Hash []byte `json:"hash"`
+}
+
+// Range is the summary of a sub-range of keys, from Start included to End
+// excluded, an empty End covering the keys up to the end of the range
+type Range struct {
+	Start []byte `json:"start"`
+	End   []byte `json:"end"`
+	Count int    `json:"count"`
+	Hash  []byte `json:"hash"`
+}
+
+// Summary summarizes the keys selected by a request
+type Summary struct {
+	// Height is the height of the ledger the summary was computed at
+	Height uint64 `json:"height"`
+
+	// Count is the number of keys in the range
+	Count int `json:"count"`
+
+	// Hash is the hash of the keys and values in the range, in key order
+	Hash []byte `json:"hash"`
+
+	// Keys are the hashes of the keys in the range, set if the range holds
+	// less than MaxKeys keys or if they were requested
+	Keys []KeyHash `json:"keys,omitempty"`
+
+	// Ranges tile the range with the sub-ranges it was split into, set if
+	// the keys are not
+	Ranges []Range `json:"ranges,omitempty"`
+}
+
+// RangeScanner scans the state of a namespace, such as a ledger.QueryExecutor
+type RangeScanner interface {
+	GetStateRangeScanIterator(namespace string, startKey string, endKey string) (commonledger.ResultsIterator, error)
+}
+
+// Summarize summarizes the keys selected by the request
+func Summarize(scanner RangeScanner, req Request) (*Summary, error) {
+	if !req.ListKeys && req.Fanout < 2 {
+		return nil, errors.Errorf("fanout must be at least 2, got %d", req.Fanout)
+	}
+
+	itr, err := scanner.GetStateRangeScanIterator(req.Namespace, req.Start, req.End)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed scanning state")
+	}
+	defer itr.Close()
+
+	var keys []KeyHash
+	for {
+		res, err := itr.Next()
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed scanning state")
+		}
+		if res == nil {
+			break
+		}
+		kv := res.(*queryresult.KV)
+		keys = append(keys, KeyHash{Key: []byte(kv.Key), Hash: hashKey(kv.Key, kv.Value)})
+	}
+
+	summary := &Summary{Count: len(keys), Hash: hashKeys(keys)}
+	if req.ListKeys || len(keys) <= req.MaxKeys {
+		summary.Keys = keys
+		return summary, nil
+	}
+
+	// Split into ranges of step keys, the first and the last range being
+	// extended to the bounds of the request so that the keys the other peer
+	// holds outside of the keys of this peer are covered
+	step := (len(keys) + req.Fanout - 1) / req.Fanout
+	for i := 0; i < len(keys); i += step {
+		j := i + step
+		if j > len(keys) {
+			j = len(keys)
+		}
+		r := Range{Start: keys[i].Key, Count: j - i, Hash: hashKeys(keys[i:j])}
+		if i == 0 {
+			r.Start = []byte(req.Start)
+		}
+		if j < len(keys) {
+			r.End = keys[j].Key
+		} else {
+			r.End = []byte(req.End)
+		}
+		summary.Ranges = append(summary.Ranges, r)
+	}
+	return summary, nil
+}
+
+// hashKey hashes a key with its value
+func hashKey(key string, value []byte) []byte {
+	h := sha256.New()
+	writeField(h, []byte(key))
+	writeField(h, value)
+	return h.Sum(nil)
+}
+
+// hashKeys hashes the hashes of the keys in order
+func hashKeys(keys []KeyHash) []byte {
+	h := sha256.New()
+	for _, k := range keys {
+		h.Write(k.Hash)
+	}
+	return h.Sum(nil)
+}
+
+// writeField writes the field prefixed with its length, so that the
+// boundaries between the fields are unambiguous
+func writeField(h hash.Hash, field []byte) {
+	length := make([]byte, binary.MaxVarintLen64)
+	h.Write(length[:binary.PutUvarint(length, uint64(len(field)))])
+	h.Write(field)
+}
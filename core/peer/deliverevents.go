@@ -22,6 +22,7 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/deliver"
 	"github.com/hyperledger/fabric/common/flogging"
+	commonutil "github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/aclmgmt/resources"
 	"github.com/hyperledger/fabric/core/ledger/util"
 	"github.com/hyperledger/fabric/protos/common"
@@ -48,11 +49,59 @@ type PolicyCheckerProvider func(resourceName string) deliver.PolicyCheckerFunc
 type server struct {
 	dh                    *deliver.Handler
 	policyCheckerProvider PolicyCheckerProvider
+	coalescing            coalescingConfig
+	metrics               *DeliverEventsMetrics
+}
+
+// coalescingConfig configures how filtered blocks are coalesced for clients
+// lagging behind the ledger
+type coalescingConfig struct {
+	// lagThreshold is the number of blocks a client lags behind from which
+	// its filtered blocks are merged, zero disabling the coalescing
+	lagThreshold uint64
+	// maxBlocks is the maximum number of blocks merged into a single response
+	maxBlocks int
+}
+
+// lagRecorder records how many requested blocks a client lags behind the ledger
+type lagRecorder struct {
+	metrics  *DeliverEventsMetrics
+	client   string
+	channels map[string]struct{}
+}
+
+func newLagRecorder(metrics *DeliverEventsMetrics, client string) *lagRecorder {
+	return &lagRecorder{
+		metrics:  metrics,
+		client:   client,
+		channels: map[string]struct{}{},
+	}
+}
+
+// record records the lag of the client when the block is sent and returns
+// the channel of the block
+func (lr *lagRecorder) record(block *common.Block, behind uint64) string {
+	channelID, err := utils.GetChainIDFromBlock(block)
+	if err != nil {
+		logger.Debugf("Failed to get channel of block [%d]: %s", block.Header.Number, err)
+		return ""
+	}
+	lr.channels[channelID] = struct{}{}
+	lr.metrics.clientLagged(channelID, lr.client, behind)
+	return channelID
+}
+
+// close removes the lag of the client once its stream ends
+func (lr *lagRecorder) close() {
+	for channelID := range lr.channels {
+		lr.metrics.clientGone(channelID, lr.client)
+	}
 }
 
 // blockResponseSender structure used to send block responses
 type blockResponseSender struct {
 	peer.Deliver_DeliverServer
+	*lagRecorder
 }
 
 // SendStatusResponse generates status reply proto message
@@ -65,18 +114,36 @@ func (brs *blockResponseSender) SendStatusResponse(status common.Status) error {
 
 // SendBlockResponse generates deliver response with block message
 func (brs *blockResponseSender) SendBlockResponse(block *common.Block) error {
+	return brs.SendBlockResponseBehind(block, 0)
+}
+
+// SendBlockResponseBehind generates deliver response with block message and
+// records the lag of the client
+func (brs *blockResponseSender) SendBlockResponseBehind(block *common.Block, behind uint64) error {
+	brs.record(block, behind)
 	response := &peer.DeliverResponse{
 		Type: &peer.DeliverResponse_Block{Block: block},
 	}
 	return brs.Send(response)
 }
 
-// filteredBlockResponseSender structure used to send filtered block responses
+// filteredBlockResponseSender structure used to send filtered block responses.
+// While the client lags behind the ledger, the filtered blocks are merged into
+// a summary carrying the number of the latest block and the transactions of
+// all the merged blocks, rather than being sent one by one.
 type filteredBlockResponseSender struct {
 	peer.Deliver_DeliverFilteredServer
+	*lagRecorder
+	coalescing    coalescingConfig
+	pending       *peer.FilteredBlock
+	pendingBlocks int
+	channelID     string
 }
 
 func (fbrs *filteredBlockResponseSender) SendStatusResponse(status common.Status) error {
+	if err := fbrs.flush(); err != nil {
+		return err
+	}
 	response := &peer.DeliverResponse{
 		Type: &peer.DeliverResponse_Status{Status: status},
 	}
@@ -85,6 +152,14 @@ func (fbrs *filteredBlockResponseSender) SendStatusResponse(status common.Status
 
 // SendBlockResponse generates deliver response with block message
 func (fbrs *filteredBlockResponseSender) SendBlockResponse(block *common.Block) error {
+	return fbrs.SendBlockResponseBehind(block, 0)
+}
+
+// SendBlockResponseBehind generates deliver response with filtered block
+// message, coalescing the filtered blocks while the client lags behind
+func (fbrs *filteredBlockResponseSender) SendBlockResponseBehind(block *common.Block, behind uint64) error {
+	fbrs.channelID = fbrs.record(block, behind)
+
 	// Generates filtered block response
 	b := blockEvent(*block)
 	filteredBlock, err := b.toFilteredBlock()
@@ -92,6 +167,48 @@ func (fbrs *filteredBlockResponseSender) SendBlockResponse(block *common.Block)
 		logger.Warningf("Failed to generate filtered block due to: %s", err)
 		return fbrs.SendStatusResponse(common.Status_BAD_REQUEST)
 	}
+
+	threshold := fbrs.coalescing.lagThreshold
+	if threshold == 0 || (fbrs.pending == nil && behind < threshold) {
+		return fbrs.sendFilteredBlock(filteredBlock)
+	}
+
+	fbrs.coalesce(filteredBlock)
+	if behind < threshold || fbrs.pendingBlocks >= fbrs.coalescing.maxBlocks {
+		return fbrs.flush()
+	}
+	return nil
+}
+
+// coalesce merges the filtered block into the pending one
+func (fbrs *filteredBlockResponseSender) coalesce(filteredBlock *peer.FilteredBlock) {
+	fbrs.pendingBlocks++
+	if fbrs.pending == nil {
+		fbrs.pending = filteredBlock
+		return
+	}
+	fbrs.pending.Number = filteredBlock.Number
+	if filteredBlock.ChannelId != "" {
+		fbrs.pending.ChannelId = filteredBlock.ChannelId
+	}
+	fbrs.pending.FilteredTransactions = append(fbrs.pending.FilteredTransactions, filteredBlock.FilteredTransactions...)
+}
+
+// flush sends the pending filtered block, if any
+func (fbrs *filteredBlockResponseSender) flush() error {
+	if fbrs.pending == nil {
+		return nil
+	}
+	filteredBlock, coalesced := fbrs.pending, fbrs.pendingBlocks-1
+	fbrs.pending, fbrs.pendingBlocks = nil, 0
+	if coalesced > 0 {
+		logger.Debugf("Coalesced %d filtered blocks up to block [%d] for lagging client %s", coalesced+1, filteredBlock.Number, fbrs.client)
+		fbrs.metrics.blocksCoalesced(fbrs.channelID, coalesced)
+	}
+	return fbrs.sendFilteredBlock(filteredBlock)
+}
+
+func (fbrs *filteredBlockResponseSender) sendFilteredBlock(filteredBlock *peer.FilteredBlock) error {
 	response := &peer.DeliverResponse{
 		Type: &peer.DeliverResponse_FilteredBlock{FilteredBlock: filteredBlock},
 	}
@@ -109,12 +226,16 @@ type blockEvent common.Block
 func (s *server) DeliverFiltered(srv peer.Deliver_DeliverFilteredServer) error {
 	logger.Debugf("Starting new DeliverFiltered handler")
 	defer dumpStacktraceOnPanic()
+	lag := newLagRecorder(s.metrics, commonutil.ExtractRemoteAddress(srv.Context()))
+	defer lag.close()
 	// getting policy checker based on resources.Event_FilteredBlock resource name
 	deliverServer := &deliver.Server{
 		Receiver:      srv,
 		PolicyChecker: s.policyCheckerProvider(resources.Event_FilteredBlock),
 		ResponseSender: &filteredBlockResponseSender{
 			Deliver_DeliverFilteredServer: srv,
+			lagRecorder:                   lag,
+			coalescing:                    s.coalescing,
 		},
 	}
 	return s.dh.Handle(srv.Context(), deliverServer)
@@ -124,12 +245,15 @@ func (s *server) DeliverFiltered(srv peer.Deliver_DeliverFilteredServer) error {
 func (s *server) Deliver(srv peer.Deliver_DeliverServer) (err error) {
 	logger.Debugf("Starting new Deliver handler")
 	defer dumpStacktraceOnPanic()
+	lag := newLagRecorder(s.metrics, commonutil.ExtractRemoteAddress(srv.Context()))
+	defer lag.close()
 	// getting policy checker based on resources.Event_Block resource name
 	deliverServer := &deliver.Server{
 		PolicyChecker: s.policyCheckerProvider(resources.Event_Block),
 		Receiver:      srv,
 		ResponseSender: &blockResponseSender{
 			Deliver_DeliverServer: srv,
+			lagRecorder:           lag,
 		},
 	}
 	return s.dh.Handle(srv.Context(), deliverServer)
@@ -137,7 +261,7 @@ func (s *server) Deliver(srv peer.Deliver_DeliverServer) (err error) {
 
 // NewDeliverEventsServer creates a peer.Deliver server to deliver block and
 // filtered block events
-func NewDeliverEventsServer(mutualTLS bool, policyCheckerProvider PolicyCheckerProvider, chainManager deliver.ChainManager) peer.DeliverServer {
+func NewDeliverEventsServer(mutualTLS bool, policyCheckerProvider PolicyCheckerProvider, chainManager deliver.ChainManager, metrics *DeliverEventsMetrics) peer.DeliverServer {
 	timeWindow := viper.GetDuration("peer.authentication.timewindow")
 	if timeWindow == 0 {
 		defaultTimeWindow := 15 * time.Minute
@@ -145,9 +269,25 @@ func NewDeliverEventsServer(mutualTLS bool, policyCheckerProvider PolicyCheckerP
 		timeWindow = defaultTimeWindow
 	}
 	return &server{
-		dh: deliver.NewHandler(chainManager, timeWindow, mutualTLS),
+		dh:                    deliver.NewHandler(chainManager, timeWindow, mutualTLS),
 		policyCheckerProvider: policyCheckerProvider,
+		coalescing:            getCoalescingConfig(),
+		metrics:               metrics,
+	}
+}
+
+// getCoalescingConfig reads the coalescing of filtered blocks from the peer configuration
+func getCoalescingConfig() coalescingConfig {
+	conf := coalescingConfig{
+		lagThreshold: uint64(viper.GetInt("peer.events.coalescing.lagThreshold")),
+		maxBlocks:    viper.GetInt("peer.events.coalescing.maxBlocks"),
+	}
+	if conf.lagThreshold > 0 && conf.maxBlocks <= 0 {
+		defaultMaxBlocks := 100
+		logger.Warningf("`peer.events.coalescing.maxBlocks` not set; defaulting to %d", defaultMaxBlocks)
+		conf.maxBlocks = defaultMaxBlocks
 	}
+	return conf
 }
 
 func (s *server) sendProducer(srv peer.Deliver_DeliverFilteredServer) func(msg proto.Message) error {
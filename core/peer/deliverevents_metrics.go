@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package peer
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	deliverMetricsNamespace = "peer"
+	deliverMetricsSubsystem = "deliver"
+)
+
+// DeliverEventsMetrics are the Prometheus metrics of the deliver events
+// service.  The series labeled with a client are removed when the stream of
+// the client ends.  A nil DeliverEventsMetrics records nothing.
+type DeliverEventsMetrics struct {
+	clientLag *prometheus.GaugeVec
+	coalesced *prometheus.CounterVec
+}
+
+// NewDeliverEventsMetrics creates the metrics of the deliver events service
+// and registers them with the registerer.
+func NewDeliverEventsMetrics(registerer prometheus.Registerer) (*DeliverEventsMetrics, error) {
+	m := &DeliverEventsMetrics{
+		clientLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: deliverMetricsNamespace,
+			Subsystem: deliverMetricsSubsystem,
+			Name:      "client_lag_blocks",
+			Help:      "The number of requested blocks committed but not yet sent to a deliver client, by channel and client.",
+		}, []string{"channel", "client"}),
+		coalesced: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: deliverMetricsNamespace,
+			Subsystem: deliverMetricsSubsystem,
+			Name:      "blocks_coalesced_total",
+			Help:      "The number of filtered blocks merged into a later one for lagging clients, by channel.",
+		}, []string{"channel"}),
+	}
+	for _, c := range []prometheus.Collector{m.clientLag, m.coalesced} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *DeliverEventsMetrics) clientLagged(channelID, client string, behind uint64) {
+	if m == nil {
+		return
+	}
+	m.clientLag.WithLabelValues(channelID, client).Set(float64(behind))
+}
+
+func (m *DeliverEventsMetrics) clientGone(channelID, client string) {
+	if m == nil {
+		return
+	}
+	m.clientLag.DeleteLabelValues(channelID, client)
+}
+
+func (m *DeliverEventsMetrics) blocksCoalesced(channelID string, count int) {
+	if m == nil {
+		return
+	}
+	m.coalesced.WithLabelValues(channelID).Add(float64(count))
+}
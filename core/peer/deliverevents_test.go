@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package peer
 
 import (
+	"fmt"
 	"io"
 	"sync"
 	"testing"
@@ -20,6 +21,7 @@ import (
 	"github.com/hyperledger/fabric/protos/orderer"
 	"github.com/hyperledger/fabric/protos/peer"
 	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -371,7 +373,7 @@ func TestEventsServer_DeliverFiltered(t *testing.T) {
 			wg := &sync.WaitGroup{}
 			chainManager, deliverServer := test.prepare(wg)
 
-			server := NewDeliverEventsServer(false, defaultPolicyCheckerProvider, chainManager)
+			server := NewDeliverEventsServer(false, defaultPolicyCheckerProvider, chainManager, nil)
 			err := server.DeliverFiltered(deliverServer)
 			wg.Wait()
 			// no error expected
@@ -379,6 +381,87 @@ func TestEventsServer_DeliverFiltered(t *testing.T) {
 		})
 	}
 }
+func TestFilteredBlockResponseSenderCoalescing(t *testing.T) {
+	var blocks []*common.Block
+	for i := 0; i < 6; i++ {
+		payload, err := createEndorsement("testChainID", fmt.Sprintf("tx%d", i), nil)
+		assert.NoError(t, err)
+		block, err := createTestBlock([]*common.Envelope{{Payload: utils.MarshalOrPanic(payload)}})
+		assert.NoError(t, err)
+		block.Header.Number = uint64(i)
+		blocks = append(blocks, block)
+	}
+
+	var sent []*peer.DeliverResponse
+	deliverServer := &mockDeliverServer{}
+	deliverServer.On("Send", mock.Anything).Run(func(args mock.Arguments) {
+		sent = append(sent, args.Get(0).(*peer.DeliverResponse))
+	}).Return(nil)
+
+	registry := prometheus.NewRegistry()
+	metrics, err := NewDeliverEventsMetrics(registry)
+	assert.NoError(t, err)
+	lag := newLagRecorder(metrics, "client")
+	sender := &filteredBlockResponseSender{
+		Deliver_DeliverFilteredServer: deliverServer,
+		lagRecorder:                   lag,
+		coalescing:                    coalescingConfig{lagThreshold: 2, maxBlocks: 2},
+	}
+
+	// blocks 0 and 1 are 5 and 4 blocks behind, so they are merged up to
+	// the maximum, blocks 2 and 3 are merged until the client catches up
+	// and block 4 is sent as is
+	for i, behind := range []uint64{5, 4, 3, 1, 0} {
+		assert.NoError(t, sender.SendBlockResponseBehind(blocks[i], behind))
+	}
+	lagSamples := gatherMetric(t, registry, "peer_deliver_client_lag_blocks")
+	assert.Equal(t, []float64{0}, lagSamples)
+
+	// a pending block is sent ahead of the status
+	assert.NoError(t, sender.SendBlockResponseBehind(blocks[5], 3))
+	assert.NoError(t, sender.SendStatusResponse(common.Status_SUCCESS))
+
+	var numbers []uint64
+	var txIDs [][]string
+	for _, response := range sent[:len(sent)-1] {
+		filteredBlock := response.GetFilteredBlock()
+		assert.Equal(t, "testChainID", filteredBlock.ChannelId)
+		numbers = append(numbers, filteredBlock.Number)
+		var ids []string
+		for _, tx := range filteredBlock.FilteredTransactions {
+			ids = append(ids, tx.Txid)
+		}
+		txIDs = append(txIDs, ids)
+	}
+	assert.Equal(t, []uint64{1, 3, 4, 5}, numbers)
+	assert.Equal(t, [][]string{{"tx0", "tx1"}, {"tx2", "tx3"}, {"tx4"}, {"tx5"}}, txIDs)
+	assert.Equal(t, common.Status_SUCCESS, sent[len(sent)-1].GetStatus())
+	assert.Equal(t, []float64{2}, gatherMetric(t, registry, "peer_deliver_blocks_coalesced_total"))
+
+	lag.close()
+	assert.Empty(t, gatherMetric(t, registry, "peer_deliver_client_lag_blocks"))
+}
+
+// gatherMetric returns the values of the gauge or counter series of the family
+func gatherMetric(t *testing.T, registry *prometheus.Registry, name string) []float64 {
+	families, err := registry.Gather()
+	assert.NoError(t, err)
+	var values []float64
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, m := range family.Metric {
+			if m.Gauge != nil {
+				values = append(values, m.Gauge.GetValue())
+			} else {
+				values = append(values, m.Counter.GetValue())
+			}
+		}
+	}
+	return values
+}
+
 func createDefaultSupportMamangerMock(config testConfig, chaincodeActionPayload *peer.ChaincodeActionPayload) *mockChainManager {
 	chainManager := &mockChainManager{}
 	iter := &mockIterator{}
@@ -20,6 +20,7 @@ import (
 	commonledger "github.com/hyperledger/fabric/common/ledger"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
 	fileledger "github.com/hyperledger/fabric/common/ledger/blockledger/file"
+	"github.com/hyperledger/fabric/common/ordererendpoints"
 	"github.com/hyperledger/fabric/common/policies"
 	"github.com/hyperledger/fabric/core/comm"
 	"github.com/hyperledger/fabric/core/committer"
@@ -304,6 +305,7 @@ func createChain(cid string, ledger ledger.PeerLedger, cb *common.Block, ccp ccp
 	gossipEventer := service.GetGossipService().NewConfigEventer()
 
 	gossipCallbackWrapper := func(bundle *channelconfig.Bundle) {
+		updateOrdererEndpoints(bundle)
 		ac, ok := bundle.ApplicationConfig()
 		if !ok {
 			// TODO, handle a missing ApplicationConfig more gracefully
@@ -479,6 +481,17 @@ func GetCurrConfigBlock(cid string) *common.Block {
 	return nil
 }
 
+// records the orderer endpoints advertised for the channel, so that the
+// delivery client prefers the orderers of the peer's region
+func updateOrdererEndpoints(bundle *channelconfig.Bundle) {
+	var orderer channelconfig.Orderer
+	if oc, ok := bundle.OrdererConfig(); ok {
+		orderer = oc
+	}
+	endpoints := ordererendpoints.FromConfig(orderer, bundle.ChannelConfig().OrdererAddresses())
+	ordererendpoints.Update(bundle.ConfigtxValidator().ChainID(), endpoints)
+}
+
 // updates the trusted roots for the peer based on updates to channels
 func updateTrustedRoots(cm channelconfig.Resources) {
 	// this is triggered on per channel basis so first update the roots for the channel
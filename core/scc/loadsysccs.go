@@ -12,6 +12,7 @@ import (
 	"plugin"
 	"sync"
 
+	"github.com/hyperledger/fabric/common/pluginabi"
 	"github.com/hyperledger/fabric/common/viperutil"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
 	"github.com/pkg/errors"
@@ -66,6 +67,9 @@ func loadPlugin(path string) *shim.Chaincode {
 	if _, err := os.Stat(path); err != nil {
 		panic(fmt.Errorf("Could not find plugin at path %s: %s", path, err))
 	}
+	if err := pluginabi.Check(path); err != nil {
+		panic(fmt.Errorf("Refusing to load plugin at path %s: %s", path, err))
+	}
 
 	p, err := plugin.Open(path)
 	if err != nil {
@@ -140,3 +140,10 @@ type PrivateChannelDataNotAvailable string
 func (f PrivateChannelDataNotAvailable) Error() string {
 	return "as V1_2 or later capability is not enabled, private channel collections and data are not available"
 }
+
+// ChaincodeLimitsNotAvailable when V1_3 or later capability is not enabled
+type ChaincodeLimitsNotAvailable string
+
+func (f ChaincodeLimitsNotAvailable) Error() string {
+	return "as V1_3 or later capability is not enabled, chaincode limits are not available"
+}
@@ -542,6 +542,7 @@ func (lscc *lifeCycleSysCC) executeDeployOrUpgrade(
 	chainname string,
 	cds *pb.ChaincodeDeploymentSpec,
 	policy, escc, vscc, collectionConfigBytes []byte,
+	limits *ccprovider.ChaincodeLimits,
 	function string,
 ) (*ccprovider.ChaincodeData, error) {
 
@@ -563,6 +564,7 @@ func (lscc *lifeCycleSysCC) executeDeployOrUpgrade(
 		return nil, fmt.Errorf("%s", retErrMsg)
 	}
 	cd := ccpack.GetChaincodeData()
+	cd.Limits = limits
 
 	switch function {
 	case DEPLOY:
@@ -776,7 +778,10 @@ func (lscc *lifeCycleSysCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response
 		if !ac.Capabilities().PrivateChannelData() && len(args) > 6 {
 			return shim.Error(PrivateChannelDataNotAvailable("").Error())
 		}
-		if ac.Capabilities().PrivateChannelData() && len(args) > 7 {
+		if ac.Capabilities().PrivateChannelData() && !ac.Capabilities().ChaincodeLimits() && len(args) > 7 {
+			return shim.Error(ChaincodeLimitsNotAvailable("").Error())
+		}
+		if ac.Capabilities().ChaincodeLimits() && len(args) > 8 {
 			return shim.Error(InvalidArgsLenErr(len(args)).Error())
 		}
 
@@ -791,6 +796,7 @@ func (lscc *lifeCycleSysCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response
 		// args[4] is the name of escc
 		// args[5] is the name of vscc
 		// args[6] is a marshalled CollectionConfigPackage struct
+		// args[7] is a marshalled ChaincodeLimits struct
 		var EP []byte
 		if len(args) > 3 && len(args[3]) > 0 {
 			EP = args[3]
@@ -823,7 +829,15 @@ func (lscc *lifeCycleSysCC) Invoke(stub shim.ChaincodeStubInterface) pb.Response
 			collectionsConfig = args[6]
 		}
 
-		cd, err := lscc.executeDeployOrUpgrade(stub, channel, cds, EP, escc, vscc, collectionsConfig, function)
+		var limits *ccprovider.ChaincodeLimits
+		if ac.Capabilities().ChaincodeLimits() && len(args) > 7 && len(args[7]) > 0 {
+			limits, err = ccprovider.GetChaincodeLimits(args[7])
+			if err != nil {
+				return shim.Error(err.Error())
+			}
+		}
+
+		cd, err := lscc.executeDeployOrUpgrade(stub, channel, cds, EP, escc, vscc, collectionsConfig, limits, function)
 		if err != nil {
 			return shim.Error(err.Error())
 		}
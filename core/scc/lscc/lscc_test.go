@@ -424,6 +424,48 @@ func testDeploy(t *testing.T, ccname string, version string, path string, forceB
 	}
 }
 
+// TestDeployWithLimits tests the chaincode limits supplied upon deploy
+func TestDeployWithLimits(t *testing.T) {
+	path := "github.com/hyperledger/fabric/examples/chaincode/go/example02/cmd"
+	capabilities := &config.MockApplicationCapabilities{PrivateChannelDataRv: true}
+	mocksccProvider := (&mscc.MocksccProviderFactory{
+		ApplicationConfigBool: true,
+		ApplicationConfigRv:   &config.MockApplication{CapabilitiesRv: capabilities},
+	}).NewSystemChaincodeProvider().(*mscc.MocksccProviderImpl)
+
+	scc := New(mocksccProvider, mockAclProvider)
+	scc.support = &lscc.MockSupport{}
+	stub := shim.NewMockStub("lscc", scc)
+	res := stub.MockInit("1", nil)
+	assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+	stub.ChannelID = chainid
+
+	cds, err := constructDeploymentSpec("example02", path, "1.0", [][]byte{[]byte("init")}, false, true, scc)
+	assert.NoError(t, err)
+	limits := &ccprovider.ChaincodeLimits{ExecuteTimeout: 5000, Memory: 1 << 28, CPUShares: 512}
+	sProp, _ := putils.MockSignedEndorserProposal2OrPanic(chainid, &pb.ChaincodeSpec{}, id)
+	args := [][]byte{[]byte("deploy"), []byte("test"), utils.MarshalOrPanic(cds), nil, []byte("escc"), []byte("vscc"), nil, utils.MarshalOrPanic(limits)}
+
+	// the limits require the V1_3 capability
+	res = stub.MockInvokeWithSignedProposal("1", args, sProp)
+	assert.Equal(t, ChaincodeLimitsNotAvailable("").Error(), res.Message)
+
+	capabilities.ChaincodeLimitsRv = true
+	invalidArgs := append([][]byte{}, args...)
+	invalidArgs[7] = utils.MarshalOrPanic(&ccprovider.ChaincodeLimits{Memory: -1})
+	res = stub.MockInvokeWithSignedProposal("1", invalidArgs, sProp)
+	assert.Equal(t, "invalid chaincode limits (execute timeout 0, memory -1, cpu shares 0), limits cannot be negative", res.Message)
+
+	res = stub.MockInvokeWithSignedProposal("1", append(args, nil), sProp)
+	assert.Equal(t, InvalidArgsLenErr(9).Error(), res.Message)
+
+	res = stub.MockInvokeWithSignedProposal("1", args, sProp)
+	assert.Equal(t, int32(shim.OK), res.Status, res.Message)
+	cd := &ccprovider.ChaincodeData{}
+	assert.NoError(t, proto.Unmarshal(stub.State["example02"], cd))
+	assert.True(t, proto.Equal(limits, cd.Limits))
+}
+
 // TestUpgrade tests the upgrade function with various inputs for basic use cases
 func TestUpgrade(t *testing.T) {
 	path := "github.com/hyperledger/fabric/examples/chaincode/go/example02/cmd"
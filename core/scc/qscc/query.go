@@ -7,6 +7,7 @@ SPDX-License-Identifier: Apache-2.0
 package qscc
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 
@@ -33,6 +34,7 @@ func New(aclProvider aclmgmt.ACLProvider) *LedgerQuerier {
 // - GetBlockByNumber returns a block
 // - GetBlockByHash returns a block
 // - GetTransactionByID returns a transaction
+// - GetValidationFailureByTxID returns why a transaction is invalid
 type LedgerQuerier struct {
 	aclProvider aclmgmt.ACLProvider
 }
@@ -46,6 +48,8 @@ const (
 	GetBlockByHash     string = "GetBlockByHash"
 	GetTransactionByID string = "GetTransactionByID"
 	GetBlockByTxID     string = "GetBlockByTxID"
+
+	GetValidationFailureByTxID string = "GetValidationFailureByTxID"
 )
 
 // Init is called once per chain when the chain is created.
@@ -64,6 +68,7 @@ func (e *LedgerQuerier) Init(stub shim.ChaincodeStubInterface) pb.Response {
 // # GetBlockByNumber: Return the block specified by block number in args[2]
 // # GetBlockByHash: Return the block specified by block hash in args[2]
 // # GetTransactionByID: Return the transaction specified by ID in args[2]
+// # GetValidationFailureByTxID: Return as JSON why the transaction specified by ID in args[2] is invalid
 func (e *LedgerQuerier) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 	args := stub.GetArgs()
 
@@ -108,6 +113,8 @@ func (e *LedgerQuerier) Invoke(stub shim.ChaincodeStubInterface) pb.Response {
 		return getChainInfo(targetLedger)
 	case GetBlockByTxID:
 		return getBlockByTxID(targetLedger, args[2])
+	case GetValidationFailureByTxID:
+		return getValidationFailureByTxID(targetLedger, args[2])
 	}
 
 	return shim.Error(fmt.Sprintf("Requested function %s not found.", fname))
@@ -207,6 +214,25 @@ func getBlockByTxID(vledger ledger.PeerLedger, rawTxID []byte) pb.Response {
 	return shim.Success(bytes)
 }
 
+func getValidationFailureByTxID(vledger ledger.PeerLedger, rawTxID []byte) pb.Response {
+	txID := string(rawTxID)
+	retriever, ok := vledger.(ledger.ValidationFailureRetriever)
+	if !ok {
+		return shim.Error("Ledger does not keep the validation failures")
+	}
+	failure, err := retriever.GetValidationFailureByTxID(txID)
+	if err != nil {
+		return shim.Error(fmt.Sprintf("Failed to get validation failure for txID %s, error %s", txID, err))
+	}
+
+	bytes, err := json.Marshal(failure)
+	if err != nil {
+		return shim.Error(err.Error())
+	}
+
+	return shim.Success(bytes)
+}
+
 func getACLResource(fname string) string {
 	return "qscc/" + fname
 }
@@ -179,6 +179,22 @@ func TestQueryGetBlockByTxID(t *testing.T) {
 	assert.Equal(t, int32(shim.ERROR), res.Status, "GetBlockByTxID should have failed with blank txId.")
 }
 
+func TestQueryGetValidationFailureByTxID(t *testing.T) {
+	chainid := "mytestchainid9"
+	path := tempDir(t, "test9")
+	defer os.RemoveAll(path)
+
+	stub, err := setupTestLedger(chainid, path)
+	if err != nil {
+		t.Fatalf(err.Error())
+	}
+
+	args := [][]byte{[]byte(GetValidationFailureByTxID), []byte(chainid), []byte("unknown")}
+	prop := resetProvider(resources.Qscc_GetValidationFailureByTxID, chainid, &peer2.SignedProposal{}, nil)
+	res := stub.MockInvokeWithSignedProposal("1", args, prop)
+	assert.Equal(t, int32(shim.ERROR), res.Status, "GetValidationFailureByTxID should have failed with an unknown txId.")
+}
+
 func TestFailingAccessControl(t *testing.T) {
 	chainid := "mytestchainid6"
 	path := tempDir(t, "test6")
@@ -322,6 +338,12 @@ func TestQueryGeneratedBlock(t *testing.T) {
 					prop = resetProvider(resources.Qscc_GetTransactionByID, chainid, &peer2.SignedProposal{}, nil)
 					res = stub.MockInvokeWithSignedProposal("4", args, prop)
 					assert.Equal(t, int32(shim.OK), res.Status, "GetTransactionById should have succeeded for txid: %s", chdr.TxId)
+
+					args = [][]byte{[]byte(GetValidationFailureByTxID), []byte(chainid), []byte(chdr.TxId)}
+					prop = resetProvider(resources.Qscc_GetValidationFailureByTxID, chainid, &peer2.SignedProposal{}, nil)
+					res = stub.MockInvokeWithSignedProposal("5", args, prop)
+					assert.Equal(t, int32(shim.OK), res.Status, "GetValidationFailureByTxID should have succeeded for txid: %s", chdr.TxId)
+					assert.Equal(t, "null", string(res.Payload), "a valid transaction has no validation failure")
 				}
 			}
 		}
@@ -119,25 +119,50 @@ func computeOrdererEndpoints(ordererGrp map[string]*common.ConfigGroup, ordererA
 		if err := proto.Unmarshal(mspConfig.Config, fabricConfig); err != nil {
 			return nil, errors.Wrap(err, "failed marshaling FabricMSPConfig")
 		}
-		res[fabricConfig.Name] = &discovery.Endpoints{}
-		for _, endpoint := range ordererAddresses.Addresses {
-			host, portStr, err := net.SplitHostPort(endpoint)
-			if err != nil {
-				return nil, errors.Errorf("failed parsing orderer endpoint %s", endpoint)
+		// Prefer the endpoints the organization advertises, with their region and priority,
+		// over the channel wide orderer addresses
+		endpoints := []*common.OrdererEndpoint{}
+		if value, exists := group.Values[channelconfig.OrdererEndpointsKey]; exists {
+			ordererEndpoints := &common.OrdererEndpoints{}
+			if err := proto.Unmarshal(value.Value, ordererEndpoints); err != nil {
+				return nil, errors.Wrap(err, "failed parsing OrdererEndpoints")
+			}
+			endpoints = ordererEndpoints.Endpoints
+		}
+		if len(endpoints) == 0 {
+			for _, address := range ordererAddresses.Addresses {
+				endpoints = append(endpoints, &common.OrdererEndpoint{Address: address})
 			}
-			port, err := strconv.ParseInt(portStr, 10, 32)
+		}
+		res[fabricConfig.Name] = &discovery.Endpoints{}
+		for _, endpoint := range endpoints {
+			ep, err := toDiscoveryEndpoint(endpoint)
 			if err != nil {
-				return nil, errors.Errorf("%s is not a valid port number", portStr)
+				return nil, err
 			}
-			res[fabricConfig.Name].Endpoint = append(res[fabricConfig.Name].Endpoint, &discovery.Endpoint{
-				Host: host,
-				Port: uint32(port),
-			})
+			res[fabricConfig.Name].Endpoint = append(res[fabricConfig.Name].Endpoint, ep)
 		}
 	}
 	return res, nil
 }
 
+func toDiscoveryEndpoint(endpoint *common.OrdererEndpoint) (*discovery.Endpoint, error) {
+	host, portStr, err := net.SplitHostPort(endpoint.Address)
+	if err != nil {
+		return nil, errors.Errorf("failed parsing orderer endpoint %s", endpoint.Address)
+	}
+	port, err := strconv.ParseInt(portStr, 10, 32)
+	if err != nil {
+		return nil, errors.Errorf("%s is not a valid port number", portStr)
+	}
+	return &discovery.Endpoint{
+		Host:     host,
+		Port:     uint32(port),
+		Region:   endpoint.Region,
+		Priority: endpoint.Priority,
+	}, nil
+}
+
 func appendMSPConfigs(ordererGrp, appGrp map[string]*common.ConfigGroup, output map[string]*msp.FabricMSPConfig) error {
 	for _, group := range []map[string]*common.ConfigGroup{ordererGrp, appGrp} {
 		for _, grp := range group {
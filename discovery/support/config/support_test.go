@@ -23,6 +23,7 @@ import (
 	"github.com/hyperledger/fabric/discovery/support/config"
 	"github.com/hyperledger/fabric/discovery/support/mocks"
 	"github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/discovery"
 	"github.com/onsi/gomega/gexec"
 	"github.com/stretchr/testify/assert"
 )
@@ -90,6 +91,7 @@ func TestMSPIDMapping(t *testing.T) {
 	for _, org := range ordererConfig.Orderer.Organizations {
 		org.MSPDir = filepath.Join(cryptoConfigDir, "ordererOrganizations", "example.com", "msp")
 		org.Name = randString()
+		org.OrdererEndpoints = []*genesisconfig.OrdererEndpoint{{Address: "orderer.eu.example.com:7050", Region: "eu", Priority: 2}}
 	}
 
 	// Randomize organization names
@@ -130,6 +132,9 @@ func TestMSPIDMapping(t *testing.T) {
 		"Org2MSP":    {},
 	}
 	assert.Equal(t, expected, actualKeys)
+
+	// The orderer org advertises its own endpoints
+	assert.Equal(t, []*discovery.Endpoint{{Host: "orderer.eu.example.com", Port: 7050, Region: "eu", Priority: 2}}, res.Orderers["OrdererMSP"].Endpoint)
 }
 
 func TestSupportGreenPath(t *testing.T) {
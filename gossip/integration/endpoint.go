@@ -0,0 +1,159 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package integration
+
+import (
+	"net"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/hyperledger/fabric/gossip/util"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+)
+
+// ExternalEndpointOverride assembles the endpoint a peer publishes to peers
+// outside of its organization from its deployment environment, for peers
+// whose address is only known once deployed, such as in Kubernetes or
+// behind a load balancer or a NAT.  The host is taken from exactly one of
+// Host, HostEnv and Interface.
+type ExternalEndpointOverride struct {
+	// Host is the host name or address published
+	Host string
+
+	// HostEnv is the name of the environment variable holding the host, such
+	// as the pod IP exposed by the Kubernetes downward API
+	HostEnv string
+
+	// Interface is the name of the network interface whose address is
+	// published, preferring its IPv4 address
+	Interface string
+
+	// Port is the port published, such as the port a NAT or a load balancer
+	// forwards to the peer, or the port of the peer if 0
+	Port int
+}
+
+// interfaceAddrs returns the addresses of the network interface of the name
+type interfaceAddrs func(name string) ([]net.Addr, error)
+
+func systemInterfaceAddrs(name string) ([]net.Addr, error) {
+	iface, err := net.InterfaceByName(name)
+	if err != nil {
+		return nil, err
+	}
+	return iface.Addrs()
+}
+
+// resolveExternalEndpoint returns the endpoint published to peers outside of
+// the organization of the peer at selfEndpoint.  It is the override of the
+// deployment environment, if one is declared, or peer.gossip.externalEndpoint.
+func resolveExternalEndpoint(selfEndpoint string) (string, error) {
+	environment := viper.GetString("peer.gossip.deploymentEnvironment")
+	if environment == "" {
+		return viper.GetString("peer.gossip.externalEndpoint"), nil
+	}
+	overrides := map[string]ExternalEndpointOverride{}
+	if err := viper.UnmarshalKey("peer.gossip.externalEndpointOverrides", &overrides); err != nil {
+		return "", errors.Wrap(err, "invalid external endpoint overrides")
+	}
+	//viper将键名转换为小写
+	override, ok := overrides[strings.ToLower(environment)]
+	if !ok {
+		return "", errors.Errorf("no external endpoint override for deployment environment %s", environment)
+	}
+	endpoint, err := override.resolve(selfEndpoint, os.LookupEnv, systemInterfaceAddrs)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed to resolve external endpoint of deployment environment "+environment)
+	}
+	if host, _, _ := net.SplitHostPort(endpoint); !globalAddress(host) {
+		util.GetLogger(util.GossipLogger, "").Warningf("External endpoint %s of deployment environment %s is not a global address, peers of other organizations outside of its network cannot reach it", endpoint, environment)
+	}
+	return endpoint, nil
+}
+
+// resolve returns the endpoint assembled from the override
+func (o ExternalEndpointOverride) resolve(selfEndpoint string, lookupEnv func(string) (string, bool), addrs interfaceAddrs) (string, error) {
+	sources := 0
+	for _, source := range []string{o.Host, o.HostEnv, o.Interface} {
+		if source != "" {
+			sources++
+		}
+	}
+	if sources != 1 {
+		return "", errors.New("exactly one of host, hostEnv and interface must be set")
+	}
+	if o.Port < 0 || o.Port > 65535 {
+		return "", errors.Errorf("invalid port %d", o.Port)
+	}
+
+	host := o.Host
+	switch {
+	case o.HostEnv != "":
+		value, ok := lookupEnv(o.HostEnv)
+		if !ok || value == "" {
+			return "", errors.Errorf("environment variable %s is not set", o.HostEnv)
+		}
+		host = value
+	case o.Interface != "":
+		var err error
+		if host, err = interfaceAddress(o.Interface, addrs); err != nil {
+			return "", err
+		}
+	}
+
+	port := strconv.Itoa(o.Port)
+	if o.Port == 0 {
+		_, selfPort, err := net.SplitHostPort(selfEndpoint)
+		if err != nil {
+			return "", errors.Wrapf(err, "misconfigured endpoint %s", selfEndpoint)
+		}
+		port = selfPort
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+// interfaceAddress returns the IPv4 address of the interface, or its IPv6
+// address if it has none, skipping the link-local ones, which are only
+// reachable from the link
+func interfaceAddress(name string, addrs interfaceAddrs) (string, error) {
+	ifaceAddrs, err := addrs(name)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to get the addresses of interface %s", name)
+	}
+	var ipv6 net.IP
+	for _, addr := range ifaceAddrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLinkLocalUnicast() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
+			return ipNet.IP.String(), nil
+		}
+		if ipv6 == nil {
+			ipv6 = ipNet.IP
+		}
+	}
+	if ipv6 == nil {
+		return "", errors.Errorf("interface %s has no routable address", name)
+	}
+	return ipv6.String(), nil
+}
+
+// globalAddress returns false if the host is a loopback, private or
+// link-local address, and true otherwise, including for host names
+func globalAddress(host string) bool {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return true
+	}
+	if ip.IsLoopback() || ip.IsLinkLocalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	return true
+}
@@ -0,0 +1,106 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package integration
+
+import (
+	"net"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveOverride(t *testing.T) {
+	env := func(name string) (string, bool) {
+		if name == "POD_IP" {
+			return "10.244.1.7", true
+		}
+		return "", false
+	}
+	addrs := func(name string) ([]net.Addr, error) {
+		switch name {
+		case "eth0":
+			return []net.Addr{
+				&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+				&net.IPNet{IP: net.ParseIP("2001:db8::7"), Mask: net.CIDRMask(64, 128)},
+				&net.IPNet{IP: net.ParseIP("192.0.2.7"), Mask: net.CIDRMask(24, 32)},
+			}, nil
+		case "eth1":
+			return []net.Addr{
+				&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)},
+				&net.IPNet{IP: net.ParseIP("2001:db8::7"), Mask: net.CIDRMask(64, 128)},
+			}, nil
+		case "lo":
+			return []net.Addr{&net.IPNet{IP: net.ParseIP("fe80::1"), Mask: net.CIDRMask(64, 128)}}, nil
+		}
+		return nil, errors.New("no such network interface")
+	}
+
+	for _, tc := range []struct {
+		override ExternalEndpointOverride
+		endpoint string
+		err      string
+	}{
+		{ExternalEndpointOverride{Host: "lb.example.com", Port: 443}, "lb.example.com:443", ""},
+		// 未配置端口时使用对等节点自身的端口
+		{ExternalEndpointOverride{Host: "lb.example.com"}, "lb.example.com:7051", ""},
+		{ExternalEndpointOverride{HostEnv: "POD_IP"}, "10.244.1.7:7051", ""},
+		// 优先使用IPv4地址，跳过链路本地地址
+		{ExternalEndpointOverride{Interface: "eth0", Port: 30051}, "192.0.2.7:30051", ""},
+		{ExternalEndpointOverride{Interface: "eth1"}, "[2001:db8::7]:7051", ""},
+		{ExternalEndpointOverride{}, "", "exactly one of host, hostEnv and interface must be set"},
+		{ExternalEndpointOverride{Host: "lb.example.com", HostEnv: "POD_IP"}, "", "exactly one of host, hostEnv and interface must be set"},
+		{ExternalEndpointOverride{Host: "lb.example.com", Port: 70000}, "", "invalid port 70000"},
+		{ExternalEndpointOverride{HostEnv: "NODE_IP"}, "", "environment variable NODE_IP is not set"},
+		{ExternalEndpointOverride{Interface: "lo"}, "", "interface lo has no routable address"},
+		{ExternalEndpointOverride{Interface: "eth9"}, "", "failed to get the addresses of interface eth9: no such network interface"},
+	} {
+		endpoint, err := tc.override.resolve("0.0.0.0:7051", env, addrs)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tc.endpoint, endpoint)
+	}
+}
+
+func TestResolveExternalEndpoint(t *testing.T) {
+	defer viper.Reset()
+
+	viper.Set("peer.gossip.externalEndpoint", "peer0.org1.example.com:7051")
+	endpoint, err := resolveExternalEndpoint("0.0.0.0:7051")
+	assert.NoError(t, err)
+	assert.Equal(t, "peer0.org1.example.com:7051", endpoint)
+
+	viper.Set("peer.gossip.externalEndpointOverrides", map[string]interface{}{
+		"loadbalancer": map[string]interface{}{"host": "lb.example.com", "port": 443},
+		"broken":       map[string]interface{}{},
+	})
+	// 部署环境名称不区分大小写
+	viper.Set("peer.gossip.deploymentEnvironment", "LoadBalancer")
+	endpoint, err = resolveExternalEndpoint("0.0.0.0:7051")
+	assert.NoError(t, err)
+	assert.Equal(t, "lb.example.com:443", endpoint)
+
+	viper.Set("peer.gossip.deploymentEnvironment", "broken")
+	_, err = resolveExternalEndpoint("0.0.0.0:7051")
+	assert.EqualError(t, err, "failed to resolve external endpoint of deployment environment broken: exactly one of host, hostEnv and interface must be set")
+
+	viper.Set("peer.gossip.deploymentEnvironment", "staging")
+	_, err = resolveExternalEndpoint("0.0.0.0:7051")
+	assert.EqualError(t, err, "no external endpoint override for deployment environment staging")
+}
+
+func TestGlobalAddress(t *testing.T) {
+	assert.True(t, globalAddress("peer0.org1.example.com"))
+	assert.True(t, globalAddress("192.0.2.7"))
+	assert.False(t, globalAddress("127.0.0.1"))
+	assert.False(t, globalAddress("10.244.1.7"))
+	assert.False(t, globalAddress("fe80::1"))
+}
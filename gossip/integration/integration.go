@@ -82,7 +82,10 @@ func NewGossipComponent(peerIdentity []byte, endpoint string, s *grpc.Server,
 	secureDialOpts api.PeerSecureDialOpts, certs *common.TLSCertificates, gossipMetrics *metrics.GossipMetrics,
 	bootPeers ...string) (gossip.Gossip, error) {
 
-	externalEndpoint := viper.GetString("peer.gossip.externalEndpoint")
+	externalEndpoint, err := resolveExternalEndpoint(endpoint)
+	if err != nil {
+		return nil, err
+	}
 
 	conf, err := newConfig(endpoint, externalEndpoint, certs, bootPeers...)
 	if err != nil {
@@ -66,11 +66,12 @@ type deliveryFactoryImpl struct {
 // Returns an instance of delivery client
 func (*deliveryFactoryImpl) Service(g GossipService, endpoints []string, mcs api.MessageCryptoService) (deliverclient.DeliverService, error) {
 	return deliverclient.NewDeliverService(&deliverclient.Config{
-		CryptoSvc:   mcs,
-		Gossip:      g,
-		Endpoints:   endpoints,
-		ConnFactory: deliverclient.DefaultConnectionFactory,
-		ABCFactory:  deliverclient.DefaultABCFactory,
+		CryptoSvc:      mcs,
+		Gossip:         g,
+		Endpoints:      endpoints,
+		ConnFactory:    deliverclient.DefaultConnectionFactory,
+		ABCFactory:     deliverclient.DefaultABCFactory,
+		EndpointRanker: deliverclient.DefaultEndpointRanker,
 	})
 }
 
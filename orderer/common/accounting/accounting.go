@@ -0,0 +1,178 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package accounting meters the messages and bytes each organization submits
+// to and receives from the orderer on each channel, so that the members of a
+// consortium can share the cost of the ordering service by usage.
+package accounting
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/orderer/common/privacy"
+	cb "github.com/hyperledger/fabric/protos/common"
+	mspprotos "github.com/hyperledger/fabric/protos/msp"
+)
+
+// Usage is the usage of the orderer by an organization on a channel
+type Usage struct {
+	Channel           string `json:"channel_id"`
+	MSPID             string `json:"msp_id"`
+	SubmittedMessages uint64 `json:"submitted_messages"`
+	SubmittedBytes    uint64 `json:"submitted_bytes"`
+	DeliveredMessages uint64 `json:"delivered_messages"`
+	DeliveredBytes    uint64 `json:"delivered_bytes"`
+}
+
+// Report is the usage of the orderer by every organization on every channel
+// since the meter was created
+type Report struct {
+	Since time.Time `json:"since"`
+	Usage []Usage   `json:"usage"`
+}
+
+type key struct {
+	channel string
+	mspID   string
+}
+
+// Meter accumulates the usage of the orderer by each organization on each
+// channel.  The usage is held in memory, so it restarts from zero when the
+// orderer restarts.
+type Meter struct {
+	since      time.Time
+	anonymizer *privacy.Anonymizer
+
+	mutex sync.Mutex
+	usage map[key]*Usage
+}
+
+// NewMeter creates a Meter with no usage.  The anonymizer may be nil, in
+// which case the usage is reported by MSP ID rather than by its anonymized
+// form.
+func NewMeter(anonymizer *privacy.Anonymizer) *Meter {
+	return &Meter{
+		since:      time.Now(),
+		anonymizer: anonymizer,
+		usage:      map[key]*Usage{},
+	}
+}
+
+// Submitted records a message of size bytes submitted for ordering on the
+// channel by the organization
+func (m *Meter) Submitted(channelID, mspID string, size int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	u := m.get(channelID, mspID)
+	u.SubmittedMessages++
+	u.SubmittedBytes += uint64(size)
+}
+
+// Delivered records a block of size bytes of the channel delivered to the
+// organization
+func (m *Meter) Delivered(channelID, mspID string, size int) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	u := m.get(channelID, mspID)
+	u.DeliveredMessages++
+	u.DeliveredBytes += uint64(size)
+}
+
+func (m *Meter) get(channelID, mspID string) *Usage {
+	mspID = m.anonymizer.MSPID(mspID)
+	k := key{channel: channelID, mspID: mspID}
+	u, ok := m.usage[k]
+	if !ok {
+		u = &Usage{Channel: channelID, MSPID: mspID}
+		m.usage[k] = u
+	}
+	return u
+}
+
+// Report returns the usage recorded, sorted by channel and organization
+func (m *Meter) Report() Report {
+	m.mutex.Lock()
+	report := Report{Since: m.since, Usage: make([]Usage, 0, len(m.usage))}
+	for _, u := range m.usage {
+		report.Usage = append(report.Usage, *u)
+	}
+	m.mutex.Unlock()
+
+	sort.Slice(report.Usage, func(i, j int) bool {
+		if report.Usage[i].Channel != report.Usage[j].Channel {
+			return report.Usage[i].Channel < report.Usage[j].Channel
+		}
+		return report.Usage[i].MSPID < report.Usage[j].MSPID
+	})
+	return report
+}
+
+// ServeHTTP serves the usage report as JSON, or as CSV if the format query
+// parameter is csv
+func (m *Meter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := m.Report()
+	switch format := req.URL.Query().Get("format"); format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="usage.csv"`)
+		writeCSV(w, report)
+	default:
+		http.Error(w, "unsupported format "+format, http.StatusBadRequest)
+	}
+}
+
+// writeCSV writes a row per channel and organization, preceded by a header
+func writeCSV(w http.ResponseWriter, report Report) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"channel_id", "msp_id", "submitted_messages", "submitted_bytes", "delivered_messages", "delivered_bytes"})
+	for _, u := range report.Usage {
+		cw.Write([]string{
+			u.Channel,
+			u.MSPID,
+			strconv.FormatUint(u.SubmittedMessages, 10),
+			strconv.FormatUint(u.SubmittedBytes, 10),
+			strconv.FormatUint(u.DeliveredMessages, 10),
+			strconv.FormatUint(u.DeliveredBytes, 10),
+		})
+	}
+	cw.Flush()
+}
+
+// CreatorMSPID returns the MSP ID of the creator of the envelope, or the
+// empty string if the envelope has no well formed creator
+func CreatorMSPID(env *cb.Envelope) string {
+	payload := &cb.Payload{}
+	if err := proto.Unmarshal(env.GetPayload(), payload); err != nil || payload.Header == nil {
+		return ""
+	}
+	shdr := &cb.SignatureHeader{}
+	if err := proto.Unmarshal(payload.Header.SignatureHeader, shdr); err != nil {
+		return ""
+	}
+	creator := &mspprotos.SerializedIdentity{}
+	if err := proto.Unmarshal(shdr.Creator, creator); err != nil {
+		return ""
+	}
+	return creator.Mspid
+}
@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package accounting
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/orderer/common/privacy"
+	cb "github.com/hyperledger/fabric/protos/common"
+	mspprotos "github.com/hyperledger/fabric/protos/msp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMeter(t *testing.T) {
+	m := NewMeter(nil)
+	m.Submitted("foo", "Org2MSP", 100)
+	m.Submitted("foo", "Org1MSP", 10)
+	m.Submitted("foo", "Org1MSP", 20)
+	m.Delivered("foo", "Org1MSP", 1000)
+	m.Delivered("bar", "Org2MSP", 500)
+
+	report := m.Report()
+	assert.Equal(t, m.since, report.Since)
+	assert.Equal(t, []Usage{
+		{Channel: "bar", MSPID: "Org2MSP", DeliveredMessages: 1, DeliveredBytes: 500},
+		{Channel: "foo", MSPID: "Org1MSP", SubmittedMessages: 2, SubmittedBytes: 30, DeliveredMessages: 1, DeliveredBytes: 1000},
+		{Channel: "foo", MSPID: "Org2MSP", SubmittedMessages: 1, SubmittedBytes: 100},
+	}, report.Usage)
+
+	// 报告是计数的副本
+	report.Usage[0].DeliveredBytes = 0
+	assert.Equal(t, uint64(500), m.Report().Usage[0].DeliveredBytes)
+}
+
+func TestAnonymizedMeter(t *testing.T) {
+	anonymizer, err := privacy.New(privacy.Config{Mode: privacy.ModeHash, Key: []byte("0123456789abcdef")})
+	require.NoError(t, err)
+	m := NewMeter(anonymizer)
+	m.Submitted("foo", "Org1MSP", 10)
+	m.Delivered("foo", "Org1MSP", 1000)
+
+	// 匿名化后同一组织的用量仍然合并
+	report := m.Report()
+	require.Len(t, report.Usage, 1)
+	assert.Equal(t, anonymizer.MSPID("Org1MSP"), report.Usage[0].MSPID)
+	assert.Equal(t, uint64(1), report.Usage[0].SubmittedMessages)
+	assert.Equal(t, uint64(1), report.Usage[0].DeliveredMessages)
+}
+
+func TestServeHTTP(t *testing.T) {
+	m := NewMeter(nil)
+	m.Submitted("foo", "Org1MSP", 10)
+	m.Delivered("foo", "Org1MSP", 1000)
+
+	t.Run("JSON", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/accounting", nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+		var report Report
+		require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+		assert.Equal(t, m.Report().Usage, report.Usage)
+		assert.Contains(t, resp.Body.String(), `"channel_id":"foo","msp_id":"Org1MSP","submitted_messages":1`)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/accounting?format=csv", nil))
+		assert.Equal(t, http.StatusOK, resp.Code)
+		assert.Equal(t, "text/csv", resp.Header().Get("Content-Type"))
+		assert.Equal(t, "channel_id,msp_id,submitted_messages,submitted_bytes,delivered_messages,delivered_bytes\n"+
+			"foo,Org1MSP,1,10,1,1000\n", resp.Body.String())
+	})
+
+	t.Run("UnsupportedFormat", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/accounting?format=xml", nil))
+		assert.Equal(t, http.StatusBadRequest, resp.Code)
+	})
+
+	t.Run("MethodNotAllowed", func(t *testing.T) {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/accounting", nil))
+		assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+		assert.Equal(t, http.MethodGet, resp.Header().Get("Allow"))
+	})
+}
+
+func TestCreatorMSPID(t *testing.T) {
+	creator, _ := proto.Marshal(&mspprotos.SerializedIdentity{Mspid: "Org1MSP", IdBytes: []byte("cert")})
+	shdr, _ := proto.Marshal(&cb.SignatureHeader{Creator: creator})
+	payload, _ := proto.Marshal(&cb.Payload{Header: &cb.Header{SignatureHeader: shdr}})
+	assert.Equal(t, "Org1MSP", CreatorMSPID(&cb.Envelope{Payload: payload}))
+
+	assert.Equal(t, "", CreatorMSPID(nil))
+	assert.Equal(t, "", CreatorMSPID(&cb.Envelope{Payload: []byte("garbage")}))
+	noHeader, _ := proto.Marshal(&cb.Payload{})
+	assert.Equal(t, "", CreatorMSPID(&cb.Envelope{Payload: noHeader}))
+}
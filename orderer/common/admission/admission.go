@@ -0,0 +1,172 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package admission implements latency-budget admission control for the
+// broadcast service.  The controller tracks how long recent messages took to
+// be enqueued and, while the 99th percentile exceeds the configured SLO,
+// sheds the lowest priority traffic so that critical channels keep their
+// latency under overload.
+package admission
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/admission"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+const (
+	// DefaultWindow is the period over which latencies are considered when none is configured.
+	DefaultWindow = 10 * time.Second
+
+	// minSamples is the number of latencies required before the p99 is trusted.
+	minSamples = 100
+
+	// maxSamples bounds the memory used by the window under heavy load.
+	maxSamples = 10000
+
+	// evaluationInterval bounds how often the p99 is recomputed.
+	evaluationInterval = 100 * time.Millisecond
+)
+
+// ErrOverloaded is returned for messages shed because the latency SLO is exceeded.
+var ErrOverloaded = errors.New("orderer is shedding load, enqueue latency exceeds its SLO")
+
+// Config contains the configuration of a Controller.
+type Config struct {
+	// LatencySLO is the p99 enqueue latency above which low priority traffic is rejected.
+	LatencySLO time.Duration
+
+	// Window is the period over which enqueue latencies are measured.
+	Window time.Duration
+
+	// CriticalChannels are never shed.
+	CriticalChannels []string
+}
+
+type sample struct {
+	at      time.Time
+	latency time.Duration
+}
+
+// Controller decides whether to admit broadcast messages.  Messages for
+// critical channels and config updates are always admitted.  Normal messages
+// for other channels are rejected while the p99 enqueue latency over the
+// window exceeds the SLO.
+type Controller struct {
+	slo      time.Duration
+	window   time.Duration
+	critical map[string]struct{}
+	now      func() time.Time
+
+	mutex         sync.Mutex
+	samples       []sample
+	p99           time.Duration
+	overloaded    bool
+	lastEvaluated time.Time
+}
+
+// NewController creates a Controller, or returns an error if the SLO is not positive
+func NewController(conf Config) (*Controller, error) {
+	if conf.LatencySLO <= 0 {
+		return nil, errors.Errorf("latency SLO must be positive, got %s", conf.LatencySLO)
+	}
+	window := conf.Window
+	if window <= 0 {
+		window = DefaultWindow
+	}
+	critical := make(map[string]struct{}, len(conf.CriticalChannels))
+	for _, channelID := range conf.CriticalChannels {
+		critical[channelID] = struct{}{}
+	}
+	return &Controller{
+		slo:      conf.LatencySLO,
+		window:   window,
+		critical: critical,
+		now:      time.Now,
+	}, nil
+}
+
+// Admit returns ErrOverloaded if the message should be rejected
+func (c *Controller) Admit(channelID string, isConfig bool) error {
+	if isConfig {
+		return nil
+	}
+	if _, ok := c.critical[channelID]; ok {
+		return nil
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.evaluate(false)
+	if c.overloaded {
+		return ErrOverloaded
+	}
+	return nil
+}
+
+// Observe records the time taken to enqueue a message
+func (c *Controller) Observe(latency time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.samples = append(c.samples, sample{at: c.now(), latency: latency})
+	if len(c.samples) > maxSamples {
+		c.samples = c.samples[len(c.samples)-maxSamples:]
+	}
+}
+
+// P99 returns the p99 enqueue latency over the window, or zero if too few
+// messages were enqueued to compute it
+func (c *Controller) P99() time.Duration {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.evaluate(true)
+	return c.p99
+}
+
+// evaluate must be called with the mutex held
+func (c *Controller) evaluate(force bool) {
+	now := c.now()
+	if !force && now.Sub(c.lastEvaluated) < evaluationInterval {
+		return
+	}
+	c.lastEvaluated = now
+
+	cutoff := now.Add(-c.window)
+	expired := sort.Search(len(c.samples), func(i int) bool { return c.samples[i].at.After(cutoff) })
+	c.samples = c.samples[expired:]
+
+	c.p99 = 0
+	if len(c.samples) >= minSamples {
+		latencies := make([]time.Duration, len(c.samples))
+		for i, s := range c.samples {
+			latencies[i] = s.latency
+		}
+		sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+		c.p99 = latencies[(len(latencies)*99-1)/100]
+	}
+
+	overloaded := c.p99 > c.slo
+	if overloaded != c.overloaded {
+		if overloaded {
+			logger.Warningf("Enqueue latency p99 of %s exceeds the SLO of %s, rejecting traffic for non-critical channels", c.p99, c.slo)
+		} else {
+			logger.Infof("Enqueue latency p99 of %s is within the SLO of %s, admitting all traffic", c.p99, c.slo)
+		}
+	}
+	c.overloaded = overloaded
+}
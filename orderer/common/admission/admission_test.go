@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package admission
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClock struct {
+	now time.Time
+}
+
+func (fc *fakeClock) Now() time.Time {
+	return fc.now
+}
+
+func (fc *fakeClock) Advance(d time.Duration) {
+	fc.now = fc.now.Add(d)
+}
+
+func newTestController(t *testing.T) (*Controller, *fakeClock) {
+	c, err := NewController(Config{
+		LatencySLO:       100 * time.Millisecond,
+		Window:           time.Second,
+		CriticalChannels: []string{"critical"},
+	})
+	require.NoError(t, err)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c.now = clock.Now
+	return c, clock
+}
+
+func TestNewControllerBadSLO(t *testing.T) {
+	_, err := NewController(Config{})
+	assert.EqualError(t, err, "latency SLO must be positive, got 0s")
+}
+
+func TestAdmit(t *testing.T) {
+	c, clock := newTestController(t)
+
+	// Too few samples to judge
+	for i := 0; i < minSamples-1; i++ {
+		c.Observe(time.Second)
+	}
+	assert.NoError(t, c.Admit("normal", false))
+	assert.Equal(t, time.Duration(0), c.P99())
+
+	c.Observe(time.Second)
+	clock.Advance(evaluationInterval)
+	assert.Equal(t, ErrOverloaded, c.Admit("normal", false))
+	assert.NoError(t, c.Admit("normal", true), "config updates should not be shed")
+	assert.NoError(t, c.Admit("critical", false), "critical channels should not be shed")
+	assert.Equal(t, time.Second, c.P99())
+
+	// Once the slow samples leave the window, traffic is admitted again
+	clock.Advance(time.Second)
+	for i := 0; i < minSamples; i++ {
+		c.Observe(time.Millisecond)
+	}
+	clock.Advance(evaluationInterval)
+	assert.NoError(t, c.Admit("normal", false))
+	assert.Equal(t, time.Millisecond, c.P99())
+}
+
+func TestP99IgnoresOutliers(t *testing.T) {
+	c, _ := newTestController(t)
+	for i := 0; i < 1000; i++ {
+		latency := 10 * time.Millisecond
+		if i%200 == 0 {
+			latency = time.Second
+		}
+		c.Observe(latency)
+	}
+	assert.Equal(t, 10*time.Millisecond, c.P99())
+	assert.NoError(t, c.Admit("normal", false))
+}
+
+func TestSamplesBounded(t *testing.T) {
+	c, _ := newTestController(t)
+	for i := 0; i < maxSamples+10; i++ {
+		c.Observe(time.Millisecond)
+	}
+	assert.Len(t, c.samples, maxSamples)
+}
@@ -0,0 +1,188 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package admissionplugin loads the custom checks run by the broadcast
+// service on the normal messages once they are validated and before they
+// are ordered.  The checks are compiled in, and registered by name, or built
+// as Go plugins exporting
+//
+//	func NewAdmissionPlugin(parameters map[string]string) (admissionplugin.Plugin, error)
+//
+// so that deployments can enforce sender quotas, business hours or content
+// filters without forking the broadcast handler.
+package admissionplugin
+
+import (
+	"os"
+	"plugin"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/pluginabi"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/admissionplugin"
+
+// pluginFactory is the symbol Go plugins export to create their Plugin
+const pluginFactory = "NewAdmissionPlugin"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// Request is a normal message which passed validation, submitted to the
+// admission plugins before it is ordered.
+type Request struct {
+	ChannelID string
+	TxID      string
+
+	// Creator is the serialized identity of the creator of the message,
+	// whose signature was checked
+	Creator []byte
+
+	Envelope *cb.Envelope
+}
+
+// Plugin decides whether normal messages are ordered.
+type Plugin interface {
+	// Admit returns an error if the message must not be ordered.  If the
+	// error has a RetryAfter() time.Duration method, the client is told
+	// when to submit the message again.
+	Admit(req *Request) error
+}
+
+// Factory creates a Plugin from its parameters.
+type Factory func(parameters map[string]string) (Plugin, error)
+
+var builtins = map[string]Factory{
+	"businesshours": NewBusinessHours,
+	"contentfilter": NewContentFilter,
+}
+
+// Register makes a compiled-in plugin available under the name.  It is
+// meant to be called from init functions and panics if the name is taken.
+func Register(name string, factory Factory) {
+	if _, exists := builtins[name]; exists {
+		logger.Panicf("Admission plugin %s registered twice", name)
+	}
+	builtins[name] = factory
+}
+
+// Config configures an admission plugin.
+type Config struct {
+	// Name is the name of a compiled-in plugin, or identifies the plugin
+	// of the Library in logs and errors
+	Name string
+
+	// Library is the path of a Go plugin
+	Library string
+
+	// Channels are the channels whose messages the plugin checks, every
+	// channel if none
+	Channels []string
+
+	Parameters map[string]string
+}
+
+// Chain runs admission plugins in order, each message being ordered only if
+// every plugin admits it.
+type Chain struct {
+	plugins []*scopedPlugin
+}
+
+// scopedPlugin is a plugin checking the messages of some channels only
+type scopedPlugin struct {
+	name     string
+	channels map[string]struct{}
+	plugin   Plugin
+}
+
+// Load creates the Chain of the configured plugins, or returns nil if none
+// is configured.
+func Load(configs []Config) (*Chain, error) {
+	if len(configs) == 0 {
+		return nil, nil
+	}
+	chain := &Chain{}
+	for _, conf := range configs {
+		if conf.Name == "" {
+			return nil, errors.New("admission plugin has no name")
+		}
+		factory, err := factoryOf(conf)
+		if err != nil {
+			return nil, errors.WithMessage(err, "admission plugin "+conf.Name)
+		}
+		p, err := factory(conf.Parameters)
+		if err != nil {
+			return nil, errors.WithMessage(err, "failed to create admission plugin "+conf.Name)
+		}
+		if p == nil {
+			return nil, errors.Errorf("admission plugin %s created no plugin", conf.Name)
+		}
+		sp := &scopedPlugin{name: conf.Name, plugin: p}
+		if len(conf.Channels) > 0 {
+			sp.channels = map[string]struct{}{}
+			for _, channelID := range conf.Channels {
+				sp.channels[channelID] = struct{}{}
+			}
+		}
+		chain.plugins = append(chain.plugins, sp)
+		logger.Infof("Loaded admission plugin %s for channels %v", conf.Name, conf.Channels)
+	}
+	return chain, nil
+}
+
+// factoryOf returns the factory of the compiled-in plugin or of the Go
+// plugin of the config
+func factoryOf(conf Config) (Factory, error) {
+	if conf.Library == "" {
+		factory, ok := builtins[conf.Name]
+		if !ok {
+			return nil, errors.Errorf("no compiled-in admission plugin is named %s", conf.Name)
+		}
+		return factory, nil
+	}
+
+	if _, err := os.Stat(conf.Library); err != nil {
+		return nil, errors.Wrapf(err, "could not find plugin at path %s", conf.Library)
+	}
+	if err := pluginabi.Check(conf.Library); err != nil {
+		return nil, err
+	}
+	p, err := plugin.Open(conf.Library)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening plugin at path %s", conf.Library)
+	}
+	symbol, err := p.Lookup(pluginFactory)
+	if err != nil {
+		return nil, errors.Wrapf(err, "plugin at path %s must export %s", conf.Library, pluginFactory)
+	}
+	factory, ok := symbol.(func(map[string]string) (Plugin, error))
+	if !ok {
+		return nil, errors.Errorf("%s of plugin at path %s does not match expected definition func(map[string]string) (admissionplugin.Plugin, error)", pluginFactory, conf.Library)
+	}
+	return factory, nil
+}
+
+// Admit returns the error of the first plugin of the channel of the message
+// refusing it.
+func (c *Chain) Admit(req *Request) error {
+	for _, sp := range c.plugins {
+		if sp.channels != nil {
+			if _, ok := sp.channels[req.ChannelID]; !ok {
+				continue
+			}
+		}
+		if err := sp.plugin.Admit(req); err != nil {
+			return errors.WithMessage(err, "rejected by admission plugin "+sp.name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package admissionplugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockPlugin struct {
+	err      error
+	requests []*Request
+}
+
+func (mp *mockPlugin) Admit(req *Request) error {
+	mp.requests = append(mp.requests, req)
+	return mp.err
+}
+
+func request(channelID string, data []byte) *Request {
+	payload, _ := proto.Marshal(&cb.Payload{Data: data})
+	return &Request{ChannelID: channelID, Envelope: &cb.Envelope{Payload: payload}}
+}
+
+func TestLoad(t *testing.T) {
+	chain, err := Load(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, chain)
+
+	first, second := &mockPlugin{}, &mockPlugin{err: errors.New("quota exceeded")}
+	Register("first", func(map[string]string) (Plugin, error) { return first, nil })
+	Register("second", func(map[string]string) (Plugin, error) { return second, nil })
+	assert.Panics(t, func() { Register("first", nil) })
+
+	chain, err = Load([]Config{{Name: "first"}, {Name: "second", Channels: []string{"foo"}}})
+	require.NoError(t, err)
+
+	// 插件只检查其所属通道的消息
+	assert.NoError(t, chain.Admit(request("bar", nil)))
+	assert.EqualError(t, chain.Admit(request("foo", nil)), "rejected by admission plugin second: quota exceeded")
+	assert.Len(t, first.requests, 2)
+	assert.Len(t, second.requests, 1)
+
+	first.err = errors.New("refused")
+	assert.EqualError(t, chain.Admit(request("foo", nil)), "rejected by admission plugin first: refused")
+	assert.Len(t, second.requests, 1)
+}
+
+func TestLoadFailure(t *testing.T) {
+	Register("broken", func(map[string]string) (Plugin, error) { return nil, errors.New("no configuration") })
+	for _, tc := range []struct {
+		conf Config
+		err  string
+	}{
+		{Config{}, "admission plugin has no name"},
+		{Config{Name: "unknown"}, "admission plugin unknown: no compiled-in admission plugin is named unknown"},
+		{Config{Name: "broken"}, "failed to create admission plugin broken: no configuration"},
+		{Config{Name: "contentfilter"}, "failed to create admission plugin contentfilter: deny is required"},
+	} {
+		_, err := Load([]Config{tc.conf})
+		assert.EqualError(t, err, tc.err)
+	}
+
+	_, err := Load([]Config{{Name: "custom", Library: "/nonexistent/plugin.so"}})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "admission plugin custom: could not find plugin at path /nonexistent/plugin.so")
+}
+
+func TestBusinessHours(t *testing.T) {
+	_, err := NewBusinessHours(map[string]string{"open": "9am", "close": "17:00"})
+	assert.EqualError(t, err, `invalid open: "9am" is not a time of day such as 09:00`)
+	_, err = NewBusinessHours(map[string]string{"open": "17:00", "close": "09:00"})
+	assert.EqualError(t, err, "open 17:00 is not before close 09:00")
+	_, err = NewBusinessHours(map[string]string{"open": "09:00", "close": "17:00", "days": "Mon,Someday"})
+	assert.EqualError(t, err, "invalid day Someday")
+
+	p, err := NewBusinessHours(map[string]string{"open": "09:00", "close": "17:30", "days": "Mon, Tue,Wed,Thu,Fri"})
+	require.NoError(t, err)
+	bh := p.(*BusinessHours)
+	// 2018-06-01是星期五
+	now := time.Date(2018, 6, 1, 12, 0, 0, 0, time.UTC)
+	bh.now = func() time.Time { return now }
+	assert.NoError(t, bh.Admit(request("foo", nil)))
+
+	now = time.Date(2018, 6, 1, 8, 30, 0, 0, time.UTC)
+	err = bh.Admit(request("foo", nil))
+	assert.EqualError(t, err, "messages are not admitted out of business hours, next opening at 2018-06-01T09:00:00Z")
+	assert.Equal(t, 30*time.Minute, err.(interface{ RetryAfter() time.Duration }).RetryAfter())
+
+	// 周五下班后要等到周一开门
+	now = time.Date(2018, 6, 1, 17, 30, 0, 0, time.UTC)
+	err = bh.Admit(request("foo", nil))
+	assert.EqualError(t, err, "messages are not admitted out of business hours, next opening at 2018-06-04T09:00:00Z")
+	assert.Equal(t, 63*time.Hour+30*time.Minute, err.(interface{ RetryAfter() time.Duration }).RetryAfter())
+}
+
+func TestContentFilter(t *testing.T) {
+	_, err := NewContentFilter(map[string]string{"deny": "("})
+	assert.Error(t, err)
+
+	p, err := NewContentFilter(map[string]string{"deny": "(?i)forbidden"})
+	require.NoError(t, err)
+	assert.NoError(t, p.Admit(request("foo", []byte("allowed content"))))
+	assert.EqualError(t, p.Admit(request("foo", []byte("some FORBIDDEN content"))), "payload matches denied content (?i)forbidden")
+	assert.Error(t, p.Admit(&Request{Envelope: &cb.Envelope{Payload: []byte("garbage")}}))
+}
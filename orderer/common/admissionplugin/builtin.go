@@ -0,0 +1,146 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package admissionplugin
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// BusinessHours admits the messages submitted within daily opening hours.
+type BusinessHours struct {
+	// open and closing are the minutes of the day of the opening hours
+	open, closing int
+	location      *time.Location
+	days          map[time.Weekday]bool
+	now           func() time.Time
+}
+
+// NewBusinessHours creates the businesshours plugin.  Its parameters are
+// the "open" and "close" times of day, such as 09:00 and 17:30, the
+// "location" of the hours, UTC by default, and the "days" of the week,
+// such as Mon,Tue,Wed,Thu,Fri, every day by default.
+func NewBusinessHours(parameters map[string]string) (Plugin, error) {
+	open, err := parseClock(parameters["open"])
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid open")
+	}
+	closing, err := parseClock(parameters["close"])
+	if err != nil {
+		return nil, errors.WithMessage(err, "invalid close")
+	}
+	if open >= closing {
+		return nil, errors.Errorf("open %s is not before close %s", parameters["open"], parameters["close"])
+	}
+	location := time.UTC
+	if name := parameters["location"]; name != "" {
+		if location, err = time.LoadLocation(name); err != nil {
+			return nil, errors.Wrap(err, "invalid location")
+		}
+	}
+	bh := &BusinessHours{open: open, closing: closing, location: location, days: map[time.Weekday]bool{}, now: time.Now}
+	if parameters["days"] == "" {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			bh.days[day] = true
+		}
+	}
+	for _, name := range strings.Split(parameters["days"], ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		day, ok := weekdays[strings.ToLower(name)]
+		if !ok {
+			return nil, errors.Errorf("invalid day %s", name)
+		}
+		bh.days[day] = true
+	}
+	return bh, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+func parseClock(clock string) (int, error) {
+	t, err := time.Parse("15:04", clock)
+	if err != nil {
+		return 0, errors.Errorf("%q is not a time of day such as 09:00", clock)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// at returns the time at the minutes of the day, days after the day of now
+func (bh *BusinessHours) at(now time.Time, days, minutes int) time.Time {
+	return time.Date(now.Year(), now.Month(), now.Day()+days, minutes/60, minutes%60, 0, 0, bh.location)
+}
+
+// Admit returns an error telling when the next opening is if the message is
+// submitted out of the opening hours.
+func (bh *BusinessHours) Admit(req *Request) error {
+	now := bh.now().In(bh.location)
+	if bh.days[now.Weekday()] && !now.Before(bh.at(now, 0, bh.open)) && now.Before(bh.at(now, 0, bh.closing)) {
+		return nil
+	}
+	for i := 0; i <= 7; i++ {
+		opening := bh.at(now, i, bh.open)
+		if bh.days[opening.Weekday()] && opening.After(now) {
+			return &closedError{opening: opening, retryAfter: opening.Sub(now)}
+		}
+	}
+	return errors.New("no opening hours")
+}
+
+// closedError is returned for the messages submitted out of the opening
+// hours
+type closedError struct {
+	opening    time.Time
+	retryAfter time.Duration
+}
+
+func (e *closedError) Error() string {
+	return fmt.Sprintf("messages are not admitted out of business hours, next opening at %s", e.opening.Format(time.RFC3339))
+}
+
+// RetryAfter returns how long until the next opening.
+func (e *closedError) RetryAfter() time.Duration { return e.retryAfter }
+
+// ContentFilter refuses the messages whose payload data matches a pattern.
+type ContentFilter struct {
+	deny *regexp.Regexp
+}
+
+// NewContentFilter creates the contentfilter plugin.  Its "deny" parameter
+// is the regular expression matched against the payload data of messages.
+func NewContentFilter(parameters map[string]string) (Plugin, error) {
+	if parameters["deny"] == "" {
+		return nil, errors.New("deny is required")
+	}
+	deny, err := regexp.Compile(parameters["deny"])
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid deny")
+	}
+	return &ContentFilter{deny: deny}, nil
+}
+
+// Admit returns an error if the payload data of the message matches.
+func (cf *ContentFilter) Admit(req *Request) error {
+	payload := &cb.Payload{}
+	if err := proto.Unmarshal(req.Envelope.Payload, payload); err != nil {
+		return errors.Wrap(err, "could not unmarshal payload")
+	}
+	if cf.deny.Match(payload.Data) {
+		return errors.Errorf("payload matches denied content %s", cf.deny)
+	}
+	return nil
+}
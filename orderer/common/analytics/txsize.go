@@ -0,0 +1,307 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package analytics analyzes the blocks committed by the orderer to report
+// on how the applications use the channels, such as which components of
+// their transactions take up the bytes of the blocks.
+package analytics
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/pkg/errors"
+)
+
+// Sizes are the bytes of endorser transactions attributed to each of their
+// components.  The endorsements include the certificates of the endorsers,
+// and Other is the encoding overhead and the fields of no other component,
+// such as the proposal hashes and chaincode responses.
+type Sizes struct {
+	Headers      uint64 `json:"headers"`
+	Creators     uint64 `json:"creators"`
+	Signatures   uint64 `json:"signatures"`
+	Endorsements uint64 `json:"endorsements"`
+	RWSets       uint64 `json:"rw_sets"`
+	Inputs       uint64 `json:"inputs"`
+	Events       uint64 `json:"events"`
+	Other        uint64 `json:"other"`
+}
+
+func (s *Sizes) add(o Sizes) {
+	s.Headers += o.Headers
+	s.Creators += o.Creators
+	s.Signatures += o.Signatures
+	s.Endorsements += o.Endorsements
+	s.RWSets += o.RWSets
+	s.Inputs += o.Inputs
+	s.Events += o.Events
+	s.Other += o.Other
+}
+
+func (s Sizes) sum() uint64 {
+	return s.Headers + s.Creators + s.Signatures + s.Endorsements + s.RWSets + s.Inputs + s.Events + s.Other
+}
+
+// divide returns the sizes divided by n, rounded down
+func (s Sizes) divide(n uint64) Sizes {
+	return Sizes{
+		Headers:      s.Headers / n,
+		Creators:     s.Creators / n,
+		Signatures:   s.Signatures / n,
+		Endorsements: s.Endorsements / n,
+		RWSets:       s.RWSets / n,
+		Inputs:       s.Inputs / n,
+		Events:       s.Events / n,
+		Other:        s.Other / n,
+	}
+}
+
+// ChaincodeSizes are the sizes of the transactions invoking a chaincode on
+// a channel.  PerTransaction normalizes the components to the average
+// transaction, so that chaincodes with different volumes can be compared.
+type ChaincodeSizes struct {
+	Channel        string `json:"channel_id"`
+	Chaincode      string `json:"chaincode"`
+	Transactions   uint64 `json:"transactions"`
+	Bytes          uint64 `json:"bytes"`
+	MaxBytes       uint64 `json:"max_bytes"`
+	MaxTxID        string `json:"max_tx_id"`
+	Components     Sizes  `json:"components"`
+	PerTransaction Sizes  `json:"per_transaction"`
+}
+
+// TxSizeReport is the sizes of the endorser transactions of every chaincode
+// on every channel committed since the analyzer was created.  Skipped is
+// the number of endorser transactions too malformed to be attributed.
+type TxSizeReport struct {
+	Since      time.Time        `json:"since"`
+	Blocks     uint64           `json:"blocks"`
+	Skipped    uint64           `json:"skipped"`
+	Chaincodes []ChaincodeSizes `json:"chaincodes"`
+}
+
+type key struct {
+	channel   string
+	chaincode string
+}
+
+// TxSizeAnalyzer attributes the bytes of the endorser transactions of the
+// blocks it analyzes to their components, aggregated per chaincode.  The
+// sizes are held in memory, so they restart from zero when the orderer
+// restarts.
+type TxSizeAnalyzer struct {
+	since time.Time
+
+	mutex      sync.Mutex
+	blocks     uint64
+	skipped    uint64
+	chaincodes map[key]*ChaincodeSizes
+}
+
+// NewTxSizeAnalyzer creates a TxSizeAnalyzer which has analyzed no block
+func NewTxSizeAnalyzer() *TxSizeAnalyzer {
+	return &TxSizeAnalyzer{
+		since:      time.Now(),
+		chaincodes: map[key]*ChaincodeSizes{},
+	}
+}
+
+// AnalyzeBlock adds the sizes of the endorser transactions of the block
+func (a *TxSizeAnalyzer) AnalyzeBlock(block *cb.Block) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	a.blocks++
+	for _, data := range block.GetData().GetData() {
+		channelID, chaincode, txID, sizes, err := attribute(data)
+		if err == errNotEndorserTransaction {
+			continue
+		}
+		if err != nil {
+			a.skipped++
+			continue
+		}
+		k := key{channel: channelID, chaincode: chaincode}
+		cs, ok := a.chaincodes[k]
+		if !ok {
+			cs = &ChaincodeSizes{Channel: channelID, Chaincode: chaincode}
+			a.chaincodes[k] = cs
+		}
+		cs.Transactions++
+		cs.Bytes += uint64(len(data))
+		cs.Components.add(sizes)
+		if uint64(len(data)) > cs.MaxBytes {
+			cs.MaxBytes, cs.MaxTxID = uint64(len(data)), txID
+		}
+	}
+}
+
+var (
+	errNotEndorserTransaction = errors.New("not an endorser transaction")
+	errMalformed              = errors.New("malformed endorser transaction")
+)
+
+// attribute returns the channel, chaincode and ID of the endorser
+// transaction of the envelope, and the sizes of its components
+func attribute(data []byte) (channelID, chaincode, txID string, sizes Sizes, err error) {
+	env := &cb.Envelope{}
+	payload := &cb.Payload{}
+	chdr := &cb.ChannelHeader{}
+	if proto.Unmarshal(data, env) != nil || proto.Unmarshal(env.Payload, payload) != nil || payload.Header == nil ||
+		proto.Unmarshal(payload.Header.ChannelHeader, chdr) != nil {
+		//无法确定交易类型时不计入任何链码
+		return "", "", "", sizes, errNotEndorserTransaction
+	}
+	if chdr.Type != int32(cb.HeaderType_ENDORSER_TRANSACTION) {
+		return "", "", "", sizes, errNotEndorserTransaction
+	}
+
+	sizes.Signatures = uint64(len(env.Signature))
+	sizes.Headers = uint64(len(payload.Header.ChannelHeader))
+	if err := attributeSignatureHeader(payload.Header.SignatureHeader, &sizes); err != nil {
+		return "", "", "", sizes, err
+	}
+	tx := &pb.Transaction{}
+	if err := proto.Unmarshal(payload.Data, tx); err != nil {
+		return "", "", "", sizes, errMalformed
+	}
+	for _, action := range tx.Actions {
+		if err := attributeSignatureHeader(action.Header, &sizes); err != nil {
+			return "", "", "", sizes, err
+		}
+		ccPayload := &pb.ChaincodeActionPayload{}
+		if err := proto.Unmarshal(action.Payload, ccPayload); err != nil {
+			return "", "", "", sizes, errMalformed
+		}
+		sizes.Inputs += uint64(len(ccPayload.ChaincodeProposalPayload))
+		if ccPayload.Action == nil {
+			continue
+		}
+		for _, e := range ccPayload.Action.Endorsements {
+			sizes.Endorsements += uint64(len(e.Endorser) + len(e.Signature))
+		}
+		response := &pb.ProposalResponsePayload{}
+		ccAction := &pb.ChaincodeAction{}
+		if proto.Unmarshal(ccPayload.Action.ProposalResponsePayload, response) != nil || proto.Unmarshal(response.Extension, ccAction) != nil {
+			return "", "", "", sizes, errMalformed
+		}
+		sizes.RWSets += uint64(len(ccAction.Results))
+		sizes.Events += uint64(len(ccAction.Events))
+		if chaincode == "" && ccAction.ChaincodeId != nil {
+			chaincode = ccAction.ChaincodeId.Name
+		}
+	}
+	if chaincode == "" {
+		//背书结果未声明链码时使用提案头部中的链码
+		extension := &pb.ChaincodeHeaderExtension{}
+		if proto.Unmarshal(chdr.Extension, extension) == nil && extension.ChaincodeId != nil {
+			chaincode = extension.ChaincodeId.Name
+		}
+	}
+
+	if total := uint64(len(data)); total > sizes.sum() {
+		sizes.Other = total - sizes.sum()
+	}
+	return chdr.ChannelId, chaincode, chdr.TxId, sizes, nil
+}
+
+// attributeSignatureHeader attributes the creator of the signature header
+// to the creators and the rest of it to the headers
+func attributeSignatureHeader(header []byte, sizes *Sizes) error {
+	shdr := &cb.SignatureHeader{}
+	if err := proto.Unmarshal(header, shdr); err != nil {
+		return errMalformed
+	}
+	sizes.Creators += uint64(len(shdr.Creator))
+	sizes.Headers += uint64(len(header) - len(shdr.Creator))
+	return nil
+}
+
+// Report returns the sizes analyzed, sorted by channel and chaincode
+func (a *TxSizeAnalyzer) Report() TxSizeReport {
+	a.mutex.Lock()
+	report := TxSizeReport{Since: a.since, Blocks: a.blocks, Skipped: a.skipped, Chaincodes: make([]ChaincodeSizes, 0, len(a.chaincodes))}
+	for _, cs := range a.chaincodes {
+		copied := *cs
+		copied.PerTransaction = cs.Components.divide(cs.Transactions)
+		report.Chaincodes = append(report.Chaincodes, copied)
+	}
+	a.mutex.Unlock()
+
+	sort.Slice(report.Chaincodes, func(i, j int) bool {
+		if report.Chaincodes[i].Channel != report.Chaincodes[j].Channel {
+			return report.Chaincodes[i].Channel < report.Chaincodes[j].Channel
+		}
+		return report.Chaincodes[i].Chaincode < report.Chaincodes[j].Chaincode
+	})
+	return report
+}
+
+// ServeHTTP serves the report as JSON, or as CSV if the format query
+// parameter is csv, restricted to a channel if the channel query parameter
+// is set
+func (a *TxSizeAnalyzer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := a.Report()
+	if channelID := req.URL.Query().Get("channel"); channelID != "" {
+		chaincodes := []ChaincodeSizes{}
+		for _, cs := range report.Chaincodes {
+			if cs.Channel == channelID {
+				chaincodes = append(chaincodes, cs)
+			}
+		}
+		report.Chaincodes = chaincodes
+	}
+	switch format := req.URL.Query().Get("format"); format {
+	case "", "json":
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(report); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", `attachment; filename="txsize.csv"`)
+		writeCSV(w, report)
+	default:
+		http.Error(w, "unsupported format "+format, http.StatusBadRequest)
+	}
+}
+
+// writeCSV writes a row per channel and chaincode, preceded by a header
+func writeCSV(w http.ResponseWriter, report TxSizeReport) {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"channel_id", "chaincode", "transactions", "bytes", "max_bytes", "max_tx_id",
+		"headers", "creators", "signatures", "endorsements", "rw_sets", "inputs", "events", "other"})
+	for _, cs := range report.Chaincodes {
+		row := []string{
+			cs.Channel,
+			cs.Chaincode,
+			strconv.FormatUint(cs.Transactions, 10),
+			strconv.FormatUint(cs.Bytes, 10),
+			strconv.FormatUint(cs.MaxBytes, 10),
+			cs.MaxTxID,
+		}
+		c := cs.Components
+		for _, size := range []uint64{c.Headers, c.Creators, c.Signatures, c.Endorsements, c.RWSets, c.Inputs, c.Events, c.Other} {
+			row = append(row, strconv.FormatUint(size, 10))
+		}
+		cw.Write(row)
+	}
+	cw.Flush()
+}
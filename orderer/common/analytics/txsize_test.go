@@ -0,0 +1,145 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package analytics
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	pb "github.com/hyperledger/fabric/protos/peer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func marshal(t *testing.T, msg proto.Message) []byte {
+	data, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	return data
+}
+
+// endorserTx returns an endorser transaction invoking the chaincode with a
+// read write set of rwSetSize bytes and two endorsements
+func endorserTx(t *testing.T, channelID, chaincode, txID string, rwSetSize int) []byte {
+	shdr := marshal(t, &cb.SignatureHeader{Creator: bytes.Repeat([]byte("c"), 100), Nonce: []byte("nonce")})
+	ccAction := marshal(t, &pb.ChaincodeAction{
+		Results:     bytes.Repeat([]byte("r"), rwSetSize),
+		Events:      []byte("event"),
+		ChaincodeId: &pb.ChaincodeID{Name: chaincode},
+	})
+	ccPayload := marshal(t, &pb.ChaincodeActionPayload{
+		ChaincodeProposalPayload: []byte("input"),
+		Action: &pb.ChaincodeEndorsedAction{
+			ProposalResponsePayload: marshal(t, &pb.ProposalResponsePayload{ProposalHash: []byte("hash"), Extension: ccAction}),
+			Endorsements: []*pb.Endorsement{
+				{Endorser: bytes.Repeat([]byte("e"), 50), Signature: []byte("sig1")},
+				{Endorser: bytes.Repeat([]byte("e"), 50), Signature: []byte("sig2")},
+			},
+		},
+	})
+	return marshal(t, &cb.Envelope{
+		Payload: marshal(t, &cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader:   marshal(t, &cb.ChannelHeader{Type: int32(cb.HeaderType_ENDORSER_TRANSACTION), ChannelId: channelID, TxId: txID}),
+				SignatureHeader: shdr,
+			},
+			Data: marshal(t, &pb.Transaction{Actions: []*pb.TransactionAction{{Header: shdr, Payload: ccPayload}}}),
+		}),
+		Signature: []byte("signature"),
+	})
+}
+
+func TestAttribute(t *testing.T) {
+	data := endorserTx(t, "foo", "mycc", "tx1", 1000)
+	channelID, chaincode, txID, sizes, err := attribute(data)
+	require.NoError(t, err)
+	assert.Equal(t, "foo", channelID)
+	assert.Equal(t, "mycc", chaincode)
+	assert.Equal(t, "tx1", txID)
+	assert.Equal(t, uint64(200), sizes.Creators)
+	assert.Equal(t, uint64(len("signature")), sizes.Signatures)
+	assert.Equal(t, uint64(108), sizes.Endorsements)
+	assert.Equal(t, uint64(1000), sizes.RWSets)
+	assert.Equal(t, uint64(len("input")), sizes.Inputs)
+	assert.Equal(t, uint64(len("event")), sizes.Events)
+	assert.NotZero(t, sizes.Headers)
+	assert.NotZero(t, sizes.Other)
+	// 各部分之和等于交易大小
+	assert.Equal(t, uint64(len(data)), sizes.sum())
+
+	config := marshal(t, &cb.Envelope{Payload: marshal(t, &cb.Payload{Header: &cb.Header{
+		ChannelHeader: marshal(t, &cb.ChannelHeader{Type: int32(cb.HeaderType_CONFIG), ChannelId: "foo"}),
+	}})})
+	_, _, _, _, err = attribute(config)
+	assert.Equal(t, errNotEndorserTransaction, err)
+
+	malformed := marshal(t, &cb.Envelope{Payload: marshal(t, &cb.Payload{
+		Header: &cb.Header{ChannelHeader: marshal(t, &cb.ChannelHeader{Type: int32(cb.HeaderType_ENDORSER_TRANSACTION), ChannelId: "foo"})},
+		Data:   []byte("garbage"),
+	})})
+	_, _, _, _, err = attribute(malformed)
+	assert.Equal(t, errMalformed, err)
+}
+
+func TestTxSizeAnalyzer(t *testing.T) {
+	a := NewTxSizeAnalyzer()
+	small := endorserTx(t, "foo", "mycc", "tx1", 100)
+	large := endorserTx(t, "foo", "mycc", "tx2", 3000)
+	a.AnalyzeBlock(&cb.Block{Data: &cb.BlockData{Data: [][]byte{small, large, []byte("garbage")}}})
+	a.AnalyzeBlock(&cb.Block{Data: &cb.BlockData{Data: [][]byte{endorserTx(t, "bar", "othercc", "tx3", 10)}}})
+
+	report := a.Report()
+	assert.Equal(t, a.since, report.Since)
+	assert.Equal(t, uint64(2), report.Blocks)
+	require.Len(t, report.Chaincodes, 2)
+	assert.Equal(t, "bar", report.Chaincodes[0].Channel)
+	assert.Equal(t, "othercc", report.Chaincodes[0].Chaincode)
+
+	mycc := report.Chaincodes[1]
+	assert.Equal(t, uint64(2), mycc.Transactions)
+	assert.Equal(t, uint64(len(small)+len(large)), mycc.Bytes)
+	assert.Equal(t, uint64(len(large)), mycc.MaxBytes)
+	assert.Equal(t, "tx2", mycc.MaxTxID)
+	assert.Equal(t, uint64(3100), mycc.Components.RWSets)
+	assert.Equal(t, uint64(1550), mycc.PerTransaction.RWSets)
+	assert.Equal(t, mycc.Bytes, mycc.Components.sum())
+}
+
+func TestTxSizeServeHTTP(t *testing.T) {
+	a := NewTxSizeAnalyzer()
+	a.AnalyzeBlock(&cb.Block{Data: &cb.BlockData{Data: [][]byte{
+		endorserTx(t, "foo", "mycc", "tx1", 100),
+		endorserTx(t, "bar", "othercc", "tx2", 100),
+	}}})
+
+	resp := httptest.NewRecorder()
+	a.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/analytics/txsize?channel=foo", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var report TxSizeReport
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &report))
+	require.Len(t, report.Chaincodes, 1)
+	assert.Equal(t, "mycc", report.Chaincodes[0].Chaincode)
+
+	resp = httptest.NewRecorder()
+	a.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/analytics/txsize?format=csv", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.Equal(t, "text/csv", resp.Header().Get("Content-Type"))
+	assert.Contains(t, resp.Body.String(), "channel_id,chaincode,transactions,bytes,max_bytes,max_tx_id,headers,creators,signatures,endorsements,rw_sets,inputs,events,other\n")
+	assert.Contains(t, resp.Body.String(), "\nfoo,mycc,1,")
+
+	resp = httptest.NewRecorder()
+	a.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/analytics/txsize?format=xml", nil))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = httptest.NewRecorder()
+	a.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/analytics/txsize", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
@@ -0,0 +1,312 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package asyncop runs the heavy admin operations of the orderer, such as
+// backups and snapshots, in the background.  The admin call starting an
+// operation returns its ID at once, instead of holding the connection until
+// the operation completes, and the operation is then monitored and cancelled
+// through the operations endpoint.
+package asyncop
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const pkgLogID = "orderer/common/asyncop"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// DefaultRetention is the number of finished operations whose status is kept
+// when no retention is given.
+const DefaultRetention = 100
+
+// State is the state of an operation.
+type State string
+
+const (
+	Running   State = "running"
+	Succeeded State = "succeeded"
+	Failed    State = "failed"
+	Cancelled State = "cancelled"
+)
+
+var (
+	// ErrNotFound is returned for an operation which does not exist or
+	// whose status is no longer kept
+	ErrNotFound = errors.New("operation not found")
+
+	// ErrConflict is returned when starting an operation while another of
+	// the same kind runs on the same target
+	ErrConflict = errors.New("an operation of this kind is already running on this target")
+
+	// ErrFinished is returned when cancelling an operation which finished
+	ErrFinished = errors.New("operation already finished")
+)
+
+// Progress reports that done units of work out of total are complete.
+type Progress func(done, total uint64)
+
+// Func is the body of an operation.  The context is done when the operation
+// is cancelled, and the result is served in the status of the operation.
+type Func func(ctx context.Context, progress Progress) (interface{}, error)
+
+// Status is the exported form of the state of an operation.
+type Status struct {
+	ID       string      `json:"id"`
+	Kind     string      `json:"kind"`
+	Target   string      `json:"target,omitempty"`
+	State    State       `json:"state"`
+	Done     uint64      `json:"done"`
+	Total    uint64      `json:"total"`
+	Started  time.Time   `json:"started"`
+	Finished *time.Time  `json:"finished,omitempty"`
+	Error    string      `json:"error,omitempty"`
+	Result   interface{} `json:"result,omitempty"`
+}
+
+type operation struct {
+	status Status
+	cancel context.CancelFunc
+}
+
+// Manager runs the operations and keeps their status, that of the finished
+// operations being dropped oldest first beyond the retention.
+type Manager struct {
+	prefix    string
+	retention int
+	now       func() time.Time
+
+	mutex    sync.Mutex
+	ops      map[string]*operation
+	finished []string
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+// NewManager creates a Manager serving the operations under the prefix, and
+// keeping the status of up to retention finished operations, DefaultRetention
+// if it is not positive.
+func NewManager(prefix string, retention int) *Manager {
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Manager{
+		prefix:    prefix,
+		retention: retention,
+		now:       time.Now,
+		ops:       map[string]*operation{},
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+}
+
+// Start runs the operation of the kind on the target in the background, and
+// returns its status.  It returns ErrConflict if an operation of the same
+// kind runs on the target.
+func (m *Manager) Start(kind, target string, fn Func) (*Status, error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.ctx.Err() != nil {
+		return nil, errors.New("operations are stopped")
+	}
+	for _, op := range m.ops {
+		if op.status.State == Running && op.status.Kind == kind && op.status.Target == target {
+			return nil, ErrConflict
+		}
+	}
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(m.ctx)
+	op := &operation{
+		status: Status{ID: id, Kind: kind, Target: target, State: Running, Started: m.now()},
+		cancel: cancel,
+	}
+	m.ops[id] = op
+	logger.Infof("Started operation %s: %s of %s", id, kind, target)
+
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		defer cancel()
+		result, err := fn(ctx, func(done, total uint64) {
+			m.mutex.Lock()
+			op.status.Done, op.status.Total = done, total
+			m.mutex.Unlock()
+		})
+		m.finish(ctx, op, result, err)
+	}()
+
+	status := op.status
+	return &status, nil
+}
+
+func (m *Manager) finish(ctx context.Context, op *operation, result interface{}, err error) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	finished := m.now()
+	op.status.Finished = &finished
+	switch {
+	case err != nil && ctx.Err() == context.Canceled:
+		op.status.State = Cancelled
+		op.status.Error = err.Error()
+		logger.Infof("Operation %s cancelled", op.status.ID)
+	case err != nil:
+		op.status.State = Failed
+		op.status.Error = err.Error()
+		logger.Errorf("Operation %s failed: %s", op.status.ID, err)
+	default:
+		op.status.State = Succeeded
+		op.status.Result = result
+		logger.Infof("Operation %s completed in %s", op.status.ID, finished.Sub(op.status.Started))
+	}
+
+	//超出保留数量时丢弃最早结束的操作
+	m.finished = append(m.finished, op.status.ID)
+	for len(m.finished) > m.retention {
+		delete(m.ops, m.finished[0])
+		m.finished = m.finished[1:]
+	}
+}
+
+func newID() (string, error) {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "", errors.Wrap(err, "failed to generate operation ID")
+	}
+	return hex.EncodeToString(id), nil
+}
+
+// Get returns the status of the operation, or false if it is not found.
+func (m *Manager) Get(id string) (*Status, bool) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	op, ok := m.ops[id]
+	if !ok {
+		return nil, false
+	}
+	status := op.status
+	return &status, true
+}
+
+// List returns the status of the operations, the latest started first.
+func (m *Manager) List() []*Status {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	statuses := make([]*Status, 0, len(m.ops))
+	for _, op := range m.ops {
+		status := op.status
+		statuses = append(statuses, &status)
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Started.After(statuses[j].Started) })
+	return statuses
+}
+
+// Cancel cancels the running operation, which is cancelled once it notices.
+func (m *Manager) Cancel(id string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	op, ok := m.ops[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if op.status.State != Running {
+		return ErrFinished
+	}
+	logger.Infof("Cancelling operation %s", id)
+	op.cancel()
+	return nil
+}
+
+// Stop cancels the running operations, waiting for them to return, and
+// refuses to start new ones.
+func (m *Manager) Stop() {
+	m.mutex.Lock()
+	m.cancel()
+	m.mutex.Unlock()
+	m.wg.Wait()
+}
+
+// ServeHTTP serves the operations under the prefix: GET of the prefix lists
+// the operations, GET of the prefix followed by an ID returns the status of
+// the operation, and DELETE of it cancels the operation.
+func (m *Manager) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	id := strings.Trim(strings.TrimPrefix(req.URL.Path, strings.TrimSuffix(m.prefix, "/")), "/")
+	if id == "" {
+		if req.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, m.List())
+		return
+	}
+
+	switch req.Method {
+	case http.MethodGet:
+		status, ok := m.Get(id)
+		if !ok {
+			http.Error(w, ErrNotFound.Error(), http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, status)
+	case http.MethodDelete:
+		switch err := m.Cancel(id); err {
+		case nil:
+			logger.Infof("Cancellation of operation %s requested by %s", id, req.RemoteAddr)
+			w.WriteHeader(http.StatusAccepted)
+		case ErrNotFound:
+			http.Error(w, err.Error(), http.StatusNotFound)
+		default:
+			http.Error(w, err.Error(), http.StatusConflict)
+		}
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// WriteStarted answers the status of an operation just started, along with
+// its location under the prefix, or the error starting it.
+func (m *Manager) WriteStarted(w http.ResponseWriter, status *Status, err error) {
+	switch {
+	case err == ErrConflict:
+		http.Error(w, err.Error(), http.StatusConflict)
+	case err != nil:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	default:
+		w.Header().Set("Location", strings.TrimSuffix(m.prefix, "/")+"/"+status.ID)
+		writeJSON(w, http.StatusAccepted, status)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warningf("Failed writing operations response: %s", err)
+	}
+}
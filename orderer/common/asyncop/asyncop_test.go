@@ -0,0 +1,182 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package asyncop
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// blockingFunc returns an operation which reports half of its progress and
+// runs until released or cancelled, and the channel of its release
+func blockingFunc() (Func, chan error) {
+	release := make(chan error)
+	return func(ctx context.Context, progress Progress) (interface{}, error) {
+		progress(1, 2)
+		select {
+		case err := <-release:
+			return "done", err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}, release
+}
+
+func waitFinished(t *testing.T, m *Manager, id string) *Status {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, ok := m.Get(id)
+		require.True(t, ok)
+		if status.State != Running {
+			return status
+		}
+		require.True(t, time.Now().Before(deadline), "operation %s did not finish", id)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func waitProgress(t *testing.T, m *Manager, id string) *Status {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, ok := m.Get(id)
+		require.True(t, ok)
+		if status.Total != 0 {
+			return status
+		}
+		require.True(t, time.Now().Before(deadline), "operation %s reported no progress", id)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOperation(t *testing.T) {
+	m := NewManager("/operations", 0)
+	defer m.Stop()
+
+	fn, release := blockingFunc()
+	started, err := m.Start("backup", "mychannel", fn)
+	require.NoError(t, err)
+	assert.Equal(t, Running, started.State)
+	assert.Len(t, started.ID, 16)
+
+	status := waitProgress(t, m, started.ID)
+	assert.Equal(t, uint64(1), status.Done)
+	assert.Equal(t, uint64(2), status.Total)
+
+	// a second operation of the kind on the target conflicts, but not on
+	// another target
+	_, err = m.Start("backup", "mychannel", fn)
+	assert.Equal(t, ErrConflict, err)
+	other, err := m.Start("snapshot", "mychannel", fn)
+	require.NoError(t, err)
+	require.NoError(t, m.Cancel(other.ID))
+
+	release <- nil
+	status = waitFinished(t, m, started.ID)
+	assert.Equal(t, Succeeded, status.State)
+	assert.Equal(t, "done", status.Result)
+	assert.NotNil(t, status.Finished)
+	assert.Equal(t, Cancelled, waitFinished(t, m, other.ID).State)
+	assert.Equal(t, ErrFinished, m.Cancel(started.ID))
+	assert.Equal(t, ErrNotFound, m.Cancel("unknown"))
+
+	// the operation may run again once finished
+	again, err := m.Start("backup", "mychannel", fn)
+	require.NoError(t, err)
+	release <- errors.New("disk full")
+	status = waitFinished(t, m, again.ID)
+	assert.Equal(t, Failed, status.State)
+	assert.Equal(t, "disk full", status.Error)
+	assert.Nil(t, status.Result)
+	assert.Len(t, m.List(), 3)
+}
+
+func TestRetention(t *testing.T) {
+	m := NewManager("/operations", 2)
+	defer m.Stop()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		status, err := m.Start("backup", "mychannel", func(context.Context, Progress) (interface{}, error) { return nil, nil })
+		require.NoError(t, err)
+		waitFinished(t, m, status.ID)
+		ids = append(ids, status.ID)
+	}
+	_, ok := m.Get(ids[0])
+	assert.False(t, ok, "the oldest finished operation is dropped")
+	_, ok = m.Get(ids[2])
+	assert.True(t, ok)
+}
+
+func TestStop(t *testing.T) {
+	m := NewManager("/operations", 0)
+	fn, _ := blockingFunc()
+	started, err := m.Start("backup", "mychannel", fn)
+	require.NoError(t, err)
+
+	m.Stop()
+	status, _ := m.Get(started.ID)
+	assert.Equal(t, Cancelled, status.State)
+	_, err = m.Start("backup", "mychannel", fn)
+	assert.EqualError(t, err, "operations are stopped")
+}
+
+func TestServeHTTP(t *testing.T) {
+	m := NewManager("/operations/", 0)
+	defer m.Stop()
+	fn, release := blockingFunc()
+	defer close(release)
+
+	resp := httptest.NewRecorder()
+	started, err := m.Start("backup", "mychannel", fn)
+	m.WriteStarted(resp, started, err)
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	assert.Equal(t, "/operations/"+started.ID, resp.Header().Get("Location"))
+
+	resp = httptest.NewRecorder()
+	_, err = m.Start("backup", "mychannel", fn)
+	m.WriteStarted(resp, nil, err)
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	for _, tc := range []struct {
+		method string
+		target string
+		status int
+	}{
+		{http.MethodPost, "/operations", http.StatusMethodNotAllowed},
+		{http.MethodGet, "/operations/unknown", http.StatusNotFound},
+		{http.MethodPut, "/operations/" + started.ID, http.StatusMethodNotAllowed},
+		{http.MethodDelete, "/operations/unknown", http.StatusNotFound},
+		{http.MethodGet, "/operations/" + started.ID, http.StatusOK},
+		{http.MethodDelete, "/operations/" + started.ID, http.StatusAccepted},
+	} {
+		resp := httptest.NewRecorder()
+		m.ServeHTTP(resp, httptest.NewRequest(tc.method, tc.target, nil))
+		assert.Equal(t, tc.status, resp.Code, "%s %s", tc.method, tc.target)
+	}
+	waitFinished(t, m, started.ID)
+
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/operations/"+started.ID, nil))
+	assert.Equal(t, http.StatusConflict, resp.Code)
+
+	resp = httptest.NewRecorder()
+	m.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/operations", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var statuses []*Status
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&statuses))
+	require.Len(t, statuses, 1)
+	assert.Equal(t, Cancelled, statuses[0].State)
+	assert.Equal(t, "mychannel", statuses[0].Target)
+}
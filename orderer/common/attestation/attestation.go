@@ -0,0 +1,220 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package attestation serves a signed statement of the orderer's identity,
+// so that clients and peers can pin and verify the node they talk to before
+// submitting transactions.  The statement carries the signing certificate
+// chain, the TLS certificate, the capabilities enabled on the system channel
+// and a timestamp, and is signed with the orderer's signing identity.
+package attestation
+
+import (
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/flogging"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/attestation"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// maxNonceLength bounds the size of the client supplied nonce.
+const maxNonceLength = 256
+
+// Identity is the static part of the orderer's identity.
+type Identity struct {
+	// MSPID is the identifier of the orderer's local MSP.
+	MSPID string
+
+	// SigningCertChain is the PEM encoded signing certificate of the
+	// orderer, followed by the intermediate and root certificates of its MSP.
+	SigningCertChain [][]byte
+
+	// TLSCert is the PEM encoded TLS certificate of the orderer, if TLS is enabled.
+	TLSCert []byte
+}
+
+// Capabilities are the capabilities enabled on the system channel.
+type Capabilities struct {
+	Channel []string `json:"channel"`
+	Orderer []string `json:"orderer"`
+}
+
+// Statement is the content of an attestation.
+type Statement struct {
+	MSPID            string       `json:"mspid"`
+	SigningCertChain []string     `json:"signing_cert_chain"`
+	TLSCert          string       `json:"tls_cert,omitempty"`
+	Capabilities     Capabilities `json:"capabilities"`
+	Timestamp        time.Time    `json:"timestamp"`
+	Nonce            string       `json:"nonce,omitempty"`
+}
+
+// Attestation is the response of the attestation endpoint.  The statement is
+// the JSON encoding of a Statement and the signature is computed over these
+// exact bytes, so verifiers need not re-encode the statement.
+type Attestation struct {
+	Statement []byte `json:"statement"`
+	Signature []byte `json:"signature"`
+}
+
+// Handler serves attestations.  A client may bind the attestation to its
+// request by passing a nonce query parameter, which is included in the
+// signed statement.
+type Handler struct {
+	identity     Identity
+	capabilities func() Capabilities
+	signer       crypto.Signer
+	now          func() time.Time
+}
+
+// NewHandler creates a Handler attesting the identity, and the capabilities
+// returned by the function at the time of each request.
+func NewHandler(identity Identity, capabilities func() Capabilities, signer crypto.Signer) *Handler {
+	return &Handler{
+		identity:     identity,
+		capabilities: capabilities,
+		signer:       signer,
+		now:          time.Now,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+	nonce := r.URL.Query().Get("nonce")
+	if len(nonce) > maxNonceLength {
+		http.Error(w, "nonce too long", http.StatusBadRequest)
+		return
+	}
+
+	attestation, err := h.attest(nonce)
+	if err != nil {
+		logger.Errorf("Failed creating attestation: %s", err)
+		http.Error(w, "failed creating attestation", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(attestation); err != nil {
+		logger.Warningf("Failed writing attestation: %s", err)
+	}
+}
+
+func (h *Handler) attest(nonce string) (*Attestation, error) {
+	statement := &Statement{
+		MSPID:        h.identity.MSPID,
+		TLSCert:      string(h.identity.TLSCert),
+		Capabilities: h.capabilities(),
+		Timestamp:    h.now().UTC(),
+		Nonce:        nonce,
+	}
+	for _, cert := range h.identity.SigningCertChain {
+		statement.SigningCertChain = append(statement.SigningCertChain, string(cert))
+	}
+
+	statementBytes, err := json.Marshal(statement)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed marshaling statement")
+	}
+	signature, err := h.signer.Sign(statementBytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed signing statement")
+	}
+	return &Attestation{
+		Statement: statementBytes,
+		Signature: signature,
+	}, nil
+}
+
+// Verify checks that the attestation is signed by the first certificate of
+// its signing certificate chain and returns its statement.  Callers are
+// responsible for checking the chain, the timestamp and the nonce against
+// their expectations.
+func Verify(attestation *Attestation) (*Statement, error) {
+	statement := &Statement{}
+	if err := json.Unmarshal(attestation.Statement, statement); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshaling statement")
+	}
+	if len(statement.SigningCertChain) == 0 {
+		return nil, errors.New("statement contains no signing certificate")
+	}
+	block, _ := pem.Decode([]byte(statement.SigningCertChain[0]))
+	if block == nil {
+		return nil, errors.New("signing certificate is not PEM encoded")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed parsing signing certificate")
+	}
+	publicKey, ok := cert.PublicKey.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, errors.Errorf("unsupported signing key type %T", cert.PublicKey)
+	}
+
+	sig := struct{ R, S *big.Int }{}
+	if _, err := asn1.Unmarshal(attestation.Signature, &sig); err != nil {
+		return nil, errors.Wrap(err, "failed unmarshaling signature")
+	}
+	digest := sha256.Sum256(attestation.Statement)
+	if !ecdsa.Verify(publicKey, digest[:], sig.R, sig.S) {
+		return nil, errors.New("signature verification failed")
+	}
+	return statement, nil
+}
+
+// CapabilitiesFromConfig returns the capabilities enabled in a channel config.
+func CapabilitiesFromConfig(config *cb.Config) Capabilities {
+	var capabilities Capabilities
+	if config == nil || config.ChannelGroup == nil {
+		return capabilities
+	}
+	capabilities.Channel = capabilityNames(config.ChannelGroup)
+	if orderer, ok := config.ChannelGroup.Groups[channelconfig.OrdererGroupKey]; ok {
+		capabilities.Orderer = capabilityNames(orderer)
+	}
+	return capabilities
+}
+
+func capabilityNames(group *cb.ConfigGroup) []string {
+	value, ok := group.Values[channelconfig.CapabilitiesKey]
+	if !ok {
+		return nil
+	}
+	capabilities := &cb.Capabilities{}
+	if err := proto.Unmarshal(value.Value, capabilities); err != nil {
+		logger.Warningf("Failed unmarshaling capabilities: %s", err)
+		return nil
+	}
+	var names []string
+	for name := range capabilities.Capabilities {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,130 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package attestation
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/crypto/tlsgen"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type ecdsaSigner struct {
+	key *ecdsa.PrivateKey
+}
+
+func (s *ecdsaSigner) Sign(message []byte) ([]byte, error) {
+	digest := sha256.Sum256(message)
+	r, ss, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(struct{ R, S interface{} }{r, ss})
+}
+
+func newTestHandler(t *testing.T) *Handler {
+	ca, err := tlsgen.NewCA()
+	require.NoError(t, err)
+	pair, err := ca.NewClientCertKeyPair()
+	require.NoError(t, err)
+
+	h := NewHandler(Identity{
+		MSPID:            "OrdererMSP",
+		SigningCertChain: [][]byte{pair.Cert, ca.CertBytes()},
+		TLSCert:          []byte("tls-cert"),
+	}, func() Capabilities {
+		return Capabilities{Channel: []string{"V1_1"}}
+	}, &ecdsaSigner{key: pair.Signer.(*ecdsa.PrivateKey)})
+	h.now = func() time.Time { return time.Unix(1000, 0) }
+	return h
+}
+
+func TestHandler(t *testing.T) {
+	h := newTestHandler(t)
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/attestation?nonce=abc", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+
+	attestation := &Attestation{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), attestation))
+	statement, err := Verify(attestation)
+	require.NoError(t, err)
+	assert.Equal(t, "OrdererMSP", statement.MSPID)
+	assert.Len(t, statement.SigningCertChain, 2)
+	assert.Equal(t, "tls-cert", statement.TLSCert)
+	assert.Equal(t, []string{"V1_1"}, statement.Capabilities.Channel)
+	assert.Equal(t, "abc", statement.Nonce)
+	assert.True(t, statement.Timestamp.Equal(time.Unix(1000, 0)))
+
+	attestation.Statement = []byte(strings.Replace(string(attestation.Statement), "abc", "abd", 1))
+	_, err = Verify(attestation)
+	assert.EqualError(t, err, "signature verification failed")
+}
+
+func TestHandlerBadRequests(t *testing.T) {
+	h := newTestHandler(t)
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/attestation", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/attestation?nonce="+strings.Repeat("a", maxNonceLength+1), nil))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+}
+
+func TestVerifyErrors(t *testing.T) {
+	_, err := Verify(&Attestation{Statement: []byte("{}")})
+	assert.EqualError(t, err, "statement contains no signing certificate")
+
+	_, err = Verify(&Attestation{Statement: []byte(`{"signing_cert_chain":["garbage"]}`)})
+	assert.EqualError(t, err, "signing certificate is not PEM encoded")
+}
+
+func TestCapabilitiesFromConfig(t *testing.T) {
+	capabilitiesValue := func(names ...string) *cb.ConfigValue {
+		capabilities := &cb.Capabilities{Capabilities: map[string]*cb.Capability{}}
+		for _, name := range names {
+			capabilities.Capabilities[name] = &cb.Capability{}
+		}
+		return &cb.ConfigValue{Value: utils.MarshalOrPanic(capabilities)}
+	}
+	config := &cb.Config{
+		ChannelGroup: &cb.ConfigGroup{
+			Values: map[string]*cb.ConfigValue{
+				channelconfig.CapabilitiesKey: capabilitiesValue("V1_1"),
+			},
+			Groups: map[string]*cb.ConfigGroup{
+				channelconfig.OrdererGroupKey: {
+					Values: map[string]*cb.ConfigValue{
+						channelconfig.CapabilitiesKey: capabilitiesValue("V1_1", "V1_0_2"),
+					},
+				},
+			},
+		},
+	}
+
+	assert.Equal(t, Capabilities{
+		Channel: []string{"V1_1"},
+		Orderer: []string{"V1_0_2", "V1_1"},
+	}, CapabilitiesFromConfig(config))
+	assert.Equal(t, Capabilities{}, CapabilitiesFromConfig(nil))
+}
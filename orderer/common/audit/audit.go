@@ -0,0 +1,331 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package audit keeps a trail of the messages broadcast to the orderer, one
+// JSON line per message, in which each line is chained to the previous one by
+// its hash so that lines removed or altered afterwards can be detected.
+package audit
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/orderer/common/privacy"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/audit"
+
+// rotatedTimeFormat is the format of the suffix of the rotated audit logs,
+// which sorts in the order they were rotated
+const rotatedTimeFormat = "20060102T150405.000000000"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// Record describes the handling of a message broadcast to the orderer
+type Record struct {
+	TxID          string    `json:"tx_id"`
+	ChannelID     string    `json:"channel_id"`
+	ClientSubject string    `json:"client_subject"`
+	RemoteAddress string    `json:"remote_address"`
+	Status        string    `json:"status"`
+	Received      time.Time `json:"received"`
+	Responded     time.Time `json:"responded"`
+}
+
+// Entry is a line of the audit log: a record numbered and chained to the
+// line before it.  The hash is the SHA-256 of the hash of the previous line
+// followed by the JSON encoding of the entry without its hash.
+type Entry struct {
+	Seq uint64 `json:"seq"`
+	Record
+	PrevHash string `json:"prev_hash"`
+	Hash     string `json:"hash,omitempty"`
+}
+
+// computeHash returns the hash of the entry
+func (e Entry) computeHash() (string, error) {
+	e.Hash = ""
+	data, err := json.Marshal(e)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write([]byte(e.PrevHash))
+	h.Write(data)
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Config configures a Log
+type Config struct {
+	// Path is the path of the file the log is written to.
+	Path string
+
+	// MaxFileSize is the size in bytes past which the file is rotated, or zero
+	// to never rotate it.  Rotated files are renamed with the time of their
+	// rotation appended to the path, and the chain continues in the new file.
+	MaxFileSize int64
+
+	// MaxBackups is the number of rotated files kept, or zero to keep them all.
+	MaxBackups int
+
+	// Anonymizer anonymizes the client subjects of the records, or is nil to
+	// write them as they are.  The remote addresses are anonymized by the
+	// server before they reach the broadcast handler.
+	Anonymizer *privacy.Anonymizer
+}
+
+// Log writes the audit records to a file
+type Log struct {
+	conf Config
+
+	mutex    sync.Mutex
+	file     *os.File
+	size     int64
+	seq      uint64
+	lastHash string
+}
+
+// NewLog opens the audit log for appending.  If the file exists, the chain
+// is verified and continued from its last line, or from the last line of the
+// latest rotated file if it is empty, so that an orderer does not start on a
+// log which was tampered with.
+func NewLog(conf Config) (*Log, error) {
+	l := &Log{conf: conf}
+	last, err := lastEntry(conf.Path)
+	if err != nil {
+		return nil, err
+	}
+	if last.Seq == 0 {
+		backups, err := Backups(conf.Path)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to list rotated audit logs")
+		}
+		if len(backups) > 0 {
+			if last, err = lastEntry(backups[len(backups)-1]); err != nil {
+				return nil, err
+			}
+		}
+	}
+	l.seq, l.lastHash = last.Seq, last.Hash
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// lastEntry verifies the file at path and returns its last entry, or an
+// empty entry if the file is empty or does not exist
+func lastEntry(path string) (Entry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return Entry{}, nil
+	}
+	if err != nil {
+		return Entry{}, errors.Wrap(err, "failed to open audit log")
+	}
+	defer f.Close()
+	last, err := verify(f, "", true)
+	if err != nil {
+		return Entry{}, errors.WithMessage(err, "existing audit log "+path+" is invalid")
+	}
+	return last, nil
+}
+
+func (l *Log) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.conf.Path), 0755); err != nil {
+		return errors.Wrap(err, "failed to create directory of audit log")
+	}
+	f, err := os.OpenFile(l.conf.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return errors.Wrap(err, "failed to open audit log")
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return errors.Wrap(err, "failed to open audit log")
+	}
+	l.file, l.size = f, info.Size()
+	return nil
+}
+
+// Audit writes the record to the log, logging the error if it cannot be
+// written, so that the broadcast service does not depend on the audit log.
+func (l *Log) Audit(rec *Record) {
+	if err := l.Write(rec); err != nil {
+		logger.Errorf("Failed to write audit record of transaction %s on channel %s: %s", rec.TxID, rec.ChannelID, err)
+	}
+}
+
+// Write appends the record to the log.
+func (l *Log) Write(rec *Record) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return errors.New("audit log is closed")
+	}
+	entry := Entry{Seq: l.seq + 1, Record: *rec, PrevHash: l.lastHash}
+	entry.ClientSubject = l.conf.Anonymizer.Subject(entry.ClientSubject)
+	entry.Received, entry.Responded = rec.Received.UTC(), rec.Responded.UTC()
+	hash, err := entry.computeHash()
+	if err != nil {
+		return errors.Wrap(err, "failed to hash audit record")
+	}
+	entry.Hash = hash
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal audit record")
+	}
+	line = append(line, '\n')
+
+	if l.conf.MaxFileSize > 0 && l.size > 0 && l.size+int64(len(line)) > l.conf.MaxFileSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+	n, err := l.file.Write(line)
+	l.size += int64(n)
+	if err != nil {
+		return errors.Wrap(err, "failed to write audit record")
+	}
+	l.seq, l.lastHash = entry.Seq, entry.Hash
+	return nil
+}
+
+// Rotate renames the log aside and starts a new one, even if the log has not
+// reached its maximum size, such as during a maintenance window.
+func (l *Log) Rotate() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if l.file == nil {
+		return errors.New("audit log is closed")
+	}
+	return l.rotate()
+}
+
+// rotate renames the file of the log and opens a new one, removing the
+// oldest rotated files beyond the number kept
+func (l *Log) rotate() error {
+	if err := l.file.Close(); err != nil {
+		return errors.Wrap(err, "failed to close audit log")
+	}
+	l.file = nil
+	rotated := l.conf.Path + "." + time.Now().UTC().Format(rotatedTimeFormat)
+	if err := os.Rename(l.conf.Path, rotated); err != nil {
+		return errors.Wrap(err, "failed to rotate audit log")
+	}
+	logger.Infof("Rotated audit log to %s", rotated)
+	if err := l.open(); err != nil {
+		return err
+	}
+
+	if l.conf.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := Backups(l.conf.Path)
+	if err != nil {
+		logger.Warningf("Failed to list rotated audit logs: %s", err)
+		return nil
+	}
+	for len(backups) > l.conf.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			logger.Warningf("Failed to remove rotated audit log %s: %s", backups[0], err)
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
+
+// Close closes the file of the log.
+func (l *Log) Close() error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// Backups returns the paths of the files rotated from the audit log at path,
+// oldest first.
+func Backups(path string) ([]string, error) {
+	matches, err := filepath.Glob(path + ".*")
+	if err != nil {
+		return nil, err
+	}
+	var backups []string
+	for _, m := range matches {
+		if _, err := time.Parse(rotatedTimeFormat, m[len(path)+1:]); err == nil {
+			backups = append(backups, m)
+		}
+	}
+	sort.Strings(backups)
+	return backups, nil
+}
+
+// Verify checks the chain of the entries read from r, whose first entry must
+// follow the hash prevHash, and returns the last entry.  The files of a log
+// are verified in order by passing the hash of the last entry of a file as
+// the prevHash of the next one.  If r has no entries, the entry returned
+// carries only prevHash as its hash.
+func Verify(r io.Reader, prevHash string) (Entry, error) {
+	return verify(r, prevHash, false)
+}
+
+// verify checks the chain of the entries read from r, trusting the previous
+// hash of the first entry if trustFirst is set
+func verify(r io.Reader, prevHash string, trustFirst bool) (Entry, error) {
+	last := Entry{Hash: prevHash}
+	first := true
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return last, errors.Wrapf(err, "line after entry %d is not an audit entry", last.Seq)
+		}
+		if first && trustFirst {
+			prevHash = entry.PrevHash
+		}
+		if !first && entry.Seq != last.Seq+1 {
+			return last, errors.Errorf("entry %d follows entry %d", entry.Seq, last.Seq)
+		}
+		if entry.PrevHash != prevHash {
+			return last, errors.Errorf("entry %d does not follow the hash of the previous entry", entry.Seq)
+		}
+		hash, err := entry.computeHash()
+		if err != nil {
+			return last, err
+		}
+		if entry.Hash != hash {
+			return last, errors.Errorf("entry %d does not match its hash", entry.Seq)
+		}
+		first = false
+		last, prevHash = entry, entry.Hash
+	}
+	if err := scanner.Err(); err != nil {
+		return last, errors.Wrap(err, "failed to read audit log")
+	}
+	return last, nil
+}
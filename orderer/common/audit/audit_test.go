@@ -0,0 +1,196 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/orderer/common/privacy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func record(i int) *Record {
+	now := time.Now()
+	return &Record{
+		TxID:          fmt.Sprintf("tx%d", i),
+		ChannelID:     "foo",
+		ClientSubject: "CN=client",
+		RemoteAddress: "127.0.0.1:7050",
+		Status:        "SUCCESS",
+		Received:      now,
+		Responded:     now.Add(time.Millisecond),
+	}
+}
+
+func tempLog(t *testing.T) (string, func()) {
+	dir, err := ioutil.TempDir("", "audit")
+	require.NoError(t, err)
+	return filepath.Join(dir, "audit.log"), func() { os.RemoveAll(dir) }
+}
+
+func TestLogChain(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	l, err := NewLog(Config{Path: path})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, l.Write(record(i)))
+	}
+	require.NoError(t, l.Close())
+	assert.Error(t, l.Write(record(3)))
+
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	last, err := Verify(bytes.NewReader(data), "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), last.Seq)
+	assert.Equal(t, "tx2", last.TxID)
+	assert.Equal(t, "CN=client", last.ClientSubject)
+
+	// 重新打开的日志从最后一行继续哈希链
+	l, err = NewLog(Config{Path: path})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(record(3)))
+	require.NoError(t, l.Close())
+	data, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	last, err = Verify(bytes.NewReader(data), "")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(4), last.Seq)
+}
+
+func TestLogAnonymized(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	anonymizer, err := privacy.New(privacy.Config{Mode: privacy.ModeTruncate, Key: []byte("0123456789abcdef")})
+	require.NoError(t, err)
+	l, err := NewLog(Config{Path: path, Anonymizer: anonymizer})
+	require.NoError(t, err)
+	rec := record(0)
+	rec.ClientSubject = "CN=alice,O=Org1"
+	require.NoError(t, l.Write(rec))
+	require.NoError(t, l.Close())
+
+	// 记录的是匿名化后的主体，链仍可验证
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	last, err := Verify(bytes.NewReader(data), "")
+	require.NoError(t, err)
+	assert.Equal(t, "O=Org1", last.ClientSubject)
+	assert.Equal(t, "CN=alice,O=Org1", rec.ClientSubject)
+}
+
+func TestVerifyTampered(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	l, err := NewLog(Config{Path: path})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, l.Write(record(i)))
+	}
+	require.NoError(t, l.Close())
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	lines := strings.SplitAfter(string(data), "\n")
+
+	altered := strings.Replace(string(data), `"tx_id":"tx1"`, `"tx_id":"tx9"`, 1)
+	_, err = Verify(strings.NewReader(altered), "")
+	assert.EqualError(t, err, "entry 2 does not match its hash")
+
+	removed := lines[0] + lines[2]
+	_, err = Verify(strings.NewReader(removed), "")
+	assert.EqualError(t, err, "entry 3 follows entry 1")
+
+	truncated := lines[1] + lines[2]
+	_, err = Verify(strings.NewReader(truncated), "")
+	assert.EqualError(t, err, "entry 2 does not follow the hash of the previous entry")
+
+	_, err = Verify(strings.NewReader(lines[0]+"garbage\n"), "")
+	assert.Error(t, err)
+
+	// 被篡改的日志不能继续写入
+	require.NoError(t, ioutil.WriteFile(path, []byte(altered), 0640))
+	_, err = NewLog(Config{Path: path})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "is invalid: entry 2 does not match its hash")
+}
+
+func TestLogRotation(t *testing.T) {
+	path, cleanup := tempLog(t)
+	defer cleanup()
+
+	l, err := NewLog(Config{Path: path, MaxFileSize: 1, MaxBackups: 2})
+	require.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		require.NoError(t, l.Write(record(i)))
+		// 轮转文件名精确到纳秒，确保按轮转顺序排列
+		time.Sleep(time.Millisecond)
+	}
+	require.NoError(t, l.Close())
+
+	backups, err := Backups(path)
+	require.NoError(t, err)
+	require.Len(t, backups, 2)
+
+	// 保留的轮转文件与当前文件组成连续的哈希链，最早的文件接在被删除的文件之后
+	data, err := ioutil.ReadFile(backups[0])
+	require.NoError(t, err)
+	var prev Entry
+	require.NoError(t, json.Unmarshal(data[:bytes.IndexByte(data, '\n')], &prev))
+	assert.Equal(t, uint64(2), prev.Seq)
+	prev.Hash = prev.PrevHash
+	for _, p := range append(backups, path) {
+		data, err := ioutil.ReadFile(p)
+		require.NoError(t, err)
+		prev, err = Verify(bytes.NewReader(data), prev.Hash)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, uint64(4), prev.Seq)
+
+	// 当前文件不存在时从最新的轮转文件继续哈希链
+	require.NoError(t, os.Rename(path, path+"."+time.Now().UTC().Format(rotatedTimeFormat)))
+	l, err = NewLog(Config{Path: path})
+	require.NoError(t, err)
+	require.NoError(t, l.Write(record(4)))
+	require.NoError(t, l.Close())
+	data, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	last, err := Verify(bytes.NewReader(data), prev.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), last.Seq)
+
+	// 未达到文件大小上限时也可以强制轮转
+	before, err := Backups(path)
+	require.NoError(t, err)
+	l, err = NewLog(Config{Path: path})
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	require.NoError(t, l.Rotate())
+	require.NoError(t, l.Write(record(5)))
+	require.NoError(t, l.Close())
+	assert.EqualError(t, l.Rotate(), "audit log is closed")
+	after, err := Backups(path)
+	require.NoError(t, err)
+	assert.Len(t, after, len(before)+1)
+	data, err = ioutil.ReadFile(path)
+	require.NoError(t, err)
+	last, err = Verify(bytes.NewReader(data), last.Hash)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), last.Seq)
+}
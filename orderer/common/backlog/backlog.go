@@ -0,0 +1,349 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package backlog queues the messages submitted to consenters within a
+// memory budget shared by every channel, spilling the messages beyond the
+// budget to disk, so that a stalled consenter cannot get the orderer killed
+// for running out of memory.
+package backlog
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/backlog"
+
+// recordHeaderSize is the size of the length prefixing each spilled message
+const recordHeaderSize = 4
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// ErrFull is returned for the messages which neither fit in the memory
+// budget nor in the space left to spill them.
+var ErrFull = errors.New("backlog of the consenter is full")
+
+// Config contains the limits of the backlogs.
+type Config struct {
+	// MaxMemory is the number of bytes of the messages queued in memory by
+	// every channel together
+	MaxMemory int64
+
+	// SpillDir is the directory the messages beyond MaxMemory are spilled
+	// to, none if empty, in which case they are rejected
+	SpillDir string
+
+	// MaxSpillSize is the number of bytes of the messages spilled by each
+	// channel, no limit if 0
+	MaxSpillSize int64
+
+	// MaxPriority is the number of priority messages each channel queues
+	// ahead of its other messages, none if 0, in which case the priority
+	// messages are queued as any other
+	MaxPriority int
+}
+
+// Pool accounts for the memory of the queues of every channel.
+type Pool struct {
+	conf Config
+
+	mutex  sync.Mutex
+	memory int64
+}
+
+// NewPool creates a Pool, creating the spill directory if it is set.
+func NewPool(conf Config) (*Pool, error) {
+	if conf.MaxMemory <= 0 {
+		return nil, errors.Errorf("maximum backlog memory must be positive, got %d", conf.MaxMemory)
+	}
+	if conf.MaxSpillSize < 0 {
+		return nil, errors.Errorf("maximum spill size must not be negative, got %d", conf.MaxSpillSize)
+	}
+	if conf.MaxPriority < 0 {
+		return nil, errors.Errorf("maximum priority messages must not be negative, got %d", conf.MaxPriority)
+	}
+	if conf.SpillDir != "" {
+		if err := os.MkdirAll(conf.SpillDir, 0750); err != nil {
+			return nil, errors.Wrapf(err, "failed to create spill directory %s", conf.SpillDir)
+		}
+	}
+	return &Pool{conf: conf}, nil
+}
+
+// Memory returns the number of bytes of the messages queued in memory.
+func (p *Pool) Memory() int64 {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	return p.memory
+}
+
+func (p *Pool) reserve(size int64) bool {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	if p.memory+size > p.conf.MaxMemory {
+		return false
+	}
+	p.memory += size
+	return true
+}
+
+func (p *Pool) release(size int64) {
+	p.mutex.Lock()
+	p.memory -= size
+	p.mutex.Unlock()
+}
+
+// Queue is the FIFO backlog of the messages of a channel.  The messages are
+// kept in memory while the budget of the Pool allows, and spilled to disk
+// from then on until the spilled messages are drained, so that their order
+// is preserved.  The priority messages are received before the others, in
+// the order they were pushed, so that a config update does not wait behind a
+// deep backlog of transactions.
+type Queue struct {
+	pool *Pool
+	name string
+	out  chan []byte
+	quit chan struct{}
+	done chan struct{}
+
+	mutex    sync.Mutex
+	cond     *sync.Cond
+	closed   bool
+	memory   [][]byte
+	priority [][]byte
+
+	// the spilled messages are in the spill file from readOffset to
+	// writeOffset
+	spill       *os.File
+	readOffset  int64
+	writeOffset int64
+}
+
+// NewQueue creates the queue of the channel, discarding the messages it
+// spilled before the orderer restarted, as the queued messages are not
+// persistent.
+func (p *Pool) NewQueue(channelID string) (*Queue, error) {
+	q := &Queue{
+		pool: p,
+		name: channelID,
+		out:  make(chan []byte),
+		quit: make(chan struct{}),
+		done: make(chan struct{}),
+	}
+	q.cond = sync.NewCond(&q.mutex)
+	if p.conf.SpillDir != "" {
+		spill, err := os.OpenFile(filepath.Join(p.conf.SpillDir, channelID+".spill"), os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0640)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open spill file of channel %s", channelID)
+		}
+		q.spill = spill
+	}
+	go q.feed()
+	return q, nil
+}
+
+// Push queues the message, returning ErrFull if it can be neither kept in
+// memory nor spilled.
+func (q *Queue) Push(msg []byte) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return errors.New("backlog closed")
+	}
+
+	size := int64(len(msg))
+	if q.spilled() == 0 && q.pool.reserve(size) {
+		q.memory = append(q.memory, msg)
+		q.cond.Signal()
+		return nil
+	}
+	if q.spill == nil {
+		return ErrFull
+	}
+	if max := q.pool.conf.MaxSpillSize; max > 0 && q.spilled()+recordHeaderSize+size > max {
+		return ErrFull
+	}
+
+	record := make([]byte, recordHeaderSize+len(msg))
+	binary.BigEndian.PutUint32(record, uint32(len(msg)))
+	copy(record[recordHeaderSize:], msg)
+	if _, err := q.spill.WriteAt(record, q.writeOffset); err != nil {
+		return errors.Wrap(err, "failed to spill message")
+	}
+	if q.spilled() == 0 {
+		logger.Warningf("[channel: %s] Backlog memory exhausted, spilling messages to disk", q.name)
+	}
+	q.writeOffset += int64(len(record))
+	q.cond.Signal()
+	return nil
+}
+
+// PushPriority queues the message ahead of the messages pushed by Push,
+// returning ErrFull if MaxPriority messages are already queued ahead.  The
+// priority messages are kept in memory outside of the budget of the Pool,
+// which MaxPriority bounds instead.
+func (q *Queue) PushPriority(msg []byte) error {
+	if q.pool.conf.MaxPriority == 0 {
+		return q.Push(msg)
+	}
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if q.closed {
+		return errors.New("backlog closed")
+	}
+	if len(q.priority) >= q.pool.conf.MaxPriority {
+		return ErrFull
+	}
+	logger.Debugf("[channel: %s] Queueing priority message ahead of %d messages in memory and %d bytes spilled", q.name, len(q.memory), q.spilled())
+	q.priority = append(q.priority, msg)
+	q.cond.Signal()
+	return nil
+}
+
+// Out returns the channel the messages are received from, the priority
+// messages first and then the others, each in the order they were pushed.
+// It is closed once the Queue is.
+func (q *Queue) Out() <-chan []byte {
+	return q.out
+}
+
+// Spilled returns the number of bytes spilled to disk and not drained yet.
+func (q *Queue) Spilled() int64 {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.spilled()
+}
+
+// Close discards the queued messages and removes the spill file.
+func (q *Queue) Close() {
+	q.mutex.Lock()
+	if q.closed {
+		q.mutex.Unlock()
+		return
+	}
+	q.closed = true
+	close(q.quit)
+	q.cond.Signal()
+	q.mutex.Unlock()
+	<-q.done
+
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for _, msg := range q.memory {
+		q.pool.release(int64(len(msg)))
+	}
+	q.memory, q.priority = nil, nil
+	if q.spill != nil {
+		q.spill.Close()
+		os.Remove(q.spill.Name())
+	}
+}
+
+func (q *Queue) spilled() int64 {
+	return q.writeOffset - q.readOffset
+}
+
+// feed sends the messages to Out.  A message stays accounted for in the
+// queue until it is received.
+func (q *Queue) feed() {
+	defer close(q.done)
+	defer close(q.out)
+	for {
+		msg, size, priority, err := q.next()
+		if err != nil {
+			logger.Errorf("[channel: %s] Dropping the spilled messages, could not read them back: %s", q.name, err)
+			q.dropSpilled()
+			continue
+		}
+		if msg == nil {
+			return
+		}
+		select {
+		case q.out <- msg:
+			q.remove(size, priority)
+		case <-q.quit:
+			return
+		}
+	}
+}
+
+// next waits for the oldest priority message, or the oldest message if there
+// is none, returning nil once the Queue is closed
+func (q *Queue) next() ([]byte, int64, bool, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	for !q.closed && len(q.priority) == 0 && len(q.memory) == 0 && q.spilled() == 0 {
+		q.cond.Wait()
+	}
+	if q.closed {
+		return nil, 0, false, nil
+	}
+	if len(q.priority) > 0 {
+		return q.priority[0], int64(len(q.priority[0])), true, nil
+	}
+	if len(q.memory) > 0 {
+		return q.memory[0], int64(len(q.memory[0])), false, nil
+	}
+
+	header := make([]byte, recordHeaderSize)
+	if _, err := q.spill.ReadAt(header, q.readOffset); err != nil {
+		return nil, 0, false, err
+	}
+	msg := make([]byte, binary.BigEndian.Uint32(header))
+	if _, err := q.spill.ReadAt(msg, q.readOffset+recordHeaderSize); err != nil {
+		return nil, 0, false, err
+	}
+	return msg, recordHeaderSize + int64(len(msg)), false, nil
+}
+
+// remove forgets the oldest message, or the oldest priority message, once
+// received
+func (q *Queue) remove(size int64, priority bool) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if priority {
+		q.priority[0] = nil
+		q.priority = q.priority[1:]
+		return
+	}
+	if len(q.memory) > 0 {
+		q.memory[0] = nil
+		q.memory = q.memory[1:]
+		q.pool.release(size)
+		return
+	}
+	q.readOffset += size
+	if q.spilled() == 0 {
+		q.truncate()
+		logger.Infof("[channel: %s] Spilled backlog drained", q.name)
+	}
+}
+
+func (q *Queue) dropSpilled() {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.readOffset = q.writeOffset
+	q.truncate()
+}
+
+// truncate empties the spill file once its messages are drained.  It must
+// be called with the mutex held.
+func (q *Queue) truncate() {
+	q.readOffset, q.writeOffset = 0, 0
+	if err := q.spill.Truncate(0); err != nil {
+		logger.Warningf("[channel: %s] Could not truncate spill file: %s", q.name, err)
+	}
+}
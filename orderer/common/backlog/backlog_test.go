@@ -0,0 +1,162 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backlog
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, q *Queue) []byte {
+	select {
+	case msg := <-q.Out():
+		return msg
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for message")
+		return nil
+	}
+}
+
+// eventually waits for the messages received to be removed from the queues
+func eventually(size func() int64, expected int64) int64 {
+	for i := 0; i < 100 && size() != expected; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	return size()
+}
+
+func TestNewPool(t *testing.T) {
+	_, err := NewPool(Config{})
+	assert.EqualError(t, err, "maximum backlog memory must be positive, got 0")
+	_, err = NewPool(Config{MaxMemory: 10, MaxSpillSize: -1})
+	assert.EqualError(t, err, "maximum spill size must not be negative, got -1")
+	_, err = NewPool(Config{MaxMemory: 10, MaxPriority: -1})
+	assert.EqualError(t, err, "maximum priority messages must not be negative, got -1")
+}
+
+func TestMemoryOnly(t *testing.T) {
+	pool, err := NewPool(Config{MaxMemory: 10})
+	require.NoError(t, err)
+	foo, err := pool.NewQueue("foo")
+	require.NoError(t, err)
+	defer foo.Close()
+	bar, err := pool.NewQueue("bar")
+	require.NoError(t, err)
+	defer bar.Close()
+
+	// 内存预算由所有通道共享
+	assert.NoError(t, foo.Push([]byte("123456")))
+	assert.NoError(t, bar.Push([]byte("1234")))
+	assert.Equal(t, ErrFull, foo.Push([]byte("1")))
+	assert.Equal(t, int64(10), pool.Memory())
+
+	assert.Equal(t, []byte("123456"), receive(t, foo))
+	assert.Equal(t, int64(4), eventually(pool.Memory, 4))
+	assert.NoError(t, bar.Push([]byte("abc")))
+	assert.Equal(t, []byte("1234"), receive(t, bar))
+	assert.Equal(t, []byte("abc"), receive(t, bar))
+	assert.Equal(t, int64(0), eventually(pool.Memory, 0))
+}
+
+func TestSpill(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backlog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pool, err := NewPool(Config{MaxMemory: 8, SpillDir: dir, MaxSpillSize: 23})
+	require.NoError(t, err)
+	q, err := pool.NewQueue("foo")
+	require.NoError(t, err)
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, q.Push([]byte(fmt.Sprintf("msg%d", i))))
+	}
+	assert.Equal(t, int64(16), q.Spilled())
+	assert.Equal(t, ErrFull, q.Push([]byte("msg4")))
+
+	// 先取出内存中的消息，再按顺序取出写入磁盘的消息
+	assert.Equal(t, []byte("msg0"), receive(t, q))
+	assert.NoError(t, q.Push([]byte("new")))
+	for _, expected := range []string{"msg1", "msg2", "msg3", "new"} {
+		assert.Equal(t, []byte(expected), receive(t, q))
+	}
+	assert.Equal(t, int64(0), eventually(q.Spilled, 0))
+	assert.Equal(t, int64(0), pool.Memory())
+
+	info, err := os.Stat(filepath.Join(dir, "foo.spill"))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+
+	// 磁盘已清空，新消息重新放入内存
+	assert.NoError(t, q.Push([]byte("msg5")))
+	assert.Equal(t, int64(0), q.Spilled())
+	assert.Equal(t, int64(4), eventually(pool.Memory, 4))
+
+	q.Close()
+	_, ok := <-q.Out()
+	assert.False(t, ok)
+	assert.Equal(t, int64(0), eventually(pool.Memory, 0))
+	assert.Error(t, q.Push([]byte("msg6")))
+	_, err = os.Stat(filepath.Join(dir, "foo.spill"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPriority(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backlog")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	pool, err := NewPool(Config{MaxMemory: 8, SpillDir: dir, MaxPriority: 2})
+	require.NoError(t, err)
+	q, err := pool.NewQueue("foo")
+	require.NoError(t, err)
+	defer q.Close()
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, q.Push([]byte(fmt.Sprintf("msg%d", i))))
+	}
+	assert.Equal(t, int64(8), q.Spilled())
+
+	// 优先消息不占用内存预算，数量超过上限时被拒绝
+	assert.NoError(t, q.PushPriority([]byte("config0")))
+	assert.NoError(t, q.PushPriority([]byte("config1")))
+	assert.Equal(t, ErrFull, q.PushPriority([]byte("config2")))
+
+	// 接收协程最多已取出一条普通消息，其余普通消息包括写入磁盘的消息都排在优先消息之后
+	var received []string
+	for i := 0; i < 5; i++ {
+		received = append(received, string(receive(t, q)))
+	}
+	if received[0] == "msg0" {
+		assert.Equal(t, []string{"msg0", "config0", "config1", "msg1", "msg2"}, received)
+	} else {
+		assert.Equal(t, []string{"config0", "config1", "msg0", "msg1", "msg2"}, received)
+	}
+	assert.Equal(t, int64(0), eventually(pool.Memory, 0))
+	assert.NoError(t, q.PushPriority([]byte("config2")))
+	assert.Equal(t, []byte("config2"), receive(t, q))
+}
+
+func TestPriorityDisabled(t *testing.T) {
+	pool, err := NewPool(Config{MaxMemory: 4})
+	require.NoError(t, err)
+	q, err := pool.NewQueue("foo")
+	require.NoError(t, err)
+	defer q.Close()
+
+	// 未配置优先通道时优先消息与其他消息一样排队
+	assert.NoError(t, q.PushPriority([]byte("msg0")))
+	assert.Equal(t, ErrFull, q.PushPriority([]byte("msg1")))
+	assert.Equal(t, []byte("msg0"), receive(t, q))
+}
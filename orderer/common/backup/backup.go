@@ -0,0 +1,484 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package backup takes consistent backups of the block ledger of a channel,
+// along with the write-ahead log of its consenter, while the orderer runs.
+//
+// The height of the ledger is pinned while no block is being committed, the
+// write-ahead log being copied at that point, and the block files are then
+// copied up to the last block under that height.  The block files being
+// only appended to, the blocks copied cannot change nor be torn by the
+// blocks committed meanwhile.  The block index is not backed up: the file
+// ledger rebuilds it from the block files of a restored channel.
+//
+// A snapshot is a backup of the blocks of a channel from its latest config
+// block on, from which a new orderer joins the channel without replaying the
+// blocks before it.
+//
+// The backups and snapshots requested over HTTP are taken in the background
+// as asynchronous operations, whose progress is the number of blocks copied.
+package backup
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/ledger/blkstorage/fsblkstorage"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	"github.com/hyperledger/fabric/orderer/common/asyncop"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const pkgLogID = "orderer/common/backup"
+
+var logger = flogging.MustGetLogger(pkgLogID)
+
+const (
+	// ManifestFileName is the name of the manifest of a backup, written
+	// last so that a backup without manifest is known to be incomplete
+	ManifestFileName = "manifest.json"
+
+	// walDirName is the directory of a backup holding the write-ahead log
+	// of the consenter
+	walDirName = "wal"
+
+	blockfilePrefix = "blockfile_"
+)
+
+// ErrUnknownChannel is returned when backing up a channel which does not exist
+var ErrUnknownChannel = errors.New("channel does not exist")
+
+// Channel is a channel whose ledger is backed up
+type Channel interface {
+	// Checkpoint calls fn with the height of the ledger while no block is
+	// being committed
+	Checkpoint(fn func(height uint64) error) error
+
+	// WAL returns the write-ahead log of the consenter of the channel, or
+	// nil if it keeps none
+	WAL() consensus.WALCopier
+
+	// Reader returns the reader of the ledger of the channel
+	Reader() blockledger.Reader
+}
+
+// Channels looks up the channels of the orderer
+type Channels interface {
+	// Channel returns the channel, or false if it does not exist
+	Channel(channelID string) (Channel, bool)
+}
+
+// File is a file of a backup
+type File struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// Manifest describes a backup, the paths of its files being relative to its
+// directory.  The manifest of a snapshot also records its first block, the
+// latest config block of the channel, and the hash of that block.
+type Manifest struct {
+	Channel         string    `json:"channel"`
+	First           uint64    `json:"first,omitempty"`
+	Height          uint64    `json:"height"`
+	ConfigBlockHash string    `json:"config_block_hash,omitempty"`
+	LastBlockHash   string    `json:"last_block_hash"`
+	Created         time.Time `json:"created"`
+	Dir             string    `json:"dir"`
+	Files           []File    `json:"files"`
+}
+
+// Backups takes the backups of the channels of the orderer, one at a time
+type Backups struct {
+	ledgerDir string
+	dir       string
+	channels  Channels
+	ops       *asyncop.Manager
+	now       func() time.Time
+
+	mutex sync.Mutex
+}
+
+// New creates the backups of the channels whose file ledger is in the ledger
+// directory, each backup being written in a directory of its own under dir,
+// the backups requested over HTTP running as operations of ops
+func New(ledgerDir, dir string, channels Channels, ops *asyncop.Manager) *Backups {
+	return &Backups{
+		ledgerDir: ledgerDir,
+		dir:       dir,
+		channels:  channels,
+		ops:       ops,
+		now:       time.Now,
+	}
+}
+
+// Take backs up the channel, reporting the blocks copied to progress if it
+// is not nil, and returns the manifest of the backup.  A backup which fails
+// or whose context is done is removed.
+func (b *Backups) Take(ctx context.Context, channelID string, progress asyncop.Progress) (*Manifest, error) {
+	manifest, err := b.write(ctx, channelID, "", progress, b.take)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("[channel: %s] Backed up %d blocks in %s", channelID, manifest.Height, manifest.Dir)
+	return manifest, nil
+}
+
+// Snapshot takes a snapshot of the channel, reporting the blocks copied to
+// progress if it is not nil, and returns its manifest.  A snapshot which
+// fails or whose context is done is removed.
+func (b *Backups) Snapshot(ctx context.Context, channelID string, progress asyncop.Progress) (*Manifest, error) {
+	manifest, err := b.write(ctx, channelID, snapshotDirPrefix, progress, b.snapshot)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("[channel: %s] Took snapshot of blocks %d to %d in %s", channelID, manifest.First, manifest.Height-1, manifest.Dir)
+	return manifest, nil
+}
+
+// write writes a backup of the channel taken by fn in a directory of its own,
+// named after the time with the given prefix, and then its manifest
+func (b *Backups) write(ctx context.Context, channelID, prefix string, progress asyncop.Progress, fn takeFunc) (*Manifest, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	channel, ok := b.channels.Channel(channelID)
+	if !ok {
+		return nil, ErrUnknownChannel
+	}
+	created := b.now().UTC()
+	dir := filepath.Join(b.dir, channelID, prefix+created.Format("20060102T150405.000000000Z"))
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, errors.Wrapf(err, "failed to create backup directory %s", dir)
+	}
+	manifest, err := fn(channel, channelID, dir, &blockCopier{ctx: ctx, progress: progress})
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	manifest.Created = created
+	manifest.Dir = dir
+	if err := writeManifest(dir, manifest); err != nil {
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	return manifest, nil
+}
+
+// takeFunc writes a backup of the channel in the directory, copying its
+// blocks with the block copier
+type takeFunc func(channel Channel, channelID, dir string, bc *blockCopier) (*Manifest, error)
+
+func (b *Backups) take(channel Channel, channelID, dir string, bc *blockCopier) (*Manifest, error) {
+	manifest := &Manifest{Channel: channelID}
+	err := channel.Checkpoint(func(height uint64) error {
+		manifest.Height = height
+		wal := channel.WAL()
+		if wal == nil {
+			return nil
+		}
+		return errors.WithMessage(wal.CopyWAL(filepath.Join(dir, walDirName)), "failed to back up the write-ahead log")
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bc.src = filepath.Join(b.ledgerDir, fsblkstorage.ChainsDir, channelID)
+	bc.dst = filepath.Join(dir, fsblkstorage.ChainsDir, channelID)
+	bc.height = manifest.Height
+	files, err := bc.copy()
+	if err != nil {
+		return nil, err
+	}
+	if bc.lastHeader != nil {
+		manifest.LastBlockHash = hex.EncodeToString(bc.lastHeader.Hash())
+	}
+
+	// the files of the write-ahead log are hashed once copied
+	walFiles, err := hashTree(filepath.Join(dir, walDirName))
+	if err != nil {
+		return nil, err
+	}
+	manifest.Files = append(files, walFiles...)
+	return manifest, relativize(dir, manifest.Files)
+}
+
+// relativize makes the paths of the files relative to the directory
+func relativize(dir string, files []File) error {
+	for i := range files {
+		path, err := filepath.Rel(dir, files[i].Path)
+		if err != nil {
+			return err
+		}
+		files[i].Path = path
+	}
+	return nil
+}
+
+// ServeHTTP starts backing up the channel of the query parameter on POST,
+// and answers the operation taking the backup, whose result is its manifest.
+func (b *Backups) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	b.serve(w, req, "Backup", b.Take)
+}
+
+// SnapshotHandler returns the handler starting a snapshot of the channel of
+// the query parameter on POST, and answering the operation taking it
+func (b *Backups) SnapshotHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		b.serve(w, req, "Snapshot", b.Snapshot)
+	})
+}
+
+func (b *Backups) serve(w http.ResponseWriter, req *http.Request, kind string, take func(context.Context, string, asyncop.Progress) (*Manifest, error)) {
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	channelID := req.URL.Query().Get("channel")
+	if channelID == "" {
+		http.Error(w, "missing channel", http.StatusBadRequest)
+		return
+	}
+	if _, ok := b.channels.Channel(channelID); !ok {
+		http.Error(w, ErrUnknownChannel.Error(), http.StatusNotFound)
+		return
+	}
+	logger.Infof("[channel: %s] %s requested by %s", channelID, kind, req.RemoteAddr)
+	status, err := b.ops.Start(strings.ToLower(kind), channelID, func(ctx context.Context, progress asyncop.Progress) (interface{}, error) {
+		return take(ctx, channelID, progress)
+	})
+	b.ops.WriteStarted(w, status, err)
+}
+
+// blockCopier copies the blocks of a ledger from the first block to the
+// height from its block files, until its context is done
+type blockCopier struct {
+	ctx      context.Context
+	progress asyncop.Progress
+	src      string
+	dst      string
+	first    uint64
+	height   uint64
+
+	next        uint64
+	firstHeader *cb.BlockHeader
+	lastHeader  *cb.BlockHeader
+}
+
+// copy copies the block files holding the blocks under the height, the last
+// one being cut after the last of them, and returns the files copied.  The
+// blocks before the first block are skipped, and the files copied numbered
+// from zero, so that the copy is the ledger of a channel starting at the
+// first block.
+func (bc *blockCopier) copy() ([]File, error) {
+	if err := os.MkdirAll(bc.dst, 0750); err != nil {
+		return nil, errors.Wrapf(err, "failed to create directory %s", bc.dst)
+	}
+	var files []File
+	for suffix := 0; bc.next < bc.height; suffix++ {
+		name := fmt.Sprintf("%s%06d", blockfilePrefix, suffix)
+		file, err := bc.copyFile(name, fmt.Sprintf("%s%06d", blockfilePrefix, len(files)))
+		if err != nil {
+			return nil, err
+		}
+		if file != nil {
+			files = append(files, *file)
+		}
+	}
+	return files, nil
+}
+
+// copyFile copies the blocks of the block file to the block file of the copy
+// of the given name, and returns nil if none of them is copied
+func (bc *blockCopier) copyFile(name, dstName string) (*File, error) {
+	src, err := os.Open(filepath.Join(bc.src, name))
+	if os.IsNotExist(err) {
+		return nil, errors.Errorf("ledger holds %d blocks, below its height %d", bc.next, bc.height)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open block file")
+	}
+	defer src.Close()
+
+	path := filepath.Join(bc.dst, dstName)
+	dst, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create block file")
+	}
+	defer dst.Close()
+
+	h := sha256.New()
+	w := &countingWriter{w: io.MultiWriter(dst, h)}
+	r := bufio.NewReader(src)
+	for bc.next < bc.height {
+		if err := bc.copyBlock(r, w); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, errors.WithMessage(err, fmt.Sprintf("failed to copy block %d from %s", bc.next, name))
+		}
+	}
+	if w.n == 0 {
+		return nil, errors.Wrap(os.Remove(path), "failed to remove empty block file")
+	}
+	if err := dst.Sync(); err != nil {
+		return nil, errors.Wrap(err, "failed to sync block file")
+	}
+	return &File{Path: path, Size: w.n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// copyBlock copies a block, prefixed by its length, and checks that it is
+// the next block of the ledger.  The blocks before the first block are read
+// but not copied.  It returns io.EOF at the end of the file.
+func (bc *blockCopier) copyBlock(r *bufio.Reader, w io.Writer) error {
+	length, err := binary.ReadUvarint(r)
+	if err == io.EOF {
+		return io.EOF
+	}
+	if err != nil {
+		return errors.Wrap(err, "torn block length")
+	}
+	block := make([]byte, length)
+	if _, err := io.ReadFull(r, block); err != nil {
+		return errors.Wrap(err, "torn block")
+	}
+	header, err := blockHeader(block)
+	if err != nil {
+		return err
+	}
+	if header.Number != bc.next {
+		return errors.Errorf("found block %d instead", header.Number)
+	}
+	if header.Number < bc.first {
+		bc.next++
+		return nil
+	}
+	if err := bc.ctx.Err(); err != nil {
+		return err
+	}
+
+	prefix := make([]byte, binary.MaxVarintLen64)
+	if _, err := w.Write(prefix[:binary.PutUvarint(prefix, length)]); err != nil {
+		return errors.Wrap(err, "failed to write block")
+	}
+	if _, err := w.Write(block); err != nil {
+		return errors.Wrap(err, "failed to write block")
+	}
+	if header.Number == bc.first {
+		bc.firstHeader = header
+	}
+	bc.next++
+	bc.lastHeader = header
+	if bc.progress != nil {
+		bc.progress(bc.next-bc.first, bc.height-bc.first)
+	}
+	return nil
+}
+
+// blockHeader decodes the header of a block serialized in a block file
+func blockHeader(block []byte) (*cb.BlockHeader, error) {
+	buf := proto.NewBuffer(block)
+	header := &cb.BlockHeader{}
+	var err error
+	if header.Number, err = buf.DecodeVarint(); err != nil {
+		return nil, errors.Wrap(err, "malformed block header")
+	}
+	if header.DataHash, err = buf.DecodeRawBytes(false); err != nil {
+		return nil, errors.Wrap(err, "malformed block header")
+	}
+	if header.PreviousHash, err = buf.DecodeRawBytes(false); err != nil {
+		return nil, errors.Wrap(err, "malformed block header")
+	}
+	return header, nil
+}
+
+// hashTree returns the files under the root, which may not exist
+func hashTree(root string) ([]File, error) {
+	var files []File
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if os.IsNotExist(err) && path == root {
+			return filepath.SkipDir
+		}
+		if err != nil || info.IsDir() {
+			return err
+		}
+		file, err := hashFile(path)
+		if err != nil {
+			return err
+		}
+		files = append(files, *file)
+		return nil
+	})
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, errors.Wrap(err, "failed to hash backup files")
+}
+
+func hashFile(path string) (*File, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return nil, err
+	}
+	return &File{Path: path, Size: n, SHA256: hex.EncodeToString(h.Sum(nil))}, nil
+}
+
+// writeManifest writes the manifest of the backup in its directory, the
+// manifest being renamed in place once synced
+func writeManifest(dir string, manifest *Manifest) error {
+	encoded, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "failed to encode manifest")
+	}
+	tmp := filepath.Join(dir, ManifestFileName+".tmp")
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return errors.Wrap(err, "failed to create manifest")
+	}
+	if _, err = f.Write(encoded); err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to write manifest")
+	}
+	return errors.Wrap(os.Rename(tmp, filepath.Join(dir, ManifestFileName)), "failed to write manifest")
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,257 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backup
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/ledger/blkstorage/fsblkstorage"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	fileledger "github.com/hyperledger/fabric/common/ledger/blockledger/file"
+	"github.com/hyperledger/fabric/orderer/common/asyncop"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type walCopier struct{}
+
+func (walCopier) CopyWAL(dir string) error {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, "wal"), []byte("pending"), 0640)
+}
+
+type channel struct {
+	blockledger.ReadWriter
+	wal    consensus.WALCopier
+	height uint64
+}
+
+func (c *channel) Checkpoint(fn func(height uint64) error) error {
+	if c.height != 0 {
+		return fn(c.height)
+	}
+	return fn(c.Height())
+}
+
+func (c *channel) WAL() consensus.WALCopier {
+	return c.wal
+}
+
+func (c *channel) Reader() blockledger.Reader {
+	return c.ReadWriter
+}
+
+type channels map[string]*channel
+
+func (cs channels) Channel(channelID string) (Channel, bool) {
+	c, ok := cs[channelID]
+	if !ok {
+		return nil, false
+	}
+	return c, true
+}
+
+func newLedger(t *testing.T, dir string, blocks int) (blockledger.Factory, blockledger.ReadWriter) {
+	lf := fileledger.New(dir)
+	ledger, err := lf.GetOrCreate("mychannel")
+	require.NoError(t, err)
+	for i := 0; i < blocks; i++ {
+		env := &cb.Envelope{Payload: []byte{byte(i)}}
+		require.NoError(t, ledger.Append(blockledger.CreateNextBlock(ledger, []*cb.Envelope{env})))
+	}
+	return lf, ledger
+}
+
+func TestTake(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	lf, ledger := newLedger(t, filepath.Join(dir, "ledger"), 5)
+	defer lf.Close()
+
+	backups := New(filepath.Join(dir, "ledger"), filepath.Join(dir, "backups"), channels{
+		"mychannel": {ReadWriter: ledger, wal: walCopier{}},
+	}, nil)
+	backups.now = func() time.Time { return time.Date(2018, 1, 2, 3, 4, 5, 0, time.UTC) }
+	manifest, err := backups.Take(context.Background(), "mychannel", nil)
+	require.NoError(t, err)
+
+	assert.Equal(t, "mychannel", manifest.Channel)
+	assert.Equal(t, uint64(5), manifest.Height)
+	assert.Equal(t, filepath.Join(dir, "backups", "mychannel", "20180102T030405.000000000Z"), manifest.Dir)
+	lastBlock := blockledger.GetBlock(ledger, 4)
+	assert.Equal(t, hex.EncodeToString(lastBlock.Header.Hash()), manifest.LastBlockHash)
+	require.Len(t, manifest.Files, 2)
+	assert.Equal(t, filepath.Join("chains", "mychannel", "blockfile_000000"), manifest.Files[0].Path)
+	assert.Equal(t, filepath.Join("wal", "wal"), manifest.Files[1].Path)
+	for _, file := range manifest.Files {
+		hashed, err := hashFile(filepath.Join(manifest.Dir, file.Path))
+		require.NoError(t, err)
+		assert.Equal(t, file.Size, hashed.Size)
+		assert.Equal(t, file.SHA256, hashed.SHA256)
+	}
+
+	// the manifest is written along with the backup
+	encoded, err := ioutil.ReadFile(filepath.Join(manifest.Dir, ManifestFileName))
+	require.NoError(t, err)
+	written := &Manifest{}
+	require.NoError(t, json.Unmarshal(encoded, written))
+	assert.Equal(t, manifest.Files, written.Files)
+
+	// and the ledger restored from the backup rebuilds its index
+	restored, err := fileledger.New(manifest.Dir).GetOrCreate("mychannel")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), restored.Height())
+	assert.Equal(t, lastBlock.Header.Hash(), blockledger.GetBlock(restored, 4).Header.Hash())
+}
+
+func TestTakeCutsBlockFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	lf, ledger := newLedger(t, filepath.Join(dir, "ledger"), 5)
+	defer lf.Close()
+
+	// a block being written after the checkpoint is left out
+	blockfile := filepath.Join(dir, "ledger", fsblkstorage.ChainsDir, "mychannel", "blockfile_000000")
+	f, err := os.OpenFile(blockfile, os.O_WRONLY|os.O_APPEND, 0640)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{100, 1, 2})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	backups := New(filepath.Join(dir, "ledger"), filepath.Join(dir, "backups"), channels{
+		"mychannel": {ReadWriter: ledger, height: 3},
+	}, nil)
+	manifest, err := backups.Take(context.Background(), "mychannel", nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), manifest.Height)
+	assert.Equal(t, hex.EncodeToString(blockledger.GetBlock(ledger, 2).Header.Hash()), manifest.LastBlockHash)
+	require.Len(t, manifest.Files, 1)
+
+	restored, err := fileledger.New(manifest.Dir).GetOrCreate("mychannel")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), restored.Height())
+}
+
+func TestTakeFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	lf, ledger := newLedger(t, filepath.Join(dir, "ledger"), 2)
+	defer lf.Close()
+
+	backups := New(filepath.Join(dir, "ledger"), filepath.Join(dir, "backups"), channels{
+		"mychannel": {ReadWriter: ledger, height: 10},
+	}, nil)
+	_, err = backups.Take(context.Background(), "mychannel", nil)
+	assert.EqualError(t, err, "ledger holds 2 blocks, below its height 10")
+	_, err = backups.Take(context.Background(), "otherchannel", nil)
+	assert.Equal(t, ErrUnknownChannel, err)
+
+	// the failed backup is removed
+	entries, err := ioutil.ReadDir(filepath.Join(dir, "backups", "mychannel"))
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestTakeProgressAndCancel(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	lf, ledger := newLedger(t, filepath.Join(dir, "ledger"), 3)
+	defer lf.Close()
+	backups := New(filepath.Join(dir, "ledger"), filepath.Join(dir, "backups"), channels{
+		"mychannel": {ReadWriter: ledger},
+	}, nil)
+
+	var reported [][2]uint64
+	_, err = backups.Take(context.Background(), "mychannel", func(done, total uint64) {
+		reported = append(reported, [2]uint64{done, total})
+	})
+	require.NoError(t, err)
+	assert.Equal(t, [][2]uint64{{1, 3}, {2, 3}, {3, 3}}, reported)
+
+	// a backup whose context is done is removed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = backups.Take(ctx, "mychannel", nil)
+	assert.Equal(t, context.Canceled, errors.Cause(err))
+	entries, err := ioutil.ReadDir(filepath.Join(dir, "backups", "mychannel"))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1)
+}
+
+func TestServeHTTP(t *testing.T) {
+	dir, err := ioutil.TempDir("", "backup")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	lf, ledger := newLedger(t, filepath.Join(dir, "ledger"), 1)
+	defer lf.Close()
+	ops := asyncop.NewManager("/operations", 0)
+	defer ops.Stop()
+	backups := New(filepath.Join(dir, "ledger"), filepath.Join(dir, "backups"), channels{
+		"mychannel": {ReadWriter: ledger},
+	}, ops)
+
+	for _, tc := range []struct {
+		method string
+		target string
+		status int
+	}{
+		{http.MethodGet, "/backup?channel=mychannel", http.StatusMethodNotAllowed},
+		{http.MethodPost, "/backup", http.StatusBadRequest},
+		{http.MethodPost, "/backup?channel=otherchannel", http.StatusNotFound},
+		{http.MethodPost, "/backup?channel=mychannel", http.StatusAccepted},
+	} {
+		resp := httptest.NewRecorder()
+		backups.ServeHTTP(resp, httptest.NewRequest(tc.method, tc.target, nil))
+		assert.Equal(t, tc.status, resp.Code, tc.target)
+		if tc.status != http.StatusAccepted {
+			continue
+		}
+
+		// the backup is taken by the operation answered
+		started := &asyncop.Status{}
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(started))
+		assert.Equal(t, "/operations/"+started.ID, resp.Header().Get("Location"))
+		assert.Equal(t, "backup", started.Kind)
+		assert.Equal(t, "mychannel", started.Target)
+		status := waitFinished(t, ops, started.ID)
+		assert.Equal(t, asyncop.Succeeded, status.State)
+		assert.Equal(t, uint64(1), status.Done)
+		assert.Equal(t, uint64(1), status.Result.(*Manifest).Height)
+	}
+}
+
+// waitFinished waits for the operation to finish and returns its status
+func waitFinished(t *testing.T, ops *asyncop.Manager, id string) *asyncop.Status {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, ok := ops.Get(id)
+		require.True(t, ok)
+		if status.State != asyncop.Running {
+			return status
+		}
+		require.True(t, time.Now().Before(deadline), "operation %s did not finish", id)
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,212 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hyperledger/fabric/common/ledger/blkstorage/fsblkstorage"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	fileledger "github.com/hyperledger/fabric/common/ledger/blockledger/file"
+	"github.com/hyperledger/fabric/common/tools/blockarchive/archive"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+// snapshotDirPrefix prefixes the directories of the snapshots, which are
+// written along with the backups of their channel
+const snapshotDirPrefix = "snapshot-"
+
+// snapshot copies the blocks of the channel from its latest config block up
+// to the height of the ledger
+func (b *Backups) snapshot(channel Channel, channelID, dir string, bc *blockCopier) (*Manifest, error) {
+	manifest := &Manifest{Channel: channelID}
+	err := channel.Checkpoint(func(height uint64) error {
+		if height == 0 {
+			return errors.New("ledger is empty")
+		}
+		lastBlock := blockledger.GetBlock(channel.Reader(), height-1)
+		if lastBlock == nil {
+			return errors.Errorf("failed to read block %d", height-1)
+		}
+		index, err := utils.GetLastConfigIndexFromBlock(lastBlock)
+		if err != nil {
+			return errors.WithMessage(err, "failed to find the latest config block")
+		}
+		manifest.First = index
+		manifest.Height = height
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	bc.src = filepath.Join(b.ledgerDir, fsblkstorage.ChainsDir, channelID)
+	bc.dst = filepath.Join(dir, fsblkstorage.ChainsDir, channelID)
+	bc.first = manifest.First
+	bc.height = manifest.Height
+	if manifest.Files, err = bc.copy(); err != nil {
+		return nil, err
+	}
+	manifest.ConfigBlockHash = hex.EncodeToString(bc.firstHeader.Hash())
+	manifest.LastBlockHash = hex.EncodeToString(bc.lastHeader.Hash())
+	return manifest, relativize(dir, manifest.Files)
+}
+
+// Import imports the snapshot of the given directory into the file ledger of
+// the ledger directory, from which the orderer then serves the channel as if
+// it had replayed the blocks before the snapshot.  The snapshot must start at
+// the given config block, the latest config block of the channel obtained
+// from a trusted source, and the following blocks must be chained to it and
+// signed as its block validation policy requires.  The channel must not have
+// a ledger yet, and the orderer must not be running.
+func Import(ledgerDir, snapshotDir string, configBlock *cb.Block) (*Manifest, error) {
+	manifest, err := readManifest(snapshotDir)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.ConfigBlockHash == "" {
+		return nil, errors.Errorf("%s is a backup, not a snapshot", snapshotDir)
+	}
+	if configBlock.GetHeader() == nil || configBlock.Header.Number != manifest.First ||
+		hex.EncodeToString(configBlock.Header.Hash()) != manifest.ConfigBlockHash {
+		return nil, errors.Errorf("snapshot of channel %s does not start at the given config block", manifest.Channel)
+	}
+	chainDir := filepath.Join(ledgerDir, fsblkstorage.ChainsDir, manifest.Channel)
+	if _, err := os.Stat(chainDir); err == nil {
+		return nil, errors.Errorf("channel %s already has a ledger", manifest.Channel)
+	}
+
+	// 先在暂存目录中校验快照，通过后再移入账本目录
+	if err := os.MkdirAll(ledgerDir, 0750); err != nil {
+		return nil, errors.Wrapf(err, "failed to create ledger directory %s", ledgerDir)
+	}
+	staging, err := ioutil.TempDir(ledgerDir, snapshotDirPrefix)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create staging directory")
+	}
+	defer os.RemoveAll(staging)
+	if err := copySnapshot(snapshotDir, staging, manifest); err != nil {
+		return nil, err
+	}
+	if err := verifySnapshot(staging, manifest); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(chainDir), 0750); err != nil {
+		return nil, errors.Wrap(err, "failed to create chains directory")
+	}
+	if err := os.Rename(filepath.Join(staging, fsblkstorage.ChainsDir, manifest.Channel), chainDir); err != nil {
+		return nil, errors.Wrap(err, "failed to move the ledger of the snapshot")
+	}
+	logger.Infof("[channel: %s] Imported snapshot of blocks %d to %d from %s", manifest.Channel, manifest.First, manifest.Height-1, snapshotDir)
+	return manifest, nil
+}
+
+func readManifest(dir string) (*Manifest, error) {
+	encoded, err := ioutil.ReadFile(filepath.Join(dir, ManifestFileName))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read manifest")
+	}
+	manifest := &Manifest{}
+	if err := json.Unmarshal(encoded, manifest); err != nil {
+		return nil, errors.Wrap(err, "failed to decode manifest")
+	}
+	return manifest, nil
+}
+
+// copySnapshot copies the block files of the snapshot into the staging
+// directory, checking their hash against the manifest
+func copySnapshot(snapshotDir, staging string, manifest *Manifest) error {
+	chainDir := filepath.Join(fsblkstorage.ChainsDir, manifest.Channel)
+	if err := os.MkdirAll(filepath.Join(staging, chainDir), 0750); err != nil {
+		return errors.Wrap(err, "failed to create staging directory")
+	}
+	for _, file := range manifest.Files {
+		path := filepath.Clean(file.Path)
+		if filepath.Dir(path) != chainDir || !strings.HasPrefix(filepath.Base(path), blockfilePrefix) {
+			return errors.Errorf("snapshot holds unexpected file %s", file.Path)
+		}
+		if err := copyFile(filepath.Join(snapshotDir, path), filepath.Join(staging, path), file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string, file File) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return errors.Wrap(err, "failed to open snapshot file")
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0640)
+	if err != nil {
+		return errors.Wrap(err, "failed to create block file")
+	}
+	defer out.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(out, h), in)
+	if err != nil {
+		return errors.Wrapf(err, "failed to copy %s", file.Path)
+	}
+	if n != file.Size || hex.EncodeToString(h.Sum(nil)) != file.SHA256 {
+		return errors.Errorf("file %s does not match the manifest", file.Path)
+	}
+	return errors.Wrap(out.Sync(), "failed to sync block file")
+}
+
+// verifySnapshot opens the ledger of the snapshot in the staging directory,
+// and checks its blocks: they must be chained to the config block and signed
+// as its block validation policy requires, the last block must refer to the
+// config block as the latest one, and be the last block of the manifest
+func verifySnapshot(staging string, manifest *Manifest) error {
+	lf := fileledger.New(staging)
+	defer lf.Close()
+	ledger, err := lf.GetOrCreate(manifest.Channel)
+	if err != nil {
+		return errors.WithMessage(err, "failed to open the ledger of the snapshot")
+	}
+	if ledger.Height() != manifest.Height {
+		return errors.Errorf("snapshot holds blocks up to height %d, not %d", ledger.Height(), manifest.Height)
+	}
+
+	verifier := archive.NewConfigVerifier(manifest.Channel, manifest.First)
+	itr, _ := ledger.Iterator(&ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: manifest.First}}})
+	defer itr.Close()
+	var block *cb.Block
+	for number := manifest.First; number < manifest.Height; number++ {
+		var status cb.Status
+		if block, status = itr.Next(); status != cb.Status_SUCCESS {
+			return errors.Errorf("failed to read block %d of the snapshot: %s", number, status)
+		}
+		if err := verifier.Verify(block); err != nil {
+			return errors.WithMessage(err, "snapshot verification failed")
+		}
+	}
+	index, err := utils.GetLastConfigIndexFromBlock(block)
+	if err != nil {
+		return errors.WithMessage(err, "snapshot verification failed")
+	}
+	if index != manifest.First {
+		return errors.Errorf("the latest config block of the snapshot is block %d, not block %d", index, manifest.First)
+	}
+	if hex.EncodeToString(block.Header.Hash()) != manifest.LastBlockHash {
+		return errors.New("the last block of the snapshot does not match the manifest")
+	}
+	return nil
+}
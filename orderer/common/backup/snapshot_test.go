@@ -0,0 +1,154 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package backup
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	fileledger "github.com/hyperledger/fabric/common/ledger/blockledger/file"
+	"github.com/hyperledger/fabric/common/localmsp"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/configtxgentest"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/encoder"
+	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	"github.com/hyperledger/fabric/common/util"
+	msptesttools "github.com/hyperledger/fabric/msp/mgmt/testtools"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func TestMain(m *testing.M) {
+	if err := msptesttools.LoadDevMsp(); err != nil {
+		fmt.Printf("Failed to load dev MSP: %s\n", err)
+		os.Exit(-1)
+	}
+	os.Exit(m.Run())
+}
+
+// appendSigned appends a block of the envelopes to the ledger, signed by the
+// local MSP identity and recording the given block as its last config
+func appendSigned(t *testing.T, ledger blockledger.ReadWriter, lastConfig uint64, envs ...*cb.Envelope) *cb.Block {
+	block := blockledger.CreateNextBlock(ledger, envs)
+	signer := localmsp.NewSigner()
+	value := utils.MarshalOrPanic(&cb.LastConfig{Index: lastConfig})
+	block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{Value: value})
+	shdr, err := signer.NewSignatureHeader()
+	require.NoError(t, err)
+	shdrBytes := utils.MarshalOrPanic(shdr)
+	signature, err := signer.Sign(util.ConcatenateBytes(value, shdrBytes, block.Header.Bytes()))
+	require.NoError(t, err)
+	block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{
+		Value:      value,
+		Signatures: []*cb.MetadataSignature{{SignatureHeader: shdrBytes, Signature: signature}},
+	})
+	require.NoError(t, ledger.Append(block))
+	return block
+}
+
+// newChannelLedger returns a file ledger holding the genesis block, two
+// blocks, a config block and two more blocks
+func newChannelLedger(t *testing.T, dir string) (blockledger.Factory, blockledger.ReadWriter) {
+	lf := fileledger.New(dir)
+	ledger, err := lf.GetOrCreate("mychannel")
+	require.NoError(t, err)
+	genesisBlock := encoder.New(configtxgentest.Load(genesisconfig.SampleInsecureSoloProfile)).GenesisBlockForChannel("mychannel")
+	require.NoError(t, ledger.Append(genesisBlock))
+	configEnv, err := utils.ExtractEnvelope(genesisBlock, 0)
+	require.NoError(t, err)
+
+	appendSigned(t, ledger, 0, &cb.Envelope{Payload: []byte("tx1")})
+	appendSigned(t, ledger, 0, &cb.Envelope{Payload: []byte("tx2")})
+	appendSigned(t, ledger, 3, configEnv)
+	appendSigned(t, ledger, 3, &cb.Envelope{Payload: []byte("tx4")})
+	appendSigned(t, ledger, 3, &cb.Envelope{Payload: []byte("tx5")})
+	return lf, ledger
+}
+
+func TestSnapshot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	lf, ledger := newChannelLedger(t, filepath.Join(dir, "ledger"))
+	defer lf.Close()
+
+	backups := New(filepath.Join(dir, "ledger"), filepath.Join(dir, "backups"), channels{
+		"mychannel": {ReadWriter: ledger},
+	}, nil)
+	manifest, err := backups.Snapshot(context.Background(), "mychannel", nil)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(3), manifest.First)
+	assert.Equal(t, uint64(6), manifest.Height)
+	assert.Equal(t, "snapshot-", filepath.Base(manifest.Dir)[:len(snapshotDirPrefix)])
+	configBlock := blockledger.GetBlock(ledger, 3)
+	assert.Equal(t, hex.EncodeToString(configBlock.Header.Hash()), manifest.ConfigBlockHash)
+	assert.Equal(t, hex.EncodeToString(blockledger.GetBlock(ledger, 5).Header.Hash()), manifest.LastBlockHash)
+	require.Len(t, manifest.Files, 1)
+	assert.Equal(t, filepath.Join("chains", "mychannel", "blockfile_000000"), manifest.Files[0].Path)
+
+	// a new orderer imports the snapshot, and serves the channel from it
+	target := filepath.Join(dir, "target")
+	imported, err := Import(target, manifest.Dir, configBlock)
+	require.NoError(t, err)
+	assert.Equal(t, manifest.LastBlockHash, imported.LastBlockHash)
+	restoredFactory := fileledger.New(target)
+	restored, err := restoredFactory.GetOrCreate("mychannel")
+	require.NoError(t, err)
+	assert.Equal(t, uint64(6), restored.Height())
+	for number := uint64(3); number < 6; number++ {
+		assert.Equal(t, blockledger.GetBlock(ledger, number), blockledger.GetBlock(restored, number))
+	}
+	appendSigned(t, restored, 3, &cb.Envelope{Payload: []byte("tx6")})
+	assert.Equal(t, uint64(7), restored.Height())
+	restoredFactory.Close()
+
+	_, err = Import(target, manifest.Dir, configBlock)
+	assert.EqualError(t, err, "channel mychannel already has a ledger")
+}
+
+func TestImportFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "snapshot")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	lf, ledger := newChannelLedger(t, filepath.Join(dir, "ledger"))
+	defer lf.Close()
+
+	backups := New(filepath.Join(dir, "ledger"), filepath.Join(dir, "backups"), channels{
+		"mychannel": {ReadWriter: ledger},
+	}, nil)
+	manifest, err := backups.Snapshot(context.Background(), "mychannel", nil)
+	require.NoError(t, err)
+	backup, err := backups.Take(context.Background(), "mychannel", nil)
+	require.NoError(t, err)
+	configBlock := blockledger.GetBlock(ledger, 3)
+	target := filepath.Join(dir, "target")
+
+	_, err = Import(target, backup.Dir, configBlock)
+	assert.EqualError(t, err, fmt.Sprintf("%s is a backup, not a snapshot", backup.Dir))
+	_, err = Import(target, manifest.Dir, blockledger.GetBlock(ledger, 0))
+	assert.EqualError(t, err, "snapshot of channel mychannel does not start at the given config block")
+
+	blockfile := filepath.Join(manifest.Dir, manifest.Files[0].Path)
+	data, err := ioutil.ReadFile(blockfile)
+	require.NoError(t, err)
+	data[len(data)-1] ^= 0xff
+	require.NoError(t, ioutil.WriteFile(blockfile, data, 0640))
+	_, err = Import(target, manifest.Dir, configBlock)
+	assert.EqualError(t, err, "file chains/mychannel/blockfile_000000 does not match the manifest")
+
+	// nothing is left behind by a failed import
+	entries, err := ioutil.ReadDir(target)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
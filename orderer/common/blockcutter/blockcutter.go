@@ -36,6 +36,9 @@ type Receiver interface {
 
 	// Cut returns the current batch and starts a new one
 	Cut() []*cb.Envelope
+
+	// PendingBytes returns the size of the messages pending in the current batch
+	PendingBytes() uint32
 }
 
 //块分割工具
@@ -155,6 +158,11 @@ func (r *receiver) Cut() []*cb.Envelope {
 	return batch
 }
 
+// PendingBytes returns the size of the messages pending in the current batch
+func (r *receiver) PendingBytes() uint32 {
+	return r.pendingBatchSizeBytes
+}
+
 func messageSizeBytes(message *cb.Envelope) uint32 {
 	return uint32(len(message.Payload) + len(message.Signature))
 }
@@ -54,10 +54,12 @@ func TestNormalBatch(t *testing.T) {
 	batches, pending := r.Ordered(tx)
 	assert.Nil(t, batches, "Should not have created batch")
 	assert.True(t, pending, "Should have message pending in the receiver")
+	assert.Equal(t, messageSizeBytes(tx), r.PendingBytes(), "Should report the size of the pending message")
 
 	batches, pending = r.Ordered(tx)
 	assert.NotNil(t, batches, "Should have created batch")
 	assert.False(t, pending, "Should not have message pending in the receiver")
+	assert.Zero(t, r.PendingBytes(), "Should not report pending bytes once the batch is cut")
 }
 
 func TestBatchSizePreferredMaxBytesOverflow(t *testing.T) {
@@ -19,6 +19,15 @@ type OrdererConfig struct {
 	consensusTypeReturnsOnCall map[int]struct {
 		result1 string
 	}
+	ConsensusMetadataVersionStub        func() uint32
+	consensusMetadataVersionMutex       sync.RWMutex
+	consensusMetadataVersionArgsForCall []struct{}
+	consensusMetadataVersionReturns     struct {
+		result1 uint32
+	}
+	consensusMetadataVersionReturnsOnCall map[int]struct {
+		result1 uint32
+	}
 	BatchSizeStub        func() *ab.BatchSize
 	batchSizeMutex       sync.RWMutex
 	batchSizeArgsForCall []struct{}
@@ -46,6 +55,33 @@ type OrdererConfig struct {
 	maxChannelsCountReturnsOnCall map[int]struct {
 		result1 uint64
 	}
+	IngressWeightStub        func() uint32
+	ingressWeightMutex       sync.RWMutex
+	ingressWeightArgsForCall []struct{}
+	ingressWeightReturns     struct {
+		result1 uint32
+	}
+	ingressWeightReturnsOnCall map[int]struct {
+		result1 uint32
+	}
+	IngressQuotasStub        func() map[string]*ab.IngressQuota
+	ingressQuotasMutex       sync.RWMutex
+	ingressQuotasArgsForCall []struct{}
+	ingressQuotasReturns     struct {
+		result1 map[string]*ab.IngressQuota
+	}
+	ingressQuotasReturnsOnCall map[int]struct {
+		result1 map[string]*ab.IngressQuota
+	}
+	TransactionLabelsStub        func() map[string]*ab.TransactionLabel
+	transactionLabelsMutex       sync.RWMutex
+	transactionLabelsArgsForCall []struct{}
+	transactionLabelsReturns     struct {
+		result1 map[string]*ab.TransactionLabel
+	}
+	transactionLabelsReturnsOnCall map[int]struct {
+		result1 map[string]*ab.TransactionLabel
+	}
 	KafkaBrokersStub        func() []string
 	kafkaBrokersMutex       sync.RWMutex
 	kafkaBrokersArgsForCall []struct{}
@@ -95,6 +131,8 @@ func (fake *OrdererConfig) ConsensusType() string {
 func (fake *OrdererConfig) ConsensusTypeCallCount() int {
 	fake.consensusTypeMutex.RLock()
 	defer fake.consensusTypeMutex.RUnlock()
+	fake.consensusMetadataVersionMutex.RLock()
+	defer fake.consensusMetadataVersionMutex.RUnlock()
 	return len(fake.consensusTypeArgsForCall)
 }
 
@@ -117,6 +155,46 @@ func (fake *OrdererConfig) ConsensusTypeReturnsOnCall(i int, result1 string) {
 	}{result1}
 }
 
+func (fake *OrdererConfig) ConsensusMetadataVersion() uint32 {
+	fake.consensusMetadataVersionMutex.Lock()
+	ret, specificReturn := fake.consensusMetadataVersionReturnsOnCall[len(fake.consensusMetadataVersionArgsForCall)]
+	fake.consensusMetadataVersionArgsForCall = append(fake.consensusMetadataVersionArgsForCall, struct{}{})
+	fake.recordInvocation("ConsensusMetadataVersion", []interface{}{})
+	fake.consensusMetadataVersionMutex.Unlock()
+	if fake.ConsensusMetadataVersionStub != nil {
+		return fake.ConsensusMetadataVersionStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.consensusMetadataVersionReturns.result1
+}
+
+func (fake *OrdererConfig) ConsensusMetadataVersionCallCount() int {
+	fake.consensusMetadataVersionMutex.RLock()
+	defer fake.consensusMetadataVersionMutex.RUnlock()
+	return len(fake.consensusMetadataVersionArgsForCall)
+}
+
+func (fake *OrdererConfig) ConsensusMetadataVersionReturns(result1 uint32) {
+	fake.ConsensusMetadataVersionStub = nil
+	fake.consensusMetadataVersionReturns = struct {
+		result1 uint32
+	}{result1}
+}
+
+func (fake *OrdererConfig) ConsensusMetadataVersionReturnsOnCall(i int, result1 uint32) {
+	fake.ConsensusMetadataVersionStub = nil
+	if fake.consensusMetadataVersionReturnsOnCall == nil {
+		fake.consensusMetadataVersionReturnsOnCall = make(map[int]struct {
+			result1 uint32
+		})
+	}
+	fake.consensusMetadataVersionReturnsOnCall[i] = struct {
+		result1 uint32
+	}{result1}
+}
+
 func (fake *OrdererConfig) BatchSize() *ab.BatchSize {
 	fake.batchSizeMutex.Lock()
 	ret, specificReturn := fake.batchSizeReturnsOnCall[len(fake.batchSizeArgsForCall)]
@@ -237,6 +315,126 @@ func (fake *OrdererConfig) MaxChannelsCountReturnsOnCall(i int, result1 uint64)
 	}{result1}
 }
 
+func (fake *OrdererConfig) IngressWeight() uint32 {
+	fake.ingressWeightMutex.Lock()
+	ret, specificReturn := fake.ingressWeightReturnsOnCall[len(fake.ingressWeightArgsForCall)]
+	fake.ingressWeightArgsForCall = append(fake.ingressWeightArgsForCall, struct{}{})
+	fake.recordInvocation("IngressWeight", []interface{}{})
+	fake.ingressWeightMutex.Unlock()
+	if fake.IngressWeightStub != nil {
+		return fake.IngressWeightStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.ingressWeightReturns.result1
+}
+
+func (fake *OrdererConfig) IngressWeightCallCount() int {
+	fake.ingressWeightMutex.RLock()
+	defer fake.ingressWeightMutex.RUnlock()
+	return len(fake.ingressWeightArgsForCall)
+}
+
+func (fake *OrdererConfig) IngressWeightReturns(result1 uint32) {
+	fake.IngressWeightStub = nil
+	fake.ingressWeightReturns = struct {
+		result1 uint32
+	}{result1}
+}
+
+func (fake *OrdererConfig) IngressWeightReturnsOnCall(i int, result1 uint32) {
+	fake.IngressWeightStub = nil
+	if fake.ingressWeightReturnsOnCall == nil {
+		fake.ingressWeightReturnsOnCall = make(map[int]struct {
+			result1 uint32
+		})
+	}
+	fake.ingressWeightReturnsOnCall[i] = struct {
+		result1 uint32
+	}{result1}
+}
+
+func (fake *OrdererConfig) IngressQuotas() map[string]*ab.IngressQuota {
+	fake.ingressQuotasMutex.Lock()
+	ret, specificReturn := fake.ingressQuotasReturnsOnCall[len(fake.ingressQuotasArgsForCall)]
+	fake.ingressQuotasArgsForCall = append(fake.ingressQuotasArgsForCall, struct{}{})
+	fake.recordInvocation("IngressQuotas", []interface{}{})
+	fake.ingressQuotasMutex.Unlock()
+	if fake.IngressQuotasStub != nil {
+		return fake.IngressQuotasStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.ingressQuotasReturns.result1
+}
+
+func (fake *OrdererConfig) IngressQuotasCallCount() int {
+	fake.ingressQuotasMutex.RLock()
+	defer fake.ingressQuotasMutex.RUnlock()
+	return len(fake.ingressQuotasArgsForCall)
+}
+
+func (fake *OrdererConfig) IngressQuotasReturns(result1 map[string]*ab.IngressQuota) {
+	fake.IngressQuotasStub = nil
+	fake.ingressQuotasReturns = struct {
+		result1 map[string]*ab.IngressQuota
+	}{result1}
+}
+
+func (fake *OrdererConfig) IngressQuotasReturnsOnCall(i int, result1 map[string]*ab.IngressQuota) {
+	fake.IngressQuotasStub = nil
+	if fake.ingressQuotasReturnsOnCall == nil {
+		fake.ingressQuotasReturnsOnCall = make(map[int]struct {
+			result1 map[string]*ab.IngressQuota
+		})
+	}
+	fake.ingressQuotasReturnsOnCall[i] = struct {
+		result1 map[string]*ab.IngressQuota
+	}{result1}
+}
+
+func (fake *OrdererConfig) TransactionLabels() map[string]*ab.TransactionLabel {
+	fake.transactionLabelsMutex.Lock()
+	ret, specificReturn := fake.transactionLabelsReturnsOnCall[len(fake.transactionLabelsArgsForCall)]
+	fake.transactionLabelsArgsForCall = append(fake.transactionLabelsArgsForCall, struct{}{})
+	fake.recordInvocation("TransactionLabels", []interface{}{})
+	fake.transactionLabelsMutex.Unlock()
+	if fake.TransactionLabelsStub != nil {
+		return fake.TransactionLabelsStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.transactionLabelsReturns.result1
+}
+
+func (fake *OrdererConfig) TransactionLabelsCallCount() int {
+	fake.transactionLabelsMutex.RLock()
+	defer fake.transactionLabelsMutex.RUnlock()
+	return len(fake.transactionLabelsArgsForCall)
+}
+
+func (fake *OrdererConfig) TransactionLabelsReturns(result1 map[string]*ab.TransactionLabel) {
+	fake.TransactionLabelsStub = nil
+	fake.transactionLabelsReturns = struct {
+		result1 map[string]*ab.TransactionLabel
+	}{result1}
+}
+
+func (fake *OrdererConfig) TransactionLabelsReturnsOnCall(i int, result1 map[string]*ab.TransactionLabel) {
+	fake.TransactionLabelsStub = nil
+	if fake.transactionLabelsReturnsOnCall == nil {
+		fake.transactionLabelsReturnsOnCall = make(map[int]struct {
+			result1 map[string]*ab.TransactionLabel
+		})
+	}
+	fake.transactionLabelsReturnsOnCall[i] = struct {
+		result1 map[string]*ab.TransactionLabel
+	}{result1}
+}
+
 func (fake *OrdererConfig) KafkaBrokers() []string {
 	fake.kafkaBrokersMutex.Lock()
 	ret, specificReturn := fake.kafkaBrokersReturnsOnCall[len(fake.kafkaBrokersArgsForCall)]
@@ -362,12 +560,20 @@ func (fake *OrdererConfig) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.consensusTypeMutex.RLock()
 	defer fake.consensusTypeMutex.RUnlock()
+	fake.consensusMetadataVersionMutex.RLock()
+	defer fake.consensusMetadataVersionMutex.RUnlock()
 	fake.batchSizeMutex.RLock()
 	defer fake.batchSizeMutex.RUnlock()
 	fake.batchTimeoutMutex.RLock()
 	defer fake.batchTimeoutMutex.RUnlock()
 	fake.maxChannelsCountMutex.RLock()
 	defer fake.maxChannelsCountMutex.RUnlock()
+	fake.ingressWeightMutex.RLock()
+	defer fake.ingressWeightMutex.RUnlock()
+	fake.ingressQuotasMutex.RLock()
+	defer fake.ingressQuotasMutex.RUnlock()
+	fake.transactionLabelsMutex.RLock()
+	defer fake.transactionLabelsMutex.RUnlock()
 	fake.kafkaBrokersMutex.RLock()
 	defer fake.kafkaBrokersMutex.RUnlock()
 	fake.organizationsMutex.RLock()
@@ -0,0 +1,75 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broadcast
+
+import (
+	"fmt"
+	"time"
+)
+
+// ChannelQueues reports the work queued in the consenters of the channels
+type ChannelQueues interface {
+	// PendingBytes returns the size of the messages the consenter of the
+	// channel accepted and has not written in blocks yet, or false if the
+	// channel does not exist or its consenter does not report it
+	PendingBytes(channelID string) (int64, bool)
+}
+
+// Backpressure rejects the normal messages of the channels whose consenter
+// has more than HighWatermark bytes of messages pending, rather than letting
+// the queue of the consenter grow without bounds.  The rejected messages are
+// answered SERVICE_UNAVAILABLE with a retry_after growing with the excess of
+// pending bytes, so that clients back off for longer the deeper the queue.
+// Config messages are never rejected, so that a channel can always be
+// reconfigured.  A nil Backpressure rejects no message.
+type Backpressure struct {
+	// HighWatermark is the number of pending bytes past which messages are
+	// rejected
+	HighWatermark int64
+
+	// RetryAfter is the backoff hinted to the clients of the messages rejected
+	// at the high watermark, scaled by the ratio of the pending bytes to it
+	RetryAfter time.Duration
+
+	queues ChannelQueues
+}
+
+// NewBackpressure creates the Backpressure.
+func NewBackpressure(highWatermark int64, retryAfter time.Duration, queues ChannelQueues) *Backpressure {
+	return &Backpressure{HighWatermark: highWatermark, RetryAfter: retryAfter, queues: queues}
+}
+
+// backpressureError is returned for the messages rejected because the queue
+// of their consenter is too deep
+type backpressureError struct {
+	pending    int64
+	watermark  int64
+	retryAfter time.Duration
+}
+
+func (e *backpressureError) Error() string {
+	return fmt.Sprintf("consenter has %d bytes pending, over the high watermark of %d bytes", e.pending, e.watermark)
+}
+
+func (e *backpressureError) RetryAfter() time.Duration { return e.retryAfter }
+
+// check returns an error if the consenter of the channel has more bytes
+// pending than the high watermark
+func (bp *Backpressure) check(channelID string) error {
+	if bp == nil || bp.HighWatermark <= 0 {
+		return nil
+	}
+	pending, ok := bp.queues.PendingBytes(channelID)
+	if !ok || pending <= bp.HighWatermark {
+		return nil
+	}
+	return &backpressureError{
+		pending:    pending,
+		watermark:  bp.HighWatermark,
+		retryAfter: time.Duration(float64(bp.RetryAfter) * float64(pending) / float64(bp.HighWatermark)),
+	}
+}
@@ -8,18 +8,35 @@ package broadcast
 
 import (
 	"io"
+	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/comm"
+	"github.com/hyperledger/fabric/orderer/common/admissionplugin"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/crash"
+	"github.com/hyperledger/fabric/orderer/common/misbehavior"
 	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	"github.com/hyperledger/fabric/orderer/common/streamquota"
+	"github.com/hyperledger/fabric/orderer/common/tracing"
 	cb "github.com/hyperledger/fabric/protos/common"
 	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
 	"github.com/op/go-logging"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 const pkgLogID = "orderer/common/broadcast"
 
+// DuplicateInfo is the info of the successful responses to the messages not
+// ordered because they were enqueued already
+const DuplicateInfo = "duplicate of a message already enqueued"
+
 var logger *logging.Logger
 
 func init() {
@@ -30,6 +47,9 @@ func init() {
 type Handler interface {
 	// Handle starts a service thread for a given gRPC connection and services the broadcast connection
 	Handle(srv ab.AtomicBroadcast_BroadcastServer) error
+
+	// HandleBatch services a broadcast connection whose messages carry batches of envelopes
+	HandleBatch(srv ab.AtomicBroadcast_BroadcastBatchServer) error
 }
 
 // ChannelSupportRegistrar provides a way for the Handler to look up the Support for a channel
@@ -44,6 +64,10 @@ type ChannelSupportRegistrar interface {
 type ChannelSupport interface {
 	msgprocessor.Processor
 	Consenter
+
+	// IngressWeight returns the share of the ordering throughput granted to the channel
+	// while other channels compete for it, as set by the channel config
+	IngressWeight() uint32
 }
 
 // Consenter provides methods to send messages through consensus
@@ -58,36 +82,471 @@ type Consenter interface {
 	//处理配置交易消息
 	Configure(config *cb.Envelope, configSeq uint64) error
 
-	// WaitReady blocks waiting for consenter to be ready for accepting new messages.
-	// This is useful when consenter needs to temporarily block ingress messages so
-	// that in-flight messages can be consumed. It could return error if consenter is
-	// in erroneous states. If this blocking behavior is not desired, consenter could
-	// simply return nil.
+	// WaitReadyContext blocks waiting for consenter to be ready for accepting new messages,
+	// until the context is done.  This is useful when consenter needs to temporarily block
+	// ingress messages so that in-flight messages can be consumed. It could return error if
+	// consenter is in erroneous states or is not ready when the context is done.
 	//等待共识组件允许接收新消息的信号
-	WaitReady() error
+	WaitReadyContext(ctx context.Context) error
+}
+
+// AdmissionController decides whether to accept messages based on the
+// enqueue latency the orderer is achieving
+type AdmissionController interface {
+	// Admit returns an error if the message should be rejected with SERVICE_UNAVAILABLE
+	Admit(channelID string, isConfig bool) error
+
+	// Observe records how long an admitted message took to be enqueued
+	Observe(latency time.Duration)
+}
+
+// MalformedRecorder records envelopes rejected because they could not be parsed
+type MalformedRecorder interface {
+	// Record stores the envelope of the given kind together with its parse error
+	Record(kind string, env *cb.Envelope, parseErr error)
+}
+
+// DuplicateDetector remembers the transaction IDs of the messages recently
+// enqueued for ordering
+type DuplicateDetector interface {
+	// Add records the transaction ID of a message about to be enqueued on the
+	// channel, and returns false if it is recorded already
+	Add(channelID, txID string) bool
+
+	// Remove forgets the transaction ID of a message which could not be enqueued
+	Remove(channelID, txID string)
+}
+
+// RateLimiter throttles the messages broadcast on each channel and by each
+// client identity
+type RateLimiter interface {
+	// Allow returns an error if the message of the creator on the channel
+	// exceeds a rate limit
+	Allow(channelID string, creator []byte) error
+}
+
+// IngressQuotas charges the normal messages of each organization against
+// the ingress quota the config of their channel grants it
+type IngressQuotas interface {
+	// Charge returns an error whose cause is msgprocessor.ErrQuotaExceeded if
+	// the organization of the creator has no transaction left in its quota on
+	// the channel
+	Charge(channelID string, creator []byte) error
+}
+
+// AuditSink keeps the trail of the messages handled by the broadcast service
+type AuditSink interface {
+	// Audit records the handling of a message.  It is called for every
+	// message, once its response is known, and handles its own errors.
+	Audit(rec *audit.Record)
+}
+
+// AdmissionPlugin runs the custom checks deciding whether normal messages
+// are ordered, such as quotas of their senders or content filters
+type AdmissionPlugin interface {
+	// Admit returns an error if the message must not be ordered.  The
+	// message is answered FORBIDDEN with the error.
+	Admit(req *admissionplugin.Request) error
+}
+
+// CommitNotifier notifies the handler when the messages whose clients wait
+// for their commit are committed in a block
+type CommitNotifier interface {
+	// Register awaits the commit of the transaction of the channel, and
+	// returns a channel receiving the number of the block committing it and
+	// the function to call once the commit is no longer awaited.  It returns
+	// an error if no more commits can be awaited.
+	Register(channelID, txID string) (<-chan uint64, func(), error)
+}
+
+// IngressScheduler schedules the messages passed to the consenters across
+// the channels, so that a channel flooding the orderer cannot monopolize
+// its ordering throughput
+type IngressScheduler interface {
+	// Schedule calls enqueue, which passes a message of the channel of the
+	// given weight to its consenter, once the message has its turn, and
+	// returns the error of enqueue.  It returns an error without calling
+	// enqueue if the message cannot be scheduled.
+	Schedule(ctx context.Context, channelID string, weight uint32, enqueue func() error) error
+}
+
+// MisbehaviorDetector scores the suspicious messages of each client and
+// identity, and blocks the worst offenders
+type MisbehaviorDetector interface {
+	// Blocked returns an error if the client with the given ID, or the
+	// creator, which may be nil, is blocked
+	Blocked(clientID string, creator []byte) error
+
+	// RecordClient charges the suspicious message of the pattern sent on the
+	// channel to the client with the given ID, logged and reported by the
+	// anonymized ID, claiming to be the creator
+	RecordClient(clientID, anonymizedID string, claimedCreator []byte, pattern misbehavior.Pattern, channelID string)
+
+	// RecordIdentity charges the suspicious message of the pattern sent on
+	// the channel to the creator, whose signature was checked
+	RecordIdentity(creator []byte, pattern misbehavior.Pattern, channelID string)
+}
+
+// IntakeJournal durably records the messages passed to the consenters until
+// they are committed, so that they are passed again to their consenter if
+// the orderer crashes before ordering them
+type IntakeJournal interface {
+	// Append durably records the message before it is passed to its consenter
+	Append(env *cb.Envelope) error
+
+	// Discard forgets the message its consenter rejected
+	Discard(env *cb.Envelope)
+}
+
+// NonceTracker rejects the messages replayed by checking that the nonce of
+// each message advances past those its creator used before
+type NonceTracker interface {
+	// Advance records the nonce of the validated message, or returns an
+	// error whose cause is msgprocessor.ErrStaleNonce if its creator used a
+	// nonce as high before on the channel
+	Advance(channelID string, env *cb.Envelope) error
+
+	// Withdraw forgets the nonce of a message which was not ordered
+	Withdraw(channelID string, env *cb.Envelope)
 }
 
 type handlerImpl struct {
-	sm ChannelSupportRegistrar
+	sm              ChannelSupportRegistrar
+	admission       AdmissionController
+	malformed       MalformedRecorder
+	duplicates      DuplicateDetector
+	limiter         RateLimiter
+	quotas          IngressQuotas
+	window          int
+	metrics         *Metrics
+	audit           AuditSink
+	sizeLimits      *SizeLimits
+	admissionPlugin AdmissionPlugin
+	streamLimits    *StreamLimits
+	readyTimeout    time.Duration
+	tracer          *tracing.Tracer
+	commits         CommitNotifier
+	commitTimeout   time.Duration
+	scheduler       IngressScheduler
+	heartbeats      *Heartbeats
+	crashes         *crash.Reporter
+	receipts        *Receipts
+	backpressure    *Backpressure
+	misbehavior     MisbehaviorDetector
+	journal         IntakeJournal
+	nonces          NonceTracker
+	streamQuota     *streamquota.Quota
+	verifier        *msgprocessor.VerifierPool
+	forwarding      *Forwarding
+}
+
+// HandlerOptions configures the optional behavior of a Handler.  Each field may
+// be left zero, which disables the behavior it configures.
+type HandlerOptions struct {
+	// Admission admits the messages, or all messages are admitted if nil
+	Admission AdmissionController
+	// Malformed records the malformed messages, which are only logged if nil
+	Malformed MalformedRecorder
+	// Duplicates detects the messages submitted again, which are ordered
+	// again if nil
+	Duplicates DuplicateDetector
+	// Limiter throttles the messages, which are not throttled if nil
+	Limiter RateLimiter
+	// Quotas charges the normal messages against the ingress quotas of the
+	// organizations of their creators, which are not enforced if nil.  The
+	// config update messages are not charged.
+	Quotas IngressQuotas
+	// Window is the number of messages of a broadcast stream processed at
+	// once, and defaults to 1, which preserves the order in which the messages
+	// of a stream are enqueued
+	Window int
+	// Metrics records the broadcast metrics, or nothing is recorded if nil
+	Metrics *Metrics
+	// AuditSink keeps the audit trail, or none is kept if nil
+	AuditSink AuditSink
+	// SizeLimits checks the size of messages as they are received, or their
+	// size is only checked when they are processed if nil
+	SizeLimits *SizeLimits
+	// AdmissionPlugin makes custom checks on the normal messages once they are
+	// validated and rate limited, or none is made if nil
+	AdmissionPlugin AdmissionPlugin
+	// StreamLimits limits the number and lifetime of the streams of each
+	// client, which may keep any number of streams open for as long as they
+	// like if nil
+	StreamLimits *StreamLimits
+	// ReadyTimeout is how long a message waits for its consenter to be ready
+	// before it is rejected with SERVICE_UNAVAILABLE, or as long as the stream
+	// lasts if zero
+	ReadyTimeout time.Duration
+	// Tracer traces the messages, which are not traced if nil
+	Tracer *tracing.Tracer
+	// Commits notifies the commit of messages, or the messages are answered
+	// once they are enqueued even if their client waits for their commit if nil
+	Commits CommitNotifier
+	// CommitTimeout is how long a message waits for its commit before it is
+	// answered anyway, or as long as the stream lasts if zero
+	CommitTimeout time.Duration
+	// Scheduler schedules the messages passed to their consenter, which are
+	// passed as soon as they are processed if nil
+	Scheduler IngressScheduler
+	// Heartbeats sends heartbeats to the clients asking for them, or none is
+	// sent if nil
+	Heartbeats *Heartbeats
+	// Crashes counts the panics recovered while processing messages, which
+	// are only reported if nil
+	Crashes *crash.Reporter
+	// Receipts signs receipts for the clients asking for them, or none is
+	// signed if nil
+	Receipts *Receipts
+	// Backpressure rejects the normal messages while their consenter has too
+	// much work pending, or they are passed however much it has if nil
+	Backpressure *Backpressure
+	// Misbehavior scores the suspicious messages, which are only rejected if nil
+	Misbehavior MisbehaviorDetector
+	// Journal records the messages answered SUCCESS until they are ordered,
+	// or they are lost if the orderer crashes before then if nil
+	Journal IntakeJournal
+	// Nonces requires the nonces of the messages of each creator to advance,
+	// or they are not required to if nil
+	Nonces NonceTracker
+	// StreamQuota limits the streams on which each identity submits messages
+	// at once, or it may submit on any number of streams if nil
+	StreamQuota *streamquota.Quota
+	// Verifier validates the normal messages, or they are validated on the
+	// goroutine processing them, and those of a batch one after another, if nil
+	Verifier *msgprocessor.VerifierPool
+	// Forwarding forwards the messages of the channels led by another orderer
+	// to the leader, or they are passed to the consenter of this orderer if nil
+	Forwarding *Forwarding
 }
 
-// NewHandlerImpl constructs a new implementation of the Handler interface
-func NewHandlerImpl(sm ChannelSupportRegistrar) Handler {
+// NewHandlerImpl constructs a new implementation of the Handler interface.
+func NewHandlerImpl(sm ChannelSupportRegistrar, opts HandlerOptions) Handler {
+	window := opts.Window
+	if window < 1 {
+		window = 1
+	}
 	return &handlerImpl{
-		sm: sm,
+		sm:              sm,
+		admission:       opts.Admission,
+		malformed:       opts.Malformed,
+		duplicates:      opts.Duplicates,
+		limiter:         opts.Limiter,
+		quotas:          opts.Quotas,
+		window:          window,
+		metrics:         opts.Metrics,
+		audit:           opts.AuditSink,
+		sizeLimits:      opts.SizeLimits,
+		admissionPlugin: opts.AdmissionPlugin,
+		streamLimits:    opts.StreamLimits,
+		readyTimeout:    opts.ReadyTimeout,
+		tracer:          opts.Tracer,
+		commits:         opts.Commits,
+		commitTimeout:   opts.CommitTimeout,
+		scheduler:       opts.Scheduler,
+		heartbeats:      opts.Heartbeats,
+		crashes:         opts.Crashes,
+		receipts:        opts.Receipts,
+		backpressure:    opts.Backpressure,
+		misbehavior:     opts.Misbehavior,
+		journal:         opts.Journal,
+		nonces:          opts.Nonces,
+		streamQuota:     opts.StreamQuota,
+		verifier:        opts.Verifier,
+		forwarding:      opts.Forwarding,
 	}
 }
 
-// Handle starts a service thread for a given gRPC connection and services the broadcast connection
+// Handle starts a service thread for a given gRPC connection and services the broadcast connection.
+// Messages are received while earlier ones are processed, up to the in-flight window, and each
+// response carries the position of its message in the stream as it may be sent out of order.
+// The stream is refused if its client has too many streams open, and closed once idle for longer
+// than the idle timeout of the stream limits.  A message whose creator has too many other streams
+// open on its channel is rejected, which ends the stream.  If the client sets WaitForCommitKey in the metadata
+// of the stream, each message is answered once it is committed in a block, so the client should
+// widen the in-flight window to keep messages flowing while earlier ones await their block.
+// If the client sets util.HeartbeatIntervalKey in the metadata of the stream, heartbeats are
+// sent at that interval with the height of the channel of the last message received, and if it
+// sets ReceiptsKey, the responses to the messages enqueued carry a receipt signed by the orderer.
+// A stream ended by a rejection returns a gRPC status carrying the ErrorDetail of the rejection.
 //用for循环来接收来自peer节点的消息
 func (bh *handlerImpl) Handle(srv ab.AtomicBroadcast_BroadcastServer) error {
 	addr := util.ExtractRemoteAddress(srv.Context())
+	closeStream, err := bh.streamLimits.open(srv.Context())
+	if err != nil {
+		logger.Warningf("Refusing broadcast stream from %s: %s", addr, err)
+		return err
+	}
+	defer closeStream()
+	//消息流在其消息通过验证的身份的配额中计数，直到消息流结束
+	quota := bh.streamQuota.Open(streamquota.Broadcast)
+	defer quota.Close()
+	subject := bh.clientSubject(srv.Context())
+	waitCommit := bh.commits != nil && WaitsForCommit(srv.Context())
 	logger.Debugf("Starting new broadcast loop for %s", addr)
+
+	//接收协程在处理已接收消息的同时等待接收下一个消息
+	done := make(chan struct{})
+	defer close(done)
+	received := make(chan receivedMsg)
+	go func() {
+		for {
+			msg, err := srv.Recv()
+			select {
+			case received <- receivedMsg{msg: msg, err: err, at: time.Now()}:
+			case <-done:
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	//响应通道容量为在途窗口大小，处理协程发送响应时不会阻塞
+	responses := make(chan *ab.BroadcastResponse, bh.window)
+	var seq uint64
+	inFlight := 0
+	receiving := true
+	//空闲计时器在没有在途消息时计时，收到消息后重新计时
+	idleTimeout := bh.streamLimits.idleTimeout()
+	var idle *time.Timer
+	if idleTimeout > 0 {
+		idle = time.NewTimer(idleTimeout)
+		defer idle.Stop()
+	}
+	resetIdle := func() {
+		if idle == nil {
+			return
+		}
+		//计时器只在没有在途消息时被读取，需先确保其已停止并清空
+		if !idle.Stop() {
+			select {
+			case <-idle.C:
+			default:
+			}
+		}
+		idle.Reset(idleTimeout)
+	}
+	//客户端要求心跳时，定期发送最后收到的消息所属通道的高度
+	var heartbeats <-chan time.Time
+	var channelID string
+	//结束消息流的拒绝，消息流以携带其错误详情的gRPC状态结束
+	var rejection error
+	if interval := bh.heartbeats.interval(srv.Context()); interval > 0 {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		heartbeats = ticker.C
+	}
 	//消息处理循环
+	for receiving || inFlight > 0 {
+		//在途消息达到窗口大小时暂停接收
+		var next <-chan receivedMsg
+		if receiving && inFlight < bh.window {
+			next = received
+		}
+		var idleC <-chan time.Time
+		if idle != nil && inFlight == 0 {
+			idleC = idle.C
+		}
+
+		select {
+		case <-srv.Context().Done():
+			logger.Debugf("Broadcast stream from %s ended: %s", addr, srv.Context().Err())
+			return srv.Context().Err()
+
+		case <-idleC:
+			logger.Warningf("Closing broadcast stream from %s, idle for %s", addr, idleTimeout)
+			return status.Errorf(codes.DeadlineExceeded, "broadcast stream idle for %s", idleTimeout)
+
+		case r := <-next:
+			if r.err == io.EOF {
+				logger.Debugf("Received EOF from %s, hangup", addr)
+				receiving = false
+				continue
+			}
+			if r.err != nil {
+				logger.Warningf("Error reading from %s: %s", addr, r.err)
+				return r.err
+			}
+			if heartbeats != nil {
+				if chdr, err := utils.ChannelHeader(r.msg); err == nil {
+					channelID = chdr.ChannelId
+				}
+			}
+			seq++
+			inFlight++
+			resetIdle()
+			go bh.processInFlight(srv.Context(), r.msg, r.at, waitCommit, seq, addr, subject, quota, responses)
+
+		case resp := <-responses:
+			inFlight--
+			resetIdle()
+			if err := srv.Send(resp); err != nil {
+				logger.Warningf("Error sending to %s: %s", addr, err)
+				return err
+			}
+			//被拒绝的消息回复错误状态后停止接收，在途消息回复后结束消息流
+			if resp.Status != cb.Status_SUCCESS {
+				receiving = false
+				if rejection == nil {
+					rejection = rejectionStatus(resp)
+				}
+			}
+
+		case <-heartbeats:
+			if err := srv.Send(bh.heartbeats.heartbeat(channelID)); err != nil {
+				logger.Warningf("Error sending heartbeat to %s: %s", addr, err)
+				return err
+			}
+		}
+	}
+	return rejection
+}
+
+// receivedMsg is the result of receiving from a broadcast stream
+type receivedMsg struct {
+	msg *cb.Envelope
+	err error
+	at  time.Time
+}
+
+// processInFlight processes a message received on a broadcast stream at the
+// given time and passes the response tagged with its position in the stream
+// to responses
+func (bh *handlerImpl) processInFlight(ctx context.Context, msg *cb.Envelope, received time.Time, waitCommit bool, seq uint64, addr, subject string, quota *streamquota.Stream, responses chan<- *ab.BroadcastResponse) {
+	defer func() {
+		//处理协程不在gRPC服务的panic恢复范围内
+		if r := recover(); r != nil {
+			report := bh.crashes.Report("Broadcast", addr, msg, r)
+			responses <- &ab.BroadcastResponse{Status: cb.Status_INTERNAL_SERVER_ERROR, Info: report.Info(), CorrelationId: seq}
+		}
+	}()
+	resp := bh.processMessage(ctx, msg, received, waitCommit, addr, subject, quota, nil)
+	resp.CorrelationId = seq
+	responses <- resp
+}
+
+// HandleBatch services a batch broadcast connection, replying to each batch
+// with the responses to its envelopes in order.  Unlike Handle, it does not
+// end the stream when an envelope is rejected, as the responses tell the
+// client which envelopes to submit again, and it answers the envelopes once
+// they are enqueued, as a batch may not be committed in a single block.
+func (bh *handlerImpl) HandleBatch(srv ab.AtomicBroadcast_BroadcastBatchServer) error {
+	addr := util.ExtractRemoteAddress(srv.Context())
+	closeStream, err := bh.streamLimits.open(srv.Context())
+	if err != nil {
+		logger.Warningf("Refusing batch broadcast stream from %s: %s", addr, err)
+		return err
+	}
+	defer closeStream()
+	quota := bh.streamQuota.Open(streamquota.Broadcast)
+	defer quota.Close()
+	subject := bh.clientSubject(srv.Context())
+	logger.Debugf("Starting new batch broadcast loop for %s", addr)
 	for {
-		//等待接收消息
-		//监听提交的交易消息请求
-		msg, err := srv.Recv()
+		batch, err := srv.Recv()
+		received := time.Now()
 		if err == io.EOF {
 			logger.Debugf("Received EOF from %s, hangup", addr)
 			return nil
@@ -97,82 +556,563 @@ func (bh *handlerImpl) Handle(srv ab.AtomicBroadcast_BroadcastServer) error {
 			return err
 		}
 
-		//检查消息envelop中的一些字段，比如channelId
-		//如果是HeaderType_CONFIG_UPDATE类型的消息，则会将消息经过bh.sm.Process(msg)
-		//检查获取的通道头部chdr，配置交易消息标志位isConfig、通道链支持对象（通道消息处理器）
-		chdr, isConfig, processor, err := bh.sm.BroadcastChannelSupport(msg)
+		//并行验证批次内的普通交易消息，再按批次内顺序逐个处理消息，每个消息对应一个响应
+		verifications := bh.preverify(batch.Envelopes)
+		resp := &ab.BroadcastBatchResponse{Responses: make([]*ab.BroadcastResponse, len(batch.Envelopes))}
+		for i, msg := range batch.Envelopes {
+			resp.Responses[i] = bh.processMessage(srv.Context(), msg, received, false, addr, subject, quota, verifications[i])
+		}
+		logger.Debugf("Broadcast has processed batch of %d messages from %s", len(batch.Envelopes), addr)
+
+		if err = srv.Send(resp); err != nil {
+			logger.Warningf("Error sending to %s: %s", addr, err)
+			return err
+		}
+	}
+}
+
+// preverify starts validating the normal messages of a batch on the verifier
+// pool, and returns their verifications, nil for the other messages.  The
+// messages are validated whether or not they are rejected before their
+// validation once processed.
+func (bh *handlerImpl) preverify(msgs []*cb.Envelope) []*msgprocessor.Verification {
+	verifications := make([]*msgprocessor.Verification, len(msgs))
+	if bh.verifier == nil {
+		return verifications
+	}
+	for i, msg := range msgs {
+		_, isConfig, processor, err := bh.sm.BroadcastChannelSupport(msg)
+		if err != nil || isConfig {
+			continue
+		}
+		verifications[i] = bh.verifier.Verify(processor, msg)
+	}
+	return verifications
+}
+
+// processMessage validates the message received at the given time on the
+// stream counted by quota and enqueues it for ordering, waiting for its
+// commit if asked to, and returns the response to send to the client.  The
+// verification of the message, if not nil, was started before it was
+// processed.
+func (bh *handlerImpl) processMessage(ctx context.Context, msg *cb.Envelope, received time.Time, waitCommit bool, addr, subject string, quota *streamquota.Stream, verification *msgprocessor.Verification) *ab.BroadcastResponse {
+	bh.metrics.messageReceived()
+	//从接收消息时开始追踪，各处理阶段的span是其子span
+	ctx, span := bh.tracer.StartServerSpan(ctx, tracing.SpanBroadcast, received)
+	chdr, resp := bh.enqueueMessage(ctx, msg, addr, received, waitCommit, quota, verification)
+	bh.recordMisbehavior(ctx, msg, chdr, resp)
+	if resp.Status != cb.Status_SUCCESS {
+		bh.metrics.messageRejected(resp.Status)
+		span.SetError(resp.Info)
+	}
+	if chdr != nil {
+		span.SetAttribute("channel", chdr.ChannelId)
+		span.SetAttribute("tx_id", chdr.TxId)
+	}
+	span.SetAttribute("status", resp.Status.String())
+	span.End()
+	if bh.audit != nil {
+		rec := &audit.Record{
+			ClientSubject: subject,
+			RemoteAddress: addr,
+			Status:        resp.Status.String(),
+			Received:      received,
+			Responded:     time.Now(),
+		}
+		if chdr != nil {
+			rec.TxID, rec.ChannelID = chdr.TxId, chdr.ChannelId
+		}
+		bh.audit.Audit(rec)
+	}
+	return resp
+}
+
+// clientSubject returns the subject of the TLS certificate of the client of
+// the stream, which is only needed for the audit trail
+func (bh *handlerImpl) clientSubject(ctx context.Context) string {
+	if bh.audit == nil {
+		return ""
+	}
+	cert := comm.ExtractCertificateFromContext(ctx)
+	if cert == nil {
+		return ""
+	}
+	return cert.Subject.String()
+}
+
+// enqueueMessage processes the message received at the given time on the
+// stream of the context, and returns its channel header, if it could be
+// parsed, and the response to it.  If waitCommit is set, the response is
+// returned once the message is committed or the commit timeout expires.  The
+// stream is counted by quota towards the quota of the creator of the message
+// once the message is validated.  A normal message is validated by its
+// verification, if it is not nil and of its processor, or on the verifier
+// pool.
+func (bh *handlerImpl) enqueueMessage(ctx context.Context, msg *cb.Envelope, addr string, received time.Time, waitCommit bool, quota *streamquota.Stream, verification *msgprocessor.Verification) (*cb.ChannelHeader, *ab.BroadcastResponse) {
+	//签名头部每个消息只解析一次，其创建者在签名验证之前仅用于封禁检查
+	shdr := signatureHeader(msg)
+
+	//因行为异常被封禁的客户端或身份的消息在解析处理之前拒绝
+	if bh.misbehavior != nil {
+		if err := bh.misbehavior.Blocked(clientID(ctx), shdr.GetCreator()); err != nil {
+			logger.Warningf("Rejecting broadcast of message from %s with FORBIDDEN: %s", addr, err)
+			return nil, reject(cb.Status_FORBIDDEN, errorDetail(ab.ErrorDetail_CLIENT_BLOCKED, err), err)
+		}
+	}
+
+	//检查消息envelop中的一些字段，比如channelId
+	//如果是HeaderType_CONFIG_UPDATE类型的消息，则会将消息经过bh.sm.Process(msg)
+	//检查获取的通道头部chdr，配置交易消息标志位isConfig、通道链支持对象（通道消息处理器）
+	chdr, isConfig, processor, err := bh.sm.BroadcastChannelSupport(msg)
+	if err != nil {
+		channelID := "<malformed_header>"
+		if chdr != nil {
+			channelID = chdr.ChannelId
+		} else if bh.malformed != nil {
+			//无法解析通道头部的消息存入畸形消息语料库
+			bh.malformed.Record("broadcast", msg, err)
+		}
+		logger.Warningf("[channel: %s] Could not get message processor for serving %s: %s", channelID, addr, err)
+		detail := malformedDetail(msg)
+		if chdr != nil {
+			//通道头部可以解析，但消息类型不允许直接提交
+			detail = &ab.ErrorDetail{Code: ab.ErrorDetail_INVALID_MESSAGE, FieldPath: "payload.header.channel_header.type"}
+		}
+		return chdr, reject(cb.Status_BAD_REQUEST, detail, err)
+	}
+
+	//在验证消息之前拒绝超过通道最大消息大小的消息
+	if err = bh.sizeLimits.check(chdr.ChannelId, msg); err != nil {
+		logger.Warningf("[channel: %s] Rejecting broadcast of message from %s with BAD_REQUEST: %s", chdr.ChannelId, addr, err)
+		return chdr, reject(cb.Status_BAD_REQUEST, errorDetail(ab.ErrorDetail_MESSAGE_TOO_LARGE, err), err)
+	}
+
+	//按入队延迟预算进行准入控制，超出SLO时拒绝低优先级通道的消息
+	if bh.admission != nil {
+		if err = bh.admission.Admit(chdr.ChannelId, isConfig); err != nil {
+			logger.Warningf("[channel: %s] Rejecting broadcast of message from %s with SERVICE_UNAVAILABLE: rejected by admission control: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_OVERLOADED, err), err)
+		}
+	}
+
+	//共识组件积压的消息字节数超过高水位时拒绝普通交易消息，并提示客户端退避时间
+	if !isConfig {
+		if err = bh.backpressure.check(chdr.ChannelId); err != nil {
+			logger.Warningf("[channel: %s] Rejecting broadcast of normal message from %s with SERVICE_UNAVAILABLE: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_OVERLOADED, err), err)
+		}
+	}
+
+	//检查共识组件是否已经准备好可以接受新交易消息
+	//solo共识组件，调用的时候返回nil，表示任何时候都允许Broadcast服务处理句柄接受新的消息
+	//最多等待就绪超时时间，超时后拒绝消息并提示客户端重试时间
+	waitStart := time.Now()
+	_, waitSpan := bh.tracer.StartSpan(ctx, tracing.SpanWaitReady)
+	err = bh.waitReady(ctx, processor)
+	endSpan(waitSpan, err)
+	waitReady := time.Since(waitStart)
+	if err != nil {
+		logger.Warningf("[channel: %s] Rejecting broadcast of message from %s with SERVICE_UNAVAILABLE: rejected by Consenter: %s", chdr.ChannelId, addr, err)
+		return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_CONSENTER_UNAVAILABLE, err), err)
+	}
+
+	//通道由其他排序节点领导时转发给领导者，由其验证、计费与排序消息
+	if resp, ok := bh.forwarding.forward(ctx, chdr.ChannelId, msg, addr); ok {
+		return chdr, resp
+	}
+
+	//等待提交的交易消息，以及其提交通知
+	var awaited *cb.ChannelHeader
+	var committed <-chan uint64
+	var release func()
+	//消息验证时的通道配置序号，签入回执
+	var acceptedSeq uint64
+
+	//检查是否为配置交易消息
+	if !isConfig {
+		//普通交易信息
+		logger.Debugf("[channel: %s] Broadcast is processing normal message from %s with txid '%s' of type %s", chdr.ChannelId, addr, chdr.TxId, cb.HeaderType_name[chdr.Type])
+
+		//解析获取通道的最新配置序号
+		//签名与策略验证在验证协程池上进行，配置序号在验证之前读取
+		_, processSpan := bh.tracer.StartSpan(ctx, tracing.SpanProcess)
+		if verification == nil || !verification.Of(processor, msg) {
+			verification = bh.verifier.Verify(processor, msg)
+		}
+		configSeq, err := verification.Wait()
+		endSpan(processSpan, err)
 		if err != nil {
-			channelID := "<malformed_header>"
-			if chdr != nil {
-				channelID = chdr.ChannelId
-			}
-			logger.Warningf("[channel: %s] Could not get message processor for serving %s: %s", channelID, addr, err)
-			return srv.Send(&ab.BroadcastResponse{Status: cb.Status_BAD_REQUEST, Info: err.Error()})
+			logger.Warningf("[channel: %s] Rejecting broadcast of normal message from %s because of error: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(ClassifyError(err), ClassifyErrorDetail(err), err)
 		}
 
-		//检查共识组件是否已经准备好可以接受新交易消息
-		//solo共识组件，调用的时候返回nil，表示任何时候都允许Broadcast服务处理句柄接受新的消息
-		if err = processor.WaitReady(); err != nil {
-			logger.Warningf("[channel: %s] Rejecting broadcast of message from %s with SERVICE_UNAVAILABLE: rejected by Consenter: %s", chdr.ChannelId, addr, err)
-			return srv.Send(&ab.BroadcastResponse{Status: cb.Status_SERVICE_UNAVAILABLE, Info: err.Error()})
+		acceptedSeq = configSeq
+
+		//签名验证通过后按通道与客户端身份限流
+		if err = bh.allow(chdr.ChannelId, shdr.GetCreator()); err != nil {
+			logger.Warningf("[channel: %s] Rejecting broadcast of normal message from %s with SERVICE_UNAVAILABLE: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_RATE_LIMITED, err), err)
 		}
 
-		//检查是否为配置交易消息
-		if !isConfig {
-			//普通交易信息
-			logger.Debugf("[channel: %s] Broadcast is processing normal message from %s with txid '%s' of type %s", chdr.ChannelId, addr, chdr.TxId, cb.HeaderType_name[chdr.Type])
+		//签名验证通过后，在入口处按创建者所属组织的入口配额计费，共识组件重新验证时不再计费
+		if err = bh.charge(chdr.ChannelId, shdr.GetCreator()); err != nil {
+			logger.Warningf("[channel: %s] Rejecting broadcast of normal message from %s with FORBIDDEN: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(ClassifyError(err), ClassifyErrorDetail(err), err)
+		}
 
-			//解析获取通道的最新配置序号
-			configSeq, err := processor.ProcessNormalMsg(msg)
-			if err != nil {
-				logger.Warningf("[channel: %s] Rejecting broadcast of normal message from %s because of error: %s", chdr.ChannelId, addr, err)
-				return srv.Send(&ab.BroadcastResponse{Status: ClassifyError(err), Info: err.Error()})
-			}
+		//签名验证通过后，消息流计入创建者在通道上的消息流配额
+		if err = acquireStream(quota, chdr.ChannelId, shdr.GetCreator()); err != nil {
+			logger.Warningf("[channel: %s] Rejecting broadcast of normal message from %s with SERVICE_UNAVAILABLE: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_RATE_LIMITED, err), err)
+		}
 
-			//构造新的普通交易消息并发送到共识组件链对象排序请求处理
-			err = processor.Order(msg, configSeq)
-			if err != nil {
-				logger.Warningf("[channel: %s] Rejecting broadcast of normal message from %s with SERVICE_UNAVAILABLE: rejected by Order: %s", chdr.ChannelId, addr, err)
-				return srv.Send(&ab.BroadcastResponse{Status: cb.Status_SERVICE_UNAVAILABLE, Info: err.Error()})
+		//由准入插件执行部署自定义的检查
+		if err = bh.admit(chdr, msg, shdr.GetCreator()); err != nil {
+			logger.Warningf("[channel: %s] Rejecting broadcast of normal message from %s with FORBIDDEN: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(cb.Status_FORBIDDEN, errorDetail(ab.ErrorDetail_ADMISSION_DENIED, err), err)
+		}
+
+		//重复提交的交易消息直接确认，不再排序
+		dedupTxID := bh.dedupTxID(chdr, shdr)
+		if dedupTxID != "" && !bh.duplicates.Add(chdr.ChannelId, dedupTxID) {
+			logger.Debugf("[channel: %s] Acknowledging duplicate of transaction %s from %s without ordering it", chdr.ChannelId, dedupTxID, addr)
+			return chdr, &ab.BroadcastResponse{Status: cb.Status_SUCCESS, Info: DuplicateInfo}
+		}
+
+		//严格排序模式下拒绝创建者重放的或早于其上一个消息的nonce
+		if err = bh.advanceNonce(chdr.ChannelId, msg); err != nil {
+			if dedupTxID != "" {
+				bh.duplicates.Remove(chdr.ChannelId, dedupTxID)
 			}
-		} else { // isConfig
-			//通道配置交易消息：创建或更新应用通道
-			logger.Debugf("[channel: %s] Broadcast is processing config update message from %s", chdr.ChannelId, addr)
+			logger.Warningf("[channel: %s] Rejecting broadcast of normal message from %s because of error: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(ClassifyError(err), ClassifyErrorDetail(err), err)
+		}
 
-			//获取配置交易消息与通道的最新配置序号
-			config, configSeq, err := processor.ProcessConfigUpdateMsg(msg)
-			if err != nil {
-				logger.Warningf("[channel: %s] Rejecting broadcast of config message from %s because of error: %s", chdr.ChannelId, addr, err)
-				return srv.Send(&ab.BroadcastResponse{Status: ClassifyError(err), Info: err.Error()})
+		//共识组件可能立即切块，需在提交之前登记等待交易提交
+		if waitCommit {
+			if committed, release, err = bh.commits.Register(chdr.ChannelId, chdr.TxId); err != nil {
+				if dedupTxID != "" {
+					bh.duplicates.Remove(chdr.ChannelId, dedupTxID)
+				}
+				bh.withdrawNonce(chdr.ChannelId, msg)
+				logger.Warningf("[channel: %s] Rejecting broadcast of normal message from %s with SERVICE_UNAVAILABLE: cannot wait for its commit: %s", chdr.ChannelId, addr, err)
+				return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_OVERLOADED, err), err)
 			}
+			awaited = chdr
+		}
 
-			//构造新的配置交易消息发送到共识组件链对象请求处理
-			err = processor.Configure(config, configSeq)
-			if err != nil {
-				logger.Warningf("[channel: %s] Rejecting broadcast of config message from %s with SERVICE_UNAVAILABLE: rejected by Configure: %s", chdr.ChannelId, addr, err)
-				return srv.Send(&ab.BroadcastResponse{Status: cb.Status_SERVICE_UNAVAILABLE, Info: err.Error()})
+		//构造新的普通交易消息并发送到共识组件链对象排序请求处理
+		//共识组件可能立即排序消息，需在提交之前开始追踪其共识阶段
+		bh.tracer.Submitted(ctx, chdr.ChannelId, chdr.TxId)
+		_, orderSpan := bh.tracer.StartSpan(ctx, tracing.SpanOrder)
+		scheduled, err := bh.schedule(ctx, chdr.ChannelId, processor, func() error {
+			return bh.journaled(msg, func() error { return processor.Order(msg, configSeq) })
+		})
+		endSpan(orderSpan, err)
+		if err != nil {
+			if release != nil {
+				release()
+			}
+			bh.tracer.Withdraw(chdr.ChannelId, chdr.TxId)
+			if dedupTxID != "" {
+				bh.duplicates.Remove(chdr.ChannelId, dedupTxID)
 			}
+			bh.withdrawNonce(chdr.ChannelId, msg)
+			if !scheduled {
+				logger.Warningf("[channel: %s] Rejecting broadcast of normal message from %s with SERVICE_UNAVAILABLE: not scheduled: %s", chdr.ChannelId, addr, err)
+				return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_OVERLOADED, err), err)
+			}
+			logger.Warningf("[channel: %s] Rejecting broadcast of normal message from %s with SERVICE_UNAVAILABLE: rejected by Order: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_CONSENTER_UNAVAILABLE, err), err)
 		}
+	} else { // isConfig
+		//通道配置交易消息：创建或更新应用通道
+		logger.Debugf("[channel: %s] Broadcast is processing config update message from %s", chdr.ChannelId, addr)
 
-		logger.Debugf("[channel: %s] Broadcast has successfully enqueued message of type %s from %s", chdr.ChannelId, cb.HeaderType_name[chdr.Type], addr)
+		//获取配置交易消息与通道的最新配置序号
+		_, processSpan := bh.tracer.StartSpan(ctx, tracing.SpanProcess)
+		config, configSeq, err := processor.ProcessConfigUpdateMsg(msg)
+		endSpan(processSpan, err)
+		if err != nil {
+			logger.Warningf("[channel: %s] Rejecting broadcast of config message from %s because of error: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(ClassifyError(err), ClassifyErrorDetail(err), err)
+		}
+		acceptedSeq = configSeq
+
+		if err = bh.allow(chdr.ChannelId, shdr.GetCreator()); err != nil {
+			logger.Warningf("[channel: %s] Rejecting broadcast of config message from %s with SERVICE_UNAVAILABLE: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_RATE_LIMITED, err), err)
+		}
 
-		//发送成功处理状态相应消息
-		err = srv.Send(&ab.BroadcastResponse{Status: cb.Status_SUCCESS})
+		if err = acquireStream(quota, chdr.ChannelId, shdr.GetCreator()); err != nil {
+			logger.Warningf("[channel: %s] Rejecting broadcast of config message from %s with SERVICE_UNAVAILABLE: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_RATE_LIMITED, err), err)
+		}
+
+		if err = bh.advanceNonce(chdr.ChannelId, msg); err != nil {
+			logger.Warningf("[channel: %s] Rejecting broadcast of config message from %s because of error: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(ClassifyError(err), ClassifyErrorDetail(err), err)
+		}
+
+		//构造新的配置交易消息发送到共识组件链对象请求处理
+		//排序的是新构造的配置交易消息，按其通道与交易ID追踪
+		var configChdr *cb.ChannelHeader
+		if bh.tracer != nil || waitCommit {
+			configChdr, _ = utils.ChannelHeader(config)
+		}
+		//提交的是新构造的配置交易消息，等待其提交
+		if waitCommit && configChdr != nil {
+			if committed, release, err = bh.commits.Register(configChdr.ChannelId, configChdr.TxId); err != nil {
+				bh.withdrawNonce(chdr.ChannelId, msg)
+				logger.Warningf("[channel: %s] Rejecting broadcast of config message from %s with SERVICE_UNAVAILABLE: cannot wait for its commit: %s", chdr.ChannelId, addr, err)
+				return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_OVERLOADED, err), err)
+			}
+			awaited = configChdr
+		}
+		if configChdr != nil {
+			bh.tracer.Submitted(ctx, configChdr.ChannelId, configChdr.TxId)
+		}
+		_, orderSpan := bh.tracer.StartSpan(ctx, tracing.SpanOrder)
+		scheduled, err := bh.schedule(ctx, chdr.ChannelId, processor, func() error {
+			return bh.journaled(config, func() error { return processor.Configure(config, configSeq) })
+		})
+		endSpan(orderSpan, err)
 		if err != nil {
-			logger.Warningf("[channel: %s] Error sending to %s: %s", chdr.ChannelId, addr, err)
-			return err
+			if release != nil {
+				release()
+			}
+			if configChdr != nil {
+				bh.tracer.Withdraw(configChdr.ChannelId, configChdr.TxId)
+			}
+			bh.withdrawNonce(chdr.ChannelId, msg)
+			if !scheduled {
+				logger.Warningf("[channel: %s] Rejecting broadcast of config message from %s with SERVICE_UNAVAILABLE: not scheduled: %s", chdr.ChannelId, addr, err)
+				return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_OVERLOADED, err), err)
+			}
+			logger.Warningf("[channel: %s] Rejecting broadcast of config message from %s with SERVICE_UNAVAILABLE: rejected by Configure: %s", chdr.ChannelId, addr, err)
+			return chdr, reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_CONSENTER_UNAVAILABLE, err), err)
 		}
 	}
+
+	latency := time.Since(received)
+	if bh.admission != nil {
+		bh.admission.Observe(latency)
+	}
+	bh.metrics.messageEnqueued(chdr.ChannelId, isConfig, proto.Size(msg), waitReady, latency)
+
+	logger.Debugf("[channel: %s] Broadcast has successfully enqueued message of type %s from %s", chdr.ChannelId, cb.HeaderType_name[chdr.Type], addr)
+	//在等待提交之前签署回执，其时间为消息入队的时间
+	receipt := bh.receipt(ctx, chdr, acceptedSeq, msg, addr)
+	resp := &ab.BroadcastResponse{Status: cb.Status_SUCCESS}
+	if awaited != nil {
+		resp = bh.awaitCommit(ctx, committed, release, awaited, addr)
+	}
+	resp.Receipt = receipt
+	return chdr, resp
 }
 
-// ClassifyError converts an error type into a status code.
-func ClassifyError(err error) cb.Status {
-	switch errors.Cause(err) {
-	case msgprocessor.ErrChannelDoesNotExist:
-		return cb.Status_NOT_FOUND
-	case msgprocessor.ErrPermissionDenied:
-		return cb.Status_FORBIDDEN
+// receipt returns the signed receipt of the message enqueued, if the client
+// asks for one.  The message being enqueued already, it is answered SUCCESS
+// without receipt if the receipt cannot be signed.
+func (bh *handlerImpl) receipt(ctx context.Context, chdr *cb.ChannelHeader, configSeq uint64, msg *cb.Envelope, addr string) *ab.SignedReceipt {
+	if !bh.receipts.wanted(ctx) {
+		return nil
+	}
+	receipt, err := bh.receipts.sign(chdr, configSeq, msg)
+	if err != nil {
+		logger.Errorf("[channel: %s] Could not sign receipt of transaction %s for %s: %s", chdr.ChannelId, chdr.TxId, addr, err)
+		return nil
+	}
+	return receipt
+}
+
+// recordMisbehavior charges the suspicious message, given its channel header,
+// if it could be parsed, and its response, to its client, and its replays to
+// their creator as well, whose signature was checked
+func (bh *handlerImpl) recordMisbehavior(ctx context.Context, msg *cb.Envelope, chdr *cb.ChannelHeader, resp *ab.BroadcastResponse) {
+	if bh.misbehavior == nil {
+		return
+	}
+	var channelID string
+	if chdr != nil {
+		channelID = chdr.ChannelId
+	}
+	var pattern misbehavior.Pattern
+	switch {
+	case resp.Status == cb.Status_SUCCESS && resp.Info == DuplicateInfo:
+		pattern = misbehavior.Replay
+	case resp.ErrorDetail == nil:
+		return
+	case resp.ErrorDetail.Code == ab.ErrorDetail_MALFORMED_MESSAGE:
+		pattern = misbehavior.MalformedMessage
+	case resp.ErrorDetail.Code == ab.ErrorDetail_PERMISSION_DENIED:
+		pattern = misbehavior.InvalidSignature
+	case resp.ErrorDetail.Code == ab.ErrorDetail_INVALID_MESSAGE && chdr != nil && chdr.Type == int32(cb.HeaderType_CONFIG_UPDATE):
+		pattern = misbehavior.MalformedConfig
 	default:
-		return cb.Status_BAD_REQUEST
+		return
+	}
+	creator := messageCreator(msg)
+	bh.misbehavior.RecordClient(clientID(ctx), anonymizedClientID(ctx), creator, pattern, channelID)
+	if pattern == misbehavior.Replay {
+		bh.misbehavior.RecordIdentity(creator, pattern, channelID)
+	}
+}
+
+// messageCreator returns the creator claimed by the signature header of the
+// message, nil if it cannot be parsed
+func messageCreator(msg *cb.Envelope) []byte {
+	return signatureHeader(msg).GetCreator()
+}
+
+// signatureHeader returns the signature header of the message, nil if it
+// cannot be parsed
+func signatureHeader(msg *cb.Envelope) *cb.SignatureHeader {
+	payload, err := utils.UnmarshalPayload(msg.GetPayload())
+	if err != nil || payload.Header == nil {
+		return nil
+	}
+	shdr, err := utils.GetSignatureHeader(payload.Header.SignatureHeader)
+	if err != nil {
+		return nil
+	}
+	return shdr
+}
+
+// waitReady waits for the consenter to be ready to accept messages, for at
+// most the ready timeout
+func (bh *handlerImpl) waitReady(ctx context.Context, consenter Consenter) error {
+	if bh.readyTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, bh.readyTimeout)
+		defer cancel()
+	}
+	return consenter.WaitReadyContext(ctx)
+}
+
+// schedule passes the message to its consenter with enqueue once the
+// ingress scheduler gives it its turn, and returns whether it had its turn
+// and the error of either the scheduler or enqueue
+func (bh *handlerImpl) schedule(ctx context.Context, channelID string, support ChannelSupport, enqueue func() error) (bool, error) {
+	if bh.scheduler == nil {
+		return true, enqueue()
+	}
+	scheduled := false
+	err := bh.scheduler.Schedule(ctx, channelID, support.IngressWeight(), func() error {
+		scheduled = true
+		return enqueue()
+	})
+	return scheduled, err
+}
+
+// advanceNonce records the nonce of the message, unless its creator used a
+// nonce as high before
+func (bh *handlerImpl) advanceNonce(channelID string, msg *cb.Envelope) error {
+	if bh.nonces == nil {
+		return nil
+	}
+	return bh.nonces.Advance(channelID, msg)
+}
+
+// withdrawNonce forgets the nonce of the message which was not ordered
+func (bh *handlerImpl) withdrawNonce(channelID string, msg *cb.Envelope) {
+	if bh.nonces != nil {
+		bh.nonces.Withdraw(channelID, msg)
+	}
+}
+
+// journaled records the message in the intake journal before passing it to
+// its consenter with enqueue, and forgets it if the consenter rejects it
+func (bh *handlerImpl) journaled(env *cb.Envelope, enqueue func() error) error {
+	if bh.journal == nil {
+		return enqueue()
+	}
+	if err := bh.journal.Append(env); err != nil {
+		return errors.WithMessage(err, "could not journal message")
+	}
+	if err := enqueue(); err != nil {
+		bh.journal.Discard(env)
+		return err
+	}
+	return nil
+}
+
+// endSpan ends the span of a stage, marking it failed if the stage failed
+func endSpan(span *tracing.Span, err error) {
+	if err != nil {
+		span.SetError(err.Error())
+	}
+	span.End()
+}
+
+// allow applies the rate limits to the message of the creator.  It is
+// called once the message has been processed, so that its creator, whose
+// signature was checked, cannot be impersonated to exhaust the rate of
+// another client.
+func (bh *handlerImpl) allow(channelID string, creator []byte) error {
+	if bh.limiter == nil {
+		return nil
+	}
+	return bh.limiter.Allow(channelID, creator)
+}
+
+// charge charges the normal message of the creator against the ingress
+// quota of its organization.  Like allow, it is called once the message has
+// been processed, and it is called once per message, as the consenters do
+// not charge the messages they revalidate.
+func (bh *handlerImpl) charge(channelID string, creator []byte) error {
+	if bh.quotas == nil {
+		return nil
+	}
+	return bh.quotas.Charge(channelID, creator)
+}
+
+// acquireStream counts the stream towards the quota of the creator of the
+// message on the channel.  Like allow, it is called once the message has
+// been processed, so that nobody can exhaust the quota of another identity.
+func acquireStream(quota *streamquota.Stream, channelID string, creator []byte) error {
+	if quota == nil {
+		return nil
+	}
+	return quota.Acquire(channelID, creator)
+}
+
+// admit submits the message of the creator to the admission plugin
+func (bh *handlerImpl) admit(chdr *cb.ChannelHeader, msg *cb.Envelope, creator []byte) error {
+	if bh.admissionPlugin == nil {
+		return nil
+	}
+	return bh.admissionPlugin.Admit(&admissionplugin.Request{ChannelID: chdr.ChannelId, TxID: chdr.TxId, Creator: creator, Envelope: msg})
+}
+
+// dedupTxID returns the transaction ID under which the message, given its
+// channel and signature headers, is deduplicated, if any.  Only the
+// transaction IDs bound to the nonce and the creator of the message, whose
+// signature was checked, are considered, so that nobody else can claim the
+// transaction ID of a client.
+func (bh *handlerImpl) dedupTxID(chdr *cb.ChannelHeader, shdr *cb.SignatureHeader) string {
+	if bh.duplicates == nil || chdr.TxId == "" || shdr == nil {
+		return ""
+	}
+	if utils.CheckTxID(chdr.TxId, shdr.Nonce, shdr.Creator) != nil {
+		return ""
+	}
+	return chdr.TxId
+}
+
+// ClassifyError converts an error into a status code: the status of the
+// category of the first error along its causes implementing
+// msgprocessor.StatusCoder, or BAD_REQUEST if none does.
+func ClassifyError(err error) cb.Status {
+	for err != nil {
+		if sc, ok := err.(msgprocessor.StatusCoder); ok {
+			return sc.Status()
+		}
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		err = cause.Cause()
 	}
+	return cb.Status_BAD_REQUEST
 }
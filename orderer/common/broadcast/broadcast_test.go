@@ -7,20 +7,47 @@ SPDX-License-Identifier: Apache-2.0
 package broadcast
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/binary"
 	"fmt"
 	"io"
+	"net"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/flogging"
+	mockcrypto "github.com/hyperledger/fabric/common/mocks/crypto"
+	"github.com/hyperledger/fabric/common/retry"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/orderer/common/admissionplugin"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/crash"
+	"github.com/hyperledger/fabric/orderer/common/dedup"
+	"github.com/hyperledger/fabric/orderer/common/misbehavior"
 	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	"github.com/hyperledger/fabric/orderer/common/privacy"
+	"github.com/hyperledger/fabric/orderer/common/ratelimit"
+	"github.com/hyperledger/fabric/orderer/common/streamquota"
+	"github.com/hyperledger/fabric/orderer/common/tracing"
+	"github.com/hyperledger/fabric/orderer/consensus"
 	cb "github.com/hyperledger/fabric/protos/common"
 	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 func init() {
@@ -105,15 +132,29 @@ func (mm *mockSupportManager) BroadcastChannelSupport(msg *cb.Envelope) (*cb.Cha
 	return mm.ChdrVal, mm.MsgProcessorIsConfig, mm.MsgProcessorVal, mm.MsgProcessorErr
 }
 
+type mockMalformedRecorder struct {
+	kinds []string
+}
+
+func (mmr *mockMalformedRecorder) Record(kind string, env *cb.Envelope, parseErr error) {
+	mmr.kinds = append(mmr.kinds, kind)
+}
+
 type mockSupport struct {
 	ProcessConfigEnv *cb.Envelope
 	ProcessConfigSeq uint64
 	ProcessErr       error
 	rejectEnqueue    bool
+	notReady         bool
+	weight           uint32
 }
 
-func (ms *mockSupport) WaitReady() error {
-	return nil
+func (ms *mockSupport) WaitReadyContext(ctx context.Context) error {
+	if !ms.notReady {
+		return nil
+	}
+	<-ctx.Done()
+	return &consensus.NotReadyError{Reason: "reprocessing", Position: 10, Wait: 3 * time.Second}
 }
 
 // Order sends a message for ordering
@@ -129,6 +170,10 @@ func (ms *mockSupport) Configure(config *cb.Envelope, configSeq uint64) error {
 	return ms.Order(config, configSeq)
 }
 
+func (ms *mockSupport) IngressWeight() uint32 {
+	return ms.weight
+}
+
 func (ms *mockSupport) ClassifyMsg(chdr *cb.ChannelHeader) msgprocessor.Classification {
 	panic("UNIMPLMENTED")
 }
@@ -154,7 +199,7 @@ func getMockSupportManager() *mockSupportManager {
 
 func TestEnqueueFailure(t *testing.T) {
 	mm := getMockSupportManager()
-	bh := NewHandlerImpl(mm)
+	bh := NewHandlerImpl(mm, HandlerOptions{})
 	m := newMockB()
 	defer close(m.recvChan)
 	done := make(chan struct{})
@@ -192,18 +237,89 @@ func TestClassifyError(t *testing.T) {
 	t.Run("Forbidden", func(t *testing.T) {
 		assert.Equal(t, cb.Status_FORBIDDEN, ClassifyError(msgprocessor.ErrPermissionDenied))
 	})
+	t.Run("IdentityExpired", func(t *testing.T) {
+		assert.Equal(t, cb.Status_FORBIDDEN, ClassifyError(errors.Wrap(msgprocessor.ErrIdentityExpired, "certificate expired at 2018-01-01T00:00:00Z")))
+	})
+	t.Run("QuotaExceeded", func(t *testing.T) {
+		assert.Equal(t, cb.Status_FORBIDDEN, ClassifyError(errors.Wrap(msgprocessor.ErrQuotaExceeded, "organization Org1MSP exceeds its quota")))
+	})
+	t.Run("RateLimited", func(t *testing.T) {
+		assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, ClassifyError(errors.Wrap(msgprocessor.ErrRateLimited, "too many")))
+	})
 	t.Run("WrappedErr", func(t *testing.T) {
 		assert.Equal(t, cb.Status_NOT_FOUND, ClassifyError(errors.Wrap(msgprocessor.ErrChannelDoesNotExist, "A wrapped error")))
 	})
+	t.Run("Transient", func(t *testing.T) {
+		err := msgprocessor.NewError(msgprocessor.Transient, "consenter is catching up")
+		assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, ClassifyError(errors.Wrap(errors.WithStack(err), "wrapped")))
+	})
+	t.Run("StatusCoder", func(t *testing.T) {
+		assert.Equal(t, cb.Status_REQUEST_ENTITY_TOO_LARGE, ClassifyError(errors.Wrap(tooLargeError{}, "wrapped")))
+	})
 	t.Run("DefaultBadReq", func(t *testing.T) {
 		assert.Equal(t, cb.Status_BAD_REQUEST, ClassifyError(fmt.Errorf("Foo")))
 	})
 }
 
+// TestClassifyErrorRetryability checks that the status and the detail of the
+// rejections agree on whether clients retry them, as the categories of the
+// errors tell
+func TestClassifyErrorRetryability(t *testing.T) {
+	for _, tc := range []struct {
+		err       error
+		retryable bool
+	}{
+		{msgprocessor.ErrEmptyMessage, false},
+		{msgprocessor.ErrChannelDoesNotExist, false},
+		{msgprocessor.ErrPermissionDenied, false},
+		{errors.Wrap(msgprocessor.ErrIdentityExpired, "wrapped"), false},
+		{errors.Wrap(msgprocessor.ErrQuotaExceeded, "wrapped"), false},
+		{errors.Wrap(msgprocessor.ErrRateLimited, "wrapped"), true},
+		{errors.Wrap(retryableError{msgprocessor.ErrRateLimited}, "wrapped"), true},
+		{msgprocessor.NewError(msgprocessor.Transient, "consenter is catching up"), true},
+		{msgprocessor.NewError(msgprocessor.Validation, "bad signature"), false},
+		{tooLargeError{}, false},
+		{fmt.Errorf("Foo"), false},
+	} {
+		resp := reject(ClassifyError(tc.err), ClassifyErrorDetail(tc.err), tc.err)
+		assert.Equal(t, tc.retryable, retry.Status(resp.Status).Retryable(), "status of %s", tc.err)
+		assert.Equal(t, tc.retryable, retry.Broadcast(resp).Retryable(), "detail of %s", tc.err)
+	}
+}
+
+// tooLargeError determines its own status
+type tooLargeError struct{}
+
+func (tooLargeError) Error() string     { return "too large" }
+func (tooLargeError) Status() cb.Status { return cb.Status_REQUEST_ENTITY_TOO_LARGE }
+
+// retryableError is an error telling when to retry
+type retryableError struct {
+	error
+}
+
+func (e retryableError) Cause() error              { return e.error }
+func (e retryableError) RetryAfter() time.Duration { return 2 * time.Second }
+
+func TestClassifyErrorDetail(t *testing.T) {
+	assert.Equal(t, ab.ErrorDetail_CHANNEL_NOT_FOUND, ClassifyErrorDetail(errors.Wrap(msgprocessor.ErrChannelDoesNotExist, "wrapped")).Code)
+	assert.Equal(t, ab.ErrorDetail_PERMISSION_DENIED, ClassifyErrorDetail(msgprocessor.ErrPermissionDenied).Code)
+	assert.Equal(t, ab.ErrorDetail_IDENTITY_EXPIRED, ClassifyErrorDetail(errors.Wrap(msgprocessor.ErrIdentityExpired, "wrapped")).Code)
+	assert.Equal(t, ab.ErrorDetail_QUOTA_EXCEEDED, ClassifyErrorDetail(errors.Wrap(msgprocessor.ErrQuotaExceeded, "wrapped")).Code)
+	assert.Equal(t, ab.ErrorDetail_INVALID_MESSAGE, ClassifyErrorDetail(fmt.Errorf("Foo")).Code)
+
+	detail := ClassifyErrorDetail(errors.Wrap(retryableError{msgprocessor.ErrRateLimited}, "too many"))
+	assert.Equal(t, ab.ErrorDetail_RATE_LIMITED, detail.Code)
+	assert.Equal(t, int64(2), detail.RetryAfter.Seconds)
+
+	// 没有重试提示的错误不设置retry_after
+	assert.Nil(t, ClassifyErrorDetail(msgprocessor.ErrRateLimited).RetryAfter)
+}
+
 func TestBadChannelId(t *testing.T) {
 	mm := getMockSupportManager()
 	mm.MsgProcessorVal = &mockSupport{ProcessErr: msgprocessor.ErrChannelDoesNotExist}
-	bh := NewHandlerImpl(mm)
+	bh := NewHandlerImpl(mm, HandlerOptions{})
 	m := newMockB()
 	defer close(m.recvChan)
 	done := make(chan struct{})
@@ -228,7 +344,7 @@ func TestBadChannelId(t *testing.T) {
 func TestGoodConfigUpdate(t *testing.T) {
 	mm := getMockSupportManager()
 	mm.MsgProcessorIsConfig = true
-	bh := NewHandlerImpl(mm)
+	bh := NewHandlerImpl(mm, HandlerOptions{})
 	m := newMockB()
 	defer close(m.recvChan)
 	go bh.Handle(m)
@@ -242,7 +358,7 @@ func TestBadConfigUpdate(t *testing.T) {
 	mm := getMockSupportManager()
 	mm.MsgProcessorIsConfig = true
 	mm.MsgProcessorVal.ProcessErr = fmt.Errorf("Error")
-	bh := NewHandlerImpl(mm)
+	bh := NewHandlerImpl(mm, HandlerOptions{})
 	m := newMockB()
 	defer close(m.recvChan)
 	go bh.Handle(m)
@@ -252,63 +368,1541 @@ func TestBadConfigUpdate(t *testing.T) {
 	assert.NotEqual(t, cb.Status_SUCCESS, reply.Status, "Should have rejected CONFIG_UPDATE")
 }
 
-func TestGracefulShutdown(t *testing.T) {
-	bh := NewHandlerImpl(nil)
+type mockAdmission struct {
+	admitErr  error
+	isConfig  bool
+	latencies []time.Duration
+}
+
+func (ma *mockAdmission) Admit(channelID string, isConfig bool) error {
+	ma.isConfig = isConfig
+	return ma.admitErr
+}
+
+func (ma *mockAdmission) Observe(latency time.Duration) {
+	ma.latencies = append(ma.latencies, latency)
+}
+
+func TestAdmission(t *testing.T) {
+	mm := getMockSupportManager()
+	admission := &mockAdmission{}
+	bh := NewHandlerImpl(mm, HandlerOptions{Admission: admission})
 	m := newMockB()
-	close(m.recvChan)
-	assert.NoError(t, bh.Handle(m), "Should exit normally upon EOF")
+	defer close(m.recvChan)
+	go bh.Handle(m)
+
+	m.recvChan <- nil
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.False(t, admission.isConfig)
+	assert.Len(t, admission.latencies, 1, "Enqueue latency should have been observed")
+
+	admission.admitErr = fmt.Errorf("overloaded")
+	m.recvChan <- nil
+	reply = <-m.sendChan
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status, "Should have shed the message")
+	assert.Equal(t, "overloaded", reply.Info)
+	assert.Len(t, admission.latencies, 1, "Rejected messages should not be observed")
 }
 
-func TestRejected(t *testing.T) {
-	mm := &mockSupportManager{
-		MsgProcessorVal: &mockSupport{ProcessErr: fmt.Errorf("Reject")},
-		ChdrVal:         &cb.ChannelHeader{},
+type mockLimiter struct {
+	allowErr error
+	channels []string
+	creators []string
+}
+
+func (ml *mockLimiter) Allow(channelID string, creator []byte) error {
+	ml.channels = append(ml.channels, channelID)
+	ml.creators = append(ml.creators, string(creator))
+	return ml.allowErr
+}
+
+func TestRateLimit(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel"}
+	limiter := &mockLimiter{}
+	bh := NewHandlerImpl(mm, HandlerOptions{Limiter: limiter})
+	m := newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(m)
+
+	env, _ := signedEnvelope(t, []byte("nonce"), []byte("creator"))
+	m.recvChan <- env
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Equal(t, []string{"mychannel"}, limiter.channels)
+	assert.Equal(t, []string{"creator"}, limiter.creators)
+
+	// config updates are throttled as well
+	mm.MsgProcessorIsConfig = true
+	limiter.allowErr = fmt.Errorf("rate limit exceeded")
+	m.recvChan <- env
+	reply = <-m.sendChan
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+	assert.Equal(t, "rate limit exceeded", reply.Info)
+	assert.Equal(t, ab.ErrorDetail_RATE_LIMITED, reply.ErrorDetail.Code)
+	assert.Nil(t, reply.ErrorDetail.RetryAfter)
+	assert.Len(t, limiter.channels, 2)
+
+	// the stream ends with the rejection, the client reconnects to retry
+	limiter.allowErr = retryableError{errors.Wrap(ratelimit.ErrRateLimited, "message rate exceeded")}
+	m = newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(m)
+	m.recvChan <- env
+	reply = <-m.sendChan
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+	assert.Equal(t, ab.ErrorDetail_RATE_LIMITED, reply.ErrorDetail.Code)
+	assert.Equal(t, int64(2), reply.ErrorDetail.RetryAfter.Seconds)
+}
+
+type mockQuotas struct {
+	chargeErr error
+	channels  []string
+	creators  []string
+}
+
+func (mq *mockQuotas) Charge(channelID string, creator []byte) error {
+	mq.channels = append(mq.channels, channelID)
+	mq.creators = append(mq.creators, string(creator))
+	return mq.chargeErr
+}
+
+func TestIngressQuotas(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel"}
+	quotas := &mockQuotas{}
+	bh := NewHandlerImpl(mm, HandlerOptions{Quotas: quotas})
+	m := newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(m)
+
+	env, _ := signedEnvelope(t, []byte("nonce"), []byte("creator"))
+	m.recvChan <- env
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Equal(t, []string{"mychannel"}, quotas.channels)
+	assert.Equal(t, []string{"creator"}, quotas.creators)
+
+	// config updates are not charged
+	mm.MsgProcessorIsConfig = true
+	quotas.chargeErr = errors.Wrap(msgprocessor.ErrQuotaExceeded, "organization Org1MSP exceeds its quota")
+	m.recvChan <- env
+	reply = <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Len(t, quotas.channels, 1)
+
+	// normal messages exceeding the quota are rejected before they are ordered
+	mm.MsgProcessorIsConfig = false
+	m.recvChan <- env
+	reply = <-m.sendChan
+	assert.Equal(t, cb.Status_FORBIDDEN, reply.Status)
+	assert.Equal(t, ab.ErrorDetail_QUOTA_EXCEEDED, reply.ErrorDetail.Code)
+	assert.Len(t, quotas.channels, 2)
+}
+
+func TestRejectionStatus(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel"}
+	limiter := &mockLimiter{allowErr: retryableError{errors.Wrap(ratelimit.ErrRateLimited, "message rate exceeded")}}
+	bh := NewHandlerImpl(mm, HandlerOptions{Limiter: limiter})
+	m := newMockB()
+	defer close(m.recvChan)
+	done := make(chan error)
+	go func() {
+		done <- bh.Handle(m)
+	}()
+
+	env, _ := signedEnvelope(t, []byte("nonce"), []byte("creator"))
+	m.recvChan <- env
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+
+	// 拒绝结束消息流，其状态携带错误详情
+	err := <-done
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	assert.Equal(t, codes.Unavailable, st.Code())
+	assert.Equal(t, reply.Info, st.Message())
+	require.Len(t, st.Details(), 1)
+	assert.True(t, proto.Equal(reply.ErrorDetail, st.Details()[0].(*ab.ErrorDetail)))
+}
+
+type mockScheduler struct {
+	channels []string
+	weights  []uint32
+	err      error
+}
+
+func (ms *mockScheduler) Schedule(ctx context.Context, channelID string, weight uint32, enqueue func() error) error {
+	ms.channels = append(ms.channels, channelID)
+	ms.weights = append(ms.weights, weight)
+	if ms.err != nil {
+		return ms.err
+	}
+	return enqueue()
+}
+
+func TestIngressScheduler(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel"}
+	mm.MsgProcessorVal = &mockSupport{weight: 3}
+	scheduler := &mockScheduler{}
+	bh := NewHandlerImpl(mm, HandlerOptions{Scheduler: scheduler})
+
+	send := func() *ab.BroadcastResponse {
+		m := newMockB()
+		defer close(m.recvChan)
+		go bh.Handle(m)
+		m.recvChan <- nil
+		return <-m.sendChan
 	}
-	bh := NewHandlerImpl(mm)
+
+	// 普通消息与配置消息均按通道权重调度
+	assert.Equal(t, cb.Status_SUCCESS, send().Status)
+	mm.MsgProcessorIsConfig = true
+	assert.Equal(t, cb.Status_SUCCESS, send().Status)
+	assert.Equal(t, []string{"mychannel", "mychannel"}, scheduler.channels)
+	assert.Equal(t, []uint32{3, 3}, scheduler.weights)
+
+	// 未被调度的消息因过载被拒绝
+	mm.MsgProcessorIsConfig = false
+	scheduler.err = fmt.Errorf("ingress queue of the channel is full")
+	reply := send()
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+	assert.Equal(t, "ingress queue of the channel is full", reply.Info)
+	assert.Equal(t, ab.ErrorDetail_OVERLOADED, reply.ErrorDetail.Code)
+
+	// 被调度但共识组件拒绝的消息仍按共识组件不可用拒绝
+	scheduler.err = nil
+	mm.MsgProcessorVal.rejectEnqueue = true
+	reply = send()
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+	assert.Equal(t, ab.ErrorDetail_CONSENTER_UNAVAILABLE, reply.ErrorDetail.Code)
+}
+
+func TestReadyTimeout(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.MsgProcessorVal = &mockSupport{notReady: true}
+	bh := NewHandlerImpl(mm, HandlerOptions{ReadyTimeout: 10 * time.Millisecond})
 	m := newMockB()
 	defer close(m.recvChan)
 	go bh.Handle(m)
 
-	m.recvChan <- nil
+	// 共识组件未在超时时间内就绪时拒绝消息并提示重试时间
+	m.recvChan <- &cb.Envelope{Payload: []byte("Some bytes")}
 	reply := <-m.sendChan
-	assert.Equal(t, cb.Status_BAD_REQUEST, reply.Status, "Should have rejected CONFIG_UPDATE")
-	assert.Equal(t, mm.MsgProcessorVal.ProcessErr.Error(), reply.Info, "Should have rejected CONFIG_UPDATE")
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+	assert.Equal(t, "consenter is not ready: reprocessing, 10 messages ahead, retry after 3s", reply.Info)
+	assert.Equal(t, ab.ErrorDetail_CONSENTER_UNAVAILABLE, reply.ErrorDetail.Code)
+	assert.Equal(t, int64(3), reply.ErrorDetail.RetryAfter.Seconds)
 }
 
-func TestBadStreamRecv(t *testing.T) {
-	bh := NewHandlerImpl(nil)
-	assert.Error(t, bh.Handle(&erroneousRecvMockB{}), "Should catch unexpected stream error")
+type mockAdmissionPlugin struct {
+	err      error
+	requests []*admissionplugin.Request
 }
 
-func TestBadStreamSend(t *testing.T) {
+func (m *mockAdmissionPlugin) Admit(req *admissionplugin.Request) error {
+	m.requests = append(m.requests, req)
+	return m.err
+}
+
+func TestAdmissionPlugin(t *testing.T) {
 	mm := getMockSupportManager()
-	bh := NewHandlerImpl(mm)
-	m := &erroneousSendMockB{recvVal: nil}
-	assert.Error(t, bh.Handle(m), "Should catch unexpected stream error")
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel", TxId: "tx1"}
+	plugin := &mockAdmissionPlugin{}
+	bh := NewHandlerImpl(mm, HandlerOptions{AdmissionPlugin: plugin})
+	m := newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(m)
+
+	env, _ := signedEnvelope(t, []byte("nonce"), []byte("creator"))
+	m.recvChan <- env
+	assert.Equal(t, cb.Status_SUCCESS, (<-m.sendChan).Status)
+	require.Len(t, plugin.requests, 1)
+	assert.Equal(t, "mychannel", plugin.requests[0].ChannelID)
+	assert.Equal(t, "tx1", plugin.requests[0].TxID)
+	assert.Equal(t, []byte("creator"), plugin.requests[0].Creator)
+	assert.Equal(t, env, plugin.requests[0].Envelope)
+
+	plugin.err = retryableError{errors.New("closed")}
+	m.recvChan <- env
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_FORBIDDEN, reply.Status)
+	assert.Equal(t, "closed", reply.Info)
+	assert.Equal(t, ab.ErrorDetail_ADMISSION_DENIED, reply.ErrorDetail.Code)
+	assert.Equal(t, int64(2), reply.ErrorDetail.RetryAfter.Seconds)
+
+	// 配置更新消息不经过准入插件，拒绝后消息流已结束，需重新建立
+	mm.MsgProcessorIsConfig = true
+	m = newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(m)
+	m.recvChan <- env
+	assert.Equal(t, cb.Status_SUCCESS, (<-m.sendChan).Status)
+	assert.Len(t, plugin.requests, 2)
 }
 
-func TestMalformedHeader(t *testing.T) {
+func signedEnvelope(t *testing.T, nonce, creator []byte) (*cb.Envelope, string) {
+	txid, err := utils.ComputeTxID(nonce, creator)
+	require.NoError(t, err)
+	payload := &cb.Payload{
+		Header: &cb.Header{
+			SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{Nonce: nonce, Creator: creator}),
+		},
+	}
+	return &cb.Envelope{Payload: utils.MarshalOrPanic(payload)}, txid
+}
+
+func TestDuplicates(t *testing.T) {
 	mm := getMockSupportManager()
-	mm.ChdrVal = nil
-	mm.MsgProcessorErr = errors.New("Mocked Error")
-	bh := NewHandlerImpl(mm)
+	duplicates, err := dedup.NewCache(10, 0)
+	require.NoError(t, err)
+	bh := NewHandlerImpl(mm, HandlerOptions{Duplicates: duplicates})
 	m := newMockB()
+	go bh.Handle(m)
+
+	env, txid := signedEnvelope(t, []byte("nonce"), []byte("creator"))
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel", TxId: txid}
+
+	m.recvChan <- env
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Empty(t, reply.Info)
+
+	// the retry is acknowledged without being ordered
+	mm.MsgProcessorVal.rejectEnqueue = true
+	m.recvChan <- env
+	reply = <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Equal(t, DuplicateInfo, reply.Info)
+
+	// a message which could not be enqueued can be submitted again
+	env, txid = signedEnvelope(t, []byte("othernonce"), []byte("creator"))
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel", TxId: txid}
+	m.recvChan <- env
+	reply = <-m.sendChan
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+
+	// the stream ends with the rejection, the client reconnects to retry
+	mm.MsgProcessorVal.rejectEnqueue = false
+	m = newMockB()
 	defer close(m.recvChan)
-	done := make(chan struct{})
+	go bh.Handle(m)
+	m.recvChan <- env
+	reply = <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Empty(t, reply.Info)
+
+	// transaction IDs not bound to the nonce and creator are not deduplicated
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel", TxId: "claimed"}
+	for i := 0; i < 2; i++ {
+		m.recvChan <- env
+		reply = <-m.sendChan
+		assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+		assert.Empty(t, reply.Info)
+	}
+}
+
+func TestNonces(t *testing.T) {
+	mm := getMockSupportManager()
+	duplicates, err := dedup.NewCache(10, 0)
+	require.NoError(t, err)
+	bh := NewHandlerImpl(mm, HandlerOptions{Duplicates: duplicates, Nonces: msgprocessor.NewNonceTracker(nil)})
+	m := newMockB()
+	go bh.Handle(m)
+
+	counterNonce := func(counter uint64) []byte {
+		nonce := make([]byte, 24)
+		binary.BigEndian.PutUint64(nonce, counter)
+		return nonce
+	}
+	send := func(env *cb.Envelope, txid string) *ab.BroadcastResponse {
+		mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel", TxId: txid}
+		m.recvChan <- env
+		return <-m.sendChan
+	}
+
+	first, firstTxID := signedEnvelope(t, counterNonce(2), []byte("creator"))
+	assert.Equal(t, cb.Status_SUCCESS, send(first, firstTxID).Status)
+
+	// the retry remembered by the duplicate cache is still acknowledged
+	reply := send(first, firstTxID)
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Equal(t, DuplicateInfo, reply.Info)
+
+	// a message with an older counter is rejected, and the stream ends
+	stale, staleTxID := signedEnvelope(t, counterNonce(1), []byte("creator"))
+	reply = send(stale, staleTxID)
+	assert.Equal(t, cb.Status_BAD_REQUEST, reply.Status)
+	assert.Contains(t, reply.Info, "stale nonce")
+
+	// the counter of a message which could not be enqueued is withdrawn
+	m = newMockB()
+	go bh.Handle(m)
+	next, nextTxID := signedEnvelope(t, counterNonce(3), []byte("creator"))
+	mm.MsgProcessorVal.rejectEnqueue = true
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, send(next, nextTxID).Status)
+	mm.MsgProcessorVal.rejectEnqueue = false
+	m = newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(m)
+	assert.Equal(t, cb.Status_SUCCESS, send(next, nextTxID).Status)
+}
+
+func TestStreamQuota(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel"}
+	quota, err := streamquota.New(streamquota.Config{MaxBroadcastStreams: 1}, prometheus.NewRegistry())
+	require.NoError(t, err)
+	bh := NewHandlerImpl(mm, HandlerOptions{StreamQuota: quota})
+
+	handle := func() (*mockB, <-chan struct{}) {
+		m := newMockB()
+		done := make(chan struct{})
+		go func() {
+			bh.Handle(m)
+			close(done)
+		}()
+		return m, done
+	}
+	send := func(m *mockB, creator string) *ab.BroadcastResponse {
+		env, _ := signedEnvelope(t, []byte("nonce"), []byte(creator))
+		m.recvChan <- env
+		return <-m.sendChan
+	}
+
+	first, firstDone := handle()
+	assert.Equal(t, cb.Status_SUCCESS, send(first, "creator").Status)
+	assert.Equal(t, cb.Status_SUCCESS, send(first, "creator").Status)
+
+	// a second stream of the identity is rejected, and ends
+	second, _ := handle()
+	reply := send(second, "creator")
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+	assert.Equal(t, ab.ErrorDetail_RATE_LIMITED, reply.ErrorDetail.Code)
+	assert.Contains(t, reply.Info, "too many streams")
+
+	// the other identities have their own quota
+	other, _ := handle()
+	defer close(other.recvChan)
+	assert.Equal(t, cb.Status_SUCCESS, send(other, "other").Status)
+
+	// the quota is released once the stream ends
+	close(first.recvChan)
+	<-firstDone
+	third, _ := handle()
+	defer close(third.recvChan)
+	assert.Equal(t, cb.Status_SUCCESS, send(third, "creator").Status)
+}
+
+type mockBatchB struct {
+	mockStream
+	recvChan chan *ab.BroadcastBatch
+	sendChan chan *ab.BroadcastBatchResponse
+}
+
+func newMockBatchB() *mockBatchB {
+	return &mockBatchB{
+		recvChan: make(chan *ab.BroadcastBatch),
+		sendChan: make(chan *ab.BroadcastBatchResponse),
+	}
+}
+
+func (m *mockBatchB) Send(br *ab.BroadcastBatchResponse) error {
+	m.sendChan <- br
+	return nil
+}
+
+func (m *mockBatchB) Recv() (*ab.BroadcastBatch, error) {
+	msg, ok := <-m.recvChan
+	if !ok {
+		return msg, io.EOF
+	}
+	return msg, nil
+}
+
+func TestHandleBatch(t *testing.T) {
+	mm := getMockSupportManager()
+	limiter := &mockLimiter{}
+	bh := NewHandlerImpl(mm, HandlerOptions{Limiter: limiter})
+	m := newMockBatchB()
+	done := make(chan error)
 	go func() {
-		bh.Handle(m)
-		close(done)
+		done <- bh.HandleBatch(m)
 	}()
 
-	m.recvChan <- nil
+	m.recvChan <- &ab.BroadcastBatch{Envelopes: []*cb.Envelope{{}, {}, {}}}
 	reply := <-m.sendChan
-	if reply.Status != cb.Status_BAD_REQUEST {
-		t.Fatalf("Should have rejected message for malformed header")
+	require.Len(t, reply.Responses, 3)
+	for _, resp := range reply.Responses {
+		assert.Equal(t, cb.Status_SUCCESS, resp.Status)
+	}
+	assert.Len(t, limiter.channels, 3, "Each envelope of the batch should have been processed")
+
+	m.recvChan <- &ab.BroadcastBatch{}
+	reply = <-m.sendChan
+	assert.Empty(t, reply.Responses)
+
+	// rejected envelopes do not end the stream
+	limiter.allowErr = fmt.Errorf("too fast")
+	m.recvChan <- &ab.BroadcastBatch{Envelopes: []*cb.Envelope{{}, {}}}
+	reply = <-m.sendChan
+	require.Len(t, reply.Responses, 2)
+	for _, resp := range reply.Responses {
+		assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, resp.Status)
+		assert.Equal(t, "too fast", resp.Info)
 	}
+	limiter.allowErr = nil
+
+	mm.MsgProcessorVal.ProcessErr = msgprocessor.ErrChannelDoesNotExist
+	m.recvChan <- &ab.BroadcastBatch{Envelopes: []*cb.Envelope{{}}}
+	reply = <-m.sendChan
+	require.Len(t, reply.Responses, 1)
+	assert.Equal(t, cb.Status_NOT_FOUND, reply.Responses[0].Status)
 
+	close(m.recvChan)
 	select {
-	case <-done:
+	case err := <-done:
+		assert.NoError(t, err, "Should exit normally upon EOF")
 	case <-time.After(time.Second):
 		t.Fatalf("Should have terminated the stream")
 	}
 }
+
+// concurrentSupport validates its messages once as many are validated at
+// once as expected, and records the order they are enqueued in
+type concurrentSupport struct {
+	mockSupport
+	expected int
+
+	mutex      sync.Mutex
+	validating int
+	validated  chan struct{}
+	ordered    []string
+}
+
+func (cs *concurrentSupport) ProcessNormalMsg(msg *cb.Envelope) (uint64, error) {
+	cs.mutex.Lock()
+	cs.validating++
+	if cs.validating == cs.expected {
+		close(cs.validated)
+	}
+	cs.mutex.Unlock()
+	select {
+	case <-cs.validated:
+		return 7, nil
+	case <-time.After(time.Second):
+		return 0, fmt.Errorf("validated alone")
+	}
+}
+
+func (cs *concurrentSupport) Order(env *cb.Envelope, configSeq uint64) error {
+	cs.mutex.Lock()
+	defer cs.mutex.Unlock()
+	cs.ordered = append(cs.ordered, fmt.Sprintf("%s@%d", env.Payload, configSeq))
+	return nil
+}
+
+type concurrentSupportManager struct {
+	support *concurrentSupport
+}
+
+func (sm *concurrentSupportManager) BroadcastChannelSupport(msg *cb.Envelope) (*cb.ChannelHeader, bool, ChannelSupport, error) {
+	return &cb.ChannelHeader{}, false, sm.support, nil
+}
+
+func TestHandleBatchVerifierPool(t *testing.T) {
+	support := &concurrentSupport{expected: 3, validated: make(chan struct{})}
+	verifier := msgprocessor.NewVerifierPool(3)
+	defer verifier.Stop()
+	bh := NewHandlerImpl(&concurrentSupportManager{support: support}, HandlerOptions{Verifier: verifier})
+	m := newMockBatchB()
+	defer close(m.recvChan)
+	go bh.HandleBatch(m)
+
+	m.recvChan <- &ab.BroadcastBatch{Envelopes: []*cb.Envelope{{Payload: []byte("a")}, {Payload: []byte("b")}, {Payload: []byte("c")}}}
+	reply := <-m.sendChan
+	require.Len(t, reply.Responses, 3)
+	for _, resp := range reply.Responses {
+		assert.Equal(t, cb.Status_SUCCESS, resp.Status, resp.Info)
+	}
+	assert.Equal(t, []string{"a@7", "b@7", "c@7"}, support.ordered, "Should have enqueued the messages validated concurrently in order, with their config sequence")
+}
+
+// metricValue returns the value of the counter, or the sample count of the
+// histogram, of the family with the labels
+func metricValue(t *testing.T, registry *prometheus.Registry, name string, labels map[string]string) float64 {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range family.Metric {
+			for _, lp := range m.Label {
+				if labels[lp.GetName()] != lp.GetValue() {
+					continue metrics
+				}
+			}
+			if m.Histogram != nil {
+				return float64(m.Histogram.GetSampleCount())
+			}
+			return m.Counter.GetValue()
+		}
+	}
+	return 0
+}
+
+func TestMetrics(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	metrics, err := NewMetrics(registry)
+	require.NoError(t, err)
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "foo"}
+	bh := NewHandlerImpl(mm, HandlerOptions{Metrics: metrics})
+	m := newMockBatchB()
+	defer close(m.recvChan)
+	go bh.HandleBatch(m)
+
+	env := &cb.Envelope{Payload: []byte("payload")}
+	m.recvChan <- &ab.BroadcastBatch{Envelopes: []*cb.Envelope{env, env}}
+	<-m.sendChan
+
+	mm.MsgProcessorVal.ProcessErr = msgprocessor.ErrPermissionDenied
+	m.recvChan <- &ab.BroadcastBatch{Envelopes: []*cb.Envelope{env}}
+	<-m.sendChan
+	mm.MsgProcessorVal.ProcessErr = nil
+	mm.MsgProcessorVal.rejectEnqueue = true
+	m.recvChan <- &ab.BroadcastBatch{Envelopes: []*cb.Envelope{env}}
+	<-m.sendChan
+
+	assert.Equal(t, float64(4), metricValue(t, registry, "orderer_broadcast_messages_received_total", nil))
+	assert.Equal(t, float64(1), metricValue(t, registry, "orderer_broadcast_messages_rejected_total", map[string]string{"status": "FORBIDDEN"}))
+	assert.Equal(t, float64(1), metricValue(t, registry, "orderer_broadcast_messages_rejected_total", map[string]string{"status": "SERVICE_UNAVAILABLE"}))
+	assert.Equal(t, float64(2), metricValue(t, registry, "orderer_broadcast_messages_enqueued_total", map[string]string{"channel": "foo", "type": "normal"}))
+	assert.Equal(t, float64(2*proto.Size(env)), metricValue(t, registry, "orderer_broadcast_enqueued_bytes_total", map[string]string{"channel": "foo"}))
+	assert.Equal(t, float64(2), metricValue(t, registry, "orderer_broadcast_enqueue_duration_seconds", map[string]string{"channel": "foo"}))
+	assert.Equal(t, float64(2), metricValue(t, registry, "orderer_broadcast_wait_ready_duration_seconds", map[string]string{"channel": "foo"}))
+
+	_, err = NewMetrics(registry)
+	assert.Error(t, err, "Registering the metrics twice should fail")
+}
+
+type panickingSupportManager struct{}
+
+func (panickingSupportManager) BroadcastChannelSupport(msg *cb.Envelope) (*cb.ChannelHeader, bool, ChannelSupport, error) {
+	panic("bug")
+}
+
+func TestPanicReported(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	crashes, err := crash.NewReporter(registry)
+	require.NoError(t, err)
+	bh := NewHandlerImpl(panickingSupportManager{}, HandlerOptions{Crashes: crashes})
+	m := newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(m)
+
+	m.recvChan <- &cb.Envelope{Payload: []byte("payload")}
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_INTERNAL_SERVER_ERROR, reply.Status)
+	assert.Contains(t, reply.Info, "crash report")
+	assert.Equal(t, float64(1), metricValue(t, registry, "orderer_crash_panics_total", map[string]string{"service": "Broadcast"}))
+}
+
+func TestMaxMessageSize(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.MsgProcessorVal.ProcessErr = fmt.Errorf("processed")
+	limits := &SizeLimits{MaxMessageSize: 20, Channels: map[string]uint32{"big": 200, "unlimited": 0}}
+	bh := NewHandlerImpl(mm, HandlerOptions{SizeLimits: limits})
+	m := newMockBatchB()
+	defer close(m.recvChan)
+	go bh.HandleBatch(m)
+
+	small := &cb.Envelope{Payload: []byte("small")}
+	large := &cb.Envelope{Payload: make([]byte, 100)}
+	for _, tc := range []struct {
+		channelID string
+		msg       *cb.Envelope
+		rejected  bool
+	}{
+		{"foo", small, false},
+		{"foo", large, true},
+		{"big", large, false},
+		{"big", &cb.Envelope{Payload: make([]byte, 300)}, true},
+		{"unlimited", &cb.Envelope{Payload: make([]byte, 300)}, false},
+	} {
+		mm.ChdrVal = &cb.ChannelHeader{ChannelId: tc.channelID}
+		m.recvChan <- &ab.BroadcastBatch{Envelopes: []*cb.Envelope{tc.msg}}
+		resp := (<-m.sendChan).Responses[0]
+		assert.Equal(t, cb.Status_BAD_REQUEST, resp.Status)
+		if tc.rejected {
+			// 超过大小限制的消息不经过验证即被拒绝
+			assert.Contains(t, resp.Info, "exceeds the maximum message size")
+			assert.Equal(t, ab.ErrorDetail_MESSAGE_TOO_LARGE, resp.ErrorDetail.Code)
+		} else {
+			assert.Equal(t, "processed", resp.Info)
+		}
+	}
+
+	var noLimits *SizeLimits
+	assert.NoError(t, noLimits.check("foo", large))
+}
+
+type mockAuditSink struct {
+	mutex   sync.Mutex
+	records []*audit.Record
+}
+
+func (m *mockAuditSink) Audit(rec *audit.Record) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.records = append(m.records, rec)
+}
+
+// tlsBatchB is a batch broadcast stream whose client presented a TLS certificate
+type tlsBatchB struct {
+	*mockBatchB
+	cert *x509.Certificate
+}
+
+func (m *tlsBatchB) Context() context.Context {
+	authInfo := credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{m.cert}}}
+	return peer.NewContext(context.Background(), &peer.Peer{AuthInfo: authInfo})
+}
+
+func TestAudit(t *testing.T) {
+	sink := &mockAuditSink{}
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "foo", TxId: "tx1"}
+	bh := NewHandlerImpl(mm, HandlerOptions{AuditSink: sink})
+	m := &tlsBatchB{mockBatchB: newMockBatchB(), cert: &x509.Certificate{Subject: pkix.Name{CommonName: "client", Organization: []string{"org1"}}}}
+	defer close(m.recvChan)
+	go bh.HandleBatch(m)
+
+	before := time.Now()
+	m.recvChan <- &ab.BroadcastBatch{Envelopes: []*cb.Envelope{{}}}
+	<-m.sendChan
+	mm.MsgProcessorVal.ProcessErr = msgprocessor.ErrPermissionDenied
+	m.recvChan <- &ab.BroadcastBatch{Envelopes: []*cb.Envelope{{}}}
+	<-m.sendChan
+	mm.ChdrVal, mm.MsgProcessorErr = nil, fmt.Errorf("malformed")
+	m.recvChan <- &ab.BroadcastBatch{Envelopes: []*cb.Envelope{{}}}
+	<-m.sendChan
+
+	sink.mutex.Lock()
+	defer sink.mutex.Unlock()
+	require.Len(t, sink.records, 3)
+	rec := sink.records[0]
+	assert.Equal(t, "tx1", rec.TxID)
+	assert.Equal(t, "foo", rec.ChannelID)
+	assert.Equal(t, "CN=client,O=org1", rec.ClientSubject)
+	assert.Equal(t, "SUCCESS", rec.Status)
+	assert.False(t, rec.Received.Before(before))
+	assert.False(t, rec.Responded.Before(rec.Received))
+	assert.Equal(t, "FORBIDDEN", sink.records[1].Status)
+	// 无法解析通道头部的消息也记入审计日志
+	assert.Equal(t, "BAD_REQUEST", sink.records[2].Status)
+	assert.Empty(t, sink.records[2].ChannelID)
+	assert.Equal(t, "CN=client,O=org1", sink.records[2].ClientSubject)
+}
+
+// slowSupport holds the messages whose payload is "slow" in Order until
+// released, and rejects the messages whose payload is "rejected"
+type slowSupport struct {
+	mockSupport
+	release chan struct{}
+}
+
+func (ss *slowSupport) Order(env *cb.Envelope, configSeq uint64) error {
+	if env != nil && string(env.Payload) == "slow" {
+		<-ss.release
+	}
+	return nil
+}
+
+func (ss *slowSupport) ProcessNormalMsg(msg *cb.Envelope) (uint64, error) {
+	if msg != nil && string(msg.Payload) == "rejected" {
+		return 0, fmt.Errorf("Reject")
+	}
+	return 0, nil
+}
+
+type slowSupportManager struct {
+	support *slowSupport
+}
+
+func (sm *slowSupportManager) BroadcastChannelSupport(msg *cb.Envelope) (*cb.ChannelHeader, bool, ChannelSupport, error) {
+	return &cb.ChannelHeader{}, false, sm.support, nil
+}
+
+func TestPipelined(t *testing.T) {
+	support := &slowSupport{release: make(chan struct{})}
+	bh := NewHandlerImpl(&slowSupportManager{support: support}, HandlerOptions{Window: 2})
+	m := newMockB()
+	done := make(chan struct{})
+	go func() {
+		bh.Handle(m)
+		close(done)
+	}()
+
+	m.recvChan <- &cb.Envelope{Payload: []byte("slow")}
+	m.recvChan <- &cb.Envelope{Payload: []byte("fast")}
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Equal(t, uint64(2), reply.CorrelationId, "Should have answered the second message while the first is in flight")
+
+	close(support.release)
+	reply = <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Equal(t, uint64(1), reply.CorrelationId)
+
+	close(m.recvChan)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Should have terminated the stream")
+	}
+}
+
+func TestInFlightWindow(t *testing.T) {
+	support := &slowSupport{release: make(chan struct{})}
+	bh := NewHandlerImpl(&slowSupportManager{support: support}, HandlerOptions{})
+	m := newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(m)
+
+	m.recvChan <- &cb.Envelope{Payload: []byte("slow")}
+	// 下一个消息已被接收，但在途窗口已满
+	m.recvChan <- &cb.Envelope{Payload: []byte("fast")}
+	select {
+	case reply := <-m.sendChan:
+		t.Fatalf("Should not have processed a message beyond the window, got response %d", reply.CorrelationId)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(support.release)
+	for i := uint64(1); i <= 2; i++ {
+		reply := <-m.sendChan
+		assert.Equal(t, i, reply.CorrelationId, "Should have answered in order with a window of 1")
+	}
+}
+
+func TestRejectedWithInFlight(t *testing.T) {
+	support := &slowSupport{release: make(chan struct{})}
+	bh := NewHandlerImpl(&slowSupportManager{support: support}, HandlerOptions{Window: 2})
+	m := newMockB()
+	defer close(m.recvChan)
+	done := make(chan struct{})
+	go func() {
+		bh.Handle(m)
+		close(done)
+	}()
+
+	m.recvChan <- &cb.Envelope{Payload: []byte("slow")}
+	m.recvChan <- &cb.Envelope{Payload: []byte("rejected")}
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_BAD_REQUEST, reply.Status)
+	assert.Equal(t, uint64(2), reply.CorrelationId)
+
+	select {
+	case <-done:
+		t.Fatalf("Should have waited for the message in flight")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(support.release)
+	reply = <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Equal(t, uint64(1), reply.CorrelationId)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Should have terminated the stream")
+	}
+}
+
+func TestStreamLimits(t *testing.T) {
+	mm := getMockSupportManager()
+	bh := NewHandlerImpl(mm, HandlerOptions{StreamLimits: NewStreamLimits(1, 0)})
+	first := newMockB()
+	done := make(chan error)
+	go func() {
+		done <- bh.Handle(first)
+	}()
+	first.recvChan <- nil
+	assert.Equal(t, cb.Status_SUCCESS, (<-first.sendChan).Status)
+
+	// 同一客户端的第二个消息流被拒绝
+	err := bh.Handle(newMockB())
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// 第一个消息流结束后可以再打开消息流
+	close(first.recvChan)
+	assert.NoError(t, <-done)
+	second := newMockB()
+	close(second.recvChan)
+	assert.NoError(t, bh.Handle(second))
+}
+
+func TestClientID(t *testing.T) {
+	anonymizer, err := privacy.New(privacy.Config{Mode: privacy.ModeTruncate, Key: []byte("0123456789abcdef")})
+	require.NoError(t, err)
+	anonymized := func(ip string) context.Context {
+		ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 7050}})
+		return anonymizer.Context(ctx)
+	}
+
+	// 共享匿名地址的客户端按真实地址区分，日志中只出现匿名地址
+	first, second := anonymized("10.1.2.3"), anonymized("10.1.2.4")
+	assert.Equal(t, "10.1.2.3", clientID(first))
+	assert.Equal(t, "10.1.2.4", clientID(second))
+	assert.Equal(t, "10.1.2.0/24", anonymizedClientID(first))
+	assert.Equal(t, "10.1.2.0/24", anonymizedClientID(second))
+}
+
+func TestIdleTimeout(t *testing.T) {
+	mm := getMockSupportManager()
+	bh := NewHandlerImpl(mm, HandlerOptions{StreamLimits: NewStreamLimits(0, 50*time.Millisecond)})
+	m := newMockB()
+	defer close(m.recvChan)
+	done := make(chan error)
+	go func() {
+		done <- bh.Handle(m)
+	}()
+	m.recvChan <- nil
+	assert.Equal(t, cb.Status_SUCCESS, (<-m.sendChan).Status)
+
+	select {
+	case err := <-done:
+		assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	case <-time.After(time.Second):
+		t.Fatalf("Should have closed the idle stream")
+	}
+}
+
+func TestGracefulShutdown(t *testing.T) {
+	bh := NewHandlerImpl(nil, HandlerOptions{})
+	m := newMockB()
+	close(m.recvChan)
+	assert.NoError(t, bh.Handle(m), "Should exit normally upon EOF")
+}
+
+func TestRejected(t *testing.T) {
+	mm := &mockSupportManager{
+		MsgProcessorVal: &mockSupport{ProcessErr: fmt.Errorf("Reject")},
+		ChdrVal:         &cb.ChannelHeader{},
+	}
+	bh := NewHandlerImpl(mm, HandlerOptions{})
+	m := newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(m)
+
+	m.recvChan <- nil
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_BAD_REQUEST, reply.Status, "Should have rejected CONFIG_UPDATE")
+	assert.Equal(t, mm.MsgProcessorVal.ProcessErr.Error(), reply.Info, "Should have rejected CONFIG_UPDATE")
+}
+
+func TestBadStreamRecv(t *testing.T) {
+	bh := NewHandlerImpl(nil, HandlerOptions{})
+	assert.Error(t, bh.Handle(&erroneousRecvMockB{}), "Should catch unexpected stream error")
+}
+
+func TestBadStreamSend(t *testing.T) {
+	mm := getMockSupportManager()
+	bh := NewHandlerImpl(mm, HandlerOptions{})
+	m := &erroneousSendMockB{recvVal: nil}
+	assert.Error(t, bh.Handle(m), "Should catch unexpected stream error")
+}
+
+func TestMalformedHeader(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = nil
+	mm.MsgProcessorErr = errors.New("Mocked Error")
+	malformed := &mockMalformedRecorder{}
+	bh := NewHandlerImpl(mm, HandlerOptions{Malformed: malformed})
+	m := newMockB()
+	defer close(m.recvChan)
+	done := make(chan struct{})
+	go func() {
+		bh.Handle(m)
+		close(done)
+	}()
+
+	m.recvChan <- nil
+	reply := <-m.sendChan
+	if reply.Status != cb.Status_BAD_REQUEST {
+		t.Fatalf("Should have rejected message for malformed header")
+	}
+	assert.Equal(t, &ab.ErrorDetail{Code: ab.ErrorDetail_MALFORMED_MESSAGE, FieldPath: "payload.header"}, reply.ErrorDetail)
+	assert.Equal(t, []string{"broadcast"}, malformed.kinds, "Should have recorded the malformed message")
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Should have terminated the stream")
+	}
+}
+
+// tracedMockB is a broadcast stream whose client propagates its trace
+type tracedMockB struct {
+	*mockB
+}
+
+func (m tracedMockB) Context() context.Context {
+	md := metadata.Pairs(tracing.TraceparentKey, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	return metadata.NewIncomingContext(m.mockB.Context(), md)
+}
+
+func TestTracing(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel", TxId: "tx1"}
+	// 只追踪客户端传播并采样的追踪
+	tracer, err := tracing.New(tracing.Config{SampleRatio: 0})
+	require.NoError(t, err)
+	bh := NewHandlerImpl(mm, HandlerOptions{Tracer: tracer})
+
+	m := newMockB()
+	go bh.Handle(m)
+	m.recvChan <- nil
+	assert.Equal(t, cb.Status_SUCCESS, (<-m.sendChan).Status)
+	close(m.recvChan)
+	assert.Empty(t, tracer.Spans(""))
+
+	m = newMockB()
+	go bh.Handle(tracedMockB{m})
+	m.recvChan <- nil
+	assert.Equal(t, cb.Status_SUCCESS, (<-m.sendChan).Status)
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel", TxId: "tx2"}
+	mm.MsgProcessorVal.rejectEnqueue = true
+	m.recvChan <- nil
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, (<-m.sendChan).Status)
+	close(m.recvChan)
+
+	// 各处理阶段的span是消息span的子span
+	spans := tracer.TransactionSpans("mychannel", "tx1")
+	require.Len(t, spans, 4)
+	names := map[string]tracing.SpanData{}
+	for _, span := range spans {
+		names[span.Name] = span
+	}
+	broadcast := names[tracing.SpanBroadcast]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", broadcast.TraceID)
+	assert.Equal(t, "SUCCESS", broadcast.Attributes["status"])
+	for _, name := range []string{tracing.SpanWaitReady, tracing.SpanProcess, tracing.SpanOrder} {
+		require.Contains(t, names, name)
+		assert.Equal(t, broadcast.SpanID, names[name].ParentID, name)
+	}
+
+	// 提交的交易在切块时结束共识阶段，被拒绝的交易不再追踪
+	tracer.BlockCut("mychannel", 1, []string{"tx1", "tx2"})
+	spans = tracer.TransactionSpans("mychannel", "tx1")
+	require.Len(t, spans, 5)
+	assert.Equal(t, tracing.SpanConsensus, spans[4].Name)
+
+	spans = tracer.TransactionSpans("mychannel", "tx2")
+	require.Len(t, spans, 4)
+	for _, span := range spans {
+		switch span.Name {
+		case tracing.SpanBroadcast:
+			assert.Equal(t, "SERVICE_UNAVAILABLE", span.Attributes["status"])
+			assert.NotEmpty(t, span.Error)
+		case tracing.SpanOrder:
+			assert.Equal(t, "Reject", span.Error)
+		}
+	}
+}
+
+// waitCommitMockB is a broadcast stream whose client waits for the commit
+// of its messages
+type waitCommitMockB struct {
+	*mockB
+}
+
+func (m waitCommitMockB) Context() context.Context {
+	return metadata.NewIncomingContext(m.mockB.Context(), metadata.Pairs(WaitForCommitKey, "true"))
+}
+
+type mockCommitNotifier struct {
+	mutex      sync.Mutex
+	registered []string
+	released   int
+	committed  chan uint64
+	err        error
+}
+
+func (mcn *mockCommitNotifier) Register(channelID, txID string) (<-chan uint64, func(), error) {
+	mcn.mutex.Lock()
+	defer mcn.mutex.Unlock()
+	if mcn.err != nil {
+		return nil, nil, mcn.err
+	}
+	mcn.registered = append(mcn.registered, channelID+"/"+txID)
+	return mcn.committed, func() {
+		mcn.mutex.Lock()
+		defer mcn.mutex.Unlock()
+		mcn.released++
+	}, nil
+}
+
+func TestWaitForCommit(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel", TxId: "tx1"}
+	notifier := &mockCommitNotifier{committed: make(chan uint64, 1)}
+	bh := NewHandlerImpl(mm, HandlerOptions{Commits: notifier, CommitTimeout: 20 * time.Millisecond})
+
+	// 未要求等待提交的消息入队后即回复
+	m := newMockB()
+	go bh.Handle(m)
+	m.recvChan <- nil
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Empty(t, reply.Info)
+	assert.Equal(t, ab.BroadcastResponse_COMMIT_UNKNOWN, reply.CommitStatus)
+	assert.Empty(t, notifier.registered)
+	close(m.recvChan)
+
+	// 提交的交易回复其所在区块
+	m = newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(waitCommitMockB{m})
+	notifier.committed <- 3
+	m.recvChan <- nil
+	reply = <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Equal(t, "committed in block 3", reply.Info)
+	assert.Equal(t, ab.BroadcastResponse_COMMITTED, reply.CommitStatus)
+	assert.Equal(t, uint64(3), reply.BlockNumber)
+	assert.Equal(t, []string{"mychannel/tx1"}, notifier.registered)
+
+	// 超时未提交的交易仍回复成功
+	m.recvChan <- nil
+	reply = <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Equal(t, "enqueued but not committed within 20ms", reply.Info)
+	assert.Equal(t, ab.BroadcastResponse_NOT_COMMITTED, reply.CommitStatus)
+	assert.Zero(t, reply.BlockNumber)
+
+	// 等待的是新构造的配置交易
+	mm.MsgProcessorIsConfig = true
+	mm.MsgProcessorVal.ProcessConfigEnv = &cb.Envelope{Payload: utils.MarshalOrPanic(&cb.Payload{
+		Header: &cb.Header{ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{ChannelId: "mychannel", TxId: "config1"})},
+	})}
+	notifier.committed <- 4
+	m.recvChan <- nil
+	reply = <-m.sendChan
+	assert.Equal(t, "committed in block 4", reply.Info)
+	assert.Equal(t, "mychannel/config1", notifier.registered[2])
+
+	// 共识组件拒绝的交易不再等待
+	mm.MsgProcessorVal.rejectEnqueue = true
+	m.recvChan <- nil
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, (<-m.sendChan).Status)
+	notifier.mutex.Lock()
+	assert.Equal(t, 4, notifier.released)
+	notifier.mutex.Unlock()
+}
+
+func TestWaitForCommitUnavailable(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel", TxId: "tx1"}
+	notifier := &mockCommitNotifier{err: errors.New("too many transactions awaiting their commit")}
+	bh := NewHandlerImpl(mm, HandlerOptions{Commits: notifier})
+
+	// 无法等待提交的交易不被排序
+	m := newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(waitCommitMockB{m})
+	m.recvChan <- nil
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+	assert.Equal(t, "too many transactions awaiting their commit", reply.Info)
+
+	assert.False(t, WaitsForCommit(context.Background()))
+	assert.False(t, WaitsForCommit(metadata.NewIncomingContext(context.Background(), metadata.Pairs(WaitForCommitKey, "yes"))))
+}
+
+// heartbeatMockB is a broadcast stream whose client asks for heartbeats
+type heartbeatMockB struct {
+	*mockB
+	interval string
+}
+
+func (m heartbeatMockB) Context() context.Context {
+	return metadata.NewIncomingContext(m.mockB.Context(), metadata.Pairs(util.HeartbeatIntervalKey, m.interval))
+}
+
+type mockChannelHeights map[string]uint64
+
+func (mch mockChannelHeights) Height(channelID string) (uint64, bool) {
+	height, ok := mch[channelID]
+	return height, ok
+}
+
+func TestHeartbeats(t *testing.T) {
+	mm := getMockSupportManager()
+	heartbeats := NewHeartbeats(10*time.Millisecond, mockChannelHeights{"mychannel": 42})
+	bh := NewHandlerImpl(mm, HandlerOptions{Heartbeats: heartbeats})
+	m := heartbeatMockB{mockB: newMockB(), interval: "1ms"}
+	defer close(m.recvChan)
+	go bh.Handle(m)
+
+	// before the first message, the heartbeats report no channel
+	start := time.Now()
+	reply := <-m.sendChan
+	require.NotNil(t, reply.Heartbeat)
+	assert.Empty(t, reply.Heartbeat.ChannelId)
+	assert.Zero(t, reply.Heartbeat.Height)
+	assert.NotNil(t, reply.Heartbeat.Timestamp)
+	assert.True(t, time.Since(start) >= 10*time.Millisecond, "the interval should be raised to the minimum")
+
+	m.recvChan <- &cb.Envelope{Payload: utils.MarshalOrPanic(&cb.Payload{
+		Header: &cb.Header{ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{ChannelId: "mychannel"})},
+	})}
+	for {
+		reply = <-m.sendChan
+		if reply.Heartbeat == nil {
+			assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+			break
+		}
+	}
+	reply = <-m.sendChan
+	require.NotNil(t, reply.Heartbeat)
+	assert.Equal(t, "mychannel", reply.Heartbeat.ChannelId)
+	assert.Equal(t, uint64(42), reply.Heartbeat.Height)
+	assert.Equal(t, cb.Status_UNKNOWN, reply.Status)
+	assert.Zero(t, reply.CorrelationId)
+}
+
+func TestHeartbeatsNotAskedFor(t *testing.T) {
+	mm := getMockSupportManager()
+	heartbeats := NewHeartbeats(time.Millisecond, mockChannelHeights{})
+	bh := NewHandlerImpl(mm, HandlerOptions{Heartbeats: heartbeats})
+	m := newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(m)
+
+	select {
+	case reply := <-m.sendChan:
+		t.Fatalf("Should not have sent %v", reply)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// nor are they sent without Heartbeats
+	var nilHeartbeats *Heartbeats
+	assert.Zero(t, nilHeartbeats.interval(heartbeatMockB{mockB: m, interval: "1s"}.Context()))
+}
+
+// receiptsMockB is a broadcast stream whose client asks for receipts
+type receiptsMockB struct {
+	*mockB
+}
+
+func (m receiptsMockB) Context() context.Context {
+	return metadata.NewIncomingContext(m.mockB.Context(), metadata.Pairs(ReceiptsKey, "true"))
+}
+
+func TestReceipts(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel", TxId: "tx1"}
+	mm.MsgProcessorVal.ProcessConfigSeq = 7
+	bh := NewHandlerImpl(mm, HandlerOptions{Receipts: NewReceipts(mockcrypto.FakeLocalSigner)})
+	m := receiptsMockB{newMockB()}
+	defer close(m.recvChan)
+	go bh.Handle(m)
+
+	msg := &cb.Envelope{Payload: []byte("payload")}
+	m.recvChan <- msg
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	require.NotNil(t, reply.Receipt)
+	receipt := &ab.Receipt{}
+	require.NoError(t, proto.Unmarshal(reply.Receipt.Receipt, receipt))
+	assert.Equal(t, "mychannel", receipt.ChannelId)
+	assert.Equal(t, "tx1", receipt.TxId)
+	assert.Equal(t, uint64(7), receipt.ConfigSeq)
+	assert.NotNil(t, receipt.Timestamp)
+	assert.Equal(t, util.ComputeSHA256(msg.Payload), receipt.PayloadHash)
+	shdr := &cb.SignatureHeader{}
+	require.NoError(t, proto.Unmarshal(reply.Receipt.SignatureHeader, shdr))
+	assert.Equal(t, mockcrypto.FakeLocalSigner.Identity, shdr.Creator)
+	// the mock signer returns what it signs
+	assert.Equal(t, util.ConcatenateBytes(reply.Receipt.Receipt, reply.Receipt.SignatureHeader), reply.Receipt.Signature)
+
+	// rejected messages get no receipt
+	mm.MsgProcessorVal.ProcessErr = msgprocessor.ErrChannelDoesNotExist
+	m.recvChan <- msg
+	reply = <-m.sendChan
+	assert.NotEqual(t, cb.Status_SUCCESS, reply.Status)
+	assert.Nil(t, reply.Receipt)
+}
+
+func TestReceiptsNotAskedFor(t *testing.T) {
+	mm := getMockSupportManager()
+	bh := NewHandlerImpl(mm, HandlerOptions{Receipts: NewReceipts(mockcrypto.FakeLocalSigner)})
+	m := newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(m)
+
+	m.recvChan <- nil
+	reply := <-m.sendChan
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Nil(t, reply.Receipt)
+
+	// nor are they signed without Receipts
+	var nilReceipts *Receipts
+	assert.False(t, nilReceipts.wanted(receiptsMockB{m}.Context()))
+	assert.False(t, NewReceipts(nil).wanted(metadata.NewIncomingContext(context.Background(), metadata.Pairs(ReceiptsKey, "maybe"))))
+}
+
+type mockChannelQueues map[string]int64
+
+func (mcq mockChannelQueues) PendingBytes(channelID string) (int64, bool) {
+	pending, ok := mcq[channelID]
+	return pending, ok
+}
+
+func TestBackpressure(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel"}
+	queues := mockChannelQueues{"mychannel": 1000}
+	backpressure := NewBackpressure(1000, time.Second, queues)
+	bh := NewHandlerImpl(mm, HandlerOptions{Backpressure: backpressure})
+	m := newMockB()
+	go bh.Handle(m)
+
+	// at the high watermark, messages are still accepted
+	m.recvChan <- nil
+	assert.Equal(t, cb.Status_SUCCESS, (<-m.sendChan).Status)
+
+	// past it, they are rejected with a backoff growing with the queue
+	queues["mychannel"] = 3000
+	m.recvChan <- nil
+	reply := <-m.sendChan
+	close(m.recvChan)
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+	assert.Equal(t, "consenter has 3000 bytes pending, over the high watermark of 1000 bytes", reply.Info)
+	require.NotNil(t, reply.ErrorDetail)
+	assert.Equal(t, ab.ErrorDetail_OVERLOADED, reply.ErrorDetail.Code)
+	assert.Equal(t, int64(3), reply.ErrorDetail.RetryAfter.Seconds)
+
+	// config messages are never rejected
+	mm.MsgProcessorIsConfig = true
+	m = newMockB()
+	defer close(m.recvChan)
+	go bh.Handle(m)
+	m.recvChan <- nil
+	assert.Equal(t, cb.Status_SUCCESS, (<-m.sendChan).Status)
+
+	// nor are the messages of channels whose queue is not reported
+	var nilBackpressure *Backpressure
+	assert.NoError(t, nilBackpressure.check("mychannel"))
+	assert.NoError(t, backpressure.check("otherchannel"))
+	assert.NoError(t, NewBackpressure(0, time.Second, queues).check("mychannel"))
+}
+
+func TestMisbehavior(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel"}
+	mm.MsgProcessorVal.ProcessErr = msgprocessor.ErrPermissionDenied
+	detector := misbehavior.New(misbehavior.Config{
+		Weights:        map[misbehavior.Pattern]float64{misbehavior.InvalidSignature: 1},
+		BlockThreshold: 2,
+		BlockDuration:  time.Minute,
+	})
+	bh := NewHandlerImpl(mm, HandlerOptions{Misbehavior: detector})
+
+	// the stream is closed after each rejection
+	send := func() *ab.BroadcastResponse {
+		m := newMockB()
+		defer close(m.recvChan)
+		go bh.Handle(m)
+		m.recvChan <- nil
+		return <-m.sendChan
+	}
+
+	// messages failing the policy checks are rejected and scored
+	for i := 0; i < 2; i++ {
+		reply := send()
+		assert.Equal(t, cb.Status_FORBIDDEN, reply.Status)
+		assert.Equal(t, ab.ErrorDetail_PERMISSION_DENIED, reply.ErrorDetail.Code)
+	}
+	report := detector.Report()
+	require.Len(t, report.Subjects, 1)
+	assert.Equal(t, misbehavior.Client, report.Subjects[0].Kind)
+	assert.Equal(t, uint64(2), report.Subjects[0].Counts[misbehavior.InvalidSignature])
+	assert.Equal(t, "mychannel", report.Subjects[0].LastChannel)
+
+	// once blocked, the client is rejected before its messages are processed
+	mm.MsgProcessorVal.ProcessErr = nil
+	reply := send()
+	assert.Equal(t, cb.Status_FORBIDDEN, reply.Status)
+	require.NotNil(t, reply.ErrorDetail)
+	assert.Equal(t, ab.ErrorDetail_CLIENT_BLOCKED, reply.ErrorDetail.Code)
+	assert.InDelta(t, 60, reply.ErrorDetail.RetryAfter.Seconds, 1)
+
+	// until unblocked
+	require.True(t, detector.Unblock(report.Subjects[0].Key))
+	assert.Equal(t, cb.Status_SUCCESS, send().Status)
+}
+
+type mockIntakeJournal struct {
+	appendErr error
+	appended  []*cb.Envelope
+	discarded []*cb.Envelope
+}
+
+func (mij *mockIntakeJournal) Append(env *cb.Envelope) error {
+	if mij.appendErr != nil {
+		return mij.appendErr
+	}
+	mij.appended = append(mij.appended, env)
+	return nil
+}
+
+func (mij *mockIntakeJournal) Discard(env *cb.Envelope) {
+	mij.discarded = append(mij.discarded, env)
+}
+
+func TestIntakeJournal(t *testing.T) {
+	mm := getMockSupportManager()
+	journal := &mockIntakeJournal{}
+	bh := NewHandlerImpl(mm, HandlerOptions{Journal: journal})
+
+	send := func(msg *cb.Envelope) *ab.BroadcastResponse {
+		m := newMockB()
+		defer close(m.recvChan)
+		go bh.Handle(m)
+		m.recvChan <- msg
+		return <-m.sendChan
+	}
+
+	// messages are journaled before they are enqueued
+	tx := &cb.Envelope{Payload: []byte("tx")}
+	assert.Equal(t, cb.Status_SUCCESS, send(tx).Status)
+	assert.Equal(t, []*cb.Envelope{tx}, journal.appended)
+
+	// as are the config messages built from config updates, rather than the
+	// updates themselves
+	mm.MsgProcessorIsConfig = true
+	config := &cb.Envelope{Payload: []byte("config")}
+	mm.MsgProcessorVal.ProcessConfigEnv = config
+	assert.Equal(t, cb.Status_SUCCESS, send(&cb.Envelope{Payload: []byte("update")}).Status)
+	assert.Equal(t, []*cb.Envelope{tx, config}, journal.appended)
+	assert.Empty(t, journal.discarded)
+
+	// the messages the consenter rejects are forgotten
+	mm.MsgProcessorIsConfig = false
+	mm.MsgProcessorVal.rejectEnqueue = true
+	rejected := &cb.Envelope{Payload: []byte("rejected")}
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, send(rejected).Status)
+	assert.Equal(t, []*cb.Envelope{rejected}, journal.discarded)
+
+	// and the messages which cannot be journaled are not enqueued
+	mm.MsgProcessorVal.rejectEnqueue = false
+	journal.appendErr = errors.New("disk full")
+	reply := send(&cb.Envelope{Payload: []byte("unjournaled")})
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+	assert.Contains(t, reply.Info, "could not journal message: disk full")
+	assert.Len(t, journal.appended, 3)
+}
+
+type mockChannelLeaders map[string]string
+
+func (mcl mockChannelLeaders) Leader(channelID string) string {
+	return mcl[channelID]
+}
+
+// mockForwarder answers the messages forwarded with its responses in turn,
+// or fails if the response is nil
+type mockForwarder struct {
+	responses []*ab.BroadcastResponse
+	endpoints []string
+	metadata  metadata.MD
+}
+
+func (mf *mockForwarder) Forward(ctx context.Context, endpoint string, msg *cb.Envelope) (*ab.BroadcastResponse, error) {
+	mf.endpoints = append(mf.endpoints, endpoint)
+	mf.metadata, _ = metadata.FromOutgoingContext(ctx)
+	resp := mf.responses[0]
+	if len(mf.responses) > 1 {
+		mf.responses = mf.responses[1:]
+	}
+	if resp == nil {
+		return nil, errors.New("connection refused")
+	}
+	return resp, nil
+}
+
+// forwardedMockB is a broadcast stream of messages forwarded by another
+// orderer
+type forwardedMockB struct {
+	*mockB
+}
+
+func (m forwardedMockB) Context() context.Context {
+	return metadata.NewIncomingContext(m.mockB.Context(), metadata.Pairs(ForwardedKey, "true"))
+}
+
+func TestForwarding(t *testing.T) {
+	mm := getMockSupportManager()
+	mm.ChdrVal = &cb.ChannelHeader{ChannelId: "mychannel"}
+	// the consenter of a follower does not accept the messages
+	mm.MsgProcessorVal.rejectEnqueue = true
+	leaders := mockChannelLeaders{"mychannel": "leader:7050"}
+	forwarder := &mockForwarder{responses: []*ab.BroadcastResponse{{Status: cb.Status_SUCCESS, Info: "ordered by leader"}}}
+	bh := NewHandlerImpl(mm, HandlerOptions{Forwarding: NewForwarding(3, time.Millisecond, leaders, forwarder)})
+
+	send := func(m ab.AtomicBroadcast_BroadcastServer, recvChan chan *cb.Envelope, sendChan chan *ab.BroadcastResponse) *ab.BroadcastResponse {
+		defer close(recvChan)
+		go bh.Handle(m)
+		recvChan <- &cb.Envelope{Payload: []byte("tx")}
+		return <-sendChan
+	}
+
+	// the messages are forwarded to the leader, which answers the client
+	m := newMockB()
+	reply := send(waitCommitMockB{mockB: m}, m.recvChan, m.sendChan)
+	assert.Equal(t, cb.Status_SUCCESS, reply.Status)
+	assert.Equal(t, "ordered by leader", reply.Info)
+	assert.Equal(t, []string{"leader:7050"}, forwarder.endpoints)
+	assert.Equal(t, []string{"true"}, forwarder.metadata[ForwardedKey])
+	assert.Equal(t, []string{"true"}, forwarder.metadata[WaitForCommitKey])
+
+	// the leader is retried while it cannot be reached or is unavailable
+	forwarder.endpoints = nil
+	forwarder.responses = []*ab.BroadcastResponse{nil, {Status: cb.Status_SERVICE_UNAVAILABLE}, {Status: cb.Status_SUCCESS}}
+	m = newMockB()
+	assert.Equal(t, cb.Status_SUCCESS, send(m, m.recvChan, m.sendChan).Status)
+	assert.Len(t, forwarder.endpoints, 3)
+
+	// up to the retry budget
+	forwarder.endpoints = nil
+	forwarder.responses = []*ab.BroadcastResponse{nil}
+	m = newMockB()
+	reply = send(m, m.recvChan, m.sendChan)
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+	assert.Equal(t, "could not forward message to the leader of the channel: connection refused", reply.Info)
+	require.NotNil(t, reply.ErrorDetail)
+	assert.Equal(t, ab.ErrorDetail_CONSENTER_UNAVAILABLE, reply.ErrorDetail.Code)
+	assert.Len(t, forwarder.endpoints, 3)
+
+	// the messages forwarded by another orderer are not forwarded again
+	forwarder.endpoints = nil
+	m = newMockB()
+	reply = send(forwardedMockB{mockB: m}, m.recvChan, m.sendChan)
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, reply.Status)
+	assert.Contains(t, reply.Info, "Reject")
+	assert.Empty(t, forwarder.endpoints)
+
+	// nor are those of the channels this orderer leads
+	mm.MsgProcessorVal.rejectEnqueue = false
+	leaders["mychannel"] = ""
+	m = newMockB()
+	assert.Equal(t, cb.Status_SUCCESS, send(m, m.recvChan, m.sendChan).Status)
+	assert.Empty(t, forwarder.endpoints)
+}
@@ -0,0 +1,82 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broadcast
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+// WaitForCommitKey is the gRPC metadata key by which the client of a
+// broadcast stream asks for each message to be answered once it is
+// committed in a block, rather than once it is enqueued for ordering
+const WaitForCommitKey = "wait-for-commit"
+
+// CommittedInfo is the format of the info of the successful responses to
+// the messages committed in a block, whose CommitStatus is COMMITTED
+const CommittedInfo = "committed in block %d"
+
+// NotCommittedInfo is the format of the info of the successful responses to
+// the messages enqueued but not committed in a block before the commit
+// timeout, whose CommitStatus is NOT_COMMITTED
+const NotCommittedInfo = "enqueued but not committed within %s"
+
+// WaitsForCommit returns whether the client of the broadcast stream of the
+// context asks for its messages to be answered once they are committed
+func WaitsForCommit(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md[WaitForCommitKey]) == 0 {
+		return false
+	}
+	wait, err := strconv.ParseBool(md[WaitForCommitKey][0])
+	return err == nil && wait
+}
+
+// awaitCommit waits for the message awaited by the committed channel to be
+// committed, for at most the commit timeout, and returns the response to it.
+// The message is answered SUCCESS even if it is not committed in time, as
+// it is enqueued already and may still be committed, and the CommitStatus
+// of the response tells whether it was.
+func (bh *handlerImpl) awaitCommit(ctx context.Context, committed <-chan uint64, release func(), chdr *cb.ChannelHeader, addr string) *ab.BroadcastResponse {
+	defer release()
+	var timeout <-chan time.Time
+	if bh.commitTimeout > 0 {
+		timer := time.NewTimer(bh.commitTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case number := <-committed:
+		logger.Debugf("[channel: %s] Transaction %s from %s committed in block %d", chdr.ChannelId, chdr.TxId, addr, number)
+		return &ab.BroadcastResponse{
+			Status:       cb.Status_SUCCESS,
+			Info:         fmt.Sprintf(CommittedInfo, number),
+			CommitStatus: ab.BroadcastResponse_COMMITTED,
+			BlockNumber:  number,
+		}
+	case <-timeout:
+		logger.Warningf("[channel: %s] Transaction %s from %s not committed within %s", chdr.ChannelId, chdr.TxId, addr, bh.commitTimeout)
+		return &ab.BroadcastResponse{
+			Status:       cb.Status_SUCCESS,
+			Info:         fmt.Sprintf(NotCommittedInfo, bh.commitTimeout),
+			CommitStatus: ab.BroadcastResponse_NOT_COMMITTED,
+		}
+	case <-ctx.Done():
+		//消息流已结束，响应不会被发送
+		return &ab.BroadcastResponse{
+			Status:       cb.Status_SUCCESS,
+			Info:         "stream ended before the commit",
+			CommitStatus: ab.BroadcastResponse_NOT_COMMITTED,
+		}
+	}
+}
@@ -0,0 +1,133 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broadcast
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric/common/retry"
+	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// retryAfter is implemented by the errors of the rejections which tell when
+// the message may be submitted again
+type retryAfter interface {
+	RetryAfter() time.Duration
+}
+
+// ClassifyErrorDetail converts an error type into the machine-readable detail
+// of a rejection, as ClassifyError converts it into a status code.  The
+// detail carries the retry-after hint of the error, if any.  The errors which
+// have no specific reason, but a status the retry package classifies as
+// retryable, have an unspecified reason, so that clients retry them as the
+// status tells.
+func ClassifyErrorDetail(err error) *ab.ErrorDetail {
+	switch errors.Cause(err) {
+	case msgprocessor.ErrChannelDoesNotExist:
+		return errorDetail(ab.ErrorDetail_CHANNEL_NOT_FOUND, err)
+	case msgprocessor.ErrPermissionDenied:
+		return errorDetail(ab.ErrorDetail_PERMISSION_DENIED, err)
+	case msgprocessor.ErrIdentityExpired:
+		return errorDetail(ab.ErrorDetail_IDENTITY_EXPIRED, err)
+	case msgprocessor.ErrRateLimited:
+		return errorDetail(ab.ErrorDetail_RATE_LIMITED, err)
+	case msgprocessor.ErrQuotaExceeded:
+		return errorDetail(ab.ErrorDetail_QUOTA_EXCEEDED, err)
+	default:
+		if retry.Status(ClassifyError(err)).Retryable() {
+			return errorDetail(ab.ErrorDetail_UNSPECIFIED, err)
+		}
+		return errorDetail(ab.ErrorDetail_INVALID_MESSAGE, err)
+	}
+}
+
+// errorDetail returns the detail of a rejection with the code, with the
+// retry-after hint found along the causes of the error
+func errorDetail(code ab.ErrorDetail_Code, err error) *ab.ErrorDetail {
+	detail := &ab.ErrorDetail{Code: code}
+	for err != nil {
+		if ra, ok := err.(retryAfter); ok {
+			if wait := ra.RetryAfter(); wait > 0 {
+				detail.RetryAfter = ptypes.DurationProto(wait)
+			}
+			break
+		}
+		cause, ok := err.(interface{ Cause() error })
+		if !ok {
+			break
+		}
+		err = cause.Cause()
+	}
+	return detail
+}
+
+// malformedDetail returns the detail of the rejection of a message whose
+// channel header could not be parsed, pointing at the first field which
+// could not be
+func malformedDetail(msg *cb.Envelope) *ab.ErrorDetail {
+	detail := &ab.ErrorDetail{Code: ab.ErrorDetail_MALFORMED_MESSAGE}
+	payload, err := utils.UnmarshalPayload(msg.GetPayload())
+	switch {
+	case err != nil:
+		detail.FieldPath = "payload"
+	case payload.Header == nil:
+		detail.FieldPath = "payload.header"
+	default:
+		detail.FieldPath = "payload.header.channel_header"
+	}
+	return detail
+}
+
+// reject returns the response rejecting a message with the status because of
+// the error
+func reject(status cb.Status, detail *ab.ErrorDetail, err error) *ab.BroadcastResponse {
+	return &ab.BroadcastResponse{Status: status, Info: err.Error(), ErrorDetail: detail}
+}
+
+// rejectionStatus returns the gRPC status ending a broadcast stream after the
+// rejection of a message, which carries the detail of the rejection as status
+// details for the clients reading the status of the stream rather than the
+// responses
+func rejectionStatus(resp *ab.BroadcastResponse) error {
+	st := status.New(grpcCode(resp.Status), resp.Info)
+	if resp.ErrorDetail == nil {
+		return st.Err()
+	}
+	withDetail, err := st.WithDetails(resp.ErrorDetail)
+	if err != nil {
+		logger.Warningf("Failed attaching the error detail to the status of the stream: %s", err)
+		return st.Err()
+	}
+	return withDetail.Err()
+}
+
+// grpcCode returns the gRPC code matching the status of a rejection
+func grpcCode(s cb.Status) codes.Code {
+	switch s {
+	case cb.Status_BAD_REQUEST:
+		return codes.InvalidArgument
+	case cb.Status_FORBIDDEN:
+		return codes.PermissionDenied
+	case cb.Status_NOT_FOUND:
+		return codes.NotFound
+	case cb.Status_REQUEST_ENTITY_TOO_LARGE:
+		return codes.ResourceExhausted
+	case cb.Status_SERVICE_UNAVAILABLE:
+		return codes.Unavailable
+	case cb.Status_INTERNAL_SERVER_ERROR:
+		return codes.Internal
+	default:
+		return codes.Unknown
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broadcast
+
+import (
+	"time"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+// ForwardedKey is the gRPC metadata key set on the broadcast streams of the
+// messages forwarded by another orderer, which are never forwarded again, so
+// that orderers disagreeing on the leader do not pass messages back and forth
+const ForwardedKey = "broadcast-forwarded"
+
+// ChannelLeaders reports the leaders of the channels whose consenter elects
+// one
+type ChannelLeaders interface {
+	// Leader returns the endpoint of the AtomicBroadcast service of the
+	// orderer leading the channel, or "" if this orderer accepts the messages
+	// of the channel itself
+	Leader(channelID string) string
+}
+
+// Forwarder broadcasts messages to other orderers
+type Forwarder interface {
+	// Forward broadcasts the message to the orderer at the endpoint, with the
+	// outgoing metadata of the context, and returns its response
+	Forward(ctx context.Context, endpoint string, msg *cb.Envelope) (*ab.BroadcastResponse, error)
+}
+
+// Forwarding forwards the messages of the channels led by another orderer to
+// the leader, rather than passing them to the consenter of this orderer,
+// which does not accept them.  The leader validates, charges and orders the
+// messages, and its response is passed back to the client.  A message the
+// leader cannot be reached for, or which it answers SERVICE_UNAVAILABLE, is
+// forwarded again, to the leader of the channel at the time, up to
+// MaxAttempts times RetryInterval apart.  A nil Forwarding forwards no
+// message.
+type Forwarding struct {
+	// MaxAttempts is the number of times a message is forwarded before it is
+	// rejected with SERVICE_UNAVAILABLE
+	MaxAttempts int
+
+	// RetryInterval is the time waited before forwarding a message again
+	RetryInterval time.Duration
+
+	leaders   ChannelLeaders
+	forwarder Forwarder
+}
+
+// NewForwarding creates the Forwarding.
+func NewForwarding(maxAttempts int, retryInterval time.Duration, leaders ChannelLeaders, forwarder Forwarder) *Forwarding {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	return &Forwarding{MaxAttempts: maxAttempts, RetryInterval: retryInterval, leaders: leaders, forwarder: forwarder}
+}
+
+// forward forwards the message of the stream of the context to the leader of
+// its channel, and returns the response to it, or false if the message is
+// not forwarded because this orderer leads the channel or the message was
+// forwarded to it already
+func (f *Forwarding) forward(ctx context.Context, channelID string, msg *cb.Envelope, addr string) (*ab.BroadcastResponse, bool) {
+	if f == nil || forwarded(ctx) {
+		return nil, false
+	}
+	outgoing := metadata.NewOutgoingContext(ctx, forwardedMetadata(ctx))
+	var err error
+attempts:
+	for attempt := 1; attempt <= f.MaxAttempts; attempt++ {
+		if attempt > 1 {
+			logger.Warningf("[channel: %s] Could not forward broadcast of message from %s, attempt %d of %d: %s", channelID, addr, attempt-1, f.MaxAttempts, err)
+			select {
+			case <-time.After(f.RetryInterval):
+			case <-ctx.Done():
+				err = ctx.Err()
+				break attempts
+			}
+		}
+		//每次转发前重新获取领导者，领导者变为本节点时由本节点处理
+		leader := f.leaders.Leader(channelID)
+		if leader == "" {
+			return nil, false
+		}
+		var resp *ab.BroadcastResponse
+		resp, err = f.forwarder.Forward(outgoing, leader, msg)
+		if err == nil && resp.Status != cb.Status_SERVICE_UNAVAILABLE {
+			logger.Debugf("[channel: %s] Forwarded broadcast of message from %s to leader %s", channelID, addr, leader)
+			return resp, true
+		}
+		if err == nil {
+			err = errors.Errorf("leader %s answered %s: %s", leader, resp.Status, resp.Info)
+		}
+	}
+	err = errors.WithMessage(err, "could not forward message to the leader of the channel")
+	logger.Warningf("[channel: %s] Rejecting broadcast of message from %s with SERVICE_UNAVAILABLE: %s", channelID, addr, err)
+	return reject(cb.Status_SERVICE_UNAVAILABLE, errorDetail(ab.ErrorDetail_CONSENTER_UNAVAILABLE, err), err), true
+}
+
+// forwarded returns whether the messages of the stream of the context were
+// forwarded by another orderer
+func forwarded(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	return ok && len(md[ForwardedKey]) > 0
+}
+
+// forwardedMetadata returns the metadata of the stream on which a message
+// of the stream of the context is forwarded: the client of the leader is
+// asked to wait for the commit of the message or for a receipt if the client
+// of the stream asked for it
+func forwardedMetadata(ctx context.Context) metadata.MD {
+	md := metadata.Pairs(ForwardedKey, "true")
+	incoming, _ := metadata.FromIncomingContext(ctx)
+	for _, key := range []string{WaitForCommitKey, ReceiptsKey} {
+		if values := incoming[key]; len(values) > 0 {
+			md[key] = values
+		}
+	}
+	return md
+}
@@ -0,0 +1,64 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broadcast
+
+import (
+	"time"
+
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric/common/util"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"golang.org/x/net/context"
+)
+
+// ChannelHeights returns the height of the channels reported by heartbeats
+type ChannelHeights interface {
+	// Height returns the height of the channel, or false if the channel
+	// does not exist
+	Height(channelID string) (uint64, bool)
+}
+
+// Heartbeats configures the heartbeats sent on the broadcast streams whose
+// clients ask for them by setting util.HeartbeatIntervalKey in the metadata
+// of the stream.  A heartbeat is a response carrying no status nor
+// correlation ID, but the height of the channel of the last message received
+// on the stream.  A nil Heartbeats sends no heartbeat.
+type Heartbeats struct {
+	// MinInterval is the shortest interval at which heartbeats are sent,
+	// the interval a client asks for being raised to it
+	MinInterval time.Duration
+
+	heights ChannelHeights
+}
+
+// NewHeartbeats creates the Heartbeats.
+func NewHeartbeats(minInterval time.Duration, heights ChannelHeights) *Heartbeats {
+	return &Heartbeats{MinInterval: minInterval, heights: heights}
+}
+
+// interval returns the interval at which heartbeats are sent on the stream of
+// the context, 0 if none are
+func (hb *Heartbeats) interval(ctx context.Context) time.Duration {
+	if hb == nil {
+		return 0
+	}
+	interval := util.ExtractHeartbeatInterval(ctx)
+	if interval > 0 && interval < hb.MinInterval {
+		interval = hb.MinInterval
+	}
+	return interval
+}
+
+// heartbeat returns the heartbeat reporting the height of the channel, or
+// no height if the channel is unknown
+func (hb *Heartbeats) heartbeat(channelID string) *ab.BroadcastResponse {
+	heartbeat := &ab.Heartbeat{ChannelId: channelID, Timestamp: ptypes.TimestampNow()}
+	if channelID != "" {
+		heartbeat.Height, _ = hb.heights.Height(channelID)
+	}
+	return &ab.BroadcastResponse{Heartbeat: heartbeat}
+}
@@ -0,0 +1,113 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broadcast
+
+import (
+	"time"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	metricsNamespace = "orderer"
+	metricsSubsystem = "broadcast"
+)
+
+// Metrics are the Prometheus metrics of the broadcast service.  The metrics
+// labeled with a channel are only recorded for messages enqueued, whose
+// channel exists or is being created, so that clients cannot create a series
+// per channel ID they make up.  A nil Metrics records nothing.
+type Metrics struct {
+	received        prometheus.Counter
+	rejected        *prometheus.CounterVec
+	enqueued        *prometheus.CounterVec
+	enqueuedBytes   *prometheus.CounterVec
+	enqueueDuration *prometheus.HistogramVec
+	waitReady       *prometheus.HistogramVec
+}
+
+// NewMetrics creates the metrics of the broadcast service and registers them
+// with the registerer.
+func NewMetrics(registerer prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		received: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "messages_received_total",
+			Help:      "The number of messages received on broadcast streams.",
+		}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "messages_rejected_total",
+			Help:      "The number of messages rejected, by status of the response.",
+		}, []string{"status"}),
+		enqueued: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "messages_enqueued_total",
+			Help:      "The number of messages enqueued for ordering, by channel and type of message.",
+		}, []string{"channel", "type"}),
+		enqueuedBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "enqueued_bytes_total",
+			Help:      "The size of the messages enqueued for ordering, by channel.",
+		}, []string{"channel"}),
+		enqueueDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "enqueue_duration_seconds",
+			Help:      "The time from the receipt of a message to its enqueueing, by channel.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"channel"}),
+		waitReady: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: metricsNamespace,
+			Subsystem: metricsSubsystem,
+			Name:      "wait_ready_duration_seconds",
+			Help:      "The time the messages enqueued waited for the consenter to be ready, by channel.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"channel"}),
+	}
+	for _, c := range []prometheus.Collector{m.received, m.rejected, m.enqueued, m.enqueuedBytes, m.enqueueDuration, m.waitReady} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Metrics) messageReceived() {
+	if m == nil {
+		return
+	}
+	m.received.Inc()
+}
+
+func (m *Metrics) messageRejected(status cb.Status) {
+	if m == nil {
+		return
+	}
+	m.rejected.WithLabelValues(status.String()).Inc()
+}
+
+// messageEnqueued records a message of size bytes enqueued on the channel,
+// which waited for the consenter to be ready and took latency to enqueue
+func (m *Metrics) messageEnqueued(channelID string, isConfig bool, size int, waitReady, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	msgType := "normal"
+	if isConfig {
+		msgType = "config"
+	}
+	m.enqueued.WithLabelValues(channelID, msgType).Inc()
+	m.enqueuedBytes.WithLabelValues(channelID).Add(float64(size))
+	m.enqueueDuration.WithLabelValues(channelID).Observe(latency.Seconds())
+	m.waitReady.WithLabelValues(channelID).Observe(waitReady.Seconds())
+}
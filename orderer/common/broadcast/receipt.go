@@ -0,0 +1,87 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broadcast
+
+import (
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/util"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+// ReceiptsKey is the gRPC metadata key by which the client of a broadcast
+// stream asks for the successful responses to its messages to carry a
+// receipt signed by the orderer
+const ReceiptsKey = "broadcast-receipts"
+
+// Receipts signs the receipts attesting that the orderer accepted messages
+// for ordering, on the streams whose clients ask for them by setting
+// ReceiptsKey to true in the metadata of the stream.  The receipt binds the
+// channel, transaction ID and payload hash of the message to the config
+// sequence it was validated against and to the time it was accepted, so that
+// a client can prove that this orderer took its transaction.  A nil Receipts
+// signs no receipt.
+type Receipts struct {
+	signer crypto.LocalSigner
+}
+
+// NewReceipts creates the Receipts signed with the signer of the orderer.
+func NewReceipts(signer crypto.LocalSigner) *Receipts {
+	return &Receipts{signer: signer}
+}
+
+// wanted returns whether the client of the broadcast stream of the context
+// asks for receipts
+func (r *Receipts) wanted(ctx context.Context) bool {
+	if r == nil {
+		return false
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md[ReceiptsKey]) == 0 {
+		return false
+	}
+	want, err := strconv.ParseBool(md[ReceiptsKey][0])
+	return err == nil && want
+}
+
+// sign returns the receipt of the message, with the channel header chdr,
+// accepted for ordering at the config sequence, signed as watermarks are
+func (r *Receipts) sign(chdr *cb.ChannelHeader, configSeq uint64, msg *cb.Envelope) (*ab.SignedReceipt, error) {
+	receipt, err := proto.Marshal(&ab.Receipt{
+		ChannelId:   chdr.ChannelId,
+		TxId:        chdr.TxId,
+		ConfigSeq:   configSeq,
+		Timestamp:   ptypes.TimestampNow(),
+		PayloadHash: util.ComputeSHA256(msg.Payload),
+	})
+	if err != nil {
+		return nil, err
+	}
+	shdr, err := r.signer.NewSignatureHeader()
+	if err != nil {
+		return nil, err
+	}
+	shdrBytes, err := proto.Marshal(shdr)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := r.signer.Sign(util.ConcatenateBytes(receipt, shdrBytes))
+	if err != nil {
+		return nil, err
+	}
+	return &ab.SignedReceipt{
+		Receipt:         receipt,
+		SignatureHeader: shdrBytes,
+		Signature:       signature,
+	}, nil
+}
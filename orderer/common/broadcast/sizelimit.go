@@ -0,0 +1,44 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broadcast
+
+import (
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// SizeLimits are the maximum sizes of the messages broadcast on each
+// channel, checked before the messages are processed so that oversized
+// messages do not cost a full validation.  A limit of zero sets no limit, and
+// a nil SizeLimits checks nothing.
+type SizeLimits struct {
+	// MaxMessageSize is the maximum size in bytes of the messages of the
+	// channels without a limit of their own.
+	MaxMessageSize uint32
+
+	// Channels maps channel IDs to the maximum size of their messages.
+	Channels map[string]uint32
+}
+
+// check returns an error if the message exceeds the maximum size of the channel
+func (sl *SizeLimits) check(channelID string, msg *cb.Envelope) error {
+	if sl == nil {
+		return nil
+	}
+	limit, ok := sl.Channels[channelID]
+	if !ok {
+		limit = sl.MaxMessageSize
+	}
+	if limit == 0 {
+		return nil
+	}
+	if size := proto.Size(msg); size > int(limit) {
+		return errors.Errorf("message of %d bytes exceeds the maximum message size of %d bytes", size, limit)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package broadcast
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/comm"
+	"github.com/hyperledger/fabric/orderer/common/privacy"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StreamLimits bound the broadcast streams of each client, so that the
+// streams clients leave open do not pile up until the orderer runs out of
+// file descriptors.  A nil StreamLimits sets no limit.
+type StreamLimits struct {
+	// MaxStreamsPerClient is the number of broadcast streams each client may
+	// have open at once, no limit if 0.  Clients are identified by their TLS
+	// certificate or, without one, by their host.
+	MaxStreamsPerClient int
+
+	// IdleTimeout closes the broadcast streams on which no message was
+	// received nor is being processed for that long, never if 0.
+	IdleTimeout time.Duration
+
+	mutex   sync.Mutex
+	streams map[string]int
+}
+
+// NewStreamLimits creates the StreamLimits.
+func NewStreamLimits(maxStreamsPerClient int, idleTimeout time.Duration) *StreamLimits {
+	return &StreamLimits{
+		MaxStreamsPerClient: maxStreamsPerClient,
+		IdleTimeout:         idleTimeout,
+		streams:             map[string]int{},
+	}
+}
+
+// open counts the stream of the context towards the limit of its client, and
+// returns the function to call once the stream ends
+func (sl *StreamLimits) open(ctx context.Context) (func(), error) {
+	if sl == nil || sl.MaxStreamsPerClient == 0 {
+		return func() {}, nil
+	}
+	client := clientID(ctx)
+	sl.mutex.Lock()
+	defer sl.mutex.Unlock()
+	if sl.streams[client] >= sl.MaxStreamsPerClient {
+		return nil, status.Errorf(codes.ResourceExhausted, "client already has %d broadcast streams open, the maximum", sl.streams[client])
+	}
+	sl.streams[client]++
+	return func() {
+		sl.mutex.Lock()
+		defer sl.mutex.Unlock()
+		if sl.streams[client]--; sl.streams[client] == 0 {
+			delete(sl.streams, client)
+		}
+	}, nil
+}
+
+// idleTimeout returns the idle timeout of the streams, 0 if none
+func (sl *StreamLimits) idleTimeout() time.Duration {
+	if sl == nil {
+		return 0
+	}
+	return sl.IdleTimeout
+}
+
+// clientID identifies the client of the stream by the hash of its TLS
+// certificate or, without one, by its real host, even if the context was
+// anonymized, so that the clients sharing an anonymized host are limited and
+// blocked apart.  It must not be logged, anonymizedClientID is.
+func clientID(ctx context.Context) string {
+	return identifyClient(ctx, privacy.RemoteAddress(ctx))
+}
+
+// anonymizedClientID identifies the client of the stream as clientID does,
+// but by its host as anonymized in the context
+func anonymizedClientID(ctx context.Context) string {
+	return identifyClient(ctx, util.ExtractRemoteAddress(ctx))
+}
+
+func identifyClient(ctx context.Context, addr string) string {
+	if cert := comm.ExtractCertificateFromContext(ctx); cert != nil {
+		hash := sha256.Sum256(cert.Raw)
+		return hex.EncodeToString(hash[:])
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
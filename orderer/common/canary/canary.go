@@ -0,0 +1,264 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package canary periodically submits a no-op transaction of its own on a
+// designated channel and measures how long it takes to be enqueued and then
+// committed, the truest signal that ordering is functioning end to end.
+//
+// The canary transactions are signed by the orderer and ordered like any
+// other message, through the filters of the channel: the identity of the
+// orderer must satisfy the Writers policy of the channel.  Their type being
+// MESSAGE, peers mark them invalid without further effect.
+package canary
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/flogging"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const pkgLogID = "orderer/common/canary"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// payload is the data of the canary transactions
+var payload = []byte("orderer canary")
+
+// The stages of a probe reported by the failures metric
+const (
+	stageSubmit = "submit"
+	stageCommit = "commit"
+)
+
+// Channel is the channel the canary transactions are ordered on
+type Channel interface {
+	// ProcessNormalMsg checks the message against the filters of the
+	// channel, and returns the config sequence it was checked against
+	ProcessNormalMsg(env *cb.Envelope) (configSeq uint64, err error)
+
+	// Order passes a normal message to the consenter of the channel
+	Order(env *cb.Envelope, configSeq uint64) error
+}
+
+// Channels looks up the channels of the orderer
+type Channels interface {
+	// Channel returns the channel, or false if it does not exist
+	Channel(channelID string) (Channel, bool)
+}
+
+// Commits notifies the commit of the transactions
+type Commits interface {
+	// Register awaits the commit of the transaction of the channel, and
+	// returns the channel receiving the number of the block committing it
+	// and the function to call once the commit is no longer awaited
+	Register(channelID, txID string) (<-chan uint64, func(), error)
+}
+
+// Config contains the configuration of a Canary
+type Config struct {
+	// ChannelID is the channel the canary transactions are submitted on
+	ChannelID string
+
+	// Interval is how often a canary transaction is submitted
+	Interval time.Duration
+
+	// Timeout is how long the commit of a canary transaction is awaited
+	// before the probe is reported failed
+	Timeout time.Duration
+}
+
+// Canary submits a canary transaction every interval, one at a time, and
+// records its latencies in its metrics.
+type Canary struct {
+	conf     Config
+	channels Channels
+	signer   crypto.LocalSigner
+	commits  Commits
+	now      func() time.Time
+
+	enqueueDuration *prometheus.HistogramVec
+	commitDuration  *prometheus.HistogramVec
+	failures        *prometheus.CounterVec
+	lastCommit      *prometheus.GaugeVec
+
+	mutex sync.Mutex
+	stop  chan struct{}
+	wg    sync.WaitGroup
+}
+
+// New creates a Canary of the channel, signing its transactions with the
+// signer, whose metrics are registered with the registerer
+func New(conf Config, channels Channels, signer crypto.LocalSigner, commits Commits, registerer prometheus.Registerer) (*Canary, error) {
+	if conf.ChannelID == "" {
+		return nil, errors.New("canary channel not set")
+	}
+	if conf.Interval <= 0 || conf.Timeout <= 0 {
+		return nil, errors.New("canary interval and timeout must be positive")
+	}
+	c := &Canary{
+		conf:     conf,
+		channels: channels,
+		signer:   signer,
+		commits:  commits,
+		now:      time.Now,
+		enqueueDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "orderer",
+			Subsystem: "canary",
+			Name:      "enqueue_duration_seconds",
+			Help:      "The time taken to enqueue the canary transactions, by channel.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"channel"}),
+		commitDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: "orderer",
+			Subsystem: "canary",
+			Name:      "commit_duration_seconds",
+			Help:      "The time from the submission of the canary transactions to their commit, by channel.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"channel"}),
+		failures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "orderer",
+			Subsystem: "canary",
+			Name:      "failures_total",
+			Help:      "The number of canary transactions which could not be submitted or were not committed in time, by channel and stage.",
+		}, []string{"channel", "stage"}),
+		lastCommit: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "orderer",
+			Subsystem: "canary",
+			Name:      "last_commit_timestamp_seconds",
+			Help:      "The time of the last commit of a canary transaction, by channel.",
+		}, []string{"channel"}),
+	}
+	for _, collector := range []prometheus.Collector{c.enqueueDuration, c.commitDuration, c.failures, c.lastCommit} {
+		if err := registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// Start submits a canary transaction every interval until Stop is called
+func (c *Canary) Start() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.stop != nil {
+		return
+	}
+	c.stop = make(chan struct{})
+	stop := c.stop
+
+	c.wg.Add(1)
+	go func() {
+		defer c.wg.Done()
+		ticker := time.NewTicker(c.conf.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				//探测失败已经计入指标，此处只需记录日志
+				if err := c.Probe(stop); err != nil {
+					logger.Warningf("[channel: %s] Canary probe failed: %s", c.conf.ChannelID, err)
+				}
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops submitting canary transactions, waiting for the probe under way
+func (c *Canary) Stop() {
+	c.mutex.Lock()
+	if c.stop != nil {
+		close(c.stop)
+		c.stop = nil
+	}
+	c.mutex.Unlock()
+	c.wg.Wait()
+}
+
+// Probe submits a canary transaction and waits for its commit, until the
+// timeout or until stop is closed, and records its latencies.  It returns
+// an error if the transaction could not be submitted or was not committed.
+func (c *Canary) Probe(stop <-chan struct{}) error {
+	channelID := c.conf.ChannelID
+	env, txID, err := c.newTransaction()
+	if err != nil {
+		c.failures.WithLabelValues(channelID, stageSubmit).Inc()
+		return err
+	}
+	channel, ok := c.channels.Channel(channelID)
+	if !ok {
+		c.failures.WithLabelValues(channelID, stageSubmit).Inc()
+		return errors.New("channel does not exist")
+	}
+	// 须在入队前等待提交，交易可能随即被提交
+	committed, release, err := c.commits.Register(channelID, txID)
+	if err != nil {
+		c.failures.WithLabelValues(channelID, stageSubmit).Inc()
+		return errors.WithMessage(err, "could not await the commit of the canary transaction")
+	}
+	defer release()
+
+	start := c.now()
+	configSeq, err := channel.ProcessNormalMsg(env)
+	if err == nil {
+		err = channel.Order(env, configSeq)
+	}
+	if err != nil {
+		c.failures.WithLabelValues(channelID, stageSubmit).Inc()
+		return errors.WithMessage(err, "could not enqueue the canary transaction")
+	}
+	c.enqueueDuration.WithLabelValues(channelID).Observe(c.now().Sub(start).Seconds())
+
+	timeout := time.NewTimer(c.conf.Timeout)
+	defer timeout.Stop()
+	select {
+	case number := <-committed:
+		now := c.now()
+		c.commitDuration.WithLabelValues(channelID).Observe(now.Sub(start).Seconds())
+		c.lastCommit.WithLabelValues(channelID).Set(float64(now.UnixNano()) / float64(time.Second))
+		logger.Debugf("[channel: %s] Canary transaction %s committed in block %d after %s", channelID, txID, number, now.Sub(start))
+		return nil
+	case <-timeout.C:
+		c.failures.WithLabelValues(channelID, stageCommit).Inc()
+		return errors.Errorf("canary transaction %s not committed within %s", txID, c.conf.Timeout)
+	case <-stop:
+		return errors.New("canary stopped")
+	}
+}
+
+// newTransaction returns a canary transaction signed by the signer, and its
+// transaction ID
+func (c *Canary) newTransaction() (*cb.Envelope, string, error) {
+	shdr, err := c.signer.NewSignatureHeader()
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "could not create the signature header")
+	}
+	chdr := utils.MakeChannelHeader(cb.HeaderType_MESSAGE, 0, c.conf.ChannelID, 0)
+	if chdr.TxId, err = utils.ComputeTxID(shdr.Nonce, shdr.Creator); err != nil {
+		return nil, "", errors.WithMessage(err, "could not compute the transaction ID")
+	}
+	payloadBytes := utils.MarshalOrPanic(&cb.Payload{
+		Header: utils.MakePayloadHeader(chdr, shdr),
+		Data:   payload,
+	})
+	sig, err := c.signer.Sign(payloadBytes)
+	if err != nil {
+		return nil, "", errors.WithMessage(err, "could not sign the canary transaction")
+	}
+	return &cb.Envelope{Payload: payloadBytes, Signature: sig}, chdr.TxId, nil
+}
@@ -0,0 +1,148 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package canary
+
+import (
+	"testing"
+	"time"
+
+	mockcrypto "github.com/hyperledger/fabric/common/mocks/crypto"
+	"github.com/hyperledger/fabric/orderer/common/commitnotify"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// channel commits the messages ordered at once, unless they are dropped
+type channel struct {
+	notifier   *commitnotify.Notifier
+	processErr error
+	drop       bool
+	ordered    []*cb.Envelope
+}
+
+func (c *channel) ProcessNormalMsg(env *cb.Envelope) (uint64, error) {
+	return 3, c.processErr
+}
+
+func (c *channel) Order(env *cb.Envelope, configSeq uint64) error {
+	c.ordered = append(c.ordered, env)
+	if !c.drop {
+		c.notifier.Committed(&cb.Block{
+			Header: &cb.BlockHeader{Number: uint64(len(c.ordered))},
+			Data:   &cb.BlockData{Data: [][]byte{utils.MarshalOrPanic(env)}},
+		})
+	}
+	return nil
+}
+
+type channels map[string]*channel
+
+func (cs channels) Channel(channelID string) (Channel, bool) {
+	c, ok := cs[channelID]
+	if !ok {
+		return nil, false
+	}
+	return c, true
+}
+
+func metric(t *testing.T, registry *prometheus.Registry, name string, labels ...string) *dto.Metric {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range family.Metric {
+			for i, label := range m.Label {
+				if label.GetValue() != labels[i] {
+					continue metrics
+				}
+			}
+			return m
+		}
+	}
+	return nil
+}
+
+func newCanary(t *testing.T, ch *channel) (*Canary, *prometheus.Registry) {
+	registry := prometheus.NewRegistry()
+	c, err := New(Config{ChannelID: "mychannel", Interval: time.Hour, Timeout: 50 * time.Millisecond},
+		channels{"mychannel": ch}, &mockcrypto.LocalSigner{Identity: []byte("orderer"), Nonce: []byte("nonce")}, ch.notifier, registry)
+	require.NoError(t, err)
+	return c, registry
+}
+
+func TestProbe(t *testing.T) {
+	ch := &channel{notifier: commitnotify.New(0)}
+	c, registry := newCanary(t, ch)
+
+	require.NoError(t, c.Probe(nil))
+	require.Len(t, ch.ordered, 1)
+	payload, err := utils.UnmarshalPayload(ch.ordered[0].Payload)
+	require.NoError(t, err)
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	require.NoError(t, err)
+	assert.Equal(t, int32(cb.HeaderType_MESSAGE), chdr.Type)
+	assert.Equal(t, "mychannel", chdr.ChannelId)
+	assert.NotEmpty(t, chdr.TxId)
+
+	assert.Equal(t, uint64(1), metric(t, registry, "orderer_canary_enqueue_duration_seconds", "mychannel").Histogram.GetSampleCount())
+	assert.Equal(t, uint64(1), metric(t, registry, "orderer_canary_commit_duration_seconds", "mychannel").Histogram.GetSampleCount())
+	assert.NotZero(t, metric(t, registry, "orderer_canary_last_commit_timestamp_seconds", "mychannel").Gauge.GetValue())
+	assert.Zero(t, ch.notifier.Waiters())
+}
+
+func TestProbeFailures(t *testing.T) {
+	ch := &channel{notifier: commitnotify.New(0), drop: true}
+	c, registry := newCanary(t, ch)
+
+	err := c.Probe(nil)
+	assert.Contains(t, err.Error(), "not committed within 50ms")
+	assert.Equal(t, 1.0, metric(t, registry, "orderer_canary_failures_total", "mychannel", stageCommit).Counter.GetValue())
+	assert.Nil(t, metric(t, registry, "orderer_canary_commit_duration_seconds", "mychannel"))
+	assert.Zero(t, ch.notifier.Waiters(), "the commit is no longer awaited")
+
+	ch.processErr = errors.New("permission denied")
+	assert.EqualError(t, c.Probe(nil), "could not enqueue the canary transaction: permission denied")
+	assert.Equal(t, 1.0, metric(t, registry, "orderer_canary_failures_total", "mychannel", stageSubmit).Counter.GetValue())
+	assert.Len(t, ch.ordered, 1)
+
+	c.conf.ChannelID = "otherchannel"
+	assert.EqualError(t, c.Probe(nil), "channel does not exist")
+	assert.Equal(t, 1.0, metric(t, registry, "orderer_canary_failures_total", "otherchannel", stageSubmit).Counter.GetValue())
+}
+
+func TestStartStop(t *testing.T) {
+	ch := &channel{notifier: commitnotify.New(0), drop: true}
+	registry := prometheus.NewRegistry()
+	c, err := New(Config{ChannelID: "mychannel", Interval: time.Millisecond, Timeout: time.Hour},
+		channels{"mychannel": ch}, &mockcrypto.LocalSigner{}, ch.notifier, registry)
+	require.NoError(t, err)
+
+	c.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for ch.notifier.Waiters() == 0 {
+		require.True(t, time.Now().Before(deadline), "no canary transaction submitted")
+		time.Sleep(time.Millisecond)
+	}
+	// stopping interrupts the probe awaiting its commit
+	c.Stop()
+	assert.Zero(t, ch.notifier.Waiters())
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{Interval: time.Second, Timeout: time.Second}, channels{}, &mockcrypto.LocalSigner{}, commitnotify.New(0), prometheus.NewRegistry())
+	assert.EqualError(t, err, "canary channel not set")
+	_, err = New(Config{ChannelID: "mychannel", Timeout: time.Second}, channels{}, &mockcrypto.LocalSigner{}, commitnotify.New(0), prometheus.NewRegistry())
+	assert.EqualError(t, err, "canary interval and timeout must be positive")
+}
@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package chainhealth serves the health of the chain of every channel, so
+// that load balancers can stop routing the broadcasts of a channel to the
+// orderers whose chain is not ready to accept new messages, such as a Kafka
+// chain reprocessing the messages resubmitted after a reconfiguration.
+package chainhealth
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	"github.com/op/go-logging"
+)
+
+const pkgLogID = "orderer/common/chainhealth"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// Channels gives access to the health of the chains of the channels.
+type Channels interface {
+	// ChannelIDs returns the IDs of the channels
+	ChannelIDs() []string
+
+	// ChannelStatus returns the health of the chain of the channel, and
+	// whether the channel exists
+	ChannelStatus(channelID string) (consensus.ChainStatus, bool)
+}
+
+// ChannelHealth is the health of the chain of a channel.
+type ChannelHealth struct {
+	ChannelID string `json:"channel_id"`
+	consensus.ChainStatus
+}
+
+// Handler serves the health of the chain of the channel whose ID follows the
+// prefix in the path, or of every channel if none does.  The response has
+// status OK if the chains are ready, and Service Unavailable otherwise.
+type Handler struct {
+	prefix   string
+	channels Channels
+}
+
+// NewHandler creates a Handler serving the paths under the prefix.
+func NewHandler(prefix string, channels Channels) *Handler {
+	return &Handler{prefix: prefix, channels: channels}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	channelID := strings.Trim(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(h.prefix, "/")), "/")
+	if channelID != "" {
+		status, ok := h.channels.ChannelStatus(channelID)
+		if !ok {
+			http.Error(w, "channel does not exist", http.StatusNotFound)
+			return
+		}
+		h.write(w, status.Ready, ChannelHealth{ChannelID: channelID, ChainStatus: status})
+		return
+	}
+
+	channelIDs := h.channels.ChannelIDs()
+	sort.Strings(channelIDs)
+	ready := true
+	health := []ChannelHealth{}
+	for _, channelID := range channelIDs {
+		status, ok := h.channels.ChannelStatus(channelID)
+		if !ok {
+			continue
+		}
+		ready = ready && status.Ready
+		health = append(health, ChannelHealth{ChannelID: channelID, ChainStatus: status})
+	}
+	h.write(w, ready, health)
+}
+
+func (h *Handler) write(w http.ResponseWriter, ready bool, health interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if !ready {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	if err := json.NewEncoder(w).Encode(health); err != nil {
+		logger.Warningf("Failed writing chain health: %s", err)
+	}
+}
@@ -0,0 +1,77 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package chainhealth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/fabric/orderer/consensus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockChannels map[string]consensus.ChainStatus
+
+func (m mockChannels) ChannelIDs() []string {
+	var channelIDs []string
+	for channelID := range m {
+		channelIDs = append(channelIDs, channelID)
+	}
+	return channelIDs
+}
+
+func (m mockChannels) ChannelStatus(channelID string) (consensus.ChainStatus, bool) {
+	status, ok := m[channelID]
+	return status, ok
+}
+
+func TestHandler(t *testing.T) {
+	channels := mockChannels{
+		"foo": {Ready: true},
+		"bar": {Ready: false, Reason: "reprocessing", Lag: 3},
+	}
+	handler := NewHandler("/healthz/channels/", channels)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/healthz/channels/foo")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var health ChannelHealth
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &health))
+	assert.Equal(t, ChannelHealth{ChannelID: "foo", ChainStatus: consensus.ChainStatus{Ready: true}}, health)
+
+	rec = serve(http.MethodGet, "/healthz/channels/bar")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &health))
+	assert.Equal(t, "reprocessing", health.Reason)
+	assert.Equal(t, int64(3), health.Lag)
+
+	rec = serve(http.MethodGet, "/healthz/channels/baz")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(http.MethodGet, "/healthz/channels/")
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	var all []ChannelHealth
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &all))
+	require.Len(t, all, 2)
+	assert.Equal(t, "bar", all[0].ChannelID)
+	assert.Equal(t, "foo", all[1].ChannelID)
+
+	delete(channels, "bar")
+	rec = serve(http.MethodGet, "/healthz/channels")
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(http.MethodPost, "/healthz/channels/foo")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,155 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package channeladmin serves the administration of the channels of the
+// orderer: listing the channels with the height of their ledger and the health
+// of their chain, and removing the channels which were decommissioned, whose
+// chain and ledger would otherwise hold memory and files until the orderer
+// stops.
+//
+// Channels are still created by broadcasting a channel creation transaction
+// to the system channel, so that every orderer of the network creates them.
+package channeladmin
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	"github.com/op/go-logging"
+)
+
+const pkgLogID = "orderer/common/channeladmin"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// Channels gives access to the channels of the orderer.
+type Channels interface {
+	// ChannelIDs returns the IDs of the channels
+	ChannelIDs() []string
+
+	// ChannelHeight returns the height of the ledger of the channel, and
+	// whether the channel exists
+	ChannelHeight(channelID string) (uint64, bool)
+
+	// ChannelStatus returns the health of the chain of the channel, and
+	// whether the channel exists
+	ChannelStatus(channelID string) (consensus.ChainStatus, bool)
+
+	// SystemChannelID returns the ID of the system channel
+	SystemChannelID() string
+
+	// RemoveChannel halts the chain of the channel and removes its ledger
+	RemoveChannel(channelID string) error
+}
+
+// ChannelInfo describes a channel.
+type ChannelInfo struct {
+	ChannelID     string                `json:"channel_id"`
+	Height        uint64                `json:"height"`
+	SystemChannel bool                  `json:"system_channel"`
+	Status        consensus.ChainStatus `json:"status"`
+}
+
+// Handler serves, on GET, the channel whose ID follows the prefix in the
+// path, or every channel if none does, and removes the channel on DELETE.
+// The system channel cannot be removed.
+type Handler struct {
+	prefix   string
+	channels Channels
+}
+
+// NewHandler creates a Handler serving the paths under the prefix.
+func NewHandler(prefix string, channels Channels) *Handler {
+	return &Handler{prefix: prefix, channels: channels}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	channelID := strings.Trim(strings.TrimPrefix(r.URL.Path, strings.TrimSuffix(h.prefix, "/")), "/")
+
+	switch r.Method {
+	case http.MethodGet:
+		if channelID == "" {
+			h.write(w, h.list())
+			return
+		}
+		info, ok := h.info(channelID)
+		if !ok {
+			http.Error(w, "channel does not exist", http.StatusNotFound)
+			return
+		}
+		h.write(w, info)
+	case http.MethodDelete:
+		if channelID == "" {
+			http.Error(w, "missing channel", http.StatusBadRequest)
+			return
+		}
+		if channelID == h.channels.SystemChannelID() {
+			http.Error(w, "the system channel cannot be removed", http.StatusConflict)
+			return
+		}
+		if _, ok := h.channels.ChannelHeight(channelID); !ok {
+			http.Error(w, "channel does not exist", http.StatusNotFound)
+			return
+		}
+		if err := h.channels.RemoveChannel(channelID); err != nil {
+			logger.Errorf("Failed removing channel %s: %s", channelID, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		logger.Infof("Channel %s removed through the operations service", channelID)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// list returns the channels sorted by ID
+func (h *Handler) list() []ChannelInfo {
+	channelIDs := h.channels.ChannelIDs()
+	sort.Strings(channelIDs)
+	infos := []ChannelInfo{}
+	for _, channelID := range channelIDs {
+		// 通道可能在列举后被删除
+		if info, ok := h.info(channelID); ok {
+			infos = append(infos, info)
+		}
+	}
+	return infos
+}
+
+func (h *Handler) info(channelID string) (ChannelInfo, bool) {
+	height, ok := h.channels.ChannelHeight(channelID)
+	if !ok {
+		return ChannelInfo{}, false
+	}
+	status, ok := h.channels.ChannelStatus(channelID)
+	if !ok {
+		return ChannelInfo{}, false
+	}
+	return ChannelInfo{
+		ChannelID:     channelID,
+		Height:        height,
+		SystemChannel: channelID == h.channels.SystemChannelID(),
+		Status:        status,
+	}, true
+}
+
+func (h *Handler) write(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warningf("Failed writing channels: %s", err)
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package channeladmin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/fabric/orderer/consensus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockChannel struct {
+	height uint64
+	status consensus.ChainStatus
+}
+
+type mockChannels struct {
+	channels  map[string]mockChannel
+	removeErr error
+}
+
+func (m *mockChannels) ChannelIDs() []string {
+	var channelIDs []string
+	for channelID := range m.channels {
+		channelIDs = append(channelIDs, channelID)
+	}
+	return channelIDs
+}
+
+func (m *mockChannels) ChannelHeight(channelID string) (uint64, bool) {
+	channel, ok := m.channels[channelID]
+	return channel.height, ok
+}
+
+func (m *mockChannels) ChannelStatus(channelID string) (consensus.ChainStatus, bool) {
+	channel, ok := m.channels[channelID]
+	return channel.status, ok
+}
+
+func (m *mockChannels) SystemChannelID() string {
+	return "system"
+}
+
+func (m *mockChannels) RemoveChannel(channelID string) error {
+	if m.removeErr != nil {
+		return m.removeErr
+	}
+	delete(m.channels, channelID)
+	return nil
+}
+
+func TestHandler(t *testing.T) {
+	channels := &mockChannels{channels: map[string]mockChannel{
+		"system": {height: 3, status: consensus.ChainStatus{Ready: true}},
+		"foo":    {height: 10, status: consensus.ChainStatus{Ready: true}},
+		"bar":    {height: 1, status: consensus.ChainStatus{Reason: "reprocessing", Lag: 3}},
+	}}
+	handler := NewHandler("/channels/", channels)
+
+	serve := func(method, target string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		return rec
+	}
+
+	rec := serve(http.MethodGet, "/channels/")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var all []ChannelInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &all))
+	assert.Equal(t, []ChannelInfo{
+		{ChannelID: "bar", Height: 1, Status: consensus.ChainStatus{Reason: "reprocessing", Lag: 3}},
+		{ChannelID: "foo", Height: 10, Status: consensus.ChainStatus{Ready: true}},
+		{ChannelID: "system", Height: 3, SystemChannel: true, Status: consensus.ChainStatus{Ready: true}},
+	}, all)
+
+	rec = serve(http.MethodGet, "/channels/foo")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var info ChannelInfo
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &info))
+	assert.Equal(t, ChannelInfo{ChannelID: "foo", Height: 10, Status: consensus.ChainStatus{Ready: true}}, info)
+
+	rec = serve(http.MethodGet, "/channels/baz")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(http.MethodDelete, "/channels/system")
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	rec = serve(http.MethodDelete, "/channels/baz")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+	rec = serve(http.MethodDelete, "/channels/")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	channels.removeErr = errors.New("disk failure")
+	rec = serve(http.MethodDelete, "/channels/bar")
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "disk failure")
+
+	channels.removeErr = nil
+	rec = serve(http.MethodDelete, "/channels/bar")
+	assert.Equal(t, http.StatusNoContent, rec.Code)
+	assert.NotContains(t, channels.channels, "bar")
+	rec = serve(http.MethodGet, "/channels/bar")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(http.MethodPost, "/channels/foo")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, "GET, DELETE", rec.Header().Get("Allow"))
+}
@@ -0,0 +1,107 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package channelvalidation serves the dry run of channel creations: a
+// channel creation transaction is validated against the system channel, its
+// consortium membership, policies and filters, exactly as the broadcast
+// service would, and the config of the genesis block of the channel is
+// predicted, without the channel being created.
+package channelvalidation
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/tools/protolator"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/op/go-logging"
+)
+
+const pkgLogID = "orderer/common/channelvalidation"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// maxRequestBytes bounds the size of the transactions validated
+const maxRequestBytes = 10 * 1024 * 1024
+
+// Validator validates channel creation transactions against the system
+// channel without creating the channels.
+type Validator interface {
+	// ValidateChannelCreation returns the config of the genesis block the
+	// channel would be created with, or the reason the transaction would be
+	// rejected
+	ValidateChannelCreation(env *cb.Envelope) (*cb.Config, error)
+}
+
+// Result is the outcome of the validation of a channel creation transaction.
+type Result struct {
+	ChannelID string          `json:"channel_id"`
+	Valid     bool            `json:"valid"`
+	Error     string          `json:"error,omitempty"`
+	Config    json.RawMessage `json:"config,omitempty"`
+}
+
+// Handler validates the channel creation transaction posted as a marshaled
+// envelope, such as the output of configtxgen -outputCreateChannelTx, and
+// writes the Result as JSON.  A transaction which would be rejected is
+// reported with status OK and the reason of the rejection.
+type Handler struct {
+	validator Validator
+}
+
+// NewHandler creates a Handler.
+func NewHandler(validator Validator) *Handler {
+	return &Handler{validator: validator}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err != nil {
+		http.Error(w, "failed reading request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	env := &cb.Envelope{}
+	if err := proto.Unmarshal(body, env); err != nil {
+		http.Error(w, "request is not a marshaled envelope: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	result := &Result{}
+	result.ChannelID, _ = utils.ChannelID(env)
+	config, err := h.validator.ValidateChannelCreation(env)
+	if err != nil {
+		logger.Debugf("[channel: %s] Channel creation would be rejected: %s", result.ChannelID, err)
+		result.Error = err.Error()
+	} else {
+		buf := &bytes.Buffer{}
+		if err := protolator.DeepMarshalJSON(buf, config); err != nil {
+			http.Error(w, "failed encoding the genesis config: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+		result.Valid = true
+		result.Config = buf.Bytes()
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		logger.Warningf("Failed writing channel creation validation result: %s", err)
+	}
+}
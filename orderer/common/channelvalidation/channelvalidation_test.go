@@ -0,0 +1,84 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package channelvalidation
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockValidator struct {
+	config *cb.Config
+	err    error
+}
+
+func (mv *mockValidator) ValidateChannelCreation(env *cb.Envelope) (*cb.Config, error) {
+	return mv.config, mv.err
+}
+
+func creationTx(channelID string) []byte {
+	return utils.MarshalOrPanic(&cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
+					Type:      int32(cb.HeaderType_CONFIG_UPDATE),
+					ChannelId: channelID,
+				}),
+			},
+		}),
+	})
+}
+
+func validate(t *testing.T, h *Handler, body []byte) (*httptest.ResponseRecorder, *Result) {
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/channelcreation/validate", bytes.NewReader(body)))
+	if resp.Code != http.StatusOK {
+		return resp, nil
+	}
+	result := &Result{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), result))
+	return resp, result
+}
+
+func TestHandlerValid(t *testing.T) {
+	h := NewHandler(&mockValidator{config: &cb.Config{Sequence: 1, ChannelGroup: cb.NewConfigGroup()}})
+	resp, result := validate(t, h, creationTx("mychannel"))
+	assert.Equal(t, "application/json", resp.Header().Get("Content-Type"))
+	assert.Equal(t, "mychannel", result.ChannelID)
+	assert.True(t, result.Valid)
+	assert.Empty(t, result.Error)
+
+	config := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(result.Config, &config))
+	assert.Equal(t, "1", config["sequence"])
+}
+
+func TestHandlerInvalid(t *testing.T) {
+	h := NewHandler(&mockValidator{err: errors.New("Unknown consortium name: Foo")})
+	_, result := validate(t, h, creationTx("mychannel"))
+	assert.Equal(t, &Result{ChannelID: "mychannel", Error: "Unknown consortium name: Foo"}, result)
+}
+
+func TestHandlerBadRequest(t *testing.T) {
+	h := NewHandler(&mockValidator{})
+	resp, _ := validate(t, h, []byte("garbage"))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+	assert.Contains(t, resp.Body.String(), "request is not a marshaled envelope")
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/channelcreation/validate", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
@@ -0,0 +1,159 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package commitnotify notifies the broadcast handler when the transactions
+// whose clients wait for their commit are committed in a block, so that the
+// clients need not correlate the blocks of a Deliver stream themselves.
+package commitnotify
+
+import (
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/commitnotify"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// DefaultMaxWaiters is the number of transactions awaited at once when no
+// limit is configured.
+const DefaultMaxWaiters = 10000
+
+// blockBuffer is the number of committed blocks the notifier may lag behind
+const blockBuffer = 1000
+
+// ErrTooManyWaiters is returned when as many transactions as allowed are
+// awaited already
+var ErrTooManyWaiters = errors.New("too many transactions awaiting their commit")
+
+type txKey struct {
+	channelID string
+	txID      string
+}
+
+// Notifier notifies the waiters of transactions of the blocks committing
+// them.
+type Notifier struct {
+	maxWaiters int
+
+	mutex   sync.Mutex
+	waiters map[txKey]map[chan uint64]struct{}
+	count   int
+}
+
+// New creates a Notifier allowing at most maxWaiters transactions to be
+// awaited at once, or DefaultMaxWaiters if it is not positive
+func New(maxWaiters int) *Notifier {
+	if maxWaiters <= 0 {
+		maxWaiters = DefaultMaxWaiters
+	}
+	return &Notifier{
+		maxWaiters: maxWaiters,
+		waiters:    map[txKey]map[chan uint64]struct{}{},
+	}
+}
+
+// Register awaits the commit of the transaction of the channel.  It returns
+// a channel receiving the number of the block committing the transaction,
+// and a function to call once the commit is no longer awaited.  It must be
+// called before the transaction is enqueued, as it may be committed at once.
+func (n *Notifier) Register(channelID, txID string) (<-chan uint64, func(), error) {
+	if txID == "" {
+		return nil, nil, errors.New("transaction has no ID")
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.count >= n.maxWaiters {
+		return nil, nil, ErrTooManyWaiters
+	}
+	key := txKey{channelID: channelID, txID: txID}
+	committed := make(chan uint64, 1)
+	if n.waiters[key] == nil {
+		n.waiters[key] = map[chan uint64]struct{}{}
+	}
+	n.waiters[key][committed] = struct{}{}
+	n.count++
+	return committed, func() { n.release(key, committed) }, nil
+}
+
+// release forgets a waiter, if it was not notified already
+func (n *Notifier) release(key txKey, committed chan uint64) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if _, ok := n.waiters[key][committed]; !ok {
+		return
+	}
+	delete(n.waiters[key], committed)
+	if len(n.waiters[key]) == 0 {
+		delete(n.waiters, key)
+	}
+	n.count--
+}
+
+// Waiters returns the number of commits awaited
+func (n *Notifier) Waiters() int {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	return n.count
+}
+
+// Committed notifies the waiters of the transactions of the committed block
+func (n *Notifier) Committed(block *cb.Block) {
+	if block.Data == nil {
+		return
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if n.count == 0 {
+		return
+	}
+	for _, data := range block.Data.Data {
+		env, err := utils.UnmarshalEnvelope(data)
+		if err != nil {
+			continue
+		}
+		chdr, err := utils.ChannelHeader(env)
+		if err != nil {
+			continue
+		}
+		key := txKey{channelID: chdr.ChannelId, txID: chdr.TxId}
+		for committed := range n.waiters[key] {
+			committed <- block.Header.Number
+			n.count--
+		}
+		delete(n.waiters, key)
+	}
+}
+
+// Follow notifies the waiters of the blocks committed on every channel of
+// the multicaster, until the multicaster is closed.  The awaited
+// transactions committed while the notifier was dropped for lagging behind
+// are not notified.
+func (n *Notifier) Follow(blocks *fanout.Multicaster) {
+	go func() {
+		for {
+			sub := blocks.Subscribe(fanout.AllChannels, blockBuffer)
+			for block := range sub.Blocks() {
+				n.Committed(block)
+			}
+			if sub.Err() != fanout.ErrSlowSubscriber {
+				logger.Infof("Commit notifier stopped following blocks: %s", sub.Err())
+				return
+			}
+			logger.Warningf("Commit notifier fell behind the committed blocks, resubscribing: %s", sub.Err())
+		}
+	}()
+}
@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package commitnotify
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/orderer/common/fanout"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newBlock(number uint64, channelID string, txIDs ...string) *cb.Block {
+	block := cb.NewBlock(number, nil)
+	for _, txID := range txIDs {
+		env := &cb.Envelope{Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
+				ChannelId: channelID,
+				TxId:      txID,
+			})},
+		})}
+		block.Data.Data = append(block.Data.Data, utils.MarshalOrPanic(env))
+	}
+	return block
+}
+
+func TestCommitted(t *testing.T) {
+	n := New(0)
+	first, _, err := n.Register("foo", "tx1")
+	require.NoError(t, err)
+	second, _, err := n.Register("foo", "tx1")
+	require.NoError(t, err)
+	other, _, err := n.Register("bar", "tx1")
+	require.NoError(t, err)
+	assert.Equal(t, 3, n.Waiters())
+
+	// 同一交易的所有等待者均被通知，其他通道的同名交易不受影响
+	n.Committed(newBlock(4, "foo", "tx0", "tx1"))
+	assert.Equal(t, uint64(4), <-first)
+	assert.Equal(t, uint64(4), <-second)
+	assert.Len(t, other, 0)
+	assert.Equal(t, 1, n.Waiters())
+
+	// 无法解析的交易被跳过
+	block := newBlock(2, "bar", "tx1")
+	block.Data.Data = append([][]byte{[]byte("garbage")}, block.Data.Data...)
+	n.Committed(block)
+	assert.Equal(t, uint64(2), <-other)
+	assert.Equal(t, 0, n.Waiters())
+}
+
+func TestRegister(t *testing.T) {
+	n := New(2)
+	_, _, err := n.Register("foo", "")
+	assert.EqualError(t, err, "transaction has no ID")
+
+	_, release, err := n.Register("foo", "tx1")
+	require.NoError(t, err)
+	committed, _, err := n.Register("foo", "tx2")
+	require.NoError(t, err)
+	_, _, err = n.Register("foo", "tx3")
+	assert.Equal(t, ErrTooManyWaiters, err)
+
+	// 取消等待后释放名额，重复取消无影响
+	release()
+	release()
+	assert.Equal(t, 1, n.Waiters())
+	_, _, err = n.Register("foo", "tx3")
+	assert.NoError(t, err)
+
+	n.Committed(newBlock(1, "foo", "tx1"))
+	assert.Equal(t, 2, n.Waiters())
+	n.Committed(newBlock(2, "foo", "tx2"))
+	assert.Equal(t, uint64(2), <-committed)
+}
+
+func TestFollow(t *testing.T) {
+	blocks := fanout.New()
+	n := New(0)
+	n.Follow(blocks)
+
+	committed, _, err := n.Register("foo", "tx1")
+	require.NoError(t, err)
+	//等待订阅建立
+	for blocks.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	blocks.Publish("foo", newBlock(7, "foo", "tx1"))
+	select {
+	case number := <-committed:
+		assert.Equal(t, uint64(7), number)
+	case <-time.After(time.Second):
+		t.Fatal("transaction commit was not notified")
+	}
+}
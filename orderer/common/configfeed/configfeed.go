@@ -0,0 +1,297 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package configfeed streams the config changes of a channel to subscribers,
+// so that SDKs and gateways can react to new orderer endpoints or policies
+// without polling the blocks of the channel.
+package configfeed
+
+import (
+	"math"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	"github.com/hyperledger/fabric/common/tools/configtxlator/update"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/comm"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const pkgLogID = "orderer/common/configfeed"
+
+// blockBuffer is the number of blocks of the channel a subscription buffers
+// while it looks for config blocks among them
+const blockBuffer = 100
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// Chain is a channel whose config changes are streamed
+type Chain interface {
+	// Sequence returns the current config sequence number
+	Sequence() uint64
+
+	// ConfigProto returns the config in force on the channel
+	ConfigProto() *cb.Config
+
+	// Reader returns the chain Reader for the chain
+	Reader() blockledger.Reader
+
+	// Errored returns a channel which closes when the backing consenter has errored
+	Errored() <-chan struct{}
+}
+
+// ChainManager looks up the channels subscribed to
+type ChainManager interface {
+	GetChain(channelID string) (Chain, bool)
+}
+
+// PolicyChecker checks the subscription request against the readers policy
+// of the channel
+type PolicyChecker func(env *cb.Envelope, channelID string) error
+
+// Sender sends the responses of a subscription
+type Sender interface {
+	Send(*ab.ConfigChangeResponse) error
+}
+
+// Handler serves subscriptions to the config changes of channels.
+type Handler struct {
+	ChainManager     ChainManager
+	Fanout           *fanout.Multicaster
+	PolicyChecker    PolicyChecker
+	TimeWindow       time.Duration
+	BindingInspector comm.BindingInspector
+}
+
+// NewHandler creates a Handler following the blocks published by the fanout.
+func NewHandler(cm ChainManager, blockFanout *fanout.Multicaster, policyChecker PolicyChecker, timeWindow time.Duration, mutualTLS bool) *Handler {
+	return &Handler{
+		ChainManager:     cm,
+		Fanout:           blockFanout,
+		PolicyChecker:    policyChecker,
+		TimeWindow:       timeWindow,
+		BindingInspector: comm.NewBindingInspector(mutualTLS, extractChannelHeaderCertHash),
+	}
+}
+
+func extractChannelHeaderCertHash(msg proto.Message) []byte {
+	chdr, isChannelHeader := msg.(*cb.ChannelHeader)
+	if !isChannelHeader || chdr == nil {
+		return nil
+	}
+	return chdr.TlsCertHash
+}
+
+// Handle serves the subscription requested by the envelope.  It sends the
+// config in force on the channel, then each config committed on it with its
+// difference from the previous one, until the client goes away or a status
+// ends the subscription.
+func (h *Handler) Handle(ctx context.Context, env *cb.Envelope, srv Sender) error {
+	addr := util.ExtractRemoteAddress(ctx)
+	chdr, shdr, err := h.validate(ctx, env)
+	if err != nil {
+		logger.Warningf("Rejecting config subscription from %s: %s", addr, err)
+		return sendStatus(srv, cb.Status_BAD_REQUEST)
+	}
+
+	chain, ok := h.ChainManager.GetChain(chdr.ChannelId)
+	if !ok {
+		logger.Debugf("Rejecting config subscription from %s because channel %s not found", addr, chdr.ChannelId)
+		return sendStatus(srv, cb.Status_NOT_FOUND)
+	}
+
+	ac := &accessControl{
+		chain:     chain,
+		env:       env,
+		channelID: chdr.ChannelId,
+		check:     h.PolicyChecker,
+		expiresAt: crypto.ExpiresAt(shdr.Creator),
+	}
+	if err := ac.evaluate(); err != nil {
+		logger.Warningf("[channel: %s] Client authorization revoked for config subscription from %s: %s", chdr.ChannelId, addr, err)
+		return sendStatus(srv, cb.Status_FORBIDDEN)
+	}
+
+	//先订阅新区块再读取当前配置，避免遗漏两者之间提交的配置区块
+	sub := h.Fanout.Subscribe(chdr.ChannelId, blockBuffer)
+	defer sub.Cancel()
+
+	current := chain.ConfigProto()
+	lastConfig, err := lastConfigIndex(chain.Reader())
+	if err != nil {
+		logger.Errorf("[channel: %s] Could not find the last config block for config subscription from %s: %s", chdr.ChannelId, addr, err)
+		return sendStatus(srv, cb.Status_INTERNAL_SERVER_ERROR)
+	}
+	if err := sendChange(srv, &ab.ConfigChange{BlockNumber: lastConfig, Config: current}); err != nil {
+		return err
+	}
+	logger.Debugf("[channel: %s] Started config subscription from %s at config sequence %d", chdr.ChannelId, addr, current.Sequence)
+
+	for {
+		select {
+		case <-ctx.Done():
+			logger.Debugf("[channel: %s] Context of config subscription from %s finished", chdr.ChannelId, addr)
+			return nil
+		case <-chain.Errored():
+			logger.Warningf("[channel: %s] Ending config subscription from %s because of consenter error", chdr.ChannelId, addr)
+			return sendStatus(srv, cb.Status_SERVICE_UNAVAILABLE)
+		case block, ok := <-sub.Blocks():
+			if !ok {
+				logger.Warningf("[channel: %s] Ending config subscription from %s: %s", chdr.ChannelId, addr, sub.Err())
+				return sendStatus(srv, cb.Status_SERVICE_UNAVAILABLE)
+			}
+			config, err := blockConfig(block)
+			if err != nil {
+				logger.Errorf("[channel: %s] Could not decode config of block %d for config subscription from %s: %s", chdr.ChannelId, block.Header.Number, addr, err)
+				return sendStatus(srv, cb.Status_INTERNAL_SERVER_ERROR)
+			}
+			//跳过普通区块以及订阅开始前已生效的配置
+			if config == nil || config.Sequence <= current.Sequence {
+				continue
+			}
+			if err := ac.evaluate(); err != nil {
+				logger.Warningf("[channel: %s] Client authorization revoked for config subscription from %s: %s", chdr.ChannelId, addr, err)
+				return sendStatus(srv, cb.Status_FORBIDDEN)
+			}
+			delta, err := update.Compute(current, config)
+			if err != nil {
+				logger.Errorf("[channel: %s] Could not compute config delta of block %d for config subscription from %s: %s", chdr.ChannelId, block.Header.Number, addr, err)
+				return sendStatus(srv, cb.Status_INTERNAL_SERVER_ERROR)
+			}
+			delta.ChannelId = chdr.ChannelId
+			if err := sendChange(srv, &ab.ConfigChange{BlockNumber: block.Header.Number, Config: config, Delta: delta}); err != nil {
+				return err
+			}
+			logger.Debugf("[channel: %s] Sent config sequence %d of block %d to %s", chdr.ChannelId, config.Sequence, block.Header.Number, addr)
+			current = config
+		}
+	}
+}
+
+// validate checks the headers of the subscription request
+func (h *Handler) validate(ctx context.Context, env *cb.Envelope) (*cb.ChannelHeader, *cb.SignatureHeader, error) {
+	payload, err := utils.UnmarshalPayload(env.Payload)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "envelope has no payload")
+	}
+	if payload.Header == nil {
+		return nil, nil, errors.New("envelope has no payload header")
+	}
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to unmarshal channel header")
+	}
+	shdr, err := utils.GetSignatureHeader(payload.Header.SignatureHeader)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "failed to unmarshal signature header")
+	}
+
+	if chdr.GetTimestamp() == nil {
+		return nil, nil, errors.New("channel header in envelope must contain timestamp")
+	}
+	envTime := time.Unix(chdr.GetTimestamp().Seconds, int64(chdr.GetTimestamp().Nanos)).UTC()
+	serverTime := time.Now()
+	if math.Abs(float64(serverTime.UnixNano()-envTime.UnixNano())) > float64(h.TimeWindow.Nanoseconds()) {
+		return nil, nil, errors.Errorf("envelope timestamp %s is more than %s apart from current server time %s", envTime, h.TimeWindow, serverTime)
+	}
+	if err := h.BindingInspector(ctx, chdr); err != nil {
+		return nil, nil, err
+	}
+	return chdr, shdr, nil
+}
+
+// accessControl checks the subscription request against the readers policy
+// of the channel again whenever its config changed, and ends the
+// subscription once the identity of the client expires
+type accessControl struct {
+	chain     Chain
+	env       *cb.Envelope
+	channelID string
+	check     PolicyChecker
+	expiresAt time.Time
+
+	checked  bool
+	sequence uint64
+}
+
+func (ac *accessControl) evaluate() error {
+	if !ac.expiresAt.IsZero() && time.Now().After(ac.expiresAt) {
+		return errors.Errorf("client identity expired %v before", time.Since(ac.expiresAt))
+	}
+	sequence := ac.chain.Sequence()
+	if ac.checked && sequence == ac.sequence {
+		return nil
+	}
+	if err := ac.check(ac.env, ac.channelID); err != nil {
+		return err
+	}
+	ac.checked, ac.sequence = true, sequence
+	return nil
+}
+
+// lastConfigIndex returns the number of the last config block of the ledger
+func lastConfigIndex(reader blockledger.Reader) (uint64, error) {
+	lastBlock := blockledger.GetBlock(reader, reader.Height()-1)
+	if lastBlock == nil {
+		return 0, errors.New("could not read last block")
+	}
+	return utils.GetLastConfigIndexFromBlock(lastBlock)
+}
+
+// blockConfig returns the config carried by the block, or nil if it is not
+// a config block
+func blockConfig(block *cb.Block) (*cb.Config, error) {
+	if block.Data == nil || len(block.Data.Data) != 1 {
+		return nil, nil
+	}
+	env, err := utils.ExtractEnvelope(block, 0)
+	if err != nil {
+		return nil, err
+	}
+	payload, err := utils.UnmarshalPayload(env.Payload)
+	if err != nil {
+		return nil, err
+	}
+	if payload.Header == nil {
+		return nil, errors.New("missing payload header")
+	}
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		return nil, err
+	}
+	if chdr.Type != int32(cb.HeaderType_CONFIG) {
+		return nil, nil
+	}
+	configEnv := &cb.ConfigEnvelope{}
+	if err := proto.Unmarshal(payload.Data, configEnv); err != nil {
+		return nil, err
+	}
+	if configEnv.Config == nil {
+		return nil, errors.New("config envelope has no config")
+	}
+	return configEnv.Config, nil
+}
+
+func sendStatus(srv Sender, status cb.Status) error {
+	return srv.Send(&ab.ConfigChangeResponse{Type: &ab.ConfigChangeResponse_Status{Status: status}})
+}
+
+func sendChange(srv Sender, change *ab.ConfigChange) error {
+	return srv.Send(&ab.ConfigChangeResponse{Type: &ab.ConfigChangeResponse_Change{Change: change}})
+}
@@ -0,0 +1,194 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package configfeed
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	ramledger "github.com/hyperledger/fabric/common/ledger/blockledger/ram"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+const channelID = "foo"
+
+type mockChain struct {
+	ledger   blockledger.ReadWriter
+	config   *cb.Config
+	sequence uint64
+	errored  chan struct{}
+}
+
+func (mc *mockChain) Sequence() uint64                 { return mc.sequence }
+func (mc *mockChain) ConfigProto() *cb.Config          { return mc.config }
+func (mc *mockChain) Reader() blockledger.Reader       { return mc.ledger }
+func (mc *mockChain) Errored() <-chan struct{}         { return mc.errored }
+func (mc *mockChain) GetChain(id string) (Chain, bool) { return mc, id == channelID }
+
+type mockSender chan *ab.ConfigChangeResponse
+
+func (ms mockSender) Send(resp *ab.ConfigChangeResponse) error {
+	ms <- resp
+	return nil
+}
+
+func channelConfig(sequence uint64, addresses string) *cb.Config {
+	return &cb.Config{
+		Sequence: sequence,
+		ChannelGroup: &cb.ConfigGroup{
+			Values: map[string]*cb.ConfigValue{
+				"OrdererAddresses": {Value: []byte(addresses), Version: sequence, ModPolicy: "Admins"},
+			},
+			ModPolicy: "Admins",
+		},
+	}
+}
+
+func configBlock(number uint64, config *cb.Config) *cb.Block {
+	payload := &cb.Payload{
+		Header: utils.MakePayloadHeader(utils.MakeChannelHeader(cb.HeaderType_CONFIG, 0, channelID, 0), &cb.SignatureHeader{}),
+		Data:   utils.MarshalOrPanic(&cb.ConfigEnvelope{Config: config}),
+	}
+	block := cb.NewBlock(number, nil)
+	block.Data.Data = [][]byte{utils.MarshalOrPanic(&cb.Envelope{Payload: utils.MarshalOrPanic(payload)})}
+	block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{
+		Value: utils.MarshalOrPanic(&cb.LastConfig{Index: number}),
+	})
+	return block
+}
+
+func newChain(t *testing.T) *mockChain {
+	rl, err := ramledger.New(10).GetOrCreate(channelID)
+	require.NoError(t, err)
+	config := channelConfig(0, "orderer0:7050")
+	require.NoError(t, rl.Append(configBlock(0, config)))
+	return &mockChain{ledger: rl, config: config, errored: make(chan struct{})}
+}
+
+func request(channel string) *cb.Envelope {
+	payload := &cb.Payload{
+		Header: utils.MakePayloadHeader(utils.MakeChannelHeader(cb.HeaderType_DELIVER_SEEK_INFO, 0, channel, 0), &cb.SignatureHeader{Creator: []byte("creator")}),
+	}
+	return &cb.Envelope{Payload: utils.MarshalOrPanic(payload)}
+}
+
+func allow(*cb.Envelope, string) error { return nil }
+
+func TestSubscribe(t *testing.T) {
+	chain := newChain(t)
+	blockFanout := fanout.New()
+	h := NewHandler(chain, blockFanout, allow, time.Minute, false)
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := make(mockSender, 10)
+	done := make(chan error)
+	go func() { done <- h.Handle(ctx, request(channelID), srv) }()
+
+	initial := (<-srv).GetChange()
+	require.NotNil(t, initial)
+	assert.Equal(t, uint64(0), initial.BlockNumber)
+	assert.Equal(t, uint64(0), initial.Config.Sequence)
+	assert.Nil(t, initial.Delta)
+
+	// 普通区块与已生效的配置不推送
+	normal := cb.NewBlock(1, nil)
+	normal.Data.Data = [][]byte{utils.MarshalOrPanic(&cb.Envelope{Payload: utils.MarshalOrPanic(&cb.Payload{
+		Header: utils.MakePayloadHeader(utils.MakeChannelHeader(cb.HeaderType_ENDORSER_TRANSACTION, 0, channelID, 0), &cb.SignatureHeader{}),
+	})})}
+	blockFanout.Publish(channelID, normal)
+	blockFanout.Publish(channelID, configBlock(1, channelConfig(0, "orderer0:7050")))
+
+	updated := channelConfig(1, "orderer0:7050,orderer1:7050")
+	chain.config, chain.sequence = updated, 1
+	blockFanout.Publish(channelID, configBlock(2, updated))
+
+	change := (<-srv).GetChange()
+	require.NotNil(t, change)
+	assert.Equal(t, uint64(2), change.BlockNumber)
+	assert.Equal(t, uint64(1), change.Config.Sequence)
+	require.NotNil(t, change.Delta)
+	assert.Equal(t, channelID, change.Delta.ChannelId)
+	assert.Equal(t, []byte("orderer0:7050,orderer1:7050"), change.Delta.WriteSet.Values["OrdererAddresses"].Value)
+
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, 0, blockFanout.Subscribers())
+}
+
+func TestSubscribeRejected(t *testing.T) {
+	chain := newChain(t)
+	h := NewHandler(chain, fanout.New(), allow, time.Minute, false)
+	ctx := context.Background()
+
+	srv := make(mockSender, 1)
+	assert.NoError(t, h.Handle(ctx, &cb.Envelope{Payload: []byte("garbage")}, srv))
+	assert.Equal(t, cb.Status_BAD_REQUEST, (<-srv).GetStatus())
+
+	stale := request(channelID)
+	h.TimeWindow = -time.Minute
+	assert.NoError(t, h.Handle(ctx, stale, srv))
+	assert.Equal(t, cb.Status_BAD_REQUEST, (<-srv).GetStatus())
+	h.TimeWindow = time.Minute
+
+	assert.NoError(t, h.Handle(ctx, request("bar"), srv))
+	assert.Equal(t, cb.Status_NOT_FOUND, (<-srv).GetStatus())
+
+	h.PolicyChecker = func(*cb.Envelope, string) error { return errors.New("not a reader") }
+	assert.NoError(t, h.Handle(ctx, request(channelID), srv))
+	assert.Equal(t, cb.Status_FORBIDDEN, (<-srv).GetStatus())
+}
+
+func TestSubscribeRevoked(t *testing.T) {
+	chain := newChain(t)
+	blockFanout := fanout.New()
+	allowed := true
+	h := NewHandler(chain, blockFanout, func(*cb.Envelope, string) error {
+		if !allowed {
+			return errors.New("not a reader")
+		}
+		return nil
+	}, time.Minute, false)
+	srv := make(mockSender, 10)
+	done := make(chan error)
+	go func() { done <- h.Handle(context.Background(), request(channelID), srv) }()
+	require.NotNil(t, (<-srv).GetChange())
+
+	// 新配置撤销客户端的读权限后结束订阅
+	allowed = false
+	updated := channelConfig(1, "orderer1:7050")
+	chain.config, chain.sequence = updated, 1
+	blockFanout.Publish(channelID, configBlock(1, updated))
+	assert.Equal(t, cb.Status_FORBIDDEN, (<-srv).GetStatus())
+	assert.NoError(t, <-done)
+}
+
+func TestSubscribeEnded(t *testing.T) {
+	chain := newChain(t)
+	blockFanout := fanout.New()
+	h := NewHandler(chain, blockFanout, allow, time.Minute, false)
+	srv := make(mockSender, 10)
+	done := make(chan error)
+	go func() { done <- h.Handle(context.Background(), request(channelID), srv) }()
+	require.NotNil(t, (<-srv).GetChange())
+
+	blockFanout.Close()
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, (<-srv).GetStatus())
+	assert.NoError(t, <-done)
+
+	go func() { done <- h.Handle(context.Background(), request(channelID), srv) }()
+	require.NotNil(t, (<-srv).GetChange())
+	close(chain.errored)
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, (<-srv).GetStatus())
+	assert.NoError(t, <-done)
+}
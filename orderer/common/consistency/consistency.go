@@ -0,0 +1,246 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package consistency cross-checks the state the orderer is running with
+// against the latest config of every channel, reporting the drift between
+// them.  Drift usually comes from operator errors, such as a local MSP
+// folder left stale after the channel MSP was rotated, an orderer listening
+// on an address the channel does not advertise, or a consenter missing from
+// the orderer binary.
+package consistency
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"sort"
+
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/flogging"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/op/go-logging"
+)
+
+const pkgLogID = "orderer/common/consistency"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// The checks performed on every channel
+const (
+	// ConsensusCheck verifies that the chain runs on the configured
+	// consensus type, and that the orderer supports it
+	ConsensusCheck = "consensus"
+
+	// EndpointCheck verifies that the orderer listens on one of the
+	// addresses advertised for its organization or channel
+	EndpointCheck = "endpoint"
+
+	// MSPCheck verifies that the local MSP belongs to an orderer
+	// organization of the channel and that the local signing identity is
+	// valid under the channel MSP
+	MSPCheck = "msp"
+)
+
+// Runtime describes the state the orderer is running with.
+type Runtime struct {
+	// LocalMSPID is the identifier of the local MSP
+	LocalMSPID string
+
+	// Identity is the serialized local signing identity
+	Identity []byte
+
+	// ListenAddress is the host:port the orderer listens on
+	ListenAddress string
+
+	// Consenters are the consensus types the orderer supports
+	Consenters []string
+}
+
+// Channel describes a channel served by the orderer.
+type Channel struct {
+	// Resources are the resources built from the latest config of the channel
+	Resources channelconfig.Resources
+
+	// ConsensusType is the consensus type of the running chain
+	ConsensusType string
+}
+
+// Drift is an inconsistency found by a check.
+type Drift struct {
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// ChannelReport lists the drift found on a channel.
+type ChannelReport struct {
+	ChannelID      string  `json:"channel_id"`
+	ConfigSequence uint64  `json:"config_sequence"`
+	Drift          []Drift `json:"drift,omitempty"`
+}
+
+// Report is the result of checking every channel.
+type Report struct {
+	Consistent bool            `json:"consistent"`
+	Channels   []ChannelReport `json:"channels"`
+}
+
+// Check cross-checks the runtime against every channel, ordering the
+// channel reports by channel ID.
+func Check(runtime Runtime, channels []Channel) *Report {
+	report := &Report{Consistent: true}
+	for _, channel := range channels {
+		channelReport := CheckChannel(runtime, channel)
+		if len(channelReport.Drift) > 0 {
+			report.Consistent = false
+		}
+		report.Channels = append(report.Channels, channelReport)
+	}
+	sort.Slice(report.Channels, func(i, j int) bool {
+		return report.Channels[i].ChannelID < report.Channels[j].ChannelID
+	})
+	return report
+}
+
+// CheckChannel cross-checks the runtime against a channel.
+func CheckChannel(runtime Runtime, channel Channel) ChannelReport {
+	validator := channel.Resources.ConfigtxValidator()
+	report := ChannelReport{
+		ChannelID:      validator.ChainID(),
+		ConfigSequence: validator.Sequence(),
+	}
+	drift := func(check, format string, args ...interface{}) {
+		report.Drift = append(report.Drift, Drift{Check: check, Message: fmt.Sprintf(format, args...)})
+	}
+
+	ordererConfig, ok := channel.Resources.OrdererConfig()
+	if !ok {
+		drift(ConsensusCheck, "channel config has no orderer section")
+		return report
+	}
+
+	configured := ordererConfig.ConsensusType()
+	if channel.ConsensusType != configured {
+		drift(ConsensusCheck, "config requires consensus type %s but the chain is running %s", configured, channel.ConsensusType)
+	}
+	if !contains(runtime.Consenters, configured) {
+		drift(ConsensusCheck, "config requires consensus type %s which this orderer does not support", configured)
+	}
+
+	var localOrg channelconfig.Org
+	for _, org := range ordererConfig.Organizations() {
+		if org.MSPID() == runtime.LocalMSPID {
+			localOrg = org
+		}
+	}
+	if localOrg == nil {
+		drift(MSPCheck, "local MSP %s is not an orderer organization of the channel", runtime.LocalMSPID)
+	}
+	if err := validateIdentity(channel.Resources, runtime.Identity); err != nil {
+		drift(MSPCheck, "local signing identity is not valid under the channel MSP, the local MSP folder may be stale: %s", err)
+	}
+
+	addresses := advertisedAddresses(localOrg, channel.Resources)
+	if runtime.ListenAddress != "" && !listensOnAny(runtime.ListenAddress, addresses) {
+		drift(EndpointCheck, "listen address %s matches none of the advertised orderer addresses %v", runtime.ListenAddress, addresses)
+	}
+
+	return report
+}
+
+func validateIdentity(resources channelconfig.Resources, serialized []byte) error {
+	identity, err := resources.MSPManager().DeserializeIdentity(serialized)
+	if err != nil {
+		return err
+	}
+	return identity.Validate()
+}
+
+// advertisedAddresses returns the endpoints advertised by the organization,
+// falling back to the addresses of the channel
+func advertisedAddresses(org channelconfig.Org, resources channelconfig.Resources) []string {
+	var addresses []string
+	if org, ok := org.(interface{ Endpoints() []*cb.OrdererEndpoint }); ok {
+		for _, endpoint := range org.Endpoints() {
+			addresses = append(addresses, endpoint.Address)
+		}
+	}
+	if len(addresses) == 0 {
+		addresses = resources.ChannelConfig().OrdererAddresses()
+	}
+	return addresses
+}
+
+// listensOnAny returns whether one of the addresses reaches the listen
+// address, comparing the host only if the orderer listens on a specific one
+func listensOnAny(listenAddress string, addresses []string) bool {
+	listenHost, listenPort, err := net.SplitHostPort(listenAddress)
+	if err != nil {
+		return false
+	}
+	anyHost := listenHost == ""
+	if ip := net.ParseIP(listenHost); ip != nil && ip.IsUnspecified() {
+		anyHost = true
+	}
+	for _, address := range addresses {
+		host, port, err := net.SplitHostPort(address)
+		if err != nil || port != listenPort {
+			continue
+		}
+		if anyHost || host == listenHost {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// Handler serves the consistency report of the channels returned by the
+// function at the time of each request.
+type Handler struct {
+	runtime  Runtime
+	channels func() []Channel
+}
+
+// NewHandler creates a Handler.
+func NewHandler(runtime Runtime, channels func() []Channel) *Handler {
+	return &Handler{
+		runtime:  runtime,
+		channels: channels,
+	}
+}
+
+// ServeHTTP implements http.Handler
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "only GET is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	report := Check(h.runtime, h.channels())
+	for _, channel := range report.Channels {
+		for _, drift := range channel.Drift {
+			logger.Warningf("[channel: %s] Runtime drifted from config (%s check): %s", channel.ChannelID, drift.Check, drift.Message)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(report); err != nil {
+		logger.Warningf("Failed writing consistency report: %s", err)
+	}
+}
@@ -0,0 +1,157 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package consistency
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/channelconfig"
+	mockconfig "github.com/hyperledger/fabric/common/mocks/config"
+	mockconfigtx "github.com/hyperledger/fabric/common/mocks/configtx"
+	"github.com/hyperledger/fabric/msp"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mockOrg struct {
+	mspID     string
+	endpoints []*cb.OrdererEndpoint
+}
+
+func (mo *mockOrg) Name() string                     { return mo.mspID }
+func (mo *mockOrg) MSPID() string                    { return mo.mspID }
+func (mo *mockOrg) Endpoints() []*cb.OrdererEndpoint { return mo.endpoints }
+
+type mockIdentity struct {
+	msp.Identity
+	validateErr error
+}
+
+func (mi *mockIdentity) Validate() error {
+	return mi.validateErr
+}
+
+// mockMSPManager deserializes the identities named by their bytes
+type mockMSPManager struct {
+	msp.MSPManager
+	identities map[string]*mockIdentity
+}
+
+func (mm *mockMSPManager) DeserializeIdentity(serialized []byte) (msp.Identity, error) {
+	identity, ok := mm.identities[string(serialized)]
+	if !ok {
+		return nil, errors.New("unknown MSP")
+	}
+	return identity, nil
+}
+
+func newChannel(channelID, consensusType string, org channelconfig.Org) Channel {
+	return Channel{
+		Resources: &mockconfig.Resources{
+			ConfigtxValidatorVal: &mockconfigtx.Validator{ChainIDVal: channelID, SequenceVal: 3},
+			OrdererConfigVal: &mockconfig.Orderer{
+				ConsensusTypeVal: "solo",
+				OrganizationsVal: map[string]channelconfig.Org{org.Name(): org},
+			},
+			ChannelConfigVal: &mockconfig.Channel{OrdererAddressesVal: []string{"orderer0:7050"}},
+			MSPManagerVal: &mockMSPManager{identities: map[string]*mockIdentity{
+				"valid":   {},
+				"expired": {validateErr: errors.New("certificate has expired")},
+			}},
+		},
+		ConsensusType: consensusType,
+	}
+}
+
+var runtime = Runtime{
+	LocalMSPID:    "OrdererMSP",
+	Identity:      []byte("valid"),
+	ListenAddress: "0.0.0.0:7050",
+	Consenters:    []string{"solo", "kafka"},
+}
+
+func TestCheckChannelConsistent(t *testing.T) {
+	report := CheckChannel(runtime, newChannel("foo", "solo", &mockOrg{mspID: "OrdererMSP"}))
+	assert.Equal(t, ChannelReport{ChannelID: "foo", ConfigSequence: 3}, report)
+}
+
+func TestCheckChannelDrift(t *testing.T) {
+	t.Run("Consensus", func(t *testing.T) {
+		rt := runtime
+		rt.Consenters = []string{"kafka"}
+		report := CheckChannel(rt, newChannel("foo", "kafka", &mockOrg{mspID: "OrdererMSP"}))
+		assert.Equal(t, []Drift{
+			{Check: ConsensusCheck, Message: "config requires consensus type solo but the chain is running kafka"},
+			{Check: ConsensusCheck, Message: "config requires consensus type solo which this orderer does not support"},
+		}, report.Drift)
+	})
+
+	t.Run("StaleMSP", func(t *testing.T) {
+		rt := runtime
+		rt.Identity = []byte("expired")
+		report := CheckChannel(rt, newChannel("foo", "solo", &mockOrg{mspID: "OrdererMSP"}))
+		require.Len(t, report.Drift, 1)
+		assert.Equal(t, MSPCheck, report.Drift[0].Check)
+		assert.Contains(t, report.Drift[0].Message, "certificate has expired")
+	})
+
+	t.Run("ForeignMSP", func(t *testing.T) {
+		report := CheckChannel(runtime, newChannel("foo", "solo", &mockOrg{mspID: "OtherMSP"}))
+		assert.Equal(t, []Drift{
+			{Check: MSPCheck, Message: "local MSP OrdererMSP is not an orderer organization of the channel"},
+		}, report.Drift)
+	})
+
+	t.Run("Endpoint", func(t *testing.T) {
+		rt := runtime
+		rt.ListenAddress = "0.0.0.0:7051"
+		report := CheckChannel(rt, newChannel("foo", "solo", &mockOrg{mspID: "OrdererMSP"}))
+		assert.Equal(t, []Drift{
+			{Check: EndpointCheck, Message: "listen address 0.0.0.0:7051 matches none of the advertised orderer addresses [orderer0:7050]"},
+		}, report.Drift)
+
+		org := &mockOrg{mspID: "OrdererMSP", endpoints: []*cb.OrdererEndpoint{{Address: "orderer1:7051"}}}
+		report = CheckChannel(rt, newChannel("foo", "solo", org))
+		assert.Empty(t, report.Drift, "the organization endpoints take precedence over the channel addresses")
+	})
+}
+
+func TestListensOnAny(t *testing.T) {
+	assert.True(t, listensOnAny(":7050", []string{"orderer0:7050"}))
+	assert.True(t, listensOnAny("10.0.0.1:7050", []string{"orderer0:8050", "10.0.0.1:7050"}))
+	assert.False(t, listensOnAny("10.0.0.1:7050", []string{"10.0.0.2:7050"}))
+	assert.False(t, listensOnAny("garbage", []string{"orderer0:7050"}))
+}
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(runtime, func() []Channel {
+		return []Channel{
+			newChannel("foo", "kafka", &mockOrg{mspID: "OrdererMSP"}),
+			newChannel("bar", "solo", &mockOrg{mspID: "OrdererMSP"}),
+		}
+	})
+
+	resp := httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/consistency", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	report := &Report{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), report))
+	assert.False(t, report.Consistent)
+	require.Len(t, report.Channels, 2)
+	assert.Equal(t, "bar", report.Channels[0].ChannelID)
+	assert.Empty(t, report.Channels[0].Drift)
+	assert.Len(t, report.Channels[1].Drift, 1)
+
+	resp = httptest.NewRecorder()
+	h.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/consistency", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
@@ -0,0 +1,175 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package corpus collects envelopes rejected as malformed into a bounded
+// directory, for operators debugging misbehaving clients and as seed
+// material for fuzzing the protobuf parsers.  Each distinct envelope is
+// stored once, as <sha256>.<kind>.env next to a <sha256>.<kind>.err file
+// holding the parse error, and the oldest entries are evicted once the
+// directory holds the maximum number of entries.
+package corpus
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/flogging"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/corpus"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+const (
+	envelopeSuffix = ".env"
+	errorSuffix    = ".err"
+)
+
+// Collector persists redacted copies of malformed envelopes.  A nil
+// Collector records nothing.
+type Collector struct {
+	dir        string
+	maxEntries int
+
+	mutex   sync.Mutex
+	entries []string // entry names, oldest first
+	known   map[string]struct{}
+}
+
+// NewCollector creates a Collector storing up to maxEntries envelopes in the
+// directory, which is created if needed.  Entries left in the directory by a
+// previous run count towards the maximum.
+func NewCollector(dir string, maxEntries int) (*Collector, error) {
+	if maxEntries <= 0 {
+		return nil, errors.Errorf("maximum number of corpus entries must be positive, got %d", maxEntries)
+	}
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, errors.Wrapf(err, "failed creating corpus directory %s", dir)
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading corpus directory %s", dir)
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+
+	c := &Collector{
+		dir:        dir,
+		maxEntries: maxEntries,
+		known:      make(map[string]struct{}),
+	}
+	for _, file := range files {
+		if name := strings.TrimSuffix(file.Name(), envelopeSuffix); name != file.Name() {
+			c.entries = append(c.entries, name)
+			c.known[name] = struct{}{}
+		}
+	}
+	return c, nil
+}
+
+// Record stores a redacted copy of an envelope of the given kind, such as
+// broadcast or deliver, which was rejected with the parse error.  Failures
+// to store the envelope are logged and otherwise ignored.
+func (c *Collector) Record(kind string, env *cb.Envelope, parseErr error) {
+	if c == nil || env == nil {
+		return
+	}
+	data, err := proto.Marshal(Redact(env))
+	if err != nil {
+		logger.Warningf("Failed marshaling malformed %s envelope: %s", kind, err)
+		return
+	}
+	digest := sha256.Sum256(data)
+	name := fmt.Sprintf("%s.%s", hex.EncodeToString(digest[:]), kind)
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if _, ok := c.known[name]; ok {
+		return
+	}
+	for len(c.entries) >= c.maxEntries {
+		c.remove(c.entries[0])
+		c.entries = c.entries[1:]
+	}
+	path := filepath.Join(c.dir, name)
+	if err := ioutil.WriteFile(path+errorSuffix, []byte(parseErr.Error()+"\n"), 0640); err != nil {
+		logger.Warningf("Failed writing malformed %s envelope to corpus: %s", kind, err)
+		return
+	}
+	if err := ioutil.WriteFile(path+envelopeSuffix, data, 0640); err != nil {
+		logger.Warningf("Failed writing malformed %s envelope to corpus: %s", kind, err)
+		os.Remove(path + errorSuffix)
+		return
+	}
+	c.entries = append(c.entries, name)
+	c.known[name] = struct{}{}
+	logger.Debugf("Recorded malformed %s envelope as %s", kind, name)
+}
+
+// remove must be called with the mutex held
+func (c *Collector) remove(name string) {
+	path := filepath.Join(c.dir, name)
+	for _, suffix := range []string{envelopeSuffix, errorSuffix} {
+		if err := os.Remove(path + suffix); err != nil && !os.IsNotExist(err) {
+			logger.Warningf("Failed evicting corpus entry %s: %s", name, err)
+		}
+	}
+	delete(c.known, name)
+}
+
+// Redact returns a copy of the envelope stripped of its signature and, when
+// the payload header can be parsed, of the identity and nonce of its
+// creator, which are replaced by their SHA-256 hashes.  The channel header
+// and payload data are kept byte for byte, so that the copy reproduces the
+// parse error of the original.
+func Redact(env *cb.Envelope) *cb.Envelope {
+	redacted := &cb.Envelope{Payload: env.Payload}
+
+	payload := &cb.Payload{}
+	if err := proto.Unmarshal(env.Payload, payload); err != nil || payload.Header == nil {
+		return redacted
+	}
+	sigHdr := &cb.SignatureHeader{}
+	if err := proto.Unmarshal(payload.Header.SignatureHeader, sigHdr); err != nil {
+		return redacted
+	}
+	sigHdr.Creator = hash(sigHdr.Creator)
+	sigHdr.Nonce = hash(sigHdr.Nonce)
+	sigHdrBytes, err := proto.Marshal(sigHdr)
+	if err != nil {
+		return redacted
+	}
+	payload.Header.SignatureHeader = sigHdrBytes
+	payloadBytes, err := proto.Marshal(payload)
+	if err != nil {
+		return redacted
+	}
+	redacted.Payload = payloadBytes
+	return redacted
+}
+
+func hash(data []byte) []byte {
+	if len(data) == 0 {
+		return data
+	}
+	digest := sha256.Sum256(data)
+	return digest[:]
+}
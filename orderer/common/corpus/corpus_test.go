@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package corpus
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func marshal(t *testing.T, msg proto.Message) []byte {
+	data, err := proto.Marshal(msg)
+	require.NoError(t, err)
+	return data
+}
+
+func corpusFiles(t *testing.T, dir, pattern string) []string {
+	files, err := filepath.Glob(filepath.Join(dir, pattern))
+	require.NoError(t, err)
+	return files
+}
+
+func TestRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "corpus")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	c, err := NewCollector(dir, 2)
+	require.NoError(t, err)
+
+	c.Record("broadcast", &cb.Envelope{Payload: []byte("garbage-1"), Signature: []byte("sig")}, errors.New("bad payload"))
+	c.Record("broadcast", &cb.Envelope{Payload: []byte("garbage-1"), Signature: []byte("other-sig")}, errors.New("bad payload"))
+	envFiles := corpusFiles(t, dir, "*.broadcast.env")
+	require.Len(t, envFiles, 1, "envelopes differing only in their signature should be stored once")
+
+	stored := &cb.Envelope{}
+	require.NoError(t, proto.Unmarshal(readFile(t, envFiles[0]), stored))
+	assert.Equal(t, []byte("garbage-1"), stored.Payload)
+	assert.Empty(t, stored.Signature)
+	errFile := envFiles[0][:len(envFiles[0])-len(envelopeSuffix)] + errorSuffix
+	assert.Equal(t, "bad payload\n", string(readFile(t, errFile)))
+
+	c.Record("deliver", &cb.Envelope{Payload: []byte("garbage-2")}, errors.New("bad seek info"))
+	c.Record("deliver", &cb.Envelope{Payload: []byte("garbage-3")}, errors.New("bad seek info"))
+	assert.Empty(t, corpusFiles(t, dir, "*.broadcast.*"), "the oldest entry should be evicted")
+	assert.Len(t, corpusFiles(t, dir, "*.env"), 2)
+	assert.Len(t, corpusFiles(t, dir, "*.err"), 2)
+
+	// Entries from a previous run count towards the maximum
+	c, err = NewCollector(dir, 2)
+	require.NoError(t, err)
+	c.Record("deliver", &cb.Envelope{Payload: []byte("garbage-4")}, errors.New("bad seek info"))
+	assert.Len(t, corpusFiles(t, dir, "*.env"), 2)
+
+	var nilCollector *Collector
+	nilCollector.Record("deliver", &cb.Envelope{}, errors.New("ignored"))
+}
+
+func TestNewCollectorErrors(t *testing.T) {
+	_, err := NewCollector("/nonexistent", 0)
+	assert.EqualError(t, err, "maximum number of corpus entries must be positive, got 0")
+}
+
+func TestRedact(t *testing.T) {
+	env := &cb.Envelope{
+		Payload: marshal(t, &cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader: []byte("malformed-channel-header"),
+				SignatureHeader: marshal(t, &cb.SignatureHeader{
+					Creator: []byte("creator"),
+					Nonce:   []byte("nonce"),
+				}),
+			},
+			Data: []byte("data"),
+		}),
+		Signature: []byte("signature"),
+	}
+
+	redacted := Redact(env)
+	assert.Empty(t, redacted.Signature)
+	payload := &cb.Payload{}
+	require.NoError(t, proto.Unmarshal(redacted.Payload, payload))
+	assert.Equal(t, []byte("malformed-channel-header"), payload.Header.ChannelHeader)
+	assert.Equal(t, []byte("data"), payload.Data)
+	sigHdr := &cb.SignatureHeader{}
+	require.NoError(t, proto.Unmarshal(payload.Header.SignatureHeader, sigHdr))
+	assert.Equal(t, hash([]byte("creator")), sigHdr.Creator)
+	assert.Equal(t, hash([]byte("nonce")), sigHdr.Nonce)
+	assert.Equal(t, []byte("signature"), env.Signature, "the original envelope should not be modified")
+
+	assert.Equal(t, []byte("garbage"), Redact(&cb.Envelope{Payload: []byte("garbage")}).Payload)
+}
+
+func readFile(t *testing.T, path string) []byte {
+	data, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	return data
+}
@@ -0,0 +1,183 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package crash reports the panics recovered in the service handlers of the
+// orderer as structured crash reports, so that a message triggering a bug
+// fails its own request with INTERNAL_SERVER_ERROR and leaves a trace of what
+// was handled, rather than being noticed only by a line in the log.
+package crash
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/flogging"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/op/go-logging"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const pkgLogID = "orderer/common/crash"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// Report is the structured report of a panic recovered in a service handler
+type Report struct {
+	ID      string       `json:"id"`
+	Time    time.Time    `json:"time"`
+	Service string       `json:"service"`
+	Remote  string       `json:"remote,omitempty"`
+	Message *MessageInfo `json:"message,omitempty"`
+	Panic   string       `json:"panic"`
+	Stack   string       `json:"stack"`
+}
+
+// Info is the text returned to the client whose request triggered the panic,
+// letting operators find the report from the client's error
+func (r *Report) Info() string {
+	return "internal error, crash report " + r.ID
+}
+
+// MessageInfo describes the message being handled when the panic occurred,
+// from its headers only: neither its payload nor its signatures are reported.
+type MessageInfo struct {
+	ChannelID   string `json:"channel_id,omitempty"`
+	TxID        string `json:"tx_id,omitempty"`
+	Type        string `json:"type,omitempty"`
+	CreatorMSP  string `json:"creator_msp,omitempty"`
+	PayloadSize int    `json:"payload_size"`
+	ParseError  string `json:"parse_error,omitempty"`
+}
+
+// Describe returns the description of the message, nil if there is none.
+// It never panics, whatever the message.
+func Describe(msg *cb.Envelope) (info *MessageInfo) {
+	if msg == nil {
+		return nil
+	}
+	info = &MessageInfo{PayloadSize: len(msg.Payload)}
+	defer func() {
+		if r := recover(); r != nil {
+			info.ParseError = fmt.Sprintf("panic parsing the headers: %v", r)
+		}
+	}()
+	payload, err := utils.UnmarshalPayload(msg.Payload)
+	if err != nil {
+		info.ParseError = err.Error()
+		return info
+	}
+	if payload.Header == nil {
+		info.ParseError = "missing header"
+		return info
+	}
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		info.ParseError = err.Error()
+		return info
+	}
+	info.ChannelID = chdr.ChannelId
+	info.TxID = chdr.TxId
+	info.Type = cb.HeaderType(chdr.Type).String()
+	shdr, err := utils.GetSignatureHeader(payload.Header.SignatureHeader)
+	if err != nil {
+		info.ParseError = err.Error()
+		return info
+	}
+	creator := &msp.SerializedIdentity{}
+	if err := proto.Unmarshal(shdr.Creator, creator); err == nil {
+		info.CreatorMSP = creator.Mspid
+	}
+	return info
+}
+
+// Reporter logs the crash reports and counts them.  A nil Reporter logs the
+// reports without counting them.
+type Reporter struct {
+	panics *prometheus.CounterVec
+}
+
+// NewReporter creates a Reporter whose metric is registered with the
+// registerer.
+func NewReporter(registerer prometheus.Registerer) (*Reporter, error) {
+	r := &Reporter{
+		panics: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "orderer",
+			Subsystem: "crash",
+			Name:      "panics_total",
+			Help:      "The number of panics recovered in service handlers, by service.",
+		}, []string{"service"}),
+	}
+	if err := registerer.Register(r.panics); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Report logs and counts the report of the panic value recovered while the
+// service handled msg, which may be nil, for the remote client.  It must be
+// called by the deferred function recovering the panic, for the stack
+// reported to lead to the panic.
+func (r *Reporter) Report(service, remote string, msg *cb.Envelope, value interface{}) *Report {
+	report := &Report{
+		ID:      newID(),
+		Time:    time.Now(),
+		Service: service,
+		Remote:  remote,
+		Message: Describe(msg),
+		Panic:   fmt.Sprint(value),
+		Stack:   string(debug.Stack()),
+	}
+	data, err := json.Marshal(report)
+	if err != nil {
+		logger.Criticalf("%s handler triggered panic: %s\n%s", service, report.Panic, report.Stack)
+	} else {
+		logger.Criticalf("%s handler triggered panic, crash report: %s", service, data)
+	}
+	if r != nil {
+		r.panics.WithLabelValues(service).Inc()
+	}
+	return report
+}
+
+// Handler serves HTTP requests with next, replying with 500 Internal Server
+// Error and a crash report when next panics.  The panics aborting the
+// handler on purpose, with http.ErrAbortHandler, are left to the server.
+func (r *Reporter) Handler(service string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		defer func() {
+			value := recover()
+			if value == nil {
+				return
+			}
+			if value == http.ErrAbortHandler {
+				panic(value)
+			}
+			report := r.Report(service, req.RemoteAddr, nil, value)
+			http.Error(w, report.Info(), http.StatusInternalServerError)
+		}()
+		next.ServeHTTP(w, req)
+	})
+}
+
+func newID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(id)
+}
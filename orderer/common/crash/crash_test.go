@@ -0,0 +1,129 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package crash
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func panicCount(t *testing.T, registry *prometheus.Registry, service string) float64 {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "orderer_crash_panics_total" {
+			continue
+		}
+		for _, m := range family.Metric {
+			if m.Label[0].GetValue() == service {
+				return m.Counter.GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestDescribe(t *testing.T) {
+	assert.Nil(t, Describe(nil))
+
+	env := &cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
+					Type:      int32(cb.HeaderType_ENDORSER_TRANSACTION),
+					ChannelId: "mychannel",
+					TxId:      "tx1",
+				}),
+				SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{
+					Creator: utils.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: []byte("cert")}),
+				}),
+			},
+			Data: []byte("secret"),
+		}),
+		Signature: []byte("signature"),
+	}
+	assert.Equal(t, &MessageInfo{
+		ChannelID:   "mychannel",
+		TxID:        "tx1",
+		Type:        "ENDORSER_TRANSACTION",
+		CreatorMSP:  "Org1MSP",
+		PayloadSize: len(env.Payload),
+	}, Describe(env))
+
+	info := Describe(&cb.Envelope{Payload: []byte("garbage")})
+	assert.Equal(t, len("garbage"), info.PayloadSize)
+	assert.NotEmpty(t, info.ParseError)
+	assert.Equal(t, "missing header", Describe(&cb.Envelope{Payload: utils.MarshalOrPanic(&cb.Payload{})}).ParseError)
+}
+
+func TestReport(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	reporter, err := NewReporter(registry)
+	require.NoError(t, err)
+
+	var report *Report
+	func() {
+		defer func() {
+			report = reporter.Report("Broadcast", "1.2.3.4:5678", &cb.Envelope{Payload: []byte("garbage")}, recover())
+		}()
+		panic("bug")
+	}()
+	assert.Equal(t, "Broadcast", report.Service)
+	assert.Equal(t, "1.2.3.4:5678", report.Remote)
+	assert.Equal(t, "bug", report.Panic)
+	assert.Contains(t, report.Stack, "TestReport")
+	assert.Equal(t, len("garbage"), report.Message.PayloadSize)
+	assert.Len(t, report.ID, 16)
+	assert.Equal(t, "internal error, crash report "+report.ID, report.Info())
+	assert.Equal(t, float64(1), panicCount(t, registry, "Broadcast"))
+
+	_, err = NewReporter(registry)
+	assert.Error(t, err, "Registering the metric twice should fail")
+
+	// a nil reporter reports without counting
+	report = (*Reporter)(nil).Report("Deliver", "", nil, "bug")
+	assert.Nil(t, report.Message)
+}
+
+func TestHandler(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	reporter, err := NewReporter(registry)
+	require.NoError(t, err)
+
+	handler := reporter.Handler("operations /bug", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic("bug")
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/bug", nil))
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Contains(t, rec.Body.String(), "crash report")
+	assert.Equal(t, float64(1), panicCount(t, registry, "operations /bug"))
+
+	handler = reporter.Handler("operations /ok", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/ok", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	// the panics aborting the handler are left to the server
+	handler = reporter.Handler("operations /abort", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+	assert.Panics(t, func() {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/abort", nil))
+	})
+	assert.Equal(t, float64(0), panicCount(t, registry, "operations /abort"))
+}
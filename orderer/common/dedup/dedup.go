@@ -0,0 +1,105 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package dedup remembers the transaction IDs of the messages recently
+// enqueued by the broadcast service, so that a message submitted again,
+// typically by a client retrying with the transaction ID derived from its
+// idempotency key, is acknowledged without being ordered twice.
+package dedup
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Cache remembers up to a fixed number of transaction IDs per channel,
+// forgetting the oldest ones first, and optionally for a limited time only.
+type Cache struct {
+	size int
+	ttl  time.Duration
+	now  func() time.Time
+
+	mutex    sync.Mutex
+	channels map[string]*window
+}
+
+// window is a ring of the transaction IDs of a channel, the slots of the
+// removed ones being empty
+type window struct {
+	slots []entry
+	next  int
+	ids   map[string]int
+}
+
+// entry is a transaction ID and the time it was recorded
+type entry struct {
+	txID  string
+	added time.Time
+}
+
+// NewCache creates a Cache of size transaction IDs per channel, each
+// remembered for the ttl, or until evicted if the ttl is 0.
+func NewCache(size int, ttl time.Duration) (*Cache, error) {
+	if size <= 0 {
+		return nil, errors.Errorf("cache size must be positive, got %d", size)
+	}
+	if ttl < 0 {
+		return nil, errors.Errorf("cache TTL must not be negative, got %s", ttl)
+	}
+	return &Cache{
+		size:     size,
+		ttl:      ttl,
+		now:      time.Now,
+		channels: map[string]*window{},
+	}, nil
+}
+
+// Add records the transaction ID of a message about to be enqueued on the
+// channel, and returns false if it is recorded already.
+func (c *Cache) Add(channelID, txID string) bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	w, ok := c.channels[channelID]
+	if !ok {
+		w = &window{slots: make([]entry, c.size), ids: map[string]int{}}
+		c.channels[channelID] = w
+	}
+	now := c.now()
+	if slot, exists := w.ids[txID]; exists {
+		if c.ttl == 0 || now.Sub(w.slots[slot].added) < c.ttl {
+			return false
+		}
+		//记录已过期，视为新消息
+		w.slots[slot] = entry{}
+	}
+
+	if evicted := w.slots[w.next]; evicted.txID != "" {
+		delete(w.ids, evicted.txID)
+	}
+	w.slots[w.next] = entry{txID: txID, added: now}
+	w.ids[txID] = w.next
+	w.next = (w.next + 1) % c.size
+	return true
+}
+
+// Remove forgets the transaction ID of a message which could not be
+// enqueued, so that it can be submitted again.
+func (c *Cache) Remove(channelID, txID string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	w, ok := c.channels[channelID]
+	if !ok {
+		return
+	}
+	if slot, exists := w.ids[txID]; exists {
+		delete(w.ids, txID)
+		w.slots[slot] = entry{}
+	}
+}
@@ -0,0 +1,78 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package dedup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCache(t *testing.T) {
+	_, err := NewCache(0, 0)
+	assert.EqualError(t, err, "cache size must be positive, got 0")
+}
+
+func TestCache(t *testing.T) {
+	c, err := NewCache(2, 0)
+	require.NoError(t, err)
+
+	assert.True(t, c.Add("mychannel", "tx1"))
+	assert.False(t, c.Add("mychannel", "tx1"))
+
+	// channels are independent
+	assert.True(t, c.Add("otherchannel", "tx1"))
+
+	// the oldest transaction ID is forgotten first
+	assert.True(t, c.Add("mychannel", "tx2"))
+	assert.True(t, c.Add("mychannel", "tx3"))
+	assert.False(t, c.Add("mychannel", "tx2"))
+	assert.True(t, c.Add("mychannel", "tx1"))
+
+	// a removed transaction ID can be added again
+	c.Remove("mychannel", "tx1")
+	c.Remove("unknownchannel", "tx1")
+	assert.True(t, c.Add("mychannel", "tx1"))
+	assert.False(t, c.Add("mychannel", "tx1"))
+}
+
+func TestCacheRemoveThenEvict(t *testing.T) {
+	c, err := NewCache(2, 0)
+	require.NoError(t, err)
+
+	assert.True(t, c.Add("mychannel", "tx1"))
+	c.Remove("mychannel", "tx1")
+	assert.True(t, c.Add("mychannel", "tx2"))
+	assert.True(t, c.Add("mychannel", "tx1"))
+
+	// tx2 is the oldest once tx1 is added again
+	assert.True(t, c.Add("mychannel", "tx3"))
+	assert.False(t, c.Add("mychannel", "tx1"))
+}
+
+func TestCacheTTL(t *testing.T) {
+	_, err := NewCache(2, -time.Second)
+	assert.EqualError(t, err, "cache TTL must not be negative, got -1s")
+
+	c, err := NewCache(2, time.Minute)
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	c.now = func() time.Time { return now }
+
+	assert.True(t, c.Add("mychannel", "tx1"))
+	now = now.Add(30 * time.Second)
+	assert.False(t, c.Add("mychannel", "tx1"))
+	assert.True(t, c.Add("mychannel", "tx2"))
+
+	// 过期的交易ID重新提交时被视为新消息
+	now = now.Add(30 * time.Second)
+	assert.True(t, c.Add("mychannel", "tx1"))
+	assert.False(t, c.Add("mychannel", "tx1"))
+	assert.False(t, c.Add("mychannel", "tx2"))
+}
@@ -0,0 +1,241 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package embargo holds back the new blocks of embargoed channels from the
+// deliver clients which are not consenters, for a delay after their commit
+// and until a number of later blocks are committed, supporting the regulatory
+// embargoes of channels carrying market data.  The consenters of the channels
+// sync immediately.
+package embargo
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/op/go-logging"
+)
+
+const pkgLogID = "orderer/common/embargo"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// blockBuffer is the number of committed blocks the embargo may lag behind
+const blockBuffer = 1000
+
+// Channel is the embargo of a channel
+type Channel struct {
+	// ChannelID is the channel embargoed
+	ChannelID string
+
+	// Delay is how long after its commit a block is held back
+	Delay time.Duration
+
+	// Blocks is the number of blocks committed after a block until it is
+	// released
+	Blocks uint64
+}
+
+// ChannelHeights reports the height of the ledgers of the channels
+type ChannelHeights interface {
+	// Height returns the height of the channel, or false if the channel
+	// does not exist
+	Height(channelID string) (uint64, bool)
+}
+
+type commit struct {
+	number uint64
+	time   time.Time
+}
+
+type waiter struct {
+	number   uint64
+	released chan struct{}
+	timer    *time.Timer
+}
+
+type channel struct {
+	Channel
+
+	height   uint64   // the height of the ledger, as far as known
+	observed uint64   // the height of the ledger, as far as followed
+	known    uint64   // the blocks below were committed before the embargo was created
+	expired  uint64   // the blocks below were followed longer than the delay ago
+	commits  []commit // the blocks committed within the delay, oldest first
+	waiters  map[*waiter]struct{}
+}
+
+// Embargo holds back the new blocks of the embargoed channels.  The commit
+// times of the blocks are learnt by following the committed blocks, so the
+// blocks committed before the embargo was created are held back as if
+// committed at that time, and a block missed while lagging behind as if
+// committed with the next block followed.
+type Embargo struct {
+	started time.Time
+	now     func() time.Time
+
+	mutex    sync.Mutex
+	channels map[string]*channel
+}
+
+// New creates an Embargo of the channels, whose current heights are those
+// of the ledgers.
+func New(channels []Channel, heights ChannelHeights) *Embargo {
+	e := &Embargo{
+		started:  time.Now(),
+		now:      time.Now,
+		channels: map[string]*channel{},
+	}
+	for _, c := range channels {
+		height, _ := heights.Height(c.ChannelID)
+		e.channels[c.ChannelID] = &channel{
+			Channel:  c,
+			height:   height,
+			observed: height,
+			known:    height,
+			waiters:  map[*waiter]struct{}{},
+		}
+	}
+	return e
+}
+
+// Hold returns a channel closed once the block with the given number of the
+// channel, whose ledger has the given height, may be delivered to the clients
+// which are not consenters, and the function to call once the block is no
+// longer awaited.  It returns a nil channel if the block may be delivered at
+// once.
+func (e *Embargo) Hold(channelID string, number, height uint64) (<-chan struct{}, func()) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	ch, ok := e.channels[channelID]
+	if !ok {
+		return nil, func() {}
+	}
+	if height > ch.height {
+		ch.height = height
+	}
+	w := &waiter{number: number, released: make(chan struct{})}
+	if e.schedule(ch, w) {
+		return nil, func() {}
+	}
+	logger.Debugf("[channel: %s] Holding back block %d", channelID, number)
+	return w.released, func() { e.cancel(ch, w) }
+}
+
+// schedule releases the waiter if its block may be delivered, and returns
+// true, or else waits for its release, on a timer if the block is only held
+// back by its delay or for more blocks otherwise.  It must be called with the
+// mutex held.
+func (e *Embargo) schedule(ch *channel, w *waiter) bool {
+	releaseAt, ok := e.releaseTime(ch, w.number)
+	if !ok {
+		ch.waiters[w] = struct{}{}
+		return false
+	}
+	delete(ch.waiters, w)
+	wait := releaseAt.Sub(e.now())
+	if wait <= 0 {
+		close(w.released)
+		return true
+	}
+	w.timer = time.AfterFunc(wait, func() { close(w.released) })
+	return false
+}
+
+// cancel forgets the waiter, if it was not released already
+func (e *Embargo) cancel(ch *channel, w *waiter) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	delete(ch.waiters, w)
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+}
+
+// releaseTime returns when the block may be delivered, or false if more
+// blocks must be committed or followed to know it.  It must be called with
+// the mutex held.
+func (e *Embargo) releaseTime(ch *channel, number uint64) (time.Time, bool) {
+	if ch.height <= number || ch.height-number-1 < ch.Blocks {
+		return time.Time{}, false
+	}
+	if ch.Delay <= 0 {
+		return time.Time{}, true
+	}
+	if number < ch.known {
+		return e.started.Add(ch.Delay), true
+	}
+	if number >= ch.observed {
+		// committed, but not followed yet
+		return time.Time{}, false
+	}
+	if number < ch.expired {
+		return time.Time{}, true
+	}
+	// the first block followed at or after the block was committed no
+	// earlier than it, a block missed while lagging behind
+	i := sort.Search(len(ch.commits), func(i int) bool { return ch.commits[i].number >= number })
+	return ch.commits[i].time.Add(ch.Delay), true
+}
+
+// Committed records the commit of the block of the channel, and releases
+// the waiters of the blocks it releases.
+func (e *Embargo) Committed(channelID string, block *cb.Block) {
+	if block.Header == nil {
+		return
+	}
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	ch, ok := e.channels[channelID]
+	if !ok {
+		return
+	}
+	now := e.now()
+	number := block.Header.Number
+	if number+1 > ch.observed {
+		ch.observed = number + 1
+	}
+	if number+1 > ch.height {
+		ch.height = number + 1
+	}
+	ch.commits = append(ch.commits, commit{number: number, time: now})
+	for len(ch.commits) > 0 && now.Sub(ch.commits[0].time) >= ch.Delay {
+		ch.expired = ch.commits[0].number + 1
+		ch.commits = ch.commits[1:]
+	}
+	for w := range ch.waiters {
+		e.schedule(ch, w)
+	}
+}
+
+// Follow records the commits of the blocks of the embargoed channels
+// published by the multicaster, until the multicaster is closed.
+func (e *Embargo) Follow(blocks *fanout.Multicaster) {
+	for channelID := range e.channels {
+		go e.follow(blocks, channelID)
+	}
+}
+
+func (e *Embargo) follow(blocks *fanout.Multicaster, channelID string) {
+	for {
+		sub := blocks.Subscribe(channelID, blockBuffer)
+		for block := range sub.Blocks() {
+			e.Committed(channelID, block)
+		}
+		if sub.Err() != fanout.ErrSlowSubscriber {
+			logger.Infof("[channel: %s] Embargo stopped following blocks: %s", channelID, sub.Err())
+			return
+		}
+		logger.Warningf("[channel: %s] Embargo fell behind the committed blocks, resubscribing: %s", channelID, sub.Err())
+	}
+}
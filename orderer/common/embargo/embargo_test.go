@@ -0,0 +1,153 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package embargo
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/orderer/common/fanout"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func block(number uint64) *cb.Block {
+	return &cb.Block{Header: &cb.BlockHeader{Number: number}}
+}
+
+func isReleased(held <-chan struct{}) bool {
+	select {
+	case <-held:
+		return true
+	default:
+		return false
+	}
+}
+
+type heights map[string]uint64
+
+func (h heights) Height(channelID string) (uint64, bool) {
+	height, ok := h[channelID]
+	return height, ok
+}
+
+func newTestEmbargo(h heights, channels ...Channel) (*Embargo, *time.Time) {
+	now := time.Unix(1500000000, 0)
+	e := New(channels, h)
+	e.started = now
+	e.now = func() time.Time { return now }
+	return e, &now
+}
+
+func TestNotEmbargoed(t *testing.T) {
+	e, _ := newTestEmbargo(heights{"mychannel": 11}, Channel{ChannelID: "embargoed", Blocks: 5})
+
+	held, release := e.Hold("mychannel", 10, 11)
+	assert.Nil(t, held)
+	release()
+}
+
+func TestBlocks(t *testing.T) {
+	e, _ := newTestEmbargo(heights{}, Channel{ChannelID: "mychannel", Blocks: 2})
+
+	// released once 2 blocks are committed after it
+	held, _ := e.Hold("mychannel", 10, 13)
+	assert.Nil(t, held)
+
+	held, release := e.Hold("mychannel", 12, 13)
+	require.NotNil(t, held)
+	e.Committed("mychannel", block(13))
+	assert.False(t, isReleased(held))
+	e.Committed("mychannel", block(14))
+	assert.True(t, isReleased(held))
+	release()
+
+	// the waiters whose client is gone are forgotten
+	_, release = e.Hold("mychannel", 20, 15)
+	release()
+	assert.Empty(t, e.channels["mychannel"].waiters)
+
+	// the blocks of other channels do not count
+	held, _ = e.Hold("mychannel", 14, 15)
+	e.Committed("otherchannel", block(15))
+	e.Committed("otherchannel", block(16))
+	assert.False(t, isReleased(held))
+}
+
+func TestDelay(t *testing.T) {
+	e, now := newTestEmbargo(heights{"mychannel": 10}, Channel{ChannelID: "mychannel", Delay: time.Minute})
+
+	// the blocks committed before the embargo was created are held back as
+	// if committed then
+	*now = now.Add(30 * time.Second)
+	held, release := e.Hold("mychannel", 5, 10)
+	require.NotNil(t, held)
+	release()
+	*now = now.Add(30 * time.Second)
+	held, _ = e.Hold("mychannel", 5, 10)
+	assert.Nil(t, held)
+
+	// a block committed but not followed yet is held back until followed
+	held, release = e.Hold("mychannel", 10, 11)
+	require.NotNil(t, held)
+	e.Committed("mychannel", block(10))
+	assert.False(t, isReleased(held))
+	release()
+
+	// and then for the delay after
+	*now = now.Add(59 * time.Second)
+	held, _ = e.Hold("mychannel", 10, 11)
+	require.NotNil(t, held)
+	*now = now.Add(time.Second)
+	held, _ = e.Hold("mychannel", 10, 11)
+	assert.Nil(t, held)
+
+	// a block followed longer than the delay ago is forgotten
+	e.Committed("mychannel", block(11))
+	assert.Len(t, e.channels["mychannel"].commits, 1)
+	held, _ = e.Hold("mychannel", 10, 12)
+	assert.Nil(t, held)
+}
+
+func TestDelayTimer(t *testing.T) {
+	e := New([]Channel{{ChannelID: "mychannel", Delay: 50 * time.Millisecond, Blocks: 1}}, heights{})
+
+	held, release := e.Hold("mychannel", 0, 1)
+	defer release()
+	require.NotNil(t, held)
+	e.Committed("mychannel", block(0))
+	e.Committed("mychannel", block(1))
+	assert.False(t, isReleased(held))
+
+	select {
+	case <-held:
+	case <-time.After(5 * time.Second):
+		t.Fatal("block not released after its delay")
+	}
+}
+
+func TestFollow(t *testing.T) {
+	e := New([]Channel{{ChannelID: "mychannel", Blocks: 1}}, heights{})
+	blocks := fanout.New()
+	e.Follow(blocks)
+
+	held, release := e.Hold("mychannel", 0, 1)
+	defer release()
+	require.NotNil(t, held)
+	for blocks.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	blocks.Publish("mychannel", block(1))
+
+	select {
+	case <-held:
+	case <-time.After(5 * time.Second):
+		t.Fatal("block not released by the committed block")
+	}
+	blocks.Close()
+}
@@ -0,0 +1,237 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package fairqueue schedules the messages enqueued for ordering across the
+// channels of the orderer with weighted fair queuing, so that a channel
+// flooding the orderer cannot monopolize its ordering throughput.  As many
+// messages as the concurrency of the scheduler are passed to the consenters
+// at once; the others wait in a queue per channel, and whenever a consenter
+// accepts a message, the next one is taken from the channel whose share of
+// the throughput is the most behind its weight.
+package fairqueue
+
+import (
+	"container/heap"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// DefaultConcurrency is the number of messages passed to the consenters at
+// once when no concurrency is configured
+const DefaultConcurrency = 16
+
+// DefaultQueueDepth is the number of messages a channel of weight 1 may
+// queue when no queue depth is configured
+const DefaultQueueDepth = 1000
+
+// ErrQueueFull is the cause of the errors returned for the messages of a
+// channel which has its quota of messages queued already
+var ErrQueueFull = errors.New("ingress queue of the channel is full")
+
+// Config contains the configuration of a Scheduler.
+type Config struct {
+	// Concurrency is the number of messages passed to the consenters at
+	// once, DefaultConcurrency if 0
+	Concurrency int
+
+	// QueueDepth is the number of messages a channel may queue for each
+	// unit of its weight, DefaultQueueDepth if 0
+	QueueDepth int
+}
+
+// request is a message waiting for its turn to be passed to its consenter
+type request struct {
+	channel *channel
+	start   float64
+	finish  float64
+	seq     uint64
+	ready   chan struct{}
+	index   int
+}
+
+// channel keeps the scheduling state of a channel
+type channel struct {
+	id     string
+	queued int
+	// finish is the virtual finish time of the latest message of the channel
+	finish float64
+}
+
+// requests is the heap of the queued messages by virtual finish time, the
+// earliest queued first among equal times
+type requests []*request
+
+func (r requests) Len() int { return len(r) }
+func (r requests) Less(i, j int) bool {
+	if r[i].finish != r[j].finish {
+		return r[i].finish < r[j].finish
+	}
+	return r[i].seq < r[j].seq
+}
+func (r requests) Swap(i, j int) {
+	r[i], r[j] = r[j], r[i]
+	r[i].index, r[j].index = i, j
+}
+func (r *requests) Push(x interface{}) {
+	req := x.(*request)
+	req.index = len(*r)
+	*r = append(*r, req)
+}
+func (r *requests) Pop() interface{} {
+	old := *r
+	req := old[len(old)-1]
+	req.index = -1
+	*r = old[:len(old)-1]
+	return req
+}
+
+// Scheduler passes the messages of the channels to their consenters in
+// weighted fair order.  A nil Scheduler passes messages at once.
+type Scheduler struct {
+	concurrency int
+	queueDepth  int
+
+	mutex    sync.Mutex
+	active   int
+	seq      uint64
+	virtual  float64
+	queue    requests
+	channels map[string]*channel
+}
+
+// New creates a Scheduler
+func New(conf Config) *Scheduler {
+	if conf.Concurrency <= 0 {
+		conf.Concurrency = DefaultConcurrency
+	}
+	if conf.QueueDepth <= 0 {
+		conf.QueueDepth = DefaultQueueDepth
+	}
+	return &Scheduler{
+		concurrency: conf.Concurrency,
+		queueDepth:  conf.QueueDepth,
+		channels:    map[string]*channel{},
+	}
+}
+
+// Schedule calls enqueue, which passes a message of the channel of the
+// given weight to its consenter, once the message has its turn, and returns
+// the error of enqueue.  It returns an error without calling enqueue if the
+// channel has its quota of messages queued or if the context is done before
+// the message has its turn.
+func (s *Scheduler) Schedule(ctx context.Context, channelID string, weight uint32, enqueue func() error) error {
+	if s == nil {
+		return enqueue()
+	}
+	if weight == 0 {
+		weight = 1
+	}
+
+	s.mutex.Lock()
+	ch, ok := s.channels[channelID]
+	if !ok {
+		ch = &channel{id: channelID}
+		s.channels[channelID] = ch
+	}
+	//消息的虚拟完成时间由通道权重决定，权重越大消息间隔越小
+	start := s.virtual
+	if ch.finish > start {
+		start = ch.finish
+	}
+	finish := start + 1/float64(weight)
+
+	if s.active < s.concurrency && len(s.queue) == 0 {
+		ch.finish = finish
+		s.advance(start)
+		s.active++
+		s.mutex.Unlock()
+		defer s.done()
+		return enqueue()
+	}
+
+	if ch.queued >= s.queueDepth*int(weight) {
+		s.forget(ch)
+		s.mutex.Unlock()
+		return errors.Wrapf(ErrQueueFull, "channel %s has %d messages queued", channelID, s.queueDepth*int(weight))
+	}
+	ch.finish = finish
+	ch.queued++
+	s.seq++
+	req := &request{channel: ch, start: start, finish: finish, seq: s.seq, ready: make(chan struct{})}
+	heap.Push(&s.queue, req)
+	s.mutex.Unlock()
+
+	select {
+	case <-req.ready:
+	case <-ctx.Done():
+		s.mutex.Lock()
+		if req.index >= 0 {
+			//消息仍在排队，撤回后其余消息的顺序不变
+			heap.Remove(&s.queue, req.index)
+			ch.queued--
+			s.forget(ch)
+			s.mutex.Unlock()
+			return errors.Wrapf(ctx.Err(), "message of channel %s not scheduled", channelID)
+		}
+		//消息已轮到处理，照常提交
+		s.mutex.Unlock()
+	}
+	defer s.done()
+	return enqueue()
+}
+
+// done frees the turn of a message passed to its consenter, giving it to
+// the queued message of the earliest virtual finish time
+func (s *Scheduler) done() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.active--
+	if len(s.queue) == 0 {
+		return
+	}
+	req := heap.Pop(&s.queue).(*request)
+	req.channel.queued--
+	s.advance(req.start)
+	s.forget(req.channel)
+	s.active++
+	close(req.ready)
+}
+
+// advance moves the virtual time to the virtual start time of the message
+// passed to its consenter, so that the channels which had no messages
+// queued start from it rather than make up for the time they were idle
+func (s *Scheduler) advance(start float64) {
+	if start > s.virtual {
+		s.virtual = start
+	}
+}
+
+// forget removes the state of a channel without queued messages once the
+// virtual time has passed its latest message, as it would start afresh
+func (s *Scheduler) forget(ch *channel) {
+	if ch.queued == 0 && ch.finish <= s.virtual {
+		delete(s.channels, ch.id)
+	}
+}
+
+// Queued returns the number of messages of each channel waiting for their
+// turn
+func (s *Scheduler) Queued() map[string]int {
+	queued := map[string]int{}
+	if s == nil {
+		return queued
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for id, ch := range s.channels {
+		if ch.queued > 0 {
+			queued[id] = ch.queued
+		}
+	}
+	return queued
+}
@@ -0,0 +1,166 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fairqueue
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// hold takes the only turn of the scheduler until the returned function is
+// called
+func hold(t *testing.T, s *Scheduler) func() {
+	held := make(chan struct{})
+	release := make(chan struct{})
+	go s.Schedule(context.Background(), "hold", 1, func() error {
+		close(held)
+		<-release
+		return nil
+	})
+	<-held
+	return func() { close(release) }
+}
+
+// queue schedules a message of the channel in the background, waiting until
+// it is queued
+func queue(t *testing.T, s *Scheduler, ctx context.Context, channelID string, weight uint32, enqueue func() error) <-chan error {
+	queued := s.Queued()[channelID]
+	result := make(chan error, 1)
+	go func() { result <- s.Schedule(ctx, channelID, weight, enqueue) }()
+	for i := 0; s.Queued()[channelID] == queued; i++ {
+		require.True(t, i < 1000, "message of %s not queued", channelID)
+		time.Sleep(time.Millisecond)
+	}
+	return result
+}
+
+func TestNilScheduler(t *testing.T) {
+	var s *Scheduler
+	called := false
+	assert.NoError(t, s.Schedule(context.Background(), "foo", 1, func() error { called = true; return nil }))
+	assert.True(t, called)
+	assert.Empty(t, s.Queued())
+}
+
+func TestSchedule(t *testing.T) {
+	s := New(Config{})
+	assert.Equal(t, DefaultConcurrency, s.concurrency)
+	assert.Equal(t, DefaultQueueDepth, s.queueDepth)
+
+	// 未排队时直接提交，并返回提交的错误
+	assert.EqualError(t, s.Schedule(context.Background(), "foo", 1, func() error { return errors.New("rejected") }), "rejected")
+	assert.NoError(t, s.Schedule(context.Background(), "foo", 0, func() error { return nil }))
+	assert.Equal(t, 0, s.active)
+}
+
+func TestWeightedFairOrder(t *testing.T) {
+	s := New(Config{Concurrency: 1})
+	release := hold(t, s)
+
+	var mutex sync.Mutex
+	var order []string
+	enqueue := func(channelID string) func() error {
+		return func() error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			order = append(order, channelID)
+			return nil
+		}
+	}
+	var results []<-chan error
+	for i := 0; i < 4; i++ {
+		results = append(results, queue(t, s, context.Background(), "light", 1, enqueue("light")))
+	}
+	for i := 0; i < 4; i++ {
+		results = append(results, queue(t, s, context.Background(), "heavy", 2, enqueue("heavy")))
+	}
+	assert.Equal(t, map[string]int{"light": 4, "heavy": 4}, s.Queued())
+
+	// 权重为2的通道在竞争时获得两倍的排序吞吐量
+	release()
+	for _, result := range results {
+		assert.NoError(t, <-result)
+	}
+	assert.Equal(t, []string{"heavy", "light", "heavy", "heavy", "light", "heavy", "light", "light"}, order)
+	assert.Empty(t, s.Queued())
+}
+
+func TestIdleChannelDoesNotMakeUp(t *testing.T) {
+	s := New(Config{Concurrency: 1})
+	// 独占期间推进虚拟时间
+	for i := 0; i < 10; i++ {
+		require.NoError(t, s.Schedule(context.Background(), "busy", 1, func() error { return nil }))
+	}
+	release := hold(t, s)
+
+	var mutex sync.Mutex
+	var order []string
+	enqueue := func(channelID string) func() error {
+		return func() error {
+			mutex.Lock()
+			defer mutex.Unlock()
+			order = append(order, channelID)
+			return nil
+		}
+	}
+	var results []<-chan error
+	for i := 0; i < 2; i++ {
+		results = append(results, queue(t, s, context.Background(), "busy", 1, enqueue("busy")))
+	}
+	for i := 0; i < 2; i++ {
+		results = append(results, queue(t, s, context.Background(), "idle", 1, enqueue("idle")))
+	}
+	release()
+	for _, result := range results {
+		assert.NoError(t, <-result)
+	}
+	// 先前空闲的通道不会因空闲时间而获得补偿，两个通道交替排序
+	assert.Equal(t, []string{"idle", "busy", "idle", "busy"}, order)
+}
+
+func TestQueueFull(t *testing.T) {
+	s := New(Config{Concurrency: 1, QueueDepth: 1})
+	release := hold(t, s)
+
+	first := queue(t, s, context.Background(), "foo", 1, func() error { return nil })
+	err := s.Schedule(context.Background(), "foo", 1, func() error { t.Fatal("enqueued past the quota"); return nil })
+	assert.Equal(t, ErrQueueFull, errors.Cause(err))
+	assert.EqualError(t, err, "channel foo has 1 messages queued: ingress queue of the channel is full")
+
+	// 配额按通道权重计算
+	second := queue(t, s, context.Background(), "bar", 2, func() error { return nil })
+	third := queue(t, s, context.Background(), "bar", 2, func() error { return nil })
+	assert.Equal(t, ErrQueueFull, errors.Cause(s.Schedule(context.Background(), "bar", 2, func() error { return nil })))
+
+	release()
+	for _, result := range []<-chan error{first, second, third} {
+		assert.NoError(t, <-result)
+	}
+}
+
+func TestScheduleCanceled(t *testing.T) {
+	s := New(Config{Concurrency: 1})
+	release := hold(t, s)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	canceled := queue(t, s, ctx, "foo", 1, func() error { t.Fatal("canceled message enqueued"); return nil })
+	pending := queue(t, s, context.Background(), "bar", 1, func() error { return nil })
+	cancel()
+	err := <-canceled
+	assert.Equal(t, context.Canceled, errors.Cause(err))
+	assert.Equal(t, map[string]int{"bar": 1}, s.Queued())
+
+	release()
+	assert.NoError(t, <-pending)
+	assert.Empty(t, s.Queued())
+}
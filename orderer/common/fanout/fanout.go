@@ -0,0 +1,173 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package fanout multicasts freshly committed blocks to subscribers inside
+// the orderer, such as deliver streams waiting at the tip of a chain, event
+// bridges or analytics tailers, so that they need not each poll the ledger.
+package fanout
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/op/go-logging"
+)
+
+const pkgLogID = "orderer/common/fanout"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// AllChannels subscribes to the blocks of every channel.
+const AllChannels = ""
+
+var (
+	// ErrSlowSubscriber is reported by a subscription which was dropped
+	// because its buffer was full when a block was published.  The
+	// subscriber should resume from the ledger.
+	ErrSlowSubscriber = errors.New("subscriber could not keep up with published blocks")
+
+	// ErrCanceled is reported by a subscription which was canceled.
+	ErrCanceled = errors.New("subscription canceled")
+)
+
+// Multicaster delivers each published block to the subscribers of its
+// channel.  Publishing never blocks; a subscriber whose buffer is full is
+// dropped rather than holding up the block writer.  A nil Multicaster
+// discards published blocks.
+type Multicaster struct {
+	mutex       sync.Mutex
+	subscribers map[*Subscription]struct{}
+}
+
+// New creates a Multicaster without subscribers
+func New() *Multicaster {
+	return &Multicaster{
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the blocks published for a channel.
+type Subscription struct {
+	multicaster *Multicaster
+	channelID   string
+	blocks      chan *cb.Block
+	err         error
+}
+
+// Subscribe registers a subscriber for the blocks of channelID, or of every
+// channel for AllChannels, buffering up to bufferSize blocks.
+func (m *Multicaster) Subscribe(channelID string, bufferSize int) *Subscription {
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	s := &Subscription{
+		multicaster: m,
+		channelID:   channelID,
+		blocks:      make(chan *cb.Block, bufferSize),
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	m.subscribers[s] = struct{}{}
+	return s
+}
+
+// Publish delivers the block to the subscribers of channelID
+func (m *Multicaster) Publish(channelID string, block *cb.Block) {
+	if m == nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for s := range m.subscribers {
+		if s.channelID != AllChannels && s.channelID != channelID {
+			continue
+		}
+		select {
+		case s.blocks <- block:
+		default:
+			logger.Warningf("[channel: %s] Dropping subscriber which could not keep up at block %d", channelID, block.Header.Number)
+			m.remove(s, ErrSlowSubscriber)
+		}
+	}
+}
+
+// Subscribers returns the number of registered subscribers
+func (m *Multicaster) Subscribers() int {
+	if m == nil {
+		return 0
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return len(m.subscribers)
+}
+
+// Queued returns the number of blocks published but not yet received by
+// the subscribers
+func (m *Multicaster) Queued() int {
+	if m == nil {
+		return 0
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	queued := 0
+	for s := range m.subscribers {
+		queued += len(s.blocks)
+	}
+	return queued
+}
+
+// Close drops every subscriber
+func (m *Multicaster) Close() {
+	if m == nil {
+		return
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	for s := range m.subscribers {
+		m.remove(s, ErrCanceled)
+	}
+}
+
+// remove must be called with the mutex held
+func (m *Multicaster) remove(s *Subscription, err error) {
+	if _, ok := m.subscribers[s]; !ok {
+		return
+	}
+	delete(m.subscribers, s)
+	s.err = err
+	close(s.blocks)
+}
+
+// Blocks returns the channel on which blocks are received.  It is closed
+// when the subscription ends, after which Err reports why.
+func (s *Subscription) Blocks() <-chan *cb.Block {
+	return s.blocks
+}
+
+// Err returns the reason the subscription ended, or nil while it is active
+func (s *Subscription) Err() error {
+	s.multicaster.mutex.Lock()
+	defer s.multicaster.mutex.Unlock()
+	return s.err
+}
+
+// Cancel unregisters the subscription
+func (s *Subscription) Cancel() {
+	s.multicaster.mutex.Lock()
+	defer s.multicaster.mutex.Unlock()
+	s.multicaster.remove(s, ErrCanceled)
+}
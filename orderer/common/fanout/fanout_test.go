@@ -0,0 +1,73 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fanout
+
+import (
+	"testing"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublish(t *testing.T) {
+	m := New()
+	foo := m.Subscribe("foo", 10)
+	all := m.Subscribe(AllChannels, 10)
+	assert.Equal(t, 2, m.Subscribers())
+
+	m.Publish("foo", cb.NewBlock(1, nil))
+	m.Publish("bar", cb.NewBlock(2, nil))
+	assert.Equal(t, 3, m.Queued())
+
+	assert.Equal(t, uint64(1), (<-foo.Blocks()).Header.Number)
+	assert.Len(t, foo.Blocks(), 0)
+	assert.Equal(t, uint64(1), (<-all.Blocks()).Header.Number)
+	assert.Equal(t, uint64(2), (<-all.Blocks()).Header.Number)
+	assert.NoError(t, foo.Err())
+}
+
+func TestSlowSubscriber(t *testing.T) {
+	m := New()
+	slow := m.Subscribe("foo", 1)
+	fast := m.Subscribe("foo", 10)
+
+	m.Publish("foo", cb.NewBlock(1, nil))
+	m.Publish("foo", cb.NewBlock(2, nil))
+
+	assert.Equal(t, 1, m.Subscribers())
+	block, ok := <-slow.Blocks()
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), block.Header.Number)
+	_, ok = <-slow.Blocks()
+	assert.False(t, ok)
+	assert.Equal(t, ErrSlowSubscriber, slow.Err())
+	assert.Len(t, fast.Blocks(), 2)
+}
+
+func TestCancel(t *testing.T) {
+	m := New()
+	s := m.Subscribe("foo", 0)
+	s.Cancel()
+	s.Cancel()
+	_, ok := <-s.Blocks()
+	assert.False(t, ok)
+	assert.Equal(t, ErrCanceled, s.Err())
+	assert.Equal(t, 0, m.Subscribers())
+
+	other := m.Subscribe("foo", 1)
+	m.Close()
+	assert.Equal(t, ErrCanceled, other.Err())
+	m.Publish("foo", cb.NewBlock(1, nil))
+}
+
+func TestNilMulticaster(t *testing.T) {
+	var m *Multicaster
+	m.Publish("foo", cb.NewBlock(1, nil))
+	m.Close()
+	assert.Equal(t, 0, m.Subscribers())
+	assert.Equal(t, 0, m.Queued())
+}
@@ -0,0 +1,167 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fanout
+
+import (
+	"sync"
+
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+)
+
+// tipBlockBuffer is the number of published blocks buffered for an
+// iterator waiting at the tip of its chain
+const tipBlockBuffer = 16
+
+// Reader reads a channel's ledger, whose iterators, once they reach the tip
+// of the chain, receive the blocks published to the multicaster instead of
+// waiting on the ledger.  They subscribe only while at the tip, and resume
+// from the ledger whenever they fall behind it again, such as when dropped
+// as slow subscribers.
+type Reader struct {
+	blockledger.Reader
+	channelID   string
+	multicaster *Multicaster
+}
+
+// NewReader creates a Reader of the ledger of channelID following the
+// blocks published to the multicaster
+func NewReader(reader blockledger.Reader, channelID string, multicaster *Multicaster) *Reader {
+	return &Reader{
+		Reader:      reader,
+		channelID:   channelID,
+		multicaster: multicaster,
+	}
+}
+
+// Iterator implements blockledger.Reader
+func (r *Reader) Iterator(startType *ab.SeekPosition) (blockledger.Iterator, uint64) {
+	ledgerIterator, number := r.Reader.Iterator(startType)
+	return &tipIterator{
+		reader:         r,
+		ledgerIterator: ledgerIterator,
+		next:           number,
+		closed:         make(chan struct{}),
+	}, number
+}
+
+type tipIterator struct {
+	reader    *Reader
+	closeOnce sync.Once
+	closed    chan struct{}
+
+	mutex          sync.Mutex
+	ledgerIterator blockledger.Iterator //落后于链顶时读取账本，从订阅接收区块后作废
+	next           uint64
+	subscription   *Subscription
+}
+
+// Next returns the next block, reading it from the ledger if it is committed
+// already, or else waiting for it to be published
+func (i *tipIterator) Next() (*cb.Block, cb.Status) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	for {
+		select {
+		case <-i.closed:
+			return nil, cb.Status_SERVICE_UNAVAILABLE
+		default:
+		}
+
+		if i.next < i.reader.Height() {
+			//落后于链顶时取消订阅，以免缓冲区被填满
+			if i.subscription != nil {
+				i.subscription.Cancel()
+				i.subscription = nil
+			}
+			return i.nextFromLedger()
+		}
+
+		if i.subscription == nil {
+			//订阅后再次检查账本高度，以免错过订阅前发布的区块
+			i.subscription = i.reader.multicaster.Subscribe(i.reader.channelID, tipBlockBuffer)
+			continue
+		}
+
+		//等待区块时释放锁，使Close可以取消订阅
+		subscription := i.subscription
+		i.mutex.Unlock()
+		var block *cb.Block
+		ok := true
+		select {
+		case block, ok = <-subscription.Blocks():
+		case <-i.closed:
+		}
+		i.mutex.Lock()
+
+		if !ok {
+			err := subscription.Err()
+			i.subscription = nil
+			if err == ErrSlowSubscriber {
+				//被作为慢订阅者丢弃，从账本恢复
+				continue
+			}
+			return nil, cb.Status_SERVICE_UNAVAILABLE
+		}
+		if block == nil || block.Header.Number != i.next {
+			//早于下一个区块的区块已从账本读取，之后的区块留待从账本读取
+			continue
+		}
+		i.next++
+		if i.ledgerIterator != nil {
+			i.ledgerIterator.Close()
+			i.ledgerIterator = nil
+		}
+		return block, cb.Status_SUCCESS
+	}
+}
+
+// nextFromLedger must be called with the mutex held
+func (i *tipIterator) nextFromLedger() (*cb.Block, cb.Status) {
+	block, status := i.ensureLedgerIterator().Next()
+	if status == cb.Status_SUCCESS {
+		i.next = block.Header.Number + 1
+	}
+	return block, status
+}
+
+// ensureLedgerIterator must be called with the mutex held
+func (i *tipIterator) ensureLedgerIterator() blockledger.Iterator {
+	if i.ledgerIterator == nil {
+		i.ledgerIterator, _ = i.reader.Reader.Iterator(&ab.SeekPosition{
+			Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: i.next}},
+		})
+	}
+	return i.ledgerIterator
+}
+
+// ReadyChan supplies a channel which is closed when Next will not block
+func (i *tipIterator) ReadyChan() <-chan struct{} {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	return i.ensureLedgerIterator().ReadyChan()
+}
+
+// Close cancels the subscription of the iterator and releases its ledger
+// iterator.  A Next waiting for a block returns SERVICE_UNAVAILABLE.
+func (i *tipIterator) Close() {
+	i.closeOnce.Do(func() {
+		close(i.closed)
+	})
+
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+	if i.subscription != nil {
+		i.subscription.Cancel()
+		i.subscription = nil
+	}
+	if i.ledgerIterator != nil {
+		i.ledgerIterator.Close()
+		i.ledgerIterator = nil
+	}
+}
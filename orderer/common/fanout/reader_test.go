@@ -0,0 +1,160 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fanout
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	fileledger "github.com/hyperledger/fabric/common/ledger/blockledger/file"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// ledger appends blocks to a ledger and publishes them, as the block writer
+// does
+type ledger struct {
+	t           *testing.T
+	rl          blockledger.ReadWriter
+	multicaster *Multicaster
+}
+
+func newLedger(t *testing.T, multicaster *Multicaster) (*ledger, func()) {
+	dir, err := ioutil.TempDir("", "fanout")
+	require.NoError(t, err)
+	lf := fileledger.New(dir)
+	rl, err := lf.GetOrCreate("foo")
+	require.NoError(t, err)
+	return &ledger{t: t, rl: rl, multicaster: multicaster}, func() {
+		lf.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func (l *ledger) commit() *cb.Block {
+	block := blockledger.CreateNextBlock(l.rl, []*cb.Envelope{{Payload: []byte("tx")}})
+	assert.NoError(l.t, l.rl.Append(block))
+	l.multicaster.Publish("foo", block)
+	return block
+}
+
+func nextBlock(t *testing.T, it blockledger.Iterator) *cb.Block {
+	type result struct {
+		block  *cb.Block
+		status cb.Status
+	}
+	results := make(chan result, 1)
+	go func() {
+		block, status := it.Next()
+		results <- result{block, status}
+	}()
+	select {
+	case r := <-results:
+		require.Equal(t, cb.Status_SUCCESS, r.status)
+		return r.block
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the next block")
+		return nil
+	}
+}
+
+func TestReader(t *testing.T) {
+	m := New()
+	l, cleanup := newLedger(t, m)
+	defer cleanup()
+	l.commit()
+	l.commit()
+
+	it, number := NewReader(l.rl, "foo", m).Iterator(&ab.SeekPosition{Type: &ab.SeekPosition_Oldest{Oldest: &ab.SeekOldest{}}})
+	defer it.Close()
+	assert.Equal(t, uint64(0), number)
+
+	// 落后于链顶时从账本读取，不订阅
+	assert.Equal(t, uint64(0), nextBlock(t, it).Header.Number)
+	assert.Equal(t, uint64(1), nextBlock(t, it).Header.Number)
+	assert.Equal(t, 0, m.Subscribers())
+
+	// 到达链顶后接收发布的区块
+	published := make(chan *cb.Block, 1)
+	go func() {
+		for m.Subscribers() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		published <- l.commit()
+	}()
+	block := nextBlock(t, it)
+	assert.True(t, <-published == block, "the block should be the one published")
+
+	// 再次落后于链顶时取消订阅并从账本读取
+	l.commit()
+	l.commit()
+	assert.Equal(t, uint64(3), nextBlock(t, it).Header.Number)
+	assert.Equal(t, 0, m.Subscribers())
+	assert.Equal(t, uint64(4), nextBlock(t, it).Header.Number)
+}
+
+func TestReaderSlowSubscriber(t *testing.T) {
+	m := New()
+	l, cleanup := newLedger(t, m)
+	defer cleanup()
+	l.commit()
+
+	it, _ := NewReader(l.rl, "foo", m).Iterator(&ab.SeekPosition{Type: &ab.SeekPosition_Newest{Newest: &ab.SeekNewest{}}})
+	defer it.Close()
+	assert.Equal(t, uint64(0), nextBlock(t, it).Header.Number)
+
+	// 订阅者被丢弃后从账本恢复
+	done := make(chan *cb.Block)
+	go func() {
+		block, _ := it.Next()
+		done <- block
+	}()
+	for m.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	m.mutex.Lock()
+	for s := range m.subscribers {
+		m.remove(s, ErrSlowSubscriber)
+	}
+	m.mutex.Unlock()
+	l.commit()
+	select {
+	case block := <-done:
+		assert.Equal(t, uint64(1), block.Header.Number)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the next block")
+	}
+}
+
+func TestReaderClose(t *testing.T) {
+	m := New()
+	l, cleanup := newLedger(t, m)
+	defer cleanup()
+	l.commit()
+
+	it, _ := NewReader(l.rl, "foo", m).Iterator(&ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: 1}}})
+	statuses := make(chan cb.Status)
+	go func() {
+		_, status := it.Next()
+		statuses <- status
+	}()
+	for m.Subscribers() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	// 关闭迭代器唤醒等待中的Next并取消订阅
+	it.Close()
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, <-statuses)
+	assert.Equal(t, 0, m.Subscribers())
+	_, status := it.Next()
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, status)
+}
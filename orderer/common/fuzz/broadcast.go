@@ -0,0 +1,265 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fuzz
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/orderer/common/broadcast"
+	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// StandardChannelID is the ID of the standard channel the broadcast harness
+// routes messages to, the messages of the other channels being routed to the
+// system channel as the registrar of the orderer does.
+const StandardChannelID = "fuzzchannel"
+
+// The operations of a broadcast stream script, selected by the low bits of
+// the first byte of each event
+const (
+	opEOF = iota
+	opRecvError
+	opEnvelope
+	opPayload
+)
+
+// Broadcast drives a broadcast stream of a handler backed by the message
+// processors of a standard and of a system channel, with the script encoded
+// by data.  Each event of the script is a byte selecting an operation and,
+// for the envelopes, a two byte big-endian length followed by the marshaled
+// envelope, or by the payload of an unsigned envelope, truncated if data is.
+// The stream receives the envelopes in turn, failing to receive those which
+// cannot be unmarshaled as gRPC does, until the script runs out or a receive
+// error or an EOF is interleaved.
+//
+// The harness panics if the handler recovered from a panic, or if the
+// responses do not classify the messages as expected: every response has a
+// status a client can act upon, the messages without a channel header are
+// rejected with BAD_REQUEST, every message is answered unless one was
+// rejected or the stream failed, and the stream only fails if one was or if
+// receiving did.
+func Broadcast(data []byte) int {
+	return runBroadcast(broadcastHandler(), data)
+}
+
+var (
+	broadcastOnce sync.Once
+	sharedHandler broadcast.Handler
+)
+
+// broadcastHandler lazily creates the broadcast handler shared by the
+// streams, processing up to four messages of a stream at once.
+func broadcastHandler() broadcast.Handler {
+	broadcastOnce.Do(func() {
+		standard, system := processors()
+		registrar := &registrar{
+			channels: map[string]*channelSupport{
+				SystemChannelID:   {Processor: system},
+				StandardChannelID: {Processor: standard},
+			},
+		}
+		sharedHandler = broadcast.NewHandlerImpl(registrar, broadcast.HandlerOptions{Window: 4})
+	})
+	return sharedHandler
+}
+
+func runBroadcast(h broadcast.Handler, data []byte) int {
+	stream := newScriptedStream(data)
+	defer stream.cancel()
+	err := h.Handle(stream)
+	received, failed := stream.result()
+
+	interesting := false
+	answered := map[uint64]bool{}
+	rejected := false
+	for _, resp := range stream.responses {
+		if resp.Status == cb.Status_INTERNAL_SERVER_ERROR {
+			panic(fmt.Sprintf("broadcast handler panicked: %s", resp.Info))
+		}
+		if resp.CorrelationId == 0 || resp.CorrelationId > uint64(len(received)) {
+			panic(fmt.Sprintf("response to message %d of %d received", resp.CorrelationId, len(received)))
+		}
+		if answered[resp.CorrelationId] {
+			panic(fmt.Sprintf("message %d answered twice", resp.CorrelationId))
+		}
+		answered[resp.CorrelationId] = true
+
+		msg := received[resp.CorrelationId-1]
+		switch resp.Status {
+		case cb.Status_SUCCESS:
+			interesting = true
+		case cb.Status_BAD_REQUEST, cb.Status_FORBIDDEN, cb.Status_NOT_FOUND, cb.Status_SERVICE_UNAVAILABLE:
+			rejected = true
+		default:
+			panic(fmt.Sprintf("message %d answered with unexpected status %s", resp.CorrelationId, resp.Status))
+		}
+		if _, chdrErr := utils.ChannelHeader(msg); chdrErr != nil {
+			if resp.Status != cb.Status_BAD_REQUEST || resp.ErrorDetail.GetCode() != ab.ErrorDetail_MALFORMED_MESSAGE {
+				panic(fmt.Sprintf("message %d without channel header (%s) answered with %s", resp.CorrelationId, chdrErr, resp.Status))
+			}
+		} else if resp.Status == cb.Status_BAD_REQUEST || resp.Status == cb.Status_FORBIDDEN {
+			//通道头部可解析的消息经过了消息处理器，值得优先变异
+			interesting = true
+		}
+	}
+
+	//被拒绝的消息以携带错误详情的gRPC状态结束消息流
+	if err != nil && !failed && !rejected {
+		panic(fmt.Sprintf("broadcast stream failed although receiving did not: %s", err))
+	}
+	if err == nil && !rejected && len(answered) != len(received) {
+		panic(fmt.Sprintf("%d of %d messages answered", len(answered), len(received)))
+	}
+	if len(received) == 0 {
+		return Uninteresting
+	}
+	if interesting {
+		return Interesting
+	}
+	return Uninteresting
+}
+
+// scriptedStream is a broadcast stream receiving the events of a script and
+// recording the responses sent.  The handler receives on a goroutine of its
+// own, which may still be receiving when the handler returns.
+type scriptedStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mutex    sync.Mutex
+	script   []byte
+	received []*cb.Envelope
+	failed   bool
+
+	responses []*ab.BroadcastResponse
+}
+
+func newScriptedStream(script []byte) *scriptedStream {
+	ctx, cancel := context.WithCancel(peer.NewContext(context.Background(), &peer.Peer{}))
+	return &scriptedStream{ctx: ctx, cancel: cancel, script: script}
+}
+
+func (s *scriptedStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *scriptedStream) Send(resp *ab.BroadcastResponse) error {
+	s.responses = append(s.responses, resp)
+	return nil
+}
+
+func (s *scriptedStream) Recv() (*cb.Envelope, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if len(s.script) == 0 {
+		return nil, io.EOF
+	}
+	op := s.script[0] & 3
+	s.script = s.script[1:]
+	switch op {
+	case opEOF:
+		//gRPC在流结束后总是返回EOF
+		s.script = nil
+		return nil, io.EOF
+	case opRecvError:
+		s.script = nil
+		s.failed = true
+		return nil, status.Error(codes.Canceled, "context canceled")
+	}
+
+	var length int
+	if len(s.script) >= 2 {
+		length = int(binary.BigEndian.Uint16(s.script))
+		s.script = s.script[2:]
+	}
+	if length > len(s.script) {
+		length = len(s.script)
+	}
+	data := s.script[:length]
+	s.script = s.script[length:]
+
+	env := &cb.Envelope{Payload: data}
+	if op == opEnvelope {
+		env = &cb.Envelope{}
+		if err := proto.Unmarshal(data, env); err != nil {
+			//与gRPC相同，无法反序列化的消息使接收失败
+			s.script = nil
+			s.failed = true
+			return nil, status.Errorf(codes.Internal, "grpc: failed to unmarshal the received message %v", err)
+		}
+	}
+	s.received = append(s.received, env)
+	return env, nil
+}
+
+// result returns the envelopes received so far, and whether receiving failed
+func (s *scriptedStream) result() ([]*cb.Envelope, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.received, s.failed
+}
+
+// registrar routes the messages to the support of their channel, or of the
+// system channel if their channel does not exist, as the registrar of the
+// orderer does
+type registrar struct {
+	channels map[string]*channelSupport
+}
+
+func (r *registrar) BroadcastChannelSupport(msg *cb.Envelope) (*cb.ChannelHeader, bool, broadcast.ChannelSupport, error) {
+	chdr, err := utils.ChannelHeader(msg)
+	if err != nil {
+		return nil, false, nil, fmt.Errorf("could not determine channel ID: %s", err)
+	}
+	cs, ok := r.channels[chdr.ChannelId]
+	if !ok {
+		cs = r.channels[SystemChannelID]
+	}
+	switch cs.ClassifyMsg(chdr) {
+	case msgprocessor.ConfigUpdateMsg:
+		return chdr, true, cs, nil
+	case msgprocessor.ConfigMsg:
+		return chdr, false, nil, fmt.Errorf("message is of type that cannot be processed directly")
+	default:
+		return chdr, false, cs, nil
+	}
+}
+
+// channelSupport backs a channel with its message processor and with a
+// consenter enqueuing every message at once
+type channelSupport struct {
+	msgprocessor.Processor
+}
+
+func (cs *channelSupport) Order(env *cb.Envelope, configSeq uint64) error {
+	return nil
+}
+
+func (cs *channelSupport) Configure(config *cb.Envelope, configSeq uint64) error {
+	return nil
+}
+
+func (cs *channelSupport) WaitReadyContext(ctx context.Context) error {
+	return nil
+}
+
+func (cs *channelSupport) IngressWeight() uint32 {
+	return 1
+}
@@ -0,0 +1,193 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fuzz
+
+import (
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"sync"
+	"testing"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func event(op byte, data []byte) []byte {
+	e := []byte{op, 0, 0}
+	binary.BigEndian.PutUint16(e[1:], uint16(len(data)))
+	return append(e, data...)
+}
+
+func envelopeOfType(typ cb.HeaderType, channelID string) []byte {
+	return utils.MarshalOrPanic(&cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader:   utils.MarshalOrPanic(&cb.ChannelHeader{Type: int32(typ), ChannelId: channelID}),
+				SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{}),
+			},
+			Data: []byte("data"),
+		}),
+	})
+}
+
+func broadcastSeeds() map[string][]byte {
+	normal := envelopeOfType(cb.HeaderType_ENDORSER_TRANSACTION, StandardChannelID)
+	config := envelopeOfType(cb.HeaderType_CONFIG, SystemChannelID)
+	update := envelopeOfType(cb.HeaderType_CONFIG_UPDATE, "newchannel")
+	payload := utils.UnmarshalPayloadOrPanic(utils.UnmarshalEnvelopeOrPanic(normal).Payload)
+
+	concat := func(events ...[]byte) []byte {
+		var script []byte
+		for _, e := range events {
+			script = append(script, e...)
+		}
+		return script
+	}
+	return map[string][]byte{
+		"Empty":             {},
+		"EOF":               {opEOF},
+		"Garbage":           event(opEnvelope, []byte("garbage")),
+		"GarbagePayload":    event(opPayload, []byte("garbage")),
+		"NilHeader":         event(opPayload, nil),
+		"Normal":            concat(event(opEnvelope, normal), event(opEnvelope, normal)),
+		"SystemChannel":     event(opEnvelope, envelopeOfType(cb.HeaderType_MESSAGE, SystemChannelID)),
+		"Config":            event(opEnvelope, config),
+		"ConfigUpdate":      event(opEnvelope, update),
+		"TruncatedEnvelope": event(opEnvelope, normal[:len(normal)/2]),
+		"TruncatedPayload":  event(opPayload, utils.MarshalOrPanic(payload)[:len(normal)/3]),
+		"TruncatedScript":   event(opEnvelope, normal)[:len(normal)/2],
+		"InterleavedEOF":    concat(event(opEnvelope, normal), []byte{opEOF}, event(opEnvelope, normal)),
+		"RecvError":         concat(event(opEnvelope, normal), []byte{opRecvError}, event(opEnvelope, normal)),
+	}
+}
+
+func TestBroadcastHarness(t *testing.T) {
+	for name, seed := range broadcastSeeds() {
+		crash := Replay(Broadcast, seed)
+		assert.Nil(t, crash, "Broadcast panicked on %s", name)
+	}
+
+	assert.Equal(t, Uninteresting, Broadcast(nil))
+	assert.Equal(t, Interesting, Broadcast(broadcastSeeds()["Normal"]))
+}
+
+// TestBroadcastConcurrentStreams runs randomly mutated scripts on concurrent
+// streams of the shared handler
+func TestBroadcastConcurrentStreams(t *testing.T) {
+	var seeds [][]byte
+	for _, seed := range broadcastSeeds() {
+		seeds = append(seeds, seed)
+	}
+
+	var wg sync.WaitGroup
+	crashes := make(chan *Crash, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(r *rand.Rand) {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				script := append([]byte{}, seeds[r.Intn(len(seeds))]...)
+				for k := r.Intn(4); k > 0 && len(script) > 0; k-- {
+					switch r.Intn(3) {
+					case 0:
+						script[r.Intn(len(script))] ^= byte(1 << uint(r.Intn(8)))
+					case 1:
+						script = script[:r.Intn(len(script))]
+					default:
+						script = append(script, seeds[r.Intn(len(seeds))]...)
+					}
+				}
+				if crash := Replay(Broadcast, script); crash != nil {
+					crashes <- crash
+					return
+				}
+			}
+		}(rand.New(rand.NewSource(int64(i))))
+	}
+	wg.Wait()
+	close(crashes)
+	for crash := range crashes {
+		t.Errorf("Broadcast panicked at %s: %s", crash.Location, crash.Panic)
+	}
+}
+
+// answeringHandler answers the first message of its streams with the
+// response, and ignores the others
+type answeringHandler struct {
+	response *ab.BroadcastResponse
+}
+
+func (h *answeringHandler) Handle(srv ab.AtomicBroadcast_BroadcastServer) error {
+	for answered := false; ; answered = true {
+		if _, err := srv.Recv(); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if !answered {
+			srv.Send(h.response)
+		}
+	}
+}
+
+func (h *answeringHandler) HandleBatch(srv ab.AtomicBroadcast_BroadcastBatchServer) error {
+	return nil
+}
+
+func TestBroadcastViolations(t *testing.T) {
+	normal := event(opEnvelope, envelopeOfType(cb.HeaderType_ENDORSER_TRANSACTION, StandardChannelID))
+
+	for _, tc := range []struct {
+		name     string
+		response *ab.BroadcastResponse
+		script   []byte
+		panic    string
+	}{
+		{
+			name:     "RecoveredPanic",
+			response: &ab.BroadcastResponse{Status: cb.Status_INTERNAL_SERVER_ERROR, Info: "internal error, crash report 1234", CorrelationId: 1},
+			script:   normal,
+			panic:    "broadcast handler panicked: internal error, crash report 1234",
+		},
+		{
+			name:     "UnexpectedStatus",
+			response: &ab.BroadcastResponse{Status: cb.Status_NOT_IMPLEMENTED, CorrelationId: 1},
+			script:   normal,
+			panic:    "message 1 answered with unexpected status NOT_IMPLEMENTED",
+		},
+		{
+			name:     "MalformedAccepted",
+			response: &ab.BroadcastResponse{Status: cb.Status_SUCCESS, CorrelationId: 1},
+			script:   event(opPayload, []byte("garbage")),
+			panic:    "message 1 without channel header",
+		},
+		{
+			name:     "UnknownMessage",
+			response: &ab.BroadcastResponse{Status: cb.Status_SUCCESS, CorrelationId: 2},
+			script:   normal,
+			panic:    "response to message 2 of 1 received",
+		},
+		{
+			name:     "Unanswered",
+			response: &ab.BroadcastResponse{Status: cb.Status_SUCCESS, CorrelationId: 1},
+			script:   append(append([]byte{}, normal...), normal...),
+			panic:    "1 of 2 messages answered",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			crash := Replay(func(data []byte) int {
+				return runBroadcast(&answeringHandler{response: tc.response}, data)
+			}, tc.script)
+			if assert.NotNil(t, crash) {
+				assert.Contains(t, crash.Panic, tc.panic)
+			}
+		})
+	}
+}
@@ -0,0 +1,197 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package fuzz contains go-fuzz harnesses for the paths through which the
+// orderer parses untrusted input: the envelope utilities, the normal and
+// config update processing of the message processors of a standard and of a
+// system channel bootstrapped from the SampleSingleMSPSolo profile, and the
+// broadcast streams served by a handler backed by these message processors.
+//
+// To fuzz, build the harness selected with -func and run it on a corpus
+// directory, which may be seeded with the .env files collected under
+// Debug.MalformedCorpusDir by an orderer:
+//
+//	go-fuzz-build -func ConfigUpdateMsg github.com/hyperledger/fabric/orderer/common/fuzz
+//	go-fuzz -bin fuzz-fuzz.zip -workdir workdir
+//
+// Building with -libfuzzer produces a libFuzzer archive from the same
+// harnesses.  The crashers found in workdir/crashers can then be grouped by
+// root cause with Triage.
+package fuzz
+
+import (
+	"sync"
+
+	"github.com/hyperledger/fabric/common/capabilities"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/configtx"
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/configtxgentest"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/encoder"
+	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/op/go-logging"
+)
+
+const pkgLogID = "orderer/common/fuzz"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// The values returned by the harnesses, following the go-fuzz convention.
+const (
+	// Skip tells the fuzzer not to add the input to the corpus
+	Skip = -1
+
+	// Uninteresting tells the fuzzer to keep the input with normal priority
+	Uninteresting = 0
+
+	// Interesting tells the fuzzer to prioritize the input, as it was
+	// parsed far enough to exercise the processing logic
+	Interesting = 1
+)
+
+// Func is the signature of a harness.
+type Func func(data []byte) int
+
+// SystemChannelID is the ID of the system channel the message processors
+// are bootstrapped with.
+const SystemChannelID = "fuzzsystemchannel"
+
+// Envelope exercises the envelope parsing utilities on data, interpreted as
+// a marshaled envelope.
+func Envelope(data []byte) int {
+	env, err := utils.UnmarshalEnvelope(data)
+	if err != nil {
+		return Skip
+	}
+	payload, err := utils.UnmarshalPayload(env.Payload)
+	if err != nil || payload.Header == nil {
+		return Uninteresting
+	}
+	if _, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader); err != nil {
+		return Uninteresting
+	}
+	if _, err := utils.GetSignatureHeader(payload.Header.SignatureHeader); err != nil {
+		return Uninteresting
+	}
+
+	configUpdateEnv, err := configtx.UnmarshalConfigUpdateEnvelope(payload.Data)
+	if err == nil {
+		configtx.UnmarshalConfigUpdate(configUpdateEnv.ConfigUpdate)
+	}
+	if _, err := utils.UnmarshalEnvelopeOfType(env, cb.HeaderType_CONFIG, &cb.ConfigEnvelope{}); err == nil {
+		return Interesting
+	}
+	if _, err := utils.ChannelHeader(env); err == nil {
+		return Interesting
+	}
+	return Uninteresting
+}
+
+// NormalMsg feeds data, interpreted as a marshaled envelope, to the normal
+// message processing of a standard and of a system channel.
+func NormalMsg(data []byte) int {
+	env, err := utils.UnmarshalEnvelope(data)
+	if err != nil {
+		return Skip
+	}
+	standard, system := processors()
+	_, standardErr := standard.ProcessNormalMsg(env)
+	_, systemErr := system.ProcessNormalMsg(env)
+	if standardErr == nil || systemErr == nil {
+		return Interesting
+	}
+	return Uninteresting
+}
+
+// ConfigUpdateMsg feeds data, interpreted as a marshaled envelope, to the
+// config update processing of a standard and of a system channel.
+func ConfigUpdateMsg(data []byte) int {
+	env, err := utils.UnmarshalEnvelope(data)
+	if err != nil {
+		return Skip
+	}
+	standard, system := processors()
+	_, _, standardErr := standard.ProcessConfigUpdateMsg(env)
+	_, _, systemErr := system.ProcessConfigUpdateMsg(env)
+	if standardErr == nil || systemErr == nil {
+		return Interesting
+	}
+	return Uninteresting
+}
+
+var (
+	processorsOnce    sync.Once
+	standardProcessor *msgprocessor.StandardChannel
+	systemProcessor   *msgprocessor.SystemChannel
+)
+
+// processors lazily creates the message processors shared by the harnesses,
+// as go-fuzz invokes them repeatedly within a single process.
+func processors() (*msgprocessor.StandardChannel, *msgprocessor.SystemChannel) {
+	processorsOnce.Do(func() {
+		conf := configtxgentest.Load(genesisconfig.SampleSingleMSPSoloProfile)
+		conf.Orderer.Capabilities = map[string]bool{
+			capabilities.OrdererV1_1: true,
+		}
+		channelGroup, err := encoder.NewChannelGroup(conf)
+		if err != nil {
+			logger.Panicf("Failed creating channel group: %s", err)
+		}
+		bundle, err := channelconfig.NewBundle(SystemChannelID, &cb.Config{ChannelGroup: channelGroup})
+		if err != nil {
+			logger.Panicf("Failed creating channel config bundle: %s", err)
+		}
+
+		s := &support{Bundle: bundle}
+		s.templator = msgprocessor.NewDefaultTemplator(s)
+		standardProcessor = msgprocessor.NewStandardChannel(s, msgprocessor.CreateStandardChannelFilters(bundle))
+		systemProcessor = msgprocessor.NewSystemChannel(s, s.templator, msgprocessor.CreateSystemChannelFilters(s, bundle), nil)
+	})
+	return standardProcessor, systemProcessor
+}
+
+// support backs the message processors with a fixed channel config.  It
+// signs nothing, so the config envelopes it produces carry no signature.
+type support struct {
+	*channelconfig.Bundle
+	templator *msgprocessor.DefaultTemplator
+}
+
+func (s *support) Sequence() uint64 {
+	return s.ConfigtxValidator().Sequence()
+}
+
+func (s *support) ChainID() string {
+	return s.ConfigtxValidator().ChainID()
+}
+
+func (s *support) Signer() crypto.LocalSigner {
+	return nil
+}
+
+func (s *support) ProposeConfigUpdate(env *cb.Envelope) (*cb.ConfigEnvelope, error) {
+	return s.ConfigtxValidator().ProposeConfigUpdate(env)
+}
+
+func (s *support) NewChannelConfig(envConfigUpdate *cb.Envelope) (channelconfig.Resources, error) {
+	return s.templator.NewChannelConfig(envConfigUpdate)
+}
+
+func (s *support) CreateBundle(channelID string, config *cb.Config) (channelconfig.Resources, error) {
+	return channelconfig.NewBundle(channelID, config)
+}
+
+func (s *support) ChannelsCount() int {
+	return 1
+}
@@ -0,0 +1,59 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fuzz
+
+import (
+	"testing"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHarnesses(t *testing.T) {
+	configUpdate := utils.MarshalOrPanic(&cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
+					Type:      int32(cb.HeaderType_CONFIG_UPDATE),
+					ChannelId: "foo",
+				}),
+				SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{}),
+			},
+			Data: utils.MarshalOrPanic(&cb.ConfigUpdateEnvelope{
+				ConfigUpdate: utils.MarshalOrPanic(&cb.ConfigUpdate{
+					ChannelId: "foo",
+					ReadSet:   &cb.ConfigGroup{},
+					WriteSet:  &cb.ConfigGroup{},
+				}),
+			}),
+		}),
+	})
+
+	seeds := map[string][]byte{
+		"Empty":           {},
+		"Garbage":         []byte("garbage"),
+		"GarbagePayload":  utils.MarshalOrPanic(&cb.Envelope{Payload: []byte("garbage")}),
+		"NilHeader":       utils.MarshalOrPanic(&cb.Envelope{Payload: utils.MarshalOrPanic(&cb.Payload{})}),
+		"ConfigUpdate":    configUpdate,
+		"TruncatedUpdate": configUpdate[:len(configUpdate)/2],
+	}
+
+	for name, harness := range map[string]Func{
+		"Envelope":        Envelope,
+		"NormalMsg":       NormalMsg,
+		"ConfigUpdateMsg": ConfigUpdateMsg,
+	} {
+		for seedName, seed := range seeds {
+			crash := Replay(harness, seed)
+			assert.Nil(t, crash, "%s panicked on %s", name, seedName)
+		}
+	}
+
+	assert.Equal(t, Skip, Envelope([]byte("garbage")))
+	assert.Equal(t, Interesting, Envelope(configUpdate))
+}
@@ -0,0 +1,142 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fuzz
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Crash describes an input which makes a harness panic.
+type Crash struct {
+	// Input is the path of the input
+	Input string
+
+	// Panic is the value the harness panicked with
+	Panic string
+
+	// Location is the function and source line which panicked
+	Location string
+
+	// Stack is the stack trace of the panicking goroutine
+	Stack string
+}
+
+// Bucket groups the crashes which panicked at the same location, and so
+// likely share a root cause.
+type Bucket struct {
+	Location string
+	Crashes  []*Crash
+}
+
+// Replay runs the harness on the input and returns the resulting Crash, or
+// nil if the harness did not panic.
+func Replay(fn Func, data []byte) (crash *Crash) {
+	defer func() {
+		if r := recover(); r != nil {
+			stack := string(debug.Stack())
+			crash = &Crash{
+				Panic:    fmt.Sprint(r),
+				Location: panicLocation(stack),
+				Stack:    stack,
+			}
+		}
+	}()
+	fn(data)
+	return nil
+}
+
+// Triage replays the harness on every input of a go-fuzz crashers
+// directory, skipping the .output and .quoted files go-fuzz writes next to
+// each input, and returns the crashes grouped by location, the largest
+// buckets first.  Inputs which no longer crash are omitted.
+func Triage(fn Func, dir string) ([]*Bucket, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed reading crashers directory %s", dir)
+	}
+
+	buckets := map[string]*Bucket{}
+	for _, file := range files {
+		if file.IsDir() || strings.Contains(file.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(dir, file.Name())
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed reading crasher %s", path)
+		}
+		crash := Replay(fn, data)
+		if crash == nil {
+			logger.Debugf("Input %s no longer crashes", path)
+			continue
+		}
+		crash.Input = path
+		bucket, ok := buckets[crash.Location]
+		if !ok {
+			bucket = &Bucket{Location: crash.Location}
+			buckets[crash.Location] = bucket
+		}
+		bucket.Crashes = append(bucket.Crashes, crash)
+	}
+
+	var result []*Bucket
+	for _, bucket := range buckets {
+		result = append(result, bucket)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if len(result[i].Crashes) != len(result[j].Crashes) {
+			return len(result[i].Crashes) > len(result[j].Crashes)
+		}
+		return result[i].Location < result[j].Location
+	})
+	return result, nil
+}
+
+// panicLocation extracts from a stack trace the first frame after the call
+// to panic which is not in the runtime, which is where the harness panicked
+// or, for runtime errors, where the faulting operation was executed.
+func panicLocation(stack string) string {
+	lines := strings.Split(stack, "\n")
+	panicked := false
+	// Frames are pairs of a function line followed by a tab indented file:line
+	for i := 1; i+1 < len(lines); i += 2 {
+		function := lines[i]
+		if !panicked {
+			panicked = strings.HasPrefix(function, "panic(")
+			continue
+		}
+		if strings.HasPrefix(function, "runtime.") {
+			continue
+		}
+		return fmt.Sprintf("%s %s", trimArgs(function), trimOffset(strings.TrimSpace(lines[i+1])))
+	}
+	return "<unknown>"
+}
+
+// trimArgs removes the argument values of a function line, which vary
+// between crashes at the same location
+func trimArgs(function string) string {
+	if i := strings.LastIndex(function, "("); i > 0 {
+		return function[:i]
+	}
+	return function
+}
+
+// trimOffset removes the program counter offset of a file line
+func trimOffset(file string) string {
+	if i := strings.Index(file, " +0x"); i > 0 {
+		return file[:i]
+	}
+	return file
+}
@@ -0,0 +1,74 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package fuzz
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func crashingHarness(data []byte) int {
+	switch {
+	case strings.HasPrefix(string(data), "nil"):
+		var m map[string]int
+		m["boom"]++
+	case strings.HasPrefix(string(data), "index"):
+		return int(data[len(data)+1])
+	case strings.HasPrefix(string(data), "panic"):
+		panic("explicit " + string(data))
+	}
+	return Uninteresting
+}
+
+func TestReplay(t *testing.T) {
+	assert.Nil(t, Replay(crashingHarness, []byte("fine")))
+
+	crash := Replay(crashingHarness, []byte("panic"))
+	require.NotNil(t, crash)
+	assert.Equal(t, "explicit panic", crash.Panic)
+	assert.Contains(t, crash.Location, "fuzz.crashingHarness")
+	assert.Contains(t, crash.Location, "triage_test.go")
+
+	crash = Replay(crashingHarness, []byte("index"))
+	require.NotNil(t, crash)
+	assert.Contains(t, crash.Panic, "index out of range")
+	assert.Contains(t, crash.Location, "fuzz.crashingHarness")
+}
+
+func TestTriage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "crashers")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for name, content := range map[string]string{
+		"a":        "panic-1",
+		"b":        "panic-2",
+		"b.output": "panic: explicit panic-2",
+		"b.quoted": `"panic-2"`,
+		"c":        "index",
+		"d":        "fixed",
+	} {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0600))
+	}
+
+	buckets, err := Triage(crashingHarness, dir)
+	require.NoError(t, err)
+	require.Len(t, buckets, 2)
+	require.Len(t, buckets[0].Crashes, 2, "both explicit panics share a location")
+	assert.Equal(t, buckets[0].Location, buckets[0].Crashes[0].Location)
+	assert.Len(t, buckets[1].Crashes, 1)
+	assert.Equal(t, filepath.Join(dir, "c"), buckets[1].Crashes[0].Input)
+
+	_, err = Triage(crashingHarness, filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
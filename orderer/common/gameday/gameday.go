@@ -0,0 +1,220 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package gameday simulates the overload of channels on demand, failing or
+// delaying the admission of their broadcast messages by the consenter for a
+// bounded time, so that operators can rehearse the backoff behavior of their
+// clients against a production-like orderer.
+package gameday
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/gameday"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// ErrSimulatedOverload is returned in place of the consenter being ready on
+// the channels whose overload is simulated with rejections.
+var ErrSimulatedOverload = errors.New("consenter is overloaded (simulated for a game day exercise)")
+
+// Simulation is the overload simulated on a channel.
+type Simulation struct {
+	Channel string `json:"channel"`
+
+	// Reject fails the messages of the channel as if the consenter was not
+	// ready
+	Reject bool `json:"reject"`
+
+	// Latency delays the messages of the channel before they reach the
+	// consenter
+	Latency time.Duration `json:"latency"`
+
+	// Until is when the simulation ends
+	Until time.Time `json:"until"`
+}
+
+// Request starts a simulation, with durations such as "30s".
+type Request struct {
+	Channel  string `json:"channel"`
+	Reject   bool   `json:"reject"`
+	Latency  string `json:"latency"`
+	Duration string `json:"duration"`
+}
+
+// Simulator holds the overload simulations of the channels.
+type Simulator struct {
+	maxDuration time.Duration
+	now         func() time.Time
+	sleep       func(time.Duration)
+
+	mutex       sync.Mutex
+	simulations map[string]Simulation
+}
+
+// NewSimulator creates a Simulator whose simulations last at most
+// maxDuration.
+func NewSimulator(maxDuration time.Duration) *Simulator {
+	return &Simulator{
+		maxDuration: maxDuration,
+		now:         time.Now,
+		sleep:       time.Sleep,
+		simulations: map[string]Simulation{},
+	}
+}
+
+// Start simulates the overload of the channel for the duration, replacing
+// the simulation in progress on the channel, if any.
+func (s *Simulator) Start(channelID string, reject bool, latency, duration time.Duration) (Simulation, error) {
+	switch {
+	case channelID == "":
+		return Simulation{}, errors.New("channel is required")
+	case !reject && latency <= 0:
+		return Simulation{}, errors.New("either reject or a positive latency is required")
+	case latency < 0:
+		return Simulation{}, errors.Errorf("latency must not be negative, got %s", latency)
+	case duration <= 0:
+		return Simulation{}, errors.Errorf("duration must be positive, got %s", duration)
+	case duration > s.maxDuration:
+		return Simulation{}, errors.Errorf("duration %s exceeds the maximum duration of %s", duration, s.maxDuration)
+	case latency > duration:
+		return Simulation{}, errors.Errorf("latency %s exceeds the duration of %s", latency, duration)
+	}
+
+	sim := Simulation{Channel: channelID, Reject: reject, Latency: latency, Until: s.now().Add(duration)}
+	s.mutex.Lock()
+	s.simulations[channelID] = sim
+	s.mutex.Unlock()
+	logger.Warningf("[channel: %s] Simulating overload until %s: reject %t, latency %s", channelID, sim.Until, reject, latency)
+	return sim, nil
+}
+
+// Stop ends the simulation in progress on the channel, returning whether
+// there was one.
+func (s *Simulator) Stop(channelID string) bool {
+	s.mutex.Lock()
+	_, ok := s.active(channelID)
+	delete(s.simulations, channelID)
+	s.mutex.Unlock()
+	if ok {
+		logger.Warningf("[channel: %s] Stopped simulating overload", channelID)
+	}
+	return ok
+}
+
+// Simulations returns the simulations in progress, sorted by channel.
+func (s *Simulator) Simulations() []Simulation {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	sims := []Simulation{}
+	for channelID := range s.simulations {
+		if sim, ok := s.active(channelID); ok {
+			sims = append(sims, sim)
+		}
+	}
+	sort.Slice(sims, func(i, j int) bool { return sims[i].Channel < sims[j].Channel })
+	return sims
+}
+
+// WaitReady applies the simulation in progress on the channel, if any.  It
+// is called before the consenter of the channel is asked whether it is
+// ready, and returns ErrSimulatedOverload if the simulation rejects the
+// messages of the channel.
+func (s *Simulator) WaitReady(channelID string) error {
+	s.mutex.Lock()
+	sim, ok := s.active(channelID)
+	s.mutex.Unlock()
+	if !ok {
+		return nil
+	}
+	if sim.Latency > 0 {
+		s.sleep(sim.Latency)
+	}
+	if sim.Reject {
+		return ErrSimulatedOverload
+	}
+	return nil
+}
+
+// active returns the simulation in progress on the channel, forgetting it
+// once over.  It must be called with the mutex held.
+func (s *Simulator) active(channelID string) (Simulation, bool) {
+	sim, ok := s.simulations[channelID]
+	if !ok {
+		return Simulation{}, false
+	}
+	if !s.now().Before(sim.Until) {
+		delete(s.simulations, channelID)
+		logger.Infof("[channel: %s] Overload simulation ended", channelID)
+		return Simulation{}, false
+	}
+	return sim, true
+}
+
+// ServeHTTP lists the simulations in progress on GET, starts the simulation
+// of the Request in the body on POST, and stops the simulation of the
+// channel query parameter on DELETE.
+func (s *Simulator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		writeJSON(w, http.StatusOK, s.Simulations())
+	case http.MethodPost:
+		r := &Request{}
+		if err := json.NewDecoder(req.Body).Decode(r); err != nil {
+			http.Error(w, "invalid request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		var latency, duration time.Duration
+		var err error
+		if r.Latency != "" {
+			if latency, err = time.ParseDuration(r.Latency); err != nil {
+				http.Error(w, "invalid latency: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+		}
+		if duration, err = time.ParseDuration(r.Duration); err != nil {
+			http.Error(w, "invalid duration: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		logger.Warningf("Overload simulation of channel %s requested by %s", r.Channel, req.RemoteAddr)
+		sim, err := s.Start(r.Channel, r.Reject, latency, duration)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusCreated, sim)
+	case http.MethodDelete:
+		if !s.Stop(req.URL.Query().Get("channel")) {
+			http.Error(w, "no overload simulation in progress on the channel", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, POST, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Warningf("Failed writing overload simulation response: %s", err)
+	}
+}
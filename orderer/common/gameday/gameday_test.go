@@ -0,0 +1,115 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gameday
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newSimulator() (*Simulator, *time.Time, *[]time.Duration) {
+	s := NewSimulator(10 * time.Minute)
+	now := time.Unix(1000, 0)
+	var slept []time.Duration
+	s.now = func() time.Time { return now }
+	s.sleep = func(d time.Duration) { slept = append(slept, d) }
+	return s, &now, &slept
+}
+
+func TestSimulation(t *testing.T) {
+	s, now, slept := newSimulator()
+	assert.NoError(t, s.WaitReady("foo"))
+
+	_, err := s.Start("foo", true, 0, time.Minute)
+	require.NoError(t, err)
+	_, err = s.Start("bar", false, time.Second, 2*time.Minute)
+	require.NoError(t, err)
+
+	assert.Equal(t, ErrSimulatedOverload, s.WaitReady("foo"))
+	assert.NoError(t, s.WaitReady("bar"))
+	assert.NoError(t, s.WaitReady("baz"))
+	assert.Equal(t, []time.Duration{time.Second}, *slept)
+	assert.Len(t, s.Simulations(), 2)
+
+	// 模拟到期后自动结束
+	*now = now.Add(time.Minute)
+	assert.NoError(t, s.WaitReady("foo"))
+	sims := s.Simulations()
+	require.Len(t, sims, 1)
+	assert.Equal(t, "bar", sims[0].Channel)
+
+	assert.True(t, s.Stop("bar"))
+	assert.False(t, s.Stop("bar"))
+	assert.Empty(t, s.Simulations())
+}
+
+func TestStartRejected(t *testing.T) {
+	s, _, _ := newSimulator()
+	for _, tc := range []struct {
+		channelID string
+		reject    bool
+		latency   time.Duration
+		duration  time.Duration
+		err       string
+	}{
+		{"", true, 0, time.Minute, "channel is required"},
+		{"foo", false, 0, time.Minute, "either reject or a positive latency is required"},
+		{"foo", true, -time.Second, time.Minute, "latency must not be negative, got -1s"},
+		{"foo", true, 0, 0, "duration must be positive, got 0s"},
+		{"foo", true, 0, time.Hour, "duration 1h0m0s exceeds the maximum duration of 10m0s"},
+		{"foo", false, 2 * time.Minute, time.Minute, "latency 2m0s exceeds the duration of 1m0s"},
+	} {
+		_, err := s.Start(tc.channelID, tc.reject, tc.latency, tc.duration)
+		assert.EqualError(t, err, tc.err)
+	}
+	assert.Empty(t, s.Simulations())
+}
+
+func TestServeHTTP(t *testing.T) {
+	s, _, _ := newSimulator()
+
+	resp := httptest.NewRecorder()
+	s.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/gameday/overload", strings.NewReader(`{"channel":"foo","latency":"500ms","duration":"5m"}`)))
+	require.Equal(t, http.StatusCreated, resp.Code)
+	sim := Simulation{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &sim))
+	assert.Equal(t, "foo", sim.Channel)
+	assert.Equal(t, 500*time.Millisecond, sim.Latency)
+	assert.True(t, time.Unix(1300, 0).Equal(sim.Until))
+
+	for _, body := range []string{`garbage`, `{"channel":"foo","duration":"forever"}`, `{"channel":"foo","reject":true,"latency":"slow","duration":"1m"}`, `{"channel":"foo","reject":true,"duration":"1h"}`} {
+		resp = httptest.NewRecorder()
+		s.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/gameday/overload", strings.NewReader(body)))
+		assert.Equal(t, http.StatusBadRequest, resp.Code, body)
+	}
+
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/gameday/overload", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var sims []Simulation
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &sims))
+	assert.Len(t, sims, 1)
+
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/gameday/overload?channel=foo", nil))
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/gameday/overload?channel=foo", nil))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp = httptest.NewRecorder()
+	s.ServeHTTP(resp, httptest.NewRequest(http.MethodPut, "/gameday/overload", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+	assert.Equal(t, "GET, POST, DELETE", resp.Header().Get("Allow"))
+}
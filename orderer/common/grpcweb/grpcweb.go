@@ -0,0 +1,317 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package grpcweb serves the gRPC services of the orderer to gRPC-Web
+// clients, such as the browser-based tools calling Broadcast and Deliver, on
+// a listener of its own.
+//
+// A gRPC-Web request is a POST whose body holds the messages of the client in
+// gRPC framing, base64 encoded for the text content types.  It is translated
+// into a gRPC request passed to the gRPC server, whose responses are framed
+// the same way, and whose trailers, such as grpc-status, are sent in a final
+// frame flagged as trailers since browsers cannot read HTTP trailers.  The
+// client messages are all read before the request is served, so the streams
+// of the client are half-duplex: a Broadcast client sends its envelopes and
+// then reads their responses.
+package grpcweb
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"golang.org/x/net/http2"
+)
+
+const pkgLogID = "orderer/common/grpcweb"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+const (
+	contentTypeGRPCWeb     = "application/grpc-web"
+	contentTypeGRPCWebText = "application/grpc-web-text"
+	contentTypeGRPC        = "application/grpc"
+
+	// trailerFlag flags the frame holding the trailers of the response
+	trailerFlag byte = 0x80
+)
+
+// Handler translates the gRPC-Web requests into gRPC requests served by a
+// gRPC server, such as a *grpc.Server.
+type Handler struct {
+	grpcServer      http.Handler
+	allowedOrigins  map[string]bool
+	allowAll        bool
+	maxRequestBytes int64
+}
+
+// NewHandler creates a Handler of the gRPC server answering the cross-origin
+// requests of the allowed origins, of every origin if one of them is "*".
+// The messages of a request are read at once, up to maxRequestBytes.
+func NewHandler(grpcServer http.Handler, allowedOrigins []string, maxRequestBytes int64) *Handler {
+	h := &Handler{grpcServer: grpcServer, allowedOrigins: map[string]bool{}, maxRequestBytes: maxRequestBytes}
+	for _, origin := range allowedOrigins {
+		if origin == "*" {
+			h.allowAll = true
+		}
+		h.allowedOrigins[origin] = true
+	}
+	return h
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if origin := req.Header.Get("Origin"); origin != "" {
+		if !h.allowAll && !h.allowedOrigins[origin] {
+			http.Error(w, "origin not allowed", http.StatusForbidden)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Add("Vary", "Origin")
+		w.Header().Set("Access-Control-Expose-Headers", "grpc-status, grpc-message")
+	}
+	if req.Method == http.MethodOptions {
+		//跨域预检请求
+		w.Header().Set("Access-Control-Allow-Methods", "POST")
+		w.Header().Set("Access-Control-Allow-Headers", req.Header.Get("Access-Control-Request-Headers"))
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.Header().Set("Allow", "POST, OPTIONS")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	contentType, text, ok := grpcWebContentType(req.Header.Get("Content-Type"))
+	if !ok {
+		http.Error(w, "unsupported content type, expected "+contentTypeGRPCWeb, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	grpcReq := req.WithContext(req.Context())
+	grpcReq.ProtoMajor, grpcReq.ProtoMinor, grpcReq.Proto = 2, 0, "HTTP/2"
+	grpcReq.Header = http.Header{}
+	for k, vv := range req.Header {
+		grpcReq.Header[k] = vv
+	}
+	grpcReq.Header.Set("Content-Type", contentTypeGRPC+subtype(contentType))
+	grpcReq.Header.Del("Content-Length")
+
+	// HTTP/1.x不允许开始响应后继续读取请求体，因此先读取客户端的全部消息
+	var body io.Reader = http.MaxBytesReader(w, req.Body, h.maxRequestBytes)
+	if text {
+		body = base64.NewDecoder(base64.StdEncoding, body)
+	}
+	messages, err := ioutil.ReadAll(body)
+	if err != nil {
+		http.Error(w, "invalid request body: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	grpcReq.Body = ioutil.NopCloser(bytes.NewReader(messages))
+
+	rw := newResponseWriter(w, req.Context(), contentType, text)
+	h.grpcServer.ServeHTTP(rw, grpcReq)
+	rw.finish()
+}
+
+// grpcWebContentType returns the gRPC-Web content type, without parameters,
+// and whether it is a text one, or false if it is not a gRPC-Web one
+func grpcWebContentType(contentType string) (string, bool, bool) {
+	contentType = strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	switch {
+	case contentType == contentTypeGRPCWebText || strings.HasPrefix(contentType, contentTypeGRPCWebText+"+"):
+		return contentType, true, true
+	case contentType == contentTypeGRPCWeb || strings.HasPrefix(contentType, contentTypeGRPCWeb+"+"):
+		return contentType, false, true
+	default:
+		return "", false, false
+	}
+}
+
+// subtype returns the subtype of the content type, such as "+proto", if any
+func subtype(contentType string) string {
+	if i := strings.Index(contentType, "+"); i >= 0 {
+		return contentType[i:]
+	}
+	return ""
+}
+
+// responseWriter translates the gRPC response written by the gRPC server
+// into a gRPC-Web response: the headers set once the response is started
+// are trailers, written in a trailer frame when the response is finished
+type responseWriter struct {
+	w           http.ResponseWriter
+	ctx         context.Context
+	contentType string
+	text        bool
+
+	header      http.Header
+	wroteHeader bool
+	sent        map[string]bool
+}
+
+func newResponseWriter(w http.ResponseWriter, ctx context.Context, contentType string, text bool) *responseWriter {
+	return &responseWriter{
+		w:           w,
+		ctx:         ctx,
+		contentType: contentType,
+		text:        text,
+		header:      http.Header{},
+		sent:        map[string]bool{},
+	}
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.header
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	if rw.wroteHeader {
+		return
+	}
+	rw.wroteHeader = true
+	for k, vv := range rw.header {
+		if k == "Trailer" || strings.HasPrefix(k, http2.TrailerPrefix) {
+			continue
+		}
+		rw.sent[k] = true
+		if k == "Content-Type" {
+			continue
+		}
+		rw.w.Header()[k] = vv
+	}
+	rw.w.Header().Set("Content-Type", rw.contentType)
+	rw.w.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	return rw.write(b)
+}
+
+// write writes the bytes of gRPC frames, base64 encoded for a text response
+func (rw *responseWriter) write(b []byte) (int, error) {
+	if !rw.text {
+		return rw.w.Write(b)
+	}
+	if _, err := rw.w.Write([]byte(base64.StdEncoding.EncodeToString(b))); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+func (rw *responseWriter) Flush() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	if f, ok := rw.w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// CloseNotify is required by the gRPC server, the request being done when
+// the client goes away
+func (rw *responseWriter) CloseNotify() <-chan bool {
+	closed := make(chan bool, 1)
+	go func() {
+		<-rw.ctx.Done()
+		closed <- true
+	}()
+	return closed
+}
+
+// finish writes the trailer frame, holding the headers not sent yet
+func (rw *responseWriter) finish() {
+	if !rw.wroteHeader {
+		rw.WriteHeader(http.StatusOK)
+	}
+	trailers := http.Header{}
+	for k, vv := range rw.header {
+		switch {
+		case k == "Trailer":
+		case strings.HasPrefix(k, http2.TrailerPrefix):
+			trailers[strings.TrimPrefix(k, http2.TrailerPrefix)] = vv
+		case !rw.sent[k]:
+			trailers[k] = vv
+		}
+	}
+	var keys []string
+	for k := range trailers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	buf := &bytes.Buffer{}
+	for _, k := range keys {
+		for _, v := range trailers[k] {
+			buf.WriteString(strings.ToLower(k) + ": " + v + "\r\n")
+		}
+	}
+	frame := make([]byte, 5, 5+buf.Len())
+	frame[0] = trailerFlag
+	binary.BigEndian.PutUint32(frame[1:], uint32(buf.Len()))
+	if _, err := rw.write(append(frame, buf.Bytes()...)); err != nil {
+		logger.Debugf("Failed writing gRPC-Web trailers: %s", err)
+		return
+	}
+	rw.Flush()
+}
+
+// Server serves a Handler on a listener of its own
+type Server struct {
+	httpServer *http.Server
+	listener   net.Listener
+}
+
+// NewServer creates a Server of the handler listening on the address, over
+// TLS if the TLS config is not nil
+func NewServer(address string, tlsConfig *tls.Config, handler http.Handler) (*Server, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to listen on %s", address)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	return &Server{
+		httpServer: &http.Server{Handler: handler},
+		listener:   listener,
+	}, nil
+}
+
+// Address returns the address the Server listens on
+func (s *Server) Address() string {
+	return s.listener.Addr().String()
+}
+
+// Start serves the requests until Stop is called
+func (s *Server) Start() error {
+	err := s.httpServer.Serve(s.listener)
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Stop closes the listener and the connections of the Server
+func (s *Server) Stop() error {
+	return s.httpServer.Close()
+}
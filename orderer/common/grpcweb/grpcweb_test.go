@@ -0,0 +1,201 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package grpcweb
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// atomicBroadcast answers each broadcast envelope with the status of its
+// payload, and fails the deliver requests after sending a block
+type atomicBroadcast struct {
+	ab.AtomicBroadcastServer
+}
+
+func (atomicBroadcast) Broadcast(srv ab.AtomicBroadcast_BroadcastServer) error {
+	for {
+		env, err := srv.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := srv.Send(&ab.BroadcastResponse{Status: cb.Status_SUCCESS, Info: string(env.Payload)}); err != nil {
+			return err
+		}
+	}
+}
+
+func (atomicBroadcast) Deliver(srv ab.AtomicBroadcast_DeliverServer) error {
+	if _, err := srv.Recv(); err != nil {
+		return err
+	}
+	if err := srv.Send(&ab.DeliverResponse{Type: &ab.DeliverResponse_Block{Block: &cb.Block{Header: &cb.BlockHeader{Number: 7}}}}); err != nil {
+		return err
+	}
+	return status.Error(codes.NotFound, "no more blocks")
+}
+
+func frame(flag byte, data []byte) []byte {
+	f := make([]byte, 5, 5+len(data))
+	f[0] = flag
+	binary.BigEndian.PutUint32(f[1:], uint32(len(data)))
+	return append(f, data...)
+}
+
+// readFrames splits the body of a response into its data messages and its
+// trailers
+func readFrames(t *testing.T, body []byte) ([][]byte, string) {
+	var messages [][]byte
+	for len(body) > 0 {
+		require.True(t, len(body) >= 5)
+		length := binary.BigEndian.Uint32(body[1:5])
+		data := body[5 : 5+length]
+		if body[0] == trailerFlag {
+			require.Len(t, body, int(5+length), "the trailer frame is the last one")
+			return messages, string(data)
+		}
+		messages = append(messages, data)
+		body = body[5+length:]
+	}
+	t.Fatal("missing trailer frame")
+	return nil, ""
+}
+
+func newTestServer(t *testing.T, allowedOrigins ...string) *httptest.Server {
+	grpcServer := grpc.NewServer()
+	ab.RegisterAtomicBroadcastServer(grpcServer, atomicBroadcast{})
+	return httptest.NewServer(NewHandler(grpcServer, allowedOrigins, 1024))
+}
+
+func TestBroadcast(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	var body []byte
+	for _, payload := range []string{"first", "second"} {
+		env, err := proto.Marshal(&cb.Envelope{Payload: []byte(payload)})
+		require.NoError(t, err)
+		body = append(body, frame(0, env)...)
+	}
+	resp, err := http.Post(server.URL+"/orderer.AtomicBroadcast/Broadcast", "application/grpc-web+proto", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/grpc-web+proto", resp.Header.Get("Content-Type"))
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	messages, trailers := readFrames(t, respBody)
+	require.Len(t, messages, 2)
+	for i, info := range []string{"first", "second"} {
+		br := &ab.BroadcastResponse{}
+		require.NoError(t, proto.Unmarshal(messages[i], br))
+		assert.Equal(t, cb.Status_SUCCESS, br.Status)
+		assert.Equal(t, info, br.Info)
+	}
+	assert.Contains(t, trailers, "grpc-status: 0\r\n")
+}
+
+func TestDeliverText(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	env, err := proto.Marshal(&cb.Envelope{Payload: []byte("seek")})
+	require.NoError(t, err)
+	body := base64.StdEncoding.EncodeToString(frame(0, env))
+	resp, err := http.Post(server.URL+"/orderer.AtomicBroadcast/Deliver", "application/grpc-web-text", strings.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "application/grpc-web-text", resp.Header.Get("Content-Type"))
+
+	// each write is encoded on its own, padding included
+	encoded, err := ioutil.ReadAll(resp.Body)
+	require.NoError(t, err)
+	var respBody []byte
+	for len(encoded) > 0 {
+		end := bytes.IndexByte(encoded, '=')
+		for end >= 0 && end+1 < len(encoded) && encoded[end+1] == '=' {
+			end++
+		}
+		if end < 0 {
+			end = len(encoded) - 1
+		}
+		chunk, err := base64.StdEncoding.DecodeString(string(encoded[:end+1]))
+		require.NoError(t, err)
+		respBody = append(respBody, chunk...)
+		encoded = encoded[end+1:]
+	}
+	messages, trailers := readFrames(t, respBody)
+	require.Len(t, messages, 1)
+	dr := &ab.DeliverResponse{}
+	require.NoError(t, proto.Unmarshal(messages[0], dr))
+	assert.Equal(t, uint64(7), dr.GetBlock().Header.Number)
+	assert.Contains(t, trailers, "grpc-status: 5\r\n")
+	assert.Contains(t, trailers, "grpc-message: no more blocks\r\n")
+}
+
+func TestCORS(t *testing.T) {
+	server := newTestServer(t, "https://dashboard.example.com")
+	defer server.Close()
+
+	req, err := http.NewRequest(http.MethodOptions, server.URL+"/orderer.AtomicBroadcast/Deliver", nil)
+	require.NoError(t, err)
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	req.Header.Set("Access-Control-Request-Headers", "content-type,x-grpc-web")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNoContent, resp.StatusCode)
+	assert.Equal(t, "https://dashboard.example.com", resp.Header.Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "content-type,x-grpc-web", resp.Header.Get("Access-Control-Allow-Headers"))
+	assert.Contains(t, resp.Header.Get("Access-Control-Expose-Headers"), "grpc-status")
+
+	req.Header.Set("Origin", "https://evil.example.com")
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+}
+
+func TestInvalidRequests(t *testing.T) {
+	server := newTestServer(t)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/orderer.AtomicBroadcast/Deliver")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/orderer.AtomicBroadcast/Deliver", "application/json", strings.NewReader("{}"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusUnsupportedMediaType, resp.StatusCode)
+
+	resp, err = http.Post(server.URL+"/orderer.AtomicBroadcast/Deliver", "application/grpc-web", bytes.NewReader(make([]byte, 2048)))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "the request exceeds the maximum size")
+}
@@ -0,0 +1,226 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package hotreload applies the changes of the orderer configuration to the
+// settings which may change while the orderer runs, such as the log levels
+// and the rate limits, when the orderer receives a SIGHUP or an admin asks
+// for it.  The other settings are structural: their changes are reported,
+// and only take effect once the orderer restarts.
+package hotreload
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/hotreload"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// Setting is a hot reloadable setting of the orderer configuration.
+type Setting struct {
+	// Name is the path of the setting in the configuration, a section and
+	// one of its fields, such as General.LogLevel
+	Name string
+
+	// Apply applies the value of the setting in the updated configuration,
+	// which differs from its value in the current one
+	Apply func(current, updated *localconfig.TopLevel) error
+}
+
+// SettingInfo reports whether a setting of the orderer configuration is hot
+// reloadable.
+type SettingInfo struct {
+	Name          string `json:"name"`
+	HotReloadable bool   `json:"hotReloadable"`
+}
+
+// Report is the outcome of a reload of the orderer configuration, listing
+// the settings which changed.
+type Report struct {
+	// Applied are the hot reloadable settings applied
+	Applied []string `json:"applied"`
+	// Failed are the errors of the hot reloadable settings which kept their
+	// previous value
+	Failed map[string]string `json:"failed,omitempty"`
+	// RestartRequired are the structural settings, which keep their previous
+	// value until the orderer restarts
+	RestartRequired []string `json:"restartRequired"`
+}
+
+// Reloader reloads the orderer configuration, applying the changes of its
+// hot reloadable settings.
+type Reloader struct {
+	load     func() (*localconfig.TopLevel, error)
+	names    []string
+	settings map[string]Setting
+
+	mutex   sync.Mutex
+	current localconfig.TopLevel
+}
+
+// NewReloader creates a Reloader of the configuration the orderer started
+// with, loading the updated configuration with load.  It panics if a setting
+// is not a field of a section of the configuration.
+func NewReloader(current *localconfig.TopLevel, load func() (*localconfig.TopLevel, error), settings ...Setting) *Reloader {
+	r := &Reloader{
+		load:     load,
+		names:    settingNames(reflect.TypeOf(*current)),
+		settings: map[string]Setting{},
+		current:  *current,
+	}
+	for _, s := range settings {
+		if !r.exists(s.Name) {
+			logger.Panicf("Hot reloadable setting %s is not a setting of the orderer configuration", s.Name)
+		}
+		r.settings[s.Name] = s
+	}
+	return r
+}
+
+// settingNames returns the paths of the fields of the sections of the
+// configuration, in the order they are declared
+func settingNames(topLevel reflect.Type) []string {
+	var names []string
+	for i := 0; i < topLevel.NumField(); i++ {
+		section := topLevel.Field(i)
+		if section.Type.Kind() != reflect.Struct {
+			names = append(names, section.Name)
+			continue
+		}
+		for j := 0; j < section.Type.NumField(); j++ {
+			names = append(names, section.Name+"."+section.Type.Field(j).Name)
+		}
+	}
+	return names
+}
+
+func (r *Reloader) exists(name string) bool {
+	for _, n := range r.names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Settings returns the settings of the orderer configuration, and whether
+// each is hot reloadable.
+func (r *Reloader) Settings() []SettingInfo {
+	infos := make([]SettingInfo, 0, len(r.names))
+	for _, name := range r.names {
+		_, hot := r.settings[name]
+		infos = append(infos, SettingInfo{Name: name, HotReloadable: hot})
+	}
+	return infos
+}
+
+// Reload loads the configuration and applies the changes of its hot
+// reloadable settings, or returns an error if it cannot be loaded.
+func (r *Reloader) Reload() (report *Report, err error) {
+	defer func() {
+		//配置无效时localconfig可能panic，应作为错误报告
+		if p := recover(); p != nil {
+			report, err = nil, errors.Errorf("invalid orderer configuration: %v", p)
+		}
+	}()
+	updated, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	report = &Report{Applied: []string{}, RestartRequired: []string{}}
+	for _, name := range r.names {
+		currentValue, updatedValue := field(&r.current, name), field(updated, name)
+		if reflect.DeepEqual(currentValue.Interface(), updatedValue.Interface()) {
+			continue
+		}
+		setting, hot := r.settings[name]
+		if !hot {
+			logger.Warningf("Setting %s changed, the change takes effect once the orderer restarts", name)
+			report.RestartRequired = append(report.RestartRequired, name)
+			continue
+		}
+		if err := setting.Apply(&r.current, updated); err != nil {
+			logger.Warningf("Failed to reload setting %s: %s", name, err)
+			if report.Failed == nil {
+				report.Failed = map[string]string{}
+			}
+			report.Failed[name] = err.Error()
+			continue
+		}
+		logger.Infof("Reloaded setting %s", name)
+		currentValue.Set(updatedValue)
+		report.Applied = append(report.Applied, name)
+	}
+	return report, nil
+}
+
+// field returns the addressable value of the setting in the configuration
+func field(conf *localconfig.TopLevel, name string) reflect.Value {
+	v := reflect.ValueOf(conf).Elem()
+	for _, f := range strings.Split(name, ".") {
+		v = v.FieldByName(f)
+	}
+	return v
+}
+
+// Run reloads the configuration on every signal received, until the channel
+// is closed, such as the SIGHUP signals relayed by signal.Notify.
+func (r *Reloader) Run(signals <-chan os.Signal) {
+	for sig := range signals {
+		logger.Infof("Received %s, reloading the orderer configuration", sig)
+		if _, err := r.Reload(); err != nil {
+			logger.Errorf("Failed to reload the orderer configuration: %s", err)
+		}
+	}
+}
+
+// ServeHTTP returns the settings of the orderer configuration, and whether
+// each is hot reloadable, to a GET, and reloads the configuration on a POST,
+// responding with the Report of the reload.
+func (r *Reloader) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	var resp interface{}
+	status := http.StatusOK
+	switch req.Method {
+	case http.MethodGet:
+		resp = r.Settings()
+	case http.MethodPost:
+		report, err := r.Reload()
+		if err != nil {
+			logger.Warningf("Failed to reload the orderer configuration: %s", err)
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if report.Failed != nil {
+			status = http.StatusInternalServerError
+		}
+		resp = report
+	default:
+		w.Header().Set("Allow", "GET, POST")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(resp)
+}
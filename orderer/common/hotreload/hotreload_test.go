@@ -0,0 +1,165 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package hotreload
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConfig serves the configurations loaded by a Reloader
+type fakeConfig struct {
+	conf localconfig.TopLevel
+	err  error
+}
+
+func (f *fakeConfig) load() (*localconfig.TopLevel, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	conf := f.conf
+	return &conf, nil
+}
+
+func TestNewReloader(t *testing.T) {
+	assert.Panics(t, func() {
+		NewReloader(&localconfig.TopLevel{}, nil, Setting{Name: "General.NoSuchSetting"})
+	})
+	assert.Panics(t, func() {
+		NewReloader(&localconfig.TopLevel{}, nil, Setting{Name: "General.Keepalive.ServerInterval"})
+	})
+
+	defaults := func() (*localconfig.TopLevel, error) {
+		conf := localconfig.Defaults
+		return &conf, nil
+	}
+	report, err := NewReloader(&localconfig.Defaults, defaults).Reload()
+	require.NoError(t, err)
+	assert.Empty(t, report.RestartRequired, "every setting of the configuration is compared")
+
+	r := NewReloader(&localconfig.TopLevel{}, nil, Setting{Name: "General.LogLevel"})
+	settings := r.Settings()
+	assert.Contains(t, settings, SettingInfo{Name: "General.LogLevel", HotReloadable: true})
+	assert.Contains(t, settings, SettingInfo{Name: "General.Keepalive", HotReloadable: false})
+	assert.Contains(t, settings, SettingInfo{Name: "Operations.ListenAddress", HotReloadable: false})
+}
+
+func TestReload(t *testing.T) {
+	current := localconfig.TopLevel{}
+	current.General.LogLevel = "info"
+	current.General.RateLimit.ChannelRate = 10
+	fake := &fakeConfig{conf: current}
+
+	var applied []string
+	r := NewReloader(&current, fake.load,
+		Setting{Name: "General.LogLevel", Apply: func(current, updated *localconfig.TopLevel) error {
+			applied = append(applied, current.General.LogLevel+"->"+updated.General.LogLevel)
+			return nil
+		}},
+		Setting{Name: "General.RateLimit", Apply: func(current, updated *localconfig.TopLevel) error {
+			return errors.New("rate limiting is disabled")
+		}},
+	)
+
+	report, err := r.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, &Report{Applied: []string{}, RestartRequired: []string{}}, report, "nothing changed")
+
+	fake.conf.General.LogLevel = "debug"
+	fake.conf.General.RateLimit.ChannelRate = 20
+	fake.conf.General.ListenPort = 7050
+	report, err = r.Reload()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"General.LogLevel"}, report.Applied)
+	assert.Equal(t, map[string]string{"General.RateLimit": "rate limiting is disabled"}, report.Failed)
+	assert.Equal(t, []string{"General.ListenPort"}, report.RestartRequired)
+	assert.Equal(t, []string{"info->debug"}, applied)
+	assert.Equal(t, "info", current.General.LogLevel, "the configuration the orderer started with is left untouched")
+
+	// the settings applied are not applied again, while the others are still
+	// reported
+	report, err = r.Reload()
+	require.NoError(t, err)
+	assert.Empty(t, report.Applied)
+	assert.Contains(t, report.Failed, "General.RateLimit")
+	assert.Equal(t, []string{"General.ListenPort"}, report.RestartRequired)
+	assert.Len(t, applied, 1)
+
+	fake.err = errors.New("no such file")
+	_, err = r.Reload()
+	assert.EqualError(t, err, "no such file")
+
+	r = NewReloader(&current, func() (*localconfig.TopLevel, error) { panic("bad duration") })
+	_, err = r.Reload()
+	assert.EqualError(t, err, "invalid orderer configuration: bad duration")
+}
+
+func TestRun(t *testing.T) {
+	fake := &fakeConfig{}
+	reloaded := make(chan string, 1)
+	r := NewReloader(&localconfig.TopLevel{}, fake.load, Setting{Name: "General.LogLevel", Apply: func(current, updated *localconfig.TopLevel) error {
+		reloaded <- updated.General.LogLevel
+		return nil
+	}})
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	go func() {
+		r.Run(signals)
+		close(done)
+	}()
+	fake.conf.General.LogLevel = "debug"
+	signals <- syscall.SIGHUP
+	select {
+	case level := <-reloaded:
+		assert.Equal(t, "debug", level)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the configuration was not reloaded")
+	}
+	close(signals)
+	<-done
+}
+
+func TestServeHTTP(t *testing.T) {
+	fake := &fakeConfig{}
+	r := NewReloader(&localconfig.TopLevel{}, fake.load, Setting{Name: "General.LogLevel", Apply: func(current, updated *localconfig.TopLevel) error {
+		return nil
+	}})
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/config/reload", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var settings []SettingInfo
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &settings))
+	assert.Contains(t, settings, SettingInfo{Name: "General.LogLevel", HotReloadable: true})
+
+	fake.conf.General.LogLevel = "debug"
+	fake.conf.General.ListenPort = 7050
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/config/reload", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"applied": ["General.LogLevel"], "restartRequired": ["General.ListenPort"]}`, resp.Body.String())
+
+	fake.err = errors.New("no such file")
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/config/reload", nil))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/config/reload", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
@@ -0,0 +1,645 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package intake journals the messages accepted for ordering on disk before
+// the broadcast clients are answered SUCCESS, until they are committed in a
+// block, and replays them into their consenter after the orderer restarts.
+// Consenters such as solo keep the messages they accepted in memory until
+// they cut them into a block, so an orderer crash would otherwise silently
+// lose the transactions its clients were told were accepted.
+package intake
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/intake"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+const (
+	journalFileName = "journal"
+
+	// records are prefixed by the length and the checksum of their kind
+	// and payload
+	headerSize = 8
+
+	// blockBuffer is the number of committed blocks the journal may lag
+	// behind
+	blockBuffer = 1000
+
+	// compactionThreshold is the size from which the journal is rewritten
+	// once it holds mostly messages already committed
+	compactionThreshold = 64 * 1024 * 1024
+)
+
+// an appended record holds a message accepted for ordering, with its
+// channel and the height of the channel when it was accepted, and a done
+// record the digests of the messages since committed or rejected
+const (
+	appendedRecord byte = iota
+	doneRecord
+)
+
+type digest [sha256.Size]byte
+
+// Channel is the ledger and the consenter of a channel the journal replays
+// messages into
+type Channel interface {
+	// Height returns the height of the ledger of the channel
+	Height() uint64
+
+	// Iterator returns an iterator over the blocks of the channel from the
+	// start position
+	Iterator(startPosition *ab.SeekPosition) (blockledger.Iterator, uint64)
+
+	// WaitReady blocks until the consenter of the channel accepts messages
+	WaitReady() error
+
+	// Order passes a normal message to the consenter of the channel
+	Order(env *cb.Envelope, configSeq uint64) error
+
+	// Configure passes a config message to the consenter of the channel
+	Configure(config *cb.Envelope, configSeq uint64) error
+}
+
+// Channels looks up the channels of the orderer
+type Channels interface {
+	// Channel returns the channel, or false if it does not exist
+	Channel(channelID string) (Channel, bool)
+}
+
+type entry struct {
+	channelID string
+	height    uint64
+	envelope  []byte
+	since     time.Time
+
+	digest  digest
+	element *list.Element // the element of the entry in the order of the journal
+}
+
+// Journal is the write-ahead journal of the messages accepted for ordering
+// and not committed yet.  A message is forgotten once it is committed, once
+// its consenter rejects it, or once it stayed pending for longer than the
+// retention while the orderer runs, as the consenters may drop the messages
+// which are no longer valid after a config update.
+//
+// The appends are committed in groups: the records appended while the
+// journal is being synced to disk are synced together by the next sync, so
+// that concurrent broadcasts share an fsync rather than queuing one each.
+type Journal struct {
+	retention time.Duration
+	now       func() time.Time
+
+	mutex    sync.Mutex
+	dir      string
+	file     *os.File
+	size     int64 // the size of the journal file
+	live     int64 // the size of the appended records of the pending messages
+	pending  map[digest]*entry
+	order    *list.List // the pending entries, the oldest first
+	appended uint64     // the number of appended records written
+	synced   uint64     // the number of appended records synced to disk
+	channels Channels
+
+	// syncMutex serializes the syncs, the appends waiting for it being
+	// synced together by the next one
+	syncMutex sync.Mutex
+}
+
+// Open opens the journal in the directory, creating it if needed, and loads
+// the messages pending when the orderer stopped.  A torn record at the end
+// of the journal, written as the orderer crashed, is discarded.
+func Open(dir string, retention time.Duration) (*Journal, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, errors.Wrapf(err, "failed to create intake journal directory %s", dir)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, journalFileName), os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open intake journal")
+	}
+	j := &Journal{
+		retention: retention,
+		now:       time.Now,
+		dir:       dir,
+		file:      file,
+		pending:   map[digest]*entry{},
+		order:     list.New(),
+	}
+	end, err := j.load()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Truncate(end); err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "failed to truncate intake journal")
+	}
+	if _, err := file.Seek(end, io.SeekStart); err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "failed to seek intake journal")
+	}
+	j.size = end
+	if len(j.pending) > 0 {
+		logger.Infof("Intake journal holds %d messages pending when the orderer stopped", len(j.pending))
+	}
+	return j, nil
+}
+
+// load reads the records of the journal, and returns the offset of the end
+// of the last valid one
+func (j *Journal) load() (int64, error) {
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return 0, errors.Wrap(err, "failed to read intake journal")
+	}
+	buf := &bytes.Buffer{}
+	if _, err := buf.ReadFrom(j.file); err != nil {
+		return 0, errors.Wrap(err, "failed to read intake journal")
+	}
+	data := buf.Bytes()
+
+	now := j.now()
+	var offset int64
+	for len(data)-int(offset) >= headerSize {
+		header := data[offset : offset+headerSize]
+		size := int64(binary.BigEndian.Uint32(header))
+		if size == 0 || offset+headerSize+size > int64(len(data)) {
+			break
+		}
+		record := data[offset+headerSize : offset+headerSize+size]
+		if crc32.ChecksumIEEE(record) != binary.BigEndian.Uint32(header[4:]) {
+			break
+		}
+		switch record[0] {
+		case appendedRecord:
+			e, err := decodeEntry(record[1:])
+			if err != nil {
+				return 0, errors.WithMessage(err, "invalid intake journal record")
+			}
+			e.since = now
+			j.add(e)
+		case doneRecord:
+			digests := record[1:]
+			for len(digests) >= sha256.Size {
+				var d digest
+				copy(d[:], digests)
+				j.remove(d)
+				digests = digests[sha256.Size:]
+			}
+		default:
+			return 0, errors.Errorf("unknown intake journal record kind %d at offset %d", record[0], offset)
+		}
+		offset += headerSize + size
+	}
+	if offset < int64(len(data)) {
+		logger.Warningf("Discarding %d bytes of torn intake journal records", int64(len(data))-offset)
+	}
+	return offset, nil
+}
+
+// encode returns the payload of the appended record of the entry
+func (e *entry) encode() []byte {
+	payload := make([]byte, 1+8+2+len(e.channelID)+len(e.envelope))
+	payload[0] = appendedRecord
+	binary.BigEndian.PutUint64(payload[1:], e.height)
+	binary.BigEndian.PutUint16(payload[9:], uint16(len(e.channelID)))
+	copy(payload[11:], e.channelID)
+	copy(payload[11+len(e.channelID):], e.envelope)
+	return payload
+}
+
+// recordSize returns the size of the appended record of the entry
+func (e *entry) recordSize() int64 {
+	return int64(headerSize + 1 + 8 + 2 + len(e.channelID) + len(e.envelope))
+}
+
+func decodeEntry(payload []byte) (*entry, error) {
+	if len(payload) < 10 {
+		return nil, errors.New("truncated appended record")
+	}
+	channelIDSize := int(binary.BigEndian.Uint16(payload[8:]))
+	if len(payload) < 10+channelIDSize {
+		return nil, errors.New("truncated appended record")
+	}
+	return &entry{
+		height:    binary.BigEndian.Uint64(payload),
+		channelID: string(payload[10 : 10+channelIDSize]),
+		envelope:  append([]byte{}, payload[10+channelIDSize:]...),
+	}, nil
+}
+
+// Append journals the message, whose consenter is about to accept it, and
+// returns once it is synced to disk, along with the messages appended
+// concurrently.  The message is journaled on the channel of its channel
+// header, the system channel for channel creations.
+func (j *Journal) Append(env *cb.Envelope) error {
+	envelope, err := proto.Marshal(env)
+	if err != nil {
+		return errors.Wrap(err, "failed to marshal message")
+	}
+	chdr, err := utils.ChannelHeader(env)
+	if err != nil {
+		return errors.WithMessage(err, "failed to determine the channel of the message")
+	}
+	e := &entry{channelID: chdr.ChannelId, envelope: envelope}
+
+	j.mutex.Lock()
+	e.since = j.now()
+	if j.channels != nil {
+		if ch, ok := j.channels.Channel(e.channelID); ok {
+			e.height = ch.Height()
+		}
+	}
+	if err := j.write(e.encode()); err != nil {
+		j.mutex.Unlock()
+		return err
+	}
+	j.appended++
+	seq := j.appended
+	j.add(e)
+	j.mutex.Unlock()
+
+	if err := j.sync(seq); err != nil {
+		j.mutex.Lock()
+		j.remove(e.digest)
+		j.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// sync returns once the appended records up to the given one are synced to
+// disk, syncing them along with those appended since if no sync covered them
+func (j *Journal) sync(seq uint64) error {
+	j.syncMutex.Lock()
+	defer j.syncMutex.Unlock()
+	j.mutex.Lock()
+	if j.synced >= seq {
+		j.mutex.Unlock()
+		return nil
+	}
+	file, appended := j.file, j.appended
+	j.mutex.Unlock()
+
+	err := file.Sync()
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	if j.synced >= seq {
+		//同步期间日志已被压缩或清空，记录无需再同步
+		return nil
+	}
+	if err != nil {
+		return errors.Wrap(err, "failed to sync intake journal")
+	}
+	j.synced = appended
+	return nil
+}
+
+// Discard forgets the message, which its consenter did not accept.
+func (j *Journal) Discard(env *cb.Envelope) {
+	envelope, err := proto.Marshal(env)
+	if err != nil {
+		return
+	}
+	j.forget([]digest{sha256.Sum256(envelope)})
+}
+
+// Pending returns the number of messages journaled and not committed yet.
+func (j *Journal) Pending() int {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return len(j.pending)
+}
+
+// Start replays the messages pending when the orderer stopped into their
+// consenters, once the consenters are ready, except those committed before it
+// stopped, and forgets the messages committed from now on in the blocks
+// published by the multicaster until it is closed.
+func (j *Journal) Start(channels Channels, blocks *fanout.Multicaster) {
+	// the committed blocks are followed before the ledgers are scanned, so
+	// that no block is missed in between
+	sub := blocks.Subscribe(fanout.AllChannels, blockBuffer)
+
+	j.mutex.Lock()
+	j.channels = channels
+	byChannel := map[string][]*entry{}
+	var channelIDs []string
+	for element := j.order.Front(); element != nil; element = element.Next() {
+		e := element.Value.(*entry)
+		if _, ok := byChannel[e.channelID]; !ok {
+			channelIDs = append(channelIDs, e.channelID)
+		}
+		byChannel[e.channelID] = append(byChannel[e.channelID], e)
+	}
+	j.mutex.Unlock()
+
+	for _, channelID := range channelIDs {
+		ch, ok := channels.Channel(channelID)
+		if !ok {
+			logger.Warningf("[channel: %s] Dropping %d journaled messages of a channel which does not exist", channelID, len(byChannel[channelID]))
+			j.forget(digests(byChannel[channelID]))
+			continue
+		}
+		go j.replay(channelID, ch, byChannel[channelID])
+	}
+	go j.follow(blocks, sub)
+}
+
+func digests(entries []*entry) []digest {
+	ds := make([]digest, len(entries))
+	for i, e := range entries {
+		ds[i] = e.digest
+	}
+	return ds
+}
+
+// replay forgets the messages of the channel committed since they were
+// journaled, then passes the others to its consenter again
+func (j *Journal) replay(channelID string, ch Channel, entries []*entry) {
+	from := entries[0].height
+	for _, e := range entries {
+		if e.height < from {
+			from = e.height
+		}
+	}
+	j.scan(channelID, ch, from)
+
+	if err := ch.WaitReady(); err != nil {
+		logger.Warningf("[channel: %s] Cannot replay journaled messages, consenter not ready: %s", channelID, err)
+		return
+	}
+	replayed := 0
+	for _, e := range entries {
+		d := e.digest
+		if !j.isPending(d) {
+			continue
+		}
+		env := &cb.Envelope{}
+		if err := proto.Unmarshal(e.envelope, env); err != nil {
+			logger.Warningf("[channel: %s] Dropping journaled message which cannot be parsed: %s", channelID, err)
+			j.forget([]digest{d})
+			continue
+		}
+		// the config sequence of the messages is unknown, so the consenter
+		// validates them again against the current config
+		if err := j.enqueue(ch, env); err != nil {
+			logger.Warningf("[channel: %s] Dropping journaled message rejected by the consenter: %s", channelID, err)
+			j.forget([]digest{d})
+			continue
+		}
+		replayed++
+	}
+	logger.Infof("[channel: %s] Replayed %d journaled messages into the consenter", channelID, replayed)
+}
+
+func (j *Journal) enqueue(ch Channel, env *cb.Envelope) error {
+	chdr, err := utils.ChannelHeader(env)
+	if err != nil {
+		return err
+	}
+	switch chdr.Type {
+	case int32(cb.HeaderType_CONFIG), int32(cb.HeaderType_ORDERER_TRANSACTION):
+		return ch.Configure(env, 0)
+	default:
+		return ch.Order(env, 0)
+	}
+}
+
+// scan forgets the messages committed in the blocks of the channel from the
+// given number up to its current height
+func (j *Journal) scan(channelID string, ch Channel, from uint64) {
+	height := ch.Height()
+	if from >= height {
+		return
+	}
+	iter, _ := ch.Iterator(&ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: from}}})
+	defer iter.Close()
+	for number := from; number < height; number++ {
+		block, status := iter.Next()
+		if status != cb.Status_SUCCESS {
+			logger.Warningf("[channel: %s] Could not read block %d to find the journaled messages committed: %s", channelID, number, status)
+			return
+		}
+		j.committed(block)
+	}
+}
+
+// follow forgets the messages committed in the blocks of the subscription,
+// resubscribing if it falls behind, and the messages pending for longer than
+// the retention
+func (j *Journal) follow(blocks *fanout.Multicaster, sub *fanout.Subscription) {
+	var expire <-chan time.Time
+	if j.retention > 0 {
+		ticker := time.NewTicker(j.retention / 2)
+		defer ticker.Stop()
+		expire = ticker.C
+	}
+	for {
+		select {
+		case block, ok := <-sub.Blocks():
+			if ok {
+				j.committed(block)
+				continue
+			}
+			if sub.Err() != fanout.ErrSlowSubscriber {
+				logger.Infof("Intake journal stopped following blocks: %s", sub.Err())
+				return
+			}
+			logger.Warningf("Intake journal fell behind the committed blocks, resubscribing: %s", sub.Err())
+			sub = blocks.Subscribe(fanout.AllChannels, blockBuffer)
+		case <-expire:
+			j.expire()
+		}
+	}
+}
+
+// committed forgets the messages committed in the block
+func (j *Journal) committed(block *cb.Block) {
+	if block.Data == nil {
+		return
+	}
+	ds := make([]digest, len(block.Data.Data))
+	for i, data := range block.Data.Data {
+		ds[i] = sha256.Sum256(data)
+	}
+	j.forget(ds)
+}
+
+// expire forgets the messages pending for longer than the retention, which
+// are the oldest ones
+func (j *Journal) expire() {
+	now := j.now()
+	var expired []digest
+	j.mutex.Lock()
+	for element := j.order.Front(); element != nil; element = element.Next() {
+		e := element.Value.(*entry)
+		if now.Sub(e.since) < j.retention {
+			break
+		}
+		expired = append(expired, e.digest)
+	}
+	j.mutex.Unlock()
+	if len(expired) > 0 {
+		logger.Warningf("Forgetting %d journaled messages not committed within %s", len(expired), j.retention)
+		j.forget(expired)
+	}
+}
+
+func (j *Journal) isPending(d digest) bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	_, ok := j.pending[d]
+	return ok
+}
+
+// forget forgets the pending messages among the digests, the journal being
+// emptied once no message is pending, or compacted once it holds mostly
+// forgotten messages.  The done record is not synced, as a message replayed
+// after being committed is only invalidated as a duplicate.
+func (j *Journal) forget(ds []digest) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	var done []byte
+	for _, d := range ds {
+		if _, ok := j.pending[d]; ok {
+			j.remove(d)
+			done = append(done, d[:]...)
+		}
+	}
+	if len(done) == 0 {
+		return
+	}
+	var err error
+	switch {
+	case len(j.pending) == 0:
+		err = j.reset()
+	case j.size > compactionThreshold && j.size > 4*j.live:
+		err = j.compact()
+	default:
+		err = j.write(append([]byte{doneRecord}, done...))
+	}
+	if err != nil {
+		logger.Errorf("Failed to record committed messages in intake journal, they may be replayed after a restart: %s", err)
+	}
+}
+
+// Close closes the journal file.
+func (j *Journal) Close() error {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.file.Close()
+}
+
+func (j *Journal) add(e *entry) {
+	e.digest = sha256.Sum256(e.envelope)
+	if _, ok := j.pending[e.digest]; ok {
+		return
+	}
+	j.pending[e.digest] = e
+	e.element = j.order.PushBack(e)
+	j.live += e.recordSize()
+}
+
+func (j *Journal) remove(d digest) {
+	e, ok := j.pending[d]
+	if !ok {
+		return
+	}
+	delete(j.pending, d)
+	j.order.Remove(e.element)
+	j.live -= e.recordSize()
+}
+
+// frame returns the record of the payload, prefixed by its header
+func frame(payload []byte) []byte {
+	record := make([]byte, headerSize+len(payload))
+	copy(record[headerSize:], payload)
+	binary.BigEndian.PutUint32(record, uint32(len(payload)))
+	binary.BigEndian.PutUint32(record[4:], crc32.ChecksumIEEE(payload))
+	return record
+}
+
+// write appends the record of the payload to the journal, without syncing
+// it to disk
+func (j *Journal) write(payload []byte) error {
+	record := frame(payload)
+	if _, err := j.file.Write(record); err != nil {
+		return errors.Wrap(err, "failed to write intake journal record")
+	}
+	j.size += int64(len(record))
+	return nil
+}
+
+// reset empties the journal, whose records then need no sync as no message
+// is pending
+func (j *Journal) reset() error {
+	if err := j.file.Truncate(0); err != nil {
+		return errors.Wrap(err, "failed to truncate intake journal")
+	}
+	if _, err := j.file.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "failed to seek intake journal")
+	}
+	j.size = 0
+	j.synced = j.appended
+	return nil
+}
+
+// compact rewrites the journal with the pending messages only, replacing the
+// journal file once the new one is synced to disk
+func (j *Journal) compact() error {
+	path := filepath.Join(j.dir, journalFileName)
+	tmp, err := os.OpenFile(path+".tmp", os.O_RDWR|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return errors.Wrap(err, "failed to create compacted intake journal")
+	}
+	var size int64
+	for element := j.order.Front(); element != nil; element = element.Next() {
+		record := frame(element.Value.(*entry).encode())
+		if _, err := tmp.Write(record); err != nil {
+			tmp.Close()
+			return errors.Wrap(err, "failed to write compacted intake journal")
+		}
+		size += int64(len(record))
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to sync compacted intake journal")
+	}
+	if err := os.Rename(path+".tmp", path); err != nil {
+		tmp.Close()
+		return errors.Wrap(err, "failed to replace intake journal")
+	}
+	j.file.Close()
+	j.file = tmp
+	j.size = size
+	j.synced = j.appended
+	logger.Debugf("Compacted intake journal to %d pending messages", len(j.pending))
+	return nil
+}
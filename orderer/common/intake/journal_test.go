@@ -0,0 +1,304 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package intake
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	ramledger "github.com/hyperledger/fabric/common/ledger/blockledger/ram"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func envelope(channelID, txID string, headerType cb.HeaderType) *cb.Envelope {
+	return &cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{Type: int32(headerType), ChannelId: channelID, TxId: txID}),
+			},
+		}),
+	}
+}
+
+type channel struct {
+	blockledger.ReadWriter
+
+	mutex      sync.Mutex
+	ordered    []*cb.Envelope
+	configured []*cb.Envelope
+	orderErr   error
+	replayed   chan struct{}
+}
+
+func newChannel() *channel {
+	ledger, _ := ramledger.New(10).GetOrCreate("mychannel")
+	ledger.Append(blockledger.CreateNextBlock(ledger, []*cb.Envelope{envelope("mychannel", "genesis", cb.HeaderType_CONFIG)}))
+	return &channel{ReadWriter: ledger, replayed: make(chan struct{}, 10)}
+}
+
+func (c *channel) WaitReady() error { return nil }
+
+func (c *channel) Order(env *cb.Envelope, configSeq uint64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer func() { c.replayed <- struct{}{} }()
+	if c.orderErr != nil {
+		return c.orderErr
+	}
+	c.ordered = append(c.ordered, env)
+	return nil
+}
+
+func (c *channel) Configure(config *cb.Envelope, configSeq uint64) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	defer func() { c.replayed <- struct{}{} }()
+	c.configured = append(c.configured, config)
+	return nil
+}
+
+type channels map[string]*channel
+
+func (cs channels) Channel(channelID string) (Channel, bool) {
+	c, ok := cs[channelID]
+	if !ok {
+		return nil, false
+	}
+	return c, true
+}
+
+func tempDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "intake")
+	require.NoError(t, err)
+	return dir
+}
+
+func TestReopen(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j, err := Open(dir, time.Minute)
+	require.NoError(t, err)
+	tx1 := envelope("mychannel", "tx1", cb.HeaderType_ENDORSER_TRANSACTION)
+	tx2 := envelope("mychannel", "tx2", cb.HeaderType_ENDORSER_TRANSACTION)
+	tx3 := envelope("mychannel", "tx3", cb.HeaderType_ENDORSER_TRANSACTION)
+	require.NoError(t, j.Append(tx1))
+	require.NoError(t, j.Append(tx2))
+	require.NoError(t, j.Append(tx3))
+	j.Discard(tx2)
+	assert.Equal(t, 2, j.Pending())
+	require.NoError(t, j.Close())
+
+	j, err = Open(dir, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 2, j.Pending())
+
+	// the journal is emptied once no message is pending
+	j.Discard(tx1)
+	j.Discard(tx3)
+	assert.Equal(t, 0, j.Pending())
+	info, err := os.Stat(filepath.Join(dir, journalFileName))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+	require.NoError(t, j.Close())
+}
+
+func TestTornRecord(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j, err := Open(dir, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, j.Append(envelope("mychannel", "tx1", cb.HeaderType_ENDORSER_TRANSACTION)))
+	require.NoError(t, j.Append(envelope("mychannel", "tx2", cb.HeaderType_ENDORSER_TRANSACTION)))
+	require.NoError(t, j.Close())
+
+	path := filepath.Join(dir, journalFileName)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-3))
+
+	j, err = Open(dir, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, j.Pending())
+	require.NoError(t, j.Append(envelope("mychannel", "tx3", cb.HeaderType_ENDORSER_TRANSACTION)))
+	require.NoError(t, j.Close())
+
+	j, err = Open(dir, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 2, j.Pending())
+	require.NoError(t, j.Close())
+}
+
+func TestGroupCommit(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j, err := Open(dir, time.Minute)
+	require.NoError(t, err)
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			env := envelope("mychannel", fmt.Sprintf("tx%d", i), cb.HeaderType_ENDORSER_TRANSACTION)
+			assert.NoError(t, j.Append(env))
+			if i%2 == 0 {
+				j.Discard(env)
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(t, 50, j.Pending())
+	assert.Equal(t, uint64(100), j.synced)
+	require.NoError(t, j.Close())
+
+	j, err = Open(dir, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 50, j.Pending())
+	require.NoError(t, j.Close())
+}
+
+func TestReplay(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ch := newChannel()
+	cs := channels{"mychannel": ch}
+
+	j, err := Open(dir, time.Minute)
+	require.NoError(t, err)
+	j.channels = cs
+	committedTx := envelope("mychannel", "tx1", cb.HeaderType_ENDORSER_TRANSACTION)
+	lostTx := envelope("mychannel", "tx2", cb.HeaderType_ENDORSER_TRANSACTION)
+	lostConfig := envelope("mychannel", "config", cb.HeaderType_CONFIG)
+	require.NoError(t, j.Append(committedTx))
+	require.NoError(t, j.Append(lostTx))
+	require.NoError(t, j.Append(lostConfig))
+	require.NoError(t, j.Append(envelope("gone", "tx3", cb.HeaderType_ENDORSER_TRANSACTION)))
+	require.NoError(t, j.Close())
+	// the orderer crashed once the first message was committed, but before
+	// the journal learnt it
+	require.NoError(t, ch.Append(blockledger.CreateNextBlock(ch, []*cb.Envelope{committedTx})))
+
+	j, err = Open(dir, time.Minute)
+	require.NoError(t, err)
+	defer j.Close()
+	blocks := fanout.New()
+	defer blocks.Close()
+	j.Start(cs, blocks)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-ch.replayed:
+		case <-time.After(5 * time.Second):
+			t.Fatal("journaled messages not replayed")
+		}
+	}
+	ch.mutex.Lock()
+	require.Len(t, ch.ordered, 1)
+	assert.True(t, proto.Equal(lostTx, ch.ordered[0]))
+	require.Len(t, ch.configured, 1)
+	assert.True(t, proto.Equal(lostConfig, ch.configured[0]))
+	ch.mutex.Unlock()
+	assert.Equal(t, 2, j.Pending())
+
+	// the replayed messages are forgotten once committed
+	blocks.Publish("mychannel", blockledger.CreateNextBlock(ch, []*cb.Envelope{lostTx, lostConfig}))
+	for j.Pending() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestReplayRejected(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+	ch := newChannel()
+	ch.orderErr = errors.New("chain halted")
+
+	j, err := Open(dir, time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, j.Append(envelope("mychannel", "tx1", cb.HeaderType_ENDORSER_TRANSACTION)))
+	require.NoError(t, j.Close())
+
+	j, err = Open(dir, time.Minute)
+	require.NoError(t, err)
+	defer j.Close()
+	blocks := fanout.New()
+	defer blocks.Close()
+	j.Start(channels{"mychannel": ch}, blocks)
+
+	select {
+	case <-ch.replayed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("journaled message not replayed")
+	}
+	for j.Pending() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestExpire(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j, err := Open(dir, time.Minute)
+	require.NoError(t, err)
+	defer j.Close()
+	now := time.Unix(1500000000, 0)
+	j.now = func() time.Time { return now }
+
+	require.NoError(t, j.Append(envelope("mychannel", "tx1", cb.HeaderType_ENDORSER_TRANSACTION)))
+	now = now.Add(30 * time.Second)
+	require.NoError(t, j.Append(envelope("mychannel", "tx2", cb.HeaderType_ENDORSER_TRANSACTION)))
+
+	now = now.Add(30 * time.Second)
+	j.expire()
+	assert.Equal(t, 1, j.Pending())
+	now = now.Add(30 * time.Second)
+	j.expire()
+	assert.Equal(t, 0, j.Pending())
+}
+
+func TestCompact(t *testing.T) {
+	dir := tempDir(t)
+	defer os.RemoveAll(dir)
+
+	j, err := Open(dir, time.Minute)
+	require.NoError(t, err)
+	kept := envelope("mychannel", "kept", cb.HeaderType_ENDORSER_TRANSACTION)
+	forgotten := envelope("mychannel", "forgotten", cb.HeaderType_ENDORSER_TRANSACTION)
+	require.NoError(t, j.Append(kept))
+	require.NoError(t, j.Append(forgotten))
+
+	j.mutex.Lock()
+	require.NoError(t, j.compact())
+	j.mutex.Unlock()
+	j.Discard(forgotten)
+	j.mutex.Lock()
+	require.NoError(t, j.compact())
+	j.mutex.Unlock()
+	assert.Equal(t, j.live, j.size)
+	require.NoError(t, j.Append(envelope("mychannel", "appended", cb.HeaderType_ENDORSER_TRANSACTION)))
+	require.NoError(t, j.Close())
+
+	j, err = Open(dir, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 2, j.Pending())
+	require.NoError(t, j.Close())
+}
@@ -33,27 +33,64 @@ type TopLevel struct {
 	FileLedger FileLedger //文件账本配置对象
 	RAMLedger  RAMLedger //RAM账本配置对象
 	Kafka      Kafka //Kafka共识组件配置对象
+	BFT        BFT //BFT共识组件配置对象
 	Debug      Debug //调试信息配置对象
+	Operations Operations //运维服务配置对象
 }
 
 // General contains config which should be common among all orderer types.
 type General struct {
-	LedgerType     string
-	ListenAddress  string
-	ListenPort     uint16
-	TLS            TLS
-	Keepalive      Keepalive
-	GenesisMethod  string
-	GenesisProfile string
-	SystemChannel  string
-	GenesisFile    string
-	Profile        Profile
-	LogLevel       string
-	LogFormat      string
-	LocalMSPDir    string
-	LocalMSPID     string
-	BCCSP          *bccsp.FactoryOpts
-	Authentication Authentication
+	LedgerType              string
+	ListenAddress           string
+	ListenPort              uint16
+	TLS                     TLS
+	Keepalive               Keepalive
+	GenesisMethod           string
+	GenesisProfile          string
+	SystemChannel           string
+	GenesisFile             string
+	Profile                 Profile
+	LogLevel                string
+	LogFormat               string
+	LocalMSPDir             string
+	LocalMSPID              string
+	BCCSP                   *bccsp.FactoryOpts
+	Authentication          Authentication
+	MemoryTuning            MemoryTuning
+	Admission               Admission
+	Deduplication           Deduplication
+	ReplayProtection        ReplayProtection
+	RateLimit               RateLimit
+	Broadcast               Broadcast
+	StreamQuota             StreamQuota
+	Accounting              Accounting
+	Audit                   Audit
+	RuleChain               []string
+	Expiration              Expiration
+	FilterPlugins           []FilterPlugin
+	AdmissionPlugins        []AdmissionPlugin
+	Standby                 Standby
+	VersionSkew             VersionSkew
+	SystemChannelProtection SystemChannelProtection
+	SLO                     SLO
+	GameDay                 GameDay
+	Backlog                 Backlog
+	FairQueue               FairQueue
+	Maintenance             Maintenance
+	Analytics               Analytics
+	Privacy                 Privacy
+	Tracing                 Tracing
+	Heartbeat               Heartbeat
+	Misbehavior             Misbehavior
+	Embargoes               []ChannelEmbargo
+	IntakeJournal           IntakeJournal
+	Backup                  Backup
+	Restore                 Restore
+	Canary                  Canary
+	GRPCWeb                 GRPCWeb
+	Tenants                 []Tenant
+	LogSampling             []LogSampling
+	BatchSigning            BatchSigning
 }
 
 // Keepalive contains configuration for gRPC servers.
@@ -74,9 +111,394 @@ type TLS struct {
 }
 
 // Authentication contains configuration parameters related to authenticating
-// client messages.
+// client messages and the orderer's replies.
 type Authentication struct {
 	TimeWindow time.Duration
+	DeliverMAC bool
+}
+
+// Admission contains configuration for latency-budget admission control of
+// broadcast messages.
+type Admission struct {
+	Enabled          bool
+	LatencySLO       time.Duration
+	Window           time.Duration
+	CriticalChannels []string
+}
+
+// Deduplication contains configuration for acknowledging broadcast messages
+// submitted again without ordering them twice.  A zero TTL remembers the
+// transaction IDs until they are evicted.
+type Deduplication struct {
+	Enabled   bool
+	CacheSize int
+	TTL       time.Duration
+}
+
+// ReplayProtection contains configuration for the strict ordering mode, in
+// which the nonce of each message broadcast to the listed Channels, or to
+// every channel if none is listed, must start with a counter greater than
+// that of the previous message of its creator.
+type ReplayProtection struct {
+	Enabled  bool
+	Channels []string
+}
+
+// RateLimit contains the rates at which each channel and each client
+// identity may broadcast messages.  A zero rate sets no limit, and a zero
+// burst defaults to the rate.
+type RateLimit struct {
+	ChannelRate  float64
+	ChannelBurst int
+	Channels     []ChannelRateLimit
+	ClientRate   float64
+	ClientBurst  int
+}
+
+// ChannelRateLimit overrides the rate limit of a channel.
+type ChannelRateLimit struct {
+	Channel string
+	Rate    float64
+	Burst   int
+}
+
+// Broadcast contains configuration for servicing broadcast streams.  A zero
+// MaxMessageSize, MaxStreamsPerClient, IdleTimeout, ReadyTimeout or
+// CommitTimeout sets no limit, while a zero MaxCommitWaiters allows the
+// default number of transactions to await their commit at once.  Receipts
+// signs receipts of the messages accepted for the clients asking for them.
+// A non-zero PendingBytesWatermark rejects the normal messages of the
+// channels whose consenter has more bytes pending, hinting a backoff of
+// BackpressureRetryAfter at the watermark.  The normal messages are validated
+// on VerifyWorkers workers, one per CPU if 0, or on the goroutine processing
+// them if negative.  ForwardToLeader forwards the messages of the channels
+// led by another orderer to the leader, up to ForwardAttempts times
+// ForwardRetryInterval apart.
+type Broadcast struct {
+	InFlightWindow         int
+	MaxMessageSize         uint32
+	Channels               []ChannelMaxMessageSize
+	MaxStreamsPerClient    int
+	IdleTimeout            time.Duration
+	ReadyTimeout           time.Duration
+	CommitTimeout          time.Duration
+	MaxCommitWaiters       int
+	Receipts               bool
+	PendingBytesWatermark  uint32
+	BackpressureRetryAfter time.Duration
+	VerifyWorkers          int
+	ForwardToLeader        bool
+	ForwardAttempts        int
+	ForwardRetryInterval   time.Duration
+}
+
+// ChannelMaxMessageSize overrides the maximum message size of a channel.
+type ChannelMaxMessageSize struct {
+	Channel        string
+	MaxMessageSize uint32
+}
+
+// StreamQuota contains the number of broadcast and deliver streams each
+// client identity may have open on a channel at once.  A zero maximum sets no
+// limit.
+type StreamQuota struct {
+	MaxBroadcastStreams int
+	MaxDeliverStreams   int
+	Channels            []ChannelStreamQuota
+}
+
+// ChannelStreamQuota overrides the stream quota of a channel.
+type ChannelStreamQuota struct {
+	Channel             string
+	MaxBroadcastStreams int
+	MaxDeliverStreams   int
+}
+
+// Accounting contains configuration for metering the usage of the orderer by
+// each organization.
+type Accounting struct {
+	Enabled bool
+}
+
+// Audit contains configuration for the audit log of the messages broadcast to
+// the orderer.
+type Audit struct {
+	Enabled     bool
+	File        string
+	MaxFileSize uint32
+	MaxBackups  int
+}
+
+// Expiration contains configuration for the Expiration rule rejecting the
+// messages signed by expired identities.  Messages signed by identities
+// expired for less than the GracePeriod of their channel are accepted with a
+// warning instead.
+type Expiration struct {
+	GracePeriod time.Duration
+	Channels    []ChannelExpiration
+}
+
+// ChannelExpiration overrides the expiration grace period of a channel.
+type ChannelExpiration struct {
+	Channel     string
+	GracePeriod time.Duration
+}
+
+// FilterPlugin contains configuration for a WebAssembly module admitting the
+// messages broadcast to the channels it applies to, every channel if none.
+type FilterPlugin struct {
+	Name           string
+	Path           string
+	Channels       []string
+	Fuel           uint64
+	MaxMemoryPages uint32
+}
+
+// AdmissionPlugin contains configuration for a custom check of the normal
+// messages broadcast to the channels it applies to, every channel if none,
+// compiled in or loaded from the Go plugin at Library.
+type AdmissionPlugin struct {
+	Name       string
+	Library    string
+	Channels   []string
+	Parameters map[string]string
+}
+
+// Standby contains configuration for running the orderer as a cold standby
+// replicating the blocks of an active orderer until promoted.
+type Standby struct {
+	Enabled      bool
+	Source       string
+	PollInterval time.Duration
+	PromoteAfter time.Duration
+}
+
+// SystemChannelProtection contains configuration for hardening the system
+// channel.
+type SystemChannelProtection struct {
+	BroadcastPolicy           string
+	ChannelCreationsPerMinute uint32
+	ChannelCreationBurst      uint32
+	AlertOnConfigChange       bool
+}
+
+// SLO contains the service level objectives declared for the channels and
+// how often they are evaluated.
+type SLO struct {
+	EvaluationInterval time.Duration
+	Channels           []ChannelSLO
+}
+
+// VersionSkew contains configuration for the negotiation of the versions of
+// the orderers connecting to each other.
+type VersionSkew struct {
+	Policy       string
+	MaxMinorSkew int
+}
+
+// GameDay contains configuration for the overload simulations of the game
+// day exercises.
+type GameDay struct {
+	Enabled     bool
+	MaxDuration time.Duration
+}
+
+// Backlog contains configuration for the messages queued to the consenters.
+// Messages are queued only if MaxMemory is set.
+type Backlog struct {
+	MaxMemory    uint32
+	SpillDir     string
+	MaxSpillSize uint32
+	MaxPriority  int
+}
+
+// FairQueue contains configuration for scheduling the messages passed to the
+// consenters fairly across the channels, by the ingress weights of their
+// channel config.  A zero Concurrency or QueueDepth defaults to 16 messages
+// passed at once and 1000 messages queued per unit of weight.
+type FairQueue struct {
+	Enabled     bool
+	Concurrency int
+	QueueDepth  int
+}
+
+// Maintenance contains the maintenance windows of the orderer and the
+// operations it runs during them.
+type Maintenance struct {
+	Location      string
+	CheckInterval time.Duration
+	MaxConcurrent int
+	Windows       []MaintenanceWindow
+	Tasks         []MaintenanceTask
+}
+
+// MaintenanceWindow declares a window opening at Start on each of the Days,
+// or every day if none is declared, and lasting for Duration.
+type MaintenanceWindow struct {
+	Days     []string
+	Start    string
+	Duration time.Duration
+}
+
+// MaintenanceTask declares an operation run during the maintenance windows
+// at most once every Interval, or once per window if Interval is zero.
+type MaintenanceTask struct {
+	Operation string
+	Interval  time.Duration
+}
+
+// Analytics contains configuration for the analyses of the committed blocks.
+type Analytics struct {
+	TxSizeReport bool
+}
+
+// Privacy contains configuration for the anonymization of the identities of
+// the clients in the logs, metrics and audit log.  An empty Mode disables it.
+type Privacy struct {
+	Mode       string
+	KeyFile    string
+	HashLength int
+}
+
+// Tracing contains configuration for the tracing of the messages broadcast
+// through the stages of ordering.
+type Tracing struct {
+	Enabled     bool
+	SampleRatio float64
+	Capacity    int
+}
+
+// Heartbeat contains configuration for the heartbeats sent on the broadcast
+// and deliver streams whose clients ask for them.  A zero MinInterval
+// defaults to 1 second.
+type Heartbeat struct {
+	Enabled     bool
+	MinInterval time.Duration
+}
+
+// Misbehavior contains configuration for scoring the suspicious broadcast
+// messages of each client and identity.  The score of a subject halves every
+// HalfLife, and a subject whose score reaches BlockThreshold is blocked for
+// BlockDuration, never if BlockThreshold is 0.  A pattern of zero weight is
+// not scored.
+type Misbehavior struct {
+	Enabled        bool
+	HalfLife       time.Duration
+	BlockThreshold float64
+	BlockDuration  time.Duration
+	Weights        MisbehaviorWeights
+}
+
+// MisbehaviorWeights contains the score of each suspicious pattern.
+type MisbehaviorWeights struct {
+	InvalidSignature float64
+	Replay           float64
+	MalformedMessage float64
+	MalformedConfig  float64
+}
+
+// ChannelEmbargo contains the embargo of a channel, whose new blocks are held
+// back from the deliver clients which are not consenters for Delay after
+// their commit and until Blocks later blocks are committed.
+type ChannelEmbargo struct {
+	Channel string
+	Delay   time.Duration
+	Blocks  uint64
+}
+
+// IntakeJournal contains configuration for journaling the broadcast messages
+// in Dir before they are answered SUCCESS, so that the messages not yet
+// committed when the orderer crashes are passed again to their consenter on
+// restart.  A message not committed within Retention is forgotten.
+type IntakeJournal struct {
+	Enabled   bool
+	Dir       string
+	Retention time.Duration
+}
+
+// Backup contains configuration for the backups and snapshots of the file
+// ledgers of the channels taken by admins while the orderer runs, each
+// being written in a directory of its own under Dir.
+type Backup struct {
+	Enabled bool
+	Dir     string
+}
+
+// Restore contains configuration for starting the orderer on ledgers
+// restored from a backup: their hash chain is verified, and the orderer is
+// fenced from consenting until they have caught up with the orderers at
+// Peers, compared every PollInterval.
+type Restore struct {
+	Enabled      bool
+	Peers        []string
+	PollInterval time.Duration
+}
+
+// Canary contains configuration for the canary transactions submitted by the
+// orderer every Interval on Channel, whose commit is awaited for Timeout.
+type Canary struct {
+	Enabled  bool
+	Channel  string
+	Interval time.Duration
+	Timeout  time.Duration
+}
+
+// GRPCWeb contains configuration for serving the AtomicBroadcast service to
+// gRPC-Web clients, such as browsers, on a listener of its own sharing the
+// TLS settings of the general listener.  Browsers of the AllowedOrigins, or of
+// any origin if it holds "*", may call it cross-origin.
+type GRPCWeb struct {
+	Enabled        bool
+	ListenAddress  string
+	ListenPort     uint16
+	AllowedOrigins []string
+}
+
+// Tenant contains the configuration of a tenant, served the channels of the
+// given IDs on a listener of its own, the other channels looking to its
+// clients as if they did not exist.  Clients must present a certificate
+// issued by one of ClientRootCAs if set, and otherwise are authenticated as
+// on the general listener.
+type Tenant struct {
+	Name          string
+	ListenAddress string
+	ListenPort    uint16
+	Channels      []string
+	ClientRootCAs []string
+}
+
+// LogSampling contains the sampling of the debug messages of the modules
+// matching the regular expression Module: the first Initial messages of a
+// module are logged every Tick, and then one in Thereafter.
+type LogSampling struct {
+	Module     string
+	Initial    int
+	Thereafter int
+	Tick       time.Duration
+}
+
+// BatchSigning contains configuration for signing the blocks in batches: the
+// root of the Merkle tree of the blocks of a batch is signed once MaxBlocks
+// blocks are written, or MaxDelay after the first block of the batch.
+type BatchSigning struct {
+	Enabled   bool
+	MaxBlocks int
+	MaxDelay  time.Duration
+}
+
+// ChannelSLO contains the service level objectives of a channel.  A zero
+// limit declares no objective.
+type ChannelSLO struct {
+	Channel           string
+	MaxBlockInterval  time.Duration
+	MaxEnqueueLatency time.Duration
+}
+
+// MemoryTuning contains garbage collector and heap ballast settings applied
+// at startup.  Empty values leave the Go runtime defaults in place.
+type MemoryTuning struct {
+	GOGC    string
+	Ballast string
 }
 
 // Profile contains configuration for Go pprof profiling.
@@ -104,6 +526,17 @@ type Kafka struct {
 	TLS     TLS
 }
 
+// BFT contains configuration for the BFT orderer, ordering with the
+// compiled-in library of the Name or with the Go plugin at Library.  The
+// consenter is only available if Name is set, and cannot be used along with
+// batch signing, as its blocks carry the signatures of a quorum of orderers.
+type BFT struct {
+	Name       string
+	Library    string
+	Parameters map[string]string
+	WALDir     string
+}
+
 // Retry contains configuration related to retries and timeouts when the
 // connection to the Kafka cluster cannot be established, or when Metadata
 // requests needs to be repeated (because the cluster is in the middle of a
@@ -149,10 +582,78 @@ type Consumer struct {
 
 // Debug contains configuration for the orderer's debug parameters.
 type Debug struct {
-	BroadcastTraceDir string
-	DeliverTraceDir   string
+	BroadcastTraceDir         string
+	DeliverTraceDir           string
+	TxTimelineSize            int
+	MalformedCorpusDir        string
+	MalformedCorpusMaxEntries int
+	FaultInjection            FaultInjection
+}
+
+// FaultInjection contains configuration for the faults injected into the
+// chains of the solo consenter, to exercise the retry logic of clients.
+type FaultInjection struct {
+	Enabled  bool
+	Delay    time.Duration
+	NotReady FaultPattern
+	Drop     FaultPattern
+	Reorder  int
+}
+
+// FaultPattern selects the calls or messages into which a fault is injected
+// by their position on each chain, counted from 1.
+type FaultPattern struct {
+	Positions []uint64
+	Every     uint64
 }
 
+// Operations contains configuration for the operations server.  An empty
+// ListenAddress disables the server.  MetricsLabels are added to every
+// metric served at /metrics.
+type Operations struct {
+	ListenAddress  string
+	TLS            TLS
+	Authentication OperationsAuthentication
+	FlightRecorder FlightRecorder
+	AsyncOps       AsyncOps
+	MetricsLabels  map[string]string
+}
+
+// OperationsAuthentication contains the credentials granting the roles of
+// the operations server.  When none is set, every client accepted by the TLS
+// layer is granted the admin role.
+type OperationsAuthentication struct {
+	Admin   OperationsCredentials
+	Metrics OperationsCredentials
+}
+
+// OperationsCredentials contains the client certificate organizational units
+// and the static bearer tokens granting a role of the operations server.
+type OperationsCredentials struct {
+	ClientOUs []string
+	Tokens    []string
+}
+
+// FlightRecorder contains configuration for the recorder of the recent
+// history of internal metrics served on the operations server.
+type FlightRecorder struct {
+	SampleInterval time.Duration
+	Retention      time.Duration
+}
+
+// AsyncOps contains configuration for the heavy admin operations, such as
+// backups and snapshots, which run in the background.
+type AsyncOps struct {
+	Retention int
+}
+
+// The default resource limits of the filter plugins, which are set per plugin
+// and so are not carried by Defaults.
+const (
+	defaultFilterPluginFuel           = 10000000
+	defaultFilterPluginMaxMemoryPages = 256
+)
+
 // Defaults carries the default orderer configuration values.
 var Defaults = TopLevel{
 	General: General{
@@ -175,6 +676,115 @@ var Defaults = TopLevel{
 		Authentication: Authentication{
 			TimeWindow: time.Duration(15 * time.Minute),
 		},
+		Admission: Admission{
+			Enabled:    false,
+			LatencySLO: 500 * time.Millisecond,
+			Window:     10 * time.Second,
+		},
+		Deduplication: Deduplication{
+			Enabled:   false,
+			CacheSize: 10000,
+		},
+		ReplayProtection: ReplayProtection{
+			Enabled: false,
+		},
+		SLO: SLO{
+			EvaluationInterval: 10 * time.Second,
+		},
+		Broadcast: Broadcast{
+			InFlightWindow:         1,
+			BackpressureRetryAfter: time.Second,
+			ForwardToLeader:        false,
+			ForwardAttempts:        3,
+			ForwardRetryInterval:   500 * time.Millisecond,
+		},
+		Audit: Audit{
+			Enabled:     false,
+			File:        "/var/hyperledger/production/orderer/audit/audit.log",
+			MaxFileSize: 100 * 1024 * 1024,
+		},
+		Standby: Standby{
+			Enabled:      false,
+			PollInterval: 5 * time.Second,
+		},
+		VersionSkew: VersionSkew{
+			Policy:       "warn",
+			MaxMinorSkew: 1,
+		},
+		GameDay: GameDay{
+			Enabled:     false,
+			MaxDuration: 15 * time.Minute,
+		},
+		Backlog: Backlog{
+			MaxMemory:    0,
+			SpillDir:     "",
+			MaxSpillSize: 0,
+			MaxPriority:  16,
+		},
+		Maintenance: Maintenance{
+			CheckInterval: time.Minute,
+			MaxConcurrent: 1,
+		},
+		Analytics: Analytics{
+			TxSizeReport: false,
+		},
+		Privacy: Privacy{
+			Mode:       "",
+			KeyFile:    "",
+			HashLength: 16,
+		},
+		Tracing: Tracing{
+			Enabled:     false,
+			SampleRatio: 0,
+			Capacity:    10000,
+		},
+		Heartbeat: Heartbeat{
+			Enabled:     false,
+			MinInterval: time.Second,
+		},
+		Misbehavior: Misbehavior{
+			Enabled:        false,
+			HalfLife:       10 * time.Minute,
+			BlockThreshold: 0,
+			BlockDuration:  10 * time.Minute,
+			Weights: MisbehaviorWeights{
+				InvalidSignature: 1,
+				Replay:           0.2,
+				MalformedMessage: 1,
+				MalformedConfig:  2,
+			},
+		},
+		IntakeJournal: IntakeJournal{
+			Enabled:   false,
+			Dir:       "/var/hyperledger/production/orderer/intake",
+			Retention: 10 * time.Minute,
+		},
+		Canary: Canary{
+			Enabled:  false,
+			Interval: 30 * time.Second,
+			Timeout:  30 * time.Second,
+		},
+		GRPCWeb: GRPCWeb{
+			Enabled:       false,
+			ListenAddress: "127.0.0.1",
+			ListenPort:    7080,
+		},
+		Backup: Backup{
+			Enabled: false,
+			Dir:     "/var/hyperledger/production/orderer/backups",
+		},
+		Restore: Restore{
+			Enabled:      false,
+			PollInterval: 5 * time.Second,
+		},
+		LogSampling: []LogSampling{
+			{Module: "orderer/common/broadcast", Initial: 100, Thereafter: 100, Tick: time.Second},
+		},
+		BatchSigning: BatchSigning{
+			Enabled:   false,
+			MaxBlocks: 10,
+			MaxDelay:  time.Second,
+		},
 	},
 	RAMLedger: RAMLedger{
 		HistorySize: 10000,
@@ -212,9 +822,24 @@ var Defaults = TopLevel{
 			Enabled: false,
 		},
 	},
+	BFT: BFT{
+		WALDir: "/var/hyperledger/production/orderer/bft",
+	},
 	Debug: Debug{
-		BroadcastTraceDir: "",
-		DeliverTraceDir:   "",
+		BroadcastTraceDir:         "",
+		DeliverTraceDir:           "",
+		TxTimelineSize:            0,
+		MalformedCorpusDir:        "",
+		MalformedCorpusMaxEntries: 1000,
+	},
+	Operations: Operations{
+		FlightRecorder: FlightRecorder{
+			SampleInterval: 10 * time.Second,
+			Retention:      15 * time.Minute,
+		},
+		AsyncOps: AsyncOps{
+			Retention: 100,
+		},
 	},
 }
 
@@ -265,8 +890,39 @@ func (c *TopLevel) completeInitialization(configDir string) {
 		coreconfig.TranslatePathInPlace(configDir, &c.General.TLS.Certificate)
 		coreconfig.TranslatePathInPlace(configDir, &c.General.GenesisFile)
 		coreconfig.TranslatePathInPlace(configDir, &c.General.LocalMSPDir)
+		if c.General.Privacy.KeyFile != "" {
+			coreconfig.TranslatePathInPlace(configDir, &c.General.Privacy.KeyFile)
+		}
+		for i := range c.General.FilterPlugins {
+			coreconfig.TranslatePathInPlace(configDir, &c.General.FilterPlugins[i].Path)
+		}
+		for i := range c.General.Tenants {
+			c.General.Tenants[i].ClientRootCAs = translateCAs(configDir, c.General.Tenants[i].ClientRootCAs)
+		}
+		c.Operations.TLS.ClientRootCAs = translateCAs(configDir, c.Operations.TLS.ClientRootCAs)
+		coreconfig.TranslatePathInPlace(configDir, &c.Operations.TLS.PrivateKey)
+		coreconfig.TranslatePathInPlace(configDir, &c.Operations.TLS.Certificate)
 	}()
 
+	//过滤插件的资源限制
+	for i := range c.General.FilterPlugins {
+		fp := &c.General.FilterPlugins[i]
+		if fp.Path == "" {
+			logger.Panicf("General.FilterPlugins[%d].Path must be set", i)
+		}
+		if fp.Name == "" {
+			fp.Name = fp.Path
+		}
+		if fp.Fuel == 0 {
+			logger.Infof("General.FilterPlugins[%d].Fuel unset, setting to %d", i, defaultFilterPluginFuel)
+			fp.Fuel = defaultFilterPluginFuel
+		}
+		if fp.MaxMemoryPages == 0 {
+			logger.Infof("General.FilterPlugins[%d].MaxMemoryPages unset, setting to %d", i, defaultFilterPluginMaxMemoryPages)
+			fp.MaxMemoryPages = defaultFilterPluginMaxMemoryPages
+		}
+	}
+
 	for {
 		switch {
 		case c.General.LedgerType == "":
@@ -318,10 +974,108 @@ func (c *TopLevel) completeInitialization(configDir string) {
 			logger.Infof("General.Authentication.TimeWindow unset, setting to %s", Defaults.General.Authentication.TimeWindow)
 			c.General.Authentication.TimeWindow = Defaults.General.Authentication.TimeWindow
 
+		case c.General.Admission.Enabled && c.General.Admission.LatencySLO == 0:
+			logger.Infof("Admission control enabled and General.Admission.LatencySLO unset, setting to %s", Defaults.General.Admission.LatencySLO)
+			c.General.Admission.LatencySLO = Defaults.General.Admission.LatencySLO
+		case c.General.Admission.Enabled && c.General.Admission.Window == 0:
+			logger.Infof("Admission control enabled and General.Admission.Window unset, setting to %s", Defaults.General.Admission.Window)
+			c.General.Admission.Window = Defaults.General.Admission.Window
+		case c.General.Deduplication.Enabled && c.General.Deduplication.CacheSize == 0:
+			logger.Infof("Deduplication enabled and General.Deduplication.CacheSize unset, setting to %d", Defaults.General.Deduplication.CacheSize)
+			c.General.Deduplication.CacheSize = Defaults.General.Deduplication.CacheSize
+		case len(c.General.SLO.Channels) > 0 && c.General.SLO.EvaluationInterval == 0:
+			logger.Infof("SLOs declared and General.SLO.EvaluationInterval unset, setting to %s", Defaults.General.SLO.EvaluationInterval)
+			c.General.SLO.EvaluationInterval = Defaults.General.SLO.EvaluationInterval
+		case c.General.Broadcast.InFlightWindow == 0:
+			logger.Infof("General.Broadcast.InFlightWindow unset, setting to %d", Defaults.General.Broadcast.InFlightWindow)
+			c.General.Broadcast.InFlightWindow = Defaults.General.Broadcast.InFlightWindow
+		case c.General.Broadcast.PendingBytesWatermark > 0 && c.General.Broadcast.BackpressureRetryAfter == 0:
+			logger.Infof("General.Broadcast.PendingBytesWatermark set and General.Broadcast.BackpressureRetryAfter unset, setting to %s", Defaults.General.Broadcast.BackpressureRetryAfter)
+			c.General.Broadcast.BackpressureRetryAfter = Defaults.General.Broadcast.BackpressureRetryAfter
+		case c.General.Broadcast.ForwardToLeader && c.General.Broadcast.ForwardAttempts == 0:
+			logger.Infof("General.Broadcast.ForwardToLeader set and General.Broadcast.ForwardAttempts unset, setting to %d", Defaults.General.Broadcast.ForwardAttempts)
+			c.General.Broadcast.ForwardAttempts = Defaults.General.Broadcast.ForwardAttempts
+		case c.General.Broadcast.ForwardToLeader && c.General.Broadcast.ForwardRetryInterval == 0:
+			logger.Infof("General.Broadcast.ForwardToLeader set and General.Broadcast.ForwardRetryInterval unset, setting to %s", Defaults.General.Broadcast.ForwardRetryInterval)
+			c.General.Broadcast.ForwardRetryInterval = Defaults.General.Broadcast.ForwardRetryInterval
+		case c.General.Audit.Enabled && c.General.Audit.File == "":
+			logger.Infof("Audit enabled and General.Audit.File unset, setting to %s", Defaults.General.Audit.File)
+			c.General.Audit.File = Defaults.General.Audit.File
+		case c.General.Audit.Enabled && c.General.Audit.MaxFileSize == 0:
+			logger.Infof("Audit enabled and General.Audit.MaxFileSize unset, setting to %d", Defaults.General.Audit.MaxFileSize)
+			c.General.Audit.MaxFileSize = Defaults.General.Audit.MaxFileSize
+		case c.General.Standby.Enabled && c.General.Standby.PollInterval == 0:
+			logger.Infof("Standby enabled and General.Standby.PollInterval unset, setting to %s", Defaults.General.Standby.PollInterval)
+			c.General.Standby.PollInterval = Defaults.General.Standby.PollInterval
+		case c.General.VersionSkew.Policy == "":
+			logger.Infof("General.VersionSkew.Policy unset, setting to %s", Defaults.General.VersionSkew.Policy)
+			c.General.VersionSkew.Policy = Defaults.General.VersionSkew.Policy
+		case c.General.GameDay.Enabled && c.General.GameDay.MaxDuration == 0:
+			logger.Infof("Game day enabled and General.GameDay.MaxDuration unset, setting to %s", Defaults.General.GameDay.MaxDuration)
+			c.General.GameDay.MaxDuration = Defaults.General.GameDay.MaxDuration
+		case c.General.Privacy.Mode != "" && c.General.Privacy.KeyFile == "":
+			logger.Panicf("General.Privacy.KeyFile must be set if General.Privacy.Mode is set.")
+		case c.General.Privacy.Mode != "" && c.General.Privacy.HashLength == 0:
+			logger.Infof("Privacy mode enabled and General.Privacy.HashLength unset, setting to %d", Defaults.General.Privacy.HashLength)
+			c.General.Privacy.HashLength = Defaults.General.Privacy.HashLength
+		case c.General.Tracing.Enabled && c.General.Tracing.Capacity == 0:
+			logger.Infof("Tracing enabled and General.Tracing.Capacity unset, setting to %d", Defaults.General.Tracing.Capacity)
+			c.General.Tracing.Capacity = Defaults.General.Tracing.Capacity
+		case c.General.Heartbeat.Enabled && c.General.Heartbeat.MinInterval == 0:
+			logger.Infof("Heartbeats enabled and General.Heartbeat.MinInterval unset, setting to %s", Defaults.General.Heartbeat.MinInterval)
+			c.General.Heartbeat.MinInterval = Defaults.General.Heartbeat.MinInterval
+		case c.General.Misbehavior.Enabled && c.General.Misbehavior.HalfLife == 0:
+			logger.Infof("Misbehavior detection enabled and General.Misbehavior.HalfLife unset, setting to %s", Defaults.General.Misbehavior.HalfLife)
+			c.General.Misbehavior.HalfLife = Defaults.General.Misbehavior.HalfLife
+		case c.General.Misbehavior.Enabled && c.General.Misbehavior.BlockThreshold > 0 && c.General.Misbehavior.BlockDuration == 0:
+			logger.Infof("Misbehavior blocking enabled and General.Misbehavior.BlockDuration unset, setting to %s", Defaults.General.Misbehavior.BlockDuration)
+			c.General.Misbehavior.BlockDuration = Defaults.General.Misbehavior.BlockDuration
+		case c.General.Misbehavior.Enabled && c.General.Misbehavior.Weights == MisbehaviorWeights{}:
+			logger.Infof("Misbehavior detection enabled and General.Misbehavior.Weights unset, setting to %+v", Defaults.General.Misbehavior.Weights)
+			c.General.Misbehavior.Weights = Defaults.General.Misbehavior.Weights
+		case c.General.IntakeJournal.Enabled && c.General.IntakeJournal.Dir == "":
+			logger.Infof("Intake journal enabled and General.IntakeJournal.Dir unset, setting to %s", Defaults.General.IntakeJournal.Dir)
+			c.General.IntakeJournal.Dir = Defaults.General.IntakeJournal.Dir
+		case c.General.IntakeJournal.Enabled && c.General.IntakeJournal.Retention == 0:
+			logger.Infof("Intake journal enabled and General.IntakeJournal.Retention unset, setting to %s", Defaults.General.IntakeJournal.Retention)
+			c.General.IntakeJournal.Retention = Defaults.General.IntakeJournal.Retention
+		case c.General.Backup.Enabled && c.General.Backup.Dir == "":
+			logger.Infof("Backups enabled and General.Backup.Dir unset, setting to %s", Defaults.General.Backup.Dir)
+			c.General.Backup.Dir = Defaults.General.Backup.Dir
+		case c.General.Restore.Enabled && c.General.Restore.PollInterval == 0:
+			logger.Infof("Restore enabled and General.Restore.PollInterval unset, setting to %s", Defaults.General.Restore.PollInterval)
+			c.General.Restore.PollInterval = Defaults.General.Restore.PollInterval
+		case c.General.GRPCWeb.Enabled && c.General.GRPCWeb.ListenAddress == "":
+			logger.Infof("gRPC-Web enabled and General.GRPCWeb.ListenAddress unset, setting to %s", Defaults.General.GRPCWeb.ListenAddress)
+			c.General.GRPCWeb.ListenAddress = Defaults.General.GRPCWeb.ListenAddress
+		case c.General.GRPCWeb.Enabled && c.General.GRPCWeb.ListenPort == 0:
+			logger.Infof("gRPC-Web enabled and General.GRPCWeb.ListenPort unset, setting to %d", Defaults.General.GRPCWeb.ListenPort)
+			c.General.GRPCWeb.ListenPort = Defaults.General.GRPCWeb.ListenPort
+		case c.General.Canary.Enabled && c.General.Canary.Interval == 0:
+			logger.Infof("Canary enabled and General.Canary.Interval unset, setting to %s", Defaults.General.Canary.Interval)
+			c.General.Canary.Interval = Defaults.General.Canary.Interval
+		case c.General.Canary.Enabled && c.General.Canary.Timeout == 0:
+			logger.Infof("Canary enabled and General.Canary.Timeout unset, setting to %s", Defaults.General.Canary.Timeout)
+			c.General.Canary.Timeout = Defaults.General.Canary.Timeout
+		case c.General.BatchSigning.Enabled && c.General.BatchSigning.MaxBlocks == 0:
+			logger.Infof("Batch signing enabled and General.BatchSigning.MaxBlocks unset, setting to %d", Defaults.General.BatchSigning.MaxBlocks)
+			c.General.BatchSigning.MaxBlocks = Defaults.General.BatchSigning.MaxBlocks
+		case c.General.BatchSigning.Enabled && c.General.BatchSigning.MaxDelay == 0:
+			logger.Infof("Batch signing enabled and General.BatchSigning.MaxDelay unset, setting to %s", Defaults.General.BatchSigning.MaxDelay)
+			c.General.BatchSigning.MaxDelay = Defaults.General.BatchSigning.MaxDelay
+
+		case c.Debug.MalformedCorpusDir != "" && c.Debug.MalformedCorpusMaxEntries == 0:
+			logger.Infof("Debug.MalformedCorpusDir set and Debug.MalformedCorpusMaxEntries unset, setting to %d", Defaults.Debug.MalformedCorpusMaxEntries)
+			c.Debug.MalformedCorpusMaxEntries = Defaults.Debug.MalformedCorpusMaxEntries
+
 		case c.FileLedger.Prefix == "":
 			logger.Infof("FileLedger.Prefix unset, setting to %s", Defaults.FileLedger.Prefix)
 			c.FileLedger.Prefix = Defaults.FileLedger.Prefix
 
+		case c.BFT.Name != "" && c.BFT.WALDir == "":
+			logger.Infof("BFT.Name set and BFT.WALDir unset, setting to %s", Defaults.BFT.WALDir)
+			c.BFT.WALDir = Defaults.BFT.WALDir
+
 		case c.Kafka.Retry.ShortInterval == 0:
 			logger.Infof("Kafka.Retry.ShortInterval unset, setting to %v", Defaults.Kafka.Retry.ShortInterval)
 			c.Kafka.Retry.ShortInterval = Defaults.Kafka.Retry.ShortInterval
@@ -367,6 +1121,16 @@ func (c *TopLevel) completeInitialization(configDir string) {
 			logger.Infof("Kafka.Version unset, setting to %v", Defaults.Kafka.Version)
 			c.Kafka.Version = Defaults.Kafka.Version
 
+		case c.Operations.FlightRecorder.SampleInterval == 0:
+			logger.Infof("Operations.FlightRecorder.SampleInterval unset, setting to %v", Defaults.Operations.FlightRecorder.SampleInterval)
+			c.Operations.FlightRecorder.SampleInterval = Defaults.Operations.FlightRecorder.SampleInterval
+		case c.Operations.FlightRecorder.Retention == 0:
+			logger.Infof("Operations.FlightRecorder.Retention unset, setting to %v", Defaults.Operations.FlightRecorder.Retention)
+			c.Operations.FlightRecorder.Retention = Defaults.Operations.FlightRecorder.Retention
+		case c.Operations.AsyncOps.Retention == 0:
+			logger.Infof("Operations.AsyncOps.Retention unset, setting to %v", Defaults.Operations.AsyncOps.Retention)
+			c.Operations.AsyncOps.Retention = Defaults.Operations.AsyncOps.Retention
+
 		default:
 			return
 		}
@@ -0,0 +1,417 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package maintenance runs the maintenance operations of the orderer, such
+// as rotating the audit log or compacting the block index, during the
+// maintenance windows declared in its configuration, so that operators need
+// not schedule calls to the operations service from outside the orderer.
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const pkgLogID = "orderer/common/maintenance"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// DefaultCheckInterval is how often the windows are checked for due
+// operations when no interval is configured.
+const DefaultCheckInterval = time.Minute
+
+// Operation is a maintenance operation.  The context is done when the
+// window the operation was started in closes or the Scheduler is stopped.
+type Operation func(ctx context.Context) error
+
+// Window declares a maintenance window opening at Start, a time of day such
+// as 02:00, on each of the Days, such as sat, or every day if none is
+// declared, and lasting for Duration.
+type Window struct {
+	Days     []string
+	Start    string
+	Duration time.Duration
+}
+
+// Task declares that an operation runs during the maintenance windows, at
+// most once every Interval, or once per window if no interval is declared.
+type Task struct {
+	Operation string
+	Interval  time.Duration
+}
+
+// Config contains the configuration of a Scheduler.
+type Config struct {
+	// Location is the time zone of the windows, the local one if empty
+	Location string
+
+	// CheckInterval is how often the windows are checked for due operations
+	CheckInterval time.Duration
+
+	// MaxConcurrent is the number of operations which may run at the same
+	// time, 1 if not set
+	MaxConcurrent int
+
+	Windows []Window
+	Tasks   []Task
+}
+
+type window struct {
+	days     map[time.Weekday]bool
+	start    int
+	duration time.Duration
+}
+
+// TaskStatus is the state of a task.
+type TaskStatus struct {
+	Operation string     `json:"operation"`
+	Interval  string     `json:"interval,omitempty"`
+	Running   bool       `json:"running"`
+	Runs      int        `json:"runs"`
+	Started   *time.Time `json:"started,omitempty"`
+	Finished  *time.Time `json:"finished,omitempty"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// Status is the exported form of the state of the Scheduler.
+type Status struct {
+	Now        time.Time     `json:"now"`
+	WindowEnd  *time.Time    `json:"windowEnd,omitempty"`
+	NextWindow *time.Time    `json:"nextWindow,omitempty"`
+	Tasks      []*TaskStatus `json:"tasks"`
+}
+
+type task struct {
+	conf      Task
+	operation Operation
+	status    TaskStatus
+
+	// windowEnd is the end of the window the task last started in
+	windowEnd time.Time
+}
+
+// Scheduler starts the tasks due during the maintenance windows.  A task
+// never runs twice at the same time, and no more than MaxConcurrent tasks
+// run at the same time; a task which cannot start because of them starts
+// at a later check if its window is still open.
+type Scheduler struct {
+	interval time.Duration
+	location *time.Location
+	windows  []window
+	now      func() time.Time
+
+	mutex   sync.Mutex
+	tasks   []*task
+	running int
+	max     int
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stop    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewScheduler creates a Scheduler of the tasks, running the operations of
+// their names, or returns an error if a window or task is invalid.
+func NewScheduler(conf Config, operations map[string]Operation) (*Scheduler, error) {
+	location := time.Local
+	if conf.Location != "" {
+		var err error
+		if location, err = time.LoadLocation(conf.Location); err != nil {
+			return nil, errors.Wrapf(err, "invalid maintenance time zone %s", conf.Location)
+		}
+	}
+	if conf.MaxConcurrent < 0 {
+		return nil, errors.Errorf("maximum concurrent maintenance operations must not be negative, got %d", conf.MaxConcurrent)
+	}
+	s := &Scheduler{
+		interval: conf.CheckInterval,
+		location: location,
+		now:      time.Now,
+		max:      conf.MaxConcurrent,
+	}
+	if s.interval <= 0 {
+		s.interval = DefaultCheckInterval
+	}
+	if s.max == 0 {
+		s.max = 1
+	}
+	s.ctx, s.cancel = context.WithCancel(context.Background())
+
+	for i, w := range conf.Windows {
+		parsed, err := parseWindow(w)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid maintenance window %d", i+1)
+		}
+		s.windows = append(s.windows, parsed)
+	}
+	scheduled := map[string]bool{}
+	for _, t := range conf.Tasks {
+		operation, ok := operations[t.Operation]
+		if !ok {
+			return nil, errors.Errorf("unknown maintenance operation %q, the operations of this orderer are %s", t.Operation, strings.Join(names(operations), ", "))
+		}
+		if scheduled[t.Operation] {
+			return nil, errors.Errorf("maintenance operation %s scheduled more than once", t.Operation)
+		}
+		if t.Interval < 0 {
+			return nil, errors.Errorf("maintenance operation %s has a negative interval", t.Operation)
+		}
+		scheduled[t.Operation] = true
+		status := TaskStatus{Operation: t.Operation}
+		if t.Interval > 0 {
+			status.Interval = t.Interval.String()
+		}
+		s.tasks = append(s.tasks, &task{conf: t, operation: operation, status: status})
+	}
+	if len(s.tasks) > 0 && len(s.windows) == 0 {
+		return nil, errors.New("maintenance operations scheduled without a maintenance window")
+	}
+	return s, nil
+}
+
+func names(operations map[string]Operation) []string {
+	var names []string
+	for name := range operations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func parseWindow(w Window) (window, error) {
+	t, err := time.Parse("15:04", w.Start)
+	if err != nil {
+		return window{}, errors.Errorf("%q is not a time of day such as 02:00", w.Start)
+	}
+	if w.Duration <= 0 || w.Duration > 24*time.Hour {
+		return window{}, errors.Errorf("duration %s is not between 0 and 24h", w.Duration)
+	}
+	parsed := window{days: map[time.Weekday]bool{}, start: t.Hour()*60 + t.Minute(), duration: w.Duration}
+	for _, name := range w.Days {
+		day, ok := weekdays[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return window{}, errors.Errorf("invalid day %s", name)
+		}
+		parsed.days[day] = true
+	}
+	if len(w.Days) == 0 {
+		for day := time.Sunday; day <= time.Saturday; day++ {
+			parsed.days[day] = true
+		}
+	}
+	return parsed, nil
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+}
+
+// opening returns the opening of the window on the day, days after the day
+// of now, if the window opens that day
+func (w window) opening(now time.Time, days int) (time.Time, bool) {
+	opening := time.Date(now.Year(), now.Month(), now.Day()+days, w.start/60, w.start%60, 0, 0, now.Location())
+	return opening, w.days[opening.Weekday()]
+}
+
+// windowEnd returns the latest end of the windows open at now
+func (s *Scheduler) windowEnd(now time.Time) (time.Time, bool) {
+	now = now.In(s.location)
+	var end time.Time
+	for _, w := range s.windows {
+		//窗口最长一天，可能从前一天开始
+		for days := -1; days <= 0; days++ {
+			opening, ok := w.opening(now, days)
+			if ok && !now.Before(opening) && now.Before(opening.Add(w.duration)) && opening.Add(w.duration).After(end) {
+				end = opening.Add(w.duration)
+			}
+		}
+	}
+	return end, !end.IsZero()
+}
+
+// nextWindow returns the next opening of a window after now
+func (s *Scheduler) nextWindow(now time.Time) (time.Time, bool) {
+	now = now.In(s.location)
+	var next time.Time
+	for _, w := range s.windows {
+		for days := 0; days <= 7; days++ {
+			opening, ok := w.opening(now, days)
+			if ok && opening.After(now) {
+				if next.IsZero() || opening.Before(next) {
+					next = opening
+				}
+				break
+			}
+		}
+	}
+	return next, !next.IsZero()
+}
+
+// Start checks the windows for due tasks every interval until Stop is called
+func (s *Scheduler) Start() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.stop != nil {
+		s.mutex.Unlock()
+		return
+	}
+	s.stop = make(chan struct{})
+	stop := s.stop
+	s.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		s.Check()
+		for {
+			select {
+			case <-ticker.C:
+				s.Check()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops checking the windows and cancels the running operations,
+// waiting for them to return
+func (s *Scheduler) Stop() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	if s.stop != nil {
+		close(s.stop)
+		s.stop = nil
+	}
+	s.cancel()
+	s.mutex.Unlock()
+	s.wg.Wait()
+}
+
+// Check starts the tasks due in the window open now, if any, and returns the
+// operations started
+func (s *Scheduler) Check() []string {
+	if s == nil {
+		return nil
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := s.now()
+	end, open := s.windowEnd(now)
+	if !open || s.ctx.Err() != nil {
+		return nil
+	}
+	var started []string
+	for _, t := range s.tasks {
+		if s.running >= s.max {
+			break
+		}
+		if t.status.Running || !t.due(now, end) {
+			continue
+		}
+		t.status.Running = true
+		t.status.Runs++
+		t.status.Started = &now
+		t.windowEnd = end
+		s.running++
+		s.run(t, end, end.Sub(now))
+		started = append(started, t.conf.Operation)
+	}
+	return started
+}
+
+// due returns whether the task is due in the window ending at end
+func (t *task) due(now, end time.Time) bool {
+	if t.status.Started == nil {
+		return true
+	}
+	if t.conf.Interval == 0 {
+		//未声明间隔时每个窗口运行一次
+		return !t.windowEnd.Equal(end)
+	}
+	return now.Sub(*t.status.Started) >= t.conf.Interval
+}
+
+// run must be called with the mutex held
+func (s *Scheduler) run(t *task, end time.Time, remaining time.Duration) {
+	logger.Infof("Starting maintenance operation %s, window closes at %s", t.conf.Operation, end.Format(time.RFC3339))
+	ctx, cancel := context.WithTimeout(s.ctx, remaining)
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer cancel()
+		err := t.operation(ctx)
+
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		finished := s.now()
+		t.status.Running = false
+		t.status.Finished = &finished
+		t.status.Error = ""
+		s.running--
+		if err != nil {
+			t.status.Error = err.Error()
+			logger.Errorf("Maintenance operation %s failed: %s", t.conf.Operation, err)
+			return
+		}
+		logger.Infof("Maintenance operation %s completed in %s", t.conf.Operation, finished.Sub(*t.status.Started))
+	}()
+}
+
+// Status returns the state of the tasks, in the order they are declared,
+// and of the windows
+func (s *Scheduler) Status() *Status {
+	status := &Status{Tasks: []*TaskStatus{}}
+	if s == nil {
+		return status
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	status.Now = s.now()
+	if end, ok := s.windowEnd(status.Now); ok {
+		status.WindowEnd = &end
+	}
+	if next, ok := s.nextWindow(status.Now); ok {
+		status.NextWindow = &next
+	}
+	for _, t := range s.tasks {
+		copied := t.status
+		status.Tasks = append(status.Tasks, &copied)
+	}
+	return status
+}
+
+// ServeHTTP writes the status as JSON
+func (s *Scheduler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.Status()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
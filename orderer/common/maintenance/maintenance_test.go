@@ -0,0 +1,204 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package maintenance
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+// blockingOperation returns an operation which runs until released, and the
+// channels of its starts and of its releases
+func blockingOperation() (Operation, chan context.Context, chan error) {
+	started := make(chan context.Context, 10)
+	release := make(chan error)
+	return func(ctx context.Context) error {
+		started <- ctx
+		select {
+		case err := <-release:
+			return err
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}, started, release
+}
+
+func TestNewScheduler(t *testing.T) {
+	noop := map[string]Operation{"rotate-audit-log": func(context.Context) error { return nil }}
+	window := Window{Start: "02:00", Duration: time.Hour}
+	for _, tc := range []struct {
+		conf Config
+		err  string
+	}{
+		{Config{Windows: []Window{window}, Tasks: []Task{{Operation: "snapshot-raft"}}}, `unknown maintenance operation "snapshot-raft", the operations of this orderer are rotate-audit-log`},
+		{Config{Windows: []Window{window}, Tasks: []Task{{Operation: "rotate-audit-log"}, {Operation: "rotate-audit-log"}}}, "maintenance operation rotate-audit-log scheduled more than once"},
+		{Config{Windows: []Window{window}, Tasks: []Task{{Operation: "rotate-audit-log", Interval: -time.Hour}}}, "maintenance operation rotate-audit-log has a negative interval"},
+		{Config{Tasks: []Task{{Operation: "rotate-audit-log"}}}, "maintenance operations scheduled without a maintenance window"},
+		{Config{Windows: []Window{window, {Start: "2am", Duration: time.Hour}}}, `invalid maintenance window 2: "2am" is not a time of day such as 02:00`},
+		{Config{Windows: []Window{{Start: "02:00", Duration: 25 * time.Hour}}}, "invalid maintenance window 1: duration 25h0m0s is not between 0 and 24h"},
+		{Config{Windows: []Window{{Days: []string{"caturday"}, Start: "02:00", Duration: time.Hour}}}, "invalid maintenance window 1: invalid day caturday"},
+		{Config{MaxConcurrent: -1}, "maximum concurrent maintenance operations must not be negative, got -1"},
+		{Config{Location: "Nowhere/Special"}, "invalid maintenance time zone Nowhere/Special: unknown time zone Nowhere/Special"},
+	} {
+		_, err := NewScheduler(tc.conf, noop)
+		assert.EqualError(t, err, tc.err)
+	}
+}
+
+func TestWindows(t *testing.T) {
+	s, err := NewScheduler(Config{
+		Location: "UTC",
+		Windows: []Window{
+			{Days: []string{"sat"}, Start: "23:00", Duration: 3 * time.Hour},
+			{Days: []string{"Wed"}, Start: "02:00", Duration: time.Hour},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	// 2018-03-03是星期六
+	sat := time.Date(2018, 3, 3, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		now  time.Time
+		open bool
+		end  time.Time
+		next time.Time
+	}{
+		{sat.Add(22 * time.Hour), false, time.Time{}, sat.Add(23 * time.Hour)},
+		{sat.Add(23 * time.Hour), true, sat.Add(26 * time.Hour), sat.Add(4*24*time.Hour + 2*time.Hour)},
+		// 跨越午夜的窗口在次日仍然开放
+		{sat.Add(25 * time.Hour), true, sat.Add(26 * time.Hour), sat.Add(4*24*time.Hour + 2*time.Hour)},
+		{sat.Add(26 * time.Hour), false, time.Time{}, sat.Add(4*24*time.Hour + 2*time.Hour)},
+		{sat.Add(4*24*time.Hour + 150*time.Minute), true, sat.Add(4*24*time.Hour + 3*time.Hour), sat.Add(7*24*time.Hour + 23*time.Hour)},
+	} {
+		end, open := s.windowEnd(tc.now)
+		assert.Equal(t, tc.open, open, "window open at %s", tc.now)
+		assert.True(t, tc.end.Equal(end), "window open at %s ends at %s", tc.now, end)
+		next, ok := s.nextWindow(tc.now)
+		require.True(t, ok)
+		assert.True(t, tc.next.Equal(next), "next window after %s opens at %s", tc.now, next)
+	}
+}
+
+func TestScheduler(t *testing.T) {
+	compact, compactStarted, compactRelease := blockingOperation()
+	rotate, rotateStarted, rotateRelease := blockingOperation()
+	s, err := NewScheduler(Config{
+		Location: "UTC",
+		Windows:  []Window{{Start: "02:00", Duration: 2 * time.Hour}},
+		Tasks: []Task{
+			{Operation: "compact-block-index"},
+			{Operation: "rotate-audit-log", Interval: 30 * time.Minute},
+		},
+	}, map[string]Operation{"compact-block-index": compact, "rotate-audit-log": rotate})
+	require.NoError(t, err)
+	day := time.Date(2018, 3, 3, 0, 0, 0, 0, time.UTC)
+	now := day.Add(time.Hour)
+	s.now = func() time.Time { return now }
+
+	// 窗口外不运行
+	assert.Empty(t, s.Check())
+
+	// 同时只运行一个操作，运行中的操作不重复启动
+	now = day.Add(2 * time.Hour)
+	assert.Equal(t, []string{"compact-block-index"}, s.Check())
+	ctx := <-compactStarted
+	deadline, ok := ctx.Deadline()
+	require.True(t, ok)
+	assert.WithinDuration(t, time.Now().Add(2*time.Hour), deadline, time.Minute)
+	assert.Empty(t, s.Check())
+	compactRelease <- errors.New("compaction failed")
+	s.wg.Wait()
+
+	now = now.Add(time.Minute)
+	assert.Equal(t, []string{"rotate-audit-log"}, s.Check())
+	<-rotateStarted
+	rotateRelease <- nil
+	s.wg.Wait()
+
+	// 未声明间隔的操作每个窗口运行一次，其余的操作按间隔运行
+	now = now.Add(10 * time.Minute)
+	assert.Empty(t, s.Check())
+	now = now.Add(20 * time.Minute)
+	assert.Equal(t, []string{"rotate-audit-log"}, s.Check())
+	<-rotateStarted
+	rotateRelease <- nil
+	s.wg.Wait()
+
+	status := s.Status()
+	require.Len(t, status.Tasks, 2)
+	assert.Equal(t, "compact-block-index", status.Tasks[0].Operation)
+	assert.Equal(t, 1, status.Tasks[0].Runs)
+	assert.Equal(t, "compaction failed", status.Tasks[0].Error)
+	assert.Equal(t, 2, status.Tasks[1].Runs)
+	assert.Equal(t, "30m0s", status.Tasks[1].Interval)
+	assert.Empty(t, status.Tasks[1].Error)
+	require.NotNil(t, status.WindowEnd)
+	assert.Equal(t, day.Add(4*time.Hour), status.WindowEnd.UTC())
+
+	// 下一个窗口再次运行，停止调度器时取消运行中的操作
+	now = day.Add(26 * time.Hour)
+	assert.Equal(t, []string{"compact-block-index"}, s.Check())
+	<-compactStarted
+	s.Stop()
+	status = s.Status()
+	assert.False(t, status.Tasks[0].Running)
+	assert.Equal(t, context.Canceled.Error(), status.Tasks[0].Error)
+	assert.Empty(t, s.Check())
+}
+
+func TestMaxConcurrent(t *testing.T) {
+	compact, compactStarted, compactRelease := blockingOperation()
+	rotate, rotateStarted, rotateRelease := blockingOperation()
+	s, err := NewScheduler(Config{
+		MaxConcurrent: 2,
+		Windows:       []Window{{Start: "00:00", Duration: 24 * time.Hour}},
+		Tasks:         []Task{{Operation: "compact-block-index"}, {Operation: "rotate-audit-log"}},
+	}, map[string]Operation{"compact-block-index": compact, "rotate-audit-log": rotate})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"compact-block-index", "rotate-audit-log"}, s.Check())
+	<-compactStarted
+	<-rotateStarted
+	compactRelease <- nil
+	rotateRelease <- nil
+	s.wg.Wait()
+}
+
+func TestServeHTTP(t *testing.T) {
+	s, err := NewScheduler(Config{
+		Windows: []Window{{Start: "02:00", Duration: time.Hour}},
+		Tasks:   []Task{{Operation: "rotate-audit-log"}},
+	}, map[string]Operation{"rotate-audit-log": func(context.Context) error { return nil }})
+	require.NoError(t, err)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/maintenance", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var status Status
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	require.Len(t, status.Tasks, 1)
+	assert.Equal(t, "rotate-audit-log", status.Tasks[0].Operation)
+	assert.NotNil(t, status.NextWindow)
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/maintenance", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	var disabled *Scheduler
+	assert.Empty(t, disabled.Check())
+	assert.Empty(t, disabled.Status().Tasks)
+	disabled.Start()
+	disabled.Stop()
+}
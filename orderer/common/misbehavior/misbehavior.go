@@ -0,0 +1,361 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package misbehavior scores the suspicious messages broadcast by each client
+// and identity, such as messages with invalid signatures, replays of
+// transactions and malformed config updates, so that operators can spot the
+// Byzantine clients of the ordering service and, optionally, have the orderer
+// block them once their score crosses a threshold.
+//
+// The messages whose signature was not checked cannot be attributed to their
+// claimed creator, who could otherwise be framed by anyone, and are charged to
+// the client which sent them, identified by its TLS certificate or, without
+// one, by its host.  The messages whose signature was checked, such as
+// replays, are charged to their creator as well.
+//
+// The clients are told apart by their real host, even in privacy mode, so
+// that the clients sharing an anonymized host are neither blocked together
+// nor able to evade their block by switching hosts; the logs and the report
+// only show the anonymized host.
+package misbehavior
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/flogging"
+	mspprotos "github.com/hyperledger/fabric/protos/msp"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/misbehavior"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// sweepInterval is how often the subjects whose score decayed away are
+// forgotten
+const sweepInterval = time.Minute
+
+// forgottenScore is the score below which an unblocked subject is forgotten
+const forgottenScore = 0.01
+
+// ErrBlocked is the cause of the errors returned for the messages of blocked
+// clients and identities.
+var ErrBlocked = errors.New("blocked for misbehaving")
+
+// Pattern is a kind of suspicious message
+type Pattern string
+
+const (
+	// InvalidSignature is a message whose signature does not satisfy the
+	// policy of its channel
+	InvalidSignature Pattern = "invalid_signature"
+
+	// Replay is a message resubmitting a transaction already enqueued
+	Replay Pattern = "replay"
+
+	// MalformedMessage is a message whose headers cannot be parsed
+	MalformedMessage Pattern = "malformed_message"
+
+	// MalformedConfig is a config update rejected as invalid
+	MalformedConfig Pattern = "malformed_config"
+)
+
+// Subject kinds
+const (
+	// Client is a client of the orderer, identified by its TLS certificate or
+	// its host
+	Client = "client"
+
+	// Identity is the creator of messages whose signature was checked
+	Identity = "identity"
+)
+
+// Config contains the configuration of a Detector.
+type Config struct {
+	// Weights is the score of each pattern, patterns without a weight not
+	// being scored
+	Weights map[Pattern]float64
+
+	// HalfLife is how long it takes the score of a subject to halve
+	HalfLife time.Duration
+
+	// BlockThreshold is the score from which a subject is blocked, never if 0
+	BlockThreshold float64
+
+	// BlockDuration is how long a subject stays blocked
+	BlockDuration time.Duration
+}
+
+// Subject is a client or an identity in the report of a Detector.  The key
+// of a client is its anonymized ID in privacy mode, which several clients may
+// share.
+type Subject struct {
+	Key          string             `json:"key"`
+	Kind         string             `json:"kind"`
+	MSPID        string             `json:"msp_id,omitempty"`
+	Score        float64            `json:"score"`
+	Counts       map[Pattern]uint64 `json:"counts"`
+	LastChannel  string             `json:"last_channel,omitempty"`
+	FirstSeen    time.Time          `json:"first_seen"`
+	LastSeen     time.Time          `json:"last_seen"`
+	BlockedUntil *time.Time         `json:"blocked_until,omitempty"`
+}
+
+// Report is the subjects of a Detector, the highest score first
+type Report struct {
+	Subjects []Subject `json:"subjects"`
+}
+
+type subject struct {
+	Subject
+	updated time.Time // when the score was last decayed
+}
+
+// Detector aggregates the suspicious messages of each subject into a score
+// decaying with time, and blocks the subjects whose score crosses the
+// threshold if there is one.  The scores are held in memory, so they restart
+// from zero when the orderer restarts.
+type Detector struct {
+	conf Config
+	now  func() time.Time
+
+	mutex     sync.Mutex
+	subjects  map[string]*subject
+	lastSweep time.Time
+}
+
+// New creates a Detector with no subject.
+func New(conf Config) *Detector {
+	return &Detector{
+		conf:     conf,
+		now:      time.Now,
+		subjects: map[string]*subject{},
+	}
+}
+
+// ClientKey returns the key of the client identified by the given ID
+func ClientKey(clientID string) string {
+	return Client + ":" + clientID
+}
+
+// IdentityKey returns the key of the identity serialized as creator, and its
+// MSP ID if it can be parsed
+func IdentityKey(creator []byte) (string, string) {
+	hash := sha256.Sum256(creator)
+	key := Identity + ":" + hex.EncodeToString(hash[:])
+	sid := &mspprotos.SerializedIdentity{}
+	if err := proto.Unmarshal(creator, sid); err != nil {
+		return key, ""
+	}
+	return key, sid.Mspid
+}
+
+// RecordClient charges the suspicious message of the pattern sent on the
+// channel to the client with the given ID, which is logged and reported by
+// the given anonymized ID instead, the same as the ID if privacy mode is
+// disabled.  The MSP ID of the creator claimed by the message, which may be
+// nil, is kept for the report but not trusted.
+func (d *Detector) RecordClient(clientID, anonymizedID string, claimedCreator []byte, pattern Pattern, channelID string) {
+	var mspID string
+	if len(claimedCreator) > 0 {
+		_, mspID = IdentityKey(claimedCreator)
+	}
+	d.record(ClientKey(clientID), ClientKey(anonymizedID), Client, mspID, pattern, channelID)
+}
+
+// RecordIdentity charges the suspicious message of the pattern sent on the
+// channel to the identity serialized as creator, whose signature was checked.
+func (d *Detector) RecordIdentity(creator []byte, pattern Pattern, channelID string) {
+	key, mspID := IdentityKey(creator)
+	d.record(key, key, Identity, mspID, pattern, channelID)
+}
+
+// record charges the suspicious message to the subject with the given key,
+// shown by the given key in the logs and the report
+func (d *Detector) record(key, shownKey, kind, mspID string, pattern Pattern, channelID string) {
+	weight, ok := d.conf.Weights[pattern]
+	if !ok || weight <= 0 {
+		return
+	}
+	now := d.now()
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	d.sweep(now)
+
+	s, ok := d.subjects[key]
+	if !ok {
+		s = &subject{
+			Subject: Subject{Key: shownKey, Kind: kind, Counts: map[Pattern]uint64{}, FirstSeen: now},
+			updated: now,
+		}
+		d.subjects[key] = s
+	}
+	d.decay(s, now)
+	s.Score += weight
+	s.Counts[pattern]++
+	s.LastSeen = now
+	s.LastChannel = channelID
+	if mspID != "" {
+		s.MSPID = mspID
+	}
+
+	if d.conf.BlockThreshold > 0 && s.Score >= d.conf.BlockThreshold && !d.blocked(s, now) {
+		until := now.Add(d.conf.BlockDuration)
+		s.BlockedUntil = &until
+		logger.Warningf("Blocking %s %s of MSP %q with score %.2f until %s, last on channel %s for %s", kind, s.Key, s.MSPID, s.Score, until.Format(time.RFC3339), channelID, pattern)
+	}
+}
+
+// decay decays the score of the subject up to now
+func (d *Detector) decay(s *subject, now time.Time) {
+	if d.conf.HalfLife > 0 {
+		if elapsed := now.Sub(s.updated); elapsed > 0 {
+			s.Score *= math.Pow(0.5, float64(elapsed)/float64(d.conf.HalfLife))
+		}
+	}
+	s.updated = now
+}
+
+func (d *Detector) blocked(s *subject, now time.Time) bool {
+	return s.BlockedUntil != nil && now.Before(*s.BlockedUntil)
+}
+
+// sweep forgets the unblocked subjects whose score decayed away, at most
+// every sweepInterval.  It must be called with the mutex held.
+func (d *Detector) sweep(now time.Time) {
+	if now.Sub(d.lastSweep) < sweepInterval {
+		return
+	}
+	d.lastSweep = now
+	for key, s := range d.subjects {
+		d.decay(s, now)
+		if s.Score < forgottenScore && !d.blocked(s, now) {
+			delete(d.subjects, key)
+		}
+	}
+}
+
+// blockedError is returned for the messages of a blocked subject, with how
+// long until it is unblocked
+type blockedError struct {
+	error
+	retryAfter time.Duration
+}
+
+// Cause returns ErrBlocked.
+func (e *blockedError) Cause() error { return ErrBlocked }
+
+// RetryAfter returns how long until the subject is unblocked.
+func (e *blockedError) RetryAfter() time.Duration { return e.retryAfter }
+
+// Blocked returns an error if the client with the given ID, or the identity
+// serialized as creator, is blocked.  The creator may be nil.
+func (d *Detector) Blocked(clientID string, creator []byte) error {
+	keys := []string{ClientKey(clientID)}
+	if len(creator) > 0 {
+		key, _ := IdentityKey(creator)
+		keys = append(keys, key)
+	}
+	now := d.now()
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	for _, key := range keys {
+		if s, ok := d.subjects[key]; ok && d.blocked(s, now) {
+			return &blockedError{
+				error:      errors.Wrapf(ErrBlocked, "%s %s", s.Kind, s.Key),
+				retryAfter: s.BlockedUntil.Sub(now),
+			}
+		}
+	}
+	return nil
+}
+
+// Unblock unblocks and forgets the subjects with the given key of the
+// report, returning whether one was known
+func (d *Detector) Unblock(key string) bool {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	var ok bool
+	for k, s := range d.subjects {
+		if s.Key == key {
+			delete(d.subjects, k)
+			ok = true
+		}
+	}
+	if ok {
+		logger.Infof("Forgot the misbehavior of %s", key)
+	}
+	return ok
+}
+
+// Report returns the subjects with their score decayed up to now, the
+// highest score first
+func (d *Detector) Report() Report {
+	now := d.now()
+	d.mutex.Lock()
+	report := Report{Subjects: make([]Subject, 0, len(d.subjects))}
+	for _, s := range d.subjects {
+		d.decay(s, now)
+		subject := s.Subject
+		subject.Counts = make(map[Pattern]uint64, len(s.Counts))
+		for pattern, count := range s.Counts {
+			subject.Counts[pattern] = count
+		}
+		if !d.blocked(s, now) {
+			subject.BlockedUntil = nil
+		}
+		report.Subjects = append(report.Subjects, subject)
+	}
+	d.mutex.Unlock()
+
+	sort.Slice(report.Subjects, func(i, j int) bool {
+		if report.Subjects[i].Score != report.Subjects[j].Score {
+			return report.Subjects[i].Score > report.Subjects[j].Score
+		}
+		return report.Subjects[i].Key < report.Subjects[j].Key
+	})
+	return report
+}
+
+// ServeHTTP serves the report as JSON on GET, and unblocks and forgets the
+// subject whose key is the subject query parameter on DELETE
+func (d *Detector) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(d.Report()); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	case http.MethodDelete:
+		key := req.URL.Query().Get("subject")
+		if key == "" {
+			http.Error(w, "missing subject", http.StatusBadRequest)
+			return
+		}
+		if !d.Unblock(key) {
+			http.Error(w, fmt.Sprintf("unknown subject %s", key), http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.Header().Set("Allow", "GET, DELETE")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,181 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package misbehavior
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/protos/msp"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var creator = utils.MarshalOrPanic(&msp.SerializedIdentity{Mspid: "Org1MSP", IdBytes: []byte("cert")})
+
+func newTestDetector(conf Config) (*Detector, *time.Time) {
+	now := time.Unix(1500000000, 0)
+	d := New(conf)
+	d.now = func() time.Time { return now }
+	return d, &now
+}
+
+func TestScoreDecays(t *testing.T) {
+	d, now := newTestDetector(Config{
+		Weights:  map[Pattern]float64{InvalidSignature: 2, MalformedMessage: 1},
+		HalfLife: time.Minute,
+	})
+
+	d.RecordClient("host1", "host1", creator, InvalidSignature, "mychannel")
+	d.RecordClient("host1", "host1", nil, MalformedMessage, "")
+	// patterns without a weight are not scored
+	d.RecordClient("host2", "host2", nil, Replay, "mychannel")
+
+	report := d.Report()
+	require.Len(t, report.Subjects, 1)
+	subject := report.Subjects[0]
+	assert.Equal(t, ClientKey("host1"), subject.Key)
+	assert.Equal(t, Client, subject.Kind)
+	assert.Equal(t, "Org1MSP", subject.MSPID)
+	assert.Equal(t, 3.0, subject.Score)
+	assert.Equal(t, map[Pattern]uint64{InvalidSignature: 1, MalformedMessage: 1}, subject.Counts)
+	assert.Nil(t, subject.BlockedUntil)
+
+	*now = now.Add(2 * time.Minute)
+	assert.InDelta(t, 0.75, d.Report().Subjects[0].Score, 1e-9)
+
+	// idle subjects are forgotten once their score decayed away
+	*now = now.Add(time.Hour)
+	d.RecordIdentity(creator, MalformedMessage, "mychannel")
+	report = d.Report()
+	require.Len(t, report.Subjects, 1)
+	assert.Equal(t, Identity, report.Subjects[0].Kind)
+}
+
+func TestReportOrder(t *testing.T) {
+	d, _ := newTestDetector(Config{Weights: map[Pattern]float64{Replay: 1}})
+
+	d.RecordClient("host1", "host1", nil, Replay, "mychannel")
+	d.RecordIdentity(creator, Replay, "mychannel")
+	d.RecordIdentity(creator, Replay, "mychannel")
+
+	report := d.Report()
+	require.Len(t, report.Subjects, 2)
+	key, mspID := IdentityKey(creator)
+	assert.Equal(t, key, report.Subjects[0].Key)
+	assert.Equal(t, Identity, report.Subjects[0].Kind)
+	assert.Equal(t, "Org1MSP", mspID)
+	assert.Equal(t, ClientKey("host1"), report.Subjects[1].Key)
+}
+
+func TestBlocking(t *testing.T) {
+	d, now := newTestDetector(Config{
+		Weights:        map[Pattern]float64{Replay: 1, MalformedConfig: 2},
+		BlockThreshold: 3,
+		BlockDuration:  time.Minute,
+	})
+
+	d.RecordIdentity(creator, Replay, "mychannel")
+	d.RecordIdentity(creator, Replay, "mychannel")
+	assert.NoError(t, d.Blocked("host1", creator))
+
+	d.RecordIdentity(creator, Replay, "mychannel")
+	err := d.Blocked("host1", creator)
+	require.Error(t, err)
+	assert.Equal(t, ErrBlocked, errors.Cause(err))
+	assert.Equal(t, time.Minute, err.(interface{ RetryAfter() time.Duration }).RetryAfter())
+	// only the identity is blocked, whatever client it connects through
+	assert.NoError(t, d.Blocked("host1", nil))
+
+	d.RecordClient("host1", "host1", nil, MalformedConfig, "mychannel")
+	d.RecordClient("host1", "host1", nil, MalformedConfig, "mychannel")
+	assert.Error(t, d.Blocked("host1", nil))
+	assert.NoError(t, d.Blocked("host2", nil))
+
+	// until the block expires
+	*now = now.Add(time.Minute)
+	assert.NoError(t, d.Blocked("host1", creator))
+	assert.Nil(t, d.Report().Subjects[0].BlockedUntil)
+}
+
+func TestNoBlocking(t *testing.T) {
+	d, _ := newTestDetector(Config{Weights: map[Pattern]float64{InvalidSignature: 1}})
+
+	for i := 0; i < 100; i++ {
+		d.RecordClient("host1", "host1", nil, InvalidSignature, "mychannel")
+	}
+	assert.NoError(t, d.Blocked("host1", nil))
+}
+
+func TestAnonymizedClients(t *testing.T) {
+	d, _ := newTestDetector(Config{
+		Weights:        map[Pattern]float64{MalformedConfig: 1},
+		BlockThreshold: 2,
+		BlockDuration:  time.Minute,
+	})
+
+	// 共享匿名地址的客户端分别计分与封禁，日志与报告只显示匿名地址
+	d.RecordClient("10.1.2.3", "10.1.2.0/24", nil, MalformedConfig, "mychannel")
+	d.RecordClient("10.1.2.3", "10.1.2.0/24", nil, MalformedConfig, "mychannel")
+	d.RecordClient("10.1.2.4", "10.1.2.0/24", nil, MalformedConfig, "mychannel")
+	err := d.Blocked("10.1.2.3", nil)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), ClientKey("10.1.2.0/24"))
+	assert.NotContains(t, err.Error(), "10.1.2.3")
+	assert.NoError(t, d.Blocked("10.1.2.4", nil))
+
+	report := d.Report()
+	require.Len(t, report.Subjects, 2)
+	for _, subject := range report.Subjects {
+		assert.Equal(t, ClientKey("10.1.2.0/24"), subject.Key)
+	}
+
+	// the clients are unblocked by the key of the report
+	assert.True(t, d.Unblock(ClientKey("10.1.2.0/24")))
+	assert.NoError(t, d.Blocked("10.1.2.3", nil))
+	assert.Empty(t, d.Report().Subjects)
+}
+
+func TestServeHTTP(t *testing.T) {
+	d, _ := newTestDetector(Config{
+		Weights:        map[Pattern]float64{InvalidSignature: 1},
+		BlockThreshold: 1,
+		BlockDuration:  time.Minute,
+	})
+	d.RecordClient("host1", "host1", creator, InvalidSignature, "mychannel")
+
+	resp := httptest.NewRecorder()
+	d.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/misbehavior", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	report := &Report{}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), report))
+	require.Len(t, report.Subjects, 1)
+	assert.Equal(t, "Org1MSP", report.Subjects[0].MSPID)
+	assert.NotNil(t, report.Subjects[0].BlockedUntil)
+
+	resp = httptest.NewRecorder()
+	d.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/misbehavior", nil))
+	assert.Equal(t, http.StatusBadRequest, resp.Code)
+
+	resp = httptest.NewRecorder()
+	d.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/misbehavior?subject=client:host1", nil))
+	assert.Equal(t, http.StatusNoContent, resp.Code)
+	assert.NoError(t, d.Blocked("host1", nil))
+
+	resp = httptest.NewRecorder()
+	d.ServeHTTP(resp, httptest.NewRequest(http.MethodDelete, "/misbehavior?subject=client:host1", nil))
+	assert.Equal(t, http.StatusNotFound, resp.Code)
+
+	resp = httptest.NewRecorder()
+	d.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/misbehavior", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"fmt"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+)
+
+// Category is the kind of failure an error rejecting a message reports, which
+// tells the client whether and when submitting the message again may succeed.
+type Category int
+
+const (
+	// Validation is the category of the errors rejecting messages which are
+	// malformed or otherwise invalid, and would be rejected again
+	Validation Category = iota
+
+	// Policy is the category of the errors rejecting messages which the
+	// policies of the channel do not permit
+	Policy
+
+	// Resource is the category of the errors rejecting messages for resources,
+	// such as channels, which do not exist
+	Resource
+
+	// Transient is the category of the errors rejecting messages which may be
+	// accepted if submitted again later
+	Transient
+)
+
+func (c Category) String() string {
+	switch c {
+	case Validation:
+		return "validation"
+	case Policy:
+		return "policy"
+	case Resource:
+		return "resource"
+	case Transient:
+		return "transient"
+	default:
+		return fmt.Sprintf("Category(%d)", int(c))
+	}
+}
+
+// Status returns the status the messages rejected with an error of the
+// category are answered with
+func (c Category) Status() cb.Status {
+	switch c {
+	case Policy:
+		return cb.Status_FORBIDDEN
+	case Resource:
+		return cb.Status_NOT_FOUND
+	case Transient:
+		return cb.Status_SERVICE_UNAVAILABLE
+	default:
+		return cb.Status_BAD_REQUEST
+	}
+}
+
+// StatusCoder is implemented by the errors which determine the status of the
+// messages they reject.
+type StatusCoder interface {
+	error
+
+	// Status returns the status the rejected message is answered with
+	Status() cb.Status
+}
+
+// Error is an error of a category rejecting a message.  The errors wrapping
+// an Error, for instance with errors.Wrap, have it as their cause.
+type Error struct {
+	category Category
+	text     string
+}
+
+// NewError returns an Error of the category with the text.
+func NewError(category Category, text string) *Error {
+	return &Error{category: category, text: text}
+}
+
+func (e *Error) Error() string {
+	return e.text
+}
+
+// Category returns the category of the error
+func (e *Error) Category() Category {
+	return e.category
+}
+
+// Status returns the status of the category of the error
+func (e *Error) Status() cb.Status {
+	return e.category.Status()
+}
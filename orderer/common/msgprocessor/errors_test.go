@@ -0,0 +1,56 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"testing"
+
+	"github.com/hyperledger/fabric/common/retry"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorCategories(t *testing.T) {
+	for _, tc := range []struct {
+		err      *Error
+		category Category
+		status   cb.Status
+	}{
+		{ErrEmptyMessage, Validation, cb.Status_BAD_REQUEST},
+		{ErrStaleNonce, Validation, cb.Status_BAD_REQUEST},
+		{ErrPermissionDenied, Policy, cb.Status_FORBIDDEN},
+		{ErrIdentityExpired, Policy, cb.Status_FORBIDDEN},
+		{ErrQuotaExceeded, Policy, cb.Status_FORBIDDEN},
+		{ErrChannelDoesNotExist, Resource, cb.Status_NOT_FOUND},
+		{ErrRateLimited, Transient, cb.Status_SERVICE_UNAVAILABLE},
+	} {
+		assert.Equal(t, tc.category, tc.err.Category(), tc.err.Error())
+		assert.Equal(t, tc.status, tc.err.Status(), tc.err.Error())
+	}
+}
+
+// TestCategoryRetryability checks that the clients classifying the statuses
+// with the retry package retry the transient errors only
+func TestCategoryRetryability(t *testing.T) {
+	for _, c := range []Category{Validation, Policy, Resource, Transient} {
+		assert.Equal(t, c == Transient, retry.Status(c.Status()).Retryable(), c.String())
+	}
+}
+
+func TestError(t *testing.T) {
+	err := NewError(Transient, "consenter is catching up")
+	assert.EqualError(t, err, "consenter is catching up")
+	assert.Equal(t, "transient", err.Category().String())
+
+	var sc StatusCoder = err
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, sc.Status())
+	assert.Equal(t, err, errors.Cause(errors.Wrap(errors.WithStack(err), "wrapped")), "the wrapping errors have the Error as cause")
+
+	assert.Equal(t, "Category(7)", Category(7).String())
+	assert.Equal(t, cb.Status_BAD_REQUEST, Category(7).Status())
+}
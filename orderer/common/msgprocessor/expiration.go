@@ -27,8 +27,16 @@ func NewExpirationRejectRule(filterSupport resources) Rule {
 	return &expirationRejectRule{filterSupport: filterSupport}
 }
 
+// NewExpirationRejectRuleWithGracePeriod returns a rule that rejects messages signed
+// by identities which have expired for longer than the grace period, and only warns
+// about the messages signed by identities which have expired within it.
+func NewExpirationRejectRuleWithGracePeriod(filterSupport resources, gracePeriod time.Duration) Rule {
+	return &expirationRejectRule{filterSupport: filterSupport, gracePeriod: gracePeriod}
+}
+
 type expirationRejectRule struct {
 	filterSupport resources
+	gracePeriod   time.Duration
 }
 
 // Apply checks whether the identity that created the envelope has expired
@@ -47,8 +55,14 @@ func (exp *expirationRejectRule) Apply(message *common.Envelope) error {
 	}
 	expirationTime := crypto.ExpiresAt(signedData[0].Identity)
 	// Identity cannot expire, or identity has not expired yet
-	if expirationTime.IsZero() || time.Now().Before(expirationTime) {
+	now := time.Now()
+	if expirationTime.IsZero() || now.Before(expirationTime) {
+		return nil
+	}
+	if now.Before(expirationTime.Add(exp.gracePeriod)) {
+		logger.Warningf("Accepting message signed by identity whose certificate expired at %s, within the grace period of %s",
+			expirationTime.Format(time.RFC3339), exp.gracePeriod)
 		return nil
 	}
-	return errors.New("identity expired")
+	return errors.Wrapf(errors.WithStack(ErrIdentityExpired), "certificate expired at %s", expirationTime.Format(time.RFC3339))
 }
@@ -10,13 +10,16 @@ import (
 	"io/ioutil"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/mocks/config"
 	"github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/msp"
 	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -108,11 +111,26 @@ func TestExpirationRejectRule(t *testing.T) {
 		env := createEnvelope(t, createX509Identity(t, "expiredCert.pem"))
 		err := NewExpirationRejectRule(resources).Apply(env)
 		assert.Error(t, err)
-		assert.Equal(t, err.Error(), "identity expired")
+		assert.Equal(t, ErrIdentityExpired, errors.Cause(err))
+		assert.Regexp(t, "^certificate expired at .+: identity expired$", err.Error())
 
 		err = NewExpirationRejectRule(resources).Apply(env)
 		assert.NoError(t, err)
 	})
+	t.Run("ExpiredX509IdentityWithinGracePeriod", func(t *testing.T) {
+		resources := &resourcesMock{}
+		resources.On("OrdererConfig").Return(activeCapability, true)
+		env := createEnvelope(t, createX509Identity(t, "expiredCert.pem"))
+		signedData, err := env.AsSignedData()
+		assert.NoError(t, err)
+		expiredFor := time.Since(crypto.ExpiresAt(signedData[0].Identity))
+
+		err = NewExpirationRejectRuleWithGracePeriod(resources, expiredFor+time.Hour).Apply(env)
+		assert.NoError(t, err)
+
+		err = NewExpirationRejectRuleWithGracePeriod(resources, expiredFor-time.Hour).Apply(env)
+		assert.Equal(t, ErrIdentityExpired, errors.Cause(err))
+	})
 	t.Run("IdemixIdentity", func(t *testing.T) {
 		setupMock()
 		env := createEnvelope(t, createIdemixIdentity(t))
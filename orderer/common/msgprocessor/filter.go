@@ -7,13 +7,14 @@ SPDX-License-Identifier: Apache-2.0
 package msgprocessor
 
 import (
-	"errors"
+	"fmt"
+	"sync"
 
 	ab "github.com/hyperledger/fabric/protos/common"
 )
 
 // ErrEmptyMessage is returned by the empty message filter on rejection.
-var ErrEmptyMessage = errors.New("Message was empty")
+var ErrEmptyMessage = NewError(Validation, "Message was empty")
 
 // Rule defines a filter function which accepts, rejects, or forwards (to the next rule) an Envelope
 type Rule interface {
@@ -42,8 +43,10 @@ func (a acceptRule) Apply(message *ab.Envelope) error {
 	return nil
 }
 
-// RuleSet is used to apply a collection of rules
+// RuleSet is used to apply a collection of rules, which may be replaced while
+// the set is in use
 type RuleSet struct {
+	mutex sync.RWMutex
 	rules []Rule
 }
 
@@ -56,7 +59,11 @@ func NewRuleSet(rules []Rule) *RuleSet {
 
 // Apply applies the rules given for this set in order, returning nil on valid or err on invalid
 func (rs *RuleSet) Apply(message *ab.Envelope) error {
-	for _, rule := range rs.rules {
+	rs.mutex.RLock()
+	rules := rs.rules
+	rs.mutex.RUnlock()
+
+	for _, rule := range rules {
 		err := rule.Apply(message)
 		if err != nil {
 			return err
@@ -64,3 +71,47 @@ func (rs *RuleSet) Apply(message *ab.Envelope) error {
 	}
 	return nil
 }
+
+// Names returns the names of the rules of the set, in the order they are applied
+func (rs *RuleSet) Names() []string {
+	rs.mutex.RLock()
+	defer rs.mutex.RUnlock()
+
+	names := make([]string, len(rs.rules))
+	for i, rule := range rs.rules {
+		names[i] = RuleName(rule)
+	}
+	return names
+}
+
+// replace replaces the rules of the set, messages being filtered meanwhile
+// finishing with the previous rules
+func (rs *RuleSet) replace(rules []Rule) {
+	rs.mutex.Lock()
+	rs.rules = rules
+	rs.mutex.Unlock()
+}
+
+// RuleName returns the name under which the rule is listed in a rule chain
+func RuleName(rule Rule) string {
+	switch r := rule.(type) {
+	case interface{ Name() string }:
+		return r.Name()
+	case emptyRejectRule:
+		return EmptyRejectRuleName
+	case acceptRule:
+		return "Accept"
+	case *expirationRejectRule:
+		return ExpirationRuleName
+	case *MaxBytesRule:
+		return SizeFilterRuleName
+	case *SigFilter:
+		return SigFilterRuleName
+	case *LabelFilter:
+		return LabelRuleName
+	case *SystemChainFilter:
+		return "SystemChannelFilter"
+	default:
+		return fmt.Sprintf("%T", rule)
+	}
+}
@@ -0,0 +1,142 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"sync"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/channelconfig"
+	mspprotos "github.com/hyperledger/fabric/protos/msp"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/pkg/errors"
+)
+
+// ErrQuotaExceeded is returned for transactions rejected because the
+// organization of their creator exceeds its ingress quota on the channel.
+var ErrQuotaExceeded = NewError(Policy, "ingress quota exceeded")
+
+// QuotaSupport looks up the orderer config of the channels whose quotas are
+// enforced
+type QuotaSupport interface {
+	// OrdererConfig returns the config.Orderer of the channel, or false if
+	// the channel does not exist
+	OrdererConfig(channelID string) (channelconfig.Orderer, bool)
+}
+
+// IngressQuotas charges the messages of each organization against the
+// ingress quota the Orderer config group of their channel grants it.  The
+// quotas are read from the latest config at every charge, the token bucket
+// of an organization restarting full whenever its quota changes.
+//
+// The buckets depend on the traffic of the orderer and on time, so they are
+// charged once, by the broadcast handler as the messages are received, and
+// never by the filters the consenters apply again when revalidating the
+// messages: a message answered SUCCESS is not dropped afterwards, and the
+// replicas of a consenter do not decide differently which messages to order.
+type IngressQuotas struct {
+	support QuotaSupport
+	now     func() time.Time
+
+	mutex   sync.Mutex
+	buckets map[string]map[string]*quotaBucket
+}
+
+// quotaBucket is the token bucket of an organization and the quota it was
+// created for
+type quotaBucket struct {
+	rate   uint32
+	burst  uint32
+	bucket *tokenBucket
+}
+
+// NewIngressQuotas creates the ingress quotas, at every charge the orderer
+// config of the channel is retrieved from the support to get the latest
+// quotas
+func NewIngressQuotas(support QuotaSupport) *IngressQuotas {
+	return &IngressQuotas{
+		support: support,
+		now:     time.Now,
+		buckets: map[string]map[string]*quotaBucket{},
+	}
+}
+
+// Charge returns an error whose cause is ErrQuotaExceeded if the
+// organization of the creator has no transaction left in its quota on the
+// channel, and otherwise takes a transaction from it.  The creator must have
+// been authenticated, so that nobody can exhaust the quota of another
+// organization.
+func (iq *IngressQuotas) Charge(channelID string, creator []byte) error {
+	ordererConfig, ok := iq.support.OrdererConfig(channelID)
+	if !ok {
+		return errors.Errorf("could not find orderer config of channel %s", channelID)
+	}
+	quotas := ordererConfig.IngressQuotas()
+	if len(quotas) == 0 {
+		iq.forget(channelID, "")
+		return nil
+	}
+
+	sid := &mspprotos.SerializedIdentity{}
+	if err := proto.Unmarshal(creator, sid); err != nil {
+		return errors.Wrap(err, "could not determine the organization of the message")
+	}
+
+	quota, ok := quotas[sid.Mspid]
+	if !ok {
+		iq.forget(channelID, sid.Mspid)
+		return nil
+	}
+	if !iq.bucket(channelID, sid.Mspid, quota).take() {
+		return errors.Wrapf(errors.WithStack(ErrQuotaExceeded), "organization %s exceeds its quota of %d transactions per second", sid.Mspid, quota.Rate)
+	}
+	return nil
+}
+
+// bucket returns the token bucket of the organization on the channel,
+// created anew if its quota changed
+func (iq *IngressQuotas) bucket(channelID, mspID string, quota *ab.IngressQuota) *tokenBucket {
+	iq.mutex.Lock()
+	defer iq.mutex.Unlock()
+	buckets, ok := iq.buckets[channelID]
+	if !ok {
+		buckets = map[string]*quotaBucket{}
+		iq.buckets[channelID] = buckets
+	}
+	qb, ok := buckets[mspID]
+	if !ok || qb.rate != quota.Rate || qb.burst != quota.Burst {
+		burst := quota.Burst
+		if burst == 0 {
+			burst = quota.Rate
+		}
+		tb := newTokenBucket(float64(quota.Rate)/float64(time.Second), burst)
+		tb.now = iq.now
+		tb.last = iq.now()
+		qb = &quotaBucket{rate: quota.Rate, burst: quota.Burst, bucket: tb}
+		buckets[mspID] = qb
+	}
+	return qb.bucket
+}
+
+// forget drops the token bucket of an organization which no longer has a
+// quota on the channel, or those of every organization of the channel if no
+// organization is given
+func (iq *IngressQuotas) forget(channelID, mspID string) {
+	iq.mutex.Lock()
+	defer iq.mutex.Unlock()
+	buckets, ok := iq.buckets[channelID]
+	if !ok {
+		return
+	}
+	if mspID != "" {
+		delete(buckets, mspID)
+	}
+	if mspID == "" || len(buckets) == 0 {
+		delete(iq.buckets, channelID)
+	}
+}
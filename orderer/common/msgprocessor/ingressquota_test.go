@@ -0,0 +1,124 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/channelconfig"
+	mockchannelconfig "github.com/hyperledger/fabric/common/mocks/config"
+	"github.com/hyperledger/fabric/protos/msp"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockQuotaSupport map[string]*mockchannelconfig.Orderer
+
+func (mqs mockQuotaSupport) OrdererConfig(channelID string) (channelconfig.Orderer, bool) {
+	oc, ok := mqs[channelID]
+	return oc, ok
+}
+
+func orgCreator(mspID string) []byte {
+	return utils.MarshalOrPanic(&msp.SerializedIdentity{Mspid: mspID})
+}
+
+func newTestIngressQuotas(quotas map[string]*ab.IngressQuota) (*IngressQuotas, *mockchannelconfig.Orderer, *time.Time) {
+	ordererConfig := &mockchannelconfig.Orderer{IngressQuotasVal: quotas}
+	iq := NewIngressQuotas(mockQuotaSupport{testChannelID: ordererConfig})
+	now := time.Unix(1500000000, 0)
+	iq.now = func() time.Time { return now }
+	return iq, ordererConfig, &now
+}
+
+func TestIngressQuotas(t *testing.T) {
+	iq, _, now := newTestIngressQuotas(map[string]*ab.IngressQuota{
+		"Org1MSP": {MspId: "Org1MSP", Rate: 2},
+	})
+	org1 := orgCreator("Org1MSP")
+
+	assert.NoError(t, iq.Charge(testChannelID, org1))
+	assert.NoError(t, iq.Charge(testChannelID, org1))
+	err := iq.Charge(testChannelID, org1)
+	assert.Equal(t, ErrQuotaExceeded, errors.Cause(err))
+	assert.Contains(t, err.Error(), "organization Org1MSP exceeds its quota of 2 transactions per second")
+
+	// the organizations without a quota are not limited
+	assert.NoError(t, iq.Charge(testChannelID, orgCreator("Org2MSP")))
+
+	*now = now.Add(500 * time.Millisecond)
+	assert.NoError(t, iq.Charge(testChannelID, org1))
+	assert.Error(t, iq.Charge(testChannelID, org1))
+
+	// the channels are not known to the quotas
+	assert.Error(t, iq.Charge("otherchannel", org1))
+}
+
+func TestIngressQuotasBurst(t *testing.T) {
+	iq, _, _ := newTestIngressQuotas(map[string]*ab.IngressQuota{
+		"Org1MSP": {MspId: "Org1MSP", Rate: 1, Burst: 3},
+	})
+	org1 := orgCreator("Org1MSP")
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, iq.Charge(testChannelID, org1))
+	}
+	assert.Error(t, iq.Charge(testChannelID, org1))
+}
+
+func TestIngressQuotasPerChannel(t *testing.T) {
+	quotas := map[string]*ab.IngressQuota{"Org1MSP": {MspId: "Org1MSP", Rate: 1}}
+	iq := NewIngressQuotas(mockQuotaSupport{
+		"channel1": {IngressQuotasVal: quotas},
+		"channel2": {IngressQuotasVal: quotas},
+	})
+	org1 := orgCreator("Org1MSP")
+
+	assert.NoError(t, iq.Charge("channel1", org1))
+	assert.Error(t, iq.Charge("channel1", org1))
+	assert.NoError(t, iq.Charge("channel2", org1))
+}
+
+func TestIngressQuotasConfigChange(t *testing.T) {
+	iq, ordererConfig, _ := newTestIngressQuotas(map[string]*ab.IngressQuota{
+		"Org1MSP": {MspId: "Org1MSP", Rate: 1},
+	})
+	org1 := orgCreator("Org1MSP")
+
+	assert.NoError(t, iq.Charge(testChannelID, org1))
+	assert.Error(t, iq.Charge(testChannelID, org1))
+
+	// a new quota applies at once
+	ordererConfig.IngressQuotasVal = map[string]*ab.IngressQuota{
+		"Org1MSP": {MspId: "Org1MSP", Rate: 2},
+	}
+	assert.NoError(t, iq.Charge(testChannelID, org1))
+	assert.NoError(t, iq.Charge(testChannelID, org1))
+	assert.Error(t, iq.Charge(testChannelID, org1))
+
+	// as does the removal of a quota
+	ordererConfig.IngressQuotasVal = nil
+	assert.NoError(t, iq.Charge(testChannelID, org1))
+	assert.Empty(t, iq.buckets)
+}
+
+func TestIngressQuotasMalformed(t *testing.T) {
+	iq, _, _ := newTestIngressQuotas(map[string]*ab.IngressQuota{
+		"Org1MSP": {MspId: "Org1MSP", Rate: 1},
+	})
+
+	err := iq.Charge(testChannelID, []byte("garbage"))
+	assert.Error(t, err)
+	assert.NotEqual(t, ErrQuotaExceeded, errors.Cause(err))
+
+	// without quotas the creator is not parsed
+	iq, _, _ = newTestIngressQuotas(nil)
+	assert.NoError(t, iq.Charge(testChannelID, []byte("garbage")))
+}
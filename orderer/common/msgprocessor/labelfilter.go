@@ -0,0 +1,103 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/msp"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+// LabelFilterSupport provides the resources required for the label filter
+type LabelFilterSupport interface {
+	// OrdererConfig returns the config.Orderer for the channel and whether the Orderer config exists
+	OrdererConfig() (channelconfig.Orderer, bool)
+
+	// MSPManager returns the msp.MSPManager for the chain
+	MSPManager() msp.MSPManager
+}
+
+// LabelFilter rejects the messages carrying visibility labels which the
+// Orderer config group of the channel does not list, or which the
+// organizational units of their creator do not allow.  The labels are read
+// from the latest config at every evaluation.
+type LabelFilter struct {
+	support LabelFilterSupport
+}
+
+// NewLabelFilter creates a label filter, at every evaluation the orderer
+// config is retrieved from the support to get the latest labels
+func NewLabelFilter(support LabelFilterSupport) *LabelFilter {
+	return &LabelFilter{support: support}
+}
+
+// Apply returns an error if a label of the message is not configured, or an
+// error whose cause is ErrPermissionDenied if the certificate of its creator
+// has none of the organizational units of one of its labels.  The messages
+// without labels are accepted.
+func (lf *LabelFilter) Apply(message *cb.Envelope) error {
+	payload, err := utils.UnmarshalPayload(message.Payload)
+	if err != nil {
+		return errors.WithMessage(err, "could not determine the labels of the message")
+	}
+	if payload.Header == nil {
+		return errors.New("could not determine the labels of the message: missing header")
+	}
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		return errors.WithMessage(err, "could not determine the labels of the message")
+	}
+	if len(chdr.Labels) == 0 {
+		return nil
+	}
+
+	ordererConfig, ok := lf.support.OrdererConfig()
+	if !ok {
+		return errors.New("could not find orderer config")
+	}
+	shdr, err := utils.GetSignatureHeader(payload.Header.SignatureHeader)
+	if err != nil {
+		return errors.WithMessage(err, "could not determine the creator of the message")
+	}
+	identity, err := lf.support.MSPManager().DeserializeIdentity(shdr.Creator)
+	if err != nil {
+		return errors.WithMessage(err, "could not determine the creator of the message")
+	}
+	//创建者证书的组织单元
+	ous := map[string]struct{}{}
+	for _, ou := range identity.GetOrganizationalUnits() {
+		ous[ou.OrganizationalUnitIdentifier] = struct{}{}
+	}
+
+	labels := ordererConfig.TransactionLabels()
+	for _, name := range chdr.Labels {
+		label, ok := labels[name]
+		if !ok {
+			return errors.Errorf("label %s is not configured on the channel", name)
+		}
+		if !allowsLabel(label.OrganizationalUnits, name, ous) {
+			return errors.Wrapf(errors.WithStack(ErrPermissionDenied), "the organizational units of the creator do not allow label %s", name)
+		}
+	}
+	return nil
+}
+
+// allowsLabel returns whether one of the organizational units of the creator
+// is one of those of the label, the name of the label if it has none
+func allowsLabel(labelOUs []string, name string, creatorOUs map[string]struct{}) bool {
+	if len(labelOUs) == 0 {
+		labelOUs = []string{name}
+	}
+	for _, ou := range labelOUs {
+		if _, ok := creatorOUs[ou]; ok {
+			return true
+		}
+	}
+	return false
+}
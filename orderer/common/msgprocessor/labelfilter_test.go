@@ -0,0 +1,93 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"testing"
+
+	mockchannelconfig "github.com/hyperledger/fabric/common/mocks/config"
+	"github.com/hyperledger/fabric/msp"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// ouMSPManager deserializes the creators, which are names, into identities
+// with the organizational units of the names
+type ouMSPManager struct {
+	msp.MSPManager
+	ous map[string][]string
+}
+
+func (m *ouMSPManager) DeserializeIdentity(creator []byte) (msp.Identity, error) {
+	ous, ok := m.ous[string(creator)]
+	if !ok {
+		return nil, errors.Errorf("unknown creator %s", creator)
+	}
+	return &ouIdentity{ous: ous}, nil
+}
+
+type ouIdentity struct {
+	msp.Identity
+	ous []string
+}
+
+func (id *ouIdentity) GetOrganizationalUnits() []*msp.OUIdentifier {
+	var ous []*msp.OUIdentifier
+	for _, ou := range id.ous {
+		ous = append(ous, &msp.OUIdentifier{OrganizationalUnitIdentifier: ou})
+	}
+	return ous
+}
+
+func makeLabeledEnvelope(creator string, labels ...string) *cb.Envelope {
+	return &cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
+					Type:      int32(cb.HeaderType_ENDORSER_TRANSACTION),
+					ChannelId: testChannelID,
+					Labels:    labels,
+				}),
+				SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{Creator: []byte(creator)}),
+			},
+		}),
+	}
+}
+
+func TestLabelFilter(t *testing.T) {
+	lf := NewLabelFilter(&mockchannelconfig.Resources{
+		OrdererConfigVal: &mockchannelconfig.Orderer{TransactionLabelsVal: map[string]*ab.TransactionLabel{
+			"hr":      {Name: "hr", ReadersPolicy: "/Channel/Application/HRReaders"},
+			"finance": {Name: "finance", OrganizationalUnits: []string{"finance", "audit"}, ReadersPolicy: "/Channel/Application/FinanceReaders"},
+		}},
+		MSPManagerVal: &ouMSPManager{ous: map[string][]string{
+			"alice": {"hr"},
+			"bob":   {"audit", "it"},
+		}},
+	})
+
+	assert.NoError(t, lf.Apply(makeLabeledEnvelope("alice", "hr")))
+	assert.NoError(t, lf.Apply(makeLabeledEnvelope("bob", "finance")))
+	assert.NoError(t, lf.Apply(makeLabeledEnvelope("carol")), "the messages without labels are accepted")
+
+	err := lf.Apply(makeLabeledEnvelope("alice", "hr", "finance"))
+	assert.Equal(t, ErrPermissionDenied, errors.Cause(err))
+	assert.Contains(t, err.Error(), "the organizational units of the creator do not allow label finance")
+
+	err = lf.Apply(makeLabeledEnvelope("alice", "legal"))
+	assert.EqualError(t, err, "label legal is not configured on the channel")
+	assert.NotEqual(t, ErrPermissionDenied, errors.Cause(err))
+
+	err = lf.Apply(makeLabeledEnvelope("carol", "hr"))
+	assert.Contains(t, err.Error(), "could not determine the creator of the message")
+
+	assert.Error(t, lf.Apply(&cb.Envelope{Payload: []byte("garbage")}))
+	assert.Error(t, lf.Apply(&cb.Envelope{Payload: utils.MarshalOrPanic(&cb.Payload{})}))
+}
@@ -9,8 +9,6 @@ SPDX-License-Identifier: Apache-2.0
 package msgprocessor
 
 import (
-	"errors"
-
 	"github.com/hyperledger/fabric/common/flogging"
 	cb "github.com/hyperledger/fabric/protos/common"
 	logging "github.com/op/go-logging"
@@ -32,11 +30,15 @@ func init() {
 
 // ErrChannelDoesNotExist is returned by the system channel for transactions which
 // are not for the system channel ID and are not attempting to create a new channel
-var ErrChannelDoesNotExist = errors.New("channel does not exist")
+var ErrChannelDoesNotExist = NewError(Resource, "channel does not exist")
 
 // ErrPermissionDenied is returned by errors which are caused by transactions
 // which are not permitted due to an authorization failure.
-var ErrPermissionDenied = errors.New("permission denied")
+var ErrPermissionDenied = NewError(Policy, "permission denied")
+
+// ErrIdentityExpired is returned for transactions signed by an identity whose
+// certificate has expired.
+var ErrIdentityExpired = NewError(Policy, "identity expired")
 
 // Classification represents the possible message types for the system.
 type Classification int
@@ -0,0 +1,136 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"sync"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+)
+
+// ErrStaleNonce is returned for transactions whose creator already used a
+// nonce as high on the channel.
+var ErrStaleNonce = NewError(Validation, "stale nonce")
+
+// nonceCounterSize is the size of the counter heading the nonces in strict
+// ordering mode
+const nonceCounterSize = 8
+
+// NonceTracker enforces strict ordering of the messages of each creator: the
+// nonce of the signature header of a message must start with a big-endian
+// counter greater than that of the previous message of its creator on the
+// channel, so that captured envelopes replayed to the orderer are rejected.
+// The counters are only kept in memory.
+type NonceTracker struct {
+	channels map[string]bool
+
+	mutex    sync.Mutex
+	counters map[nonceKey]*nonceCounter
+}
+
+// nonceKey identifies a creator on a channel
+type nonceKey struct {
+	channelID string
+	creator   [sha256.Size]byte
+}
+
+// nonceCounter is the counter of the last message of a creator, and that
+// of the message before, restored if the last message is withdrawn
+type nonceCounter struct {
+	last     uint64
+	previous uint64
+}
+
+// NewNonceTracker creates a NonceTracker for the messages of the channels,
+// or of every channel if none is given
+func NewNonceTracker(channels []string) *NonceTracker {
+	nt := &NonceTracker{counters: map[nonceKey]*nonceCounter{}}
+	if len(channels) > 0 {
+		nt.channels = map[string]bool{}
+		for _, channelID := range channels {
+			nt.channels[channelID] = true
+		}
+	}
+	return nt
+}
+
+// Advance returns an error whose cause is ErrStaleNonce if the creator of
+// the message used a counter as high before on the channel, and otherwise
+// records its counter.  The message must have been validated, so that its
+// creator is authenticated.
+func (nt *NonceTracker) Advance(channelID string, env *cb.Envelope) error {
+	if nt.channels != nil && !nt.channels[channelID] {
+		return nil
+	}
+	key, counter, err := nonceOf(channelID, env)
+	if err != nil {
+		return err
+	}
+
+	nt.mutex.Lock()
+	defer nt.mutex.Unlock()
+	nc, ok := nt.counters[key]
+	if !ok {
+		nt.counters[key] = &nonceCounter{last: counter}
+		return nil
+	}
+	if counter <= nc.last {
+		return errors.Wrapf(errors.WithStack(ErrStaleNonce), "nonce counter %d is not greater than %d, the last of its creator", counter, nc.last)
+	}
+	nc.previous, nc.last = nc.last, counter
+	return nil
+}
+
+// Withdraw forgets the counter of a message which was not ordered, unless
+// its creator has advanced its counter since, so that the message may be
+// submitted again
+func (nt *NonceTracker) Withdraw(channelID string, env *cb.Envelope) {
+	if nt.channels != nil && !nt.channels[channelID] {
+		return
+	}
+	key, counter, err := nonceOf(channelID, env)
+	if err != nil {
+		return
+	}
+
+	nt.mutex.Lock()
+	defer nt.mutex.Unlock()
+	nc, ok := nt.counters[key]
+	if !ok || nc.last != counter {
+		return
+	}
+	if nc.previous == 0 {
+		delete(nt.counters, key)
+		return
+	}
+	nc.last, nc.previous = nc.previous, 0
+}
+
+// nonceOf returns the creator of the message on the channel, and the counter
+// heading its nonce
+func nonceOf(channelID string, env *cb.Envelope) (nonceKey, uint64, error) {
+	payload, err := utils.UnmarshalPayload(env.Payload)
+	if err != nil {
+		return nonceKey{}, 0, errors.WithMessage(err, "could not determine the nonce of the message")
+	}
+	if payload.Header == nil {
+		return nonceKey{}, 0, errors.New("could not determine the nonce of the message: missing header")
+	}
+	shdr, err := utils.GetSignatureHeader(payload.Header.SignatureHeader)
+	if err != nil {
+		return nonceKey{}, 0, errors.WithMessage(err, "could not determine the nonce of the message")
+	}
+	if len(shdr.Nonce) < nonceCounterSize {
+		return nonceKey{}, 0, errors.Errorf("nonce of %d bytes does not start with a %d byte counter", len(shdr.Nonce), nonceCounterSize)
+	}
+	key := nonceKey{channelID: channelID, creator: sha256.Sum256(shdr.Creator)}
+	return key, binary.BigEndian.Uint64(shdr.Nonce[:nonceCounterSize]), nil
+}
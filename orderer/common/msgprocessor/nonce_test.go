@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"encoding/binary"
+	"testing"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeNonceEnvelope(creator string, counter uint64) *cb.Envelope {
+	nonce := make([]byte, 24)
+	binary.BigEndian.PutUint64(nonce, counter)
+	return &cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader:   utils.MarshalOrPanic(&cb.ChannelHeader{Type: int32(cb.HeaderType_ENDORSER_TRANSACTION), ChannelId: testChannelID}),
+				SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{Creator: []byte(creator), Nonce: nonce}),
+			},
+		}),
+	}
+}
+
+func TestNonceTracker(t *testing.T) {
+	nt := NewNonceTracker(nil)
+
+	assert.NoError(t, nt.Advance(testChannelID, makeNonceEnvelope("alice", 5)))
+	assert.NoError(t, nt.Advance(testChannelID, makeNonceEnvelope("alice", 7)))
+
+	// 重放的或更早的计数器被拒绝
+	err := nt.Advance(testChannelID, makeNonceEnvelope("alice", 7))
+	assert.Equal(t, ErrStaleNonce, errors.Cause(err))
+	assert.Contains(t, err.Error(), "nonce counter 7 is not greater than 7, the last of its creator")
+	err = nt.Advance(testChannelID, makeNonceEnvelope("alice", 6))
+	assert.Equal(t, ErrStaleNonce, errors.Cause(err))
+
+	// 计数器按创建者和通道分别维护
+	assert.NoError(t, nt.Advance(testChannelID, makeNonceEnvelope("bob", 1)))
+	assert.NoError(t, nt.Advance("otherchannel", makeNonceEnvelope("alice", 1)))
+
+	_, _, err = nonceOf(testChannelID, &cb.Envelope{Payload: utils.MarshalOrPanic(&cb.Payload{
+		Header: &cb.Header{SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{Nonce: []byte("short")})},
+	})})
+	assert.EqualError(t, err, "nonce of 5 bytes does not start with a 8 byte counter")
+	_, _, err = nonceOf(testChannelID, &cb.Envelope{Payload: utils.MarshalOrPanic(&cb.Payload{})})
+	assert.EqualError(t, err, "could not determine the nonce of the message: missing header")
+}
+
+func TestNonceTrackerWithdraw(t *testing.T) {
+	nt := NewNonceTracker(nil)
+
+	assert.NoError(t, nt.Advance(testChannelID, makeNonceEnvelope("alice", 5)))
+	assert.NoError(t, nt.Advance(testChannelID, makeNonceEnvelope("alice", 7)))
+	nt.Withdraw(testChannelID, makeNonceEnvelope("alice", 7))
+	assert.NoError(t, nt.Advance(testChannelID, makeNonceEnvelope("alice", 7)))
+
+	// 创建者之后推进了计数器时不撤回
+	assert.NoError(t, nt.Advance(testChannelID, makeNonceEnvelope("alice", 9)))
+	nt.Withdraw(testChannelID, makeNonceEnvelope("alice", 7))
+	assert.Error(t, nt.Advance(testChannelID, makeNonceEnvelope("alice", 8)))
+
+	// 撤回创建者的第一个消息后其计数器重新开始
+	assert.NoError(t, nt.Advance(testChannelID, makeNonceEnvelope("bob", 3)))
+	nt.Withdraw(testChannelID, makeNonceEnvelope("bob", 3))
+	assert.NoError(t, nt.Advance(testChannelID, makeNonceEnvelope("bob", 1)))
+}
+
+func TestNonceTrackerChannels(t *testing.T) {
+	nt := NewNonceTracker([]string{testChannelID})
+
+	assert.NoError(t, nt.Advance(testChannelID, makeNonceEnvelope("alice", 5)))
+	assert.Error(t, nt.Advance(testChannelID, makeNonceEnvelope("alice", 5)))
+
+	// 未列出的通道不检查
+	assert.NoError(t, nt.Advance("otherchannel", makeNonceEnvelope("alice", 5)))
+	assert.NoError(t, nt.Advance("otherchannel", makeNonceEnvelope("alice", 5)))
+	assert.NoError(t, nt.Advance("otherchannel", &cb.Envelope{}))
+}
@@ -0,0 +1,295 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/policies"
+	"github.com/pkg/errors"
+)
+
+// The names of the built-in rules which may be listed in a rule chain.
+const (
+	EmptyRejectRuleName = "EmptyReject"
+	ExpirationRuleName  = "Expiration"
+	SizeFilterRuleName  = "SizeFilter"
+	SigFilterRuleName   = "SigFilter"
+	LabelRuleName       = "Label"
+)
+
+// DefaultRuleChain lists the built-in rules in the order they are applied
+// when no rule chain is configured, the filter plugins following them.
+var DefaultRuleChain = []string{EmptyRejectRuleName, ExpirationRuleName, SizeFilterRuleName, SigFilterRuleName, LabelRuleName}
+
+// ExpirationGracePeriods are the periods during which the messages signed by
+// expired identities are accepted with a warning rather than rejected.
+type ExpirationGracePeriods struct {
+	// GracePeriod applies to the channels not listed in Channels
+	GracePeriod time.Duration
+	// Channels maps channel IDs to their grace period
+	Channels map[string]time.Duration
+}
+
+// forChannel returns the grace period of the channel
+func (g ExpirationGracePeriods) forChannel(channelID string) time.Duration {
+	if gracePeriod, ok := g.Channels[channelID]; ok {
+		return gracePeriod
+	}
+	return g.GracePeriod
+}
+
+// RuleChain builds the rule sets of the channels from an ordered list of the
+// names of built-in rules and filter plugins.  Updating the chain rebuilds
+// the rule sets of every channel in place, so that rules are inserted,
+// removed or reordered without restarting the orderer.  A nil RuleChain
+// builds the default rule sets.
+type RuleChain struct {
+	mutex        sync.Mutex
+	names        []string
+	plugins      PluginRules
+	gracePeriods ExpirationGracePeriods
+	channels     map[string]*channelRules
+}
+
+// channelRules are the rule set of a channel and what it is built from
+type channelRules struct {
+	resources channelconfig.Resources
+	tail      []Rule
+	ruleSet   *RuleSet
+}
+
+// NewRuleChain creates a rule chain applying the named rules in order, or
+// the default chain followed by every filter plugin if no name is given.
+func NewRuleChain(names []string, plugins PluginRules) (*RuleChain, error) {
+	rc := &RuleChain{channels: map[string]*channelRules{}}
+	if err := rc.Update(names, plugins); err != nil {
+		return nil, err
+	}
+	return rc, nil
+}
+
+// Update validates the rule chain, then rebuilds the rule sets of every
+// channel from it.  The previous chain is kept if the new one is invalid.
+func (rc *RuleChain) Update(names []string, plugins PluginRules) error {
+	if len(names) == 0 {
+		names = append([]string{}, DefaultRuleChain...)
+		for _, p := range plugins {
+			names = append(names, p.Name())
+		}
+	}
+	if err := validateRuleChain(names, plugins); err != nil {
+		return err
+	}
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.names = names
+	rc.plugins = plugins
+	rc.rebuild()
+	return nil
+}
+
+// SetExpirationGracePeriods sets the grace periods of the expiration rule,
+// then rebuilds the rule sets of every channel.
+func (rc *RuleChain) SetExpirationGracePeriods(gracePeriods ExpirationGracePeriods) {
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	rc.gracePeriods = gracePeriods
+	rc.rebuild()
+}
+
+// rebuild rebuilds the rule sets of every channel in place, the caller
+// holding the mutex
+func (rc *RuleChain) rebuild() {
+	for channelID, cr := range rc.channels {
+		cr.ruleSet.replace(rc.build(channelID, cr.resources, cr.tail))
+		logger.Infof("[channel: %s] Rule chain set to %v", channelID, cr.ruleSet.Names())
+	}
+}
+
+// validateRuleChain checks that every rule of the chain is known and listed
+// once, and that the chain checks the signatures of the messages
+func validateRuleChain(names []string, plugins PluginRules) error {
+	known := map[string]bool{}
+	for _, name := range DefaultRuleChain {
+		known[name] = true
+	}
+	for _, p := range plugins {
+		if known[p.Name()] {
+			return errors.Errorf("filter plugin %s shadows another rule of the same name", p.Name())
+		}
+		known[p.Name()] = true
+	}
+
+	listed := map[string]bool{}
+	for _, name := range names {
+		if !known[name] {
+			return errors.Errorf("unknown rule %s in rule chain", name)
+		}
+		if listed[name] {
+			return errors.Errorf("rule %s listed twice in rule chain", name)
+		}
+		listed[name] = true
+	}
+	if !listed[SigFilterRuleName] {
+		return errors.Errorf("rule chain must include %s", SigFilterRuleName)
+	}
+	for _, p := range plugins {
+		if !listed[p.Name()] {
+			logger.Warningf("Filter plugin %s is not listed in the rule chain and will not be applied", p.Name())
+		}
+	}
+	return nil
+}
+
+// build creates the rules of the chain for the channel, the filter plugins
+// not applying to the channel being skipped
+func (rc *RuleChain) build(channelID string, resources channelconfig.Resources, tail []Rule) []Rule {
+	ordererConfig, ok := resources.OrdererConfig()
+	if !ok {
+		logger.Panicf("Missing orderer config")
+	}
+	plugins := map[string]Rule{}
+	for _, r := range rc.plugins.ForChannel(channelID) {
+		plugins[RuleName(r)] = r
+	}
+
+	var rules []Rule
+	for _, name := range rc.names {
+		switch name {
+		case EmptyRejectRuleName:
+			rules = append(rules, EmptyRejectRule)
+		case ExpirationRuleName:
+			rules = append(rules, NewExpirationRejectRuleWithGracePeriod(resources, rc.gracePeriods.forChannel(channelID)))
+		case SizeFilterRuleName:
+			rules = append(rules, NewSizeFilter(ordererConfig))
+		case SigFilterRuleName:
+			rules = append(rules, NewSigFilter(policies.ChannelWriters, resources))
+		case LabelRuleName:
+			rules = append(rules, NewLabelFilter(resources))
+		default:
+			if r, ok := plugins[name]; ok {
+				rules = append(rules, r)
+			}
+		}
+	}
+	return append(rules, tail...)
+}
+
+// register builds the rule set of the channel and keeps it to be rebuilt on updates
+func (rc *RuleChain) register(resources channelconfig.Resources, tail ...Rule) *RuleSet {
+	channelID := resources.ConfigtxValidator().ChainID()
+
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	cr := &channelRules{
+		resources: resources,
+		tail:      tail,
+		ruleSet:   NewRuleSet(rc.build(channelID, resources, tail)),
+	}
+	rc.channels[channelID] = cr
+	return cr.ruleSet
+}
+
+// StandardChannelFilters creates the rule set of a normal (non-system) channel.
+func (rc *RuleChain) StandardChannelFilters(resources channelconfig.Resources) *RuleSet {
+	if rc == nil {
+		return CreateStandardChannelFilters(resources)
+	}
+	return rc.register(resources)
+}
+
+// SystemChannelFilters creates the rule set of the ordering system channel,
+// which ends with the system channel filter whatever the rule chain.
+func (rc *RuleChain) SystemChannelFilters(chainCreator ChainCreator, resources channelconfig.Resources) *RuleSet {
+	if rc == nil {
+		return CreateSystemChannelFilters(chainCreator, resources)
+	}
+	return rc.register(resources, NewSystemChannelFilter(resources, chainCreator))
+}
+
+// Plugins returns the filter plugins of the rule chain.
+func (rc *RuleChain) Plugins() PluginRules {
+	if rc == nil {
+		return nil
+	}
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	return rc.plugins
+}
+
+// ActiveRules returns the names of the rules applied to the messages of each channel, in order.
+func (rc *RuleChain) ActiveRules() map[string][]string {
+	if rc == nil {
+		return nil
+	}
+	rc.mutex.Lock()
+	defer rc.mutex.Unlock()
+	active := make(map[string][]string, len(rc.channels))
+	for channelID, cr := range rc.channels {
+		active[channelID] = cr.ruleSet.Names()
+	}
+	return active
+}
+
+// Handler returns the handler of the admin operations on the rule chain.  A
+// GET responds with the rules applied to each channel, or to the channel
+// given as query parameter, and a POST updates the chain from the rule chain
+// and filter plugins returned by load.
+func (rc *RuleChain) Handler(load func() ([]string, PluginRules, error)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+		case http.MethodPost:
+			names, plugins, err := load()
+			if err == nil {
+				err = rc.Update(names, plugins)
+			}
+			if err != nil {
+				logger.Warningf("Failed to reload the rule chain: %s", err)
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		active := rc.ActiveRules()
+		if channelID := req.URL.Query().Get("channel"); channelID != "" {
+			rules, ok := active[channelID]
+			if !ok {
+				http.Error(w, "channel does not exist", http.StatusNotFound)
+				return
+			}
+			active = map[string][]string{channelID: rules}
+		}
+		channels := make([]string, 0, len(active))
+		for channelID := range active {
+			channels = append(channels, channelID)
+		}
+		sort.Strings(channels)
+
+		type channelChain struct {
+			Channel string   `json:"channel"`
+			Rules   []string `json:"rules"`
+		}
+		resp := []channelChain{}
+		for _, channelID := range channels {
+			resp = append(resp, channelChain{Channel: channelID, Rules: active[channelID]})
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(resp)
+	})
+}
@@ -0,0 +1,163 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	mockchannelconfig "github.com/hyperledger/fabric/common/mocks/config"
+	mockconfigtx "github.com/hyperledger/fabric/common/mocks/configtx"
+	mockpolicies "github.com/hyperledger/fabric/common/mocks/policies"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ruleChainResources(channelID string) *mockchannelconfig.Resources {
+	return &mockchannelconfig.Resources{
+		ConfigtxValidatorVal: &mockconfigtx.Validator{ChainIDVal: channelID},
+		PolicyManagerVal:     &mockpolicies.Manager{Policy: &mockpolicies.Policy{}},
+		OrdererConfigVal: &mockchannelconfig.Orderer{
+			BatchSizeVal:    &ab.BatchSize{AbsoluteMaxBytes: 100},
+			CapabilitiesVal: &mockchannelconfig.OrdererCapabilities{},
+		},
+	}
+}
+
+func TestRuleChainDefault(t *testing.T) {
+	plugin, err := NewWasmRule(filterPlugin("testdata/maxsize.wasm"))
+	require.NoError(t, err)
+	rc, err := NewRuleChain(nil, PluginRules{plugin})
+	require.NoError(t, err)
+
+	rs := rc.StandardChannelFilters(ruleChainResources("foo"))
+	assert.Equal(t, []string{EmptyRejectRuleName, ExpirationRuleName, SizeFilterRuleName, SigFilterRuleName, LabelRuleName, "test"}, rs.Names())
+	assert.NoError(t, rs.Apply(makeEnvelope()))
+
+	rs = rc.SystemChannelFilters(nil, ruleChainResources("system"))
+	assert.Equal(t, "SystemChannelFilter", rs.Names()[len(rs.Names())-1])
+
+	assert.Len(t, rc.ActiveRules(), 2)
+}
+
+func TestRuleChainNil(t *testing.T) {
+	var rc *RuleChain
+	rs := rc.StandardChannelFilters(ruleChainResources("foo"))
+	assert.Equal(t, DefaultRuleChain, rs.Names())
+	assert.Nil(t, rc.ActiveRules())
+	assert.Nil(t, rc.Plugins())
+}
+
+func TestRuleChainUpdate(t *testing.T) {
+	rc, err := NewRuleChain(nil, nil)
+	require.NoError(t, err)
+	rs := rc.StandardChannelFilters(ruleChainResources("foo"))
+	sys := rc.SystemChannelFilters(nil, ruleChainResources("system"))
+
+	plugin, err := NewWasmRule(filterPlugin("testdata/maxsize.wasm"))
+	require.NoError(t, err)
+	require.NoError(t, rc.Update([]string{"test", SigFilterRuleName}, PluginRules{plugin}))
+
+	assert.Equal(t, []string{"test", SigFilterRuleName}, rs.Names(), "rule sets are updated in place")
+	assert.Equal(t, []string{"test", SigFilterRuleName, "SystemChannelFilter"}, sys.Names())
+	err = rs.Apply(&cb.Envelope{Payload: make([]byte, 200)})
+	assert.Equal(t, ErrPermissionDenied, errors.Cause(err))
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, names := range [][]string{
+			{"missing", SigFilterRuleName},
+			{SigFilterRuleName, SigFilterRuleName},
+			{EmptyRejectRuleName, SizeFilterRuleName},
+		} {
+			assert.Error(t, rc.Update(names, nil), "%v", names)
+		}
+		assert.Equal(t, []string{"test", SigFilterRuleName}, rs.Names(), "previous chain is kept")
+	})
+
+	t.Run("PluginForOtherChannel", func(t *testing.T) {
+		conf := filterPlugin("testdata/maxsize.wasm")
+		conf.Channels = []string{"bar"}
+		plugin, err := NewWasmRule(conf)
+		require.NoError(t, err)
+		require.NoError(t, rc.Update([]string{"test", SigFilterRuleName}, PluginRules{plugin}))
+		assert.Equal(t, []string{SigFilterRuleName}, rs.Names())
+	})
+}
+
+func TestRuleChainExpirationGracePeriods(t *testing.T) {
+	rc, err := NewRuleChain(nil, nil)
+	require.NoError(t, err)
+	foo := rc.StandardChannelFilters(ruleChainResources("foo"))
+	bar := rc.StandardChannelFilters(ruleChainResources("bar"))
+
+	rc.SetExpirationGracePeriods(ExpirationGracePeriods{
+		GracePeriod: time.Hour,
+		Channels:    map[string]time.Duration{"bar": 0},
+	})
+
+	expirationRule := func(rs *RuleSet) *expirationRejectRule {
+		for _, r := range rs.rules {
+			if exp, ok := r.(*expirationRejectRule); ok {
+				return exp
+			}
+		}
+		return nil
+	}
+	assert.Equal(t, time.Hour, expirationRule(foo).gracePeriod)
+	assert.Equal(t, time.Duration(0), expirationRule(bar).gracePeriod)
+}
+
+func TestRuleChainHandler(t *testing.T) {
+	rc, err := NewRuleChain(nil, nil)
+	require.NoError(t, err)
+	rc.StandardChannelFilters(ruleChainResources("foo"))
+	rc.StandardChannelFilters(ruleChainResources("bar"))
+
+	var loadErr error
+	handler := rc.Handler(func() ([]string, PluginRules, error) {
+		return []string{SizeFilterRuleName, SigFilterRuleName}, nil, loadErr
+	})
+
+	type channelChain struct {
+		Channel string
+		Rules   []string
+	}
+	serve := func(method, target string) (*httptest.ResponseRecorder, []channelChain) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, target, nil))
+		var resp []channelChain
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+		}
+		return rec, resp
+	}
+
+	rec, resp := serve(http.MethodGet, "/msgprocessor/rules")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []channelChain{{"bar", DefaultRuleChain}, {"foo", DefaultRuleChain}}, resp)
+
+	rec, resp = serve(http.MethodPost, "/msgprocessor/rules?channel=foo")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, []channelChain{{"foo", []string{SizeFilterRuleName, SigFilterRuleName}}}, resp)
+
+	rec, _ = serve(http.MethodGet, "/msgprocessor/rules?channel=baz")
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	loadErr = errors.New("bad config")
+	rec, _ = serve(http.MethodPost, "/msgprocessor/rules")
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, rec.Body.String(), "bad config")
+
+	rec, _ = serve(http.MethodDelete, "/msgprocessor/rules")
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
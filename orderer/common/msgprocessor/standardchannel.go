@@ -44,18 +44,20 @@ func NewStandardChannel(support StandardChannelSupport, filters *RuleSet) *Stand
 	}
 }
 
-// CreateStandardChannelFilters creates the set of filters for a normal (non-system) chain
-func CreateStandardChannelFilters(filterSupport channelconfig.Resources) *RuleSet {
+// CreateStandardChannelFilters creates the set of filters for a normal (non-system) chain,
+// the extra rules being applied to the messages which satisfy the channel writers policy
+func CreateStandardChannelFilters(filterSupport channelconfig.Resources, extra ...Rule) *RuleSet {
 	ordererConfig, ok := filterSupport.OrdererConfig()
 	if !ok {
 		logger.Panicf("Missing orderer config")
 	}
-	return NewRuleSet([]Rule{
+	return NewRuleSet(append([]Rule{
 		EmptyRejectRule,
 		NewExpirationRejectRule(filterSupport),
 		NewSizeFilter(ordererConfig),
 		NewSigFilter(policies.ChannelWriters, filterSupport),
-	})
+		NewLabelFilter(filterSupport),
+	}, extra...))
 }
 
 // ClassifyMsg inspects the message to determine which type of processing is necessary
@@ -29,31 +29,38 @@ type ChannelConfigTemplator interface {
 type SystemChannel struct {
 	*StandardChannel
 	templator ChannelConfigTemplator
+	guard     *SystemChannelGuard
 }
 
-// NewSystemChannel creates a new system channel message processor.
-func NewSystemChannel(support StandardChannelSupport, templator ChannelConfigTemplator, filters *RuleSet) *SystemChannel {
+// NewSystemChannel creates a new system channel message processor.  The guard, if non-nil,
+// applies additional restrictions to the messages broadcast to the system channel.
+func NewSystemChannel(support StandardChannelSupport, templator ChannelConfigTemplator, filters *RuleSet, guard *SystemChannelGuard) *SystemChannel {
 	logger.Debugf("Creating system channel msg processor for channel %s", support.ChainID())
 	return &SystemChannel{
 		StandardChannel: NewStandardChannel(support, filters),
 		templator:       templator,
+		guard:           guard,
 	}
 }
 
-// CreateSystemChannelFilters creates the set of filters for the ordering system chain.
+// CreateSystemChannelFilters creates the set of filters for the ordering system chain,
+// the extra rules being applied to the messages which satisfy the channel writers policy.
 //对接收到的交易信息一次进行过滤处理
-func CreateSystemChannelFilters(chainCreator ChainCreator, ledgerResources channelconfig.Resources) *RuleSet {
+func CreateSystemChannelFilters(chainCreator ChainCreator, ledgerResources channelconfig.Resources, extra ...Rule) *RuleSet {
 	ordererConfig, ok := ledgerResources.OrdererConfig()
 	if !ok {
 		logger.Panicf("Cannot create system channel filters without orderer config")
 	}
-	return NewRuleSet([]Rule{
+	rules := []Rule{
 		EmptyRejectRule, //拒绝空消息过滤器
 		NewExpirationRejectRule(ledgerResources), //拒绝过期的签名者身份证书的过滤器
 		NewSizeFilter(ordererConfig), //消息最大字节书过滤器
 		NewSigFilter(policies.ChannelWriters, ledgerResources), //验证消息签名是否满足ChannelWriters通道写权限策略要求的过滤器
-		NewSystemChannelFilter(ledgerResources, chainCreator), //验证系统通道合法消息的过滤器，即检查所接受的消息是否为创建新应用通道的配置交易消息
-	})
+		NewLabelFilter(ledgerResources), //拒绝创建者组织单元不允许其可见性标签的消息的过滤器
+	}
+	rules = append(rules, extra...) //过滤插件
+	rules = append(rules, NewSystemChannelFilter(ledgerResources, chainCreator)) //验证系统通道合法消息的过滤器，即检查所接受的消息是否为创建新应用通道的配置交易消息
+	return NewRuleSet(rules)
 }
 
 // ProcessNormalMsg handles normal messages, rejecting them if they are not bound for the system channel ID
@@ -72,6 +79,10 @@ func (s *SystemChannel) ProcessNormalMsg(msg *cb.Envelope) (configSeq uint64, er
 		return 0, ErrChannelDoesNotExist
 	}
 
+	if err := s.guard.Admit(msg); err != nil {
+		return 0, err
+	}
+
 	return s.StandardChannel.ProcessNormalMsg(msg)
 }
 
@@ -80,6 +91,32 @@ func (s *SystemChannel) ProcessNormalMsg(msg *cb.Envelope) (configSeq uint64, er
 // ORDERER_TRANSACTION, and in the standard CONFIG_UPDATE case, a resulting CONFIG message
 //当创建新的应用通道的时候调用
 func (s *SystemChannel) ProcessConfigUpdateMsg(envConfigUpdate *cb.Envelope) (config *cb.Envelope, configSeq uint64, err error) {
+	channelID, err := utils.ChannelID(envConfigUpdate)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	//系统通道防护：检查专用广播策略
+	if err := s.guard.Admit(envConfigUpdate); err != nil {
+		return nil, 0, err
+	}
+
+	config, configSeq, err = s.processConfigUpdateMsg(envConfigUpdate)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	//通道创建请求验证通过后才消耗通道创建速率限制，无效请求不占用合法请求的额度
+	if channelID != s.support.ChainID() {
+		if err := s.guard.AdmitChannelCreation(); err != nil {
+			return nil, 0, err
+		}
+	}
+	return config, configSeq, nil
+}
+
+// processConfigUpdateMsg processes a config update which was already admitted by the guard
+func (s *SystemChannel) processConfigUpdateMsg(envConfigUpdate *cb.Envelope) (config *cb.Envelope, configSeq uint64, err error) {
 	//获取消息中的通道ID
 	channelID, err := utils.ChannelID(envConfigUpdate)
 	if err != nil {
@@ -101,31 +138,69 @@ func (s *SystemChannel) ProcessConfigUpdateMsg(envConfigUpdate *cb.Envelope) (co
 	logger.Debugf("Processing channel create tx for channel %s on system channel %s", channelID, s.support.ChainID())
 
 	// If the channel ID does not match the system channel, then this must be a channel creation transaction
+	_, wrappedOrdererTransaction, err := s.processChannelCreation(channelID, envConfigUpdate)
+	if err != nil {
+		return nil, 0, err
+	}
 
+	//返回新的通道配置交易消息与当前系统通道 配置序号
+	return wrappedOrdererTransaction, s.support.Sequence(), nil
+}
+
+// ValidateChannelCreation validates a channel creation transaction as the
+// broadcast service would, against the consortiums, policies and filters of
+// the system channel, and returns the config of the genesis block of the
+// channel.  Unlike ProcessConfigUpdateMsg it does not count the transaction
+// against the channel creation rate limit, so that validating a transaction
+// never prevents its creation.
+func (s *SystemChannel) ValidateChannelCreation(envConfigUpdate *cb.Envelope) (*cb.Config, error) {
+	channelID, err := utils.ChannelID(envConfigUpdate)
+	if err != nil {
+		return nil, err
+	}
+	if channelID == s.support.ChainID() {
+		return nil, fmt.Errorf("transaction updates the system channel %s rather than creating a channel", channelID)
+	}
+
+	if err := s.guard.Admit(envConfigUpdate); err != nil {
+		return nil, err
+	}
+
+	configEnv, _, err := s.processChannelCreation(channelID, envConfigUpdate)
+	if err != nil {
+		return nil, err
+	}
+	return configEnv.Config, nil
+}
+
+// processChannelCreation creates the config of the new channel from the
+// config update, and returns it together with the orderer transaction
+// carrying it, once the transaction passed the filters of the system channel
+func (s *SystemChannel) processChannelCreation(channelID string, envConfigUpdate *cb.Envelope) (*cb.ConfigEnvelope, *cb.Envelope, error) {
 	//创建新的应用通道，其通道配置序号默认初始化为0
 	//创建新应用通道的通道配置实体Bundle结构对象（该对象封装了通道配置对象channelConfig、策略管理器policyManager、配置交易管理器configtxManager等）
 	//用于管理新应用通道的通道配置消息等，并且通过自身的配置交易管理器维护通道的配置序号，默认初始化为0
 	bundle, err := s.templator.NewChannelConfig(envConfigUpdate)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
 	}
 
 	//构造新的通道配置更新交易消息（ConfigEnvelope），注意将消息的通道配置序号更新为1
 	newChannelConfigEnv, err := bundle.ConfigtxValidator().ProposeConfigUpdate(envConfigUpdate)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
 	}
 
 	//创建内层的配置交易消息（CONFIG类型）
 	newChannelEnvConfig, err := utils.CreateSignedEnvelope(cb.HeaderType_CONFIG, channelID, s.support.Signer(), newChannelConfigEnv, msgVersion, epoch)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
 	}
 
 	//创建外层的配置交易消息（ORDERER——TRANSACTION类型）
 	wrappedOrdererTransaction, err := utils.CreateSignedEnvelope(cb.HeaderType_ORDERER_TRANSACTION, s.support.ChainID(), s.support.Signer(), newChannelEnvConfig, msgVersion, epoch)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
 	}
 
 	// We re-apply the filters here, especially for the size filter, to ensure that the transaction we
@@ -137,11 +212,10 @@ func (s *SystemChannel) ProcessConfigUpdateMsg(envConfigUpdate *cb.Envelope) (co
 	//利用系统通道消息处理器定义的5个默认消息过滤器检查过滤该消息，如果过滤不包含任何错误，则调用系统通道链支持对象的s.support.Sequence()方法，获取当前通道的最新配置序号
 	err = s.StandardChannel.filters.Apply(wrappedOrdererTransaction)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, err
 	}
 
-	//返回新的通道配置交易消息与当前系统通道 配置序号
-	return wrappedOrdererTransaction, s.support.Sequence(), nil
+	return newChannelConfigEnv, wrappedOrdererTransaction, nil
 }
 
 // ProcessConfigMsg takes envelope of following two types:
@@ -189,7 +263,9 @@ func (s *SystemChannel) ProcessConfigMsg(env *cb.Envelope) (*cb.Envelope, uint64
 			return nil, 0, fmt.Errorf("Abort processing config msg because payload data unmarshalling error: %s", err)
 		}
 
-		return s.ProcessConfigUpdateMsg(configEnvelope.LastUpdate)
+		// The channel creation request was admitted when broadcast, not subjecting it to the
+		// guard again keeps the revalidation from consuming the channel creation rate limit
+		return s.processConfigUpdateMsg(configEnvelope.LastUpdate)
 
 	default:
 		return nil, 0, fmt.Errorf("Panic processing config msg due to unexpected envelope type %s", cb.HeaderType_name[chdr.Type])
@@ -316,6 +392,11 @@ func (dt *DefaultTemplator) NewChannelConfig(envConfigUpdate *cb.Envelope) (chan
 
 	channelGroup := cb.NewConfigGroup()
 
+	// Copy the fields of the system channel Channel group unknown to this
+	// version along with the values and policies, so that the new channel
+	// inherits them as it would from a newer orderer
+	channelGroup.XXX_unrecognized = append([]byte(nil), systemChannelGroup.XXX_unrecognized...)
+
 	// Copy the system channel Channel level config to the new config
 	for key, value := range systemChannelGroup.Values {
 		channelGroup.Values[key] = proto.Clone(value).(*cb.ConfigValue)
@@ -39,14 +39,14 @@ func TestProcessSystemChannelNormalMsg(t *testing.T) {
 	t.Run("Missing header", func(t *testing.T) {
 		mscs := &mockSystemChannelSupport{}
 		ms := &mockSystemChannelFilterSupport{}
-		_, err := NewSystemChannel(ms, mscs, nil).ProcessNormalMsg(&cb.Envelope{})
+		_, err := NewSystemChannel(ms, mscs, nil, nil).ProcessNormalMsg(&cb.Envelope{})
 		assert.NotNil(t, err)
 		assert.Regexp(t, "no header was set", err.Error())
 	})
 	t.Run("Mismatched channel ID", func(t *testing.T) {
 		mscs := &mockSystemChannelSupport{}
 		ms := &mockSystemChannelFilterSupport{}
-		_, err := NewSystemChannel(ms, mscs, nil).ProcessNormalMsg(&cb.Envelope{
+		_, err := NewSystemChannel(ms, mscs, nil, nil).ProcessNormalMsg(&cb.Envelope{
 			Payload: utils.MarshalOrPanic(&cb.Payload{
 				Header: &cb.Header{
 					ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -62,7 +62,7 @@ func TestProcessSystemChannelNormalMsg(t *testing.T) {
 		ms := &mockSystemChannelFilterSupport{
 			SequenceVal: 7,
 		}
-		cs, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessNormalMsg(&cb.Envelope{
+		cs, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessNormalMsg(&cb.Envelope{
 			Payload: utils.MarshalOrPanic(&cb.Payload{
 				Header: &cb.Header{
 					ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -81,7 +81,7 @@ func TestSystemChannelConfigUpdateMsg(t *testing.T) {
 	t.Run("Missing header", func(t *testing.T) {
 		mscs := &mockSystemChannelSupport{}
 		ms := &mockSystemChannelFilterSupport{}
-		_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessConfigUpdateMsg(&cb.Envelope{})
+		_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessConfigUpdateMsg(&cb.Envelope{})
 		assert.NotNil(t, err)
 		assert.Regexp(t, "no header was set", err.Error())
 	})
@@ -91,7 +91,7 @@ func TestSystemChannelConfigUpdateMsg(t *testing.T) {
 			SequenceVal:            7,
 			ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
 		}
-		config, cs, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessConfigUpdateMsg(&cb.Envelope{
+		config, cs, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessConfigUpdateMsg(&cb.Envelope{
 			Payload: utils.MarshalOrPanic(&cb.Payload{
 				Header: &cb.Header{
 					ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -111,7 +111,7 @@ func TestSystemChannelConfigUpdateMsg(t *testing.T) {
 		ms := &mockSystemChannelFilterSupport{
 			ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
 		}
-		_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessConfigUpdateMsg(&cb.Envelope{
+		_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessConfigUpdateMsg(&cb.Envelope{
 			Payload: utils.MarshalOrPanic(&cb.Payload{
 				Header: &cb.Header{
 					ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -131,7 +131,7 @@ func TestSystemChannelConfigUpdateMsg(t *testing.T) {
 		ms := &mockSystemChannelFilterSupport{
 			ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
 		}
-		_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessConfigUpdateMsg(&cb.Envelope{
+		_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessConfigUpdateMsg(&cb.Envelope{
 			Payload: utils.MarshalOrPanic(&cb.Payload{
 				Header: &cb.Header{
 					ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -149,7 +149,7 @@ func TestSystemChannelConfigUpdateMsg(t *testing.T) {
 		ms := &mockSystemChannelFilterSupport{
 			ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
 		}
-		_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessConfigUpdateMsg(&cb.Envelope{
+		_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessConfigUpdateMsg(&cb.Envelope{
 			Payload: utils.MarshalOrPanic(&cb.Payload{
 				Header: &cb.Header{
 					ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -170,7 +170,7 @@ func TestSystemChannelConfigUpdateMsg(t *testing.T) {
 			SequenceVal:            7,
 			ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
 		}
-		_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{RejectRule})).ProcessConfigUpdateMsg(&cb.Envelope{
+		_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{RejectRule}), nil).ProcessConfigUpdateMsg(&cb.Envelope{
 			Payload: utils.MarshalOrPanic(&cb.Payload{
 				Header: &cb.Header{
 					ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -191,7 +191,7 @@ func TestSystemChannelConfigUpdateMsg(t *testing.T) {
 			SequenceVal:            7,
 			ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
 		}
-		config, cs, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessConfigUpdateMsg(&cb.Envelope{
+		config, cs, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessConfigUpdateMsg(&cb.Envelope{
 			Payload: utils.MarshalOrPanic(&cb.Payload{
 				Header: &cb.Header{
 					ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -206,6 +206,61 @@ func TestSystemChannelConfigUpdateMsg(t *testing.T) {
 	})
 }
 
+func TestValidateChannelCreation(t *testing.T) {
+	creationTx := &cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
+					ChannelId: testChannelID + "different",
+				}),
+			},
+		}),
+	}
+
+	t.Run("SystemChannel", func(t *testing.T) {
+		mscs := &mockSystemChannelSupport{}
+		ms := &mockSystemChannelFilterSupport{}
+		_, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ValidateChannelCreation(&cb.Envelope{
+			Payload: utils.MarshalOrPanic(&cb.Payload{
+				Header: &cb.Header{
+					ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
+						ChannelId: testChannelID,
+					}),
+				},
+			}),
+		})
+		assert.EqualError(t, err, fmt.Sprintf("transaction updates the system channel %s rather than creating a channel", testChannelID))
+	})
+	t.Run("BadByFilter", func(t *testing.T) {
+		mscs := &mockSystemChannelSupport{
+			NewChannelConfigVal: &mockconfigtx.Validator{
+				ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
+			},
+		}
+		ms := &mockSystemChannelFilterSupport{}
+		_, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{RejectRule}), nil).ValidateChannelCreation(creationTx)
+		assert.Equal(t, RejectRule.Apply(nil), err)
+	})
+	t.Run("Good", func(t *testing.T) {
+		genesisConfig := &cb.Config{Sequence: 1}
+		mscs := &mockSystemChannelSupport{
+			NewChannelConfigVal: &mockconfigtx.Validator{
+				ProposeConfigUpdateVal: &cb.ConfigEnvelope{Config: genesisConfig},
+			},
+		}
+		ms := &mockSystemChannelFilterSupport{}
+		guard := NewSystemChannelGuard(SystemChannelProtection{ChannelCreationsPerMinute: 1}, nil)
+		sc := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), guard)
+		for i := 0; i < 2; i++ {
+			config, err := sc.ValidateChannelCreation(creationTx)
+			assert.NoError(t, err)
+			assert.Equal(t, genesisConfig, config)
+		}
+		_, _, err := sc.ProcessConfigUpdateMsg(creationTx)
+		assert.NoError(t, err, "validations should not count against the channel creation rate")
+	})
+}
+
 func TestSystemChannelConfigMsg(t *testing.T) {
 	t.Run("ConfigMsg", func(t *testing.T) {
 		t.Run("BadPayloadData", func(t *testing.T) {
@@ -218,7 +273,7 @@ func TestSystemChannelConfigMsg(t *testing.T) {
 				SequenceVal:            7,
 				ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
 			}
-			_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessConfigMsg(&cb.Envelope{
+			_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessConfigMsg(&cb.Envelope{
 				Payload: utils.MarshalOrPanic(&cb.Payload{
 					Header: &cb.Header{
 						ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -242,7 +297,7 @@ func TestSystemChannelConfigMsg(t *testing.T) {
 				SequenceVal:            7,
 				ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
 			}
-			config, seq, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessConfigMsg(&cb.Envelope{
+			config, seq, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessConfigMsg(&cb.Envelope{
 				Payload: utils.MarshalOrPanic(&cb.Payload{
 					Header: &cb.Header{
 						ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -275,7 +330,7 @@ func TestSystemChannelConfigMsg(t *testing.T) {
 				SequenceVal:            7,
 				ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
 			}
-			_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessConfigMsg(&cb.Envelope{
+			_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessConfigMsg(&cb.Envelope{
 				Payload: utils.MarshalOrPanic(&cb.Payload{
 					Header: &cb.Header{
 						ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -299,7 +354,7 @@ func TestSystemChannelConfigMsg(t *testing.T) {
 				SequenceVal:            7,
 				ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
 			}
-			_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessConfigMsg(&cb.Envelope{
+			_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessConfigMsg(&cb.Envelope{
 				Payload: utils.MarshalOrPanic(&cb.Payload{
 					Header: &cb.Header{
 						ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -332,7 +387,7 @@ func TestSystemChannelConfigMsg(t *testing.T) {
 				SequenceVal:            7,
 				ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
 			}
-			config, seq, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessConfigMsg(&cb.Envelope{
+			config, seq, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessConfigMsg(&cb.Envelope{
 				Payload: utils.MarshalOrPanic(&cb.Payload{
 					Header: &cb.Header{
 						ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -386,7 +441,7 @@ func TestSystemChannelConfigMsg(t *testing.T) {
 			SequenceVal:            7,
 			ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
 		}
-		_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule})).ProcessConfigMsg(&cb.Envelope{
+		_, _, err := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), nil).ProcessConfigMsg(&cb.Envelope{
 			Payload: utils.MarshalOrPanic(&cb.Payload{
 				Header: &cb.Header{
 					ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
@@ -683,6 +738,37 @@ func TestNewChannelConfig(t *testing.T) {
 	})
 }
 
+func TestNewChannelConfigUnknownFields(t *testing.T) {
+	gConf := configtxgentest.Load(genesisconfig.SampleSingleMSPSoloProfile)
+	gConf.Orderer.Capabilities = map[string]bool{
+		capabilities.OrdererV1_1: true,
+	}
+	channelGroup, err := encoder.NewChannelGroup(gConf)
+	assert.NoError(t, err)
+
+	// field 1000 with varint value 1, unknown to this version
+	unknown := []byte{0xc0, 0x3e, 0x01}
+	channelGroup.XXX_unrecognized = unknown
+	channelGroup.Groups[channelconfig.OrdererGroupKey].XXX_unrecognized = unknown
+	channelGroup.Values[channelconfig.HashingAlgorithmKey].XXX_unrecognized = unknown
+	ctxm, err := channelconfig.NewBundle("foo", &cb.Config{ChannelGroup: channelGroup})
+	assert.NoError(t, err)
+
+	templator := NewDefaultTemplator(&mockDefaultTemplatorSupport{
+		Resources: ctxm,
+	})
+
+	createTx, err := encoder.MakeChannelCreationTransaction("bar", nil, nil, configtxgentest.Load(genesisconfig.SampleSingleMSPChannelProfile))
+	assert.NoError(t, err)
+	res, err := templator.NewChannelConfig(createTx)
+	assert.NoError(t, err)
+
+	newChannelGroup := res.ConfigtxValidator().ConfigProto().ChannelGroup
+	assert.Equal(t, unknown, newChannelGroup.XXX_unrecognized)
+	assert.Equal(t, unknown, newChannelGroup.Groups[channelconfig.OrdererGroupKey].XXX_unrecognized)
+	assert.Equal(t, unknown, newChannelGroup.Values[channelconfig.HashingAlgorithmKey].XXX_unrecognized)
+}
+
 func TestZeroVersions(t *testing.T) {
 	data := &cb.ConfigGroup{
 		Version: 7,
@@ -0,0 +1,130 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"sync"
+	"time"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// ErrRateLimited is returned for transactions rejected because they exceed a
+// configured rate limit, which may be accepted once the rate allows.
+var ErrRateLimited = NewError(Transient, "rate limit exceeded")
+
+// SystemChannelProtection configures the hardening of the system channel,
+// whose compromise affects every channel.
+type SystemChannelProtection struct {
+	// BroadcastPolicy, if set, is the policy which every message broadcast to
+	// the system channel, including channel creation requests, must satisfy
+	// in addition to the channel writers policy.
+	BroadcastPolicy string
+
+	// ChannelCreationsPerMinute limits the rate of channel creation
+	// transactions, zero disables the limit.
+	ChannelCreationsPerMinute uint32
+
+	// ChannelCreationBurst is the number of channel creation transactions
+	// accepted at once, defaulting to ChannelCreationsPerMinute.
+	ChannelCreationBurst uint32
+
+	// AlertOnConfigChange raises an alert whenever a config update to the
+	// system channel is committed.
+	AlertOnConfigChange bool
+}
+
+// SystemChannelGuard applies the broadcast restrictions of a
+// SystemChannelProtection to the messages received by the system channel.
+// A nil SystemChannelGuard admits every message.
+type SystemChannelGuard struct {
+	policy    *SigFilter
+	creations *tokenBucket
+}
+
+// NewSystemChannelGuard creates a SystemChannelGuard evaluating the broadcast
+// policy against the policy manager of the support.
+func NewSystemChannelGuard(conf SystemChannelProtection, support SigFilterSupport) *SystemChannelGuard {
+	g := &SystemChannelGuard{}
+	if conf.BroadcastPolicy != "" {
+		g.policy = NewSigFilter(conf.BroadcastPolicy, support)
+	}
+	if conf.ChannelCreationsPerMinute > 0 {
+		burst := conf.ChannelCreationBurst
+		if burst == 0 {
+			burst = conf.ChannelCreationsPerMinute
+		}
+		g.creations = newTokenBucket(float64(conf.ChannelCreationsPerMinute)/float64(time.Minute), burst)
+	}
+	return g
+}
+
+// Admit returns an error if the message broadcast to the system channel does
+// not satisfy the broadcast policy.
+func (g *SystemChannelGuard) Admit(msg *cb.Envelope) error {
+	if g == nil || g.policy == nil {
+		return nil
+	}
+	if err := g.policy.Apply(msg); err != nil {
+		return errors.WithMessage(err, "system channel broadcast policy not satisfied")
+	}
+	return nil
+}
+
+// AdmitChannelCreation counts a channel creation against the creation rate
+// limit, returning an error if the limit is exceeded.  It is called once the
+// channel creation request was validated, so that invalid requests do not
+// consume the limit of the valid ones.
+func (g *SystemChannelGuard) AdmitChannelCreation() error {
+	if g == nil || g.creations == nil {
+		return nil
+	}
+	if !g.creations.take() {
+		return errors.Wrap(errors.WithStack(ErrRateLimited), "too many channel creation requests")
+	}
+	return nil
+}
+
+// tokenBucket admits events at a sustained rate with bursts of up to its capacity.
+type tokenBucket struct {
+	mutex    sync.Mutex
+	rate     float64 // tokens per nanosecond
+	capacity float64
+	tokens   float64
+	last     time.Time
+	now      func() time.Time
+}
+
+func newTokenBucket(rate float64, capacity uint32) *tokenBucket {
+	return &tokenBucket{
+		rate:     rate,
+		capacity: float64(capacity),
+		tokens:   float64(capacity),
+		last:     time.Now(),
+		now:      time.Now,
+	}
+}
+
+// take consumes a token, returning false if none is available
+func (tb *tokenBucket) take() bool {
+	tb.mutex.Lock()
+	defer tb.mutex.Unlock()
+
+	now := tb.now()
+	tb.tokens += float64(now.Sub(tb.last)) * tb.rate
+	if tb.tokens > tb.capacity {
+		tb.tokens = tb.capacity
+	}
+	tb.last = now
+
+	if tb.tokens < 1 {
+		return false
+	}
+	tb.tokens--
+	return true
+}
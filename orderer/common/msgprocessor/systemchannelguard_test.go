@@ -0,0 +1,127 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	mockchannelconfig "github.com/hyperledger/fabric/common/mocks/config"
+	mockconfigtx "github.com/hyperledger/fabric/common/mocks/configtx"
+	mockpolicies "github.com/hyperledger/fabric/common/mocks/policies"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSystemChannelGuardPolicy(t *testing.T) {
+	policy := &mockpolicies.Policy{}
+	mpm := &mockchannelconfig.Resources{
+		PolicyManagerVal: &mockpolicies.Manager{Policy: policy},
+	}
+	g := NewSystemChannelGuard(SystemChannelProtection{BroadcastPolicy: "/Channel/Orderer/Admins"}, mpm)
+	assert.NoError(t, g.Admit(makeEnvelope()))
+	assert.NoError(t, g.AdmitChannelCreation(), "no rate limit is configured")
+
+	policy.Err = fmt.Errorf("not an admin")
+	err := g.Admit(makeEnvelope())
+	assert.Equal(t, ErrPermissionDenied, errors.Cause(err))
+	assert.Contains(t, err.Error(), "system channel broadcast policy not satisfied")
+
+	var nilGuard *SystemChannelGuard
+	assert.NoError(t, nilGuard.Admit(makeEnvelope()))
+	assert.NoError(t, nilGuard.AdmitChannelCreation())
+}
+
+func TestSystemChannelGuardRateLimit(t *testing.T) {
+	g := NewSystemChannelGuard(SystemChannelProtection{ChannelCreationsPerMinute: 2, ChannelCreationBurst: 1}, nil)
+	now := time.Unix(1000, 0)
+	g.creations.now = func() time.Time { return now }
+	g.creations.last = now
+
+	assert.NoError(t, g.AdmitChannelCreation())
+	err := g.AdmitChannelCreation()
+	assert.Equal(t, ErrRateLimited, errors.Cause(err))
+	assert.NoError(t, g.Admit(makeEnvelope()), "only channel creations should be rate limited")
+
+	now = now.Add(30 * time.Second)
+	assert.NoError(t, g.AdmitChannelCreation())
+	assert.Error(t, g.AdmitChannelCreation())
+
+	g = NewSystemChannelGuard(SystemChannelProtection{ChannelCreationsPerMinute: 3}, nil)
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, g.AdmitChannelCreation(), "burst should default to the rate")
+	}
+}
+
+func TestSystemChannelGuarded(t *testing.T) {
+	mpm := &mockchannelconfig.Resources{
+		PolicyManagerVal: &mockpolicies.Manager{Policy: &mockpolicies.Policy{Err: fmt.Errorf("not an admin")}},
+	}
+	guard := NewSystemChannelGuard(SystemChannelProtection{BroadcastPolicy: "/Channel/Orderer/Admins"}, mpm)
+	ms := &mockSystemChannelFilterSupport{
+		SequenceVal:            7,
+		ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
+	}
+	sc := NewSystemChannel(ms, &mockSystemChannelSupport{}, NewRuleSet([]Rule{AcceptRule}), guard)
+	env := &cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
+					ChannelId: testChannelID,
+				}),
+				SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{}),
+			},
+		}),
+	}
+
+	_, err := sc.ProcessNormalMsg(env)
+	assert.Equal(t, ErrPermissionDenied, errors.Cause(err))
+	_, _, err = sc.ProcessConfigUpdateMsg(env)
+	assert.Equal(t, ErrPermissionDenied, errors.Cause(err))
+}
+
+func TestSystemChannelGuardInvalidCreations(t *testing.T) {
+	guard := NewSystemChannelGuard(SystemChannelProtection{ChannelCreationsPerMinute: 1}, nil)
+	now := time.Unix(1000, 0)
+	guard.creations.now = func() time.Time { return now }
+	guard.creations.last = now
+
+	mscs := &mockSystemChannelSupport{
+		NewChannelConfigVal: &mockconfigtx.Validator{
+			ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
+		},
+	}
+	ms := &mockSystemChannelFilterSupport{
+		SequenceVal:            7,
+		ProposeConfigUpdateVal: &cb.ConfigEnvelope{},
+	}
+	creation := &cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{
+				ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{
+					ChannelId: testChannelID + "different",
+				}),
+			},
+		}),
+	}
+
+	// the invalid channel creation requests do not consume the limit
+	rejecting := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{RejectRule}), guard)
+	for i := 0; i < 3; i++ {
+		_, _, err := rejecting.ProcessConfigUpdateMsg(creation)
+		assert.Equal(t, RejectRule.Apply(nil), err)
+	}
+
+	accepting := NewSystemChannel(ms, mscs, NewRuleSet([]Rule{AcceptRule}), guard)
+	_, _, err := accepting.ProcessConfigUpdateMsg(creation)
+	assert.NoError(t, err)
+	_, _, err = accepting.ProcessConfigUpdateMsg(creation)
+	assert.Equal(t, ErrRateLimited, errors.Cause(err))
+}
@@ -0,0 +1,20 @@
+;; Source of maxsize.wasm, a filter plugin rejecting the messages larger than
+;; 100 bytes.
+(module
+  (import "env" "reject" (func $reject (param i32 i32)))
+  (memory (export "memory") 1)
+  (data (i32.const 60000) "too long")
+  (func (export "alloc") (param i32) (result i32)
+    i32.const 0)
+  (func (export "admit") (param i32 i32) (result i32)
+    local.get 1
+    i32.const 100
+    i32.gt_u
+    if (result i32)
+      i32.const 60000
+      i32.const 8
+      call $reject
+      i32.const 1
+    else
+      i32.const 0
+    end))
@@ -0,0 +1,10 @@
+;; Source of spin.wasm, a filter plugin which never decides.
+(module
+  (memory (export "memory") 1)
+  (func (export "alloc") (param i32) (result i32)
+    i32.const 0)
+  (func (export "admit") (param i32 i32) (result i32)
+    loop
+      br 0
+    end
+    i32.const 0))
@@ -0,0 +1,119 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"runtime"
+	"sync"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+)
+
+// NormalMsgProcessor validates normal messages, such as a Processor.
+type NormalMsgProcessor interface {
+	// ProcessNormalMsg returns the configuration sequence number the message
+	// was validated against, or an error if the message is not valid
+	ProcessNormalMsg(env *cb.Envelope) (configSeq uint64, err error)
+}
+
+// VerifierPool validates normal messages, whose signature checks and policy
+// evaluations are the CPU hotspot of the orderer, on a fixed number of
+// workers, so that the messages of a stream or of a batch are validated
+// concurrently without the validations of every stream competing for the
+// CPUs.
+//
+// A message validated on the pool is validated as ProcessNormalMsg does: the
+// configuration sequence number is read before its filters are applied, so
+// that the consenter validates again the messages validated against a
+// configuration which changed since.
+type VerifierPool struct {
+	tasks chan *Verification
+	wg    sync.WaitGroup
+}
+
+// NewVerifierPool creates a VerifierPool validating messages on the number
+// of workers, or on one worker per CPU if workers is not positive.
+func NewVerifierPool(workers int) *VerifierPool {
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+	p := &VerifierPool{tasks: make(chan *Verification)}
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			for v := range p.tasks {
+				v.run()
+			}
+		}()
+	}
+	return p
+}
+
+// Verify starts validating the message with the processor, waiting for a
+// worker to be available, and returns its Verification.  A nil VerifierPool
+// validates the message at once on the goroutine of the caller.
+func (p *VerifierPool) Verify(processor NormalMsgProcessor, env *cb.Envelope) *Verification {
+	v := &Verification{processor: processor, env: env, done: make(chan struct{})}
+	if p == nil {
+		v.run()
+		return v
+	}
+	p.tasks <- v
+	return v
+}
+
+// Stop stops the workers once the messages being validated are.  Verify must
+// not be called once the pool is stopped.
+func (p *VerifierPool) Stop() {
+	if p == nil {
+		return
+	}
+	close(p.tasks)
+	p.wg.Wait()
+}
+
+// Verification is the validation of a normal message by a processor, which
+// may still be in progress.
+type Verification struct {
+	processor NormalMsgProcessor
+	env       *cb.Envelope
+	done      chan struct{}
+
+	configSeq uint64
+	err       error
+	panicked  interface{}
+}
+
+func (v *Verification) run() {
+	defer close(v.done)
+	defer func() {
+		//在调用方的协程中重新panic，使其崩溃恢复机制生效
+		if r := recover(); r != nil {
+			v.panicked = r
+		}
+	}()
+	v.configSeq, v.err = v.processor.ProcessNormalMsg(v.env)
+}
+
+// Of returns whether the verification is the validation of the message by
+// the processor, as the processor of a channel may change while the message
+// is validated.  The processors must be comparable, such as pointers.
+func (v *Verification) Of(processor NormalMsgProcessor, env *cb.Envelope) bool {
+	return v.processor == processor && v.env == env
+}
+
+// Wait waits for the validation to complete and returns its outcome, as
+// ProcessNormalMsg does.  It panics with the value the processor panicked
+// with, if it did.
+func (v *Verification) Wait() (configSeq uint64, err error) {
+	<-v.done
+	if v.panicked != nil {
+		panic(v.panicked)
+	}
+	return v.configSeq, v.err
+}
@@ -0,0 +1,148 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingProcessor validates the messages once released, counting those
+// validated at once
+type blockingProcessor struct {
+	release chan struct{}
+
+	mutex      sync.Mutex
+	validating int
+	peak       int
+}
+
+func (bp *blockingProcessor) ProcessNormalMsg(env *cb.Envelope) (uint64, error) {
+	bp.mutex.Lock()
+	bp.validating++
+	if bp.validating > bp.peak {
+		bp.peak = bp.validating
+	}
+	bp.mutex.Unlock()
+
+	<-bp.release
+
+	bp.mutex.Lock()
+	bp.validating--
+	bp.mutex.Unlock()
+	if string(env.Payload) == "invalid" {
+		return 3, ErrPermissionDenied
+	}
+	if string(env.Payload) == "panic" {
+		panic("bad processor")
+	}
+	return 3, nil
+}
+
+func (bp *blockingProcessor) peakValidating() int {
+	bp.mutex.Lock()
+	defer bp.mutex.Unlock()
+	return bp.peak
+}
+
+func TestVerifierPool(t *testing.T) {
+	processor := &blockingProcessor{release: make(chan struct{})}
+	pool := NewVerifierPool(2)
+	defer pool.Stop()
+
+	envs := []*cb.Envelope{{Payload: []byte("a")}, {Payload: []byte("invalid")}}
+	var verifications []*Verification
+	for _, env := range envs {
+		verifications = append(verifications, pool.Verify(processor, env))
+	}
+	// both workers are busy, so the third message waits for one of them
+	third := make(chan *Verification)
+	go func() {
+		third <- pool.Verify(processor, &cb.Envelope{Payload: []byte("c")})
+	}()
+	select {
+	case <-third:
+		t.Fatal("Should have waited for a worker")
+	case <-time.After(100 * time.Millisecond):
+	}
+	assert.Equal(t, 2, processor.peakValidating(), "Should have validated two messages at once")
+
+	close(processor.release)
+	verifications = append(verifications, <-third)
+	configSeq, err := verifications[0].Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), configSeq)
+	_, err = verifications[1].Wait()
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = verifications[2].Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, processor.peakValidating())
+
+	assert.True(t, verifications[0].Of(processor, envs[0]))
+	assert.False(t, verifications[0].Of(processor, envs[1]))
+	assert.False(t, verifications[0].Of(&blockingProcessor{}, envs[0]))
+}
+
+func TestVerifierPoolPanic(t *testing.T) {
+	processor := &blockingProcessor{release: make(chan struct{})}
+	close(processor.release)
+	pool := NewVerifierPool(1)
+	defer pool.Stop()
+
+	v := pool.Verify(processor, &cb.Envelope{Payload: []byte("panic")})
+	assert.PanicsWithValue(t, "bad processor", func() { v.Wait() }, "Should have panicked on the goroutine waiting")
+
+	// the worker survives the panic
+	_, err := pool.Verify(processor, &cb.Envelope{}).Wait()
+	assert.NoError(t, err)
+}
+
+func TestNilVerifierPool(t *testing.T) {
+	processor := &blockingProcessor{release: make(chan struct{})}
+	close(processor.release)
+	var pool *VerifierPool
+
+	v := pool.Verify(processor, &cb.Envelope{Payload: []byte("invalid")})
+	select {
+	case <-v.done:
+	default:
+		t.Fatal("Should have validated the message at once")
+	}
+	_, err := v.Wait()
+	assert.Equal(t, ErrPermissionDenied, err)
+	pool.Stop()
+}
+
+func TestVerifierPoolStop(t *testing.T) {
+	processor := &blockingProcessor{release: make(chan struct{})}
+	pool := NewVerifierPool(0)
+	v := pool.Verify(processor, &cb.Envelope{})
+
+	stopped := make(chan struct{})
+	go func() {
+		pool.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		t.Fatal("Should have waited for the message being validated")
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(processor.release)
+	_, err := v.Wait()
+	require.NoError(t, err)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Should have stopped")
+	}
+}
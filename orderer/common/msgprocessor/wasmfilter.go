@@ -0,0 +1,228 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/wasm"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// maxRejectReason bounds the length of the reason a filter plugin gives for
+// rejecting a message
+const maxRejectReason = 1024
+
+var (
+	allocType = wasm.FuncType{Params: []wasm.ValueType{wasm.I32}, Results: []wasm.ValueType{wasm.I32}}
+	admitType = wasm.FuncType{Params: []wasm.ValueType{wasm.I32, wasm.I32}, Results: []wasm.ValueType{wasm.I32}}
+	bytesType = wasm.FuncType{Params: []wasm.ValueType{wasm.I32, wasm.I32}}
+)
+
+// FilterPlugin configures a filter plugin, a WebAssembly module deciding on
+// the messages broadcast to the channels it applies to.
+//
+// The module must export its memory as "memory", a function "alloc" taking a
+// size and returning the address of that many bytes of its memory, and a
+// function "admit" taking the address and size of a marshaled Envelope and
+// returning zero to accept it.  It may import "env.reject", taking the
+// address and size of the reason for rejecting the message, and "env.log",
+// taking the address and size of a message logged at debug level.
+type FilterPlugin struct {
+	// Name identifies the plugin in logs and in the reasons for rejections.
+	Name string
+
+	// Path is the path of the module in the WebAssembly binary format.
+	Path string
+
+	// Channels are the channels the plugin applies to, every channel if empty.
+	Channels []string
+
+	// Fuel is the number of instructions the plugin may execute per message.
+	Fuel uint64
+
+	// MaxMemoryPages is the number of 64KiB pages the memory of the plugin
+	// may grow to.
+	MaxMemoryPages uint32
+}
+
+// WasmRule is a Rule running a filter plugin.  Each message is admitted by
+// a fresh instance of the module, so that its decision depends only on the
+// message, and any failure of the module rejects the message.
+type WasmRule struct {
+	conf     FilterPlugin
+	channels map[string]struct{}
+
+	mutex  sync.RWMutex
+	module *wasm.Module
+}
+
+// NewWasmRule loads the module of the filter plugin and creates its Rule.
+func NewWasmRule(conf FilterPlugin) (*WasmRule, error) {
+	r := &WasmRule{conf: conf}
+	if len(conf.Channels) > 0 {
+		r.channels = map[string]struct{}{}
+		for _, channelID := range conf.Channels {
+			r.channels[channelID] = struct{}{}
+		}
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Name returns the name of the filter plugin.
+func (r *WasmRule) Name() string {
+	return r.conf.Name
+}
+
+// Reload loads the module of the filter plugin again, so that the plugin can
+// be updated without restarting the orderer.  The rule keeps its module if
+// the new one is invalid.
+func (r *WasmRule) Reload() error {
+	binary, err := ioutil.ReadFile(r.conf.Path)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read filter plugin %s", r.conf.Name)
+	}
+	module, err := wasm.Compile(binary)
+	if err != nil {
+		return errors.WithMessage(err, "failed to compile filter plugin "+r.conf.Name)
+	}
+	if !module.ExportsMemory("memory") {
+		return errors.Errorf("filter plugin %s does not export its memory", r.conf.Name)
+	}
+	for name, expected := range map[string]wasm.FuncType{"alloc": allocType, "admit": admitType} {
+		ft, ok := module.ExportedFunc(name)
+		if !ok {
+			return errors.Errorf("filter plugin %s does not export %s", r.conf.Name, name)
+		}
+		if !ft.Equal(expected) {
+			return errors.Errorf("filter plugin %s exports %s with the wrong signature", r.conf.Name, name)
+		}
+	}
+
+	r.mutex.Lock()
+	r.module = module
+	r.mutex.Unlock()
+	return nil
+}
+
+// Apply runs the filter plugin on the message.
+func (r *WasmRule) Apply(message *cb.Envelope) error {
+	r.mutex.RLock()
+	module := r.module
+	r.mutex.RUnlock()
+
+	data, err := proto.Marshal(message)
+	if err != nil {
+		return errors.Wrapf(err, "filter plugin %s could not marshal message", r.conf.Name)
+	}
+
+	var reason string
+	hosts := map[string]wasm.HostFunc{
+		"env.reject": {Type: bytesType, Fn: func(inst *wasm.Instance, args []uint64) ([]uint64, error) {
+			b, err := readBytes(inst, args, maxRejectReason)
+			reason = string(b)
+			return nil, err
+		}},
+		"env.log": {Type: bytesType, Fn: func(inst *wasm.Instance, args []uint64) ([]uint64, error) {
+			b, err := readBytes(inst, args, maxRejectReason)
+			logger.Debugf("Filter plugin %s: %s", r.conf.Name, b)
+			return nil, err
+		}},
+	}
+	inst, err := module.Instantiate(hosts, wasm.Limits{Fuel: r.conf.Fuel, MaxMemoryPages: r.conf.MaxMemoryPages})
+	if err != nil {
+		return errors.WithMessage(err, "failed to instantiate filter plugin "+r.conf.Name)
+	}
+	results, err := inst.Call("alloc", uint64(len(data)))
+	if err != nil {
+		return errors.WithMessage(err, "filter plugin "+r.conf.Name+" failed")
+	}
+	ptr := uint32(results[0])
+	if err := inst.Write(ptr, data); err != nil {
+		return errors.WithMessage(err, "filter plugin "+r.conf.Name+" allocated invalid memory")
+	}
+	results, err = inst.Call("admit", uint64(ptr), uint64(len(data)))
+	if err != nil {
+		return errors.WithMessage(err, "filter plugin "+r.conf.Name+" failed")
+	}
+	if uint32(results[0]) != 0 {
+		if reason == "" {
+			reason = "message not admitted"
+		}
+		return errors.Wrapf(errors.WithStack(ErrPermissionDenied), "rejected by filter plugin %s: %s", r.conf.Name, reason)
+	}
+	return nil
+}
+
+// readBytes reads the bytes of the memory of the instance at the address
+// and size given as arguments, truncated to max bytes
+func readBytes(inst *wasm.Instance, args []uint64, max uint32) ([]byte, error) {
+	size := uint32(args[1])
+	if size > max {
+		size = max
+	}
+	return inst.Read(uint32(args[0]), size)
+}
+
+// PluginRules are the filter plugins of the orderer.
+type PluginRules []*WasmRule
+
+// ForChannel returns the filter plugins applying to the channel.
+func (p PluginRules) ForChannel(channelID string) []Rule {
+	var rules []Rule
+	for _, r := range p {
+		if r.channels != nil {
+			if _, ok := r.channels[channelID]; !ok {
+				continue
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// ReloadHandler returns the handler of the admin operation reloading the
+// modules of the filter plugins.  It responds with the names of the plugins
+// reloaded and the errors of those which kept their previous module.
+func (p PluginRules) ReloadHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		resp := struct {
+			Reloaded []string          `json:"reloaded"`
+			Failed   map[string]string `json:"failed,omitempty"`
+		}{Reloaded: []string{}}
+		for _, r := range p {
+			if err := r.Reload(); err != nil {
+				logger.Warningf("Failed to reload filter plugin %s: %s", r.Name(), err)
+				if resp.Failed == nil {
+					resp.Failed = map[string]string{}
+				}
+				resp.Failed[r.Name()] = err.Error()
+				continue
+			}
+			logger.Infof("Reloaded filter plugin %s", r.Name())
+			resp.Reloaded = append(resp.Reloaded, r.Name())
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if resp.Failed != nil {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		json.NewEncoder(w).Encode(resp)
+	})
+}
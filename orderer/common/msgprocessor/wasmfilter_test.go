@@ -0,0 +1,113 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package msgprocessor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func filterPlugin(path string) FilterPlugin {
+	return FilterPlugin{Name: "test", Path: path, Fuel: 100000, MaxMemoryPages: 4}
+}
+
+func TestWasmRule(t *testing.T) {
+	r, err := NewWasmRule(filterPlugin("testdata/maxsize.wasm"))
+	require.NoError(t, err)
+	assert.Equal(t, "test", r.Name())
+
+	assert.NoError(t, r.Apply(&cb.Envelope{Payload: []byte("small")}))
+
+	err = r.Apply(&cb.Envelope{Payload: make([]byte, 200)})
+	assert.Equal(t, ErrPermissionDenied, errors.Cause(err))
+	assert.Contains(t, err.Error(), "rejected by filter plugin test: too long")
+}
+
+func TestWasmRuleFailsClosed(t *testing.T) {
+	r, err := NewWasmRule(filterPlugin("testdata/spin.wasm"))
+	require.NoError(t, err)
+	err = r.Apply(&cb.Envelope{Payload: []byte("small")})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "filter plugin test failed: out of fuel")
+	assert.NotEqual(t, ErrPermissionDenied, errors.Cause(err))
+}
+
+func TestNewWasmRuleInvalid(t *testing.T) {
+	_, err := NewWasmRule(filterPlugin("testdata/missing.wasm"))
+	assert.Error(t, err)
+
+	_, err = NewWasmRule(filterPlugin("testdata/cert.pem"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to compile filter plugin test")
+}
+
+func TestWasmRuleReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "filterplugin")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "plugin.wasm")
+	copyFile := func(src string) {
+		data, err := ioutil.ReadFile(src)
+		require.NoError(t, err)
+		require.NoError(t, ioutil.WriteFile(path, data, 0644))
+	}
+
+	copyFile("testdata/spin.wasm")
+	r, err := NewWasmRule(filterPlugin(path))
+	require.NoError(t, err)
+	rules := PluginRules{r}
+	assert.Error(t, r.Apply(&cb.Envelope{}))
+
+	copyFile("testdata/maxsize.wasm")
+	resp := httptest.NewRecorder()
+	rules.ReloadHandler().ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/filterplugins/reload", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	assert.JSONEq(t, `{"reloaded":["test"]}`, resp.Body.String())
+	assert.NoError(t, r.Apply(&cb.Envelope{}))
+
+	// 无效的模块不替换已加载的模块
+	require.NoError(t, ioutil.WriteFile(path, []byte("garbage"), 0644))
+	resp = httptest.NewRecorder()
+	rules.ReloadHandler().ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/filterplugins/reload", nil))
+	assert.Equal(t, http.StatusInternalServerError, resp.Code)
+	var body struct {
+		Reloaded []string          `json:"reloaded"`
+		Failed   map[string]string `json:"failed"`
+	}
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &body))
+	assert.Empty(t, body.Reloaded)
+	assert.Contains(t, body.Failed["test"], "failed to compile filter plugin test")
+	assert.NoError(t, r.Apply(&cb.Envelope{}))
+
+	resp = httptest.NewRecorder()
+	rules.ReloadHandler().ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/filterplugins/reload", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
+
+func TestPluginRulesForChannel(t *testing.T) {
+	all, err := NewWasmRule(filterPlugin("testdata/maxsize.wasm"))
+	require.NoError(t, err)
+	conf := filterPlugin("testdata/maxsize.wasm")
+	conf.Channels = []string{"foo"}
+	foo, err := NewWasmRule(conf)
+	require.NoError(t, err)
+
+	rules := PluginRules{all, foo}
+	assert.Equal(t, []Rule{all, foo}, rules.ForChannel("foo"))
+	assert.Equal(t, []Rule{all}, rules.ForChannel("bar"))
+	assert.Empty(t, PluginRules(nil).ForChannel("foo"))
+}
@@ -8,13 +8,19 @@ package multichannel
 
 import (
 	"sync"
+	"time"
 
+	"github.com/hyperledger/fabric/common/blocksig"
 	newchannelconfig "github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/configtx"
 	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	"github.com/hyperledger/fabric/common/txtimeline"
 	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
+	"github.com/hyperledger/fabric/orderer/common/tracing"
 	cb "github.com/hyperledger/fabric/protos/common"
+	mspprotos "github.com/hyperledger/fabric/protos/msp"
 	"github.com/hyperledger/fabric/protos/utils"
 
 	"github.com/golang/protobuf/proto"
@@ -38,7 +44,38 @@ type BlockWriter struct {
 	lastConfigBlockNum uint64
 	lastConfigSeq      uint64
 	lastBlock          *cb.Block
+	lastRandomBeacon   []byte
 	committingBlock    sync.Mutex
+	fenced             bool //通道被删除后不再提交区块，由committingBlock保护
+	txTimeline         *txtimeline.Recorder
+	tracer             *tracing.Tracer
+	blockFanout        *fanout.Multicaster
+	batchSigning       *BatchSigning
+	batch              *signatureBatch
+}
+
+// BatchSigning configures the block writers to sign the blocks in batches: rather
+// than signing each block, a block writer signs the root of the Merkle tree of the
+// blocks of a batch once the batch closes, which pays off at block rates where
+// signing dominates the cost of writing the blocks.  The blocks of a batch are held
+// until the batch closes, and are then appended to the ledger with the leaf hashes of
+// the batch and the signatures of their root, so that each block is verified on its
+// own, as the blocksig package does.
+type BatchSigning struct {
+	// MaxBlocks is the number of blocks after which a batch is closed
+	MaxBlocks int
+	// MaxDelay is the time after which a batch is closed, counted from its first block,
+	// which bounds the time the blocks are held before they are appended
+	MaxDelay time.Duration
+}
+
+// signatureBatch is the batch of the blocks written since the last batch closed,
+// which are held until the batch closes
+type signatureBatch struct {
+	firstBlock uint64
+	blocks     []*cb.Block
+	leaves     [][]byte
+	timer      *time.Timer
 }
 
 func newBlockWriter(lastBlock *cb.Block, r *Registrar, support blockWriterSupport) *BlockWriter {
@@ -47,6 +84,10 @@ func newBlockWriter(lastBlock *cb.Block, r *Registrar, support blockWriterSuppor
 		lastConfigSeq: support.Sequence(),
 		lastBlock:     lastBlock,
 		registrar:     r,
+		txTimeline:    r.txTimeline,
+		tracer:        r.tracer,
+		blockFanout:   r.blockFanout,
+		batchSigning:  r.batchSigning,
 	}
 
 	// If this is the genesis block, the lastconfig field may be empty, and, the last config block is necessarily block 0
@@ -59,6 +100,14 @@ func newBlockWriter(lastBlock *cb.Block, r *Registrar, support blockWriterSuppor
 		}
 	}
 
+	// Blocks written before random beacons were introduced carry no beacon, in which case the
+	// beacon chain starts afresh with the next block
+	if beacon, err := utils.GetRandomBeaconFromBlock(lastBlock); err == nil {
+		bw.lastRandomBeacon = beacon
+	} else {
+		logger.Debugf("[channel: %s] Block %d carries no random beacon: %s", support.ChainID(), lastBlock.Header.Number, err)
+	}
+
 	logger.Debugf("[channel: %s] Creating block writer for tip of chain (blockNumber=%d, lastConfigBlockNum=%d, lastConfigSeq=%d)", support.ChainID(), lastBlock.Header.Number, bw.lastConfigBlockNum, bw.lastConfigSeq)
 	return bw
 }
@@ -89,9 +138,29 @@ func (bw *BlockWriter) CreateNextBlock(messages []*cb.Envelope) *cb.Block {
 	//设置交易集合数据
 	block.Data = data
 
+	bw.recordBlockCut(block.Header.Number, messages)
+
 	return block
 }
 
+// recordBlockCut notes the cut of the block in the transaction timeline, if one is being recorded,
+// and in the tracer, if messages are traced.
+func (bw *BlockWriter) recordBlockCut(blockNumber uint64, messages []*cb.Envelope) {
+	if bw.txTimeline == nil && bw.tracer == nil {
+		return
+	}
+	txIDs := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		chdr, err := utils.ChannelHeader(msg)
+		if err != nil {
+			continue
+		}
+		txIDs = append(txIDs, chdr.TxId)
+	}
+	bw.txTimeline.BlockCut(bw.support.ChainID(), blockNumber, txIDs)
+	bw.tracer.BlockCut(bw.support.ChainID(), blockNumber, txIDs)
+}
+
 // WriteConfigBlock should be invoked for blocks which contain a config transaction.
 // This call will block until the new config has taken effect, then will return
 // while the block is written asynchronously to disk.
@@ -149,6 +218,12 @@ func (bw *BlockWriter) WriteConfigBlock(block *cb.Block, encodedMetadataValue []
 
 		//更新通道上链支持对象的通道配置实体，不需要直接修改多通道注册管理器上的链支持对象字典
 		bw.support.Update(bundle)
+
+		//系统通道配置变更告警
+		if bw.registrar != nil && bw.registrar.protection.AlertOnConfigChange && chdr.ChannelId == bw.registrar.systemChannelID {
+			logger.Warningf("ALERT: [channel: %s] System channel config updated to sequence %d in block %d, update signed by %v",
+				chdr.ChannelId, configEnvelope.Config.Sequence, block.Header.Number, configUpdateSigners(configEnvelope.LastUpdate))
+		}
 	default:
 		logger.Panicf("Told to write a config block with unknown header type: %v", chdr.Type)
 	}
@@ -178,30 +253,93 @@ func (bw *BlockWriter) WriteBlock(block *cb.Block, encodedMetadataValue []byte)
 	}()
 }
 
+// Checkpoint calls fn with the height of the ledger while no block is being
+// committed, waiting for the block being committed if any, so that fn sees
+// the ledger and the state of the consenter at the same block.  The open
+// signature batch, if any, is closed first so that its blocks are in the
+// ledger.  Blocks are not committed until fn returns.
+func (bw *BlockWriter) Checkpoint(fn func(height uint64) error) error {
+	bw.committingBlock.Lock()
+	defer bw.committingBlock.Unlock()
+	if bw.batch != nil && !bw.fenced {
+		bw.closeBatch()
+	}
+	return fn(bw.support.Height())
+}
+
+// Fence waits for the block being committed, if any, and stops the block writer
+// from committing the blocks written afterwards, so that the ledger of a removed
+// channel is not written while it is deleted.  The blocks of the open signature
+// batch, if any, are dropped.
+func (bw *BlockWriter) Fence() {
+	bw.committingBlock.Lock()
+	defer bw.committingBlock.Unlock()
+	bw.fenced = true
+	if bw.batch != nil {
+		bw.batch.timer.Stop()
+		logger.Warningf("[channel: %s] Dropping blocks %d to %d of the open signature batch of the removed channel", bw.support.ChainID(), bw.batch.firstBlock, bw.batch.firstBlock+uint64(len(bw.batch.blocks))-1)
+		bw.batch = nil
+	}
+}
+
 // commitBlock should only ever be invoked with the bw.committingBlock held
 // this ensures that the encoded config sequence numbers stay in sync
 //提交区块到区块账本中（可以用于更新区块元数据）
 func (bw *BlockWriter) commitBlock(encodedMetadataValue []byte) {
+	if bw.fenced {
+		logger.Warningf("[channel: %s] Dropping block %d written after the channel was removed", bw.support.ChainID(), bw.lastBlock.GetHeader().Number)
+		return
+	}
+
 	// Set the orderer-related metadata field
 	//更新Orderer相关的元数据
 	if encodedMetadataValue != nil {
 		bw.lastBlock.Metadata.Metadata[cb.BlockMetadataIndex_ORDERER] = utils.MarshalOrPanic(&cb.Metadata{Value: encodedMetadataValue})
 	}
+	//添加区块元数据中的随机信标
+	bw.addRandomBeacon(bw.lastBlock)
+	if bw.batchSigning != nil {
+		//批量签名模式下最新配置区块不单独签名，由批次的默克尔树叶子覆盖
+		bw.addLastConfig(bw.lastBlock)
+		//区块暂存于批次中，批次关闭时签名后再写入账本
+		bw.addToBatch(bw.lastBlock)
+		return
+	}
 	//添加区块元数据中的签名
 	bw.addBlockSignature(bw.lastBlock)
 	//添加区块元数据中的最新配置区块和签名
 	bw.addLastConfigSignature(bw.lastBlock)
 
+	bw.appendBlock(bw.lastBlock)
+}
+
+// appendBlock appends the signed block to the ledger and publishes it
+func (bw *BlockWriter) appendBlock(block *cb.Block) {
 	//添加新的区块
 	//将新区块添加到当前的通道账本的区块数据文件中，建立并更新区块索引信息
-	err := bw.support.Append(bw.lastBlock)
+	err := bw.support.Append(block)
 	if err != nil {
 		logger.Panicf("[channel: %s] Could not append block: %s", bw.support.ChainID(), err)
 	}
-	logger.Debugf("[channel: %s] Wrote block %d", bw.support.ChainID(), bw.lastBlock.GetHeader().Number)
+	logger.Debugf("[channel: %s] Wrote block %d", bw.support.ChainID(), block.GetHeader().Number)
+	bw.tracer.BlockCommitted(bw.support.ChainID(), block.GetHeader().Number)
+
+	bw.blockFanout.Publish(bw.support.ChainID(), block)
+}
+
+// addRandomBeacon sets the random beacon value of the block, chaining it to the
+// beacon of the previously written block so that anyone holding the chain can verify it
+func (bw *BlockWriter) addRandomBeacon(block *cb.Block) {
+	beacon := utils.ComputeRandomBeacon(bw.lastRandomBeacon, block.Header)
+	bw.lastRandomBeacon = beacon
+
+	block.Metadata.Metadata[cb.BlockMetadataIndex_RANDOM_BEACON] = utils.MarshalOrPanic(&cb.Metadata{
+		Value: beacon,
+	})
 }
 
 //封装了对签名头部（含有签名者身份信息与消息随机数Nonce）与区块头部对的组合信息签名
+//保留共识组件已放入区块的其他排序节点的签名
 func (bw *BlockWriter) addBlockSignature(block *cb.Block) {
 	blockSignature := &cb.MetadataSignature{
 		SignatureHeader: utils.MarshalOrPanic(utils.NewSignatureHeaderOrPanic(bw.support)),
@@ -213,17 +351,49 @@ func (bw *BlockWriter) addBlockSignature(block *cb.Block) {
 
 	blockSignature.Signature = utils.SignOrPanic(bw.support, util.ConcatenateBytes(blockSignatureValue, blockSignature.SignatureHeader, block.Header.Bytes()))
 
+	signatures := &cb.Metadata{}
+	if existing := block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES]; len(existing) > 0 {
+		if err := proto.Unmarshal(existing, signatures); err != nil {
+			logger.Panicf("[channel: %s] Block %d carries malformed signatures: %s", bw.support.ChainID(), block.Header.Number, err)
+		}
+	}
+
 	block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{
-		Value: blockSignatureValue,
-		Signatures: []*cb.MetadataSignature{
-			blockSignature,
-		},
+		Value:      blockSignatureValue,
+		Signatures: append(signatures.Signatures, blockSignature),
 	})
 }
 
 //封装了当前最新配置值lastConfigValue（记录最新配置区块的区块号）和组合信息（包含lastConfigValue、签名头部与区块头部）的签名
 //同时，该方法还获取了当前通道的最新配置序号configSeq
 func (bw *BlockWriter) addLastConfigSignature(block *cb.Block) {
+	lastConfigSignature := &cb.MetadataSignature{
+		SignatureHeader: utils.MarshalOrPanic(utils.NewSignatureHeaderOrPanic(bw.support)),
+	}
+
+	lastConfigValue := bw.lastConfigValue(block)
+
+	lastConfigSignature.Signature = utils.SignOrPanic(bw.support, util.ConcatenateBytes(lastConfigValue, lastConfigSignature.SignatureHeader, block.Header.Bytes()))
+
+	block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{
+		Value: lastConfigValue,
+		Signatures: []*cb.MetadataSignature{
+			lastConfigSignature,
+		},
+	})
+}
+
+// addLastConfig sets the last config of the block without signing it, for the
+// blocks signed in batches
+func (bw *BlockWriter) addLastConfig(block *cb.Block) {
+	block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{
+		Value: bw.lastConfigValue(block),
+	})
+}
+
+// lastConfigValue returns the encoded last config of the block, noting the block
+// as the last config block if the config sequence changed
+func (bw *BlockWriter) lastConfigValue(block *cb.Block) []byte {
 	configSeq := bw.support.Sequence()
 	//如果区块写组件上最新配置区块的区块号小鱼configSeq，则说明当前通道配置发生了更新
 	//因此，将刽写组件上最新的配置区块的区块号更新为最新区块号，将最新的通道配置序号更新为configSeq
@@ -233,19 +403,102 @@ func (bw *BlockWriter) addLastConfigSignature(block *cb.Block) {
 		bw.lastConfigSeq = configSeq
 	}
 
-	lastConfigSignature := &cb.MetadataSignature{
-		SignatureHeader: utils.MarshalOrPanic(utils.NewSignatureHeaderOrPanic(bw.support)),
+	logger.Debugf("[channel: %s] About to write block, setting its LAST_CONFIG to %d", bw.support.ChainID(), bw.lastConfigBlockNum)
+	return utils.MarshalOrPanic(&cb.LastConfig{Index: bw.lastConfigBlockNum})
+}
+
+// addToBatch adds the block to the open signature batch, opening one if needed,
+// and closes the batch if the block is a config block or the batch is full.  The
+// last config of the block must be set.  The batch is otherwise closed by a timer
+// once MaxDelay elapsed since its first block.  Config blocks close their batch, so
+// that the blocks of a batch are validated against a single config.
+func (bw *BlockWriter) addToBatch(block *cb.Block) {
+	leaf, err := blocksig.Leaf(block)
+	if err != nil {
+		logger.Panicf("[channel: %s] Could not compute the leaf of block %d: %s", bw.support.ChainID(), block.Header.Number, err)
 	}
+	if bw.batch == nil {
+		batch := &signatureBatch{firstBlock: block.Header.Number}
+		batch.timer = time.AfterFunc(bw.batchSigning.MaxDelay, func() {
+			bw.closeBatchAfterDelay(batch)
+		})
+		bw.batch = batch
+	}
+	bw.batch.blocks = append(bw.batch.blocks, block)
+	bw.batch.leaves = append(bw.batch.leaves, leaf)
 
-	lastConfigValue := utils.MarshalOrPanic(&cb.LastConfig{Index: bw.lastConfigBlockNum})
-	logger.Debugf("[channel: %s] About to write block, setting its LAST_CONFIG to %d", bw.support.ChainID(), bw.lastConfigBlockNum)
+	if bw.lastConfigBlockNum == block.Header.Number || len(bw.batch.leaves) >= bw.batchSigning.MaxBlocks {
+		bw.closeBatch()
+	}
+}
 
-	lastConfigSignature.Signature = utils.SignOrPanic(bw.support, util.ConcatenateBytes(lastConfigValue, lastConfigSignature.SignatureHeader, block.Header.Bytes()))
+// closeBatchAfterDelay closes the batch once MaxDelay elapsed since its first block,
+// unless it was closed in the meantime
+func (bw *BlockWriter) closeBatchAfterDelay(batch *signatureBatch) {
+	bw.committingBlock.Lock()
+	defer bw.committingBlock.Unlock()
+	if bw.batch != batch || bw.fenced {
+		return
+	}
+	bw.closeBatch()
+}
 
-	block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{
-		Value: lastConfigValue,
+// closeBatch signs the root of the open signature batch, and appends the blocks of
+// the batch to the ledger, each with the leaf hashes of the batch and the signature.
+// It must be called with bw.committingBlock held.
+func (bw *BlockWriter) closeBatch() {
+	batch := bw.batch
+	bw.batch = nil
+	batch.timer.Stop()
+
+	batchSignature := &cb.MetadataSignature{
+		SignatureHeader: utils.MarshalOrPanic(utils.NewSignatureHeaderOrPanic(bw.support)),
+	}
+	batchSignature.Signature = utils.SignOrPanic(bw.support, blocksig.SignedBytes(blocksig.Root(batch.leaves), batchSignature.SignatureHeader))
+	signatures := utils.MarshalOrPanic(&cb.Metadata{
+		Value: blocksig.BatchValue(&cb.BlockSignatureBatch{
+			FirstBlock: batch.firstBlock,
+			LeafHashes: batch.leaves,
+		}),
 		Signatures: []*cb.MetadataSignature{
-			lastConfigSignature,
+			batchSignature,
 		},
 	})
+
+	for _, block := range batch.blocks {
+		block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = signatures
+		bw.appendBlock(block)
+	}
+	logger.Debugf("[channel: %s] Signed blocks %d to %d in a batch", bw.support.ChainID(), batch.firstBlock, batch.firstBlock+uint64(len(batch.blocks))-1)
+}
+
+// configUpdateSigners returns the MSP IDs of the creators of the signatures on
+// a config update, for reporting purposes
+func configUpdateSigners(configUpdate *cb.Envelope) []string {
+	if configUpdate == nil {
+		return nil
+	}
+	payload, err := utils.UnmarshalPayload(configUpdate.Payload)
+	if err != nil {
+		return nil
+	}
+	configUpdateEnv, err := configtx.UnmarshalConfigUpdateEnvelope(payload.Data)
+	if err != nil {
+		return nil
+	}
+	var signers []string
+	for _, sig := range configUpdateEnv.Signatures {
+		sigHdr, err := utils.GetSignatureHeader(sig.SignatureHeader)
+		if err != nil {
+			signers = append(signers, "<malformed>")
+			continue
+		}
+		id := &mspprotos.SerializedIdentity{}
+		if err := proto.Unmarshal(sigHdr.Creator, id); err != nil {
+			signers = append(signers, "<malformed>")
+			continue
+		}
+		signers = append(signers, id.Mspid)
+	}
+	return signers
 }
@@ -17,14 +17,19 @@ limitations under the License.
 package multichannel
 
 import (
+	"fmt"
 	"testing"
+	"time"
 
+	"github.com/hyperledger/fabric/common/blocksig"
 	newchannelconfig "github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
 	mockconfigtx "github.com/hyperledger/fabric/common/mocks/configtx"
 	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
 	cb "github.com/hyperledger/fabric/protos/common"
+	mspprotos "github.com/hyperledger/fabric/protos/msp"
 	"github.com/hyperledger/fabric/protos/utils"
 	"github.com/stretchr/testify/assert"
 )
@@ -68,6 +73,17 @@ func TestBlockSignature(t *testing.T) {
 	md := utils.GetMetadataFromBlockOrPanic(block, cb.BlockMetadataIndex_SIGNATURES)
 	assert.Nil(t, md.Value, "Value is empty in this case")
 	assert.NotNil(t, md.Signatures, "Should have signature")
+
+	// the signatures of the other orderers are kept
+	other := &cb.MetadataSignature{SignatureHeader: []byte("other"), Signature: []byte("signature")}
+	block = cb.NewBlock(8, []byte("foo"))
+	block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{Signatures: []*cb.MetadataSignature{other}})
+	bw.addBlockSignature(block)
+
+	md = utils.GetMetadataFromBlockOrPanic(block, cb.BlockMetadataIndex_SIGNATURES)
+	if assert.Len(t, md.Signatures, 2) {
+		assert.Equal(t, other.Signature, md.Signatures[0].Signature)
+	}
 }
 
 func TestBlockLastConfig(t *testing.T) {
@@ -99,6 +115,23 @@ func TestBlockLastConfig(t *testing.T) {
 	assert.Equal(t, newBlockNum, lc)
 }
 
+func TestBlockRandomBeacon(t *testing.T) {
+	bw := &BlockWriter{}
+
+	first := cb.NewBlock(8, []byte("foo"))
+	bw.addRandomBeacon(first)
+	firstBeacon, err := utils.GetRandomBeaconFromBlock(first)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.ComputeRandomBeacon(nil, first.Header), firstBeacon)
+
+	second := cb.NewBlock(9, first.Header.Hash())
+	bw.addRandomBeacon(second)
+	secondBeacon, err := utils.GetRandomBeaconFromBlock(second)
+	assert.NoError(t, err)
+	assert.Equal(t, utils.ComputeRandomBeacon(firstBeacon, second.Header), secondBeacon)
+	assert.NotEqual(t, firstBeacon, secondBeacon)
+}
+
 func TestWriteConfigBlock(t *testing.T) {
 	// TODO, use assert.PanicsWithValue once available
 	t.Run("EmptyBlock", func(t *testing.T) {
@@ -192,3 +225,182 @@ func TestGoodWriteConfig(t *testing.T) {
 	omd := utils.GetMetadataFromBlockOrPanic(block, cb.BlockMetadataIndex_ORDERER)
 	assert.Equal(t, consenterMetadata, omd.Value)
 }
+
+func TestWriteBlockPublishes(t *testing.T) {
+	l := NewRAMLedger(10)
+	multicaster := fanout.New()
+	subscription := multicaster.Subscribe(genesisconfig.TestChainID, 1)
+
+	bw := &BlockWriter{
+		support: &mockBlockWriterSupport{
+			LocalSigner: mockCrypto(),
+			ReadWriter:  l,
+			Validator:   &mockconfigtx.Validator{ChainIDVal: genesisconfig.TestChainID},
+		},
+		blockFanout: multicaster,
+	}
+
+	block := cb.NewBlock(1, genesisBlock.Header.Hash())
+	bw.WriteBlock(block, nil)
+
+	published := <-subscription.Blocks()
+	assert.Equal(t, block.Header, published.Header)
+	assert.Equal(t, uint64(2), l.Height(), "block should be committed before it is published")
+}
+
+func TestCheckpoint(t *testing.T) {
+	l := NewRAMLedger(10)
+	bw := &BlockWriter{
+		support: &mockBlockWriterSupport{
+			LocalSigner: mockCrypto(),
+			ReadWriter:  l,
+			Validator:   &mockconfigtx.Validator{ChainIDVal: genesisconfig.TestChainID},
+		},
+		blockFanout: fanout.New(),
+	}
+
+	// the checkpoint waits for the block being committed
+	bw.WriteBlock(cb.NewBlock(1, genesisBlock.Header.Hash()), nil)
+	var height uint64
+	assert.NoError(t, bw.Checkpoint(func(h uint64) error {
+		height = h
+		return nil
+	}))
+	assert.Equal(t, uint64(2), height)
+	assert.EqualError(t, bw.Checkpoint(func(uint64) error { return fmt.Errorf("failed") }), "failed")
+}
+
+// writeBlocks writes count blocks with the block writer and returns them once
+// they are committed
+func writeBlocks(bw *BlockWriter, count int) []*cb.Block {
+	var blocks []*cb.Block
+	for i := 0; i < count; i++ {
+		block := bw.CreateNextBlock([]*cb.Envelope{{Payload: []byte(fmt.Sprintf("tx%d", i))}})
+		bw.WriteBlock(block, nil)
+		blocks = append(blocks, block)
+	}
+	bw.committingBlock.Lock()
+	bw.committingBlock.Unlock()
+	return blocks
+}
+
+// assertBatchSigned checks that each block is signed in the batch of the
+// blocks, the mock signer signing data with the data itself
+func assertBatchSigned(t *testing.T, blocks ...*cb.Block) {
+	for _, block := range blocks {
+		batch, _, err := blocksig.Batch(block)
+		if !assert.NoError(t, err) || !assert.NotNil(t, batch, "block %d", block.Header.Number) {
+			continue
+		}
+		assert.Equal(t, blocks[0].Header.Number, batch.FirstBlock, "block %d", block.Header.Number)
+		assert.Len(t, batch.LeafHashes, len(blocks), "block %d", block.Header.Number)
+		signatureSet, err := blocksig.SignedData(block)
+		if assert.NoError(t, err, "block %d", block.Header.Number) && assert.Len(t, signatureSet, 1) {
+			assert.Equal(t, signatureSet[0].Data, signatureSet[0].Signature, "block %d", block.Header.Number)
+		}
+	}
+}
+
+func newBatchSigningBlockWriter(batchSigning *BatchSigning) (*BlockWriter, *mockconfigtx.Validator) {
+	validator := &mockconfigtx.Validator{ChainIDVal: genesisconfig.TestChainID}
+	bw := &BlockWriter{
+		support: &mockBlockWriterSupport{
+			LocalSigner: mockCrypto(),
+			ReadWriter:  NewRAMLedger(10),
+			Validator:   validator,
+		},
+		lastBlock:    genesisBlock,
+		blockFanout:  fanout.New(),
+		batchSigning: batchSigning,
+	}
+	return bw, validator
+}
+
+func TestBatchSignature(t *testing.T) {
+	bw, validator := newBatchSigningBlockWriter(&BatchSigning{MaxBlocks: 3, MaxDelay: time.Hour})
+
+	blocks := writeBlocks(bw, 4)
+	for _, block := range blocks[:3] {
+		lastConfig := utils.GetMetadataFromBlockOrPanic(block, cb.BlockMetadataIndex_LAST_CONFIG)
+		assert.Empty(t, lastConfig.Signatures, "the last config of block %d is covered by the batch", block.Header.Number)
+		assert.Equal(t, uint64(0), utils.GetLastConfigIndexFromBlockOrPanic(block))
+	}
+	assertBatchSigned(t, blocks[:3]...)
+	assert.Equal(t, uint64(4), bw.support.Height(), "the block of the open batch is held")
+	assert.Equal(t, uint64(4), bw.batch.firstBlock, "the next block opens a new batch")
+
+	// config blocks close their batch
+	validator.SequenceVal = 1
+	blocks = append(blocks, writeBlocks(bw, 1)...)
+	assert.Equal(t, uint64(5), utils.GetLastConfigIndexFromBlockOrPanic(blocks[4]))
+	assert.Equal(t, uint64(6), bw.support.Height())
+	assertBatchSigned(t, blocks[3:]...)
+	for number, block := range blocks {
+		assert.Equal(t, block, blockledger.GetBlock(bw.support, uint64(number+1)))
+	}
+}
+
+func TestBatchSignatureMaxDelay(t *testing.T) {
+	bw, _ := newBatchSigningBlockWriter(&BatchSigning{MaxBlocks: 10, MaxDelay: 10 * time.Millisecond})
+
+	// the batch is closed after MaxDelay although no more blocks are written
+	blocks := writeBlocks(bw, 2)
+	closed := func() bool {
+		bw.committingBlock.Lock()
+		defer bw.committingBlock.Unlock()
+		return bw.batch == nil
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !closed() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	bw.committingBlock.Lock()
+	defer bw.committingBlock.Unlock()
+	assert.Nil(t, bw.batch)
+	assert.Equal(t, uint64(3), bw.support.Height())
+	assertBatchSigned(t, blocks...)
+}
+
+func TestBatchSignatureCheckpointAndFence(t *testing.T) {
+	bw, _ := newBatchSigningBlockWriter(&BatchSigning{MaxBlocks: 10, MaxDelay: time.Hour})
+
+	// the checkpoint closes the open batch so that the ledger holds its blocks
+	blocks := writeBlocks(bw, 2)
+	var height uint64
+	assert.NoError(t, bw.Checkpoint(func(h uint64) error {
+		height = h
+		return nil
+	}))
+	assert.Equal(t, uint64(3), height)
+	assertBatchSigned(t, blocks...)
+
+	// the blocks held when the channel is removed are dropped
+	writeBlocks(bw, 1)
+	bw.Fence()
+	assert.Nil(t, bw.batch)
+	assert.Equal(t, uint64(3), bw.support.Height())
+}
+
+func TestConfigUpdateSigners(t *testing.T) {
+	signature := func(mspID string) *cb.ConfigSignature {
+		return &cb.ConfigSignature{
+			SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{
+				Creator: utils.MarshalOrPanic(&mspprotos.SerializedIdentity{Mspid: mspID}),
+			}),
+		}
+	}
+	configUpdate := &cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Data: utils.MarshalOrPanic(&cb.ConfigUpdateEnvelope{
+				Signatures: []*cb.ConfigSignature{
+					signature("OrdererMSP"),
+					signature("Org1MSP"),
+					{SignatureHeader: []byte("garbage")},
+				},
+			}),
+		}),
+	}
+
+	assert.Equal(t, []string{"OrdererMSP", "Org1MSP", "<malformed>"}, configUpdateSigners(configUpdate))
+	assert.Nil(t, configUpdateSigners(nil))
+}
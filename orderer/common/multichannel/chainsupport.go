@@ -7,15 +7,19 @@ SPDX-License-Identifier: Apache-2.0
 package multichannel
 
 import (
+	"time"
+
 	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
 	"github.com/hyperledger/fabric/orderer/common/blockcutter"
 	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	"github.com/hyperledger/fabric/orderer/common/tracing"
 	"github.com/hyperledger/fabric/orderer/consensus"
 	cb "github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/utils"
 
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 // ChainSupport holds the resources for a particular channel.
@@ -26,6 +30,7 @@ type ChainSupport struct {
 	consensus.Chain //共识组件链对象
 	cutter blockcutter.Receiver //消息切割组件
 	crypto.LocalSigner //本地签名者
+	consensusType string //共识组件链对象创建时使用的共识类型
 }
 
 func newChainSupport(
@@ -51,12 +56,12 @@ func newChainSupport(
 	cs := &ChainSupport{
 		ledgerResources: ledgerResources, //区块账本资源对象
 		LocalSigner:     signer, //本地签名者
-		cutter:          blockcutter.NewReceiverImpl(ledgerResources), //消息切割组件
+		cutter:          newTracedCutter(blockcutter.NewReceiverImpl(ledgerResources), ledgerResources.ConfigtxValidator().ChainID(), registrar.tracer), //消息切割组件
 	}
 
 	// Set up the msgprocessor
 	//设置标准的通道消息处理器
-	cs.Processor = msgprocessor.NewStandardChannel(cs, msgprocessor.CreateStandardChannelFilters(cs))
+	cs.Processor = msgprocessor.NewStandardChannel(cs, registrar.ruleChain.StandardChannelFilters(cs))
 
 	// Set up the block writer
 	//将区块写入组件
@@ -70,6 +75,7 @@ func newChainSupport(
 	if !ok {
 		logger.Panicf("Error retrieving consenter of type: %s", consenterType)
 	}
+	cs.consensusType = consenterType
 
 	//注意链支持对象cs实现了ConsenterSupport接口，以支持Solo与Kafka共识组件链对象
 	//Solo共识组件只使用了cs参数，kafka共识组件则使用了两个参数
@@ -102,6 +108,29 @@ func (cs *ChainSupport) BlockCutter() blockcutter.Receiver {
 	return cs.cutter
 }
 
+// tracedCutter reports to the tracer each message ordered by the consenter,
+// whichever the consenter, as it hands them to the block cutter
+type tracedCutter struct {
+	blockcutter.Receiver
+	channelID string
+	tracer    *tracing.Tracer
+}
+
+// newTracedCutter returns the block cutter, wrapped if the tracer is non-nil
+func newTracedCutter(cutter blockcutter.Receiver, channelID string, tracer *tracing.Tracer) blockcutter.Receiver {
+	if tracer == nil {
+		return cutter
+	}
+	return &tracedCutter{Receiver: cutter, channelID: channelID, tracer: tracer}
+}
+
+func (tc *tracedCutter) Ordered(msg *cb.Envelope) ([][]*cb.Envelope, bool) {
+	if chdr, err := utils.ChannelHeader(msg); err == nil {
+		tc.tracer.Ordered(tc.channelID, chdr.TxId)
+	}
+	return tc.Receiver.Ordered(msg)
+}
+
 // Validate passes through to the underlying configtx.Validator
 func (cs *ChainSupport) Validate(configEnv *cb.ConfigEnvelope) error {
 	return cs.ConfigtxValidator().Validate(configEnv)
@@ -143,3 +172,38 @@ func (cs *ChainSupport) ConfigProto() *cb.Config {
 func (cs *ChainSupport) Sequence() uint64 {
 	return cs.ConfigtxValidator().Sequence()
 }
+
+// IngressWeight returns the share of the ordering throughput granted to the
+// channel by its latest config
+func (cs *ChainSupport) IngressWeight() uint32 {
+	return cs.SharedConfig().IngressWeight()
+}
+
+// ConsensusType returns the consensus type the chain was started with, which
+// may differ from the one in the latest config if it was changed since.
+func (cs *ChainSupport) ConsensusType() string {
+	return cs.consensusType
+}
+
+// statusProbeTimeout bounds how long the readiness of a chain which does not
+// report its status is probed
+const statusProbeTimeout = 100 * time.Millisecond
+
+// Status returns the health of the chain as reported by its consenter or,
+// should the consenter not report it, as probed by WaitReadyContext.
+func (cs *ChainSupport) Status() consensus.ChainStatus {
+	if reporter, ok := cs.Chain.(consensus.StatusReporter); ok {
+		return reporter.Status()
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), statusProbeTimeout)
+	defer cancel()
+	err := cs.WaitReadyContext(ctx)
+	if err == nil {
+		return consensus.ChainStatus{Ready: true}
+	}
+	status := consensus.ChainStatus{Reason: err.Error(), Lag: -1}
+	if notReady, ok := err.(*consensus.NotReadyError); ok {
+		status.Reason, status.Lag = notReady.Reason, notReady.Position
+	}
+	return status
+}
@@ -11,13 +11,17 @@ package multichannel
 
 import (
 	"fmt"
+	"sync"
 
 	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/configtx"
 	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	"github.com/hyperledger/fabric/common/txtimeline"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
 	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	"github.com/hyperledger/fabric/orderer/common/tracing"
 	"github.com/hyperledger/fabric/orderer/consensus"
 	cb "github.com/hyperledger/fabric/protos/common"
 	ab "github.com/hyperledger/fabric/protos/orderer"
@@ -97,6 +101,7 @@ type ledgerResources struct {
 // Registrar serves as a point of access and control for the individual channel resources.
 type Registrar struct {
 	chains          map[string]*ChainSupport //链支持对象字典
+	chainsLock      sync.Mutex //串行化链支持对象字典的更新（创建与删除通道），读取时无需加锁
 	consenters      map[string]consensus.Consenter //共识组件字典
 	ledgerFactory   blockledger.Factory //账本工厂对象组件
 	signer          crypto.LocalSigner //本地签名者ITIS
@@ -104,6 +109,12 @@ type Registrar struct {
 	systemChannel   *ChainSupport //系统通道链支持对象
 	templator       msgprocessor.ChannelConfigTemplator //通道配置模板，用于生成消息处理器
 	callbacks       []func(bundle *channelconfig.Bundle) //TLS认证链接回调函数列表
+	txTimeline      *txtimeline.Recorder //交易流程时间线记录器，为nil时不记录
+	tracer          *tracing.Tracer //消息追踪器，为nil时不追踪
+	blockFanout     *fanout.Multicaster //新区块分发器，将提交的区块推送给节点内部订阅者
+	protection      msgprocessor.SystemChannelProtection //系统通道防护配置
+	ruleChain       *msgprocessor.RuleChain //消息过滤规则链
+	batchSigning    *BatchSigning //批量区块签名配置，为nil时逐个区块签名
 }
 
 func getConfigTx(reader blockledger.Reader) *cb.Envelope {
@@ -123,6 +134,23 @@ func getConfigTx(reader blockledger.Reader) *cb.Envelope {
 	return utils.ExtractEnvelopeOrPanic(configBlock, 0)
 }
 
+// RegistrarOptions configures the optional behavior of the Registrar created by
+// NewRegistrar.  Each field may be left zero, which disables the behavior it
+// configures.
+type RegistrarOptions struct {
+	// TxTimeline is notified of every block cut on any channel
+	TxTimeline *txtimeline.Recorder
+	// Tracer is notified of every message ordered and every block cut and committed
+	Tracer *tracing.Tracer
+	// Protection hardens the system channel
+	Protection msgprocessor.SystemChannelProtection
+	// RuleChain builds the rules admitting the messages of each channel
+	RuleChain *msgprocessor.RuleChain
+	// BatchSigning signs the blocks of every channel in batches, or each block
+	// on its own if nil
+	BatchSigning *BatchSigning
+}
+
 // NewRegistrar produces an instance of a *Registrar.
 //创建Orderer节点上的多通道注册管理器对象。同时，创建系统通道与现存应用通道的链支持对象（chainSupport），将这些通道都注册到多通道注册管理器上，
 // 并调用chain.start()方法，一次启动每一个应用通道关联的链支持对象，最后启动系统通道的
@@ -130,13 +158,19 @@ func getConfigTx(reader blockledger.Reader) *cb.Envelope {
 //实现多通道管理机制，支持多个通道及其链上的数据相互隔离，确保只有同意个通道内的Peer才能接受该通道上的账本数据，切不允许其他通扫上的节点或外部非法节点接受与访问本通道数据，从而报数通道上的数据隐私
 //可用于创建多通道注册管理器对象
 func NewRegistrar(ledgerFactory blockledger.Factory, consenters map[string]consensus.Consenter,
-	signer crypto.LocalSigner, callbacks ...func(bundle *channelconfig.Bundle)) *Registrar {
+	signer crypto.LocalSigner, opts RegistrarOptions, callbacks ...func(bundle *channelconfig.Bundle)) *Registrar {
 	r := &Registrar{
 		chains:        make(map[string]*ChainSupport), //链支持对象字典
 		ledgerFactory: ledgerFactory, //账本工厂对象
 		consenters:    consenters, //共识组件字典
 		signer:        signer, //本地签名者
 		callbacks:     callbacks, //回调函数（比如TLS认证链接毁掉函数）
+		txTimeline:    opts.TxTimeline, //交易流程时间线记录器
+		tracer:        opts.Tracer, //消息追踪器
+		blockFanout:   fanout.New(), //新区块分发器
+		protection:    opts.Protection, //系统通道防护配置
+		ruleChain:     opts.RuleChain, //消息过滤规则链
+		batchSigning:  opts.BatchSigning, //批量区块签名配置
 	}
 
 	//获取该账本工厂对象关联的现存通道ID列表
@@ -173,7 +207,8 @@ func NewRegistrar(ledgerFactory blockledger.Factory, consenters map[string]conse
 			//创建默认通道配置模板
 			r.templator = msgprocessor.NewDefaultTemplator(chain)
 			//创建系统通道消息处理器
-			chain.Processor = msgprocessor.NewSystemChannel(chain, r.templator, msgprocessor.CreateSystemChannelFilters(r, chain))
+			chain.Processor = msgprocessor.NewSystemChannel(chain, r.templator, opts.RuleChain.SystemChannelFilters(r, chain),
+				msgprocessor.NewSystemChannelGuard(opts.Protection, chain))
 
 			// Retrieve genesis block to log its hash. See FAB-5450 for the purpose
 			//将账本的区块迭代器指针设置为最旧的区块位置
@@ -224,6 +259,27 @@ func NewRegistrar(ledgerFactory blockledger.Factory, consenters map[string]conse
 	return r
 }
 
+// TxTimeline returns the recorder of transaction timelines, which is nil if recording is disabled.
+func (r *Registrar) TxTimeline() *txtimeline.Recorder {
+	return r.txTimeline
+}
+
+// Tracer returns the tracer of the messages ordered, which is nil if tracing is disabled.
+func (r *Registrar) Tracer() *tracing.Tracer {
+	return r.tracer
+}
+
+// BlockFanout returns the multicaster to which every channel's block writer
+// publishes blocks once they are committed to the ledger.
+func (r *Registrar) BlockFanout() *fanout.Multicaster {
+	return r.blockFanout
+}
+
+// LedgerFactory returns the factory of the ledgers of the channels.
+func (r *Registrar) LedgerFactory() blockledger.Factory {
+	return r.ledgerFactory
+}
+
 // SystemChannelID returns the ChannelID for the system channel.
 func (r *Registrar) SystemChannelID() string {
 	return r.systemChannelID
@@ -247,6 +303,13 @@ func (r *Registrar) BroadcastChannelSupport(msg *cb.Envelope) (*cb.ChannelHeader
 		cs = r.systemChannel
 	}
 
+	return classifyBroadcast(chdr, cs)
+}
+
+// classifyBroadcast returns the message channel header, whether the message
+// is a config update and the channel resources processing it, or an error if
+// the message cannot be processed directly
+func classifyBroadcast(chdr *cb.ChannelHeader, cs *ChainSupport) (*cb.ChannelHeader, bool, *ChainSupport, error) {
 	isConfig := false
 	//检查消息的通道头部类型
 	//如果是通道配置交易消息类型，则设置配置交易消息标志位为true，否则设置为false，以表示区分普通交易消息和配置交易消息
@@ -261,6 +324,33 @@ func (r *Registrar) BroadcastChannelSupport(msg *cb.Envelope) (*cb.ChannelHeader
 	return chdr, isConfig, cs, nil
 }
 
+// channelCreationValidator is implemented by the message processor of the
+// system channel
+type channelCreationValidator interface {
+	ValidateChannelCreation(envConfigUpdate *cb.Envelope) (*cb.Config, error)
+}
+
+// ValidateChannelCreation validates a channel creation transaction against
+// the system channel without creating the channel, and returns the config of
+// the genesis block the channel would be created with.
+func (r *Registrar) ValidateChannelCreation(env *cb.Envelope) (*cb.Config, error) {
+	chdr, err := utils.ChannelHeader(env)
+	if err != nil {
+		return nil, fmt.Errorf("could not determine channel ID: %s", err)
+	}
+	if chdr.Type != int32(cb.HeaderType_CONFIG_UPDATE) {
+		return nil, errors.Errorf("message of type %s is not a channel creation transaction", cb.HeaderType(chdr.Type))
+	}
+	if _, ok := r.chains[chdr.ChannelId]; ok {
+		return nil, errors.Errorf("channel %s already exists", chdr.ChannelId)
+	}
+	validator, ok := r.systemChannel.Processor.(channelCreationValidator)
+	if !ok {
+		return nil, errors.New("system channel processor cannot validate channel creations")
+	}
+	return validator.ValidateChannelCreation(env)
+}
+
 // GetChain retrieves the chain support for a chain (and whether it exists)
 func (r *Registrar) GetChain(chainID string) (*ChainSupport, bool) {
 	cs, ok := r.chains[chainID]
@@ -315,6 +405,9 @@ func (r *Registrar) newChain(configtx *cb.Envelope) {
 	//添加创世区块（将配置交易消息构造成配置区块，也就是当前新应用通道的创世区块）
 	ledgerResources.Append(blockledger.CreateNextBlock(ledgerResources, []*cb.Envelope{configtx}))
 
+	r.chainsLock.Lock()
+	defer r.chainsLock.Unlock()
+
 	// Copy the map to allow concurrent reads from broadcast/deliver while the new chainSupport is
 	//复制当前Orderer上多通道注册管理器的chains链支持对象字典，允许同时提供Broadcast/Deliver服务
 	newChains := make(map[string]*ChainSupport)
@@ -339,11 +432,96 @@ func (r *Registrar) newChain(configtx *cb.Envelope) {
 	r.chains = newChains
 }
 
+// RemoveChannel halts the chain of a decommissioned channel, stops serving the
+// channel and removes its ledger, releasing the memory and the files they
+// hold.  The system channel cannot be removed, and the ledger factory must
+// implement blockledger.Remover.  The ledger is removed once the chain has
+// terminated and the block being committed, if any, is written.  The deliver
+// streams of the channel reading its ledger are then ended, while those waiting
+// at the tip for new blocks keep waiting until their clients disconnect.
+func (r *Registrar) RemoveChannel(channelID string) error {
+	remover, ok := r.ledgerFactory.(blockledger.Remover)
+	if !ok {
+		return errors.New("the ledger does not support removing channels")
+	}
+
+	r.chainsLock.Lock()
+	defer r.chainsLock.Unlock()
+
+	if channelID == r.systemChannelID {
+		return errors.Errorf("cannot remove the system channel %s", channelID)
+	}
+	cs, ok := r.chains[channelID]
+	if !ok {
+		return errors.Errorf("channel %s does not exist", channelID)
+	}
+
+	//复制链支持对象字典并去掉该通道，停止为其提供Broadcast/Deliver服务
+	newChains := make(map[string]*ChainSupport, len(r.chains))
+	for key, value := range r.chains {
+		if key != channelID {
+			newChains[key] = value
+		}
+	}
+	r.chains = newChains
+
+	//停止共识组件链对象并等待其终止，阻止其后写入区块，再删除区块账本
+	cs.Halt()
+	if terminator, ok := cs.Chain.(consensus.Terminator); ok {
+		<-terminator.Terminated()
+	}
+	cs.BlockWriter.Fence()
+	if err := remover.Remove(channelID); err != nil {
+		return errors.Wrapf(err, "error removing the ledger of channel %s", channelID)
+	}
+	logger.Infof("Removed channel %s", channelID)
+	return nil
+}
+
+// ChannelHeight returns the height of the ledger of the channel, and whether
+// the channel exists.
+func (r *Registrar) ChannelHeight(channelID string) (uint64, bool) {
+	cs, ok := r.GetChain(channelID)
+	if !ok {
+		return 0, false
+	}
+	return cs.Height(), true
+}
+
 // ChannelsCount returns the count of the current total number of channels.
 func (r *Registrar) ChannelsCount() int {
 	return len(r.chains)
 }
 
+// ChannelStatus returns the health of the chain of the channel, and whether
+// the channel exists.
+func (r *Registrar) ChannelStatus(channelID string) (consensus.ChainStatus, bool) {
+	cs, ok := r.GetChain(channelID)
+	if !ok {
+		return consensus.ChainStatus{}, false
+	}
+	return cs.Status(), true
+}
+
+// ChannelIDs returns the IDs of all channels currently known to the registrar.
+func (r *Registrar) ChannelIDs() []string {
+	chains := r.chains
+	ids := make([]string, 0, len(chains))
+	for id := range chains {
+		ids = append(ids, id)
+	}
+	return ids
+}
+
+// ConsenterTypes returns the consensus types the registrar can create chains for.
+func (r *Registrar) ConsenterTypes() []string {
+	types := make([]string, 0, len(r.consenters))
+	for consensusType := range r.consenters {
+		types = append(types, consensusType)
+	}
+	return types
+}
+
 // NewChannelConfig produces a new template channel configuration based on the system channel's current config.
 func (r *Registrar) NewChannelConfig(envConfigUpdate *cb.Envelope) (channelconfig.Resources, error) {
 	return r.templator.NewChannelConfig(envConfigUpdate)
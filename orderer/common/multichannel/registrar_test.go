@@ -12,17 +12,20 @@ import (
 	"time"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
 	ramledger "github.com/hyperledger/fabric/common/ledger/blockledger/ram"
 	mockchannelconfig "github.com/hyperledger/fabric/common/mocks/config"
+	mockconfigtx "github.com/hyperledger/fabric/common/mocks/configtx"
 	mockcrypto "github.com/hyperledger/fabric/common/mocks/crypto"
 	mockpolicies "github.com/hyperledger/fabric/common/mocks/policies"
 	"github.com/hyperledger/fabric/common/tools/configtxgen/configtxgentest"
 	"github.com/hyperledger/fabric/common/tools/configtxgen/encoder"
 	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
 	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
 	"github.com/hyperledger/fabric/orderer/consensus"
 	cb "github.com/hyperledger/fabric/protos/common"
 	ab "github.com/hyperledger/fabric/protos/orderer"
@@ -31,6 +34,7 @@ import (
 	mmsp "github.com/hyperledger/fabric/common/mocks/msp"
 	"github.com/pkg/errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 var conf *genesisconfig.Profile
@@ -111,7 +115,7 @@ func TestNoSystemChain(t *testing.T) {
 	consenters := make(map[string]consensus.Consenter)
 	consenters[conf.Orderer.OrdererType] = &mockConsenter{}
 
-	assert.Panics(t, func() { NewRegistrar(lf, consenters, mockCrypto()) }, "Should have panicked when starting without a system chain")
+	assert.Panics(t, func() { NewRegistrar(lf, consenters, mockCrypto(), RegistrarOptions{}) }, "Should have panicked when starting without a system chain")
 }
 
 // This test checks to make sure that the orderer refuses to come up if there are multiple system channels
@@ -129,7 +133,7 @@ func TestMultiSystemChannel(t *testing.T) {
 	consenters := make(map[string]consensus.Consenter)
 	consenters[conf.Orderer.OrdererType] = &mockConsenter{}
 
-	assert.Panics(t, func() { NewRegistrar(lf, consenters, mockCrypto()) }, "Two system channels should have caused panic")
+	assert.Panics(t, func() { NewRegistrar(lf, consenters, mockCrypto(), RegistrarOptions{}) }, "Two system channels should have caused panic")
 }
 
 // This test essentially brings the entire system up and is ultimately what main.go will replicate
@@ -139,13 +143,15 @@ func TestManagerImpl(t *testing.T) {
 	consenters := make(map[string]consensus.Consenter)
 	consenters[conf.Orderer.OrdererType] = &mockConsenter{}
 
-	manager := NewRegistrar(lf, consenters, mockCrypto())
+	manager := NewRegistrar(lf, consenters, mockCrypto(), RegistrarOptions{})
 
 	_, ok := manager.GetChain("Fake")
 	assert.False(t, ok, "Should not have found a chain that was not created")
 
 	chainSupport, ok := manager.GetChain(genesisconfig.TestChainID)
 	assert.True(t, ok, "Should have gotten chain which was initialized by ramledger")
+	assert.Equal(t, conf.Orderer.OrdererType, chainSupport.ConsensusType(), "Should have recorded the consensus type the chain was started with")
+	assert.Equal(t, []string{conf.Orderer.OrdererType}, manager.ConsenterTypes())
 
 	messages := make([]*cb.Envelope, conf.Orderer.BatchSize.MaxMessageCount)
 	for i := 0; i < int(conf.Orderer.BatchSize.MaxMessageCount); i++ {
@@ -181,7 +187,7 @@ func TestNewChain(t *testing.T) {
 	consenters := make(map[string]consensus.Consenter)
 	consenters[conf.Orderer.OrdererType] = &mockConsenter{}
 
-	manager := NewRegistrar(lf, consenters, mockCrypto())
+	manager := NewRegistrar(lf, consenters, mockCrypto(), RegistrarOptions{})
 	orglessChannelConf := configtxgentest.Load(genesisconfig.SampleSingleMSPChannelProfile)
 	orglessChannelConf.Application.Organizations = nil
 	envConfigUpdate, err := encoder.MakeChannelCreationTransaction(newChainID, mockCrypto(), nil, orglessChannelConf)
@@ -275,6 +281,127 @@ func TestNewChain(t *testing.T) {
 	assert.Equal(t, expectedLastConfigSeq, rcs.lastConfigSeq, "On restart, incorrect lastConfigSeq")
 }
 
+func TestRemoveChannel(t *testing.T) {
+	newChainID := "test-new-chain"
+	lf, _ := NewRAMLedgerAndFactory(10)
+
+	consenters := make(map[string]consensus.Consenter)
+	consenters[conf.Orderer.OrdererType] = &mockConsenter{}
+
+	manager := NewRegistrar(lf, consenters, mockCrypto(), RegistrarOptions{})
+	ledger, err := lf.GetOrCreate(newChainID)
+	assert.NoError(t, err)
+	assert.NoError(t, ledger.Append(blockledger.CreateNextBlock(ledger, []*cb.Envelope{makeNormalTx(newChainID, 0)})))
+	done := make(chan struct{})
+	close(done)
+	manager.chains[newChainID] = &ChainSupport{
+		ledgerResources: &ledgerResources{ReadWriter: ledger},
+		Chain:           &mockChain{queue: make(chan *cb.Envelope), done: done},
+		BlockWriter:     &BlockWriter{},
+	}
+
+	height, ok := manager.ChannelHeight(newChainID)
+	assert.True(t, ok)
+	assert.Equal(t, uint64(1), height)
+
+	assert.EqualError(t, manager.RemoveChannel(manager.SystemChannelID()), "cannot remove the system channel "+manager.SystemChannelID())
+	assert.EqualError(t, manager.RemoveChannel("nonexistent"), "channel nonexistent does not exist")
+
+	assert.NoError(t, manager.RemoveChannel(newChainID))
+	_, ok = manager.GetChain(newChainID)
+	assert.False(t, ok, "Removed channel should no longer be served")
+	_, ok = manager.ChannelHeight(newChainID)
+	assert.False(t, ok)
+	assert.Equal(t, []string{manager.SystemChannelID()}, manager.ChannelIDs())
+	assert.Equal(t, []string{manager.SystemChannelID()}, lf.ChainIDs(), "Ledger of the removed channel should have been removed")
+
+	manager.ledgerFactory = &struct{ blockledger.Factory }{lf}
+	assert.EqualError(t, manager.RemoveChannel(newChainID), "the ledger does not support removing channels")
+}
+
+// gatedLedger holds the blocks appended until it is released
+type gatedLedger struct {
+	blockledger.ReadWriter
+	appending chan struct{}
+	release   chan struct{}
+}
+
+func (gl *gatedLedger) Append(block *cb.Block) error {
+	select {
+	case gl.appending <- struct{}{}:
+	default:
+	}
+	<-gl.release
+	return gl.ReadWriter.Append(block)
+}
+
+func TestRemoveChannelBlockInFlight(t *testing.T) {
+	newChainID := "test-new-chain"
+	lf, _ := NewRAMLedgerAndFactory(10)
+
+	consenters := make(map[string]consensus.Consenter)
+	consenters[conf.Orderer.OrdererType] = &mockConsenter{}
+
+	manager := NewRegistrar(lf, consenters, mockCrypto(), RegistrarOptions{})
+	ledger, err := lf.GetOrCreate(newChainID)
+	require.NoError(t, err)
+	firstBlock := blockledger.CreateNextBlock(ledger, []*cb.Envelope{makeNormalTx(newChainID, 0)})
+	require.NoError(t, ledger.Append(firstBlock))
+
+	gated := &gatedLedger{ReadWriter: ledger, appending: make(chan struct{}, 1), release: make(chan struct{})}
+	bw := &BlockWriter{
+		support: &mockBlockWriterSupport{
+			LocalSigner: mockCrypto(),
+			ReadWriter:  gated,
+			Validator:   &mockconfigtx.Validator{ChainIDVal: newChainID},
+		},
+		blockFanout: fanout.New(),
+		lastBlock:   firstBlock,
+	}
+	chain := &mockChain{queue: make(chan *cb.Envelope), done: make(chan struct{})}
+	manager.chains[newChainID] = &ChainSupport{
+		ledgerResources: &ledgerResources{ReadWriter: ledger},
+		Chain:           chain,
+		BlockWriter:     bw,
+	}
+
+	// 链仍在提交区块时删除通道
+	bw.WriteBlock(bw.CreateNextBlock([]*cb.Envelope{makeNormalTx(newChainID, 1)}), nil)
+	<-gated.appending
+	removed := make(chan error, 1)
+	go func() {
+		removed <- manager.RemoveChannel(newChainID)
+	}()
+
+	// 链终止之前不删除账本
+	select {
+	case <-removed:
+		t.Fatal("the ledger should not be removed before the chain terminates")
+	case <-time.After(50 * time.Millisecond):
+	}
+	_, ok := manager.GetChain(newChainID)
+	assert.False(t, ok, "Removed channel should no longer be served")
+
+	// 提交中的区块写入之前不删除账本
+	close(chain.done)
+	select {
+	case <-removed:
+		t.Fatal("the ledger should not be removed while a block is committed")
+	case <-time.After(50 * time.Millisecond):
+	}
+	assert.Contains(t, lf.ChainIDs(), newChainID)
+
+	close(gated.release)
+	assert.NoError(t, <-removed)
+	assert.Equal(t, uint64(2), ledger.Height(), "the block in flight should be committed before the ledger is removed")
+	assert.NotContains(t, lf.ChainIDs(), newChainID)
+
+	// 删除后写入的区块被丢弃
+	bw.WriteBlock(bw.CreateNextBlock([]*cb.Envelope{makeNormalTx(newChainID, 2)}), nil)
+	bw.Fence()
+	assert.Equal(t, uint64(2), ledger.Height())
+}
+
 func testLastConfigBlockNumber(t *testing.T, block *cb.Block, expectedBlockNumber uint64) {
 	metadataItem := &cb.Metadata{}
 	err := proto.Unmarshal(block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG], metadataItem)
@@ -353,9 +480,41 @@ func TestResourcesCheck(t *testing.T) {
 func TestBroadcastChannelSupportRejection(t *testing.T) {
 	ledgerFactory, _ := NewRAMLedgerAndFactory(10)
 	mockConsenters := map[string]consensus.Consenter{conf.Orderer.OrdererType: &mockConsenter{}}
-	registrar := NewRegistrar(ledgerFactory, mockConsenters, mockCrypto())
+	registrar := NewRegistrar(ledgerFactory, mockConsenters, mockCrypto(), RegistrarOptions{})
 	randomValue := 1
 	configTx := makeConfigTx(genesisconfig.TestChainID, randomValue)
 	_, _, _, err := registrar.BroadcastChannelSupport(configTx)
 	assert.Error(t, err, "Messages of type HeaderType_CONFIG should return an error.")
 }
+
+func TestValidateChannelCreation(t *testing.T) {
+	ledgerFactory, _ := NewRAMLedgerAndFactory(10)
+	mockConsenters := map[string]consensus.Consenter{conf.Orderer.OrdererType: &mockConsenter{}}
+	registrar := NewRegistrar(ledgerFactory, mockConsenters, mockCrypto(), RegistrarOptions{})
+
+	channelConf := configtxgentest.Load(genesisconfig.SampleSingleMSPChannelProfile)
+	channelConf.Application.Organizations = nil
+	envConfigUpdate, err := encoder.MakeChannelCreationTransaction("test-new-chain", mockCrypto(), nil, channelConf)
+	assert.NoError(t, err, "Constructing chain creation tx")
+
+	config, err := registrar.ValidateChannelCreation(envConfigUpdate)
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(1), config.Sequence)
+	assert.NotNil(t, config.ChannelGroup.Groups[channelconfig.ApplicationGroupKey])
+	_, ok := registrar.GetChain("test-new-chain")
+	assert.False(t, ok, "Validating the channel creation must not create the channel")
+
+	envConfigUpdate, err = encoder.MakeChannelCreationTransaction(genesisconfig.TestChainID, mockCrypto(), nil, channelConf)
+	assert.NoError(t, err)
+	_, err = registrar.ValidateChannelCreation(envConfigUpdate)
+	assert.EqualError(t, err, "channel testchainid already exists")
+
+	_, err = registrar.ValidateChannelCreation(makeConfigTx("test-new-chain", 1))
+	assert.EqualError(t, err, "message of type CONFIG is not a channel creation transaction")
+
+	channelConf.Consortium = "UnknownConsortium"
+	envConfigUpdate, err = encoder.MakeChannelCreationTransaction("test-new-chain", mockCrypto(), nil, channelConf)
+	assert.NoError(t, err)
+	_, err = registrar.ValidateChannelCreation(envConfigUpdate)
+	assert.EqualError(t, err, "Unknown consortium name: UnknownConsortium")
+}
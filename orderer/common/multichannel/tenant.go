@@ -0,0 +1,141 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package multichannel
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/common/txtimeline"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
+	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	"github.com/hyperledger/fabric/orderer/common/tracing"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+)
+
+// Tenant is the view of the registrar restricted to the channels of a
+// tenant, so that a single orderer can serve disjoint sets of channels to
+// different listeners.  The channels of the tenant are declared up front,
+// and may be created through the system channel by the tenant before they
+// exist.  The other channels look exactly like channels which do not exist:
+// they cannot be looked up, and their messages are rejected by the system
+// channel as those of channels which do not exist, without creating them.
+type Tenant struct {
+	name      string
+	registrar *Registrar
+	channels  map[string]struct{}
+	hidden    *ChainSupport
+}
+
+// Tenant returns the view of the registrar for the tenant of the given name,
+// restricted to the channels of the given IDs
+func (r *Registrar) Tenant(name string, channelIDs []string) *Tenant {
+	channels := make(map[string]struct{}, len(channelIDs))
+	for _, channelID := range channelIDs {
+		channels[channelID] = struct{}{}
+	}
+	//其他租户的通道交由系统通道按不存在的通道处理，但不能创建通道
+	hidden := *r.systemChannel
+	hidden.Processor = hiddenChannelProcessor{Processor: r.systemChannel.Processor}
+	return &Tenant{
+		name:      name,
+		registrar: r,
+		channels:  channels,
+		hidden:    &hidden,
+	}
+}
+
+// Name returns the name of the tenant.
+func (t *Tenant) Name() string {
+	return t.name
+}
+
+// BroadcastChannelSupport returns the message channel header, whether the
+// message is a config update and the channel resources for a message of a
+// channel of the tenant, as the registrar does.  The messages of the other
+// channels are processed as those of channels which do not exist.
+func (t *Tenant) BroadcastChannelSupport(msg *cb.Envelope) (*cb.ChannelHeader, bool, *ChainSupport, error) {
+	chdr, err := utils.ChannelHeader(msg)
+	if err != nil {
+		return nil, false, nil, fmt.Errorf("could not determine channel ID: %s", err)
+	}
+	if _, ok := t.channels[chdr.ChannelId]; ok {
+		return t.registrar.BroadcastChannelSupport(msg)
+	}
+	return classifyBroadcast(chdr, t.hidden)
+}
+
+// GetChain retrieves the chain support for a channel of the tenant (and
+// whether it exists)
+func (t *Tenant) GetChain(chainID string) (*ChainSupport, bool) {
+	if _, ok := t.channels[chainID]; !ok {
+		return nil, false
+	}
+	return t.registrar.GetChain(chainID)
+}
+
+// ChannelsCount returns the number of channels of the tenant which exist.
+func (t *Tenant) ChannelsCount() int {
+	return len(t.ChannelIDs())
+}
+
+// ChannelStatus returns the health of the chain of a channel of the tenant,
+// and whether the channel exists.
+func (t *Tenant) ChannelStatus(channelID string) (consensus.ChainStatus, bool) {
+	cs, ok := t.GetChain(channelID)
+	if !ok {
+		return consensus.ChainStatus{}, false
+	}
+	return cs.Status(), true
+}
+
+// ChannelIDs returns the IDs of the channels of the tenant which exist.
+func (t *Tenant) ChannelIDs() []string {
+	var ids []string
+	for _, id := range t.registrar.ChannelIDs() {
+		if _, ok := t.channels[id]; ok {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// TxTimeline returns the recorder of transaction timelines of the registrar.
+func (t *Tenant) TxTimeline() *txtimeline.Recorder {
+	return t.registrar.TxTimeline()
+}
+
+// Tracer returns the tracer of the messages ordered of the registrar.
+func (t *Tenant) Tracer() *tracing.Tracer {
+	return t.registrar.Tracer()
+}
+
+// BlockFanout returns the multicaster of the blocks of the registrar, whose
+// subscribers must look up the channels through the tenant.
+func (t *Tenant) BlockFanout() *fanout.Multicaster {
+	return t.registrar.BlockFanout()
+}
+
+// hiddenChannelProcessor rejects the messages of the channels a tenant does
+// not see, as the system channel rejects those of the channels which do not
+// exist, including the config updates which would create them
+type hiddenChannelProcessor struct {
+	msgprocessor.Processor
+}
+
+func (hiddenChannelProcessor) ProcessNormalMsg(env *cb.Envelope) (uint64, error) {
+	return 0, msgprocessor.ErrChannelDoesNotExist
+}
+
+func (hiddenChannelProcessor) ProcessConfigUpdateMsg(env *cb.Envelope) (*cb.Envelope, uint64, error) {
+	return nil, 0, msgprocessor.ErrChannelDoesNotExist
+}
+
+func (hiddenChannelProcessor) ProcessConfigMsg(env *cb.Envelope) (*cb.Envelope, uint64, error) {
+	return nil, 0, msgprocessor.ErrChannelDoesNotExist
+}
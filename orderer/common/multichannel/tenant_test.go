@@ -0,0 +1,86 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package multichannel
+
+import (
+	"testing"
+
+	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTenantRegistrar() *Registrar {
+	ledgerFactory, _ := NewRAMLedgerAndFactory(10)
+	mockConsenters := map[string]consensus.Consenter{conf.Orderer.OrdererType: &mockConsenter{}}
+	return NewRegistrar(ledgerFactory, mockConsenters, mockCrypto(), RegistrarOptions{})
+}
+
+func makeConfigUpdateTx(t *testing.T, chainID string) *cb.Envelope {
+	env, err := utils.CreateSignedEnvelope(cb.HeaderType_CONFIG_UPDATE, chainID, mockCrypto(), &cb.ConfigUpdateEnvelope{}, 0, 0)
+	require.NoError(t, err)
+	return env
+}
+
+func TestTenantLookup(t *testing.T) {
+	registrar := newTenantRegistrar()
+
+	tenant := registrar.Tenant("tenant1", []string{"foo"})
+	assert.Equal(t, "tenant1", tenant.Name())
+	_, ok := tenant.GetChain(genesisconfig.TestChainID)
+	assert.False(t, ok, "the system channel is not a channel of the tenant")
+	_, ok = tenant.ChannelStatus(genesisconfig.TestChainID)
+	assert.False(t, ok)
+	assert.Empty(t, tenant.ChannelIDs(), "the channel of the tenant does not exist yet")
+	assert.Equal(t, 0, tenant.ChannelsCount())
+
+	tenant = registrar.Tenant("tenant2", []string{genesisconfig.TestChainID})
+	cs, ok := tenant.GetChain(genesisconfig.TestChainID)
+	require.True(t, ok)
+	assert.Equal(t, genesisconfig.TestChainID, cs.ChainID())
+	assert.Equal(t, []string{genesisconfig.TestChainID}, tenant.ChannelIDs())
+	assert.Equal(t, 1, tenant.ChannelsCount())
+}
+
+func TestTenantBroadcastChannelSupport(t *testing.T) {
+	registrar := newTenantRegistrar()
+	tenant := registrar.Tenant("tenant1", []string{"foo"})
+
+	t.Run("DeclaredChannelCreation", func(t *testing.T) {
+		_, isConfig, cs, err := tenant.BroadcastChannelSupport(makeConfigUpdateTx(t, "foo"))
+		require.NoError(t, err)
+		assert.True(t, isConfig)
+		assert.Equal(t, registrar.systemChannel, cs, "the channels of the tenant are created through the system channel")
+	})
+
+	for _, channelID := range []string{"bar", genesisconfig.TestChainID} {
+		t.Run("Hidden"+channelID, func(t *testing.T) {
+			_, isConfig, cs, err := tenant.BroadcastChannelSupport(makeNormalTx(channelID, 1))
+			require.NoError(t, err)
+			assert.False(t, isConfig)
+			_, err = cs.ProcessNormalMsg(makeNormalTx(channelID, 1))
+			assert.Equal(t, msgprocessor.ErrChannelDoesNotExist, err)
+
+			_, isConfig, cs, err = tenant.BroadcastChannelSupport(makeConfigUpdateTx(t, channelID))
+			require.NoError(t, err)
+			assert.True(t, isConfig)
+			_, _, err = cs.ProcessConfigUpdateMsg(makeConfigUpdateTx(t, channelID))
+			assert.Equal(t, msgprocessor.ErrChannelDoesNotExist, err)
+		})
+	}
+
+	_, _, _, err := tenant.BroadcastChannelSupport(makeConfigTx("bar", 1))
+	assert.Error(t, err, "Messages of type HeaderType_CONFIG should return an error.")
+
+	// the system channel of the registrar is left untouched
+	_, err = registrar.systemChannel.ProcessNormalMsg(makeNormalTx(genesisconfig.TestChainID, 1))
+	assert.NotEqual(t, msgprocessor.ErrChannelDoesNotExist, err)
+}
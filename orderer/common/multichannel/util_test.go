@@ -17,6 +17,7 @@ import (
 	"github.com/hyperledger/fabric/orderer/consensus"
 	cb "github.com/hyperledger/fabric/protos/common"
 	"github.com/hyperledger/fabric/protos/utils"
+	"golang.org/x/net/context"
 )
 
 type mockConsenter struct {
@@ -58,6 +59,10 @@ func (mch *mockChain) WaitReady() error {
 	return nil
 }
 
+func (mch *mockChain) WaitReadyContext(ctx context.Context) error {
+	return nil
+}
+
 func (mch *mockChain) Start() {
 	go func() {
 		defer close(mch.done)
@@ -107,6 +112,10 @@ func (mch *mockChain) Halt() {
 	close(mch.queue)
 }
 
+func (mch *mockChain) Terminated() <-chan struct{} {
+	return mch.done
+}
+
 func makeConfigTx(chainID string, i int) *cb.Envelope {
 	group := cb.NewConfigGroup()
 	group.Groups[channelconfig.OrdererGroupKey] = cb.NewConfigGroup()
@@ -0,0 +1,106 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operations
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+)
+
+// Role is the access granted to a client of the operations endpoint.
+type Role int
+
+const (
+	// RoleNone grants access to nothing
+	RoleNone Role = iota
+	// RoleMetrics grants access to the monitoring handlers
+	RoleMetrics
+	// RoleAdmin grants access to every handler
+	RoleAdmin
+)
+
+// String returns the name of the role
+func (r Role) String() string {
+	switch r {
+	case RoleMetrics:
+		return "metrics"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "none"
+	}
+}
+
+// Auth contains the authentication configuration of the operations
+// endpoint.  When neither client OUs nor tokens are configured, every client
+// accepted by the TLS layer is granted RoleAdmin.
+type Auth struct {
+	// ClientOURoles maps the organizational units of the verified client
+	// certificates to the role they grant
+	ClientOURoles map[string]Role
+	// TokenRoles maps the static bearer tokens to the role they grant
+	TokenRoles map[string]Role
+}
+
+// Enabled returns whether the clients must authenticate
+func (a Auth) Enabled() bool {
+	return len(a.ClientOURoles) > 0 || len(a.TokenRoles) > 0
+}
+
+// role returns the role of the client of the request, and whether the client
+// presented valid credentials at all.  A bearer token takes precedence over the
+// client certificate, so that a client is never granted more than its token
+// asks for.
+func (a Auth) role(req *http.Request) (Role, bool) {
+	if !a.Enabled() {
+		return RoleAdmin, true
+	}
+
+	if header := req.Header.Get("Authorization"); header != "" {
+		token := strings.TrimPrefix(header, "Bearer ")
+		if token == header {
+			return RoleNone, false
+		}
+		granted := RoleNone
+		for t, role := range a.TokenRoles {
+			// 遍历全部令牌，避免比较耗时泄露令牌内容
+			if subtle.ConstantTimeCompare([]byte(t), []byte(token)) == 1 {
+				granted = role
+			}
+		}
+		return granted, granted != RoleNone
+	}
+
+	if req.TLS == nil || len(req.TLS.VerifiedChains) == 0 || len(req.TLS.VerifiedChains[0]) == 0 {
+		return RoleNone, false
+	}
+	granted := RoleNone
+	for _, ou := range req.TLS.VerifiedChains[0][0].Subject.OrganizationalUnit {
+		if role := a.ClientOURoles[ou]; role > granted {
+			granted = role
+		}
+	}
+	return granted, true
+}
+
+// authorize serves the requests of the clients granted at least the role
+func (a Auth) authorize(required Role, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		role, authenticated := a.role(req)
+		switch {
+		case !authenticated:
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, "authentication required", http.StatusUnauthorized)
+		case role < required:
+			logger.Debugf("Rejecting operations request for %s from %s: role %s, %s required", req.URL.Path, req.RemoteAddr, role, required)
+			http.Error(w, "forbidden", http.StatusForbidden)
+		default:
+			next.ServeHTTP(w, req)
+		}
+	})
+}
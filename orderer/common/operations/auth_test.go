@@ -0,0 +1,116 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operations
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func clientCertRequest(ous ...string) *http.Request {
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	cert := &x509.Certificate{Subject: pkix.Name{OrganizationalUnit: ous}}
+	req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+	return req
+}
+
+func TestAuthRole(t *testing.T) {
+	auth := Auth{
+		ClientOURoles: map[string]Role{"monitoring": RoleMetrics, "ops": RoleAdmin},
+		TokenRoles:    map[string]Role{"metrics-token": RoleMetrics, "admin-token": RoleAdmin},
+	}
+
+	tests := []struct {
+		name          string
+		req           *http.Request
+		role          Role
+		authenticated bool
+	}{
+		{"no OU", clientCertRequest(), RoleNone, true},
+		{"metrics OU", clientCertRequest("monitoring"), RoleMetrics, true},
+		{"highest OU", clientCertRequest("monitoring", "ops"), RoleAdmin, true},
+		{"unmapped OU", clientCertRequest("peers"), RoleNone, true},
+	}
+	for _, tc := range tests {
+		role, authenticated := auth.role(tc.req)
+		assert.Equal(t, tc.role, role, tc.name)
+		assert.Equal(t, tc.authenticated, authenticated, tc.name)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, "/", nil)
+	role, authenticated := auth.role(req)
+	assert.Equal(t, RoleNone, role)
+	assert.False(t, authenticated)
+
+	// the token takes precedence over the client certificate
+	req = clientCertRequest("ops")
+	req.Header.Set("Authorization", "Bearer metrics-token")
+	role, authenticated = auth.role(req)
+	assert.Equal(t, RoleMetrics, role)
+	assert.True(t, authenticated)
+
+	req.Header.Set("Authorization", "Bearer wrong-token")
+	_, authenticated = auth.role(req)
+	assert.False(t, authenticated)
+
+	req.Header.Set("Authorization", "Basic admin-token")
+	_, authenticated = auth.role(req)
+	assert.False(t, authenticated)
+
+	// without configuration every client is an admin
+	role, authenticated = Auth{}.role(req)
+	assert.Equal(t, RoleAdmin, role)
+	assert.True(t, authenticated)
+}
+
+func TestSystemAuth(t *testing.T) {
+	system := NewSystem(Options{
+		ListenAddress: "127.0.0.1:0",
+		Auth: Auth{
+			TokenRoles: map[string]Role{"metrics-token": RoleMetrics, "admin-token": RoleAdmin},
+		},
+	})
+	ok := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {})
+	system.RegisterHandlerWithRole("/status", RoleMetrics, ok)
+	system.RegisterHandler("/admin", ok)
+	require.NoError(t, system.Start())
+	defer system.Stop()
+
+	tests := []struct {
+		path   string
+		token  string
+		status int
+	}{
+		{"status", "", http.StatusUnauthorized},
+		{"status", "wrong-token", http.StatusUnauthorized},
+		{"status", "metrics-token", http.StatusOK},
+		{"status", "admin-token", http.StatusOK},
+		{"admin", "metrics-token", http.StatusForbidden},
+		{"admin", "admin-token", http.StatusOK},
+	}
+	for _, tc := range tests {
+		req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("http://%s/%s", system.Addr(), tc.path), nil)
+		require.NoError(t, err)
+		if tc.token != "" {
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, tc.status, resp.StatusCode, "%s with %q", tc.path, tc.token)
+		if tc.status == http.StatusUnauthorized {
+			assert.Equal(t, "Bearer", resp.Header.Get("WWW-Authenticate"))
+		}
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operations
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/prometheus/common/model"
+)
+
+// MetricLabels adds constant labels, such as the name of the deployment, to
+// the metrics gathered from a gatherer.  Unlike the constant labels of the
+// metrics themselves, the labels may be changed while the metrics are served.
+type MetricLabels struct {
+	mutex  sync.RWMutex
+	labels []*dto.LabelPair
+}
+
+// NewMetricLabels creates MetricLabels adding the labels, or returns an error
+// if a label name is invalid
+func NewMetricLabels(labels map[string]string) (*MetricLabels, error) {
+	ml := &MetricLabels{}
+	if err := ml.Set(labels); err != nil {
+		return nil, err
+	}
+	return ml, nil
+}
+
+// Set replaces the labels added to the metrics, or returns an error, leaving
+// them unchanged, if a label name is invalid
+func (ml *MetricLabels) Set(labels map[string]string) error {
+	pairs := make([]*dto.LabelPair, 0, len(labels))
+	for name, value := range labels {
+		if !model.LabelName(name).IsValid() || strings.HasPrefix(name, model.ReservedLabelPrefix) {
+			return errors.Errorf("invalid metric label name '%s'", name)
+		}
+		pairs = append(pairs, &dto.LabelPair{Name: proto.String(name), Value: proto.String(value)})
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].GetName() < pairs[j].GetName() })
+
+	ml.mutex.Lock()
+	ml.labels = pairs
+	ml.mutex.Unlock()
+	return nil
+}
+
+// Gatherer returns a gatherer of the metrics of the gatherer with the labels
+// added.  The labels a metric already has are not replaced.
+func (ml *MetricLabels) Gatherer(g prometheus.Gatherer) prometheus.Gatherer {
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		mfs, err := g.Gather()
+		ml.mutex.RLock()
+		labels := ml.labels
+		ml.mutex.RUnlock()
+		if len(labels) == 0 {
+			return mfs, err
+		}
+		//收集到的指标族是每次新建的，可以直接修改
+		for _, mf := range mfs {
+			for _, m := range mf.Metric {
+				m.Label = addLabels(m.Label, labels)
+			}
+		}
+		return mfs, err
+	})
+}
+
+// addLabels returns the label pairs sorted by name, with the labels whose
+// name is not among them
+func addLabels(pairs, labels []*dto.LabelPair) []*dto.LabelPair {
+	existing := make(map[string]bool, len(pairs))
+	for _, p := range pairs {
+		existing[p.GetName()] = true
+	}
+	for _, l := range labels {
+		if !existing[l.GetName()] {
+			pairs = append(pairs, l)
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool { return pairs[i].GetName() < pairs[j].GetName() })
+	return pairs
+}
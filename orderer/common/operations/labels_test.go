@@ -0,0 +1,58 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operations
+
+import (
+	"sort"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetricLabels(t *testing.T) {
+	_, err := NewMetricLabels(map[string]string{"not-valid": "foo"})
+	assert.EqualError(t, err, "invalid metric label name 'not-valid'")
+	_, err = NewMetricLabels(map[string]string{"__name__": "foo"})
+	assert.EqualError(t, err, "invalid metric label name '__name__'")
+
+	registry := prometheus.NewRegistry()
+	counter := prometheus.NewCounterVec(prometheus.CounterOpts{Name: "requests"}, []string{"region"})
+	registry.MustRegister(counter)
+	counter.WithLabelValues("eu").Inc()
+
+	ml, err := NewMetricLabels(nil)
+	require.NoError(t, err)
+	gatherer := ml.Gatherer(registry)
+
+	labels := func() map[string]string {
+		mfs, err := gatherer.Gather()
+		require.NoError(t, err)
+		require.Len(t, mfs, 1)
+		require.Len(t, mfs[0].Metric, 1)
+		pairs := map[string]string{}
+		var names []string
+		for _, p := range mfs[0].Metric[0].Label {
+			pairs[p.GetName()] = p.GetValue()
+			names = append(names, p.GetName())
+		}
+		assert.True(t, sort.StringsAreSorted(names), "the labels are sorted by name")
+		return pairs
+	}
+	assert.Equal(t, map[string]string{"region": "eu"}, labels())
+
+	// the labels of the metrics are not replaced
+	require.NoError(t, ml.Set(map[string]string{"deployment": "prod", "region": "us", "zone": "a"}))
+	assert.Equal(t, map[string]string{"deployment": "prod", "region": "eu", "zone": "a"}, labels())
+
+	assert.Error(t, ml.Set(map[string]string{"0zone": "a"}))
+	assert.Equal(t, map[string]string{"deployment": "prod", "region": "eu", "zone": "a"}, labels(), "the labels are unchanged by an invalid update")
+
+	require.NoError(t, ml.Set(nil))
+	assert.Equal(t, map[string]string{"region": "eu"}, labels())
+}
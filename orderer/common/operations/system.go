@@ -0,0 +1,168 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package operations provides the orderer's operations endpoint, an HTTP
+// server separate from the gRPC services on which operational handlers, such
+// as runtime status reports, are registered.
+package operations
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/orderer/common/crash"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/operations"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// TLS contains the TLS configuration of the operations endpoint.
+type TLS struct {
+	Enabled            bool
+	CertFile           string
+	KeyFile            string
+	ClientCertRequired bool
+	ClientCACertFiles  []string
+}
+
+// Config returns the tls.Config for the server, or nil if TLS is disabled
+func (t TLS) Config() (*tls.Config, error) {
+	if !t.Enabled {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load operations server key pair")
+	}
+	caCertPool := x509.NewCertPool()
+	for _, caPath := range t.ClientCACertFiles {
+		caPem, err := ioutil.ReadFile(caPath)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read client CA certificate %s", caPath)
+		}
+		if !caCertPool.AppendCertsFromPEM(caPem) {
+			return nil, errors.Errorf("no certificates found in %s", caPath)
+		}
+	}
+	authType := tls.VerifyClientCertIfGiven
+	if t.ClientCertRequired {
+		authType = tls.RequireAndVerifyClientCert
+	}
+
+	return &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+		ClientCAs:    caCertPool,
+		ClientAuth:   authType,
+	}, nil
+}
+
+// Options contains the configuration of the operations System.
+type Options struct {
+	ListenAddress string
+	TLS           TLS
+	Auth          Auth
+
+	// Crashes reports the panics of the handlers, which are answered with
+	// 500 Internal Server Error.  If nil, the panics are reported without
+	// being counted.
+	Crashes *crash.Reporter
+}
+
+// System is the operations endpoint.  Handlers may be registered before or
+// after the System is started.
+type System struct {
+	options    Options
+	mux        *http.ServeMux
+	httpServer *http.Server
+	listener   net.Listener
+}
+
+// NewSystem creates an operations System which has not been started
+func NewSystem(o Options) *System {
+	mux := http.NewServeMux()
+	return &System{
+		options: o,
+		mux:     mux,
+		httpServer: &http.Server{
+			Handler:      mux,
+			ReadTimeout:  10 * time.Second,
+			WriteTimeout: 2 * time.Minute,
+		},
+	}
+}
+
+// RegisterHandler serves the handler at the given pattern to the clients
+// granted RoleAdmin
+func (s *System) RegisterHandler(pattern string, handler http.Handler) {
+	s.RegisterHandlerWithRole(pattern, RoleAdmin, handler)
+}
+
+// RegisterHandlerWithRole serves the handler at the given pattern to the
+// clients granted at least the role
+func (s *System) RegisterHandlerWithRole(pattern string, role Role, handler http.Handler) {
+	s.mux.Handle(pattern, s.options.Auth.authorize(role, s.options.Crashes.Handler("operations "+pattern, handler)))
+}
+
+// Start begins serving on the listen address
+func (s *System) Start() error {
+	tlsConfig, err := s.options.TLS.Config()
+	if err != nil {
+		return err
+	}
+
+	listener, err := net.Listen("tcp", s.options.ListenAddress)
+	if err != nil {
+		return errors.Wrapf(err, "failed to listen on %s", s.options.ListenAddress)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsConfig)
+	}
+	s.listener = listener
+	if len(s.options.Auth.TokenRoles) > 0 && tlsConfig == nil {
+		logger.Warning("Operations server authenticates bearer tokens without TLS, the tokens are sent in the clear")
+	}
+
+	go func() {
+		if err := s.httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			logger.Errorf("Operations server stopped serving: %s", err)
+		}
+	}()
+	logger.Infof("Operations server listening on %s", listener.Addr())
+	return nil
+}
+
+// Stop shuts the server down, waiting briefly for in flight requests
+func (s *System) Stop() error {
+	if s.listener == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.httpServer.Shutdown(ctx)
+}
+
+// Addr returns the address the server is listening on, once started
+func (s *System) Addr() string {
+	if s.listener == nil {
+		return ""
+	}
+	return s.listener.Addr().String()
+}
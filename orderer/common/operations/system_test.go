@@ -0,0 +1,81 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operations
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSystem(t *testing.T) {
+	system := NewSystem(Options{ListenAddress: "127.0.0.1:0"})
+	system.RegisterHandler("/before", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "before")
+	}))
+	assert.Empty(t, system.Addr())
+
+	require.NoError(t, system.Start())
+	defer system.Stop()
+	system.RegisterHandler("/after", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, "after")
+	}))
+
+	for _, path := range []string{"before", "after"} {
+		resp, err := http.Get(fmt.Sprintf("http://%s/%s", system.Addr(), path))
+		require.NoError(t, err)
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		assert.Equal(t, path, string(body))
+	}
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/missing", system.Addr()))
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	assert.NoError(t, system.Stop())
+}
+
+func TestSystemBadListenAddress(t *testing.T) {
+	system := NewSystem(Options{ListenAddress: "bad-address"})
+	assert.Error(t, system.Start())
+	assert.NoError(t, system.Stop())
+}
+
+func TestSystemBadTLS(t *testing.T) {
+	system := NewSystem(Options{
+		ListenAddress: "127.0.0.1:0",
+		TLS:           TLS{Enabled: true, CertFile: "missing.pem", KeyFile: "missing.key"},
+	})
+	err := system.Start()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to load operations server key pair")
+}
+
+func TestSystemHandlerPanic(t *testing.T) {
+	system := NewSystem(Options{ListenAddress: "127.0.0.1:0"})
+	require.NoError(t, system.Start())
+	defer system.Stop()
+	system.RegisterHandler("/bug", http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		panic("bug")
+	}))
+
+	resp, err := http.Get(fmt.Sprintf("http://%s/bug", system.Addr()))
+	require.NoError(t, err)
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusInternalServerError, resp.StatusCode)
+	assert.Contains(t, string(body), "crash report")
+}
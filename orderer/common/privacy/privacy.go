@@ -0,0 +1,230 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package privacy anonymizes the identities of the clients of the orderer,
+// their addresses, the subjects of their certificates and their MSP IDs,
+// before they are written to the logs, the metrics and the audit trail.  The
+// identities are replaced by a keyed HMAC of them, so that the records of a
+// client can still be correlated, and attributed to it by those holding the
+// key, without the key being derivable from the records.
+package privacy
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net"
+	"strings"
+
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// ModeHash replaces every identity by its HMAC
+	ModeHash = "hash"
+
+	// ModeTruncate truncates the addresses to their network, /24 for IPv4 and
+	// /48 for IPv6, and the subjects to the attributes of their organization,
+	// and replaces the MSP IDs, which cannot be truncated, by their HMAC
+	ModeTruncate = "truncate"
+)
+
+// MinKeySize is the minimum size in bytes of the HMAC key
+const MinKeySize = 16
+
+// DefaultHashLength is the number of hex digits of the HMAC kept when no
+// length is configured
+const DefaultHashLength = 16
+
+// hashPrefix marks the anonymized identities
+const hashPrefix = "anon-"
+
+// Config contains the configuration of an Anonymizer.
+type Config struct {
+	// Mode is ModeHash or ModeTruncate
+	Mode string
+
+	// Key is the key of the HMAC
+	Key []byte
+
+	// HashLength is the number of hex digits of the HMAC kept, at most 64
+	HashLength int
+}
+
+// Anonymizer anonymizes the identities of clients.  A nil Anonymizer returns
+// the identities unchanged, so that callers need not check whether privacy
+// mode is enabled.
+type Anonymizer struct {
+	mode   string
+	key    []byte
+	length int
+}
+
+// New creates an Anonymizer, or returns an error if the configuration is
+// invalid.
+func New(conf Config) (*Anonymizer, error) {
+	if conf.Mode != ModeHash && conf.Mode != ModeTruncate {
+		return nil, errors.Errorf("unknown anonymization mode %q, the modes are %s and %s", conf.Mode, ModeHash, ModeTruncate)
+	}
+	if len(conf.Key) < MinKeySize {
+		return nil, errors.Errorf("anonymization key must be at least %d bytes, got %d", MinKeySize, len(conf.Key))
+	}
+	length := conf.HashLength
+	if length == 0 {
+		length = DefaultHashLength
+	}
+	if length < 0 || length > 2*sha256.Size {
+		return nil, errors.Errorf("anonymization hash length must be between 1 and %d, got %d", 2*sha256.Size, conf.HashLength)
+	}
+	return &Anonymizer{mode: conf.Mode, key: conf.Key, length: length}, nil
+}
+
+// Mode returns the mode of the anonymizer, or the empty string if it is nil
+func (a *Anonymizer) Mode() string {
+	if a == nil {
+		return ""
+	}
+	return a.mode
+}
+
+// hash returns the HMAC of the identity of the kind.  The kind is part of
+// the message so that equal identities of different kinds are not linked.
+func (a *Anonymizer) hash(kind, identity string) string {
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(identity))
+	return hashPrefix + hex.EncodeToString(mac.Sum(nil))[:a.length]
+}
+
+// Address anonymizes the host of a network address such as 10.1.2.3:7050,
+// keeping its port, so that the connections from a host remain grouped.
+func (a *Anonymizer) Address(addr string) string {
+	if a == nil || addr == "" {
+		return addr
+	}
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		host, port = addr, ""
+	}
+	if a.mode == ModeTruncate {
+		host = truncateHost(host)
+	} else {
+		host = a.hash("address", host)
+	}
+	if port == "" {
+		return host
+	}
+	return net.JoinHostPort(host, port)
+}
+
+// truncateHost returns the network of an IP address, or the domain of a
+// host name without its first label
+func truncateHost(host string) string {
+	ip := net.ParseIP(host)
+	if ip == nil {
+		if i := strings.Index(host, "."); i >= 0 {
+			return "*" + host[i:]
+		}
+		return "*"
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return ip4.Mask(net.CIDRMask(24, 32)).String() + "/24"
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String() + "/48"
+}
+
+// organizationAttributes are the attributes of a subject kept when truncated
+var organizationAttributes = map[string]bool{"O": true, "OU": true, "C": true}
+
+// Subject anonymizes the subject of a certificate, formatted as by
+// pkix.Name.String
+func (a *Anonymizer) Subject(subject string) string {
+	if a == nil || subject == "" {
+		return subject
+	}
+	if a.mode == ModeHash {
+		return a.hash("subject", subject)
+	}
+	var kept []string
+	for _, attr := range splitSubject(subject) {
+		if i := strings.Index(attr, "="); i > 0 && organizationAttributes[attr[:i]] {
+			kept = append(kept, attr)
+		}
+	}
+	return strings.Join(kept, ",")
+}
+
+// splitSubject splits a subject into its attributes, at the commas which are
+// not escaped
+func splitSubject(subject string) []string {
+	var attrs []string
+	start := 0
+	for i := 0; i < len(subject); i++ {
+		switch subject[i] {
+		case '\\':
+			i++
+		case ',', '+':
+			attrs = append(attrs, subject[start:i])
+			start = i + 1
+		}
+	}
+	return append(attrs, subject[start:])
+}
+
+// MSPID anonymizes an MSP ID
+func (a *Anonymizer) MSPID(mspID string) string {
+	if a == nil || mspID == "" {
+		return mspID
+	}
+	return a.hash("mspid", mspID)
+}
+
+// remoteAddressKey is the key of the context value holding the address of
+// the peer of an anonymized context
+type remoteAddressKey struct{}
+
+// Context returns a context whose peer address is anonymized, so that the
+// handlers given it log, export and audit the anonymized address.  The
+// transport authentication information of the peer is kept, for the TLS
+// bindings to be checked, and so is the real address, for RemoteAddress.
+func (a *Anonymizer) Context(ctx context.Context) context.Context {
+	if a == nil {
+		return ctx
+	}
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ctx
+	}
+	ctx = context.WithValue(ctx, remoteAddressKey{}, p.Addr.String())
+	return peer.NewContext(ctx, &peer.Peer{
+		Addr:     anonymizedAddr{network: p.Addr.Network(), addr: a.Address(p.Addr.String())},
+		AuthInfo: p.AuthInfo,
+	})
+}
+
+// RemoteAddress returns the real address of the peer of the context, even if
+// the context was anonymized, for the limits and the blocks keyed on the
+// client, which must not merge the clients sharing an anonymized address.
+// The address it returns must never be logged, exported nor audited.
+func RemoteAddress(ctx context.Context) string {
+	if addr, ok := ctx.Value(remoteAddressKey{}).(string); ok {
+		return addr
+	}
+	return util.ExtractRemoteAddress(ctx)
+}
+
+// anonymizedAddr is a network address whose host is anonymized
+type anonymizedAddr struct {
+	network string
+	addr    string
+}
+
+func (aa anonymizedAddr) Network() string { return aa.network }
+func (aa anonymizedAddr) String() string  { return aa.addr }
@@ -0,0 +1,108 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package privacy
+
+import (
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/peer"
+)
+
+var key = []byte("0123456789abcdef")
+
+func TestNew(t *testing.T) {
+	for _, tc := range []struct {
+		conf Config
+		err  string
+	}{
+		{Config{Mode: "redact", Key: key}, `unknown anonymization mode "redact", the modes are hash and truncate`},
+		{Config{Mode: ModeHash, Key: []byte("short")}, "anonymization key must be at least 16 bytes, got 5"},
+		{Config{Mode: ModeHash, Key: key, HashLength: 65}, "anonymization hash length must be between 1 and 64, got 65"},
+	} {
+		_, err := New(tc.conf)
+		assert.EqualError(t, err, tc.err)
+	}
+}
+
+func TestHash(t *testing.T) {
+	a, err := New(Config{Mode: ModeHash, Key: key})
+	require.NoError(t, err)
+	assert.Equal(t, ModeHash, a.Mode())
+
+	addr := a.Address("10.1.2.3:7050")
+	assert.True(t, strings.HasPrefix(addr, "anon-"), addr)
+	assert.True(t, strings.HasSuffix(addr, ":7050"), addr)
+	assert.Len(t, addr, len("anon-")+DefaultHashLength+len(":7050"))
+	// 同一主机的连接仍可关联
+	assert.Equal(t, strings.TrimSuffix(addr, ":7050"), strings.TrimSuffix(a.Address("10.1.2.3:7051"), ":7051"))
+	assert.NotEqual(t, addr, a.Address("10.1.2.4:7050"))
+
+	subject := a.Subject("CN=alice,O=Org1,C=US")
+	assert.True(t, strings.HasPrefix(subject, "anon-"), subject)
+	assert.Equal(t, subject, a.Subject("CN=alice,O=Org1,C=US"))
+	// 不同类型的相同身份不可关联
+	assert.NotEqual(t, a.MSPID("Org1MSP"), a.Subject("Org1MSP"))
+
+	// 不同密钥的摘要不同
+	other, err := New(Config{Mode: ModeHash, Key: []byte("fedcba9876543210"), HashLength: 8})
+	require.NoError(t, err)
+	assert.NotEqual(t, a.MSPID("Org1MSP")[:len("anon-")+8], other.MSPID("Org1MSP"))
+	assert.Len(t, other.MSPID("Org1MSP"), len("anon-")+8)
+
+	assert.Empty(t, a.Address(""))
+	assert.Empty(t, a.Subject(""))
+	assert.Empty(t, a.MSPID(""))
+}
+
+func TestTruncate(t *testing.T) {
+	a, err := New(Config{Mode: ModeTruncate, Key: key})
+	require.NoError(t, err)
+
+	assert.Equal(t, "10.1.2.0/24:7050", a.Address("10.1.2.3:7050"))
+	assert.Equal(t, "[2001:db8:1::/48]:7050", a.Address("[2001:db8:1:2::3]:7050"))
+	assert.Equal(t, "*.example.com:7050", a.Address("peer0.example.com:7050"))
+	assert.Equal(t, "10.1.2.0/24", a.Address("10.1.2.3"))
+
+	assert.Equal(t, "O=Org1,C=US", a.Subject("CN=alice,O=Org1,C=US"))
+	assert.Equal(t, `OU=client,O=Org\, Inc.`, a.Subject(`CN=alice\, bob,OU=client,O=Org\, Inc.`))
+	assert.True(t, strings.HasPrefix(a.MSPID("Org1MSP"), "anon-"))
+}
+
+func TestNilAnonymizer(t *testing.T) {
+	var a *Anonymizer
+	assert.Empty(t, a.Mode())
+	assert.Equal(t, "10.1.2.3:7050", a.Address("10.1.2.3:7050"))
+	assert.Equal(t, "CN=alice", a.Subject("CN=alice"))
+	assert.Equal(t, "Org1MSP", a.MSPID("Org1MSP"))
+	ctx := context.Background()
+	assert.Equal(t, ctx, a.Context(ctx))
+}
+
+func TestContext(t *testing.T) {
+	a, err := New(Config{Mode: ModeTruncate, Key: key})
+	require.NoError(t, err)
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP("10.1.2.3"), Port: 7050}})
+	anonymized := a.Context(ctx)
+	assert.Equal(t, "10.1.2.0/24:7050", util.ExtractRemoteAddress(anonymized))
+	p, ok := peer.FromContext(anonymized)
+	require.True(t, ok)
+	assert.Equal(t, "tcp", p.Addr.Network())
+
+	// 真实地址仅供按客户端限流与封禁使用
+	assert.Equal(t, "10.1.2.3:7050", RemoteAddress(anonymized))
+	assert.Equal(t, "10.1.2.3:7050", RemoteAddress(ctx))
+
+	// 没有对端信息的上下文保持不变
+	assert.Equal(t, context.Background(), a.Context(context.Background()))
+}
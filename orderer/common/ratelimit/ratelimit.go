@@ -0,0 +1,288 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package ratelimit throttles the messages broadcast to the orderer with
+// token buckets, one per channel and optionally one per client identity, so
+// that a client flooding a channel cannot starve the other channels or the
+// other clients of the ordering service.
+package ratelimit
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// idleSweepInterval is how often the buckets which filled up again are
+// forgotten
+const idleSweepInterval = time.Minute
+
+// ErrRateLimited is the cause of the errors returned for the messages
+// exceeding a rate limit.
+var ErrRateLimited = errors.New("rate limit exceeded")
+
+// limitedError is returned for a message exceeding a rate limit, with how
+// long until the exhausted bucket holds a token again.
+type limitedError struct {
+	error
+	retryAfter time.Duration
+}
+
+// Cause returns ErrRateLimited.
+func (e *limitedError) Cause() error { return ErrRateLimited }
+
+// RetryAfter returns how long the client should wait before submitting the
+// message again.
+func (e *limitedError) RetryAfter() time.Duration { return e.retryAfter }
+
+func newLimitedError(wait time.Duration, format string, args ...interface{}) error {
+	return &limitedError{error: errors.Wrapf(ErrRateLimited, format+", retry after %s", append(args, wait)...), retryAfter: wait}
+}
+
+// ChannelLimit overrides the default limit of a channel.
+type ChannelLimit struct {
+	Channel string
+
+	// Rate is the number of messages per second the channel accepts, 0 for
+	// no limit
+	Rate float64
+
+	// Burst is the number of messages the channel accepts at once beyond
+	// its rate, defaulting to the rate
+	Burst int
+}
+
+// Config contains the configuration of a Limiter.
+type Config struct {
+	// ChannelRate is the number of messages per second each channel accepts
+	// unless overridden by Channels, 0 for no limit
+	ChannelRate float64
+	// ChannelBurst is the number of messages a channel accepts at once
+	// beyond its rate, defaulting to the rate
+	ChannelBurst int
+
+	// Channels overrides the limit of individual channels
+	Channels []ChannelLimit
+
+	// ClientRate is the number of messages per second each client identity
+	// may broadcast across all channels, 0 for no limit
+	ClientRate float64
+	// ClientBurst is the number of messages a client may broadcast at once
+	// beyond its rate, defaulting to the rate
+	ClientBurst int
+}
+
+// Enabled returns whether the configuration limits any rate
+func (c Config) Enabled() bool {
+	if c.ChannelRate > 0 || c.ClientRate > 0 {
+		return true
+	}
+	for _, l := range c.Channels {
+		if l.Rate > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+type limit struct {
+	rate  float64 // tokens per second
+	burst float64
+}
+
+func newLimit(rate float64, burst int) limit {
+	if burst < 1 {
+		burst = int(rate)
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return limit{rate: rate, burst: float64(burst)}
+}
+
+// bucket holds the messages a channel or a client may still send
+type bucket struct {
+	limit  limit
+	tokens float64
+	last   time.Time
+}
+
+func (b *bucket) refill(now time.Time) {
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.limit.rate
+		if b.tokens > b.limit.burst {
+			b.tokens = b.limit.burst
+		}
+		b.last = now
+	}
+}
+
+// wait returns the time until the bucket holds a token
+func (b *bucket) wait() time.Duration {
+	return time.Duration((1 - b.tokens) / b.limit.rate * float64(time.Second))
+}
+
+// Limiter throttles the messages of each channel and of each client
+// identity independently.
+type Limiter struct {
+	channelLimit  limit
+	channelLimits map[string]limit
+	clientLimit   limit
+	now           func() time.Time
+
+	mutex     sync.Mutex
+	channels  map[string]*bucket
+	clients   map[string]*bucket
+	lastSweep time.Time
+}
+
+// NewLimiter creates a Limiter enforcing the limits of the configuration, or
+// returns an error if a limit is invalid
+func NewLimiter(conf Config) (*Limiter, error) {
+	l := &Limiter{
+		now:      time.Now,
+		channels: map[string]*bucket{},
+		clients:  map[string]*bucket{},
+	}
+	if err := l.setLimits(conf); err != nil {
+		return nil, err
+	}
+	l.lastSweep = l.now()
+	return l, nil
+}
+
+// Update enforces the limits of the configuration from now on, or returns an
+// error, leaving the limits unchanged, if a limit is invalid.  The tokens the
+// buckets hold are kept, up to the new bursts.
+func (l *Limiter) Update(conf Config) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if err := l.setLimits(conf); err != nil {
+		return err
+	}
+	for channelID, b := range l.channels {
+		channelLimit, ok := l.channelLimits[channelID]
+		if !ok {
+			channelLimit = l.channelLimit
+		}
+		l.rebucket(l.channels, channelID, b, channelLimit)
+	}
+	for creator, b := range l.clients {
+		l.rebucket(l.clients, creator, b, l.clientLimit)
+	}
+	return nil
+}
+
+// setLimits validates the limits of the configuration and sets them.  It must
+// be called with the mutex held, or before the Limiter is shared.
+func (l *Limiter) setLimits(conf Config) error {
+	if conf.ChannelRate < 0 || conf.ClientRate < 0 {
+		return errors.New("rate limits must not be negative")
+	}
+	channelLimits := map[string]limit{}
+	for _, cl := range conf.Channels {
+		if cl.Channel == "" {
+			return errors.New("rate limit declared without a channel")
+		}
+		if _, exists := channelLimits[cl.Channel]; exists {
+			return errors.Errorf("rate limit of channel %s declared more than once", cl.Channel)
+		}
+		if cl.Rate < 0 {
+			return errors.Errorf("rate limit of channel %s is negative", cl.Channel)
+		}
+		channelLimits[cl.Channel] = newLimit(cl.Rate, cl.Burst)
+	}
+	l.channelLimit = newLimit(conf.ChannelRate, conf.ChannelBurst)
+	l.channelLimits = channelLimits
+	l.clientLimit = newLimit(conf.ClientRate, conf.ClientBurst)
+	return nil
+}
+
+// rebucket sets the limit of a bucket, forgetting it if the limit is removed.
+// It must be called with the mutex held.
+func (l *Limiter) rebucket(buckets map[string]*bucket, key string, b *bucket, lim limit) {
+	if lim.rate == 0 {
+		delete(buckets, key)
+		return
+	}
+	//先按旧速率补充令牌，再按新的突发量截断
+	b.refill(l.now())
+	b.limit = lim
+	if b.tokens > lim.burst {
+		b.tokens = lim.burst
+	}
+}
+
+// Allow consumes a token of the channel and of the client identity, and
+// returns an error whose cause is ErrRateLimited if either has none left.
+// The error has a RetryAfter method returning when a token is available.
+// No token is consumed from either bucket when the message is rejected.
+func (l *Limiter) Allow(channelID string, creator []byte) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	if now.Sub(l.lastSweep) > idleSweepInterval {
+		l.sweep(now)
+	}
+
+	channelLimit, ok := l.channelLimits[channelID]
+	if !ok {
+		channelLimit = l.channelLimit
+	}
+	channel := l.bucket(l.channels, channelID, channelLimit, now)
+	if channel != nil && channel.tokens < 1 {
+		return newLimitedError(channel.wait(), "message rate of channel %s exceeded", channelID)
+	}
+
+	var client *bucket
+	if len(creator) > 0 {
+		client = l.bucket(l.clients, string(creator), l.clientLimit, now)
+	}
+	if client != nil && client.tokens < 1 {
+		return newLimitedError(client.wait(), "message rate of the client exceeded")
+	}
+
+	if channel != nil {
+		channel.tokens--
+	}
+	if client != nil {
+		client.tokens--
+	}
+	return nil
+}
+
+// bucket returns the refilled bucket of the key, or nil if it has no limit.
+// It must be called with the mutex held.
+func (l *Limiter) bucket(buckets map[string]*bucket, key string, lim limit, now time.Time) *bucket {
+	if lim.rate == 0 {
+		return nil
+	}
+	b, exists := buckets[key]
+	if !exists {
+		b = &bucket{limit: lim, tokens: lim.burst, last: now}
+		buckets[key] = b
+	}
+	b.refill(now)
+	return b
+}
+
+// sweep forgets the buckets which are full again, as the same bucket would
+// be created if their channel or client came back.  It must be called with
+// the mutex held.
+func (l *Limiter) sweep(now time.Time) {
+	for _, buckets := range []map[string]*bucket{l.channels, l.clients} {
+		for key, b := range buckets {
+			b.refill(now)
+			if b.tokens >= b.limit.burst {
+				delete(buckets, key)
+			}
+		}
+	}
+	l.lastSweep = now
+}
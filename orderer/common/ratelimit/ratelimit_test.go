@@ -0,0 +1,134 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLimiter(t *testing.T) {
+	_, err := NewLimiter(Config{ClientRate: -1})
+	assert.EqualError(t, err, "rate limits must not be negative")
+
+	_, err = NewLimiter(Config{Channels: []ChannelLimit{{Rate: 1}}})
+	assert.EqualError(t, err, "rate limit declared without a channel")
+
+	_, err = NewLimiter(Config{Channels: []ChannelLimit{{Channel: "foo"}, {Channel: "foo"}}})
+	assert.EqualError(t, err, "rate limit of channel foo declared more than once")
+
+	_, err = NewLimiter(Config{Channels: []ChannelLimit{{Channel: "foo", Rate: -1}}})
+	assert.EqualError(t, err, "rate limit of channel foo is negative")
+
+	assert.False(t, Config{}.Enabled())
+	assert.True(t, Config{ClientRate: 1}.Enabled())
+	assert.True(t, Config{Channels: []ChannelLimit{{Channel: "foo", Rate: 1}}}.Enabled())
+}
+
+func TestChannelLimit(t *testing.T) {
+	l, err := NewLimiter(Config{
+		ChannelRate:  2,
+		ChannelBurst: 3,
+		Channels:     []ChannelLimit{{Channel: "unlimited"}, {Channel: "slow", Rate: 0.5}},
+	})
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, l.Allow("foo", nil))
+	}
+	err = l.Allow("foo", nil)
+	assert.EqualError(t, err, "message rate of channel foo exceeded, retry after 500ms: rate limit exceeded")
+	assert.Equal(t, ErrRateLimited, errors.Cause(err))
+	assert.Equal(t, 500*time.Millisecond, err.(interface{ RetryAfter() time.Duration }).RetryAfter())
+
+	// the other channels are throttled independently
+	assert.NoError(t, l.Allow("bar", nil))
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, l.Allow("unlimited", nil))
+	}
+
+	// the burst of an overridden channel defaults to its rate, at least one
+	assert.NoError(t, l.Allow("slow", nil))
+	assert.Error(t, l.Allow("slow", nil))
+
+	now = now.Add(time.Second)
+	assert.NoError(t, l.Allow("foo", nil))
+	assert.NoError(t, l.Allow("foo", nil))
+	assert.Error(t, l.Allow("foo", nil))
+}
+
+func TestClientLimit(t *testing.T) {
+	l, err := NewLimiter(Config{ChannelRate: 10, ClientRate: 1})
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+
+	assert.NoError(t, l.Allow("foo", []byte("alice")))
+	err = l.Allow("bar", []byte("alice"))
+	assert.EqualError(t, err, "message rate of the client exceeded, retry after 1s: rate limit exceeded")
+	assert.NoError(t, l.Allow("foo", []byte("bob")))
+
+	// the rejected messages do not consume the tokens of the channel
+	for i := 0; i < 20; i++ {
+		l.Allow("foo", []byte("alice"))
+	}
+	for i := 0; i < 8; i++ {
+		assert.NoError(t, l.Allow("foo", []byte{byte(i)}))
+	}
+	assert.Error(t, l.Allow("foo", []byte("carol")))
+}
+
+func TestSweep(t *testing.T) {
+	l, err := NewLimiter(Config{ChannelRate: 1, ClientRate: 1})
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+
+	assert.NoError(t, l.Allow("foo", []byte("alice")))
+	assert.Len(t, l.channels, 1)
+	assert.Len(t, l.clients, 1)
+
+	now = now.Add(2 * idleSweepInterval)
+	assert.NoError(t, l.Allow("bar", nil))
+	assert.Len(t, l.channels, 1, "the full bucket of foo should have been forgotten")
+	assert.Empty(t, l.clients)
+}
+
+func TestUpdate(t *testing.T) {
+	l, err := NewLimiter(Config{ChannelRate: 1, ChannelBurst: 4, ClientRate: 1})
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	l.now = func() time.Time { return now }
+
+	assert.NoError(t, l.Allow("foo", []byte("alice")))
+	assert.Error(t, l.Allow("foo", []byte("alice")))
+
+	assert.EqualError(t, l.Update(Config{ChannelRate: -1}), "rate limits must not be negative")
+	assert.Error(t, l.Allow("foo", []byte("alice")), "the limits are unchanged by an invalid update")
+
+	// the tokens left are kept, up to the new burst, and the client limit is
+	// removed
+	require.NoError(t, l.Update(Config{ChannelRate: 1, ChannelBurst: 2}))
+	assert.Empty(t, l.clients)
+	assert.NoError(t, l.Allow("foo", []byte("alice")))
+	assert.NoError(t, l.Allow("foo", []byte("alice")))
+	assert.Error(t, l.Allow("foo", []byte("alice")))
+
+	require.NoError(t, l.Update(Config{ChannelRate: 10, Channels: []ChannelLimit{{Channel: "foo", Rate: 0.5}}}))
+	err = l.Allow("foo", nil)
+	assert.EqualError(t, err, "message rate of channel foo exceeded, retry after 2s: rate limit exceeded")
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, l.Allow("bar", nil))
+	}
+}
@@ -0,0 +1,283 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package restore fences an orderer started on ledgers restored from a
+// backup until they have caught up with the other orderers of the cluster.
+//
+// A restored ledger lags behind the cluster by the blocks committed since
+// the backup was taken.  An orderer consenting on it would order blocks
+// conflicting with those the cluster already committed, so before its
+// consenters start, the hash chain of every restored ledger is verified,
+// and the blocks it misses are replicated from the orderer reporting the
+// highest ledger, until a majority of the peers report heights the ledgers
+// have reached.
+package restore
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	ramledger "github.com/hyperledger/fabric/common/ledger/blockledger/ram"
+	"github.com/hyperledger/fabric/common/tools/blockarchive/archive"
+	"github.com/hyperledger/fabric/orderer/common/standby"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/restore"
+
+var logger = flogging.MustGetLogger(pkgLogID)
+
+// Peer is another orderer of the cluster
+type Peer interface {
+	standby.Source
+
+	// Height returns the height of the ledger of the channel on the peer
+	Height(channelID string) (uint64, error)
+}
+
+// Config contains the configuration of a Fence
+type Config struct {
+	// PollInterval is how long the fence waits between two rounds of
+	// comparing the heights and replicating the blocks of the channels
+	PollInterval time.Duration
+}
+
+// ChannelStatus is the restore status of a channel
+type ChannelStatus struct {
+	Height        uint64 `json:"height"`
+	ClusterHeight uint64 `json:"cluster_height"`
+	Source        string `json:"source,omitempty"`
+	Answered      int    `json:"answered"`
+	CaughtUp      bool   `json:"caught_up"`
+	Error         string `json:"error,omitempty"`
+}
+
+// Status is the restore status of the orderer
+type Status struct {
+	Fenced   bool                      `json:"fenced"`
+	Peers    int                       `json:"peers"`
+	Channels map[string]*ChannelStatus `json:"channels"`
+}
+
+// Fence keeps the orderer from consenting until its restored ledgers have
+// caught up with the cluster
+type Fence struct {
+	conf       Config
+	peers      map[string]Peer
+	replicator *standby.Replicator
+
+	mutex   sync.Mutex
+	sources map[string]string // 各通道拉取区块的来源，即账本最高的节点
+	status  Status
+}
+
+// New verifies the hash chain of the restored ledgers, which must hold the
+// system channel, and creates the Fence catching them up with the peers,
+// identified by their addresses
+func New(conf Config, ledgers blockledger.Factory, peers map[string]Peer) (*Fence, error) {
+	if conf.PollInterval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+	if len(peers) == 0 {
+		return nil, errors.New("no peers to compare the ledgers with")
+	}
+	if err := VerifyLedgers(ledgers); err != nil {
+		return nil, err
+	}
+
+	f := &Fence{
+		conf:    conf,
+		peers:   peers,
+		sources: map[string]string{},
+		status:  Status{Fenced: true, Peers: len(peers), Channels: map[string]*ChannelStatus{}},
+	}
+	replicator, err := standby.NewReplicator(standby.Config{PollInterval: conf.PollInterval}, f, ledgers)
+	if err != nil {
+		return nil, err
+	}
+	f.replicator = replicator
+	return f, nil
+}
+
+// VerifyLedgers checks that every block of each ledger is chained by hash
+// to the previous one and signed according to the BlockValidation policy
+// of its channel, starting from the genesis block
+func VerifyLedgers(ledgers blockledger.Factory) error {
+	for _, channelID := range ledgers.ChainIDs() {
+		ledger, err := ledgers.GetOrCreate(channelID)
+		if err != nil {
+			return errors.Wrapf(err, "error opening ledger of channel %s", channelID)
+		}
+		if err := VerifyLedger(channelID, ledger); err != nil {
+			return errors.WithMessage(err, "restored ledger of channel "+channelID+" is invalid")
+		}
+		logger.Infof("[channel: %s] Verified the %d blocks of the restored ledger", channelID, ledger.Height())
+	}
+	return nil
+}
+
+// VerifyLedger checks the blocks of the ledger of the channel, from the
+// genesis block on
+func VerifyLedger(channelID string, ledger blockledger.Reader) error {
+	empty, _ := ramledger.New(1).GetOrCreate(channelID)
+	verifier, err := archive.NewVerifier(channelID, empty)
+	if err != nil {
+		return err
+	}
+	height := ledger.Height()
+	it, _ := ledger.Iterator(&ab.SeekPosition{Type: &ab.SeekPosition_Oldest{Oldest: &ab.SeekOldest{}}})
+	defer it.Close()
+	for number := uint64(0); number < height; number++ {
+		block, status := it.Next()
+		if status != cb.Status_SUCCESS {
+			return errors.Errorf("could not read block %d: %s", number, status)
+		}
+		if err := verifier.Verify(block); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run compares the heights of the ledgers with the peers and replicates the
+// blocks they miss every poll interval, and returns once every ledger has
+// caught up
+func (f *Fence) Run() {
+	logger.Infof("Fencing the orderer until its restored ledgers catch up with %d peers", len(f.peers))
+	ticker := time.NewTicker(f.conf.PollInterval)
+	defer ticker.Stop()
+	for !f.catchUp() {
+		<-ticker.C
+	}
+
+	f.mutex.Lock()
+	f.status.Fenced = false
+	f.mutex.Unlock()
+	logger.Info("Restored ledgers caught up with the cluster, lifting the fence")
+}
+
+// Status returns the restore status of the orderer
+func (f *Fence) Status() Status {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	status := Status{
+		Fenced:   f.status.Fenced,
+		Peers:    f.status.Peers,
+		Channels: make(map[string]*ChannelStatus, len(f.status.Channels)),
+	}
+	for channelID, cs := range f.status.Channels {
+		copied := *cs
+		status.Channels[channelID] = &copied
+	}
+	return status
+}
+
+// ServeHTTP serves the restore status as JSON
+func (f *Fence) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(f.Status()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// Pull passes to fn the blocks of the channel from the peer reporting the
+// highest ledger in the current round
+func (f *Fence) Pull(channelID string, start uint64, fn func(*cb.Block) error) error {
+	f.mutex.Lock()
+	address, ok := f.sources[channelID]
+	f.mutex.Unlock()
+	if !ok {
+		return errors.Errorf("no peer reported the height of channel %s", channelID)
+	}
+	return f.peers[address].Pull(channelID, start, fn)
+}
+
+// catchUp runs a round of comparing the heights and replicating the blocks
+// of the channels, and returns whether every ledger has caught up
+func (f *Fence) catchUp() bool {
+	// 通道包括本地账本以及系统通道中已复制的区块所创建的通道
+	channels := f.replicator.Channels()
+	cluster := make(map[string]*ChannelStatus, len(channels))
+	sources := make(map[string]string, len(channels))
+	for _, channelID := range channels {
+		cs, source := f.clusterHeight(channelID)
+		cluster[channelID] = cs
+		if source != "" {
+			sources[channelID] = source
+		}
+	}
+	f.mutex.Lock()
+	f.sources = sources
+	f.mutex.Unlock()
+
+	replicated := f.replicator.CatchUp()
+
+	quorum := len(f.peers)/2 + 1
+	caughtUp := true
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	for _, channelID := range f.replicator.Channels() {
+		cs, ok := cluster[channelID]
+		if !ok {
+			// 本轮复制的系统通道区块新创建的通道，下一轮再比较其高度
+			cs = &ChannelStatus{}
+		}
+		if rs, exists := replicated.Channels[channelID]; exists {
+			cs.Height = rs.Height
+			if rs.Error != "" {
+				cs.Error = rs.Error
+			}
+		}
+		cs.CaughtUp = ok && cs.Answered >= quorum && cs.Height >= cs.ClusterHeight
+		if !cs.CaughtUp {
+			caughtUp = false
+		}
+		f.status.Channels[channelID] = cs
+	}
+	return caughtUp
+}
+
+// clusterHeight returns the highest height of the ledger of the channel
+// reported by the peers, along with the address of the peer reporting it
+func (f *Fence) clusterHeight(channelID string) (*ChannelStatus, string) {
+	addresses := make([]string, 0, len(f.peers))
+	for address := range f.peers {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+
+	cs := &ChannelStatus{}
+	var lastErr error
+	for _, address := range addresses {
+		height, err := f.peers[address].Height(channelID)
+		if err != nil {
+			lastErr = err
+			logger.Warningf("[channel: %s] Failed to get the height of the ledger on %s: %s", channelID, address, err)
+			continue
+		}
+		cs.Answered++
+		if cs.Source == "" || height > cs.ClusterHeight {
+			cs.ClusterHeight = height
+			cs.Source = address
+		}
+	}
+	if cs.Answered == 0 && lastErr != nil {
+		cs.Error = lastErr.Error()
+	}
+	return cs, cs.Source
+}
@@ -0,0 +1,237 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package restore
+
+import (
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	ramledger "github.com/hyperledger/fabric/common/ledger/blockledger/ram"
+	"github.com/hyperledger/fabric/common/localmsp"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/configtxgentest"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/encoder"
+	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	"github.com/hyperledger/fabric/common/util"
+	msptesttools "github.com/hyperledger/fabric/msp/mgmt/testtools"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	systemChannelID = "systemchannel"
+	appChannelID    = "appchannel"
+)
+
+func TestMain(m *testing.M) {
+	if err := msptesttools.LoadDevMsp(); err != nil {
+		fmt.Printf("Failed to load dev MSP: %s\n", err)
+		os.Exit(-1)
+	}
+	os.Exit(m.Run())
+}
+
+// mockPeer serves the blocks of its ledgers, or fails with err
+type mockPeer struct {
+	ledgers blockledger.Factory
+	err     error
+}
+
+func (mp *mockPeer) Pull(channelID string, start uint64, fn func(*cb.Block) error) error {
+	if mp.err != nil {
+		return mp.err
+	}
+	ledger, _ := mp.ledgers.GetOrCreate(channelID)
+	for number := start; number < ledger.Height(); number++ {
+		if err := fn(blockledger.GetBlock(ledger, number)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mp *mockPeer) Height(channelID string) (uint64, error) {
+	if mp.err != nil {
+		return 0, mp.err
+	}
+	ledger, _ := mp.ledgers.GetOrCreate(channelID)
+	return ledger.Height(), nil
+}
+
+func appendBlock(t *testing.T, ledger blockledger.ReadWriter, envs ...*cb.Envelope) {
+	block := blockledger.CreateNextBlock(ledger, envs)
+	signer := localmsp.NewSigner()
+	value := utils.MarshalOrPanic(&cb.LastConfig{Index: 0})
+	block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{Value: value})
+	shdr, _ := signer.NewSignatureHeader()
+	shdrBytes := utils.MarshalOrPanic(shdr)
+	signature, _ := signer.Sign(util.ConcatenateBytes(value, shdrBytes, block.Header.Bytes()))
+	block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{
+		Value:      value,
+		Signatures: []*cb.MetadataSignature{{SignatureHeader: shdrBytes, Signature: signature}},
+	})
+	require.NoError(t, ledger.Append(block))
+}
+
+func genesisBlock(channelID string) *cb.Block {
+	return encoder.New(configtxgentest.Load(genesisconfig.SampleInsecureSoloProfile)).GenesisBlockForChannel(channelID)
+}
+
+// newCluster returns the ledgers of an orderer whose system channel created
+// the application channel, which holds the given number of blocks besides
+// its genesis block
+func newCluster(t *testing.T, blocks int) blockledger.Factory {
+	lf := ramledger.New(10)
+	system, _ := lf.GetOrCreate(systemChannelID)
+	require.NoError(t, system.Append(genesisBlock(systemChannelID)))
+
+	configtx, err := utils.ExtractEnvelope(genesisBlock(appChannelID), 0)
+	require.NoError(t, err)
+	ordererTx, err := utils.CreateSignedEnvelope(cb.HeaderType_ORDERER_TRANSACTION, systemChannelID, localmsp.NewSigner(), configtx, 0, 0)
+	require.NoError(t, err)
+	appendBlock(t, system, ordererTx)
+
+	app, _ := lf.GetOrCreate(appChannelID)
+	require.NoError(t, app.Append(blockledger.CreateNextBlock(app, []*cb.Envelope{configtx})))
+	for i := 0; i < blocks; i++ {
+		appendBlock(t, app, &cb.Envelope{Payload: []byte(fmt.Sprintf("tx%d", i))})
+	}
+	return lf
+}
+
+// restored returns a copy of the first blocks of each ledger, as restored
+// from a backup taken when the ledgers had those heights
+func restored(source blockledger.Factory, heights map[string]uint64) blockledger.Factory {
+	lf := ramledger.New(10)
+	for channelID, height := range heights {
+		from, _ := source.GetOrCreate(channelID)
+		to, _ := lf.GetOrCreate(channelID)
+		for number := uint64(0); number < height; number++ {
+			to.Append(blockledger.GetBlock(from, number))
+		}
+	}
+	return lf
+}
+
+func height(lf blockledger.Factory, channelID string) uint64 {
+	ledger, _ := lf.GetOrCreate(channelID)
+	return ledger.Height()
+}
+
+func TestNew(t *testing.T) {
+	cluster := newCluster(t, 2)
+	peers := map[string]Peer{"orderer1": &mockPeer{ledgers: cluster}}
+
+	_, err := New(Config{}, cluster, peers)
+	assert.EqualError(t, err, "poll interval must be positive")
+
+	_, err = New(Config{PollInterval: time.Second}, cluster, nil)
+	assert.EqualError(t, err, "no peers to compare the ledgers with")
+
+	_, err = New(Config{PollInterval: time.Second}, ramledger.New(10), peers)
+	assert.EqualError(t, err, "no system channel among the ledgers")
+
+	f, err := New(Config{PollInterval: time.Second}, restored(cluster, map[string]uint64{systemChannelID: 2, appChannelID: 2}), peers)
+	require.NoError(t, err)
+	assert.True(t, f.Status().Fenced)
+}
+
+func TestVerifyLedger(t *testing.T) {
+	cluster := newCluster(t, 3)
+	app, _ := cluster.GetOrCreate(appChannelID)
+	assert.NoError(t, VerifyLedger(appChannelID, app))
+
+	blockledger.GetBlock(app, 2).Header.PreviousHash = []byte("garbage")
+	assert.EqualError(t, VerifyLedger(appChannelID, app), "block 2 is not chained to block 1")
+
+	_, err := New(Config{PollInterval: time.Second}, cluster, map[string]Peer{"orderer1": &mockPeer{ledgers: cluster}})
+	assert.EqualError(t, err, "restored ledger of channel appchannel is invalid: block 2 is not chained to block 1")
+}
+
+func TestCatchUp(t *testing.T) {
+	ahead := newCluster(t, 4)
+	behind := restored(ahead, map[string]uint64{systemChannelID: 2, appChannelID: 3})
+	local := restored(ahead, map[string]uint64{systemChannelID: 2, appChannelID: 2})
+
+	f, err := New(Config{PollInterval: time.Second}, local, map[string]Peer{
+		"orderer1": &mockPeer{ledgers: behind},
+		"orderer2": &mockPeer{ledgers: ahead},
+	})
+	require.NoError(t, err)
+
+	assert.True(t, f.catchUp())
+	assert.Equal(t, uint64(5), height(local, appChannelID))
+	assert.Equal(t, &ChannelStatus{
+		Height:        5,
+		ClusterHeight: 5,
+		Source:        "orderer2",
+		Answered:      2,
+		CaughtUp:      true,
+	}, f.Status().Channels[appChannelID])
+}
+
+func TestCatchUpCreatedChannel(t *testing.T) {
+	cluster := newCluster(t, 2)
+	local := restored(cluster, map[string]uint64{systemChannelID: 1})
+
+	f, err := New(Config{PollInterval: time.Second}, local, map[string]Peer{"orderer1": &mockPeer{ledgers: cluster}})
+	require.NoError(t, err)
+
+	// 通道在备份之后创建，下一轮才复制其区块
+	assert.False(t, f.catchUp())
+	assert.NotContains(t, local.ChainIDs(), appChannelID)
+	assert.False(t, f.Status().Channels[appChannelID].CaughtUp)
+
+	assert.True(t, f.catchUp())
+	assert.Equal(t, uint64(3), height(local, appChannelID))
+	assert.True(t, f.Status().Channels[appChannelID].CaughtUp)
+}
+
+func TestCatchUpWithoutQuorum(t *testing.T) {
+	cluster := newCluster(t, 2)
+	local := restored(cluster, map[string]uint64{systemChannelID: 2, appChannelID: 3})
+
+	f, err := New(Config{PollInterval: time.Second}, local, map[string]Peer{
+		"orderer1": &mockPeer{ledgers: cluster},
+		"orderer2": &mockPeer{err: errors.New("unreachable")},
+		"orderer3": &mockPeer{err: errors.New("unreachable")},
+	})
+	require.NoError(t, err)
+
+	// 多数节点未报告高度时，无法确认本地账本未落后
+	assert.False(t, f.catchUp())
+	status := f.Status()
+	assert.True(t, status.Fenced)
+	assert.Equal(t, 1, status.Channels[appChannelID].Answered)
+	assert.False(t, status.Channels[appChannelID].CaughtUp)
+}
+
+func TestRun(t *testing.T) {
+	cluster := newCluster(t, 2)
+	local := restored(cluster, map[string]uint64{systemChannelID: 2, appChannelID: 1})
+
+	f, err := New(Config{PollInterval: time.Millisecond}, local, map[string]Peer{"orderer1": &mockPeer{ledgers: cluster}})
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		f.Run()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("fence was not lifted")
+	}
+	assert.False(t, f.Status().Fenced)
+	assert.Equal(t, uint64(3), height(local, appChannelID))
+}
@@ -5,6 +5,8 @@ package server
 
 import (
 	"bytes"
+	"crypto/tls"
+	"crypto/x509"
 	"fmt"
 	"io/ioutil"
 	"net"
@@ -12,24 +14,75 @@ import (
 	_ "net/http/pprof" // This is essentially the main package for the orderer
 
 	"os"
+	"os/signal"
+	"runtime"
+	"strconv"
+	"syscall"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/flightrecorder"
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/flogging/httpadmin"
 	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	"github.com/hyperledger/fabric/common/memtuning"
 	"github.com/hyperledger/fabric/common/tools/configtxgen/encoder"
 	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	"github.com/hyperledger/fabric/common/txtimeline"
 	"github.com/hyperledger/fabric/core/comm"
 	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/orderer/common/accounting"
+	"github.com/hyperledger/fabric/orderer/common/admission"
+	"github.com/hyperledger/fabric/orderer/common/admissionplugin"
+	"github.com/hyperledger/fabric/orderer/common/analytics"
+	"github.com/hyperledger/fabric/orderer/common/asyncop"
+	"github.com/hyperledger/fabric/orderer/common/attestation"
+	"github.com/hyperledger/fabric/orderer/common/audit"
+	"github.com/hyperledger/fabric/orderer/common/backlog"
+	"github.com/hyperledger/fabric/orderer/common/backup"
 	"github.com/hyperledger/fabric/orderer/common/bootstrap/file"
+	"github.com/hyperledger/fabric/orderer/common/broadcast"
+	"github.com/hyperledger/fabric/orderer/common/canary"
+	"github.com/hyperledger/fabric/orderer/common/chainhealth"
+	"github.com/hyperledger/fabric/orderer/common/channeladmin"
+	"github.com/hyperledger/fabric/orderer/common/channelvalidation"
+	"github.com/hyperledger/fabric/orderer/common/commitnotify"
+	"github.com/hyperledger/fabric/orderer/common/consistency"
+	"github.com/hyperledger/fabric/orderer/common/corpus"
+	"github.com/hyperledger/fabric/orderer/common/crash"
+	"github.com/hyperledger/fabric/orderer/common/dedup"
+	"github.com/hyperledger/fabric/orderer/common/embargo"
+	"github.com/hyperledger/fabric/orderer/common/fairqueue"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
+	"github.com/hyperledger/fabric/orderer/common/gameday"
+	"github.com/hyperledger/fabric/orderer/common/grpcweb"
+	"github.com/hyperledger/fabric/orderer/common/hotreload"
+	"github.com/hyperledger/fabric/orderer/common/intake"
 	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/hyperledger/fabric/orderer/common/maintenance"
 	"github.com/hyperledger/fabric/orderer/common/metadata"
+	"github.com/hyperledger/fabric/orderer/common/misbehavior"
+	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
 	"github.com/hyperledger/fabric/orderer/common/multichannel"
+	"github.com/hyperledger/fabric/orderer/common/operations"
+	"github.com/hyperledger/fabric/orderer/common/privacy"
+	"github.com/hyperledger/fabric/orderer/common/ratelimit"
+	"github.com/hyperledger/fabric/orderer/common/restore"
+	"github.com/hyperledger/fabric/orderer/common/slo"
+	"github.com/hyperledger/fabric/orderer/common/standby"
+	"github.com/hyperledger/fabric/orderer/common/streamquota"
+	"github.com/hyperledger/fabric/orderer/common/tracing"
+	"github.com/hyperledger/fabric/orderer/common/versionskew"
 	"github.com/hyperledger/fabric/orderer/consensus"
+	"github.com/hyperledger/fabric/orderer/consensus/bft"
+	"github.com/hyperledger/fabric/orderer/consensus/faulty"
+	"github.com/hyperledger/fabric/orderer/consensus/forward"
 	"github.com/hyperledger/fabric/orderer/consensus/kafka"
 	"github.com/hyperledger/fabric/orderer/consensus/solo"
 	cb "github.com/hyperledger/fabric/protos/common"
+	mspprotos "github.com/hyperledger/fabric/protos/msp"
 	ab "github.com/hyperledger/fabric/protos/orderer"
 	"github.com/hyperledger/fabric/protos/utils"
 
@@ -38,11 +91,32 @@ import (
 	mspmgmt "github.com/hyperledger/fabric/msp/mgmt"
 	"github.com/hyperledger/fabric/orderer/common/performance"
 	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
 	"gopkg.in/alecthomas/kingpin.v2"
 )
 
 const pkgLogID = "orderer/common/server"
 
+// flightRecorderBlockBuffer is the number of blocks the flight recorder may
+// lag behind the block writers before its subscription is dropped
+const flightRecorderBlockBuffer = 1000
+
+// sloBlockBuffer is the number of blocks the SLO monitor may lag behind the
+// block writers before its subscription is dropped
+const sloBlockBuffer = 1000
+
+// analyticsBlockBuffer is the number of blocks the analyses may lag behind
+// the block writers before their subscription is dropped
+const analyticsBlockBuffer = 1000
+
+// standbyDialTimeout is how long a standby, or an orderer restored from
+// backups, waits for the connection to another orderer to be established
+const standbyDialTimeout = 10 * time.Second
+
 var logger *logging.Logger
 
 func init() {
@@ -56,6 +130,11 @@ var (
 	start     = app.Command("start", "Start the orderer node").Default()
 	version   = app.Command("version", "Show version information")
 	benchmark = app.Command("benchmark", "Run orderer in benchmark mode")
+
+	snapshot            = app.Command("snapshot", "Manage the snapshots of the channels")
+	snapshotImport      = snapshot.Command("import", "Import the snapshot of a channel into the file ledger before starting the orderer")
+	snapshotDir         = snapshotImport.Arg("dir", "Directory of the snapshot").Required().String()
+	snapshotConfigBlock = snapshotImport.Flag("configBlock", "File holding the latest config block of the channel, obtained from a trusted source").Required().String()
 )
 
 // Main is the entry point of orderer process
@@ -78,9 +157,17 @@ func Main() {
 	//初始化日志级别
 	//负责设置orderer节点上的日志后端输出流 输出格式与默认日志级别
 	initializeLoggingLevel(conf)
+	//按配置调整GC参数与堆内存压舱物
+	initializeMemoryTuning(conf)
 	//初始化MSP组件
 	initializeLocalMsp(conf)
 
+	//导入通道快照后退出，新的排序节点启动后即从快照加入通道
+	if fullCmd == snapshotImport.FullCommand() {
+		importSnapshot(conf, *snapshotDir, *snapshotConfigBlock)
+		return
+	}
+
 	//打印配置信息
 	prettyPrintStruct(conf)
 	//启动 Orderer排序服务器
@@ -95,6 +182,35 @@ func Start(cmd string, conf *localconfig.TopLevel) {
 	//初始化grpc服务器配置
 	//首先利用Orderer配置对象conf初始化TLS安全认证配置选项secureOpts
 	serverConfig := initializeServerConfig(conf)
+	//创建Prometheus指标注册表与排序节点之间的版本协商器
+	registry := prometheus.NewRegistry()
+	metricLabels := initializeMetricLabels(conf)
+	versionSkew := initializeVersionSkew(conf, registry)
+	//创建服务处理句柄panic的崩溃报告器
+	crashes := initializeCrashReporter(registry)
+	//备用模式下先复制活动节点的通道区块，直至被提升为活动节点后再启动服务
+	var opsSystem *operations.System
+	standbyMode := cmd == start.FullCommand() && conf.General.Standby.Enabled
+	if standbyMode {
+		opsSystem = initializeOperationsSystem(conf, crashes)
+		//备用模式下即提供Prometheus指标，包括与活动节点的版本偏差
+		if opsSystem != nil {
+			opsSystem.RegisterHandlerWithRole("/metrics", operations.RoleMetrics, promhttp.HandlerFor(metricLabels.Gatherer(registry), promhttp.HandlerOpts{}))
+		}
+		runStandby(conf, serverConfig.SecOpts, signer, opsSystem, versionSkew)
+	}
+	//从备份恢复的账本追上集群中的其他节点之前，不启动共识组件和服务
+	restoreMode := cmd == start.FullCommand() && conf.General.Restore.Enabled
+	if restoreMode {
+		if standbyMode {
+			logger.Fatal("Failed to start: General.Standby and General.Restore cannot both be enabled")
+		}
+		opsSystem = initializeOperationsSystem(conf, crashes)
+		if opsSystem != nil {
+			opsSystem.RegisterHandlerWithRole("/metrics", operations.RoleMetrics, promhttp.HandlerFor(metricLabels.Gatherer(registry), promhttp.HandlerOpts{}))
+		}
+		runRestore(conf, serverConfig.SecOpts, signer, opsSystem, versionSkew)
+	}
 	//初始化grpc服务
 	grpcServer := initializeGrpcServer(conf, serverConfig)
 	//构造CA证书支持组件对象
@@ -118,11 +234,87 @@ func Start(cmd string, conf *localconfig.TopLevel) {
 	//初始化多通道管理器对象
 	//创建多通道注册管理器对象，用于注册Orderer节点上的所有通道（包括系统通道和应用通道），负责维护通道、账本等重要资源
 	//可以创建solo和kafka两种类型的共识组件
-	manager := initializeMultichannelRegistrar(conf, signer, tlsCallback)
+	//加载过滤插件并创建消息过滤规则链
+	ruleChain := initializeRuleChain(conf)
+	//创建消息追踪器
+	tracer := initializeTracer(conf)
+	manager := initializeMultichannelRegistrar(conf, signer, initializeTxTimeline(conf), tracer, ruleChain, tlsCallback)
+	//创建通道SLO监控器
+	sloMonitor := initializeSLOMonitor(conf, manager)
+	//创建客户端身份匿名化器
+	anonymizer := initializeAnonymizer(conf)
+	//创建组织用量计量器
+	meter := initializeAccountingMeter(conf, anonymizer)
+	//创建Broadcast服务指标
+	broadcastMetrics := initializeBroadcastMetrics(registry)
+	//打开Broadcast服务的审计日志
+	auditLog := initializeAuditLog(conf, anonymizer)
+	//创建演练用的过载模拟器
+	overloadSim := initializeOverloadSimulator(conf)
+	//创建交易提交通知器，供等待交易提交的客户端使用
+	commits := initializeCommitNotifier(conf, manager)
+	//创建可疑消息的检测器
+	misbehaviorDetector := initializeMisbehaviorDetector(conf)
+	//打开Broadcast消息的接收日志
+	intakeJournal := initializeIntakeJournal(conf, manager)
 	//设置TLS双向任之鞥标志位
 	mutualTLS := serverConfig.SecOpts.UseTLS && serverConfig.SecOpts.RequireClientCert
+	//各监听器的Orderer排序服务器共用准入控制、去重、限流等组件
+	admissionController := initializeAdmissionController(conf)
+	malformedCorpus := initializeMalformedCorpus(conf)
+	duplicates := initializeDuplicateCache(conf)
+	nonceTracker := initializeNonceTracker(conf)
+	limiter := initializeRateLimiter(conf)
+	sizeLimits := initializeSizeLimits(conf)
+	admissionPlugins := initializeAdmissionPlugins(conf)
+	streamLimits := initializeStreamLimits(conf)
+	streamQuota := initializeStreamQuota(conf, registry)
+	scheduler := initializeIngressScheduler(conf)
+	embargoes := initializeEmbargoes(conf)
+	verifier := initializeVerifierPool(conf)
+	forwarder := initializeForwarder(conf, serverConfig.SecOpts)
+	serverOpts := ServerOptions{
+		Admission:              admissionController,
+		DeliverMAC:             conf.General.Authentication.DeliverMAC,
+		Malformed:              malformedCorpus,
+		Duplicates:             duplicates,
+		SLOMonitor:             sloMonitor,
+		Limiter:                limiter,
+		Quotas:                 msgprocessor.NewIngressQuotas(channelOrdererConfigs{manager}),
+		BroadcastWindow:        conf.General.Broadcast.InFlightWindow,
+		BroadcastReadyTimeout:  conf.General.Broadcast.ReadyTimeout,
+		Meter:                  meter,
+		BroadcastMetrics:       broadcastMetrics,
+		AuditSink:              auditLog,
+		SizeLimits:             sizeLimits,
+		Overload:               overloadSim,
+		AdmissionPlugin:        admissionPlugins,
+		StreamLimits:           streamLimits,
+		VersionSkew:            versionSkew,
+		Anonymizer:             anonymizer,
+		Commits:                commits,
+		CommitTimeout:          conf.General.Broadcast.CommitTimeout,
+		Scheduler:              scheduler,
+		HeartbeatMinInterval:   heartbeatMinInterval(conf),
+		Crashes:                crashes,
+		Receipts:               conf.General.Broadcast.Receipts,
+		PendingBytesWatermark:  conf.General.Broadcast.PendingBytesWatermark,
+		BackpressureRetryAfter: conf.General.Broadcast.BackpressureRetryAfter,
+		MisbehaviorDetector:    misbehaviorDetector,
+		Embargoes:              embargoes,
+		IntakeJournal:          intakeJournal,
+		NonceTracker:           nonceTracker,
+		StreamQuota:            streamQuota,
+		Verifier:               verifier,
+		Forwarder:              forwarder,
+		ForwardAttempts:        conf.General.Broadcast.ForwardAttempts,
+		ForwardRetryInterval:   conf.General.Broadcast.ForwardRetryInterval,
+	}
+	newServer := func(r channelRegistry, mutualTLS bool) ab.AtomicBroadcastServer {
+		return NewServer(r, signer, &conf.Debug, conf.General.Authentication.TimeWindow, mutualTLS, serverOpts)
+	}
 	//创建Orderer排序服务器
-	server := NewServer(manager, signer, &conf.Debug, conf.General.Authentication.TimeWindow, mutualTLS)
+	server := newServer(manager, mutualTLS)
 
 	//分析命令类型
 	switch cmd {
@@ -131,6 +323,96 @@ func Start(cmd string, conf *localconfig.TopLevel) {
 		logger.Infof("Starting %s", metadata.GetVersionInfo())
 		//goroutine启动go profile服务
 		initializeProfilingService(conf)
+		//启动运维服务，备用模式和恢复模式下已经启动
+		if opsSystem == nil {
+			opsSystem = initializeOperationsSystem(conf, crashes)
+		}
+		//在运维服务上提供节点身份证明
+		initializeAttestation(conf, opsSystem, signer, manager)
+		//在运维服务上提供运行状态与通道配置的一致性检查
+		initializeConsistencyCheck(conf, opsSystem, signer, manager)
+		//在运维服务上提供内部指标的近期历史
+		initializeFlightRecorder(conf, opsSystem, manager)
+		//在运维服务上提供通道创建交易的预验证
+		if opsSystem != nil {
+			opsSystem.RegisterHandler("/channelcreation/validate", channelvalidation.NewHandler(manager))
+		}
+		//在运维服务上提供各通道共识组件的就绪状态，供负载均衡器检查
+		if opsSystem != nil {
+			opsSystem.RegisterHandlerWithRole("/healthz/channels/", operations.RoleMetrics, chainhealth.NewHandler("/healthz/channels/", manager))
+		}
+		//在运维服务上提供通道列表（账本高度与共识组件状态）的查询，以及已停用通道的删除
+		if opsSystem != nil {
+			opsSystem.RegisterHandlerWithRole("/channels/", operations.RoleAdmin, channeladmin.NewHandler("/channels/", manager))
+		}
+		//在运维服务上提供通道SLO的评估状态
+		if opsSystem != nil && sloMonitor != nil {
+			opsSystem.RegisterHandlerWithRole("/slo", operations.RoleMetrics, sloMonitor)
+		}
+		//在运维服务上提供消息在各排序阶段的追踪
+		if opsSystem != nil && tracer != nil {
+			opsSystem.RegisterHandlerWithRole("/tracing", operations.RoleMetrics, tracer)
+		}
+		//在运维服务上提供各组织在各通道的用量报告
+		if opsSystem != nil && meter != nil {
+			opsSystem.RegisterHandlerWithRole("/accounting", operations.RoleAdmin, meter)
+		}
+		//在运维服务上提供各客户端与身份的可疑消息评分，以及解除封禁
+		if opsSystem != nil && misbehaviorDetector != nil {
+			opsSystem.RegisterHandlerWithRole("/misbehavior", operations.RoleAdmin, misbehaviorDetector)
+		}
+		//在运维服务上提供Prometheus指标，备用模式和恢复模式下已经提供
+		if opsSystem != nil && !standbyMode && !restoreMode {
+			opsSystem.RegisterHandlerWithRole("/metrics", operations.RoleMetrics, promhttp.HandlerFor(metricLabels.Gatherer(registry), promhttp.HandlerOpts{}))
+		}
+		//在运维服务上提供过滤插件的重新加载（插件可能随规则链的重新加载而变化），
+		//以及各通道生效的消息过滤规则链的查询与重新加载
+		if opsSystem != nil {
+			opsSystem.RegisterHandlerWithRole("/filterplugins/reload", operations.RoleAdmin, http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				ruleChain.Plugins().ReloadHandler().ServeHTTP(w, req)
+			}))
+			opsSystem.RegisterHandlerWithRole("/msgprocessor/rules", operations.RoleAdmin, ruleChain.Handler(reloadRuleChain))
+		}
+		//在运维服务上提供演练用的过载模拟
+		if opsSystem != nil && overloadSim != nil {
+			opsSystem.RegisterHandlerWithRole("/gameday/overload", operations.RoleAdmin, overloadSim)
+		}
+		//在运维服务上提供交易大小的分析报告
+		if opsSystem != nil {
+			if analyzer := initializeTxSizeAnalyzer(conf, manager); analyzer != nil {
+				opsSystem.RegisterHandlerWithRole("/analytics/txsize", operations.RoleMetrics, analyzer)
+			}
+		}
+		//在运维服务上提供按模块与通道动态调整日志级别及调试日志采样
+		if opsSystem != nil {
+			opsSystem.RegisterHandlerWithRole("/logspec", operations.RoleAdmin, httpadmin.NewSpecHandler())
+			opsSystem.RegisterHandlerWithRole("/logspec/sampling", operations.RoleAdmin, httpadmin.NewSamplingHandler())
+		}
+		//在运维服务上提供后台运行的耗时管理操作的查询与取消，以及通道账本的在线备份
+		if opsSystem != nil {
+			asyncOps := asyncop.NewManager("/operations/", conf.Operations.AsyncOps.Retention)
+			opsSystem.RegisterHandlerWithRole("/operations/", operations.RoleAdmin, asyncOps)
+			if backups := initializeBackups(conf, manager, asyncOps); backups != nil {
+				opsSystem.RegisterHandlerWithRole("/backup", operations.RoleAdmin, backups)
+				opsSystem.RegisterHandlerWithRole("/snapshot", operations.RoleAdmin, backups.SnapshotHandler())
+			}
+		}
+		//收到SIGHUP或在运维服务上被请求时热加载可在运行中变更的配置
+		initializeHotReload(conf, opsSystem, limiter, metricLabels)
+		//在维护窗口内运行维护操作，并在运维服务上提供其状态
+		initializeMaintenance(conf, opsSystem, manager, auditLog)
+		//周期性提交探测交易，度量端到端的入队与提交延迟
+		initializeCanary(conf, manager, signer, commits, registry)
+		//在各租户的监听器上提供其通道的Orderer排序服务
+		for _, tenantServer := range initializeTenantServers(conf, serverConfig, manager, newServer) {
+			go func(tenantServer *comm.GRPCServer) {
+				if err := tenantServer.Start(); err != nil {
+					logger.Errorf("Tenant server on %s stopped: %s", tenantServer.Address(), err)
+				}
+			}(tenantServer)
+		}
+		//在独立的监听器上为gRPC-Web客户端提供Orderer排序服务
+		initializeGRPCWebServer(conf, serverConfig, server)
 		//将Orderer排序服务器注册到grpc服务器上
 		ab.RegisterAtomicBroadcastServer(grpcServer.Server(), server)
 		logger.Info("Beginning to serve requests")
@@ -148,10 +430,104 @@ func Start(cmd string, conf *localconfig.TopLevel) {
 	}
 }
 
-// Set the logging level
+// Replicate the blocks of the active orderer as a standby until promoted
+func runStandby(conf *localconfig.TopLevel, secOpts *comm.SecureOptions, signer crypto.LocalSigner, opsSystem *operations.System, versionSkew *versionskew.Negotiator) {
+	if conf.General.LedgerType == "ram" {
+		logger.Fatal("Failed to start standby: the ram ledger does not persist the replicated blocks")
+	}
+	if conf.General.Standby.Source == "" {
+		logger.Fatal("Failed to start standby: General.Standby.Source unset")
+	}
+
+	lf, _ := createLedgerFactory(conf)
+	if len(lf.ChainIDs()) == 0 {
+		initializeBootstrapChannel(conf, lf)
+	}
+
+	client, err := newReplicationClient(secOpts)
+	if err != nil {
+		logger.Fatal("Failed to create standby client:", err)
+	}
+
+	replicator, err := standby.NewReplicator(standby.Config{
+		PollInterval: conf.General.Standby.PollInterval,
+		PromoteAfter: conf.General.Standby.PromoteAfter,
+	}, standby.NewDeliverSource(client, conf.General.Standby.Source, signer, versionSkew), lf)
+	if err != nil {
+		logger.Fatal("Failed to create standby replicator:", err)
+	}
+	if opsSystem != nil {
+		opsSystem.RegisterHandlerWithRole("/standby", operations.RoleMetrics, replicator)
+		opsSystem.RegisterHandlerWithRole("/standby/promote", operations.RoleAdmin, replicator.PromoteHandler())
+	}
+
+	logger.Infof("Standby mode enabled, replicating the blocks of %s", conf.General.Standby.Source)
+	replicator.Run()
+	// 多通道注册管理器将重新打开账本
+	lf.Close()
+}
+
+// Verify the ledgers restored from backups and catch them up with the other
+// orderers of the cluster before consenting
+func runRestore(conf *localconfig.TopLevel, secOpts *comm.SecureOptions, signer crypto.LocalSigner, opsSystem *operations.System, versionSkew *versionskew.Negotiator) {
+	if conf.General.LedgerType == "ram" {
+		logger.Fatal("Failed to start restore: the ram ledger does not persist the restored blocks")
+	}
+	if len(conf.General.Restore.Peers) == 0 {
+		logger.Fatal("Failed to start restore: General.Restore.Peers unset")
+	}
+
+	lf, _ := createLedgerFactory(conf)
+	client, err := newReplicationClient(secOpts)
+	if err != nil {
+		logger.Fatal("Failed to create restore client:", err)
+	}
+	peers := make(map[string]restore.Peer, len(conf.General.Restore.Peers))
+	for _, address := range conf.General.Restore.Peers {
+		peers[address] = standby.NewDeliverSource(client, address, signer, versionSkew)
+	}
+
+	fence, err := restore.New(restore.Config{PollInterval: conf.General.Restore.PollInterval}, lf, peers)
+	if err != nil {
+		logger.Fatal("Failed to start restore:", err)
+	}
+	if opsSystem != nil {
+		opsSystem.RegisterHandlerWithRole("/restore", operations.RoleMetrics, fence)
+	}
+
+	fence.Run()
+	// 多通道注册管理器将重新打开账本
+	lf.Close()
+}
+
+// Create the client connecting to other orderers, to replicate their blocks or
+// forward broadcasts to them, with the server TLS certificate
+func newReplicationClient(secOpts *comm.SecureOptions) (*comm.GRPCClient, error) {
+	clientSecOpts := &comm.SecureOptions{}
+	if secOpts != nil && secOpts.UseTLS {
+		clientSecOpts = &comm.SecureOptions{
+			UseTLS:            true,
+			RequireClientCert: true,
+			Certificate:       secOpts.Certificate,
+			Key:               secOpts.Key,
+			ServerRootCAs:     secOpts.ServerRootCAs,
+		}
+	}
+	return comm.NewGRPCClient(comm.ClientConfig{
+		SecOpts: clientSecOpts,
+		Timeout: standbyDialTimeout,
+	})
+}
+
+// Set the logging level, and the sampling of debug messages
 func initializeLoggingLevel(conf *localconfig.TopLevel) {
 	flogging.InitBackend(flogging.SetFormat(conf.General.LogFormat), os.Stderr)
 	flogging.InitFromSpec(conf.General.LogLevel)
+	for _, sampling := range conf.General.LogSampling {
+		if err := flogging.SetSampling(sampling.Module, sampling.Initial, sampling.Thereafter, sampling.Tick); err != nil {
+			logger.Warningf("Ignoring sampling of modules '%s': %s", sampling.Module, err)
+		}
+	}
 }
 
 // Start the profiling service if enabled.
@@ -165,6 +541,864 @@ func initializeProfilingService(conf *localconfig.TopLevel) {
 	}
 }
 
+// Create the broadcast admission controller if latency-budget admission control is enabled
+func initializeAdmissionController(conf *localconfig.TopLevel) broadcast.AdmissionController {
+	if !conf.General.Admission.Enabled {
+		return nil
+	}
+	controller, err := admission.NewController(admission.Config{
+		LatencySLO:       conf.General.Admission.LatencySLO,
+		Window:           conf.General.Admission.Window,
+		CriticalChannels: conf.General.Admission.CriticalChannels,
+	})
+	if err != nil {
+		logger.Fatal("Failed to create admission controller:", err)
+	}
+	logger.Infof("Admission control enabled with a p99 enqueue latency SLO of %s", conf.General.Admission.LatencySLO)
+	return controller
+}
+
+// Create the overload simulator if game day exercises are enabled
+func initializeOverloadSimulator(conf *localconfig.TopLevel) *gameday.Simulator {
+	if !conf.General.GameDay.Enabled {
+		return nil
+	}
+	logger.Warningf("Game day enabled, overload may be simulated on any channel for up to %s", conf.General.GameDay.MaxDuration)
+	return gameday.NewSimulator(conf.General.GameDay.MaxDuration)
+}
+
+// Create the pool of the backlogs of the consenters, nil if disabled
+func initializeBacklogPool(conf *localconfig.TopLevel) *backlog.Pool {
+	if conf.General.Backlog.MaxMemory == 0 {
+		return nil
+	}
+	pool, err := backlog.NewPool(backlog.Config{
+		MaxMemory:    int64(conf.General.Backlog.MaxMemory),
+		SpillDir:     conf.General.Backlog.SpillDir,
+		MaxSpillSize: int64(conf.General.Backlog.MaxSpillSize),
+		MaxPriority:  conf.General.Backlog.MaxPriority,
+	})
+	if err != nil {
+		logger.Fatal("Failed to create consenter backlogs:", err)
+	}
+	return pool
+}
+
+// Wrap the solo consenter to inject the faults configured, if enabled
+func initializeFaultInjection(conf *localconfig.TopLevel, inner consensus.Consenter) consensus.Consenter {
+	fi := conf.Debug.FaultInjection
+	if !fi.Enabled {
+		return inner
+	}
+	logger.Warningf("Fault injection enabled, the solo consenter delays, drops, reorders and rejects messages")
+	return faulty.New(inner, faulty.Faults{
+		Delay:    fi.Delay,
+		NotReady: faulty.Pattern{Positions: fi.NotReady.Positions, Every: fi.NotReady.Every},
+		Drop:     faulty.Pattern{Positions: fi.Drop.Positions, Every: fi.Drop.Every},
+		Reorder:  fi.Reorder,
+	})
+}
+
+// Load the admission plugins checking the normal messages before they are ordered
+func initializeAdmissionPlugins(conf *localconfig.TopLevel) broadcast.AdmissionPlugin {
+	var configs []admissionplugin.Config
+	for _, ap := range conf.General.AdmissionPlugins {
+		configs = append(configs, admissionplugin.Config{
+			Name:       ap.Name,
+			Library:    ap.Library,
+			Channels:   ap.Channels,
+			Parameters: ap.Parameters,
+		})
+	}
+	chain, err := admissionplugin.Load(configs)
+	if err != nil {
+		logger.Fatal("Failed to load admission plugins:", err)
+	}
+	if chain == nil {
+		return nil
+	}
+	return chain
+}
+
+// Create the broadcast duplicate cache if deduplication is enabled
+func initializeDuplicateCache(conf *localconfig.TopLevel) broadcast.DuplicateDetector {
+	if !conf.General.Deduplication.Enabled {
+		return nil
+	}
+	cache, err := dedup.NewCache(conf.General.Deduplication.CacheSize, conf.General.Deduplication.TTL)
+	if err != nil {
+		logger.Fatal("Failed to create duplicate cache:", err)
+	}
+	logger.Infof("Deduplication enabled for the last %d transactions of each channel, remembered for %s", conf.General.Deduplication.CacheSize, conf.General.Deduplication.TTL)
+	return cache
+}
+
+// Create the tracker of the nonces of each creator if replay protection is enabled
+func initializeNonceTracker(conf *localconfig.TopLevel) *msgprocessor.NonceTracker {
+	if !conf.General.ReplayProtection.Enabled {
+		return nil
+	}
+	if len(conf.General.ReplayProtection.Channels) == 0 {
+		logger.Info("Replay protection enabled, the nonces of each creator must increase on every channel")
+	} else {
+		logger.Infof("Replay protection enabled, the nonces of each creator must increase on channels %v", conf.General.ReplayProtection.Channels)
+	}
+	return msgprocessor.NewNonceTracker(conf.General.ReplayProtection.Channels)
+}
+
+// Create the anonymizer of the client identities if privacy mode is enabled
+func initializeAnonymizer(conf *localconfig.TopLevel) *privacy.Anonymizer {
+	if conf.General.Privacy.Mode == "" {
+		return nil
+	}
+	key, err := ioutil.ReadFile(conf.General.Privacy.KeyFile)
+	if err != nil {
+		logger.Fatal("Failed to read anonymization key:", err)
+	}
+	anonymizer, err := privacy.New(privacy.Config{
+		Mode:       conf.General.Privacy.Mode,
+		Key:        bytes.TrimSpace(key),
+		HashLength: conf.General.Privacy.HashLength,
+	})
+	if err != nil {
+		logger.Fatal("Failed to create anonymizer:", err)
+	}
+	logger.Infof("Privacy mode %s enabled for the client identities in the logs, metrics and audit log", conf.General.Privacy.Mode)
+	return anonymizer
+}
+
+// Create the tracer of the messages ordered if tracing is enabled
+func initializeTracer(conf *localconfig.TopLevel) *tracing.Tracer {
+	if !conf.General.Tracing.Enabled {
+		return nil
+	}
+	tracer, err := tracing.New(tracing.Config{
+		SampleRatio: conf.General.Tracing.SampleRatio,
+		Capacity:    conf.General.Tracing.Capacity,
+	})
+	if err != nil {
+		logger.Fatal("Failed to create tracer:", err)
+	}
+	logger.Infof("Tracing enabled for the messages broadcast, sampling %g of those not traced by their client", conf.General.Tracing.SampleRatio)
+	return tracer
+}
+
+// Create the usage meter if accounting is enabled
+func initializeAccountingMeter(conf *localconfig.TopLevel, anonymizer *privacy.Anonymizer) *accounting.Meter {
+	if !conf.General.Accounting.Enabled {
+		return nil
+	}
+	logger.Info("Accounting enabled for the messages submitted and the blocks delivered per organization")
+	return accounting.NewMeter(anonymizer)
+}
+
+// Create the maximum message sizes of the broadcast service if any is set
+func initializeSizeLimits(conf *localconfig.TopLevel) *broadcast.SizeLimits {
+	if conf.General.Broadcast.MaxMessageSize == 0 && len(conf.General.Broadcast.Channels) == 0 {
+		return nil
+	}
+	limits := &broadcast.SizeLimits{
+		MaxMessageSize: conf.General.Broadcast.MaxMessageSize,
+		Channels:       map[string]uint32{},
+	}
+	for _, c := range conf.General.Broadcast.Channels {
+		limits.Channels[c.Channel] = c.MaxMessageSize
+	}
+	logger.Infof("Broadcast messages limited to %d bytes, with %d channel overrides", limits.MaxMessageSize, len(limits.Channels))
+	return limits
+}
+
+// Create the negotiator of the versions of the orderers connecting to each
+// other, registering its metrics in the registry
+func initializeVersionSkew(conf *localconfig.TopLevel, registry prometheus.Registerer) *versionskew.Negotiator {
+	negotiator, err := versionskew.NewNegotiator(versionskew.Config{
+		Policy:       conf.General.VersionSkew.Policy,
+		MaxMinorSkew: conf.General.VersionSkew.MaxMinorSkew,
+	}, versionskew.Local(), registry)
+	if err != nil {
+		logger.Fatal("Failed to create version skew negotiator:", err)
+	}
+	return negotiator
+}
+
+// Create the limits of the broadcast streams of each client if any is set
+func initializeStreamLimits(conf *localconfig.TopLevel) *broadcast.StreamLimits {
+	if conf.General.Broadcast.MaxStreamsPerClient == 0 && conf.General.Broadcast.IdleTimeout == 0 {
+		return nil
+	}
+	logger.Infof("Broadcast streams limited to %d per client, closed once idle for %s", conf.General.Broadcast.MaxStreamsPerClient, conf.General.Broadcast.IdleTimeout)
+	return broadcast.NewStreamLimits(conf.General.Broadcast.MaxStreamsPerClient, conf.General.Broadcast.IdleTimeout)
+}
+
+// Create the quota of the broadcast and deliver streams of each identity on
+// each channel if any limit is set
+func initializeStreamQuota(conf *localconfig.TopLevel, registry prometheus.Registerer) *streamquota.Quota {
+	quotaConf := streamquota.Config{
+		MaxBroadcastStreams: conf.General.StreamQuota.MaxBroadcastStreams,
+		MaxDeliverStreams:   conf.General.StreamQuota.MaxDeliverStreams,
+	}
+	for _, cq := range conf.General.StreamQuota.Channels {
+		quotaConf.Channels = append(quotaConf.Channels, streamquota.ChannelLimit{Channel: cq.Channel, MaxBroadcastStreams: cq.MaxBroadcastStreams, MaxDeliverStreams: cq.MaxDeliverStreams})
+	}
+	if !quotaConf.Enabled() {
+		return nil
+	}
+	quota, err := streamquota.New(quotaConf, registry)
+	if err != nil {
+		logger.Fatal("Failed to create stream quota:", err)
+	}
+	logger.Infof("Streams limited to %d broadcast and %d deliver streams per identity on each channel, with %d channel overrides",
+		quotaConf.MaxBroadcastStreams, quotaConf.MaxDeliverStreams, len(quotaConf.Channels))
+	return quota
+}
+
+// Create the scheduler of the messages passed to the consenters across the
+// channels if fair queuing is enabled
+func initializeIngressScheduler(conf *localconfig.TopLevel) broadcast.IngressScheduler {
+	if !conf.General.FairQueue.Enabled {
+		return nil
+	}
+	logger.Infof("Fair queuing of the channels enabled with %d messages passed to the consenters at once", conf.General.FairQueue.Concurrency)
+	return fairqueue.New(fairqueue.Config{
+		Concurrency: conf.General.FairQueue.Concurrency,
+		QueueDepth:  conf.General.FairQueue.QueueDepth,
+	})
+}
+
+// Create the detector scoring the suspicious messages of the clients and
+// identities if misbehavior detection is enabled
+func initializeMisbehaviorDetector(conf *localconfig.TopLevel) *misbehavior.Detector {
+	if !conf.General.Misbehavior.Enabled {
+		return nil
+	}
+	mc := conf.General.Misbehavior
+	if mc.BlockThreshold > 0 {
+		logger.Infof("Misbehavior detection enabled, blocking the clients and identities scoring %g for %s", mc.BlockThreshold, mc.BlockDuration)
+	} else {
+		logger.Info("Misbehavior detection enabled, without blocking")
+	}
+	return misbehavior.New(misbehavior.Config{
+		Weights: map[misbehavior.Pattern]float64{
+			misbehavior.InvalidSignature: mc.Weights.InvalidSignature,
+			misbehavior.Replay:           mc.Weights.Replay,
+			misbehavior.MalformedMessage: mc.Weights.MalformedMessage,
+			misbehavior.MalformedConfig:  mc.Weights.MalformedConfig,
+		},
+		HalfLife:       mc.HalfLife,
+		BlockThreshold: mc.BlockThreshold,
+		BlockDuration:  mc.BlockDuration,
+	})
+}
+
+// Open the journal of the broadcast messages not yet committed, and replay
+// those pending since the orderer stopped, if the intake journal is enabled
+func initializeIntakeJournal(conf *localconfig.TopLevel, manager *multichannel.Registrar) *intake.Journal {
+	if !conf.General.IntakeJournal.Enabled {
+		return nil
+	}
+	journal, err := intake.Open(conf.General.IntakeJournal.Dir, conf.General.IntakeJournal.Retention)
+	if err != nil {
+		logger.Fatalf("Failed to open the intake journal: %s", err)
+	}
+	logger.Infof("Intake journal enabled in %s, retaining messages for %s", conf.General.IntakeJournal.Dir, conf.General.IntakeJournal.Retention)
+	journal.Start(intakeChannels{Registrar: manager}, manager.BlockFanout())
+	return journal
+}
+
+// Create the backups of the channel ledgers if they are enabled, which
+// requires a file ledger at a known location
+func initializeBackups(conf *localconfig.TopLevel, manager *multichannel.Registrar, asyncOps *asyncop.Manager) *backup.Backups {
+	if !conf.General.Backup.Enabled {
+		return nil
+	}
+	if conf.General.LedgerType != "file" || conf.FileLedger.Location == "" {
+		logger.Warning("Backups enabled but not available: they require a file ledger with FileLedger.Location set")
+		return nil
+	}
+	logger.Infof("Backups enabled in %s", conf.General.Backup.Dir)
+	return backup.New(conf.FileLedger.Location, conf.General.Backup.Dir, backupChannels{Registrar: manager}, asyncOps)
+}
+
+// Start submitting the canary transactions on their channel if the canary is
+// enabled, recording their latencies in the registry
+func initializeCanary(conf *localconfig.TopLevel, manager *multichannel.Registrar, signer crypto.LocalSigner, commits *commitnotify.Notifier, registry prometheus.Registerer) {
+	if !conf.General.Canary.Enabled {
+		return
+	}
+	c, err := canary.New(canary.Config{
+		ChannelID: conf.General.Canary.Channel,
+		Interval:  conf.General.Canary.Interval,
+		Timeout:   conf.General.Canary.Timeout,
+	}, canaryChannels{Registrar: manager}, signer, commits, registry)
+	if err != nil {
+		logger.Fatalf("Failed to initialize the canary: %s", err)
+	}
+	c.Start()
+	logger.Infof("[channel: %s] Canary transactions submitted every %s", conf.General.Canary.Channel, conf.General.Canary.Interval)
+}
+
+// Import the snapshot of a channel into the file ledger, after verifying it
+// against the latest config block of the channel read from the given file
+func importSnapshot(conf *localconfig.TopLevel, dir, configBlockFile string) {
+	if conf.General.LedgerType != "file" || conf.FileLedger.Location == "" {
+		logger.Error("Snapshots can only be imported into a file ledger with FileLedger.Location set")
+		os.Exit(1)
+	}
+	configBlockBytes, err := ioutil.ReadFile(configBlockFile)
+	if err != nil {
+		logger.Error("failed to read config block: ", err)
+		os.Exit(1)
+	}
+	configBlock, err := utils.UnmarshalBlock(configBlockBytes)
+	if err != nil {
+		logger.Error("failed to unmarshal config block: ", err)
+		os.Exit(1)
+	}
+	manifest, err := backup.Import(conf.FileLedger.Location, dir, configBlock)
+	if err != nil {
+		logger.Error("failed to import snapshot: ", err)
+		os.Exit(1)
+	}
+	fmt.Printf("Imported blocks %d to %d of channel %s\n", manifest.First, manifest.Height-1, manifest.Channel)
+}
+
+// The embargoes of the channels whose new blocks are held back from the
+// deliver clients which are not consenters
+func initializeEmbargoes(conf *localconfig.TopLevel) []embargo.Channel {
+	var embargoes []embargo.Channel
+	for _, c := range conf.General.Embargoes {
+		logger.Infof("[channel: %s] Blocks embargoed for %s and %d blocks from the clients which are not consenters", c.Channel, c.Delay, c.Blocks)
+		embargoes = append(embargoes, embargo.Channel{
+			ChannelID: c.Channel,
+			Delay:     c.Delay,
+			Blocks:    c.Blocks,
+		})
+	}
+	return embargoes
+}
+
+// The shortest interval of the heartbeats sent to the clients of broadcast
+// and deliver streams asking for them, 0 if heartbeats are disabled
+func heartbeatMinInterval(conf *localconfig.TopLevel) time.Duration {
+	if !conf.General.Heartbeat.Enabled {
+		return 0
+	}
+	logger.Infof("Heartbeats sent to the clients of broadcast and deliver streams asking for them, at most every %s", conf.General.Heartbeat.MinInterval)
+	return conf.General.Heartbeat.MinInterval
+}
+
+// Create the metrics of the broadcast service in the registry
+func initializeBroadcastMetrics(registry prometheus.Registerer) *broadcast.Metrics {
+	metrics, err := broadcast.NewMetrics(registry)
+	if err != nil {
+		logger.Fatal("Failed to register broadcast metrics:", err)
+	}
+	return metrics
+}
+
+// Create the reporter of the panics recovered in the service handlers
+func initializeCrashReporter(registry prometheus.Registerer) *crash.Reporter {
+	crashes, err := crash.NewReporter(registry)
+	if err != nil {
+		logger.Fatal("Failed to register crash metrics:", err)
+	}
+	return crashes
+}
+
+// Open the audit log of the broadcast service if auditing is enabled
+func initializeAuditLog(conf *localconfig.TopLevel, anonymizer *privacy.Anonymizer) broadcast.AuditSink {
+	if !conf.General.Audit.Enabled {
+		return nil
+	}
+	auditLog, err := audit.NewLog(audit.Config{
+		Path:        conf.General.Audit.File,
+		MaxFileSize: int64(conf.General.Audit.MaxFileSize),
+		MaxBackups:  conf.General.Audit.MaxBackups,
+		Anonymizer:  anonymizer,
+	})
+	if err != nil {
+		logger.Fatal("Failed to open audit log:", err)
+	}
+	logger.Infof("Audit log of broadcast messages enabled at %s", conf.General.Audit.File)
+	return auditLog
+}
+
+// Load the filter plugins and create the rule chain admitting the messages broadcast to the channels
+func initializeRuleChain(conf *localconfig.TopLevel) *msgprocessor.RuleChain {
+	plugins, err := loadFilterPlugins(conf)
+	if err != nil {
+		logger.Fatal("Failed to load filter plugin:", err)
+	}
+	ruleChain, err := msgprocessor.NewRuleChain(conf.General.RuleChain, plugins)
+	if err != nil {
+		logger.Fatal("Invalid rule chain:", err)
+	}
+	gracePeriods := msgprocessor.ExpirationGracePeriods{
+		GracePeriod: conf.General.Expiration.GracePeriod,
+		Channels:    map[string]time.Duration{},
+	}
+	for _, c := range conf.General.Expiration.Channels {
+		gracePeriods.Channels[c.Channel] = c.GracePeriod
+	}
+	if gracePeriods.GracePeriod > 0 || len(gracePeriods.Channels) > 0 {
+		logger.Warningf("Messages signed by expired identities accepted for %s, with %d channel overrides",
+			gracePeriods.GracePeriod, len(gracePeriods.Channels))
+		ruleChain.SetExpirationGracePeriods(gracePeriods)
+	}
+	return ruleChain
+}
+
+// Load the filter plugins admitting the messages broadcast to their channels
+func loadFilterPlugins(conf *localconfig.TopLevel) (msgprocessor.PluginRules, error) {
+	var plugins msgprocessor.PluginRules
+	for _, fp := range conf.General.FilterPlugins {
+		rule, err := msgprocessor.NewWasmRule(msgprocessor.FilterPlugin{
+			Name:           fp.Name,
+			Path:           fp.Path,
+			Channels:       fp.Channels,
+			Fuel:           fp.Fuel,
+			MaxMemoryPages: fp.MaxMemoryPages,
+		})
+		if err != nil {
+			return nil, err
+		}
+		logger.Infof("Loaded filter plugin %s from %s", fp.Name, fp.Path)
+		plugins = append(plugins, rule)
+	}
+	return plugins, nil
+}
+
+// Read the rule chain and the filter plugins again from the orderer configuration,
+// which is reported as an error rather than a panic should it have become invalid
+func reloadRuleChain() (ruleChain []string, plugins msgprocessor.PluginRules, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = errors.Errorf("invalid orderer configuration: %v", r)
+		}
+	}()
+	conf, err := localconfig.Load()
+	if err != nil {
+		return nil, nil, err
+	}
+	plugins, err = loadFilterPlugins(conf)
+	if err != nil {
+		return nil, nil, err
+	}
+	return conf.General.RuleChain, plugins, nil
+}
+
+// Create the broadcast rate limiter if a rate limit is configured
+func initializeRateLimiter(conf *localconfig.TopLevel) broadcast.RateLimiter {
+	rateConf := rateLimitConfig(conf)
+	if !rateConf.Enabled() {
+		return nil
+	}
+	limiter, err := ratelimit.NewLimiter(rateConf)
+	if err != nil {
+		logger.Fatal("Failed to create rate limiter:", err)
+	}
+	logger.Infof("Rate limiting enabled with %v messages per second per channel, %d channel overrides and %v messages per second per client",
+		rateConf.ChannelRate, len(rateConf.Channels), rateConf.ClientRate)
+	return limiter
+}
+
+// The rate limits of the orderer configuration
+func rateLimitConfig(conf *localconfig.TopLevel) ratelimit.Config {
+	rateConf := ratelimit.Config{
+		ChannelRate:  conf.General.RateLimit.ChannelRate,
+		ChannelBurst: conf.General.RateLimit.ChannelBurst,
+		ClientRate:   conf.General.RateLimit.ClientRate,
+		ClientBurst:  conf.General.RateLimit.ClientBurst,
+	}
+	for _, cl := range conf.General.RateLimit.Channels {
+		rateConf.Channels = append(rateConf.Channels, ratelimit.ChannelLimit{Channel: cl.Channel, Rate: cl.Rate, Burst: cl.Burst})
+	}
+	return rateConf
+}
+
+// Create the labels added to the metrics served by the operations server
+func initializeMetricLabels(conf *localconfig.TopLevel) *operations.MetricLabels {
+	metricLabels, err := operations.NewMetricLabels(conf.Operations.MetricsLabels)
+	if err != nil {
+		logger.Fatal("Failed to set the metrics labels:", err)
+	}
+	return metricLabels
+}
+
+// Reload the hot reloadable settings of the orderer configuration when a
+// SIGHUP is received, or when an admin asks for it on the operations server
+func initializeHotReload(conf *localconfig.TopLevel, opsSystem *operations.System, limiter broadcast.RateLimiter, metricLabels *operations.MetricLabels) {
+	reloader := hotreload.NewReloader(conf, localconfig.Load,
+		hotreload.Setting{Name: "General.LogLevel", Apply: func(current, updated *localconfig.TopLevel) error {
+			//新配置未设置级别的模块保留其当前级别
+			flogging.InitFromSpec(updated.General.LogLevel)
+			return nil
+		}},
+		hotreload.Setting{Name: "General.LogSampling", Apply: func(current, updated *localconfig.TopLevel) error {
+			sampled := map[string]bool{}
+			for _, sampling := range updated.General.LogSampling {
+				if err := flogging.SetSampling(sampling.Module, sampling.Initial, sampling.Thereafter, sampling.Tick); err != nil {
+					return errors.Wrapf(err, "invalid sampling of modules '%s'", sampling.Module)
+				}
+				sampled[sampling.Module] = true
+			}
+			//停止新配置中不再存在的采样
+			for _, sampling := range current.General.LogSampling {
+				if !sampled[sampling.Module] {
+					flogging.SetSampling(sampling.Module, 0, 0, 0)
+				}
+			}
+			return nil
+		}},
+		hotreload.Setting{Name: "General.RateLimit", Apply: func(current, updated *localconfig.TopLevel) error {
+			rateLimiter, ok := limiter.(*ratelimit.Limiter)
+			if !ok {
+				return errors.New("no rate limit was set when the orderer started")
+			}
+			return rateLimiter.Update(rateLimitConfig(updated))
+		}},
+		hotreload.Setting{Name: "Operations.MetricsLabels", Apply: func(current, updated *localconfig.TopLevel) error {
+			return metricLabels.Set(updated.Operations.MetricsLabels)
+		}},
+	)
+	if opsSystem != nil {
+		opsSystem.RegisterHandlerWithRole("/config/reload", operations.RoleAdmin, reloader)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	go reloader.Run(signals)
+}
+
+// Create the pool of workers validating the normal messages, unless they are
+// to be validated on the goroutine processing them
+func initializeVerifierPool(conf *localconfig.TopLevel) *msgprocessor.VerifierPool {
+	workers := conf.General.Broadcast.VerifyWorkers
+	if workers < 0 {
+		return nil
+	}
+	if workers == 0 {
+		workers = runtime.NumCPU()
+	}
+	logger.Infof("Validating normal messages on %d workers", workers)
+	return msgprocessor.NewVerifierPool(workers)
+}
+
+// Create the client forwarding the broadcasts of the channels led by another
+// orderer to the leader, if forwarding is enabled
+func initializeForwarder(conf *localconfig.TopLevel, secOpts *comm.SecureOptions) *forward.Client {
+	if !conf.General.Broadcast.ForwardToLeader {
+		return nil
+	}
+	client, err := newReplicationClient(secOpts)
+	if err != nil {
+		logger.Fatal("Failed to create broadcast forwarding client:", err)
+	}
+	logger.Infof("Forwarding broadcasts to the leaders of the channels, up to %d attempts", conf.General.Broadcast.ForwardAttempts)
+	return forward.NewClient(client)
+}
+
+// Create the malformed envelope collector if a corpus directory is configured
+func initializeMalformedCorpus(conf *localconfig.TopLevel) *corpus.Collector {
+	if conf.Debug.MalformedCorpusDir == "" {
+		return nil
+	}
+	collector, err := corpus.NewCollector(conf.Debug.MalformedCorpusDir, conf.Debug.MalformedCorpusMaxEntries)
+	if err != nil {
+		logger.Fatal("Failed to create malformed envelope corpus:", err)
+	}
+	logger.Infof("Collecting up to %d malformed envelopes in %s", conf.Debug.MalformedCorpusMaxEntries, conf.Debug.MalformedCorpusDir)
+	return collector
+}
+
+// Apply the garbage collector and heap ballast settings
+func initializeMemoryTuning(conf *localconfig.TopLevel) {
+	err := memtuning.Apply(memtuning.Config{
+		GOGC:    conf.General.MemoryTuning.GOGC,
+		Ballast: conf.General.MemoryTuning.Ballast,
+	})
+	if err != nil {
+		logger.Fatal("Failed to apply memory tuning:", err)
+	}
+}
+
+// Start the operations server if a listen address is configured
+func initializeOperationsSystem(conf *localconfig.TopLevel, crashes *crash.Reporter) *operations.System {
+	if conf.Operations.ListenAddress == "" {
+		return nil
+	}
+	system := operations.NewSystem(operations.Options{
+		ListenAddress: conf.Operations.ListenAddress,
+		TLS: operations.TLS{
+			Enabled:            conf.Operations.TLS.Enabled,
+			CertFile:           conf.Operations.TLS.Certificate,
+			KeyFile:            conf.Operations.TLS.PrivateKey,
+			ClientCertRequired: conf.Operations.TLS.ClientAuthRequired,
+			ClientCACertFiles:  conf.Operations.TLS.ClientRootCAs,
+		},
+		Auth:    operationsAuth(conf.Operations.Authentication),
+		Crashes: crashes,
+	})
+	system.RegisterHandlerWithRole("/runtime/memory", operations.RoleMetrics, memtuning.Handler())
+	if err := system.Start(); err != nil {
+		logger.Fatal("Failed to start operations server:", err)
+	}
+	return system
+}
+
+// operationsAuth maps the configured credentials to the roles they grant, a
+// credential set for both roles granting the admin one
+func operationsAuth(conf localconfig.OperationsAuthentication) operations.Auth {
+	auth := operations.Auth{
+		ClientOURoles: map[string]operations.Role{},
+		TokenRoles:    map[string]operations.Role{},
+	}
+	for _, creds := range []struct {
+		localconfig.OperationsCredentials
+		role operations.Role
+	}{
+		{conf.Metrics, operations.RoleMetrics},
+		{conf.Admin, operations.RoleAdmin},
+	} {
+		for _, ou := range creds.ClientOUs {
+			auth.ClientOURoles[ou] = creds.role
+		}
+		for _, token := range creds.Tokens {
+			auth.TokenRoles[token] = creds.role
+		}
+	}
+	return auth
+}
+
+// Serve the attestation of the orderer's identity on the operations server, if enabled
+func initializeAttestation(conf *localconfig.TopLevel, opsSystem *operations.System, signer crypto.LocalSigner, manager *multichannel.Registrar) {
+	if opsSystem == nil {
+		return
+	}
+	identity, err := attestationIdentity(conf, signer)
+	if err != nil {
+		logger.Fatal("Failed to initialize attestation:", err)
+	}
+	capabilities := func() attestation.Capabilities {
+		chain, ok := manager.GetChain(manager.SystemChannelID())
+		if !ok {
+			return attestation.Capabilities{}
+		}
+		return attestation.CapabilitiesFromConfig(chain.ConfigtxValidator().ConfigProto())
+	}
+	opsSystem.RegisterHandler("/attestation", attestation.NewHandler(identity, capabilities, signer))
+}
+
+// Serve the report of the drift between the runtime state and the channel configs on the operations server
+func initializeConsistencyCheck(conf *localconfig.TopLevel, opsSystem *operations.System, signer crypto.LocalSigner, manager *multichannel.Registrar) {
+	if opsSystem == nil {
+		return
+	}
+	sigHdr, err := signer.NewSignatureHeader()
+	if err != nil {
+		logger.Fatal("Failed to initialize consistency check:", err)
+	}
+	runtime := consistency.Runtime{
+		LocalMSPID:    conf.General.LocalMSPID,
+		Identity:      sigHdr.Creator,
+		ListenAddress: net.JoinHostPort(conf.General.ListenAddress, strconv.Itoa(int(conf.General.ListenPort))),
+		Consenters:    manager.ConsenterTypes(),
+	}
+	channels := func() []consistency.Channel {
+		var channels []consistency.Channel
+		for _, channelID := range manager.ChannelIDs() {
+			chain, ok := manager.GetChain(channelID)
+			if !ok {
+				continue
+			}
+			channels = append(channels, consistency.Channel{
+				Resources:     chain,
+				ConsensusType: chain.ConsensusType(),
+			})
+		}
+		return channels
+	}
+	opsSystem.RegisterHandlerWithRole("/consistency", operations.RoleMetrics, consistency.NewHandler(runtime, channels))
+}
+
+// Create the monitor of the channel SLOs if any is declared, and start evaluating them
+func initializeSLOMonitor(conf *localconfig.TopLevel, manager *multichannel.Registrar) *slo.Monitor {
+	if len(conf.General.SLO.Channels) == 0 {
+		return nil
+	}
+	var objectives []slo.Objective
+	for _, c := range conf.General.SLO.Channels {
+		objectives = append(objectives, slo.Objective{
+			Channel:           c.Channel,
+			MaxBlockInterval:  c.MaxBlockInterval,
+			MaxEnqueueLatency: c.MaxEnqueueLatency,
+		})
+	}
+	monitor, err := slo.NewMonitor(slo.Config{
+		EvaluationInterval: conf.General.SLO.EvaluationInterval,
+		Objectives:         objectives,
+	})
+	if err != nil {
+		logger.Fatal("Failed to initialize SLO monitor:", err)
+	}
+
+	blocks := manager.BlockFanout().Subscribe(fanout.AllChannels, sloBlockBuffer)
+	go func() {
+		for block := range blocks.Blocks() {
+			if channelID, err := utils.GetChainIDFromBlock(block); err == nil {
+				monitor.BlockCut(channelID)
+			}
+		}
+		logger.Warningf("SLO monitor stopped observing blocks: %s", blocks.Err())
+	}()
+
+	monitor.Start()
+	return monitor
+}
+
+// Create the notifier of the commits awaited by broadcast clients, and feed it
+// the committed blocks
+func initializeCommitNotifier(conf *localconfig.TopLevel, manager *multichannel.Registrar) *commitnotify.Notifier {
+	notifier := commitnotify.New(conf.General.Broadcast.MaxCommitWaiters)
+	notifier.Follow(manager.BlockFanout())
+	return notifier
+}
+
+// Create the analyzer of the transaction sizes if the report is enabled, and
+// feed it the committed blocks
+func initializeTxSizeAnalyzer(conf *localconfig.TopLevel, manager *multichannel.Registrar) *analytics.TxSizeAnalyzer {
+	if !conf.General.Analytics.TxSizeReport {
+		return nil
+	}
+	analyzer := analytics.NewTxSizeAnalyzer()
+	blocks := manager.BlockFanout().Subscribe(fanout.AllChannels, analyticsBlockBuffer)
+	go func() {
+		for block := range blocks.Blocks() {
+			analyzer.AnalyzeBlock(block)
+		}
+		logger.Warningf("Transaction size analyzer stopped observing blocks: %s", blocks.Err())
+	}()
+	logger.Info("Transaction size report enabled")
+	return analyzer
+}
+
+// Schedule the maintenance operations declared to run during the maintenance
+// windows, and serve the state of their tasks on the operations server
+func initializeMaintenance(conf *localconfig.TopLevel, opsSystem *operations.System, manager *multichannel.Registrar, auditLog broadcast.AuditSink) {
+	if len(conf.General.Maintenance.Tasks) == 0 {
+		return
+	}
+	ops := map[string]maintenance.Operation{}
+	if l, ok := auditLog.(*audit.Log); ok {
+		ops["rotate-audit-log"] = func(context.Context) error { return l.Rotate() }
+	}
+	if c, ok := manager.LedgerFactory().(interface{ CompactIndex() error }); ok {
+		ops["compact-block-index"] = func(context.Context) error { return c.CompactIndex() }
+	}
+	var windows []maintenance.Window
+	for _, w := range conf.General.Maintenance.Windows {
+		windows = append(windows, maintenance.Window{Days: w.Days, Start: w.Start, Duration: w.Duration})
+	}
+	var tasks []maintenance.Task
+	for _, t := range conf.General.Maintenance.Tasks {
+		tasks = append(tasks, maintenance.Task{Operation: t.Operation, Interval: t.Interval})
+	}
+	scheduler, err := maintenance.NewScheduler(maintenance.Config{
+		Location:      conf.General.Maintenance.Location,
+		CheckInterval: conf.General.Maintenance.CheckInterval,
+		MaxConcurrent: conf.General.Maintenance.MaxConcurrent,
+		Windows:       windows,
+		Tasks:         tasks,
+	}, ops)
+	if err != nil {
+		logger.Fatal("Failed to initialize maintenance scheduler:", err)
+	}
+	scheduler.Start()
+	logger.Infof("Maintenance scheduler started with %d tasks in %d windows", len(tasks), len(windows))
+	if opsSystem != nil {
+		opsSystem.RegisterHandlerWithRole("/maintenance", operations.RoleMetrics, scheduler)
+	}
+}
+
+// Record the recent history of internal metrics and serve it on the operations server
+func initializeFlightRecorder(conf *localconfig.TopLevel, opsSystem *operations.System, manager *multichannel.Registrar) {
+	if opsSystem == nil {
+		return
+	}
+	recorder, err := flightrecorder.NewRecorder(conf.Operations.FlightRecorder.SampleInterval, conf.Operations.FlightRecorder.Retention)
+	if err != nil {
+		logger.Fatal("Failed to initialize flight recorder:", err)
+	}
+	blockFanout := manager.BlockFanout()
+	recorder.Gauge("fanout.queued_blocks", func() float64 { return float64(blockFanout.Queued()) })
+
+	blocks := blockFanout.Subscribe(fanout.AllChannels, flightRecorderBlockBuffer)
+	go func() {
+		lastBlock := map[string]time.Time{}
+		for block := range blocks.Blocks() {
+			channelID, err := utils.GetChainIDFromBlock(block)
+			if err != nil {
+				continue
+			}
+			now := time.Now()
+			if last, ok := lastBlock[channelID]; ok {
+				recorder.Observe("block_interval_seconds."+channelID, now.Sub(last).Seconds())
+			}
+			lastBlock[channelID] = now
+		}
+		logger.Warningf("Flight recorder stopped observing block intervals: %s", blocks.Err())
+	}()
+
+	recorder.Start()
+	opsSystem.RegisterHandler("/runtime/flightrecorder", recorder)
+}
+
+// attestationIdentity gathers the signing certificate chain and TLS certificate of the orderer
+func attestationIdentity(conf *localconfig.TopLevel, signer crypto.LocalSigner) (attestation.Identity, error) {
+	identity := attestation.Identity{MSPID: conf.General.LocalMSPID}
+
+	sigHdr, err := signer.NewSignatureHeader()
+	if err != nil {
+		return identity, err
+	}
+	creator := &mspprotos.SerializedIdentity{}
+	if err := proto.Unmarshal(sigHdr.Creator, creator); err != nil {
+		return identity, errors.Wrap(err, "failed unmarshaling signing identity")
+	}
+	identity.SigningCertChain = append(identity.SigningCertChain, creator.IdBytes)
+
+	mspConfig, err := msp.GetVerifyingMspConfig(conf.General.LocalMSPDir, conf.General.LocalMSPID, msp.ProviderTypeToString(msp.FABRIC))
+	if err != nil {
+		return identity, errors.Wrap(err, "failed loading local MSP certificates")
+	}
+	fabricMSPConfig := &mspprotos.FabricMSPConfig{}
+	if err := proto.Unmarshal(mspConfig.Config, fabricMSPConfig); err != nil {
+		return identity, errors.Wrap(err, "failed unmarshaling local MSP config")
+	}
+	identity.SigningCertChain = append(identity.SigningCertChain, fabricMSPConfig.IntermediateCerts...)
+	identity.SigningCertChain = append(identity.SigningCertChain, fabricMSPConfig.RootCerts...)
+
+	if conf.General.TLS.Enabled {
+		identity.TLSCert, err = ioutil.ReadFile(conf.General.TLS.Certificate)
+		if err != nil {
+			return identity, errors.Wrap(err, "failed reading TLS certificate")
+		}
+	}
+	return identity, nil
+}
+
+// Create the transaction timeline recorder if enabled, and serve it alongside the profiling service.
+func initializeTxTimeline(conf *localconfig.TopLevel) *txtimeline.Recorder {
+	if conf.Debug.TxTimelineSize <= 0 {
+		return nil
+	}
+	if !conf.General.Profile.Enabled {
+		logger.Warning("Transaction timeline is recorded but will not be served, as the profiling service is disabled")
+	}
+	timeline := txtimeline.NewRecorder(conf.Debug.TxTimelineSize)
+	http.Handle("/debug/txtimeline", timeline)
+	return timeline
+}
+
 func initializeServerConfig(conf *localconfig.TopLevel) comm.ServerConfig {
 	// secure server config
 	//首先利用Orderer配置对象conf初始化TLS安全认证配置选项secureOpts
@@ -228,6 +1462,102 @@ func initializeServerConfig(conf *localconfig.TopLevel) comm.ServerConfig {
 	return comm.ServerConfig{SecOpts: secureOpts, KaOpts: kaOpts}
 }
 
+// Create the servers of the tenants, each serving the channels of its tenant
+// on a listener of its own
+func initializeTenantServers(conf *localconfig.TopLevel, serverConfig comm.ServerConfig, manager *multichannel.Registrar, newServer func(r channelRegistry, mutualTLS bool) ab.AtomicBroadcastServer) []*comm.GRPCServer {
+	var servers []*comm.GRPCServer
+	tenants := map[string]struct{}{}
+	owners := map[string]string{}
+	for _, tc := range conf.General.Tenants {
+		if tc.Name == "" {
+			logger.Fatal("Failed to serve tenant: General.Tenants entry without Name")
+		}
+		if _, ok := tenants[tc.Name]; ok {
+			logger.Fatalf("Failed to serve tenant %s: declared twice", tc.Name)
+		}
+		tenants[tc.Name] = struct{}{}
+		//租户之间的通道不能重叠
+		for _, channelID := range tc.Channels {
+			if owner, ok := owners[channelID]; ok {
+				logger.Fatalf("Failed to serve tenant %s: channel %s already served to tenant %s", tc.Name, channelID, owner)
+			}
+			owners[channelID] = tc.Name
+		}
+
+		secOpts := *serverConfig.SecOpts
+		if len(tc.ClientRootCAs) > 0 {
+			if !secOpts.UseTLS {
+				logger.Fatalf("Failed to serve tenant %s: ClientRootCAs set without TLS", tc.Name)
+			}
+			secOpts.RequireClientCert = true
+			secOpts.ClientRootCAs = nil
+			for _, clientRoot := range tc.ClientRootCAs {
+				root, err := ioutil.ReadFile(clientRoot)
+				if err != nil {
+					logger.Fatalf("Failed to load ClientRootCAs file '%s' of tenant %s (%s)", clientRoot, tc.Name, err)
+				}
+				secOpts.ClientRootCAs = append(secOpts.ClientRootCAs, root)
+			}
+		}
+		tenantConfig := serverConfig
+		tenantConfig.SecOpts = &secOpts
+
+		lis, err := net.Listen("tcp", fmt.Sprintf("%s:%d", tc.ListenAddress, tc.ListenPort))
+		if err != nil {
+			logger.Fatalf("Failed to listen for tenant %s: %s", tc.Name, err)
+		}
+		grpcServer, err := comm.NewGRPCServerFromListener(lis, tenantConfig)
+		if err != nil {
+			logger.Fatalf("Failed to return new GRPC server for tenant %s: %s", tc.Name, err)
+		}
+		ab.RegisterAtomicBroadcastServer(grpcServer.Server(), newServer(manager.Tenant(tc.Name, tc.Channels), secOpts.UseTLS && secOpts.RequireClientCert))
+		logger.Infof("Serving %d channels to tenant %s on %s", len(tc.Channels), tc.Name, grpcServer.Address())
+		servers = append(servers, grpcServer)
+	}
+	return servers
+}
+
+// Serve the orderer to the gRPC-Web clients on a listener of its own if it is
+// enabled, over TLS if the general listener uses TLS
+func initializeGRPCWebServer(conf *localconfig.TopLevel, serverConfig comm.ServerConfig, server ab.AtomicBroadcastServer) {
+	if !conf.General.GRPCWeb.Enabled {
+		return
+	}
+	var tlsConfig *tls.Config
+	if secOpts := serverConfig.SecOpts; secOpts != nil && secOpts.UseTLS {
+		cert, err := tls.X509KeyPair(secOpts.Certificate, secOpts.Key)
+		if err != nil {
+			logger.Fatalf("Failed to load the TLS key pair of the gRPC-Web server: %s", err)
+		}
+		tlsConfig = &tls.Config{
+			MinVersion:   tls.VersionTLS12,
+			Certificates: []tls.Certificate{cert},
+			CipherSuites: secOpts.CipherSuites,
+		}
+		if secOpts.RequireClientCert {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+			tlsConfig.ClientCAs = x509.NewCertPool()
+			for _, root := range secOpts.ClientRootCAs {
+				tlsConfig.ClientCAs.AppendCertsFromPEM(root)
+			}
+		}
+	}
+
+	grpcServer := grpc.NewServer(grpc.MaxSendMsgSize(comm.MaxSendMsgSize), grpc.MaxRecvMsgSize(comm.MaxRecvMsgSize))
+	ab.RegisterAtomicBroadcastServer(grpcServer, server)
+	address := fmt.Sprintf("%s:%d", conf.General.GRPCWeb.ListenAddress, conf.General.GRPCWeb.ListenPort)
+	webServer, err := grpcweb.NewServer(address, tlsConfig, grpcweb.NewHandler(grpcServer, conf.General.GRPCWeb.AllowedOrigins, int64(comm.MaxRecvMsgSize)))
+	if err != nil {
+		logger.Fatalf("Failed to start the gRPC-Web server: %s", err)
+	}
+	go func() {
+		if err := webServer.Start(); err != nil {
+			logger.Errorf("gRPC-Web server on %s stopped: %s", webServer.Address(), err)
+		}
+	}()
+	logger.Infof("Serving gRPC-Web clients on %s", webServer.Address())
+}
+
 //首先创建系统通道的创世区块，初始化系统通道的区块账本对象及其区块数据存储对象，然后将创世区块添加到本地的区块数据文件中
 //其中创世区块包含了系统通道的出事配置信息
 func initializeBootstrapChannel(conf *localconfig.TopLevel, lf blockledger.Factory) {
@@ -297,7 +1627,7 @@ func initializeLocalMsp(conf *localconfig.TopLevel) {
 
 //创建并初始化Orderer节点上的多通道注册管理器对象，用于注册管理Orderer节点上的所有通道（包括系统通道和应用通道）、区块账本、共识组件等资源
 //多通道注册管理器相当于Orderer节点上的“资源管理器”，位每一个通道创建关联的共识组件链对象，负责交易排序、打包处快、提交账本以及通道管理等工作
-func initializeMultichannelRegistrar(conf *localconfig.TopLevel, signer crypto.LocalSigner,
+func initializeMultichannelRegistrar(conf *localconfig.TopLevel, signer crypto.LocalSigner, txTimeline *txtimeline.Recorder, tracer *tracing.Tracer, ruleChain *msgprocessor.RuleChain,
 	callbacks ...func(bundle *channelconfig.Bundle)) *multichannel.Registrar {
 	//创建通道的账本工厂对象lf，根据Orderer的配置信息对象conf参数
 	lf, _ := createLedgerFactory(conf)
@@ -314,12 +1644,48 @@ func initializeMultichannelRegistrar(conf *localconfig.TopLevel, signer crypto.L
 	consenters := make(map[string]consensus.Consenter)
 	//solo类型共识组件
 	//直接返回solo共识组件对象
-	consenters["solo"] = solo.New()
+	consenters["solo"] = initializeFaultInjection(conf, solo.New(initializeBacklogPool(conf)))
 	//kafka类型共识组件
 	consenters["kafka"] = kafka.New(conf.Kafka)
+	//BFT类型共识组件，仅在配置了BFT库时可用
+	if conf.BFT.Name != "" {
+		//批量签名会替换区块中其他排序节点的签名
+		if conf.General.BatchSigning.Enabled {
+			logger.Panicf("The BFT consenter cannot be used along with batch signing, which replaces the signatures of the other orderers")
+		}
+		bftConsenter, err := bft.New(conf.BFT)
+		if err != nil {
+			logger.Panicf("Failed to initialize BFT consenter: %s", err)
+		}
+		consenters["bft"] = bftConsenter
+	}
+
+	//系统通道防护配置
+	protection := msgprocessor.SystemChannelProtection{
+		BroadcastPolicy:           conf.General.SystemChannelProtection.BroadcastPolicy,
+		ChannelCreationsPerMinute: conf.General.SystemChannelProtection.ChannelCreationsPerMinute,
+		ChannelCreationBurst:      conf.General.SystemChannelProtection.ChannelCreationBurst,
+		AlertOnConfigChange:       conf.General.SystemChannelProtection.AlertOnConfigChange,
+	}
+
+	//批量区块签名配置，未启用时逐个区块签名
+	var batchSigning *multichannel.BatchSigning
+	if conf.General.BatchSigning.Enabled {
+		logger.Warningf("Signing blocks in batches of up to %d blocks, clients not using the blocksig package will reject them", conf.General.BatchSigning.MaxBlocks)
+		batchSigning = &multichannel.BatchSigning{
+			MaxBlocks: conf.General.BatchSigning.MaxBlocks,
+			MaxDelay:  conf.General.BatchSigning.MaxDelay,
+		}
+	}
 
 	//创建多通道注册管理器对象
-	return multichannel.NewRegistrar(lf, consenters, signer, callbacks...)
+	return multichannel.NewRegistrar(lf, consenters, signer, multichannel.RegistrarOptions{
+		TxTimeline:   txTimeline,
+		Tracer:       tracer,
+		Protection:   protection,
+		RuleChain:    ruleChain,
+		BatchSigning: batchSigning,
+	}, callbacks...)
 }
 
 func updateTrustedRoots(srv *comm.GRPCServer, rootCASupport *comm.CASupport,
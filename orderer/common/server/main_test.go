@@ -40,6 +40,28 @@ func TestInitializeLoggingLevel(t *testing.T) {
 	assert.Equal(t, flogging.GetModuleLevel("foo"), "DEBUG")
 }
 
+func TestInitializeAdmissionController(t *testing.T) {
+	assert.Nil(t, initializeAdmissionController(&localconfig.TopLevel{}))
+	assert.NotNil(t, initializeAdmissionController(&localconfig.TopLevel{
+		General: localconfig.General{
+			Admission: localconfig.Admission{Enabled: true, LatencySLO: time.Second},
+		},
+	}))
+}
+
+func TestInitializeOperationsSystem(t *testing.T) {
+	assert.Nil(t, initializeOperationsSystem(&localconfig.TopLevel{}, nil))
+
+	system := initializeOperationsSystem(&localconfig.TopLevel{
+		Operations: localconfig.Operations{ListenAddress: "127.0.0.1:0"},
+	}, nil)
+	defer system.Stop()
+	resp, err := http.Get("http://" + system.Addr() + "/runtime/memory")
+	assert.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
 func TestInitializeProfilingService(t *testing.T) {
 	// get a free random port
 	listenAddr := func() string {
@@ -235,10 +257,26 @@ func TestInitializeMultiChainManager(t *testing.T) {
 	conf := genesisConfig(t)
 	assert.NotPanics(t, func() {
 		initializeLocalMsp(conf)
-		initializeMultichannelRegistrar(conf, localmsp.NewSigner())
+		initializeMultichannelRegistrar(conf, localmsp.NewSigner(), nil, nil, nil)
 	})
 }
 
+func TestAttestationIdentity(t *testing.T) {
+	cleanup := configtest.SetDevFabricConfigPath(t)
+	defer cleanup()
+	conf := genesisConfig(t)
+	initializeLocalMsp(conf)
+
+	identity, err := attestationIdentity(conf, localmsp.NewSigner())
+	assert.NoError(t, err)
+	assert.Equal(t, conf.General.LocalMSPID, identity.MSPID)
+	assert.True(t, len(identity.SigningCertChain) >= 2, "chain should hold the signing cert followed by the MSP root")
+	assert.Nil(t, identity.TLSCert)
+
+	// Attestation is served only alongside the operations server
+	assert.NotPanics(t, func() { initializeAttestation(conf, nil, localmsp.NewSigner(), nil) })
+}
+
 func TestInitializeGrpcServer(t *testing.T) {
 	// get a free random port
 	listenAddr := func() string {
@@ -296,7 +334,7 @@ func TestUpdateTrustedRoots(t *testing.T) {
 			updateTrustedRoots(grpcServer, caSupport, bundle)
 		}
 	}
-	initializeMultichannelRegistrar(genesisConfig(t), localmsp.NewSigner(), callback)
+	initializeMultichannelRegistrar(genesisConfig(t), localmsp.NewSigner(), nil, nil, nil, callback)
 	t.Logf("# app CAs: %d", len(caSupport.AppRootCAsByChain[genesisconfig.TestChainID]))
 	t.Logf("# orderer CAs: %d", len(caSupport.OrdererRootCAsByChain[genesisconfig.TestChainID]))
 	// mutual TLS not required so no updates should have occurred
@@ -327,7 +365,7 @@ func TestUpdateTrustedRoots(t *testing.T) {
 			updateTrustedRoots(grpcServer, caSupport, bundle)
 		}
 	}
-	initializeMultichannelRegistrar(genesisConfig(t), localmsp.NewSigner(), callback)
+	initializeMultichannelRegistrar(genesisConfig(t), localmsp.NewSigner(), nil, nil, nil, callback)
 	t.Logf("# app CAs: %d", len(caSupport.AppRootCAsByChain[genesisconfig.TestChainID]))
 	t.Logf("# orderer CAs: %d", len(caSupport.OrdererRootCAsByChain[genesisconfig.TestChainID]))
 	// mutual TLS is required so updates should have occurred
@@ -10,73 +10,478 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
-	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/deliver"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
 	"github.com/hyperledger/fabric/common/policies"
+	"github.com/hyperledger/fabric/common/txtimeline"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/orderer/common/accounting"
+	"github.com/hyperledger/fabric/orderer/common/backup"
 	"github.com/hyperledger/fabric/orderer/common/broadcast"
+	"github.com/hyperledger/fabric/orderer/common/canary"
+	"github.com/hyperledger/fabric/orderer/common/configfeed"
+	"github.com/hyperledger/fabric/orderer/common/corpus"
+	"github.com/hyperledger/fabric/orderer/common/crash"
+	"github.com/hyperledger/fabric/orderer/common/embargo"
+	"github.com/hyperledger/fabric/orderer/common/fanout"
+	"github.com/hyperledger/fabric/orderer/common/gameday"
+	"github.com/hyperledger/fabric/orderer/common/intake"
 	localconfig "github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/hyperledger/fabric/orderer/common/misbehavior"
 	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
 	"github.com/hyperledger/fabric/orderer/common/multichannel"
+	"github.com/hyperledger/fabric/orderer/common/privacy"
+	"github.com/hyperledger/fabric/orderer/common/slo"
+	"github.com/hyperledger/fabric/orderer/common/streamquota"
+	"github.com/hyperledger/fabric/orderer/common/tracing"
+	"github.com/hyperledger/fabric/orderer/common/versionskew"
+	"github.com/hyperledger/fabric/orderer/common/watermark"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	"github.com/hyperledger/fabric/orderer/consensus/forward"
 	cb "github.com/hyperledger/fabric/protos/common"
 	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
 	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
+// channelRegistry is the registrar of the channels served by a server, either the
+// multichannel.Registrar of the orderer or the view of a tenant
+type channelRegistry interface {
+	BroadcastChannelSupport(msg *cb.Envelope) (*cb.ChannelHeader, bool, *multichannel.ChainSupport, error)
+	GetChain(chainID string) (*multichannel.ChainSupport, bool)
+	TxTimeline() *txtimeline.Recorder
+	Tracer() *tracing.Tracer
+	BlockFanout() *fanout.Multicaster
+}
+
 type broadcastSupport struct {
-	*multichannel.Registrar
+	channelRegistry
+	overload *gameday.Simulator
 }
 
 func (bs broadcastSupport) BroadcastChannelSupport(msg *cb.Envelope) (*cb.ChannelHeader, bool, broadcast.ChannelSupport, error) {
-	return bs.Registrar.BroadcastChannelSupport(msg)
+	chdr, isConfig, cs, err := bs.channelRegistry.BroadcastChannelSupport(msg)
+	if err != nil || bs.overload == nil {
+		return chdr, isConfig, cs, err
+	}
+	return chdr, isConfig, overloadedChannel{ChannelSupport: cs, channelID: chdr.ChannelId, overload: bs.overload}, nil
 }
 
-type deliverSupport struct {
+// overloadedChannel applies the overload simulated on the channel before its
+// consenter is asked whether it is ready
+type overloadedChannel struct {
+	broadcast.ChannelSupport
+	channelID string
+	overload  *gameday.Simulator
+}
+
+func (oc overloadedChannel) WaitReadyContext(ctx context.Context) error {
+	if err := oc.overload.WaitReady(oc.channelID); err != nil {
+		return err
+	}
+	return oc.ChannelSupport.WaitReadyContext(ctx)
+}
+
+// channelHeights reports the height of the channels in the broadcast heartbeats
+type channelHeights struct {
+	channelRegistry
+}
+
+func (ch channelHeights) Height(channelID string) (uint64, bool) {
+	cs, ok := ch.channelRegistry.GetChain(channelID)
+	if !ok {
+		return 0, false
+	}
+	return cs.Height(), true
+}
+
+// channelQueues reports the pending bytes of the consenters of the channels
+// for the broadcast backpressure
+type channelQueues struct {
+	channelRegistry
+}
+
+func (cq channelQueues) PendingBytes(channelID string) (int64, bool) {
+	cs, ok := cq.channelRegistry.GetChain(channelID)
+	if !ok {
+		return 0, false
+	}
+	reporter, ok := cs.Chain.(consensus.QueueReporter)
+	if !ok {
+		return 0, false
+	}
+	return reporter.PendingBytes(), true
+}
+
+// channelLeaders reports the leaders of the channels whose consenter elects
+// one, to which the broadcasts are forwarded
+type channelLeaders struct {
+	channelRegistry
+}
+
+func (cl channelLeaders) Leader(channelID string) string {
+	cs, ok := cl.channelRegistry.GetChain(channelID)
+	if !ok {
+		return ""
+	}
+	reporter, ok := cs.Chain.(consensus.LeaderReporter)
+	if !ok {
+		return ""
+	}
+	return reporter.Leader()
+}
+
+// channelOrdererConfigs looks up the orderer config of the channels whose
+// ingress quotas are enforced
+type channelOrdererConfigs struct {
 	*multichannel.Registrar
 }
 
+func (co channelOrdererConfigs) OrdererConfig(channelID string) (channelconfig.Orderer, bool) {
+	cs, ok := co.Registrar.GetChain(channelID)
+	if !ok {
+		return nil, false
+	}
+	return cs.OrdererConfig()
+}
+
+// intakeChannels looks up the channels whose journaled messages are passed
+// again to their consenter
+type intakeChannels struct {
+	*multichannel.Registrar
+}
+
+func (ic intakeChannels) Channel(channelID string) (intake.Channel, bool) {
+	cs, ok := ic.Registrar.GetChain(channelID)
+	if !ok {
+		return nil, false
+	}
+	return cs, true
+}
+
+// backupChannels looks up the channels whose ledger is backed up
+type backupChannels struct {
+	*multichannel.Registrar
+}
+
+func (bc backupChannels) Channel(channelID string) (backup.Channel, bool) {
+	cs, ok := bc.Registrar.GetChain(channelID)
+	if !ok {
+		return nil, false
+	}
+	return backupChannel{ChainSupport: cs}, true
+}
+
+// backupChannel backs up the write-ahead log of the consenter of the channel
+// along with its ledger, if it keeps one
+type backupChannel struct {
+	*multichannel.ChainSupport
+}
+
+func (bc backupChannel) WAL() consensus.WALCopier {
+	copier, _ := bc.Chain.(consensus.WALCopier)
+	return copier
+}
+
+// canaryChannels looks up the channel the canary transactions are ordered on
+type canaryChannels struct {
+	*multichannel.Registrar
+}
+
+func (cc canaryChannels) Channel(channelID string) (canary.Channel, bool) {
+	cs, ok := cc.Registrar.GetChain(channelID)
+	if !ok {
+		return nil, false
+	}
+	return cs, true
+}
+
+type deliverSupport struct {
+	channelRegistry
+}
+
+// GetChain returns the chain whose deliver streams, once at the tip, receive
+// the blocks published to the block fanout rather than waiting on the ledger
 func (ds deliverSupport) GetChain(chainID string) (deliver.Chain, bool) {
-	return ds.Registrar.GetChain(chainID)
+	cs, ok := ds.channelRegistry.GetChain(chainID)
+	if !ok {
+		return nil, false
+	}
+	return deliverChain{ChainSupport: cs, reader: fanout.NewReader(cs, chainID, ds.BlockFanout())}, true
+}
+
+type deliverChain struct {
+	*multichannel.ChainSupport
+	reader blockledger.Reader
+}
+
+func (dc deliverChain) Reader() blockledger.Reader {
+	return dc.reader
+}
+
+type configfeedSupport struct {
+	channelRegistry
+}
+
+func (cs configfeedSupport) GetChain(chainID string) (configfeed.Chain, bool) {
+	return cs.channelRegistry.GetChain(chainID)
+}
+
+type watermarkSupport struct {
+	channelRegistry
+}
+
+func (ws watermarkSupport) GetChain(chainID string) (watermark.Chain, bool) {
+	return ws.channelRegistry.GetChain(chainID)
 }
 
 type server struct {
-	bh    broadcast.Handler
-	dh    *deliver.Handler
-	debug *localconfig.Debug
-	*multichannel.Registrar
+	bh         broadcast.Handler
+	dh         *deliver.Handler
+	ch         *configfeed.Handler
+	wh         *watermark.Handler
+	debug      *localconfig.Debug
+	deliverMAC bool
+	slo        *slo.Monitor
+	meter      *accounting.Meter
+	skew       *versionskew.Negotiator
+	anonymizer *privacy.Anonymizer
+	commitWait bool
+	crashes    *crash.Reporter
+	quota      *streamquota.Quota
+	channelRegistry
 }
 
 type responseSender struct {
 	ab.AtomicBroadcast_DeliverServer
+	mac *deliver.StreamMAC
 }
 
 func (rs *responseSender) SendStatusResponse(status cb.Status) error {
 	reply := &ab.DeliverResponse{
 		Type: &ab.DeliverResponse_Status{Status: status},
 	}
-	return rs.Send(reply)
+	return rs.send(reply)
 }
 
 func (rs *responseSender) SendBlockResponse(block *cb.Block) error {
 	response := &ab.DeliverResponse{
 		Type: &ab.DeliverResponse_Block{Block: block},
 	}
+	return rs.send(response)
+}
+
+func (rs *responseSender) SendHeartbeatResponse(heartbeat *ab.Heartbeat) error {
+	response := &ab.DeliverResponse{
+		Type: &ab.DeliverResponse_Heartbeat{Heartbeat: heartbeat},
+	}
+	return rs.send(response)
+}
+
+func (rs *responseSender) send(response *ab.DeliverResponse) error {
+	if rs.mac != nil {
+		if err := rs.mac.Seal(response); err != nil {
+			return err
+		}
+	}
 	return rs.Send(response)
 }
 
-// NewServer creates an ab.AtomicBroadcastServer based on the broadcast target and ledger Reader
+// ServerOptions configures the optional behavior of the server created by
+// NewServer.  Each field may be left zero, which disables the behavior it
+// configures.
+type ServerOptions struct {
+	// Admission admits the broadcast messages, or all are admitted if nil
+	Admission broadcast.AdmissionController
+	// DeliverMAC attaches a MAC to the Deliver responses of the clients asking for it
+	DeliverMAC bool
+	// Malformed collects the malformed messages and Deliver requests
+	Malformed *corpus.Collector
+	// Duplicates detects the broadcast messages submitted again
+	Duplicates broadcast.DuplicateDetector
+	// SLOMonitor evaluates the SLOs of the channels
+	SLOMonitor *slo.Monitor
+	// Limiter throttles the broadcast messages
+	Limiter broadcast.RateLimiter
+	// Quotas enforces the ingress quotas of the organizations on the normal
+	// broadcast messages, and must be shared by the servers of the orderer
+	Quotas *msgprocessor.IngressQuotas
+	// BroadcastWindow is the number of messages of a broadcast stream processed at once
+	BroadcastWindow int
+	// BroadcastReadyTimeout is how long a broadcast message waits for its consenter to be ready
+	BroadcastReadyTimeout time.Duration
+	// Meter meters the usage of each organization
+	Meter *accounting.Meter
+	// BroadcastMetrics records the broadcast metrics
+	BroadcastMetrics *broadcast.Metrics
+	// AuditSink keeps the audit trail of the broadcast messages
+	AuditSink broadcast.AuditSink
+	// SizeLimits checks the size of the broadcast messages as they are received
+	SizeLimits *broadcast.SizeLimits
+	// Overload simulates the overload of the consenters
+	Overload *gameday.Simulator
+	// AdmissionPlugin makes custom checks on the normal broadcast messages
+	AdmissionPlugin broadcast.AdmissionPlugin
+	// StreamLimits limits the number and lifetime of the broadcast streams of each client
+	StreamLimits *broadcast.StreamLimits
+	// VersionSkew negotiates the version spoken with the other orderers
+	VersionSkew *versionskew.Negotiator
+	// Anonymizer anonymizes the identities of the clients
+	Anonymizer *privacy.Anonymizer
+	// Commits notifies the commit of the broadcast messages to the clients waiting for it
+	Commits broadcast.CommitNotifier
+	// CommitTimeout is how long a broadcast message waits for its commit
+	CommitTimeout time.Duration
+	// Scheduler schedules the broadcast messages passed to their consenter
+	Scheduler broadcast.IngressScheduler
+	// HeartbeatMinInterval is the shortest interval of the heartbeats sent to
+	// the clients asking for them, which are not sent if zero
+	HeartbeatMinInterval time.Duration
+	// Crashes reports the panics of the handlers
+	Crashes *crash.Reporter
+	// Receipts signs receipts for the broadcast clients asking for them
+	Receipts bool
+	// PendingBytesWatermark is the work pending in a consenter above which
+	// the normal broadcast messages are rejected
+	PendingBytesWatermark uint32
+	// BackpressureRetryAfter is the retry delay told to the rejected clients
+	BackpressureRetryAfter time.Duration
+	// MisbehaviorDetector scores the suspicious broadcast messages
+	MisbehaviorDetector *misbehavior.Detector
+	// Embargoes delay the new blocks of their channels sent to the clients
+	// which are not consenters
+	Embargoes []embargo.Channel
+	// IntakeJournal records the broadcast messages answered SUCCESS until they
+	// are ordered, and must be started by the caller
+	IntakeJournal *intake.Journal
+	// NonceTracker requires the nonces of the messages of each creator to advance
+	NonceTracker *msgprocessor.NonceTracker
+	// StreamQuota limits the streams of each identity on each channel
+	StreamQuota *streamquota.Quota
+	// Verifier validates the normal broadcast messages in parallel
+	Verifier *msgprocessor.VerifierPool
+	// Forwarder forwards the broadcast messages of the channels led by
+	// another orderer to the leader, which are passed to the consenter of
+	// this orderer if nil
+	Forwarder *forward.Client
+	// ForwardAttempts is the number of times a message is forwarded before
+	// it is rejected
+	ForwardAttempts int
+	// ForwardRetryInterval is the time waited before forwarding a message again
+	ForwardRetryInterval time.Duration
+}
+
+// NewServer creates an ab.AtomicBroadcastServer based on the broadcast target and ledger Reader.
+// The channel registry is either the registrar of the orderer or the view of a tenant.
 //创建orderer排序服务器，并注册到本地默认的grpc服务器（默认为7050端口），提供Broadcast()与Deliver()借口
 //通过自身的Broadcast服务器处理句柄与Deliver服务器处理句柄，分别接受与处理对应的消息请求
 //同时，基于自身的多通道注册管理器对象管理Orderer节点上所有的通道配置和账本 共识组件等，并创建共识组件链对象负责通道管理 交易拍戏工作
-func NewServer(r *multichannel.Registrar, _ crypto.LocalSigner, debug *localconfig.Debug, timeWindow time.Duration, mutualTLS bool) ab.AtomicBroadcastServer {
+func NewServer(r channelRegistry, signer crypto.LocalSigner, debug *localconfig.Debug, timeWindow time.Duration, mutualTLS bool, opts ServerOptions) ab.AtomicBroadcastServer {
+	//客户端要求心跳时发送的心跳，最短间隔为0时不发送
+	var heartbeats *broadcast.Heartbeats
+	if opts.HeartbeatMinInterval > 0 {
+		heartbeats = broadcast.NewHeartbeats(opts.HeartbeatMinInterval, channelHeights{channelRegistry: r})
+	}
+	//客户端要求回执时以本节点身份签署的回执，未启用时不签署
+	var receipts *broadcast.Receipts
+	if opts.Receipts {
+		receipts = broadcast.NewReceipts(signer)
+	}
+	//共识组件积压的消息字节数超过高水位时拒绝普通交易消息，高水位为0时不拒绝
+	var backpressure *broadcast.Backpressure
+	if opts.PendingBytesWatermark > 0 {
+		backpressure = broadcast.NewBackpressure(int64(opts.PendingBytesWatermark), opts.BackpressureRetryAfter, channelQueues{channelRegistry: r})
+	}
+	//对客户端与身份的可疑消息评分，未启用时只拒绝可疑消息
+	var misbehaving broadcast.MisbehaviorDetector
+	if opts.MisbehaviorDetector != nil {
+		misbehaving = opts.MisbehaviorDetector
+	}
+	//应答成功之前将消息写入接收日志，崩溃重启后重新发送未提交的消息，未启用时不记录
+	var journal broadcast.IntakeJournal
+	if opts.IntakeJournal != nil {
+		journal = opts.IntakeJournal
+	}
+	//按组织的入口配额对普通交易消息计费，由本节点的所有服务器共享
+	var quotas broadcast.IngressQuotas
+	if opts.Quotas != nil {
+		quotas = opts.Quotas
+	}
+	//严格排序模式下要求各创建者的nonce递增，拒绝重放的消息，未启用时不检查
+	var nonces broadcast.NonceTracker
+	if opts.NonceTracker != nil {
+		nonces = opts.NonceTracker
+	}
+	//通道由其他排序节点领导时将消息转发给领导者，未启用时交给本节点的共识组件
+	var forwarding *broadcast.Forwarding
+	if opts.Forwarder != nil {
+		forwarding = broadcast.NewForwarding(opts.ForwardAttempts, opts.ForwardRetryInterval, channelLeaders{channelRegistry: r}, opts.Forwarder)
+	}
 	s := &server{
-		dh:        deliver.NewHandler(deliverSupport{Registrar: r}, timeWindow, mutualTLS), //Deliver服务处理句柄
-		bh:        broadcast.NewHandlerImpl(broadcastSupport{Registrar: r}), //Broadcast服务处理句柄
-		debug:     debug, //调试信息
-		Registrar: r, //多通道注册管理器
+		dh: deliver.NewHandler(deliverSupport{channelRegistry: r}, timeWindow, mutualTLS), //Deliver服务处理句柄
+		bh: broadcast.NewHandlerImpl(broadcastSupport{channelRegistry: r, overload: opts.Overload}, broadcast.HandlerOptions{
+			Admission:       opts.Admission,
+			Malformed:       opts.Malformed,
+			Duplicates:      opts.Duplicates,
+			Limiter:         opts.Limiter,
+			Quotas:          quotas,
+			Window:          opts.BroadcastWindow,
+			Metrics:         opts.BroadcastMetrics,
+			AuditSink:       opts.AuditSink,
+			SizeLimits:      opts.SizeLimits,
+			AdmissionPlugin: opts.AdmissionPlugin,
+			StreamLimits:    opts.StreamLimits,
+			ReadyTimeout:    opts.BroadcastReadyTimeout,
+			Tracer:          r.Tracer(),
+			Commits:         opts.Commits,
+			CommitTimeout:   opts.CommitTimeout,
+			Scheduler:       opts.Scheduler,
+			Heartbeats:      heartbeats,
+			Crashes:         opts.Crashes,
+			Receipts:        receipts,
+			Backpressure:    backpressure,
+			Misbehavior:     misbehaving,
+			Journal:         journal,
+			Nonces:          nonces,
+			StreamQuota:     opts.StreamQuota,
+			Verifier:        opts.Verifier,
+			Forwarding:      forwarding,
+		}), //Broadcast服务处理句柄
+		debug:      debug, //调试信息
+		deliverMAC: opts.DeliverMAC, //是否对Deliver响应消息附加MAC
+		slo:        opts.SLOMonitor, //通道SLO监控器，为nil时不评估
+		meter:      opts.Meter, //组织用量计量器，为nil时不计量
+		skew:       opts.VersionSkew, //排序节点之间的版本协商器，为nil时不协商
+		anonymizer: opts.Anonymizer, //客户端身份匿名化器，为nil时不匿名化
+		commitWait: opts.Commits != nil, //客户端是否可以等待交易提交
+		crashes:    opts.Crashes, //处理句柄panic的崩溃报告器，为nil时只记录日志
+		quota:      opts.StreamQuota, //各身份在各通道的消息流配额，为nil时不限制
+		channelRegistry: r, //多通道注册管理器或租户视图
+	}
+	//通道配置变更订阅服务处理句柄
+	s.ch = configfeed.NewHandler(configfeedSupport{channelRegistry: r}, r.BlockFanout(), s.checkReaders, timeWindow, mutualTLS)
+	//通道高度水位查询服务处理句柄，以本节点身份签名
+	s.wh = watermark.NewHandler(watermarkSupport{channelRegistry: r}, s.checkReaders, signer, timeWindow, mutualTLS)
+	//无法解析的Deliver请求存入畸形消息语料库
+	s.dh.MalformedRecorder = opts.Malformed
+	//Deliver消息流的心跳最短间隔
+	s.dh.MinHeartbeatInterval = opts.HeartbeatMinInterval
+	//禁发通道的新区块延迟发送给非共识节点客户端
+	if len(opts.Embargoes) > 0 {
+		e := embargo.New(opts.Embargoes, channelHeights{channelRegistry: r})
+		e.Follow(r.BlockFanout())
+		s.dh.Embargo = e
 	}
 	return s
 }
@@ -112,6 +517,262 @@ type broadcastMsgTracer struct {
 	msgTracer
 }
 
+// broadcastTimelineTracer records in the transaction timeline when each transaction is
+// received and, once the handler has replied with success, when it was enqueued for ordering.
+// Responses may be sent out of order, so they are matched to their messages by correlation ID.
+// The handler replies to the transactions of streams waiting for their commit only once they
+// are committed, so the enqueue of these is not recorded.
+type broadcastTimelineTracer struct {
+	ab.AtomicBroadcast_BroadcastServer
+	timeline   *txtimeline.Recorder
+	waitCommit bool
+
+	mutex    sync.Mutex
+	received uint64
+	pending  map[uint64]*cb.ChannelHeader
+}
+
+func (btt *broadcastTimelineTracer) Recv() (*cb.Envelope, error) {
+	msg, err := btt.AtomicBroadcast_BroadcastServer.Recv()
+	if err == nil {
+		btt.mutex.Lock()
+		btt.received++
+		if chdr, err := utils.ChannelHeader(msg); err == nil {
+			if btt.pending == nil {
+				btt.pending = map[uint64]*cb.ChannelHeader{}
+			}
+			btt.pending[btt.received] = chdr
+			btt.timeline.BroadcastReceived(chdr.ChannelId, chdr.TxId)
+		}
+		btt.mutex.Unlock()
+	}
+	return msg, err
+}
+
+func (btt *broadcastTimelineTracer) Send(resp *ab.BroadcastResponse) error {
+	btt.mutex.Lock()
+	chdr, ok := btt.pending[resp.CorrelationId]
+	delete(btt.pending, resp.CorrelationId)
+	btt.mutex.Unlock()
+	if ok && resp.Status == cb.Status_SUCCESS && !btt.waitCommit {
+		btt.timeline.Enqueued(chdr.ChannelId, chdr.TxId)
+	}
+	return btt.AtomicBroadcast_BroadcastServer.Send(resp)
+}
+
+// broadcastSLOTracer reports to the SLO monitor how long each message took
+// to be enqueued for ordering, from its receipt to the success response.
+type broadcastSLOTracer struct {
+	ab.AtomicBroadcast_BroadcastServer
+	monitor *slo.Monitor
+
+	mutex    sync.Mutex
+	received uint64
+	pending  map[uint64]receipt
+}
+
+// receipt records when a message of a channel was received
+type receipt struct {
+	channelID string
+	at        time.Time
+}
+
+func (bst *broadcastSLOTracer) Recv() (*cb.Envelope, error) {
+	msg, err := bst.AtomicBroadcast_BroadcastServer.Recv()
+	if err == nil {
+		bst.mutex.Lock()
+		bst.received++
+		if chdr, err := utils.ChannelHeader(msg); err == nil && chdr.ChannelId != "" {
+			if bst.pending == nil {
+				bst.pending = map[uint64]receipt{}
+			}
+			bst.pending[bst.received] = receipt{channelID: chdr.ChannelId, at: time.Now()}
+		}
+		bst.mutex.Unlock()
+	}
+	return msg, err
+}
+
+func (bst *broadcastSLOTracer) Send(resp *ab.BroadcastResponse) error {
+	bst.mutex.Lock()
+	r, ok := bst.pending[resp.CorrelationId]
+	delete(bst.pending, resp.CorrelationId)
+	bst.mutex.Unlock()
+	// 重复提交的消息未再次入队，不计入入队延迟
+	if ok && resp.Status == cb.Status_SUCCESS && resp.Info != broadcast.DuplicateInfo {
+		bst.monitor.Enqueued(r.channelID, time.Since(r.at))
+	}
+	return bst.AtomicBroadcast_BroadcastServer.Send(resp)
+}
+
+// broadcastUsageTracer records in the usage meter the messages enqueued for
+// ordering, charged to the organization of their creator, whose signature the
+// handler has checked once it replies with success.
+type broadcastUsageTracer struct {
+	ab.AtomicBroadcast_BroadcastServer
+	meter *accounting.Meter
+
+	mutex    sync.Mutex
+	received uint64
+	pending  map[uint64]submission
+}
+
+// submission records the size of a message of a channel and the organization
+// of its creator
+type submission struct {
+	channelID string
+	mspID     string
+	size      int
+}
+
+func (but *broadcastUsageTracer) Recv() (*cb.Envelope, error) {
+	msg, err := but.AtomicBroadcast_BroadcastServer.Recv()
+	if err == nil {
+		but.mutex.Lock()
+		but.received++
+		chdr, err := utils.ChannelHeader(msg)
+		if mspID := accounting.CreatorMSPID(msg); err == nil && mspID != "" {
+			if but.pending == nil {
+				but.pending = map[uint64]submission{}
+			}
+			but.pending[but.received] = submission{channelID: chdr.ChannelId, mspID: mspID, size: proto.Size(msg)}
+		}
+		but.mutex.Unlock()
+	}
+	return msg, err
+}
+
+func (but *broadcastUsageTracer) Send(resp *ab.BroadcastResponse) error {
+	but.mutex.Lock()
+	sub, ok := but.pending[resp.CorrelationId]
+	delete(but.pending, resp.CorrelationId)
+	but.mutex.Unlock()
+	// 重复提交的消息未再次排序，不计入用量
+	if ok && resp.Status == cb.Status_SUCCESS && resp.Info != broadcast.DuplicateInfo {
+		but.meter.Submitted(sub.channelID, sub.mspID, sub.size)
+	}
+	return but.AtomicBroadcast_BroadcastServer.Send(resp)
+}
+
+// deliverUsage holds the channel and the organization of the requester of
+// the last deliver request of a stream, which the deliver handler serves
+// before receiving the next one
+type deliverUsage struct {
+	meter     *accounting.Meter
+	channelID string
+	mspID     string
+}
+
+// deliverUsageReceiver records the requester of each deliver request
+type deliverUsageReceiver struct {
+	deliver.Receiver
+	usage *deliverUsage
+}
+
+func (dur *deliverUsageReceiver) Recv() (*cb.Envelope, error) {
+	env, err := dur.Receiver.Recv()
+	dur.usage.channelID, dur.usage.mspID = "", ""
+	if err == nil {
+		if chdr, err := utils.ChannelHeader(env); err == nil {
+			dur.usage.channelID, dur.usage.mspID = chdr.ChannelId, accounting.CreatorMSPID(env)
+		}
+	}
+	return env, err
+}
+
+// deliverUsageSender records in the usage meter the blocks delivered, charged
+// to the organization of the requester, whose signature the deliver handler
+// has checked against the Readers policy of the channel before sending blocks.
+type deliverUsageSender struct {
+	deliver.ResponseSender
+	usage *deliverUsage
+}
+
+func (dus *deliverUsageSender) SendBlockResponse(block *cb.Block) error {
+	err := dus.ResponseSender.SendBlockResponse(block)
+	if err == nil && dus.usage.mspID != "" {
+		dus.usage.meter.Delivered(dus.usage.channelID, dus.usage.mspID, proto.Size(block))
+	}
+	return err
+}
+
+// deliverTraceSender records the delivery of the blocks containing traced
+// messages to the stream of the context
+type deliverTraceSender struct {
+	deliver.ResponseSender
+	ctx    context.Context
+	tracer *tracing.Tracer
+}
+
+func (dts *deliverTraceSender) SendBlockResponse(block *cb.Block) error {
+	var span *tracing.Span
+	if channelID, err := utils.GetChainIDFromBlock(block); err == nil {
+		span = dts.tracer.StartDelivery(dts.ctx, channelID, block.Header.Number)
+	}
+	err := dts.ResponseSender.SendBlockResponse(block)
+	if err != nil {
+		span.SetError(err.Error())
+	}
+	span.End()
+	return err
+}
+
+// broadcastBatchTracer does for the envelopes of each batch what the
+// message, timeline, SLO and usage tracers do for the messages of a broadcast stream.
+type broadcastBatchTracer struct {
+	ab.AtomicBroadcast_BroadcastBatchServer
+	msgTracer
+	timeline    *txtimeline.Recorder
+	monitor     *slo.Monitor
+	meter       *accounting.Meter
+	headers     []*cb.ChannelHeader
+	submissions []submission
+	received    time.Time
+}
+
+func (bbt *broadcastBatchTracer) Recv() (*ab.BroadcastBatch, error) {
+	batch, err := bbt.AtomicBroadcast_BroadcastBatchServer.Recv()
+	bbt.headers, bbt.submissions, bbt.received = nil, nil, time.Now()
+	if err != nil {
+		return batch, err
+	}
+	for _, msg := range batch.Envelopes {
+		if traceDir := bbt.debug.BroadcastTraceDir; traceDir != "" {
+			bbt.trace(traceDir, msg, nil)
+		}
+		chdr, err := utils.ChannelHeader(msg)
+		if err != nil {
+			chdr = nil
+		} else if bbt.timeline != nil {
+			bbt.timeline.BroadcastReceived(chdr.ChannelId, chdr.TxId)
+		}
+		bbt.headers = append(bbt.headers, chdr)
+		if bbt.meter != nil {
+			bbt.submissions = append(bbt.submissions, submission{mspID: accounting.CreatorMSPID(msg), size: proto.Size(msg)})
+		}
+	}
+	return batch, nil
+}
+
+func (bbt *broadcastBatchTracer) Send(resp *ab.BroadcastBatchResponse) error {
+	for i, r := range resp.Responses {
+		if i >= len(bbt.headers) || bbt.headers[i] == nil || r.Status != cb.Status_SUCCESS {
+			continue
+		}
+		chdr := bbt.headers[i]
+		if bbt.timeline != nil {
+			bbt.timeline.Enqueued(chdr.ChannelId, chdr.TxId)
+		}
+		if bbt.monitor != nil && r.Info != broadcast.DuplicateInfo {
+			bbt.monitor.Enqueued(chdr.ChannelId, time.Since(bbt.received))
+		}
+		if bbt.meter != nil && r.Info != broadcast.DuplicateInfo && bbt.submissions[i].mspID != "" {
+			bbt.meter.Submitted(chdr.ChannelId, bbt.submissions[i].mspID, bbt.submissions[i].size)
+		}
+	}
+	return bbt.AtomicBroadcast_BroadcastBatchServer.Send(resp)
+}
+
 func (bmt *broadcastMsgTracer) Recv() (*cb.Envelope, error) {
 	msg, err := bmt.AtomicBroadcast_BroadcastServer.Recv()
 	if traceDir := bmt.debug.BroadcastTraceDir; traceDir != "" {
@@ -120,6 +781,67 @@ func (bmt *broadcastMsgTracer) Recv() (*cb.Envelope, error) {
 	return msg, err
 }
 
+// lastMsg remembers the last message received on a stream, to describe it
+// in the crash report should the handler of the stream panic
+type lastMsg struct {
+	msg atomic.Value
+}
+
+func (lm *lastMsg) store(msg *cb.Envelope) {
+	if msg != nil {
+		lm.msg.Store(msg)
+	}
+}
+
+func (lm *lastMsg) load() *cb.Envelope {
+	msg, _ := lm.msg.Load().(*cb.Envelope)
+	return msg
+}
+
+type lastMsgBroadcastSrv struct {
+	ab.AtomicBroadcast_BroadcastServer
+	last *lastMsg
+}
+
+func (lmbs *lastMsgBroadcastSrv) Recv() (*cb.Envelope, error) {
+	msg, err := lmbs.AtomicBroadcast_BroadcastServer.Recv()
+	lmbs.last.store(msg)
+	return msg, err
+}
+
+type lastMsgReceiver struct {
+	deliver.Receiver
+	last *lastMsg
+}
+
+func (lmr *lastMsgReceiver) Recv() (*cb.Envelope, error) {
+	msg, err := lmr.Receiver.Recv()
+	lmr.last.store(msg)
+	return msg, err
+}
+
+// anonymizedBroadcastSrv hands the broadcast handler a stream context whose
+// peer address is anonymized
+type anonymizedBroadcastSrv struct {
+	ab.AtomicBroadcast_BroadcastServer
+	ctx context.Context
+}
+
+func (abs *anonymizedBroadcastSrv) Context() context.Context {
+	return abs.ctx
+}
+
+// anonymizedBroadcastBatchSrv does for batch streams what
+// anonymizedBroadcastSrv does for broadcast streams
+type anonymizedBroadcastBatchSrv struct {
+	ab.AtomicBroadcast_BroadcastBatchServer
+	ctx context.Context
+}
+
+func (abbs *anonymizedBroadcastBatchSrv) Context() context.Context {
+	return abbs.ctx
+}
+
 type deliverMsgTracer struct {
 	deliver.Receiver
 	msgTracer
@@ -134,16 +856,36 @@ func (dmt *deliverMsgTracer) Recv() (*cb.Envelope, error) {
 }
 
 // Broadcast receives a stream of messages from a client for ordering
-func (s *server) Broadcast(srv ab.AtomicBroadcast_BroadcastServer) error {
+func (s *server) Broadcast(srv ab.AtomicBroadcast_BroadcastServer) (err error) {
 	logger.Debugf("Starting new Broadcast handler")
-	defer func() {
+	last := &lastMsg{}
+	defer func(stream ab.AtomicBroadcast_BroadcastServer) {
+		//panic只结束本消息流，回复客户端INTERNAL_SERVER_ERROR并记录崩溃报告
 		if r := recover(); r != nil {
-			logger.Criticalf("Broadcast client triggered panic: %s\n%s", r, debug.Stack())
+			report := s.crashes.Report("Broadcast", s.remoteAddress(stream), last.load(), r)
+			if stream != nil {
+				err = stream.Send(&ab.BroadcastResponse{Status: cb.Status_INTERNAL_SERVER_ERROR, Info: report.Info()})
+			}
 		}
 		logger.Debugf("Closing Broadcast stream")
-	}()
+	}(srv)
+	//等待交易提交的消息流的响应时间不是入队延迟
+	waitCommit := s.commitWait && broadcast.WaitsForCommit(srv.Context())
+	if timeline := s.TxTimeline(); timeline != nil {
+		srv = &broadcastTimelineTracer{AtomicBroadcast_BroadcastServer: srv, timeline: timeline, waitCommit: waitCommit}
+	}
+	if s.slo != nil && !waitCommit {
+		srv = &broadcastSLOTracer{AtomicBroadcast_BroadcastServer: srv, monitor: s.slo}
+	}
+	if s.meter != nil {
+		srv = &broadcastUsageTracer{AtomicBroadcast_BroadcastServer: srv, meter: s.meter}
+	}
+	//处理句柄的日志和审计记录只能看到匿名化后的客户端地址
+	if s.anonymizer != nil {
+		srv = &anonymizedBroadcastSrv{AtomicBroadcast_BroadcastServer: srv, ctx: s.anonymizer.Context(srv.Context())}
+	}
 	return s.bh.Handle(&broadcastMsgTracer{
-		AtomicBroadcast_BroadcastServer: srv,
+		AtomicBroadcast_BroadcastServer: &lastMsgBroadcastSrv{AtomicBroadcast_BroadcastServer: srv, last: last},
 		msgTracer: msgTracer{
 			debug:    s.debug,
 			function: "Broadcast",
@@ -151,46 +893,153 @@ func (s *server) Broadcast(srv ab.AtomicBroadcast_BroadcastServer) error {
 	})
 }
 
+// BroadcastBatch receives a stream of batches of messages from a client for ordering
+func (s *server) BroadcastBatch(srv ab.AtomicBroadcast_BroadcastBatchServer) (err error) {
+	logger.Debugf("Starting new BroadcastBatch handler")
+	defer func(stream ab.AtomicBroadcast_BroadcastBatchServer) {
+		//批次的响应须与其消息一一对应，panic以gRPC错误结束消息流
+		if r := recover(); r != nil {
+			report := s.crashes.Report("BroadcastBatch", s.remoteAddress(stream), nil, r)
+			err = status.Error(codes.Internal, report.Info())
+		}
+		logger.Debugf("Closing BroadcastBatch stream")
+	}(srv)
+	if s.anonymizer != nil {
+		srv = &anonymizedBroadcastBatchSrv{AtomicBroadcast_BroadcastBatchServer: srv, ctx: s.anonymizer.Context(srv.Context())}
+	}
+	return s.bh.HandleBatch(&broadcastBatchTracer{
+		AtomicBroadcast_BroadcastBatchServer: srv,
+		msgTracer: msgTracer{
+			debug:    s.debug,
+			function: "BroadcastBatch",
+		},
+		timeline: s.TxTimeline(),
+		monitor:  s.slo,
+		meter:    s.meter,
+	})
+}
+
 // Deliver sends a stream of blocks to a client after ordering
 //Deliver区块请求服务方法
-func (s *server) Deliver(srv ab.AtomicBroadcast_DeliverServer) error {
+func (s *server) Deliver(srv ab.AtomicBroadcast_DeliverServer) (err error) {
 	logger.Debugf("Starting new Deliver handler")
+	var rs *responseSender
+	last := &lastMsg{}
 	defer func() {
+		//panic只结束本消息流，回复客户端INTERNAL_SERVER_ERROR并记录崩溃报告
 		if r := recover(); r != nil {
-			logger.Criticalf("Deliver client triggered panic: %s\n%s", r, debug.Stack())
+			s.crashes.Report("Deliver", s.remoteAddress(srv), last.load(), r)
+			if srv != nil && rs != nil {
+				err = rs.SendStatusResponse(cb.Status_INTERNAL_SERVER_ERROR)
+			}
 		}
 		logger.Debugf("Closing Deliver stream")
 	}()
-
-	//定义策略检查器
-	//用于检查接受的区块请求消息必须满足指定通道上的访问控制权限策略的要求
-	policyChecker := func(env *cb.Envelope, channelID string) error {
-		//获取指定通道的链支持对象
-		chain, ok := s.GetChain(channelID)
-		if !ok {
-			return errors.Errorf("channel %s not found", channelID)
-		}
-		//创建消息过滤器
-		sf := msgprocessor.NewSigFilter(policies.ChannelReaders, chain)
-		//过滤消息
-		return sf.Apply(env)
+	rs = &responseSender{
+		AtomicBroadcast_DeliverServer: srv,
+		mac:                           s.streamMAC(srv),
+	}
+	//与以备用节点身份连接的排序节点协商版本
+	if err := s.skew.NegotiateServer(util.ExtractRemoteAddress(srv.Context()), srv); err != nil {
+		return err
 	}
+
 	deliverServer := &deliver.Server{
-		PolicyChecker: deliver.PolicyCheckerFunc(policyChecker),
+		PolicyChecker: deliver.PolicyCheckerFunc(s.checkReaders),
 		Receiver: &deliverMsgTracer{
-			Receiver: srv,
+			Receiver: &lastMsgReceiver{Receiver: srv, last: last},
 			msgTracer: msgTracer{
 				debug:    s.debug,
 				function: "Deliver",
 			},
 		},
-		ResponseSender: &responseSender{
-			AtomicBroadcast_DeliverServer: srv,
-		},
+		ResponseSender:  rs,
+		HeartbeatSender: rs,
+	}
+	//消息流计入授权请求者在通道上的消息流配额，消息流结束时释放
+	if quota := s.quota.Open(streamquota.Deliver); quota != nil {
+		defer quota.Close()
+		deliverServer.StreamQuota = quota
+	}
+	//按请求者所属组织计量发送的区块
+	if s.meter != nil {
+		usage := &deliverUsage{meter: s.meter}
+		deliverServer.Receiver = &deliverUsageReceiver{Receiver: deliverServer.Receiver, usage: usage}
+		deliverServer.ResponseSender = &deliverUsageSender{ResponseSender: deliverServer.ResponseSender, usage: usage}
+	}
+	//追踪包含被追踪消息的区块的发送
+	if tracer := s.Tracer(); tracer != nil {
+		deliverServer.ResponseSender = &deliverTraceSender{ResponseSender: deliverServer.ResponseSender, ctx: srv.Context(), tracer: tracer}
 	}
 
 	//Deliver服务消息处理
-	return s.dh.Handle(srv.Context(), deliverServer)
+	return s.dh.Handle(s.anonymizer.Context(srv.Context()), deliverServer)
+}
+
+// SubscribeConfig sends a client the config changes of a channel as they are committed
+func (s *server) SubscribeConfig(env *cb.Envelope, srv ab.AtomicBroadcast_SubscribeConfigServer) (err error) {
+	logger.Debugf("Starting new SubscribeConfig handler")
+	defer func() {
+		if r := recover(); r != nil {
+			s.crashes.Report("SubscribeConfig", s.remoteAddress(srv), env, r)
+			if srv != nil {
+				err = srv.Send(&ab.ConfigChangeResponse{Type: &ab.ConfigChangeResponse_Status{Status: cb.Status_INTERNAL_SERVER_ERROR}})
+			}
+		}
+		logger.Debugf("Closing SubscribeConfig stream")
+	}()
+	return s.ch.Handle(s.anonymizer.Context(srv.Context()), env, srv)
+}
+
+// Watermark returns the current height and last block hash of a channel, signed by the orderer
+func (s *server) Watermark(ctx context.Context, env *cb.Envelope) (resp *ab.WatermarkResponse, err error) {
+	ctx = s.anonymizer.Context(ctx)
+	defer func() {
+		if r := recover(); r != nil {
+			s.crashes.Report("Watermark", util.ExtractRemoteAddress(ctx), env, r)
+			resp, err = &ab.WatermarkResponse{Status: cb.Status_INTERNAL_SERVER_ERROR}, nil
+		}
+	}()
+	return s.wh.Handle(ctx, env), nil
+}
+
+// remoteAddress returns the anonymized address of the client of the stream,
+// for the crash reports of its handler
+func (s *server) remoteAddress(stream grpc.ServerStream) string {
+	if stream == nil {
+		return ""
+	}
+	return util.ExtractRemoteAddress(s.anonymizer.Context(stream.Context()))
+}
+
+// checkReaders is the policy checker of the requests to read a channel
+//定义策略检查器
+//用于检查接受的区块请求消息必须满足指定通道上的访问控制权限策略的要求
+func (s *server) checkReaders(env *cb.Envelope, channelID string) error {
+	//获取指定通道的链支持对象
+	chain, ok := s.GetChain(channelID)
+	if !ok {
+		return errors.Errorf("channel %s not found", channelID)
+	}
+	//创建消息过滤器
+	sf := msgprocessor.NewSigFilter(policies.ChannelReaders, chain)
+	//过滤消息
+	return sf.Apply(env)
+}
+
+// streamMAC returns the StreamMAC authenticating the responses of the stream,
+// or nil if responses are not authenticated
+//创建基于TLS会话密钥的Deliver响应消息认证对象
+func (s *server) streamMAC(srv ab.AtomicBroadcast_DeliverServer) *deliver.StreamMAC {
+	if !s.deliverMAC {
+		return nil
+	}
+	key, err := deliver.SessionMACKeyFromContext(srv.Context())
+	if err != nil {
+		logger.Debugf("Not authenticating deliver responses: %s", err)
+		return nil
+	}
+	return deliver.NewStreamMAC(key)
 }
 
 //发送指定执行结果状态类型的Deliver服务相应消息
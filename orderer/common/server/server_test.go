@@ -14,15 +14,23 @@ import (
 	"time"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/deliver"
+	"github.com/hyperledger/fabric/orderer/common/accounting"
+	"github.com/hyperledger/fabric/orderer/common/broadcast"
 	localconfig "github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/hyperledger/fabric/orderer/common/multichannel"
 	cb "github.com/hyperledger/fabric/protos/common"
+	mspprotos "github.com/hyperledger/fabric/protos/msp"
 	ab "github.com/hyperledger/fabric/protos/orderer"
 	"github.com/hyperledger/fabric/protos/utils"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/net/context"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 func TestBroadcastNoPanic(t *testing.T) {
@@ -30,11 +38,57 @@ func TestBroadcastNoPanic(t *testing.T) {
 	_ = (&server{}).Broadcast(nil)
 }
 
+func TestBroadcastBatchNoPanic(t *testing.T) {
+	// Defer recovers from the panic
+	_ = (&server{}).BroadcastBatch(nil)
+}
+
 func TestDeliverNoPanic(t *testing.T) {
 	// Defer recovers from the panic
 	_ = (&server{}).Deliver(nil)
 }
 
+type panickingHandler struct{}
+
+func (panickingHandler) Handle(srv ab.AtomicBroadcast_BroadcastServer) error {
+	srv.Recv()
+	panic("bug")
+}
+
+func (panickingHandler) HandleBatch(srv ab.AtomicBroadcast_BroadcastBatchServer) error {
+	panic("bug")
+}
+
+// peerBroadcastSrv is a recordingBroadcastSrv with a peer in its context
+type peerBroadcastSrv struct {
+	recordingBroadcastSrv
+}
+
+func (peerBroadcastSrv) Context() context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{})
+}
+
+type batchSrv struct {
+	ab.AtomicBroadcast_BroadcastBatchServer
+}
+
+func (batchSrv) Context() context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{})
+}
+
+func TestBroadcastPanicReported(t *testing.T) {
+	s := &server{bh: panickingHandler{}, debug: &localconfig.Debug{}, channelRegistry: &multichannel.Registrar{}}
+	srv := &peerBroadcastSrv{recordingBroadcastSrv{mockBroadcastSrv: mockBroadcastSrv{msg: &cb.Envelope{Payload: []byte("payload")}}}}
+	assert.NoError(t, s.Broadcast(srv))
+	require.Len(t, srv.sent, 1)
+	assert.Equal(t, cb.Status_INTERNAL_SERVER_ERROR, srv.sent[0].Status)
+	assert.Contains(t, srv.sent[0].Info, "crash report")
+
+	err := s.BroadcastBatch(batchSrv{})
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Contains(t, err.Error(), "crash report")
+}
+
 type recvr interface {
 	Recv() (*cb.Envelope, error)
 }
@@ -61,6 +115,10 @@ func (mbs *mockBroadcastSrv) Send(br *ab.BroadcastResponse) error {
 
 type mockDeliverSrv mockSrv
 
+func (mds *mockDeliverSrv) Context() context.Context {
+	return peer.NewContext(context.Background(), &peer.Peer{})
+}
+
 func (mds *mockDeliverSrv) CreateStatusReply(status cb.Status) proto.Message {
 	return &ab.DeliverResponse{
 		Type: &ab.DeliverResponse_Status{Status: status},
@@ -149,3 +207,92 @@ func TestDeliverMsgTrace(t *testing.T) {
 		}
 	}, t)
 }
+
+type recordingDeliverSrv struct {
+	mockDeliverSrv
+	sent []*ab.DeliverResponse
+}
+
+func (rds *recordingDeliverSrv) Send(resp *ab.DeliverResponse) error {
+	rds.sent = append(rds.sent, resp)
+	return nil
+}
+
+func TestResponseSenderMAC(t *testing.T) {
+	srv := &recordingDeliverSrv{}
+	rs := &responseSender{AtomicBroadcast_DeliverServer: srv}
+	assert.NoError(t, rs.SendBlockResponse(cb.NewBlock(0, nil)))
+	assert.Empty(t, srv.sent[0].Mac)
+
+	rs.mac = deliver.NewStreamMAC([]byte("key"))
+	assert.NoError(t, rs.SendBlockResponse(cb.NewBlock(1, nil)))
+	assert.NoError(t, rs.SendStatusResponse(cb.Status_SUCCESS))
+
+	verifier := deliver.NewStreamMAC([]byte("key"))
+	for _, resp := range srv.sent[1:] {
+		assert.NoError(t, verifier.Verify(resp))
+	}
+
+	// Streams without a mutual TLS session are served without MACs
+	assert.Nil(t, (&server{deliverMAC: true}).streamMAC(&mockDeliverSrv{}))
+	assert.Nil(t, (&server{}).streamMAC(&mockDeliverSrv{}))
+}
+
+// usageEnvelope returns an envelope of the channel created by the MSP
+func usageEnvelope(channelID, mspID string) *cb.Envelope {
+	creator := utils.MarshalOrPanic(&mspprotos.SerializedIdentity{Mspid: mspID})
+	payload := &cb.Payload{
+		Header: utils.MakePayloadHeader(utils.MakeChannelHeader(cb.HeaderType_MESSAGE, 0, channelID, 0), utils.MakeSignatureHeader(creator, nil)),
+	}
+	return &cb.Envelope{Payload: utils.MarshalOrPanic(payload)}
+}
+
+type recordingBroadcastSrv struct {
+	mockBroadcastSrv
+	sent []*ab.BroadcastResponse
+}
+
+func (rbs *recordingBroadcastSrv) Send(resp *ab.BroadcastResponse) error {
+	rbs.sent = append(rbs.sent, resp)
+	return nil
+}
+
+func TestBroadcastUsageTracer(t *testing.T) {
+	meter := accounting.NewMeter(nil)
+	msg := usageEnvelope("foo", "Org1MSP")
+	but := &broadcastUsageTracer{
+		AtomicBroadcast_BroadcastServer: &recordingBroadcastSrv{mockBroadcastSrv: mockBroadcastSrv{msg: msg}},
+		meter:                           meter,
+	}
+	for i := 0; i < 4; i++ {
+		_, err := but.Recv()
+		assert.NoError(t, err)
+	}
+
+	// 响应可能乱序发送，只有成功排序的消息计入用量
+	assert.NoError(t, but.Send(&ab.BroadcastResponse{Status: cb.Status_SUCCESS, CorrelationId: 3}))
+	assert.NoError(t, but.Send(&ab.BroadcastResponse{Status: cb.Status_SUCCESS, CorrelationId: 1}))
+	assert.NoError(t, but.Send(&ab.BroadcastResponse{Status: cb.Status_FORBIDDEN, CorrelationId: 2}))
+	assert.NoError(t, but.Send(&ab.BroadcastResponse{Status: cb.Status_SUCCESS, Info: broadcast.DuplicateInfo, CorrelationId: 4}))
+	assert.Equal(t, []accounting.Usage{
+		{Channel: "foo", MSPID: "Org1MSP", SubmittedMessages: 2, SubmittedBytes: 2 * uint64(proto.Size(msg))},
+	}, meter.Report().Usage)
+	assert.Empty(t, but.pending)
+}
+
+func TestDeliverUsage(t *testing.T) {
+	meter := accounting.NewMeter(nil)
+	usage := &deliverUsage{meter: meter}
+	receiver := &deliverUsageReceiver{Receiver: &mockDeliverSrv{msg: usageEnvelope("foo", "Org1MSP")}, usage: usage}
+	sender := &deliverUsageSender{ResponseSender: &responseSender{AtomicBroadcast_DeliverServer: &recordingDeliverSrv{}}, usage: usage}
+
+	_, err := receiver.Recv()
+	assert.NoError(t, err)
+	block := cb.NewBlock(0, nil)
+	assert.NoError(t, sender.SendBlockResponse(block))
+	assert.NoError(t, sender.SendBlockResponse(block))
+	assert.NoError(t, sender.SendStatusResponse(cb.Status_SUCCESS))
+	assert.Equal(t, []accounting.Usage{
+		{Channel: "foo", MSPID: "Org1MSP", DeliveredMessages: 2, DeliveredBytes: 2 * uint64(proto.Size(block))},
+	}, meter.Report().Usage)
+}
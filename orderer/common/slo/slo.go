@@ -0,0 +1,354 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package slo evaluates the service level objectives declared for the
+// channels of the orderer, the longest a message may wait to be cut into a
+// block and the p99 latency of enqueueing a message, and reports their
+// breaches, so that alerting can be built on the orderer's own evaluation
+// instead of duplicating the objectives in external rules.
+package slo
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/slo"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+const (
+	// DefaultEvaluationInterval is how often the objectives are evaluated when
+	// no interval is configured.
+	DefaultEvaluationInterval = 10 * time.Second
+
+	// maxSamples bounds the enqueue latencies kept per channel between two
+	// evaluations.
+	maxSamples = 10000
+
+	// maxEvents is the number of breach events the Monitor remembers.
+	maxEvents = 100
+)
+
+// The objectives a channel may declare
+const (
+	BlockInterval  = "block_interval"
+	EnqueueLatency = "enqueue_latency"
+)
+
+// Objective declares the SLOs of a channel.  A zero limit declares no
+// objective.
+type Objective struct {
+	Channel string
+
+	// MaxBlockInterval is the longest a message may wait after being
+	// enqueued for a block to be cut
+	MaxBlockInterval time.Duration
+
+	// MaxEnqueueLatency is the p99 latency of enqueueing the messages of the
+	// channel between two evaluations
+	MaxEnqueueLatency time.Duration
+}
+
+// Config contains the configuration of a Monitor.
+type Config struct {
+	EvaluationInterval time.Duration
+	Objectives         []Objective
+}
+
+// Event reports that an objective of a channel started or stopped being
+// breached.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Channel  string    `json:"channel"`
+	SLO      string    `json:"slo"`
+	Breached bool      `json:"breached"`
+	Limit    string    `json:"limit"`
+	Observed string    `json:"observed"`
+}
+
+// ObjectiveStatus is the state of an objective of a channel as of the last
+// evaluation.
+type ObjectiveStatus struct {
+	Channel  string     `json:"channel"`
+	SLO      string     `json:"slo"`
+	Limit    string     `json:"limit"`
+	Observed string     `json:"observed"`
+	Breached bool       `json:"breached"`
+	Since    *time.Time `json:"since,omitempty"`
+	Breaches int        `json:"breaches"`
+}
+
+// Status is the exported form of the state of the Monitor.
+type Status struct {
+	Evaluated  time.Time          `json:"evaluated"`
+	Objectives []*ObjectiveStatus `json:"objectives"`
+	Events     []Event            `json:"events"`
+}
+
+type channelState struct {
+	objective    Objective
+	latencies    []time.Duration
+	pendingSince time.Time
+	status       map[string]*ObjectiveStatus
+}
+
+// Monitor evaluates the objectives of the channels at a fixed interval.
+// All methods are safe to call on a nil Monitor, in which case they do
+// nothing, so that call sites need not check whether objectives are declared.
+type Monitor struct {
+	interval time.Duration
+	now      func() time.Time
+
+	mutex     sync.Mutex
+	channels  map[string]*channelState
+	events    []Event
+	evaluated time.Time
+	stop      chan struct{}
+}
+
+// NewMonitor creates a Monitor of the objectives, or returns an error if an
+// objective is invalid
+func NewMonitor(conf Config) (*Monitor, error) {
+	interval := conf.EvaluationInterval
+	if interval <= 0 {
+		interval = DefaultEvaluationInterval
+	}
+	m := &Monitor{
+		interval: interval,
+		now:      time.Now,
+		channels: map[string]*channelState{},
+	}
+	for _, o := range conf.Objectives {
+		if o.Channel == "" {
+			return nil, errors.New("SLO declared without a channel")
+		}
+		if _, exists := m.channels[o.Channel]; exists {
+			return nil, errors.Errorf("SLO of channel %s declared more than once", o.Channel)
+		}
+		if o.MaxBlockInterval < 0 || o.MaxEnqueueLatency < 0 {
+			return nil, errors.Errorf("SLO of channel %s has a negative limit", o.Channel)
+		}
+		state := &channelState{objective: o, status: map[string]*ObjectiveStatus{}}
+		if o.MaxBlockInterval > 0 {
+			state.status[BlockInterval] = &ObjectiveStatus{Channel: o.Channel, SLO: BlockInterval, Limit: o.MaxBlockInterval.String()}
+		}
+		if o.MaxEnqueueLatency > 0 {
+			state.status[EnqueueLatency] = &ObjectiveStatus{Channel: o.Channel, SLO: EnqueueLatency, Limit: o.MaxEnqueueLatency.String()}
+		}
+		m.channels[o.Channel] = state
+	}
+	return m, nil
+}
+
+// Enqueued records that a message of the channel was enqueued for ordering
+// after the given latency
+func (m *Monitor) Enqueued(channelID string, latency time.Duration) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	state, ok := m.channels[channelID]
+	if !ok {
+		return
+	}
+	state.latencies = append(state.latencies, latency)
+	if len(state.latencies) > maxSamples {
+		state.latencies = state.latencies[len(state.latencies)-maxSamples:]
+	}
+	if state.pendingSince.IsZero() {
+		state.pendingSince = m.now()
+	}
+}
+
+// BlockCut records that a block was cut on the channel
+func (m *Monitor) BlockCut(channelID string) {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if state, ok := m.channels[channelID]; ok {
+		state.pendingSince = time.Time{}
+	}
+}
+
+// Start evaluates the objectives every interval until Stop is called
+func (m *Monitor) Start() {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	if m.stop != nil {
+		m.mutex.Unlock()
+		return
+	}
+	m.stop = make(chan struct{})
+	stop := m.stop
+	m.mutex.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(m.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				m.Evaluate()
+			case <-stop:
+				return
+			}
+		}
+	}()
+}
+
+// Stop stops evaluating the objectives
+func (m *Monitor) Stop() {
+	if m == nil {
+		return
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.stop != nil {
+		close(m.stop)
+		m.stop = nil
+	}
+}
+
+// Evaluate evaluates the objectives and returns the events of the objectives
+// which started or stopped being breached
+func (m *Monitor) Evaluate() []Event {
+	if m == nil {
+		return nil
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	now := m.now()
+	m.evaluated = now
+	var events []Event
+	for _, state := range m.channels {
+		if status, ok := state.status[BlockInterval]; ok {
+			var waited time.Duration
+			if !state.pendingSince.IsZero() {
+				waited = now.Sub(state.pendingSince)
+			}
+			if event, changed := m.update(status, now, waited, waited > state.objective.MaxBlockInterval); changed {
+				events = append(events, event)
+			}
+		}
+		if status, ok := state.status[EnqueueLatency]; ok {
+			p99 := percentile99(state.latencies)
+			if event, changed := m.update(status, now, p99, p99 > state.objective.MaxEnqueueLatency); changed {
+				events = append(events, event)
+			}
+		}
+		state.latencies = nil
+	}
+
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].Channel != events[j].Channel {
+			return events[i].Channel < events[j].Channel
+		}
+		return events[i].SLO < events[j].SLO
+	})
+	for _, event := range events {
+		if event.Breached {
+			logger.Warningf("[channel: %s] SLO breached: %s of %s exceeds %s", event.Channel, event.SLO, event.Observed, event.Limit)
+		} else {
+			logger.Infof("[channel: %s] SLO met again: %s of %s is within %s", event.Channel, event.SLO, event.Observed, event.Limit)
+		}
+	}
+	m.events = append(m.events, events...)
+	if len(m.events) > maxEvents {
+		m.events = m.events[len(m.events)-maxEvents:]
+	}
+	return events
+}
+
+// update must be called with the mutex held
+func (m *Monitor) update(status *ObjectiveStatus, now time.Time, observed time.Duration, breached bool) (Event, bool) {
+	status.Observed = observed.String()
+	if breached == status.Breached {
+		return Event{}, false
+	}
+	status.Breached = breached
+	status.Since = nil
+	if breached {
+		since := now
+		status.Since = &since
+		status.Breaches++
+	}
+	return Event{
+		Time:     now,
+		Channel:  status.Channel,
+		SLO:      status.SLO,
+		Breached: breached,
+		Limit:    status.Limit,
+		Observed: status.Observed,
+	}, true
+}
+
+func percentile99(latencies []time.Duration) time.Duration {
+	if len(latencies) == 0 {
+		return 0
+	}
+	sorted := make([]time.Duration, len(latencies))
+	copy(sorted, latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[(len(sorted)*99-1)/100]
+}
+
+// Status returns the state of the objectives as of the last evaluation,
+// ordered by channel, and the recent breach events, oldest first
+func (m *Monitor) Status() *Status {
+	status := &Status{Objectives: []*ObjectiveStatus{}, Events: []Event{}}
+	if m == nil {
+		return status
+	}
+
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	status.Evaluated = m.evaluated
+	for _, state := range m.channels {
+		for _, s := range state.status {
+			copied := *s
+			status.Objectives = append(status.Objectives, &copied)
+		}
+	}
+	sort.Slice(status.Objectives, func(i, j int) bool {
+		if status.Objectives[i].Channel != status.Objectives[j].Channel {
+			return status.Objectives[i].Channel < status.Objectives[j].Channel
+		}
+		return status.Objectives[i].SLO < status.Objectives[j].SLO
+	})
+	status.Events = append(status.Events, m.events...)
+	return status
+}
+
+// ServeHTTP writes the status as JSON
+func (m *Monitor) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(m.Status()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package slo
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMonitor(t *testing.T) {
+	_, err := NewMonitor(Config{Objectives: []Objective{{MaxBlockInterval: time.Second}}})
+	assert.EqualError(t, err, "SLO declared without a channel")
+
+	_, err = NewMonitor(Config{Objectives: []Objective{{Channel: "foo"}, {Channel: "foo"}}})
+	assert.EqualError(t, err, "SLO of channel foo declared more than once")
+
+	_, err = NewMonitor(Config{Objectives: []Objective{{Channel: "foo", MaxEnqueueLatency: -time.Second}}})
+	assert.EqualError(t, err, "SLO of channel foo has a negative limit")
+
+	m, err := NewMonitor(Config{})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultEvaluationInterval, m.interval)
+}
+
+func TestBlockInterval(t *testing.T) {
+	m, err := NewMonitor(Config{Objectives: []Objective{{Channel: "foo", MaxBlockInterval: 2 * time.Second}}})
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	m.now = func() time.Time { return now }
+
+	// an idle channel does not breach its block interval
+	now = now.Add(time.Minute)
+	assert.Empty(t, m.Evaluate())
+
+	m.Enqueued("foo", time.Millisecond)
+	now = now.Add(time.Second)
+	m.Enqueued("foo", time.Millisecond)
+	now = now.Add(1500 * time.Millisecond)
+	events := m.Evaluate()
+	require.Len(t, events, 1)
+	assert.Equal(t, Event{Time: now, Channel: "foo", SLO: BlockInterval, Breached: true, Limit: "2s", Observed: "2.5s"}, events[0])
+
+	// the breach is reported once
+	now = now.Add(time.Second)
+	assert.Empty(t, m.Evaluate())
+
+	m.BlockCut("foo")
+	events = m.Evaluate()
+	require.Len(t, events, 1)
+	assert.False(t, events[0].Breached)
+
+	status := m.Status()
+	require.Len(t, status.Objectives, 1)
+	assert.Equal(t, &ObjectiveStatus{Channel: "foo", SLO: BlockInterval, Limit: "2s", Observed: "0s", Breaches: 1}, status.Objectives[0])
+	assert.Len(t, status.Events, 2)
+}
+
+func TestEnqueueLatency(t *testing.T) {
+	m, err := NewMonitor(Config{Objectives: []Objective{
+		{Channel: "foo", MaxEnqueueLatency: 100 * time.Millisecond},
+		{Channel: "bar", MaxEnqueueLatency: 100 * time.Millisecond, MaxBlockInterval: time.Hour},
+	}})
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	m.now = func() time.Time { return now }
+
+	for i := 0; i < 99; i++ {
+		m.Enqueued("foo", 10*time.Millisecond)
+		m.Enqueued("bar", 10*time.Millisecond)
+	}
+	m.Enqueued("foo", time.Second)
+	m.Enqueued("bar", time.Second)
+	m.Enqueued("bar", time.Second)
+	m.Enqueued("baz", time.Hour)
+
+	events := m.Evaluate()
+	require.Len(t, events, 1)
+	assert.Equal(t, "bar", events[0].Channel)
+	assert.Equal(t, EnqueueLatency, events[0].SLO)
+	assert.Equal(t, "1s", events[0].Observed)
+
+	// the latencies are evaluated over each interval
+	events = m.Evaluate()
+	require.Len(t, events, 1)
+	assert.False(t, events[0].Breached)
+
+	status := m.Status()
+	require.Len(t, status.Objectives, 3)
+	assert.Equal(t, "bar", status.Objectives[0].Channel)
+	assert.Equal(t, BlockInterval, status.Objectives[0].SLO)
+	assert.Equal(t, "foo", status.Objectives[2].Channel)
+	assert.Equal(t, now, status.Evaluated)
+}
+
+func TestNilMonitor(t *testing.T) {
+	var m *Monitor
+	m.Enqueued("foo", time.Second)
+	m.BlockCut("foo")
+	m.Start()
+	m.Stop()
+	assert.Nil(t, m.Evaluate())
+	assert.Empty(t, m.Status().Objectives)
+}
+
+func TestStartStop(t *testing.T) {
+	m, err := NewMonitor(Config{EvaluationInterval: 10 * time.Millisecond})
+	require.NoError(t, err)
+	m.Start()
+	m.Start()
+	deadline := time.Now().Add(5 * time.Second)
+	for m.Status().Evaluated.IsZero() && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	assert.False(t, m.Status().Evaluated.IsZero())
+	m.Stop()
+	m.Stop()
+}
+
+func TestServeHTTP(t *testing.T) {
+	m, err := NewMonitor(Config{Objectives: []Objective{{Channel: "foo", MaxBlockInterval: time.Second}}})
+	require.NoError(t, err)
+	m.Evaluate()
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/slo", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	status := &Status{}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), status))
+	assert.Len(t, status.Objectives, 1)
+
+	rec = httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/slo", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
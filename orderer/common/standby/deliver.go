@@ -0,0 +1,175 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package standby
+
+import (
+	"context"
+	"math"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/comm"
+	"github.com/hyperledger/fabric/orderer/common/versionskew"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+// DeliverSource is a Source pulling the blocks from the Deliver service of
+// the active orderer
+type DeliverSource struct {
+	client  *comm.GRPCClient
+	address    string
+	signer     crypto.LocalSigner
+	negotiator *versionskew.Negotiator
+
+	mutex sync.Mutex
+	conn  *grpc.ClientConn
+}
+
+// NewDeliverSource creates a DeliverSource connecting with the client to
+// the active orderer at the address, and signing its requests with the
+// signer.  The negotiator may be nil, in which case the versions of the
+// orderers are not negotiated.
+func NewDeliverSource(client *comm.GRPCClient, address string, signer crypto.LocalSigner, negotiator *versionskew.Negotiator) *DeliverSource {
+	return &DeliverSource{
+		client:     client,
+		address:    address,
+		signer:     signer,
+		negotiator: negotiator,
+	}
+}
+
+// Pull passes to fn the blocks of the channel from number start to the
+// newest block of the active orderer
+func (ds *DeliverSource) Pull(channelID string, start uint64, fn func(*cb.Block) error) error {
+	return ds.deliver(channelID, &ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: start}}},
+		&ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: math.MaxUint64}}}, fn)
+}
+
+// Height returns the height of the ledger of the channel on the orderer
+func (ds *DeliverSource) Height(channelID string) (uint64, error) {
+	newest := &ab.SeekPosition{Type: &ab.SeekPosition_Newest{Newest: &ab.SeekNewest{}}}
+	var height uint64
+	err := ds.deliver(channelID, newest, newest, func(block *cb.Block) error {
+		if block.GetHeader() == nil {
+			return errors.Errorf("newest block of channel %s from %s has no header", channelID, ds.address)
+		}
+		height = block.Header.Number + 1
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	if height == 0 {
+		return 0, errors.Errorf("channel %s does not exist on %s", channelID, ds.address)
+	}
+	return height, nil
+}
+
+// deliver passes to fn the blocks of the channel between the start and stop
+// positions
+func (ds *DeliverSource) deliver(channelID string, start, stop *ab.SeekPosition, fn func(*cb.Block) error) error {
+	conn, err := ds.connection()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ds.negotiator.OutgoingContext(context.Background()))
+	defer cancel()
+	stream, err := ab.NewAtomicBroadcastClient(conn).Deliver(ctx)
+	if err != nil {
+		ds.reset(conn)
+		return errors.Wrapf(err, "error opening deliver stream to %s", ds.address)
+	}
+
+	env, err := ds.seekEnvelope(channelID, start, stop)
+	if err != nil {
+		return err
+	}
+	if err := stream.Send(env); err != nil {
+		ds.reset(conn)
+		return errors.Wrapf(err, "error sending deliver request to %s", ds.address)
+	}
+	//活动节点在响应头中声明其版本
+	if err := ds.negotiator.NegotiateClient(ds.address, stream); err != nil {
+		ds.reset(conn)
+		return err
+	}
+
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			ds.reset(conn)
+			return errors.Wrapf(err, "error receiving blocks from %s", ds.address)
+		}
+		switch t := resp.Type.(type) {
+		case *ab.DeliverResponse_Block:
+			if err := fn(t.Block); err != nil {
+				return err
+			}
+		case *ab.DeliverResponse_Status:
+			// 请求的区块已全部发送，或者活动节点上尚无新区块
+			if t.Status == cb.Status_SUCCESS || t.Status == cb.Status_NOT_FOUND {
+				return nil
+			}
+			return errors.Errorf("deliver of channel %s from %s failed with status %s", channelID, ds.address, t.Status)
+		default:
+			return errors.Errorf("unexpected deliver response type %T", t)
+		}
+	}
+}
+
+// seekEnvelope creates the request of the blocks of the channel between the
+// start and stop positions, bound to the TLS certificate of the client as
+// required by the Deliver service when mutual TLS is enabled
+func (ds *DeliverSource) seekEnvelope(channelID string, start, stop *ab.SeekPosition) (*cb.Envelope, error) {
+	var tlsCertHash []byte
+	if ds.client.MutualTLSRequired() {
+		if cert := ds.client.Certificate(); len(cert.Certificate) > 0 {
+			tlsCertHash = util.ComputeSHA256(cert.Certificate[0])
+		}
+	}
+	seekInfo := &ab.SeekInfo{
+		Start:    start,
+		Stop:     stop,
+		Behavior: ab.SeekInfo_FAIL_IF_NOT_READY,
+	}
+	env, err := utils.CreateSignedEnvelopeWithTLSBinding(cb.HeaderType_DELIVER_SEEK_INFO, channelID, ds.signer, seekInfo, 0, 0, tlsCertHash)
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating deliver request")
+	}
+	return env, nil
+}
+
+func (ds *DeliverSource) connection() (*grpc.ClientConn, error) {
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+	if ds.conn != nil {
+		return ds.conn, nil
+	}
+	conn, err := ds.client.NewConnection(ds.address, "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "error connecting to %s", ds.address)
+	}
+	ds.conn = conn
+	return conn, nil
+}
+
+// reset closes the connection after a failure, so that the next pull
+// reconnects to the active orderer
+func (ds *DeliverSource) reset(conn *grpc.ClientConn) {
+	ds.mutex.Lock()
+	defer ds.mutex.Unlock()
+	if ds.conn == conn {
+		ds.conn.Close()
+		ds.conn = nil
+	}
+}
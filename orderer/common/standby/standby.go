@@ -0,0 +1,435 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package standby runs an orderer as a cold standby of an active orderer:
+// the standby replicates the blocks of every channel of the active orderer
+// into its own ledgers, without participating in consensus or serving
+// clients, until it is promoted to take over for disaster recovery.
+package standby
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/channelconfig"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	ramledger "github.com/hyperledger/fabric/common/ledger/blockledger/ram"
+	"github.com/hyperledger/fabric/common/tools/blockarchive/archive"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/common/standby"
+
+// PromotedByAdmin is the reason recorded when the standby is promoted by
+// the admin operation
+const PromotedByAdmin = "promoted by an administrator"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// Source provides the blocks written by the active orderer
+type Source interface {
+	// Pull passes to fn, in order, the blocks of the channel which the
+	// active orderer has written from number start on, and returns once it
+	// has no more blocks or fn returns an error
+	Pull(channelID string, start uint64, fn func(*cb.Block) error) error
+}
+
+// Config contains the configuration of a Replicator
+type Config struct {
+	// PollInterval is how long the replicator waits between two pulls of
+	// the blocks of the channels
+	PollInterval time.Duration
+
+	// PromoteAfter, if not zero, is how long the active orderer may be
+	// unreachable before the standby promotes itself
+	PromoteAfter time.Duration
+}
+
+// ChannelStatus is the replication status of a channel
+type ChannelStatus struct {
+	Height uint64 `json:"height"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Status is the replication status of the standby
+type Status struct {
+	Promoted    bool                      `json:"promoted"`
+	Reason      string                    `json:"reason,omitempty"`
+	LastContact *time.Time                `json:"last_contact,omitempty"`
+	Channels    map[string]*ChannelStatus `json:"channels"`
+}
+
+// Replicator replicates the blocks of the active orderer into the ledgers
+// of the standby.  Each block is verified to extend the ledger of its
+// channel and to be signed according to the BlockValidation policy of the
+// channel, and the genesis block of each channel created on the system
+// channel is checked against the channel creation transaction.
+type Replicator struct {
+	conf    Config
+	source  Source
+	ledgers blockledger.Factory
+	now     func() time.Time
+
+	systemChannelID string
+	genesisHashes   map[string][]byte // 系统通道中创建的通道及其创世区块的数据哈希
+	verifiers       map[string]*archive.Verifier
+	started         time.Time
+	promote         chan string
+
+	mutex  sync.Mutex
+	status Status
+}
+
+// NewReplicator creates a Replicator of the blocks of the source into the
+// ledgers, which must hold the system channel
+func NewReplicator(conf Config, source Source, ledgers blockledger.Factory) (*Replicator, error) {
+	if conf.PollInterval <= 0 {
+		return nil, errors.New("poll interval must be positive")
+	}
+	r := &Replicator{
+		conf:          conf,
+		source:        source,
+		ledgers:       ledgers,
+		now:           time.Now,
+		genesisHashes: map[string][]byte{},
+		verifiers:     map[string]*archive.Verifier{},
+		promote:       make(chan string, 1),
+		status:        Status{Channels: map[string]*ChannelStatus{}},
+	}
+
+	for _, channelID := range ledgers.ChainIDs() {
+		ledger, err := ledgers.GetOrCreate(channelID)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error opening ledger of channel %s", channelID)
+		}
+		r.status.Channels[channelID] = &ChannelStatus{Height: ledger.Height()}
+		if isSystemChannel(channelID, ledger) {
+			r.systemChannelID = channelID
+		}
+	}
+	if r.systemChannelID == "" {
+		return nil, errors.New("no system channel among the ledgers")
+	}
+
+	// 扫描本地已有的系统通道区块，记录其中创建的通道
+	system, _ := ledgers.GetOrCreate(r.systemChannelID)
+	it, _ := system.Iterator(&ab.SeekPosition{Type: &ab.SeekPosition_Oldest{Oldest: &ab.SeekOldest{}}})
+	defer it.Close()
+	for number := uint64(0); number < system.Height(); number++ {
+		block, status := it.Next()
+		if status != cb.Status_SUCCESS {
+			return nil, errors.Errorf("could not read block %d of the system channel: %s", number, status)
+		}
+		r.recordCreatedChannels(block)
+	}
+	return r, nil
+}
+
+// Run replicates the blocks of the active orderer every poll interval, and
+// returns once the standby is promoted
+func (r *Replicator) Run() {
+	r.started = r.now()
+	logger.Infof("Replicating %d channels as a standby orderer", len(r.channels()))
+	ticker := time.NewTicker(r.conf.PollInterval)
+	defer ticker.Stop()
+	for {
+		r.replicate()
+
+		reason := r.unreachableReason()
+		if reason == "" {
+			select {
+			case reason = <-r.promote:
+			case <-ticker.C:
+				continue
+			}
+		}
+
+		r.mutex.Lock()
+		r.status.Promoted = true
+		r.status.Reason = reason
+		r.mutex.Unlock()
+		logger.Warningf("Standby orderer %s, stopping replication", reason)
+		return
+	}
+}
+
+// CatchUp replicates the blocks of the source once, without promoting the
+// standby, and returns the replication status
+func (r *Replicator) CatchUp() Status {
+	r.replicate()
+	return r.Status()
+}
+
+// Channels returns the channels replicated, including the channels created
+// by the blocks of the system channel replicated so far
+func (r *Replicator) Channels() []string {
+	return r.channels()
+}
+
+// Promote requests the promotion of the standby, which happens once the
+// replication in progress completes
+func (r *Replicator) Promote(reason string) {
+	select {
+	case r.promote <- reason:
+	default:
+	}
+}
+
+// Status returns the replication status of the standby
+func (r *Replicator) Status() Status {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	status := Status{
+		Promoted:    r.status.Promoted,
+		Reason:      r.status.Reason,
+		LastContact: r.status.LastContact,
+		Channels:    make(map[string]*ChannelStatus, len(r.status.Channels)),
+	}
+	for channelID, cs := range r.status.Channels {
+		copied := *cs
+		status.Channels[channelID] = &copied
+	}
+	return status
+}
+
+// ServeHTTP serves the replication status as JSON
+func (r *Replicator) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(r.Status()); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+// PromoteHandler returns the handler of the admin operation promoting the
+// standby.  It accepts the request, and the standby stops replicating and
+// starts consenting once the replication in progress completes.
+func (r *Replicator) PromoteHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if r.Status().Promoted {
+			http.Error(w, "standby already promoted", http.StatusConflict)
+			return
+		}
+		logger.Warningf("Promotion of the standby orderer requested by %s", req.RemoteAddr)
+		r.Promote(PromotedByAdmin)
+		w.WriteHeader(http.StatusAccepted)
+	})
+}
+
+// unreachableReason returns the reason to promote the standby if the active
+// orderer has been unreachable for longer than allowed
+func (r *Replicator) unreachableReason() string {
+	if r.conf.PromoteAfter == 0 {
+		return ""
+	}
+	r.mutex.Lock()
+	since := r.started
+	if r.status.LastContact != nil {
+		since = *r.status.LastContact
+	}
+	r.mutex.Unlock()
+	if r.now().Sub(since) <= r.conf.PromoteAfter {
+		return ""
+	}
+	return "promoted as the active orderer was unreachable for more than " + r.conf.PromoteAfter.String()
+}
+
+// channels returns the channels to replicate, the system channel first, so
+// that the channels it creates are replicated in the same round
+func (r *Replicator) channels() []string {
+	others := map[string]struct{}{}
+	for _, channelID := range r.ledgers.ChainIDs() {
+		others[channelID] = struct{}{}
+	}
+	for channelID := range r.genesisHashes {
+		others[channelID] = struct{}{}
+	}
+	delete(others, r.systemChannelID)
+
+	channels := make([]string, 0, len(others))
+	for channelID := range others {
+		channels = append(channels, channelID)
+	}
+	sort.Strings(channels)
+	return append([]string{r.systemChannelID}, channels...)
+}
+
+func (r *Replicator) replicate() {
+	// 先复制系统通道，再列举通道，使系统通道本轮创建的通道在同一轮中复制
+	r.replicateAndReport(r.systemChannelID)
+	for _, channelID := range r.channels()[1:] {
+		r.replicateAndReport(channelID)
+	}
+}
+
+// replicateAndReport replicates the blocks of the channel and records the
+// outcome in the status of the channel
+func (r *Replicator) replicateAndReport(channelID string) {
+	contacted, err := r.replicateChannel(channelID)
+
+	r.mutex.Lock()
+	if contacted {
+		now := r.now()
+		r.status.LastContact = &now
+	}
+	cs, ok := r.status.Channels[channelID]
+	if !ok {
+		cs = &ChannelStatus{}
+		r.status.Channels[channelID] = cs
+	}
+	if ledger, exists := r.ledger(channelID); exists {
+		cs.Height = ledger.Height()
+	}
+	cs.Error = ""
+	if err != nil {
+		cs.Error = err.Error()
+	}
+	r.mutex.Unlock()
+
+	if err != nil {
+		logger.Warningf("[channel: %s] Failed to replicate blocks: %s", channelID, err)
+	}
+}
+
+// replicateChannel appends to the ledger of the channel the blocks pulled
+// from the active orderer, and returns whether the active orderer answered
+func (r *Replicator) replicateChannel(channelID string) (bool, error) {
+	var reader blockledger.Reader
+	ledger, exists := r.ledger(channelID)
+	if exists {
+		reader = ledger
+	} else {
+		// 通道账本在验证其创世区块后才创建，避免遗留空的通道账本
+		reader, _ = ramledger.New(1).GetOrCreate(channelID)
+	}
+
+	verifier, ok := r.verifiers[channelID]
+	if !ok {
+		var err error
+		if verifier, err = archive.NewVerifier(channelID, reader); err != nil {
+			return false, err
+		}
+		r.verifiers[channelID] = verifier
+	}
+
+	contacted := false
+	err := r.source.Pull(channelID, reader.Height(), func(block *cb.Block) error {
+		contacted = true
+		if block.GetHeader() != nil && block.Header.Number == 0 && channelID != r.systemChannelID {
+			expected, ok := r.genesisHashes[channelID]
+			if !ok {
+				return errors.Errorf("channel %s was not created on the system channel", channelID)
+			}
+			if !bytes.Equal(block.GetData().Hash(), expected) {
+				return errors.Errorf("genesis block of channel %s does not match its creation transaction", channelID)
+			}
+		}
+		if err := verifier.Verify(block); err != nil {
+			return err
+		}
+
+		if ledger == nil {
+			var err error
+			if ledger, err = r.ledgers.GetOrCreate(channelID); err != nil {
+				delete(r.verifiers, channelID)
+				return errors.Wrapf(err, "error creating ledger of channel %s", channelID)
+			}
+			logger.Infof("[channel: %s] Created ledger of channel replicated from the active orderer", channelID)
+		}
+		if err := ledger.Append(block); err != nil {
+			// 验证器已越过未写入账本的区块，下一轮基于账本重新创建验证器
+			delete(r.verifiers, channelID)
+			return errors.Wrapf(err, "error appending block %d", block.Header.Number)
+		}
+		if channelID == r.systemChannelID {
+			r.recordCreatedChannels(block)
+		}
+		return nil
+	})
+	return contacted || err == nil, err
+}
+
+// ledger returns the ledger of the channel if the standby has one
+func (r *Replicator) ledger(channelID string) (blockledger.ReadWriter, bool) {
+	for _, existing := range r.ledgers.ChainIDs() {
+		if existing == channelID {
+			ledger, err := r.ledgers.GetOrCreate(channelID)
+			return ledger, err == nil
+		}
+	}
+	return nil, false
+}
+
+// recordCreatedChannels records the channels created by the channel
+// creation transactions of a block of the system channel, with the data
+// hash of the genesis block the orderer builds from each
+func (r *Replicator) recordCreatedChannels(block *cb.Block) {
+	for i := range block.GetData().GetData() {
+		env, err := utils.ExtractEnvelope(block, i)
+		if err != nil {
+			continue
+		}
+		chdr, err := utils.ChannelHeader(env)
+		if err != nil || chdr.Type != int32(cb.HeaderType_ORDERER_TRANSACTION) {
+			continue
+		}
+		payload, err := utils.UnmarshalPayload(env.Payload)
+		if err != nil {
+			continue
+		}
+		configtx, err := utils.UnmarshalEnvelope(payload.Data)
+		if err != nil {
+			continue
+		}
+		newChdr, err := utils.ChannelHeader(configtx)
+		if err != nil {
+			continue
+		}
+		// 与多通道注册管理器创建通道时构造创世区块的方式一致
+		r.genesisHashes[newChdr.ChannelId] = (&cb.BlockData{Data: [][]byte{utils.MarshalOrPanic(configtx)}}).Hash()
+	}
+}
+
+// isSystemChannel returns whether the genesis block of the ledger holds a
+// consortiums config, as only the system channel does
+func isSystemChannel(channelID string, ledger blockledger.Reader) bool {
+	genesis := blockledger.GetBlock(ledger, 0)
+	if genesis == nil {
+		return false
+	}
+	configtxEnv, err := utils.ExtractEnvelope(genesis, 0)
+	if err != nil {
+		return false
+	}
+	bundle, err := channelconfig.NewBundleFromEnvelope(configtxEnv)
+	if err != nil {
+		return false
+	}
+	_, ok := bundle.ConsortiumsConfig()
+	return ok
+}
@@ -0,0 +1,303 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package standby
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	ramledger "github.com/hyperledger/fabric/common/ledger/blockledger/ram"
+	"github.com/hyperledger/fabric/common/localmsp"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/configtxgentest"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/encoder"
+	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	"github.com/hyperledger/fabric/common/util"
+	msptesttools "github.com/hyperledger/fabric/msp/mgmt/testtools"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	systemChannelID = "systemchannel"
+	appChannelID    = "appchannel"
+)
+
+func TestMain(m *testing.M) {
+	if err := msptesttools.LoadDevMsp(); err != nil {
+		fmt.Printf("Failed to load dev MSP: %s\n", err)
+		os.Exit(-1)
+	}
+	os.Exit(m.Run())
+}
+
+// mockSource serves the blocks of its ledgers, or fails with err
+type mockSource struct {
+	ledgers blockledger.Factory
+	err     error
+}
+
+func (ms *mockSource) Pull(channelID string, start uint64, fn func(*cb.Block) error) error {
+	if ms.err != nil {
+		return ms.err
+	}
+	ledger, _ := ms.ledgers.GetOrCreate(channelID)
+	for number := start; number < ledger.Height(); number++ {
+		if err := fn(blockledger.GetBlock(ledger, number)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// signBlock signs the block as an orderer would, recording the genesis block
+// as its last config
+func signBlock(block *cb.Block, signer crypto.LocalSigner) {
+	value := utils.MarshalOrPanic(&cb.LastConfig{Index: 0})
+	block.Metadata.Metadata[cb.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&cb.Metadata{Value: value})
+
+	shdr, _ := signer.NewSignatureHeader()
+	shdrBytes := utils.MarshalOrPanic(shdr)
+	signature, _ := signer.Sign(util.ConcatenateBytes(value, shdrBytes, block.Header.Bytes()))
+	block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{
+		Value:      value,
+		Signatures: []*cb.MetadataSignature{{SignatureHeader: shdrBytes, Signature: signature}},
+	})
+}
+
+func appendBlock(t *testing.T, ledger blockledger.ReadWriter, envs ...*cb.Envelope) {
+	block := blockledger.CreateNextBlock(ledger, envs)
+	signBlock(block, localmsp.NewSigner())
+	require.NoError(t, ledger.Append(block))
+}
+
+// genesisBlock returns a genesis block of an application channel, whose
+// config has no consortiums, unlike the one of the system channel
+func genesisBlock(channelID string) *cb.Block {
+	profile := configtxgentest.Load(genesisconfig.SampleInsecureSoloProfile)
+	profile.Consortiums = nil
+	profile.Application = &genesisconfig.Application{}
+	return encoder.New(profile).GenesisBlockForChannel(channelID)
+}
+
+var (
+	systemGenesisOnce sync.Once
+	systemGenesis     *cb.Block
+)
+
+// systemGenesisBlock returns a copy of the genesis block of the system
+// channel the active and the standby share, as every genesis block generated
+// has a nonce of its own
+func systemGenesisBlock() *cb.Block {
+	systemGenesisOnce.Do(func() {
+		systemGenesis = encoder.New(configtxgentest.Load(genesisconfig.SampleInsecureSoloProfile)).GenesisBlockForChannel(systemChannelID)
+	})
+	return proto.Clone(systemGenesis).(*cb.Block)
+}
+
+// newActive returns the ledgers of an active orderer, whose system channel
+// created the application channel, which holds two blocks besides its
+// genesis block
+func newActive(t *testing.T) blockledger.Factory {
+	lf := ramledger.New(10)
+	system, _ := lf.GetOrCreate(systemChannelID)
+	require.NoError(t, system.Append(systemGenesisBlock()))
+
+	configtx, err := utils.ExtractEnvelope(genesisBlock(appChannelID), 0)
+	require.NoError(t, err)
+	ordererTx, err := utils.CreateSignedEnvelope(cb.HeaderType_ORDERER_TRANSACTION, systemChannelID, localmsp.NewSigner(), configtx, 0, 0)
+	require.NoError(t, err)
+	appendBlock(t, system, ordererTx)
+
+	app, _ := lf.GetOrCreate(appChannelID)
+	require.NoError(t, app.Append(blockledger.CreateNextBlock(app, []*cb.Envelope{configtx})))
+	for i := 0; i < 2; i++ {
+		appendBlock(t, app, &cb.Envelope{Payload: []byte(fmt.Sprintf("tx%d", i))})
+	}
+	return lf
+}
+
+// newStandby returns the ledgers of a standby bootstrapped with the genesis
+// block of the system channel
+func newStandby(t *testing.T) blockledger.Factory {
+	lf := ramledger.New(10)
+	system, _ := lf.GetOrCreate(systemChannelID)
+	require.NoError(t, system.Append(systemGenesisBlock()))
+	return lf
+}
+
+func height(lf blockledger.Factory, channelID string) uint64 {
+	ledger, _ := lf.GetOrCreate(channelID)
+	return ledger.Height()
+}
+
+func TestNewReplicator(t *testing.T) {
+	_, err := NewReplicator(Config{}, &mockSource{}, newStandby(t))
+	assert.EqualError(t, err, "poll interval must be positive")
+
+	_, err = NewReplicator(Config{PollInterval: time.Second}, &mockSource{}, ramledger.New(10))
+	assert.EqualError(t, err, "no system channel among the ledgers")
+
+	r, err := NewReplicator(Config{PollInterval: time.Second}, &mockSource{}, newStandby(t))
+	require.NoError(t, err)
+	assert.Equal(t, systemChannelID, r.systemChannelID)
+	assert.Equal(t, []string{systemChannelID}, r.channels())
+}
+
+func TestReplicate(t *testing.T) {
+	active := newActive(t)
+	standby := newStandby(t)
+	r, err := NewReplicator(Config{PollInterval: time.Second}, &mockSource{ledgers: active}, standby)
+	require.NoError(t, err)
+
+	r.replicate()
+	assert.Equal(t, uint64(2), height(standby, systemChannelID))
+	assert.Equal(t, uint64(3), height(standby, appChannelID))
+	status := r.Status()
+	assert.False(t, status.Promoted)
+	assert.NotNil(t, status.LastContact)
+	assert.Equal(t, &ChannelStatus{Height: 3}, status.Channels[appChannelID])
+
+	// 后续轮次只复制新的区块
+	app, _ := active.GetOrCreate(appChannelID)
+	appendBlock(t, app, &cb.Envelope{Payload: []byte("tx2")})
+	r.replicate()
+	assert.Equal(t, uint64(4), height(standby, appChannelID))
+
+	// 重启后从本地系统通道恢复已创建的通道
+	r, err = NewReplicator(Config{PollInterval: time.Second}, &mockSource{ledgers: active}, standby)
+	require.NoError(t, err)
+	assert.Equal(t, []string{systemChannelID, appChannelID}, r.channels())
+	assert.Contains(t, r.genesisHashes, appChannelID)
+}
+
+func TestReplicateTamperedBlock(t *testing.T) {
+	active := newActive(t)
+	app, _ := active.GetOrCreate(appChannelID)
+	blockledger.GetBlock(app, 2).Data.Data[0] = []byte("tampered")
+
+	standby := newStandby(t)
+	r, err := NewReplicator(Config{PollInterval: time.Second}, &mockSource{ledgers: active}, standby)
+	require.NoError(t, err)
+
+	r.replicate()
+	assert.Equal(t, uint64(2), height(standby, appChannelID))
+	assert.Equal(t, &ChannelStatus{Height: 2, Error: "data hash of block 2 does not match its data"}, r.Status().Channels[appChannelID])
+}
+
+func TestReplicateUncreatedChannel(t *testing.T) {
+	active := newActive(t)
+	other, _ := active.GetOrCreate("otherchannel")
+	require.NoError(t, other.Append(genesisBlock("otherchannel")))
+
+	standby := newStandby(t)
+	r, err := NewReplicator(Config{PollInterval: time.Second}, &mockSource{ledgers: active}, standby)
+	require.NoError(t, err)
+
+	contacted, err := r.replicateChannel("otherchannel")
+	assert.True(t, contacted)
+	assert.EqualError(t, err, "channel otherchannel was not created on the system channel")
+	assert.NotContains(t, standby.ChainIDs(), "otherchannel")
+}
+
+func TestReplicateMismatchedGenesis(t *testing.T) {
+	active := newActive(t)
+	standby := newStandby(t)
+	r, err := NewReplicator(Config{PollInterval: time.Second}, &mockSource{ledgers: active}, standby)
+	require.NoError(t, err)
+	r.replicate()
+
+	r.genesisHashes["otherchannel"] = []byte("hash")
+	other, _ := active.GetOrCreate("otherchannel")
+	require.NoError(t, other.Append(genesisBlock("otherchannel")))
+	_, err = r.replicateChannel("otherchannel")
+	assert.EqualError(t, err, "genesis block of channel otherchannel does not match its creation transaction")
+	assert.NotContains(t, standby.ChainIDs(), "otherchannel")
+}
+
+func TestPromote(t *testing.T) {
+	r, err := NewReplicator(Config{PollInterval: time.Millisecond}, &mockSource{ledgers: newActive(t)}, newStandby(t))
+	require.NoError(t, err)
+
+	r.Promote(PromotedByAdmin)
+	r.Promote("ignored while a promotion is pending")
+	r.Run()
+	status := r.Status()
+	assert.True(t, status.Promoted)
+	assert.Equal(t, PromotedByAdmin, status.Reason)
+	require.NotNil(t, status.Channels[appChannelID])
+	assert.Equal(t, uint64(3), status.Channels[appChannelID].Height)
+}
+
+func TestPromoteUnreachable(t *testing.T) {
+	source := &mockSource{err: errors.New("connection refused")}
+	r, err := NewReplicator(Config{PollInterval: time.Millisecond, PromoteAfter: 10 * time.Millisecond}, source, newStandby(t))
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	go func() {
+		r.Run()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("standby was not promoted")
+	}
+	status := r.Status()
+	assert.True(t, status.Promoted)
+	assert.Equal(t, "promoted as the active orderer was unreachable for more than 10ms", status.Reason)
+	assert.Nil(t, status.LastContact)
+	assert.Equal(t, "connection refused", status.Channels[systemChannelID].Error)
+}
+
+func TestServeHTTP(t *testing.T) {
+	r, err := NewReplicator(Config{PollInterval: time.Millisecond}, &mockSource{ledgers: newActive(t)}, newStandby(t))
+	require.NoError(t, err)
+	r.replicate()
+
+	resp := httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/standby", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var status Status
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &status))
+	require.NotNil(t, status.Channels[systemChannelID])
+	assert.Equal(t, uint64(2), status.Channels[systemChannelID].Height)
+	require.NotNil(t, status.Channels[appChannelID])
+	assert.Equal(t, uint64(3), status.Channels[appChannelID].Height)
+
+	resp = httptest.NewRecorder()
+	r.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/standby", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+	assert.Equal(t, http.MethodGet, resp.Header().Get("Allow"))
+
+	promote := r.PromoteHandler()
+	resp = httptest.NewRecorder()
+	promote.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/standby/promote", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+	assert.Equal(t, http.MethodPost, resp.Header().Get("Allow"))
+
+	resp = httptest.NewRecorder()
+	promote.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/standby/promote", nil))
+	assert.Equal(t, http.StatusAccepted, resp.Code)
+	r.Run()
+
+	resp = httptest.NewRecorder()
+	promote.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/standby/promote", nil))
+	assert.Equal(t, http.StatusConflict, resp.Code)
+}
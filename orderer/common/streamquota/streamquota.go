@@ -0,0 +1,223 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package streamquota bounds the broadcast and deliver streams each
+// authenticated identity holds open on a channel, so that a misconfigured
+// client, such as a gateway opening a stream per request, cannot exhaust the
+// orderer however many hosts or TLS certificates it connects from.
+//
+// A stream counts towards the quota of an identity once a message it signed
+// was authenticated on the stream, so that nobody can exhaust the quota of
+// another identity, and until the stream ends.
+package streamquota
+
+import (
+	"crypto/sha256"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Kind is the kind of a stream
+type Kind string
+
+const (
+	// Broadcast streams submit messages for ordering
+	Broadcast Kind = "broadcast"
+	// Deliver streams read the blocks of a channel
+	Deliver Kind = "deliver"
+)
+
+// ErrTooManyStreams is the cause of the errors returned for the streams
+// exceeding the quota of their identity.
+var ErrTooManyStreams = errors.New("too many streams")
+
+// ChannelLimit overrides the default limits of a channel.
+type ChannelLimit struct {
+	Channel string
+
+	// MaxBroadcastStreams is the number of broadcast streams each identity
+	// may have open on the channel, 0 for no limit
+	MaxBroadcastStreams int
+
+	// MaxDeliverStreams is the number of deliver streams each identity may
+	// have open on the channel, 0 for no limit
+	MaxDeliverStreams int
+}
+
+// Config contains the configuration of a Quota.
+type Config struct {
+	// MaxBroadcastStreams is the number of broadcast streams each identity
+	// may have open on a channel unless overridden by Channels, 0 for no
+	// limit
+	MaxBroadcastStreams int
+
+	// MaxDeliverStreams is the number of deliver streams each identity may
+	// have open on a channel unless overridden by Channels, 0 for no limit
+	MaxDeliverStreams int
+
+	// Channels overrides the limits of individual channels
+	Channels []ChannelLimit
+}
+
+// Enabled returns whether the configuration limits any stream
+func (c Config) Enabled() bool {
+	if c.MaxBroadcastStreams > 0 || c.MaxDeliverStreams > 0 {
+		return true
+	}
+	for _, cl := range c.Channels {
+		if cl.MaxBroadcastStreams > 0 || cl.MaxDeliverStreams > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// max returns the limit of the streams of the kind
+func (cl ChannelLimit) max(kind Kind) int {
+	if kind == Broadcast {
+		return cl.MaxBroadcastStreams
+	}
+	return cl.MaxDeliverStreams
+}
+
+// streamKey identifies the streams of a kind of an identity on a channel
+type streamKey struct {
+	kind      Kind
+	channelID string
+	identity  [sha256.Size]byte
+}
+
+// Quota counts the streams each identity has open on each channel.  A nil
+// Quota sets no limit.
+type Quota struct {
+	defaults ChannelLimit
+	channels map[string]ChannelLimit
+	rejected *prometheus.CounterVec
+
+	mutex sync.Mutex
+	open  map[streamKey]int
+}
+
+// New creates a Quota enforcing the limits of the configuration, and
+// registers the counter of the streams it rejects with the registerer.  It
+// returns an error if a limit is invalid.
+func New(conf Config, registerer prometheus.Registerer) (*Quota, error) {
+	if conf.MaxBroadcastStreams < 0 || conf.MaxDeliverStreams < 0 {
+		return nil, errors.New("stream limits must not be negative")
+	}
+	q := &Quota{
+		defaults: ChannelLimit{MaxBroadcastStreams: conf.MaxBroadcastStreams, MaxDeliverStreams: conf.MaxDeliverStreams},
+		channels: map[string]ChannelLimit{},
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "orderer",
+			Subsystem: "stream_quota",
+			Name:      "rejected_total",
+			Help:      "The number of streams rejected because their identity had too many streams open, by kind of stream and channel.",
+		}, []string{"kind", "channel"}),
+		open: map[streamKey]int{},
+	}
+	for _, cl := range conf.Channels {
+		if cl.Channel == "" {
+			return nil, errors.New("stream limit declared without a channel")
+		}
+		if _, exists := q.channels[cl.Channel]; exists {
+			return nil, errors.Errorf("stream limit of channel %s declared more than once", cl.Channel)
+		}
+		if cl.MaxBroadcastStreams < 0 || cl.MaxDeliverStreams < 0 {
+			return nil, errors.Errorf("stream limit of channel %s is negative", cl.Channel)
+		}
+		q.channels[cl.Channel] = cl
+	}
+	if err := registerer.Register(q.rejected); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+// Open returns the Stream tracking the identities a stream of the kind counts
+// towards, nil if the Quota is nil
+func (q *Quota) Open(kind Kind) *Stream {
+	if q == nil {
+		return nil
+	}
+	return &Stream{quota: q, kind: kind, held: map[streamKey]bool{}}
+}
+
+// Streams returns the number of streams of the kind the identity has open on
+// the channel
+func (q *Quota) Streams(kind Kind, channelID string, identity []byte) int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return q.open[streamKey{kind: kind, channelID: channelID, identity: sha256.Sum256(identity)}]
+}
+
+// limit returns the number of streams of the kind an identity may have open
+// on the channel
+func (q *Quota) limit(kind Kind, channelID string) int {
+	if cl, ok := q.channels[channelID]; ok {
+		return cl.max(kind)
+	}
+	return q.defaults.max(kind)
+}
+
+// Stream is a stream counting towards the quotas of the identities
+// authenticated on it.  A nil Stream counts towards no quota.
+type Stream struct {
+	quota *Quota
+	kind  Kind
+
+	mutex sync.Mutex
+	held  map[streamKey]bool
+}
+
+// Acquire counts the stream towards the quota of the identity on the
+// channel, unless it already does, and returns an error whose cause is
+// ErrTooManyStreams if the identity has as many other streams open on the
+// channel as it may.  The identity must have been authenticated.
+func (s *Stream) Acquire(channelID string, identity []byte) error {
+	if s == nil {
+		return nil
+	}
+	max := s.quota.limit(s.kind, channelID)
+	if max == 0 {
+		return nil
+	}
+	key := streamKey{kind: s.kind, channelID: channelID, identity: sha256.Sum256(identity)}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.held[key] {
+		return nil
+	}
+	s.quota.mutex.Lock()
+	defer s.quota.mutex.Unlock()
+	if open := s.quota.open[key]; open >= max {
+		s.quota.rejected.WithLabelValues(string(s.kind), channelID).Inc()
+		return errors.Wrapf(ErrTooManyStreams, "identity already has %d %s streams open on channel %s, the maximum", open, s.kind, channelID)
+	}
+	s.quota.open[key]++
+	s.held[key] = true
+	return nil
+}
+
+// Close releases the quotas the stream counts towards, once it ended
+func (s *Stream) Close() {
+	if s == nil {
+		return
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.quota.mutex.Lock()
+	defer s.quota.mutex.Unlock()
+	for key := range s.held {
+		if s.quota.open[key]--; s.quota.open[key] == 0 {
+			delete(s.quota.open, key)
+		}
+		delete(s.held, key)
+	}
+}
@@ -0,0 +1,148 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package streamquota
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func rejectedCount(t *testing.T, registry *prometheus.Registry, kind Kind, channelID string) float64 {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != "orderer_stream_quota_rejected_total" {
+			continue
+		}
+		for _, m := range family.Metric {
+			labels := map[string]string{}
+			for _, l := range m.Label {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["kind"] == string(kind) && labels["channel"] == channelID {
+				return m.Counter.GetValue()
+			}
+		}
+	}
+	return 0
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{MaxDeliverStreams: -1}, prometheus.NewRegistry())
+	assert.EqualError(t, err, "stream limits must not be negative")
+
+	_, err = New(Config{Channels: []ChannelLimit{{MaxBroadcastStreams: 1}}}, prometheus.NewRegistry())
+	assert.EqualError(t, err, "stream limit declared without a channel")
+
+	_, err = New(Config{Channels: []ChannelLimit{{Channel: "foo"}, {Channel: "foo"}}}, prometheus.NewRegistry())
+	assert.EqualError(t, err, "stream limit of channel foo declared more than once")
+
+	_, err = New(Config{Channels: []ChannelLimit{{Channel: "foo", MaxBroadcastStreams: -1}}}, prometheus.NewRegistry())
+	assert.EqualError(t, err, "stream limit of channel foo is negative")
+
+	registry := prometheus.NewRegistry()
+	_, err = New(Config{MaxBroadcastStreams: 1}, registry)
+	require.NoError(t, err)
+	_, err = New(Config{MaxBroadcastStreams: 1}, registry)
+	assert.Error(t, err)
+
+	assert.False(t, Config{}.Enabled())
+	assert.True(t, Config{MaxDeliverStreams: 1}.Enabled())
+	assert.True(t, Config{Channels: []ChannelLimit{{Channel: "foo", MaxBroadcastStreams: 1}}}.Enabled())
+}
+
+func TestAcquire(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	q, err := New(Config{MaxBroadcastStreams: 2, MaxDeliverStreams: 1}, registry)
+	require.NoError(t, err)
+
+	first, second, third := q.Open(Broadcast), q.Open(Broadcast), q.Open(Broadcast)
+	assert.NoError(t, first.Acquire("foo", []byte("alice")))
+	// 同一消息流只计入一次
+	assert.NoError(t, first.Acquire("foo", []byte("alice")))
+	assert.NoError(t, second.Acquire("foo", []byte("alice")))
+	assert.Equal(t, 2, q.Streams(Broadcast, "foo", []byte("alice")))
+
+	err = third.Acquire("foo", []byte("alice"))
+	assert.EqualError(t, err, "identity already has 2 broadcast streams open on channel foo, the maximum: too many streams")
+	assert.Equal(t, ErrTooManyStreams, errors.Cause(err))
+	assert.Equal(t, float64(1), rejectedCount(t, registry, Broadcast, "foo"))
+
+	// 配额按身份、通道与消息流类型分别计数
+	assert.NoError(t, third.Acquire("foo", []byte("bob")))
+	assert.NoError(t, third.Acquire("bar", []byte("alice")))
+	deliver := q.Open(Deliver)
+	assert.NoError(t, deliver.Acquire("foo", []byte("alice")))
+	assert.Error(t, q.Open(Deliver).Acquire("foo", []byte("alice")))
+	assert.Equal(t, float64(1), rejectedCount(t, registry, Deliver, "foo"))
+
+	// 消息流结束后释放其配额
+	first.Close()
+	assert.Equal(t, 1, q.Streams(Broadcast, "foo", []byte("alice")))
+	assert.NoError(t, third.Acquire("foo", []byte("alice")))
+	second.Close()
+	third.Close()
+	deliver.Close()
+	assert.Empty(t, q.open)
+}
+
+func TestChannelLimits(t *testing.T) {
+	q, err := New(Config{
+		MaxBroadcastStreams: 1,
+		Channels:            []ChannelLimit{{Channel: "unlimited"}, {Channel: "busy", MaxBroadcastStreams: 3, MaxDeliverStreams: 1}},
+	}, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	for i := 0; i < 10; i++ {
+		assert.NoError(t, q.Open(Broadcast).Acquire("unlimited", []byte("alice")))
+		assert.NoError(t, q.Open(Deliver).Acquire("foo", []byte("alice")))
+	}
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, q.Open(Broadcast).Acquire("busy", []byte("alice")))
+	}
+	assert.Error(t, q.Open(Broadcast).Acquire("busy", []byte("alice")))
+	assert.NoError(t, q.Open(Deliver).Acquire("busy", []byte("alice")))
+	assert.Error(t, q.Open(Deliver).Acquire("busy", []byte("alice")))
+	assert.NoError(t, q.Open(Broadcast).Acquire("foo", []byte("alice")))
+	assert.Error(t, q.Open(Broadcast).Acquire("foo", []byte("alice")))
+}
+
+func TestNilQuota(t *testing.T) {
+	var q *Quota
+	s := q.Open(Broadcast)
+	assert.Nil(t, s)
+	assert.NoError(t, s.Acquire("foo", []byte("alice")))
+	s.Close()
+}
+
+func TestConcurrentStreams(t *testing.T) {
+	q, err := New(Config{MaxDeliverStreams: 5}, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	acquired := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if q.Open(Deliver).Acquire("foo", []byte("alice")) == nil {
+				mutex.Lock()
+				acquired++
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 5, acquired)
+	assert.Equal(t, 5, q.Streams(Deliver, "foo", []byte("alice")))
+}
@@ -0,0 +1,632 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package tracing traces the messages broadcast to the orderer through the
+// stages of ordering, so that their latency can be attributed end to end:
+// ingress, the handling of a message by the broadcast service, consensus,
+// until the message is ordered by the consenter, cut, until the block of the
+// ordered message is cut, the commit of the block to the ledger, and its
+// delivery to each Deliver stream.
+//
+// The spans follow the data model of OpenTelemetry, and their trace and span
+// IDs are those of the W3C Trace Context.  A client joins the spans of the
+// messages of a stream to its own trace by sending the traceparent gRPC
+// metadata when opening the stream.  The spans of the commit and of the
+// deliveries of a block form a trace of their own, linked to the spans of
+// the messages it contains.  The finished spans are kept in memory and
+// exported as JSON.
+package tracing
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	mathrand "math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+const pkgLogID = "orderer/common/tracing"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+const (
+	// DefaultCapacity is the number of finished spans kept when no capacity
+	// is configured.
+	DefaultCapacity = 10000
+
+	// maxPending bounds the transactions traced while they await their block.
+	maxPending = 100000
+
+	// maxBlocks bounds the blocks whose commit and deliveries are traced.
+	maxBlocks = 1000
+)
+
+// TraceparentKey is the gRPC metadata key carrying the W3C traceparent of
+// the span of the client.
+const TraceparentKey = "traceparent"
+
+// The names of the spans
+const (
+	// SpanBroadcast spans the handling of a message by the broadcast service,
+	// from its receipt to the response
+	SpanBroadcast = "orderer.broadcast"
+
+	// SpanProcess spans the validation of a message by its message processor
+	SpanProcess = "orderer.broadcast.process"
+
+	// SpanWaitReady spans the wait for the consenter to be ready
+	SpanWaitReady = "orderer.broadcast.wait_ready"
+
+	// SpanOrder spans the submission of a message to the consenter
+	SpanOrder = "orderer.broadcast.order"
+
+	// SpanConsensus spans the wait of a submitted message to be ordered by
+	// the consenter
+	SpanConsensus = "orderer.consensus"
+
+	// SpanCut spans the wait of an ordered message for its block to be cut
+	SpanCut = "orderer.cut"
+
+	// SpanBlock spans the commit of a block, from its cut to its append to
+	// the ledger, and is the root of the trace of the block
+	SpanBlock = "orderer.block"
+
+	// SpanDeliver spans the delivery of a committed block to a Deliver stream
+	SpanDeliver = "orderer.deliver"
+)
+
+// TraceID identifies a trace
+type TraceID [16]byte
+
+func (id TraceID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanID identifies a span within its trace
+type SpanID [8]byte
+
+func (id SpanID) String() string { return hex.EncodeToString(id[:]) }
+
+// SpanContext identifies a span
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid returns false if the trace or the span ID is zero
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != TraceID{} && sc.SpanID != SpanID{}
+}
+
+// Traceparent returns the W3C traceparent of the sampled span
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", sc.TraceID, sc.SpanID)
+}
+
+// ParseTraceparent parses a W3C traceparent, and returns the span it
+// identifies and whether it is sampled
+func ParseTraceparent(traceparent string) (SpanContext, bool, error) {
+	var sc SpanContext
+	fields := strings.Split(strings.TrimSpace(traceparent), "-")
+	if len(fields) < 4 || len(fields[0]) != 2 || len(fields[3]) != 2 {
+		return sc, false, errors.Errorf("malformed traceparent %q", traceparent)
+	}
+	version, err := strconv.ParseUint(fields[0], 16, 8)
+	//版本00不允许附加字段，更高版本的附加字段被忽略
+	if err != nil || version == 0xff || (version == 0 && len(fields) != 4) {
+		return sc, false, errors.Errorf("unsupported traceparent version in %q", traceparent)
+	}
+	if n, err := hex.Decode(sc.TraceID[:], []byte(fields[1])); err != nil || n != len(sc.TraceID) || len(fields[1]) != 2*len(sc.TraceID) {
+		return sc, false, errors.Errorf("malformed trace ID in traceparent %q", traceparent)
+	}
+	if n, err := hex.Decode(sc.SpanID[:], []byte(fields[2])); err != nil || n != len(sc.SpanID) || len(fields[2]) != 2*len(sc.SpanID) {
+		return sc, false, errors.Errorf("malformed span ID in traceparent %q", traceparent)
+	}
+	if !sc.IsValid() {
+		return sc, false, errors.Errorf("zero trace or span ID in traceparent %q", traceparent)
+	}
+	flags, err := strconv.ParseUint(fields[3], 16, 8)
+	if err != nil {
+		return sc, false, errors.Errorf("malformed flags in traceparent %q", traceparent)
+	}
+	return sc, flags&1 == 1, nil
+}
+
+// Link is the exported form of a link from a span to another
+type Link struct {
+	TraceID string `json:"trace_id"`
+	SpanID  string `json:"span_id"`
+}
+
+// SpanData is the exported form of a finished span
+type SpanData struct {
+	TraceID    string            `json:"trace_id"`
+	SpanID     string            `json:"span_id"`
+	ParentID   string            `json:"parent_span_id,omitempty"`
+	Name       string            `json:"name"`
+	Start      time.Time         `json:"start"`
+	End        time.Time         `json:"end"`
+	Duration   string            `json:"duration"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Links      []Link            `json:"links,omitempty"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// Export is the exported form of the finished spans
+type Export struct {
+	Spans []SpanData `json:"spans"`
+}
+
+// Span is a span being recorded.  A span is used by a single goroutine, and
+// all its methods are safe to call on a nil Span, which is not sampled.
+type Span struct {
+	tracer     *Tracer
+	ctx        SpanContext
+	parent     SpanID
+	name       string
+	start      time.Time
+	attributes map[string]string
+	links      []SpanContext
+	err        string
+}
+
+// Context returns the identity of the span
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.ctx
+}
+
+// SetAttribute sets an attribute of the span
+func (s *Span) SetAttribute(key, value string) {
+	if s == nil {
+		return
+	}
+	if s.attributes == nil {
+		s.attributes = map[string]string{}
+	}
+	s.attributes[key] = value
+}
+
+// AddLink links the span to another
+func (s *Span) AddLink(sc SpanContext) {
+	if s == nil || !sc.IsValid() {
+		return
+	}
+	s.links = append(s.links, sc)
+}
+
+// SetError marks the span as failed with the description
+func (s *Span) SetError(description string) {
+	if s == nil {
+		return
+	}
+	s.err = description
+}
+
+// End finishes the span
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.tracer.mutex.Lock()
+	defer s.tracer.mutex.Unlock()
+	s.tracer.record(s, s.tracer.now())
+}
+
+type spanKey struct{}
+
+// ContextWithSpan returns a context carrying the span
+func ContextWithSpan(ctx context.Context, span *Span) context.Context {
+	if span == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, spanKey{}, span)
+}
+
+// FromContext returns the span carried by the context, or nil
+func FromContext(ctx context.Context) *Span {
+	span, _ := ctx.Value(spanKey{}).(*Span)
+	return span
+}
+
+// remoteSpan returns the span the client propagated in the gRPC metadata of
+// the context, if any, and whether it is sampled
+func remoteSpan(ctx context.Context) (SpanContext, bool, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok || len(md[TraceparentKey]) == 0 {
+		return SpanContext{}, false, false
+	}
+	sc, sampled, err := ParseTraceparent(md[TraceparentKey][0])
+	if err != nil {
+		logger.Debugf("Ignoring traceparent of client: %s", err)
+		return SpanContext{}, false, false
+	}
+	return sc, sampled, true
+}
+
+// Config contains the configuration of a Tracer.
+type Config struct {
+	// SampleRatio is the fraction of the messages whose client propagates no
+	// trace which are traced, between 0 and 1.  The messages whose client
+	// propagates a trace are traced if the client samples its trace.
+	SampleRatio float64
+
+	// Capacity is the number of finished spans kept, oldest evicted first
+	Capacity int
+}
+
+type txKey struct {
+	channelID string
+	txID      string
+}
+
+// pendingTx is a transaction traced while it awaits its block
+type pendingTx struct {
+	span      SpanContext
+	submitted time.Time
+	ordered   time.Time
+}
+
+type blockKey struct {
+	channelID string
+	number    uint64
+}
+
+// tracedBlock is a block containing traced transactions
+type tracedBlock struct {
+	span      SpanContext
+	cut       time.Time
+	committed time.Time
+	txs       []SpanContext
+}
+
+// Tracer records the spans of the messages ordered and of their blocks.  All
+// methods are safe to call on a nil Tracer, in which case they do nothing,
+// so that call sites need not check whether tracing is enabled.
+type Tracer struct {
+	ratio  float64
+	now    func() time.Time
+	sample func(ratio float64) bool
+
+	mutex      sync.Mutex
+	spans      []SpanData
+	head       int
+	count      int
+	pending    map[txKey]*pendingTx
+	blocks     map[blockKey]*tracedBlock
+	blockOrder []blockKey
+}
+
+// New creates a Tracer, or returns an error if the configuration is invalid
+func New(conf Config) (*Tracer, error) {
+	if conf.SampleRatio < 0 || conf.SampleRatio > 1 {
+		return nil, errors.Errorf("sample ratio must be between 0 and 1, got %g", conf.SampleRatio)
+	}
+	if conf.Capacity < 0 {
+		return nil, errors.Errorf("span capacity must not be negative, got %d", conf.Capacity)
+	}
+	capacity := conf.Capacity
+	if capacity == 0 {
+		capacity = DefaultCapacity
+	}
+	return &Tracer{
+		ratio:   conf.SampleRatio,
+		now:     time.Now,
+		sample:  func(ratio float64) bool { return mathrand.Float64() < ratio },
+		spans:   make([]SpanData, capacity),
+		pending: map[txKey]*pendingTx{},
+		blocks:  map[blockKey]*tracedBlock{},
+	}, nil
+}
+
+func newSpanID() SpanID {
+	var id SpanID
+	rand.Read(id[:])
+	return id
+}
+
+func newTraceID() TraceID {
+	var id TraceID
+	rand.Read(id[:])
+	return id
+}
+
+// newSpan returns a span, the child of the parent if it is valid, or the
+// root of a new trace
+func (t *Tracer) newSpan(parent SpanContext, name string, start time.Time) *Span {
+	span := &Span{tracer: t, name: name, start: start}
+	if parent.IsValid() {
+		span.ctx = SpanContext{TraceID: parent.TraceID, SpanID: newSpanID()}
+		span.parent = parent.SpanID
+	} else {
+		span.ctx = SpanContext{TraceID: newTraceID(), SpanID: newSpanID()}
+	}
+	return span
+}
+
+// StartServerSpan starts the span of a request of a client at the given
+// time.  The span is the child of the span the client propagated, the
+// request is not traced if the client does not sample its trace, and the
+// span is the root of a new trace sampled at the sample ratio if the client
+// propagated none.  The returned context carries the span, if it is sampled.
+func (t *Tracer) StartServerSpan(ctx context.Context, name string, start time.Time) (context.Context, *Span) {
+	if t == nil {
+		return ctx, nil
+	}
+	parent, sampled, ok := remoteSpan(ctx)
+	if (ok && !sampled) || (!ok && !t.sample(t.ratio)) {
+		return ctx, nil
+	}
+	span := t.newSpan(parent, name, start)
+	return ContextWithSpan(ctx, span), span
+}
+
+// StartSpan starts a child of the span carried by the context.  Nothing is
+// traced if the context carries no span.
+func (t *Tracer) StartSpan(ctx context.Context, name string) (context.Context, *Span) {
+	parent := FromContext(ctx)
+	if t == nil || parent == nil {
+		return ctx, nil
+	}
+	span := t.newSpan(parent.ctx, name, t.now())
+	return ContextWithSpan(ctx, span), span
+}
+
+// Submitted records that the transaction of the span carried by the context
+// is about to be submitted to the consenter of the channel, so that its
+// consensus, its cut and its block are traced.  It must be called before the
+// transaction is submitted, as the consenter may order it at once.
+func (t *Tracer) Submitted(ctx context.Context, channelID, txID string) {
+	span := FromContext(ctx)
+	if t == nil || span == nil || txID == "" {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if len(t.pending) >= maxPending {
+		logger.Debugf("[channel: %s] Not tracing transaction %s to its block, %d transactions traced already", channelID, txID, maxPending)
+		return
+	}
+	t.pending[txKey{channelID: channelID, txID: txID}] = &pendingTx{span: span.ctx, submitted: t.now()}
+}
+
+// Withdraw forgets a transaction the consenter did not accept
+func (t *Tracer) Withdraw(channelID, txID string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.pending, txKey{channelID: channelID, txID: txID})
+}
+
+// Ordered records that the consenter of the channel ordered the transaction,
+// which now waits in the block cutter for its block to be cut
+func (t *Tracer) Ordered(channelID, txID string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	tx, ok := t.pending[txKey{channelID: channelID, txID: txID}]
+	if !ok || !tx.ordered.IsZero() {
+		return
+	}
+	tx.ordered = t.now()
+	span := t.newSpan(tx.span, SpanConsensus, tx.submitted)
+	span.SetAttribute("channel", channelID)
+	t.record(span, tx.ordered)
+}
+
+// BlockCut records that the transactions were cut into the block of the
+// channel.  The transactions which were not ordered first, such as config
+// transactions cut into blocks of their own, end their consensus with the
+// cut.
+func (t *Tracer) BlockCut(channelID string, number uint64, txIDs []string) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	now := t.now()
+	var block *tracedBlock
+	for _, txID := range txIDs {
+		key := txKey{channelID: channelID, txID: txID}
+		tx, ok := t.pending[key]
+		if !ok {
+			continue
+		}
+		delete(t.pending, key)
+		if block == nil {
+			block = &tracedBlock{span: SpanContext{TraceID: newTraceID(), SpanID: newSpanID()}, cut: now}
+		}
+		block.txs = append(block.txs, tx.span)
+
+		var span *Span
+		if tx.ordered.IsZero() {
+			span = t.newSpan(tx.span, SpanConsensus, tx.submitted)
+		} else {
+			span = t.newSpan(tx.span, SpanCut, tx.ordered)
+		}
+		span.SetAttribute("channel", channelID)
+		span.SetAttribute("block_number", strconv.FormatUint(number, 10))
+		span.AddLink(block.span)
+		t.record(span, now)
+	}
+	if block == nil {
+		return
+	}
+
+	key := blockKey{channelID: channelID, number: number}
+	t.blocks[key] = block
+	t.blockOrder = append(t.blockOrder, key)
+	if len(t.blockOrder) > maxBlocks {
+		delete(t.blocks, t.blockOrder[0])
+		t.blockOrder = t.blockOrder[1:]
+	}
+}
+
+// BlockCommitted records that the block of the channel was appended to the
+// ledger
+func (t *Tracer) BlockCommitted(channelID string, number uint64) {
+	if t == nil {
+		return
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	block, ok := t.blocks[blockKey{channelID: channelID, number: number}]
+	if !ok || !block.committed.IsZero() {
+		return
+	}
+	block.committed = t.now()
+	span := &Span{tracer: t, ctx: block.span, name: SpanBlock, start: block.cut}
+	span.SetAttribute("channel", channelID)
+	span.SetAttribute("block_number", strconv.FormatUint(number, 10))
+	span.SetAttribute("traced_transactions", strconv.Itoa(len(block.txs)))
+	for _, tx := range block.txs {
+		span.AddLink(tx)
+	}
+	t.record(span, block.committed)
+}
+
+// StartDelivery starts the span of the delivery of the block of the channel
+// to the Deliver stream of the context, from the commit of the block.  It
+// returns nil if the block contains no traced transaction.  The span is
+// linked to the span the client of the stream propagated, if any.
+func (t *Tracer) StartDelivery(ctx context.Context, channelID string, number uint64) *Span {
+	if t == nil {
+		return nil
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	block, ok := t.blocks[blockKey{channelID: channelID, number: number}]
+	if !ok {
+		return nil
+	}
+	//区块可能在提交记录之前已被发送
+	start := block.committed
+	if start.IsZero() {
+		start = t.now()
+	}
+	span := t.newSpan(block.span, SpanDeliver, start)
+	span.SetAttribute("channel", channelID)
+	span.SetAttribute("block_number", strconv.FormatUint(number, 10))
+	if remote, _, ok := remoteSpan(ctx); ok {
+		span.AddLink(remote)
+	}
+	return span
+}
+
+// record keeps the span finished at the given time, evicting the oldest
+// span if the capacity is reached.  It must be called with the mutex held.
+func (t *Tracer) record(s *Span, end time.Time) {
+	data := SpanData{
+		TraceID:    s.ctx.TraceID.String(),
+		SpanID:     s.ctx.SpanID.String(),
+		Name:       s.name,
+		Start:      s.start,
+		End:        end,
+		Duration:   end.Sub(s.start).String(),
+		Attributes: s.attributes,
+		Error:      s.err,
+	}
+	if s.parent != (SpanID{}) {
+		data.ParentID = s.parent.String()
+	}
+	for _, link := range s.links {
+		data.Links = append(data.Links, Link{TraceID: link.TraceID.String(), SpanID: link.SpanID.String()})
+	}
+	t.spans[(t.head+t.count)%len(t.spans)] = data
+	if t.count < len(t.spans) {
+		t.count++
+	} else {
+		t.head = (t.head + 1) % len(t.spans)
+	}
+}
+
+// Spans returns the finished spans of the trace, or all of them if the
+// trace ID is empty, oldest first
+func (t *Tracer) Spans(traceID string) []SpanData {
+	spans := []SpanData{}
+	if t == nil {
+		return spans
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	for i := 0; i < t.count; i++ {
+		span := t.spans[(t.head+i)%len(t.spans)]
+		if traceID == "" || span.TraceID == traceID {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// TransactionSpans returns the finished spans of the transaction of the
+// channel, and those of the trace of its block, oldest first.  The spans of
+// the other messages of the trace of the client are left out.
+func (t *Tracer) TransactionSpans(channelID, txID string) []SpanData {
+	all := t.Spans("")
+	txSpans := map[string]bool{}
+	for _, span := range all {
+		if span.Name == SpanBroadcast && span.Attributes["channel"] == channelID && span.Attributes["tx_id"] == txID {
+			txSpans[span.SpanID] = true
+		}
+	}
+	//区块的追踪通过切块span的链接关联
+	blockTraces := map[string]bool{}
+	for _, span := range all {
+		if txSpans[span.ParentID] {
+			for _, link := range span.Links {
+				blockTraces[link.TraceID] = true
+			}
+		}
+	}
+	spans := []SpanData{}
+	for _, span := range all {
+		if txSpans[span.SpanID] || txSpans[span.ParentID] || blockTraces[span.TraceID] {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// ServeHTTP writes the finished spans as JSON, those of the trace given by
+// the trace_id parameter, or those of the transaction given by the channel
+// and tx_id parameters, or all of them
+func (t *Tracer) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	query := req.URL.Query()
+	export := &Export{}
+	if txID := query.Get("tx_id"); txID != "" {
+		export.Spans = t.TransactionSpans(query.Get("channel"), txID)
+	} else {
+		export.Spans = t.Spans(query.Get("trace_id"))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(export); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,239 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package tracing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+const clientTraceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+// newTestTracer returns a tracer sampling every message whose clock advances
+// by a second each time it is read
+func newTestTracer(t *testing.T, capacity int) *Tracer {
+	tracer, err := New(Config{SampleRatio: 1, Capacity: capacity})
+	require.NoError(t, err)
+	now := time.Unix(1000, 0)
+	tracer.now = func() time.Time {
+		now = now.Add(time.Second)
+		return now
+	}
+	return tracer
+}
+
+func spansByName(spans []SpanData) map[string]SpanData {
+	byName := map[string]SpanData{}
+	for _, span := range spans {
+		byName[span.Name] = span
+	}
+	return byName
+}
+
+func TestParseTraceparent(t *testing.T) {
+	sc, sampled, err := ParseTraceparent(clientTraceparent)
+	require.NoError(t, err)
+	assert.True(t, sampled)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", sc.TraceID.String())
+	assert.Equal(t, "00f067aa0ba902b7", sc.SpanID.String())
+	assert.Equal(t, clientTraceparent, sc.Traceparent())
+
+	_, sampled, err = ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	require.NoError(t, err)
+	assert.False(t, sampled)
+
+	// 更高版本的附加字段被忽略
+	_, _, err = ParseTraceparent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra")
+	assert.NoError(t, err)
+
+	for _, traceparent := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e47-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902zz-01",
+	} {
+		_, _, err := ParseTraceparent(traceparent)
+		assert.Error(t, err, traceparent)
+	}
+}
+
+func TestNew(t *testing.T) {
+	_, err := New(Config{SampleRatio: 1.5})
+	assert.EqualError(t, err, "sample ratio must be between 0 and 1, got 1.5")
+	_, err = New(Config{Capacity: -1})
+	assert.EqualError(t, err, "span capacity must not be negative, got -1")
+}
+
+func TestSampling(t *testing.T) {
+	tracer, err := New(Config{SampleRatio: 0})
+	require.NoError(t, err)
+
+	// 客户端未传播追踪时按采样比例采样
+	ctx, span := tracer.StartServerSpan(context.Background(), SpanBroadcast, time.Now())
+	assert.Nil(t, span)
+	_, child := tracer.StartSpan(ctx, SpanProcess)
+	assert.Nil(t, child)
+
+	// 客户端采样的追踪总是被追踪
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(TraceparentKey, clientTraceparent))
+	ctx, span = tracer.StartServerSpan(ctx, SpanBroadcast, time.Now())
+	require.NotNil(t, span)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.Context().TraceID.String())
+	assert.Equal(t, span, FromContext(ctx))
+
+	// 客户端未采样的追踪不被追踪
+	tracer.ratio = 1
+	ctx = metadata.NewIncomingContext(context.Background(), metadata.Pairs(TraceparentKey, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00"))
+	_, span = tracer.StartServerSpan(ctx, SpanBroadcast, time.Now())
+	assert.Nil(t, span)
+}
+
+func TestTransactionStages(t *testing.T) {
+	tracer := newTestTracer(t, 0)
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TraceparentKey, clientTraceparent))
+
+	ctx, broadcast := tracer.StartServerSpan(ctx, SpanBroadcast, time.Unix(1000, 0))
+	broadcast.SetAttribute("channel", "foo")
+	broadcast.SetAttribute("tx_id", "tx1")
+	_, process := tracer.StartSpan(ctx, SpanProcess)
+	process.End()
+	tracer.Submitted(ctx, "foo", "tx1")
+	broadcast.End()
+
+	tracer.Ordered("foo", "tx1")
+	// 其他交易不影响被追踪的交易
+	tracer.Ordered("foo", "tx2")
+	tracer.BlockCut("foo", 5, []string{"tx3", "tx1"})
+	tracer.BlockCommitted("foo", 5)
+	deliver := tracer.StartDelivery(context.Background(), "foo", 5)
+	require.NotNil(t, deliver)
+	deliver.End()
+	assert.Nil(t, tracer.StartDelivery(context.Background(), "foo", 6))
+
+	spans := tracer.TransactionSpans("foo", "tx1")
+	byName := spansByName(spans)
+	require.Len(t, spans, 6, "%v", spans)
+
+	tx := byName[SpanBroadcast]
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tx.TraceID)
+	assert.Equal(t, "00f067aa0ba902b7", tx.ParentID)
+	for _, name := range []string{SpanProcess, SpanConsensus, SpanCut} {
+		assert.Equal(t, tx.TraceID, byName[name].TraceID, name)
+		assert.Equal(t, tx.SpanID, byName[name].ParentID, name)
+	}
+	assert.Equal(t, byName[SpanConsensus].End, byName[SpanCut].Start)
+
+	// 区块的追踪与交易的追踪通过链接关联
+	block := byName[SpanBlock]
+	assert.NotEqual(t, tx.TraceID, block.TraceID)
+	assert.Empty(t, block.ParentID)
+	assert.Equal(t, []Link{{TraceID: tx.TraceID, SpanID: tx.SpanID}}, block.Links)
+	assert.Equal(t, []Link{{TraceID: block.TraceID, SpanID: block.SpanID}}, byName[SpanCut].Links)
+	assert.Equal(t, "5", block.Attributes["block_number"])
+	assert.Equal(t, byName[SpanCut].End, block.Start)
+
+	delivery := byName[SpanDeliver]
+	assert.Equal(t, block.TraceID, delivery.TraceID)
+	assert.Equal(t, block.SpanID, delivery.ParentID)
+	assert.Equal(t, block.End, delivery.Start)
+}
+
+func TestConfigTransaction(t *testing.T) {
+	tracer := newTestTracer(t, 0)
+	ctx, broadcast := tracer.StartServerSpan(context.Background(), SpanBroadcast, time.Unix(1000, 0))
+	tracer.Submitted(ctx, "foo", "config1")
+	broadcast.End()
+
+	// 配置交易未经切块组件排序，共识阶段在切块时结束
+	tracer.BlockCut("foo", 3, []string{"config1"})
+	spans := tracer.Spans(broadcast.Context().TraceID.String())
+	byName := spansByName(spans)
+	require.Len(t, spans, 2)
+	assert.Contains(t, byName, SpanConsensus)
+	assert.Equal(t, "3", byName[SpanConsensus].Attributes["block_number"])
+
+	// 撤回的交易不再追踪
+	tracer.Submitted(ctx, "foo", "config2")
+	tracer.Withdraw("foo", "config2")
+	tracer.BlockCut("foo", 4, []string{"config2"})
+	assert.Len(t, tracer.Spans(broadcast.Context().TraceID.String()), 2)
+	assert.Nil(t, tracer.StartDelivery(context.Background(), "foo", 4))
+}
+
+func TestCapacity(t *testing.T) {
+	tracer := newTestTracer(t, 2)
+	for _, name := range []string{"a", "b", "c"} {
+		_, span := tracer.StartServerSpan(context.Background(), name, time.Now())
+		span.End()
+	}
+	spans := tracer.Spans("")
+	require.Len(t, spans, 2)
+	assert.Equal(t, "b", spans[0].Name)
+	assert.Equal(t, "c", spans[1].Name)
+}
+
+func TestNilTracer(t *testing.T) {
+	var tracer *Tracer
+	ctx, span := tracer.StartServerSpan(context.Background(), SpanBroadcast, time.Now())
+	assert.Nil(t, span)
+	span.SetAttribute("channel", "foo")
+	span.SetError("failed")
+	span.End()
+	_, span = tracer.StartSpan(ctx, SpanProcess)
+	assert.Nil(t, span)
+	tracer.Submitted(ctx, "foo", "tx1")
+	tracer.Ordered("foo", "tx1")
+	tracer.BlockCut("foo", 1, []string{"tx1"})
+	tracer.BlockCommitted("foo", 1)
+	assert.Nil(t, tracer.StartDelivery(ctx, "foo", 1))
+	assert.Empty(t, tracer.Spans(""))
+}
+
+func TestServeHTTP(t *testing.T) {
+	tracer := newTestTracer(t, 0)
+	for _, txID := range []string{"tx1", "tx2"} {
+		_, span := tracer.StartServerSpan(context.Background(), SpanBroadcast, time.Now())
+		span.SetAttribute("channel", "foo")
+		span.SetAttribute("tx_id", txID)
+		span.SetError("BAD_REQUEST")
+		span.End()
+	}
+
+	resp := httptest.NewRecorder()
+	tracer.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/tracing?channel=foo&tx_id=tx2", nil))
+	assert.Equal(t, http.StatusOK, resp.Code)
+	var export Export
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &export))
+	require.Len(t, export.Spans, 1)
+	assert.Equal(t, "tx2", export.Spans[0].Attributes["tx_id"])
+	assert.Equal(t, "BAD_REQUEST", export.Spans[0].Error)
+
+	resp = httptest.NewRecorder()
+	tracer.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/tracing?trace_id="+export.Spans[0].TraceID, nil))
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &export))
+	assert.Len(t, export.Spans, 1)
+
+	resp = httptest.NewRecorder()
+	tracer.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/tracing", nil))
+	require.NoError(t, json.Unmarshal(resp.Body.Bytes(), &export))
+	assert.Len(t, export.Spans, 2)
+
+	resp = httptest.NewRecorder()
+	tracer.ServeHTTP(resp, httptest.NewRequest(http.MethodPost, "/tracing", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.Code)
+}
@@ -0,0 +1,325 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package versionskew negotiates the versions of the orderers connecting to
+// each other, such as a standby replicating the blocks of the active
+// orderer.  The connecting orderer sends its version and features in the
+// metadata of its streams, and the other answers with its own in the header
+// of the stream, so that both sides can warn about or refuse a skew of
+// versions beyond the window the configuration allows.
+package versionskew
+
+import (
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/metadata"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	grpcmetadata "google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const pkgLogID = "orderer/common/versionskew"
+
+const (
+	// VersionKey is the metadata key of the version of an orderer
+	VersionKey = "fabric-orderer-version"
+
+	// FeaturesKey is the metadata key of the features of an orderer
+	FeaturesKey = "fabric-orderer-features"
+)
+
+// Policies applied to the skews beyond the window
+const (
+	// PolicyWarn logs the skews beyond the window
+	PolicyWarn = "warn"
+
+	// PolicyRefuse refuses the connections with a skew beyond the window
+	PolicyRefuse = "refuse"
+)
+
+// Features are the features of this orderer which the orderers connecting
+// to it may rely on.
+var Features = []string{"deliver-mac", "standby-replication"}
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// Info is the version and features an orderer advertises.
+type Info struct {
+	Version  string
+	Features []string
+}
+
+// Local returns the Info of this orderer.
+func Local() Info {
+	version := metadata.Version
+	if version == "" {
+		version = "development build"
+	}
+	return Info{Version: version, Features: Features}
+}
+
+// fromMetadata returns the Info advertised in the metadata, if any
+func fromMetadata(md grpcmetadata.MD) (Info, bool) {
+	versions := md[VersionKey]
+	if len(versions) == 0 {
+		return Info{}, false
+	}
+	info := Info{Version: versions[0]}
+	for _, features := range md[FeaturesKey] {
+		for _, feature := range strings.Split(features, ",") {
+			if feature = strings.TrimSpace(feature); feature != "" {
+				info.Features = append(info.Features, feature)
+			}
+		}
+	}
+	return info, true
+}
+
+func (i Info) metadata() grpcmetadata.MD {
+	return grpcmetadata.Pairs(VersionKey, i.Version, FeaturesKey, strings.Join(i.Features, ","))
+}
+
+// Skew returns the number of minor releases between the versions, such as
+// 1 between 1.1.0 and 1.2.3, or an error if they cannot be compared because
+// either is not a release version or their major versions differ.
+func Skew(a, b string) (int, error) {
+	aMajor, aMinor, err := parseVersion(a)
+	if err != nil {
+		return 0, err
+	}
+	bMajor, bMinor, err := parseVersion(b)
+	if err != nil {
+		return 0, err
+	}
+	if aMajor != bMajor {
+		return 0, errors.Errorf("major versions of %s and %s differ", a, b)
+	}
+	if aMinor > bMinor {
+		return aMinor - bMinor, nil
+	}
+	return bMinor - aMinor, nil
+}
+
+// parseVersion returns the major and minor versions of a version such as
+// 1.2.0 or 1.2.0-snapshot-d20f8a5
+func parseVersion(version string) (int, int, error) {
+	parts := strings.SplitN(version, ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, errors.Errorf("%q is not a release version", version)
+	}
+	major, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, errors.Errorf("%q is not a release version", version)
+	}
+	minor, err := strconv.Atoi(strings.SplitN(parts[1], "-", 2)[0])
+	if err != nil {
+		return 0, 0, errors.Errorf("%q is not a release version", version)
+	}
+	return major, minor, nil
+}
+
+// Config contains the configuration of a Negotiator.
+type Config struct {
+	// Policy is PolicyWarn or PolicyRefuse
+	Policy string
+
+	// MaxMinorSkew is the number of minor releases the versions of the
+	// orderers may differ by
+	MaxMinorSkew int
+}
+
+// Negotiator exchanges the Info of the orderers and applies the policy to
+// their skew.  A nil Negotiator neither advertises nor checks anything.
+type Negotiator struct {
+	conf  Config
+	local Info
+
+	skew     *prometheus.GaugeVec
+	outcomes *prometheus.CounterVec
+
+	// versions are the versions of the skew gauges of each remote orderer
+	mutex    sync.Mutex
+	versions map[string]string
+}
+
+// NewNegotiator creates a Negotiator and registers its metrics with the
+// registerer.
+func NewNegotiator(conf Config, local Info, registerer prometheus.Registerer) (*Negotiator, error) {
+	if conf.Policy != PolicyWarn && conf.Policy != PolicyRefuse {
+		return nil, errors.Errorf("version skew policy must be %s or %s, got %q", PolicyWarn, PolicyRefuse, conf.Policy)
+	}
+	if conf.MaxMinorSkew < 0 {
+		return nil, errors.Errorf("maximum minor version skew must not be negative, got %d", conf.MaxMinorSkew)
+	}
+	n := &Negotiator{
+		conf:     conf,
+		local:    local,
+		versions: map[string]string{},
+		skew: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: "orderer",
+			Subsystem: "cluster",
+			Name:      "version_skew",
+			Help:      "The number of minor releases between the version of this orderer and of the orderer it connected to, by address and version of that orderer, or -1 if the versions cannot be compared.",
+		}, []string{"remote", "version"}),
+		outcomes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: "orderer",
+			Subsystem: "cluster",
+			Name:      "version_negotiations_total",
+			Help:      "The number of version negotiations with other orderers, by outcome: accepted, warned or refused.",
+		}, []string{"outcome"}),
+	}
+	for _, c := range []prometheus.Collector{n.skew, n.outcomes} {
+		if err := registerer.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+// Negotiate applies the policy to the Info of the remote orderer, and
+// returns an error if the connection must be refused.  It sets the skew
+// gauge of the remote orderer, so the remote must be an orderer this one
+// connected to, as opposed to a client, whose address and advertised Info
+// are not authenticated.
+func (n *Negotiator) Negotiate(remote string, info Info) error {
+	skew, err := n.negotiate(remote, info)
+	n.setSkew(remote, info.Version, skew)
+	return err
+}
+
+// negotiate applies the policy to the Info of the remote orderer, and
+// returns the skew of the versions, -1 if they cannot be compared, and an
+// error if the connection must be refused
+func (n *Negotiator) negotiate(remote string, info Info) (int, error) {
+	skew, err := Skew(n.local.Version, info.Version)
+	if err != nil {
+		skew = -1
+		if info.Version == n.local.Version {
+			//无法比较的相同版本（如开发版本）视为没有偏差
+			n.outcomes.WithLabelValues("accepted").Inc()
+			return skew, nil
+		}
+	} else if skew <= n.conf.MaxMinorSkew {
+		if missing := missingFeatures(n.local.Features, info.Features); len(missing) > 0 {
+			logger.Infof("Orderer at %s of version %s does not support features %v", remote, info.Version, missing)
+		}
+		n.outcomes.WithLabelValues("accepted").Inc()
+		return skew, nil
+	} else {
+		err = errors.Errorf("versions are %d minor releases apart, beyond the maximum skew of %d", skew, n.conf.MaxMinorSkew)
+	}
+
+	err = errors.WithMessage(err, "version "+info.Version+" of orderer at "+remote+" is incompatible with version "+n.local.Version)
+	if n.conf.Policy == PolicyRefuse {
+		logger.Errorf("Refusing connection: %s", err)
+		n.outcomes.WithLabelValues("refused").Inc()
+		return skew, err
+	}
+	logger.Warningf("%s; features of the remote orderer: %v", err, info.Features)
+	n.outcomes.WithLabelValues("warned").Inc()
+	return skew, nil
+}
+
+// setSkew sets the skew gauge of the remote orderer, removing the gauge of
+// the version it had before if it was upgraded.  The gauges are labeled with
+// the host of the remote orderer and with its version only if it is a
+// release version, as the metadata is not signed.
+func (n *Negotiator) setSkew(remote, version string, skew int) {
+	if host, _, err := net.SplitHostPort(remote); err == nil {
+		remote = host
+	}
+	if _, _, err := parseVersion(version); err != nil && version != n.local.Version {
+		version = "unknown"
+	}
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+	if previous, ok := n.versions[remote]; ok && previous != version {
+		n.skew.DeleteLabelValues(remote, previous)
+	}
+	n.versions[remote] = version
+	n.skew.WithLabelValues(remote, version).Set(float64(skew))
+}
+
+// OutgoingContext returns the context of the streams opened to other
+// orderers, advertising the Info of this orderer.
+func (n *Negotiator) OutgoingContext(ctx context.Context) context.Context {
+	if n == nil {
+		return ctx
+	}
+	return grpcmetadata.NewOutgoingContext(ctx, n.local.metadata())
+}
+
+// NegotiateClient negotiates with the orderer at the address to which the
+// stream opened with OutgoingContext is connected.  It waits for the header
+// of the stream, so it must be called once the first request is sent.
+func (n *Negotiator) NegotiateClient(remote string, stream grpc.ClientStream) error {
+	if n == nil {
+		return nil
+	}
+	header, err := stream.Header()
+	if err != nil {
+		return errors.Wrapf(err, "error receiving header from %s", remote)
+	}
+	info, ok := fromMetadata(header)
+	if !ok {
+		info = Info{Version: "unknown"}
+	}
+	return n.Negotiate(remote, info)
+}
+
+// NegotiateServer negotiates with the orderer which opened the stream, if
+// it advertised its Info, answering with the Info of this orderer in the
+// header of the stream.  The error returned ends the stream.  Any client may
+// advertise an Info, before it is authenticated, so the negotiation is only
+// logged and counted by outcome, and sets no skew gauge.
+func (n *Negotiator) NegotiateServer(remote string, stream grpc.ServerStream) error {
+	if n == nil {
+		return nil
+	}
+	md, _ := grpcmetadata.FromIncomingContext(stream.Context())
+	info, ok := fromMetadata(md)
+	if !ok {
+		//客户端不是排序节点，不协商版本
+		return nil
+	}
+	if err := stream.SendHeader(n.local.metadata()); err != nil {
+		return err
+	}
+	if _, err := n.negotiate(remote, info); err != nil {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return nil
+}
+
+// missingFeatures returns the features of local missing from remote
+func missingFeatures(local, remote []string) []string {
+	supported := map[string]bool{}
+	for _, feature := range remote {
+		supported[feature] = true
+	}
+	var missing []string
+	for _, feature := range local {
+		if !supported[feature] {
+			missing = append(missing, feature)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
@@ -0,0 +1,174 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package versionskew
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	header metadata.MD
+}
+
+func (m *mockServerStream) Context() context.Context {
+	return m.ctx
+}
+
+func (m *mockServerStream) SendHeader(md metadata.MD) error {
+	m.header = md
+	return nil
+}
+
+type mockClientStream struct {
+	grpc.ClientStream
+	header metadata.MD
+}
+
+func (m *mockClientStream) Header() (metadata.MD, error) {
+	return m.header, nil
+}
+
+// metricValue returns the value of the gauge or counter of the family with
+// the labels
+func metricValue(t *testing.T, registry *prometheus.Registry, name string, labels map[string]string) float64 {
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+	metrics:
+		for _, m := range family.Metric {
+			for _, lp := range m.Label {
+				if labels[lp.GetName()] != lp.GetValue() {
+					continue metrics
+				}
+			}
+			if m.Gauge != nil {
+				return m.Gauge.GetValue()
+			}
+			return m.Counter.GetValue()
+		}
+	}
+	return 0
+}
+
+func TestSkew(t *testing.T) {
+	for _, tc := range []struct {
+		a, b string
+		skew int
+		err  string
+	}{
+		{"1.1.0", "1.1.3", 0, ""},
+		{"1.1.0", "1.3.0-snapshot-d20f8a5", 2, ""},
+		{"1.4.2", "1.2.0", 2, ""},
+		{"1.1.0", "2.1.0", 0, "major versions of 1.1.0 and 2.1.0 differ"},
+		{"1.1.0", "latest", 0, `"latest" is not a release version`},
+		{"1.x.0", "1.1.0", 0, `"1.x.0" is not a release version`},
+	} {
+		skew, err := Skew(tc.a, tc.b)
+		if tc.err != "" {
+			assert.EqualError(t, err, tc.err)
+			continue
+		}
+		assert.NoError(t, err)
+		assert.Equal(t, tc.skew, skew)
+	}
+}
+
+func TestNewNegotiator(t *testing.T) {
+	_, err := NewNegotiator(Config{Policy: "ignore"}, Local(), prometheus.NewRegistry())
+	assert.EqualError(t, err, `version skew policy must be warn or refuse, got "ignore"`)
+	_, err = NewNegotiator(Config{Policy: PolicyWarn, MaxMinorSkew: -1}, Local(), prometheus.NewRegistry())
+	assert.EqualError(t, err, "maximum minor version skew must not be negative, got -1")
+}
+
+func TestNegotiate(t *testing.T) {
+	local := Info{Version: "1.2.0", Features: []string{"a", "b"}}
+	registry := prometheus.NewRegistry()
+	warn, err := NewNegotiator(Config{Policy: PolicyWarn, MaxMinorSkew: 1}, local, registry)
+	require.NoError(t, err)
+	refuse, err := NewNegotiator(Config{Policy: PolicyRefuse, MaxMinorSkew: 1}, local, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	assert.NoError(t, warn.Negotiate("10.0.0.1:7050", Info{Version: "1.1.0", Features: []string{"a"}}))
+	assert.NoError(t, refuse.Negotiate("10.0.0.1:7050", Info{Version: "1.3.1"}))
+	assert.Equal(t, float64(1), metricValue(t, registry, "orderer_cluster_version_skew", map[string]string{"remote": "10.0.0.1", "version": "1.1.0"}))
+
+	// 超出偏差窗口时按策略告警或拒绝
+	assert.NoError(t, warn.Negotiate("10.0.0.1:7051", Info{Version: "1.4.0"}))
+	assert.EqualError(t, refuse.Negotiate("10.0.0.1:7050", Info{Version: "1.4.0"}), "version 1.4.0 of orderer at 10.0.0.1:7050 is incompatible with version 1.2.0: versions are 2 minor releases apart, beyond the maximum skew of 1")
+	assert.EqualError(t, refuse.Negotiate("10.0.0.1:7050", Info{Version: "2.0.0"}), "version 2.0.0 of orderer at 10.0.0.1:7050 is incompatible with version 1.2.0: major versions of 1.2.0 and 2.0.0 differ")
+	assert.Equal(t, float64(2), metricValue(t, registry, "orderer_cluster_version_skew", map[string]string{"remote": "10.0.0.1", "version": "1.4.0"}))
+	assert.Equal(t, float64(0), metricValue(t, registry, "orderer_cluster_version_skew", map[string]string{"remote": "10.0.0.1", "version": "1.1.0"}))
+
+	assert.NoError(t, warn.Negotiate("10.0.0.2:7050", Info{Version: "garbage"}))
+	assert.Equal(t, float64(-1), metricValue(t, registry, "orderer_cluster_version_skew", map[string]string{"remote": "10.0.0.2", "version": "unknown"}))
+	assert.Equal(t, float64(1), metricValue(t, registry, "orderer_cluster_version_negotiations_total", map[string]string{"outcome": "accepted"}))
+	assert.Equal(t, float64(2), metricValue(t, registry, "orderer_cluster_version_negotiations_total", map[string]string{"outcome": "warned"}))
+
+	// 无法比较的相同版本不视为偏差
+	dev, err := NewNegotiator(Config{Policy: PolicyRefuse}, Info{Version: "latest"}, prometheus.NewRegistry())
+	require.NoError(t, err)
+	assert.NoError(t, dev.Negotiate("10.0.0.1:7050", Info{Version: "latest"}))
+	assert.Error(t, dev.Negotiate("10.0.0.1:7050", Info{Version: "1.2.0"}))
+}
+
+func TestHandshake(t *testing.T) {
+	client, err := NewNegotiator(Config{Policy: PolicyRefuse}, Info{Version: "1.2.0", Features: []string{"a", "b"}}, prometheus.NewRegistry())
+	require.NoError(t, err)
+	registry := prometheus.NewRegistry()
+	server, err := NewNegotiator(Config{Policy: PolicyRefuse}, Info{Version: "1.2.1", Features: []string{"a"}}, registry)
+	require.NoError(t, err)
+
+	outgoing, _ := metadata.FromOutgoingContext(client.OutgoingContext(context.Background()))
+	stream := &mockServerStream{ctx: metadata.NewIncomingContext(context.Background(), outgoing)}
+	require.NoError(t, server.NegotiateServer("10.0.0.1:51000", stream))
+	info, ok := fromMetadata(stream.header)
+	require.True(t, ok)
+	assert.Equal(t, Info{Version: "1.2.1", Features: []string{"a"}}, info)
+	assert.NoError(t, client.NegotiateClient("10.0.0.2:7050", &mockClientStream{header: stream.header}))
+
+	// 客户端未经认证，服务端只按结果计数，不设置偏差指标
+	assert.Equal(t, float64(1), metricValue(t, registry, "orderer_cluster_version_negotiations_total", map[string]string{"outcome": "accepted"}))
+	families, err := registry.Gather()
+	require.NoError(t, err)
+	for _, family := range families {
+		assert.NotEqual(t, "orderer_cluster_version_skew", family.GetName())
+	}
+
+	// 服务端拒绝时返回FailedPrecondition
+	old, err := NewNegotiator(Config{Policy: PolicyRefuse}, Info{Version: "1.0.0"}, prometheus.NewRegistry())
+	require.NoError(t, err)
+	err = old.NegotiateServer("10.0.0.1:51000", stream)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	// 未声明版本的客户端不是排序节点，不协商
+	plain := &mockServerStream{ctx: context.Background()}
+	assert.NoError(t, server.NegotiateServer("10.0.0.3:51000", plain))
+	assert.Nil(t, plain.header)
+
+	// 服务端未声明版本时视为版本未知
+	assert.Error(t, client.NegotiateClient("10.0.0.2:7050", &mockClientStream{}))
+
+	var disabled *Negotiator
+	ctx := context.Background()
+	assert.Equal(t, ctx, disabled.OutgoingContext(ctx))
+	assert.NoError(t, disabled.NegotiateServer("10.0.0.1:51000", stream))
+	assert.NoError(t, disabled.NegotiateClient("10.0.0.2:7050", &mockClientStream{}))
+}
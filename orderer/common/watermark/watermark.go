@@ -0,0 +1,177 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package watermark answers requests for the height and last block hash of a
+// channel, signed by the orderer, so that clients can check the finality of
+// the transactions they submitted without opening a deliver stream.
+package watermark
+
+import (
+	"math"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/core/comm"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+const pkgLogID = "orderer/common/watermark"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// Chain is a channel whose watermark is requested
+type Chain interface {
+	// Reader returns the chain Reader for the chain
+	Reader() blockledger.Reader
+}
+
+// ChainManager looks up the channels whose watermark is requested
+type ChainManager interface {
+	GetChain(channelID string) (Chain, bool)
+}
+
+// PolicyChecker checks the watermark request against the readers policy of
+// the channel
+type PolicyChecker func(env *cb.Envelope, channelID string) error
+
+// Handler answers watermark requests.
+type Handler struct {
+	ChainManager     ChainManager
+	PolicyChecker    PolicyChecker
+	Signer           crypto.LocalSigner
+	TimeWindow       time.Duration
+	BindingInspector comm.BindingInspector
+}
+
+// NewHandler creates a Handler signing the watermarks with the signer.
+func NewHandler(cm ChainManager, policyChecker PolicyChecker, signer crypto.LocalSigner, timeWindow time.Duration, mutualTLS bool) *Handler {
+	return &Handler{
+		ChainManager:     cm,
+		PolicyChecker:    policyChecker,
+		Signer:           signer,
+		TimeWindow:       timeWindow,
+		BindingInspector: comm.NewBindingInspector(mutualTLS, extractChannelHeaderCertHash),
+	}
+}
+
+func extractChannelHeaderCertHash(msg proto.Message) []byte {
+	chdr, isChannelHeader := msg.(*cb.ChannelHeader)
+	if !isChannelHeader || chdr == nil {
+		return nil
+	}
+	return chdr.TlsCertHash
+}
+
+// Handle answers the watermark request of the envelope.
+func (h *Handler) Handle(ctx context.Context, env *cb.Envelope) *ab.WatermarkResponse {
+	addr := util.ExtractRemoteAddress(ctx)
+	chdr, err := h.validate(ctx, env)
+	if err != nil {
+		logger.Warningf("Rejecting watermark request from %s: %s", addr, err)
+		return &ab.WatermarkResponse{Status: cb.Status_BAD_REQUEST}
+	}
+
+	chain, ok := h.ChainManager.GetChain(chdr.ChannelId)
+	if !ok {
+		logger.Debugf("Rejecting watermark request from %s because channel %s not found", addr, chdr.ChannelId)
+		return &ab.WatermarkResponse{Status: cb.Status_NOT_FOUND}
+	}
+
+	if err := h.PolicyChecker(env, chdr.ChannelId); err != nil {
+		logger.Warningf("[channel: %s] Rejecting watermark request from %s: %s", chdr.ChannelId, addr, err)
+		return &ab.WatermarkResponse{Status: cb.Status_FORBIDDEN}
+	}
+
+	reader := chain.Reader()
+	height := reader.Height()
+	lastBlock := blockledger.GetBlock(reader, height-1)
+	if lastBlock == nil {
+		logger.Errorf("[channel: %s] Could not read block %d for watermark request from %s", chdr.ChannelId, height-1, addr)
+		return &ab.WatermarkResponse{Status: cb.Status_INTERNAL_SERVER_ERROR}
+	}
+
+	resp, err := h.sign(&ab.Watermark{
+		ChannelId:     chdr.ChannelId,
+		Height:        height,
+		LastBlockHash: lastBlock.Header.Hash(),
+		Timestamp:     ptypes.TimestampNow(),
+	})
+	if err != nil {
+		logger.Errorf("[channel: %s] Could not sign watermark for %s: %s", chdr.ChannelId, addr, err)
+		return &ab.WatermarkResponse{Status: cb.Status_INTERNAL_SERVER_ERROR}
+	}
+	logger.Debugf("[channel: %s] Sent watermark at height %d to %s", chdr.ChannelId, height, addr)
+	return resp
+}
+
+// sign returns the successful response carrying the watermark signed by the
+// orderer, as blocks are signed
+func (h *Handler) sign(wm *ab.Watermark) (*ab.WatermarkResponse, error) {
+	wmBytes, err := proto.Marshal(wm)
+	if err != nil {
+		return nil, err
+	}
+	shdr, err := h.Signer.NewSignatureHeader()
+	if err != nil {
+		return nil, err
+	}
+	shdrBytes, err := proto.Marshal(shdr)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := h.Signer.Sign(util.ConcatenateBytes(wmBytes, shdrBytes))
+	if err != nil {
+		return nil, err
+	}
+	return &ab.WatermarkResponse{
+		Status:          cb.Status_SUCCESS,
+		Watermark:       wmBytes,
+		SignatureHeader: shdrBytes,
+		Signature:       signature,
+	}, nil
+}
+
+// validate checks the headers of the watermark request
+func (h *Handler) validate(ctx context.Context, env *cb.Envelope) (*cb.ChannelHeader, error) {
+	payload, err := utils.UnmarshalPayload(env.Payload)
+	if err != nil {
+		return nil, errors.WithMessage(err, "envelope has no payload")
+	}
+	if payload.Header == nil {
+		return nil, errors.New("envelope has no payload header")
+	}
+	chdr, err := utils.UnmarshalChannelHeader(payload.Header.ChannelHeader)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to unmarshal channel header")
+	}
+
+	if chdr.GetTimestamp() == nil {
+		return nil, errors.New("channel header in envelope must contain timestamp")
+	}
+	envTime := time.Unix(chdr.GetTimestamp().Seconds, int64(chdr.GetTimestamp().Nanos)).UTC()
+	serverTime := time.Now()
+	if math.Abs(float64(serverTime.UnixNano()-envTime.UnixNano())) > float64(h.TimeWindow.Nanoseconds()) {
+		return nil, errors.Errorf("envelope timestamp %s is more than %s apart from current server time %s", envTime, h.TimeWindow, serverTime)
+	}
+	if err := h.BindingInspector(ctx, chdr); err != nil {
+		return nil, err
+	}
+	return chdr, nil
+}
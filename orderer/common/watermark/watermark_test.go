@@ -0,0 +1,107 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package watermark
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	ramledger "github.com/hyperledger/fabric/common/ledger/blockledger/ram"
+	"github.com/hyperledger/fabric/common/util"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+const channelID = "foo"
+
+type mockChain struct {
+	ledger blockledger.ReadWriter
+}
+
+func (mc *mockChain) Reader() blockledger.Reader { return mc.ledger }
+
+func (mc *mockChain) GetChain(id string) (Chain, bool) { return mc, id == channelID }
+
+// mockSigner signs a message with the message itself prefixed with "signed:"
+type mockSigner struct {
+	err error
+}
+
+func (ms *mockSigner) NewSignatureHeader() (*cb.SignatureHeader, error) {
+	return &cb.SignatureHeader{Creator: []byte("orderer"), Nonce: []byte("nonce")}, nil
+}
+
+func (ms *mockSigner) Sign(message []byte) ([]byte, error) {
+	return append([]byte("signed:"), message...), ms.err
+}
+
+func newChain(t *testing.T, blocks int) *mockChain {
+	rl, err := ramledger.New(10).GetOrCreate(channelID)
+	require.NoError(t, err)
+	for i := 0; i < blocks; i++ {
+		require.NoError(t, rl.Append(blockledger.CreateNextBlock(rl, []*cb.Envelope{{Payload: []byte("tx")}})))
+	}
+	return &mockChain{ledger: rl}
+}
+
+func request(channel string) *cb.Envelope {
+	payload := &cb.Payload{
+		Header: utils.MakePayloadHeader(utils.MakeChannelHeader(cb.HeaderType_DELIVER_SEEK_INFO, 0, channel, 0), &cb.SignatureHeader{Creator: []byte("creator")}),
+	}
+	return &cb.Envelope{Payload: utils.MarshalOrPanic(payload)}
+}
+
+func allow(*cb.Envelope, string) error { return nil }
+
+func TestWatermark(t *testing.T) {
+	chain := newChain(t, 3)
+	h := NewHandler(chain, allow, &mockSigner{}, time.Minute, false)
+
+	resp := h.Handle(context.Background(), request(channelID))
+	require.Equal(t, cb.Status_SUCCESS, resp.Status)
+	wm := &ab.Watermark{}
+	require.NoError(t, proto.Unmarshal(resp.Watermark, wm))
+	assert.Equal(t, channelID, wm.ChannelId)
+	assert.Equal(t, uint64(3), wm.Height)
+	assert.Equal(t, blockledger.GetBlock(chain.ledger, 2).Header.Hash(), wm.LastBlockHash)
+	assert.NotNil(t, wm.Timestamp)
+
+	shdr := &cb.SignatureHeader{}
+	require.NoError(t, proto.Unmarshal(resp.SignatureHeader, shdr))
+	assert.Equal(t, []byte("orderer"), shdr.Creator)
+	assert.Equal(t, append([]byte("signed:"), util.ConcatenateBytes(resp.Watermark, resp.SignatureHeader)...), resp.Signature)
+}
+
+func TestWatermarkRejected(t *testing.T) {
+	chain := newChain(t, 1)
+	h := NewHandler(chain, allow, &mockSigner{}, time.Minute, false)
+	ctx := context.Background()
+
+	assert.Equal(t, cb.Status_BAD_REQUEST, h.Handle(ctx, &cb.Envelope{Payload: []byte("garbage")}).Status)
+
+	h.TimeWindow = -time.Minute
+	assert.Equal(t, cb.Status_BAD_REQUEST, h.Handle(ctx, request(channelID)).Status)
+	h.TimeWindow = time.Minute
+
+	assert.Equal(t, cb.Status_NOT_FOUND, h.Handle(ctx, request("bar")).Status)
+
+	h.PolicyChecker = func(*cb.Envelope, string) error { return errors.New("not a reader") }
+	resp := h.Handle(ctx, request(channelID))
+	assert.Equal(t, cb.Status_FORBIDDEN, resp.Status)
+	assert.Nil(t, resp.Watermark)
+
+	h.PolicyChecker = allow
+	h.Signer = &mockSigner{err: errors.New("no key")}
+	assert.Equal(t, cb.Status_INTERNAL_SERVER_ERROR, h.Handle(ctx, request(channelID)).Status)
+}
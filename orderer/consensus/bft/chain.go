@@ -0,0 +1,693 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sync"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	"github.com/hyperledger/fabric/common/policies"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+// requests are encoded as their kind, their config sequence and the
+// envelope, or, for the signatures of the blocks, as their kind, the number
+// of the block and the header signed along with the signature
+const (
+	normalRequest byte = iota
+	configRequest
+	signatureRequest
+	requestHeaderSize = 9
+)
+
+// the signatures of the blocks cut by the other orderers and not yet by this
+// one, while a config block waits for its signatures, are kept for as many
+// blocks after the last block cut
+const maxEarlyBlocks = 100
+
+// the signature of each orderer over the header of a block must satisfy the
+// orderer writers policy of the channel
+var ordererWritersPolicy = policies.PathSeparator + policies.ChannelPrefix + policies.PathSeparator + policies.OrdererPrefix + policies.PathSeparator + "Writers"
+
+// channelResources is implemented by the support of the chains of the
+// orderer, whose ledger holds the last block written and whose policies and
+// MSPs authenticate the signatures of the other orderers.
+type channelResources interface {
+	Reader() blockledger.Reader
+	PolicyManager() policies.Manager
+	MSPManager() msp.MSPManager
+}
+
+// the checkpoint is encoded in the ORDERER metadata of the blocks as the
+// view and sequence of the decision and the number of its requests committed
+const checkpointSize = 24
+
+type request struct {
+	configSeq uint64
+	normalMsg *cb.Envelope
+	configMsg *cb.Envelope
+
+	// the header of a block and the signature of an orderer over it
+	header    *cb.BlockHeader
+	signature *cb.MetadataSignature
+}
+
+// heldBlock is a block cut and held until a quorum of orderers signed it
+type heldBlock struct {
+	batch    []*cb.Envelope
+	header   *cb.BlockHeader
+	metadata []byte
+	config   bool
+}
+
+// blockSignature is the signature of an orderer over the header of a block
+type blockSignature struct {
+	headerHash []byte
+	signature  *cb.MetadataSignature
+}
+
+type chain struct {
+	support   consensus.ConsenterSupport //共识组件支持对象
+	resources channelResources           //验证其他排序节点区块签名的通道资源
+	replica   Replica                    //BFT库为本通道创建的副本
+	wal       *wal                       //已提交给副本但尚未决议的请求的预写日志
+
+	// the last decision whose requests were cut into blocks, and the number
+	// of its requests cut, only accessed by the main loop once started
+	checkpoint Checkpoint
+	applied    uint64
+
+	// the identifier of this orderer, the header of the last block cut, the
+	// blocks cut and waiting for the signatures of a quorum, the decisions
+	// not yet cut while a config block waits, and the signatures of the
+	// other orderers over the blocks not yet written, by block number and
+	// signer, only accessed by the main loop once started
+	self       string
+	lastHeader *cb.BlockHeader
+	held       []*heldBlock
+	backlog    []*Decision
+	signatures map[uint64]map[string]*blockSignature
+
+	mutex      sync.Mutex
+	view       uint64
+	viewChange chan struct{} //视图切换期间非nil，切换完成时关闭
+	leader     string        //只接受领导者提交请求时，领导者所在排序节点的Broadcast服务地址
+
+	haltOnce sync.Once
+	exitChan chan struct{}
+}
+
+func newChain(support consensus.ConsenterSupport, resources channelResources, replica Replica, wal *wal, checkpoint Checkpoint, applied uint64) *chain {
+	return &chain{
+		support:    support,
+		resources:  resources,
+		replica:    replica,
+		wal:        wal,
+		checkpoint: checkpoint,
+		applied:    applied,
+		signatures: map[uint64]map[string]*blockSignature{},
+		view:       checkpoint.View,
+		exitChan:   make(chan struct{}),
+	}
+}
+
+// Start joins the consensus after the last decision committed, submits the
+// requests logged before a restart again and starts the main loop.
+func (ch *chain) Start() {
+	if err := ch.init(); err != nil {
+		logger.Errorf("[channel: %s] Cannot start the BFT chain: %s", ch.support.ChainID(), err)
+		ch.halt()
+		ch.wal.Close()
+		return
+	}
+	if err := ch.replica.Start(ch.checkpoint); err != nil {
+		logger.Errorf("[channel: %s] Cannot start the BFT replica: %s", ch.support.ChainID(), err)
+		ch.halt()
+		ch.wal.Close()
+		return
+	}
+	logger.Infof("[channel: %s] Started BFT replica at view %d after sequence %d", ch.support.ChainID(), ch.checkpoint.View, ch.checkpoint.Sequence)
+	ch.resubmit()
+	go ch.main()
+}
+
+// Halt leaves the consensus and stops the main loop.
+func (ch *chain) Halt() {
+	ch.halt()
+}
+
+func (ch *chain) halt() {
+	ch.haltOnce.Do(func() {
+		close(ch.exitChan)
+		ch.replica.Halt()
+	})
+}
+
+// Errored closes when the chain is halted, either on purpose or because the
+// replica failed.
+func (ch *chain) Errored() <-chan struct{} {
+	return ch.exitChan
+}
+
+// WaitReady blocks while the replicas change view.
+func (ch *chain) WaitReady() error {
+	return ch.WaitReadyContext(context.Background())
+}
+
+// WaitReadyContext blocks while the replicas change view, until the context
+// is done.
+func (ch *chain) WaitReadyContext(ctx context.Context) error {
+	ch.mutex.Lock()
+	viewChange, view := ch.viewChange, ch.view
+	ch.mutex.Unlock()
+
+	select {
+	case <-ch.exitChan:
+		return fmt.Errorf("consenter for this channel has been halted")
+	default:
+	}
+	if viewChange == nil {
+		return nil
+	}
+	select {
+	case <-viewChange:
+		return nil
+	case <-ch.exitChan:
+		return fmt.Errorf("consenter for this channel has been halted")
+	case <-ctx.Done():
+		return &consensus.NotReadyError{Reason: fmt.Sprintf("changing to view %d", view), Position: -1}
+	}
+}
+
+// Status reports the health of the chain, which is not ready while halted
+// or changing view
+func (ch *chain) Status() consensus.ChainStatus {
+	select {
+	case <-ch.exitChan:
+		return consensus.ChainStatus{Reason: "consenter for this channel has been halted", Lag: -1}
+	default:
+	}
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	if ch.viewChange != nil {
+		return consensus.ChainStatus{Reason: fmt.Sprintf("changing to view %d", ch.view), Lag: -1}
+	}
+	return consensus.ChainStatus{Ready: true}
+}
+
+// Leader returns the endpoint of the orderer of the leader of the current
+// view, to which the broadcasts are forwarded, if the replicas only accept
+// the requests submitted to the leader and this replica does not lead the
+// view.  It returns "" while the replicas change view, the broadcasts then
+// waiting for the chain to be ready.
+func (ch *chain) Leader() string {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	return ch.leader
+}
+
+// Order submits the normal message to the replica.
+func (ch *chain) Order(env *cb.Envelope, configSeq uint64) error {
+	return ch.submit(&request{configSeq: configSeq, normalMsg: env})
+}
+
+// Configure submits the config message to the replica.
+func (ch *chain) Configure(config *cb.Envelope, configSeq uint64) error {
+	return ch.submit(&request{configSeq: configSeq, configMsg: config})
+}
+
+// CopyWAL copies the log of the requests submitted and not decided yet.
+func (ch *chain) CopyWAL(dir string) error {
+	return ch.wal.copyTo(dir)
+}
+
+// 将请求写入预写日志后提交给副本，由副本广播给其他节点达成共识
+func (ch *chain) submit(req *request) error {
+	select {
+	case <-ch.exitChan:
+		return fmt.Errorf("Exiting")
+	default:
+	}
+	encoded, err := encodeRequest(req)
+	if err != nil {
+		return err
+	}
+	if err := ch.wal.Append(encoded); err != nil {
+		return errors.WithMessage(err, "cannot log request")
+	}
+	if err := ch.replica.Submit(encoded); err != nil {
+		return errors.WithMessage(err, "cannot submit request to BFT replica")
+	}
+	return nil
+}
+
+// init reads the header of the last block written, on which the next block
+// is cut, and the identifier of this orderer, whose signatures the block
+// writer adds itself
+func (ch *chain) init() error {
+	height := ch.support.Height()
+	if height == 0 {
+		return errors.New("the ledger holds no block")
+	}
+	last := blockledger.GetBlock(ch.resources.Reader(), height-1)
+	if last == nil {
+		return errors.Errorf("cannot read block %d", height-1)
+	}
+	ch.lastHeader = last.Header
+
+	shdr, err := ch.support.NewSignatureHeader()
+	if err != nil {
+		return errors.WithMessage(err, "cannot create signature header")
+	}
+	ch.self, err = ch.identifier(shdr.Creator)
+	return err
+}
+
+// resubmit submits the requests logged and not decided, which the replicas
+// may have lost with the view they were submitted in
+func (ch *chain) resubmit() {
+	pending := ch.wal.Pending()
+	if len(pending) == 0 {
+		return
+	}
+	logger.Infof("[channel: %s] Submitting %d pending requests again", ch.support.ChainID(), len(pending))
+	for _, encoded := range pending {
+		if err := ch.replica.Submit(encoded); err != nil {
+			logger.Warningf("[channel: %s] Cannot submit pending request again: %s", ch.support.ChainID(), err)
+		}
+	}
+}
+
+// 消息处理循环，按序提交副本决议的请求，并跟踪视图切换
+func (ch *chain) main() {
+	defer ch.wal.Close()
+	for {
+		select {
+		case decision, ok := <-ch.replica.Decisions():
+			if !ok {
+				logger.Errorf("[channel: %s] BFT replica failed, halting", ch.support.ChainID())
+				ch.halt()
+				return
+			}
+			ch.receive(decision)
+		case viewChange := <-ch.replica.ViewChanges():
+			if ch.changeView(viewChange) {
+				ch.resubmit()
+			}
+		case <-ch.exitChan:
+			logger.Debugf("[channel: %s] Exiting", ch.support.ChainID())
+			return
+		}
+	}
+}
+
+// receive collects the signatures of the blocks the decision carries, and
+// cuts its requests into blocks unless a config block waits for its
+// signatures, in which case the requests are cut once the config block is
+// written, with the new config.
+func (ch *chain) receive(decision *Decision) {
+	if decision.Sequence < ch.checkpoint.Sequence {
+		logger.Debugf("[channel: %s] Skipping decision %d, already committed", ch.support.ChainID(), decision.Sequence)
+		return
+	}
+	if err := ch.wal.Decided(decision.Requests); err != nil {
+		logger.Warningf("[channel: %s] Cannot log decision %d: %s", ch.support.ChainID(), decision.Sequence, err)
+	}
+
+	//先收集决议中其他排序节点的区块签名，等待签名的配置区块之后的请求暂不切割
+	for _, encoded := range decision.Requests {
+		if !IsSignatureRequest(encoded) {
+			continue
+		}
+		req, err := decodeRequest(encoded)
+		if err != nil {
+			logger.Warningf("[channel: %s] Discarding bad signature: %s", ch.support.ChainID(), err)
+			continue
+		}
+		ch.addSignature(req.header, req.signature)
+	}
+	ch.backlog = append(ch.backlog, decision)
+	ch.process()
+}
+
+// process writes the blocks signed and cuts the decisions received, in
+// order, until a config block waits for its signatures
+func (ch *chain) process() {
+	for {
+		ch.writeSigned()
+		if len(ch.backlog) == 0 || ch.configHeld() {
+			return
+		}
+		if ch.cut(ch.backlog[0]) {
+			ch.backlog = ch.backlog[1:]
+		}
+	}
+}
+
+// cut cuts the requests of the decision into blocks, and returns whether it
+// cut all of them, rather than stopping after a config block, the requests
+// after which are cut once it is written.  Every replica
+// cuts the same blocks, as the blocks are cut from the decisions only, each
+// decision ending with a block.  The checkpoint in the metadata of the
+// blocks tells which requests were cut, so that the requests after it are
+// cut after a restart, or once a config block is written.
+func (ch *chain) cut(decision *Decision) bool {
+	start := uint64(0)
+	if decision.Sequence == ch.checkpoint.Sequence {
+		start = ch.applied
+	}
+
+	seq := ch.support.Sequence()
+	for i := start; i < uint64(len(decision.Requests)); i++ {
+		if IsSignatureRequest(decision.Requests[i]) {
+			continue
+		}
+		req, err := decodeRequest(decision.Requests[i])
+		if err != nil {
+			logger.Warningf("[channel: %s] Discarding bad request: %s", ch.support.ChainID(), err)
+			continue
+		}
+
+		if req.configMsg == nil {
+			//普通交易消息，通道配置更新后需要重新过滤
+			if req.configSeq < seq {
+				if _, err := ch.support.ProcessNormalMsg(req.normalMsg); err != nil {
+					logger.Warningf("[channel: %s] Discarding bad normal message: %s", ch.support.ChainID(), err)
+					continue
+				}
+			}
+			batches, pending := ch.support.BlockCutter().Ordered(req.normalMsg)
+			for j, batch := range batches {
+				// only the last batch may hold the message
+				applied := i
+				if j == len(batches)-1 && !pending {
+					applied = i + 1
+				}
+				ch.cutBlock(batch, decision, applied, false)
+			}
+			continue
+		}
+
+		//通道配置交易消息
+		if req.configSeq < seq {
+			req.configMsg, _, err = ch.support.ProcessConfigMsg(req.configMsg)
+			if err != nil {
+				logger.Warningf("[channel: %s] Discarding bad config message: %s", ch.support.ChainID(), err)
+				continue
+			}
+		}
+		if batch := ch.support.BlockCutter().Cut(); len(batch) > 0 {
+			ch.cutBlock(batch, decision, i, false)
+		}
+		ch.cutBlock([]*cb.Envelope{req.configMsg}, decision, i+1, true)
+		//配置区块写入账本后再以新配置切割后续请求
+		return false
+	}
+	if batch := ch.support.BlockCutter().Cut(); len(batch) > 0 {
+		ch.cutBlock(batch, decision, uint64(len(decision.Requests)), false)
+	}
+	return true
+}
+
+// cutBlock holds the block of the batch until a quorum of orderers signed
+// it, and submits the signature of this orderer over its header
+func (ch *chain) cutBlock(batch []*cb.Envelope, decision *Decision, applied uint64, config bool) {
+	ch.checkpoint = Checkpoint{View: decision.View, Sequence: decision.Sequence}
+	ch.applied = applied
+	header := nextHeader(ch.lastHeader, batch)
+	ch.lastHeader = header
+	ch.held = append(ch.held, &heldBlock{
+		batch:    batch,
+		header:   header,
+		metadata: encodeCheckpoint(ch.checkpoint, applied),
+		config:   config,
+	})
+	if ch.replica.Quorum() <= 1 {
+		return
+	}
+
+	signature := &cb.MetadataSignature{SignatureHeader: utils.MarshalOrPanic(utils.NewSignatureHeaderOrPanic(ch.support))}
+	signature.Signature = utils.SignOrPanic(ch.support, util.ConcatenateBytes(nil, signature.SignatureHeader, header.Bytes()))
+	//异步提交签名，避免副本提交阻塞时卡住主循环
+	go func() {
+		if err := ch.submit(&request{header: header, signature: signature}); err != nil {
+			logger.Warningf("[channel: %s] Cannot submit signature of block %d: %s", ch.support.ChainID(), header.Number, err)
+		}
+	}()
+}
+
+// writeSigned writes the blocks held, in order, as long as they are signed
+// by a quorum of orderers, this orderer included, with the signatures of the
+// other orderers in their SIGNATURES metadata, to which the block writer
+// adds the signature of this orderer.
+func (ch *chain) writeSigned() {
+	for len(ch.held) > 0 {
+		held := ch.held[0]
+		number := held.header.Number
+		hash := held.header.Hash()
+		var signatures []*cb.MetadataSignature
+		for _, signature := range ch.signatures[number] {
+			if bytes.Equal(signature.headerHash, hash) {
+				signatures = append(signatures, signature.signature)
+			}
+		}
+		if len(signatures)+1 < ch.replica.Quorum() {
+			return
+		}
+
+		block := ch.support.CreateNextBlock(held.batch)
+		if !bytes.Equal(block.Header.Hash(), hash) {
+			logger.Panicf("[channel: %s] Block %d created does not match the block %d cut", ch.support.ChainID(), block.Header.Number, number)
+		}
+		if len(signatures) > 0 {
+			block.Metadata.Metadata[cb.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&cb.Metadata{Signatures: signatures})
+		}
+		ch.held = ch.held[1:]
+		delete(ch.signatures, number)
+		logger.Debugf("[channel: %s] Writing block %d signed by %d other orderers", ch.support.ChainID(), number, len(signatures))
+		if held.config {
+			ch.support.WriteConfigBlock(block, held.metadata)
+			continue
+		}
+		ch.support.WriteBlock(block, held.metadata)
+	}
+}
+
+// configHeld returns whether a config block waits for its signatures
+func (ch *chain) configHeld() bool {
+	for _, held := range ch.held {
+		if held.config {
+			return true
+		}
+	}
+	return false
+}
+
+// addSignature keeps the signature of another orderer over the header of a
+// block not written yet, if it satisfies the orderer writers policy.  Each
+// orderer, told apart by its identity as deserialized by the MSPs of the
+// channel, is counted once per block.  The signatures of the blocks this
+// orderer did not cut yet are kept for the next maxEarlyBlocks blocks.
+func (ch *chain) addSignature(header *cb.BlockHeader, signature *cb.MetadataSignature) {
+	written := ch.lastHeader.Number - uint64(len(ch.held))
+	if header.Number <= written || header.Number > ch.lastHeader.Number+maxEarlyBlocks {
+		logger.Debugf("[channel: %s] Ignoring signature of block %d", ch.support.ChainID(), header.Number)
+		return
+	}
+	signer, err := ch.verify(header, signature)
+	if err != nil {
+		logger.Warningf("[channel: %s] Discarding signature of block %d: %s", ch.support.ChainID(), header.Number, err)
+		return
+	}
+	if signer == ch.self {
+		return
+	}
+	signers, ok := ch.signatures[header.Number]
+	if !ok {
+		signers = map[string]*blockSignature{}
+		ch.signatures[header.Number] = signers
+	}
+	if _, signed := signers[signer]; !signed {
+		signers[signer] = &blockSignature{headerHash: header.Hash(), signature: signature}
+	}
+}
+
+// verify returns the identifier of the orderer whose signature over the
+// header satisfies the orderer writers policy of the channel
+func (ch *chain) verify(header *cb.BlockHeader, signature *cb.MetadataSignature) (string, error) {
+	shdr, err := utils.GetSignatureHeader(signature.SignatureHeader)
+	if err != nil {
+		return "", err
+	}
+	signer, err := ch.identifier(shdr.Creator)
+	if err != nil {
+		return "", err
+	}
+	policy, ok := ch.resources.PolicyManager().GetPolicy(ordererWritersPolicy)
+	if !ok {
+		return "", errors.Errorf("could not find policy %s", ordererWritersPolicy)
+	}
+	err = policy.Evaluate([]*cb.SignedData{{
+		Data:      util.ConcatenateBytes(nil, signature.SignatureHeader, header.Bytes()),
+		Identity:  shdr.Creator,
+		Signature: signature.Signature,
+	}})
+	if err != nil {
+		return "", errors.WithMessage(err, "signature does not satisfy the orderer writers policy")
+	}
+	return signer, nil
+}
+
+// identifier returns the identifier of the serialized identity within its
+// MSP, the hash of its certificate for the X.509 MSPs
+func (ch *chain) identifier(serializedIdentity []byte) (string, error) {
+	identity, err := ch.resources.MSPManager().DeserializeIdentity(serializedIdentity)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed deserializing identity")
+	}
+	return identity.GetIdentifier().Id, nil
+}
+
+// nextHeader returns the header of the block of the batch following the
+// block of the header, as the block writer creates it
+func nextHeader(last *cb.BlockHeader, batch []*cb.Envelope) *cb.BlockHeader {
+	data := &cb.BlockData{Data: make([][]byte, len(batch))}
+	var err error
+	for i, msg := range batch {
+		data.Data[i], err = proto.Marshal(msg)
+		if err != nil {
+			logger.Panicf("Could not marshal envelope: %s", err)
+		}
+	}
+	return &cb.BlockHeader{Number: last.Number + 1, PreviousHash: last.Hash(), DataHash: data.Hash()}
+}
+
+// changeView tracks the view change, and returns whether it completed
+func (ch *chain) changeView(viewChange ViewChange) bool {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	ch.view = viewChange.View
+	if !viewChange.Complete {
+		ch.leader = ""
+		if ch.viewChange == nil {
+			ch.viewChange = make(chan struct{})
+		}
+		logger.Infof("[channel: %s] Changing to view %d", ch.support.ChainID(), viewChange.View)
+		return false
+	}
+	ch.leader = viewChange.Endpoint
+	if ch.viewChange != nil {
+		close(ch.viewChange)
+		ch.viewChange = nil
+	}
+	logger.Infof("[channel: %s] Changed to view %d led by %s", ch.support.ChainID(), viewChange.View, viewChange.Leader)
+	return true
+}
+
+// IsSignatureRequest returns whether the request carries the signature of
+// an orderer over the header of a block, which the replicas of the
+// libraries only accepting the requests submitted to the leader must accept
+// from any orderer.
+func IsSignatureRequest(request []byte) bool {
+	return len(request) > 0 && request[0] == signatureRequest
+}
+
+func encodeRequest(req *request) ([]byte, error) {
+	kind, slot := normalRequest, req.configSeq
+	var msg proto.Message = req.normalMsg
+	switch {
+	case req.configMsg != nil:
+		kind, msg = configRequest, req.configMsg
+	case req.signature != nil:
+		kind, slot = signatureRequest, req.header.Number
+		msg = &cb.Metadata{Value: utils.MarshalOrPanic(req.header), Signatures: []*cb.MetadataSignature{req.signature}}
+	}
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not marshal message")
+	}
+	encoded := make([]byte, requestHeaderSize+len(data))
+	encoded[0] = kind
+	binary.BigEndian.PutUint64(encoded[1:], slot)
+	copy(encoded[requestHeaderSize:], data)
+	return encoded, nil
+}
+
+func decodeRequest(encoded []byte) (*request, error) {
+	if len(encoded) < requestHeaderSize {
+		return nil, errors.Errorf("request of %d bytes is truncated", len(encoded))
+	}
+	if encoded[0] == signatureRequest {
+		return decodeSignature(encoded)
+	}
+	env := &cb.Envelope{}
+	if err := proto.Unmarshal(encoded[requestHeaderSize:], env); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal request")
+	}
+	req := &request{configSeq: binary.BigEndian.Uint64(encoded[1:])}
+	switch encoded[0] {
+	case normalRequest:
+		req.normalMsg = env
+	case configRequest:
+		req.configMsg = env
+	default:
+		return nil, errors.Errorf("unknown request kind %d", encoded[0])
+	}
+	return req, nil
+}
+
+func decodeSignature(encoded []byte) (*request, error) {
+	metadata := &cb.Metadata{}
+	if err := proto.Unmarshal(encoded[requestHeaderSize:], metadata); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal signature")
+	}
+	header := &cb.BlockHeader{}
+	if err := proto.Unmarshal(metadata.Value, header); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal block header")
+	}
+	if len(metadata.Signatures) != 1 {
+		return nil, errors.Errorf("expected 1 signature, got %d", len(metadata.Signatures))
+	}
+	if header.Number != binary.BigEndian.Uint64(encoded[1:]) {
+		return nil, errors.Errorf("signature of block %d encoded as the signature of block %d", header.Number, binary.BigEndian.Uint64(encoded[1:]))
+	}
+	return &request{header: header, signature: metadata.Signatures[0]}, nil
+}
+
+func encodeCheckpoint(checkpoint Checkpoint, applied uint64) []byte {
+	encoded := make([]byte, checkpointSize)
+	binary.BigEndian.PutUint64(encoded, checkpoint.View)
+	binary.BigEndian.PutUint64(encoded[8:], checkpoint.Sequence)
+	binary.BigEndian.PutUint64(encoded[16:], applied)
+	return encoded
+}
+
+// decodeCheckpoint decodes the checkpoint of the metadata of the last block,
+// the zero checkpoint if the chain has no block written by this consenter
+func decodeCheckpoint(metadata *cb.Metadata) (Checkpoint, uint64, error) {
+	if metadata == nil || len(metadata.Value) == 0 {
+		return Checkpoint{}, 0, nil
+	}
+	if len(metadata.Value) != checkpointSize {
+		return Checkpoint{}, 0, errors.Errorf("BFT checkpoint of %d bytes, expected %d", len(metadata.Value), checkpointSize)
+	}
+	checkpoint := Checkpoint{
+		View:     binary.BigEndian.Uint64(metadata.Value),
+		Sequence: binary.BigEndian.Uint64(metadata.Value[8:]),
+	}
+	return checkpoint, binary.BigEndian.Uint64(metadata.Value[16:]), nil
+}
@@ -0,0 +1,479 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bft
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/common/ledger/blockledger"
+	ramledger "github.com/hyperledger/fabric/common/ledger/blockledger/ram"
+	mockconfig "github.com/hyperledger/fabric/common/mocks/config"
+	mockpolicies "github.com/hyperledger/fabric/common/mocks/policies"
+	"github.com/hyperledger/fabric/common/policies"
+	"github.com/hyperledger/fabric/msp"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	mockblockcutter "github.com/hyperledger/fabric/orderer/mocks/common/blockcutter"
+	mockmultichannel "github.com/hyperledger/fabric/orderer/mocks/common/multichannel"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+func init() {
+	flogging.SetModuleLevel(pkgLogID, "DEBUG")
+}
+
+func testMessage(data string) *cb.Envelope {
+	return &cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: &cb.Header{ChannelHeader: utils.MarshalOrPanic(&cb.ChannelHeader{ChannelId: "foo"})},
+			Data:   []byte(data),
+		}),
+	}
+}
+
+type mockReplica struct {
+	startErr    error
+	quorum      int
+	started     chan Checkpoint
+	submitted   chan []byte
+	decisions   chan *Decision
+	viewChanges chan ViewChange
+	halted      chan struct{}
+}
+
+func newMockReplica() *mockReplica {
+	return &mockReplica{
+		started:     make(chan Checkpoint, 1),
+		submitted:   make(chan []byte, 10),
+		decisions:   make(chan *Decision),
+		viewChanges: make(chan ViewChange),
+		halted:      make(chan struct{}),
+		quorum:      1,
+	}
+}
+
+func (r *mockReplica) Start(checkpoint Checkpoint) error {
+	r.started <- checkpoint
+	return r.startErr
+}
+
+func (r *mockReplica) Submit(request []byte) error {
+	r.submitted <- request
+	return nil
+}
+
+func (r *mockReplica) Quorum() int                               { return r.quorum }
+func (r *mockReplica) Decisions() <-chan *Decision               { return r.decisions }
+func (r *mockReplica) ViewChanges() <-chan ViewChange            { return r.viewChanges }
+func (r *mockReplica) Halt()                                     { close(r.halted) }
+func (r *mockReplica) NewReplica(ReplicaConfig) (Replica, error) { return r, nil }
+
+// testSupport chains the blocks it creates to the blocks of its ledger, and
+// authenticates the orderers whose serialized identity is their name
+type testSupport struct {
+	*mockmultichannel.ConsenterSupport
+	ledger   blockledger.ReadWriter
+	policies *mockpolicies.Manager
+}
+
+func newSupport() *testSupport {
+	support := &mockmultichannel.ConsenterSupport{
+		Blocks:          make(chan *cb.Block, 10),
+		BlockCutterVal:  mockblockcutter.NewReceiver(),
+		SharedConfigVal: &mockconfig.Orderer{BatchTimeoutVal: time.Second},
+		ChainIDVal:      "foo",
+	}
+	close(support.BlockCutterVal.Block)
+	ledger, _ := ramledger.New(10).GetOrCreate("foo")
+	ledger.Append(cb.NewBlock(0, nil))
+	return &testSupport{
+		ConsenterSupport: support,
+		ledger:           ledger,
+		policies: &mockpolicies.Manager{PolicyMap: map[string]policies.Policy{
+			ordererWritersPolicy: policyFunc(func(signedData []*cb.SignedData) error {
+				if string(signedData[0].Identity) == "intruder" {
+					return errors.New("not an orderer")
+				}
+				return nil
+			}),
+		}},
+	}
+}
+
+func (s *testSupport) CreateNextBlock(messages []*cb.Envelope) *cb.Block {
+	return blockledger.CreateNextBlock(s.ledger, messages)
+}
+
+func (s *testSupport) WriteBlock(block *cb.Block, encodedMetadataValue []byte) {
+	s.ledger.Append(block)
+	s.ConsenterSupport.WriteBlock(block, encodedMetadataValue)
+}
+
+func (s *testSupport) WriteConfigBlock(block *cb.Block, encodedMetadataValue []byte) {
+	s.WriteBlock(block, encodedMetadataValue)
+}
+
+func (s *testSupport) Height() uint64                  { return s.ledger.Height() }
+func (s *testSupport) Reader() blockledger.Reader      { return s.ledger }
+func (s *testSupport) PolicyManager() policies.Manager { return s.policies }
+func (s *testSupport) MSPManager() msp.MSPManager      { return mspManager{} }
+
+type policyFunc func([]*cb.SignedData) error
+
+func (f policyFunc) Evaluate(signedData []*cb.SignedData) error { return f(signedData) }
+
+type mspManager struct {
+	msp.MSPManager
+}
+
+func (mspManager) DeserializeIdentity(serializedIdentity []byte) (msp.Identity, error) {
+	if len(serializedIdentity) == 0 {
+		return identity{id: "self"}, nil
+	}
+	return identity{id: string(serializedIdentity)}, nil
+}
+
+type identity struct {
+	msp.Identity
+	id string
+}
+
+func (i identity) GetIdentifier() *msp.IdentityIdentifier {
+	return &msp.IdentityIdentifier{Mspid: "OrdererMSP", Id: i.id}
+}
+
+func newTestChain(t *testing.T, support *testSupport, checkpoint Checkpoint, applied uint64) (*chain, *mockReplica, func()) {
+	dir, err := ioutil.TempDir("", "bft-chain")
+	require.NoError(t, err)
+	w, err := openWAL(dir)
+	require.NoError(t, err)
+	replica := newMockReplica()
+	return newChain(support, support, replica, w, checkpoint, applied), replica, func() { os.RemoveAll(dir) }
+}
+
+func encodedRequest(t *testing.T, req *request) []byte {
+	encoded, err := encodeRequest(req)
+	require.NoError(t, err)
+	return encoded
+}
+
+func nextBlock(t *testing.T, support *testSupport) (*cb.Block, Checkpoint, uint64) {
+	select {
+	case block := <-support.Blocks:
+		metadata := &cb.Metadata{}
+		require.NoError(t, proto.Unmarshal(block.Metadata.Metadata[cb.BlockMetadataIndex_ORDERER], metadata))
+		checkpoint, applied, err := decodeCheckpoint(metadata)
+		require.NoError(t, err)
+		return block, checkpoint, applied
+	case <-time.After(time.Second):
+		t.Fatal("Expected a block to be written")
+		return nil, Checkpoint{}, 0
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("Condition not met in time")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestChainOrder(t *testing.T) {
+	support := newSupport()
+	ch, replica, cleanup := newTestChain(t, support, Checkpoint{}, 0)
+	defer cleanup()
+	ch.Start()
+	defer ch.Halt()
+	assert.Equal(t, Checkpoint{}, <-replica.started)
+
+	require.NoError(t, ch.Order(testMessage("first"), 0))
+	require.NoError(t, ch.Configure(testMessage("config"), 0))
+	require.NoError(t, ch.Order(testMessage("second"), 0))
+	var requests [][]byte
+	for i := 0; i < 3; i++ {
+		requests = append(requests, <-replica.submitted)
+	}
+	assert.Len(t, ch.wal.Pending(), 3)
+
+	replica.decisions <- &Decision{View: 2, Sequence: 7, Requests: requests}
+
+	// the config message is ordered in a block of its own, between the
+	// normal messages
+	for i, data := range []string{"first", "config", "second"} {
+		block, checkpoint, applied := nextBlock(t, support)
+		require.Len(t, block.Data.Data, 1)
+		assert.Equal(t, utils.MarshalOrPanic(testMessage(data)), block.Data.Data[0])
+		assert.Equal(t, Checkpoint{View: 2, Sequence: 7}, checkpoint)
+		assert.Equal(t, uint64(i+1), applied)
+	}
+	waitFor(t, func() bool { return len(ch.wal.Pending()) == 0 })
+
+	t.Run("Revalidation", func(t *testing.T) {
+		support.SequenceVal = 1
+		support.ProcessNormalMsgErr = errors.New("no longer valid")
+		replica.decisions <- &Decision{View: 2, Sequence: 8, Requests: [][]byte{
+			encodedRequest(t, &request{configSeq: 0, normalMsg: testMessage("stale")}),
+			encodedRequest(t, &request{configSeq: 1, normalMsg: testMessage("fresh")}),
+			[]byte("garbage"),
+		}}
+		block, _, applied := nextBlock(t, support)
+		assert.Equal(t, [][]byte{utils.MarshalOrPanic(testMessage("fresh"))}, block.Data.Data)
+		assert.Equal(t, uint64(3), applied)
+	})
+}
+
+// signatureOf encodes the request of the signature of the orderer over the
+// header
+func signatureOf(t *testing.T, header *cb.BlockHeader, signer string) []byte {
+	return encodedRequest(t, &request{header: header, signature: &cb.MetadataSignature{
+		SignatureHeader: utils.MarshalOrPanic(&cb.SignatureHeader{Creator: []byte(signer)}),
+		Signature:       []byte("signature of " + signer),
+	}})
+}
+
+func signersOf(t *testing.T, block *cb.Block) []string {
+	metadata, err := utils.GetMetadataFromBlock(block, cb.BlockMetadataIndex_SIGNATURES)
+	require.NoError(t, err)
+	var signers []string
+	for _, signature := range metadata.Signatures {
+		shdr, err := utils.GetSignatureHeader(signature.SignatureHeader)
+		require.NoError(t, err)
+		signers = append(signers, string(shdr.Creator))
+	}
+	return signers
+}
+
+func TestChainQuorum(t *testing.T) {
+	support := newSupport()
+	ch, replica, cleanup := newTestChain(t, support, Checkpoint{}, 0)
+	defer cleanup()
+	replica.quorum = 3
+	ch.Start()
+	defer ch.Halt()
+	<-replica.started
+
+	first := nextHeader(blockledger.GetBlock(support.ledger, 0).Header, []*cb.Envelope{testMessage("first")})
+	config := nextHeader(first, []*cb.Envelope{testMessage("config")})
+	second := nextHeader(config, []*cb.Envelope{testMessage("second")})
+
+	replica.decisions <- &Decision{View: 1, Sequence: 1, Requests: [][]byte{
+		encodedRequest(t, &request{normalMsg: testMessage("first")}),
+		encodedRequest(t, &request{configMsg: testMessage("config")}),
+		encodedRequest(t, &request{normalMsg: testMessage("second")}),
+	}}
+
+	// this orderer submits its signature of the blocks it cut, up to the
+	// config block, which the next requests wait for
+	signed := map[uint64]bool{}
+	for i := 0; i < 2; i++ {
+		submitted := <-replica.submitted
+		require.True(t, IsSignatureRequest(submitted))
+		req, err := decodeRequest(submitted)
+		require.NoError(t, err)
+		signed[req.header.Number] = true
+	}
+	assert.Equal(t, map[uint64]bool{1: true, 2: true}, signed)
+
+	// the signatures of intruders, of this orderer and the same orderer
+	// signing twice do not count towards the quorum
+	replica.decisions <- &Decision{View: 1, Sequence: 2, Requests: [][]byte{
+		signatureOf(t, first, "orderer2"),
+		signatureOf(t, first, "orderer2"),
+		signatureOf(t, first, "intruder"),
+		signatureOf(t, first, ""),
+		signatureOf(t, config, "orderer2"),
+		signatureOf(t, second, "orderer3"),
+		signatureOf(t, second, "orderer4"),
+	}}
+	select {
+	case block := <-support.Blocks:
+		t.Fatalf("Block %d written before it was signed by a quorum", block.Header.Number)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// once signed by a quorum, the blocks are written with the signatures,
+	// and the requests after the config block cut into blocks, the
+	// signatures of which were decided already
+	replica.decisions <- &Decision{View: 1, Sequence: 3, Requests: [][]byte{
+		signatureOf(t, first, "orderer3"),
+		signatureOf(t, config, "orderer3"),
+	}}
+	for i, header := range []*cb.BlockHeader{first, config, second} {
+		block, checkpoint, applied := nextBlock(t, support)
+		assert.Equal(t, header.Hash(), block.Header.Hash())
+		assert.Equal(t, Checkpoint{View: 1, Sequence: 1}, checkpoint)
+		assert.Equal(t, uint64(i+1), applied)
+		if i < 2 {
+			assert.ElementsMatch(t, []string{"orderer2", "orderer3"}, signersOf(t, block))
+		} else {
+			assert.Len(t, signersOf(t, block), 2)
+		}
+	}
+	assert.Empty(t, ch.signatures)
+
+	req, err := decodeRequest(<-replica.submitted)
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(second, req.header))
+}
+
+func TestChainRecovery(t *testing.T) {
+	support := newSupport()
+	ch, replica, cleanup := newTestChain(t, support, Checkpoint{View: 1, Sequence: 5}, 1)
+	defer cleanup()
+	ch.Start()
+	defer ch.Halt()
+	assert.Equal(t, Checkpoint{View: 1, Sequence: 5}, <-replica.started)
+
+	// decisions committed before the restart are skipped, as are the
+	// requests committed of the last one
+	replica.decisions <- &Decision{View: 1, Sequence: 4, Requests: [][]byte{
+		encodedRequest(t, &request{normalMsg: testMessage("old")}),
+	}}
+	replica.decisions <- &Decision{View: 1, Sequence: 5, Requests: [][]byte{
+		encodedRequest(t, &request{normalMsg: testMessage("committed")}),
+		encodedRequest(t, &request{normalMsg: testMessage("lost")}),
+	}}
+	block, checkpoint, applied := nextBlock(t, support)
+	assert.Equal(t, [][]byte{utils.MarshalOrPanic(testMessage("lost"))}, block.Data.Data)
+	assert.Equal(t, Checkpoint{View: 1, Sequence: 5}, checkpoint)
+	assert.Equal(t, uint64(2), applied)
+}
+
+func TestChainViewChange(t *testing.T) {
+	support := newSupport()
+	ch, replica, cleanup := newTestChain(t, support, Checkpoint{}, 0)
+	defer cleanup()
+	ch.Start()
+	defer ch.Halt()
+	<-replica.started
+
+	require.NoError(t, ch.Order(testMessage("pending"), 0))
+	submitted := <-replica.submitted
+	assert.NoError(t, ch.WaitReady())
+
+	replica.viewChanges <- ViewChange{View: 1}
+	waitFor(t, func() bool { return !ch.Status().Ready })
+	assert.Equal(t, "changing to view 1", ch.Status().Reason)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := ch.WaitReadyContext(ctx)
+	require.IsType(t, &consensus.NotReadyError{}, err)
+	assert.Equal(t, "changing to view 1", err.(*consensus.NotReadyError).Reason)
+
+	ready := make(chan error)
+	go func() { ready <- ch.WaitReady() }()
+	replica.viewChanges <- ViewChange{View: 1, Leader: "orderer2", Complete: true}
+	assert.NoError(t, <-ready)
+	assert.True(t, ch.Status().Ready)
+
+	// the request pending in the WAL is submitted again to the new leader
+	assert.Equal(t, submitted, <-replica.submitted)
+}
+
+func TestChainLeader(t *testing.T) {
+	support := newSupport()
+	ch, replica, cleanup := newTestChain(t, support, Checkpoint{}, 0)
+	defer cleanup()
+	ch.Start()
+	defer ch.Halt()
+	<-replica.started
+	assert.Equal(t, "", ch.Leader())
+
+	// the broadcasts are forwarded to the orderer of the leader
+	replica.viewChanges <- ViewChange{View: 1, Leader: "orderer2", Complete: true, Endpoint: "orderer2:7050"}
+	waitFor(t, func() bool { return ch.Leader() == "orderer2:7050" })
+
+	// but not while the replicas change view
+	replica.viewChanges <- ViewChange{View: 2}
+	waitFor(t, func() bool { return ch.Leader() == "" })
+
+	// nor once this replica leads the view
+	replica.viewChanges <- ViewChange{View: 2, Leader: "orderer1", Complete: true}
+	waitFor(t, func() bool { return ch.Status().Ready })
+	assert.Equal(t, "", ch.Leader())
+}
+
+func TestChainReplicaFailure(t *testing.T) {
+	support := newSupport()
+	ch, replica, cleanup := newTestChain(t, support, Checkpoint{}, 0)
+	defer cleanup()
+	ch.Start()
+	<-replica.started
+
+	close(replica.decisions)
+	select {
+	case <-ch.Errored():
+	case <-time.After(time.Second):
+		t.Fatal("Expected Errored to be closed once the replica failed")
+	}
+	<-replica.halted
+	assert.Error(t, ch.Order(testMessage("late"), 0))
+	assert.Error(t, ch.WaitReady())
+	assert.False(t, ch.Status().Ready)
+
+	t.Run("StartFailure", func(t *testing.T) {
+		ch, replica, cleanup := newTestChain(t, newSupport(), Checkpoint{}, 0)
+		defer cleanup()
+		replica.startErr = errors.New("no quorum")
+		ch.Start()
+		select {
+		case <-ch.Errored():
+		default:
+			t.Fatal("Expected Errored to be closed once the replica failed to start")
+		}
+	})
+}
+
+func TestConsenter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bft-consenter")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	replica := newMockReplica()
+	Register("mock", func(map[string]string) (Library, error) { return replica, nil })
+	defer delete(builtins, "mock")
+
+	c, err := New(localconfig.BFT{Name: "mock", WALDir: dir})
+	require.NoError(t, err)
+
+	support := newSupport()
+	metadata := &cb.Metadata{Value: encodeCheckpoint(Checkpoint{View: 3, Sequence: 9}, 2)}
+	ch, err := c.HandleChain(support, metadata)
+	require.NoError(t, err)
+	assert.Equal(t, Checkpoint{View: 3, Sequence: 9}, ch.(*chain).checkpoint)
+	assert.Equal(t, uint64(2), ch.(*chain).applied)
+	ch.(*chain).wal.Close()
+
+	_, err = c.HandleChain(support, &cb.Metadata{Value: []byte("bad")})
+	assert.Error(t, err)
+	_, err = c.HandleChain(support.ConsenterSupport, metadata)
+	assert.EqualError(t, err, "consenter support does not provide the ledger, policies and MSPs of the channel")
+
+	for _, conf := range []localconfig.BFT{
+		{WALDir: dir},
+		{Name: "mock"},
+		{Name: "missing", WALDir: dir},
+		{Name: "mock", Library: "/nonexistent.so", WALDir: dir},
+	} {
+		_, err := New(conf)
+		assert.Error(t, err, "%+v", conf)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package bft implements a consenter tolerating Byzantine faults on top of a
+// pluggable BFT state machine replication library.  The library orders the
+// requests submitted to the replica of each channel into decisions, which
+// every orderer cuts into the same blocks.  Unlike solo and Kafka, which
+// trust the orderers, the chains keep ordering as long as fewer than a third
+// of the orderers are faulty, whether crashed or malicious.
+//
+// Each block is held until signed by a quorum of orderers, 2f+1 of 3f+1:
+// every orderer submits its signature over the header of each block it cuts
+// as a request of its own, and writes the block with the signatures of the
+// other orderers decided, as checked against the orderer writers policy of
+// the channel, along with its own.  The peers and the other clients of the
+// channel must require as many signatures, so that a block signed by fewer
+// than f+1 correct orderers is rejected, either with a BlockValidation
+// policy of the channel requiring the signatures of 2f+1 distinct orderers,
+// such as an explicit 2f+1 out of the orderer identities signature policy,
+// or with the QuorumBlockVerification block verifier of the peers, whose
+// quorum parameter is set to 2f+1.  The block writer keeps the signatures of
+// the other orderers, which batch signing would replace, hence the BFT
+// consenter cannot be used along with batch signing.
+//
+// The libraries are compiled in and registered by name, or built as Go
+// plugins exporting
+//
+//	func NewBFTLibrary(parameters map[string]string) (bft.Library, error)
+package bft
+
+import (
+	"path/filepath"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+)
+
+const pkgLogID = "orderer/consensus/bft"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+type consenter struct {
+	library Library
+	walDir  string
+}
+
+// New creates a BFT consenter ordering with the configured library.
+func New(conf localconfig.BFT) (consensus.Consenter, error) {
+	if conf.Name == "" {
+		return nil, errors.New("BFT library has no name")
+	}
+	if conf.WALDir == "" {
+		return nil, errors.New("BFT WAL directory is not set")
+	}
+	library, err := loadLibrary(conf.Name, conf.Library, conf.Parameters)
+	if err != nil {
+		return nil, err
+	}
+	logger.Infof("Loaded BFT library %s", conf.Name)
+	return newConsenter(library, conf.WALDir), nil
+}
+
+func newConsenter(library Library, walDir string) *consenter {
+	return &consenter{library: library, walDir: walDir}
+}
+
+// HandleChain creates the replica of the channel, which joins the consensus
+// after the checkpoint of the last block once the chain is started.
+func (c *consenter) HandleChain(support consensus.ConsenterSupport, metadata *cb.Metadata) (consensus.Chain, error) {
+	resources, ok := support.(channelResources)
+	if !ok {
+		return nil, errors.New("consenter support does not provide the ledger, policies and MSPs of the channel")
+	}
+	checkpoint, applied, err := decodeCheckpoint(metadata)
+	if err != nil {
+		return nil, errors.WithMessage(err, "cannot decode ORDERER metadata of the last block")
+	}
+	w, err := openWAL(filepath.Join(c.walDir, support.ChainID()))
+	if err != nil {
+		return nil, err
+	}
+	replica, err := c.library.NewReplica(ReplicaConfig{ChannelID: support.ChainID(), Signer: support})
+	if err != nil {
+		w.Close()
+		return nil, errors.WithMessage(err, "cannot create BFT replica")
+	}
+	return newChain(support, resources, replica, w, checkpoint, applied), nil
+}
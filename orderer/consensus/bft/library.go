@@ -0,0 +1,161 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bft
+
+import (
+	"os"
+	"plugin"
+
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/pluginabi"
+	"github.com/pkg/errors"
+)
+
+// libraryFactory is the symbol Go plugins export to create their Library
+const libraryFactory = "NewBFTLibrary"
+
+// Library is a Byzantine fault tolerant state machine replication library,
+// creating a replica of the consensus of each channel.
+type Library interface {
+	// NewReplica creates the replica of the channel, which takes part in
+	// the consensus once started
+	NewReplica(conf ReplicaConfig) (Replica, error)
+}
+
+// ReplicaConfig configures the replica of a channel.
+type ReplicaConfig struct {
+	ChannelID string
+
+	// Signer signs the messages of the replica with the identity of the
+	// orderer
+	Signer crypto.LocalSigner
+}
+
+// Replica takes part in the consensus on the order of the requests of a
+// channel.  The requests submitted to any correct replica are eventually
+// decided, in the same order by every correct replica, as long as fewer than
+// a third of the replicas are faulty.
+type Replica interface {
+	// Start joins the consensus after the checkpoint, the decisions up to
+	// which are already committed
+	Start(checkpoint Checkpoint) error
+
+	// Submit proposes the request for ordering.  A request submitted more
+	// than once may be decided more than once.  The replicas of the
+	// libraries which only accept the requests submitted to the leader
+	// return an error for the requests submitted to the other replicas,
+	// except for the requests carrying the signatures of the orderers over
+	// the blocks, told apart by IsSignatureRequest, which every orderer
+	// submits to its own replica.
+	Submit(request []byte) error
+
+	// Quorum returns the number of replicas whose agreement the decisions
+	// take, 2f+1 of 3f+1 replicas.  The blocks are written once signed by
+	// as many orderers.
+	Quorum() int
+
+	// Decisions returns the decisions in the order of their sequence, and
+	// is closed if the replica fails
+	Decisions() <-chan *Decision
+
+	// ViewChanges returns the changes of view, during which no request is
+	// decided
+	ViewChanges() <-chan ViewChange
+
+	// Halt leaves the consensus
+	Halt()
+}
+
+// Checkpoint identifies the last decision committed.
+type Checkpoint struct {
+	View     uint64
+	Sequence uint64
+}
+
+// Decision is a batch of requests decided in a view.
+type Decision struct {
+	View     uint64
+	Sequence uint64
+	Requests [][]byte
+}
+
+// ViewChange tells that the replicas moved to a new view, with a new
+// leader, or are done doing so.
+type ViewChange struct {
+	View     uint64
+	Leader   string
+	Complete bool
+
+	// Endpoint is the address of the AtomicBroadcast service of the orderer
+	// of the leader, for the libraries whose replicas only accept the
+	// requests submitted to the leader: the other orderers forward the
+	// broadcasts of their clients to it.  It is empty if this replica leads
+	// the view, or if the requests may be submitted to any replica.
+	Endpoint string
+}
+
+// LibraryFactory creates a Library from its parameters, such as the
+// identity of this orderer among the replicas and their endpoints.
+type LibraryFactory func(parameters map[string]string) (Library, error)
+
+var builtins = map[string]LibraryFactory{}
+
+// Register makes a compiled-in library available under the name.  It is
+// meant to be called from init functions and panics if the name is taken.
+func Register(name string, factory LibraryFactory) {
+	if _, exists := builtins[name]; exists {
+		logger.Panicf("BFT library %s registered twice", name)
+	}
+	builtins[name] = factory
+}
+
+// loadLibrary creates the compiled-in library of the name or, if the path
+// is set, the library of the Go plugin at the path
+func loadLibrary(name, path string, parameters map[string]string) (Library, error) {
+	factory, err := factoryOf(name, path)
+	if err != nil {
+		return nil, err
+	}
+	library, err := factory(parameters)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed to create BFT library "+name)
+	}
+	if library == nil {
+		return nil, errors.Errorf("BFT library %s created no library", name)
+	}
+	return library, nil
+}
+
+func factoryOf(name, path string) (LibraryFactory, error) {
+	if path == "" {
+		factory, ok := builtins[name]
+		if !ok {
+			return nil, errors.Errorf("no compiled-in BFT library is named %s", name)
+		}
+		return factory, nil
+	}
+
+	if _, err := os.Stat(path); err != nil {
+		return nil, errors.Wrapf(err, "could not find plugin at path %s", path)
+	}
+	if err := pluginabi.Check(path); err != nil {
+		return nil, err
+	}
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error opening plugin at path %s", path)
+	}
+	symbol, err := p.Lookup(libraryFactory)
+	if err != nil {
+		return nil, errors.Wrapf(err, "plugin at path %s must export %s", path, libraryFactory)
+	}
+	factory, ok := symbol.(func(map[string]string) (Library, error))
+	if !ok {
+		return nil, errors.Errorf("%s of plugin at path %s does not match expected definition func(map[string]string) (bft.Library, error)", libraryFactory, path)
+	}
+	return factory, nil
+}
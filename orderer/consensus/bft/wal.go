@@ -0,0 +1,240 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bft
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	walFileName = "wal"
+
+	// records are prefixed by the length and the checksum of their kind
+	// and payload
+	walHeaderSize = 8
+)
+
+// a submitted record holds a request submitted to the replica, and a
+// decided record the digests of the submitted requests since decided
+const (
+	submittedRecord byte = iota
+	decidedRecord
+)
+
+// wal is the write-ahead log of the requests submitted to the replica of a
+// channel and not decided yet, which are submitted again after a restart,
+// as the replicas may have lost them along with the view they were
+// submitted in.
+type wal struct {
+	mutex   sync.Mutex
+	file    *os.File
+	pending map[[sha256.Size]byte][]byte
+	order   [][sha256.Size]byte
+}
+
+// openWAL opens the log in the directory, creating it if needed, and
+// replays its records.  A torn record at the end of the log, written as the
+// orderer crashed, is discarded.
+func openWAL(dir string) (*wal, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, errors.Wrapf(err, "failed to create WAL directory %s", dir)
+	}
+	file, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_RDWR|os.O_CREATE, 0640)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to open WAL")
+	}
+	w := &wal{file: file, pending: map[[sha256.Size]byte][]byte{}}
+	end, err := w.replay()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if err := file.Truncate(end); err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "failed to truncate WAL")
+	}
+	if _, err := file.Seek(end, io.SeekStart); err != nil {
+		file.Close()
+		return nil, errors.Wrap(err, "failed to seek WAL")
+	}
+	return w, nil
+}
+
+// replay reads the records of the log, and returns the offset of the end of
+// the last valid one
+func (w *wal) replay() (int64, error) {
+	data, err := readAll(w.file)
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read WAL")
+	}
+	var offset int64
+	for len(data)-int(offset) >= walHeaderSize {
+		header := data[offset : offset+walHeaderSize]
+		size := int64(binary.BigEndian.Uint32(header))
+		if size == 0 || offset+walHeaderSize+size > int64(len(data)) {
+			break
+		}
+		record := data[offset+walHeaderSize : offset+walHeaderSize+size]
+		if crc32.ChecksumIEEE(record) != binary.BigEndian.Uint32(header[4:]) {
+			break
+		}
+		switch record[0] {
+		case submittedRecord:
+			w.add(append([]byte{}, record[1:]...))
+		case decidedRecord:
+			digests := record[1:]
+			for len(digests) >= sha256.Size {
+				var digest [sha256.Size]byte
+				copy(digest[:], digests)
+				w.remove(digest)
+				digests = digests[sha256.Size:]
+			}
+		default:
+			return 0, errors.Errorf("unknown WAL record kind %d at offset %d", record[0], offset)
+		}
+		offset += walHeaderSize + size
+	}
+	if offset < int64(len(data)) {
+		logger.Warningf("Discarding %d bytes of torn WAL records", int64(len(data))-offset)
+	}
+	return offset, nil
+}
+
+func readAll(file *os.File) ([]byte, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	buf := &bytes.Buffer{}
+	_, err := buf.ReadFrom(file)
+	return buf.Bytes(), err
+}
+
+// Append logs the request before it is submitted.
+func (w *wal) Append(request []byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if err := w.write(submittedRecord, request); err != nil {
+		return err
+	}
+	w.add(request)
+	return nil
+}
+
+// Decided forgets the requests of the decision which were logged, the log
+// being emptied once no request is pending.
+func (w *wal) Decided(requests [][]byte) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	var digests []byte
+	for _, request := range requests {
+		digest := sha256.Sum256(request)
+		if _, ok := w.pending[digest]; ok {
+			w.remove(digest)
+			digests = append(digests, digest[:]...)
+		}
+	}
+	if len(digests) == 0 {
+		return nil
+	}
+	if len(w.pending) == 0 {
+		return w.reset()
+	}
+	return w.write(decidedRecord, digests)
+}
+
+// Pending returns the requests logged and not decided, in the order they
+// were logged.
+func (w *wal) Pending() [][]byte {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	var requests [][]byte
+	for _, digest := range w.order {
+		requests = append(requests, w.pending[digest])
+	}
+	return requests
+}
+
+// Close closes the log file.
+func (w *wal) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.file.Close()
+}
+
+// copyTo copies the log into the directory, no record being written
+// meanwhile.
+func (w *wal) copyTo(dir string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return errors.Wrapf(err, "failed to create directory %s", dir)
+	}
+	dst, err := os.OpenFile(filepath.Join(dir, walFileName), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0640)
+	if err != nil {
+		return errors.Wrap(err, "failed to create WAL copy")
+	}
+	defer dst.Close()
+	if _, err := io.Copy(dst, io.NewSectionReader(w.file, 0, 1<<62)); err != nil {
+		return errors.Wrap(err, "failed to copy WAL")
+	}
+	return errors.Wrap(dst.Sync(), "failed to sync WAL copy")
+}
+
+func (w *wal) add(request []byte) {
+	digest := sha256.Sum256(request)
+	if _, ok := w.pending[digest]; ok {
+		return
+	}
+	w.pending[digest] = request
+	w.order = append(w.order, digest)
+}
+
+func (w *wal) remove(digest [sha256.Size]byte) {
+	if _, ok := w.pending[digest]; !ok {
+		return
+	}
+	delete(w.pending, digest)
+	for i := range w.order {
+		if w.order[i] == digest {
+			w.order = append(w.order[:i], w.order[i+1:]...)
+			break
+		}
+	}
+}
+
+// write appends the record to the log and syncs it to disk
+func (w *wal) write(kind byte, payload []byte) error {
+	record := make([]byte, walHeaderSize+1+len(payload))
+	record[walHeaderSize] = kind
+	copy(record[walHeaderSize+1:], payload)
+	binary.BigEndian.PutUint32(record, uint32(1+len(payload)))
+	binary.BigEndian.PutUint32(record[4:], crc32.ChecksumIEEE(record[walHeaderSize:]))
+	if _, err := w.file.Write(record); err != nil {
+		return errors.Wrap(err, "failed to write WAL record")
+	}
+	return errors.Wrap(w.file.Sync(), "failed to sync WAL")
+}
+
+// reset empties the log
+func (w *wal) reset() error {
+	if err := w.file.Truncate(0); err != nil {
+		return errors.Wrap(err, "failed to truncate WAL")
+	}
+	if _, err := w.file.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "failed to seek WAL")
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package bft
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWAL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bft-wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := openWAL(dir)
+	require.NoError(t, err)
+	assert.Empty(t, w.Pending())
+
+	for _, request := range []string{"a", "b", "c"} {
+		require.NoError(t, w.Append([]byte(request)))
+	}
+	require.NoError(t, w.Decided([][]byte{[]byte("b"), []byte("unknown")}))
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("c")}, w.Pending())
+	require.NoError(t, w.Close())
+
+	// the pending requests survive a restart
+	w, err = openWAL(dir)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("c")}, w.Pending())
+
+	// the log is emptied once every request is decided
+	require.NoError(t, w.Decided([][]byte{[]byte("c"), []byte("a")}))
+	assert.Empty(t, w.Pending())
+	info, err := os.Stat(filepath.Join(dir, walFileName))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), info.Size())
+	require.NoError(t, w.Append([]byte("d")))
+	require.NoError(t, w.Close())
+
+	w, err = openWAL(dir)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("d")}, w.Pending())
+	require.NoError(t, w.Close())
+}
+
+func TestWALTornRecord(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bft-wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := openWAL(dir)
+	require.NoError(t, err)
+	require.NoError(t, w.Append([]byte("complete")))
+	require.NoError(t, w.Append([]byte("torn")))
+	require.NoError(t, w.Close())
+
+	path := filepath.Join(dir, walFileName)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-2))
+
+	w, err = openWAL(dir)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("complete")}, w.Pending())
+
+	// records are appended after the last valid one
+	require.NoError(t, w.Append([]byte("next")))
+	require.NoError(t, w.Close())
+	w, err = openWAL(dir)
+	require.NoError(t, err)
+	assert.Equal(t, [][]byte{[]byte("complete"), []byte("next")}, w.Pending())
+	require.NoError(t, w.Close())
+}
+
+func TestWALCopy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bft-wal")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	w, err := openWAL(filepath.Join(dir, "wal"))
+	require.NoError(t, err)
+	defer w.Close()
+	require.NoError(t, w.Append([]byte("a")))
+	require.NoError(t, w.Append([]byte("b")))
+
+	// the copy holds the pending requests, and is unaffected by later records
+	require.NoError(t, w.copyTo(filepath.Join(dir, "copy")))
+	require.NoError(t, w.Append([]byte("c")))
+	copied, err := openWAL(filepath.Join(dir, "copy"))
+	require.NoError(t, err)
+	defer copied.Close()
+	assert.Equal(t, [][]byte{[]byte("a"), []byte("b")}, copied.Pending())
+}
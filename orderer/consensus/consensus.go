@@ -7,11 +7,15 @@ SPDX-License-Identifier: Apache-2.0
 package consensus
 
 import (
+	"fmt"
+	"time"
+
 	"github.com/hyperledger/fabric/common/channelconfig"
 	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/orderer/common/blockcutter"
 	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
 	cb "github.com/hyperledger/fabric/protos/common"
+	"golang.org/x/net/context"
 )
 
 // Consenter defines the backing ordering mechanism.
@@ -58,6 +62,12 @@ type Chain interface {
 	//检查并阻塞等待共识组件能够接收和处理新消息
 	WaitReady() error
 
+	// WaitReadyContext is WaitReady bounded by the context.  If the context is
+	// done before the consenter is ready, it returns a *NotReadyError telling
+	// how far the consenter is from being ready.
+	//在上下文结束前阻塞等待共识组件能够接收新消息
+	WaitReadyContext(ctx context.Context) error
+
 	// Errored returns a channel which will close when an error has occurred.
 	// This is especially useful for the Deliver client, who must terminate waiting
 	// clients when the consenter is not up to date.
@@ -75,6 +85,93 @@ type Chain interface {
 	Halt()
 }
 
+// NotReadyError is returned by WaitReadyContext when the context is done
+// before the consenter is ready to accept new messages.
+type NotReadyError struct {
+	// Reason tells what the consenter is doing before it is ready
+	Reason string
+
+	// Position is the number of messages the consenter must process before it
+	// is ready, or -1 if it is unknown
+	Position int64
+
+	// Wait is the estimated time until the consenter is ready, zero if unknown
+	Wait time.Duration
+}
+
+func (e *NotReadyError) Error() string {
+	msg := "consenter is not ready: " + e.Reason
+	if e.Position >= 0 {
+		msg += fmt.Sprintf(", %d messages ahead", e.Position)
+	}
+	if e.Wait > 0 {
+		msg += fmt.Sprintf(", retry after %s", e.Wait)
+	}
+	return msg
+}
+
+// RetryAfter returns the estimated time until the consenter is ready.
+func (e *NotReadyError) RetryAfter() time.Duration { return e.Wait }
+
+// StatusReporter is optionally implemented by a Chain which reports its
+// health without blocking, so that clients can be routed away from the
+// orderers whose chain is not ready to accept new messages.
+type StatusReporter interface {
+	// Status returns the health of the chain.
+	Status() ChainStatus
+}
+
+// ChainStatus is the health of a chain.
+type ChainStatus struct {
+	// Ready tells whether the chain accepts new messages
+	Ready bool `json:"ready"`
+	// Reason tells why the chain is not ready
+	Reason string `json:"reason,omitempty"`
+	// Lag is the number of messages the chain must process before it is
+	// ready, or -1 if it is unknown
+	Lag int64 `json:"lag"`
+}
+
+// QueueReporter is optionally implemented by a Chain which reports the work
+// it accepted and has yet to write in blocks, so that the broadcast handler
+// can push back on clients before the queue of the chain exhausts the memory
+// of the orderer.
+type QueueReporter interface {
+	// PendingBytes returns the size of the messages accepted by Order and
+	// Configure which are not written in a block yet.  It must be safe to call
+	// concurrently with the other methods of the chain.
+	PendingBytes() int64
+}
+
+// WALCopier is optionally implemented by a Chain which keeps a write-ahead
+// log, so that the log can be backed up along with the ledger of the channel.
+type WALCopier interface {
+	// CopyWAL copies the write-ahead log of the chain into the directory,
+	// without tearing a record being written.
+	CopyWAL(dir string) error
+}
+
+// Terminator is optionally implemented by a Chain whose Halt returns before the
+// chain stops writing blocks, so that the ledger of the channel is removed only
+// once the chain has terminated.  The chains which do not implement it must have
+// terminated when Halt returns.
+type Terminator interface {
+	// Terminated returns a channel which closes once the chain, halted, has
+	// stopped processing messages and writing blocks.
+	Terminated() <-chan struct{}
+}
+
+// LeaderReporter is optionally implemented by a Chain whose orderers elect a
+// leader which alone accepts new messages, so that the broadcast handlers of
+// the other orderers forward the messages of their clients to the leader.
+type LeaderReporter interface {
+	// Leader returns the endpoint of the AtomicBroadcast service of the
+	// orderer leading the chain, or "" if this orderer accepts new messages
+	// itself.  It must be safe to call concurrently with the other methods
+	// of the chain.
+	Leader() string
+}
+
 // ConsenterSupport provides the resources available to a Consenter implementation.
 //共识组件支持对象
 type ConsenterSupport interface {
@@ -96,7 +193,10 @@ type ConsenterSupport interface {
 	//创建区块
 	CreateNextBlock(messages []*cb.Envelope) *cb.Block
 
-	// WriteBlock commits a block to the ledger.
+	// WriteBlock commits a block to the ledger.  The signatures the SIGNATURES
+	// metadata of the block carries, such as the signatures of the other
+	// orderers, are kept along with the signature of this orderer, unless
+	// the blocks are signed in batches.
 	//提交封装了普通交易消息的区块到账本
 	WriteBlock(block *cb.Block, encodedMetadataValue []byte)
 
@@ -0,0 +1,235 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package faulty
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/orderer/consensus"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+)
+
+type message struct {
+	configSeq uint64
+	normalMsg *cb.Envelope
+	configMsg *cb.Envelope
+	due       time.Time //消息转发给内层链的时间
+	reorder   int       //消息入队时的乱序分组大小
+}
+
+type chain struct {
+	consensus.Chain                            //被注入故障的内层链
+	consenter       *Consenter                 //提供当前注入的故障
+	support         consensus.ConsenterSupport //共识组件支持对象
+
+	mutex    sync.Mutex
+	messages uint64 //已接收的普通交易消息数
+	readies  uint64 //WaitReady/WaitReadyContext的调用次数
+
+	sendChan chan *message //延迟或乱序转发的消息
+	exitChan chan struct{}
+}
+
+func newChain(consenter *Consenter, support consensus.ConsenterSupport, inner consensus.Chain) *chain {
+	return &chain{
+		Chain:     inner,
+		consenter: consenter,
+		support:   support,
+		sendChan:  make(chan *message),
+		exitChan:  make(chan struct{}),
+	}
+}
+
+func (ch *chain) Start() {
+	ch.Chain.Start()
+	go ch.main()
+}
+
+func (ch *chain) Halt() {
+	select {
+	case <-ch.exitChan:
+		// Allow multiple halts without panic
+	default:
+		close(ch.exitChan)
+	}
+	ch.Chain.Halt()
+}
+
+// next counts a call or message and returns its position with the faults
+// injected into it
+func (ch *chain) next(counter *uint64) (Faults, uint64) {
+	ch.mutex.Lock()
+	defer ch.mutex.Unlock()
+	*counter++
+	return ch.consenter.Faults(), *counter
+}
+
+// Order drops the message if selected, and passes it to the inner chain
+// otherwise, delayed or reordered if asked to.
+func (ch *chain) Order(env *cb.Envelope, configSeq uint64) error {
+	faults, n := ch.next(&ch.messages)
+	if faults.Drop.matches(n) {
+		logger.Debugf("[channel: %s] Dropping message %d", ch.support.ChainID(), n)
+		return nil
+	}
+	return ch.forward(faults, &message{configSeq: configSeq, normalMsg: env})
+}
+
+// Configure passes the config message to the inner chain, delayed if asked to.
+func (ch *chain) Configure(config *cb.Envelope, configSeq uint64) error {
+	return ch.forward(ch.consenter.Faults(), &message{configSeq: configSeq, configMsg: config})
+}
+
+// forward passes the message to the inner chain directly unless it must be
+// delayed or reordered, in which case it is queued for main
+func (ch *chain) forward(faults Faults, msg *message) error {
+	if faults.Delay <= 0 && faults.Reorder <= 1 {
+		return ch.pass(msg)
+	}
+	msg.due = time.Now().Add(faults.Delay)
+	msg.reorder = faults.Reorder
+	select {
+	case ch.sendChan <- msg:
+		return nil
+	case <-ch.exitChan:
+		return errors.Errorf("Exiting")
+	}
+}
+
+func (ch *chain) pass(msg *message) error {
+	if msg.configMsg != nil {
+		return ch.Chain.Configure(msg.configMsg, msg.configSeq)
+	}
+	return ch.Chain.Order(msg.normalMsg, msg.configSeq)
+}
+
+// flush passes the messages to the inner chain in turn
+func (ch *chain) flush(msgs []*message) {
+	for _, msg := range msgs {
+		if err := ch.pass(msg); err != nil {
+			logger.Warningf("[channel: %s] Inner chain rejected a delayed message: %s", ch.support.ChainID(), err)
+		}
+	}
+}
+
+// main passes the queued messages to the inner chain once they are due,
+// reversing each group of messages to reorder
+func (ch *chain) main() {
+	var group []*message
+	var timer <-chan time.Time
+
+	for {
+		select {
+		case msg := <-ch.sendChan:
+			if wait := msg.due.Sub(time.Now()); wait > 0 {
+				select {
+				case <-time.After(wait):
+				case <-ch.exitChan:
+					return
+				}
+			}
+			if msg.configMsg != nil || msg.reorder <= 1 {
+				// 之前未满的分组按原顺序先于本消息转发
+				ch.flush(append(group, msg))
+				group, timer = nil, nil
+				continue
+			}
+			group = append(group, msg)
+			if len(group) == 1 {
+				timer = time.After(ch.support.SharedConfig().BatchTimeout())
+			}
+			if len(group) >= msg.reorder {
+				for i, j := 0, len(group)-1; i < j; i, j = i+1, j-1 {
+					group[i], group[j] = group[j], group[i]
+				}
+				ch.flush(group)
+				group, timer = nil, nil
+			}
+		case <-timer:
+			// 超时未满的分组按原顺序转发
+			ch.flush(group)
+			group, timer = nil, nil
+		case <-ch.exitChan:
+			logger.Debugf("[channel: %s] Exiting", ch.support.ChainID())
+			return
+		}
+	}
+}
+
+// WaitReady fails with a *consensus.NotReadyError if the call is selected,
+// and waits for the inner chain otherwise.
+func (ch *chain) WaitReady() error {
+	if err := ch.notReady(); err != nil {
+		return err
+	}
+	return ch.Chain.WaitReady()
+}
+
+// WaitReadyContext fails with a *consensus.NotReadyError if the call is
+// selected, and waits for the inner chain otherwise.
+func (ch *chain) WaitReadyContext(ctx context.Context) error {
+	if err := ch.notReady(); err != nil {
+		return err
+	}
+	return ch.Chain.WaitReadyContext(ctx)
+}
+
+func (ch *chain) notReady() error {
+	faults, n := ch.next(&ch.readies)
+	if !faults.NotReady.matches(n) {
+		return nil
+	}
+	logger.Debugf("[channel: %s] Failing wait for readiness %d", ch.support.ChainID(), n)
+	return &consensus.NotReadyError{Reason: "fault injected", Position: -1}
+}
+
+// Status reports the status of the inner chain, without counting as a wait
+// for readiness.
+func (ch *chain) Status() consensus.ChainStatus {
+	if reporter, ok := ch.Chain.(consensus.StatusReporter); ok {
+		return reporter.Status()
+	}
+	return consensus.ChainStatus{Ready: true, Lag: -1}
+}
+
+// PendingBytes reports the pending bytes of the inner chain, without the
+// messages being delayed or reordered, and 0 if it does not report them.
+func (ch *chain) PendingBytes() int64 {
+	if reporter, ok := ch.Chain.(consensus.QueueReporter); ok {
+		return reporter.PendingBytes()
+	}
+	return 0
+}
+
+// Leader reports the leader of the inner chain, and "" if it has none.
+func (ch *chain) Leader() string {
+	if reporter, ok := ch.Chain.(consensus.LeaderReporter); ok {
+		return reporter.Leader()
+	}
+	return ""
+}
+
+// Terminated reports the termination of the inner chain, which has terminated
+// once halted if it does not report it.
+func (ch *chain) Terminated() <-chan struct{} {
+	if terminator, ok := ch.Chain.(consensus.Terminator); ok {
+		return terminator.Terminated()
+	}
+	return ch.exitChan
+}
+
+// CopyWAL copies the write-ahead log of the inner chain, and nothing if it
+// keeps none.
+func (ch *chain) CopyWAL(dir string) error {
+	if copier, ok := ch.Chain.(consensus.WALCopier); ok {
+		return copier.CopyWAL(dir)
+	}
+	return nil
+}
@@ -0,0 +1,109 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package faulty wraps a consenter to inject faults into its chains: it
+// delays, drops and reorders the messages ordered and fails the calls waiting
+// for the chains to be ready, deterministically, so that the retry logic of
+// the broadcast handler and of the clients can be exercised in tests.  It must
+// not be used in production.
+package faulty
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/op/go-logging"
+)
+
+const pkgLogID = "orderer/consensus/faulty"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// Pattern selects calls or messages by their position, counted from 1 on
+// each chain.
+type Pattern struct {
+	// Positions are the positions selected
+	Positions []uint64
+
+	// Every, when not zero, also selects every Every-th position
+	Every uint64
+}
+
+func (p Pattern) matches(n uint64) bool {
+	if p.Every > 0 && n%p.Every == 0 {
+		return true
+	}
+	for _, position := range p.Positions {
+		if position == n {
+			return true
+		}
+	}
+	return false
+}
+
+// Faults are the faults injected into each chain.  Drop and Reorder apply to
+// the normal messages only, so that the config of the channel stays intact.
+type Faults struct {
+	// Delay holds each message for that long before passing it to the chain
+	Delay time.Duration
+
+	// NotReady selects the calls to WaitReady and WaitReadyContext failed
+	// with a *consensus.NotReadyError
+	NotReady Pattern
+
+	// Drop selects the messages accepted but never passed to the chain
+	Drop Pattern
+
+	// Reorder, when greater than 1, passes the messages to the chain in groups
+	// of that many, last first.  A group not full within the batch timeout of
+	// the channel, or followed by a config message, is passed as it is.
+	Reorder int
+}
+
+// Consenter injects faults into the chains of the consenter it wraps.
+type Consenter struct {
+	inner consensus.Consenter
+
+	mutex  sync.Mutex
+	faults Faults
+}
+
+// New creates a consenter injecting the faults into the chains of inner.
+func New(inner consensus.Consenter, faults Faults) *Consenter {
+	return &Consenter{inner: inner, faults: faults}
+}
+
+// SetFaults changes the faults injected, from the next call or message on
+// each chain.  The positions of the patterns keep being counted from the
+// start of each chain.
+func (c *Consenter) SetFaults(faults Faults) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.faults = faults
+}
+
+// Faults returns the faults injected.
+func (c *Consenter) Faults() Faults {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.faults
+}
+
+// HandleChain creates the chain of the inner consenter and wraps it.
+func (c *Consenter) HandleChain(support consensus.ConsenterSupport, metadata *cb.Metadata) (consensus.Chain, error) {
+	inner, err := c.inner.HandleChain(support, metadata)
+	if err != nil {
+		return nil, err
+	}
+	return newChain(c, support, inner), nil
+}
@@ -0,0 +1,172 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package faulty
+
+import (
+	"testing"
+	"time"
+
+	mockconfig "github.com/hyperledger/fabric/common/mocks/config"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	mockmultichannel "github.com/hyperledger/fabric/orderer/mocks/common/multichannel"
+	cb "github.com/hyperledger/fabric/protos/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+)
+
+type mockChain struct {
+	ordered chan *cb.Envelope
+	halted  bool
+}
+
+func (mc *mockChain) Order(env *cb.Envelope, configSeq uint64) error {
+	mc.ordered <- env
+	return nil
+}
+
+func (mc *mockChain) Configure(config *cb.Envelope, configSeq uint64) error {
+	mc.ordered <- config
+	return nil
+}
+
+func (mc *mockChain) WaitReady() error                           { return nil }
+func (mc *mockChain) WaitReadyContext(ctx context.Context) error { return nil }
+func (mc *mockChain) Errored() <-chan struct{}                   { return nil }
+func (mc *mockChain) Start()                                     {}
+func (mc *mockChain) Halt()                                      { mc.halted = true }
+func (mc *mockChain) HandleChain(consensus.ConsenterSupport, *cb.Metadata) (consensus.Chain, error) {
+	return mc, nil
+}
+
+func newTestChain(t *testing.T, faults Faults, batchTimeout time.Duration) (*Consenter, consensus.Chain, *mockChain) {
+	inner := &mockChain{ordered: make(chan *cb.Envelope, 10)}
+	consenter := New(inner, faults)
+	support := &mockmultichannel.ConsenterSupport{
+		SharedConfigVal: &mockconfig.Orderer{BatchTimeoutVal: batchTimeout},
+		ChainIDVal:      "foo",
+	}
+	ch, err := consenter.HandleChain(support, nil)
+	require.NoError(t, err)
+	ch.Start()
+	return consenter, ch, inner
+}
+
+func envelope(i byte) *cb.Envelope {
+	return &cb.Envelope{Payload: []byte{i}}
+}
+
+func received(t *testing.T, inner *mockChain, n int) []byte {
+	var payloads []byte
+	for i := 0; i < n; i++ {
+		select {
+		case env := <-inner.ordered:
+			payloads = append(payloads, env.Payload...)
+		case <-time.After(time.Second):
+			t.Fatalf("Expected %d messages, got %d", n, i)
+		}
+	}
+	select {
+	case env := <-inner.ordered:
+		t.Fatalf("Unexpected message %v", env.Payload)
+	case <-time.After(10 * time.Millisecond):
+	}
+	return payloads
+}
+
+func TestPattern(t *testing.T) {
+	p := Pattern{Positions: []uint64{1, 5}, Every: 3}
+	var selected []uint64
+	for n := uint64(1); n <= 7; n++ {
+		if p.matches(n) {
+			selected = append(selected, n)
+		}
+	}
+	assert.Equal(t, []uint64{1, 3, 5, 6}, selected)
+	assert.False(t, Pattern{}.matches(1))
+}
+
+func TestNoFaults(t *testing.T) {
+	_, ch, inner := newTestChain(t, Faults{}, time.Second)
+	defer ch.Halt()
+
+	for i := byte(1); i <= 3; i++ {
+		require.NoError(t, ch.Order(envelope(i), 0))
+	}
+	assert.Equal(t, []byte{1, 2, 3}, received(t, inner, 3))
+	assert.NoError(t, ch.WaitReady())
+	assert.Equal(t, consensus.ChainStatus{Ready: true, Lag: -1}, ch.(consensus.StatusReporter).Status())
+}
+
+func TestNotReady(t *testing.T) {
+	consenter, ch, _ := newTestChain(t, Faults{NotReady: Pattern{Positions: []uint64{1, 2}}}, time.Second)
+	defer ch.Halt()
+
+	err := ch.WaitReadyContext(context.Background())
+	require.IsType(t, &consensus.NotReadyError{}, err)
+	assert.Equal(t, int64(-1), err.(*consensus.NotReadyError).Position)
+	assert.Error(t, ch.WaitReady())
+	assert.NoError(t, ch.WaitReadyContext(context.Background()))
+
+	// the status does not count as a wait for readiness
+	consenter.SetFaults(Faults{NotReady: Pattern{Positions: []uint64{4}}})
+	assert.True(t, ch.(consensus.StatusReporter).Status().Ready)
+	assert.Error(t, ch.WaitReady())
+}
+
+func TestDrop(t *testing.T) {
+	_, ch, inner := newTestChain(t, Faults{Drop: Pattern{Every: 2}}, time.Second)
+	defer ch.Halt()
+
+	for i := byte(1); i <= 4; i++ {
+		require.NoError(t, ch.Order(envelope(i), 0))
+	}
+	// config messages are never dropped
+	require.NoError(t, ch.Configure(envelope(5), 0))
+	assert.Equal(t, []byte{1, 3, 5}, received(t, inner, 3))
+}
+
+func TestDelay(t *testing.T) {
+	_, ch, inner := newTestChain(t, Faults{Delay: 50 * time.Millisecond}, time.Second)
+	defer ch.Halt()
+
+	start := time.Now()
+	require.NoError(t, ch.Order(envelope(1), 0))
+	require.NoError(t, ch.Configure(envelope(2), 0))
+	assert.Equal(t, []byte{1, 2}, received(t, inner, 2))
+	assert.True(t, time.Since(start) >= 50*time.Millisecond)
+}
+
+func TestReorder(t *testing.T) {
+	_, ch, inner := newTestChain(t, Faults{Reorder: 3}, 50*time.Millisecond)
+	defer ch.Halt()
+
+	for i := byte(1); i <= 3; i++ {
+		require.NoError(t, ch.Order(envelope(i), 0))
+	}
+	assert.Equal(t, []byte{3, 2, 1}, received(t, inner, 3))
+
+	// a group not full within the batch timeout is passed as it is
+	require.NoError(t, ch.Order(envelope(4), 0))
+	require.NoError(t, ch.Order(envelope(5), 0))
+	assert.Equal(t, []byte{4, 5}, received(t, inner, 2))
+
+	// so is a group followed by a config message
+	require.NoError(t, ch.Order(envelope(6), 0))
+	require.NoError(t, ch.Order(envelope(7), 0))
+	require.NoError(t, ch.Configure(envelope(8), 0))
+	assert.Equal(t, []byte{6, 7, 8}, received(t, inner, 3))
+}
+
+func TestHalt(t *testing.T) {
+	_, ch, inner := newTestChain(t, Faults{Delay: time.Hour}, time.Second)
+	require.NoError(t, ch.Order(envelope(1), 0))
+	ch.Halt()
+	ch.Halt()
+	assert.True(t, inner.halted)
+	assert.Error(t, ch.Order(envelope(2), 0))
+}
@@ -0,0 +1,116 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package forward implements the client by which the orderers of a cluster
+// forward the broadcasts of their clients to the orderer leading a channel,
+// for the consenters whose leader alone accepts new messages.
+package forward
+
+import (
+	"sync"
+
+	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/core/comm"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+)
+
+const pkgLogID = "orderer/consensus/forward"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+// Client forwards messages to the AtomicBroadcast service of the other
+// orderers, keeping a connection open to each orderer it forwarded to.  The
+// orderers authenticate the client with the TLS certificate of the gRPC
+// client, while the messages keep the signatures of their creators and are
+// validated again by the orderer they are forwarded to.
+type Client struct {
+	client *comm.GRPCClient
+
+	mutex sync.Mutex
+	conns map[string]*grpc.ClientConn
+}
+
+// NewClient creates a Client connecting to the orderers with the gRPC client.
+func NewClient(client *comm.GRPCClient) *Client {
+	return &Client{client: client, conns: map[string]*grpc.ClientConn{}}
+}
+
+// Forward broadcasts the message to the orderer at the endpoint on a stream
+// of the context, and returns the response of the orderer.  The gRPC
+// metadata of the stream is the outgoing metadata of the context.  An error
+// is returned if the orderer could not be reached or did not respond, in
+// which case the message may or may not have been enqueued.
+func (c *Client) Forward(ctx context.Context, endpoint string, msg *cb.Envelope) (*ab.BroadcastResponse, error) {
+	conn, err := c.connection(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := ab.NewAtomicBroadcastClient(conn).Broadcast(ctx)
+	if err != nil {
+		c.reset(endpoint, conn)
+		return nil, errors.Wrapf(err, "error opening broadcast stream to %s", endpoint)
+	}
+	if err := stream.Send(msg); err != nil {
+		c.reset(endpoint, conn)
+		return nil, errors.Wrapf(err, "error forwarding message to %s", endpoint)
+	}
+	resp, err := stream.Recv()
+	if err != nil {
+		c.reset(endpoint, conn)
+		return nil, errors.Wrapf(err, "error receiving response from %s", endpoint)
+	}
+	if err := stream.CloseSend(); err != nil {
+		logger.Debugf("Error closing broadcast stream to %s: %s", endpoint, err)
+	}
+	return resp, nil
+}
+
+// Close closes the connections to the orderers.
+func (c *Client) Close() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for endpoint, conn := range c.conns {
+		conn.Close()
+		delete(c.conns, endpoint)
+	}
+}
+
+func (c *Client) connection(endpoint string) (*grpc.ClientConn, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if conn, ok := c.conns[endpoint]; ok {
+		return conn, nil
+	}
+	conn, err := c.client.NewConnection(endpoint, "")
+	if err != nil {
+		return nil, errors.Wrapf(err, "error connecting to %s", endpoint)
+	}
+	c.conns[endpoint] = conn
+	return conn, nil
+}
+
+// reset closes the connection to the orderer after a failure, so that the
+// next message forwarded to it reconnects
+func (c *Client) reset(endpoint string, conn *grpc.ClientConn) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.conns[endpoint] == conn {
+		conn.Close()
+		delete(c.conns, endpoint)
+	}
+}
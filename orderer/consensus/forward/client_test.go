@@ -0,0 +1,99 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package forward
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hyperledger/fabric/core/comm"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// leader answers each broadcast envelope with its payload and the metadata
+// of the stream
+type leader struct {
+	ab.AtomicBroadcastServer
+}
+
+func (leader) Broadcast(srv ab.AtomicBroadcast_BroadcastServer) error {
+	md, _ := metadata.FromIncomingContext(srv.Context())
+	for {
+		env, err := srv.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		info := string(env.Payload)
+		if len(md["forwarded"]) > 0 {
+			info += " forwarded"
+		}
+		if err := srv.Send(&ab.BroadcastResponse{Status: cb.Status_SUCCESS, Info: info}); err != nil {
+			return err
+		}
+	}
+}
+
+func newLeader(t *testing.T) (string, func()) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	ab.RegisterAtomicBroadcastServer(server, leader{})
+	go server.Serve(lis)
+	return lis.Addr().String(), server.Stop
+}
+
+func newTestClient(t *testing.T) *Client {
+	client, err := comm.NewGRPCClient(comm.ClientConfig{Timeout: time.Second})
+	require.NoError(t, err)
+	return NewClient(client)
+}
+
+func TestForward(t *testing.T) {
+	endpoint, stop := newLeader(t)
+	defer stop()
+	c := newTestClient(t)
+	defer c.Close()
+
+	resp, err := c.Forward(context.Background(), endpoint, &cb.Envelope{Payload: []byte("first")})
+	require.NoError(t, err)
+	assert.Equal(t, cb.Status_SUCCESS, resp.Status)
+	assert.Equal(t, "first", resp.Info)
+
+	// the connection is kept and the metadata of the context sent
+	ctx := metadata.NewOutgoingContext(context.Background(), metadata.Pairs("forwarded", "true"))
+	resp, err = c.Forward(ctx, endpoint, &cb.Envelope{Payload: []byte("second")})
+	require.NoError(t, err)
+	assert.Equal(t, "second forwarded", resp.Info)
+	assert.Len(t, c.conns, 1)
+}
+
+func TestForwardFailure(t *testing.T) {
+	endpoint, stop := newLeader(t)
+	c := newTestClient(t)
+	defer c.Close()
+
+	_, err := c.Forward(context.Background(), endpoint, &cb.Envelope{Payload: []byte("first")})
+	require.NoError(t, err)
+
+	// the connection to an orderer which stopped is dropped
+	stop()
+	_, err = c.Forward(context.Background(), endpoint, &cb.Envelope{Payload: []byte("second")})
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), endpoint)
+	assert.Empty(t, c.conns)
+}
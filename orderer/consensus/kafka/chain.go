@@ -9,6 +9,7 @@ package kafka
 import (
 	"fmt"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/Shopify/sarama"
@@ -19,6 +20,7 @@ import (
 	cb "github.com/hyperledger/fabric/protos/common"
 	ab "github.com/hyperledger/fabric/protos/orderer"
 	"github.com/hyperledger/fabric/protos/utils"
+	"golang.org/x/net/context"
 )
 
 // Used for capturing metrics -- see processMessagesToBlocks
@@ -156,20 +158,84 @@ func (chain *chainImpl) Halt() {
 }
 
 func (chain *chainImpl) WaitReady() error {
+	return chain.WaitReadyContext(context.Background())
+}
+
+// WaitReadyContext blocks while the messages resubmitted after a
+// reconfiguration are reprocessed, until the context is done.  The
+// NotReadyError returned then estimates how long reprocessing will take from
+// the progress made while waiting.
+func (chain *chainImpl) WaitReadyContext(ctx context.Context) error {
 	select {
 	case <-chain.startChan: // The Start phase has completed
+		waitStart, startPosition := time.Now(), chain.reprocessingPosition()
 		select {
 		case <-chain.haltChan: // The chain has been halted, stop here
 			return fmt.Errorf("consenter for this channel has been halted")
 			// Block waiting for all re-submitted messages to be reprocessed
 		case <-chain.doneReprocessingMsgInFlight:
 			return nil
+		case <-ctx.Done():
+			position := chain.reprocessingPosition()
+			return &consensus.NotReadyError{
+				Reason:   "reprocessing the messages resubmitted after a reconfiguration",
+				Position: position,
+				Wait:     estimateWait(startPosition, position, time.Since(waitStart)),
+			}
 		}
 	default: // Not ready yet
 		return fmt.Errorf("will not enqueue, consenter for this channel hasn't started yet")
 	}
 }
 
+// Status reports the health of the chain without blocking. Implements the
+// consensus.StatusReporter interface.
+func (chain *chainImpl) Status() consensus.ChainStatus {
+	select {
+	case <-chain.startChan:
+	default:
+		return consensus.ChainStatus{Reason: "consenter for this channel hasn't started yet", Lag: -1}
+	}
+	select {
+	case <-chain.haltChan:
+		return consensus.ChainStatus{Reason: "consenter for this channel has been halted", Lag: -1}
+	default:
+	}
+	select {
+	case <-chain.errorChan:
+		return consensus.ChainStatus{Reason: "consenter for this channel is not connected to the Kafka cluster", Lag: -1}
+	default:
+	}
+	select {
+	case <-chain.doneReprocessingMsgInFlight:
+		return consensus.ChainStatus{Ready: true}
+	default:
+		return consensus.ChainStatus{
+			Reason: "reprocessing the messages resubmitted after a reconfiguration",
+			Lag:    chain.reprocessingPosition(),
+		}
+	}
+}
+
+// reprocessingPosition returns the number of messages of the partition up to
+// the last resubmitted config message which are not processed yet
+func (chain *chainImpl) reprocessingPosition() int64 {
+	position := atomic.LoadInt64(&chain.lastResubmittedConfigOffset) - atomic.LoadInt64(&chain.lastOriginalOffsetProcessed)
+	if position < 0 {
+		return 0
+	}
+	return position
+}
+
+// estimateWait returns how long processing the messages left will take at
+// the rate they were processed while waiting, or the time waited if none was
+func estimateWait(before, after int64, waited time.Duration) time.Duration {
+	if processed := before - after; processed > 0 {
+		return time.Duration(float64(waited) * float64(after) / float64(processed))
+	}
+	return waited
+}
+
 // Implements the consensus.Chain interface. Called by Broadcast().
 func (chain *chainImpl) Order(env *cb.Envelope, configSeq uint64) error {
 	return chain.order(env, configSeq, int64(0))
@@ -554,7 +620,7 @@ func (chain *chainImpl) processRegular(regularMessage *ab.KafkaMessageRegular, r
 		logger.Debugf("[channel: %s] Ordering results: items in batch = %d, pending = %v", chain.ChainID(), len(batches), pending)
 		if len(batches) == 0 {
 			// If no block is cut, we update the `lastOriginalOffsetProcessed`, start the timer if necessary and return
-			chain.lastOriginalOffsetProcessed = newOffset
+			atomic.StoreInt64(&chain.lastOriginalOffsetProcessed, newOffset)
 			if chain.timer == nil {
 				chain.timer = time.After(chain.SharedConfig().BatchTimeout())
 				logger.Debugf("[channel: %s] Just began %s batch timer", chain.ChainID(), chain.SharedConfig().BatchTimeout().String())
@@ -576,7 +642,7 @@ func (chain *chainImpl) processRegular(regularMessage *ab.KafkaMessageRegular, r
 			// blocks, the first one should use current `lastOriginalOffsetProcessed`
 			// and the second one should use `newOffset`, which is also used to
 			// update `lastOriginalOffsetProcessed`
-			chain.lastOriginalOffsetProcessed = newOffset
+			atomic.StoreInt64(&chain.lastOriginalOffsetProcessed, newOffset)
 		}
 
 		// Commit the first block
@@ -592,7 +658,7 @@ func (chain *chainImpl) processRegular(regularMessage *ab.KafkaMessageRegular, r
 
 		// Commit the second block if exists
 		if len(batches) == 2 {
-			chain.lastOriginalOffsetProcessed = newOffset
+			atomic.StoreInt64(&chain.lastOriginalOffsetProcessed, newOffset)
 			offset++
 
 			block := chain.CreateNextBlock(batches[1])
@@ -631,7 +697,7 @@ func (chain *chainImpl) processRegular(regularMessage *ab.KafkaMessageRegular, r
 		}
 
 		logger.Debugf("[channel: %s] Creating isolated block for config message", chain.ChainID())
-		chain.lastOriginalOffsetProcessed = newOffset
+		atomic.StoreInt64(&chain.lastOriginalOffsetProcessed, newOffset)
 		block := chain.CreateNextBlock([]*cb.Envelope{message})
 		metadata := utils.MarshalOrPanic(&ab.KafkaMetadata{
 			LastOffsetPersisted:         receivedOffset,
@@ -781,7 +847,7 @@ func (chain *chainImpl) processRegular(regularMessage *ab.KafkaMessageRegular, r
 			// be valid, and resubmitted it. We need to advance lastResubmittedConfigOffset in this case in order
 			// to enforce consistency across the network.
 			if chain.lastResubmittedConfigOffset < regularMessage.OriginalOffset {
-				chain.lastResubmittedConfigOffset = regularMessage.OriginalOffset
+				atomic.StoreInt64(&chain.lastResubmittedConfigOffset, regularMessage.OriginalOffset)
 			}
 		}
 
@@ -800,8 +866,8 @@ func (chain *chainImpl) processRegular(regularMessage *ab.KafkaMessageRegular, r
 			}
 
 			logger.Debugf("[channel: %s] Resubmitted config message with offset %d, block ingress messages", chain.ChainID(), receivedOffset)
-			chain.lastResubmittedConfigOffset = receivedOffset      // Keep track of last resubmitted message offset
-			chain.doneReprocessingMsgInFlight = make(chan struct{}) // Create the channel to block ingress messages
+			atomic.StoreInt64(&chain.lastResubmittedConfigOffset, receivedOffset) // Keep track of last resubmitted message offset
+			chain.doneReprocessingMsgInFlight = make(chan struct{})               // Create the channel to block ingress messages
 
 			return nil
 		}
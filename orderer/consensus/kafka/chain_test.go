@@ -18,6 +18,7 @@ import (
 	mockconfig "github.com/hyperledger/fabric/common/mocks/config"
 	"github.com/hyperledger/fabric/orderer/common/blockcutter"
 	"github.com/hyperledger/fabric/orderer/common/msgprocessor"
+	"github.com/hyperledger/fabric/orderer/consensus"
 	mockblockcutter "github.com/hyperledger/fabric/orderer/mocks/common/blockcutter"
 	mockmultichannel "github.com/hyperledger/fabric/orderer/mocks/common/multichannel"
 	cb "github.com/hyperledger/fabric/protos/common"
@@ -25,6 +26,7 @@ import (
 	"github.com/hyperledger/fabric/protos/utils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"golang.org/x/net/context"
 )
 
 var (
@@ -641,6 +643,37 @@ func TestGetLastCutBlockNumber(t *testing.T) {
 	}
 }
 
+func TestWaitReadyContext(t *testing.T) {
+	chain := &chainImpl{
+		startChan:                   make(chan struct{}),
+		haltChan:                    make(chan struct{}),
+		doneReprocessingMsgInFlight: make(chan struct{}),
+		lastResubmittedConfigOffset: 10,
+		lastOriginalOffsetProcessed: 4,
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.Error(t, chain.WaitReadyContext(ctx), "Chain has not started yet")
+
+	// 重新处理未完成时在上下文结束后返回排队位置
+	close(chain.startChan)
+	err := chain.WaitReadyContext(ctx)
+	notReady, ok := err.(*consensus.NotReadyError)
+	assert.True(t, ok, "Expected a NotReadyError, got %v", err)
+	assert.Equal(t, int64(6), notReady.Position)
+	assert.True(t, notReady.Wait > 0)
+
+	close(chain.doneReprocessingMsgInFlight)
+	assert.NoError(t, chain.WaitReadyContext(context.Background()))
+}
+
+func TestEstimateWait(t *testing.T) {
+	// 按等待期间的处理速度估计剩余时间
+	assert.Equal(t, 2*time.Second, estimateWait(12, 8, time.Second))
+	// 等待期间没有进展时以等待时间作为估计
+	assert.Equal(t, time.Second, estimateWait(8, 8, time.Second))
+}
+
 func TestGetLastOffsetPersisted(t *testing.T) {
 	mockChannel := newChannel(channelNameForTest(t), defaultPartition)
 	mockMetadata := &cb.Metadata{Value: utils.MarshalOrPanic(&ab.KafkaMetadata{
@@ -3380,6 +3413,11 @@ func (r *mockReceiver) Cut() []*cb.Envelope {
 	return args.Get(0).([]*cb.Envelope)
 }
 
+func (r *mockReceiver) PendingBytes() uint32 {
+	args := r.Called()
+	return args.Get(0).(uint32)
+}
+
 type mockConsenterSupport struct {
 	mock.Mock
 }
@@ -17,13 +17,19 @@ limitations under the License.
 package solo
 
 import (
+	"encoding/binary"
 	"fmt"
+	"sync/atomic"
 	"time"
 
+	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/flogging"
+	"github.com/hyperledger/fabric/orderer/common/backlog"
 	"github.com/hyperledger/fabric/orderer/consensus"
 	cb "github.com/hyperledger/fabric/protos/common"
 	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"golang.org/x/net/context"
 )
 
 const pkgLogID = "orderer/consensus/solo"
@@ -34,12 +40,19 @@ func init() {
 	logger = flogging.MustGetLogger(pkgLogID)
 }
 
-type consenter struct{}
+type consenter struct {
+	backlogs *backlog.Pool
+}
 
 type chain struct {
-	support  consensus.ConsenterSupport //共识组件支持对象（链支持对象cs）
-	sendChan chan *message  //用于传递和排序交易，只存在一个单独的交易消息通道（chan*message类型，阻塞接受一个消息），并按照FIFO原则接收和排序
-	exitChan chan struct{} //用于接受退出消息，结束循环退出消息处理循环
+	support    consensus.ConsenterSupport //共识组件支持对象（链支持对象cs）
+	sendChan   chan *message  //用于传递和排序交易，只存在一个单独的交易消息通道（chan*message类型，阻塞接受一个消息），并按照FIFO原则接收和排序
+	configChan chan *message //用于传递配置交易消息，优先于sendChan中等待的普通交易消息处理
+	exitChan   chan struct{} //用于接受退出消息，结束循环退出消息处理循环
+	doneChan   chan struct{} //消息处理循环退出后关闭
+	backlog    *backlog.Queue //非nil时，Order/Configure不再阻塞，消息先缓存在backlog中，超出内存预算的部分写入磁盘
+	queued     int64          //已接受但未被消息处理循环取出的消息字节数，原子访问
+	batched    int64          //块分割工具中缓存的消息字节数，由消息处理循环原子更新
 }
 
 type message struct {
@@ -51,21 +64,36 @@ type message struct {
 // New creates a new consenter for the solo consensus scheme.
 // The solo consensus scheme is very simple, and allows only one consenter for a given chain (this process).
 // It accepts messages being delivered via Order/Configure, orders them, and then uses the blockcutter to form the messages
-// into blocks before writing to the given ledger
-func New() consensus.Consenter {
-	return &consenter{}
+// into blocks before writing to the given ledger.
+// If backlogs is not nil, Order/Configure queue the messages in the backlog of the chain rather than blocking
+// until the chain receives them, so that clients are told once the backlog is full.
+// Config messages are ordered ahead of the normal messages waiting to be ordered, in the priority lane of
+// the backlog if there is one, so that an urgent config update does not wait behind a deep backlog; the
+// normal messages ordered after it are revalidated against the new config.
+func New(backlogs *backlog.Pool) consensus.Consenter {
+	return &consenter{backlogs: backlogs}
 }
 
 func (solo *consenter) HandleChain(support consensus.ConsenterSupport, metadata *cb.Metadata) (consensus.Chain, error) {
-	return newChain(support), nil
+	ch := newChain(support)
+	if solo.backlogs != nil {
+		queue, err := solo.backlogs.NewQueue(support.ChainID())
+		if err != nil {
+			return nil, err
+		}
+		ch.backlog = queue
+	}
+	return ch, nil
 }
 
 //创建solo共识组件链对象（chain类型）
 func newChain(support consensus.ConsenterSupport) *chain {
 	return &chain{
-		support:  support, //共识组件支持对象（链支持对象cs）
-		sendChan: make(chan *message), //用于传递和排序交易
-		exitChan: make(chan struct{}), //用于接受退出消息
+		support:    support, //共识组件支持对象（链支持对象cs）
+		sendChan:   make(chan *message), //用于传递和排序交易
+		configChan: make(chan *message), //用于传递配置交易消息
+		exitChan:   make(chan struct{}), //用于接受退出消息
+		doneChan:   make(chan struct{}), //消息处理循环退出后关闭
 	}
 }
 
@@ -73,6 +101,9 @@ func newChain(support consensus.ConsenterSupport) *chain {
 func (ch *chain) Start() {
 	//利用goroutine启动指定通道上的共识组件链对象，建立消息处理循环，等待接受排序后的交易消息并处理
 	go ch.main()
+	if ch.backlog != nil {
+		go ch.drainBacklog()
+	}
 }
 
 func (ch *chain) Halt() {
@@ -81,6 +112,9 @@ func (ch *chain) Halt() {
 		// Allow multiple halts without panic
 	default:
 		close(ch.exitChan)
+		if ch.backlog != nil {
+			ch.backlog.Close()
+		}
 	}
 }
 
@@ -88,33 +122,150 @@ func (ch *chain) WaitReady() error {
 	return nil
 }
 
+func (ch *chain) WaitReadyContext(ctx context.Context) error {
+	return nil
+}
+
+// Status reports the health of the chain, which is ready until halted
+func (ch *chain) Status() consensus.ChainStatus {
+	select {
+	case <-ch.exitChan:
+		return consensus.ChainStatus{Reason: "consenter for this channel has been halted", Lag: -1}
+	default:
+		return consensus.ChainStatus{Ready: true}
+	}
+}
+
+// PendingBytes reports the size of the messages accepted and not written in
+// a block yet, whether they wait for the chain to receive them or in the
+// batch being cut
+func (ch *chain) PendingBytes() int64 {
+	return atomic.LoadInt64(&ch.queued) + atomic.LoadInt64(&ch.batched)
+}
+
+// accept counts the message as queued if submit accepts it
+func (ch *chain) accept(msg *message, submit func() error) error {
+	size := msg.size()
+	atomic.AddInt64(&ch.queued, size)
+	if err := submit(); err != nil {
+		atomic.AddInt64(&ch.queued, -size)
+		return err
+	}
+	return nil
+}
+
+// size is the size of the message as the block cutter counts it
+func (msg *message) size() int64 {
+	env := msg.normalMsg
+	if msg.configMsg != nil {
+		env = msg.configMsg
+	}
+	return int64(len(env.Payload) + len(env.Signature))
+}
+
 // Order accepts normal messages for ordering
 //构造新的普通交易消息与，封装了当前的通道配置序号与过滤后的合法原始消息，并提交给共识排序后端请求排序
 func (ch *chain) Order(env *cb.Envelope, configSeq uint64) error {
-	select {
-	//重新构造新的普通交易消息，并发送到sendChain通道
-	case ch.sendChan <- &message{
-		configSeq: configSeq, //通道的最新配置序号
-		normalMsg: env, //普通交易消息
-	}:
-		return nil
-	case <-ch.exitChan: //检查通道，退出消息
-		return fmt.Errorf("Exiting")
-	}
+	//重新构造新的普通交易消息，封装通道的最新配置序号与普通交易消息
+	msg := &message{configSeq: configSeq, normalMsg: env}
+	return ch.accept(msg, func() error {
+		if ch.backlog != nil {
+			return ch.enqueue(msg)
+		}
+		select {
+		//发送到sendChain通道
+		case ch.sendChan <- msg:
+			return nil
+		case <-ch.exitChan: //检查通道，退出消息
+			return fmt.Errorf("Exiting")
+		}
+	})
 }
 
 // Configure accepts configuration update messages for ordering
 //配置交易消息
 func (ch *chain) Configure(config *cb.Envelope, configSeq uint64) error {
+	//重新构造消息，封装通道的最新配置序号与通道配置交易消息
+	msg := &message{configSeq: configSeq, configMsg: config}
+	return ch.accept(msg, func() error {
+		if ch.backlog != nil {
+			return ch.enqueue(msg)
+		}
+		select {
+		//发送到configChan通道上
+		case ch.configChan <- msg:
+			return nil
+		case <-ch.exitChan: //检查退出消息
+			return fmt.Errorf("Exiting")
+		}
+	})
+}
+
+// backlogged messages are encoded as their kind, their config sequence and
+// the envelope
+const (
+	normalKind byte = iota
+	configKind
+	encodedHeaderSize = 9
+)
+
+//将消息编码后放入backlog，backlog已满时返回错误
+func (ch *chain) enqueue(msg *message) error {
 	select {
-	//重新构造消息，并发送到sendChain通道上
-	case ch.sendChan <- &message{
-		configSeq: configSeq, //通道的最新配置序号
-		configMsg: config, //通道配置交易消息
-	}:
-		return nil
-	case <-ch.exitChan:    //检查退出消息
+	case <-ch.exitChan:
 		return fmt.Errorf("Exiting")
+	default:
+	}
+
+	kind, env := normalKind, msg.normalMsg
+	if msg.configMsg != nil {
+		kind, env = configKind, msg.configMsg
+	}
+	data, err := proto.Marshal(env)
+	if err != nil {
+		return errors.Wrap(err, "could not marshal message")
+	}
+	encoded := make([]byte, encodedHeaderSize+len(data))
+	encoded[0] = kind
+	binary.BigEndian.PutUint64(encoded[1:], msg.configSeq)
+	copy(encoded[encodedHeaderSize:], data)
+	//配置交易消息进入优先通道，排在backlog中的普通交易消息之前
+	if kind == configKind {
+		return ch.backlog.PushPriority(encoded)
+	}
+	return ch.backlog.Push(encoded)
+}
+
+func decodeMessage(encoded []byte) (*message, error) {
+	if len(encoded) < encodedHeaderSize {
+		return nil, errors.Errorf("backlogged message of %d bytes is truncated", len(encoded))
+	}
+	env := &cb.Envelope{}
+	if err := proto.Unmarshal(encoded[encodedHeaderSize:], env); err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal backlogged message")
+	}
+	msg := &message{configSeq: binary.BigEndian.Uint64(encoded[1:])}
+	if encoded[0] == configKind {
+		msg.configMsg = env
+	} else {
+		msg.normalMsg = env
+	}
+	return msg, nil
+}
+
+//按顺序将backlog中的消息转发到sendChan通道，由消息处理循环处理
+func (ch *chain) drainBacklog() {
+	for encoded := range ch.backlog.Out() {
+		msg, err := decodeMessage(encoded)
+		if err != nil {
+			logger.Warningf("Discarding bad backlogged message: %s", err)
+			continue
+		}
+		select {
+		case ch.sendChan <- msg:
+		case <-ch.exitChan:
+			return
+		}
 	}
 }
 
@@ -123,6 +274,12 @@ func (ch *chain) Errored() <-chan struct{} {
 	return ch.exitChan
 }
 
+// Terminated returns a channel which closes once the message processing
+// loop, halted, has exited, as Halt returns while a block may be written.
+func (ch *chain) Terminated() <-chan struct{} {
+	return ch.doneChan
+}
+
 //可创建消息处理循环，阻塞并等待sendChan通道中的消息，检查是否存在Broadcast服务处理句柄过滤转发的消息
 //所以Solo共识组件链对象只存在一个单独的交易消息通道按照FIFO原则接受和处理次奥西
 //sendChan通道负责对交易进行排序，实际上只能处理一个消息，因此，只能用于测试环境而不适用于生产环境
@@ -133,98 +290,113 @@ func (ch *chain) main() {
 	//因此设置了定时打包出块的消息机制
 	var timer <-chan time.Time
 	var err error
+	defer close(ch.doneChan)
 
 	for {
+		//阻塞等待消息之前记录块分割工具中缓存的消息字节数
+		atomic.StoreInt64(&ch.batched, int64(ch.support.BlockCutter().PendingBytes()))
 		//获取当前channel的最新配置序号，阻塞等待消息
 		seq := ch.support.Sequence()
 		err = nil
+		//等待中的配置交易消息优先于普通交易消息
+		var msg *message
 		select {
-		//检查sendChain通道 消息
-		case msg := <-ch.sendChan:
-			if msg.configMsg == nil {
-				// NormalMsg
-				//普通交易消息
-
-				//判断配置交易消息经过排序后，当前通道的通道配置是否发生了更新，如果消息的configSeq较小，则说明当前通道配置已经更新，那么需要重新过滤与处理通道配置交易消息，以确保符合通道消息的要求
-				if msg.configSeq < seq {
-					//重新过滤验证普通交易消息
-					_, err = ch.support.ProcessNormalMsg(msg.normalMsg)
-					//若发现错误，则丢弃该消息，跳转继续循环
-					if err != nil {
-						logger.Warningf("Discarding bad normal message: %s", err)
-						continue
-					}
-				}
-				//用于将消息添加到缓存交易消息列表，按照交易处快规则切割成批量交易集合，同时提供Cut（）接口，不添加交易消息，直接切割当前的缓存交易消息列表构造成批量交易集合
-				batches, _ := ch.support.BlockCutter().Ordered(msg.normalMsg)
-				if len(batches) == 0 && timer == nil {
-					//如果结果中不存在处快消息并且没有设定时器，则设置定时器
-					timer = time.After(ch.support.SharedConfig().BatchTimeout())
+		case msg = <-ch.configChan:
+		default:
+		}
+		if msg == nil {
+			select {
+			case msg = <-ch.configChan:
+			//检查sendChain通道 消息
+			case msg = <-ch.sendChan:
+			//检查出块超时的定时器
+			case <-timer:
+				//clear the timer
+				//取消定时器
+				timer = nil
+
+				//将当前缓存交易消息的列表切割成批量交易集合
+				batch := ch.support.BlockCutter().Cut()
+				//如果不存在任何消息，则跳转至循环开始处继续执行
+				if len(batch) == 0 {
+					logger.Warningf("Batch timer expired with no pending requests, this might indicate a bug")
 					continue
 				}
-				//检查等待打包出块的批量交易集合列表
-				//遍历批量交易集合列表
-				for _, batch := range batches {
-					//利用区块账本写组件将批量交易集合构造成新区块
-					block := ch.support.CreateNextBlock(batch)
-					//利用区块账本写组件将区块提交到账本
-					ch.support.WriteBlock(block, nil)
-				}
-				//若存在批量交易集合列表，则取消定时器
-				if len(batches) > 0 {
-					timer = nil
-				}
-			} else {
-				// ConfigMsg
-				//通道配置交易消息：创建新的应用通道或更新通道配置
-				if msg.configSeq < seq {//检查消息中的配置序号与当前通道的配置序号
-					//重新过滤与处理配置交易消息
-					msg.configMsg, _, err = ch.support.ProcessConfigMsg(msg.configMsg)
-					//发现错误，丢弃该消息，跳转至循环开始处继续检查
-					if err != nil {
-						logger.Warningf("Discarding bad config message: %s", err)
-						continue
-					}
+				logger.Debugf("Batch timer expired, creating block")
+				//创建新的区块
+				block := ch.support.CreateNextBlock(batch)
+				//将区块写入账本
+				ch.support.WriteBlock(block, nil)
+				continue
+			//若接受到退出消息，则退出消息处理循环
+			case <-ch.exitChan:
+				logger.Debugf("Exiting")
+				return
+			}
+		}
+		//消息已被取出，不再计入排队的字节数
+		atomic.AddInt64(&ch.queued, -msg.size())
+		if msg.configMsg == nil {
+			// NormalMsg
+			//普通交易消息
+
+			//判断配置交易消息经过排序后，当前通道的通道配置是否发生了更新，如果消息的configSeq较小，则说明当前通道配置已经更新，那么需要重新过滤与处理通道配置交易消息，以确保符合通道消息的要求
+			if msg.configSeq < seq {
+				//重新过滤验证普通交易消息
+				_, err = ch.support.ProcessNormalMsg(msg.normalMsg)
+				//若发现错误，则丢弃该消息，跳转继续循环
+				if err != nil {
+					logger.Warningf("Discarding bad normal message: %s", err)
+					continue
 				}
-				//将当前缓存交易消息列表切割成交易消息
-				//如果当前接受的配置交易消息是用于创建新的应用通道，说明白不存在通道对象，则当前缓存交易列表中不存在任何交易消息。所以返回的batch是nil
-				batch := ch.support.BlockCutter().Cut()
-				if batch != nil {
-					//创建新区块
-					block := ch.support.CreateNextBlock(batch)
-					//将区块写入账本
-					ch.support.WriteBlock(block, nil)
+			}
+			//用于将消息添加到缓存交易消息列表，按照交易处快规则切割成批量交易集合，同时提供Cut（）接口，不添加交易消息，直接切割当前的缓存交易消息列表构造成批量交易集合
+			batches, _ := ch.support.BlockCutter().Ordered(msg.normalMsg)
+			if len(batches) == 0 && timer == nil {
+				//如果结果中不存在处快消息并且没有设定时器，则设置定时器
+				timer = time.After(ch.support.SharedConfig().BatchTimeout())
+				continue
+			}
+			//检查等待打包出块的批量交易集合列表
+			//遍历批量交易集合列表
+			for _, batch := range batches {
+				//利用区块账本写组件将批量交易集合构造成新区块
+				block := ch.support.CreateNextBlock(batch)
+				//利用区块账本写组件将区块提交到账本
+				ch.support.WriteBlock(block, nil)
+			}
+			//若存在批量交易集合列表，则取消定时器
+			if len(batches) > 0 {
+				timer = nil
+			}
+		} else {
+			// ConfigMsg
+			//通道配置交易消息：创建新的应用通道或更新通道配置
+			if msg.configSeq < seq {//检查消息中的配置序号与当前通道的配置序号
+				//重新过滤与处理配置交易消息
+				msg.configMsg, _, err = ch.support.ProcessConfigMsg(msg.configMsg)
+				//发现错误，丢弃该消息，跳转至循环开始处继续检查
+				if err != nil {
+					logger.Warningf("Discarding bad config message: %s", err)
+					continue
 				}
-
-				//将配置交易消息构造成新区块
-				block := ch.support.CreateNextBlock([]*cb.Envelope{msg.configMsg})
-				//创建新的应用通道或更新通道配置，在利用区块账本写组件将新区块提交到账本
-				//将配置区块写入账本，同事执行通道管理
-				ch.support.WriteConfigBlock(block, nil)
-				timer = nil//取消定时器
 			}
-		//检查出块超时的定时器
-		case <-timer:
-			//clear the timer
-			//取消定时器
-			timer = nil
-
-			//将当前缓存交易消息的列表切割成批量交易集合
+			//将当前缓存交易消息列表切割成交易消息
+			//如果当前接受的配置交易消息是用于创建新的应用通道，说明白不存在通道对象，则当前缓存交易列表中不存在任何交易消息。所以返回的batch是nil
 			batch := ch.support.BlockCutter().Cut()
-			//如果不存在任何消息，则跳转至循环开始处继续执行
-			if len(batch) == 0 {
-				logger.Warningf("Batch timer expired with no pending requests, this might indicate a bug")
-				continue
+			if batch != nil {
+				//创建新区块
+				block := ch.support.CreateNextBlock(batch)
+				//将区块写入账本
+				ch.support.WriteBlock(block, nil)
 			}
-			logger.Debugf("Batch timer expired, creating block")
-			//创建新的区块
-			block := ch.support.CreateNextBlock(batch)
-			//将区块写入账本
-			ch.support.WriteBlock(block, nil)
-		//若接受到退出消息，则退出消息处理循环
-		case <-ch.exitChan:
-			logger.Debugf("Exiting")
-			return
+
+			//将配置交易消息构造成新区块
+			block := ch.support.CreateNextBlock([]*cb.Envelope{msg.configMsg})
+			//创建新的应用通道或更新通道配置，在利用区块账本写组件将新区块提交到账本
+			//将配置区块写入账本，同事执行通道管理
+			ch.support.WriteConfigBlock(block, nil)
+			timer = nil//取消定时器
 		}
 	}
 }
@@ -17,12 +17,15 @@ limitations under the License.
 package solo
 
 import (
+	"bytes"
 	"fmt"
 	"testing"
 	"time"
 
 	"github.com/hyperledger/fabric/common/flogging"
 	mockconfig "github.com/hyperledger/fabric/common/mocks/config"
+	"github.com/hyperledger/fabric/orderer/common/backlog"
+	"github.com/hyperledger/fabric/orderer/consensus"
 	mockblockcutter "github.com/hyperledger/fabric/orderer/mocks/common/blockcutter"
 	mockmultichannel "github.com/hyperledger/fabric/orderer/mocks/common/multichannel"
 	cb "github.com/hyperledger/fabric/protos/common"
@@ -92,7 +95,7 @@ func TestStart(t *testing.T) {
 		SharedConfigVal: &mockconfig.Orderer{BatchTimeoutVal: batchTimeout},
 	}
 	close(support.BlockCutterVal.Block)
-	bs, _ := New().HandleChain(support, nil)
+	bs, _ := New(nil).HandleChain(support, nil)
 	bs.Start()
 	defer bs.Halt()
 
@@ -105,6 +108,119 @@ func TestStart(t *testing.T) {
 	}
 }
 
+func TestBacklog(t *testing.T) {
+	batchTimeout, _ := time.ParseDuration("1ms")
+	support := &mockmultichannel.ConsenterSupport{
+		ChainIDVal:      "foo",
+		Blocks:          make(chan *cb.Block),
+		BlockCutterVal:  mockblockcutter.NewReceiver(),
+		SharedConfigVal: &mockconfig.Orderer{BatchTimeoutVal: batchTimeout},
+	}
+	close(support.BlockCutterVal.Block)
+	pool, err := backlog.NewPool(backlog.Config{MaxMemory: int64(3 * len(utils.MarshalOrPanic(testMessage)))})
+	assert.NoError(t, err)
+	bs, err := New(pool).HandleChain(support, nil)
+	assert.NoError(t, err)
+	defer bs.Halt()
+
+	// 链未启动时消息缓存在backlog中，超出内存预算且未配置磁盘目录时被拒绝
+	for {
+		if err = bs.Order(testMessage, 0); err != nil {
+			break
+		}
+	}
+	assert.Equal(t, backlog.ErrFull, err)
+
+	support.BlockCutterVal.CutNext = true
+	bs.Start()
+	select {
+	case <-support.Blocks:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected backlogged message to be ordered")
+	}
+
+	bs.Halt()
+	assert.NotNil(t, bs.Order(testMessage, 0), "Order should not be accepted after halt")
+}
+
+func TestPendingBytes(t *testing.T) {
+	support := &mockmultichannel.ConsenterSupport{
+		ChainIDVal:      "foo",
+		Blocks:          make(chan *cb.Block),
+		BlockCutterVal:  mockblockcutter.NewReceiver(),
+		SharedConfigVal: &mockconfig.Orderer{BatchTimeoutVal: time.Hour},
+	}
+	close(support.BlockCutterVal.Block)
+	pool, err := backlog.NewPool(backlog.Config{MaxMemory: 1 << 20})
+	assert.NoError(t, err)
+	bs, err := New(pool).HandleChain(support, nil)
+	assert.NoError(t, err)
+	defer bs.Halt()
+	size := int64(len(testMessage.Payload) + len(testMessage.Signature))
+
+	// 链未启动时消息积压在backlog中
+	assert.NoError(t, bs.Order(testMessage, 0))
+	assert.NoError(t, bs.Order(testMessage, 0))
+	assert.Equal(t, 2*size, bs.(consensus.QueueReporter).PendingBytes())
+
+	// 被取出后缓存在块分割工具中，仍未写入区块
+	bs.Start()
+	deadline := time.Now().Add(time.Second)
+	for len(support.BlockCutterVal.CurBatch) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Equal(t, 2*size, bs.(consensus.QueueReporter).PendingBytes())
+
+	// 写入区块后不再计入
+	assert.NoError(t, bs.Configure(testMessage, 0))
+	<-support.Blocks
+	<-support.Blocks
+	deadline = time.Now().Add(time.Second)
+	for bs.(consensus.QueueReporter).PendingBytes() != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	assert.Zero(t, bs.(consensus.QueueReporter).PendingBytes())
+}
+
+func TestBacklogConfigPriority(t *testing.T) {
+	batchTimeout, _ := time.ParseDuration("1h")
+	support := &mockmultichannel.ConsenterSupport{
+		ChainIDVal:      "foo",
+		Blocks:          make(chan *cb.Block),
+		BlockCutterVal:  mockblockcutter.NewReceiver(),
+		SharedConfigVal: &mockconfig.Orderer{BatchTimeoutVal: batchTimeout},
+	}
+	close(support.BlockCutterVal.Block)
+	support.BlockCutterVal.CutNext = true
+	pool, err := backlog.NewPool(backlog.Config{MaxMemory: 1 << 20, MaxPriority: 1})
+	assert.NoError(t, err)
+	bs, err := New(pool).HandleChain(support, nil)
+	assert.NoError(t, err)
+	defer bs.Halt()
+
+	configMessage := &cb.Envelope{Payload: []byte("config")}
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, bs.Order(testMessage, 0))
+	}
+	assert.Nil(t, bs.Configure(configMessage, 0))
+	assert.Equal(t, backlog.ErrFull, bs.Configure(configMessage, 0))
+
+	// 配置交易消息排在backlog中的普通交易消息之前，最多有一条已取出的普通交易消息在其之前
+	bs.Start()
+	var configIndex int
+	for i := 0; i < 4; i++ {
+		select {
+		case block := <-support.Blocks:
+			if bytes.Equal(block.Data.Data[0], utils.MarshalOrPanic(configMessage)) {
+				configIndex = i
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("Expected four blocks to be cut")
+		}
+	}
+	assert.True(t, configIndex <= 1, "config block was cut after %d normal blocks", configIndex)
+}
+
 func TestOrderAfterHalt(t *testing.T) {
 	batchTimeout, _ := time.ParseDuration("1ms")
 	support := &mockmultichannel.ConsenterSupport{
@@ -114,7 +230,9 @@ func TestOrderAfterHalt(t *testing.T) {
 	}
 	defer close(support.BlockCutterVal.Block)
 	bs := newChain(support)
+	assert.True(t, bs.Status().Ready)
 	bs.Halt()
+	assert.False(t, bs.Status().Ready, "Chain should not be ready after halt")
 	assert.NotNil(t, bs.Order(testMessage, 0), "Order should not be accepted after halt")
 	select {
 	case <-bs.Errored():
@@ -99,3 +99,12 @@ func (mbc *Receiver) Cut() []*cb.Envelope {
 	mbc.CurBatch = nil
 	return res
 }
+
+// PendingBytes returns the size of the payloads and signatures of the current batch
+func (mbc *Receiver) PendingBytes() uint32 {
+	var size uint32
+	for _, env := range mbc.CurBatch {
+		size += uint32(len(env.Payload) + len(env.Signature))
+	}
+	return size
+}
@@ -0,0 +1,72 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package orderertest
+
+import (
+	"net"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// errListenerClosed is returned by Accept and Dial once the listener has been closed.
+var errListenerClosed = errors.New("in-memory listener closed")
+
+// pipeListener is a net.Listener whose connections are in-memory pipes, so
+// that a gRPC server and its clients can talk without touching the network
+// stack (the same idea as grpc/test/bufconn, which is not vendored here).
+type pipeListener struct {
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// Accept blocks until a client dials or the listener is closed.
+func (pl *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-pl.conns:
+		return conn, nil
+	case <-pl.done:
+		return nil, errListenerClosed
+	}
+}
+
+// Close stops the listener; pending and future Accept and Dial calls fail.
+func (pl *pipeListener) Close() error {
+	pl.closeOnce.Do(func() { close(pl.done) })
+	return nil
+}
+
+// Addr returns a placeholder address.
+func (pl *pipeListener) Addr() net.Addr {
+	return pipeAddr{}
+}
+
+// Dial creates a new in-memory connection to the listener.
+func (pl *pipeListener) Dial() (net.Conn, error) {
+	serverSide, clientSide := net.Pipe()
+	select {
+	case pl.conns <- serverSide:
+		return clientSide, nil
+	case <-pl.done:
+		serverSide.Close()
+		clientSide.Close()
+		return nil, errListenerClosed
+	}
+}
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return "orderertest" }
@@ -0,0 +1,236 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package orderertest assembles a complete, in-process ordering service for
+// use in tests.  The orderer is backed by the solo consenter and a RAM
+// ledger, is bootstrapped from a generated genesis block, and serves the
+// AtomicBroadcast API over in-memory connections, so that SDKs and tools can
+// be exercised end to end without binding ports or writing to disk.
+package orderertest
+
+import (
+	"net"
+	"time"
+
+	"github.com/hyperledger/fabric/common/crypto"
+	"github.com/hyperledger/fabric/common/flogging"
+	ramledger "github.com/hyperledger/fabric/common/ledger/blockledger/ram"
+	mockcrypto "github.com/hyperledger/fabric/common/mocks/crypto"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/configtxgentest"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/encoder"
+	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	"github.com/hyperledger/fabric/common/txtimeline"
+	"github.com/hyperledger/fabric/core/comm"
+	"github.com/hyperledger/fabric/orderer/common/localconfig"
+	"github.com/hyperledger/fabric/orderer/common/multichannel"
+	"github.com/hyperledger/fabric/orderer/common/server"
+	"github.com/hyperledger/fabric/orderer/common/tracing"
+	"github.com/hyperledger/fabric/orderer/consensus"
+	"github.com/hyperledger/fabric/orderer/consensus/faulty"
+	"github.com/hyperledger/fabric/orderer/consensus/solo"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/op/go-logging"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const pkgLogID = "orderer/orderertest"
+
+var logger *logging.Logger
+
+func init() {
+	logger = flogging.MustGetLogger(pkgLogID)
+}
+
+const (
+	// DefaultHistorySize is the number of blocks kept per channel by the RAM ledger.
+	DefaultHistorySize = 1000
+
+	// DefaultTimeWindow is the tolerated clock skew for deliver requests.
+	DefaultTimeWindow = 15 * time.Minute
+)
+
+// Config describes the orderer to assemble.  The zero value produces a solo
+// orderer bootstrapped from the SampleInsecureSolo profile.
+type Config struct {
+	// Profile is the configtxgen profile the genesis block is generated from.
+	// If nil, the SampleInsecureSolo profile from the dev config dir is used.
+	Profile *genesisconfig.Profile
+
+	// SystemChannelID is the ID of the ordering system channel.
+	// If empty, genesisconfig.TestChainID is used.
+	SystemChannelID string
+
+	// Signer signs the blocks written by the orderer.  If nil, a fake signer
+	// which does not require an initialized MSP is used.
+	Signer crypto.LocalSigner
+
+	// HistorySize is the number of blocks retained per channel.
+	// If zero, DefaultHistorySize is used.
+	HistorySize int
+
+	// TimeWindow bounds the timestamp skew of deliver requests.
+	// If zero, DefaultTimeWindow is used.
+	TimeWindow time.Duration
+
+	// Debug carries the broadcast and deliver trace settings.
+	Debug localconfig.Debug
+
+	// TxTimeline, if set, records the timeline of every transaction ordered.
+	TxTimeline *txtimeline.Recorder
+
+	// Tracer, if set, traces the messages ordered and their blocks.
+	Tracer *tracing.Tracer
+
+	// HeartbeatMinInterval is the shortest interval of the heartbeats sent
+	// to the clients asking for them.  If zero, no heartbeat is sent.
+	HeartbeatMinInterval time.Duration
+
+	// Faults, if set, are injected into the chains of the solo consenter.
+	// They can be changed while the orderer runs through Orderer.Faults.
+	Faults *faulty.Faults
+}
+
+// Orderer is an in-process ordering service.
+type Orderer struct {
+	// Registrar holds the channel resources of the orderer, letting tests
+	// inspect ledgers directly.
+	Registrar *multichannel.Registrar
+
+	// GenesisBlock is the block the system channel was bootstrapped with.
+	GenesisBlock *cb.Block
+
+	// Faults injects faults into the chains of the solo consenter, nil
+	// unless Config.Faults was set.
+	Faults *faulty.Consenter
+
+	listener   *pipeListener
+	grpcServer *comm.GRPCServer
+	serveErr   chan error
+}
+
+// New assembles and starts an in-process orderer.
+func New(conf Config) (*Orderer, error) {
+	profile := conf.Profile
+	if profile == nil {
+		profile = configtxgentest.Load(genesisconfig.SampleInsecureSoloProfile)
+	}
+	if profile.Orderer == nil {
+		return nil, errors.New("profile does not contain an orderer section")
+	}
+	if profile.Orderer.OrdererType != "solo" {
+		return nil, errors.Errorf("orderer type %s is not supported, only solo is", profile.Orderer.OrdererType)
+	}
+	channelID := conf.SystemChannelID
+	if channelID == "" {
+		channelID = genesisconfig.TestChainID
+	}
+	signer := conf.Signer
+	if signer == nil {
+		signer = mockcrypto.FakeLocalSigner
+	}
+	historySize := conf.HistorySize
+	if historySize == 0 {
+		historySize = DefaultHistorySize
+	}
+	timeWindow := conf.TimeWindow
+	if timeWindow == 0 {
+		timeWindow = DefaultTimeWindow
+	}
+
+	genesisBlock := encoder.New(profile).GenesisBlockForChannel(channelID)
+
+	lf := ramledger.New(historySize)
+	rl, err := lf.GetOrCreate(channelID)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create system channel ledger")
+	}
+	if err := rl.Append(genesisBlock); err != nil {
+		return nil, errors.Wrap(err, "failed to append genesis block")
+	}
+
+	consenters := map[string]consensus.Consenter{
+		"solo": solo.New(nil),
+	}
+	var faults *faulty.Consenter
+	if conf.Faults != nil {
+		faults = faulty.New(consenters["solo"], *conf.Faults)
+		consenters["solo"] = faults
+	}
+	registrar := multichannel.NewRegistrar(lf, consenters, signer, multichannel.RegistrarOptions{
+		TxTimeline: conf.TxTimeline,
+		Tracer:     conf.Tracer,
+	})
+
+	listener := newPipeListener()
+	grpcServer, err := comm.NewGRPCServerFromListener(listener, comm.ServerConfig{})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create gRPC server")
+	}
+	debug := conf.Debug
+	ab.RegisterAtomicBroadcastServer(grpcServer.Server(), server.NewServer(registrar, signer, &debug, timeWindow, false, server.ServerOptions{
+		HeartbeatMinInterval: conf.HeartbeatMinInterval,
+	}))
+
+	o := &Orderer{
+		Registrar:    registrar,
+		GenesisBlock: genesisBlock,
+		Faults:       faults,
+		listener:     listener,
+		grpcServer:   grpcServer,
+		serveErr:     make(chan error, 1),
+	}
+	go func() {
+		o.serveErr <- grpcServer.Start()
+	}()
+
+	logger.Debugf("Started in-process orderer for system channel %s", channelID)
+	return o, nil
+}
+
+// SystemChannelID returns the ID of the ordering system channel.
+func (o *Orderer) SystemChannelID() string {
+	return o.Registrar.SystemChannelID()
+}
+
+// Dial opens a client connection to the orderer.  The connection does not
+// use TLS; any additional dial options are appended to the defaults.
+func (o *Orderer) Dial(opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	dialOpts := []grpc.DialOption{
+		grpc.WithInsecure(),
+		grpc.WithBlock(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+			return o.listener.Dial()
+		}),
+	}
+	dialOpts = append(dialOpts, opts...)
+	return grpc.Dial(o.listener.Addr().String(), dialOpts...)
+}
+
+// NewClient returns an AtomicBroadcast client over a new connection, along
+// with the connection so that the caller can close it.
+func (o *Orderer) NewClient(opts ...grpc.DialOption) (ab.AtomicBroadcastClient, *grpc.ClientConn, error) {
+	conn, err := o.Dial(opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return ab.NewAtomicBroadcastClient(conn), conn, nil
+}
+
+// Stop shuts down the gRPC server and halts every channel.
+func (o *Orderer) Stop() {
+	o.grpcServer.Stop()
+	o.listener.Close()
+	for _, channelID := range o.Registrar.ChannelIDs() {
+		if cs, ok := o.Registrar.GetChain(channelID); ok {
+			cs.Halt()
+		}
+	}
+	if err := <-o.serveErr; err != nil {
+		logger.Debugf("In-process orderer stopped serving: %s", err)
+	}
+}
@@ -0,0 +1,298 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package orderertest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	mockcrypto "github.com/hyperledger/fabric/common/mocks/crypto"
+	"github.com/hyperledger/fabric/common/tools/configtxgen/configtxgentest"
+	genesisconfig "github.com/hyperledger/fabric/common/tools/configtxgen/localconfig"
+	"github.com/hyperledger/fabric/common/txtimeline"
+	"github.com/hyperledger/fabric/common/util"
+	"github.com/hyperledger/fabric/orderer/common/tracing"
+	"github.com/hyperledger/fabric/orderer/consensus/faulty"
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/hyperledger/fabric/protos/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/context"
+	"google.golang.org/grpc/metadata"
+)
+
+func seekBlock(t *testing.T, channelID string, number uint64) *cb.Envelope {
+	env, err := utils.CreateSignedEnvelope(cb.HeaderType_DELIVER_SEEK_INFO, channelID, mockcrypto.FakeLocalSigner, &ab.SeekInfo{
+		Start:    &ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: number}}},
+		Stop:     &ab.SeekPosition{Type: &ab.SeekPosition_Specified{Specified: &ab.SeekSpecified{Number: number}}},
+		Behavior: ab.SeekInfo_BLOCK_UNTIL_READY,
+	}, 0, 0)
+	require.NoError(t, err)
+	return env
+}
+
+func TestUnsupportedOrdererType(t *testing.T) {
+	profile := configtxgentest.Load(genesisconfig.SampleInsecureKafkaProfile)
+	// 示例配置中OrdererType位于合并的OrdererDefaults之前，加载后会被其覆盖为solo
+	profile.Orderer.OrdererType = "kafka"
+	_, err := New(Config{Profile: profile})
+	assert.EqualError(t, err, "orderer type kafka is not supported, only solo is")
+}
+
+func TestBroadcastDeliver(t *testing.T) {
+	o, err := New(Config{})
+	require.NoError(t, err)
+	defer o.Stop()
+
+	client, conn, err := o.NewClient()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	channelID := o.SystemChannelID()
+
+	deliver, err := client.Deliver(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, deliver.Send(seekBlock(t, channelID, 0)))
+	resp, err := deliver.Recv()
+	require.NoError(t, err)
+	assert.True(t, proto.Equal(o.GenesisBlock, resp.GetBlock()), "expected the genesis block")
+	resp, err = deliver.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, cb.Status_SUCCESS, resp.GetStatus())
+
+	broadcast, err := client.Broadcast(context.Background())
+	require.NoError(t, err)
+	env, err := utils.CreateSignedEnvelope(cb.HeaderType_MESSAGE, channelID, mockcrypto.FakeLocalSigner, &cb.Envelope{Payload: []byte("payload")}, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, broadcast.Send(env))
+	bresp, err := broadcast.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, cb.Status_SUCCESS, bresp.Status)
+
+	require.NoError(t, deliver.Send(seekBlock(t, channelID, 1)))
+	resp, err = deliver.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, resp.GetBlock(), "expected block 1")
+	assert.Equal(t, uint64(1), resp.GetBlock().Header.Number)
+	assert.Len(t, resp.GetBlock().Data.Data, 1)
+}
+
+func TestStop(t *testing.T) {
+	o, err := New(Config{})
+	require.NoError(t, err)
+	o.Stop()
+
+	_, err = o.listener.Dial()
+	assert.Equal(t, errListenerClosed, err)
+}
+
+func TestTxTimeline(t *testing.T) {
+	timeline := txtimeline.NewRecorder(10)
+	o, err := New(Config{TxTimeline: timeline})
+	require.NoError(t, err)
+	defer o.Stop()
+
+	client, conn, err := o.NewClient()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	chdr := utils.MakeChannelHeader(cb.HeaderType_MESSAGE, 0, o.SystemChannelID(), 0)
+	chdr.TxId = "tx1"
+	env := &cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: utils.MakePayloadHeader(chdr, &cb.SignatureHeader{}),
+			Data:   []byte("payload"),
+		}),
+	}
+
+	broadcast, err := client.Broadcast(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, broadcast.Send(env))
+	bresp, err := broadcast.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, cb.Status_SUCCESS, bresp.Status)
+
+	deliver, err := client.Deliver(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, deliver.Send(seekBlock(t, o.SystemChannelID(), 1)))
+	_, err = deliver.Recv()
+	require.NoError(t, err)
+
+	txs := timeline.Timeline().Transactions
+	require.Len(t, txs, 1)
+	assert.Equal(t, "tx1", txs[0].TxID)
+	assert.Equal(t, o.SystemChannelID(), txs[0].ChannelID)
+	assert.NotNil(t, txs[0].BroadcastReceived)
+	assert.NotNil(t, txs[0].Enqueued)
+	assert.NotNil(t, txs[0].BlockCut)
+	assert.Equal(t, uint64(1), *txs[0].BlockNumber)
+}
+
+func TestTracing(t *testing.T) {
+	tracer, err := tracing.New(tracing.Config{})
+	require.NoError(t, err)
+	o, err := New(Config{Tracer: tracer})
+	require.NoError(t, err)
+	defer o.Stop()
+
+	client, conn, err := o.NewClient()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	chdr := utils.MakeChannelHeader(cb.HeaderType_MESSAGE, 0, o.SystemChannelID(), 0)
+	chdr.TxId = "tx1"
+	env := &cb.Envelope{
+		Payload: utils.MarshalOrPanic(&cb.Payload{
+			Header: utils.MakePayloadHeader(chdr, &cb.SignatureHeader{}),
+			Data:   []byte("payload"),
+		}),
+	}
+
+	// 客户端通过traceparent元数据传播其追踪
+	ctx := metadata.AppendToOutgoingContext(context.Background(), tracing.TraceparentKey, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	broadcast, err := client.Broadcast(ctx)
+	require.NoError(t, err)
+	require.NoError(t, broadcast.Send(env))
+	bresp, err := broadcast.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, cb.Status_SUCCESS, bresp.Status)
+
+	deliver, err := client.Deliver(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, deliver.Send(seekBlock(t, o.SystemChannelID(), 1)))
+	_, err = deliver.Recv()
+	require.NoError(t, err)
+
+	// 区块发送的span在客户端收到区块之后结束
+	names := map[string]tracing.SpanData{}
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		for _, span := range tracer.TransactionSpans(o.SystemChannelID(), "tx1") {
+			names[span.Name] = span
+		}
+		if _, ok := names[tracing.SpanDeliver]; ok {
+			break
+		}
+	}
+	for _, name := range []string{tracing.SpanBroadcast, tracing.SpanWaitReady, tracing.SpanProcess, tracing.SpanOrder, tracing.SpanConsensus, tracing.SpanCut, tracing.SpanBlock, tracing.SpanDeliver} {
+		assert.Contains(t, names, name)
+	}
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", names[tracing.SpanBroadcast].TraceID)
+	assert.Equal(t, "1", names[tracing.SpanBlock].Attributes["block_number"])
+	assert.Equal(t, names[tracing.SpanBlock].SpanID, names[tracing.SpanDeliver].ParentID)
+}
+
+func TestHeartbeats(t *testing.T) {
+	o, err := New(Config{HeartbeatMinInterval: time.Millisecond})
+	require.NoError(t, err)
+	defer o.Stop()
+
+	client, conn, err := o.NewClient()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), util.HeartbeatIntervalKey, "10ms")
+	deliver, err := client.Deliver(ctx)
+	require.NoError(t, err)
+	require.NoError(t, deliver.Send(seekBlock(t, o.SystemChannelID(), 1)))
+	resp, err := deliver.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, resp.GetHeartbeat(), "expected a heartbeat while block 1 is awaited")
+	assert.Equal(t, o.SystemChannelID(), resp.GetHeartbeat().ChannelId)
+	assert.Equal(t, uint64(1), resp.GetHeartbeat().Height)
+
+	broadcast, err := client.Broadcast(ctx)
+	require.NoError(t, err)
+	env, err := utils.CreateSignedEnvelope(cb.HeaderType_MESSAGE, o.SystemChannelID(), mockcrypto.FakeLocalSigner, &cb.Envelope{Payload: []byte("payload")}, 0, 0)
+	require.NoError(t, err)
+	require.NoError(t, broadcast.Send(env))
+	for {
+		bresp, err := broadcast.Recv()
+		require.NoError(t, err)
+		if bresp.Heartbeat == nil {
+			assert.Equal(t, cb.Status_SUCCESS, bresp.Status)
+			break
+		}
+	}
+
+	// the heartbeats end once the block awaited is delivered
+	for {
+		resp, err = deliver.Recv()
+		require.NoError(t, err)
+		if resp.GetHeartbeat() == nil {
+			break
+		}
+	}
+	require.NotNil(t, resp.GetBlock(), "expected block 1")
+	resp, err = deliver.Recv()
+	require.NoError(t, err)
+	assert.Equal(t, cb.Status_SUCCESS, resp.GetStatus())
+
+	// the heartbeats of the broadcast stream report the channel of the
+	// message, eventually at its new height
+	for {
+		bresp, err := broadcast.Recv()
+		require.NoError(t, err)
+		require.NotNil(t, bresp.Heartbeat)
+		assert.Equal(t, o.SystemChannelID(), bresp.Heartbeat.ChannelId)
+		if bresp.Heartbeat.Height == 2 {
+			break
+		}
+	}
+}
+
+func TestFaults(t *testing.T) {
+	o, err := New(Config{Faults: &faulty.Faults{
+		NotReady: faulty.Pattern{Positions: []uint64{1}},
+		Drop:     faulty.Pattern{Positions: []uint64{1}},
+	}})
+	require.NoError(t, err)
+	defer o.Stop()
+
+	client, conn, err := o.NewClient()
+	require.NoError(t, err)
+	defer conn.Close()
+
+	channelID := o.SystemChannelID()
+	// the stream is closed once a message is rejected, so each message is
+	// sent on a stream of its own
+	send := func(payload string) cb.Status {
+		broadcast, err := client.Broadcast(context.Background())
+		require.NoError(t, err)
+		defer broadcast.CloseSend()
+		env, err := utils.CreateSignedEnvelope(cb.HeaderType_MESSAGE, channelID, mockcrypto.FakeLocalSigner, &cb.Envelope{Payload: []byte(payload)}, 0, 0)
+		require.NoError(t, err)
+		require.NoError(t, broadcast.Send(env))
+		resp, err := broadcast.Recv()
+		require.NoError(t, err)
+		return resp.Status
+	}
+
+	// the first message is rejected until retried, then dropped
+	assert.Equal(t, cb.Status_SERVICE_UNAVAILABLE, send("dropped"))
+	assert.Equal(t, cb.Status_SUCCESS, send("dropped"))
+	assert.Equal(t, cb.Status_SUCCESS, send("ordered"))
+
+	deliver, err := client.Deliver(context.Background())
+	require.NoError(t, err)
+	require.NoError(t, deliver.Send(seekBlock(t, channelID, 1)))
+	resp, err := deliver.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, resp.GetBlock(), "expected block 1")
+	require.Len(t, resp.GetBlock().Data.Data, 1)
+	env, err := utils.UnmarshalEnvelope(resp.GetBlock().Data.Data[0])
+	require.NoError(t, err)
+	payload, err := utils.UnmarshalPayload(env.Payload)
+	require.NoError(t, err)
+	inner, err := utils.UnmarshalEnvelope(payload.Data)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("ordered"), inner.Payload)
+
+	o.Faults.SetFaults(faulty.Faults{})
+	assert.Equal(t, cb.Status_SUCCESS, send("ordered again"))
+}
@@ -77,8 +77,13 @@ var (
 	vscc                  string
 	policyMarshalled      []byte
 	transient             string
+	idempotencyKey        string
 	collectionsConfigFile string
 	collectionConfigBytes []byte
+	executeTimeout        time.Duration
+	memoryLimit           int64
+	cpuShares             int64
+	limitsBytes           []byte
 	peerAddresses         []string
 	tlsRootCertFiles      []string
 	connectionProfile     string
@@ -130,8 +135,16 @@ func resetFlags() {
 		"Get the installed chaincodes on a peer")
 	flags.BoolVarP(&getInstantiatedChaincodes, "instantiated", "", false,
 		"Get the instantiated chaincodes on a channel")
+	flags.StringVar(&idempotencyKey, "idempotencyKey", "",
+		fmt.Sprint("A key identifying the invocation, from which its transaction ID is derived so that its retries are recognized as duplicates"))
 	flags.StringVar(&collectionsConfigFile, "collections-config", common.UndefinedParamValue,
 		fmt.Sprint("The fully qualified path to the collection JSON file including the file name"))
+	flags.DurationVar(&executeTimeout, "executeTimeout", 0,
+		fmt.Sprint("The timeout of the invocations of the chaincode, instead of the one configured on the peers"))
+	flags.Int64Var(&memoryLimit, "memoryLimit", 0,
+		fmt.Sprint("The memory limit of the chaincode containers in bytes, instead of the one configured on the peers"))
+	flags.Int64Var(&cpuShares, "cpuShares", 0,
+		fmt.Sprint("The CPU shares of the chaincode containers, instead of the ones configured on the peers"))
 	flags.StringArrayVarP(&peerAddresses, "peerAddresses", "", []string{common.UndefinedParamValue},
 		fmt.Sprint("The addresses of the peers to connect to"))
 	flags.StringArrayVarP(&tlsRootCertFiles, "tlsRootCertFiles", "", []string{common.UndefinedParamValue},
@@ -15,6 +15,7 @@ import (
 	"math"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/common/cauthdsl"
@@ -22,6 +23,7 @@ import (
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/chaincode"
 	"github.com/hyperledger/fabric/core/chaincode/shim"
+	"github.com/hyperledger/fabric/core/common/ccprovider"
 	"github.com/hyperledger/fabric/core/container"
 	"github.com/hyperledger/fabric/msp"
 	ccapi "github.com/hyperledger/fabric/peer/chaincode/api"
@@ -257,6 +259,19 @@ func checkChaincodeCmdParams(cmd *cobra.Command) error {
 				return errors.WithMessage(err, fmt.Sprintf("invalid collection configuration in file %s", collectionsConfigFile))
 			}
 		}
+
+		limitsBytes = nil
+		if executeTimeout != 0 || memoryLimit != 0 || cpuShares != 0 {
+			limits := &ccprovider.ChaincodeLimits{
+				ExecuteTimeout: int64(executeTimeout / time.Millisecond),
+				Memory:         memoryLimit,
+				CPUShares:      cpuShares,
+			}
+			if err := limits.Validate(); err != nil {
+				return err
+			}
+			limitsBytes = putils.MarshalOrPanic(limits)
+		}
 	}
 
 	// Check that non-empty chaincode parameters contain only Args as a key.
@@ -397,6 +412,7 @@ func InitCmdFactory(cmdName string, isEndorserRequired, isOrdererRequired bool)
 			logger.Infof("Retrieved channel (%s) orderer endpoint: %s", channelID, orderingEndpoints[0])
 			// override viper env
 			viper.Set("orderer.address", orderingEndpoints[0])
+			viper.Set("orderer.addresses", orderingEndpoints[1:])
 		}
 
 		broadcastClient, err = common.GetBroadcastClientFnc()
@@ -455,7 +471,13 @@ func ChaincodeInvokeOrQuery(
 		}
 	}
 
-	prop, txid, err := putils.CreateChaincodeProposalWithTxIDAndTransient(pcommon.HeaderType_ENDORSER_TRANSACTION, cID, invocation, creator, txID, tMap)
+	var prop *pb.Proposal
+	var txid string
+	if invoke && idempotencyKey != "" {
+		prop, txid, err = putils.CreateChaincodeProposalWithIdempotencyKey(pcommon.HeaderType_ENDORSER_TRANSACTION, cID, invocation, creator, []byte(idempotencyKey), tMap)
+	} else {
+		prop, txid, err = putils.CreateChaincodeProposalWithTxIDAndTransient(pcommon.HeaderType_ENDORSER_TRANSACTION, cID, invocation, creator, txID, tMap)
+	}
 	if err != nil {
 		return nil, errors.WithMessage(err, fmt.Sprintf("error creating proposal for %s", funcName))
 	}
@@ -44,6 +44,9 @@ func instantiateCmd(cf *ChaincodeCmdFactory) *cobra.Command {
 		"escc",
 		"vscc",
 		"collections-config",
+		"executeTimeout",
+		"memoryLimit",
+		"cpuShares",
 		"peerAddresses",
 		"tlsRootCertFiles",
 		"connectionProfile",
@@ -70,7 +73,7 @@ func instantiate(cmd *cobra.Command, cf *ChaincodeCmdFactory) (*protcommon.Envel
 		return nil, fmt.Errorf("error serializing identity for %s: %s", cf.Signer.GetIdentifier(), err)
 	}
 
-	prop, _, err := utils.CreateDeployProposalFromCDS(channelID, cds, creator, policyMarshalled, []byte(escc), []byte(vscc), collectionConfigBytes)
+	prop, _, err := utils.CreateDeployProposalFromCDSWithLimits(channelID, cds, creator, policyMarshalled, []byte(escc), []byte(vscc), collectionConfigBytes, limitsBytes)
 	if err != nil {
 		return nil, fmt.Errorf("error creating proposal  %s: %s", chainFuncName, err)
 	}
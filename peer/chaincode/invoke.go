@@ -35,6 +35,7 @@ func invokeCmd(cf *ChaincodeCmdFactory) *cobra.Command {
 		"connectionProfile",
 		"waitForEvent",
 		"waitForEventTimeout",
+		"idempotencyKey",
 	}
 	attachFlags(chaincodeInvokeCmd, flagList)
 
@@ -46,6 +46,9 @@ func upgradeCmd(cf *ChaincodeCmdFactory) *cobra.Command {
 		"tlsRootCertFiles",
 		"connectionProfile",
 		"collections-config",
+		"executeTimeout",
+		"memoryLimit",
+		"cpuShares",
 	}
 	attachFlags(chaincodeUpgradeCmd, flagList)
 
@@ -69,7 +72,7 @@ func upgrade(cmd *cobra.Command, cf *ChaincodeCmdFactory) (*protcommon.Envelope,
 		return nil, fmt.Errorf("error serializing identity for %s: %s", cf.Signer.GetIdentifier(), err)
 	}
 
-	prop, _, err := utils.CreateUpgradeProposalFromCDS(channelID, cds, creator, policyMarshalled, []byte(escc), []byte(vscc), collectionConfigBytes)
+	prop, _, err := utils.CreateUpgradeProposalFromCDSWithLimits(channelID, cds, creator, policyMarshalled, []byte(escc), []byte(vscc), collectionConfigBytes, limitsBytes)
 	if err != nil {
 		return nil, fmt.Errorf("error creating proposal %s: %s", chainFuncName, err)
 	}
@@ -27,6 +27,7 @@ import (
 	"github.com/hyperledger/fabric/protos/orderer"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/net/context"
 	"google.golang.org/grpc"
 )
 
@@ -66,6 +67,18 @@ func (*timeoutOrderer) Broadcast(orderer.AtomicBroadcast_BroadcastServer) error
 	panic("Should not have been called")
 }
 
+func (*timeoutOrderer) BroadcastBatch(orderer.AtomicBroadcast_BroadcastBatchServer) error {
+	panic("Should not have been called")
+}
+
+func (*timeoutOrderer) SubscribeConfig(*cb.Envelope, orderer.AtomicBroadcast_SubscribeConfigServer) error {
+	panic("Should not have been called")
+}
+
+func (*timeoutOrderer) Watermark(context.Context, *cb.Envelope) (*orderer.WatermarkResponse, error) {
+	panic("Should not have been called")
+}
+
 func (o *timeoutOrderer) SendBlock(seq uint64) {
 	o.blockChannel <- seq
 }
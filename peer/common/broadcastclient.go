@@ -18,8 +18,7 @@ package common
 
 import (
 	cb "github.com/hyperledger/fabric/protos/common"
-	ab "github.com/hyperledger/fabric/protos/orderer"
-	"github.com/pkg/errors"
+	"github.com/spf13/viper"
 )
 
 type BroadcastClient interface {
@@ -28,46 +27,22 @@ type BroadcastClient interface {
 	Close() error
 }
 
-type broadcastClient struct {
-	client ab.AtomicBroadcast_BroadcastClient
-}
-
 // GetBroadcastClient creates a simple instance of the BroadcastClient interface
+// sending to the orderer at orderer.address and to those at orderer.addresses,
+// through a pool of connections to them
 func GetBroadcastClient() (BroadcastClient, error) {
 	oc, err := NewOrdererClientFromEnv()
 	if err != nil {
 		return nil, err
 	}
-	bc, err := oc.Broadcast()
+	addresses := append([]string{viper.GetString("orderer.address")}, viper.GetStringSlice("orderer.addresses")...)
+	pool, err := NewOrdererPool(addresses, oc.Connect)
 	if err != nil {
 		return nil, err
 	}
-
-	return &broadcastClient{client: bc}, nil
-}
-
-func (s *broadcastClient) getAck() error {
-	msg, err := s.client.Recv()
-	if err != nil {
-		return err
-	}
-	if msg.Status != cb.Status_SUCCESS {
-		return errors.Errorf("got unexpected status: %v -- %s", msg.Status, msg.Info)
-	}
-	return nil
-}
-
-//Send data to orderer
-func (s *broadcastClient) Send(env *cb.Envelope) error {
-	if err := s.client.Send(env); err != nil {
-		return errors.WithMessage(err, "could not send")
+	if err := pool.Connect(); err != nil {
+		return nil, err
 	}
 
-	err := s.getAck()
-
-	return err
-}
-
-func (s *broadcastClient) Close() error {
-	return s.client.CloseSend()
+	return pool, nil
 }
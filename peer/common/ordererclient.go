@@ -13,6 +13,7 @@ import (
 	"github.com/hyperledger/fabric/core/comm"
 	ab "github.com/hyperledger/fabric/protos/orderer"
 	"github.com/pkg/errors"
+	"google.golang.org/grpc"
 )
 
 // OrdererClient represents a client for communicating with an ordering
@@ -40,6 +41,12 @@ func NewOrdererClientFromEnv() (*OrdererClient, error) {
 	return oClient, nil
 }
 
+// Connect returns a connection to the orderer at the address, with the
+// TLS configuration of the client
+func (oc *OrdererClient) Connect(address string) (*grpc.ClientConn, error) {
+	return oc.commonClient.NewConnection(address, oc.sn)
+}
+
 // Broadcast returns a broadcast client for the AtomicBroadcast service
 func (oc *OrdererClient) Broadcast() (ab.AtomicBroadcast_BroadcastClient, error) {
 	conn, err := oc.commonClient.NewConnection(oc.address, oc.sn)
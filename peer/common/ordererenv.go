@@ -16,6 +16,7 @@ import (
 
 var (
 	OrderingEndpoint           string
+	OrderingEndpoints          []string
 	tlsEnabled                 bool
 	clientAuth                 bool
 	caFile                     string
@@ -47,6 +48,7 @@ func SetOrdererEnv(cmd *cobra.Command, args []string) {
 	viper.Set("orderer.tls.clientKey.file", keyFile)
 	viper.Set("orderer.tls.clientCert.file", certFile)
 	viper.Set("orderer.address", OrderingEndpoint)
+	viper.Set("orderer.addresses", OrderingEndpoints)
 	viper.Set("orderer.tls.serverhostoverride", ordererTLSHostnameOverride)
 	viper.Set("orderer.tls.enabled", tlsEnabled)
 	viper.Set("orderer.tls.clientAuthRequired", clientAuth)
@@ -58,6 +60,8 @@ func AddOrdererFlags(cmd *cobra.Command) {
 	flags := cmd.PersistentFlags()
 
 	flags.StringVarP(&OrderingEndpoint, "orderer", "o", "", "Ordering service endpoint")
+	flags.StringSliceVarP(&OrderingEndpoints, "ordererAddresses", "", nil,
+		"Additional ordering service endpoints, to which transactions are sent should the orderer endpoint be unavailable")
 	flags.BoolVarP(&tlsEnabled, "tls", "", false, "Use TLS when communicating with the orderer endpoint")
 	flags.BoolVarP(&clientAuth, "clientauth", "", false,
 		"Use mutual TLS when communicating with the orderer endpoint")
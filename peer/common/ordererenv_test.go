@@ -6,6 +6,7 @@ SPDX-License-Identifier: Apache-2.0
 package common_test
 
 import (
+	"strings"
 	"testing"
 
 	"github.com/hyperledger/fabric/peer/common"
@@ -17,11 +18,12 @@ import (
 func TestOrdererCmdEnv(t *testing.T) {
 
 	var (
-		ca       = "root.crt"
-		key      = "client.key"
-		cert     = "client.crt"
-		endpoint = "orderer.example.com:7050"
-		sn       = "override.example.com"
+		ca        = "root.crt"
+		key       = "client.key"
+		cert      = "client.crt"
+		endpoint  = "orderer.example.com:7050"
+		endpoints = []string{"orderer2.example.com:7050", "orderer3.example.com:7050"}
+		sn        = "override.example.com"
 	)
 
 	runCmd := &cobra.Command{
@@ -32,6 +34,7 @@ func TestOrdererCmdEnv(t *testing.T) {
 			assert.Equal(t, key, viper.GetString("orderer.tls.clientKey.file"))
 			assert.Equal(t, cert, viper.GetString("orderer.tls.clientCert.file"))
 			assert.Equal(t, endpoint, viper.GetString("orderer.address"))
+			assert.Equal(t, endpoints, viper.GetStringSlice("orderer.addresses"))
 			assert.Equal(t, sn, viper.GetString("orderer.tls.serverhostoverride"))
 			assert.Equal(t, true, viper.GetBool("orderer.tls.enabled"))
 			assert.Equal(t, true, viper.GetBool("orderer.tls.clientAuthRequired"))
@@ -43,7 +46,7 @@ func TestOrdererCmdEnv(t *testing.T) {
 
 	runCmd.SetArgs([]string{"test", "--cafile", ca, "--keyfile", key,
 		"--certfile", cert, "--orderer", endpoint, "--tls", "--clientauth",
-		"--ordererTLSHostnameOverride", sn})
+		"--ordererTLSHostnameOverride", sn, "--ordererAddresses", strings.Join(endpoints, ",")})
 	err := runCmd.Execute()
 	assert.NoError(t, err)
 
@@ -0,0 +1,235 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/pkg/errors"
+	"google.golang.org/grpc"
+)
+
+const (
+	// weight of the latest submission in the moving averages of an endpoint
+	scoreSmoothing = 0.3
+	// latency added to the score of an endpoint for an error rate of 1
+	failurePenalty = 5 * time.Second
+	// time after which half of the error rate of an endpoint is forgiven
+	failureHalfLife = 30 * time.Second
+)
+
+// OrdererPool broadcasts messages to the ordering service over a pool of
+// connections to its endpoints, kept open across submissions.  Endpoints are
+// scored by the latency and the error rate of their recent submissions, and
+// each message is sent to the endpoint with the best score.  Should the
+// endpoint be unreachable or unavailable, the message is sent to the next
+// best endpoint; a message sent twice is only committed once, the second
+// transaction with the same ID being invalidated by the peers.
+type OrdererPool struct {
+	mutex     sync.Mutex
+	endpoints []*poolEndpoint
+	dial      func(address string) (*grpc.ClientConn, error)
+	now       func() time.Time
+}
+
+// poolEndpoint is an endpoint of the pool and its connection, if any
+type poolEndpoint struct {
+	address string
+
+	// guards the broadcast stream, on which one message is sent at once
+	streamMutex sync.Mutex
+	conn        *grpc.ClientConn
+	stream      ab.AtomicBroadcast_BroadcastClient
+
+	// guarded by the mutex of the pool
+	submissions int
+	latency     time.Duration
+	errorRate   float64
+	lastFailure time.Time
+}
+
+// NewOrdererPool creates a pool of the orderer endpoints, connected to by dial
+// when a message is first sent to them.
+func NewOrdererPool(addresses []string, dial func(address string) (*grpc.ClientConn, error)) (*OrdererPool, error) {
+	if len(addresses) == 0 {
+		return nil, errors.New("no orderer endpoints")
+	}
+	p := &OrdererPool{dial: dial, now: time.Now}
+	seen := map[string]bool{}
+	for _, address := range addresses {
+		if address == "" || seen[address] {
+			continue
+		}
+		seen[address] = true
+		p.endpoints = append(p.endpoints, &poolEndpoint{address: address})
+	}
+	return p, nil
+}
+
+// Connect opens a broadcast stream to the endpoint with the best score which
+// can be reached, and returns an error if none can.
+func (p *OrdererPool) Connect() error {
+	var err error
+	for _, e := range p.ranked() {
+		e.streamMutex.Lock()
+		err = e.connect(p.dial)
+		e.streamMutex.Unlock()
+		if err == nil {
+			return nil
+		}
+		p.record(e, 0, err)
+	}
+	return err
+}
+
+// Send sends the message to the endpoint with the best score, failing over to
+// the next endpoints, and waits for its acknowledgement.
+func (p *OrdererPool) Send(env *cb.Envelope) error {
+	var err error
+	for _, e := range p.ranked() {
+		start := p.now()
+		var resp *ab.BroadcastResponse
+		resp, err = e.broadcast(env, p.dial)
+		if err == nil && resp.Status == cb.Status_SERVICE_UNAVAILABLE {
+			err = errors.Errorf("got unexpected status: %v -- %s", resp.Status, resp.Info)
+		}
+		p.record(e, p.now().Sub(start), err)
+		if err != nil {
+			logger.Warningf("Failed to broadcast to orderer %s: %s", e.address, err)
+			continue
+		}
+		if resp.Status != cb.Status_SUCCESS {
+			return errors.Errorf("got unexpected status: %v -- %s", resp.Status, resp.Info)
+		}
+		return nil
+	}
+	return errors.WithMessage(err, "could not send")
+}
+
+// Close closes the streams and connections to every endpoint.
+func (p *OrdererPool) Close() error {
+	for _, e := range p.endpoints {
+		e.streamMutex.Lock()
+		e.reset()
+		e.streamMutex.Unlock()
+	}
+	return nil
+}
+
+// ranked returns the endpoints from the best to the worst score
+func (p *OrdererPool) ranked() []*poolEndpoint {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	now := p.now()
+	scores := make(map[*poolEndpoint]time.Duration, len(p.endpoints))
+	for _, e := range p.endpoints {
+		scores[e] = e.score(now)
+	}
+	ranked := append([]*poolEndpoint{}, p.endpoints...)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return scores[ranked[i]] < scores[ranked[j]]
+	})
+	return ranked
+}
+
+// record updates the moving averages of the endpoint with the outcome of a submission
+func (p *OrdererPool) record(e *poolEndpoint, latency time.Duration, err error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	now := p.now()
+	failure := 0.0
+	if err != nil {
+		failure = 1
+		e.lastFailure = now
+	}
+	if e.submissions == 0 {
+		e.errorRate = failure
+		if err == nil {
+			e.latency = latency
+		}
+	} else {
+		e.errorRate = e.decayedErrorRate(now)*(1-scoreSmoothing) + failure*scoreSmoothing
+		if err == nil {
+			e.latency = time.Duration(float64(e.latency)*(1-scoreSmoothing) + float64(latency)*scoreSmoothing)
+		}
+	}
+	e.submissions++
+}
+
+// score returns the expected latency of a submission to the endpoint, an
+// endpoint never used having the best score so that it gets tried
+func (e *poolEndpoint) score(now time.Time) time.Duration {
+	return e.latency + time.Duration(e.decayedErrorRate(now)*float64(failurePenalty))
+}
+
+// decayedErrorRate returns the error rate of the endpoint, forgiven as time
+// passes since its last failure so that endpoints recovering get tried again
+func (e *poolEndpoint) decayedErrorRate(now time.Time) float64 {
+	if e.lastFailure.IsZero() {
+		return e.errorRate
+	}
+	return e.errorRate * math.Pow(0.5, float64(now.Sub(e.lastFailure))/float64(failureHalfLife))
+}
+
+// connect opens the broadcast stream of the endpoint, the caller holding the stream mutex
+func (e *poolEndpoint) connect(dial func(address string) (*grpc.ClientConn, error)) error {
+	if e.stream != nil {
+		return nil
+	}
+	if e.conn == nil {
+		conn, err := dial(e.address)
+		if err != nil {
+			return errors.WithMessage(err, "orderer client failed to connect to "+e.address)
+		}
+		e.conn = conn
+	}
+	stream, err := ab.NewAtomicBroadcastClient(e.conn).Broadcast(context.Background())
+	if err != nil {
+		e.reset()
+		return err
+	}
+	e.stream = stream
+	return nil
+}
+
+// broadcast sends the message on the broadcast stream of the endpoint and
+// returns its response, the stream being reset on errors
+func (e *poolEndpoint) broadcast(env *cb.Envelope, dial func(address string) (*grpc.ClientConn, error)) (*ab.BroadcastResponse, error) {
+	e.streamMutex.Lock()
+	defer e.streamMutex.Unlock()
+	if err := e.connect(dial); err != nil {
+		return nil, err
+	}
+	if err := e.stream.Send(env); err != nil {
+		e.reset()
+		return nil, err
+	}
+	resp, err := e.stream.Recv()
+	if err != nil {
+		e.reset()
+		return nil, err
+	}
+	return resp, nil
+}
+
+// reset closes the stream and connection of the endpoint, the caller holding the stream mutex
+func (e *poolEndpoint) reset() {
+	if e.stream != nil {
+		e.stream.CloseSend()
+		e.stream = nil
+	}
+	if e.conn != nil {
+		e.conn.Close()
+		e.conn = nil
+	}
+}
@@ -0,0 +1,166 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package common
+
+import (
+	"context"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	cb "github.com/hyperledger/fabric/protos/common"
+	ab "github.com/hyperledger/fabric/protos/orderer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// poolOrderer answers every message broadcast to it with its status, after its delay
+type poolOrderer struct {
+	server   *grpc.Server
+	address  string
+	status   cb.Status
+	delay    time.Duration
+	received int32
+}
+
+func (o *poolOrderer) Broadcast(srv ab.AtomicBroadcast_BroadcastServer) error {
+	for {
+		if _, err := srv.Recv(); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+		atomic.AddInt32(&o.received, 1)
+		time.Sleep(o.delay)
+		if err := srv.Send(&ab.BroadcastResponse{Status: o.status}); err != nil {
+			return err
+		}
+	}
+}
+
+func (*poolOrderer) Deliver(ab.AtomicBroadcast_DeliverServer) error {
+	panic("Should not have been called")
+}
+
+func (*poolOrderer) BroadcastBatch(ab.AtomicBroadcast_BroadcastBatchServer) error {
+	panic("Should not have been called")
+}
+
+func (*poolOrderer) SubscribeConfig(*cb.Envelope, ab.AtomicBroadcast_SubscribeConfigServer) error {
+	panic("Should not have been called")
+}
+
+func (*poolOrderer) Watermark(context.Context, *cb.Envelope) (*ab.WatermarkResponse, error) {
+	panic("Should not have been called")
+}
+
+func startPoolOrderer(t *testing.T, status cb.Status, delay time.Duration) *poolOrderer {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	o := &poolOrderer{server: grpc.NewServer(), address: lis.Addr().String(), status: status, delay: delay}
+	ab.RegisterAtomicBroadcastServer(o.server, o)
+	go o.server.Serve(lis)
+	return o
+}
+
+func unreachableAddress(t *testing.T) string {
+	lis, err := net.Listen("tcp", "localhost:0")
+	require.NoError(t, err)
+	lis.Close()
+	return lis.Addr().String()
+}
+
+func dialPoolOrderer(address string) (*grpc.ClientConn, error) {
+	return grpc.Dial(address, grpc.WithInsecure(), grpc.WithBlock(), grpc.WithTimeout(200*time.Millisecond))
+}
+
+func TestOrdererPoolFailover(t *testing.T) {
+	down := unreachableAddress(t)
+	up := startPoolOrderer(t, cb.Status_SUCCESS, 0)
+	defer up.server.Stop()
+
+	pool, err := NewOrdererPool([]string{down, up.address, up.address}, dialPoolOrderer)
+	require.NoError(t, err)
+	defer pool.Close()
+	assert.Len(t, pool.endpoints, 2)
+
+	require.NoError(t, pool.Connect())
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, pool.Send(&cb.Envelope{}))
+	}
+	assert.Equal(t, int32(3), atomic.LoadInt32(&up.received))
+	assert.Equal(t, up.address, pool.ranked()[0].address)
+
+	t.Run("NoEndpointReachable", func(t *testing.T) {
+		pool, err := NewOrdererPool([]string{down}, dialPoolOrderer)
+		require.NoError(t, err)
+		assert.Error(t, pool.Connect())
+		err = pool.Send(&cb.Envelope{})
+		assert.Contains(t, err.Error(), "could not send")
+	})
+
+	t.Run("NoEndpoint", func(t *testing.T) {
+		_, err := NewOrdererPool(nil, dialPoolOrderer)
+		assert.Error(t, err)
+	})
+}
+
+func TestOrdererPoolStatus(t *testing.T) {
+	unavailable := startPoolOrderer(t, cb.Status_SERVICE_UNAVAILABLE, 0)
+	badRequest := startPoolOrderer(t, cb.Status_BAD_REQUEST, 0)
+	defer unavailable.server.Stop()
+	defer badRequest.server.Stop()
+
+	pool, err := NewOrdererPool([]string{unavailable.address, badRequest.address}, dialPoolOrderer)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// an unavailable orderer is failed over, while a rejection is returned
+	err = pool.Send(&cb.Envelope{})
+	assert.EqualError(t, err, "got unexpected status: BAD_REQUEST -- ")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&unavailable.received))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&badRequest.received))
+	assert.Equal(t, badRequest.address, pool.ranked()[0].address)
+}
+
+func TestOrdererPoolLatency(t *testing.T) {
+	slow := startPoolOrderer(t, cb.Status_SUCCESS, 100*time.Millisecond)
+	fast := startPoolOrderer(t, cb.Status_SUCCESS, 0)
+	defer slow.server.Stop()
+	defer fast.server.Stop()
+
+	pool, err := NewOrdererPool([]string{slow.address, fast.address}, dialPoolOrderer)
+	require.NoError(t, err)
+	defer pool.Close()
+
+	// the endpoint never used is tried, then preferred for its latency
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, pool.Send(&cb.Envelope{}))
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&slow.received))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&fast.received))
+}
+
+func TestOrdererPoolErrorRateDecay(t *testing.T) {
+	now := time.Now()
+	pool, err := NewOrdererPool([]string{"orderer1:7050", "orderer2:7050"}, nil)
+	require.NoError(t, err)
+	pool.now = func() time.Time { return now }
+
+	orderer1, orderer2 := pool.endpoints[0], pool.endpoints[1]
+	pool.record(orderer1, 0, io.EOF)
+	pool.record(orderer2, time.Second, nil)
+	assert.Equal(t, []*poolEndpoint{orderer2, orderer1}, pool.ranked())
+
+	// the failure is forgiven as time passes
+	now = now.Add(5 * failureHalfLife)
+	assert.Equal(t, []*poolEndpoint{orderer1, orderer2}, pool.ranked())
+}
@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package gossip
+
+import (
+	"github.com/hyperledger/fabric/common/policies"
+	blockverification "github.com/hyperledger/fabric/core/handlers/blockverification/api"
+	"github.com/hyperledger/fabric/msp/mgmt"
+	pcommon "github.com/hyperledger/fabric/protos/common"
+	"github.com/pkg/errors"
+)
+
+// NewBlockVerifier creates a block verification plugin from the factory and
+// initializes it with the parameters, an evaluator of the policies of the
+// channels and a deserializer of the identities of their members.  It
+// returns nil if the factory is nil.
+func NewBlockVerifier(factory blockverification.PluginFactory, parameters map[string]string, channelPolicyManagerGetter policies.ChannelPolicyManagerGetter, deserializer mgmt.DeserializersManager) (blockverification.Plugin, error) {
+	if factory == nil {
+		return nil, nil
+	}
+	plugin := factory.New()
+	evaluator := &policyEvaluator{channelPolicyManagerGetter: channelPolicyManagerGetter}
+	identities := &identityDeserializer{deserializer: deserializer}
+	if err := plugin.Init(evaluator, identities, blockverification.Parameters(parameters)); err != nil {
+		return nil, errors.WithMessage(err, "failed initializing block verification plugin")
+	}
+	return plugin, nil
+}
+
+// policyEvaluator evaluates the policies of the channels
+type policyEvaluator struct {
+	channelPolicyManagerGetter policies.ChannelPolicyManagerGetter
+}
+
+// Evaluate returns nil if the signatures satisfy the policy of the channel
+func (pe *policyEvaluator) Evaluate(channelID string, policyName string, signatures []*pcommon.SignedData) error {
+	cpm, _ := pe.channelPolicyManagerGetter.Manager(channelID)
+	if cpm == nil {
+		return errors.Errorf("could not acquire policy manager for channel %s", channelID)
+	}
+	policy, ok := cpm.GetPolicy(policyName)
+	if !ok {
+		return errors.Errorf("policy %s not found on channel %s", policyName, channelID)
+	}
+	return policy.Evaluate(signatures)
+}
+
+// identityDeserializer deserializes identities through the MSPs of the
+// channels
+type identityDeserializer struct {
+	deserializer mgmt.DeserializersManager
+}
+
+// Identifier returns the identifier of the identity within its MSP, the hash
+// of its certificate for the X.509 MSPs
+func (id *identityDeserializer) Identifier(channelID string, serializedIdentity []byte) (string, error) {
+	mspManager, ok := id.deserializer.GetChannelDeserializers()[channelID]
+	if !ok {
+		return "", errors.Errorf("could not acquire MSP manager for channel %s", channelID)
+	}
+	identity, err := mspManager.DeserializeIdentity(serializedIdentity)
+	if err != nil {
+		return "", errors.WithMessage(err, "failed deserializing identity")
+	}
+	return identity.GetIdentifier().Id, nil
+}
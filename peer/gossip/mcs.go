@@ -13,10 +13,11 @@ import (
 
 	"github.com/hyperledger/fabric/bccsp"
 	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/common/blocksig"
 	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/flogging"
 	"github.com/hyperledger/fabric/common/policies"
-	"github.com/hyperledger/fabric/common/util"
+	blockverification "github.com/hyperledger/fabric/core/handlers/blockverification/api"
 	"github.com/hyperledger/fabric/gossip/api"
 	"github.com/hyperledger/fabric/gossip/common"
 	"github.com/hyperledger/fabric/msp"
@@ -41,6 +42,7 @@ type MSPMessageCryptoService struct {
 	channelPolicyManagerGetter policies.ChannelPolicyManagerGetter
 	localSigner                crypto.LocalSigner
 	deserializer               mgmt.DeserializersManager
+	blockVerifier              blockverification.Plugin
 }
 
 // NewMCS creates a new instance of MSPMessageCryptoService
@@ -49,8 +51,9 @@ type MSPMessageCryptoService struct {
 // 1. a policies.ChannelPolicyManagerGetter that gives access to the policy manager of a given channel via the Manager method.
 // 2. an instance of crypto.LocalSigner
 // 3. an identity deserializer manager
-func NewMCS(channelPolicyManagerGetter policies.ChannelPolicyManagerGetter, localSigner crypto.LocalSigner, deserializer mgmt.DeserializersManager) *MSPMessageCryptoService {
-	return &MSPMessageCryptoService{channelPolicyManagerGetter: channelPolicyManagerGetter, localSigner: localSigner, deserializer: deserializer}
+// 4. an optional block verification plugin, the BlockValidation policy of the channel being enforced if it is nil
+func NewMCS(channelPolicyManagerGetter policies.ChannelPolicyManagerGetter, localSigner crypto.LocalSigner, deserializer mgmt.DeserializersManager, blockVerifier blockverification.Plugin) *MSPMessageCryptoService {
+	return &MSPMessageCryptoService{channelPolicyManagerGetter: channelPolicyManagerGetter, localSigner: localSigner, deserializer: deserializer, blockVerifier: blockVerifier}
 }
 
 // ValidateIdentity validates the identity of a remote peer.
@@ -138,17 +141,23 @@ func (s *MSPMessageCryptoService) VerifyBlock(chainID common.ChainID, seqNum uin
 		return fmt.Errorf("Block with id [%d] on channel [%s] does not have metadata. Block not valid.", block.Header.Number, chainID)
 	}
 
-	metadata, err := utils.GetMetadataFromBlock(block, pcommon.BlockMetadataIndex_SIGNATURES)
-	if err != nil {
-		return fmt.Errorf("Failed unmarshalling medatata for signatures [%s]", err)
-	}
-
 	// - Verify that Header.DataHash is equal to the hash of block.Data
 	// This is to ensure that the header is consistent with the data carried by this block
 	if !bytes.Equal(block.Data.Hash(), block.Header.DataHash) {
 		return fmt.Errorf("Header.DataHash is different from Hash(block.Data) for block with id [%d] on channel [%s]", block.Header.Number, chainID)
 	}
 
+	// - Prepare SignedData, out of the signatures of the block or of its batch
+	signatureSet, err := blocksig.SignedData(block)
+	if err != nil {
+		return fmt.Errorf("Failed getting the signatures of block with id [%d] on channel [%s]: [%s]", block.Header.Number, chainID, err)
+	}
+
+	// - Delegate to the block verification plugin, if any
+	if s.blockVerifier != nil {
+		return s.blockVerifier.Verify(channelID, block, signatureSet)
+	}
+
 	// - Get Policy for block validation
 
 	// Get the policy manager for channelID
@@ -164,23 +173,6 @@ func (s *MSPMessageCryptoService) VerifyBlock(chainID common.ChainID, seqNum uin
 	// ok is true if it was the policy requested, or false if it is the default policy
 	mcsLogger.Debugf("Got block validation policy for channel [%s] with flag [%t]", channelID, ok)
 
-	// - Prepare SignedData
-	signatureSet := []*pcommon.SignedData{}
-	for _, metadataSignature := range metadata.Signatures {
-		shdr, err := utils.GetSignatureHeader(metadataSignature.SignatureHeader)
-		if err != nil {
-			return fmt.Errorf("Failed unmarshalling signature header for block with id [%d] on channel [%s]: [%s]", block.Header.Number, chainID, err)
-		}
-		signatureSet = append(
-			signatureSet,
-			&pcommon.SignedData{
-				Identity:  shdr.Creator,
-				Data:      util.ConcatenateBytes(metadata.Value, metadataSignature.SignatureHeader, block.Header.Bytes()),
-				Signature: metadataSignature.Signature,
-			},
-		)
-	}
-
 	// - Evaluate policy
 	return policy.Evaluate(signatureSet)
 }
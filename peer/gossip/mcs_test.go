@@ -17,6 +17,7 @@ limitations under the License.
 package gossip
 
 import (
+	"encoding/pem"
 	"errors"
 	"reflect"
 	"strings"
@@ -26,14 +27,18 @@ import (
 	"github.com/golang/protobuf/proto"
 	"github.com/hyperledger/fabric/bccsp"
 	"github.com/hyperledger/fabric/bccsp/factory"
+	"github.com/hyperledger/fabric/common/blocksig"
 	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/localmsp"
 	mockscrypto "github.com/hyperledger/fabric/common/mocks/crypto"
 	"github.com/hyperledger/fabric/common/policies"
 	"github.com/hyperledger/fabric/common/util"
+	blockverification "github.com/hyperledger/fabric/core/handlers/blockverification/api"
+	"github.com/hyperledger/fabric/core/handlers/blockverification/builtin"
 	"github.com/hyperledger/fabric/gossip/api"
 	"github.com/hyperledger/fabric/msp"
 	"github.com/hyperledger/fabric/msp/mgmt"
+	msptesttools "github.com/hyperledger/fabric/msp/mgmt/testtools"
 	"github.com/hyperledger/fabric/peer/gossip/mocks"
 	"github.com/hyperledger/fabric/protos/common"
 	pmsp "github.com/hyperledger/fabric/protos/msp"
@@ -50,6 +55,7 @@ func TestPKIidOfCert(t *testing.T) {
 	msgCryptoService := NewMCS(&mocks.ChannelPolicyManagerGetterWithManager{},
 		&mockscrypto.LocalSigner{Identity: []byte("Alice")},
 		deserializersManager,
+		nil,
 	)
 
 	peerIdentity := []byte("Alice")
@@ -79,7 +85,7 @@ func TestPKIidOfCert(t *testing.T) {
 }
 
 func TestPKIidOfNil(t *testing.T) {
-	msgCryptoService := NewMCS(&mocks.ChannelPolicyManagerGetter{}, localmsp.NewSigner(), mgmt.NewDeserializersManager())
+	msgCryptoService := NewMCS(&mocks.ChannelPolicyManagerGetter{}, localmsp.NewSigner(), mgmt.NewDeserializersManager(), nil)
 
 	pkid := msgCryptoService.GetPKIidOfCert(nil)
 	// Check pkid is not nil
@@ -97,6 +103,7 @@ func TestValidateIdentity(t *testing.T) {
 		&mocks.ChannelPolicyManagerGetterWithManager{},
 		&mockscrypto.LocalSigner{Identity: []byte("Charlie")},
 		deserializersManager,
+		nil,
 	)
 
 	err := msgCryptoService.ValidateIdentity([]byte("Alice"))
@@ -128,6 +135,7 @@ func TestSign(t *testing.T) {
 		&mocks.ChannelPolicyManagerGetter{},
 		&mockscrypto.LocalSigner{Identity: []byte("Alice")},
 		mgmt.NewDeserializersManager(),
+		nil,
 	)
 
 	msg := []byte("Hello World!!!")
@@ -158,6 +166,7 @@ func TestVerify(t *testing.T) {
 				"C": &mocks.IdentityDeserializer{Identity: []byte("Dave"), Msg: []byte("msg4"), Mock: mock.Mock{}},
 			},
 		},
+		nil,
 	)
 
 	msg := []byte("msg1")
@@ -212,6 +221,7 @@ func TestVerifyBlock(t *testing.T) {
 				"B": &mocks.IdentityDeserializer{Identity: []byte("Charlie"), Msg: []byte("msg3"), Mock: mock.Mock{}},
 			},
 		},
+		nil,
 	)
 
 	// - Prepare testing valid block, Alice signs it.
@@ -245,6 +255,169 @@ func TestVerifyBlock(t *testing.T) {
 	assert.Error(t, msgCryptoService.VerifyBlock([]byte("C"), 42, nil))
 }
 
+type mockBlockVerifier struct {
+	err        error
+	channelID  string
+	signatures []*common.SignedData
+}
+
+func (v *mockBlockVerifier) Verify(channelID string, block *common.Block, signatures []*common.SignedData) error {
+	v.channelID = channelID
+	v.signatures = signatures
+	return v.err
+}
+
+func (v *mockBlockVerifier) Init(dependencies ...blockverification.Dependency) error {
+	return nil
+}
+
+func TestVerifyBlockWithPlugin(t *testing.T) {
+	aliceSigner := &mockscrypto.LocalSigner{Identity: []byte("Alice")}
+	verifier := &mockBlockVerifier{}
+	msgCryptoService := NewMCS(
+		&mocks.ChannelPolicyManagerGetterWithManager{Managers: map[string]policies.Manager{}},
+		aliceSigner,
+		&mocks.DeserializersManager{},
+		verifier,
+	)
+
+	// The plugin decides even though channel C has no policy manager
+	blockRaw, msg := mockBlock(t, "C", 42, aliceSigner, nil)
+	assert.NoError(t, msgCryptoService.VerifyBlock([]byte("C"), 42, blockRaw))
+	assert.Equal(t, "C", verifier.channelID)
+	assert.Len(t, verifier.signatures, 1)
+	assert.Equal(t, []byte("Alice"), verifier.signatures[0].Identity)
+	assert.Equal(t, msg, verifier.signatures[0].Data)
+
+	verifier.err = errors.New("not signed by a quorum")
+	assert.EqualError(t, msgCryptoService.VerifyBlock([]byte("C"), 42, blockRaw), "not signed by a quorum")
+
+	// The structural checks still run before the plugin
+	verifier.err = nil
+	assert.Error(t, msgCryptoService.VerifyBlock([]byte("C"), 43, blockRaw))
+	blockRaw, _ = mockBlock(t, "C", 42, aliceSigner, []byte{0})
+	assert.Error(t, msgCryptoService.VerifyBlock([]byte("C"), 42, blockRaw))
+}
+
+func TestNewBlockVerifier(t *testing.T) {
+	verifier, err := NewBlockVerifier(nil, nil, &mocks.ChannelPolicyManagerGetter{}, &mocks.DeserializersManager{})
+	assert.NoError(t, err)
+	assert.Nil(t, verifier)
+
+	aliceSigner := &mockscrypto.LocalSigner{Identity: []byte("Alice")}
+	policyManagerGetter := &mocks.ChannelPolicyManagerGetterWithManager{
+		Managers: map[string]policies.Manager{
+			"C": &mocks.ChannelPolicyManager{
+				Policy: &mocks.Policy{Deserializer: &mocks.IdentityDeserializer{Identity: []byte("Alice"), Mock: mock.Mock{}}},
+			},
+		},
+	}
+	verifier, err = NewBlockVerifier(&builtin.DefaultBlockVerificationFactory{}, nil, policyManagerGetter, &mocks.DeserializersManager{})
+	assert.NoError(t, err)
+	msgCryptoService := NewMCS(policyManagerGetter, aliceSigner, &mocks.DeserializersManager{}, verifier)
+
+	blockRaw, msg := mockBlock(t, "C", 42, aliceSigner, nil)
+	policyManagerGetter.Managers["C"].(*mocks.ChannelPolicyManager).Policy.(*mocks.Policy).Deserializer.(*mocks.IdentityDeserializer).Msg = msg
+	assert.NoError(t, msgCryptoService.VerifyBlock([]byte("C"), 42, blockRaw))
+
+	blockRaw, _ = mockBlock(t, "D", 42, aliceSigner, nil)
+	err = msgCryptoService.VerifyBlock([]byte("D"), 42, blockRaw)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "could not acquire policy manager for channel D")
+
+	_, err = NewBlockVerifier(&builtin.QuorumBlockVerificationFactory{}, map[string]string{"quorum": "zero"}, policyManagerGetter, &mocks.DeserializersManager{})
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed initializing block verification plugin")
+}
+
+func TestQuorumBlockVerifierReencodedIdentity(t *testing.T) {
+	assert.NoError(t, msptesttools.LoadMSPSetupForTesting())
+	channelID := util.GetTestChainID()
+	policyManagerGetter := &mocks.ChannelPolicyManagerGetterWithManager{
+		Managers: map[string]policies.Manager{
+			channelID: &mocks.ChannelPolicyManager{Policy: &mocks.Policy{Deserializer: mgmt.GetManagerForChain(channelID)}},
+		},
+	}
+	verifier, err := NewBlockVerifier(&builtin.QuorumBlockVerificationFactory{}, map[string]string{"quorum": "2"}, policyManagerGetter, mgmt.NewDeserializersManager())
+	assert.NoError(t, err)
+
+	// the orderer signs the block twice, the second time with its certificate
+	// encoded in another PEM block
+	signer := mgmt.GetLocalSigningIdentityOrPanic()
+	serialized, err := signer.Serialize()
+	assert.NoError(t, err)
+	sID := &pmsp.SerializedIdentity{}
+	assert.NoError(t, proto.Unmarshal(serialized, sID))
+	certBlock, _ := pem.Decode(sID.IdBytes)
+	assert.NotNil(t, certBlock)
+	certBlock.Headers = map[string]string{"Comment": "re-encoded"}
+	reencoded, err := proto.Marshal(&pmsp.SerializedIdentity{Mspid: sID.Mspid, IdBytes: pem.EncodeToMemory(certBlock)})
+	assert.NoError(t, err)
+	assert.NotEqual(t, serialized, reencoded)
+
+	var signatures []*common.SignedData
+	for _, identity := range [][]byte{serialized, reencoded} {
+		data := append([]byte("block header"), identity...)
+		signature, err := signer.Sign(data)
+		assert.NoError(t, err)
+		signatures = append(signatures, &common.SignedData{Data: data, Identity: identity, Signature: signature})
+	}
+	block := common.NewBlock(42, nil)
+	assert.NoError(t, policyManagerGetter.Managers[channelID].(*mocks.ChannelPolicyManager).Policy.Evaluate(signatures[1:]),
+		"the re-encoded identity is valid on its own")
+	assert.EqualError(t, verifier.Verify(channelID, block, signatures), "block [42] on channel [testchainid] is signed by 1 valid orderers, 2 required")
+}
+
+func TestVerifyBatchSignedBlock(t *testing.T) {
+	aliceSigner := &mockscrypto.LocalSigner{Identity: []byte("Alice")}
+	policyManagerGetter := &mocks.ChannelPolicyManagerGetterWithManager{
+		Managers: map[string]policies.Manager{
+			"C": &mocks.ChannelPolicyManager{
+				Policy: &mocks.Policy{Deserializer: &mocks.IdentityDeserializer{Identity: []byte("Alice"), Mock: mock.Mock{}}},
+			},
+		},
+	}
+	msgCryptoService := NewMCS(policyManagerGetter, aliceSigner, &mocks.DeserializersManager{}, nil)
+
+	// two blocks signed in a batch, each verified on its own
+	var blocks []*common.Block
+	var leaves [][]byte
+	for _, number := range []uint64{42, 43} {
+		blockRaw, _ := mockBlock(t, "C", number, aliceSigner, nil)
+		block, err := utils.GetBlockFromBlockBytes(blockRaw)
+		assert.NoError(t, err)
+		block.Metadata.Metadata[common.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&common.Metadata{
+			Value: utils.MarshalOrPanic(&common.LastConfig{Index: 0}),
+		})
+		leaf, err := blocksig.Leaf(block)
+		assert.NoError(t, err)
+		blocks = append(blocks, block)
+		leaves = append(leaves, leaf)
+	}
+	shdr, err := aliceSigner.NewSignatureHeader()
+	assert.NoError(t, err)
+	signature := &common.MetadataSignature{SignatureHeader: utils.MarshalOrPanic(shdr)}
+	msg := blocksig.SignedBytes(blocksig.Root(leaves), signature.SignatureHeader)
+	signature.Signature, err = aliceSigner.Sign(msg)
+	assert.NoError(t, err)
+	policyManagerGetter.Managers["C"].(*mocks.ChannelPolicyManager).Policy.(*mocks.Policy).Deserializer.(*mocks.IdentityDeserializer).Msg = msg
+	for _, block := range blocks {
+		block.Metadata.Metadata[common.BlockMetadataIndex_SIGNATURES] = utils.MarshalOrPanic(&common.Metadata{
+			Value:      blocksig.BatchValue(&common.BlockSignatureBatch{FirstBlock: 42, LeafHashes: leaves}),
+			Signatures: []*common.MetadataSignature{signature},
+		})
+		assert.NoError(t, msgCryptoService.VerifyBlock([]byte("C"), block.Header.Number, utils.MarshalOrPanic(block)))
+	}
+
+	// a block whose last config was tampered with is not part of the batch
+	blocks[1].Metadata.Metadata[common.BlockMetadataIndex_LAST_CONFIG] = utils.MarshalOrPanic(&common.Metadata{
+		Value: utils.MarshalOrPanic(&common.LastConfig{Index: 7}),
+	})
+	err = msgCryptoService.VerifyBlock([]byte("C"), 43, utils.MarshalOrPanic(blocks[1]))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "block 43 does not match its leaf in its signature batch")
+}
+
 func mockBlock(t *testing.T, channel string, seqNum uint64, localSigner crypto.LocalSigner, dataHash []byte) ([]byte, []byte) {
 	block := common.NewBlock(seqNum, nil)
 
@@ -329,6 +502,7 @@ func TestExpiration(t *testing.T) {
 		&mocks.ChannelPolicyManagerGetterWithManager{},
 		&mockscrypto.LocalSigner{Identity: []byte("Yacov")},
 		deserializersManager,
+		nil,
 	)
 
 	// Green path I check the expiration date is as expected
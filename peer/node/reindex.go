@@ -0,0 +1,55 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"fmt"
+
+	"github.com/hyperledger/fabric/core/ledger/ledgerstorage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reindexChannelIDs  []string
+	reindexCompactOnly bool
+)
+
+func reindexCmd() *cobra.Command {
+	flags := nodeReindexCmd.Flags()
+	flags.StringSliceVarP(&reindexChannelIDs, "channelID", "c", nil,
+		"The channels whose block indexes are rebuilt, defaults to all the channels")
+	flags.BoolVarP(&reindexCompactOnly, "compactOnly", "", false,
+		"Only compact the block index database, without rebuilding the indexes")
+
+	return nodeReindexCmd
+}
+
+var nodeReindexCmd = &cobra.Command{
+	Use:   "reindex",
+	Short: "Rebuilds the block indexes of the node.",
+	Long: `Rebuilds the txid, block number and block hash indexes of the block storage from the local block files, ` +
+		`and compacts the index database. The peer must be stopped.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) != 0 {
+			return fmt.Errorf("trailing args detected: %s", args)
+		}
+		// Parsing of the command line is done so silence cmd usage
+		cmd.SilenceUsage = true
+		return reindex()
+	},
+}
+
+func reindex() error {
+	if reindexCompactOnly && len(reindexChannelIDs) > 0 {
+		return fmt.Errorf("the channelID and compactOnly flags are mutually exclusive")
+	}
+	if err := ledgerstorage.RebuildBlockIndexes(reindexCompactOnly, reindexChannelIDs...); err != nil {
+		return err
+	}
+	logger.Info("The block indexes have been rebuilt, the peer can be started")
+	return nil
+}
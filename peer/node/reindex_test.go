@@ -0,0 +1,70 @@
+/*
+Copyright IBM Corp. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package node
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/hyperledger/fabric/common/ledger/testutil"
+	"github.com/hyperledger/fabric/core/ledger"
+	"github.com/hyperledger/fabric/core/ledger/ledgerstorage"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReindexCmd(t *testing.T) {
+	defer viper.Reset()
+	tempDir, err := ioutil.TempDir("", "reindex")
+	require.NoError(t, err)
+	defer os.RemoveAll(tempDir)
+	viper.Set("peer.fileSystemPath", tempDir)
+
+	provider := ledgerstorage.NewProvider()
+	store, err := provider.Open("mychannel")
+	require.NoError(t, err)
+	blocks := testutil.ConstructTestBlocks(t, 3)
+	for _, block := range blocks {
+		require.NoError(t, store.CommitWithPvtData(&ledger.BlockAndPvtData{Block: block}))
+	}
+	store.Shutdown()
+	provider.Close()
+
+	cmd := reindexCmd()
+	defer func() {
+		reindexChannelIDs, reindexCompactOnly = nil, false
+	}()
+
+	cmd.SetArgs([]string{"-c", "mychannel"})
+	assert.NoError(t, cmd.Execute())
+
+	cmd.SetArgs([]string{"-c", "otherchannel"})
+	assert.EqualError(t, cmd.Execute(), "ledger [otherchannel] does not exist")
+
+	cmd.SetArgs([]string{"--compactOnly", "-c", "mychannel"})
+	assert.EqualError(t, cmd.Execute(), "the channelID and compactOnly flags are mutually exclusive")
+
+	reindexChannelIDs = nil
+	cmd.SetArgs([]string{"--compactOnly"})
+	assert.NoError(t, cmd.Execute())
+
+	cmd.SetArgs([]string{"extra"})
+	assert.EqualError(t, cmd.Execute(), "trailing args detected: [extra]")
+
+	provider = ledgerstorage.NewProvider()
+	defer provider.Close()
+	store, err = provider.Open("mychannel")
+	require.NoError(t, err)
+	defer store.Shutdown()
+	for _, block := range blocks {
+		retrievedBlock, err := store.RetrieveBlockByHash(block.Header.Hash())
+		assert.NoError(t, err)
+		assert.Equal(t, block, retrievedBlock)
+	}
+}
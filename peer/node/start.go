@@ -25,6 +25,7 @@ import (
 	"github.com/hyperledger/fabric/common/grpclogging"
 	"github.com/hyperledger/fabric/common/grpcmetrics"
 	"github.com/hyperledger/fabric/common/localmsp"
+	"github.com/hyperledger/fabric/common/memtuning"
 	"github.com/hyperledger/fabric/common/metadata"
 	"github.com/hyperledger/fabric/common/metrics"
 	"github.com/hyperledger/fabric/common/policies"
@@ -45,18 +46,27 @@ import (
 	"github.com/hyperledger/fabric/core/comm"
 	"github.com/hyperledger/fabric/core/committer/txvalidator"
 	"github.com/hyperledger/fabric/core/common/ccprovider"
+	"github.com/hyperledger/fabric/core/common/encryptedtx"
 	"github.com/hyperledger/fabric/core/common/privdata"
+	"github.com/hyperledger/fabric/core/connectors"
 	"github.com/hyperledger/fabric/core/container"
+	"github.com/hyperledger/fabric/core/container/cclogs"
 	"github.com/hyperledger/fabric/core/container/dockercontroller"
 	"github.com/hyperledger/fabric/core/container/inproccontroller"
 	"github.com/hyperledger/fabric/core/endorser"
 	authHandler "github.com/hyperledger/fabric/core/handlers/auth"
+	"github.com/hyperledger/fabric/core/handlers/auth/filter"
+	blockverification "github.com/hyperledger/fabric/core/handlers/blockverification/api"
+	connector "github.com/hyperledger/fabric/core/handlers/connector/api"
 	endorsement2 "github.com/hyperledger/fabric/core/handlers/endorsement/api"
 	endorsement3 "github.com/hyperledger/fabric/core/handlers/endorsement/api/identities"
+	kms "github.com/hyperledger/fabric/core/handlers/kms/api"
 	"github.com/hyperledger/fabric/core/handlers/library"
 	validation "github.com/hyperledger/fabric/core/handlers/validation/api"
 	"github.com/hyperledger/fabric/core/ledger/cceventmgmt"
+	"github.com/hyperledger/fabric/core/ledger/fingerprint"
 	"github.com/hyperledger/fabric/core/ledger/ledgermgmt"
+	"github.com/hyperledger/fabric/core/ledger/statediff"
 	"github.com/hyperledger/fabric/core/operations"
 	"github.com/hyperledger/fabric/core/peer"
 	"github.com/hyperledger/fabric/core/scc"
@@ -85,6 +95,7 @@ import (
 	"github.com/hyperledger/fabric/protos/utils"
 	"github.com/hyperledger/fabric/token/server"
 	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
 	"google.golang.org/grpc"
@@ -141,6 +152,14 @@ func serve(args []string) error {
 
 	logger.Infof("Starting %s", version.GetInfo())
 
+	err := memtuning.Apply(memtuning.Config{
+		GOGC:    viper.GetString("peer.memoryTuning.gogc"),
+		Ballast: viper.GetString("peer.memoryTuning.ballast"),
+	})
+	if err != nil {
+		return errors.WithMessage(err, "failed to apply memory tuning")
+	}
+
 	//startup aclmgmt with default ACL providers (resource based and default 1.0 policies based).
 	//Users can pass in their own ACLProvider to RegisterACLProvider (currently unit tests do this)
 	aclProvider := aclmgmt.NewACLProvider(
@@ -161,7 +180,10 @@ func serve(args []string) error {
 	}
 
 	opsSystem := newOperationsSystem()
-	err := opsSystem.Start()
+	opsSystem.RegisterHandler("/runtime/memory", memtuning.Handler())
+	opsSystem.RegisterHandler("/ledger/fingerprints", fingerprint.NewHandler(fingerprint.GetRegistry()))
+	opsSystem.RegisterHandler("/ledger/state/", statediff.NewHandler(peer.GetLedger, viper.GetBool("ledger.state.allowReplay")))
+	err = opsSystem.Start()
 	if err != nil {
 		return errors.WithMessage(err, "failed to initialize operations subystems")
 	}
@@ -260,7 +282,11 @@ func serve(args []string) error {
 		}
 	}
 
-	abServer := peer.NewDeliverEventsServer(mutualTLS, policyCheckerProvider, &peer.DeliverChainManager{}, metricsProvider)
+	deliverMetrics, err := peer.NewDeliverEventsMetrics(prometheus.DefaultRegisterer)
+	if err != nil {
+		logger.Fatalf("Failed to register deliver events metrics: %s", err)
+	}
+	abServer := peer.NewDeliverEventsServer(mutualTLS, policyCheckerProvider, &peer.DeliverChainManager{}, deliverMetrics)
 	pb.RegisterDeliverServer(peerServer.Server(), abServer)
 
 	// Initialize chaincode service
@@ -288,6 +314,14 @@ func serve(args []string) error {
 	reg := library.InitRegistry(libConf)
 
 	authFilters := reg.Lookup(library.Auth).([]authHandler.Filter)
+	// the limits are enforced first, so that rejecting a proposal is cheap
+	rateLimitFilter := filter.NewRateLimitFilter(filter.RateLimitConfig{
+		MaxConcurrency: viper.GetInt("peer.limits.concurrency.endorserService"),
+		BusyBackoff:    viper.GetDuration("peer.limits.concurrency.busyBackoff"),
+		ClientRate:     viper.GetFloat64("peer.limits.proposalRate.perClient"),
+		ClientBurst:    viper.GetInt("peer.limits.proposalRate.burst"),
+	})
+	authFilters = append([]authHandler.Filter{rateLimitFilter}, authFilters...)
 	endorserSupport := &endorser.SupportImpl{
 		SignerSupport:    signingIdentity,
 		Peer:             peer.Default,
@@ -315,8 +349,29 @@ func serve(args []string) error {
 
 	policyMgr := peer.NewChannelPolicyManagerGetter()
 
+	var blockVerifierParams map[string]string
+	if libConf.BlockVerifier != nil {
+		blockVerifierParams = libConf.BlockVerifier.Parameters
+	}
+	blockVerifierFactory, _ := reg.Lookup(library.BlockVerification).(blockverification.PluginFactory)
+	blockVerifier, err := peergossip.NewBlockVerifier(blockVerifierFactory, blockVerifierParams, policyMgr, mgmt.NewDeserializersManager())
+	if err != nil {
+		return err
+	}
+
+	connectorMgr, err := newConnectorManager(reg, libConf.Connectors)
+	if err != nil {
+		return err
+	}
+	defer connectorMgr.Stop()
+
+	// the encrypted transactions are decrypted while the channels commit their blocks
+	if err := initDecrypter(reg, libConf.KMS); err != nil {
+		return err
+	}
+
 	// Initialize gossip component
-	err = initGossipService(policyMgr, metricsProvider, peerServer, serializedIdentity, peerEndpoint.Address)
+	err = initGossipService(policyMgr, blockVerifier, metricsProvider, peerServer, serializedIdentity, peerEndpoint.Address)
 	if err != nil {
 		return err
 	}
@@ -358,6 +413,7 @@ func serve(args []string) error {
 			logger.Panicf("Failed subscribing to chaincode lifecycle updates")
 		}
 		cceventmgmt.GetMgr().Register(cid, sub)
+		connectorMgr.Start(cid, peer.GetLedger(cid))
 	}, ccp, sccp, txvalidator.MapBasedPluginMapper(validationPluginsByName),
 		pr, deployedCCInfoProvider, membershipInfoProvider, metricsProvider)
 
@@ -661,6 +717,13 @@ func registerChaincodeSupport(
 		logger.Panicf("failed to register docker health check: %s", err)
 	}
 
+	//记录链码容器的输出，开发者无需访问docker即可在运维服务上查看
+	if viper.GetBool("vm.docker.logStreaming.enabled") {
+		chaincodeLogs := cclogs.NewHub(viper.GetInt("vm.docker.logStreaming.historySize"))
+		dockerProvider.ChaincodeLogs = chaincodeLogs
+		ops.RegisterHandler("/chaincode/logs", chaincodeLogs)
+	}
+
 	chaincodeSupport := chaincode.NewChaincodeSupport(
 		chaincode.GlobalConfig(),
 		ccEndpoint,
@@ -844,12 +907,48 @@ func secureDialOpts() []grpc.DialOption {
 	return dialOpts
 }
 
+// newConnectorManager creates the configured connectors, which the registry
+// looks up in the order of their configuration
+func newConnectorManager(reg library.Registry, configs []*library.HandlerConfig) (*connectors.Manager, error) {
+	factories, _ := reg.Lookup(library.Connector).([]connector.SinkFactory)
+	var sinks []*connectors.Connector
+	for i, factory := range factories {
+		name := configs[i].Name
+		if name == "" {
+			name = configs[i].Library
+		}
+		c, err := connectors.NewConnector(name, factory, configs[i].Parameters)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, c)
+	}
+	return connectors.NewManager(sinks), nil
+}
+
+// initDecrypter sets the decrypter of the encrypted transactions out of the
+// configured KMS plugin, if any.  Without it, or without the key of an
+// encrypted transaction, the commit of the block carrying the transaction
+// waits until the peer is able to decrypt it.
+func initDecrypter(reg library.Registry, config *library.HandlerConfig) error {
+	factory, _ := reg.Lookup(library.KMS).(kms.PluginFactory)
+	if factory == nil {
+		return nil
+	}
+	decrypter := factory.New()
+	if err := decrypter.Init(kms.Parameters(config.Parameters)); err != nil {
+		return errors.WithMessage(err, "failed initializing the KMS plugin")
+	}
+	encryptedtx.Initialize(decrypter)
+	return nil
+}
+
 // initGossipService will initialize the gossip service by:
 // 1. Enable TLS if configured;
 // 2. Init the message crypto service;
 // 3. Init the security advisor;
 // 4. Init gossip related struct.
-func initGossipService(policyMgr policies.ChannelPolicyManagerGetter, metricsProvider metrics.Provider,
+func initGossipService(policyMgr policies.ChannelPolicyManagerGetter, blockVerifier blockverification.Plugin, metricsProvider metrics.Provider,
 	peerServer *comm.GRPCServer, serializedIdentity []byte, peerAddr string) error {
 	var certs *gossipcommon.TLSCertificates
 	if peerServer.TLSEnabled() {
@@ -867,6 +966,7 @@ func initGossipService(policyMgr policies.ChannelPolicyManagerGetter, metricsPro
 		policyMgr,
 		localmsp.NewSigner(),
 		mgmt.NewDeserializersManager(),
+		blockVerifier,
 	)
 	secAdv := peergossip.NewSecurityAdvisor(mgmt.NewDeserializersManager())
 	bootstrap := viper.GetStringSlice("peer.gossip.bootstrap")
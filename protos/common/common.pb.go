@@ -61,51 +61,54 @@ func (x Status) String() string {
 	return proto.EnumName(Status_name, int32(x))
 }
 func (Status) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{0}
+	return fileDescriptor_common_12a67838225635c2, []int{0}
 }
 
 type HeaderType int32
 
 const (
-	HeaderType_MESSAGE              HeaderType = 0
-	HeaderType_CONFIG               HeaderType = 1
-	HeaderType_CONFIG_UPDATE        HeaderType = 2
-	HeaderType_ENDORSER_TRANSACTION HeaderType = 3
-	HeaderType_ORDERER_TRANSACTION  HeaderType = 4
-	HeaderType_DELIVER_SEEK_INFO    HeaderType = 5
-	HeaderType_CHAINCODE_PACKAGE    HeaderType = 6
-	HeaderType_PEER_ADMIN_OPERATION HeaderType = 8
-	HeaderType_TOKEN_TRANSACTION    HeaderType = 9
+	HeaderType_MESSAGE               HeaderType = 0
+	HeaderType_CONFIG                HeaderType = 1
+	HeaderType_CONFIG_UPDATE         HeaderType = 2
+	HeaderType_ENDORSER_TRANSACTION  HeaderType = 3
+	HeaderType_ORDERER_TRANSACTION   HeaderType = 4
+	HeaderType_DELIVER_SEEK_INFO     HeaderType = 5
+	HeaderType_CHAINCODE_PACKAGE     HeaderType = 6
+	HeaderType_PEER_ADMIN_OPERATION  HeaderType = 8
+	HeaderType_TOKEN_TRANSACTION     HeaderType = 9
+	HeaderType_ENCRYPTED_TRANSACTION HeaderType = 10
 )
 
 var HeaderType_name = map[int32]string{
-	0: "MESSAGE",
-	1: "CONFIG",
-	2: "CONFIG_UPDATE",
-	3: "ENDORSER_TRANSACTION",
-	4: "ORDERER_TRANSACTION",
-	5: "DELIVER_SEEK_INFO",
-	6: "CHAINCODE_PACKAGE",
-	8: "PEER_ADMIN_OPERATION",
-	9: "TOKEN_TRANSACTION",
+	0:  "MESSAGE",
+	1:  "CONFIG",
+	2:  "CONFIG_UPDATE",
+	3:  "ENDORSER_TRANSACTION",
+	4:  "ORDERER_TRANSACTION",
+	5:  "DELIVER_SEEK_INFO",
+	6:  "CHAINCODE_PACKAGE",
+	8:  "PEER_ADMIN_OPERATION",
+	9:  "TOKEN_TRANSACTION",
+	10: "ENCRYPTED_TRANSACTION",
 }
 var HeaderType_value = map[string]int32{
-	"MESSAGE":              0,
-	"CONFIG":               1,
-	"CONFIG_UPDATE":        2,
-	"ENDORSER_TRANSACTION": 3,
-	"ORDERER_TRANSACTION":  4,
-	"DELIVER_SEEK_INFO":    5,
-	"CHAINCODE_PACKAGE":    6,
-	"PEER_ADMIN_OPERATION": 8,
-	"TOKEN_TRANSACTION":    9,
+	"MESSAGE":               0,
+	"CONFIG":                1,
+	"CONFIG_UPDATE":         2,
+	"ENDORSER_TRANSACTION":  3,
+	"ORDERER_TRANSACTION":   4,
+	"DELIVER_SEEK_INFO":     5,
+	"CHAINCODE_PACKAGE":     6,
+	"PEER_ADMIN_OPERATION":  8,
+	"TOKEN_TRANSACTION":     9,
+	"ENCRYPTED_TRANSACTION": 10,
 }
 
 func (x HeaderType) String() string {
 	return proto.EnumName(HeaderType_name, int32(x))
 }
 func (HeaderType) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{1}
+	return fileDescriptor_common_12a67838225635c2, []int{1}
 }
 
 // This enum enlists indexes of the block metadata array
@@ -116,6 +119,8 @@ const (
 	BlockMetadataIndex_LAST_CONFIG         BlockMetadataIndex = 1
 	BlockMetadataIndex_TRANSACTIONS_FILTER BlockMetadataIndex = 2
 	BlockMetadataIndex_ORDERER             BlockMetadataIndex = 3
+	// e.g. For Kafka, this is where we store the last offset written to the local ledger.
+	BlockMetadataIndex_RANDOM_BEACON BlockMetadataIndex = 4
 )
 
 var BlockMetadataIndex_name = map[int32]string{
@@ -123,19 +128,21 @@ var BlockMetadataIndex_name = map[int32]string{
 	1: "LAST_CONFIG",
 	2: "TRANSACTIONS_FILTER",
 	3: "ORDERER",
+	4: "RANDOM_BEACON",
 }
 var BlockMetadataIndex_value = map[string]int32{
 	"SIGNATURES":          0,
 	"LAST_CONFIG":         1,
 	"TRANSACTIONS_FILTER": 2,
 	"ORDERER":             3,
+	"RANDOM_BEACON":       4,
 }
 
 func (x BlockMetadataIndex) String() string {
 	return proto.EnumName(BlockMetadataIndex_name, int32(x))
 }
 func (BlockMetadataIndex) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{2}
+	return fileDescriptor_common_12a67838225635c2, []int{2}
 }
 
 // LastConfig is the encoded value for the Metadata message which is encoded in the LAST_CONFIGURATION block metadata index
@@ -150,7 +157,7 @@ func (m *LastConfig) Reset()         { *m = LastConfig{} }
 func (m *LastConfig) String() string { return proto.CompactTextString(m) }
 func (*LastConfig) ProtoMessage()    {}
 func (*LastConfig) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{0}
+	return fileDescriptor_common_12a67838225635c2, []int{0}
 }
 func (m *LastConfig) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_LastConfig.Unmarshal(m, b)
@@ -190,7 +197,7 @@ func (m *Metadata) Reset()         { *m = Metadata{} }
 func (m *Metadata) String() string { return proto.CompactTextString(m) }
 func (*Metadata) ProtoMessage()    {}
 func (*Metadata) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{1}
+	return fileDescriptor_common_12a67838225635c2, []int{1}
 }
 func (m *Metadata) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Metadata.Unmarshal(m, b)
@@ -236,7 +243,7 @@ func (m *MetadataSignature) Reset()         { *m = MetadataSignature{} }
 func (m *MetadataSignature) String() string { return proto.CompactTextString(m) }
 func (*MetadataSignature) ProtoMessage()    {}
 func (*MetadataSignature) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{2}
+	return fileDescriptor_common_12a67838225635c2, []int{2}
 }
 func (m *MetadataSignature) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_MetadataSignature.Unmarshal(m, b)
@@ -282,7 +289,7 @@ func (m *Header) Reset()         { *m = Header{} }
 func (m *Header) String() string { return proto.CompactTextString(m) }
 func (*Header) ProtoMessage()    {}
 func (*Header) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{3}
+	return fileDescriptor_common_12a67838225635c2, []int{3}
 }
 func (m *Header) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Header.Unmarshal(m, b)
@@ -345,7 +352,10 @@ type ChannelHeader struct {
 	Extension []byte `protobuf:"bytes,7,opt,name=extension,proto3" json:"extension,omitempty"`
 	// If mutual TLS is employed, this represents
 	// the hash of the client's TLS certificate
-	TlsCertHash          []byte   `protobuf:"bytes,8,opt,name=tls_cert_hash,json=tlsCertHash,proto3" json:"tls_cert_hash,omitempty"`
+	TlsCertHash []byte `protobuf:"bytes,8,opt,name=tls_cert_hash,json=tlsCertHash,proto3" json:"tls_cert_hash,omitempty"`
+	// The visibility labels of the transaction, such as the departments it
+	// belongs to, which the organizational units of its creator must allow
+	Labels               []string `protobuf:"bytes,9,rep,name=labels,proto3" json:"labels,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -355,7 +365,7 @@ func (m *ChannelHeader) Reset()         { *m = ChannelHeader{} }
 func (m *ChannelHeader) String() string { return proto.CompactTextString(m) }
 func (*ChannelHeader) ProtoMessage()    {}
 func (*ChannelHeader) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{4}
+	return fileDescriptor_common_12a67838225635c2, []int{4}
 }
 func (m *ChannelHeader) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ChannelHeader.Unmarshal(m, b)
@@ -431,6 +441,13 @@ func (m *ChannelHeader) GetTlsCertHash() []byte {
 	return nil
 }
 
+func (m *ChannelHeader) GetLabels() []string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
 type SignatureHeader struct {
 	// Creator of the message, a marshaled msp.SerializedIdentity
 	Creator []byte `protobuf:"bytes,1,opt,name=creator,proto3" json:"creator,omitempty"`
@@ -445,7 +462,7 @@ func (m *SignatureHeader) Reset()         { *m = SignatureHeader{} }
 func (m *SignatureHeader) String() string { return proto.CompactTextString(m) }
 func (*SignatureHeader) ProtoMessage()    {}
 func (*SignatureHeader) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{5}
+	return fileDescriptor_common_12a67838225635c2, []int{5}
 }
 func (m *SignatureHeader) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SignatureHeader.Unmarshal(m, b)
@@ -494,7 +511,7 @@ func (m *Payload) Reset()         { *m = Payload{} }
 func (m *Payload) String() string { return proto.CompactTextString(m) }
 func (*Payload) ProtoMessage()    {}
 func (*Payload) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{6}
+	return fileDescriptor_common_12a67838225635c2, []int{6}
 }
 func (m *Payload) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Payload.Unmarshal(m, b)
@@ -543,7 +560,7 @@ func (m *Envelope) Reset()         { *m = Envelope{} }
 func (m *Envelope) String() string { return proto.CompactTextString(m) }
 func (*Envelope) ProtoMessage()    {}
 func (*Envelope) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{7}
+	return fileDescriptor_common_12a67838225635c2, []int{7}
 }
 func (m *Envelope) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Envelope.Unmarshal(m, b)
@@ -577,6 +594,56 @@ func (m *Envelope) GetSignature() []byte {
 	return nil
 }
 
+// EncryptedPayload is the data of the payload of an ENCRYPTED_TRANSACTION, which carries the data
+// of an ENDORSER_TRANSACTION encrypted for a key of the channel, the header being left in the clear
+type EncryptedPayload struct {
+	// The ID of the key the data is encrypted for, as known to the key management service
+	KeyId string `protobuf:"bytes,1,opt,name=key_id,json=keyId,proto3" json:"key_id,omitempty"`
+	// The data of the payload of the endorser transaction, encrypted
+	Ciphertext           []byte   `protobuf:"bytes,2,opt,name=ciphertext,proto3" json:"ciphertext,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *EncryptedPayload) Reset()         { *m = EncryptedPayload{} }
+func (m *EncryptedPayload) String() string { return proto.CompactTextString(m) }
+func (*EncryptedPayload) ProtoMessage()    {}
+func (*EncryptedPayload) Descriptor() ([]byte, []int) {
+	return fileDescriptor_common_12a67838225635c2, []int{8}
+}
+func (m *EncryptedPayload) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EncryptedPayload.Unmarshal(m, b)
+}
+func (m *EncryptedPayload) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EncryptedPayload.Marshal(b, m, deterministic)
+}
+func (dst *EncryptedPayload) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EncryptedPayload.Merge(dst, src)
+}
+func (m *EncryptedPayload) XXX_Size() int {
+	return xxx_messageInfo_EncryptedPayload.Size(m)
+}
+func (m *EncryptedPayload) XXX_DiscardUnknown() {
+	xxx_messageInfo_EncryptedPayload.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EncryptedPayload proto.InternalMessageInfo
+
+func (m *EncryptedPayload) GetKeyId() string {
+	if m != nil {
+		return m.KeyId
+	}
+	return ""
+}
+
+func (m *EncryptedPayload) GetCiphertext() []byte {
+	if m != nil {
+		return m.Ciphertext
+	}
+	return nil
+}
+
 // This is finalized block structure to be shared among the orderer and peer
 // Note that the BlockHeader chains to the previous BlockHeader, and the BlockData hash is embedded
 // in the BlockHeader.  This makes it natural and obvious that the Data is included in the hash, but
@@ -594,7 +661,7 @@ func (m *Block) Reset()         { *m = Block{} }
 func (m *Block) String() string { return proto.CompactTextString(m) }
 func (*Block) ProtoMessage()    {}
 func (*Block) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{8}
+	return fileDescriptor_common_12a67838225635c2, []int{9}
 }
 func (m *Block) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Block.Unmarshal(m, b)
@@ -651,7 +718,7 @@ func (m *BlockHeader) Reset()         { *m = BlockHeader{} }
 func (m *BlockHeader) String() string { return proto.CompactTextString(m) }
 func (*BlockHeader) ProtoMessage()    {}
 func (*BlockHeader) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{9}
+	return fileDescriptor_common_12a67838225635c2, []int{10}
 }
 func (m *BlockHeader) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BlockHeader.Unmarshal(m, b)
@@ -703,7 +770,7 @@ func (m *BlockData) Reset()         { *m = BlockData{} }
 func (m *BlockData) String() string { return proto.CompactTextString(m) }
 func (*BlockData) ProtoMessage()    {}
 func (*BlockData) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{10}
+	return fileDescriptor_common_12a67838225635c2, []int{11}
 }
 func (m *BlockData) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BlockData.Unmarshal(m, b)
@@ -741,7 +808,7 @@ func (m *BlockMetadata) Reset()         { *m = BlockMetadata{} }
 func (m *BlockMetadata) String() string { return proto.CompactTextString(m) }
 func (*BlockMetadata) ProtoMessage()    {}
 func (*BlockMetadata) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{11}
+	return fileDescriptor_common_12a67838225635c2, []int{12}
 }
 func (m *BlockMetadata) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BlockMetadata.Unmarshal(m, b)
@@ -781,7 +848,7 @@ func (m *OrdererBlockMetadata) Reset()         { *m = OrdererBlockMetadata{} }
 func (m *OrdererBlockMetadata) String() string { return proto.CompactTextString(m) }
 func (*OrdererBlockMetadata) ProtoMessage()    {}
 func (*OrdererBlockMetadata) Descriptor() ([]byte, []int) {
-	return fileDescriptor_common_1349c48f9b9adc99, []int{12}
+	return fileDescriptor_common_12a67838225635c2, []int{13}
 }
 func (m *OrdererBlockMetadata) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_OrdererBlockMetadata.Unmarshal(m, b)
@@ -815,6 +882,57 @@ func (m *OrdererBlockMetadata) GetConsenterMetadata() []byte {
 	return nil
 }
 
+// BlockSignatureBatch is the encoded value for the Metadata message which is encoded in the SIGNATURES block
+// metadata index of the blocks the orderer signs in batches, prefixed by "BlockSignatureBatch" and a zero byte to
+// tell it from the values signed along with a block.  Every block of a batch carries the number of the first block
+// of the batch and the leaf hashes of the blocks of the batch, in order, while the signatures of the metadata sign
+// the root of their Merkle tree, so that each block is verified on its own.
+type BlockSignatureBatch struct {
+	FirstBlock           uint64   `protobuf:"varint,1,opt,name=first_block,json=firstBlock,proto3" json:"first_block,omitempty"`
+	LeafHashes           [][]byte `protobuf:"bytes,2,rep,name=leaf_hashes,json=leafHashes,proto3" json:"leaf_hashes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *BlockSignatureBatch) Reset()         { *m = BlockSignatureBatch{} }
+func (m *BlockSignatureBatch) String() string { return proto.CompactTextString(m) }
+func (*BlockSignatureBatch) ProtoMessage()    {}
+func (*BlockSignatureBatch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_common_12a67838225635c2, []int{14}
+}
+func (m *BlockSignatureBatch) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BlockSignatureBatch.Unmarshal(m, b)
+}
+func (m *BlockSignatureBatch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BlockSignatureBatch.Marshal(b, m, deterministic)
+}
+func (dst *BlockSignatureBatch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BlockSignatureBatch.Merge(dst, src)
+}
+func (m *BlockSignatureBatch) XXX_Size() int {
+	return xxx_messageInfo_BlockSignatureBatch.Size(m)
+}
+func (m *BlockSignatureBatch) XXX_DiscardUnknown() {
+	xxx_messageInfo_BlockSignatureBatch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BlockSignatureBatch proto.InternalMessageInfo
+
+func (m *BlockSignatureBatch) GetFirstBlock() uint64 {
+	if m != nil {
+		return m.FirstBlock
+	}
+	return 0
+}
+
+func (m *BlockSignatureBatch) GetLeafHashes() [][]byte {
+	if m != nil {
+		return m.LeafHashes
+	}
+	return nil
+}
+
 func init() {
 	proto.RegisterType((*LastConfig)(nil), "common.LastConfig")
 	proto.RegisterType((*Metadata)(nil), "common.Metadata")
@@ -824,82 +942,91 @@ func init() {
 	proto.RegisterType((*SignatureHeader)(nil), "common.SignatureHeader")
 	proto.RegisterType((*Payload)(nil), "common.Payload")
 	proto.RegisterType((*Envelope)(nil), "common.Envelope")
+	proto.RegisterType((*EncryptedPayload)(nil), "common.EncryptedPayload")
 	proto.RegisterType((*Block)(nil), "common.Block")
 	proto.RegisterType((*BlockHeader)(nil), "common.BlockHeader")
 	proto.RegisterType((*BlockData)(nil), "common.BlockData")
 	proto.RegisterType((*BlockMetadata)(nil), "common.BlockMetadata")
 	proto.RegisterType((*OrdererBlockMetadata)(nil), "common.OrdererBlockMetadata")
+	proto.RegisterType((*BlockSignatureBatch)(nil), "common.BlockSignatureBatch")
 	proto.RegisterEnum("common.Status", Status_name, Status_value)
 	proto.RegisterEnum("common.HeaderType", HeaderType_name, HeaderType_value)
 	proto.RegisterEnum("common.BlockMetadataIndex", BlockMetadataIndex_name, BlockMetadataIndex_value)
 }
 
-func init() { proto.RegisterFile("common/common.proto", fileDescriptor_common_1349c48f9b9adc99) }
-
-var fileDescriptor_common_1349c48f9b9adc99 = []byte{
-	// 1013 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x55, 0x5f, 0x6f, 0xe3, 0xc4,
-	0x17, 0xdd, 0xc4, 0xf9, 0x7b, 0xdd, 0xb4, 0xee, 0xa4, 0xfd, 0xad, 0x7f, 0x85, 0xd5, 0x56, 0x86,
-	0x45, 0xa5, 0x15, 0xa9, 0xe8, 0xbe, 0xc0, 0xa3, 0x63, 0x4f, 0x5b, 0xab, 0xa9, 0x1d, 0xc6, 0xce,
-	0x22, 0x76, 0x91, 0x2c, 0x37, 0x99, 0x26, 0x11, 0x8e, 0x1d, 0xd9, 0x93, 0xaa, 0xe5, 0x95, 0x77,
-	0x84, 0x04, 0xaf, 0x7c, 0x17, 0x1e, 0x11, 0x9f, 0x07, 0xc4, 0x2b, 0x1a, 0x8f, 0xed, 0x26, 0x65,
-	0x25, 0x9e, 0xe2, 0x73, 0xe7, 0xcc, 0xbd, 0x67, 0xee, 0xb9, 0x99, 0x81, 0xee, 0x38, 0x5e, 0x2c,
-	0xe2, 0xe8, 0x54, 0xfc, 0xf4, 0x96, 0x49, 0xcc, 0x62, 0xd4, 0x10, 0xe8, 0xe0, 0xe5, 0x34, 0x8e,
-	0xa7, 0x21, 0x3d, 0xcd, 0xa2, 0x37, 0xab, 0xdb, 0x53, 0x36, 0x5f, 0xd0, 0x94, 0x05, 0x8b, 0xa5,
-	0x20, 0x6a, 0x1a, 0xc0, 0x20, 0x48, 0x99, 0x11, 0x47, 0xb7, 0xf3, 0x29, 0xda, 0x83, 0xfa, 0x3c,
-	0x9a, 0xd0, 0x7b, 0xb5, 0x72, 0x58, 0x39, 0xaa, 0x11, 0x01, 0xb4, 0x77, 0xd0, 0xba, 0xa6, 0x2c,
-	0x98, 0x04, 0x2c, 0xe0, 0x8c, 0xbb, 0x20, 0x5c, 0xd1, 0x8c, 0xb1, 0x45, 0x04, 0x40, 0x5f, 0x02,
-	0xa4, 0xf3, 0x69, 0x14, 0xb0, 0x55, 0x42, 0x53, 0xb5, 0x7a, 0x28, 0x1d, 0xc9, 0x67, 0xff, 0xef,
-	0xe5, 0x8a, 0x8a, 0xbd, 0x6e, 0xc1, 0x20, 0x6b, 0x64, 0xed, 0x5b, 0xd8, 0xfd, 0x17, 0x01, 0x7d,
-	0x0a, 0x4a, 0x49, 0xf1, 0x67, 0x34, 0x98, 0xd0, 0x24, 0x2f, 0xb8, 0x53, 0xc6, 0x2f, 0xb3, 0x30,
-	0xfa, 0x10, 0xda, 0x65, 0x48, 0xad, 0x66, 0x9c, 0xc7, 0x80, 0xf6, 0x16, 0x1a, 0x39, 0xef, 0x15,
-	0x6c, 0x8f, 0x67, 0x41, 0x14, 0xd1, 0x70, 0x33, 0x61, 0x27, 0x8f, 0xe6, 0xb4, 0xf7, 0x55, 0xae,
-	0xbe, 0xb7, 0xb2, 0xf6, 0x43, 0x15, 0x3a, 0xc6, 0xc6, 0x66, 0x04, 0x35, 0xf6, 0xb0, 0x14, 0xbd,
-	0xa9, 0x93, 0xec, 0x1b, 0xa9, 0xd0, 0xbc, 0xa3, 0x49, 0x3a, 0x8f, 0xa3, 0x2c, 0x4f, 0x9d, 0x14,
-	0x10, 0x7d, 0x01, 0xed, 0xd2, 0x0d, 0x55, 0x3a, 0xac, 0x1c, 0xc9, 0x67, 0x07, 0x3d, 0xe1, 0x57,
-	0xaf, 0xf0, 0xab, 0xe7, 0x15, 0x0c, 0xf2, 0x48, 0x46, 0x2f, 0x00, 0x8a, 0xb3, 0xcc, 0x27, 0x6a,
-	0xed, 0xb0, 0x72, 0xd4, 0x26, 0xed, 0x3c, 0x62, 0x4d, 0x50, 0x17, 0xea, 0xec, 0x9e, 0xaf, 0xd4,
-	0xb3, 0x95, 0x1a, 0xbb, 0xb7, 0x26, 0xdc, 0x38, 0xba, 0x8c, 0xc7, 0x33, 0xb5, 0x21, 0xac, 0xcd,
-	0x00, 0xef, 0x1e, 0xbd, 0x67, 0x34, 0xca, 0xf4, 0x35, 0x45, 0xf7, 0xca, 0x00, 0xd2, 0xa0, 0xc3,
-	0xc2, 0xd4, 0x1f, 0xd3, 0x84, 0xf9, 0xb3, 0x20, 0x9d, 0xa9, 0xad, 0x8c, 0x21, 0xb3, 0x30, 0x35,
-	0x68, 0xc2, 0x2e, 0x83, 0x74, 0xa6, 0xe9, 0xb0, 0xe3, 0x3e, 0xb1, 0x44, 0x85, 0xe6, 0x38, 0xa1,
-	0x01, 0x8b, 0x8b, 0x1e, 0x17, 0x90, 0x8b, 0x88, 0xe2, 0x68, 0x5c, 0x18, 0x25, 0x80, 0x86, 0xa1,
-	0x39, 0x0c, 0x1e, 0xc2, 0x38, 0x98, 0xa0, 0x4f, 0xa0, 0xb1, 0xe6, 0x8e, 0x7c, 0xb6, 0x5d, 0x0c,
-	0x91, 0x48, 0x4d, 0xf2, 0x55, 0xde, 0x69, 0x3e, 0x31, 0x79, 0x9e, 0xec, 0x5b, 0xeb, 0x43, 0x0b,
-	0x47, 0x77, 0x34, 0x8c, 0x45, 0xd7, 0x97, 0x22, 0x65, 0x21, 0x21, 0x87, 0xff, 0x31, 0x2f, 0x3f,
-	0x56, 0xa0, 0xde, 0x0f, 0xe3, 0xf1, 0x77, 0xe8, 0xe4, 0x89, 0x92, 0x6e, 0xa1, 0x24, 0x5b, 0x7e,
-	0x22, 0xe7, 0xd5, 0x9a, 0x1c, 0xf9, 0x6c, 0x77, 0x83, 0x6a, 0x06, 0x2c, 0x10, 0x0a, 0xd1, 0xe7,
-	0xd0, 0x5a, 0xe4, 0xb3, 0x9e, 0x1b, 0xbe, 0xbf, 0x41, 0x2d, 0xfe, 0x08, 0xa4, 0xa4, 0x69, 0x53,
-	0x90, 0xd7, 0x0a, 0xa2, 0xff, 0x41, 0x23, 0x5a, 0x2d, 0x6e, 0x72, 0x55, 0x35, 0x92, 0x23, 0xf4,
-	0x11, 0x74, 0x96, 0x09, 0xbd, 0x9b, 0xc7, 0xab, 0x54, 0x38, 0x25, 0x4e, 0xb6, 0x55, 0x04, 0xb9,
-	0x55, 0xe8, 0x03, 0x68, 0xf3, 0x9c, 0x82, 0x20, 0x65, 0x84, 0x16, 0x0f, 0x64, 0x3e, 0xbe, 0x84,
-	0x76, 0x29, 0xb7, 0x6c, 0x6f, 0xe5, 0x50, 0x2a, 0xdb, 0x7b, 0x02, 0x9d, 0x0d, 0x91, 0xe8, 0x60,
-	0xed, 0x34, 0x82, 0xf8, 0x28, 0xfb, 0x7b, 0xd8, 0x73, 0x92, 0x09, 0x4d, 0x68, 0xb2, 0xb9, 0xe7,
-	0x35, 0xc8, 0x61, 0x90, 0x32, 0x7f, 0x9c, 0xdd, 0x37, 0x79, 0x6b, 0x51, 0xd1, 0x84, 0xc7, 0x9b,
-	0x88, 0x40, 0xf8, 0x78, 0x2b, 0x7d, 0x06, 0x68, 0x1c, 0x47, 0x29, 0x8d, 0x18, 0x4d, 0xfc, 0xb2,
-	0xa4, 0x38, 0xe1, 0x6e, 0xb9, 0x52, 0xd4, 0x38, 0xfe, 0xad, 0x02, 0x0d, 0x97, 0x05, 0x6c, 0x95,
-	0x22, 0x19, 0x9a, 0x23, 0xfb, 0xca, 0x76, 0xbe, 0xb6, 0x95, 0x67, 0x68, 0x0b, 0x9a, 0xee, 0xc8,
-	0x30, 0xb0, 0xeb, 0x2a, 0xbf, 0x57, 0x90, 0x02, 0x72, 0x5f, 0x37, 0x7d, 0x82, 0xbf, 0x1a, 0x61,
-	0xd7, 0x53, 0x7e, 0x92, 0xd0, 0x36, 0xb4, 0xcf, 0x1d, 0xd2, 0xb7, 0x4c, 0x13, 0xdb, 0xca, 0xcf,
-	0x19, 0xb6, 0x1d, 0xcf, 0x3f, 0x77, 0x46, 0xb6, 0xa9, 0xfc, 0x22, 0xa1, 0x17, 0xa0, 0xe6, 0x6c,
-	0x1f, 0xdb, 0x9e, 0xe5, 0x7d, 0xe3, 0x7b, 0x8e, 0xe3, 0x0f, 0x74, 0x72, 0x81, 0x95, 0x5f, 0x25,
-	0x74, 0x00, 0xfb, 0x96, 0xed, 0x61, 0x62, 0xeb, 0x03, 0xdf, 0xc5, 0xe4, 0x0d, 0x26, 0x3e, 0x26,
-	0xc4, 0x21, 0xca, 0x9f, 0x12, 0xda, 0x83, 0x1d, 0x9e, 0xca, 0xba, 0x1e, 0x0e, 0xf0, 0x35, 0xb6,
-	0x3d, 0x6c, 0x2a, 0x7f, 0x49, 0x48, 0x85, 0x2e, 0x27, 0x5a, 0x06, 0xf6, 0x47, 0xb6, 0xfe, 0x46,
-	0xb7, 0x06, 0x7a, 0x7f, 0x80, 0x95, 0xbf, 0xa5, 0xe3, 0x3f, 0x2a, 0x00, 0xc2, 0x71, 0x8f, 0xdf,
-	0x21, 0x32, 0x34, 0xaf, 0xb1, 0xeb, 0xea, 0x17, 0x58, 0x79, 0x86, 0x00, 0x1a, 0x86, 0x63, 0x9f,
-	0x5b, 0x17, 0x4a, 0x05, 0xed, 0x42, 0x47, 0x7c, 0xfb, 0xa3, 0xa1, 0xa9, 0x7b, 0x58, 0xa9, 0x22,
-	0x15, 0xf6, 0xb0, 0x6d, 0x3a, 0xc4, 0xc5, 0xc4, 0xf7, 0x88, 0x6e, 0xbb, 0xba, 0xe1, 0x59, 0x8e,
-	0xad, 0x48, 0xe8, 0x39, 0x74, 0x1d, 0x62, 0x62, 0xf2, 0x64, 0xa1, 0x86, 0xf6, 0x61, 0xd7, 0xc4,
-	0x03, 0x8b, 0x2b, 0x76, 0x31, 0xbe, 0xf2, 0x2d, 0xfb, 0xdc, 0x51, 0xea, 0x3c, 0x6c, 0x5c, 0xea,
-	0x96, 0x6d, 0x38, 0x26, 0xf6, 0x87, 0xba, 0x71, 0xc5, 0xeb, 0x37, 0x78, 0x81, 0x21, 0xc6, 0xc4,
-	0xd7, 0xcd, 0x6b, 0xcb, 0xf6, 0x9d, 0x21, 0x26, 0x7a, 0x96, 0xa7, 0xc5, 0x37, 0x78, 0xce, 0x15,
-	0xb6, 0x37, 0xd2, 0xb7, 0x8f, 0xdf, 0x01, 0xda, 0x18, 0x02, 0x8b, 0x3f, 0x2a, 0x68, 0x1b, 0xc0,
-	0xb5, 0x2e, 0x6c, 0xdd, 0x1b, 0x11, 0xec, 0x2a, 0xcf, 0xd0, 0x0e, 0xc8, 0x03, 0xdd, 0xf5, 0xfc,
-	0xf2, 0x6c, 0xcf, 0xa1, 0xbb, 0x96, 0xc7, 0xf5, 0xcf, 0xad, 0x81, 0x87, 0x89, 0x52, 0xe5, 0xdd,
-	0xc8, 0xcf, 0xa1, 0x48, 0x7d, 0x17, 0x3e, 0x8e, 0x93, 0x69, 0x6f, 0xf6, 0xb0, 0xa4, 0x49, 0x48,
-	0x27, 0x53, 0x9a, 0xf4, 0x6e, 0x83, 0x9b, 0x64, 0x3e, 0x16, 0x57, 0x68, 0x9a, 0x8f, 0xd6, 0xdb,
-	0x93, 0xe9, 0x9c, 0xcd, 0x56, 0x37, 0x1c, 0x9e, 0xae, 0x91, 0x4f, 0x05, 0x59, 0xbc, 0x8f, 0x69,
-	0xfe, 0x86, 0xde, 0x34, 0x32, 0xf8, 0xfa, 0x9f, 0x00, 0x00, 0x00, 0xff, 0xff, 0xd4, 0xcc, 0xf9,
-	0x76, 0x5b, 0x07, 0x00, 0x00,
+func init() { proto.RegisterFile("common/common.proto", fileDescriptor_common_12a67838225635c2) }
+
+var fileDescriptor_common_12a67838225635c2 = []byte{
+	// 1136 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x56, 0xdf, 0x6e, 0xe3, 0xc4,
+	0x17, 0xde, 0xd4, 0x4d, 0xd2, 0x1c, 0xf7, 0x8f, 0x3b, 0x69, 0x7f, 0xeb, 0xed, 0x8f, 0xa5, 0x95,
+	0x61, 0x51, 0xd9, 0x15, 0xa9, 0xe8, 0xde, 0xc0, 0xa5, 0x63, 0x4f, 0x5b, 0xab, 0xa9, 0x1d, 0xc6,
+	0xce, 0xae, 0x76, 0x41, 0xb2, 0x1c, 0x67, 0x9a, 0x44, 0x75, 0xed, 0xc8, 0x9e, 0x54, 0x0d, 0x0f,
+	0x81, 0x90, 0xe0, 0x0e, 0xf1, 0x2e, 0x5c, 0xf2, 0x22, 0xbc, 0x01, 0x88, 0x5b, 0x34, 0x1e, 0xdb,
+	0x4d, 0xca, 0x4a, 0x5c, 0x65, 0xce, 0x77, 0xbe, 0x39, 0xff, 0xbe, 0x93, 0x49, 0xa0, 0x1d, 0x26,
+	0xb7, 0xb7, 0x49, 0x7c, 0x22, 0x3e, 0x3a, 0xb3, 0x34, 0x61, 0x09, 0x6a, 0x08, 0xeb, 0xe0, 0x70,
+	0x9c, 0x24, 0xe3, 0x88, 0x9e, 0xe4, 0xe8, 0x70, 0x7e, 0x7d, 0xc2, 0xa6, 0xb7, 0x34, 0x63, 0xc1,
+	0xed, 0x4c, 0x10, 0x35, 0x0d, 0xa0, 0x17, 0x64, 0xcc, 0x48, 0xe2, 0xeb, 0xe9, 0x18, 0xed, 0x41,
+	0x7d, 0x1a, 0x8f, 0xe8, 0xbd, 0x5a, 0x3b, 0xaa, 0x1d, 0xaf, 0x13, 0x61, 0x68, 0xdf, 0xc2, 0xc6,
+	0x15, 0x65, 0xc1, 0x28, 0x60, 0x01, 0x67, 0xdc, 0x05, 0xd1, 0x9c, 0xe6, 0x8c, 0x4d, 0x22, 0x0c,
+	0xf4, 0x35, 0x40, 0x36, 0x1d, 0xc7, 0x01, 0x9b, 0xa7, 0x34, 0x53, 0xd7, 0x8e, 0xa4, 0x63, 0xf9,
+	0xf4, 0x59, 0xa7, 0xa8, 0xa8, 0xbc, 0xeb, 0x96, 0x0c, 0xb2, 0x44, 0xd6, 0xbe, 0x83, 0xdd, 0x7f,
+	0x11, 0xd0, 0xe7, 0xa0, 0x54, 0x14, 0x7f, 0x42, 0x83, 0x11, 0x4d, 0x8b, 0x84, 0x3b, 0x15, 0x7e,
+	0x91, 0xc3, 0xe8, 0x23, 0x68, 0x55, 0x90, 0xba, 0x96, 0x73, 0x1e, 0x00, 0xed, 0x3d, 0x34, 0x0a,
+	0xde, 0x0b, 0xd8, 0x0e, 0x27, 0x41, 0x1c, 0xd3, 0x68, 0x35, 0xe0, 0x56, 0x81, 0x16, 0xb4, 0x0f,
+	0x65, 0x5e, 0xfb, 0x60, 0x66, 0xed, 0x97, 0x35, 0xd8, 0x32, 0x56, 0x2e, 0x23, 0x58, 0x67, 0x8b,
+	0x99, 0x98, 0x4d, 0x9d, 0xe4, 0x67, 0xa4, 0x42, 0xf3, 0x8e, 0xa6, 0xd9, 0x34, 0x89, 0xf3, 0x38,
+	0x75, 0x52, 0x9a, 0xe8, 0x2b, 0x68, 0x55, 0x6a, 0xa8, 0xd2, 0x51, 0xed, 0x58, 0x3e, 0x3d, 0xe8,
+	0x08, 0xbd, 0x3a, 0xa5, 0x5e, 0x1d, 0xaf, 0x64, 0x90, 0x07, 0x32, 0x7a, 0x0e, 0x50, 0xf6, 0x32,
+	0x1d, 0xa9, 0xeb, 0x47, 0xb5, 0xe3, 0x16, 0x69, 0x15, 0x88, 0x35, 0x42, 0x6d, 0xa8, 0xb3, 0x7b,
+	0xee, 0xa9, 0xe7, 0x9e, 0x75, 0x76, 0x6f, 0x8d, 0xb8, 0x70, 0x74, 0x96, 0x84, 0x13, 0xb5, 0x21,
+	0xa4, 0xcd, 0x0d, 0x3e, 0x3d, 0x7a, 0xcf, 0x68, 0x9c, 0xd7, 0xd7, 0x14, 0xd3, 0xab, 0x00, 0xa4,
+	0xc1, 0x16, 0x8b, 0x32, 0x3f, 0xa4, 0x29, 0xf3, 0x27, 0x41, 0x36, 0x51, 0x37, 0x72, 0x86, 0xcc,
+	0xa2, 0xcc, 0xa0, 0x29, 0xbb, 0x08, 0xb2, 0x09, 0xfa, 0x1f, 0x34, 0xa2, 0x60, 0x48, 0xa3, 0x4c,
+	0x6d, 0x1d, 0x49, 0xc7, 0x2d, 0x52, 0x58, 0x9a, 0x0e, 0x3b, 0xee, 0x23, 0xa9, 0x54, 0x68, 0x86,
+	0x29, 0x0d, 0x58, 0x52, 0xce, 0xbe, 0x34, 0x79, 0x71, 0x71, 0x12, 0x87, 0xa5, 0x80, 0xc2, 0xd0,
+	0x30, 0x34, 0xfb, 0xc1, 0x22, 0x4a, 0x82, 0x11, 0xfa, 0x0c, 0x1a, 0x4b, 0xaa, 0xc9, 0xa7, 0xdb,
+	0xe5, 0x72, 0x89, 0xd0, 0xa4, 0x31, 0xa9, 0x14, 0xe0, 0x9b, 0x54, 0xc4, 0xc9, 0xcf, 0x5a, 0x17,
+	0x36, 0x70, 0x7c, 0x47, 0xa3, 0x44, 0xa8, 0x31, 0x13, 0x21, 0xcb, 0x12, 0x0a, 0xf3, 0x3f, 0xf6,
+	0xc8, 0x02, 0x05, 0xc7, 0x61, 0xba, 0x98, 0x31, 0x3a, 0x2a, 0x6b, 0xda, 0x87, 0xc6, 0x0d, 0x5d,
+	0xf8, 0x53, 0x11, 0xaa, 0x45, 0xea, 0x37, 0x74, 0x61, 0x8d, 0xd0, 0xc7, 0x00, 0xe1, 0x74, 0x36,
+	0xa1, 0x29, 0xa3, 0xf7, 0xac, 0x88, 0xb4, 0x84, 0x68, 0x3f, 0xd4, 0xa0, 0xde, 0x8d, 0x92, 0xf0,
+	0x06, 0xbd, 0x7a, 0xd4, 0x54, 0xbb, 0x6c, 0x2a, 0x77, 0x3f, 0xea, 0xec, 0xc5, 0x52, 0x67, 0xf2,
+	0xe9, 0xee, 0x0a, 0xd5, 0x0c, 0x58, 0x20, 0x9a, 0x45, 0x5f, 0xc2, 0xc6, 0x6d, 0xf1, 0x75, 0x2a,
+	0x76, 0x6a, 0x7f, 0x85, 0x5a, 0x7e, 0xd7, 0x48, 0x45, 0xd3, 0xc6, 0x20, 0x2f, 0x25, 0xe4, 0x82,
+	0xc6, 0xf3, 0xdb, 0x61, 0x51, 0xd5, 0x3a, 0x29, 0x2c, 0xf4, 0x09, 0x6c, 0xcd, 0x52, 0x7a, 0x37,
+	0x4d, 0xe6, 0x99, 0x58, 0x06, 0xd1, 0xda, 0x66, 0x09, 0xe6, 0xdb, 0xf0, 0x7f, 0x68, 0xf1, 0x98,
+	0x82, 0x20, 0xe5, 0x84, 0x0d, 0x0e, 0x70, 0xa7, 0x76, 0x08, 0xad, 0xaa, 0xdc, 0x4a, 0xa9, 0xda,
+	0x91, 0x54, 0x29, 0xf5, 0x0a, 0xb6, 0x56, 0x8a, 0x44, 0x07, 0x4b, 0xdd, 0x08, 0xe2, 0x43, 0xd9,
+	0xdf, 0xc3, 0x9e, 0x93, 0x8e, 0x68, 0x4a, 0xd3, 0xd5, 0x3b, 0xaf, 0x41, 0x8e, 0x82, 0x8c, 0xf9,
+	0x61, 0xfe, 0xa4, 0x15, 0xa3, 0x45, 0xe5, 0x10, 0x1e, 0x1e, 0x3b, 0x02, 0x51, 0x75, 0x46, 0x5f,
+	0x00, 0x0a, 0x93, 0x38, 0xa3, 0x31, 0xa3, 0xa9, 0x5f, 0xa5, 0x14, 0x1d, 0xee, 0x56, 0x9e, 0x32,
+	0x87, 0xf6, 0x16, 0xda, 0x79, 0xd2, 0x6a, 0xc3, 0xbb, 0x01, 0x0b, 0x27, 0xe8, 0x10, 0xe4, 0xeb,
+	0x69, 0x9a, 0x31, 0x7f, 0xc8, 0x9d, 0xc5, 0xfc, 0x20, 0x87, 0x84, 0xe2, 0x87, 0x20, 0x47, 0x34,
+	0xb8, 0xce, 0xc7, 0x53, 0x3c, 0x94, 0x9b, 0x04, 0x38, 0x74, 0x91, 0x23, 0x2f, 0x7f, 0xab, 0x41,
+	0xc3, 0x65, 0x01, 0x9b, 0x67, 0x48, 0x86, 0xe6, 0xc0, 0xbe, 0xb4, 0x9d, 0xb7, 0xb6, 0xf2, 0x04,
+	0x6d, 0x42, 0xd3, 0x1d, 0x18, 0x06, 0x76, 0x5d, 0xe5, 0xf7, 0x1a, 0x52, 0x40, 0xee, 0xea, 0xa6,
+	0x4f, 0xf0, 0x37, 0x03, 0xec, 0x7a, 0xca, 0x8f, 0x12, 0xda, 0x86, 0xd6, 0x99, 0x43, 0xba, 0x96,
+	0x69, 0x62, 0x5b, 0xf9, 0x29, 0xb7, 0x6d, 0xc7, 0xf3, 0xcf, 0x9c, 0x81, 0x6d, 0x2a, 0x3f, 0x4b,
+	0xe8, 0x39, 0xa8, 0x05, 0xdb, 0xc7, 0xb6, 0x67, 0x79, 0xef, 0x7c, 0xcf, 0x71, 0xfc, 0x9e, 0x4e,
+	0xce, 0xb1, 0xf2, 0xab, 0x84, 0x0e, 0x60, 0xdf, 0xb2, 0x3d, 0x4c, 0x6c, 0xbd, 0xe7, 0xbb, 0x98,
+	0xbc, 0xc1, 0xc4, 0xc7, 0x84, 0x38, 0x44, 0xf9, 0x53, 0x42, 0x7b, 0xb0, 0xc3, 0x43, 0x59, 0x57,
+	0xfd, 0x1e, 0xbe, 0xc2, 0xb6, 0x87, 0x4d, 0xe5, 0x2f, 0x09, 0xa9, 0xd0, 0xe6, 0x44, 0xcb, 0xc0,
+	0xfe, 0xc0, 0xd6, 0xdf, 0xe8, 0x56, 0x4f, 0xef, 0xf6, 0xb0, 0xf2, 0xb7, 0xf4, 0xf2, 0x8f, 0x1a,
+	0x80, 0x58, 0x25, 0x8f, 0xbf, 0x7f, 0x32, 0x34, 0xaf, 0xb0, 0xeb, 0xea, 0xe7, 0x58, 0x79, 0x82,
+	0x00, 0x1a, 0x86, 0x63, 0x9f, 0x59, 0xe7, 0x4a, 0x0d, 0xed, 0xc2, 0x96, 0x38, 0xfb, 0x83, 0xbe,
+	0xa9, 0x7b, 0x58, 0x59, 0x43, 0x2a, 0xec, 0x61, 0xdb, 0x74, 0x88, 0x8b, 0x89, 0xef, 0x11, 0xdd,
+	0x76, 0x75, 0xc3, 0xb3, 0x1c, 0x5b, 0x91, 0xd0, 0x53, 0x68, 0x3b, 0xc4, 0xc4, 0xe4, 0x91, 0x63,
+	0x1d, 0xed, 0xc3, 0xae, 0x89, 0x7b, 0x16, 0xaf, 0xd8, 0xc5, 0xf8, 0xd2, 0xb7, 0xec, 0x33, 0x47,
+	0xa9, 0x73, 0xd8, 0xb8, 0xd0, 0x2d, 0xdb, 0x70, 0x4c, 0xec, 0xf7, 0x75, 0xe3, 0x92, 0xe7, 0x6f,
+	0xf0, 0x04, 0x7d, 0x8c, 0x89, 0xaf, 0x9b, 0x57, 0x96, 0xed, 0x3b, 0x7d, 0x4c, 0xf4, 0x3c, 0xce,
+	0x06, 0xbf, 0xe0, 0x39, 0x97, 0xd8, 0x5e, 0x09, 0xdf, 0x42, 0xcf, 0x60, 0x1f, 0xdb, 0x06, 0x79,
+	0xd7, 0xf7, 0xb0, 0xb9, 0xe2, 0x82, 0x97, 0x31, 0xa0, 0x95, 0xc5, 0xb3, 0xf8, 0x6f, 0x25, 0xda,
+	0x06, 0x70, 0xad, 0x73, 0x5b, 0xf7, 0x06, 0x04, 0xbb, 0xca, 0x13, 0xb4, 0x03, 0x72, 0x4f, 0x77,
+	0x3d, 0xbf, 0x6a, 0xfb, 0x29, 0xb4, 0x97, 0xe2, 0xb8, 0xfe, 0x99, 0xd5, 0xf3, 0x30, 0x51, 0xd6,
+	0xf8, 0xa0, 0x8a, 0x16, 0x15, 0x89, 0x0f, 0x87, 0xe8, 0xb6, 0xe9, 0x5c, 0xf9, 0x5d, 0xac, 0x1b,
+	0xbc, 0xd3, 0xae, 0x0b, 0x9f, 0x26, 0xe9, 0xb8, 0x33, 0x59, 0xcc, 0x68, 0x1a, 0xd1, 0xd1, 0x98,
+	0xa6, 0x9d, 0xeb, 0x60, 0x98, 0x4e, 0x43, 0xf1, 0x63, 0x91, 0x15, 0x1b, 0xfe, 0xfe, 0xd5, 0x78,
+	0xca, 0x26, 0xf3, 0x21, 0x37, 0x4f, 0x96, 0xc8, 0x27, 0x82, 0x2c, 0xfe, 0x09, 0x64, 0xc5, 0xbf,
+	0x85, 0x61, 0x23, 0x37, 0x5f, 0xff, 0x33, 0x00, 0x96, 0x04, 0x36, 0x49, 0x45, 0x08, 0x00, 0x00,
 }
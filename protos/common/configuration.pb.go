@@ -32,7 +32,7 @@ func (m *HashingAlgorithm) Reset()         { *m = HashingAlgorithm{} }
 func (m *HashingAlgorithm) String() string { return proto.CompactTextString(m) }
 func (*HashingAlgorithm) ProtoMessage()    {}
 func (*HashingAlgorithm) Descriptor() ([]byte, []int) {
-	return fileDescriptor_configuration_c60fbe5ebb3de531, []int{0}
+	return fileDescriptor_configuration_e6a42ec3432d53c6, []int{0}
 }
 func (m *HashingAlgorithm) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_HashingAlgorithm.Unmarshal(m, b)
@@ -74,7 +74,7 @@ func (m *BlockDataHashingStructure) Reset()         { *m = BlockDataHashingStruc
 func (m *BlockDataHashingStructure) String() string { return proto.CompactTextString(m) }
 func (*BlockDataHashingStructure) ProtoMessage()    {}
 func (*BlockDataHashingStructure) Descriptor() ([]byte, []int) {
-	return fileDescriptor_configuration_c60fbe5ebb3de531, []int{1}
+	return fileDescriptor_configuration_e6a42ec3432d53c6, []int{1}
 }
 func (m *BlockDataHashingStructure) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BlockDataHashingStructure.Unmarshal(m, b)
@@ -114,7 +114,7 @@ func (m *OrdererAddresses) Reset()         { *m = OrdererAddresses{} }
 func (m *OrdererAddresses) String() string { return proto.CompactTextString(m) }
 func (*OrdererAddresses) ProtoMessage()    {}
 func (*OrdererAddresses) Descriptor() ([]byte, []int) {
-	return fileDescriptor_configuration_c60fbe5ebb3de531, []int{2}
+	return fileDescriptor_configuration_e6a42ec3432d53c6, []int{2}
 }
 func (m *OrdererAddresses) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_OrdererAddresses.Unmarshal(m, b)
@@ -141,6 +141,104 @@ func (m *OrdererAddresses) GetAddresses() []string {
 	return nil
 }
 
+// OrdererEndpoints is encoded into the configuration transaction of an orderer organization as a
+// configuration item with a Key of "OrdererEndpoints" and a Value of OrdererEndpoints as marshaled
+// protobuf bytes.  When present it takes precedence over the channel wide OrdererAddresses for
+// the orderers of that organization.
+type OrdererEndpoints struct {
+	Endpoints            []*OrdererEndpoint `protobuf:"bytes,1,rep,name=endpoints,proto3" json:"endpoints,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *OrdererEndpoints) Reset()         { *m = OrdererEndpoints{} }
+func (m *OrdererEndpoints) String() string { return proto.CompactTextString(m) }
+func (*OrdererEndpoints) ProtoMessage()    {}
+func (*OrdererEndpoints) Descriptor() ([]byte, []int) {
+	return fileDescriptor_configuration_e6a42ec3432d53c6, []int{3}
+}
+func (m *OrdererEndpoints) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OrdererEndpoints.Unmarshal(m, b)
+}
+func (m *OrdererEndpoints) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OrdererEndpoints.Marshal(b, m, deterministic)
+}
+func (dst *OrdererEndpoints) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OrdererEndpoints.Merge(dst, src)
+}
+func (m *OrdererEndpoints) XXX_Size() int {
+	return xxx_messageInfo_OrdererEndpoints.Size(m)
+}
+func (m *OrdererEndpoints) XXX_DiscardUnknown() {
+	xxx_messageInfo_OrdererEndpoints.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OrdererEndpoints proto.InternalMessageInfo
+
+func (m *OrdererEndpoints) GetEndpoints() []*OrdererEndpoint {
+	if m != nil {
+		return m.Endpoints
+	}
+	return nil
+}
+
+// OrdererEndpoint is the address of an orderer along with the region it is deployed in and its
+// priority, lower values being preferred, among the orderers of that region
+type OrdererEndpoint struct {
+	Address              string   `protobuf:"bytes,1,opt,name=address,proto3" json:"address,omitempty"`
+	Region               string   `protobuf:"bytes,2,opt,name=region,proto3" json:"region,omitempty"`
+	Priority             uint32   `protobuf:"varint,3,opt,name=priority,proto3" json:"priority,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *OrdererEndpoint) Reset()         { *m = OrdererEndpoint{} }
+func (m *OrdererEndpoint) String() string { return proto.CompactTextString(m) }
+func (*OrdererEndpoint) ProtoMessage()    {}
+func (*OrdererEndpoint) Descriptor() ([]byte, []int) {
+	return fileDescriptor_configuration_e6a42ec3432d53c6, []int{4}
+}
+func (m *OrdererEndpoint) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_OrdererEndpoint.Unmarshal(m, b)
+}
+func (m *OrdererEndpoint) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_OrdererEndpoint.Marshal(b, m, deterministic)
+}
+func (dst *OrdererEndpoint) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_OrdererEndpoint.Merge(dst, src)
+}
+func (m *OrdererEndpoint) XXX_Size() int {
+	return xxx_messageInfo_OrdererEndpoint.Size(m)
+}
+func (m *OrdererEndpoint) XXX_DiscardUnknown() {
+	xxx_messageInfo_OrdererEndpoint.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_OrdererEndpoint proto.InternalMessageInfo
+
+func (m *OrdererEndpoint) GetAddress() string {
+	if m != nil {
+		return m.Address
+	}
+	return ""
+}
+
+func (m *OrdererEndpoint) GetRegion() string {
+	if m != nil {
+		return m.Region
+	}
+	return ""
+}
+
+func (m *OrdererEndpoint) GetPriority() uint32 {
+	if m != nil {
+		return m.Priority
+	}
+	return 0
+}
+
 // Consortium represents the consortium context in which the channel was created
 type Consortium struct {
 	Name                 string   `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
@@ -153,7 +251,7 @@ func (m *Consortium) Reset()         { *m = Consortium{} }
 func (m *Consortium) String() string { return proto.CompactTextString(m) }
 func (*Consortium) ProtoMessage()    {}
 func (*Consortium) Descriptor() ([]byte, []int) {
-	return fileDescriptor_configuration_c60fbe5ebb3de531, []int{3}
+	return fileDescriptor_configuration_e6a42ec3432d53c6, []int{5}
 }
 func (m *Consortium) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Consortium.Unmarshal(m, b)
@@ -221,7 +319,7 @@ func (m *Capabilities) Reset()         { *m = Capabilities{} }
 func (m *Capabilities) String() string { return proto.CompactTextString(m) }
 func (*Capabilities) ProtoMessage()    {}
 func (*Capabilities) Descriptor() ([]byte, []int) {
-	return fileDescriptor_configuration_c60fbe5ebb3de531, []int{4}
+	return fileDescriptor_configuration_e6a42ec3432d53c6, []int{6}
 }
 func (m *Capabilities) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Capabilities.Unmarshal(m, b)
@@ -262,7 +360,7 @@ func (m *Capability) Reset()         { *m = Capability{} }
 func (m *Capability) String() string { return proto.CompactTextString(m) }
 func (*Capability) ProtoMessage()    {}
 func (*Capability) Descriptor() ([]byte, []int) {
-	return fileDescriptor_configuration_c60fbe5ebb3de531, []int{5}
+	return fileDescriptor_configuration_e6a42ec3432d53c6, []int{7}
 }
 func (m *Capability) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_Capability.Unmarshal(m, b)
@@ -286,6 +384,8 @@ func init() {
 	proto.RegisterType((*HashingAlgorithm)(nil), "common.HashingAlgorithm")
 	proto.RegisterType((*BlockDataHashingStructure)(nil), "common.BlockDataHashingStructure")
 	proto.RegisterType((*OrdererAddresses)(nil), "common.OrdererAddresses")
+	proto.RegisterType((*OrdererEndpoints)(nil), "common.OrdererEndpoints")
+	proto.RegisterType((*OrdererEndpoint)(nil), "common.OrdererEndpoint")
 	proto.RegisterType((*Consortium)(nil), "common.Consortium")
 	proto.RegisterType((*Capabilities)(nil), "common.Capabilities")
 	proto.RegisterMapType((map[string]*Capability)(nil), "common.Capabilities.CapabilitiesEntry")
@@ -293,29 +393,33 @@ func init() {
 }
 
 func init() {
-	proto.RegisterFile("common/configuration.proto", fileDescriptor_configuration_c60fbe5ebb3de531)
-}
-
-var fileDescriptor_configuration_c60fbe5ebb3de531 = []byte{
-	// 314 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x91, 0x41, 0x6b, 0xf2, 0x40,
-	0x10, 0x86, 0x89, 0x7e, 0x0a, 0x8e, 0x7e, 0x60, 0x97, 0x1e, 0xac, 0xf4, 0x10, 0x42, 0x91, 0x40,
-	0x21, 0x69, 0xed, 0xa5, 0xf4, 0xa6, 0xb6, 0x50, 0x7a, 0x29, 0xc4, 0x5b, 0x6f, 0x9b, 0x64, 0x4c,
-	0x16, 0x93, 0x5d, 0x99, 0xdd, 0xb4, 0xe4, 0x57, 0xf5, 0x2f, 0x16, 0xb3, 0x16, 0x23, 0xf6, 0x36,
-	0xcf, 0xce, 0xf3, 0xce, 0xce, 0xb2, 0x30, 0x4d, 0x54, 0x59, 0x2a, 0x19, 0x26, 0x4a, 0x6e, 0x44,
-	0x56, 0x11, 0x37, 0x42, 0xc9, 0x60, 0x47, 0xca, 0x28, 0xd6, 0xb7, 0x3d, 0x6f, 0x06, 0xe3, 0x57,
-	0xae, 0x73, 0x21, 0xb3, 0x45, 0x91, 0x29, 0x12, 0x26, 0x2f, 0x19, 0x83, 0x7f, 0x92, 0x97, 0x38,
-	0x71, 0x5c, 0xc7, 0x1f, 0x44, 0x4d, 0xed, 0xdd, 0xc3, 0xd5, 0xb2, 0x50, 0xc9, 0xf6, 0x99, 0x1b,
-	0x7e, 0x08, 0xac, 0x0d, 0x55, 0x89, 0xa9, 0x08, 0xd9, 0x25, 0xf4, 0xbe, 0x44, 0x6a, 0xf2, 0x26,
-	0xf1, 0x3f, 0xb2, 0xe0, 0xdd, 0xc1, 0xf8, 0x9d, 0x52, 0x24, 0xa4, 0x45, 0x9a, 0x12, 0x6a, 0x8d,
-	0x9a, 0x5d, 0xc3, 0x80, 0xff, 0xc2, 0xc4, 0x71, 0xbb, 0xfe, 0x20, 0x3a, 0x1e, 0x78, 0x2e, 0xc0,
-	0x4a, 0x49, 0xad, 0xc8, 0x88, 0xea, 0xef, 0x35, 0xbe, 0x1d, 0x18, 0xad, 0xf8, 0x8e, 0xc7, 0xa2,
-	0x10, 0x46, 0xa0, 0x66, 0x6f, 0x30, 0x4a, 0x5a, 0xdc, 0xcc, 0x1c, 0xce, 0x67, 0x81, 0x7d, 0x5e,
-	0xd0, 0x76, 0x4f, 0xe0, 0x45, 0x1a, 0xaa, 0xa3, 0x93, 0xec, 0x74, 0x0d, 0x17, 0x67, 0x0a, 0x1b,
-	0x43, 0x77, 0x8b, 0xf5, 0x61, 0x89, 0x7d, 0xc9, 0x7c, 0xe8, 0x7d, 0xf2, 0xa2, 0xc2, 0x49, 0xc7,
-	0x75, 0xfc, 0xe1, 0x9c, 0x9d, 0xdd, 0x55, 0x47, 0x56, 0x78, 0xea, 0x3c, 0x3a, 0xde, 0x08, 0xe0,
-	0xd8, 0x58, 0xae, 0xe1, 0x46, 0x51, 0x16, 0xe4, 0xf5, 0x0e, 0xa9, 0xc0, 0x34, 0x43, 0x0a, 0x36,
-	0x3c, 0x26, 0x91, 0xd8, 0x6f, 0xd1, 0x87, 0x59, 0x1f, 0xb7, 0x99, 0x30, 0x79, 0x15, 0xef, 0x31,
-	0x6c, 0xc9, 0xa1, 0x95, 0x43, 0x2b, 0x87, 0x56, 0x8e, 0xfb, 0x0d, 0x3e, 0xfc, 0x04, 0x00, 0x00,
-	0xff, 0xff, 0xd6, 0x7e, 0xb4, 0x89, 0xf0, 0x01, 0x00, 0x00,
+	proto.RegisterFile("common/configuration.proto", fileDescriptor_configuration_e6a42ec3432d53c6)
+}
+
+var fileDescriptor_configuration_e6a42ec3432d53c6 = []byte{
+	// 376 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x92, 0xcd, 0x8a, 0xdb, 0x30,
+	0x14, 0x85, 0x71, 0xd2, 0xa4, 0xf5, 0x4d, 0x4a, 0x53, 0x51, 0x5a, 0x37, 0x74, 0x11, 0x4c, 0x09,
+	0x81, 0x82, 0xdd, 0xa6, 0x14, 0x4a, 0x77, 0x49, 0x1a, 0x68, 0x67, 0x33, 0xe0, 0xec, 0x66, 0x33,
+	0xc8, 0xb6, 0x62, 0x8b, 0xd8, 0x92, 0xb9, 0x92, 0x67, 0xf0, 0x53, 0xcd, 0x2b, 0x0e, 0xb1, 0xec,
+	0xfc, 0xce, 0x4e, 0x9f, 0xef, 0x77, 0x2c, 0x1d, 0x21, 0x18, 0x47, 0x32, 0xcf, 0xa5, 0xf0, 0x23,
+	0x29, 0xb6, 0x3c, 0x29, 0x91, 0x6a, 0x2e, 0x85, 0x57, 0xa0, 0xd4, 0x92, 0xf4, 0xcd, 0xcc, 0x9d,
+	0xc2, 0xe8, 0x1f, 0x55, 0x29, 0x17, 0xc9, 0x22, 0x4b, 0x24, 0x72, 0x9d, 0xe6, 0x84, 0xc0, 0x2b,
+	0x41, 0x73, 0xe6, 0x58, 0x13, 0x6b, 0x66, 0x07, 0xf5, 0xda, 0xfd, 0x01, 0x9f, 0x97, 0x99, 0x8c,
+	0x76, 0x7f, 0xa9, 0xa6, 0x4d, 0x60, 0xa3, 0xb1, 0x8c, 0x74, 0x89, 0x8c, 0x7c, 0x80, 0xde, 0x23,
+	0x8f, 0x75, 0x5a, 0x27, 0xde, 0x06, 0x06, 0xdc, 0xef, 0x30, 0xba, 0xc5, 0x98, 0x21, 0xc3, 0x45,
+	0x1c, 0x23, 0x53, 0x8a, 0x29, 0xf2, 0x05, 0x6c, 0xda, 0x82, 0x63, 0x4d, 0xba, 0x33, 0x3b, 0x38,
+	0x7e, 0x70, 0xff, 0x1f, 0x12, 0x6b, 0x11, 0x17, 0x92, 0x0b, 0xad, 0xc8, 0x2f, 0xb0, 0x59, 0x0b,
+	0x75, 0x62, 0x30, 0xff, 0xe4, 0x99, 0xc3, 0x7b, 0x17, 0x72, 0x70, 0x34, 0xdd, 0x7b, 0x78, 0x77,
+	0x31, 0x25, 0x0e, 0xbc, 0x6e, 0xb6, 0x6a, 0x9a, 0xb5, 0x48, 0x3e, 0x42, 0x1f, 0x59, 0xc2, 0xa5,
+	0x70, 0x3a, 0xf5, 0xa0, 0x21, 0x32, 0x86, 0x37, 0x05, 0xf2, 0xfd, 0xad, 0x54, 0x4e, 0xb7, 0xae,
+	0x76, 0x60, 0x77, 0x02, 0xb0, 0x92, 0x42, 0x49, 0xd4, 0xbc, 0x7c, 0xf9, 0xca, 0x9e, 0x2c, 0x18,
+	0xae, 0x68, 0x41, 0x43, 0x9e, 0x71, 0xcd, 0x99, 0x22, 0x37, 0x30, 0x8c, 0x4e, 0xb8, 0x69, 0x33,
+	0x6d, 0xdb, 0x9c, 0xba, 0x67, 0xb0, 0x16, 0x1a, 0xab, 0xe0, 0x2c, 0x3b, 0xde, 0xc0, 0xfb, 0x2b,
+	0x85, 0x8c, 0xa0, 0xbb, 0x63, 0x55, 0x73, 0x88, 0xfd, 0x92, 0xcc, 0xa0, 0xf7, 0x40, 0xb3, 0x92,
+	0xd5, 0xc5, 0x06, 0x73, 0x72, 0xb5, 0x57, 0x15, 0x18, 0xe1, 0x4f, 0xe7, 0xb7, 0xe5, 0x0e, 0x01,
+	0x8e, 0x83, 0xe5, 0x06, 0xbe, 0x4a, 0x4c, 0xbc, 0xb4, 0x2a, 0x18, 0x66, 0x2c, 0x4e, 0x18, 0x7a,
+	0x5b, 0x1a, 0x22, 0x8f, 0xcc, 0x13, 0x52, 0xcd, 0xbf, 0xee, 0xbe, 0x25, 0x5c, 0xa7, 0x65, 0xb8,
+	0x47, 0xff, 0x44, 0xf6, 0x8d, 0xec, 0x1b, 0xd9, 0x37, 0x72, 0xd8, 0xaf, 0xf1, 0xe7, 0xf3, 0x00,
+	0xc5, 0x89, 0x73, 0x15, 0x9c, 0x02, 0x00, 0x00,
 }
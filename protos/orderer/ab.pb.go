@@ -6,6 +6,8 @@ package orderer // import "github.com/hyperledger/fabric/protos/orderer"
 import proto "github.com/golang/protobuf/proto"
 import fmt "fmt"
 import math "math"
+import duration "github.com/golang/protobuf/ptypes/duration"
+import timestamp "github.com/golang/protobuf/ptypes/timestamp"
 import common "github.com/hyperledger/fabric/protos/common"
 
 import (
@@ -24,6 +26,88 @@ var _ = math.Inf
 // proto package needs to be updated.
 const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
 
+type BroadcastResponse_CommitStatus int32
+
+const (
+	BroadcastResponse_COMMIT_UNKNOWN BroadcastResponse_CommitStatus = 0
+	BroadcastResponse_COMMITTED      BroadcastResponse_CommitStatus = 1
+	BroadcastResponse_NOT_COMMITTED  BroadcastResponse_CommitStatus = 2
+)
+
+var BroadcastResponse_CommitStatus_name = map[int32]string{
+	0: "COMMIT_UNKNOWN",
+	1: "COMMITTED",
+	2: "NOT_COMMITTED",
+}
+var BroadcastResponse_CommitStatus_value = map[string]int32{
+	"COMMIT_UNKNOWN": 0,
+	"COMMITTED":      1,
+	"NOT_COMMITTED":  2,
+}
+
+func (x BroadcastResponse_CommitStatus) String() string {
+	return proto.EnumName(BroadcastResponse_CommitStatus_name, int32(x))
+}
+func (BroadcastResponse_CommitStatus) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{0, 0}
+}
+
+type ErrorDetail_Code int32
+
+const (
+	ErrorDetail_UNSPECIFIED           ErrorDetail_Code = 0
+	ErrorDetail_MALFORMED_MESSAGE     ErrorDetail_Code = 1
+	ErrorDetail_INVALID_MESSAGE       ErrorDetail_Code = 2
+	ErrorDetail_MESSAGE_TOO_LARGE     ErrorDetail_Code = 3
+	ErrorDetail_CHANNEL_NOT_FOUND     ErrorDetail_Code = 4
+	ErrorDetail_PERMISSION_DENIED     ErrorDetail_Code = 5
+	ErrorDetail_RATE_LIMITED          ErrorDetail_Code = 6
+	ErrorDetail_OVERLOADED            ErrorDetail_Code = 7
+	ErrorDetail_CONSENTER_UNAVAILABLE ErrorDetail_Code = 8
+	ErrorDetail_ADMISSION_DENIED      ErrorDetail_Code = 9
+	ErrorDetail_IDENTITY_EXPIRED      ErrorDetail_Code = 10
+	ErrorDetail_CLIENT_BLOCKED        ErrorDetail_Code = 11
+	ErrorDetail_QUOTA_EXCEEDED        ErrorDetail_Code = 12
+)
+
+var ErrorDetail_Code_name = map[int32]string{
+	0:  "UNSPECIFIED",
+	1:  "MALFORMED_MESSAGE",
+	2:  "INVALID_MESSAGE",
+	3:  "MESSAGE_TOO_LARGE",
+	4:  "CHANNEL_NOT_FOUND",
+	5:  "PERMISSION_DENIED",
+	6:  "RATE_LIMITED",
+	7:  "OVERLOADED",
+	8:  "CONSENTER_UNAVAILABLE",
+	9:  "ADMISSION_DENIED",
+	10: "IDENTITY_EXPIRED",
+	11: "CLIENT_BLOCKED",
+	12: "QUOTA_EXCEEDED",
+}
+var ErrorDetail_Code_value = map[string]int32{
+	"UNSPECIFIED":           0,
+	"MALFORMED_MESSAGE":     1,
+	"INVALID_MESSAGE":       2,
+	"MESSAGE_TOO_LARGE":     3,
+	"CHANNEL_NOT_FOUND":     4,
+	"PERMISSION_DENIED":     5,
+	"RATE_LIMITED":          6,
+	"OVERLOADED":            7,
+	"CONSENTER_UNAVAILABLE": 8,
+	"ADMISSION_DENIED":      9,
+	"IDENTITY_EXPIRED":      10,
+	"CLIENT_BLOCKED":        11,
+	"QUOTA_EXCEEDED":        12,
+}
+
+func (x ErrorDetail_Code) String() string {
+	return proto.EnumName(ErrorDetail_Code_name, int32(x))
+}
+func (ErrorDetail_Code) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{1, 0}
+}
+
 type SeekInfo_SeekBehavior int32
 
 const (
@@ -44,14 +128,31 @@ func (x SeekInfo_SeekBehavior) String() string {
 	return proto.EnumName(SeekInfo_SeekBehavior_name, int32(x))
 }
 func (SeekInfo_SeekBehavior) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_ab_86effae0ebc2388c, []int{5, 0}
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{8, 0}
 }
 
 type BroadcastResponse struct {
 	// Status code, which may be used to programatically respond to success/failure
 	Status common.Status `protobuf:"varint,1,opt,name=status,proto3,enum=common.Status" json:"status,omitempty"`
 	// Info string which may contain additional information about the status returned
-	Info                 string   `protobuf:"bytes,2,opt,name=info,proto3" json:"info,omitempty"`
+	Info string `protobuf:"bytes,2,opt,name=info,proto3" json:"info,omitempty"`
+	// The position, counting from 1, in the Broadcast stream of the message this responds to,
+	// as the messages of a stream may be answered out of order
+	CorrelationId uint64 `protobuf:"varint,3,opt,name=correlation_id,json=correlationId,proto3" json:"correlation_id,omitempty"`
+	// The machine-readable reason the message was rejected, unset on success.  It plays the
+	// part of the details of a gRPC status, as each message of a stream is answered separately
+	ErrorDetail *ErrorDetail `protobuf:"bytes,4,opt,name=error_detail,json=errorDetail,proto3" json:"error_detail,omitempty"`
+	// The heartbeat, on the responses which are heartbeats sent on a stream whose client asked for
+	// them.  They answer no message and carry no status.
+	Heartbeat *Heartbeat `protobuf:"bytes,5,opt,name=heartbeat,proto3" json:"heartbeat,omitempty"`
+	// The receipt signed by the orderer, on the successful responses to the messages of a stream
+	// whose client asked for receipts
+	Receipt *SignedReceipt `protobuf:"bytes,6,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	// Whether the message was committed, on the successful responses to the messages of a stream
+	// whose client asked to wait for their commit
+	CommitStatus BroadcastResponse_CommitStatus `protobuf:"varint,7,opt,name=commit_status,json=commitStatus,proto3,enum=orderer.BroadcastResponse_CommitStatus" json:"commit_status,omitempty"`
+	// The number of the block the message was committed in, when it was
+	BlockNumber          uint64   `protobuf:"varint,8,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -61,7 +162,7 @@ func (m *BroadcastResponse) Reset()         { *m = BroadcastResponse{} }
 func (m *BroadcastResponse) String() string { return proto.CompactTextString(m) }
 func (*BroadcastResponse) ProtoMessage()    {}
 func (*BroadcastResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ab_86effae0ebc2388c, []int{0}
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{0}
 }
 func (m *BroadcastResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BroadcastResponse.Unmarshal(m, b)
@@ -95,6 +196,184 @@ func (m *BroadcastResponse) GetInfo() string {
 	return ""
 }
 
+func (m *BroadcastResponse) GetCorrelationId() uint64 {
+	if m != nil {
+		return m.CorrelationId
+	}
+	return 0
+}
+
+func (m *BroadcastResponse) GetErrorDetail() *ErrorDetail {
+	if m != nil {
+		return m.ErrorDetail
+	}
+	return nil
+}
+
+func (m *BroadcastResponse) GetHeartbeat() *Heartbeat {
+	if m != nil {
+		return m.Heartbeat
+	}
+	return nil
+}
+
+// ErrorDetail tells clients why a broadcast message was rejected, so that they can decide
+// whether and when to submit it again without parsing the info string of the response
+func (m *BroadcastResponse) GetReceipt() *SignedReceipt {
+	if m != nil {
+		return m.Receipt
+	}
+	return nil
+}
+
+func (m *BroadcastResponse) GetCommitStatus() BroadcastResponse_CommitStatus {
+	if m != nil {
+		return m.CommitStatus
+	}
+	return BroadcastResponse_COMMIT_UNKNOWN
+}
+
+func (m *BroadcastResponse) GetBlockNumber() uint64 {
+	if m != nil {
+		return m.BlockNumber
+	}
+	return 0
+}
+
+type ErrorDetail struct {
+	Code ErrorDetail_Code `protobuf:"varint,1,opt,name=code,proto3,enum=orderer.ErrorDetail_Code" json:"code,omitempty"`
+	// How long to wait before submitting the message again, unset if there is no hint
+	RetryAfter *duration.Duration `protobuf:"bytes,2,opt,name=retry_after,json=retryAfter,proto3" json:"retry_after,omitempty"`
+	// The path of the offending field of the envelope, such as payload.header.channel_header
+	FieldPath            string   `protobuf:"bytes,3,opt,name=field_path,json=fieldPath,proto3" json:"field_path,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ErrorDetail) Reset()         { *m = ErrorDetail{} }
+func (m *ErrorDetail) String() string { return proto.CompactTextString(m) }
+func (*ErrorDetail) ProtoMessage()    {}
+func (*ErrorDetail) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{1}
+}
+func (m *ErrorDetail) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ErrorDetail.Unmarshal(m, b)
+}
+func (m *ErrorDetail) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ErrorDetail.Marshal(b, m, deterministic)
+}
+func (dst *ErrorDetail) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ErrorDetail.Merge(dst, src)
+}
+func (m *ErrorDetail) XXX_Size() int {
+	return xxx_messageInfo_ErrorDetail.Size(m)
+}
+func (m *ErrorDetail) XXX_DiscardUnknown() {
+	xxx_messageInfo_ErrorDetail.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ErrorDetail proto.InternalMessageInfo
+
+func (m *ErrorDetail) GetCode() ErrorDetail_Code {
+	if m != nil {
+		return m.Code
+	}
+	return ErrorDetail_UNSPECIFIED
+}
+
+func (m *ErrorDetail) GetRetryAfter() *duration.Duration {
+	if m != nil {
+		return m.RetryAfter
+	}
+	return nil
+}
+
+func (m *ErrorDetail) GetFieldPath() string {
+	if m != nil {
+		return m.FieldPath
+	}
+	return ""
+}
+
+// BroadcastBatch carries envelopes submitted together to save a round trip per envelope
+type BroadcastBatch struct {
+	Envelopes            []*common.Envelope `protobuf:"bytes,1,rep,name=envelopes,proto3" json:"envelopes,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}           `json:"-"`
+	XXX_unrecognized     []byte             `json:"-"`
+	XXX_sizecache        int32              `json:"-"`
+}
+
+func (m *BroadcastBatch) Reset()         { *m = BroadcastBatch{} }
+func (m *BroadcastBatch) String() string { return proto.CompactTextString(m) }
+func (*BroadcastBatch) ProtoMessage()    {}
+func (*BroadcastBatch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{2}
+}
+func (m *BroadcastBatch) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BroadcastBatch.Unmarshal(m, b)
+}
+func (m *BroadcastBatch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BroadcastBatch.Marshal(b, m, deterministic)
+}
+func (dst *BroadcastBatch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BroadcastBatch.Merge(dst, src)
+}
+func (m *BroadcastBatch) XXX_Size() int {
+	return xxx_messageInfo_BroadcastBatch.Size(m)
+}
+func (m *BroadcastBatch) XXX_DiscardUnknown() {
+	xxx_messageInfo_BroadcastBatch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BroadcastBatch proto.InternalMessageInfo
+
+func (m *BroadcastBatch) GetEnvelopes() []*common.Envelope {
+	if m != nil {
+		return m.Envelopes
+	}
+	return nil
+}
+
+// BroadcastBatchResponse carries the response to each envelope of a batch, in the order of the batch
+type BroadcastBatchResponse struct {
+	Responses            []*BroadcastResponse `protobuf:"bytes,1,rep,name=responses,proto3" json:"responses,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *BroadcastBatchResponse) Reset()         { *m = BroadcastBatchResponse{} }
+func (m *BroadcastBatchResponse) String() string { return proto.CompactTextString(m) }
+func (*BroadcastBatchResponse) ProtoMessage()    {}
+func (*BroadcastBatchResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{3}
+}
+func (m *BroadcastBatchResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_BroadcastBatchResponse.Unmarshal(m, b)
+}
+func (m *BroadcastBatchResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_BroadcastBatchResponse.Marshal(b, m, deterministic)
+}
+func (dst *BroadcastBatchResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BroadcastBatchResponse.Merge(dst, src)
+}
+func (m *BroadcastBatchResponse) XXX_Size() int {
+	return xxx_messageInfo_BroadcastBatchResponse.Size(m)
+}
+func (m *BroadcastBatchResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_BroadcastBatchResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BroadcastBatchResponse proto.InternalMessageInfo
+
+func (m *BroadcastBatchResponse) GetResponses() []*BroadcastResponse {
+	if m != nil {
+		return m.Responses
+	}
+	return nil
+}
+
 type SeekNewest struct {
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
@@ -105,7 +384,7 @@ func (m *SeekNewest) Reset()         { *m = SeekNewest{} }
 func (m *SeekNewest) String() string { return proto.CompactTextString(m) }
 func (*SeekNewest) ProtoMessage()    {}
 func (*SeekNewest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ab_86effae0ebc2388c, []int{1}
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{4}
 }
 func (m *SeekNewest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SeekNewest.Unmarshal(m, b)
@@ -135,7 +414,7 @@ func (m *SeekOldest) Reset()         { *m = SeekOldest{} }
 func (m *SeekOldest) String() string { return proto.CompactTextString(m) }
 func (*SeekOldest) ProtoMessage()    {}
 func (*SeekOldest) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ab_86effae0ebc2388c, []int{2}
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{5}
 }
 func (m *SeekOldest) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SeekOldest.Unmarshal(m, b)
@@ -166,7 +445,7 @@ func (m *SeekSpecified) Reset()         { *m = SeekSpecified{} }
 func (m *SeekSpecified) String() string { return proto.CompactTextString(m) }
 func (*SeekSpecified) ProtoMessage()    {}
 func (*SeekSpecified) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ab_86effae0ebc2388c, []int{3}
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{6}
 }
 func (m *SeekSpecified) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SeekSpecified.Unmarshal(m, b)
@@ -208,7 +487,7 @@ func (m *SeekPosition) Reset()         { *m = SeekPosition{} }
 func (m *SeekPosition) String() string { return proto.CompactTextString(m) }
 func (*SeekPosition) ProtoMessage()    {}
 func (*SeekPosition) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ab_86effae0ebc2388c, []int{4}
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{7}
 }
 func (m *SeekPosition) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SeekPosition.Unmarshal(m, b)
@@ -378,11 +657,17 @@ func _SeekPosition_OneofSizer(msg proto.Message) (n int) {
 // the requested blocks are available, if FAIL_IF_NOT_READY is specified, the reply will return an
 // error indicating that the block is not found.  To request that all blocks be returned indefinitely
 // as they are created, behavior should be set to BLOCK_UNTIL_READY and the stop should be set to
-// specified with a number of MAX_UINT64
+// specified with a number of MAX_UINT64.  A client which cannot hold a long lived stream may
+// page through the blocks by bounding each reply with max_blocks and/or max_bytes, the reply
+// then ends with a SUCCESS status once either cap is reached, and the client resumes from the
+// block following the last one received.
 type SeekInfo struct {
 	Start                *SeekPosition         `protobuf:"bytes,1,opt,name=start,proto3" json:"start,omitempty"`
 	Stop                 *SeekPosition         `protobuf:"bytes,2,opt,name=stop,proto3" json:"stop,omitempty"`
 	Behavior             SeekInfo_SeekBehavior `protobuf:"varint,3,opt,name=behavior,proto3,enum=orderer.SeekInfo_SeekBehavior" json:"behavior,omitempty"`
+	MaxBlocks            uint64                `protobuf:"varint,4,opt,name=max_blocks,json=maxBlocks,proto3" json:"max_blocks,omitempty"`
+	MaxBytes             uint64                `protobuf:"varint,5,opt,name=max_bytes,json=maxBytes,proto3" json:"max_bytes,omitempty"`
+	Filter               *DeliverFilter        `protobuf:"bytes,6,opt,name=filter,proto3" json:"filter,omitempty"`
 	XXX_NoUnkeyedLiteral struct{}              `json:"-"`
 	XXX_unrecognized     []byte                `json:"-"`
 	XXX_sizecache        int32                 `json:"-"`
@@ -392,7 +677,7 @@ func (m *SeekInfo) Reset()         { *m = SeekInfo{} }
 func (m *SeekInfo) String() string { return proto.CompactTextString(m) }
 func (*SeekInfo) ProtoMessage()    {}
 func (*SeekInfo) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ab_86effae0ebc2388c, []int{5}
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{8}
 }
 func (m *SeekInfo) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_SeekInfo.Unmarshal(m, b)
@@ -433,21 +718,129 @@ func (m *SeekInfo) GetBehavior() SeekInfo_SeekBehavior {
 	return SeekInfo_BLOCK_UNTIL_READY
 }
 
+func (m *SeekInfo) GetMaxBlocks() uint64 {
+	if m != nil {
+		return m.MaxBlocks
+	}
+	return 0
+}
+
+func (m *SeekInfo) GetMaxBytes() uint64 {
+	if m != nil {
+		return m.MaxBytes
+	}
+	return 0
+}
+
+func (m *SeekInfo) GetFilter() *DeliverFilter {
+	if m != nil {
+		return m.Filter
+	}
+	return nil
+}
+
+// DeliverFilter narrows the blocks delivered to the transactions matching every criterion set,
+// a transaction matching a criterion if it matches any of its values.  The blocks delivered keep
+// their header and carry only the matching transactions, with the validation flags of those in
+// their metadata, so their data no longer matches the data hash of their header.  The blocks
+// without matching transactions are not delivered.
+type DeliverFilter struct {
+	// The names of the chaincodes invoked by the transactions
+	ChaincodeNames []string `protobuf:"bytes,1,rep,name=chaincode_names,json=chaincodeNames,proto3" json:"chaincode_names,omitempty"`
+	// The types of the transactions
+	Types []common.HeaderType `protobuf:"varint,2,rep,packed,name=types,proto3,enum=common.HeaderType" json:"types,omitempty"`
+	// The MSP IDs of the creators of the transactions
+	CreatorMspIds []string `protobuf:"bytes,3,rep,name=creator_msp_ids,json=creatorMspIds,proto3" json:"creator_msp_ids,omitempty"`
+	// The IDs of the transactions
+	TxIds []string `protobuf:"bytes,4,rep,name=tx_ids,json=txIds,proto3" json:"tx_ids,omitempty"`
+	// The visibility labels of the transactions.  The clients asking for labels are
+	// authorized by the readers policies of the labels rather than by the policy of
+	// the channel, and are delivered only the transactions carrying one of them.
+	Labels               []string `protobuf:"bytes,5,rep,name=labels,proto3" json:"labels,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *DeliverFilter) Reset()         { *m = DeliverFilter{} }
+func (m *DeliverFilter) String() string { return proto.CompactTextString(m) }
+func (*DeliverFilter) ProtoMessage()    {}
+func (*DeliverFilter) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{9}
+}
+func (m *DeliverFilter) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_DeliverFilter.Unmarshal(m, b)
+}
+func (m *DeliverFilter) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_DeliverFilter.Marshal(b, m, deterministic)
+}
+func (dst *DeliverFilter) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_DeliverFilter.Merge(dst, src)
+}
+func (m *DeliverFilter) XXX_Size() int {
+	return xxx_messageInfo_DeliverFilter.Size(m)
+}
+func (m *DeliverFilter) XXX_DiscardUnknown() {
+	xxx_messageInfo_DeliverFilter.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_DeliverFilter proto.InternalMessageInfo
+
+func (m *DeliverFilter) GetChaincodeNames() []string {
+	if m != nil {
+		return m.ChaincodeNames
+	}
+	return nil
+}
+
+func (m *DeliverFilter) GetTypes() []common.HeaderType {
+	if m != nil {
+		return m.Types
+	}
+	return nil
+}
+
+func (m *DeliverFilter) GetCreatorMspIds() []string {
+	if m != nil {
+		return m.CreatorMspIds
+	}
+	return nil
+}
+
+func (m *DeliverFilter) GetTxIds() []string {
+	if m != nil {
+		return m.TxIds
+	}
+	return nil
+}
+
+func (m *DeliverFilter) GetLabels() []string {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
 type DeliverResponse struct {
 	// Types that are valid to be assigned to Type:
 	//	*DeliverResponse_Status
 	//	*DeliverResponse_Block
-	Type                 isDeliverResponse_Type `protobuf_oneof:"Type"`
-	XXX_NoUnkeyedLiteral struct{}               `json:"-"`
-	XXX_unrecognized     []byte                 `json:"-"`
-	XXX_sizecache        int32                  `json:"-"`
+	//	*DeliverResponse_Heartbeat
+	Type isDeliverResponse_Type `protobuf_oneof:"Type"`
+	// mac, when the orderer is configured to authenticate its replies, is an HMAC-SHA256
+	// keyed from the mutual TLS session over the response and the mac of the previous
+	// response on the stream, so tampered, dropped or reordered responses can be detected.
+	Mac                  []byte   `protobuf:"bytes,3,opt,name=mac,proto3" json:"mac,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
 }
 
 func (m *DeliverResponse) Reset()         { *m = DeliverResponse{} }
 func (m *DeliverResponse) String() string { return proto.CompactTextString(m) }
 func (*DeliverResponse) ProtoMessage()    {}
 func (*DeliverResponse) Descriptor() ([]byte, []int) {
-	return fileDescriptor_ab_86effae0ebc2388c, []int{6}
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{10}
 }
 func (m *DeliverResponse) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_DeliverResponse.Unmarshal(m, b)
@@ -479,10 +872,16 @@ type DeliverResponse_Block struct {
 	Block *common.Block `protobuf:"bytes,2,opt,name=block,proto3,oneof"`
 }
 
+type DeliverResponse_Heartbeat struct {
+	Heartbeat *Heartbeat `protobuf:"bytes,4,opt,name=heartbeat,proto3,oneof"`
+}
+
 func (*DeliverResponse_Status) isDeliverResponse_Type() {}
 
 func (*DeliverResponse_Block) isDeliverResponse_Type() {}
 
+func (*DeliverResponse_Heartbeat) isDeliverResponse_Type() {}
+
 func (m *DeliverResponse) GetType() isDeliverResponse_Type {
 	if m != nil {
 		return m.Type
@@ -504,11 +903,26 @@ func (m *DeliverResponse) GetBlock() *common.Block {
 	return nil
 }
 
+func (m *DeliverResponse) GetHeartbeat() *Heartbeat {
+	if x, ok := m.GetType().(*DeliverResponse_Heartbeat); ok {
+		return x.Heartbeat
+	}
+	return nil
+}
+
+func (m *DeliverResponse) GetMac() []byte {
+	if m != nil {
+		return m.Mac
+	}
+	return nil
+}
+
 // XXX_OneofFuncs is for the internal use of the proto package.
 func (*DeliverResponse) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
 	return _DeliverResponse_OneofMarshaler, _DeliverResponse_OneofUnmarshaler, _DeliverResponse_OneofSizer, []interface{}{
 		(*DeliverResponse_Status)(nil),
 		(*DeliverResponse_Block)(nil),
+		(*DeliverResponse_Heartbeat)(nil),
 	}
 }
 
@@ -524,6 +938,11 @@ func _DeliverResponse_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
 		if err := b.EncodeMessage(x.Block); err != nil {
 			return err
 		}
+	case *DeliverResponse_Heartbeat:
+		b.EncodeVarint(4<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Heartbeat); err != nil {
+			return err
+		}
 	case nil:
 	default:
 		return fmt.Errorf("DeliverResponse.Type has unexpected type %T", x)
@@ -549,6 +968,14 @@ func _DeliverResponse_OneofUnmarshaler(msg proto.Message, tag, wire int, b *prot
 		err := b.DecodeMessage(msg)
 		m.Type = &DeliverResponse_Block{msg}
 		return true, err
+	case 4: // Type.heartbeat
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(Heartbeat)
+		err := b.DecodeMessage(msg)
+		m.Type = &DeliverResponse_Heartbeat{msg}
+		return true, err
 	default:
 		return false, nil
 	}
@@ -566,6 +993,11 @@ func _DeliverResponse_OneofSizer(msg proto.Message) (n int) {
 		n += 1 // tag and wire
 		n += proto.SizeVarint(uint64(s))
 		n += s
+	case *DeliverResponse_Heartbeat:
+		s := proto.Size(x.Heartbeat)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
 	case nil:
 	default:
 		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
@@ -573,94 +1005,635 @@ func _DeliverResponse_OneofSizer(msg proto.Message) (n int) {
 	return n
 }
 
-func init() {
-	proto.RegisterType((*BroadcastResponse)(nil), "orderer.BroadcastResponse")
-	proto.RegisterType((*SeekNewest)(nil), "orderer.SeekNewest")
-	proto.RegisterType((*SeekOldest)(nil), "orderer.SeekOldest")
-	proto.RegisterType((*SeekSpecified)(nil), "orderer.SeekSpecified")
-	proto.RegisterType((*SeekPosition)(nil), "orderer.SeekPosition")
-	proto.RegisterType((*SeekInfo)(nil), "orderer.SeekInfo")
-	proto.RegisterType((*DeliverResponse)(nil), "orderer.DeliverResponse")
-	proto.RegisterEnum("orderer.SeekInfo_SeekBehavior", SeekInfo_SeekBehavior_name, SeekInfo_SeekBehavior_value)
+// ConfigChange is a config committed on a channel, with its difference from the config it replaced
+type ConfigChange struct {
+	// The number of the config block carrying the config
+	BlockNumber uint64         `protobuf:"varint,1,opt,name=block_number,json=blockNumber,proto3" json:"block_number,omitempty"`
+	Config      *common.Config `protobuf:"bytes,2,opt,name=config,proto3" json:"config,omitempty"`
+	// The update which turns the previous config of the channel into this one, unset for the
+	// config in force when the subscription starts
+	Delta                *common.ConfigUpdate `protobuf:"bytes,3,opt,name=delta,proto3" json:"delta,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
 }
 
-// Reference imports to suppress errors if they are not otherwise used.
-var _ context.Context
-var _ grpc.ClientConn
-
-// This is a compile-time assertion to ensure that this generated file
-// is compatible with the grpc package it is being compiled against.
-const _ = grpc.SupportPackageIsVersion4
-
-// AtomicBroadcastClient is the client API for AtomicBroadcast service.
-//
-// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
-type AtomicBroadcastClient interface {
-	// broadcast receives a reply of Acknowledgement for each common.Envelope in order, indicating success or type of failure
-	Broadcast(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_BroadcastClient, error)
-	// deliver first requires an Envelope of type DELIVER_SEEK_INFO with Payload data as a mashaled SeekInfo message, then a stream of block replies is received.
-	Deliver(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_DeliverClient, error)
+func (m *ConfigChange) Reset()         { *m = ConfigChange{} }
+func (m *ConfigChange) String() string { return proto.CompactTextString(m) }
+func (*ConfigChange) ProtoMessage()    {}
+func (*ConfigChange) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{11}
 }
-
-type atomicBroadcastClient struct {
-	cc *grpc.ClientConn
+func (m *ConfigChange) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ConfigChange.Unmarshal(m, b)
 }
-
-func NewAtomicBroadcastClient(cc *grpc.ClientConn) AtomicBroadcastClient {
-	return &atomicBroadcastClient{cc}
+func (m *ConfigChange) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ConfigChange.Marshal(b, m, deterministic)
 }
-
-func (c *atomicBroadcastClient) Broadcast(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_BroadcastClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_AtomicBroadcast_serviceDesc.Streams[0], "/orderer.AtomicBroadcast/Broadcast", opts...)
-	if err != nil {
-		return nil, err
-	}
-	x := &atomicBroadcastBroadcastClient{stream}
-	return x, nil
+func (dst *ConfigChange) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ConfigChange.Merge(dst, src)
 }
-
-type AtomicBroadcast_BroadcastClient interface {
-	Send(*common.Envelope) error
-	Recv() (*BroadcastResponse, error)
-	grpc.ClientStream
+func (m *ConfigChange) XXX_Size() int {
+	return xxx_messageInfo_ConfigChange.Size(m)
 }
-
-type atomicBroadcastBroadcastClient struct {
-	grpc.ClientStream
+func (m *ConfigChange) XXX_DiscardUnknown() {
+	xxx_messageInfo_ConfigChange.DiscardUnknown(m)
 }
 
-func (x *atomicBroadcastBroadcastClient) Send(m *common.Envelope) error {
-	return x.ClientStream.SendMsg(m)
-}
+var xxx_messageInfo_ConfigChange proto.InternalMessageInfo
 
-func (x *atomicBroadcastBroadcastClient) Recv() (*BroadcastResponse, error) {
-	m := new(BroadcastResponse)
-	if err := x.ClientStream.RecvMsg(m); err != nil {
-		return nil, err
+func (m *ConfigChange) GetBlockNumber() uint64 {
+	if m != nil {
+		return m.BlockNumber
 	}
-	return m, nil
+	return 0
 }
 
-func (c *atomicBroadcastClient) Deliver(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_DeliverClient, error) {
-	stream, err := c.cc.NewStream(ctx, &_AtomicBroadcast_serviceDesc.Streams[1], "/orderer.AtomicBroadcast/Deliver", opts...)
-	if err != nil {
-		return nil, err
+func (m *ConfigChange) GetConfig() *common.Config {
+	if m != nil {
+		return m.Config
 	}
-	x := &atomicBroadcastDeliverClient{stream}
-	return x, nil
+	return nil
 }
 
-type AtomicBroadcast_DeliverClient interface {
-	Send(*common.Envelope) error
-	Recv() (*DeliverResponse, error)
-	grpc.ClientStream
+func (m *ConfigChange) GetDelta() *common.ConfigUpdate {
+	if m != nil {
+		return m.Delta
+	}
+	return nil
 }
 
-type atomicBroadcastDeliverClient struct {
-	grpc.ClientStream
+type ConfigChangeResponse struct {
+	// Types that are valid to be assigned to Type:
+	//	*ConfigChangeResponse_Status
+	//	*ConfigChangeResponse_Change
+	Type                 isConfigChangeResponse_Type `protobuf_oneof:"Type"`
+	XXX_NoUnkeyedLiteral struct{}                    `json:"-"`
+	XXX_unrecognized     []byte                      `json:"-"`
+	XXX_sizecache        int32                       `json:"-"`
 }
 
-func (x *atomicBroadcastDeliverClient) Send(m *common.Envelope) error {
+func (m *ConfigChangeResponse) Reset()         { *m = ConfigChangeResponse{} }
+func (m *ConfigChangeResponse) String() string { return proto.CompactTextString(m) }
+func (*ConfigChangeResponse) ProtoMessage()    {}
+func (*ConfigChangeResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{12}
+}
+func (m *ConfigChangeResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ConfigChangeResponse.Unmarshal(m, b)
+}
+func (m *ConfigChangeResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ConfigChangeResponse.Marshal(b, m, deterministic)
+}
+func (dst *ConfigChangeResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ConfigChangeResponse.Merge(dst, src)
+}
+func (m *ConfigChangeResponse) XXX_Size() int {
+	return xxx_messageInfo_ConfigChangeResponse.Size(m)
+}
+func (m *ConfigChangeResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ConfigChangeResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ConfigChangeResponse proto.InternalMessageInfo
+
+type isConfigChangeResponse_Type interface {
+	isConfigChangeResponse_Type()
+}
+
+type ConfigChangeResponse_Status struct {
+	Status common.Status `protobuf:"varint,1,opt,name=status,proto3,enum=common.Status,oneof"`
+}
+
+type ConfigChangeResponse_Change struct {
+	Change *ConfigChange `protobuf:"bytes,2,opt,name=change,proto3,oneof"`
+}
+
+func (*ConfigChangeResponse_Status) isConfigChangeResponse_Type() {}
+
+func (*ConfigChangeResponse_Change) isConfigChangeResponse_Type() {}
+
+func (m *ConfigChangeResponse) GetType() isConfigChangeResponse_Type {
+	if m != nil {
+		return m.Type
+	}
+	return nil
+}
+
+func (m *ConfigChangeResponse) GetStatus() common.Status {
+	if x, ok := m.GetType().(*ConfigChangeResponse_Status); ok {
+		return x.Status
+	}
+	return common.Status_UNKNOWN
+}
+
+func (m *ConfigChangeResponse) GetChange() *ConfigChange {
+	if x, ok := m.GetType().(*ConfigChangeResponse_Change); ok {
+		return x.Change
+	}
+	return nil
+}
+
+// XXX_OneofFuncs is for the internal use of the proto package.
+func (*ConfigChangeResponse) XXX_OneofFuncs() (func(msg proto.Message, b *proto.Buffer) error, func(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error), func(msg proto.Message) (n int), []interface{}) {
+	return _ConfigChangeResponse_OneofMarshaler, _ConfigChangeResponse_OneofUnmarshaler, _ConfigChangeResponse_OneofSizer, []interface{}{
+		(*ConfigChangeResponse_Status)(nil),
+		(*ConfigChangeResponse_Change)(nil),
+	}
+}
+
+func _ConfigChangeResponse_OneofMarshaler(msg proto.Message, b *proto.Buffer) error {
+	m := msg.(*ConfigChangeResponse)
+	// Type
+	switch x := m.Type.(type) {
+	case *ConfigChangeResponse_Status:
+		b.EncodeVarint(1<<3 | proto.WireVarint)
+		b.EncodeVarint(uint64(x.Status))
+	case *ConfigChangeResponse_Change:
+		b.EncodeVarint(2<<3 | proto.WireBytes)
+		if err := b.EncodeMessage(x.Change); err != nil {
+			return err
+		}
+	case nil:
+	default:
+		return fmt.Errorf("ConfigChangeResponse.Type has unexpected type %T", x)
+	}
+	return nil
+}
+
+func _ConfigChangeResponse_OneofUnmarshaler(msg proto.Message, tag, wire int, b *proto.Buffer) (bool, error) {
+	m := msg.(*ConfigChangeResponse)
+	switch tag {
+	case 1: // Type.status
+		if wire != proto.WireVarint {
+			return true, proto.ErrInternalBadWireType
+		}
+		x, err := b.DecodeVarint()
+		m.Type = &ConfigChangeResponse_Status{common.Status(x)}
+		return true, err
+	case 2: // Type.change
+		if wire != proto.WireBytes {
+			return true, proto.ErrInternalBadWireType
+		}
+		msg := new(ConfigChange)
+		err := b.DecodeMessage(msg)
+		m.Type = &ConfigChangeResponse_Change{msg}
+		return true, err
+	default:
+		return false, nil
+	}
+}
+
+func _ConfigChangeResponse_OneofSizer(msg proto.Message) (n int) {
+	m := msg.(*ConfigChangeResponse)
+	// Type
+	switch x := m.Type.(type) {
+	case *ConfigChangeResponse_Status:
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(x.Status))
+	case *ConfigChangeResponse_Change:
+		s := proto.Size(x.Change)
+		n += 1 // tag and wire
+		n += proto.SizeVarint(uint64(s))
+		n += s
+	case nil:
+	default:
+		panic(fmt.Sprintf("proto: unexpected type %T in oneof", x))
+	}
+	return n
+}
+
+// Watermark is the height of a channel on an orderer and the hash of the header of its last block
+type Watermark struct {
+	ChannelId     string `protobuf:"bytes,1,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
+	Height        uint64 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	LastBlockHash []byte `protobuf:"bytes,3,opt,name=last_block_hash,json=lastBlockHash,proto3" json:"last_block_hash,omitempty"`
+	// The time of the orderer when the watermark was taken
+	Timestamp            *timestamp.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Watermark) Reset()         { *m = Watermark{} }
+func (m *Watermark) String() string { return proto.CompactTextString(m) }
+func (*Watermark) ProtoMessage()    {}
+func (*Watermark) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{13}
+}
+func (m *Watermark) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Watermark.Unmarshal(m, b)
+}
+func (m *Watermark) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Watermark.Marshal(b, m, deterministic)
+}
+func (dst *Watermark) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Watermark.Merge(dst, src)
+}
+func (m *Watermark) XXX_Size() int {
+	return xxx_messageInfo_Watermark.Size(m)
+}
+func (m *Watermark) XXX_DiscardUnknown() {
+	xxx_messageInfo_Watermark.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Watermark proto.InternalMessageInfo
+
+func (m *Watermark) GetChannelId() string {
+	if m != nil {
+		return m.ChannelId
+	}
+	return ""
+}
+
+func (m *Watermark) GetHeight() uint64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *Watermark) GetLastBlockHash() []byte {
+	if m != nil {
+		return m.LastBlockHash
+	}
+	return nil
+}
+
+func (m *Watermark) GetTimestamp() *timestamp.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+type WatermarkResponse struct {
+	Status common.Status `protobuf:"varint,1,opt,name=status,proto3,enum=common.Status" json:"status,omitempty"`
+	// A marshaled Watermark, unset unless the status is SUCCESS
+	Watermark []byte `protobuf:"bytes,2,opt,name=watermark,proto3" json:"watermark,omitempty"`
+	// A marshaled SignatureHeader of the orderer
+	SignatureHeader []byte `protobuf:"bytes,3,opt,name=signature_header,json=signatureHeader,proto3" json:"signature_header,omitempty"`
+	// The signature of the orderer over the concatenation of watermark and signature_header
+	Signature            []byte   `protobuf:"bytes,4,opt,name=signature,proto3" json:"signature,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatermarkResponse) Reset()         { *m = WatermarkResponse{} }
+func (m *WatermarkResponse) String() string { return proto.CompactTextString(m) }
+func (*WatermarkResponse) ProtoMessage()    {}
+func (*WatermarkResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{14}
+}
+func (m *WatermarkResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatermarkResponse.Unmarshal(m, b)
+}
+func (m *WatermarkResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatermarkResponse.Marshal(b, m, deterministic)
+}
+func (dst *WatermarkResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatermarkResponse.Merge(dst, src)
+}
+func (m *WatermarkResponse) XXX_Size() int {
+	return xxx_messageInfo_WatermarkResponse.Size(m)
+}
+func (m *WatermarkResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatermarkResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatermarkResponse proto.InternalMessageInfo
+
+func (m *WatermarkResponse) GetStatus() common.Status {
+	if m != nil {
+		return m.Status
+	}
+	return common.Status_UNKNOWN
+}
+
+func (m *WatermarkResponse) GetWatermark() []byte {
+	if m != nil {
+		return m.Watermark
+	}
+	return nil
+}
+
+func (m *WatermarkResponse) GetSignatureHeader() []byte {
+	if m != nil {
+		return m.SignatureHeader
+	}
+	return nil
+}
+
+func (m *WatermarkResponse) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+// Heartbeat is sent periodically on the broadcast and deliver streams whose client asked for it,
+// apart from the gRPC keepalives which proxies buffering the stream may hide, so that the client
+// can detect a stalled stream and tell how fresh it is
+type Heartbeat struct {
+	// The channel whose height is reported, that of the last message received on a broadcast
+	// stream, unset before the first one
+	ChannelId string `protobuf:"bytes,1,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
+	// The height of the channel on the orderer when the heartbeat was sent
+	Height uint64 `protobuf:"varint,2,opt,name=height,proto3" json:"height,omitempty"`
+	// The time of the orderer when the heartbeat was sent
+	Timestamp            *timestamp.Timestamp `protobuf:"bytes,3,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Heartbeat) Reset()         { *m = Heartbeat{} }
+func (m *Heartbeat) String() string { return proto.CompactTextString(m) }
+func (*Heartbeat) ProtoMessage()    {}
+func (*Heartbeat) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{15}
+}
+func (m *Heartbeat) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Heartbeat.Unmarshal(m, b)
+}
+func (m *Heartbeat) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Heartbeat.Marshal(b, m, deterministic)
+}
+func (dst *Heartbeat) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Heartbeat.Merge(dst, src)
+}
+func (m *Heartbeat) XXX_Size() int {
+	return xxx_messageInfo_Heartbeat.Size(m)
+}
+func (m *Heartbeat) XXX_DiscardUnknown() {
+	xxx_messageInfo_Heartbeat.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Heartbeat proto.InternalMessageInfo
+
+func (m *Heartbeat) GetChannelId() string {
+	if m != nil {
+		return m.ChannelId
+	}
+	return ""
+}
+
+func (m *Heartbeat) GetHeight() uint64 {
+	if m != nil {
+		return m.Height
+	}
+	return 0
+}
+
+func (m *Heartbeat) GetTimestamp() *timestamp.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+// Receipt attests that an orderer accepted a message for ordering, the orderer signing it in a
+// SignedReceipt
+type Receipt struct {
+	// The channel the message was accepted for
+	ChannelId string `protobuf:"bytes,1,opt,name=channel_id,json=channelId,proto3" json:"channel_id,omitempty"`
+	// The transaction ID of the message
+	TxId string `protobuf:"bytes,2,opt,name=tx_id,json=txId,proto3" json:"tx_id,omitempty"`
+	// The sequence number of the config of the channel the message was validated against
+	ConfigSeq uint64 `protobuf:"varint,3,opt,name=config_seq,json=configSeq,proto3" json:"config_seq,omitempty"`
+	// The time of the orderer when the message was accepted
+	Timestamp *timestamp.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	// The SHA-256 hash of the payload of the envelope of the message, as received
+	PayloadHash          []byte   `protobuf:"bytes,5,opt,name=payload_hash,json=payloadHash,proto3" json:"payload_hash,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Receipt) Reset()         { *m = Receipt{} }
+func (m *Receipt) String() string { return proto.CompactTextString(m) }
+func (*Receipt) ProtoMessage()    {}
+func (*Receipt) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{16}
+}
+func (m *Receipt) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Receipt.Unmarshal(m, b)
+}
+func (m *Receipt) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Receipt.Marshal(b, m, deterministic)
+}
+func (dst *Receipt) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Receipt.Merge(dst, src)
+}
+func (m *Receipt) XXX_Size() int {
+	return xxx_messageInfo_Receipt.Size(m)
+}
+func (m *Receipt) XXX_DiscardUnknown() {
+	xxx_messageInfo_Receipt.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Receipt proto.InternalMessageInfo
+
+func (m *Receipt) GetChannelId() string {
+	if m != nil {
+		return m.ChannelId
+	}
+	return ""
+}
+
+func (m *Receipt) GetTxId() string {
+	if m != nil {
+		return m.TxId
+	}
+	return ""
+}
+
+func (m *Receipt) GetConfigSeq() uint64 {
+	if m != nil {
+		return m.ConfigSeq
+	}
+	return 0
+}
+
+func (m *Receipt) GetTimestamp() *timestamp.Timestamp {
+	if m != nil {
+		return m.Timestamp
+	}
+	return nil
+}
+
+func (m *Receipt) GetPayloadHash() []byte {
+	if m != nil {
+		return m.PayloadHash
+	}
+	return nil
+}
+
+type SignedReceipt struct {
+	// A marshaled Receipt
+	Receipt []byte `protobuf:"bytes,1,opt,name=receipt,proto3" json:"receipt,omitempty"`
+	// A marshaled SignatureHeader of the orderer
+	SignatureHeader []byte `protobuf:"bytes,2,opt,name=signature_header,json=signatureHeader,proto3" json:"signature_header,omitempty"`
+	// The signature of the orderer over the concatenation of receipt and signature_header
+	Signature            []byte   `protobuf:"bytes,3,opt,name=signature,proto3" json:"signature,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *SignedReceipt) Reset()         { *m = SignedReceipt{} }
+func (m *SignedReceipt) String() string { return proto.CompactTextString(m) }
+func (*SignedReceipt) ProtoMessage()    {}
+func (*SignedReceipt) Descriptor() ([]byte, []int) {
+	return fileDescriptor_ab_0c858c05dda8e4ff, []int{17}
+}
+func (m *SignedReceipt) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_SignedReceipt.Unmarshal(m, b)
+}
+func (m *SignedReceipt) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_SignedReceipt.Marshal(b, m, deterministic)
+}
+func (dst *SignedReceipt) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_SignedReceipt.Merge(dst, src)
+}
+func (m *SignedReceipt) XXX_Size() int {
+	return xxx_messageInfo_SignedReceipt.Size(m)
+}
+func (m *SignedReceipt) XXX_DiscardUnknown() {
+	xxx_messageInfo_SignedReceipt.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_SignedReceipt proto.InternalMessageInfo
+
+func (m *SignedReceipt) GetReceipt() []byte {
+	if m != nil {
+		return m.Receipt
+	}
+	return nil
+}
+
+func (m *SignedReceipt) GetSignatureHeader() []byte {
+	if m != nil {
+		return m.SignatureHeader
+	}
+	return nil
+}
+
+func (m *SignedReceipt) GetSignature() []byte {
+	if m != nil {
+		return m.Signature
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*BroadcastResponse)(nil), "orderer.BroadcastResponse")
+	proto.RegisterType((*ErrorDetail)(nil), "orderer.ErrorDetail")
+	proto.RegisterType((*BroadcastBatch)(nil), "orderer.BroadcastBatch")
+	proto.RegisterType((*BroadcastBatchResponse)(nil), "orderer.BroadcastBatchResponse")
+	proto.RegisterType((*SeekNewest)(nil), "orderer.SeekNewest")
+	proto.RegisterType((*SeekOldest)(nil), "orderer.SeekOldest")
+	proto.RegisterType((*SeekSpecified)(nil), "orderer.SeekSpecified")
+	proto.RegisterType((*SeekPosition)(nil), "orderer.SeekPosition")
+	proto.RegisterType((*SeekInfo)(nil), "orderer.SeekInfo")
+	proto.RegisterType((*DeliverFilter)(nil), "orderer.DeliverFilter")
+	proto.RegisterType((*DeliverResponse)(nil), "orderer.DeliverResponse")
+	proto.RegisterType((*ConfigChange)(nil), "orderer.ConfigChange")
+	proto.RegisterType((*ConfigChangeResponse)(nil), "orderer.ConfigChangeResponse")
+	proto.RegisterType((*Watermark)(nil), "orderer.Watermark")
+	proto.RegisterType((*WatermarkResponse)(nil), "orderer.WatermarkResponse")
+	proto.RegisterType((*Heartbeat)(nil), "orderer.Heartbeat")
+	proto.RegisterType((*Receipt)(nil), "orderer.Receipt")
+	proto.RegisterType((*SignedReceipt)(nil), "orderer.SignedReceipt")
+	proto.RegisterEnum("orderer.BroadcastResponse_CommitStatus", BroadcastResponse_CommitStatus_name, BroadcastResponse_CommitStatus_value)
+	proto.RegisterEnum("orderer.ErrorDetail_Code", ErrorDetail_Code_name, ErrorDetail_Code_value)
+	proto.RegisterEnum("orderer.SeekInfo_SeekBehavior", SeekInfo_SeekBehavior_name, SeekInfo_SeekBehavior_value)
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// AtomicBroadcastClient is the client API for AtomicBroadcast service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type AtomicBroadcastClient interface {
+	// broadcast receives a reply of Acknowledgement for each common.Envelope in order, indicating success or type of failure
+	Broadcast(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_BroadcastClient, error)
+	// deliver first requires an Envelope of type DELIVER_SEEK_INFO with Payload data as a mashaled SeekInfo message, then a stream of block replies is received.
+	Deliver(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_DeliverClient, error)
+	// broadcast_batch receives a reply for each BroadcastBatch in order, carrying the response to each of its envelopes.  Unlike broadcast, a rejected envelope does not end the stream.
+	BroadcastBatch(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_BroadcastBatchClient, error)
+	// subscribe_config requires an Envelope whose channel header names the channel and whose signer satisfies its readers policy, then receives the config in force followed by each config committed on the channel, until a status ends the stream.
+	SubscribeConfig(ctx context.Context, in *common.Envelope, opts ...grpc.CallOption) (AtomicBroadcast_SubscribeConfigClient, error)
+	// watermark requires an Envelope whose channel header names the channel and whose signer satisfies its readers policy, and returns the current height of the channel signed by the orderer.
+	Watermark(ctx context.Context, in *common.Envelope, opts ...grpc.CallOption) (*WatermarkResponse, error)
+}
+
+type atomicBroadcastClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewAtomicBroadcastClient(cc *grpc.ClientConn) AtomicBroadcastClient {
+	return &atomicBroadcastClient{cc}
+}
+
+func (c *atomicBroadcastClient) Broadcast(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_BroadcastClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_AtomicBroadcast_serviceDesc.Streams[0], "/orderer.AtomicBroadcast/Broadcast", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &atomicBroadcastBroadcastClient{stream}
+	return x, nil
+}
+
+type AtomicBroadcast_BroadcastClient interface {
+	Send(*common.Envelope) error
+	Recv() (*BroadcastResponse, error)
+	grpc.ClientStream
+}
+
+type atomicBroadcastBroadcastClient struct {
+	grpc.ClientStream
+}
+
+func (x *atomicBroadcastBroadcastClient) Send(m *common.Envelope) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *atomicBroadcastBroadcastClient) Recv() (*BroadcastResponse, error) {
+	m := new(BroadcastResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *atomicBroadcastClient) Deliver(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_DeliverClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_AtomicBroadcast_serviceDesc.Streams[1], "/orderer.AtomicBroadcast/Deliver", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &atomicBroadcastDeliverClient{stream}
+	return x, nil
+}
+
+type AtomicBroadcast_DeliverClient interface {
+	Send(*common.Envelope) error
+	Recv() (*DeliverResponse, error)
+	grpc.ClientStream
+}
+
+type atomicBroadcastDeliverClient struct {
+	grpc.ClientStream
+}
+
+func (x *atomicBroadcastDeliverClient) Send(m *common.Envelope) error {
 	return x.ClientStream.SendMsg(m)
 }
 
@@ -672,12 +1645,90 @@ func (x *atomicBroadcastDeliverClient) Recv() (*DeliverResponse, error) {
 	return m, nil
 }
 
+func (c *atomicBroadcastClient) BroadcastBatch(ctx context.Context, opts ...grpc.CallOption) (AtomicBroadcast_BroadcastBatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_AtomicBroadcast_serviceDesc.Streams[2], "/orderer.AtomicBroadcast/BroadcastBatch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &atomicBroadcastBroadcastBatchClient{stream}
+	return x, nil
+}
+
+type AtomicBroadcast_BroadcastBatchClient interface {
+	Send(*BroadcastBatch) error
+	Recv() (*BroadcastBatchResponse, error)
+	grpc.ClientStream
+}
+
+type atomicBroadcastBroadcastBatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *atomicBroadcastBroadcastBatchClient) Send(m *BroadcastBatch) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *atomicBroadcastBroadcastBatchClient) Recv() (*BroadcastBatchResponse, error) {
+	m := new(BroadcastBatchResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *atomicBroadcastClient) SubscribeConfig(ctx context.Context, in *common.Envelope, opts ...grpc.CallOption) (AtomicBroadcast_SubscribeConfigClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_AtomicBroadcast_serviceDesc.Streams[3], "/orderer.AtomicBroadcast/SubscribeConfig", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &atomicBroadcastSubscribeConfigClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type AtomicBroadcast_SubscribeConfigClient interface {
+	Recv() (*ConfigChangeResponse, error)
+	grpc.ClientStream
+}
+
+type atomicBroadcastSubscribeConfigClient struct {
+	grpc.ClientStream
+}
+
+func (x *atomicBroadcastSubscribeConfigClient) Recv() (*ConfigChangeResponse, error) {
+	m := new(ConfigChangeResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (c *atomicBroadcastClient) Watermark(ctx context.Context, in *common.Envelope, opts ...grpc.CallOption) (*WatermarkResponse, error) {
+	out := new(WatermarkResponse)
+	err := c.cc.Invoke(ctx, "/orderer.AtomicBroadcast/Watermark", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // AtomicBroadcastServer is the server API for AtomicBroadcast service.
 type AtomicBroadcastServer interface {
 	// broadcast receives a reply of Acknowledgement for each common.Envelope in order, indicating success or type of failure
 	Broadcast(AtomicBroadcast_BroadcastServer) error
 	// deliver first requires an Envelope of type DELIVER_SEEK_INFO with Payload data as a mashaled SeekInfo message, then a stream of block replies is received.
 	Deliver(AtomicBroadcast_DeliverServer) error
+	// broadcast_batch receives a reply for each BroadcastBatch in order, carrying the response to each of its envelopes.  Unlike broadcast, a rejected envelope does not end the stream.
+	BroadcastBatch(AtomicBroadcast_BroadcastBatchServer) error
+	// subscribe_config requires an Envelope whose channel header names the channel and whose signer satisfies its readers policy, then receives the config in force followed by each config committed on the channel, until a status ends the stream.
+	SubscribeConfig(*common.Envelope, AtomicBroadcast_SubscribeConfigServer) error
+	// watermark requires an Envelope whose channel header names the channel and whose signer satisfies its readers policy, and returns the current height of the channel signed by the orderer.
+	Watermark(context.Context, *common.Envelope) (*WatermarkResponse, error)
 }
 
 func RegisterAtomicBroadcastServer(s *grpc.Server, srv AtomicBroadcastServer) {
@@ -736,10 +1787,80 @@ func (x *atomicBroadcastDeliverServer) Recv() (*common.Envelope, error) {
 	return m, nil
 }
 
+func _AtomicBroadcast_BroadcastBatch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(AtomicBroadcastServer).BroadcastBatch(&atomicBroadcastBroadcastBatchServer{stream})
+}
+
+type AtomicBroadcast_BroadcastBatchServer interface {
+	Send(*BroadcastBatchResponse) error
+	Recv() (*BroadcastBatch, error)
+	grpc.ServerStream
+}
+
+type atomicBroadcastBroadcastBatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *atomicBroadcastBroadcastBatchServer) Send(m *BroadcastBatchResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *atomicBroadcastBroadcastBatchServer) Recv() (*BroadcastBatch, error) {
+	m := new(BroadcastBatch)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func _AtomicBroadcast_SubscribeConfig_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(common.Envelope)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(AtomicBroadcastServer).SubscribeConfig(m, &atomicBroadcastSubscribeConfigServer{stream})
+}
+
+type AtomicBroadcast_SubscribeConfigServer interface {
+	Send(*ConfigChangeResponse) error
+	grpc.ServerStream
+}
+
+type atomicBroadcastSubscribeConfigServer struct {
+	grpc.ServerStream
+}
+
+func (x *atomicBroadcastSubscribeConfigServer) Send(m *ConfigChangeResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func _AtomicBroadcast_Watermark_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(common.Envelope)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(AtomicBroadcastServer).Watermark(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/orderer.AtomicBroadcast/Watermark",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(AtomicBroadcastServer).Watermark(ctx, req.(*common.Envelope))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 var _AtomicBroadcast_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "orderer.AtomicBroadcast",
 	HandlerType: (*AtomicBroadcastServer)(nil),
-	Methods:     []grpc.MethodDesc{},
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Watermark",
+			Handler:    _AtomicBroadcast_Watermark_Handler,
+		},
+	},
 	Streams: []grpc.StreamDesc{
 		{
 			StreamName:    "Broadcast",
@@ -753,44 +1874,121 @@ var _AtomicBroadcast_serviceDesc = grpc.ServiceDesc{
 			ServerStreams: true,
 			ClientStreams: true,
 		},
+		{
+			StreamName:    "BroadcastBatch",
+			Handler:       _AtomicBroadcast_BroadcastBatch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+		{
+			StreamName:    "SubscribeConfig",
+			Handler:       _AtomicBroadcast_SubscribeConfig_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "orderer/ab.proto",
 }
 
-func init() { proto.RegisterFile("orderer/ab.proto", fileDescriptor_ab_86effae0ebc2388c) }
-
-var fileDescriptor_ab_86effae0ebc2388c = []byte{
-	// 505 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x7c, 0x93, 0xdf, 0x6e, 0x12, 0x51,
-	0x10, 0xc6, 0x59, 0xa5, 0xb4, 0x8c, 0x2d, 0xa5, 0xa7, 0x69, 0x43, 0xb8, 0x30, 0xcd, 0x26, 0x55,
-	0x8c, 0xba, 0x6b, 0x30, 0xf1, 0x42, 0x4d, 0x0c, 0x6b, 0xdb, 0x40, 0x24, 0x60, 0x16, 0x7a, 0xa1,
-	0x37, 0x64, 0x77, 0x19, 0x60, 0xed, 0xb2, 0x67, 0x73, 0xce, 0x01, 0xd3, 0xa7, 0xf0, 0x45, 0x7c,
-	0x24, 0x1f, 0xc6, 0x9c, 0x3f, 0xbb, 0x48, 0xd5, 0x5e, 0xb1, 0x33, 0xf3, 0xfb, 0xce, 0x7c, 0x33,
-	0x19, 0xa0, 0x4e, 0xd9, 0x14, 0x19, 0x32, 0x37, 0x08, 0x9d, 0x8c, 0x51, 0x41, 0xc9, 0xae, 0xc9,
-	0x34, 0x8f, 0x23, 0xba, 0x5c, 0xd2, 0xd4, 0xd5, 0x3f, 0xba, 0x6a, 0x0f, 0xe1, 0xc8, 0x63, 0x34,
-	0x98, 0x46, 0x01, 0x17, 0x3e, 0xf2, 0x8c, 0xa6, 0x1c, 0xc9, 0x13, 0xa8, 0x70, 0x11, 0x88, 0x15,
-	0x6f, 0x58, 0x67, 0x56, 0xab, 0xd6, 0xae, 0x39, 0x46, 0x33, 0x52, 0x59, 0xdf, 0x54, 0x09, 0x81,
-	0x72, 0x9c, 0xce, 0x68, 0xe3, 0xc1, 0x99, 0xd5, 0xaa, 0xfa, 0xea, 0xdb, 0xde, 0x07, 0x18, 0x21,
-	0xde, 0x0c, 0xf0, 0x3b, 0x72, 0x91, 0x47, 0xc3, 0x64, 0x2a, 0xa3, 0xa7, 0x70, 0x20, 0xa3, 0x51,
-	0x86, 0x51, 0x3c, 0x8b, 0x71, 0x4a, 0x4e, 0xa1, 0x92, 0xae, 0x96, 0x21, 0x32, 0xd5, 0xa8, 0xec,
-	0x9b, 0xc8, 0xfe, 0x69, 0xc1, 0xbe, 0x24, 0x3f, 0x53, 0x1e, 0x8b, 0x98, 0xa6, 0xe4, 0x25, 0x54,
-	0x52, 0xf5, 0xa2, 0x02, 0x1f, 0xb5, 0x8f, 0x1d, 0x33, 0x95, 0xb3, 0x69, 0xd6, 0x2d, 0xf9, 0x06,
-	0x92, 0x38, 0x55, 0x2d, 0x95, 0xb5, 0xbb, 0xb8, 0x76, 0x23, 0x71, 0x0d, 0x91, 0x37, 0x50, 0xe5,
-	0xb9, 0xa7, 0xc6, 0x43, 0xa5, 0x38, 0xdd, 0x52, 0x14, 0x8e, 0xbb, 0x25, 0x7f, 0x83, 0x7a, 0x15,
-	0x28, 0x8f, 0x6f, 0x33, 0xb4, 0x7f, 0x59, 0xb0, 0x27, 0xb1, 0x5e, 0x3a, 0xa3, 0xe4, 0x39, 0xec,
-	0x70, 0x11, 0xb0, 0xdc, 0xe9, 0xc9, 0xd6, 0x43, 0xf9, 0x40, 0xbe, 0x66, 0xc8, 0x33, 0x28, 0x73,
-	0x41, 0x33, 0x63, 0xf3, 0x3f, 0xac, 0x42, 0xc8, 0x5b, 0xd8, 0x0b, 0x71, 0x11, 0xac, 0x63, 0xca,
-	0x94, 0xc7, 0x5a, 0xfb, 0xf1, 0x16, 0x2e, 0x9b, 0xab, 0x0f, 0xcf, 0x50, 0x7e, 0xc1, 0xdb, 0xef,
-	0xf5, 0x3a, 0xf3, 0x0a, 0x39, 0x81, 0x23, 0xaf, 0x3f, 0xfc, 0xf8, 0x69, 0x72, 0x3d, 0x18, 0xf7,
-	0xfa, 0x13, 0xff, 0xb2, 0x73, 0xf1, 0xa5, 0x5e, 0x92, 0xe9, 0xab, 0x4e, 0xaf, 0x3f, 0xe9, 0x5d,
-	0x4d, 0x06, 0xc3, 0xb1, 0x49, 0x5b, 0xf6, 0x37, 0x38, 0xbc, 0xc0, 0x24, 0x5e, 0x23, 0x2b, 0x2e,
-	0xa4, 0x75, 0xff, 0x85, 0xc8, 0xdd, 0x9a, 0x1b, 0x39, 0x87, 0x9d, 0x30, 0xa1, 0xd1, 0x8d, 0x19,
-	0xf1, 0x20, 0x07, 0x3d, 0x99, 0xec, 0x96, 0x7c, 0x5d, 0xcd, 0x57, 0xd9, 0xfe, 0x61, 0xc1, 0x61,
-	0x47, 0xd0, 0x65, 0x1c, 0x15, 0x67, 0x49, 0x3e, 0x40, 0x75, 0x13, 0xd4, 0xf3, 0x07, 0x2e, 0xd3,
-	0x35, 0x26, 0x34, 0xc3, 0x66, 0xb3, 0x58, 0xc3, 0x5f, 0x97, 0x6c, 0x97, 0x5a, 0xd6, 0x2b, 0x8b,
-	0xbc, 0x83, 0x5d, 0x33, 0xc0, 0x3f, 0xe4, 0x8d, 0x42, 0x7e, 0x67, 0x48, 0x2d, 0xf6, 0xae, 0xe1,
-	0x9c, 0xb2, 0xb9, 0xb3, 0xb8, 0xcd, 0x90, 0x25, 0x38, 0x9d, 0x23, 0x73, 0x66, 0x41, 0xc8, 0xe2,
-	0x48, 0xff, 0x83, 0x78, 0x2e, 0xff, 0xfa, 0x62, 0x1e, 0x8b, 0xc5, 0x2a, 0x94, 0x0d, 0xdc, 0x3f,
-	0x68, 0x57, 0xd3, 0xae, 0xa6, 0x5d, 0x43, 0x87, 0x15, 0x15, 0xbf, 0xfe, 0x1d, 0x00, 0x00, 0xff,
-	0xff, 0x4b, 0x88, 0xa4, 0x39, 0xb1, 0x03, 0x00, 0x00,
+func init() { proto.RegisterFile("orderer/ab.proto", fileDescriptor_ab_0c858c05dda8e4ff) }
+
+var fileDescriptor_ab_0c858c05dda8e4ff = []byte{
+	// 1563 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x9c, 0x57, 0xdd, 0x6e, 0xe3, 0xc6,
+	0x15, 0x36, 0xf5, 0x67, 0xf3, 0xe8, 0xc7, 0xdc, 0xd9, 0xf5, 0x56, 0xeb, 0x36, 0x89, 0x4b, 0x60,
+	0xb3, 0x4a, 0xdb, 0xc8, 0x0b, 0x15, 0x68, 0x8b, 0xb4, 0x40, 0x4b, 0x8b, 0x74, 0x4c, 0x44, 0xa6,
+	0xdc, 0x91, 0xbc, 0x49, 0x7a, 0x43, 0x8c, 0xc8, 0x91, 0x44, 0x2c, 0x25, 0x2a, 0xc3, 0xf1, 0xc6,
+	0x06, 0x7a, 0xdb, 0x87, 0xe8, 0x6d, 0xd1, 0xcb, 0x5e, 0x14, 0xe8, 0x55, 0xde, 0xa0, 0xe8, 0x83,
+	0xf4, 0x39, 0x8a, 0x19, 0x0e, 0x29, 0x59, 0xf2, 0x6e, 0x93, 0xbd, 0xb2, 0xce, 0x77, 0xbe, 0x39,
+	0x73, 0x78, 0xfe, 0xe6, 0x18, 0x8c, 0x84, 0x85, 0x94, 0x51, 0x76, 0x4a, 0x26, 0xdd, 0x15, 0x4b,
+	0x78, 0x82, 0xf6, 0x15, 0x72, 0xfc, 0x38, 0x48, 0x16, 0x8b, 0x64, 0x79, 0x9a, 0xfd, 0xc9, 0xb4,
+	0xc7, 0x47, 0x05, 0xb8, 0x9c, 0x46, 0x33, 0x7e, 0xab, 0xe0, 0x0f, 0x67, 0x49, 0x32, 0x8b, 0xe9,
+	0xa9, 0x94, 0x26, 0x37, 0xd3, 0xd3, 0xf0, 0x86, 0x11, 0x1e, 0x15, 0xc7, 0x3e, 0xda, 0xd6, 0xf3,
+	0x68, 0x41, 0x53, 0x4e, 0x16, 0xab, 0x8c, 0x60, 0xfe, 0xa7, 0x0c, 0x8f, 0xce, 0x58, 0x42, 0xc2,
+	0x80, 0xa4, 0x1c, 0xd3, 0x74, 0x95, 0x2c, 0x53, 0x8a, 0x3e, 0x86, 0x5a, 0xca, 0x09, 0xbf, 0x49,
+	0xdb, 0xda, 0x89, 0xd6, 0x69, 0xf5, 0x5a, 0x5d, 0xe5, 0xcc, 0x48, 0xa2, 0x58, 0x69, 0x11, 0x82,
+	0x4a, 0xb4, 0x9c, 0x26, 0xed, 0xd2, 0x89, 0xd6, 0xd1, 0xb1, 0xfc, 0x8d, 0x9e, 0x43, 0x2b, 0x48,
+	0x18, 0xa3, 0xb1, 0xf4, 0xc3, 0x8f, 0xc2, 0x76, 0xf9, 0x44, 0xeb, 0x54, 0x70, 0x73, 0x03, 0x75,
+	0x43, 0xf4, 0x6b, 0x68, 0x50, 0xc6, 0x12, 0xe6, 0x87, 0x94, 0x93, 0x28, 0x6e, 0x57, 0x4e, 0xb4,
+	0x4e, 0xbd, 0xf7, 0xa4, 0xab, 0xa2, 0xd0, 0x75, 0x84, 0xd2, 0x96, 0x3a, 0x5c, 0xa7, 0x6b, 0x01,
+	0xbd, 0x04, 0x7d, 0x4e, 0x09, 0xe3, 0x13, 0x4a, 0x78, 0xbb, 0x2a, 0x4f, 0xa1, 0xe2, 0xd4, 0x45,
+	0xae, 0xc1, 0x6b, 0x12, 0x7a, 0x09, 0xfb, 0x8c, 0x06, 0x34, 0x5a, 0xf1, 0x76, 0x4d, 0xf2, 0x9f,
+	0x16, 0xfc, 0x51, 0x34, 0x5b, 0xd2, 0x10, 0x67, 0x5a, 0x9c, 0xd3, 0xd0, 0x00, 0x9a, 0xe2, 0x83,
+	0x23, 0xee, 0xab, 0x30, 0xec, 0xcb, 0x30, 0xbc, 0x28, 0xce, 0xed, 0x84, 0xac, 0xdb, 0x97, 0x7c,
+	0x15, 0x9f, 0x46, 0xb0, 0x21, 0xa1, 0x9f, 0x42, 0x63, 0x12, 0x27, 0xc1, 0x6b, 0x7f, 0x79, 0xb3,
+	0x98, 0x50, 0xd6, 0x3e, 0x90, 0xf1, 0xa8, 0x4b, 0xcc, 0x93, 0x90, 0x69, 0x43, 0x63, 0xd3, 0x00,
+	0x42, 0xd0, 0xea, 0x0f, 0x2f, 0x2f, 0xdd, 0xb1, 0x7f, 0xed, 0x7d, 0xe1, 0x0d, 0xbf, 0xf4, 0x8c,
+	0x3d, 0xd4, 0x04, 0x3d, 0xc3, 0xc6, 0x8e, 0x6d, 0x68, 0xe8, 0x11, 0x34, 0xbd, 0xe1, 0xd8, 0x5f,
+	0x43, 0x25, 0xf3, 0x5f, 0x65, 0xa8, 0x6f, 0xc4, 0x0d, 0x7d, 0x0a, 0x95, 0x20, 0x09, 0xa9, 0x4a,
+	0xe2, 0xb3, 0x87, 0x62, 0xdb, 0xed, 0x27, 0x21, 0xc5, 0x92, 0x86, 0x3e, 0x83, 0x3a, 0xa3, 0x9c,
+	0xdd, 0xf9, 0x64, 0xca, 0x29, 0x93, 0x49, 0xad, 0xf7, 0x9e, 0x75, 0xb3, 0x12, 0xea, 0xe6, 0x25,
+	0xd4, 0xb5, 0x55, 0x89, 0x61, 0x90, 0x6c, 0x4b, 0x90, 0xd1, 0x07, 0x00, 0xd3, 0x88, 0xc6, 0xa1,
+	0xbf, 0x22, 0x7c, 0x2e, 0x33, 0xae, 0x63, 0x5d, 0x22, 0x57, 0x84, 0xcf, 0xcd, 0xbf, 0x96, 0xa0,
+	0x22, 0x6e, 0x42, 0x87, 0x50, 0xbf, 0xf6, 0x46, 0x57, 0x4e, 0xdf, 0x3d, 0x77, 0x1d, 0xdb, 0xd8,
+	0x43, 0x47, 0xf0, 0xe8, 0xd2, 0x1a, 0x9c, 0x0f, 0xf1, 0xa5, 0x63, 0xfb, 0x97, 0xce, 0x68, 0x64,
+	0x7d, 0xee, 0x18, 0x1a, 0x7a, 0x0c, 0x87, 0xae, 0xf7, 0xca, 0x1a, 0xb8, 0x6b, 0xb0, 0x24, 0xb9,
+	0x99, 0xe0, 0x8f, 0x87, 0x43, 0x7f, 0x60, 0xe1, 0xcf, 0x1d, 0xa3, 0x2c, 0xe0, 0xfe, 0x85, 0xe5,
+	0x79, 0xce, 0xc0, 0x17, 0x11, 0x39, 0x1f, 0x5e, 0x7b, 0xb6, 0x51, 0x11, 0xf0, 0x95, 0x83, 0x2f,
+	0xdd, 0xd1, 0xc8, 0x1d, 0x7a, 0xbe, 0xed, 0x78, 0xe2, 0xc2, 0x2a, 0x32, 0xa0, 0x81, 0xad, 0xb1,
+	0xe3, 0x0f, 0xdc, 0x4b, 0x57, 0x84, 0xad, 0x86, 0x5a, 0x00, 0xc3, 0x57, 0x0e, 0x1e, 0x0c, 0x2d,
+	0xdb, 0xb1, 0x8d, 0x7d, 0xf4, 0x0c, 0x8e, 0xfa, 0x43, 0x6f, 0xe4, 0x78, 0x63, 0x07, 0xfb, 0xd7,
+	0x9e, 0xf5, 0xca, 0x72, 0x07, 0xd6, 0xd9, 0xc0, 0x31, 0x0e, 0xd0, 0x13, 0x30, 0x2c, 0x7b, 0xcb,
+	0xa4, 0x2e, 0x50, 0xd7, 0x76, 0xbc, 0xb1, 0x3b, 0xfe, 0xda, 0x77, 0xbe, 0xba, 0x72, 0xb1, 0x63,
+	0x1b, 0x20, 0x73, 0x38, 0x70, 0x1d, 0x6f, 0xec, 0x9f, 0x0d, 0x86, 0xfd, 0x2f, 0x1c, 0xdb, 0xa8,
+	0x0b, 0xec, 0x8f, 0xd7, 0xc3, 0xb1, 0xe5, 0x3b, 0x5f, 0xf5, 0x1d, 0x47, 0x5c, 0xd7, 0x30, 0xff,
+	0x00, 0xad, 0xa2, 0x9c, 0xce, 0x08, 0x0f, 0xe6, 0xa8, 0x0b, 0x3a, 0x5d, 0xbe, 0xa1, 0x71, 0xb2,
+	0xa2, 0xa2, 0x03, 0xcb, 0x9d, 0x7a, 0xcf, 0xc8, 0x3b, 0xd0, 0x51, 0x0a, 0xbc, 0xa6, 0x98, 0x18,
+	0x9e, 0xde, 0xb7, 0x50, 0x34, 0xf2, 0x6f, 0x40, 0x67, 0xea, 0x77, 0x6e, 0xe9, 0xf8, 0xed, 0x45,
+	0x8c, 0xd7, 0x64, 0xb3, 0x01, 0x30, 0xa2, 0xf4, 0xb5, 0x47, 0xbf, 0xa5, 0x29, 0xcf, 0xa5, 0x61,
+	0x1c, 0x0a, 0xe9, 0x05, 0x34, 0x85, 0x34, 0x5a, 0xd1, 0x20, 0x9a, 0x46, 0x34, 0x44, 0x4f, 0xa1,
+	0xa6, 0x6a, 0x5b, 0x93, 0xb5, 0xad, 0x24, 0xf3, 0x1f, 0x1a, 0x34, 0x04, 0xf3, 0x2a, 0x49, 0x23,
+	0x51, 0x32, 0xe8, 0x53, 0xa8, 0x2d, 0xa5, 0x45, 0x49, 0xac, 0xf7, 0x1e, 0xaf, 0x3b, 0xb1, 0xb8,
+	0xec, 0x62, 0x0f, 0x2b, 0x92, 0xa0, 0x27, 0xf2, 0xca, 0x76, 0xe9, 0x01, 0x7a, 0xe6, 0x8d, 0xa0,
+	0x67, 0x24, 0xf4, 0x2b, 0xd0, 0xd3, 0xdc, 0xa7, 0x76, 0x79, 0xbb, 0xd5, 0x37, 0x3d, 0xbe, 0xd8,
+	0xc3, 0x6b, 0xea, 0x59, 0x0d, 0x2a, 0xe3, 0xbb, 0x15, 0x35, 0xff, 0x5d, 0x82, 0x03, 0x41, 0x73,
+	0xc5, 0x1c, 0xfb, 0x39, 0x54, 0x53, 0x4e, 0x58, 0xee, 0xe9, 0xd1, 0x3d, 0x43, 0xf9, 0x07, 0xe1,
+	0x8c, 0x83, 0x3e, 0x81, 0x4a, 0xca, 0x93, 0x55, 0xbb, 0xf4, 0x2e, 0xae, 0xa4, 0xa0, 0xcf, 0xe0,
+	0x60, 0x42, 0xe7, 0xe4, 0x4d, 0x94, 0x30, 0xe9, 0x63, 0xab, 0xf7, 0xe1, 0x3d, 0xba, 0xb8, 0x5c,
+	0xfe, 0x38, 0x53, 0x2c, 0x5c, 0xf0, 0x45, 0x97, 0x2d, 0xc8, 0xad, 0x2f, 0x27, 0x47, 0x2a, 0x47,
+	0x66, 0x05, 0xeb, 0x0b, 0x72, 0x7b, 0x26, 0x01, 0xf4, 0x63, 0xd0, 0xa5, 0xfa, 0x8e, 0xd3, 0x54,
+	0x8e, 0xc6, 0x0a, 0x3e, 0x10, 0x5a, 0x21, 0xa3, 0x2e, 0xd4, 0xa6, 0x51, 0x2c, 0x1a, 0x7b, 0x7b,
+	0x08, 0xda, 0x34, 0x8e, 0xde, 0x50, 0x76, 0x2e, 0xb5, 0x58, 0xb1, 0xcc, 0xdf, 0x41, 0x63, 0xd3,
+	0x0b, 0xd1, 0x4e, 0xb2, 0x8e, 0xfd, 0x6b, 0x6f, 0xec, 0x0e, 0x7c, 0xec, 0x58, 0xf6, 0xd7, 0x59,
+	0xff, 0x9e, 0x5b, 0xee, 0xc0, 0x77, 0xcf, 0x65, 0xf3, 0x65, 0xb0, 0x66, 0x7e, 0xa7, 0x41, 0xf3,
+	0x9e, 0x5d, 0xf4, 0x02, 0x0e, 0x83, 0x39, 0x89, 0x96, 0x62, 0xd4, 0xf8, 0x4b, 0xb2, 0x50, 0x05,
+	0xa9, 0xe3, 0x56, 0x01, 0x7b, 0x02, 0x45, 0x1d, 0xa8, 0xf2, 0x3b, 0x51, 0xf9, 0xa5, 0x93, 0x72,
+	0xa7, 0xd5, 0x43, 0x79, 0xe5, 0x5f, 0x50, 0x12, 0x52, 0x26, 0x12, 0x85, 0x33, 0x02, 0xfa, 0x18,
+	0x0e, 0x03, 0x46, 0x09, 0x4f, 0x98, 0xbf, 0x48, 0x57, 0x7e, 0x14, 0xa6, 0xed, 0xb2, 0x34, 0xd9,
+	0x54, 0xf0, 0x65, 0xba, 0x72, 0xc3, 0x14, 0x1d, 0x41, 0x8d, 0xdf, 0x4a, 0x75, 0x45, 0xaa, 0xab,
+	0xfc, 0x56, 0xc0, 0x4f, 0xa1, 0x16, 0x93, 0x09, 0x8d, 0x45, 0xac, 0x04, 0xac, 0x24, 0xf3, 0x9f,
+	0x1a, 0x1c, 0x2a, 0xdf, 0x8b, 0x46, 0xea, 0xbc, 0xfb, 0x45, 0x14, 0x45, 0x98, 0xe9, 0xd1, 0x73,
+	0xa8, 0xca, 0xfc, 0xa8, 0x5a, 0x68, 0xe6, 0x44, 0x99, 0xa3, 0x8b, 0x3d, 0x9c, 0x69, 0x51, 0x6f,
+	0xf3, 0x19, 0xab, 0xbc, 0xed, 0x19, 0x13, 0x75, 0x5a, 0xd0, 0x90, 0x01, 0xe5, 0x05, 0x09, 0x64,
+	0xd5, 0x34, 0xb0, 0xf8, 0x59, 0x54, 0xee, 0x5f, 0x34, 0xf1, 0x80, 0x88, 0xd5, 0xa0, 0x3f, 0x27,
+	0xcb, 0x19, 0xdd, 0x79, 0x73, 0xb4, 0x9d, 0x37, 0x47, 0x3c, 0xf2, 0xd9, 0x36, 0xa1, 0x3c, 0x2d,
+	0x3e, 0x29, 0x33, 0x84, 0x95, 0x16, 0xfd, 0x0c, 0xaa, 0x21, 0x8d, 0x39, 0x51, 0x1d, 0xf5, 0xe4,
+	0x3e, 0xed, 0x7a, 0x15, 0x12, 0x4e, 0x71, 0x46, 0x31, 0xef, 0xe0, 0xc9, 0xa6, 0x1b, 0xef, 0x11,
+	0xbe, 0x53, 0xa8, 0x05, 0xf2, 0xec, 0x4e, 0x2f, 0x6d, 0x1a, 0x16, 0x07, 0x32, 0x5a, 0x11, 0x82,
+	0xbf, 0x6b, 0xa0, 0x7f, 0x49, 0x38, 0x65, 0x0b, 0xc2, 0x5e, 0x8b, 0x4e, 0x11, 0xfa, 0x25, 0x8d,
+	0xc5, 0x06, 0xa2, 0x65, 0xef, 0x91, 0x42, 0x5c, 0x39, 0xb0, 0xe6, 0x34, 0x9a, 0xcd, 0xb3, 0xc1,
+	0x52, 0xc1, 0x4a, 0x12, 0x15, 0x15, 0x93, 0x94, 0x67, 0x1d, 0xe6, 0xcf, 0x49, 0x3a, 0x57, 0xd1,
+	0x6e, 0x0a, 0x38, 0x4b, 0x21, 0x49, 0xe7, 0x62, 0xae, 0x16, 0x9b, 0x94, 0xca, 0xde, 0xf1, 0xce,
+	0x43, 0x39, 0xce, 0x19, 0x78, 0x4d, 0x36, 0xff, 0xa6, 0xc1, 0xa3, 0xc2, 0xcd, 0x1f, 0xbc, 0x70,
+	0xfd, 0x04, 0xf4, 0x6f, 0xf3, 0xc3, 0xd2, 0xf5, 0x06, 0x5e, 0x03, 0xe8, 0x13, 0x30, 0xd2, 0x68,
+	0xb6, 0x24, 0xfc, 0x86, 0x51, 0x7f, 0x2e, 0xdb, 0x45, 0xb9, 0x7f, 0x58, 0xe0, 0x59, 0x17, 0x09,
+	0x43, 0x05, 0x24, 0x3f, 0xa0, 0x81, 0xd7, 0x80, 0xf9, 0x67, 0xd0, 0x8b, 0x12, 0x7c, 0xdf, 0x50,
+	0xde, 0x0b, 0x51, 0xf9, 0x87, 0x84, 0xe8, 0x3b, 0x0d, 0xf6, 0xd5, 0x4a, 0xf6, 0xff, 0x2e, 0x7f,
+	0x0c, 0x55, 0xd9, 0xd9, 0xf9, 0x06, 0x2a, 0x1a, 0x5b, 0x9e, 0x91, 0xb5, 0xe2, 0xa7, 0xf4, 0x1b,
+	0xb5, 0x7d, 0xea, 0x19, 0x32, 0xa2, 0xdf, 0xbc, 0x7f, 0xee, 0x44, 0x53, 0xad, 0xc8, 0x5d, 0x9c,
+	0x90, 0x30, 0x2b, 0x8d, 0xaa, 0x8c, 0x5b, 0x5d, 0x61, 0xa2, 0x30, 0x4c, 0x06, 0xcd, 0x7b, 0x3b,
+	0x25, 0x6a, 0xaf, 0x97, 0x4f, 0x4d, 0xd2, 0x73, 0xf1, 0xc1, 0x6c, 0x95, 0xbe, 0x47, 0xb6, 0xca,
+	0x5b, 0xd9, 0xea, 0xfd, 0xb7, 0x04, 0x87, 0x16, 0x4f, 0x16, 0x51, 0x50, 0xbc, 0xe8, 0xe8, 0xf7,
+	0xa0, 0xaf, 0x85, 0x9d, 0xe5, 0xe1, 0xf8, 0x1d, 0x4b, 0x80, 0xb9, 0xd7, 0xd1, 0x5e, 0x6a, 0xe8,
+	0xb7, 0xb0, 0xaf, 0x66, 0xe0, 0x03, 0xc7, 0xdb, 0xdb, 0x6f, 0xc7, 0xd6, 0xe1, 0xab, 0x9d, 0x95,
+	0xe6, 0x47, 0xbb, 0x17, 0x4a, 0xc5, 0xf1, 0x47, 0x6f, 0x51, 0x6c, 0x59, 0x3c, 0x87, 0xc3, 0xd1,
+	0xcd, 0x24, 0x0d, 0x58, 0x34, 0xa1, 0xd9, 0x20, 0x78, 0xc0, 0xad, 0x0f, 0x1e, 0x9c, 0x15, 0x6b,
+	0x4b, 0xf2, 0xb3, 0x36, 0x86, 0xc4, 0xbb, 0xe2, 0xb2, 0xd3, 0xa3, 0xe6, 0xde, 0xd9, 0x35, 0x3c,
+	0x4f, 0xd8, 0xac, 0x3b, 0xbf, 0x5b, 0x51, 0x16, 0xd3, 0x70, 0x46, 0x59, 0x77, 0x4a, 0x26, 0x2c,
+	0x0a, 0xb2, 0xba, 0x49, 0xf3, 0xc3, 0x7f, 0xfa, 0xc5, 0x2c, 0xe2, 0xf3, 0x9b, 0x89, 0x30, 0x7f,
+	0xba, 0xc1, 0x3e, 0xcd, 0xd8, 0xd9, 0x7f, 0x63, 0xe9, 0xa9, 0x62, 0x4f, 0x6a, 0x52, 0xfe, 0xe5,
+	0xff, 0x06, 0x00, 0x92, 0x79, 0xf4, 0x99, 0x14, 0x0e, 0x00, 0x00,
 }
@@ -122,6 +122,12 @@ func (docv *DynamicOrdererConfigValue) VariablyOpaqueFieldProto(name string) (pr
 		return &KafkaBrokers{}, nil
 	case "ChannelRestrictions":
 		return &ChannelRestrictions{}, nil
+	case "IngressWeight":
+		return &IngressWeight{}, nil
+	case "IngressQuotas":
+		return &IngressQuotas{}, nil
+	case "TransactionLabels":
+		return &TransactionLabels{}, nil
 	case "Capabilities":
 		return &common.Capabilities{}, nil
 	default:
@@ -49,7 +49,7 @@ func (x ConsensusType_MigrationState) String() string {
 	return proto.EnumName(ConsensusType_MigrationState_name, int32(x))
 }
 func (ConsensusType_MigrationState) EnumDescriptor() ([]byte, []int) {
-	return fileDescriptor_configuration_50e2c0f98937cf5a, []int{0, 0}
+	return fileDescriptor_configuration_8db3e5bd5dced587, []int{0, 0}
 }
 
 type ConsensusType struct {
@@ -66,7 +66,11 @@ type ConsensusType struct {
 	// The migration context is the system channel block height of the config-update-tx that START migration.
 	// The context must be present in COMMIT, CONTEXT, and ABORT config-updates, and match the START block height.
 	// On NONE and START it is set =0.
-	MigrationContext     uint64   `protobuf:"varint,4,opt,name=migration_context,json=migrationContext,proto3" json:"migration_context,omitempty"`
+	MigrationContext uint64 `protobuf:"varint,4,opt,name=migration_context,json=migrationContext,proto3" json:"migration_context,omitempty"`
+	// The version of the format of the metadata, which the orderers must
+	// support and which may not be lowered once set.  Unversioned metadata
+	// is at version 0.
+	MetadataVersion      uint32   `protobuf:"varint,5,opt,name=metadata_version,json=metadataVersion,proto3" json:"metadata_version,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -76,7 +80,7 @@ func (m *ConsensusType) Reset()         { *m = ConsensusType{} }
 func (m *ConsensusType) String() string { return proto.CompactTextString(m) }
 func (*ConsensusType) ProtoMessage()    {}
 func (*ConsensusType) Descriptor() ([]byte, []int) {
-	return fileDescriptor_configuration_50e2c0f98937cf5a, []int{0}
+	return fileDescriptor_configuration_8db3e5bd5dced587, []int{0}
 }
 func (m *ConsensusType) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ConsensusType.Unmarshal(m, b)
@@ -124,6 +128,13 @@ func (m *ConsensusType) GetMigrationContext() uint64 {
 	return 0
 }
 
+func (m *ConsensusType) GetMetadataVersion() uint32 {
+	if m != nil {
+		return m.MetadataVersion
+	}
+	return 0
+}
+
 type BatchSize struct {
 	// Simply specified as number of messages for now, in the future
 	// we may want to allow this to be specified by size in bytes
@@ -143,7 +154,7 @@ func (m *BatchSize) Reset()         { *m = BatchSize{} }
 func (m *BatchSize) String() string { return proto.CompactTextString(m) }
 func (*BatchSize) ProtoMessage()    {}
 func (*BatchSize) Descriptor() ([]byte, []int) {
-	return fileDescriptor_configuration_50e2c0f98937cf5a, []int{1}
+	return fileDescriptor_configuration_8db3e5bd5dced587, []int{1}
 }
 func (m *BatchSize) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BatchSize.Unmarshal(m, b)
@@ -197,7 +208,7 @@ func (m *BatchTimeout) Reset()         { *m = BatchTimeout{} }
 func (m *BatchTimeout) String() string { return proto.CompactTextString(m) }
 func (*BatchTimeout) ProtoMessage()    {}
 func (*BatchTimeout) Descriptor() ([]byte, []int) {
-	return fileDescriptor_configuration_50e2c0f98937cf5a, []int{2}
+	return fileDescriptor_configuration_8db3e5bd5dced587, []int{2}
 }
 func (m *BatchTimeout) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_BatchTimeout.Unmarshal(m, b)
@@ -239,7 +250,7 @@ func (m *KafkaBrokers) Reset()         { *m = KafkaBrokers{} }
 func (m *KafkaBrokers) String() string { return proto.CompactTextString(m) }
 func (*KafkaBrokers) ProtoMessage()    {}
 func (*KafkaBrokers) Descriptor() ([]byte, []int) {
-	return fileDescriptor_configuration_50e2c0f98937cf5a, []int{3}
+	return fileDescriptor_configuration_8db3e5bd5dced587, []int{3}
 }
 func (m *KafkaBrokers) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_KafkaBrokers.Unmarshal(m, b)
@@ -278,7 +289,7 @@ func (m *ChannelRestrictions) Reset()         { *m = ChannelRestrictions{} }
 func (m *ChannelRestrictions) String() string { return proto.CompactTextString(m) }
 func (*ChannelRestrictions) ProtoMessage()    {}
 func (*ChannelRestrictions) Descriptor() ([]byte, []int) {
-	return fileDescriptor_configuration_50e2c0f98937cf5a, []int{4}
+	return fileDescriptor_configuration_8db3e5bd5dced587, []int{4}
 }
 func (m *ChannelRestrictions) XXX_Unmarshal(b []byte) error {
 	return xxx_messageInfo_ChannelRestrictions.Unmarshal(m, b)
@@ -305,48 +316,304 @@ func (m *ChannelRestrictions) GetMaxCount() uint64 {
 	return 0
 }
 
+// IngressWeight is the share of the ordering throughput of the orderer a
+// channel is granted while other channels compete for it
+type IngressWeight struct {
+	// The weight of the channel relative to the other channels, 1 if 0.
+	Weight               uint32   `protobuf:"varint,1,opt,name=weight,proto3" json:"weight,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IngressWeight) Reset()         { *m = IngressWeight{} }
+func (m *IngressWeight) String() string { return proto.CompactTextString(m) }
+func (*IngressWeight) ProtoMessage()    {}
+func (*IngressWeight) Descriptor() ([]byte, []int) {
+	return fileDescriptor_configuration_8db3e5bd5dced587, []int{5}
+}
+func (m *IngressWeight) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IngressWeight.Unmarshal(m, b)
+}
+func (m *IngressWeight) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IngressWeight.Marshal(b, m, deterministic)
+}
+func (dst *IngressWeight) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IngressWeight.Merge(dst, src)
+}
+func (m *IngressWeight) XXX_Size() int {
+	return xxx_messageInfo_IngressWeight.Size(m)
+}
+func (m *IngressWeight) XXX_DiscardUnknown() {
+	xxx_messageInfo_IngressWeight.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IngressWeight proto.InternalMessageInfo
+
+func (m *IngressWeight) GetWeight() uint32 {
+	if m != nil {
+		return m.Weight
+	}
+	return 0
+}
+
+// IngressQuotas are the transaction rates the organizations may broadcast to
+// a channel, as contracted with the ordering service
+type IngressQuotas struct {
+	Quotas               []*IngressQuota `protobuf:"bytes,1,rep,name=quotas,proto3" json:"quotas,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *IngressQuotas) Reset()         { *m = IngressQuotas{} }
+func (m *IngressQuotas) String() string { return proto.CompactTextString(m) }
+func (*IngressQuotas) ProtoMessage()    {}
+func (*IngressQuotas) Descriptor() ([]byte, []int) {
+	return fileDescriptor_configuration_8db3e5bd5dced587, []int{6}
+}
+func (m *IngressQuotas) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IngressQuotas.Unmarshal(m, b)
+}
+func (m *IngressQuotas) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IngressQuotas.Marshal(b, m, deterministic)
+}
+func (dst *IngressQuotas) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IngressQuotas.Merge(dst, src)
+}
+func (m *IngressQuotas) XXX_Size() int {
+	return xxx_messageInfo_IngressQuotas.Size(m)
+}
+func (m *IngressQuotas) XXX_DiscardUnknown() {
+	xxx_messageInfo_IngressQuotas.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IngressQuotas proto.InternalMessageInfo
+
+func (m *IngressQuotas) GetQuotas() []*IngressQuota {
+	if m != nil {
+		return m.Quotas
+	}
+	return nil
+}
+
+// IngressQuota is the transaction rate an organization may broadcast to a
+// channel
+type IngressQuota struct {
+	MspId                string   `protobuf:"bytes,1,opt,name=msp_id,json=mspId,proto3" json:"msp_id,omitempty"`
+	Rate                 uint32   `protobuf:"varint,2,opt,name=rate,proto3" json:"rate,omitempty"`
+	Burst                uint32   `protobuf:"varint,3,opt,name=burst,proto3" json:"burst,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *IngressQuota) Reset()         { *m = IngressQuota{} }
+func (m *IngressQuota) String() string { return proto.CompactTextString(m) }
+func (*IngressQuota) ProtoMessage()    {}
+func (*IngressQuota) Descriptor() ([]byte, []int) {
+	return fileDescriptor_configuration_8db3e5bd5dced587, []int{7}
+}
+func (m *IngressQuota) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_IngressQuota.Unmarshal(m, b)
+}
+func (m *IngressQuota) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_IngressQuota.Marshal(b, m, deterministic)
+}
+func (dst *IngressQuota) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_IngressQuota.Merge(dst, src)
+}
+func (m *IngressQuota) XXX_Size() int {
+	return xxx_messageInfo_IngressQuota.Size(m)
+}
+func (m *IngressQuota) XXX_DiscardUnknown() {
+	xxx_messageInfo_IngressQuota.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_IngressQuota proto.InternalMessageInfo
+
+func (m *IngressQuota) GetMspId() string {
+	if m != nil {
+		return m.MspId
+	}
+	return ""
+}
+
+func (m *IngressQuota) GetRate() uint32 {
+	if m != nil {
+		return m.Rate
+	}
+	return 0
+}
+
+func (m *IngressQuota) GetBurst() uint32 {
+	if m != nil {
+		return m.Burst
+	}
+	return 0
+}
+
+// TransactionLabels are the visibility labels the transactions of a channel
+// may carry, partitioning the channel without creating more channels
+type TransactionLabels struct {
+	Labels               []*TransactionLabel `protobuf:"bytes,1,rep,name=labels,proto3" json:"labels,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}            `json:"-"`
+	XXX_unrecognized     []byte              `json:"-"`
+	XXX_sizecache        int32               `json:"-"`
+}
+
+func (m *TransactionLabels) Reset()         { *m = TransactionLabels{} }
+func (m *TransactionLabels) String() string { return proto.CompactTextString(m) }
+func (*TransactionLabels) ProtoMessage()    {}
+func (*TransactionLabels) Descriptor() ([]byte, []int) {
+	return fileDescriptor_configuration_8db3e5bd5dced587, []int{8}
+}
+func (m *TransactionLabels) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TransactionLabels.Unmarshal(m, b)
+}
+func (m *TransactionLabels) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TransactionLabels.Marshal(b, m, deterministic)
+}
+func (dst *TransactionLabels) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TransactionLabels.Merge(dst, src)
+}
+func (m *TransactionLabels) XXX_Size() int {
+	return xxx_messageInfo_TransactionLabels.Size(m)
+}
+func (m *TransactionLabels) XXX_DiscardUnknown() {
+	xxx_messageInfo_TransactionLabels.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TransactionLabels proto.InternalMessageInfo
+
+func (m *TransactionLabels) GetLabels() []*TransactionLabel {
+	if m != nil {
+		return m.Labels
+	}
+	return nil
+}
+
+// TransactionLabel is a visibility label the transactions of a channel may
+// carry
+type TransactionLabel struct {
+	// The name of the label, carried by the channel header of the transactions
+	Name string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	// The organizational units the certificate of the creator of a transaction
+	// carrying the label must have one of, the name of the label if empty
+	OrganizationalUnits []string `protobuf:"bytes,2,rep,name=organizational_units,json=organizationalUnits,proto3" json:"organizational_units,omitempty"`
+	// The policy of the channel, such as /Channel/Application/HRReaders, the
+	// clients asking for the transactions carrying the label must satisfy
+	ReadersPolicy        string   `protobuf:"bytes,3,opt,name=readers_policy,json=readersPolicy,proto3" json:"readers_policy,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *TransactionLabel) Reset()         { *m = TransactionLabel{} }
+func (m *TransactionLabel) String() string { return proto.CompactTextString(m) }
+func (*TransactionLabel) ProtoMessage()    {}
+func (*TransactionLabel) Descriptor() ([]byte, []int) {
+	return fileDescriptor_configuration_8db3e5bd5dced587, []int{9}
+}
+func (m *TransactionLabel) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_TransactionLabel.Unmarshal(m, b)
+}
+func (m *TransactionLabel) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_TransactionLabel.Marshal(b, m, deterministic)
+}
+func (dst *TransactionLabel) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TransactionLabel.Merge(dst, src)
+}
+func (m *TransactionLabel) XXX_Size() int {
+	return xxx_messageInfo_TransactionLabel.Size(m)
+}
+func (m *TransactionLabel) XXX_DiscardUnknown() {
+	xxx_messageInfo_TransactionLabel.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TransactionLabel proto.InternalMessageInfo
+
+func (m *TransactionLabel) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+func (m *TransactionLabel) GetOrganizationalUnits() []string {
+	if m != nil {
+		return m.OrganizationalUnits
+	}
+	return nil
+}
+
+func (m *TransactionLabel) GetReadersPolicy() string {
+	if m != nil {
+		return m.ReadersPolicy
+	}
+	return ""
+}
+
 func init() {
 	proto.RegisterType((*ConsensusType)(nil), "orderer.ConsensusType")
 	proto.RegisterType((*BatchSize)(nil), "orderer.BatchSize")
 	proto.RegisterType((*BatchTimeout)(nil), "orderer.BatchTimeout")
 	proto.RegisterType((*KafkaBrokers)(nil), "orderer.KafkaBrokers")
 	proto.RegisterType((*ChannelRestrictions)(nil), "orderer.ChannelRestrictions")
+	proto.RegisterType((*IngressWeight)(nil), "orderer.IngressWeight")
+	proto.RegisterType((*IngressQuotas)(nil), "orderer.IngressQuotas")
+	proto.RegisterType((*IngressQuota)(nil), "orderer.IngressQuota")
+	proto.RegisterType((*TransactionLabels)(nil), "orderer.TransactionLabels")
+	proto.RegisterType((*TransactionLabel)(nil), "orderer.TransactionLabel")
 	proto.RegisterEnum("orderer.ConsensusType_MigrationState", ConsensusType_MigrationState_name, ConsensusType_MigrationState_value)
 }
 
 func init() {
-	proto.RegisterFile("orderer/configuration.proto", fileDescriptor_configuration_50e2c0f98937cf5a)
-}
-
-var fileDescriptor_configuration_50e2c0f98937cf5a = []byte{
-	// 460 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x54, 0x92, 0xdf, 0x8a, 0xda, 0x40,
-	0x14, 0xc6, 0x1b, 0x95, 0xee, 0x7a, 0x58, 0x35, 0x8e, 0x2d, 0x84, 0xee, 0x8d, 0x08, 0x0b, 0xd2,
-	0x2e, 0x11, 0xb6, 0x4f, 0x60, 0x82, 0x94, 0xa5, 0x44, 0x61, 0x9c, 0x42, 0xe9, 0x4d, 0x98, 0xc4,
-	0x63, 0x0c, 0x6b, 0x32, 0x61, 0x66, 0x02, 0xb1, 0x7d, 0x8e, 0x3e, 0x4c, 0xdf, 0xae, 0xe4, 0x9f,
-	0x7f, 0xee, 0xce, 0xf7, 0x7d, 0xbf, 0x9c, 0x99, 0x39, 0x39, 0xf0, 0x28, 0xe4, 0x0e, 0x25, 0xca,
-	0x45, 0x28, 0xd2, 0x7d, 0x1c, 0xe5, 0x92, 0xeb, 0x58, 0xa4, 0x76, 0x26, 0x85, 0x16, 0xe4, 0xae,
-	0x09, 0x67, 0xff, 0x3a, 0x30, 0x70, 0x45, 0xaa, 0x30, 0x55, 0xb9, 0x62, 0xa7, 0x0c, 0x09, 0x81,
-	0x9e, 0x3e, 0x65, 0x68, 0x19, 0x53, 0x63, 0xde, 0xa7, 0x55, 0x4d, 0x3e, 0xc1, 0x7d, 0x82, 0x9a,
-	0xef, 0xb8, 0xe6, 0x56, 0x67, 0x6a, 0xcc, 0x1f, 0xe8, 0x59, 0x93, 0x35, 0x8c, 0x92, 0x38, 0xaa,
-	0xbb, 0xfb, 0x4a, 0x73, 0x8d, 0x56, 0x77, 0x6a, 0xcc, 0x87, 0x2f, 0x4f, 0x76, 0x73, 0x88, 0x7d,
-	0x73, 0x80, 0xed, 0xb5, 0xf4, 0xb6, 0x84, 0xe9, 0x30, 0xb9, 0xd1, 0xe4, 0x0b, 0x8c, 0x2f, 0xfd,
-	0x42, 0x91, 0x6a, 0x2c, 0xb4, 0xd5, 0x9b, 0x1a, 0xf3, 0x1e, 0x35, 0xcf, 0x81, 0x5b, 0xfb, 0xb3,
-	0x3f, 0x30, 0xbc, 0x6d, 0x47, 0x08, 0x0c, 0xbd, 0xd7, 0x6f, 0xfe, 0x96, 0x2d, 0xd9, 0xca, 0x5f,
-	0x6f, 0xd6, 0x2b, 0xf3, 0x1d, 0x99, 0xc0, 0xe8, 0xe2, 0x6d, 0xd9, 0x92, 0x32, 0xd3, 0x20, 0x1f,
-	0xc0, 0xbc, 0x98, 0xee, 0xc6, 0xf3, 0x5e, 0x99, 0xd9, 0xb9, 0x45, 0x97, 0xce, 0x86, 0x32, 0xb3,
-	0x4b, 0x3e, 0xc2, 0xf8, 0x1a, 0x5d, 0xb3, 0xd5, 0x4f, 0x66, 0xf6, 0x66, 0x7f, 0x0d, 0xe8, 0x3b,
-	0x5c, 0x87, 0x87, 0x6d, 0xfc, 0x1b, 0xc9, 0x67, 0x18, 0x27, 0xbc, 0xf0, 0x13, 0x54, 0x8a, 0x47,
-	0xe8, 0x87, 0x22, 0x4f, 0x75, 0x35, 0xc4, 0x01, 0x1d, 0x25, 0xbc, 0xf0, 0x6a, 0xdf, 0x2d, 0x6d,
-	0xf2, 0x0c, 0x84, 0x07, 0x4a, 0x1c, 0x73, 0x8d, 0x7e, 0xf9, 0x51, 0x70, 0xd2, 0xa8, 0xaa, 0xc9,
-	0x0e, 0xa8, 0xd9, 0x26, 0x1e, 0x2f, 0x9c, 0xd2, 0x27, 0x36, 0x4c, 0x32, 0x89, 0x7b, 0x94, 0x12,
-	0x77, 0x57, 0x78, 0xb7, 0xc2, 0xc7, 0xe7, 0xa8, 0xe5, 0x67, 0x73, 0x78, 0xa8, 0xae, 0xc5, 0xe2,
-	0x04, 0x45, 0xae, 0x89, 0x05, 0x77, 0xba, 0x2e, 0x9b, 0x9f, 0xda, 0xca, 0x92, 0xfc, 0xce, 0xf7,
-	0x6f, 0xdc, 0x91, 0xe2, 0x0d, 0xa5, 0x2a, 0xc9, 0xa0, 0x2e, 0x2d, 0x63, 0xda, 0x2d, 0xc9, 0x46,
-	0xce, 0x5e, 0x60, 0xe2, 0x1e, 0x78, 0x9a, 0xe2, 0x91, 0xa2, 0xd2, 0x32, 0x0e, 0xcb, 0x89, 0x2b,
-	0xf2, 0x08, 0xfd, 0xf2, 0x42, 0x97, 0xc7, 0xf6, 0xe8, 0x7d, 0xc2, 0x8b, 0xea, 0x95, 0xce, 0x0f,
-	0x78, 0x12, 0x32, 0xb2, 0x0f, 0xa7, 0x0c, 0xe5, 0x11, 0x77, 0x11, 0x4a, 0x7b, 0xcf, 0x03, 0x19,
-	0x87, 0xf5, 0x12, 0xaa, 0x76, 0x3f, 0x7e, 0x3d, 0x47, 0xb1, 0x3e, 0xe4, 0x81, 0x1d, 0x8a, 0x64,
-	0x71, 0x45, 0x2f, 0x6a, 0x7a, 0x51, 0xd3, 0x8b, 0x86, 0x0e, 0xde, 0x57, 0xfa, 0xeb, 0xff, 0x00,
-	0x00, 0x00, 0xff, 0xff, 0x9f, 0x9f, 0xe4, 0x13, 0xe1, 0x02, 0x00, 0x00,
+	proto.RegisterFile("orderer/configuration.proto", fileDescriptor_configuration_8db3e5bd5dced587)
+}
+
+var fileDescriptor_configuration_8db3e5bd5dced587 = []byte{
+	// 659 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x5c, 0x94, 0xdf, 0x72, 0xd2, 0x40,
+	0x14, 0xc6, 0x4d, 0xa1, 0xb4, 0x1c, 0x0b, 0x0d, 0x4b, 0xeb, 0x44, 0x7b, 0xc3, 0x64, 0xa6, 0x23,
+	0x6a, 0x0d, 0xd3, 0x7a, 0xef, 0x4c, 0x61, 0xaa, 0xc3, 0x28, 0xa0, 0x21, 0x55, 0xc7, 0x9b, 0xcc,
+	0x26, 0x1c, 0x42, 0xa6, 0x24, 0x1b, 0x77, 0x37, 0x0a, 0xf5, 0xc6, 0x97, 0xf0, 0x0d, 0x7d, 0x10,
+	0x67, 0x93, 0xf0, 0xaf, 0x77, 0xdf, 0xf9, 0xce, 0x8f, 0xec, 0x77, 0xf6, 0x0f, 0x70, 0xc6, 0xf8,
+	0x04, 0x39, 0xf2, 0x8e, 0xcf, 0xe2, 0x69, 0x18, 0xa4, 0x9c, 0xca, 0x90, 0xc5, 0x56, 0xc2, 0x99,
+	0x64, 0xe4, 0xa0, 0x68, 0x9a, 0xff, 0xf6, 0xa0, 0xd6, 0x63, 0xb1, 0xc0, 0x58, 0xa4, 0xc2, 0x59,
+	0x26, 0x48, 0x08, 0x94, 0xe5, 0x32, 0x41, 0x43, 0x6b, 0x69, 0xed, 0xaa, 0x9d, 0x69, 0xf2, 0x0c,
+	0x0e, 0x23, 0x94, 0x74, 0x42, 0x25, 0x35, 0xf6, 0x5a, 0x5a, 0xfb, 0xc8, 0x5e, 0xd7, 0x64, 0x08,
+	0xc7, 0x51, 0x18, 0xe4, 0x5f, 0x77, 0x85, 0xa4, 0x12, 0x8d, 0x52, 0x4b, 0x6b, 0xd7, 0xaf, 0xce,
+	0xad, 0x62, 0x11, 0x6b, 0x67, 0x01, 0x6b, 0xb0, 0xa2, 0xc7, 0x0a, 0xb6, 0xeb, 0xd1, 0x4e, 0x4d,
+	0x5e, 0x41, 0x63, 0xf3, 0x3d, 0x9f, 0xc5, 0x12, 0x17, 0xd2, 0x28, 0xb7, 0xb4, 0x76, 0xd9, 0xd6,
+	0xd7, 0x8d, 0x5e, 0xee, 0x93, 0x17, 0xa0, 0xaf, 0x82, 0xb8, 0x3f, 0x91, 0x8b, 0x90, 0xc5, 0xc6,
+	0x7e, 0x4b, 0x6b, 0xd7, 0xec, 0xe3, 0x95, 0xff, 0x25, 0xb7, 0xcd, 0xdf, 0x50, 0xdf, 0x5d, 0x99,
+	0x10, 0xa8, 0x0f, 0xfa, 0xef, 0xdd, 0xb1, 0x73, 0xed, 0xdc, 0xb8, 0xc3, 0xd1, 0xf0, 0x46, 0x7f,
+	0x44, 0x9a, 0x70, 0xbc, 0xf1, 0xc6, 0xce, 0xb5, 0xed, 0xe8, 0x1a, 0x39, 0x01, 0x7d, 0x63, 0xf6,
+	0x46, 0x83, 0x41, 0xdf, 0xd1, 0xf7, 0x76, 0xd1, 0xeb, 0xee, 0xc8, 0x76, 0xf4, 0x12, 0x39, 0x85,
+	0xc6, 0x36, 0x3a, 0x74, 0x6e, 0xbe, 0x39, 0x7a, 0xd9, 0xfc, 0xab, 0x41, 0xb5, 0x4b, 0xa5, 0x3f,
+	0x1b, 0x87, 0xf7, 0x48, 0x5e, 0x42, 0x23, 0xa2, 0x0b, 0x37, 0x42, 0x21, 0x68, 0x80, 0xae, 0xcf,
+	0xd2, 0x58, 0x1a, 0x5a, 0x11, 0x9b, 0x2e, 0x06, 0xb9, 0xdf, 0x53, 0x36, 0xb9, 0x00, 0x42, 0x3d,
+	0xc1, 0xe6, 0xa9, 0x44, 0x57, 0xfd, 0xc8, 0x5b, 0x4a, 0x14, 0xd9, 0x21, 0xd4, 0x6c, 0x7d, 0xd5,
+	0x19, 0xd0, 0x45, 0x57, 0xf9, 0xc4, 0x82, 0x66, 0xc2, 0x71, 0x8a, 0x9c, 0xe3, 0x64, 0x0b, 0x2f,
+	0x65, 0x78, 0x63, 0xdd, 0x5a, 0xf1, 0x66, 0x1b, 0x8e, 0xb2, 0x58, 0x4e, 0x18, 0x21, 0x4b, 0x25,
+	0x31, 0xe0, 0x40, 0xe6, 0xb2, 0x38, 0xff, 0x55, 0xa9, 0xc8, 0x0f, 0x74, 0x7a, 0x47, 0xbb, 0x9c,
+	0xdd, 0x21, 0x17, 0x8a, 0xf4, 0x72, 0x69, 0x68, 0xad, 0x92, 0x22, 0x8b, 0xd2, 0xbc, 0x82, 0x66,
+	0x6f, 0x46, 0xe3, 0x18, 0xe7, 0x36, 0x0a, 0xc9, 0x43, 0x5f, 0xed, 0xb8, 0x20, 0x67, 0x50, 0x55,
+	0x81, 0x36, 0xc3, 0x96, 0xed, 0xc3, 0x88, 0x2e, 0xb2, 0x29, 0xcd, 0xe7, 0x50, 0xeb, 0xc7, 0x01,
+	0x47, 0x21, 0xbe, 0x62, 0x18, 0xcc, 0x24, 0x79, 0x02, 0x95, 0x5f, 0x99, 0x2a, 0xf6, 0xa5, 0xa8,
+	0xcc, 0xb7, 0x6b, 0xf0, 0x73, 0xca, 0x24, 0x15, 0xe4, 0x35, 0x54, 0x7e, 0x64, 0x2a, 0x8b, 0xf1,
+	0xf8, 0xea, 0x74, 0x7d, 0xeb, 0xb6, 0x39, 0xbb, 0x80, 0xcc, 0x11, 0x1c, 0x6d, 0xfb, 0xe4, 0x14,
+	0x2a, 0x91, 0x48, 0xdc, 0x70, 0x52, 0xcc, 0xbb, 0x1f, 0x89, 0xa4, 0x3f, 0x51, 0x8f, 0x80, 0xab,
+	0x9b, 0x9c, 0xef, 0x73, 0xa6, 0xc9, 0x09, 0xec, 0x7b, 0x29, 0x17, 0xb2, 0xd8, 0xcd, 0xbc, 0x30,
+	0xdf, 0x41, 0xc3, 0xe1, 0x34, 0x16, 0x34, 0x1b, 0xf3, 0x23, 0xf5, 0x70, 0x2e, 0xc8, 0x25, 0x54,
+	0xe6, 0x99, 0x2a, 0x42, 0x3d, 0x5d, 0x87, 0x7a, 0xc8, 0xda, 0x05, 0x68, 0xfe, 0xd1, 0x40, 0x7f,
+	0xd8, 0x54, 0x31, 0x62, 0x1a, 0xad, 0xdf, 0xa2, 0xd2, 0xe4, 0x12, 0x4e, 0x18, 0x0f, 0x68, 0x1c,
+	0xde, 0x67, 0x57, 0x99, 0xce, 0xdd, 0x34, 0x0e, 0xa5, 0xba, 0x12, 0xea, 0x14, 0x9a, 0xbb, 0xbd,
+	0x5b, 0xd5, 0x22, 0xe7, 0x50, 0xe7, 0x48, 0x27, 0xc8, 0x85, 0x9b, 0xb0, 0x79, 0xe8, 0x2f, 0xb3,
+	0x11, 0xaa, 0x76, 0xad, 0x70, 0x3f, 0x65, 0x66, 0xf7, 0x16, 0xce, 0x19, 0x0f, 0xac, 0xd9, 0x32,
+	0x41, 0x3e, 0xc7, 0x49, 0x80, 0xdc, 0x9a, 0x52, 0x8f, 0x87, 0x7e, 0xfe, 0xa7, 0x21, 0x56, 0x43,
+	0x7c, 0xbf, 0x08, 0x42, 0x39, 0x4b, 0x3d, 0xcb, 0x67, 0x51, 0x67, 0x8b, 0xee, 0xe4, 0x74, 0x27,
+	0xa7, 0x3b, 0x05, 0xed, 0x55, 0xb2, 0xfa, 0xcd, 0xff, 0x01, 0x00, 0x34, 0xa7, 0x40, 0x94, 0x91,
+	0x04, 0x00, 0x00,
 }
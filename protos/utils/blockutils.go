@@ -8,6 +8,7 @@ package utils
 
 import (
 	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/util"
 	cb "github.com/hyperledger/fabric/protos/common"
 	"github.com/pkg/errors"
 )
@@ -94,6 +95,29 @@ func GetLastConfigIndexFromBlockOrPanic(block *cb.Block) uint64 {
 	return index
 }
 
+// ComputeRandomBeacon derives the random beacon value of a block from the
+// random beacon value of the previous block and the header of the block. The
+// previous beacon may be nil for the first block of a chain carrying a beacon.
+func ComputeRandomBeacon(previousBeacon []byte, header *cb.BlockHeader) []byte {
+	return util.ComputeSHA256(util.ConcatenateBytes(previousBeacon, header.Bytes()))
+}
+
+// GetRandomBeaconFromBlock retrieves the random beacon value as encoded in the
+// block metadata
+func GetRandomBeaconFromBlock(block *cb.Block) ([]byte, error) {
+	if block.Metadata == nil || len(block.Metadata.Metadata) <= int(cb.BlockMetadataIndex_RANDOM_BEACON) {
+		return nil, errors.New("block metadata does not contain a random beacon")
+	}
+	md, err := GetMetadataFromBlock(block, cb.BlockMetadataIndex_RANDOM_BEACON)
+	if err != nil {
+		return nil, err
+	}
+	if len(md.Value) == 0 {
+		return nil, errors.New("block metadata does not contain a random beacon")
+	}
+	return md.Value, nil
+}
+
 // GetBlockFromBlockBytes marshals the bytes into Block
 func GetBlockFromBlockBytes(blockBytes []byte) (*cb.Block, error) {
 	block := &cb.Block{}
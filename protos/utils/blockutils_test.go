@@ -204,3 +204,29 @@ func TestGetLastConfigIndexFromBlock(t *testing.T) {
 		_ = utils.GetLastConfigIndexFromBlockOrPanic(block)
 	}, "Expected panic with malformed last config metadata")
 }
+
+func TestGetRandomBeaconFromBlock(t *testing.T) {
+	block := common.NewBlock(1, []byte("previous hash"))
+	beacon := utils.ComputeRandomBeacon([]byte("previous beacon"), block.Header)
+	metadata, _ := proto.Marshal(&cb.Metadata{
+		Value: beacon,
+	})
+	block.Metadata.Metadata[cb.BlockMetadataIndex_RANDOM_BEACON] = metadata
+	result, err := utils.GetRandomBeaconFromBlock(block)
+	assert.NoError(t, err, "Unexpected error returning random beacon")
+	assert.Equal(t, beacon, result, "Unexpected random beacon returned from block")
+
+	// beacon is chained to the previous beacon
+	assert.NotEqual(t, beacon, utils.ComputeRandomBeacon(nil, block.Header))
+	assert.Equal(t, beacon, utils.ComputeRandomBeacon([]byte("previous beacon"), block.Header))
+
+	// malformed metadata
+	block.Metadata.Metadata[cb.BlockMetadataIndex_RANDOM_BEACON] = []byte("bad metadata")
+	_, err = utils.GetRandomBeaconFromBlock(block)
+	assert.Error(t, err, "Expected error with malformed metadata")
+
+	// block written before random beacons were introduced
+	block.Metadata.Metadata = block.Metadata.Metadata[:cb.BlockMetadataIndex_RANDOM_BEACON]
+	_, err = utils.GetRandomBeaconFromBlock(block)
+	assert.Error(t, err, "Expected error with missing random beacon")
+}
@@ -533,6 +533,42 @@ func CreateUpgradeProposalFromCDS(
 	return createProposalFromCDS(chainID, cds, creator, "upgrade", policy, escc, vscc, collectionConfig)
 }
 
+// CreateDeployProposalFromCDSWithLimits returns a deploy proposal given a
+// serialized identity, a ChaincodeDeploymentSpec and the marshalled
+// execution limits of the chaincode
+func CreateDeployProposalFromCDSWithLimits(
+	chainID string,
+	cds *peer.ChaincodeDeploymentSpec,
+	creator []byte,
+	policy []byte,
+	escc []byte,
+	vscc []byte,
+	collectionConfig []byte,
+	limits []byte) (*peer.Proposal, string, error) {
+	if limits == nil {
+		return CreateDeployProposalFromCDS(chainID, cds, creator, policy, escc, vscc, collectionConfig)
+	}
+	return createProposalFromCDS(chainID, cds, creator, "deploy", policy, escc, vscc, collectionConfig, limits)
+}
+
+// CreateUpgradeProposalFromCDSWithLimits returns a upgrade proposal given a
+// serialized identity, a ChaincodeDeploymentSpec and the marshalled
+// execution limits of the chaincode
+func CreateUpgradeProposalFromCDSWithLimits(
+	chainID string,
+	cds *peer.ChaincodeDeploymentSpec,
+	creator []byte,
+	policy []byte,
+	escc []byte,
+	vscc []byte,
+	collectionConfig []byte,
+	limits []byte) (*peer.Proposal, string, error) {
+	if limits == nil {
+		return CreateUpgradeProposalFromCDS(chainID, cds, creator, policy, escc, vscc, collectionConfig)
+	}
+	return createProposalFromCDS(chainID, cds, creator, "upgrade", policy, escc, vscc, collectionConfig, limits)
+}
+
 // createProposalFromCDS returns a deploy or upgrade proposal given a
 // serialized identity and a ChaincodeDeploymentSpec
 func createProposalFromCDS(chainID string, msg proto.Message, creator []byte, propType string, args ...[]byte) (*peer.Proposal, string, error) {
@@ -604,6 +640,46 @@ func CheckTxID(txid string, nonce, creator []byte) error {
 	return nil
 }
 
+// ComputeIdempotentNonce derives a nonce from an idempotency key chosen by
+// the client and the creator, in place of a random one.  The proposals
+// created by a creator with the same key carry the same transaction ID, so
+// that the peers and the orderers recognize the retries of a submission as
+// duplicates of it.
+func ComputeIdempotentNonce(idempotencyKey, creator []byte) ([]byte, error) {
+	if len(idempotencyKey) == 0 {
+		return nil, errors.New("idempotency key is empty")
+	}
+
+	// the key is length prefixed so that the boundary with the creator is unambiguous
+	msg := make([]byte, binary.MaxVarintLen64, binary.MaxVarintLen64+len(idempotencyKey)+len(creator))
+	msg = msg[:binary.PutUvarint(msg, uint64(len(idempotencyKey)))]
+	msg = append(msg, idempotencyKey...)
+	msg = append(msg, creator...)
+	digest, err := factory.GetDefault().Hash(msg, &bccsp.SHA256Opts{})
+	if err != nil {
+		return nil, err
+	}
+	return digest[:crypto.NonceSize], nil
+}
+
+// CreateChaincodeProposalWithIdempotencyKey creates a proposal from given
+// input whose nonce, and therefore transaction ID, is derived from the
+// idempotency key and the creator.  It returns the proposal and the
+// associated transaction id.
+func CreateChaincodeProposalWithIdempotencyKey(typ common.HeaderType, chainID string, cis *peer.ChaincodeInvocationSpec, creator []byte, idempotencyKey []byte, transientMap map[string][]byte) (*peer.Proposal, string, error) {
+	nonce, err := ComputeIdempotentNonce(idempotencyKey, creator)
+	if err != nil {
+		return nil, "", err
+	}
+
+	txid, err := ComputeTxID(nonce, creator)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return CreateChaincodeProposalWithTxIDNonceAndTransient(txid, typ, chainID, cis, nonce, creator, transientMap)
+}
+
 // ComputeProposalBinding computes the binding of a proposal
 func ComputeProposalBinding(proposal *peer.Proposal) ([]byte, error) {
 	if proposal == nil {
@@ -15,6 +15,7 @@ import (
 	"testing"
 
 	"github.com/golang/protobuf/proto"
+	"github.com/hyperledger/fabric/common/crypto"
 	"github.com/hyperledger/fabric/common/util"
 	"github.com/hyperledger/fabric/core/chaincode/platforms"
 	"github.com/hyperledger/fabric/core/chaincode/platforms/golang"
@@ -222,6 +223,20 @@ func TestCDSProposals(t *testing.T) {
 	assert.NoError(t, err, "Unexpected error creating upgrade proposal")
 	assert.NotEqual(t, "", txid, "txid should not be empty")
 
+	// deploy and upgrade with limits
+	limits := []byte("limits")
+	prop, _, err = utils.CreateDeployProposalFromCDSWithLimits(chainID, cds, creator, policy, escc, vscc, nil, limits)
+	assert.NoError(t, err, "Unexpected error creating deploy proposal")
+	cis, err := utils.GetChaincodeInvocationSpec(prop)
+	assert.NoError(t, err)
+	assert.Len(t, cis.ChaincodeSpec.Input.Args, 8)
+	assert.Equal(t, limits, cis.ChaincodeSpec.Input.Args[7])
+
+	prop, _, err = utils.CreateUpgradeProposalFromCDSWithLimits(chainID, cds, creator, policy, escc, vscc, nil, nil)
+	assert.NoError(t, err, "Unexpected error creating upgrade proposal")
+	cis, err = utils.GetChaincodeInvocationSpec(prop)
+	assert.NoError(t, err)
+	assert.Len(t, cis.ChaincodeSpec.Input.Args, 6)
 }
 
 func TestComputeProposalBinding(t *testing.T) {
@@ -602,6 +617,46 @@ func TestEnvelope(t *testing.T) {
 	}
 }
 
+func TestProposalWithIdempotencyKey(t *testing.T) {
+	creator := []byte("creator")
+	prop, txid, err := utils.CreateChaincodeProposalWithIdempotencyKey(
+		common.HeaderType_ENDORSER_TRANSACTION,
+		util.GetTestChainID(),
+		createCIS(),
+		creator,
+		[]byte("order-42"),
+		nil,
+	)
+	assert.NoError(t, err)
+	nonce, err := utils.GetNonce(prop)
+	assert.NoError(t, err)
+	assert.Len(t, nonce, crypto.NonceSize)
+	assert.NoError(t, utils.CheckTxID(txid, nonce, creator))
+
+	// a retry carries the same transaction ID
+	_, retryTxID, err := utils.CreateChaincodeProposalWithIdempotencyKey(
+		common.HeaderType_ENDORSER_TRANSACTION,
+		util.GetTestChainID(),
+		createCIS(),
+		creator,
+		[]byte("order-42"),
+		nil,
+	)
+	assert.NoError(t, err)
+	assert.Equal(t, txid, retryTxID)
+
+	// other keys and other creators do not
+	otherNonce, err := utils.ComputeIdempotentNonce([]byte("order-43"), creator)
+	assert.NoError(t, err)
+	assert.NotEqual(t, nonce, otherNonce)
+	otherNonce, err = utils.ComputeIdempotentNonce([]byte("order-4"), []byte("2creator"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, nonce, otherNonce)
+
+	_, err = utils.ComputeIdempotentNonce(nil, creator)
+	assert.EqualError(t, err, "idempotency key is empty")
+}
+
 func TestProposalTxID(t *testing.T) {
 	nonce := []byte{1}
 	creator := []byte{2}